@@ -1,13 +1,17 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
 	_ "github.com/kurobon/gitgym/backend/internal/git/commands" // Register commands
+	"github.com/kurobon/gitgym/backend/internal/logging"
 	"github.com/kurobon/gitgym/backend/internal/mission"
 	"github.com/kurobon/gitgym/backend/internal/server"
 )
@@ -27,31 +31,136 @@ func getDataDir() string {
 }
 
 func main() {
+	// SIGINT or SIGTERM starts a graceful shutdown
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Structured log output, level and format from the environment
+	logConfig, logErr := logging.ConfigFromEnv()
+	logging.Setup(os.Stderr, logConfig)
+	if logErr != nil {
+		slog.Warn("logging configuration", "err", logErr)
+	}
+
 	dataDir := getDataDir()
 	// Check if CLEAR_REMOTES_ON_START is set to clear the remotes directory
 	if os.Getenv("CLEAR_REMOTES_ON_START") == "true" {
 		remotesDir := dataDir + "/remotes"
-		log.Printf("CLEAR_REMOTES_ON_START is set, clearing %s", remotesDir)
+		slog.Info("CLEAR_REMOTES_ON_START is set, clearing remotes", "dir", remotesDir)
 		if err := os.RemoveAll(remotesDir); err != nil {
-			log.Printf("Warning: Failed to clear remotes directory: %v", err)
+			slog.Warn("failed to clear remotes directory", "err", err)
 		}
 	}
 
 	// Initialize Core Dependencies
 	sessionManager := git.NewSessionManager()
 
+	// Limit which repositories the ingest endpoint may copy onto this server
+	ingestPolicy, err := git.IngestPolicyFromEnv()
+	if err != nil {
+		slog.Warn("ingest policy", "err", err)
+	}
+	sessionManager.IngestPolicy = ingestPolicy
+
+	// Metadata store for PRs, mission progress and the audit log
+	storeKind := os.Getenv(git.StoreEnv)
+	store, err := git.OpenStore(storeKind, dataDir+"/gitgym.db")
+	if err != nil {
+		slog.Error("failed to open metadata store", "kind", storeKind, "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	if err := sessionManager.UseStore(store); err != nil {
+		slog.Error("failed to load metadata store", "err", err)
+		os.Exit(1)
+	}
+
+	// Optionally persist sessions across restarts
+	if os.Getenv(git.PersistSessionsEnv) == "true" {
+		sessionsDir := dataDir + "/sessions"
+		if err := sessionManager.EnablePersistence(sessionsDir); err != nil {
+			slog.Warn("failed to enable session persistence", "err", err)
+		} else if n, err := sessionManager.LoadPersistedSessions(); err != nil {
+			slog.Warn("failed to load persisted sessions", "err", err)
+		} else {
+			slog.Info("session persistence enabled", "dir", sessionsDir, "restored", n)
+		}
+	}
+
+	// Evict sessions that have been idle for longer than the TTL
+	sessionTTL := git.DefaultSessionTTL
+	if v := os.Getenv(git.SessionTTLEnv); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil && ttl > 0 {
+			sessionTTL = ttl
+		} else {
+			slog.Warn("invalid "+git.SessionTTLEnv, "value", v, "using", sessionTTL)
+		}
+	}
+	// Closed on shutdown to stop the background loops
+	stop := make(chan struct{})
+
+	sessionManager.StartEviction(sessionTTL, time.Minute, stop)
+
+	// Drop objects that stayed unreachable for two sweeps
+	gcInterval := git.DefaultGCInterval
+	if v := os.Getenv(git.GCIntervalEnv); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil && interval >= 0 {
+			gcInterval = interval
+		} else {
+			slog.Warn("invalid "+git.GCIntervalEnv, "value", v, "using", gcInterval)
+		}
+	}
+	if gcInterval > 0 {
+		sessionManager.StartGarbageCollection(gcInterval, stop)
+	}
+
+	// Fetch ingested remotes that were given a sync interval from their upstream again
+	sessionManager.StartRemoteSync(stop)
+
+	// Re-register ingested remotes and resume ingests interrupted by a previous shutdown
+	go func() {
+		rec, err := sessionManager.RecoverIngests(ctx)
+		if err != nil {
+			slog.Warn("failed to recover remotes", "err", err)
+			return
+		}
+		slog.Info("remote recovery", "restored", len(rec.Restored), "resumed", len(rec.Resumed), "cleaned", len(rec.Cleaned))
+	}()
+
 	// Initialize Mission Engine
 	// We put missions in "missions" directory relative to binary? Or distinct dir.
 	// Assume "missions" dir in CWD (backend root).
 	missionLoader := mission.NewLoader("missions")
+	missionLoader.Strict = os.Getenv(mission.StrictMissionsEnv) == "true"
 	missionEngine := mission.NewEngine(missionLoader, sessionManager)
 
+	// Report authoring mistakes in mission files at startup, and on every edit when watching
+	logMissionReport := func(report *mission.ValidationReport) {
+		slog.Info("missions loaded", "missions", report.Missions, "issues", len(report.Issues))
+		for _, issue := range report.Issues {
+			slog.Warn("mission issue", "severity", issue.Severity, "file", issue.File, "field", issue.Field, "msg", issue.Message)
+		}
+	}
+	if report, err := missionLoader.Validate(); err != nil {
+		slog.Warn("failed to validate missions", "err", err)
+	} else {
+		logMissionReport(report)
+	}
+	if os.Getenv(mission.WatchMissionsEnv) == "true" {
+		go missionLoader.Watch(ctx, 2*time.Second, logMissionReport)
+	}
+
 	// Pre-ingest default remote repository asynchronously
 	// Default remote initialization removed at user request
 	// go func() { ... }()
 
 	// Initialize HTTP Server
 	srv := server.NewServer(sessionManager, missionEngine)
+	limits, err := server.LimitsFromEnv()
+	if err != nil {
+		slog.Warn("request limits", "err", err)
+	}
+	srv.Limits = limits
 
 	// Security: Use http.Server with timeouts (G114)
 	httpServer := &http.Server{
@@ -62,8 +171,42 @@ func main() {
 		IdleTimeout:  300 * time.Second,
 	}
 
-	log.Println("Server listening on :8080")
-	if err := httpServer.ListenAndServe(); err != nil {
-		log.Fatal(err)
+	// Streams would keep Shutdown waiting until its deadline
+	httpServer.RegisterOnShutdown(srv.CloseStreams)
+
+	shutdownTimeout := git.DefaultShutdownTimeout
+	if v := os.Getenv(git.ShutdownTimeoutEnv); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			shutdownTimeout = timeout
+		} else {
+			slog.Warn("invalid "+git.ShutdownTimeoutEnv, "value", v, "using", shutdownTimeout)
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.ListenAndServe() }()
+	slog.Info("server listening", "addr", httpServer.Addr)
+	select {
+	case err := <-serveErr:
+		slog.Error("server stopped", "err", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process right away
+	stopSignals()
+	slog.Info("shutting down", "timeout", shutdownTimeout)
+	close(stop)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop taking requests and let the running ones finish, then wait for
+	// commands and jobs started outside of requests and save the sessions
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("requests still running at shutdown", "err", err)
+	}
+	if err := sessionManager.Drain(shutdownCtx); err != nil {
+		slog.Warn("drain", "err", err)
 	}
+	slog.Info("shutdown complete")
 }
@@ -0,0 +1,142 @@
+package git
+
+// attributes.go - .gitattributes line-ending conversion
+//
+// NormalizeLineEndings plays the role of git's "clean" conversion for files
+// with the text attribute: CRLFs are stored as LF in the index.
+// SmudgeLineEndings is the checkout side: eol=crlf files written to the
+// worktree as LF get CRLFs back. Merge3Way consults the merge attribute.
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// Attributes are the .gitattributes rules of a worktree.
+type Attributes = state.Attributes
+
+// LoadAttributes reads the worktree's .gitattributes.
+// Wrapper around state.LoadAttributes
+func LoadAttributes(fs billy.Filesystem) *Attributes {
+	return state.LoadAttributes(fs)
+}
+
+// NormalizeLineEndings converts CRLFs to LF in the text files staged since
+// before. It returns the converted paths whose worktree copy will not get its
+// CRLFs back on checkout, which git warns about.
+func NormalizeLineEndings(repo *gogit.Repository, w *gogit.Worktree, before map[string]plumbing.Hash) ([]string, error) {
+	attrs := state.LoadAttributes(w.Filesystem)
+	if attrs.Empty() {
+		return nil, nil
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	var lossy []string
+	changed := false
+	for _, e := range idx.Entries {
+		if prev, ok := before[e.Name]; ok && prev == e.Hash {
+			continue
+		}
+		content, err := readBlob(repo, e.Hash)
+		if err != nil || !bytes.Contains(content, []byte("\r\n")) || !attrs.IsText(e.Name, content) {
+			continue
+		}
+		normalized := state.ToLF(content)
+		hash, err := writeBlob(repo, normalized)
+		if err != nil {
+			return nil, err
+		}
+		e.Hash = hash
+		e.Size = uint32(len(normalized))
+		changed = true
+		if !attrs.CheckoutCRLF(e.Name, content) {
+			lossy = append(lossy, e.Name)
+		}
+	}
+
+	if !changed {
+		return nil, nil
+	}
+	return lossy, repo.Storer.SetIndex(idx)
+}
+
+// SmudgeLineEndings rewrites eol=crlf text files whose worktree copy is still
+// exactly the LF content of the index with CRLFs, as checkout does. Files
+// edited since are left alone.
+func SmudgeLineEndings(repo *gogit.Repository) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return
+	}
+	attrs := state.LoadAttributes(w.Filesystem)
+	if attrs.Empty() {
+		return
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return
+	}
+
+	for _, e := range idx.Entries {
+		if attrs.Get(e.Name, "eol") != "crlf" {
+			continue
+		}
+		data, err := util.ReadFile(w.Filesystem, e.Name)
+		if err != nil || !bytes.Contains(data, []byte("\n")) || !attrs.CheckoutCRLF(e.Name, data) {
+			continue
+		}
+		if plumbing.ComputeHash(plumbing.BlobObject, data) != e.Hash {
+			continue
+		}
+		_ = util.WriteFile(w.Filesystem, e.Name, state.ToCRLF(data), 0644)
+	}
+}
+
+// unionMerge resolves a conflict like the union merge driver, keeping the
+// lines of both sides: ours, followed by the lines theirs added since base
+// that ours does not already have.
+func unionMerge(base, ours, theirs string) string {
+	have := make(map[string]int)
+	for _, line := range splitLines(base) {
+		have[line]++
+	}
+	for _, line := range splitLines(ours) {
+		have[line]++
+	}
+
+	result := ours
+	if result != "" && result[len(result)-1] != '\n' {
+		result += "\n"
+	}
+	for _, line := range splitLines(theirs) {
+		if have[line] > 0 {
+			have[line]--
+			continue
+		}
+		result += line
+	}
+	return result
+}
+
+// splitLines splits content after each newline, keeping them.
+func splitLines(content string) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := strings.IndexByte(content, '\n') + 1
+		if i == 0 {
+			i = len(content)
+		}
+		lines = append(lines, content[:i])
+		content = content[i:]
+	}
+	return lines
+}
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// BlameLine attributes one line of a file to the commit that last changed it.
+type BlameLine struct {
+	Line   int    `json:"line"` // 1-based line number
+	Commit string `json:"commit"`
+	Author string `json:"author"`
+	Email  string `json:"email"`
+	Date   string `json:"date"` // RFC3339
+	Text   string `json:"text"`
+}
+
+// BlameResult is the line attribution of a file at a revision.
+type BlameResult struct {
+	Path       string      `json:"path"`
+	Revision   string      `json:"revision"`   // Full hash of the blamed commit
+	TotalLines int         `json:"totalLines"` // Lines in the whole file, whatever the range
+	Lines      []BlameLine `json:"lines"`
+}
+
+// BlameFile attributes the lines start..end (1-based, inclusive) of path at rev.
+// A zero start or end leaves that side of the range open.
+func BlameFile(repo *gogit.Repository, rev, path string, start, end int) (*BlameResult, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	file, err := commit.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: no such path '%s' in %s", path, rev)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	// File content is used for the text, go-git's blame lines can differ on trailing newlines
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start < 1 || (len(lines) > 0 && start > len(lines)) {
+		return nil, fmt.Errorf("fatal: file %s has only %d lines", path, len(lines))
+	}
+	if end < start {
+		start, end = end, start
+	}
+
+	result := &BlameResult{Path: path, Revision: commit.Hash.String(), TotalLines: len(lines), Lines: []BlameLine{}}
+	if len(lines) == 0 {
+		return result, nil
+	}
+
+	blame, err := gogit.Blame(commit, path)
+	if err != nil {
+		return nil, fmt.Errorf("blame failed: %v", err)
+	}
+	for i := start - 1; i < end && i < len(blame.Lines); i++ {
+		line := blame.Lines[i]
+		result.Lines = append(result.Lines, BlameLine{
+			Line:   i + 1,
+			Commit: line.Hash.String(),
+			Author: line.AuthorName,
+			Email:  line.Author,
+			Date:   line.Date.Format(time.RFC3339),
+			Text:   lines[i],
+		})
+	}
+	return result, nil
+}
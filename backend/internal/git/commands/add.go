@@ -8,6 +8,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/kurobon/gitgym/backend/internal/git"
@@ -24,6 +25,7 @@ var _ git.Command = (*AddCommand)(nil)
 
 type AddOptions struct {
 	All       bool
+	Force     bool // Also add files excluded by .gitignore
 	Pathspecs []string
 }
 
@@ -49,7 +51,30 @@ func (c *AddCommand) Execute(ctx context.Context, s *git.Session, args []string)
 	}
 
 	// 3. Execution
-	return c.executeAdd(w, opts)
+	before := git.IndexHashes(repo)
+	out, err := c.executeAdd(repo, w, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// 4. Line endings: text files are staged with LF
+	lossy, err := git.NormalizeLineEndings(repo, w, before)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range lossy {
+		out += fmt.Sprintf("\nwarning: in the working copy of '%s', CRLF will be replaced by LF the next time Git touches it", file)
+	}
+
+	// 5. LFS clean filter: large/tracked files are staged as pointers
+	converted, err := git.ApplyLFSCleanFilter(s, repo, w, before)
+	if err != nil {
+		return "", err
+	}
+	if len(converted) > 0 {
+		out += fmt.Sprintf("\nLFS: stored %v as pointer file(s); content kept in the LFS store", converted)
+	}
+	return out, nil
 }
 
 func (c *AddCommand) parseArgs(args []string) (*AddOptions, error) {
@@ -63,6 +88,8 @@ func (c *AddCommand) parseArgs(args []string) (*AddOptions, error) {
 			return nil, fmt.Errorf("help requested")
 		case "-A", "--all":
 			opts.All = true
+		case "-f", "--force":
+			opts.Force = true
 		case "--":
 			// Remainder are pathspecs
 			if i+1 < len(cmdArgs) {
@@ -79,17 +106,25 @@ func (c *AddCommand) parseArgs(args []string) (*AddOptions, error) {
 	return opts, nil
 }
 
-func (c *AddCommand) executeAdd(w *gogit.Worktree, opts *AddOptions) (string, error) {
+func (c *AddCommand) executeAdd(repo *gogit.Repository, w *gogit.Worktree, opts *AddOptions) (string, error) {
 	if len(opts.Pathspecs) == 0 && !opts.All {
 		return "", fmt.Errorf("nothing specified, nothing added.\nMaybe you wanted to say 'git add .'?")
 	}
 
 	var err error
+	var ignored []string
 	if opts.All {
-		// "git add ." or "git add -A"
+		// "git add ." or "git add -A" (go-git's status already skips ignored files)
 		_, err = w.Add(".")
 	} else {
+		rules := git.LoadIgnoreRules(w.Filesystem)
+		tracked := git.TrackedPaths(repo)
 		for _, file := range opts.Pathspecs {
+			// Untracked paths matching .gitignore are only added with -f
+			if !opts.Force && !tracked[file] && rules.IsIgnored(file, git.IsDirPath(w.Filesystem, file)) {
+				ignored = append(ignored, file)
+				continue
+			}
 			_, e := w.Add(file)
 			if e != nil {
 				return "", e
@@ -100,6 +135,9 @@ func (c *AddCommand) executeAdd(w *gogit.Worktree, opts *AddOptions) (string, er
 	if err != nil {
 		return "", err
 	}
+	if len(ignored) > 0 {
+		return "", fmt.Errorf("The following paths are ignored by one of your .gitignore files:\n%s\nhint: Use -f if you really want to add them.", strings.Join(ignored, "\n"))
+	}
 
 	if opts.All {
 		return "Added changes", nil
@@ -107,41 +145,17 @@ func (c *AddCommand) executeAdd(w *gogit.Worktree, opts *AddOptions) (string, er
 	return "Added " + fmt.Sprintf("%v", opts.Pathspecs), nil
 }
 
-func (c *AddCommand) Help() string {
-	return `📘 GIT-ADD (1)                                          Git Manual
-
- 💡 DESCRIPTION
-    ・変更したファイルをステージングエリア（コミットする準備場所）に追加する
-    ・新規作成したファイルをGitの管理対象にする
-
- 📋 SYNOPSIS
-    git add [<options>] [--] <pathspec>...
-
- ⚙️  COMMON OPTIONS
-    .
-        カレントディレクトリ配下のすべての変更（新規・変更・削除）を追加します。
-
-    -A, --all
-        ワークツリー全体のすべての変更を追加します。
-
-    -p, --patch
-        (現在未実装) 変更箇所(hunk)を選択してステージングします。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: すべての変更をステージング
-       変更内容が大きい場合など、一旦すべてステージングします。
-       $ git add .
-
-    2. 実践: 特定のファイルだけ (Recommended)
-       関係ないファイルの巻き込み事故を防ぐため、慣れてきたらファイル指定がベストです。
-       $ git add src/main.go
-
-    3. 実践: 部分的にステージング (Advance)
-       「この修正はコミットしたいけど、あのデバッグログは入れたくない」
-       そういう時は -p (patch) オプションを使います。
-       $ git add -p
+// Spec implements git.SpecProvider.
+func (c *AddCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-A", "--all"}, Usage: "Stage every change"},
+			{Flags: []string{"-f", "--force"}, Usage: "Add ignored files too"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-add
-`
+func (c *AddCommand) Help() string {
+	return git.CommandHelp(context.Background(), "add")
 }
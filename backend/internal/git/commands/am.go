@@ -0,0 +1,307 @@
+package commands
+
+// am.go - Simulated Git Am Command
+//
+// Applies the messages of a mailbox written by format-patch as commits, each
+// keeping the author, date and message of the original. A patch that does
+// not apply stops the series: after fixing things up, "git am --continue"
+// commits it and goes on, "--skip" drops it and "--abort" returns to where
+// the series started.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("am", func() git.Command { return &AmCommand{} })
+}
+
+type AmCommand struct{}
+
+// Ensure AmCommand implements git.Command
+var _ git.Command = (*AmCommand)(nil)
+
+type AmOptions struct {
+	ThreeWay bool // -3/--3way: merge with the patch's preimage when it does not apply; kept for --continue and --skip
+	Continue bool // Commit the fixed-up patch and apply the rest
+	Skip     bool // Drop the stopped patch and apply the rest
+	Abort    bool // Return to the HEAD before the am started
+	Files    []string
+}
+
+func (c *AmCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "am"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case opts.Continue:
+		return c.continueAm(s, repo, w)
+	case opts.Skip:
+		return c.skipAm(s, repo, w)
+	case opts.Abort:
+		return c.abortAm(s, w)
+	}
+
+	if s.AmInProgress() != nil {
+		return "", fmt.Errorf("fatal: previous am is still in progress\nhint: use \"git am --continue\", \"git am --skip\" or \"git am --abort\"")
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("fatal: HEAD does not point to a commit; make an initial commit first")
+	}
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return "", err
+	}
+	for path, fs := range status {
+		if fs.Staging != gogit.Unmodified && fs.Staging != gogit.Untracked {
+			return "", fmt.Errorf("error: Dirty index: cannot apply patches (dirty: %s)", path)
+		}
+	}
+
+	var messages []string
+	for _, name := range opts.Files {
+		data, err := readPatchFile(s, name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := git.ParsePatches(data); err != nil {
+			return "", err
+		}
+		texts, err := git.SplitMbox(data)
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, texts...)
+	}
+
+	progress := &git.AmState{OrigHead: head.Hash().String(), ThreeWay: opts.ThreeWay}
+	return c.applySeries(s, repo, w, messages, progress)
+}
+
+func (c *AmCommand) parseArgs(args []string) (*AmOptions, error) {
+	opts := &AmOptions{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "-3", "--3way":
+			opts.ThreeWay = true
+		case "--continue", "--resolved", "-r":
+			opts.Continue = true
+		case "--skip":
+			opts.Skip = true
+		case "--abort":
+			opts.Abort = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown option `%s'", arg)
+			}
+			opts.Files = append(opts.Files, arg)
+		}
+	}
+
+	resume := 0
+	for _, set := range []bool{opts.Continue, opts.Skip, opts.Abort} {
+		if set {
+			resume++
+		}
+	}
+	switch {
+	case resume > 1:
+		return nil, fmt.Errorf("error: --continue, --skip and --abort are mutually exclusive")
+	case resume == 1 && len(opts.Files) > 0:
+		return nil, fmt.Errorf("error: --continue, --skip and --abort take no mailbox arguments")
+	case resume == 0 && len(opts.Files) == 0:
+		return nil, fmt.Errorf("usage: git am [-3] <mbox>...\n   or: git am (--continue | --skip | --abort)")
+	}
+	return opts, nil
+}
+
+// applySeries applies and commits messages one by one. On a patch that does
+// not apply it stops, recording the rest of the series in the session.
+func (c *AmCommand) applySeries(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, messages []string, progress *git.AmState) (string, error) {
+	var out []string
+	for i, text := range messages {
+		patches, err := git.ParsePatches([]byte(text))
+		if err != nil {
+			return "", err
+		}
+		p := patches[0]
+		out = append(out, "Applying: "+p.Subject)
+		if len(p.Files) == 0 {
+			return "", c.stop(s, out, fmt.Errorf("Patch is empty."), p, messages[i:], progress)
+		}
+
+		results, err := applyFilePatches(repo, w, p.Files, progress.ThreeWay, p.Subject)
+		if err != nil {
+			return "", c.stop(s, out, err, p, messages[i:], progress)
+		}
+		conflicts, err := writePatchResults(w, results, true)
+		if err != nil {
+			return "", err
+		}
+		if len(conflicts) > 0 {
+			out = append(out, "Using index info to reconstruct a base tree...", "Falling back to patching base and 3-way merge...")
+			for _, path := range conflicts {
+				out = append(out, fmt.Sprintf("CONFLICT (content): Merge conflict in %s", path))
+			}
+			out = append(out, git.Rerere(s, repo, w, conflicts)...)
+			progress.Conflicts = conflicts
+			return "", c.stop(s, out, fmt.Errorf("error: Failed to merge in the changes."), p, messages[i:], progress)
+		}
+
+		if err := c.commitPatch(s, w, p); err != nil {
+			return "", err
+		}
+		progress.Applied++
+	}
+
+	s.ClearAm()
+	return strings.Join(out, "\n"), nil
+}
+
+// stop records the interrupted series and builds git's report for the patch that failed.
+func (c *AmCommand) stop(s *git.Session, out []string, cause error, p *git.MailPatch, remaining []string, progress *git.AmState) error {
+	progress.Patches = remaining
+	s.StartAm(progress)
+
+	out = append(out, cause.Error())
+	out = append(out, fmt.Sprintf("Patch failed at %04d %s", progress.Applied+1, p.Subject))
+	out = append(out, `When you have resolved this problem, run "git am --continue".`)
+	out = append(out, `If you prefer to skip this patch, run "git am --skip" instead.`)
+	out = append(out, `To restore the original branch and stop patching, run "git am --abort".`)
+	return fmt.Errorf("%s", strings.Join(out, "\n"))
+}
+
+// commitPatch commits the index with the author, date and message of p.
+func (c *AmCommand) commitPatch(s *git.Session, w *gogit.Worktree, p *git.MailPatch) error {
+	author := &object.Signature{Name: p.Author, Email: p.Email, When: p.Date}
+	if author.When.IsZero() {
+		author.When = time.Now()
+	}
+	committer := s.Signature()
+	committer.When = time.Now()
+	if _, err := w.Commit(p.Message(), &gogit.CommitOptions{Author: author, Committer: committer}); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+// continueAm commits the fixed-up stopped patch and applies the rest of the series.
+func (c *AmCommand) continueAm(s *git.Session, repo *gogit.Repository, w *gogit.Worktree) (string, error) {
+	progress := s.AmInProgress()
+	if progress == nil {
+		return "", fmt.Errorf("error: Resolve operation not in progress, we are not resuming.")
+	}
+	if unresolved, err := unresolvedPaths(w, progress.Conflicts); err != nil {
+		return "", err
+	} else if len(unresolved) > 0 {
+		return "", unmergedFilesError(unresolved, "fatal: am failed")
+	}
+
+	patches, err := git.ParsePatches([]byte(progress.Patches[0]))
+	if err != nil {
+		return "", err
+	}
+	p := patches[0]
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return "", err
+	}
+	staged := false
+	for _, fs := range status {
+		if fs.Staging != gogit.Unmodified && fs.Staging != gogit.Untracked {
+			staged = true
+		}
+	}
+	if !staged {
+		return "", fmt.Errorf("Applying: %s\nNo changes - did you forget to use 'git add'?\nIf there is nothing left to stage, chances are that something else\nalready introduced the same changes; you might want to skip this patch.", p.Subject)
+	}
+
+	if err := c.commitPatch(s, w, p); err != nil {
+		return "", err
+	}
+	progress.Applied++
+	progress.Conflicts = nil
+	applied := prependLines(git.RerereRecordResolutions(s, repo, w), "Applying: "+p.Subject)
+	out, err := c.applySeries(s, repo, w, progress.Patches[1:], progress)
+	if err != nil {
+		return "", fmt.Errorf("%s\n%v", applied, err)
+	}
+	return strings.TrimSpace(applied + "\n" + out), nil
+}
+
+// skipAm throws away what the stopped patch changed and applies the rest of the series.
+func (c *AmCommand) skipAm(s *git.Session, repo *gogit.Repository, w *gogit.Worktree) (string, error) {
+	progress := s.AmInProgress()
+	if progress == nil {
+		return "", fmt.Errorf("error: Resolve operation not in progress, we are not resuming.")
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: head.Hash(), Mode: gogit.HardReset}); err != nil {
+		return "", err
+	}
+	progress.Conflicts = nil
+	git.RerereClear(s)
+	return c.applySeries(s, repo, w, progress.Patches[1:], progress)
+}
+
+// abortAm returns the branch and worktree to where they were before the series.
+func (c *AmCommand) abortAm(s *git.Session, w *gogit.Worktree) (string, error) {
+	progress := s.AmInProgress()
+	if progress == nil {
+		return "", fmt.Errorf("error: Resolve operation not in progress, we are not resuming.")
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: plumbing.NewHash(progress.OrigHead), Mode: gogit.HardReset}); err != nil {
+		return "", fmt.Errorf("failed to abort am: %v", err)
+	}
+	s.ClearAm()
+	git.RerereClear(s)
+	return "", nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *AmCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-3", "--3way"}, Usage: "Fall back to a 3-way merge"},
+			{Flags: []string{"--continue"}, Usage: "Continue after fixing the failed patch"},
+			{Flags: []string{"--skip"}, Usage: "Skip the failed patch"},
+			{Flags: []string{"--abort"}, Usage: "Cancel and go back"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
+func (c *AmCommand) Help() string {
+	return git.CommandHelp(context.Background(), "am")
+}
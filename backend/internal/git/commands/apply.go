@@ -0,0 +1,283 @@
+package commands
+
+// apply.go - Simulated Git Apply Command
+//
+// Applies a patch file, a plain diff or the messages format-patch wrote, to
+// the worktree (and with --index to the index too). Like git, either every
+// file of the patch applies or nothing changes, unless --3way falls back to
+// merging with the blob the patch was made against, leaving conflict markers.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("apply", func() git.Command { return &ApplyCommand{} })
+}
+
+type ApplyCommand struct{}
+
+// Ensure ApplyCommand implements git.Command
+var _ git.Command = (*ApplyCommand)(nil)
+
+type ApplyOptions struct {
+	Check    bool // --check: only report whether the patch applies
+	Stat     bool // --stat: print a diffstat instead of applying
+	Index    bool // --index: update the index as well as the worktree
+	ThreeWay bool // -3/--3way: merge with the patch's preimage when it does not apply; implies --index
+	Files    []string
+}
+
+func (c *ApplyCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "apply"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	var files []*git.FilePatch
+	for _, name := range opts.Files {
+		data, err := readPatchFile(s, name)
+		if err != nil {
+			return "", err
+		}
+		patches, err := git.ParsePatches(data)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range patches {
+			files = append(files, p.Files...)
+		}
+	}
+
+	if opts.Stat {
+		return patchFileStat(files), nil
+	}
+
+	results, err := applyFilePatches(repo, w, files, opts.ThreeWay, "theirs")
+	if err != nil {
+		return "", err
+	}
+	if opts.Check {
+		return "", nil
+	}
+	conflicts, err := writePatchResults(w, results, opts.Index || opts.ThreeWay)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, r := range results {
+		if r.conflict {
+			sb.WriteString(fmt.Sprintf("Applied patch to '%s' with conflicts.\n", r.path))
+		} else {
+			sb.WriteString(fmt.Sprintf("Applied patch to '%s' cleanly.\n", r.path))
+		}
+	}
+	if len(conflicts) > 0 {
+		for _, path := range conflicts {
+			sb.WriteString(fmt.Sprintf("U %s\n", path))
+		}
+		return "", fmt.Errorf("%s", strings.TrimSuffix(sb.String(), "\n"))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func (c *ApplyCommand) parseArgs(args []string) (*ApplyOptions, error) {
+	opts := &ApplyOptions{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "--check":
+			opts.Check = true
+		case "--stat":
+			opts.Stat = true
+		case "--index":
+			opts.Index = true
+		case "-3", "--3way":
+			opts.ThreeWay = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown option `%s'", arg)
+			}
+			opts.Files = append(opts.Files, arg)
+		}
+	}
+	if len(opts.Files) == 0 {
+		return nil, fmt.Errorf("usage: git apply [--check] [--stat] [--index] [-3] <patch>...")
+	}
+	return opts, nil
+}
+
+// readPatchFile reads the patch file name of the session filesystem.
+func readPatchFile(s *git.Session, name string) ([]byte, error) {
+	data, err := util.ReadFile(s.Filesystem, sessionFilePath(s, name))
+	if err != nil {
+		return nil, fmt.Errorf("error: can't open patch '%s': No such file or directory", name)
+	}
+	return data, nil
+}
+
+// patchResult is what applying one file patch produces.
+type patchResult struct {
+	path     string
+	oldPath  string // Set when the patch renames oldPath to path
+	content  string
+	deleted  bool
+	conflict bool // content has conflict markers from a three-way merge
+}
+
+// applyFilePatches computes the result of applying files to the worktree
+// without changing it, failing if any file does not apply. With threeWay, a
+// file that does not apply is merged with the patch's preimage, labelling
+// the patch's side of a conflict with label.
+func applyFilePatches(repo *gogit.Repository, w *gogit.Worktree, files []*git.FilePatch, threeWay bool, label string) ([]patchResult, error) {
+	var results []patchResult
+	for _, fp := range files {
+		var current string
+		if fp.OldPath != "" {
+			data, err := util.ReadFile(w.Filesystem, fp.OldPath)
+			if err != nil {
+				return nil, fmt.Errorf("error: %s: No such file or directory", fp.OldPath)
+			}
+			current = string(data)
+		} else if _, err := w.Filesystem.Stat(fp.NewPath); err == nil {
+			return nil, fmt.Errorf("error: %s: already exists in working directory", fp.NewPath)
+		}
+
+		r := patchResult{path: fp.Path(), deleted: fp.NewPath == ""}
+		if fp.OldPath != "" && fp.NewPath != "" && fp.OldPath != fp.NewPath {
+			r.oldPath = fp.OldPath
+		}
+		content, err := git.ApplyFilePatch(current, fp)
+		if err != nil {
+			if !threeWay {
+				return nil, fmt.Errorf("%v\nerror: %s: patch does not apply", err, fp.Path())
+			}
+			preimage, ok := git.PatchPreimage(repo, fp)
+			if !ok {
+				return nil, fmt.Errorf("%v\nerror: repository lacks the necessary blob to perform 3-way merge.\nerror: %s: patch does not apply", err, fp.Path())
+			}
+			postimage, err := git.ApplyFilePatch(preimage, fp)
+			if err != nil {
+				return nil, fmt.Errorf("%v\nerror: %s: patch does not apply", err, fp.Path())
+			}
+			content = postimage
+			if current != postimage {
+				content = git.ConflictMarkers(current, postimage, label)
+				r.conflict, r.deleted = true, false
+			}
+		}
+		r.content = content
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// writePatchResults writes results to the worktree, staging them when
+// index is set. Conflicted files are left unstaged; their paths are returned.
+func writePatchResults(w *gogit.Worktree, results []patchResult, index bool) ([]string, error) {
+	var conflicts []string
+	for _, r := range results {
+		if r.oldPath != "" {
+			if err := w.Filesystem.Remove(r.oldPath); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			if index {
+				_, _ = w.Remove(r.oldPath)
+			}
+		}
+		if r.deleted {
+			if err := w.Filesystem.Remove(r.path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			if index {
+				_, _ = w.Remove(r.path)
+			}
+			continue
+		}
+		if err := util.WriteFile(w.Filesystem, r.path, []byte(r.content), 0644); err != nil {
+			return nil, fmt.Errorf("error: unable to write file '%s': %v", r.path, err)
+		}
+		if r.conflict {
+			conflicts = append(conflicts, r.path)
+			continue
+		}
+		if index {
+			if _, err := w.Add(r.path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// patchFileStat is the diffstat of the file patches, as git apply --stat prints it.
+func patchFileStat(files []*git.FilePatch) string {
+	width := 0
+	for _, fp := range files {
+		width = max(width, len(fp.Path()))
+	}
+	var sb strings.Builder
+	added, deleted := 0, 0
+	for _, fp := range files {
+		a, d := 0, 0
+		for _, h := range fp.Hunks {
+			for _, line := range h.Lines {
+				switch line[0] {
+				case '+':
+					a++
+				case '-':
+					d++
+				}
+			}
+		}
+		sb.WriteString(fmt.Sprintf(" %-*s | %d %s%s\n", width, fp.Path(), a+d, strings.Repeat("+", min(a, 40)), strings.Repeat("-", min(d, 40))))
+		added += a
+		deleted += d
+	}
+	sb.WriteString(fmt.Sprintf(" %s changed, %s(+), %s(-)", plural(len(files), "1 file", fmt.Sprintf("%d files", len(files))),
+		plural(added, "1 insertion", fmt.Sprintf("%d insertions", added)), plural(deleted, "1 deletion", fmt.Sprintf("%d deletions", deleted))))
+	return sb.String()
+}
+
+// Spec implements git.SpecProvider.
+func (c *ApplyCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--check"}, Usage: "Only check that the patch applies"},
+			{Flags: []string{"--stat"}, Usage: "Show a diffstat instead of applying"},
+			{Flags: []string{"--index"}, Usage: "Update the index too"},
+			{Flags: []string{"-3", "--3way"}, Usage: "Fall back to a 3-way merge"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
+func (c *ApplyCommand) Help() string {
+	return git.CommandHelp(context.Background(), "apply")
+}
@@ -0,0 +1,158 @@
+package commands
+
+// archive.go - Simulated Git Archive Command
+//
+// Packs the files of a commit, without history, into a tar, tar.gz or zip
+// file in the working directory, e.g. to hand out a release. The sandbox has
+// no stdout to stream binary data to, so -o is required.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("archive", func() git.Command { return &ArchiveCommand{} })
+}
+
+type ArchiveCommand struct{}
+
+// Ensure ArchiveCommand implements git.Command
+var _ git.Command = (*ArchiveCommand)(nil)
+
+type ArchiveCommandOptions struct {
+	Format  string
+	Prefix  string
+	Output  string
+	List    bool
+	TreeIsh string
+	Paths   []string
+}
+
+func (c *ArchiveCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "archive"), nil
+		}
+		return "", err
+	}
+	if opts.List {
+		return "tar\ntgz\ntar.gz\nzip", nil
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	if opts.TreeIsh == "" {
+		return "", fmt.Errorf("usage: git archive [--format=<fmt>] [--prefix=<prefix>/] -o <file> <tree-ish> [<path>...]")
+	}
+	if opts.Output == "" {
+		return "", fmt.Errorf("fatal: refusing to write archive data to the terminal\nhint: Use -o <file> to write it to the working directory, e.g. 'git archive -o release.zip %s'.", opts.TreeIsh)
+	}
+	format := opts.Format
+	if format == "" {
+		format = git.ArchiveFormatFor(opts.Output)
+	}
+	if format == "" {
+		format = git.ArchiveTar
+	}
+
+	hash, err := git.ResolveObject(repo, opts.TreeIsh)
+	if err != nil {
+		return "", fmt.Errorf("fatal: not a valid object name: %s", opts.TreeIsh)
+	}
+	var buf bytes.Buffer
+	if err := git.WriteArchive(repo, hash, git.ArchiveOptions{Format: format, Prefix: opts.Prefix, Paths: opts.Paths}, &buf); err != nil {
+		return "", err
+	}
+
+	target := sessionFilePath(s, opts.Output)
+	if _, err := s.Filesystem.Stat(target); err != nil {
+		if err := s.CheckStorageQuota(0, 0, 1); err != nil {
+			return "", err
+		}
+	}
+	if err := s.Filesystem.MkdirAll(path.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	if err := util.WriteFile(s.Filesystem, target, buf.Bytes(), os.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("fatal: could not create archive file '%s': %v", opts.Output, err)
+	}
+	return fmt.Sprintf("Wrote %s archive of %s to %s (%d bytes)", format, opts.TreeIsh, opts.Output, buf.Len()), nil
+}
+
+func (c *ArchiveCommand) parseArgs(args []string) (*ArchiveCommandOptions, error) {
+	opts := &ArchiveCommandOptions{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "-l" || arg == "--list":
+			opts.List = true
+		case arg == "-o" || arg == "--output" || arg == "--format" || arg == "--prefix":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("error: option `%s' requires a value", strings.TrimLeft(arg, "-"))
+			}
+			i++
+			switch arg {
+			case "--format":
+				opts.Format = args[i]
+			case "--prefix":
+				opts.Prefix = args[i]
+			default:
+				opts.Output = args[i]
+			}
+		case strings.HasPrefix(arg, "--format="):
+			opts.Format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--prefix="):
+			opts.Prefix = strings.TrimPrefix(arg, "--prefix=")
+		case strings.HasPrefix(arg, "--output="):
+			opts.Output = strings.TrimPrefix(arg, "--output=")
+		case arg == "--":
+			opts.Paths = append(opts.Paths, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("error: unknown option `%s'", arg)
+		case opts.TreeIsh == "":
+			opts.TreeIsh = arg
+		default:
+			opts.Paths = append(opts.Paths, arg)
+		}
+	}
+	switch opts.Format {
+	case "", git.ArchiveTar, git.ArchiveTarGz, "tgz", git.ArchiveZip:
+	default:
+		return nil, fmt.Errorf("fatal: Unknown archive format '%s'", opts.Format)
+	}
+	return opts, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *ArchiveCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--format"}, Arg: git.ArgText, Usage: "tar or zip"},
+			{Flags: []string{"-o", "--output"}, Arg: git.ArgPath, Usage: "Write the archive to a file"},
+			{Flags: []string{"--prefix"}, Arg: git.ArgText, Usage: "Prepend a directory to every path"},
+			{Flags: []string{"-l", "--list"}, Usage: "List the formats"},
+		},
+		Args: []string{git.ArgRef, git.ArgPath},
+	}
+}
+
+func (c *ArchiveCommand) Help() string {
+	return git.CommandHelp(context.Background(), "archive")
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestArchiveCommand_WritesZipToWorkingDirectory(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-archive")
+	ctx := context.Background()
+	cmd := &ArchiveCommand{}
+
+	if _, err := cmd.Execute(ctx, s, []string{"archive", "HEAD"}); err == nil || !strings.Contains(err.Error(), "-o <file>") {
+		t.Fatalf("Expected archiving to the terminal to be refused, got %v", err)
+	}
+
+	out, err := cmd.Execute(ctx, s, []string{"archive", "--prefix=release/", "-o", "release.zip", "main"})
+	if err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+	if !strings.Contains(out, "Wrote zip archive of main to release.zip") {
+		t.Errorf("Unexpected output: %s", out)
+	}
+
+	data, err := util.ReadFile(s.Filesystem, "/testrepo/release.zip")
+	if err != nil {
+		t.Fatalf("Expected release.zip in the working directory: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "release/file.txt" {
+		t.Errorf("Expected only release/file.txt, got %v", zr.File)
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"archive", "--format=rar", "-o", "x.rar", "HEAD"}); err == nil || !strings.Contains(err.Error(), "Unknown archive format 'rar'") {
+		t.Errorf("Expected an unknown format error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAttributesTestSession(t *testing.T) (*git.Session, *gogit.Repository) {
+	t.Helper()
+	fs := memfs.New()
+	r, err := gogit.Init(memory.NewStorage(), fs)
+	require.NoError(t, err)
+	return &git.Session{
+		ID:         "test-attributes",
+		Filesystem: fs,
+		Repos:      map[string]*gogit.Repository{"repo": r},
+		CurrentDir: "/repo",
+	}, r
+}
+
+func TestAttributes_LineEndings(t *testing.T) {
+	s, repo := newAttributesTestSession(t)
+	ctx := context.Background()
+	w, _ := repo.Worktree()
+
+	require.NoError(t, util.WriteFile(w.Filesystem, ".gitattributes", []byte("*.txt text\n*.bat text eol=crlf\n*.bin binary\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "notes.txt", []byte("one\r\ntwo\r\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "build.bat", []byte("echo hi\r\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "data.bin", []byte("raw\r\n"), 0644))
+
+	out, err := (&AddCommand{}).Execute(ctx, s, []string{"add", "."})
+	require.NoError(t, err)
+	assert.Contains(t, out, "in the working copy of 'notes.txt', CRLF will be replaced by LF")
+	assert.NotContains(t, out, "build.bat", "eol=crlf files get their CRLFs back on checkout")
+	_, err = (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Add files"})
+	require.NoError(t, err)
+
+	// The index has LF; the CRLF worktree copies still count as unmodified
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	for name, want := range map[string]string{"notes.txt": "one\ntwo\n", "build.bat": "echo hi\n", "data.bin": "raw\r\n"} {
+		file, err := commit.File(name)
+		require.NoError(t, err)
+		got, _ := file.Contents()
+		assert.Equal(t, want, got, name)
+	}
+	status, err := git.LFSAwareStatus(repo, w)
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), "status: %v", status)
+
+	// A checkout writes LF; eol=crlf files are smudged back to CRLF
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master, Force: true}))
+	git.SmudgeLineEndings(repo)
+	bat, _ := util.ReadFile(w.Filesystem, "build.bat")
+	assert.Equal(t, "echo hi\r\n", string(bat))
+	notes, _ := util.ReadFile(w.Filesystem, "notes.txt")
+	assert.Equal(t, "one\ntwo\n", string(notes))
+}
+
+func TestAttributes_MergeDrivers(t *testing.T) {
+	s, repo := newAttributesTestSession(t)
+	w, _ := repo.Worktree()
+	commit := func(files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+		_, err := w.Commit("change", &gogit.CommitOptions{Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}})
+		require.NoError(t, err)
+	}
+
+	commit(map[string]string{
+		".gitattributes": "CHANGELOG merge=union\nversion.txt merge=ours\n",
+		"CHANGELOG":      "v1\n",
+		"version.txt":    "1.0\n",
+		"app.txt":        "app\n",
+	})
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	commit(map[string]string{"CHANGELOG": "v1\nfeature\n", "version.txt": "2.0-feature\n"})
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master}))
+	commit(map[string]string{"CHANGELOG": "v1\nfix\n", "version.txt": "1.1\n"})
+
+	_, err := (&MergeCommand{}).Execute(context.Background(), s, []string{"merge", "feature", "-m", "Merge feature"})
+	require.NoError(t, err)
+	changelog, _ := util.ReadFile(w.Filesystem, "CHANGELOG")
+	assert.Equal(t, "v1\nfix\nfeature\n", string(changelog))
+	version, _ := util.ReadFile(w.Filesystem, "version.txt")
+	assert.Equal(t, "1.1\n", string(version))
+}
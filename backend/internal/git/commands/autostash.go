@@ -0,0 +1,125 @@
+package commands
+
+// autostash.go - --autostash for pull and rebase
+//
+// An operation that rewrites the worktree (a rebase, a pull) started with
+// --autostash, or rebase.autostash set, stashes the local changes first and
+// applies them again once it is done. When they no longer apply cleanly the
+// entry is kept in the stash list, as git does. A rebase that stops on a
+// conflict keeps its autostash until it finishes or is aborted.
+
+import (
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// autostashMessage is the stash message of an autostash entry.
+const autostashMessage = "autostash"
+
+// autostashConfigured reports whether <section>.autostash (rebase.autostash,
+// merge.autostash) is set.
+func autostashConfigured(repo *gogit.Repository, section string) bool {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Raw.Section(section).Option("autostash"), "true")
+}
+
+// hasLocalChanges reports whether a tracked file or the index differs from HEAD.
+func hasLocalChanges(repo *gogit.Repository) (bool, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return false, err
+	}
+	return hasTrackedChanges(status), nil
+}
+
+// createAutostash stashes the local changes and returns the stash entry,
+// or a zero hash when there was nothing to stash.
+func createAutostash(repo *gogit.Repository) (plumbing.Hash, error) {
+	dirty, err := hasLocalChanges(repo)
+	if err != nil || !dirty {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := (&StashCommand{}).executePush(repo, &StashOptions{Message: autostashMessage}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := repo.Reference(plumbing.ReferenceName(StashRefName), true)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+// applyAutostash applies the autostash entry again and drops it, returning
+// git's report. A conflict keeps the entry.
+func applyAutostash(repo *gogit.Repository, stash plumbing.Hash) string {
+	if stash.IsZero() {
+		return ""
+	}
+	entries, err := stashEntries(repo)
+	if err != nil {
+		return fmt.Sprintf("error: could not read the stash: %v", err)
+	}
+	n := -1
+	for i, entry := range entries {
+		if sameStash(repo, entry, stash) {
+			n = i
+			break
+		}
+	}
+	if n < 0 {
+		return fmt.Sprintf("error: the autostash %s is no longer in the stash list", stash.String()[:7])
+	}
+
+	stashCmd := &StashCommand{}
+	if _, err := stashCmd.executeApply(repo, &StashOptions{Entry: n}); err != nil {
+		return "Applying autostash resulted in conflicts.\nYour changes are safe in the stash.\nYou can run \"git stash pop\" or \"git stash drop\" at any time."
+	}
+	_, _ = stashCmd.executeDrop(repo, &StashOptions{Entry: n})
+	return "Applied autostash."
+}
+
+// sameStash reports whether entry is the stash commit hash. Dropping an
+// older entry rewrites the newer ones, so an entry also matches when only
+// its link to the previous entry changed.
+func sameStash(repo *gogit.Repository, entry *object.Commit, hash plumbing.Hash) bool {
+	if entry.Hash == hash {
+		return true
+	}
+	original, err := repo.CommitObject(hash)
+	if err != nil || original.TreeHash != entry.TreeHash || original.Message != entry.Message {
+		return false
+	}
+	if original.NumParents() < 2 || entry.NumParents() < 2 {
+		return false
+	}
+	return original.ParentHashes[0] == entry.ParentHashes[0] && original.ParentHashes[1] == entry.ParentHashes[1]
+}
+
+// autostashLine formats the report of a created autostash.
+func autostashLine(stash plumbing.Hash) string {
+	return fmt.Sprintf("Created autostash: %s", stash.String()[:7])
+}
+
+// afterAutostash applies the autostash again and appends the report to out.
+func afterAutostash(repo *gogit.Repository, stash plumbing.Hash, out string) string {
+	line := applyAutostash(repo, stash)
+	switch {
+	case line == "":
+		return out
+	case out == "":
+		return line
+	}
+	return out + "\n" + line
+}
@@ -3,9 +3,10 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
-	gogit "github.com/go-git/go-git/v5"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -18,118 +19,151 @@ type BlameCommand struct{}
 // Ensure BlameCommand implements git.Command
 var _ git.Command = (*BlameCommand)(nil)
 
+type BlameOptions struct {
+	Revision string
+	Path     string
+	Start    int // First line of -L, 0 when open
+	End      int // Last line of -L, 0 when open
+}
+
 func (c *BlameCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.RLock()
 	defer s.RUnlock()
 
-	if len(args) < 2 {
-		return "", fmt.Errorf("usage: git blame <file>")
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "blame"), nil
+		}
+		return "", err
 	}
-	filePath := args[1]
-
-	// Normalize path
-	// If path starts with /, treat it relative to repo root (which is CurrentDir if at root)
-	// Complicated relative path logic might be needed if cwd is not root.
-	// For now, assume simple relative path handling relative to repo root or use s.GetRepo() logic.
 
 	repo := s.GetRepo()
 	if repo == nil {
 		return "", fmt.Errorf("not a git repository (or any of the parent directories)")
 	}
 
-	headRef, err := repo.Head()
+	result, err := git.BlameFile(repo, opts.Revision, opts.Path, opts.Start, opts.End)
 	if err != nil {
-		return "", fmt.Errorf("HEAD not found: %v", err)
+		return "", err
 	}
 
-	commit, err := repo.CommitObject(headRef.Hash())
-	if err != nil {
-		return "", fmt.Errorf("invalid HEAD commit: %v", err)
+	var sb strings.Builder
+	for _, line := range result.Lines {
+		date, _ := time.Parse(time.RFC3339, line.Date)
+		sb.WriteString(fmt.Sprintf("%s (%-20s %s %4d) %s\n",
+			line.Commit[:8],
+			truncateString(line.Email, 20),
+			date.Format("2006-01-02 15:04:05"),
+			line.Line,
+			line.Text))
 	}
+	return sb.String(), nil
+}
 
-	// go-git's Blame takes a path relative to the worktree root.
-	// We need to resolve filePath relative to the repo root.
-	// For simplicity in this simulation, assuming cwd is repo root or fairly flat structure.
-	// If s.CurrentDir is "/" (root of memfs), and repo is there.
-	// We just strip leading slash if present.
-	cleanPath := strings.TrimPrefix(filePath, "/")
-
-	// Check/Read file first to ensure it exists and we have content
-	// We read content ourselves to ensure we can display lines even if BlameResult.Text is weird
-	tree, err := commit.Tree()
-	if err != nil {
-		return "", err
-	}
-	file, err := tree.File(cleanPath)
-	if err != nil {
-		return "", fmt.Errorf("no such path '%s' in HEAD", cleanPath)
+func (c *BlameCommand) parseArgs(args []string) (*BlameOptions, error) {
+	opts := &BlameOptions{}
+	var positional []string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case arg == "-L":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("error: switch `L' requires a value")
+			}
+			i++
+			if err := parseLineRange(args[i], opts); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-L"):
+			if err := parseLineRange(strings.TrimPrefix(arg, "-L"), opts); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("error: unknown option '%s'", arg)
+		default:
+			positional = append(positional, arg)
+		}
 	}
 
-	// Calculate Blame
-	blameResult, err := gogit.Blame(commit, cleanPath)
-	if err != nil {
-		return "", fmt.Errorf("blame failed: %v", err)
+	switch len(positional) {
+	case 1:
+		opts.Path = positional[0]
+	case 2:
+		opts.Revision, opts.Path = positional[0], positional[1]
+	default:
+		return nil, fmt.Errorf("usage: git blame [-L <start>,<end>] [<rev>] [--] <file>")
 	}
+	return opts, nil
+}
 
-	// Read file content lines to ensure 1:1 mapping if needed
-	// But let's try using `blameResult.Lines[i].Text` first.
-	// If `Text` is empty, we fall back? No, let's use the explicit file content split.
-	content, err := file.Contents()
-	if err != nil {
-		return "", err
-	}
-	fileLines := strings.Split(content, "\n")
-	// Removing trailing empty string from split if file ends with newline?
-	// strings.Split("abc\n", "\n") -> ["abc", ""]
-	if len(fileLines) > 0 && fileLines[len(fileLines)-1] == "" {
-		fileLines = fileLines[:len(fileLines)-1]
+// parseLineRange reads an -L value: "<start>,<end>", "<start>,+<count>",
+// "<start>,-<count>", "<start>," or ",<end>".
+func parseLineRange(value string, opts *BlameOptions) error {
+	invalid := fmt.Errorf("fatal: invalid -L argument '%s'", value)
+
+	startStr, endStr, hasComma := strings.Cut(value, ",")
+	start, end := 0, 0
+	if startStr != "" {
+		n, err := strconv.Atoi(startStr)
+		if err != nil || n < 1 {
+			return invalid
+		}
+		start = n
 	}
-
-	var sb strings.Builder
-	for i, line := range blameResult.Lines {
-		if i >= len(fileLines) {
-			break
+	if !hasComma {
+		end = start
+	} else if endStr != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(endStr, "+"))
+		if err != nil {
+			return invalid
 		}
-
-		hashStr := line.Hash.String()
-		if len(hashStr) > 8 {
-			hashStr = hashStr[:8]
+		switch {
+		case strings.HasPrefix(endStr, "+"):
+			if start == 0 || n < 1 {
+				return invalid
+			}
+			end = start + n - 1
+		case n < 0:
+			if start == 0 {
+				return invalid
+			}
+			// "<start>,-<count>" counts backwards from start
+			end, start = start, start+n+1
+			if start < 1 {
+				start = 1
+			}
+		case n < 1:
+			return invalid
+		default:
+			end = n
 		}
+	}
+	if start == 0 && end == 0 {
+		return invalid
+	}
 
-		author := line.Author
-		dateStr := line.Date.Format("2006-01-02 15:04:05")
-
-		// Use actual file content for the text
-		lineText := fileLines[i]
+	opts.Start, opts.End = start, end
+	return nil
+}
 
-		sb.WriteString(fmt.Sprintf("%s (%-20s %s %4d) %s\n",
-			hashStr,
-			truncateString(author, 20),
-			dateStr,
-			i+1,
-			lineText))
+// Spec implements git.SpecProvider.
+func (c *BlameCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-L"}, Arg: git.ArgText, Usage: "Annotate only the given line range"},
+		},
+		Args: []string{git.ArgPath},
 	}
-
-	return sb.String(), nil
 }
 
 func (c *BlameCommand) Help() string {
-	return `📘 BLAME (1)                                          Git Manual
-
- 💡 DESCRIPTION
-    ファイルの各行が「誰によって」「いつ」「どのコミットで」変更されたかを表示します。
-    バグの原因調査や、コードの意図を確認する際に非常に便利です。
-
- 📋 SYNOPSIS
-    git blame <file>
-
- 🛠  EXAMPLES
-    1. README.md の履歴を見る
-       $ git blame README.md
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-blame
-`
+	return git.CommandHelp(context.Background(), "blame")
 }
 
 func truncateString(s string, l int) string {
@@ -61,3 +61,52 @@ func TestBlameBasic(t *testing.T) {
 	assert.Contains(t, lines[1], "mod@example.com")
 	assert.Contains(t, lines[1], "line2 modified")
 }
+
+func TestBlameLineRange(t *testing.T) {
+	fs := memfs.New()
+	r, _ := gogit.Init(memory.NewStorage(), fs)
+	w, _ := r.Worktree()
+	author := &object.Signature{Name: "Tester", Email: "test@example.com", When: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	f, _ := fs.Create("file.txt")
+	f.Write([]byte("a\nb\nc\nd\ne\n"))
+	f.Close()
+	w.Add("file.txt")
+	w.Commit("Initial", &gogit.CommitOptions{Author: author})
+
+	session := &git.Session{
+		ID:         "test-session",
+		Filesystem: fs,
+		Repos:      map[string]*gogit.Repository{"repo": r},
+		CurrentDir: "/repo",
+	}
+	cmd := &BlameCommand{}
+
+	tests := []struct {
+		args  []string
+		lines []string
+	}{
+		{[]string{"blame", "-L", "2,3", "file.txt"}, []string{"2) b", "3) c"}},
+		{[]string{"blame", "-L2,+2", "file.txt"}, []string{"2) b", "3) c"}},
+		{[]string{"blame", "-L", "4,", "file.txt"}, []string{"4) d", "5) e"}},
+		{[]string{"blame", "-L", ",1", "HEAD", "--", "file.txt"}, []string{"1) a"}},
+		{[]string{"blame", "-L", "3,-2", "file.txt"}, []string{"2) b", "3) c"}},
+	}
+	for _, tt := range tests {
+		output, err := cmd.Execute(context.Background(), session, tt.args)
+		assert.NoError(t, err, tt.args)
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if assert.Len(t, lines, len(tt.lines), tt.args) {
+			for i, want := range tt.lines {
+				assert.True(t, strings.HasSuffix(lines[i], want), "%v: %q", tt.args, lines[i])
+			}
+		}
+	}
+
+	_, err := cmd.Execute(context.Background(), session, []string{"blame", "-L", "9,10", "file.txt"})
+	assert.ErrorContains(t, err, "has only 5 lines")
+	_, err = cmd.Execute(context.Background(), session, []string{"blame", "-L", "x", "file.txt"})
+	assert.ErrorContains(t, err, "invalid -L argument")
+	_, err = cmd.Execute(context.Background(), session, []string{"blame", "missing.txt"})
+	assert.ErrorContains(t, err, "no such path 'missing.txt'")
+}
@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
@@ -19,6 +21,12 @@ type BranchCommand struct{}
 // Ensure BranchCommand implements git.Command
 var _ git.Command = (*BranchCommand)(nil)
 
+// Ensure BranchCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*BranchCommand)(nil)
+
+// SupportsDryRun marks branch as simulated by the engine on --dry-run.
+func (c *BranchCommand) SupportsDryRun() {}
+
 type BranchOptions struct {
 	Delete      bool
 	DeleteForce bool
@@ -29,6 +37,14 @@ type BranchOptions struct {
 	Remote      bool
 	All         bool
 	Force       bool
+	List        bool   // --list: treat positional argument as a glob pattern
+	Pattern     string // Glob pattern for listing, e.g. "feature/*"
+	Limit       int    // --limit: maximum number of branches to list
+	After       string // --after: continue listing after this branch name
+	Verbose     int    // -v shows each tip, -vv also the upstream and ahead/behind counts
+	UpstreamTo  string // --set-upstream-to / -u: remote-tracking branch to track
+	SetUpstream bool
+	Unset       bool // --unset-upstream
 }
 
 func (c *BranchCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -39,7 +55,7 @@ func (c *BranchCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "branch"), nil
 		}
 		return "", err
 	}
@@ -50,20 +66,28 @@ func (c *BranchCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	}
 
 	// 2. Dispatch
+	// TRACKING
+	if opts.SetUpstream || opts.Unset {
+		return c.configureUpstream(repo, opts)
+	}
+
 	// LIST
 	if !opts.Delete && !opts.DeleteForce && !opts.Move {
-		if opts.BranchName == "" {
-			return c.listBranches(repo, opts.Remote, opts.All)
+		if opts.BranchName == "" || opts.List {
+			return c.listBranches(repo, opts)
 		}
 		// Special case: "git branch -r" or "git branch -a" without name is list
 		if opts.Remote && !opts.Move && !opts.Delete { // "git branch -r"
-			return c.listBranches(repo, opts.Remote, opts.All)
+			return c.listBranches(repo, opts)
 		}
 		if opts.All && !opts.Move && !opts.Delete { // "git branch -a"
-			return c.listBranches(repo, opts.Remote, opts.All)
+			return c.listBranches(repo, opts)
 		}
 
 		// If name provided but not Delete/Move, it's CREATE
+		if err := git.CheckBranchPolicy(s, opts.BranchName); err != nil {
+			return "", err
+		}
 		return c.createBranch(repo, opts)
 	}
 
@@ -72,7 +96,7 @@ func (c *BranchCommand) Execute(ctx context.Context, s *git.Session, args []stri
 		if opts.BranchName == "" {
 			return "", fmt.Errorf("branch name required")
 		}
-		return c.deleteBranch(repo, opts)
+		return c.deleteBranch(s, repo, opts)
 	}
 
 	// MOVE
@@ -81,6 +105,9 @@ func (c *BranchCommand) Execute(ctx context.Context, s *git.Session, args []stri
 		// So we don't enforce opts.BranchName != "" here if we support current branch rename.
 		// However, parseArgs sets BranchName="" for implicit case.
 		// So we should allow it.
+		if err := git.CheckBranchPolicy(s, opts.NewName); err != nil {
+			return "", err
+		}
 		return c.moveBranch(repo, opts)
 	}
 
@@ -91,34 +118,62 @@ func (c *BranchCommand) parseArgs(args []string) (*BranchOptions, error) {
 	opts := &BranchOptions{
 		StartPoint: "HEAD",
 	}
-	cmdArgs := args[1:]
-
-	// Collect arguments to determine Name and StartPoint/NewName
-	var cleanArgs []string
-
-	for _, arg := range cmdArgs {
-		switch arg {
-		case "--help", "-h":
-			return nil, fmt.Errorf("help requested")
-		case "-d", "--delete":
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range parsed.Flags {
+		switch f.Name {
+		case "-l":
+			opts.List = true
+		case "--limit":
+			n, err := strconv.Atoi(f.Value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("fatal: invalid --limit value: %s", f.Value)
+			}
+			opts.Limit = n
+			opts.List = true
+		case "--after":
+			opts.After = f.Value
+			opts.List = true
+		case "-d":
 			opts.Delete = true
 		case "-D":
 			opts.DeleteForce = true // Implies Force for deletion logic
-		case "-m", "--move":
+		case "-m":
 			opts.Move = true
-		case "-f", "--force":
+		case "-f":
 			opts.Force = true
-		case "-r", "--remotes":
+		case "-r":
 			opts.Remote = true
-		case "-a", "--all":
+		case "-a":
 			opts.All = true
-		default:
-			if strings.HasPrefix(arg, "-") {
-				return nil, fmt.Errorf("unknown option: %s", arg)
-			}
-			cleanArgs = append(cleanArgs, arg)
+		case "-v":
+			opts.Verbose++
+		case "-u":
+			opts.UpstreamTo = f.Value
+			opts.SetUpstream = true
+		case "--unset-upstream":
+			opts.Unset = true
 		}
 	}
+	// Collect arguments to determine Name and StartPoint/NewName
+	cleanArgs := append(parsed.Positional, parsed.Rest...)
+
+	if opts.SetUpstream || opts.Unset {
+		if len(cleanArgs) > 0 {
+			opts.BranchName = cleanArgs[0]
+		}
+		return opts, nil
+	}
+
+	if opts.List || (opts.Verbose > 0 && len(cleanArgs) == 0) {
+		opts.List = true
+		if len(cleanArgs) > 0 {
+			opts.Pattern = cleanArgs[0]
+		}
+		return opts, nil
+	}
 
 	if len(cleanArgs) > 0 {
 		opts.BranchName = cleanArgs[0]
@@ -147,7 +202,9 @@ func (c *BranchCommand) parseArgs(args []string) (*BranchOptions, error) {
 	return opts, nil
 }
 
-func (c *BranchCommand) listBranches(repo *gogit.Repository, remote, all bool) (string, error) {
+func (c *BranchCommand) listBranches(repo *gogit.Repository, opts *BranchOptions) (string, error) {
+	remote, all := opts.Remote, opts.All
+
 	// Collect branches
 	var branches []string
 
@@ -188,7 +245,132 @@ func (c *BranchCommand) listBranches(repo *gogit.Repository, remote, all bool) (
 		}
 	}
 
-	return strings.Join(branches, "\n"), nil
+	if opts.Verbose > 0 {
+		return c.formatVerboseListing(repo, branches, opts)
+	}
+	return formatRefListing(branches, opts.Pattern, opts.After, opts.Limit, "branches")
+}
+
+// formatVerboseListing renders branch -v: the current branch marker, each tip
+// and its subject, plus the upstream and ahead/behind counts with -vv.
+func (c *BranchCommand) formatVerboseListing(repo *gogit.Repository, branches []string, opts *BranchOptions) (string, error) {
+	listing, err := formatRefListing(branches, opts.Pattern, opts.After, opts.Limit, "branches")
+	if err != nil || listing == "" {
+		return listing, err
+	}
+	lines := strings.Split(listing, "\n")
+
+	current := ""
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		current = head.Name().Short()
+	}
+	width := 0
+	for _, name := range lines {
+		if !strings.HasPrefix(name, "... ") {
+			width = max(width, len(name))
+		}
+	}
+
+	var sb strings.Builder
+	for _, name := range lines {
+		if strings.HasPrefix(name, "... ") {
+			sb.WriteString(name + "\n")
+			continue
+		}
+		refName := plumbing.NewBranchReferenceName(name)
+		local := true
+		ref, err := repo.Reference(refName, true)
+		if err != nil {
+			local = false
+			if ref, err = repo.Reference(plumbing.ReferenceName("refs/remotes/"+name), true); err != nil {
+				continue
+			}
+		}
+		marker := "  "
+		if local && name == current {
+			marker = "* "
+		}
+		line := fmt.Sprintf("%s%-*s %s", marker, width, name, ref.Hash().String()[:7])
+		if local && opts.Verbose > 1 {
+			if tracking := git.TrackingSummary(repo, name, ref.Hash()); tracking != "" {
+				line += " " + tracking
+			}
+		}
+		if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+			line += " " + strings.SplitN(commit.Message, "\n", 2)[0]
+		}
+		sb.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// configureUpstream handles --set-upstream-to and --unset-upstream for the
+// named branch, or the current one.
+func (c *BranchCommand) configureUpstream(repo *gogit.Repository, opts *BranchOptions) (string, error) {
+	branch := opts.BranchName
+	if branch == "" {
+		head, err := repo.Head()
+		if err != nil || !head.Name().IsBranch() {
+			return "", fmt.Errorf("fatal: could not set upstream of HEAD when it does not point to any branch")
+		}
+		branch = head.Name().Short()
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true); err != nil {
+		return "", fmt.Errorf("fatal: branch '%s' does not exist", branch)
+	}
+
+	if opts.Unset {
+		return "", git.UnsetUpstream(repo, branch)
+	}
+
+	// origin/feature: the longest configured remote name that prefixes the upstream wins
+	upstream := strings.TrimPrefix(opts.UpstreamTo, "refs/remotes/")
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	remote := ""
+	for name := range cfg.Remotes {
+		if strings.HasPrefix(upstream, name+"/") && len(name) > len(remote) {
+			remote = name
+		}
+	}
+	if remote == "" {
+		return "", fmt.Errorf("fatal: the requested upstream branch '%s' does not exist", opts.UpstreamTo)
+	}
+	merge := strings.TrimPrefix(upstream, remote+"/")
+	if _, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, merge), true); err != nil {
+		return "", fmt.Errorf("fatal: the requested upstream branch '%s' does not exist\nhint: If you are planning on basing your work on an upstream\nhint: branch that already exists at the remote, you may need to\nhint: run \"git fetch\" to retrieve it.", opts.UpstreamTo)
+	}
+	if err := git.SetUpstream(repo, branch, remote, merge); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("branch '%s' set up to track '%s/%s'.", branch, remote, merge), nil
+}
+
+// formatRefListing filters names by glob pattern, pages them and renders one name per line.
+// When the page is truncated, a trailer explains how to request the next one.
+func formatRefListing(names []string, pattern, after string, limit int, kind string) (string, error) {
+	if pattern != "" {
+		var matched []string
+		for _, n := range names {
+			ok, err := path.Match(pattern, n)
+			if err != nil {
+				return "", fmt.Errorf("fatal: invalid pattern '%s'", pattern)
+			}
+			if ok {
+				matched = append(matched, n)
+			}
+		}
+		names = matched
+	}
+
+	page, next := git.PaginateRefNames(names, git.RefFilter{After: after, Limit: limit})
+	out := strings.Join(page, "\n")
+	if next != "" {
+		out += fmt.Sprintf("\n... more %s available (continue with --after %s)", kind, next)
+	}
+	return out, nil
 }
 
 func (c *BranchCommand) createBranch(repo *gogit.Repository, opts *BranchOptions) (string, error) {
@@ -230,7 +412,7 @@ func (c *BranchCommand) createBranch(repo *gogit.Repository, opts *BranchOptions
 	return "Created branch " + name, nil
 }
 
-func (c *BranchCommand) deleteBranch(repo *gogit.Repository, opts *BranchOptions) (string, error) {
+func (c *BranchCommand) deleteBranch(s *git.Session, repo *gogit.Repository, opts *BranchOptions) (string, error) {
 	name := opts.BranchName
 	// TODO: support remote delete (git branch -dr origin/branch)
 	if opts.Remote {
@@ -248,6 +430,9 @@ func (c *BranchCommand) deleteBranch(repo *gogit.Repository, opts *BranchOptions
 	if err == nil && headRef.Name() == refName {
 		return "", fmt.Errorf("cannot delete branch '%s' checked out at current worktree", name)
 	}
+	if wt, ok := s.BranchWorktree(refName); ok {
+		return "", fmt.Errorf("cannot delete branch '%s' used by worktree at '/%s'", name, wt)
+	}
 
 	// Determine if Force is needed (DeleteForce or just force flag logic?)
 	// git branch -d checks merge. git branch -D skips check.
@@ -272,7 +457,9 @@ func (c *BranchCommand) deleteBranch(repo *gogit.Repository, opts *BranchOptions
 	if err := repo.Storer.RemoveReference(refName); err != nil {
 		return "", err
 	}
-	return "Deleted branch " + name, nil
+	_ = git.RenameBranchConfig(repo, name, "")
+	s.RecordReflog(fmt.Sprintf("%s%s (was %s)", reflogBranchDeletePrefix, name, targetRef.Hash().String()))
+	return fmt.Sprintf("Deleted branch %s (was %s).", name, targetRef.Hash().String()[:7]), nil
 }
 
 func (c *BranchCommand) moveBranch(repo *gogit.Repository, opts *BranchOptions) (string, error) {
@@ -316,6 +503,7 @@ func (c *BranchCommand) moveBranch(repo *gogit.Repository, opts *BranchOptions)
 	if err := repo.Storer.RemoveReference(oldRefName); err != nil {
 		return "", err // inconsistent state risk, but simulation
 	}
+	_ = git.RenameBranchConfig(repo, oldName, newName)
 
 	return fmt.Sprintf("Renamed branch %s to %s", oldName, newName), nil
 }
@@ -338,51 +526,27 @@ func (c *BranchCommand) listRemoteBranches(repo *gogit.Repository) ([]string, er
 	return remoteBranches, nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *BranchCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-d", "--delete"}, Usage: "Delete a merged branch"},
+			{Flags: []string{"-D"}, Usage: "Delete a branch even if unmerged"},
+			{Flags: []string{"-m", "--move"}, Usage: "Rename a branch"},
+			{Flags: []string{"-f", "--force"}, Usage: "Reset a branch that exists"},
+			{Flags: []string{"-a", "--all"}, Usage: "List local and remote-tracking branches"},
+			{Flags: []string{"-r", "--remotes"}, Usage: "List remote-tracking branches"},
+			{Flags: []string{"-l", "--list"}, Usage: "List branches"},
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show the last commit of each branch; -vv adds the upstream"},
+			{Flags: []string{"-u", "--set-upstream-to"}, Arg: git.ArgRef, Usage: "Set the upstream branch"},
+			{Flags: []string{"--unset-upstream"}, Usage: "Remove the upstream branch"},
+			{Flags: []string{"--limit"}, Arg: git.ArgText, Usage: "List at most n branches"},
+			{Flags: []string{"--after"}, Arg: git.ArgBranch, Usage: "List branches after this one"},
+		},
+		Args: []string{git.ArgBranch, git.ArgRef},
+	}
+}
+
 func (c *BranchCommand) Help() string {
-	return `📘 GIT-BRANCH (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ブランチ（作業の分岐）に関する以下の操作を行います：
-    ・ブランチの一覧を表示する（引数なし）
-    ・新しいブランチを作成する
-    ・ブランチ名を変更する（-m）
-    ・不要なブランチを削除する（-d）
-
- 📋 SYNOPSIS
-    git branch [--list] [-a] [-r]
-    git branch [-f] <branchname> [<start-point>]
-    git branch -d|-D <branchname>
-    git branch -m <old> <new>
-
- ⚙️  COMMON OPTIONS
-    -a, --all
-        ローカルとリモート（追跡）の両方のブランチを表示します。
-
-    -d, --delete
-        ブランチを削除します（マージ済みの安全な場合のみ）。
-
-    -D
-        ブランチを強制削除します（マージされていなくても削除）。
-        ※ ゴミ箱機能はないので、消すと元に戻すのは大変です。注意！
-
-    -m, --move
-        ブランチ名を変更（移動）します。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: 全ブランチを表示
-       リモートブランチも含めてリストアップします。
-       $ git branch -a
-
-    2. 実践: ブランチを強制削除
-       「実験したけどダメだったブランチ」を消す時などに使います。
-       マージしていなくても問答無用で削除されます。
-       $ git branch -D feature/login
-
-    3. 実践: 今のブランチ名を変更
-       「綴り間違えた！」という時に便利です。
-       $ git branch -m new-name
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-branch
-`
+	return git.CommandHelp(context.Background(), "branch")
 }
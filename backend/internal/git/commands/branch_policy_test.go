@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestBranchPolicy_CreateRejectsMissingPrefix(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-branch-policy")
+	ctx := context.Background()
+
+	cfg := &ConfigCommand{}
+	if _, err := cfg.Execute(ctx, s, []string{"config", "gitgym.branchpolicy.prefixes", "feature/,bugfix/"}); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+
+	cmd := &BranchCommand{}
+	_, err := cmd.Execute(ctx, s, []string{"branch", "login"})
+	if err == nil {
+		t.Fatal("Expected policy violation for 'login'")
+	}
+	if !strings.Contains(err.Error(), "feature/login") {
+		t.Errorf("Expected educational hint, got: %v", err)
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"branch", "feature/login"}); err != nil {
+		t.Fatalf("Expected 'feature/login' to be accepted: %v", err)
+	}
+}
+
+func TestBranchPolicy_CheckoutAndSwitchEnforceMaxLength(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-branch-policy-len")
+	ctx := context.Background()
+
+	cfg := &ConfigCommand{}
+	if _, err := cfg.Execute(ctx, s, []string{"config", "gitgym.branchpolicy.maxlength", "8"}); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+
+	if _, err := (&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "-b", "very-long-name"}); err == nil {
+		t.Error("Expected checkout -b to reject long name")
+	}
+	if _, err := (&SwitchCommand{}).Execute(ctx, s, []string{"switch", "-c", "very-long-name"}); err == nil {
+		t.Error("Expected switch -c to reject long name")
+	}
+	if _, err := (&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "-b", "short"}); err != nil {
+		t.Errorf("Expected short name to be accepted: %v", err)
+	}
+}
+
+func TestBranchPolicy_PushRejectsNewRemoteBranch(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-branch-policy-push")
+	ctx := context.Background()
+
+	if err := sm.SetRemoteBranchPolicy("remoterepo", &git.BranchPolicy{Pattern: `^(master|main|feature/.+)$`}); err != nil {
+		t.Fatalf("SetRemoteBranchPolicy failed: %v", err)
+	}
+
+	// Default branch is allowed
+	if _, err := (&PushCommand{}).Execute(ctx, s, []string{"push", "origin", "master"}); err != nil {
+		t.Fatalf("push master failed: %v", err)
+	}
+
+	repo := s.GetRepo()
+	head, _ := repo.Head()
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/wip", head.Hash()))
+
+	_, err := (&PushCommand{}).Execute(ctx, s, []string{"push", "origin", "wip"})
+	if err == nil || !strings.Contains(err.Error(), "remote rejected") {
+		t.Fatalf("Expected push of 'wip' to be rejected, got: %v", err)
+	}
+}
@@ -293,3 +293,32 @@ func TestBranchCommand_DeleteSafety(t *testing.T) {
 		t.Errorf("Expected deletion message, got: %s", res)
 	}
 }
+
+func TestBranchCommand_ListPatternAndLimit(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-branch-list-page")
+	ctx := context.Background()
+
+	cmd := &BranchCommand{}
+	for _, name := range []string{"feature/a", "feature/b", "feature/c", "bugfix/x"} {
+		if _, err := cmd.Execute(ctx, s, []string{"branch", name}); err != nil {
+			t.Fatalf("create %s failed: %v", name, err)
+		}
+	}
+
+	res, err := cmd.Execute(ctx, s, []string{"branch", "--list", "feature/*", "--limit", "2"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if !strings.HasPrefix(res, "feature/a\nfeature/b\n") || !strings.Contains(res, "--after feature/b") {
+		t.Errorf("Unexpected first page: %q", res)
+	}
+
+	res, err = cmd.Execute(ctx, s, []string{"branch", "--list", "feature/*", "--limit", "2", "--after", "feature/b"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if res != "feature/c" {
+		t.Errorf("Unexpected second page: %q", res)
+	}
+}
@@ -0,0 +1,310 @@
+package commands
+
+// bundle.go - Simulated Git Bundle Command
+//
+// "git bundle create" packs refs and their history into a single file in the
+// session filesystem; "git clone" and "git fetch" read such a file back like
+// a remote. This is how history travels without a network (an air-gapped
+// machine, a USB stick), and how missions ship prebuilt repositories.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("bundle", func() git.Command { return &BundleCommand{} })
+}
+
+type BundleCommand struct{}
+
+// Ensure BundleCommand implements git.Command
+var _ git.Command = (*BundleCommand)(nil)
+
+func (c *BundleCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: git bundle (create <file> <git-rev-list-args> | verify <file> | list-heads <file>)")
+	}
+	for _, arg := range args[1:] {
+		if arg == "-h" || arg == "--help" {
+			return git.CommandHelp(ctx, "bundle"), nil
+		}
+	}
+	if len(args) < 3 {
+		return "", fmt.Errorf("fatal: need a <file> argument")
+	}
+	file := args[2]
+
+	switch args[1] {
+	case "create":
+		return c.create(s, file, args[3:])
+	case "verify":
+		return c.verify(s, file, args[3:])
+	case "list-heads":
+		b, _, err := readBundleFile(s, file)
+		if err != nil {
+			return "", err
+		}
+		var lines []string
+		for _, ref := range b.Refs {
+			lines = append(lines, fmt.Sprintf("%s %s", ref.Hash(), ref.Name()))
+		}
+		return strings.Join(lines, "\n"), nil
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: `%s'", args[1])
+	}
+}
+
+// create writes the refs named by revs (and their history, down to any
+// excluded commits) to file.
+func (c *BundleCommand) create(s *git.Session, file string, revs []string) (string, error) {
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	var refs []*plumbing.Reference
+	var prerequisites []plumbing.Hash
+	seen := make(map[plumbing.ReferenceName]bool)
+	addRef := func(ref *plumbing.Reference) {
+		if !seen[ref.Name()] {
+			seen[ref.Name()] = true
+			refs = append(refs, ref)
+		}
+	}
+	exclude := func(rev string) error {
+		hash, err := git.ResolveRevision(repo, rev)
+		if err != nil {
+			return fmt.Errorf("fatal: bad revision '%s'", rev)
+		}
+		prerequisites = append(prerequisites, *hash)
+		return nil
+	}
+
+	for _, rev := range revs {
+		switch {
+		case rev == "--all":
+			all, err := git.AllBundleRefs(repo)
+			if err != nil {
+				return "", err
+			}
+			for _, ref := range all {
+				addRef(ref)
+			}
+		case rev == "--branches" || rev == "--tags":
+			all, err := git.AllBundleRefs(repo)
+			if err != nil {
+				return "", err
+			}
+			for _, ref := range all {
+				if (rev == "--branches" && ref.Name().IsBranch()) || (rev == "--tags" && ref.Name().IsTag()) {
+					addRef(ref)
+				}
+			}
+		case strings.HasPrefix(rev, "^"):
+			if err := exclude(rev[1:]); err != nil {
+				return "", err
+			}
+		case strings.Contains(rev, ".."):
+			from, to, _ := strings.Cut(rev, "..")
+			if strings.HasPrefix(to, ".") {
+				return "", fmt.Errorf("fatal: symmetric ranges are not supported in bundles: '%s'", rev)
+			}
+			if from == "" {
+				from = "HEAD"
+			}
+			if to == "" {
+				to = "HEAD"
+			}
+			if err := exclude(from); err != nil {
+				return "", err
+			}
+			ref, err := bundleRef(repo, to)
+			if err != nil {
+				return "", err
+			}
+			addRef(ref)
+		case strings.HasPrefix(rev, "-"):
+			return "", fmt.Errorf("error: unknown option `%s'", rev)
+		default:
+			ref, err := bundleRef(repo, rev)
+			if err != nil {
+				return "", err
+			}
+			addRef(ref)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := git.CreateBundle(repo, refs, prerequisites, &buf); err != nil {
+		return "", err
+	}
+	target := sessionFilePath(s, file)
+	if _, err := s.Filesystem.Stat(target); err != nil {
+		if err := s.CheckStorageQuota(0, 0, 1); err != nil {
+			return "", err
+		}
+	}
+	if err := s.Filesystem.MkdirAll(path.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	if err := util.WriteFile(s.Filesystem, target, buf.Bytes(), os.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("fatal: could not create '%s': %v", file, err)
+	}
+
+	out := fmt.Sprintf("Created bundle %s with %d ref(s) (%d bytes)", file, len(refs), buf.Len())
+	if len(prerequisites) > 0 {
+		out += fmt.Sprintf("\nThe bundle requires %d commit(s) the receiving repository must already have.", len(prerequisites))
+	}
+	return out, nil
+}
+
+// bundleRef resolves rev to the ref a bundle records for it: HEAD, a branch
+// or a tag. A bundle cannot record a bare commit, since the receiver would
+// have nothing to name it by.
+func bundleRef(repo *gogit.Repository, rev string) (*plumbing.Reference, error) {
+	if rev == "HEAD" {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("fatal: bad revision 'HEAD'")
+		}
+		return plumbing.NewHashReference(plumbing.HEAD, head.Hash()), nil
+	}
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.ReferenceName(rev),
+		plumbing.NewBranchReferenceName(rev),
+		plumbing.NewTagReferenceName(rev),
+		plumbing.ReferenceName("refs/remotes/" + rev),
+	} {
+		if ref, err := repo.Reference(name, true); err == nil && ref.Type() == plumbing.HashReference {
+			return plumbing.NewHashReference(name, ref.Hash()), nil
+		}
+	}
+	if _, err := git.ResolveRevision(repo, rev); err == nil {
+		return nil, fmt.Errorf("fatal: '%s' is not a branch or tag\nhint: A bundle records refs; create a branch or tag at the commit first.", rev)
+	}
+	return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+}
+
+// verify checks that file is a bundle and that the current repository (if
+// any) has its prerequisites, printing its refs like git does.
+func (c *BundleCommand) verify(s *git.Session, file string, rest []string) (string, error) {
+	quiet := false
+	for _, arg := range rest {
+		if arg == "-q" || arg == "--quiet" {
+			quiet = true
+		}
+	}
+	b, _, err := readBundleFile(s, file)
+	if err != nil {
+		return "", err
+	}
+
+	if missing := git.MissingPrerequisites(s.GetRepo(), b); len(missing) > 0 {
+		var sb strings.Builder
+		sb.WriteString("error: Repository lacks these prerequisite commits:")
+		for _, hash := range missing {
+			sb.WriteString("\nerror: " + hash.String())
+		}
+		return "", fmt.Errorf("%s", sb.String())
+	}
+	if quiet {
+		return fmt.Sprintf("%s is okay", file), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The bundle contains %s:\n", plural(len(b.Refs), "this ref", fmt.Sprintf("these %d refs", len(b.Refs)))))
+	for _, ref := range b.Refs {
+		sb.WriteString(fmt.Sprintf("%s %s\n", ref.Hash(), ref.Name()))
+	}
+	if len(b.Prerequisites) == 0 {
+		sb.WriteString("The bundle records a complete history.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("The bundle requires %s:\n", plural(len(b.Prerequisites), "this ref", fmt.Sprintf("these %d refs", len(b.Prerequisites)))))
+		for _, hash := range b.Prerequisites {
+			sb.WriteString(hash.String() + "\n")
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%s is okay", file))
+	return sb.String(), nil
+}
+
+// sessionFilePath resolves a file argument against the current directory.
+func sessionFilePath(s *git.Session, file string) string {
+	if strings.HasPrefix(file, "/") {
+		return path.Clean(file)
+	}
+	return path.Join(s.CurrentDir, file)
+}
+
+// readBundleFile reads the bundle at file and unpacks it into an in-memory repository.
+func readBundleFile(s *git.Session, file string) (*git.Bundle, *gogit.Repository, error) {
+	data, err := util.ReadFile(s.Filesystem, sessionFilePath(s, file))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fatal: could not open '%s' for reading: No such file or directory", file)
+	}
+	if !git.IsBundle(data) {
+		return nil, nil, fmt.Errorf("error: '%s' does not look like a v2 or v3 bundle file", file)
+	}
+	repo, b, err := git.OpenBundle(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error: %s: %v", file, err)
+	}
+	return b, repo, nil
+}
+
+// openBundleRemote opens the bundle file at url for clone and fetch. It
+// returns a nil bundle when url names no bundle file. The history below the
+// prerequisites of an incremental bundle is read from the current repository.
+func openBundleRemote(s *git.Session, url string) (*gogit.Repository, *git.Bundle, error) {
+	data, err := util.ReadFile(s.Filesystem, sessionFilePath(s, url))
+	if err != nil || !git.IsBundle(data) {
+		return nil, nil, nil
+	}
+	repo, b, err := git.OpenBundle(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error: %s: %v", url, err)
+	}
+	if len(b.Prerequisites) == 0 {
+		return repo, b, nil
+	}
+	local := s.GetRepo()
+	if missing := git.MissingPrerequisites(local, b); len(missing) > 0 {
+		return nil, b, fmt.Errorf("error: Repository lacks these prerequisite commits:\nerror: %s", missing[0])
+	}
+	hybrid, err := gogit.Open(git.NewHybridStorer(repo.Storer, local.Storer), nil)
+	if err != nil {
+		return nil, b, err
+	}
+	return hybrid, b, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *BundleCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"create", "list-heads", "verify"},
+		Options: []git.Option{
+			{Flags: []string{"--all"}, Usage: "Bundle every ref"},
+			{Flags: []string{"--branches"}, Usage: "Bundle every branch"},
+			{Flags: []string{"--tags"}, Usage: "Bundle every tag"},
+			{Flags: []string{"-q", "--quiet"}, Usage: "Print nothing on success"},
+		},
+		Args: []string{git.ArgPath, git.ArgRef},
+	}
+}
+
+func (c *BundleCommand) Help() string {
+	return git.CommandHelp(context.Background(), "bundle")
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestBundleCommand_CreateVerifyCloneAndFetch(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-bundle")
+	ctx := context.Background()
+	run := func(cmd string) (string, error) {
+		name, args := git.ParseCommand(cmd)
+		res, err := git.Dispatch(ctx, s, name, args)
+		if res == nil {
+			return "", err
+		}
+		return res.Stdout, err
+	}
+	mustRun := func(cmd string) string {
+		t.Helper()
+		out, err := run(cmd)
+		if err != nil {
+			t.Fatalf("%s failed: %v", cmd, err)
+		}
+		return out
+	}
+
+	mustRun("git tag v1")
+	mustRun("git bundle create /full.bundle --all")
+	out := mustRun("git bundle verify /full.bundle")
+	for _, want := range []string{"refs/heads/main", "refs/tags/v1", "The bundle records a complete history.", "/full.bundle is okay"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in verify output, got:\n%s", want, out)
+		}
+	}
+	if _, err := run("git bundle create /x.bundle HEAD~0"); err == nil || !strings.Contains(err.Error(), "is not a branch or tag") {
+		t.Errorf("Expected a bare commit to be refused, got %v", err)
+	}
+
+	// Clone the bundle like a remote
+	mustRun("cd /")
+	out = mustRun("git clone /full.bundle copy")
+	if !strings.Contains(out, "From bundle /full.bundle") {
+		t.Errorf("Unexpected clone output: %s", out)
+	}
+	copyRepo := s.Repos["copy"]
+	if copyRepo == nil {
+		t.Fatal("Expected the clone to be registered as 'copy'")
+	}
+	head, err := copyRepo.Head()
+	if err != nil || head.Name() != plumbing.NewBranchReferenceName("main") {
+		t.Fatalf("Expected the clone on main, got %v (%v)", head, err)
+	}
+
+	// An incremental bundle needs the history it leaves out
+	mustRun("cd /testrepo")
+	mustRun("git commit --allow-empty -m second")
+	mustRun("git bundle create /update.bundle v1..main")
+	out = mustRun("git bundle list-heads /update.bundle")
+	if strings.Count(out, "\n") != 0 || !strings.HasSuffix(out, " refs/heads/main") {
+		t.Errorf("Expected only main in the incremental bundle, got:\n%s", out)
+	}
+	mustRun("cd /")
+	if _, err := run("git clone /update.bundle other"); err == nil || !strings.Contains(err.Error(), "incremental bundle") {
+		t.Errorf("Expected cloning an incremental bundle to fail, got %v", err)
+	}
+
+	mustRun("cd /copy")
+	mustRun("git remote add usb /update.bundle")
+	mustRun("git bundle verify /update.bundle")
+	mustRun("git fetch usb")
+	tip, err := copyRepo.Reference("refs/remotes/usb/main", true)
+	if err != nil {
+		t.Fatalf("Expected usb/main after fetching the bundle: %v", err)
+	}
+	commit, err := copyRepo.CommitObject(tip.Hash())
+	if err != nil || commit.Message != "second" {
+		t.Errorf("Expected usb/main at the new commit, got %v (%v)", commit, err)
+	}
+}
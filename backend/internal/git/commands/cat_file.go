@@ -0,0 +1,171 @@
+package commands
+
+// cat_file.go - Simulated Git Cat-File Command
+//
+// Shows the type, size or content of an object in the repository. Together
+// with fsck this is how the lessons on Git internals look at commits, trees
+// and blobs directly.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("cat-file", func() git.Command { return &CatFileCommand{} })
+}
+
+type CatFileCommand struct{}
+
+// Ensure CatFileCommand implements git.Command
+var _ git.Command = (*CatFileCommand)(nil)
+
+type CatFileOptions struct {
+	Mode   string // "-t", "-s", "-p", "-e", or the expected type for raw content
+	Object string
+}
+
+func (c *CatFileCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "cat-file"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	hash, err := git.ResolveObject(repo, opts.Object)
+	if err != nil {
+		if opts.Mode == "-e" {
+			return "", fmt.Errorf("error: object %s does not exist", opts.Object)
+		}
+		return "", err
+	}
+	obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+	if err != nil {
+		if opts.Mode == "-e" {
+			return "", fmt.Errorf("error: object %s does not exist", opts.Object)
+		}
+		return "", fmt.Errorf("fatal: Not a valid object name %s", opts.Object)
+	}
+
+	switch opts.Mode {
+	case "-e":
+		return "", nil
+	case "-t":
+		return obj.Type().String() + "\n", nil
+	case "-s":
+		return fmt.Sprintf("%d\n", obj.Size()), nil
+	case "-p":
+		if obj.Type() == plumbing.TreeObject {
+			tree, err := object.DecodeTree(repo.Storer, obj)
+			if err != nil {
+				return "", err
+			}
+			return formatTree(tree), nil
+		}
+		return readObject(obj)
+	default:
+		if obj.Type().String() != opts.Mode {
+			return "", fmt.Errorf("fatal: git cat-file %s: bad file", opts.Object)
+		}
+		return readObject(obj)
+	}
+}
+
+func (c *CatFileCommand) parseArgs(args []string) (*CatFileOptions, error) {
+	opts := &CatFileOptions{}
+	var positional []string
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "-t", "-s", "-p", "-e":
+			if opts.Mode != "" {
+				return nil, fmt.Errorf("error: options '%s' and '%s' cannot be used together", opts.Mode, arg)
+			}
+			opts.Mode = arg
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown switch '%s'", strings.TrimLeft(arg, "-"))
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	usage := fmt.Errorf("usage: git cat-file (-t | -s | -e | -p) <object>\n   or: git cat-file <type> <object>")
+	switch {
+	case opts.Mode != "" && len(positional) == 1:
+		opts.Object = positional[0]
+	case opts.Mode == "" && len(positional) == 2:
+		if _, err := plumbing.ParseObjectType(positional[0]); err != nil {
+			return nil, fmt.Errorf("fatal: invalid object type \"%s\"", positional[0])
+		}
+		opts.Mode, opts.Object = positional[0], positional[1]
+	default:
+		return nil, usage
+	}
+	return opts, nil
+}
+
+// readObject returns the raw content of an object.
+func readObject(obj plumbing.EncodedObject) (string, error) {
+	r, err := obj.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// formatTree lists tree entries the way git cat-file -p and git ls-tree do.
+func formatTree(tree *object.Tree) string {
+	var sb strings.Builder
+	for _, e := range tree.Entries {
+		typ := "blob"
+		switch {
+		case e.Mode.IsFile():
+		case e.Mode == filemode.Submodule:
+			typ = "commit"
+		default:
+			typ = "tree"
+		}
+		sb.WriteString(fmt.Sprintf("%06o %s %s\t%s\n", uint32(e.Mode), typ, e.Hash, e.Name))
+	}
+	return sb.String()
+}
+
+// Spec implements git.SpecProvider.
+func (c *CatFileCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-t"}, Usage: "Show the object type"},
+			{Flags: []string{"-s"}, Usage: "Show the object size"},
+			{Flags: []string{"-p"}, Usage: "Pretty-print the object"},
+			{Flags: []string{"-e"}, Usage: "Check that the object exists"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *CatFileCommand) Help() string {
+	return git.CommandHelp(context.Background(), "cat-file")
+}
@@ -0,0 +1,125 @@
+package commands
+
+// check_ignore.go - Simulated Git Check-Ignore Command
+//
+// Tells which .gitignore (or .git/info/exclude) pattern, if any, excludes a path.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("check-ignore", func() git.Command { return &CheckIgnoreCommand{} })
+}
+
+type CheckIgnoreCommand struct{}
+
+// Ensure CheckIgnoreCommand implements git.Command
+var _ git.Command = (*CheckIgnoreCommand)(nil)
+
+type CheckIgnoreOptions struct {
+	Verbose     bool // -v: show the matching pattern and where it comes from
+	NonMatching bool // -n: with -v, also list paths no pattern matches
+	NoIndex     bool // --no-index: check tracked files too
+	Paths       []string
+}
+
+func (c *CheckIgnoreCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "check-ignore"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	rules := git.LoadIgnoreRules(w.Filesystem)
+	tracked := git.TrackedPaths(repo)
+
+	var sb strings.Builder
+	for _, path := range opts.Paths {
+		name := strings.TrimPrefix(path, "./")
+		var rule *git.IgnoreRule
+		// Tracked files are never ignored, whatever the patterns say
+		if opts.NoIndex || !tracked[name] {
+			rule = rules.Match(name, git.IsDirPath(w.Filesystem, name))
+		}
+
+		switch {
+		case opts.Verbose && rule != nil:
+			sb.WriteString(fmt.Sprintf("%s:%d:%s\t%s\n", rule.Source, rule.Line, rule.Pattern, path))
+		case opts.Verbose && opts.NonMatching:
+			sb.WriteString(fmt.Sprintf("::\t%s\n", path))
+		case rule != nil && !rule.Negated():
+			sb.WriteString(path + "\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+func (c *CheckIgnoreCommand) parseArgs(args []string) (*CheckIgnoreOptions, error) {
+	opts := &CheckIgnoreOptions{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "-v", "--verbose":
+			opts.Verbose = true
+		case "-n", "--non-matching":
+			opts.NonMatching = true
+		case "-vn", "-nv":
+			opts.Verbose = true
+			opts.NonMatching = true
+		case "--no-index":
+			opts.NoIndex = true
+		case "--":
+			opts.Paths = append(opts.Paths, args[i+1:]...)
+			i = len(args)
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown option `%s`", arg)
+			}
+			opts.Paths = append(opts.Paths, arg)
+		}
+	}
+	if len(opts.Paths) == 0 {
+		return nil, fmt.Errorf("fatal: no path specified")
+	}
+	if opts.NonMatching && !opts.Verbose {
+		return nil, fmt.Errorf("fatal: --non-matching is only valid with --verbose")
+	}
+	return opts, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *CheckIgnoreCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show the matching pattern"},
+			{Flags: []string{"-n", "--non-matching"}, Usage: "Show paths that match no pattern"},
+			{Flags: []string{"--no-index"}, Usage: "Check tracked files too"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
+func (c *CheckIgnoreCommand) Help() string {
+	return git.CommandHelp(context.Background(), "check-ignore")
+}
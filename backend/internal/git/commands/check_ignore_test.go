@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitignore_StatusAddCleanAndCheckIgnore(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitignore")
+	ctx := context.Background()
+
+	w, err := s.GetRepo().Worktree()
+	require.NoError(t, err)
+	write := func(name string) {
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(name), 0644))
+	}
+	require.NoError(t, util.WriteFile(w.Filesystem, ".gitignore", []byte("*.log\n!keep.log\nbuild/\n"), 0644))
+	write("debug.log")
+	write("keep.log")
+	write("build/app.bin")
+	write("notes.txt")
+
+	run := func(cmdline string) (string, error) {
+		name, args := git.ParseCommand(cmdline)
+		result, err := git.Dispatch(ctx, s, name, args)
+		return result.Stdout, err
+	}
+
+	// status lists ignored files only with --ignored
+	out, err := run("git status")
+	require.NoError(t, err)
+	assert.Contains(t, out, "notes.txt")
+	assert.Contains(t, out, "keep.log")
+	assert.NotContains(t, out, "debug.log")
+	assert.NotContains(t, out, "Ignored files")
+
+	out, err = run("git status -s --ignored")
+	require.NoError(t, err)
+	assert.Contains(t, out, "!! build/\n")
+	assert.Contains(t, out, "!! debug.log\n")
+	assert.NotContains(t, out, "!! keep.log")
+
+	// check-ignore reports the deciding pattern
+	out, err = run("git check-ignore debug.log keep.log notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "debug.log\n", out)
+
+	out, err = run("git check-ignore -v -n build/app.bin keep.log notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, ".gitignore:3:build/\tbuild/app.bin\n.gitignore:2:!keep.log\tkeep.log\n::\tnotes.txt\n", out)
+
+	// add refuses ignored paths unless forced
+	_, err = run("git add debug.log")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "The following paths are ignored by one of your .gitignore files:\ndebug.log")
+
+	_, err = run("git add .")
+	require.NoError(t, err)
+	tracked := git.TrackedPaths(s.GetRepo())
+	assert.True(t, tracked["notes.txt"])
+	assert.False(t, tracked["debug.log"])
+	assert.False(t, tracked["build/app.bin"])
+
+	_, err = run("git add -f debug.log")
+	require.NoError(t, err)
+	assert.True(t, git.TrackedPaths(s.GetRepo())["debug.log"])
+
+	// A tracked file is no longer reported as ignored
+	out, err = run("git check-ignore debug.log")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	out, err = run("git check-ignore --no-index debug.log")
+	require.NoError(t, err)
+	assert.Equal(t, "debug.log\n", out)
+
+	// clean -X removes only ignored files, directories need -d
+	write("other.log")
+	write("scratch.txt")
+	out, err = run("git clean -fX")
+	require.NoError(t, err)
+	assert.Equal(t, "Removing other.log\n", out)
+
+	out, err = run("git clean -fdX")
+	require.NoError(t, err)
+	assert.Equal(t, "Removing build/\n", out)
+	_, err = w.Filesystem.Stat("build")
+	assert.Error(t, err)
+	_, err = w.Filesystem.Stat("scratch.txt")
+	assert.NoError(t, err)
+
+	write("again.log")
+	out, err = run("git clean -fx")
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(out, "Removing again.log") && strings.Contains(out, "Removing scratch.txt"), out)
+}
@@ -20,6 +20,12 @@ type CheckoutCommand struct{}
 // Ensure CheckoutCommand implements git.Command
 var _ git.Command = (*CheckoutCommand)(nil)
 
+// Ensure CheckoutCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*CheckoutCommand)(nil)
+
+// SupportsDryRun marks checkout as simulated by the engine on --dry-run.
+func (c *CheckoutCommand) SupportsDryRun() {}
+
 // Strategy instances (stateless, can be shared)
 var (
 	fileStrategy   = &checkout.FileStrategy{}
@@ -41,11 +47,20 @@ func (c *CheckoutCommand) Execute(ctx context.Context, s *git.Session, args []st
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "checkout"), nil
 		}
 		return "", err
 	}
 
+	for _, name := range []string{opts.NewBranch, opts.ForceNewBranch, opts.OrphanBranch} {
+		if name == "" {
+			continue
+		}
+		if err := git.CheckBranchPolicy(s, name); err != nil {
+			return "", err
+		}
+	}
+
 	// 2. Resolve Context
 	cCtx, err := c.resolveContext(repo, opts)
 	if err != nil {
@@ -57,7 +72,22 @@ func (c *CheckoutCommand) Execute(ctx context.Context, s *git.Session, args []st
 	if strategy == nil {
 		return "", fmt.Errorf("internal error: unknown checkout mode")
 	}
-	return strategy.Execute(s, cCtx, opts)
+	if err := checkout.CheckBranchInOtherWorktree(s, cCtx, opts); err != nil {
+		return "", err
+	}
+	oldHead, detached := checkout.DetachedHead(repo)
+	out, err := strategy.Execute(s, cCtx, opts)
+	if err != nil || cCtx.Mode == checkout.ModeFiles {
+		return out, err
+	}
+	sparse, err := checkout.ApplySparse(repo)
+	if err != nil {
+		return "", err
+	}
+	if detached {
+		out = checkout.LeavingDetachedHead(repo, oldHead) + out
+	}
+	return sparse + out, nil
 }
 
 func (c *CheckoutCommand) selectStrategy(mode checkout.Mode) checkout.Strategy {
@@ -76,47 +106,40 @@ func (c *CheckoutCommand) selectStrategy(mode checkout.Mode) checkout.Strategy {
 }
 
 func (c *CheckoutCommand) parseArgs(args []string) (*checkout.Options, error) {
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
 	opts := &checkout.Options{}
-	cmdArgs := args[1:]
-	for i := 0; i < len(cmdArgs); i++ {
-		arg := cmdArgs[i]
-		switch arg {
+	for _, f := range parsed.Flags {
+		switch f.Name {
 		case "-b":
-			if i+1 >= len(cmdArgs) {
-				return nil, fmt.Errorf("fatal: missing branch name for -b")
-			}
-			opts.NewBranch = cmdArgs[i+1]
-			i++
+			opts.NewBranch = f.Value
 		case "-B":
-			if i+1 >= len(cmdArgs) {
-				return nil, fmt.Errorf("fatal: missing branch name for -B")
-			}
-			opts.ForceNewBranch = cmdArgs[i+1]
-			i++
+			opts.ForceNewBranch = f.Value
 		case "--orphan":
-			if i+1 >= len(cmdArgs) {
-				return nil, fmt.Errorf("fatal: missing branch name for --orphan")
-			}
-			opts.OrphanBranch = cmdArgs[i+1]
-			i++
-		case "-f", "--force":
+			opts.OrphanBranch = f.Value
+		case "-f":
 			opts.Force = true
 		case "--detach":
 			opts.Detach = true
-		case "-h", "--help":
-			return nil, fmt.Errorf("help requested")
-		case "--":
-			// End of flags, remainder are paths
-			if i+1 >= len(cmdArgs) {
-				return nil, fmt.Errorf("fatal: filename required after --")
-			}
-			opts.Files = cmdArgs[i+1:]
-			return opts, nil // Return immediately as loose args are consumed
-		default:
-			if opts.Target == "" {
-				opts.Target = arg
-			}
+		case "--no-track":
+			opts.NoTrack = true
+		case "--guess":
+			opts.NoGuess = false
+		case "--no-guess":
+			opts.NoGuess = true
+		}
+	}
+	if len(parsed.Positional) > 0 {
+		opts.Target = parsed.Positional[0]
+	}
+	if parsed.Dashed {
+		// The remainder are paths
+		if len(parsed.Rest) == 0 {
+			return nil, fmt.Errorf("fatal: filename required after --")
 		}
+		opts.Files = parsed.Rest
 	}
 	return opts, nil
 }
@@ -171,6 +194,9 @@ func (c *CheckoutCommand) resolveContext(repo *gogit.Repository, opts *checkout.
 			return nil, fmt.Errorf("fatal: invalid reference: %s", startPoint)
 		}
 		ctx.StartPointHash = hash
+		if track, ok := checkout.RemoteTrackingRef(repo, startPoint); ok && !opts.NoTrack {
+			ctx.Track = track
+		}
 
 		refName := plumbing.ReferenceName("refs/heads/" + ctx.NewBranch)
 		_, err = repo.Reference(refName, true)
@@ -210,12 +236,12 @@ func (c *CheckoutCommand) resolveContext(repo *gogit.Repository, opts *checkout.
 	}
 
 	// 1.5. Check if it's a remote branch (Auto-track)
-	remoteRefName := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/origin/%s", opts.Target))
-	if remoteRef, err := repo.Reference(remoteRefName, true); err == nil && !opts.Detach {
-		ctx.TargetRef = remoteRefName
-		h := remoteRef.Hash()
-		ctx.TargetHash = &h
-		return ctx, nil
+	if !opts.Detach && !opts.NoGuess {
+		if remoteRef, h, ok := checkout.GuessRemoteBranch(repo, opts.Target); ok {
+			ctx.TargetRef = remoteRef
+			ctx.TargetHash = &h
+			return ctx, nil
+		}
 	}
 
 	// 2. Try as hash/tag (Detached HEAD)
@@ -245,46 +271,23 @@ func (c *CheckoutCommand) resolveContext(repo *gogit.Repository, opts *checkout.
 	return nil, fmt.Errorf("error: pathspec '%s' did not match any file(s) known to git", opts.Target)
 }
 
+// Spec implements git.SpecProvider.
+func (c *CheckoutCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-b"}, Arg: git.ArgText, Usage: "Create a branch and switch to it"},
+			{Flags: []string{"-B"}, Arg: git.ArgText, Usage: "Create or reset a branch and switch to it"},
+			{Flags: []string{"--orphan"}, Arg: git.ArgText, Usage: "Create a branch with no history"},
+			{Flags: []string{"--detach"}, Usage: "Detach HEAD at the commit"},
+			{Flags: []string{"-f", "--force"}, Usage: "Throw away local changes"},
+			{Flags: []string{"--no-track"}, Usage: "Do not set up tracking"},
+			{Flags: []string{"--guess"}, Usage: "Create a branch from a matching remote branch"},
+			{Flags: []string{"--no-guess"}, Usage: "Do not guess a remote branch"},
+		},
+		Args: []string{git.ArgRef, git.ArgPath},
+	}
+}
+
 func (c *CheckoutCommand) Help() string {
-	return `📘 GIT-CHECKOUT (1)                                     Git Manual
-
- 💡 DESCRIPTION
-    HEAD（今作業しているブランチやコミット）を移動します。
-    それに合わせて、手元のファイル（ワーキングツリー）の内容も更新されます。
-    
-    主な用途：
-    1. 他のブランチに切り替える（switch使おう）
-    2. 新しいブランチを作って切り替える（switch -c使おう）
-    3. ファイルの変更を取り消して元に戻す（この使い方が重要！）
-
- 📋 SYNOPSIS
-    git checkout <branch>
-    git checkout -b <new_branch>
-    git checkout -- <file>...
-
- ⚙️  COMMON OPTIONS
-    -b <new_branch>
-        新しいブランチを作成して、すぐにそのブランチに切り替えます。
-
-    -B <new_branch>
-        ブランチが存在しても強制的に作成（リセット）して切り替えます。
-    
-    -- <file>
-        ブランチ切り替えではなく、指定したファイルの変更を取り消して元に戻します。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: 既存のブランチに切り替え
-       $ git checkout main
-
-    2. 基本: 新しいブランチを作成して切り替え
-       $ git checkout -b feature/login
-
-    3. 実践: 変更の取り消し (Important)
-       「コードいじってたら動かなくなった...元に戻したい」
-       そんな時は、ファイルを指定して checkout します。
-       $ git checkout -- src/main.go
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-checkout
-`
+	return git.CommandHelp(context.Background(), "checkout")
 }
@@ -30,8 +30,15 @@ func (s *BranchStrategy) Execute(sess *git.Session, ctx *Context, opts *Options)
 	}
 
 	sess.RecordReflog(fmt.Sprintf("checkout: moving from %s to %s", "HEAD", ctx.NewBranch))
+
+	tracking := ""
+	if ctx.Track != "" {
+		if tracking, err = setTracking(ctx.Repo, ctx.NewBranch, ctx.Track); err != nil {
+			return "", err
+		}
+	}
 	if ctx.ForceCreate {
-		return fmt.Sprintf("Reset branch '%s'", ctx.NewBranch), nil
+		return fmt.Sprintf("%sReset branch '%s'", tracking, ctx.NewBranch), nil
 	}
-	return fmt.Sprintf("Switched to a new branch '%s'", ctx.NewBranch), nil
+	return fmt.Sprintf("%sSwitched to a new branch '%s'", tracking, ctx.NewBranch), nil
 }
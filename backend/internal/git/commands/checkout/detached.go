@@ -0,0 +1,67 @@
+package checkout
+
+import (
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// orphanCutoff is how many left-behind commits the warning lists, as in git.
+const orphanCutoff = 4
+
+// DetachedHead returns the commit HEAD is detached at, if it is detached.
+func DetachedHead(repo *gogit.Repository) (plumbing.Hash, bool) {
+	head, err := repo.Head()
+	if err != nil || head.Name() != plumbing.HEAD {
+		return plumbing.ZeroHash, false
+	}
+	return head.Hash(), true
+}
+
+// LeavingDetachedHead describes what HEAD left behind when it moved away
+// from the detached commit old: git's warning listing the commits no branch
+// reaches any more, or the previous HEAD position when there are none. It is
+// empty when HEAD did not move.
+func LeavingDetachedHead(repo *gogit.Repository, old plumbing.Hash) string {
+	if head, err := repo.Head(); err == nil && head.Name() == plumbing.HEAD && head.Hash() == old {
+		return ""
+	}
+	commit, err := repo.CommitObject(old)
+	if err != nil {
+		return ""
+	}
+
+	lost := git.LeftBehind(repo, old)
+	if len(lost) == 0 {
+		return fmt.Sprintf("Previous HEAD position was %s %s\n", old.String()[:7], subject(commit.Message))
+	}
+
+	var sb strings.Builder
+	if len(lost) == 1 {
+		sb.WriteString("Warning: you are leaving 1 commit behind, not connected to\nany of your branches:\n\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Warning: you are leaving %d commits behind, not connected to\nany of your branches:\n\n", len(lost)))
+	}
+	for i, c := range lost {
+		if i == orphanCutoff && len(lost) > orphanCutoff+1 {
+			sb.WriteString(fmt.Sprintf(" ... and %d more.\n", len(lost)-orphanCutoff))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s\n", c.Hash.String()[:7], subject(c.Message)))
+	}
+	pronoun := "it"
+	if len(lost) > 1 {
+		pronoun = "them"
+	}
+	sb.WriteString(fmt.Sprintf("\nIf you want to keep %s by creating a new branch, this may be a good time\nto do so with:\n\n git branch <new-branch-name> %s\n\n", pronoun, old.String()[:7]))
+	sb.WriteString("hint: Lost them already? 'gitgym recover' lists commits the reflog still remembers.\n\n")
+	return sb.String()
+}
+
+func subject(message string) string {
+	s, _, _ := strings.Cut(message, "\n")
+	return s
+}
@@ -2,8 +2,11 @@ package checkout
 
 import (
 	"fmt"
+	"os"
 
+	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -19,7 +22,27 @@ func (s *OrphanStrategy) Execute(sess *git.Session, ctx *Context, _ *Options) (s
 	if err := ctx.Repo.Storer.SetReference(headRef); err != nil {
 		return "", fmt.Errorf("failed to set HEAD for orphan: %w", err)
 	}
+	if ctx.ClearWorktree {
+		if err := clearTracked(ctx.Repo, ctx.Worktree); err != nil {
+			return "", err
+		}
+	}
 
 	sess.RecordReflog(fmt.Sprintf("checkout: moving from %s to %s (orphan)", "HEAD", ctx.OrphanBranch))
 	return fmt.Sprintf("Switched to a new branch '%s' (orphan)", ctx.OrphanBranch), nil
 }
+
+// clearTracked removes the tracked files from the working tree and empties
+// the index. Untracked files are left alone.
+func clearTracked(repo *gogit.Repository, w *gogit.Worktree) error {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		if err := w.Filesystem.Remove(e.Name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return repo.Storer.SetIndex(&index.Index{Version: idx.Version})
+}
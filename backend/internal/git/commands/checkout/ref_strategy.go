@@ -52,7 +52,14 @@ func (s *RefStrategy) Execute(sess *git.Session, ctx *Context, opts *Options) (s
 		return fmt.Sprintf("Note: switching to '%s'.\n\nYou are in 'detached HEAD' state.", target), nil
 	}
 	if ctx.TargetRef != "" && ctx.TargetRef.IsRemote() {
-		return fmt.Sprintf("Switched to a new branch '%s'\nBranch '%s' set up to track remote branch '%s' from 'origin'.", opts.Target, opts.Target, opts.Target), nil
+		tracking := ""
+		if !opts.NoTrack {
+			var err error
+			if tracking, err = setTracking(ctx.Repo, opts.Target, ctx.TargetRef); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%sSwitched to a new branch '%s'", tracking, opts.Target), nil
 	}
 	return fmt.Sprintf("Switched to branch '%s'", opts.Target), nil
 }
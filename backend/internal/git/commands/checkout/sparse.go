@@ -0,0 +1,18 @@
+package checkout
+
+import (
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// ApplySparse removes the files a sparse checkout excludes once a checkout has
+// moved HEAD, returning the warnings for files kept because of local changes.
+func ApplySparse(repo *gogit.Repository) (string, error) {
+	warnings, err := git.ApplySparseCheckout(repo)
+	if err != nil || len(warnings) == 0 {
+		return "", err
+	}
+	return strings.Join(warnings, "\n") + "\n", nil
+}
@@ -0,0 +1,76 @@
+package checkout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// GuessRemoteBranch finds the remote-tracking branch a new local branch
+// called name would track, as "git checkout <name>" and "git switch <name>" do
+// when no such local branch exists. It only succeeds when exactly one remote
+// has a branch of that name.
+func GuessRemoteBranch(repo *gogit.Repository, name string) (plumbing.ReferenceName, plumbing.Hash, bool) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", plumbing.ZeroHash, false
+	}
+	var remotes []string
+	for remote := range cfg.Remotes {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+
+	var found *plumbing.Reference
+	for _, remote := range remotes {
+		ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, name), true)
+		if err != nil {
+			continue
+		}
+		if found != nil {
+			return "", plumbing.ZeroHash, false // Ambiguous
+		}
+		found = ref
+	}
+	if found == nil {
+		return "", plumbing.ZeroHash, false
+	}
+	return found.Name(), found.Hash(), true
+}
+
+// RemoteTrackingRef returns the remote-tracking branch a start point such as
+// "origin/feature" names, if it names one.
+func RemoteTrackingRef(repo *gogit.Repository, startPoint string) (plumbing.ReferenceName, bool) {
+	name := plumbing.ReferenceName("refs/remotes/" + strings.TrimPrefix(startPoint, "refs/remotes/"))
+	if _, err := repo.Reference(name, true); err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// setTracking makes branch track the remote-tracking branch upstream and
+// describes it the way git does.
+func setTracking(repo *gogit.Repository, branch string, upstream plumbing.ReferenceName) (string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	short := upstream.Short()
+	remote := ""
+	for name := range cfg.Remotes {
+		if strings.HasPrefix(short, name+"/") && len(name) > len(remote) {
+			remote = name
+		}
+	}
+	if remote == "" {
+		return "", nil // Not a configured remote: nothing to track
+	}
+	if err := git.SetUpstream(repo, branch, remote, strings.TrimPrefix(short, remote+"/")); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("branch '%s' set up to track '%s'.\n", branch, short), nil
+}
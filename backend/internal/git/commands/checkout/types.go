@@ -14,6 +14,8 @@ type Options struct {
 	OrphanBranch   string
 	Force          bool
 	Detach         bool
+	NoTrack        bool // --no-track: do not set up the upstream of a new branch
+	NoGuess        bool // --no-guess: do not create a branch from a remote one of the same name
 	Target         string
 	Files          []string // For "git checkout -- <file>"
 }
@@ -42,6 +44,8 @@ type Context struct {
 	TargetRef      plumbing.ReferenceName
 	TargetHash     *plumbing.Hash
 	IsDetached     bool
+	Track          plumbing.ReferenceName // Remote-tracking branch a new branch tracks
+	ClearWorktree  bool                   // Orphan branches of git switch start from an empty index and working tree
 }
 
 // Strategy defines the interface for checkout strategies.
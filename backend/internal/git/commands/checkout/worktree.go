@@ -0,0 +1,29 @@
+package checkout
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// CheckBranchInOtherWorktree refuses to check out, or reset with -B, a branch
+// that another worktree of the repository has checked out, unless forced.
+func CheckBranchInOtherWorktree(s *git.Session, ctx *Context, opts *Options) error {
+	if opts.Force {
+		return nil
+	}
+	var branch plumbing.ReferenceName
+	switch {
+	case ctx.Mode == ModeRefOrPath && !ctx.IsDetached && ctx.TargetRef.IsBranch():
+		branch = ctx.TargetRef
+	case ctx.Mode == ModeNewBranch && ctx.ForceCreate:
+		branch = plumbing.NewBranchReferenceName(ctx.NewBranch)
+	default:
+		return nil
+	}
+	if wt, ok := s.BranchWorktree(branch); ok {
+		return fmt.Errorf("fatal: '%s' is already used by worktree at '/%s'", branch.Short(), wt)
+	}
+	return nil
+}
@@ -22,7 +22,9 @@ type CherryPickCommand struct{}
 var _ git.Command = (*CherryPickCommand)(nil)
 
 type CherryPickOptions struct {
-	Args []string
+	Args     []string
+	Continue bool // Commit the resolved conflict and pick the remaining commits
+	Abort    bool // Return to the HEAD before the cherry-pick started
 }
 
 func (c *CherryPickCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -39,82 +41,105 @@ func (c *CherryPickCommand) Execute(ctx context.Context, s *git.Session, args []
 		return "", fmt.Errorf("fatal: not a git repository")
 	}
 
+	switch {
+	case opts.Continue:
+		return c.continueCherryPick(s, repo)
+	case opts.Abort:
+		return c.abortCherryPick(s, repo)
+	}
+
+	if s.CherryPickInProgress() != nil {
+		return "", fmt.Errorf("error: cherry-pick is already in progress\nhint: try \"git cherry-pick (--continue | --abort)\"\nfatal: cherry-pick failed")
+	}
+
 	commits, err := c.resolveCommits(repo, opts.Args)
 	if err != nil {
 		return "", err
 	}
 
-	return c.executeCherryPick(s, repo, commits)
+	headRef, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkLocalChanges(repo, headRef.Hash(), commits); err != nil {
+		return "", err
+	}
+	progress := &git.CherryPickState{OrigHead: headRef.Hash().String()}
+
+	return c.executeCherryPick(s, repo, commits, progress)
+}
+
+// checkLocalChanges refuses to start when picking commits would overwrite
+// local changes: changed files one of the commits touches, or anything
+// staged. Other local changes carry over, and survive --abort.
+func (c *CherryPickCommand) checkLocalChanges(repo *gogit.Repository, head plumbing.Hash, commits []*object.Commit) error {
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		base := head
+		if commit.NumParents() > 0 {
+			base = commit.ParentHashes[0]
+		}
+		blockers, err := mergeBlockers(repo, w, base, commit.Hash)
+		if err != nil {
+			return err
+		}
+		if len(blockers) > 0 {
+			return fmt.Errorf("error: your local changes would be overwritten by cherry-pick.\nhint: commit your changes or stash them to proceed.\nfatal: cherry-pick failed")
+		}
+	}
+	return nil
 }
 
 func (c *CherryPickCommand) parseArgs(args []string) (*CherryPickOptions, error) {
-	cmdArgs := args[1:]
-	if len(cmdArgs) == 0 {
-		return nil, fmt.Errorf("usage: git cherry-pick <commit>")
+	opts := &CherryPickOptions{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--continue":
+			opts.Continue = true
+		case "--abort":
+			opts.Abort = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown option `%s`", arg)
+			}
+			opts.Args = append(opts.Args, arg)
+		}
+	}
+
+	if opts.Continue && opts.Abort {
+		return nil, fmt.Errorf("error: --continue and --abort are mutually exclusive")
+	}
+	if (opts.Continue || opts.Abort) && len(opts.Args) > 0 {
+		return nil, fmt.Errorf("error: --continue and --abort take no commit arguments")
 	}
-	// For now, no flags supported, just assume all are commit args
-	return &CherryPickOptions{Args: cmdArgs}, nil
+	if !opts.Continue && !opts.Abort && len(opts.Args) == 0 {
+		return nil, fmt.Errorf("usage: git cherry-pick <commit>...\n   or: git cherry-pick (--continue | --abort)")
+	}
+	return opts, nil
 }
 
 func (c *CherryPickCommand) resolveCommits(repo *gogit.Repository, args []string) ([]*object.Commit, error) {
 	var commitsToPick []*object.Commit
 
 	for _, arg := range args {
-		if strings.Contains(arg, "..") {
-			// Range detected: A..B
-			parts := strings.SplitN(arg, "..", 2)
-			startRev, endRev := parts[0], parts[1]
-
-			if startRev == "" || endRev == "" {
-				return nil, fmt.Errorf("malformed range: %s", arg)
-			}
-
-			startHash, err := c.resolveRevision(repo, startRev)
+		if r, ok, err := git.ParseRevisionRange(repo, arg); ok {
+			// A..B picks the commits reachable from B but not from A, oldest first
 			if err != nil {
-				return nil, fmt.Errorf("invalid revision '%s': %v", startRev, err)
+				return nil, err
 			}
-			endHash, err := c.resolveRevision(repo, endRev)
-			if err != nil {
-				return nil, fmt.Errorf("invalid revision '%s': %v", endRev, err)
-			}
-
-			endCommit, err := repo.CommitObject(*endHash)
+			rangeCommits, err := r.Commits(repo)
 			if err != nil {
 				return nil, err
 			}
-
-			iter := endCommit
-			var rangeCommits []*object.Commit
-			foundStart := false
-
-			// Walk back from End until Start
-			// Safety limit for simulation?
-			for {
-				if iter.Hash == *startHash {
-					foundStart = true
-					break
-				}
-				rangeCommits = append(rangeCommits, iter)
-
-				if iter.NumParents() == 0 {
-					break
-				}
-				p, err := iter.Parent(0)
-				if err != nil {
-					return nil, fmt.Errorf("failed to traverse history: %v", err)
+			for _, commit := range rangeCommits {
+				if commit.NumParents() > 1 {
+					return nil, fmt.Errorf("error: commit %s is a merge but no -m option was given.", commit.Hash.String()[:7])
 				}
-				iter = p
 			}
-
-			if !foundStart {
-				return nil, fmt.Errorf("fatal: start revision '%s' is not an ancestor of '%s'", startRev, endRev)
-			}
-
-			// Add in correct order (Oldest -> Newest)
-			for i := len(rangeCommits) - 1; i >= 0; i-- {
-				commitsToPick = append(commitsToPick, rangeCommits[i])
-			}
-
+			commitsToPick = append(commitsToPick, rangeCommits...)
 		} else {
 			// Single commit
 			h, err := c.resolveRevision(repo, arg)
@@ -131,7 +156,10 @@ func (c *CherryPickCommand) resolveCommits(repo *gogit.Repository, args []string
 	return commitsToPick, nil
 }
 
-func (c *CherryPickCommand) executeCherryPick(_ *git.Session, repo *gogit.Repository, commitsToPick []*object.Commit) (string, error) {
+// executeCherryPick picks commitsToPick onto HEAD one by one. If a commit
+// conflicts it stops, leaving conflict markers in the worktree, and records
+// progress in the session so --continue or --abort can finish the job.
+func (c *CherryPickCommand) executeCherryPick(s *git.Session, repo *gogit.Repository, commitsToPick []*object.Commit, progress *git.CherryPickState) (string, error) {
 	w, err := repo.Worktree()
 	if err != nil {
 		return "", err
@@ -142,17 +170,12 @@ func (c *CherryPickCommand) executeCherryPick(_ *git.Session, repo *gogit.Reposi
 		return "", err
 	}
 
-	pickedCount := 0
-	for _, commitToPick := range commitsToPick {
+	for i, commitToPick := range commitsToPick {
 		// Prepare for 3-way merge
 		// Base: Parent of the commit we are picking
 		// Ours: Current HEAD
 		// Theirs: The commit we are picking
 
-		// if commitToPick.NumParents() == 0 {
-		// 	// Picking a root commit - not handled in basic flow yet
-		// }
-
 		// Get current HEAD (Ours)
 		headRef, err = repo.Head() // Update HEAD ref in each iteration as it moves
 		if err != nil {
@@ -169,65 +192,157 @@ func (c *CherryPickCommand) executeCherryPick(_ *git.Session, repo *gogit.Reposi
 		}
 
 		// Execute Merge
-		err = git.Merge3Way(w, baseCommit, oursCommit, commitToPick)
+		err = git.Merge3Way(w, baseCommit, oursCommit, commitToPick, "")
 		if err != nil {
 			if err == git.ErrConflict {
-				return "", fmt.Errorf("error: could not apply %s... %s\nhint: after resolving the conflicts, mark the corrected paths\nhint: with 'git add <paths>' or 'git rm <paths>'\nhint: and commit the result with 'git commit'", commitToPick.Hash.String()[:7], commitToPick.Message)
+				return "", c.stopOnConflict(s, repo, w, commitToPick, commitsToPick[i+1:], progress)
 			}
 			return "", fmt.Errorf("failed to cherry-pick %s: %v", commitToPick.Hash.String()[:7], err)
 		}
 
-		time.Sleep(10 * time.Millisecond)
-
-		// Commit
-		_, err = w.Commit(commitToPick.Message, &gogit.CommitOptions{
-			Author: &object.Signature{
-				Name:  commitToPick.Author.Name,
-				Email: commitToPick.Author.Email,
-				When:  time.Now(),
-			},
-			AllowEmptyCommits: true,
-		})
-		if err != nil {
-			return "", fmt.Errorf("failed to commit: %v", err)
+		if err := c.commitPick(s, w, commitToPick); err != nil {
+			return "", err
 		}
-		pickedCount++
+		progress.Picked++
 	}
 
-	return fmt.Sprintf("Cherry-pick successful. Picked %d commits to %s.", pickedCount, headRef.Name().Short()), nil
+	s.ClearCherryPick()
+	return fmt.Sprintf("Cherry-pick successful. Picked %d commits to %s.", progress.Picked, headRef.Name().Short()), nil
 }
 
-// resolveRevision delegates to the shared git.ResolveRevision helper
-func (c *CherryPickCommand) resolveRevision(repo *gogit.Repository, rev string) (*plumbing.Hash, error) {
-	return git.ResolveRevision(repo, rev)
+// commitPick commits the staged result of picking commit, keeping its message and author.
+func (c *CherryPickCommand) commitPick(s *git.Session, w *gogit.Worktree, commit *object.Commit) error {
+	time.Sleep(10 * time.Millisecond)
+
+	newHash, err := w.Commit(commit.Message, &gogit.CommitOptions{
+		Author: &object.Signature{
+			Name:  commit.Author.Name,
+			Email: commit.Author.Email,
+			When:  time.Now(),
+		},
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	s.RecordLineage(commit.Hash, newHash, git.RewriteCherryPick)
+	return nil
 }
 
-func (c *CherryPickCommand) Help() string {
-	return `📘 GIT-CHERRY-PICK (1)                                  Git Manual
+// stopOnConflict records the interrupted cherry-pick in the session and builds the conflict report.
+func (c *CherryPickCommand) stopOnConflict(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, current *object.Commit, remaining []*object.Commit, progress *git.CherryPickState) error {
+	conflicts, err := conflictedPaths(w)
+	if err != nil {
+		return err
+	}
+
+	progress.Current = current.Hash.String()
+	progress.Conflicts = conflicts
+	progress.Todo = nil
+	for _, commit := range remaining {
+		progress.Todo = append(progress.Todo, commit.Hash.String())
+	}
+	s.StartCherryPick(progress)
+
+	var sb strings.Builder
+	for _, path := range conflicts {
+		sb.WriteString(fmt.Sprintf("CONFLICT (content): Merge conflict in %s\n", path))
+	}
+	subject := strings.SplitN(strings.TrimSpace(current.Message), "\n", 2)[0]
+	sb.WriteString(fmt.Sprintf("error: could not apply %s... %s\n", current.Hash.String()[:7], subject))
+	sb.WriteString("hint: after resolving the conflicts, mark the corrected paths\n")
+	sb.WriteString("hint: with 'git add <paths>' or 'git rm <paths>'\n")
+	sb.WriteString("hint: and run 'git cherry-pick --continue'.\n")
+	sb.WriteString("hint: You can instead abort the cherry-pick with 'git cherry-pick --abort'.")
+	for _, line := range git.Rerere(s, repo, w, conflicts) {
+		sb.WriteString("\n" + line)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// continueCherryPick commits the resolved conflict and picks the commits that were still to do.
+func (c *CherryPickCommand) continueCherryPick(s *git.Session, repo *gogit.Repository) (string, error) {
+	progress := s.CherryPickInProgress()
+	if progress == nil {
+		return "", fmt.Errorf("error: no cherry-pick or revert in progress\nfatal: cherry-pick failed")
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if unresolved, err := unresolvedPaths(w, progress.Conflicts); err != nil {
+		return "", err
+	} else if len(unresolved) > 0 {
+		return "", unmergedFilesError(unresolved, "fatal: cherry-pick failed")
+	}
+
+	current, err := repo.CommitObject(plumbing.NewHash(progress.Current))
+	if err != nil {
+		return "", err
+	}
+	if err := c.commitPick(s, w, current); err != nil {
+		return "", err
+	}
+	progress.Picked++
+	recorded := git.RerereRecordResolutions(s, repo, w)
 
- 💡 DESCRIPTION
-    ・別のブランチにある「特定のコミット」だけをコピーして取り込む
-    ・指定したコミットの変更を、現在のブランチに適用する
+	var remaining []*object.Commit
+	for _, hash := range progress.Todo {
+		commit, err := repo.CommitObject(plumbing.NewHash(hash))
+		if err != nil {
+			return "", err
+		}
+		remaining = append(remaining, commit)
+	}
 
- 📋 SYNOPSIS
-    git cherry-pick <commit>...
-    git cherry-pick <start>..<end>
+	out, err := c.executeCherryPick(s, repo, remaining, progress)
+	if err != nil {
+		if len(recorded) > 0 {
+			return "", fmt.Errorf("%s", prependLines(recorded, err.Error()))
+		}
+		return "", err
+	}
+	return prependLines(recorded, out), nil
+}
 
- ⚙️  COMMON OPTIONS
-    <commit>...
-        適用したいコミットのハッシュ。複数指定可能。
+// abortCherryPick returns the branch and the files the cherry-pick wrote to
+// where they were before it, like reset --merge.
+func (c *CherryPickCommand) abortCherryPick(s *git.Session, repo *gogit.Repository) (string, error) {
+	progress := s.CherryPickInProgress()
+	if progress == nil {
+		return "", fmt.Errorf("error: no cherry-pick or revert in progress\nfatal: cherry-pick failed")
+	}
 
-    <start>..<end>
-        コミットの範囲を指定します（startを含まず、endまで）。
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if err := resetMergeTo(repo, w, plumbing.NewHash(progress.OrigHead), progress.Conflicts); err != nil {
+		return "", err
+	}
 
- 🛠  EXAMPLES
-    1. 特定のコミットを適用
-       $ git cherry-pick e5a3b21
+	s.ClearCherryPick()
+	git.RerereClear(s)
+	return "Cherry-pick aborted.", nil
+}
+
+// resolveRevision delegates to the shared git.ResolveRevision helper
+func (c *CherryPickCommand) resolveRevision(repo *gogit.Repository, rev string) (*plumbing.Hash, error) {
+	return git.ResolveRevision(repo, rev)
+}
 
-    2. 範囲適用
-       $ git cherry-pick A..B
+// Spec implements git.SpecProvider.
+func (c *CherryPickCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--continue"}, Usage: "Continue after resolving conflicts"},
+			{Flags: []string{"--abort"}, Usage: "Cancel and go back"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-cherry-pick
-`
+func (c *CherryPickCommand) Help() string {
+	return git.CommandHelp(context.Background(), "cherry-pick")
 }
@@ -6,12 +6,14 @@ import (
 	"time"
 
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCherryPickRange(t *testing.T) {
@@ -231,3 +233,137 @@ func TestCherryPickConflict(t *testing.T) {
 	assert.Contains(t, sContent, "changeA")
 	assert.Contains(t, sContent, ">>>>>>>")
 }
+
+func TestCherryPickContinueAndAbort(t *testing.T) {
+	setup := func(t *testing.T) (*git.Session, *gogit.Repository, []string, plumbing.Hash) {
+		fs := memfs.New()
+		r, _ := gogit.Init(memory.NewStorage(), fs)
+		w, _ := r.Worktree()
+		sig := &object.Signature{Name: "User", Email: "u@t.com", When: time.Now()}
+
+		writeAndAdd := func(name, content string) {
+			f, _ := fs.Create(name)
+			_, _ = f.Write([]byte(content))
+			_ = f.Close()
+			_, _ = w.Add(name)
+		}
+
+		writeAndAdd("file.txt", "base\n")
+		writeAndAdd("other.txt", "untouched\n")
+		baseHash, _ := w.Commit("Base", &gogit.CommitOptions{Author: sig})
+
+		// feature: A conflicts with main, C does not
+		writeAndAdd("file.txt", "base\nchangeA\n")
+		aHash, _ := w.Commit("Commit A", &gogit.CommitOptions{Author: sig})
+		writeAndAdd("c.txt", "C\n")
+		cHash, _ := w.Commit("Commit C", &gogit.CommitOptions{Author: sig})
+
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Hash: baseHash, Force: true}))
+		writeAndAdd("file.txt", "base\nchangeB\n")
+		bHash, _ := w.Commit("Commit B", &gogit.CommitOptions{Author: sig})
+
+		session := &git.Session{
+			ID:         "test-session",
+			Filesystem: fs,
+			Repos:      map[string]*gogit.Repository{"repo": r},
+			CurrentDir: "/repo",
+		}
+		return session, r, []string{aHash.String(), cHash.String()}, bHash
+	}
+
+	t.Run("continue after resolving", func(t *testing.T) {
+		session, r, picks, _ := setup(t)
+		cmd := &CherryPickCommand{}
+
+		_, err := cmd.Execute(context.Background(), session, append([]string{"cherry-pick"}, picks...))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CONFLICT (content): Merge conflict in file.txt")
+
+		progress := session.CherryPickInProgress()
+		require.NotNil(t, progress)
+		assert.Equal(t, picks[0], progress.Current)
+		assert.Equal(t, []string{picks[1]}, progress.Todo)
+
+		// A new cherry-pick is refused while one is in progress
+		_, err = cmd.Execute(context.Background(), session, []string{"cherry-pick", picks[1]})
+		assert.ErrorContains(t, err, "cherry-pick is already in progress")
+
+		// --continue refuses while the conflict is unresolved
+		_, err = cmd.Execute(context.Background(), session, []string{"cherry-pick", "--continue"})
+		assert.ErrorContains(t, err, "unmerged files")
+
+		w, _ := r.Worktree()
+		f, _ := session.Filesystem.Create("file.txt")
+		_, _ = f.Write([]byte("base\nchangeB\nchangeA\n"))
+		_ = f.Close()
+		_, _ = w.Add("file.txt")
+
+		out, err := cmd.Execute(context.Background(), session, []string{"cherry-pick", "--continue"})
+		require.NoError(t, err)
+		assert.Contains(t, out, "Picked 2 commits")
+		assert.Nil(t, session.CherryPickInProgress())
+
+		head, _ := r.Head()
+		headCommit, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Commit C", headCommit.Message)
+		parent, _ := headCommit.Parent(0)
+		assert.Equal(t, "Commit A", parent.Message)
+	})
+
+	t.Run("abort restores HEAD", func(t *testing.T) {
+		session, r, picks, bHash := setup(t)
+		cmd := &CherryPickCommand{}
+
+		_, err := cmd.Execute(context.Background(), session, append([]string{"cherry-pick"}, picks...))
+		require.Error(t, err)
+
+		_, err = cmd.Execute(context.Background(), session, []string{"cherry-pick", "--abort"})
+		require.NoError(t, err)
+		assert.Nil(t, session.CherryPickInProgress())
+
+		head, _ := r.Head()
+		assert.Equal(t, bHash, head.Hash())
+		f, _ := session.Filesystem.Open("file.txt")
+		content := make([]byte, 100)
+		n, _ := f.Read(content)
+		assert.Equal(t, "base\nchangeB\n", string(content[:n]))
+
+		_, err = cmd.Execute(context.Background(), session, []string{"cherry-pick", "--abort"})
+		assert.ErrorContains(t, err, "no cherry-pick or revert in progress")
+	})
+
+	t.Run("abort keeps local changes to other files", func(t *testing.T) {
+		session, r, picks, bHash := setup(t)
+		cmd := &CherryPickCommand{}
+		require.NoError(t, util.WriteFile(session.Filesystem, "other.txt", []byte("work in progress\n"), 0644))
+
+		// C is picked after A stops on its conflict, so its file must go too
+		_, err := cmd.Execute(context.Background(), session, []string{"cherry-pick", picks[1], picks[0]})
+		require.ErrorContains(t, err, "CONFLICT (content): Merge conflict in file.txt")
+		_, err = cmd.Execute(context.Background(), session, []string{"cherry-pick", "--abort"})
+		require.NoError(t, err)
+
+		head, _ := r.Head()
+		assert.Equal(t, bHash, head.Hash())
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "base\nchangeB\n", string(content))
+		_, err = session.Filesystem.Stat("c.txt")
+		assert.Error(t, err, "the picked file is gone")
+		content, _ = util.ReadFile(session.Filesystem, "other.txt")
+		assert.Equal(t, "work in progress\n", string(content), "the unrelated change survives the abort")
+	})
+
+	t.Run("refuses to overwrite local changes", func(t *testing.T) {
+		session, r, picks, bHash := setup(t)
+		cmd := &CherryPickCommand{}
+		require.NoError(t, util.WriteFile(session.Filesystem, "file.txt", []byte("base\nchangeB\nedited\n"), 0644))
+
+		_, err := cmd.Execute(context.Background(), session, []string{"cherry-pick", picks[0]})
+		require.ErrorContains(t, err, "error: your local changes would be overwritten by cherry-pick.")
+		assert.Nil(t, session.CherryPickInProgress())
+		head, _ := r.Head()
+		assert.Equal(t, bHash, head.Hash())
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "base\nchangeB\nedited\n", string(content))
+	})
+}
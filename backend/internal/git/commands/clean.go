@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
@@ -27,15 +28,32 @@ func (c *CleanCommand) executeClean(_ *git.Session, repo *gogit.Repository, opts
 	}
 
 	var candidates []string
-	for path, fStatus := range status {
-		if fStatus.Worktree == gogit.Untracked {
-			candidates = append(candidates, path)
+	if !opts.OnlyIgnored {
+		for path, fStatus := range status {
+			if fStatus.Worktree == gogit.Untracked {
+				candidates = append(candidates, path)
+			}
 		}
 	}
 
 	fs := w.Filesystem
 	var toRemoveFiles []string
 	uniqueDirs := make(map[string]bool)
+	var ignoredDirs []string
+
+	// -x / -X: ignored files too. Whole ignored directories are listed as "dir/"
+	// and, like untracked directories, only removed with -d.
+	if opts.Ignored || opts.OnlyIgnored {
+		for _, path := range git.LoadIgnoreRules(fs).IgnoredPaths(fs, git.TrackedPaths(repo)) {
+			if strings.HasSuffix(path, "/") {
+				if opts.Dir {
+					ignoredDirs = append(ignoredDirs, path)
+				}
+				continue
+			}
+			candidates = append(candidates, path)
+		}
+	}
 
 	for _, path := range candidates {
 		info, err := fs.Lstat(path)
@@ -80,6 +98,16 @@ func (c *CleanCommand) executeClean(_ *git.Session, repo *gogit.Repository, opts
 		sb.WriteString(fmt.Sprintf("%s %s\n", prefix, path))
 	}
 
+	for _, dir := range ignoredDirs {
+		prefix := "Removing"
+		if opts.DryRun {
+			prefix = "Would remove"
+		} else if err := util.RemoveAll(fs, strings.TrimSuffix(dir, "/")); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %v", dir, err)
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", prefix, dir))
+	}
+
 	// Remove Directories if opts.Dir
 	if opts.Dir {
 		var toRemoveDirs []string
@@ -128,10 +156,12 @@ type CleanCommand struct{}
 var _ git.Command = (*CleanCommand)(nil)
 
 type CleanOptions struct {
-	DryRun bool
-	Force  bool
-	Dir    bool
-	Args   []string
+	DryRun      bool
+	Force       bool
+	Dir         bool
+	Ignored     bool // -x: also remove files excluded by .gitignore
+	OnlyIgnored bool // -X: remove only files excluded by .gitignore
+	Args        []string
 }
 
 func (c *CleanCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -141,7 +171,7 @@ func (c *CleanCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "clean"), nil
 		}
 		return "", err
 	}
@@ -165,6 +195,10 @@ func (c *CleanCommand) parseArgs(args []string) (*CleanOptions, error) {
 			opts.Force = true
 		} else if arg == "-d" {
 			opts.Dir = true
+		} else if arg == "-x" {
+			opts.Ignored = true
+		} else if arg == "-X" {
+			opts.OnlyIgnored = true
 		} else if arg == "-h" || arg == "--help" {
 			return nil, fmt.Errorf("help requested")
 		} else if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
@@ -177,6 +211,10 @@ func (c *CleanCommand) parseArgs(args []string) (*CleanOptions, error) {
 					opts.Force = true
 				case 'd':
 					opts.Dir = true
+				case 'x':
+					opts.Ignored = true
+				case 'X':
+					opts.OnlyIgnored = true
 				default:
 					return nil, fmt.Errorf("unknown flag: -%c", char)
 				}
@@ -185,40 +223,26 @@ func (c *CleanCommand) parseArgs(args []string) (*CleanOptions, error) {
 			opts.Args = append(opts.Args, arg)
 		}
 	}
+	if opts.Ignored && opts.OnlyIgnored {
+		return nil, fmt.Errorf("fatal: -x and -X cannot be used together")
+	}
 	return opts, nil
 }
 
-func (c *CleanCommand) Help() string {
-	return `📘 GIT-CLEAN (1)                                        Git Manual
-
- 💡 DESCRIPTION
-    ・追跡されていないファイル（ゴミファイル）を削除する
-    ・ディレクトリをまとめて整理する
-    
-    誤って必要なファイルを消さないよう、通常は ` + "`" + `-f` + "`" + ` (force) が必須です。
-    まずは ` + "`" + `-n` + "`" + ` (dry-run) で何が消えるか確認することを推奨します。
-
- 📋 SYNOPSIS
-    git clean [-n] [-f] [-d]
-
- ⚙️  COMMON OPTIONS
-    -n, --dry-run
-        実際には削除せず、何が削除されるかを表示します。
-
-    -f, --force
-        実際に削除を実行します（必須オプション）。
-
-    -d
-        追跡されていないディレクトリも削除対象にします。
-
- 🛠  EXAMPLES
-    1. 何が消えるか確認（推奨）
-       $ git clean -n -d
-
-    2. 強制削除
-       $ git clean -f -d
+// Spec implements git.SpecProvider.
+func (c *CleanCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would be removed"},
+			{Flags: []string{"-f", "--force"}, Usage: "Remove the files"},
+			{Flags: []string{"-d"}, Usage: "Remove untracked directories too"},
+			{Flags: []string{"-x"}, Usage: "Remove ignored files too"},
+			{Flags: []string{"-X"}, Usage: "Remove only ignored files"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-clean
-`
+func (c *CleanCommand) Help() string {
+	return git.CommandHelp(context.Background(), "clean")
 }
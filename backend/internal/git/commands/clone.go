@@ -5,11 +5,13 @@ package commands
 // IMPORTANT: This implementation does NOT clone from real network URLs.
 // It looks up SharedRemotes (pre-ingested virtual remotes) or creates
 // a simulated remote from the URL. Objects are copied in-memory.
+// A bundle file in the session filesystem can be cloned as well.
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 
@@ -39,6 +41,7 @@ type CloneOptions struct {
 	Directory string
 	Depth     int
 	Branch    string
+	Sparse    bool
 }
 
 type cloneContext struct {
@@ -47,6 +50,9 @@ type cloneContext struct {
 	RemoteSt   storage.Storer
 	RemotePath string
 	RemoteURL  string // The original requested URL (for display/config)
+	Depth      int    // --depth: commits to copy per branch, 0 for all
+	Sparse     bool   // --sparse: check out only the top-level files
+	FromBundle bool   // The source is a bundle file
 }
 
 func (c *CloneCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -58,7 +64,7 @@ func (c *CloneCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "clone"), nil
 		}
 		return "", err
 	}
@@ -69,8 +75,9 @@ func (c *CloneCommand) Execute(ctx context.Context, s *git.Session, args []strin
 		return "", err
 	}
 
-	// 3. Perform Clone
-	return c.performClone(s, clCtx)
+	// 3. Perform Clone, keeping pushes from other sessions out until the copy is done
+	defer rlockRemote(s, clCtx.RemoteRepo)()
+	return c.performClone(ctx, s, clCtx)
 }
 
 func (c *CloneCommand) parseArgs(args []string) (*CloneOptions, error) {
@@ -91,6 +98,8 @@ func (c *CloneCommand) parseArgs(args []string) (*CloneOptions, error) {
 				}
 				opts.Depth = depth
 			}
+		case "--sparse":
+			opts.Sparse = true
 		case "-b", "--branch":
 			if i+1 < len(cmdArgs) {
 				i++
@@ -123,7 +132,7 @@ func (c *CloneCommand) resolveContext(s *git.Session, opts *CloneOptions) (*clon
 			return nil, fmt.Errorf("invalid url")
 		}
 		repoName = parts[len(parts)-1]
-		repoName = strings.TrimSuffix(repoName, ".git")
+		repoName = strings.TrimSuffix(strings.TrimSuffix(repoName, ".git"), ".bundle")
 	}
 
 	// SECURITY: Input Validation
@@ -143,6 +152,26 @@ func (c *CloneCommand) resolveContext(s *git.Session, opts *CloneOptions) (*clon
 	var remoteSt storage.Storer
 	var remotePath string
 
+	bundleRepo, bundle, err := openBundleRemote(s, opts.URL)
+	if bundle != nil {
+		if len(bundle.Prerequisites) > 0 {
+			return nil, fmt.Errorf("fatal: cannot clone from an incremental bundle: it needs %d commit(s) from another repository\nhint: Fetch it into a clone of that repository instead.", len(bundle.Prerequisites))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &cloneContext{
+			RepoName:   repoName,
+			RemoteRepo: bundleRepo,
+			RemoteSt:   bundleRepo.Storer,
+			RemotePath: sessionFilePath(s, opts.URL),
+			RemoteURL:  opts.URL,
+			Depth:      opts.Depth,
+			Sparse:     opts.Sparse,
+			FromBundle: true,
+		}, nil
+	}
+
 	if s.Manager != nil {
 		// Check SharedRemotes
 		if r, ok := s.Manager.GetSharedRemote(opts.URL); ok {
@@ -180,10 +209,12 @@ func (c *CloneCommand) resolveContext(s *git.Session, opts *CloneOptions) (*clon
 		RemoteSt:   remoteSt,
 		RemotePath: remotePath,
 		RemoteURL:  opts.URL,
+		Depth:      opts.Depth,
+		Sparse:     opts.Sparse,
 	}, nil
 }
 
-func (c *CloneCommand) performClone(s *git.Session, clCtx *cloneContext) (string, error) {
+func (c *CloneCommand) performClone(ctx context.Context, s *git.Session, clCtx *cloneContext) (string, error) {
 	// Create Local Working Copy
 	if errMkdir := s.Filesystem.MkdirAll(clCtx.RepoName, 0755); errMkdir != nil {
 		return "", fmt.Errorf("failed to create directory: %w", errMkdir)
@@ -205,19 +236,44 @@ func (c *CloneCommand) performClone(s *git.Session, clCtx *cloneContext) (string
 
 	localSt := filesystem.NewStorage(dotGitFS, cache.NewObjectLRUDefault())
 
-	// Perform Full Object Copy (No HybridStorer)
-	if err := c.copyObjects(clCtx.RemoteSt, localSt); err != nil {
-		return "", fmt.Errorf("failed to copy objects: %w", err)
-	}
+	var localRepo *gogit.Repository
+	if clCtx.Depth > 0 {
+		// Shallow: only the last commits of each branch; the quota is checked after checkout
+		localRepo, err = gogit.Init(localSt, repoFS)
+		if err != nil {
+			return "", fmt.Errorf("failed to init local repo: %w", err)
+		}
+		if err := c.copyShallow(clCtx.RemoteRepo, localRepo, clCtx.Depth); err != nil {
+			_ = s.RemoveAll(clCtx.RepoName)
+			return "", fmt.Errorf("failed to copy objects: %w", err)
+		}
+	} else {
+		// Perform Full Object Copy (No HybridStorer), stopping at the storage quota
+		usage := s.StorageUsage()
+		budget := func(objects int, blobBytes int64, files int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return usage.Check(objects, blobBytes, files)
+		}
+		if err := c.copyObjects(clCtx.RemoteSt, localSt, budget); err != nil {
+			_ = s.RemoveAll(clCtx.RepoName)
+			var quotaErr *git.QuotaError
+			if errors.As(err, &quotaErr) {
+				return "", err
+			}
+			return "", fmt.Errorf("failed to copy objects: %w", err)
+		}
 
-	localRepo, err := gogit.Init(localSt, repoFS)
-	if err != nil {
-		return "", fmt.Errorf("failed to init local repo: %w", err)
+		localRepo, err = gogit.Init(localSt, repoFS)
+		if err != nil {
+			return "", fmt.Errorf("failed to init local repo: %w", err)
+		}
 	}
 
 	// Copy References
 	if err := c.copyReferences(localRepo, clCtx.RemoteRepo); err != nil {
-		log.Printf("Clone: Warning - Issue copying references: %v", err)
+		slog.WarnContext(ctx, "clone: copying references failed", "err", err)
 	}
 
 	// Configure Origin
@@ -234,21 +290,85 @@ func (c *CloneCommand) performClone(s *git.Session, clCtx *cloneContext) (string
 	if err == nil {
 		cfg.Raw.Section("remote").Subsection("origin").AddOption("displayurl", clCtx.RemoteURL)
 		if err := localRepo.Storer.SetConfig(cfg); err != nil {
-			log.Printf("Clone: Warning - failed to set display URL config: %v", err)
+			slog.WarnContext(ctx, "clone: setting display URL failed", "err", err)
 		}
 	}
 
 	s.Repos[clCtx.RepoName] = localRepo
 
 	// Auto-cd
+	prevDir := s.CurrentDir
 	s.CurrentDir = "/" + clCtx.RepoName
 
 	// Checkout Default Branch
 	if err := c.checkoutDefaultBranch(localRepo, clCtx.RemoteRepo); err != nil {
-		log.Printf("Clone: Warning - Checkout default branch issue: %v", err)
+		slog.WarnContext(ctx, "clone: checking out default branch failed", "err", err)
+	}
+
+	// A sparse clone keeps only the top-level files in the working tree
+	if clCtx.Sparse {
+		if _, err := git.SetSparseCheckout(localRepo, nil); err != nil {
+			slog.WarnContext(ctx, "clone: sparse checkout failed", "err", err)
+		}
+	}
+
+	// The checked-out files count towards the quota too
+	if err := s.CheckStorageQuota(0, 0, 0); err != nil {
+		delete(s.Repos, clCtx.RepoName)
+		_ = s.RemoveAll(clCtx.RepoName)
+		s.CurrentDir = prevDir
+		return "", err
+	}
+
+	source := "Using shared remote"
+	if clCtx.FromBundle {
+		source = "From bundle " + clCtx.RemoteURL
+	}
+	if clCtx.Depth > 0 {
+		return fmt.Sprintf("Cloned into '%s'... (%s, shallow: last %d commit(s) per branch)", clCtx.RepoName, source, clCtx.Depth), nil
+	}
+	return fmt.Sprintf("Cloned into '%s'... (%s)", clCtx.RepoName, source), nil
+}
+
+// copyShallow copies the last depth commits of every branch of remote, and the
+// tags pointing into them, then records the shallow boundary in .git/shallow.
+func (c *CloneCommand) copyShallow(remote, local *gogit.Repository, depth int) error {
+	refs, err := remote.References()
+	if err != nil {
+		return err
 	}
+	var tips []plumbing.Hash
+	var tags []*plumbing.Reference
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		switch {
+		case ref.Type() != plumbing.HashReference:
+		case ref.Name().IsBranch():
+			tips = append(tips, ref.Hash())
+		case ref.Name().IsTag():
+			tags = append(tags, ref)
+		}
+		return nil
+	})
 
-	return fmt.Sprintf("Cloned into '%s'... (Using shared remote)", clCtx.RepoName), nil
+	boundary, err := git.CopyCommitsShallow(remote, local, tips, depth)
+	if err != nil {
+		return err
+	}
+	// Annotated tags come along when the commit they point at was copied
+	for _, ref := range tags {
+		tag, err := remote.TagObject(ref.Hash())
+		if err != nil || !git.HasObject(local, tag.Target) {
+			continue
+		}
+		obj, err := remote.Storer.EncodedObject(plumbing.TagObject, ref.Hash())
+		if err != nil {
+			return err
+		}
+		if _, err := local.Storer.SetEncodedObject(obj); err != nil {
+			return err
+		}
+	}
+	return local.Storer.SetShallow(boundary)
 }
 
 func (c *CloneCommand) copyReferences(local *gogit.Repository, remote *gogit.Repository) error {
@@ -258,6 +378,10 @@ func (c *CloneCommand) copyReferences(local *gogit.Repository, remote *gogit.Rep
 	}
 	return refs.ForEach(func(ref *plumbing.Reference) error {
 		name := ref.Name()
+		if ref.Type() == plumbing.HashReference && !git.HasObject(local, ref.Hash()) {
+			// Left out by a shallow clone
+			return nil
+		}
 		if name.IsBranch() {
 			newRefName := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/origin/%s", name.Short()))
 			newRef := plumbing.NewHashReference(newRefName, ref.Hash())
@@ -291,6 +415,7 @@ func (c *CloneCommand) checkoutDefaultBranch(local *gogit.Repository, remote *go
 	if ref, err := local.Reference(remoteRefName, true); err == nil {
 		newBranchRef := plumbing.NewHashReference(targetBranch, ref.Hash())
 		_ = local.Storer.SetReference(newBranchRef)
+		_ = git.SetUpstream(local, shortName, "origin", shortName)
 		return w.Checkout(&gogit.CheckoutOptions{
 			Branch: targetBranch,
 			Force:  true,
@@ -299,51 +424,41 @@ func (c *CloneCommand) checkoutDefaultBranch(local *gogit.Repository, remote *go
 	return fmt.Errorf("could not resolve default branch '%s'", shortName)
 }
 
-func (c *CloneCommand) copyObjects(src storage.Storer, dst storage.Storer) error {
+// copyObjects copies every object of src into dst. budget is called with the
+// running totals before each object is stored and stops the copy on error.
+func (c *CloneCommand) copyObjects(src storage.Storer, dst storage.Storer, budget func(objects int, blobBytes int64, files int) error) error {
 	// iterate all objects
 	iter, err := src.IterEncodedObjects(plumbing.AnyObject)
 	if err != nil {
 		return err
 	}
 
+	objects, blobBytes := 0, int64(0)
 	return iter.ForEach(func(obj plumbing.EncodedObject) error {
+		objects++
+		if obj.Type() == plumbing.BlobObject {
+			blobBytes += obj.Size()
+		}
+		if err := budget(objects, blobBytes, 0); err != nil {
+			return err
+		}
 		_, err := dst.SetEncodedObject(obj)
 		return err
 	})
 }
 
-func (c *CloneCommand) Help() string {
-	return `📘 GIT-CLONE (1)                                        Git Manual
-
- 💡 DESCRIPTION
-    ・リモートリポジトリを複製して、手元にローカルリポジトリを作成します。
-    ・GitGymでは事前定義されたリポジトリURLのみサポートしています。
-
- 📋 SYNOPSIS
-    git clone [options] <url> [<directory>]
-
- ⚙️  OPTIONS
-    -b <branch>, --branch <branch>
-        クローン後に指定したブランチをチェックアウトします。
-
-    --depth <depth>
-        指定した数のコミットのみを取得します（シャロークローン）。
-        GitGymでは参考情報として受け付けますが、全オブジェクトがコピーされます。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: リポジトリをクローン
-       $ git clone https://github.com/org/repo.git
-
-    2. 実践: ディレクトリ名を指定してクローン
-       $ git clone https://github.com/org/repo.git my-project
-
-    3. 特定のブランチをクローン
-       $ git clone -b develop https://github.com/org/repo.git
-
-    4. シャロークローン（履歴を制限）
-       $ git clone --depth 1 https://github.com/org/repo.git
+// Spec implements git.SpecProvider.
+func (c *CloneCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-b", "--branch"}, Arg: git.ArgText, Usage: "Check out this branch"},
+			{Flags: []string{"--depth"}, Arg: git.ArgText, Usage: "Clone only the last n commits"},
+			{Flags: []string{"--sparse"}, Usage: "Start with a sparse checkout"},
+		},
+		Args: []string{git.ArgText, git.ArgPath},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-clone
-`
+func (c *CloneCommand) Help() string {
+	return git.CommandHelp(context.Background(), "clone")
 }
@@ -89,3 +89,24 @@ func TestClone_CustomDefaultBranch(t *testing.T) {
 	_, err = wt.Filesystem.Stat("README.md")
 	assert.NoError(t, err, "README.md should be checked out")
 }
+
+// TestClone_InitializedBareRepo verifies that a repository created with a
+// README clones onto its default branch with the file checked out.
+func TestClone_InitializedBareRepo(t *testing.T) {
+	t.Setenv("GITGYM_DATA_ROOT", t.TempDir())
+	sm := git.NewSessionManager()
+	s, err := sm.CreateSession("test-clone-initialized")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepositoryWith(context.Background(), s.ID, "starter", git.BareRepoOptions{README: true, DefaultBranch: "trunk"}))
+
+	_, err = (&CloneCommand{}).Execute(context.Background(), s, []string{"clone", "remote://gitgym/starter.git"})
+	require.NoError(t, err)
+
+	repo := s.Repos["starter"]
+	require.NotNil(t, repo)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.NewBranchReferenceName("trunk"), head.Name())
+	_, err = s.Filesystem.Stat("/starter/README.md")
+	assert.NoError(t, err)
+}
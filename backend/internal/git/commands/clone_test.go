@@ -2,10 +2,15 @@ package commands
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
@@ -54,3 +59,76 @@ func TestCloneCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestCloneDepth_CopiesLastCommitsAndUnshallows(t *testing.T) {
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-clone-depth")
+	ctx := context.Background()
+	url := "https://github.com/example/history.git"
+
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	w, _ := remote.Worktree()
+	for i := 1; i <= 4; i++ {
+		if err := util.WriteFile(w.Filesystem, "file.txt", []byte(fmt.Sprintf("v%d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w.Add("file.txt")
+		hash, err := w.Commit(fmt.Sprintf("commit %d", i), &gogit.CommitOptions{
+			Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			remote.CreateTag("v0.1", hash, nil)
+		}
+		if i == 4 {
+			remote.CreateTag("v1.0", hash, &gogit.CreateTagOptions{Message: "release", Tagger: &object.Signature{Name: "Dev", When: time.Now()}})
+		}
+	}
+	sm.SharedRemotes[url] = remote
+
+	run := func(input string) string {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return result.Stdout
+	}
+
+	run("git clone --depth 2 " + url)
+	repo := s.Repos["history"]
+	out := run("git log --format=%s%d")
+	if strings.Count(strings.TrimSpace(out), "\n")+1 != 2 || !strings.Contains(out, "commit 3") || strings.Contains(out, "commit 2") {
+		t.Errorf("Expected the last 2 commits only, got:\n%s", out)
+	}
+	if !strings.Contains(out, "grafted") {
+		t.Errorf("Expected the shallow boundary to be decorated, got:\n%s", out)
+	}
+	if _, err := repo.Tag("v1.0"); err != nil {
+		t.Errorf("Expected the tag inside the shallow history: %v", err)
+	}
+	if _, err := repo.Tag("v0.1"); err == nil {
+		t.Error("Expected the tag outside the shallow history to be left out")
+	}
+	if len(git.ShallowBoundary(repo)) != 1 {
+		t.Errorf("Expected one shallow commit, got %d", len(git.ShallowBoundary(repo)))
+	}
+	if out := run("git fsck"); strings.Contains(out, "missing") {
+		t.Errorf("Expected fsck to accept the shallow boundary, got:\n%s", out)
+	}
+
+	run("git fetch --unshallow")
+	out = run("git log --format=%s%d")
+	if strings.Count(strings.TrimSpace(out), "\n")+1 != 4 || strings.Contains(out, "grafted") {
+		t.Errorf("Expected the full history after --unshallow, got:\n%s", out)
+	}
+	if len(git.ShallowBoundary(repo)) != 0 {
+		t.Error("Expected the repository to be complete")
+	}
+	name, args := git.ParseCommand("git fetch --unshallow")
+	if _, err := git.Dispatch(ctx, s, name, args); err == nil {
+		t.Error("Expected --unshallow on a complete repository to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollaborativeSessions(t *testing.T) {
+	sm := git.NewSessionManager()
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remote.Worktree()
+	require.NoError(t, util.WriteFile(rw.Filesystem, "README.md", []byte("shared\n"), 0644))
+	_, _ = rw.Add("README.md")
+	_, err := rw.Commit("Initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+	sm.SharedRemotes["origin"] = remote
+
+	ctx := context.Background()
+	join := func(id, name string) *git.Session {
+		s, _ := sm.CreateSession(id)
+		require.NoError(t, sm.SetSessionUser(s, git.UserIdentity{Name: name}))
+		_, err := (&CloneCommand{}).Execute(ctx, s, []string{"clone", "origin", "project"})
+		require.NoError(t, err)
+		s.CurrentDir = "/project"
+		return s
+	}
+	run := func(s *git.Session, cmd git.Command, args ...string) (string, error) {
+		return cmd.Execute(ctx, s, args)
+	}
+	edit := func(s *git.Session, content, msg string) {
+		w, _ := s.GetRepo().Worktree()
+		require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte(content), 0644))
+		_, err := run(s, &AddCommand{}, "add", "README.md")
+		require.NoError(t, err)
+		_, err = run(s, &CommitCommand{}, "commit", "-m", msg)
+		require.NoError(t, err)
+	}
+
+	alice := join("alice-session", "Alice")
+	bob := join("bob-session", "Bob Smith")
+	assert.Equal(t, "bob-smith@example.com", bob.Identity().Email)
+
+	// Both change master; Alice pushes first
+	edit(alice, "alice\n", "Alice's change")
+	edit(bob, "bob\n", "Bob's change")
+	_, err = run(alice, &PushCommand{}, "push", "origin", "master")
+	require.NoError(t, err)
+
+	head, _ := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	tip, _ := remote.CommitObject(head.Hash())
+	assert.Equal(t, "Alice", tip.Author.Name, "commits are attributed to the session's user")
+	userRef, err := remote.Reference("refs/users/alice/master", true)
+	require.NoError(t, err, "the remote tracks what each user pushed")
+	assert.Equal(t, head.Hash(), userRef.Hash())
+
+	// Bob's push collides with Alice's
+	_, err = run(bob, &PushCommand{}, "push", "origin", "master")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "non-fast-forward")
+	assert.Contains(t, err.Error(), "Alice <alice@example.com> pushed "+head.Hash().String()[:7])
+
+	presence, err := sm.Presence("origin")
+	require.NoError(t, err)
+	require.Len(t, presence.Collaborators, 2)
+	assert.Equal(t, "Alice", presence.Collaborators[0].User.Name)
+	assert.Equal(t, "master", presence.Collaborators[0].Branch)
+	assert.Equal(t, head.Hash().String(), presence.Collaborators[0].Pushed["master"])
+	assert.Empty(t, presence.Collaborators[1].Pushed)
+	require.Len(t, presence.Conflicts, 1)
+	c := presence.Conflicts[0]
+	assert.Equal(t, "origin", c.Remote)
+	assert.Equal(t, "master", c.Branch)
+	assert.Equal(t, "Bob Smith", c.User.Name)
+	assert.Equal(t, "Alice", c.PushedBy.Name)
+
+	// A user's own earlier push is not a collision with someone else
+	edit(alice, "alice again\n", "Amend-free follow up")
+	_, err = run(alice, &PushCommand{}, "push", "origin", "master")
+	require.NoError(t, err)
+	if _, err := sm.Presence("upstream"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected unknown remotes to be reported, got %v", err)
+	}
+}
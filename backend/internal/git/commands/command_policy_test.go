@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestCommandPolicy_ForbidsFlagInAnySpelling(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-command-policy")
+	ctx := context.Background()
+	s.CommandPolicy = &git.CommandPolicy{Forbid: []string{"git branch --force"}, Reason: "Moving branches by force loses work."}
+
+	run := func(line string) error {
+		name, args := git.ParseCommand(line)
+		_, err := git.Dispatch(ctx, s, name, args)
+		return err
+	}
+	for _, line := range []string{"git branch --force main HEAD", "git branch -f main HEAD"} {
+		err := run(line)
+		if err == nil || !strings.Contains(err.Error(), "'--force' cannot be used with 'git branch'") || !strings.Contains(err.Error(), "hint: Moving branches by force loses work.") {
+			t.Errorf("%s: expected the policy to refuse, got %v", line, err)
+		}
+	}
+	if err := run("git branch feature"); err != nil {
+		t.Errorf("branch without --force should run: %v", err)
+	}
+}
+
+func TestCommandPolicy_AllowList(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-command-policy-allow")
+	ctx := context.Background()
+	s.CommandPolicy = &git.CommandPolicy{Allow: []string{"status", "git add", "commit"}}
+
+	run := func(line string) (*git.CommandResult, error) {
+		name, args := git.ParseCommand(line)
+		return git.Dispatch(ctx, s, name, args)
+	}
+	result, err := run("git switch -c feature")
+	if err == nil || !strings.Contains(err.Error(), "'git switch' is not available in this mission.") || !strings.Contains(err.Error(), "Commands you can use here: status, git add, commit") {
+		t.Fatalf("expected switch to be refused, got %v", err)
+	}
+	if result.ExitCode != git.ExitError {
+		t.Errorf("expected exit code %d, got %d", git.ExitError, result.ExitCode)
+	}
+	if head, _ := s.GetRepo().Head(); head.Name().Short() != "main" {
+		t.Error("a refused command must not run")
+	}
+
+	// Shell commands stay available so the lesson remains playable
+	for _, line := range []string{"touch notes.txt", "git add notes.txt", "git status", "git commit -m 'Add notes'"} {
+		if _, err := run(line); err != nil {
+			t.Errorf("%s: %v", line, err)
+		}
+	}
+}
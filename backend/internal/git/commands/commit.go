@@ -3,15 +3,26 @@ package commands
 // commit.go - Simulated Git Commit Command
 //
 // Records changes to the repository by creating a new commit object.
-// Supports -m (message), --amend, and --allow-empty flags.
+// Supports -m (message), -a (stage tracked changes), --amend, --allow-empty,
+// --author, --date, --reset-author, --no-verify and -S (simulated signing) flags.
+//
+// Like git, --amend keeps the author and author date of the amended commit and
+// only makes the session's user the committer, unless --reset-author is given.
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -24,10 +35,24 @@ type CommitCommand struct{}
 // Ensure CommitCommand implements git.Command
 var _ git.Command = (*CommitCommand)(nil)
 
+// Ensure CommitCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*CommitCommand)(nil)
+
+// SupportsDryRun marks commit as simulated by the engine on --dry-run.
+func (c *CommitCommand) SupportsDryRun() {}
+
 type CommitOptions struct {
-	Message    string
-	Amend      bool
-	AllowEmpty bool
+	Message     string
+	HasMessage  bool // -m was given, or the message comes from the editor
+	NoEdit      bool // --no-edit: keep the amended or prepared merge message
+	Amend       bool
+	AllowEmpty  bool
+	All         bool   // -a / --all: stage modified and deleted tracked files first
+	Author      string // --author: "Name <email>", or a pattern matching an earlier author
+	Date        string // --date: author date
+	ResetAuthor bool   // --reset-author: the amended commit becomes the session user's
+	NoVerify    bool   // -n / --no-verify: skip the commit.lint check; hooks are not simulated
+	Sign        *bool  // -S / --no-gpg-sign; nil follows commit.gpgsign
 }
 
 type commitContext struct {
@@ -35,6 +60,8 @@ type commitContext struct {
 	repo        *gogit.Repository
 	message     string
 	amendCommit *object.Commit
+	author      *object.Signature // --author: name and email replacing the default author
+	date        *time.Time        // --date: replaces the default author date
 }
 
 func (c *CommitCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -45,59 +72,245 @@ func (c *CommitCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "commit"), nil
 		}
 		return "", err
 	}
+	if msg, ok := git.EditedMessage(ctx); ok {
+		opts.Message, opts.HasMessage = msg, true
+	}
+	openEditor := !opts.HasMessage && !opts.NoEdit && git.EditorAvailable(ctx)
 
 	repo := s.GetRepo()
 	if repo == nil {
 		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
 	}
 
+	// A commit adds at least a tree and a commit object
+	if err := s.CheckStorageQuota(2, 0, 0); err != nil {
+		return "", err
+	}
+
+	if !opts.All {
+		return c.commit(ctx, s, repo, opts, openEditor)
+	}
+
+	// Like git, commit -a leaves the index as it was when no commit is made:
+	// on a rejected message, an error or a trip to the editor
+	saved, err := copyIndex(repo)
+	if err != nil {
+		return "", err
+	}
+	out, err := "", stageTrackedChanges(repo)
+	if err == nil {
+		out, err = c.commit(ctx, s, repo, opts, openEditor)
+	}
+	if err != nil {
+		if restoreErr := repo.Storer.SetIndex(saved); restoreErr != nil {
+			return "", fmt.Errorf("%v\nerror: could not restore the index: %v", err, restoreErr)
+		}
+		return "", err
+	}
+	return out, nil
+}
+
+// commit creates the commit once the command line is parsed, concluding a
+// stopped merge or going through the editor when that is what it takes.
+func (c *CommitCommand) commit(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *CommitOptions, openEditor bool) (string, error) {
+	// A merge stopped on conflicts is concluded by the next commit
+	if s.MergeInProgress() != nil {
+		if opts.Amend {
+			return "", fmt.Errorf("fatal: You are in the middle of a merge -- cannot amend.")
+		}
+		if openEditor {
+			return "", mergeEditorRequest(s.MergeInProgress())
+		}
+		w, err := repo.Worktree()
+		if err != nil {
+			return "", err
+		}
+		commitHash, err := concludeMerge(s, repo, opts.Message)
+		if err != nil {
+			return "", err
+		}
+		return prependLines(git.RerereRecordResolutions(s, repo, w), fmt.Sprintf("Commit created: %s", commitHash.String())), nil
+	}
+
 	// 2. Resolve
-	cCtx, err := c.resolveContext(repo, opts, args)
+	cCtx, err := c.resolveContext(repo, opts)
 	if err != nil {
+		if openEditor && err == errNoCommitMessage {
+			return "", commitEditorRequest(repo, "")
+		}
 		return "", err
 	}
+	if openEditor && opts.Amend {
+		return "", commitEditorRequest(repo, cCtx.message)
+	}
+	if !opts.NoVerify {
+		if err := git.LintCommitMessage(repo, cCtx.message); err != nil {
+			return "", fmt.Errorf("%v\nhint: Fix the message and commit again, or skip the check with 'git commit --no-verify'.", err)
+		}
+	}
 
 	// 3. Perform
 	return c.performAction(s, cCtx, opts)
 }
 
 func (c *CommitCommand) parseArgs(args []string) (*CommitOptions, error) {
-	opts := &CommitOptions{
-		// Message: "", // Default is empty
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	// Standard git treats positional args as file paths, but we don't support that.
+	// Even if we did, "git commit --amend <text>" is usually an error (text interpreted as path).
+	if extra := append(parsed.Positional, parsed.Rest...); len(extra) > 0 {
+		return nil, fmt.Errorf("unknown argument or option: '%s'. Did you mean to use -m for message?", extra[0])
 	}
 
-	for i := 1; i < len(args); i++ {
-		arg := args[i]
-		switch arg {
-		case "-h", "--help":
-			return nil, fmt.Errorf("help requested")
+	opts := &CommitOptions{}
+	for _, f := range parsed.Flags {
+		switch f.Name {
 		case "-m":
-			if i+1 < len(args) {
-				opts.Message = args[i+1]
-				i++
-			}
+			opts.Message = f.Value
+			opts.HasMessage = true
+		case "-a":
+			opts.All = true
 		case "--amend":
 			opts.Amend = true
 		case "--allow-empty":
 			opts.AllowEmpty = true
+		case "--author":
+			opts.Author = f.Value
+		case "--date":
+			opts.Date = f.Value
+		case "--reset-author":
+			opts.ResetAuthor = true
+		case "-n":
+			opts.NoVerify = true
+		case "-S":
+			sign := true
+			opts.Sign = &sign
+		case "--no-gpg-sign":
+			sign := false
+			opts.Sign = &sign
 		case "--no-edit":
-			// Shim: In GitGym, amending without -m automatically behaves like --no-edit
-			// We just accept the flag to avoid error.
-		default:
-			// Reject positional arguments or unknown flags
-			// Standard git treats positional args as file paths, but we don't fully support that yet.
-			// Even if we did, "git commit --amend <text>" is usually an error (text interpreted as path).
-			return nil, fmt.Errorf("unknown argument or option: '%s'. Did you mean to use -m for message?", arg)
+			// Without an editor, amending without -m behaves like --no-edit anyway
+			opts.NoEdit = true
 		}
 	}
+	if opts.ResetAuthor && !opts.Amend {
+		return nil, fmt.Errorf("fatal: --reset-author can be used only with -C, -c or --amend.")
+	}
 	return opts, nil
 }
 
-func (c *CommitCommand) resolveContext(repo *gogit.Repository, opts *CommitOptions, originalArgs []string) (*commitContext, error) {
+// copyIndex returns a copy of the index that stays untouched while the index
+// changes, to put back with SetIndex.
+func copyIndex(repo *gogit.Repository) (*index.Index, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := index.NewEncoder(&buf).Encode(idx); err != nil {
+		return nil, err
+	}
+	copied := &index.Index{}
+	if err := index.NewDecoder(&buf).Decode(copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// stageTrackedChanges stages the modified and deleted tracked files, as
+// commit -a does. New files stay untracked.
+func stageTrackedChanges(repo *gogit.Repository) error {
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(status))
+	for path := range status {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	before := git.IndexHashes(repo)
+	for _, path := range paths {
+		switch status[path].Worktree {
+		case gogit.Modified:
+			_, err = w.Add(path)
+		case gogit.Deleted:
+			_, err = w.Remove(path)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = git.NormalizeLineEndings(repo, w, before)
+	return err
+}
+
+var commitAuthorIdent = regexp.MustCompile(`^\s*([^<>]*?)\s*<([^<>]*)>\s*$`)
+
+// resolveCommitAuthor turns --author into a signature. A value that is not
+// "Name <email>" names the most recent author it is a substring of, like git.
+func resolveCommitAuthor(repo *gogit.Repository, value string) (*object.Signature, error) {
+	if m := commitAuthorIdent.FindStringSubmatch(value); m != nil && m[1] != "" {
+		return &object.Signature{Name: m[1], Email: m[2]}, nil
+	}
+	var found *object.Signature
+	if value != "" {
+		if iter, err := repo.Log(&gogit.LogOptions{All: true, Order: gogit.LogOrderCommitterTime}); err == nil {
+			_ = iter.ForEach(func(commit *object.Commit) error {
+				if strings.Contains(fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email), value) {
+					found = &object.Signature{Name: commit.Author.Name, Email: commit.Author.Email}
+					return storer.ErrStop
+				}
+				return nil
+			})
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("fatal: --author '%s' is not 'Name <email>' and matches no existing author", value)
+	}
+	return found, nil
+}
+
+var commitUnixDate = regexp.MustCompile(`^@?(\d+)(?:\s+([+-]\d{4}))?$`)
+
+// parseCommitDate reads a --date value: git's internal "@<unix> <zone>" and
+// default formats, RFC 2822, or anything --since accepts.
+func parseCommitDate(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if m := commitUnixDate.FindStringSubmatch(v); m != nil && (strings.HasPrefix(v, "@") || m[2] != "") {
+		sec, _ := strconv.ParseInt(m[1], 10, 64)
+		t := time.Unix(sec, 0)
+		if m[2] != "" {
+			if zone, err := time.Parse("-0700", m[2]); err == nil {
+				t = t.In(zone.Location())
+			}
+		}
+		return t, nil
+	}
+	for _, layout := range []string{"Mon Jan 2 15:04:05 2006 -0700", time.RFC1123Z, "2 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return parseLogDate(v, now)
+}
+
+// errNoCommitMessage is returned for a new commit without a message.
+var errNoCommitMessage = fmt.Errorf("message is required. Use -m \"message\"")
+
+func (c *CommitCommand) resolveContext(repo *gogit.Repository, opts *CommitOptions) (*commitContext, error) {
 	w, err := repo.Worktree()
 	if err != nil {
 		return nil, err
@@ -120,15 +333,7 @@ func (c *CommitCommand) resolveContext(repo *gogit.Repository, opts *CommitOptio
 		ctx.amendCommit = headCommit
 
 		// Handle message reuse for amend
-		isMsgProvided := false
-		for _, arg := range originalArgs {
-			if arg == "-m" {
-				isMsgProvided = true
-				break
-			}
-		}
-
-		if isMsgProvided {
+		if opts.HasMessage {
 			ctx.message = opts.Message
 		} else {
 			ctx.message = headCommit.Message
@@ -136,19 +341,47 @@ func (c *CommitCommand) resolveContext(repo *gogit.Repository, opts *CommitOptio
 	} else {
 		// Normal Commit: Message is REQUIRED
 		if opts.Message == "" {
-			return nil, fmt.Errorf("message is required. Use -m \"message\"")
+			return nil, errNoCommitMessage
 		}
 		ctx.message = opts.Message
 	}
 
+	if opts.Author != "" {
+		if ctx.author, err = resolveCommitAuthor(repo, opts.Author); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Date != "" {
+		date, err := parseCommitDate(opts.Date, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		ctx.date = &date
+	}
+
 	return ctx, nil
 }
 
 func (c *CommitCommand) performAction(s *git.Session, ctx *commitContext, opts *CommitOptions) (string, error) {
 	var commitOpts gogit.CommitOptions
-	commitOpts.Author = git.GetDefaultSignature()
+	commitOpts.Author = s.Signature()
+	commitOpts.Committer = s.Signature()
+	if opts.Amend && !opts.ResetAuthor {
+		author := ctx.amendCommit.Author
+		commitOpts.Author = &author
+	}
+	if ctx.author != nil {
+		commitOpts.Author.Name, commitOpts.Author.Email = ctx.author.Name, ctx.author.Email
+	}
+	if ctx.date != nil {
+		commitOpts.Author.When = *ctx.date
+	}
 	commitOpts.AllowEmptyCommits = opts.AllowEmpty
 
+	if shouldSignCommit(ctx.repo, opts.Sign) {
+		commitOpts.Signer = s.SigningKey()
+	}
+
 	actionLabel := "commit"
 
 	if opts.Amend {
@@ -167,53 +400,85 @@ func (c *CommitCommand) performAction(s *git.Session, ctx *commitContext, opts *
 	}
 
 	s.RecordReflog(fmt.Sprintf("%s: %s", actionLabel, strings.Split(ctx.message, "\n")[0]))
+	if opts.Amend {
+		s.RecordLineage(ctx.amendCommit.Hash, commitHash, git.RewriteAmend)
+	}
 
 	if opts.Amend {
 		return fmt.Sprintf("Commit amended: %s", commitHash.String()), nil
 	}
-	return fmt.Sprintf("Commit created: %s", commitHash.String()), nil
+	// A commit concludes the conflicts a revert stopped on, say
+	return prependLines(git.RerereRecordResolutions(s, ctx.repo, ctx.w), fmt.Sprintf("Commit created: %s", commitHash.String())), nil
 }
 
-func (c *CommitCommand) Help() string {
-	return `📘 GIT-COMMIT (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ・ステージングエリアにある変更を記録する（セーブする）
-    ・変更内容にメッセージを付けて保存する
-
- 📋 SYNOPSIS
-    git commit -m <msg> [--amend] [--allow-empty]
-
- ⚙️  COMMON OPTIONS
-    -m <msg>
-        コミットメッセージを指定します。
-
-    --amend
-        直前のコミットを修正します（メッセージの変更や、ファイルの追加忘れ等）。
-        ※ Push済みのコミットに対して行うと履歴が壊れるため、Push前だけに行いましょう。
-
-    --allow-empty
-        変更が含まれていなくてもコミットを作成できるようにします。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: メッセージ付きでコミット
-       1コミットにつき1つの論点（変更理由）になるよう意識するのがコツです。
-       $ git commit -m "feat: add user endpoint"
-
-    2. 実践: 直前のコミットを修正 (Recommended)
-       「あっ、メッセージ間違えた！」という時に使います。
-       Push前であれば、履歴を汚さずにこっそり直せます。
-       $ git commit --amend -m "fix: typo in endpoint"
+// shouldSignCommit reports whether a commit gets signed: -S or --no-gpg-sign
+// when given, otherwise the commit.gpgsign setting.
+func shouldSignCommit(repo *gogit.Repository, flag *bool) bool {
+	if flag != nil {
+		return *flag
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	sign, err := strconv.ParseBool(cfg.Raw.Section("commit").Option("gpgsign"))
+	return err == nil && sign
+}
 
-    3. 実践: ファイルの入れ忘れを修正 + メッセージ変更
-       ファイルを追加し忘れた場合も --amend で修正できます。
-       $ git add forgotten_file.go
-       $ git commit --amend -m "fix: add user endpoint"
+// Spec implements git.SpecProvider.
+func (c *CommitCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-m", "--message"}, Arg: git.ArgText, Usage: "Commit message"},
+			{Flags: []string{"-a", "--all"}, Usage: "Stage modified and deleted tracked files first"},
+			{Flags: []string{"--amend"}, Usage: "Replace the last commit"},
+			{Flags: []string{"--no-edit"}, Usage: "Keep the message when amending"},
+			{Flags: []string{"--allow-empty"}, Usage: "Commit with no changes"},
+			{Flags: []string{"--author"}, Arg: git.ArgText, Usage: "Override the author (\"Name <email>\")"},
+			{Flags: []string{"--date"}, Arg: git.ArgText, Usage: "Override the author date"},
+			{Flags: []string{"--reset-author"}, Usage: "Make yourself the author when amending"},
+			{Flags: []string{"-n", "--no-verify"}, Usage: "Skip the commit.lint check (hooks never run here)"},
+			{Flags: []string{"-S", "--gpg-sign"}, Usage: "Sign the commit"},
+			{Flags: []string{"--no-gpg-sign"}, Usage: "Do not sign the commit"},
+		},
+	}
+}
 
-       (メッセージはそのままで良い場合)
-       $ git commit --amend --no-edit
+func (c *CommitCommand) Help() string {
+	return git.CommandHelp(context.Background(), "commit")
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-commit
-`
+// commitEditorRequest asks for a commit message, starting from message (the
+// amended commit's) and listing the staged changes like git's template.
+func commitEditorRequest(repo *gogit.Repository, message string) error {
+	lines := []string{
+		"Please enter the commit message for your changes. Lines starting",
+		"with '#' will be ignored, and an empty message aborts the commit.",
+		"",
+	}
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		lines = append(lines, "On branch "+head.Name().Short())
+	} else if err == nil {
+		lines = append(lines, "HEAD detached at "+head.Hash().String()[:7])
+	}
+	if w, err := repo.Worktree(); err == nil {
+		if status, err := w.Status(); err == nil {
+			var staged []string
+			for path, st := range status {
+				if st.Staging != gogit.Unmodified && st.Staging != gogit.Untracked {
+					staged = append(staged, fmt.Sprintf("\t%-12s%s", mapStatus(st.Staging), path))
+				}
+			}
+			if len(staged) > 0 {
+				sort.Strings(staged)
+				lines = append(lines, "Changes to be committed:")
+				lines = append(lines, staged...)
+			}
+		}
+	}
+	return &git.EditorRequest{
+		File:     git.CommitEditFile,
+		Template: strings.TrimRight(message, "\n") + "\n\n" + git.CommentLines(lines...),
+		Abort:    "Aborting commit due to empty commit message.",
+	}
 }
@@ -90,3 +90,197 @@ func TestCommitCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestCommitAmend_RecordsLineage(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-amend-lineage")
+	repo := s.GetRepo()
+
+	before, _ := repo.Head()
+	cmd := &CommitCommand{}
+	if _, err := cmd.Execute(context.Background(), s, []string{"commit", "--amend", "-m", "reworded"}); err != nil {
+		t.Fatalf("Amend failed: %v", err)
+	}
+	after, _ := repo.Head()
+
+	state, err := sm.GetGraphState("test-amend-lineage")
+	if err != nil {
+		t.Fatalf("GetGraphState failed: %v", err)
+	}
+
+	var sawNew, sawOld bool
+	for _, c := range state.Commits {
+		switch c.ID {
+		case after.Hash().String():
+			sawNew = true
+			if c.Replaces != before.Hash().String() || c.RewriteKind != git.RewriteAmend {
+				t.Errorf("Expected amended commit to replace %s, got replaces=%q kind=%q", before.Hash(), c.Replaces, c.RewriteKind)
+			}
+		case before.Hash().String():
+			sawOld = true
+			if len(c.ReplacedBy) != 1 || c.ReplacedBy[0] != after.Hash().String() {
+				t.Errorf("Expected original commit to be replaced by %s, got %v", after.Hash(), c.ReplacedBy)
+			}
+		}
+	}
+	if !sawNew || !sawOld {
+		t.Errorf("Expected both commits in graph (new=%v, old=%v)", sawNew, sawOld)
+	}
+}
+
+func TestCommit_All(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-commit-all")
+	repo := s.GetRepo()
+	commitFile(t, repo, "gone.txt", "bye", "add gone")
+	w, _ := repo.Worktree()
+
+	f, _ := w.Filesystem.Create("file.txt")
+	f.Write([]byte("changed"))
+	f.Close()
+	w.Filesystem.Remove("gone.txt")
+	f, _ = w.Filesystem.Create("new.txt")
+	f.Close()
+
+	if _, err := (&CommitCommand{}).Execute(context.Background(), s, []string{"commit", "-am", "Update tracked files"}); err != nil {
+		t.Fatalf("commit -am failed: %v", err)
+	}
+	head, _ := repo.Head()
+	c, _ := repo.CommitObject(head.Hash())
+	if c.Message != "Update tracked files" {
+		t.Errorf("unexpected message %q", c.Message)
+	}
+	if file, err := c.File("file.txt"); err != nil {
+		t.Error("file.txt missing from the commit")
+	} else if content, _ := file.Contents(); content != "changed" {
+		t.Errorf("file.txt should be committed as modified, got %q", content)
+	}
+	if _, err := c.File("gone.txt"); err == nil {
+		t.Error("the deletion of gone.txt should be committed")
+	}
+	if _, err := c.File("new.txt"); err == nil {
+		t.Error("untracked new.txt should not be committed")
+	}
+}
+
+func TestCommit_AllRejectedKeepsIndex(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-commit-all-rejected")
+	repo := s.GetRepo()
+	ctx := context.Background()
+	if _, err := (&ConfigCommand{}).Execute(ctx, s, []string{"config", "commit.lint", "conventional"}); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+	w, _ := repo.Worktree()
+	f, _ := w.Filesystem.Create("file.txt")
+	f.Write([]byte("changed"))
+	f.Close()
+	before := git.IndexHashes(repo)
+
+	_, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-am", "Update stuff."})
+	if err == nil || !strings.Contains(err.Error(), "does not follow Conventional Commits") {
+		t.Fatalf("expected the lint to reject the message, got %v", err)
+	}
+	if after := git.IndexHashes(repo); after["file.txt"] != before["file.txt"] {
+		t.Error("a rejected commit -a must leave file.txt unstaged")
+	}
+
+	if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-am", "fix: update file"}); err != nil {
+		t.Fatalf("commit -am failed: %v", err)
+	}
+	if after := git.IndexHashes(repo); after["file.txt"] == before["file.txt"] {
+		t.Error("commit -a should have staged file.txt")
+	}
+}
+
+func TestCommit_AuthorAndDate(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-commit-author")
+	repo := s.GetRepo()
+	cmd := &CommitCommand{}
+	ctx := context.Background()
+
+	_, err := cmd.Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "Pair work", "--author=Alice Example <alice@example.com>", "--date", "@1700000000 +0900"})
+	if err != nil {
+		t.Fatalf("commit --author failed: %v", err)
+	}
+	head, _ := repo.Head()
+	c, _ := repo.CommitObject(head.Hash())
+	if c.Author.Name != "Alice Example" || c.Author.Email != "alice@example.com" {
+		t.Errorf("unexpected author %s <%s>", c.Author.Name, c.Author.Email)
+	}
+	if c.Author.When.Unix() != 1700000000 {
+		t.Errorf("unexpected author date %v", c.Author.When)
+	}
+	if c.Committer.Name == "Alice Example" {
+		t.Error("the committer should stay the session's user")
+	}
+
+	// A pattern names an earlier author
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "More", "--author", "alice@"}); err != nil {
+		t.Fatalf("commit --author=<pattern> failed: %v", err)
+	}
+	head, _ = repo.Head()
+	c, _ = repo.CommitObject(head.Hash())
+	if c.Author.Name != "Alice Example" {
+		t.Errorf("expected the pattern to pick Alice, got %s", c.Author.Name)
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "x", "--author=nobody"}); err == nil || !strings.Contains(err.Error(), "matches no existing author") {
+		t.Errorf("expected an unknown author error, got %v", err)
+	}
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "x", "--date=someday"}); err == nil || !strings.Contains(err.Error(), "invalid date") {
+		t.Errorf("expected an invalid date error, got %v", err)
+	}
+}
+
+func TestCommitAmend_KeepsAuthor(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-amend-author")
+	repo := s.GetRepo()
+	cmd := &CommitCommand{}
+	ctx := context.Background()
+
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "Draft", "--author=Alice <alice@example.com>", "--date=@1700000000 +0000"}); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--amend", "-m", "Final"}); err != nil {
+		t.Fatalf("amend failed: %v", err)
+	}
+	head, _ := repo.Head()
+	c, _ := repo.CommitObject(head.Hash())
+	if c.Message != "Final" {
+		t.Errorf("expected the message to be replaced, got %q", c.Message)
+	}
+	if c.Author.Name != "Alice" || c.Author.When.Unix() != 1700000000 {
+		t.Errorf("amend should keep the author and date, got %s at %v", c.Author.Name, c.Author.When)
+	}
+	if user := s.Identity(); c.Committer.Name != user.Name || c.Committer.When.Unix() == 1700000000 {
+		t.Errorf("the committer should be the session's user now, got %s at %v", c.Committer.Name, c.Committer.When)
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--amend", "--no-edit", "--reset-author"}); err != nil {
+		t.Fatalf("amend --reset-author failed: %v", err)
+	}
+	head, _ = repo.Head()
+	c, _ = repo.CommitObject(head.Hash())
+	if user := s.Identity(); c.Author.Name != user.Name || c.Author.When.Unix() == 1700000000 || c.Message != "Final" {
+		t.Errorf("--reset-author should make the session's user the author, got %s at %v (%q)", c.Author.Name, c.Author.When, c.Message)
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"commit", "--reset-author", "-m", "x"}); err == nil || !strings.Contains(err.Error(), "only with -C, -c or --amend") {
+		t.Errorf("expected --reset-author without --amend to fail, got %v", err)
+	}
+}
+
+func TestCommitAmend_RefusedDuringMerge(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-amend-merge")
+	head, _ := s.GetRepo().Head()
+	s.StartMerge(&git.MergeState{Repo: "testrepo", MergeHead: head.Hash().String(), OrigHead: head.Hash().String(), Message: "Merge"})
+
+	_, err := (&CommitCommand{}).Execute(context.Background(), s, []string{"commit", "--amend", "-m", "x"})
+	if err == nil || !strings.Contains(err.Error(), "You are in the middle of a merge -- cannot amend.") {
+		t.Errorf("expected amend to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-complete")
+	ctx := context.Background()
+	for _, input := range []string{"git branch feature", "git branch fix-typo", "git tag v1.0", "git remote add origin https://example.com/repo.git"} {
+		name, args := git.ParseCommand(input)
+		_, err := git.Dispatch(ctx, s, name, args)
+		require.NoError(t, err, input)
+	}
+	require.NoError(t, util.WriteFile(s.Filesystem, "testrepo/docs/guide.md", []byte("guide"), 0644))
+	require.NoError(t, util.WriteFile(s.Filesystem, "testrepo/.gitignore", []byte("*.log"), 0644))
+
+	values := func(line string) []string {
+		res := git.Complete(s, line)
+		out := []string{}
+		for _, c := range res.Completions {
+			out = append(out, c.Value)
+		}
+		return out
+	}
+
+	t.Run("commands", func(t *testing.T) {
+		assert.Equal(t, []string{"git", "gitgym"}, values("gi"))
+		assert.Equal(t, []string{"cat", "cd"}, values("c"))
+		assert.Equal(t, []string{"cat-file", "check-ignore", "checkout", "cherry-pick", "clean", "clone", "commit", "config", "count-objects"}, values("git c"))
+		assert.Equal(t, []string{"rebase", "reflog", "remote", "rerere", "reset", "restore", "rev-list", "revert", "rm"}, values("git r"))
+		assert.NotContains(t, values("git "), "simulate-commit")
+	})
+
+	t.Run("subcommands", func(t *testing.T) {
+		assert.Equal(t, []string{"pop", "push"}, values("git stash p"))
+		assert.Equal(t, []string{"recover", "redo", "restore"}, values("gitgym re"))
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		assert.Equal(t, []string{"--all", "--amend", "--allow-empty", "--author"}, values("git commit --a"))
+		res := git.Complete(s, "git commit --am")
+		assert.Equal(t, "Replace the last commit", res.Completions[0].Description)
+		assert.Equal(t, []string{"--set-upstream-to=feature", "--set-upstream-to=fix-typo"}, values("git branch --set-upstream-to=f"))
+	})
+
+	t.Run("refs", func(t *testing.T) {
+		assert.Equal(t, []string{"feature", "fix-typo"}, values("git switch f"))
+		assert.Equal(t, []string{"HEAD", "feature", "fix-typo", "main", "v1.0"}, values("git merge "))
+		assert.Equal(t, []string{"v1.0"}, values("git tag -d v"))
+		assert.Equal(t, []string{"origin"}, values("git push o"))
+		assert.Equal(t, []string{"main"}, values("git push origin m"))
+		// The value of -m is free text
+		assert.Empty(t, values("git commit -m "))
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		assert.Equal(t, []string{"docs/", "file.txt"}, values("git add "))
+		assert.Equal(t, []string{"docs/guide.md"}, values("git add docs/"))
+		assert.Equal(t, []string{".gitignore"}, values("cat ."))
+		assert.Equal(t, []string{"/testrepo/docs/"}, values("ls /testrepo/d"))
+		assert.Equal(t, []string{"docs/", "file.txt"}, values("git checkout main -- "))
+		assert.Equal(t, []string{"file.txt"}, values("git log > fi"))
+	})
+
+	t.Run("last command of a line", func(t *testing.T) {
+		res := git.Complete(s, `git add . && git commit -m "a b" && git sw`)
+		assert.Equal(t, "sw", res.Word)
+		assert.Equal(t, len(`git add . && git commit -m "a b" && git `), res.Start)
+		assert.Equal(t, []string{"switch"}, values(`git add . && git commit -m "a b" && git sw`))
+		assert.Equal(t, []string{"feature", "fix-typo"}, values("git log --oneline | cat; git checkout f"))
+	})
+}
@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
@@ -25,6 +27,11 @@ func (c *ConfigCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	key := args[1]
 	value := strings.Join(args[2:], " ")
 
+	// Session-level settings that are not part of the repository config
+	if strings.HasPrefix(key, "gitgym.branchpolicy.") {
+		return c.setBranchPolicy(s, key, strings.Trim(value, "'\""))
+	}
+
 	repo := s.GetRepo()
 	if repo == nil {
 		return "", fmt.Errorf("fatal: not a git repository")
@@ -42,10 +49,19 @@ func (c *ConfigCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	case "user.email":
 		cfg.User.Email = strings.Trim(value, "'\"")
 	default:
-		// Ignore other configs or store in raw config?
-		// go-git Config struct has specific fields.
-		// For generic sections/subsections, it's more complex.
-		// For Mission setup, we only care about identity.
+		// Other keys go to the raw config, where commands such as pull read them
+		// (section.option or section.subsection.option)
+		parts := strings.Split(key, ".")
+		if len(parts) < 2 || parts[0] == "" || parts[len(parts)-1] == "" {
+			return "", fmt.Errorf("error: key does not contain a section: %s", key)
+		}
+		section := cfg.Raw.Section(parts[0])
+		option := parts[len(parts)-1]
+		if len(parts) > 2 {
+			section.Subsection(strings.Join(parts[1:len(parts)-1], ".")).SetOption(option, strings.Trim(value, "'\""))
+		} else {
+			section.SetOption(option, strings.Trim(value, "'\""))
+		}
 	}
 
 	if err := repo.Storer.SetConfig(cfg); err != nil {
@@ -55,6 +71,58 @@ func (c *ConfigCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	return "", nil
 }
 
+// setBranchPolicy updates the session's branch naming policy.
+// An empty value clears the corresponding rule.
+func (c *ConfigCommand) setBranchPolicy(s *git.Session, key, value string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	policy := &git.BranchPolicy{}
+	if s.BranchPolicy != nil {
+		*policy = *s.BranchPolicy
+	}
+
+	switch key {
+	case "gitgym.branchpolicy.pattern":
+		if value != "" {
+			if _, err := regexp.Compile(value); err != nil {
+				return "", fmt.Errorf("error: invalid pattern '%s': %v", value, err)
+			}
+		}
+		policy.Pattern = value
+	case "gitgym.branchpolicy.prefixes":
+		policy.RequiredPrefixes = nil
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				policy.RequiredPrefixes = append(policy.RequiredPrefixes, p)
+			}
+		}
+	case "gitgym.branchpolicy.maxlength":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("error: invalid max length '%s'", value)
+		}
+		policy.MaxLength = n
+	default:
+		return "", fmt.Errorf("error: unknown branch policy key '%s'", key)
+	}
+
+	if policy.IsEmpty() {
+		s.BranchPolicy = nil
+	} else {
+		s.BranchPolicy = policy
+	}
+	return "", nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *ConfigCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"gitgym.branchpolicy.maxlength", "gitgym.branchpolicy.pattern", "gitgym.branchpolicy.prefixes", "pull.rebase", "user.email", "user.name"},
+		Args:        []string{git.ArgText},
+	}
+}
+
 func (c *ConfigCommand) Help() string {
-	return "usage: git config <key> <value>"
+	return git.CommandHelp(context.Background(), "config")
 }
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// Helpers shared by the commands that stop on conflicts (merge, cherry-pick).
+// Conflicted paths are recorded in the session when the command stops; a path
+// counts as resolved once its worktree content has been staged with git add.
+
+// conflictedPaths lists the changed files that Merge3Way left with conflict markers.
+func conflictedPaths(w *gogit.Worktree) ([]string, error) {
+	status, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path, fs := range status {
+		if fs.Worktree == gogit.Unmodified {
+			continue
+		}
+		content, err := util.ReadFile(w.Filesystem, path)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(string(content), "<<<<<<< HEAD\n") {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// unresolvedPaths returns the conflicted paths whose resolution has not been staged yet.
+func unresolvedPaths(w *gogit.Worktree, conflicts []string) ([]string, error) {
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+	status, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var unresolved []string
+	for _, path := range conflicts {
+		if fs, ok := status[path]; ok && fs.Worktree != gogit.Unmodified {
+			unresolved = append(unresolved, path)
+		}
+	}
+	return unresolved, nil
+}
+
+// unmergedFilesError is git's refusal to commit while conflicts are unresolved.
+func unmergedFilesError(unresolved []string, fatal string) error {
+	var sb strings.Builder
+	sb.WriteString("error: Committing is not possible because you have unmerged files.\n")
+	sb.WriteString("hint: Fix them up in the work tree, and then use 'git add/rm <file>'\n")
+	sb.WriteString("hint: as appropriate to mark resolution and make a commit.\n")
+	for _, path := range unresolved {
+		sb.WriteString(fmt.Sprintf("U\t%s\n", path))
+	}
+	sb.WriteString(fatal)
+	return fmt.Errorf("%s", sb.String())
+}
+
+// prependLines puts lines, such as what rerere did, before out.
+func prependLines(lines []string, out string) string {
+	if len(lines) == 0 {
+		return out
+	}
+	if out == "" {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines, "\n") + "\n" + out
+}
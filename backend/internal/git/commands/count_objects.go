@@ -0,0 +1,77 @@
+package commands
+
+// count_objects.go - Simulated Git Count-Objects Command
+//
+// Reports how many objects the repository stores and how much space they
+// take. Objects are never packed in the simulation, so the pack lines of -v
+// are always zero.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("count-objects", func() git.Command { return &CountObjectsCommand{} })
+}
+
+type CountObjectsCommand struct{}
+
+// Ensure CountObjectsCommand implements git.Command
+var _ git.Command = (*CountObjectsCommand)(nil)
+
+func (c *CountObjectsCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	verbose := false
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help":
+			return git.CommandHelp(ctx, "count-objects"), nil
+		case "-v", "--verbose":
+			verbose = true
+		case "-H", "--human-readable":
+			// Sizes are always shown in kilobytes
+		default:
+			return "", fmt.Errorf("error: unknown option '%s'\nusage: git count-objects [-v] [-H | --human-readable]", strings.TrimLeft(arg, "-"))
+		}
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	count := git.CountObjects(repo)
+	if !verbose {
+		return formatObjectCount(count) + "\n", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("count: %d\n", count.Count))
+	sb.WriteString(fmt.Sprintf("size: %d\n", kilobytes(count.Size)))
+	sb.WriteString("in-pack: 0\n")
+	sb.WriteString("packs: 0\n")
+	sb.WriteString("size-pack: 0\n")
+	sb.WriteString("prune-packable: 0\n")
+	sb.WriteString("garbage: 0\n")
+	sb.WriteString("size-garbage: 0\n")
+	return sb.String(), nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *CountObjectsCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show details"},
+			{Flags: []string{"-H", "--human-readable"}, Usage: "Print sizes in human units"},
+		},
+	}
+}
+
+func (c *CountObjectsCommand) Help() string {
+	return git.CommandHelp(context.Background(), "count-objects")
+}
@@ -1,13 +1,13 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -24,16 +24,19 @@ type DiffOptions struct {
 	Cached   bool
 	Stat     bool
 	NameOnly bool
-	Ref1     string
-	Ref2     string
+	Args     []string // Revisions, or paths when they do not resolve
+	Paths    []string // Paths given after "--"
 }
 
 func (c *DiffCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	opts, err := c.parseArgs(args)
 	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "diff"), nil
+		}
 		return "", err
 	}
 
@@ -42,16 +45,17 @@ func (c *DiffCommand) Execute(ctx context.Context, s *git.Session, args []string
 		return "", fmt.Errorf("fatal: not a git repository")
 	}
 
-	return c.executeDiff(s, repo, opts)
+	return c.executeDiff(repo, opts)
 }
 
 func (c *DiffCommand) parseArgs(args []string) (*DiffOptions, error) {
 	opts := &DiffOptions{}
-	var refs []string
-
 	cmdArgs := args[1:]
-	for _, arg := range cmdArgs {
+	for i, arg := range cmdArgs {
 		switch arg {
+		case "--":
+			opts.Paths = append(opts.Paths, cmdArgs[i+1:]...)
+			return opts, nil
 		case "--cached", "--staged":
 			opts.Cached = true
 		case "--stat":
@@ -62,117 +66,106 @@ func (c *DiffCommand) parseArgs(args []string) (*DiffOptions, error) {
 			return nil, fmt.Errorf("help requested")
 		default:
 			if !strings.HasPrefix(arg, "-") {
-				refs = append(refs, arg)
+				opts.Args = append(opts.Args, arg)
 			}
 		}
 	}
-
-	if len(refs) > 0 {
-		opts.Ref1 = refs[0]
-	}
-	if len(refs) > 1 {
-		opts.Ref2 = refs[1]
-	}
-
-	// Validation
-	// git diff -> Ref1="", Ref2="", Cached=false
-	// git diff --cached -> Ref1="", Ref2="", Cached=true
-	// git diff commit -> Ref1="commit", Ref2="", Cached=false
-
 	return opts, nil
 }
 
-func (c *DiffCommand) executeDiff(s *git.Session, repo *gogit.Repository, opts *DiffOptions) (string, error) {
-	var tree1, tree2 *object.Tree
-	var err error
-
-	// 1. Resolve Tree 2 (Target)
-	if opts.Ref2 != "" {
-		// git diff ref1 ref2
-		h2, err := repo.ResolveRevision(plumbing.Revision(opts.Ref2))
-		if err != nil {
-			return "", fmt.Errorf("could not resolve %s: %w", opts.Ref2, err)
-		}
-		commit2, err := repo.CommitObject(*h2)
-		if err != nil {
-			return "", err
-		}
-		tree2, err = commit2.Tree()
-		if err != nil {
-			return "", err
-		}
-	} else if opts.Ref1 != "" && !opts.Cached {
-		// git diff ref1 -> ref1 vs Worktree
-		// Standard Git: git diff <commit> compares <commit> with working tree
-		tree2, err = s.GetWorktreeTree(repo)
-		if err != nil {
-			return "", fmt.Errorf("failed to build worktree tree: %w", err)
-		}
-	} else if opts.Cached {
-		// git diff --cached -> Index vs HEAD
-		tree2, err = s.GetIndexTree(repo)
-		if err != nil {
-			return "", fmt.Errorf("failed to build index tree: %w", err)
+// splitArgs sorts the arguments before "--" into revisions and paths, as git
+// does: revisions come first, and anything that is not one must be a path.
+func (c *DiffCommand) splitArgs(repo *gogit.Repository, opts *DiffOptions) (revs, paths []string, err error) {
+	paths = append(paths, opts.Paths...)
+	for _, arg := range opts.Args {
+		if len(paths) == len(opts.Paths) {
+			// A..B is the same as "A B", A...B compares B with the merge base
+			if r, ok, err := git.ParseRevisionRange(repo, arg); ok {
+				if err != nil {
+					return nil, nil, err
+				}
+				from := r.From
+				if r.Symmetric {
+					if from, err = r.MergeBase(repo); err != nil {
+						return nil, nil, err
+					}
+				}
+				revs = append(revs, from.String(), r.To.String())
+				continue
+			}
+			if _, err := git.ResolveRevision(repo, arg); err == nil {
+				revs = append(revs, arg)
+				continue
+			}
 		}
-	} else {
-		// git diff -> Worktree vs Index (or HEAD if no index?)
-		// Standard Git: compares working directory with index
-		tree2, err = s.GetWorktreeTree(repo)
-		if err != nil {
-			return "", fmt.Errorf("failed to build worktree tree: %w", err)
+		if !c.pathExists(repo, arg) {
+			return nil, nil, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.\nUse '--' to separate paths from revisions, like this:\n'git <command> [<revision>...] -- [<file>...]'", arg)
 		}
+		paths = append(paths, arg)
+	}
+	if len(revs) > 2 {
+		return nil, nil, fmt.Errorf("usage: git diff [<options>] [<commit> [<commit>]] [--] [<path>...]")
 	}
+	return revs, paths, nil
+}
 
-	// 2. Resolve Tree 1 (Base)
-	if opts.Ref1 != "" {
-		h1, err := repo.ResolveRevision(plumbing.Revision(opts.Ref1))
-		if err != nil {
-			return "", fmt.Errorf("could not resolve %s: %w", opts.Ref1, err)
-		}
-		commit1, err := repo.CommitObject(*h1)
-		if err != nil {
-			return "", err
+// pathExists reports whether arg names a file or directory in the working tree or the index.
+func (c *DiffCommand) pathExists(repo *gogit.Repository, arg string) bool {
+	if w, err := repo.Worktree(); err == nil {
+		if _, err := w.Filesystem.Lstat(arg); err == nil {
+			return true
 		}
-		tree1, err = commit1.Tree()
-		if err != nil {
-			return "", err
+	}
+	staged, err := git.IndexSnapshot(repo)
+	if err != nil {
+		return false
+	}
+	for name := range staged {
+		if git.MatchPathspec(name, []string{arg}) {
+			return true
 		}
-	} else if opts.Cached {
-		// git diff --cached -> Index vs HEAD. Base is HEAD.
-		head, err := repo.Head()
-		if err != nil {
-			// No commits yet, compare with empty tree
-			tree1 = &object.Tree{}
-		} else {
-			commit1, err := repo.CommitObject(head.Hash())
-			if err != nil {
-				return "", err
-			}
-			tree1, err = commit1.Tree()
-			if err != nil {
-				return "", err
-			}
+	}
+	return false
+}
+
+func (c *DiffCommand) executeDiff(repo *gogit.Repository, opts *DiffOptions) (string, error) {
+	revs, paths, err := c.splitArgs(repo, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// git diff            index -> worktree
+	// git diff --cached   HEAD (or <commit>) -> index
+	// git diff <commit>   <commit> -> worktree
+	// git diff <a> <b>    <a> -> <b>
+	var from, to git.ContentSnapshot
+	switch {
+	case len(revs) == 2:
+		if from, err = git.CommitSnapshot(repo, revs[0]); err == nil {
+			to, err = git.CommitSnapshot(repo, revs[1])
 		}
-	} else {
-		// git diff -> Worktree vs Index. Base is Index.
-		tree1, err = s.GetIndexTree(repo)
-		if err != nil {
-			// If index tree fails (e.g. empty repo), fallback to HEAD or empty
-			head, err := repo.Head()
-			if err != nil {
-				tree1 = &object.Tree{}
+	case len(revs) == 1:
+		if from, err = git.CommitSnapshot(repo, revs[0]); err == nil {
+			if opts.Cached {
+				to, err = git.IndexSnapshot(repo)
 			} else {
-				commit1, _ := repo.CommitObject(head.Hash())
-				tree1, _ = commit1.Tree()
+				to, err = git.WorktreeSnapshot(repo, false)
 			}
 		}
+	case opts.Cached:
+		if from, err = git.HeadSnapshot(repo); err == nil {
+			to, err = git.IndexSnapshot(repo)
+		}
+	default:
+		if from, err = git.IndexSnapshot(repo); err == nil {
+			to, err = git.WorktreeSnapshot(repo, false)
+		}
 	}
-
-	if tree1 == nil || tree2 == nil {
-		return "", fmt.Errorf("internal error: could not resolve trees for diff")
+	if err != nil {
+		return "", err
 	}
 
-	patch, err := tree1.Patch(tree2)
+	patch, err := git.DiffContent(from, to, paths)
 	if err != nil {
 		return "", err
 	}
@@ -185,10 +178,14 @@ func (c *DiffCommand) executeDiff(s *git.Session, repo *gogit.Repository, opts *
 		return c.formatStat(patch), nil
 	}
 
-	return patch.String(), nil
+	var buf bytes.Buffer
+	if err := diff.NewUnifiedEncoder(&buf, diff.DefaultContextLines).Encode(patch); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
-func (c *DiffCommand) formatNameOnly(patch *object.Patch) string {
+func (c *DiffCommand) formatNameOnly(patch diff.Patch) string {
 	var sb strings.Builder
 	for _, fp := range patch.FilePatches() {
 		from, to := fp.Files()
@@ -206,7 +203,7 @@ func (c *DiffCommand) formatNameOnly(patch *object.Patch) string {
 	return sb.String()
 }
 
-func (c *DiffCommand) formatStat(patch *object.Patch) string {
+func (c *DiffCommand) formatStat(patch diff.Patch) string {
 	var sb strings.Builder
 	var totalAdd, totalDel int
 	var maxLen int
@@ -232,18 +229,18 @@ func (c *DiffCommand) formatStat(patch *object.Patch) string {
 			lines := strings.Split(chunk.Content(), "\n")
 			for range lines {
 				switch chunk.Type() {
-				case 1: // Add
+				case diff.Add:
 					add++
-				case 2: // Delete
+				case diff.Delete:
 					del++
 				}
 			}
 			// Adjust for empty trailing line
 			if len(lines) > 0 && lines[len(lines)-1] == "" {
 				switch chunk.Type() {
-				case 1:
+				case diff.Add:
 					add--
-				case 2:
+				case diff.Delete:
 					del--
 				}
 			}
@@ -277,40 +274,18 @@ func (c *DiffCommand) formatStat(patch *object.Patch) string {
 	return sb.String()
 }
 
-func (c *DiffCommand) Help() string {
-	return `📘 GIT-DIFF (1)                                         Git Manual
-
- 💡 DESCRIPTION
-    ・2つのコミットを比較して、変更内容（差分）を表示する
-    ・ファイルの中身が具体的にどう変わったかを確認する
-
- 📋 SYNOPSIS
-    git diff [options] [<commit>] [<commit>]
-
- ⚙️  OPTIONS
-    --cached, --staged
-        インデックス（ステージングエリア）とHEADの差分を表示
-
-    --stat
-        変更されたファイルのリストと追加・削除行数のサマリーを表示
-
-    --name-only
-        変更されたファイル名のみを表示
-
- 🛠  EXAMPLES
-    1. 2つのコミットを比較
-       $ git diff HEAD~1 HEAD
-
-    2. ブランチ間を比較
-       $ git diff main develop
-
-    3. 変更ファイルと行数のサマリー
-       $ git diff --stat HEAD~1 HEAD
-
-    4. 変更ファイル名のみ
-       $ git diff --name-only HEAD~1 HEAD
+// Spec implements git.SpecProvider.
+func (c *DiffCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--cached", "--staged"}, Usage: "Compare the index with HEAD"},
+			{Flags: []string{"--stat"}, Usage: "Show a summary of changes"},
+			{Flags: []string{"--name-only"}, Usage: "Show only the changed paths"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-diff
-`
+func (c *DiffCommand) Help() string {
+	return git.CommandHelp(context.Background(), "diff")
 }
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatch_CommandResultPayload(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-result")
+	ctx := context.Background()
+
+	dispatch := func(input string) *git.CommandResult {
+		name, args := git.ParseCommand(input)
+		result, _ := git.Dispatch(ctx, s, name, args)
+		require.NotNil(t, result, input)
+		return result
+	}
+	head := func() string {
+		ref, err := s.GetRepo().Head()
+		require.NoError(t, err)
+		return ref.Hash().String()
+	}
+	initial := head()
+
+	t.Run("branch creates a ref", func(t *testing.T) {
+		res := dispatch("git branch feature")
+		assert.Equal(t, git.ExitOK, res.ExitCode)
+		require.NotNil(t, res.Payload)
+		assert.Equal(t, []git.RefUpdate{{Name: "refs/heads/feature", New: initial}}, res.Payload.RefUpdates)
+		assert.Empty(t, res.Payload.PreviousHead)
+		assert.False(t, res.Payload.Switched)
+	})
+
+	t.Run("switch reports the branches", func(t *testing.T) {
+		res := dispatch("git switch feature")
+		require.NotNil(t, res.Payload)
+		assert.True(t, res.Payload.Switched)
+		assert.Equal(t, "feature", res.Payload.Branch)
+		assert.Equal(t, "main", res.Payload.PreviousBranch)
+		assert.Empty(t, res.Payload.CreatedCommit)
+	})
+
+	t.Run("commit reports the new commit", func(t *testing.T) {
+		require.NoError(t, util.WriteFile(s.Filesystem, "testrepo/file.txt", []byte("changed"), 0644))
+		dispatch("git add file.txt")
+		res := dispatch(`git commit -m "Change file"`)
+		require.NotNil(t, res.Payload)
+		assert.Equal(t, head(), res.Payload.CreatedCommit)
+		assert.Equal(t, head(), res.Payload.Head)
+		assert.Equal(t, initial, res.Payload.PreviousHead)
+		assert.Equal(t, []git.RefUpdate{{Name: "refs/heads/feature", Old: initial, New: head()}}, res.Payload.RefUpdates)
+	})
+
+	t.Run("fast-forward creates no commit", func(t *testing.T) {
+		feature := head()
+		dispatch("git switch main")
+		res := dispatch("git merge feature")
+		require.NotNil(t, res.Payload)
+		assert.Equal(t, feature, res.Payload.Head)
+		assert.Empty(t, res.Payload.CreatedCommit)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		res := dispatch("git branch --dry-run other")
+		require.NotNil(t, res.Payload)
+		assert.True(t, res.Payload.DryRun)
+		assert.Empty(t, res.Payload.RefUpdates)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		res := dispatch("frobnicate")
+		assert.Equal(t, git.ExitUnknownCommand, res.ExitCode)
+		assert.Contains(t, res.Stderr, "not a recognized command")
+		assert.Equal(t, res.Stderr, res.Output())
+
+		res = dispatch("git switch no-such-branch")
+		assert.NotEqual(t, git.ExitOK, res.ExitCode)
+		assert.NotEmpty(t, res.Stderr)
+	})
+}
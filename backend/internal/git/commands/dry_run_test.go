@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun_ReportsChangesWithoutMutating(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-dry-run")
+	ctx := context.Background()
+
+	dispatch := func(input string) (string, error) {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		return result.Stdout, err
+	}
+	headHash := func() plumbing.Hash {
+		head, err := s.GetRepo().Head()
+		require.NoError(t, err)
+		return head.Hash()
+	}
+
+	_, err := dispatch("git branch feature")
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(s.Filesystem, "testrepo/file.txt", []byte("changed"), 0644))
+	_, err = dispatch("git add file.txt")
+	require.NoError(t, err)
+	before := headHash()
+
+	t.Run("commit", func(t *testing.T) {
+		out, err := dispatch(`git commit --dry-run -m "Change file"`)
+		require.NoError(t, err)
+		assert.Contains(t, out, "[dry-run] git commit -m Change file")
+		assert.Contains(t, out, "updated  refs/heads/main "+before.String()[:7]+" => ")
+		assert.Equal(t, before, headHash(), "dry-run must not move the branch")
+
+		s.RLock()
+		potential := s.PotentialCommits
+		s.RUnlock()
+		require.Len(t, potential, 1)
+		assert.Equal(t, "Change file", potential[0].Message)
+		assert.Equal(t, before.String(), potential[0].ParentID)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		out, err := dispatch("git reset --hard --dry-run")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Index entries that would change:\n  modified  file.txt")
+		assert.Contains(t, out, "Files that would change:\n  modified  file.txt")
+
+		content, err := util.ReadFile(s.Filesystem, "testrepo/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "changed", string(content))
+	})
+
+	t.Run("branch -D", func(t *testing.T) {
+		out, err := dispatch("git branch -D feature --dry-run")
+		require.NoError(t, err)
+		assert.Contains(t, out, "deleted  refs/heads/feature")
+
+		_, err = s.GetRepo().Reference(plumbing.NewBranchReferenceName("feature"), false)
+		assert.NoError(t, err, "branch must still exist")
+	})
+
+	t.Run("failing command reports the real error", func(t *testing.T) {
+		_, err := dispatch("git checkout --dry-run no-such-branch")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestEditor_MergeAmendAndTagWaitForMessage(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-editor")
+	editorCtx := git.WithEditor(context.Background())
+
+	run := func(input string) *git.CommandResult {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(editorCtx, s, name, args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return result
+	}
+	headMessage := func() string {
+		head, _ := s.GetRepo().Head()
+		commit, _ := s.GetRepo().CommitObject(head.Hash())
+		return commit.Message
+	}
+	write := func(path, content string) {
+		if err := util.WriteFile(s.Filesystem, "/testrepo/"+path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("git checkout -b feature")
+	write("feature.txt", "feature")
+	run("git add feature.txt")
+	run("git commit -m feature")
+	run("git checkout main")
+	write("main.txt", "main")
+	run("git add main.txt")
+	run("git commit -m main")
+
+	// The merge is made, then waits for its message as an unfinished merge
+	result := run("git merge feature")
+	if result.Editor == nil || !strings.HasPrefix(result.Editor.Template, "Merge branch 'feature'\n") {
+		t.Fatalf("Expected a merge editor, got %+v", result.Editor)
+	}
+	if s.MergeInProgress() == nil {
+		t.Fatal("Expected the merge to wait for its message")
+	}
+	// An empty message leaves the merge to be committed later
+	if _, err := git.CompleteEditor(context.Background(), s, result.Editor.ID, "# nothing\n", false); err == nil || !strings.Contains(err.Error(), "Not committing merge") {
+		t.Errorf("Expected the empty message to abort, got %v", err)
+	}
+	result = run("git commit")
+	if result.Editor == nil {
+		t.Fatal("Expected commit to open the editor for the merge message")
+	}
+	if _, err := git.CompleteEditor(context.Background(), s, result.Editor.ID, "Merge feature for release\n", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := headMessage(); got != "Merge feature for release" || s.MergeInProgress() != nil {
+		t.Errorf("Expected the merge commit with the edited message, got %q", got)
+	}
+
+	// --amend starts from the current message; --no-edit keeps it
+	result = run("git commit --amend")
+	if result.Editor == nil || !strings.HasPrefix(result.Editor.Template, "Merge feature for release\n") {
+		t.Fatalf("Expected an amend editor with the old message, got %+v", result.Editor)
+	}
+	// Another command closes the editor
+	run("git status")
+	if _, err := git.CompleteEditor(context.Background(), s, result.Editor.ID, "late", false); err == nil {
+		t.Error("Expected the editor to be closed by the next command")
+	}
+	run("git commit --amend --no-edit")
+	if got := headMessage(); got != "Merge feature for release" {
+		t.Errorf("Expected --no-edit to keep the message, got %q", got)
+	}
+
+	result = run("git tag -a v1.0")
+	if result.Editor == nil || result.Editor.File != git.TagEditFile {
+		t.Fatalf("Expected a tag editor, got %+v", result.Editor)
+	}
+	if _, err := git.CompleteEditor(context.Background(), s, result.Editor.ID, "First release", false); err != nil {
+		t.Fatal(err)
+	}
+	tagRef, err := s.GetRepo().Tag("v1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := s.GetRepo().TagObject(tagRef.Hash())
+	if err != nil || strings.TrimSpace(tag.Message) != "First release" {
+		t.Errorf("Expected the annotated tag message, got %v (%v)", tag, err)
+	}
+}
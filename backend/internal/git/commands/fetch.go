@@ -7,6 +7,7 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -25,11 +26,12 @@ type FetchCommand struct{}
 var _ git.Command = (*FetchCommand)(nil)
 
 type FetchOptions struct {
-	DryRun   bool
-	FetchAll bool
-	Prune    bool
-	Tags     bool
-	Remotes  []string
+	DryRun    bool
+	FetchAll  bool
+	Prune     bool
+	Tags      bool
+	Unshallow bool // --unshallow: fetch the history a shallow clone left out
+	Remotes   []string
 }
 
 func (c *FetchCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -45,7 +47,7 @@ func (c *FetchCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "fetch"), nil
 		}
 		return "", err
 	}
@@ -56,39 +58,160 @@ func (c *FetchCommand) Execute(ctx context.Context, s *git.Session, args []strin
 		return "", err
 	}
 
+	if opts.Unshallow && len(git.ShallowBoundary(repo)) == 0 {
+		return "", fmt.Errorf("fatal: --unshallow on a complete repository does not make sense")
+	}
+
 	// 3. Execution (Loop and Fetch)
-	return c.executeFetch(s, repo, remotes, opts)
+	out, err := c.executeFetch(ctx, s, repo, remotes, opts)
+	if err != nil || opts.DryRun {
+		return out, err
+	}
+	if err := c.updateShallow(ctx, s, repo, remotes, opts.Unshallow); err != nil {
+		return out, err
+	}
+	if err := c.writeFetchHead(repo, remotes[0], opts); err != nil {
+		return out, err
+	}
+	return out, nil
 }
 
-func (c *FetchCommand) parseArgs(args []string) (*FetchOptions, error) {
-	opts := &FetchOptions{}
-	cmdArgs := args[1:]
-	for i, arg := range cmdArgs {
-		switch arg {
-		case "-n", "--dry-run":
-			opts.DryRun = true
-		case "--all":
-			opts.FetchAll = true
-		case "-p", "--prune":
-			opts.Prune = true
-		case "-t", "--tags":
-			opts.Tags = true
-		case "-h", "--help":
-			return nil, fmt.Errorf("help requested")
-		default:
-			if strings.HasPrefix(arg, "-") {
-				return nil, fmt.Errorf("unknown flag: %s", arg)
+// writeFetchHead points FETCH_HEAD at the branch git pull would merge: the one
+// named on the command line, else the upstream of the current branch when it
+// tracks this remote, else the remote's default branch.
+func (c *FetchCommand) writeFetchHead(repo *gogit.Repository, rem *gogit.Remote, opts *FetchOptions) error {
+	remoteName := rem.Config().Name
+	var branch string
+	if len(opts.Remotes) > 1 && !opts.FetchAll {
+		src, _, _ := strings.Cut(strings.TrimPrefix(opts.Remotes[1], "+"), ":")
+		branch = strings.TrimPrefix(src, "refs/heads/")
+		ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch), true)
+		if err != nil {
+			return fmt.Errorf("fatal: couldn't find remote ref %s", src)
+		}
+		return git.SetSpecialRef(repo, git.FetchHead, ref.Hash())
+	}
+
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		if remote, merge, ok := git.Upstream(repo, head.Name().Short()); ok && remote == remoteName {
+			branch = merge
+		}
+	}
+	if branch == "" {
+		if ref, err := repo.Storer.Reference(plumbing.NewRemoteHEADReferenceName(remoteName)); err == nil && ref.Type() == plumbing.SymbolicReference {
+			branch = strings.TrimPrefix(ref.Target().String(), "refs/remotes/"+remoteName+"/")
+		}
+	}
+	if ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch), true); branch != "" && err == nil {
+		return git.SetSpecialRef(repo, git.FetchHead, ref.Hash())
+	}
+
+	// No default branch known: the first branch of the remote, by name
+	refs, err := repo.References()
+	if err != nil {
+		return nil
+	}
+	prefix := "refs/remotes/" + remoteName + "/"
+	var first *plumbing.Reference
+	_ = refs.ForEach(func(r *plumbing.Reference) error {
+		name := r.Name().String()
+		if r.Type() == plumbing.HashReference && strings.HasPrefix(name, prefix) && name != prefix+"HEAD" &&
+			(first == nil || name < first.Name().String()) {
+			first = r
+		}
+		return nil
+	})
+	if first != nil {
+		return git.SetSpecialRef(repo, git.FetchHead, first.Hash())
+	}
+	return nil
+}
+
+// updateShallow keeps .git/shallow in step with the history now present.
+// With unshallow the parents left out are fetched first; otherwise only the
+// commits whose parents a fetch happened to bring in leave the boundary.
+func (c *FetchCommand) updateShallow(ctx context.Context, s *git.Session, repo *gogit.Repository, remotes []*gogit.Remote, unshallow bool) error {
+	boundary := git.ShallowBoundary(repo)
+	if len(boundary) == 0 {
+		return nil
+	}
+	if unshallow {
+		var parents []plumbing.Hash
+		for hash := range boundary {
+			if commit, err := repo.CommitObject(hash); err == nil {
+				parents = append(parents, commit.ParentHashes...)
 			}
-			// Only append positional args if not skipping (handled by index loop if manual, but range is safer here if we don't skip)
-			// Wait, mixed flags/args logic in legacy was strict order? No, loop index i:
-			// legacy: for i := 0; i < len(cmdArgs); i++ ...
-			// Here range is fine unless we need to skip next arg (not needed for boolean flags).
-			// If we had value flags, we'd need manual index handling. All current flags are boolean.
-			opts.Remotes = append(opts.Remotes, arg)
 		}
-		_ = i
+		for _, rem := range remotes {
+			if len(rem.Config().URLs) == 0 {
+				continue
+			}
+			srcRepo, err := c.resolveSimulatedRemote(s, rem.Config().URLs[0])
+			if err != nil {
+				return err
+			}
+			if err := c.fetchParents(ctx, s, repo, srcRepo, parents); err != nil {
+				return err
+			}
+		}
+	}
+
+	var remaining []plumbing.Hash
+	for hash := range boundary {
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+		for _, p := range commit.ParentHashes {
+			if !git.HasObject(repo, p) {
+				remaining = append(remaining, hash)
+				break
+			}
+		}
+	}
+	if unshallow && len(remaining) > 0 {
+		return fmt.Errorf("fatal: the remote does not have the history of %d shallow commit(s)", len(remaining))
+	}
+	return repo.Storer.SetShallow(remaining)
+}
+
+// fetchParents copies the history of the shallow boundary's parents that
+// srcRepo has.
+func (c *FetchCommand) fetchParents(ctx context.Context, s *git.Session, repo, srcRepo *gogit.Repository, parents []plumbing.Hash) error {
+	defer rlockRemote(s, srcRepo)()
+	var wants []plumbing.Hash
+	for _, p := range parents {
+		if git.HasObject(srcRepo, p) {
+			wants = append(wants, p)
+		}
+	}
+	transfer, err := git.NegotiateTransfer(ctx, srcRepo, repo, wants, git.RefHaves(repo, ""))
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// The remote is shallow too; what is still missing is reported below
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.CheckStorageQuota(transfer.Objects, transfer.BlobBytes, 0); err != nil {
+		return err
+	}
+	return transfer.Apply(ctx)
+}
+
+func (c *FetchCommand) parseArgs(args []string) (*FetchOptions, error) {
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
 	}
-	return opts, nil
+	return &FetchOptions{
+		DryRun:    parsed.Has("--dry-run"),
+		FetchAll:  parsed.Has("--all"),
+		Prune:     parsed.Has("--prune"),
+		Tags:      parsed.Has("--tags"),
+		Unshallow: parsed.Has("--unshallow"),
+		Remotes:   append(parsed.Positional, parsed.Rest...),
+	}, nil
 }
 
 func (c *FetchCommand) resolveFetchTargets(repo *gogit.Repository, opts *FetchOptions) ([]*gogit.Remote, error) {
@@ -112,12 +235,16 @@ func (c *FetchCommand) resolveFetchTargets(repo *gogit.Repository, opts *FetchOp
 	return []*gogit.Remote{rem}, nil
 }
 
-func (c *FetchCommand) executeFetch(s *git.Session, repo *gogit.Repository, remotes []*gogit.Remote, opts *FetchOptions) (string, error) {
+func (c *FetchCommand) executeFetch(ctx context.Context, s *git.Session, repo *gogit.Repository, remotes []*gogit.Remote, opts *FetchOptions) (string, error) {
 	var allResults []string
 	failed := false
 
 	for _, rem := range remotes {
-		res, err := c.fetchRemote(s, repo, rem, opts.DryRun, opts.Tags, opts.Prune)
+		res, err := c.fetchRemote(ctx, s, repo, rem, opts.DryRun, opts.Tags, opts.Prune)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// Stop at the first remote the deadline cuts short
+			return "", ctxErr
+		}
 		if err != nil {
 			allResults = append(allResults, fmt.Sprintf("error: fetching %s: %v", rem.Config().Name, err))
 			failed = true
@@ -129,7 +256,7 @@ func (c *FetchCommand) executeFetch(s *git.Session, repo *gogit.Repository, remo
 	}
 
 	if failed && len(remotes) == 1 {
-		return "", fmt.Errorf("fetch failed") // Return error for single remote failure
+		return "", errors.New(strings.Join(allResults, "\n")) // Return error for single remote failure
 	}
 
 	if len(allResults) == 0 {
@@ -149,15 +276,20 @@ func (c *FetchCommand) resolveSimulatedRemote(s *git.Session, url string) (*gogi
 
 	// Check Shared Remotes
 	if s.Manager != nil {
-		if repo, ok := s.Manager.SharedRemotes[lookupKey]; ok {
+		if repo, ok := s.Manager.GetSharedRemote(lookupKey); ok {
 			return repo, nil
 		}
 		// Fallback: Check using full URL
-		if repo, ok := s.Manager.SharedRemotes[url]; ok {
+		if repo, ok := s.Manager.GetSharedRemote(url); ok {
 			return repo, nil
 		}
 	}
 
+	// A bundle file in the session filesystem
+	if repo, b, err := openBundleRemote(s, url); b != nil {
+		return repo, err
+	}
+
 	// FALLBACK: Local filesystem path (persistent remote)
 	repo, err := gogit.PlainOpen(url)
 	if err == nil {
@@ -171,7 +303,16 @@ func (c *FetchCommand) resolveSimulatedRemote(s *git.Session, url string) (*gogi
 	return nil, fmt.Errorf("remote repository '%s' not found (only local simulation supported)", url)
 }
 
-func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *gogit.Remote, isDryRun bool, fetchTags bool, prune bool) (string, error) {
+// rlockRemote read-locks src, when it is a shared remote, until the returned
+// function is called.
+func rlockRemote(s *git.Session, src *gogit.Repository) func() {
+	if s.Manager == nil {
+		return func() {}
+	}
+	return s.Manager.RLockRemote(src)
+}
+
+func (c *FetchCommand) fetchRemote(ctx context.Context, s *git.Session, repo *gogit.Repository, rem *gogit.Remote, isDryRun bool, fetchTags bool, prune bool) (string, error) {
 	cfg := rem.Config()
 	remoteName := cfg.Name
 	if len(cfg.URLs) == 0 {
@@ -184,6 +325,8 @@ func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *
 	if err != nil {
 		return "", err
 	}
+	// A push from another session must not move refs while they are copied
+	defer rlockRemote(s, srcRepo)()
 
 	// Scan remote refs (branches and tags)
 	refs, err := srcRepo.References()
@@ -191,6 +334,21 @@ func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *
 		return "", err
 	}
 
+	// Copy what the updated refs need in one go, unless it would take more
+	// than the session's storage quota allows
+	if !isDryRun {
+		transfer, err := c.negotiateFetch(ctx, repo, srcRepo, remoteName, fetchTags)
+		if err != nil {
+			return "", err
+		}
+		if err := s.CheckStorageQuota(transfer.Objects, transfer.BlobBytes, 0); err != nil {
+			return "", err
+		}
+		if err := transfer.Apply(ctx); err != nil {
+			return "", err
+		}
+	}
+
 	results := []string{fmt.Sprintf("From %s", url)}
 	updated := 0
 
@@ -213,7 +371,7 @@ func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *
 		// 1. Handle Branches
 		if r.Name().IsBranch() {
 			remoteBranches[r.Name().Short()] = true
-			res, count, err := c.handleFetchBranch(repo, srcRepo, r, remoteName, isDryRun)
+			res, count, err := c.handleFetchBranch(repo, r, remoteName, isDryRun)
 			if err != nil {
 				return err
 			}
@@ -225,7 +383,7 @@ func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *
 
 		// 2. Handle Tags
 		if fetchTags && r.Name().IsTag() {
-			res, count, err := c.handleFetchTag(repo, srcRepo, r, isDryRun)
+			res, count, err := c.handleFetchTag(repo, r, isDryRun)
 			if err != nil {
 				// Warn but don't fail entire fetch?
 				results = append(results, fmt.Sprintf(" ! [error] %s (copy failed)", r.Name().Short()))
@@ -268,7 +426,35 @@ func (c *FetchCommand) fetchRemote(s *git.Session, repo *gogit.Repository, rem *
 	return strings.Join(results, "\n"), nil
 }
 
-func (c *FetchCommand) handleFetchBranch(repo, srcRepo *gogit.Repository, r *plumbing.Reference, remoteName string, isDryRun bool) (string, int, error) {
+// negotiateFetch works out the objects a fetch from srcRepo has to copy: those
+// of the branches and tags that moved, minus what repo already has. The
+// refs of repo, the remote-tracking refs of remoteName first among them, say
+// where the walk can stop.
+func (c *FetchCommand) negotiateFetch(ctx context.Context, repo, srcRepo *gogit.Repository, remoteName string, fetchTags bool) (*git.ObjectTransfer, error) {
+	refs, err := srcRepo.References()
+	if err != nil {
+		return nil, err
+	}
+	var wanted []plumbing.Hash
+	_ = refs.ForEach(func(r *plumbing.Reference) error {
+		var local plumbing.ReferenceName
+		switch {
+		case r.Name().IsBranch():
+			local = plumbing.NewRemoteReferenceName(remoteName, r.Name().Short())
+		case fetchTags && r.Name().IsTag():
+			local = r.Name()
+		default:
+			return nil
+		}
+		if current, err := repo.Reference(local, true); err != nil || current.Hash() != r.Hash() {
+			wanted = append(wanted, r.Hash())
+		}
+		return nil
+	})
+	return git.NegotiateTransfer(ctx, srcRepo, repo, wanted, git.RefHaves(repo, ""))
+}
+
+func (c *FetchCommand) handleFetchBranch(repo *gogit.Repository, r *plumbing.Reference, remoteName string, isDryRun bool) (string, int, error) {
 	branchName := r.Name().Short()
 	localRefName := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/%s", remoteName, branchName))
 
@@ -282,15 +468,9 @@ func (c *FetchCommand) handleFetchBranch(repo, srcRepo *gogit.Repository, r *plu
 		return fmt.Sprintf(" * [dry-run] %s -> %s/%s", branchName, remoteName, branchName), 0, nil
 	}
 
-	// Copy Objects
-	err := git.CopyCommitRecursive(srcRepo, repo, r.Hash())
-	if err != nil {
-		return "", 0, err
-	}
-
-	// Update Local Reference
+	// Update Local Reference; the objects were copied by negotiateFetch
 	newRef := plumbing.NewHashReference(localRefName, r.Hash())
-	err = repo.Storer.SetReference(newRef)
+	err := repo.Storer.SetReference(newRef)
 	if err != nil {
 		return "", 0, err
 	}
@@ -303,7 +483,7 @@ func (c *FetchCommand) handleFetchBranch(repo, srcRepo *gogit.Repository, r *plu
 	return fmt.Sprintf(" * [%s] %s -> %s/%s", status, branchName, remoteName, branchName), 1, nil
 }
 
-func (c *FetchCommand) handleFetchTag(repo, srcRepo *gogit.Repository, r *plumbing.Reference, isDryRun bool) (string, int, error) {
+func (c *FetchCommand) handleFetchTag(repo *gogit.Repository, r *plumbing.Reference, isDryRun bool) (string, int, error) {
 	tagName := r.Name().Short()
 	localTagRef := r.Name()
 
@@ -317,14 +497,8 @@ func (c *FetchCommand) handleFetchTag(repo, srcRepo *gogit.Repository, r *plumbi
 		return fmt.Sprintf(" * [dry-run] %s -> %s", tagName, tagName), 0, nil
 	}
 
-	// Copy Objects
-	err := git.CopyCommitRecursive(srcRepo, repo, r.Hash())
-	if err != nil {
-		return "", 0, err
-	}
-
 	newRef := plumbing.NewHashReference(localTagRef, r.Hash())
-	err = repo.Storer.SetReference(newRef)
+	err := repo.Storer.SetReference(newRef)
 	if err != nil {
 		return "", 0, err
 	}
@@ -336,50 +510,22 @@ func (c *FetchCommand) handleFetchTag(repo, srcRepo *gogit.Repository, r *plumbi
 	return fmt.Sprintf(" * [%s] %s -> %s", status, tagName, tagName), 1, nil
 }
 
-func (c *FetchCommand) Help() string {
-	return `📘 GIT-FETCH (1)                                        Git Manual
-
- 💡 DESCRIPTION
-    ・リモートリポジトリから最新の情報をダウンロードする
-    （ワーキングツリーのファイルは更新されません。あくまで情報取得のみです）
-    
-    「何が変わったか」を確認するのに安全な操作です。
-    取得した情報は ` + "`" + `git log origin/main` + "`" + ` などで確認できます。
-
- 📋 SYNOPSIS
-    git fetch [<remote>] [<branch>]
-    git fetch --all
-    git fetch --prune
-
- ⚙️  COMMON OPTIONS
-    --all
-        登録されている全てのリモートからフェッチします。
-
-    --tags, -t
-        リモートのタグも一緒にフェッチします。
-
-    --prune, -p
-        リモートで削除されたブランチに対応するローカルの追跡ブランチを削除します。
-        （これをやらないと、ローカルに古い origin/xxx が残り続けます）
-
-    --dry-run, -n
-        実際にはフェッチを行わず、何が行われるかを表示します。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: originから最新情報を取得
-       $ git fetch
-
-    2. 実践: 情報を整理しながら取得 (Recommended)
-       「リモートで消されたブランチは、ローカルの追跡情報からも消す」
-       $ git fetch -p
-
-    3. 実践: 特定のブランチだけ取得
-       「mainの更新だけ欲しい」という時に。
-       $ git fetch origin main
+// Spec implements git.SpecProvider.
+func (c *FetchCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--all"}, Usage: "Fetch every remote"},
+			{Flags: []string{"-p", "--prune"}, Usage: "Remove deleted remote branches"},
+			{Flags: []string{"-t", "--tags"}, Usage: "Fetch every tag"},
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would be fetched"},
+			{Flags: []string{"--unshallow"}, Usage: "Fetch the full history"},
+		},
+		Args: []string{git.ArgRemote, git.ArgBranch},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-fetch
-`
+func (c *FetchCommand) Help() string {
+	return git.CommandHelp(context.Background(), "fetch")
 }
 func (c *FetchCommand) pruneRemoteBranches(repo *gogit.Repository, remoteName string, remoteBranches map[string]bool, isDryRun bool) (int, []string, error) {
 	var results []string
@@ -88,7 +88,7 @@ func TestFetch_SpecificBranch(t *testing.T) {
 	// Get the SharedRemotes repo
 	for _, sharedRepo := range setup.SM.SharedRemotes {
 		// Copy the commit object
-		git.CopyCommitRecursive(setup.RemoteRepo, sharedRepo, featureCommit)
+		_ = git.TransferObjects(context.Background(), setup.RemoteRepo, sharedRepo, []plumbing.Hash{featureCommit}, nil)
 		// Update the branch reference
 		sharedRepo.Storer.SetReference(plumbing.NewHashReference(
 			plumbing.NewBranchReferenceName("feature"),
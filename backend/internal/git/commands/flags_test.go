@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlags_ConsistentAcrossCommands(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-flags")
+	ctx := context.Background()
+
+	run := func(input string) (string, error) {
+		name, args := git.ParseCommand(input)
+		res, err := git.Dispatch(ctx, s, name, args)
+		return res.Stdout, err
+	}
+
+	// Unknown flags fail the same way everywhere, where some commands used to ignore them
+	for _, input := range []string{"git checkout --bogus main", "git branch --bogus", "git fetch --bogus", "git push --bogus", "git merge --bogus main", "git rebase --bogus main"} {
+		_, err := run(input)
+		assert.EqualError(t, err, "error: unknown option `bogus'", input)
+	}
+	_, err := run("git merge -m")
+	assert.EqualError(t, err, "error: switch `m' requires a value")
+
+	// Combined short flags and attached values
+	_, err = run("git checkout -fbtopic")
+	require.NoError(t, err)
+	head, err := s.GetRepo().Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/topic", head.Name().String())
+
+	out, err := run("git branch -vv")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Initial commit")
+
+	_, err = run("git branch -fd topic")
+	assert.Error(t, err, "cannot delete the checked out branch")
+
+	out, err = run("git rebase -h")
+	require.NoError(t, err)
+	assert.Contains(t, out, "REBASE")
+}
@@ -0,0 +1,200 @@
+package commands
+
+// format_patch.go - Simulated Git Format-Patch Command
+//
+// Writes each commit of a range as an email ("0001-Subject.patch") into the
+// session filesystem, for "git am" to apply elsewhere. This is the
+// mailing-list workflow that predates pull requests and is still how
+// projects like the Linux kernel and Git itself take contributions.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("format-patch", func() git.Command { return &FormatPatchCommand{} })
+}
+
+type FormatPatchCommand struct{}
+
+// Ensure FormatPatchCommand implements git.Command
+var _ git.Command = (*FormatPatchCommand)(nil)
+
+type FormatPatchOptions struct {
+	OutputDir string // -o: directory for the patch files
+	Stdout    bool   // --stdout: print the mbox instead of writing files
+	Count     int    // -<n>: the last n commits of Rev
+	Rev       string // <since> or <range>; with Count, the commit to count back from
+}
+
+func (c *FormatPatchCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "format-patch"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	commits, err := c.resolveCommits(repo, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var mbox strings.Builder
+	var names []string
+	for i, commit := range commits {
+		text, err := git.FormatPatch(commit, i+1, len(commits))
+		if err != nil {
+			return "", err
+		}
+		if opts.Stdout {
+			mbox.WriteString(text)
+			continue
+		}
+
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		name := path.Join(opts.OutputDir, git.PatchFileName(i+1, subject))
+		target := sessionFilePath(s, name)
+		if _, err := s.Filesystem.Stat(target); err != nil {
+			if err := s.CheckStorageQuota(0, 0, 1); err != nil {
+				return "", err
+			}
+		}
+		if err := s.Filesystem.MkdirAll(path.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := util.WriteFile(s.Filesystem, target, []byte(text), os.FileMode(0644)); err != nil {
+			return "", fmt.Errorf("fatal: could not create '%s': %v", name, err)
+		}
+		names = append(names, name)
+	}
+
+	if opts.Stdout {
+		return strings.TrimSuffix(mbox.String(), "\n"), nil
+	}
+	return strings.Join(names, "\n"), nil
+}
+
+func (c *FormatPatchCommand) parseArgs(args []string) (*FormatPatchOptions, error) {
+	opts := &FormatPatchOptions{}
+	cmdArgs := args[1:]
+	for i := 0; i < len(cmdArgs); i++ {
+		arg := cmdArgs[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--stdout":
+			opts.Stdout = true
+		case arg == "-o" || arg == "--output-directory":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("error: switch `o' requires a value")
+			}
+			i++
+			opts.OutputDir = cmdArgs[i]
+		case strings.HasPrefix(arg, "--output-directory="):
+			opts.OutputDir = strings.TrimPrefix(arg, "--output-directory=")
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			n, err := strconv.Atoi(arg[1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("error: unknown option `%s'", arg)
+			}
+			opts.Count = n
+		default:
+			if opts.Rev != "" {
+				return nil, fmt.Errorf("fatal: format-patch takes a single revision or range")
+			}
+			opts.Rev = arg
+		}
+	}
+	if opts.Rev == "" && opts.Count == 0 {
+		return nil, fmt.Errorf("usage: git format-patch [-o <dir>] [--stdout] (<since> | <revision-range> | -<n> [<commit>])")
+	}
+	return opts, nil
+}
+
+// resolveCommits returns the commits to format, oldest first. Merge commits
+// are left out, as in git.
+func (c *FormatPatchCommand) resolveCommits(repo *gogit.Repository, opts *FormatPatchOptions) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	switch {
+	case opts.Count > 0:
+		rev := opts.Rev
+		if rev == "" {
+			rev = "HEAD"
+		}
+		hash, err := git.ResolveRevision(repo, rev)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+		}
+		iter, err := repo.Log(&gogit.LogOptions{From: *hash})
+		if err != nil {
+			return nil, err
+		}
+		_ = iter.ForEach(func(commit *object.Commit) error {
+			if len(commits) == opts.Count {
+				return storer.ErrStop
+			}
+			if commit.NumParents() <= 1 {
+				commits = append(commits, commit)
+			}
+			return nil
+		})
+		slices.Reverse(commits)
+	default:
+		// <since> means <since>..HEAD
+		rev := opts.Rev
+		if !git.IsRevisionRange(rev) {
+			rev += "..HEAD"
+		}
+		r, _, err := git.ParseRevisionRange(repo, rev)
+		if err != nil {
+			return nil, err
+		}
+		all, err := r.Commits(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range all {
+			if commit.NumParents() <= 1 {
+				commits = append(commits, commit)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *FormatPatchCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-o", "--output-directory"}, Arg: git.ArgPath, Usage: "Write the patch files into a directory"},
+			{Flags: []string{"--stdout"}, Usage: "Print the patches instead of writing files"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *FormatPatchCommand) Help() string {
+	return git.CommandHelp(context.Background(), "format-patch")
+}
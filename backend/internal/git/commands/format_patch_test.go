@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPatchTestSession returns a session whose main has README and whose
+// feature branch adds two commits by another author on top.
+func newPatchTestSession(t *testing.T) (*git.Session, *gogit.Repository, plumbing.Hash) {
+	t.Helper()
+	s, err := git.NewSessionManager().CreateSession("test-patch")
+	require.NoError(t, err)
+	r, err := s.InitRepo("repo")
+	require.NoError(t, err)
+	s.CurrentDir = "/repo"
+	w, _ := r.Worktree()
+	commit := func(msg string, author *object.Signature, files map[string]string) plumbing.Hash {
+		t.Helper()
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: author})
+		require.NoError(t, err)
+		return hash
+	}
+
+	alice := &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()}
+	bob := &object.Signature{Name: "Bob", Email: "bob@example.com", When: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	base := commit("Initial commit", alice, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	commit("Add greeting\n\nSay who we greet.\n", bob, map[string]string{"README.md": "hello\nworld\n", "docs/new.txt": "new\n"})
+	commit("Shout", bob, map[string]string{"README.md": "hello\nworld!\n"})
+
+	return s, r, base
+}
+
+func TestFormatPatch_AmRoundTrip(t *testing.T) {
+	s, repo, _ := newPatchTestSession(t)
+	ctx := context.Background()
+
+	_, err := (&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "main"})
+	require.NoError(t, err)
+	out, err := (&FormatPatchCommand{}).Execute(ctx, s, []string{"format-patch", "main..feature", "-o", "out"})
+	require.NoError(t, err)
+	assert.Equal(t, "out/0001-Add-greeting.patch\nout/0002-Shout.patch", out)
+	first, err := util.ReadFile(s.Filesystem, "/repo/out/0001-Add-greeting.patch")
+	require.NoError(t, err)
+	assert.Contains(t, string(first), "From: Bob <bob@example.com>")
+	assert.Contains(t, string(first), "Subject: [PATCH 1/2] Add greeting")
+	assert.Contains(t, string(first), " 2 files changed, 2 insertions(+)")
+
+	out, err = (&AmCommand{}).Execute(ctx, s, []string{"am", "out/0001-Add-greeting.patch", "out/0002-Shout.patch"})
+	require.NoError(t, err)
+	assert.Equal(t, "Applying: Add greeting\nApplying: Shout", out)
+
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	assert.Equal(t, "Shout\n", commit.Message)
+	assert.Equal(t, "Bob", commit.Author.Name)
+	assert.True(t, commit.Author.When.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+	parent, _ := commit.Parent(0)
+	assert.Equal(t, "Add greeting\n\nSay who we greet.\n", parent.Message)
+	feature, _ := repo.Reference(plumbing.NewBranchReferenceName("feature"), true)
+	featureCommit, _ := repo.CommitObject(feature.Hash())
+	assert.Equal(t, featureCommit.TreeHash, commit.TreeHash, "am should rebuild the same tree")
+}
+
+func TestApply_CheckIndexAndThreeWay(t *testing.T) {
+	s, repo, base := newPatchTestSession(t)
+	ctx := context.Background()
+	w, _ := repo.Worktree()
+
+	_, err := (&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", base.String()})
+	require.NoError(t, err)
+	_, err = (&FormatPatchCommand{}).Execute(ctx, s, []string{"format-patch", "-1", "-o", "..", "feature~1"})
+	require.NoError(t, err)
+
+	out, err := (&ApplyCommand{}).Execute(ctx, s, []string{"apply", "--check", "../0001-Add-greeting.patch"})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	readme, _ := util.ReadFile(w.Filesystem, "README.md")
+	assert.Equal(t, "hello\n", string(readme), "--check must not change anything")
+
+	out, err = (&ApplyCommand{}).Execute(ctx, s, []string{"apply", "--stat", "../0001-Add-greeting.patch"})
+	require.NoError(t, err)
+	assert.Contains(t, out, " 2 files changed, 2 insertions(+), 0 deletions(-)")
+
+	out, err = (&ApplyCommand{}).Execute(ctx, s, []string{"apply", "--index", "../0001-Add-greeting.patch"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "Applied patch to 'README.md' cleanly.")
+	status, _ := git.LFSAwareStatus(repo, w)
+	assert.Equal(t, gogit.Added, status.File("docs/new.txt").Staging)
+	assert.Equal(t, gogit.Modified, status.File("README.md").Staging)
+
+	// A README that changed since the patch was made needs a 3-way merge
+	require.NoError(t, w.Reset(&gogit.ResetOptions{Commit: base, Mode: gogit.HardReset}))
+	require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("howdy\n"), 0644))
+	_, err = (&ApplyCommand{}).Execute(ctx, s, []string{"apply", "--check", "../0001-Add-greeting.patch"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error: README.md: patch does not apply")
+	_, statErr := w.Filesystem.Stat("docs/new.txt")
+	assert.Error(t, statErr, "a failed apply must not apply the other files")
+
+	_, err = (&ApplyCommand{}).Execute(ctx, s, []string{"apply", "-3", "../0001-Add-greeting.patch"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Applied patch to 'README.md' with conflicts.")
+	assert.Contains(t, err.Error(), "U README.md")
+	readme, _ = util.ReadFile(w.Filesystem, "README.md")
+	assert.Equal(t, "<<<<<<< HEAD\nhowdy\n=======\nhello\nworld\n>>>>>>> theirs\n", string(readme))
+}
+
+func TestAm_ThreeWayConflictContinueAndAbort(t *testing.T) {
+	s, repo, _ := newPatchTestSession(t)
+	ctx := context.Background()
+	w, _ := repo.Worktree()
+	cmd := &AmCommand{}
+
+	_, err := (&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "main"})
+	require.NoError(t, err)
+	mbox, err := (&FormatPatchCommand{}).Execute(ctx, s, []string{"format-patch", "--stdout", "main..feature"})
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(s.Filesystem, "/series.mbox", []byte(mbox+"\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("howdy\n"), 0644))
+	_, _ = w.Add("README.md")
+	_, err = cmd.Execute(ctx, s, []string{"am", "../series.mbox"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Dirty index")
+	_, err = w.Commit("Howdy", &gogit.CommitOptions{Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()}})
+	require.NoError(t, err)
+	origHead, _ := repo.Head()
+
+	// Without -3 the series stops on the first patch, leaving nothing applied
+	_, err = cmd.Execute(ctx, s, []string{"am", "../series.mbox"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Patch failed at 0001 Add greeting")
+	require.NotNil(t, s.AmInProgress())
+	_, err = cmd.Execute(ctx, s, []string{"am", "../series.mbox"})
+	assert.ErrorContains(t, err, "previous am is still in progress")
+	_, err = cmd.Execute(ctx, s, []string{"am", "--abort"})
+	require.NoError(t, err)
+	assert.Nil(t, s.AmInProgress())
+
+	// With -3 it stops on a conflict to resolve
+	_, err = cmd.Execute(ctx, s, []string{"am", "-3", "../series.mbox"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CONFLICT (content): Merge conflict in README.md")
+	_, err = cmd.Execute(ctx, s, []string{"am", "--continue"})
+	assert.ErrorContains(t, err, "U\tREADME.md")
+
+	require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("hello\nworld\n"), 0644))
+	_, _ = w.Add("README.md")
+	out, err := cmd.Execute(ctx, s, []string{"am", "--continue"})
+	require.NoError(t, err)
+	assert.Equal(t, "Applying: Add greeting\nApplying: Shout", out)
+	assert.Nil(t, s.AmInProgress())
+	readme, _ := util.ReadFile(w.Filesystem, "README.md")
+	assert.Equal(t, "hello\nworld!\n", string(readme))
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	parent, _ := commit.Parent(0)
+	grandparent, _ := parent.Parent(0)
+	assert.Equal(t, origHead.Hash(), grandparent.Hash)
+
+	// --abort goes back to where the series started
+	require.NoError(t, w.Reset(&gogit.ResetOptions{Commit: origHead.Hash(), Mode: gogit.HardReset}))
+	_, err = cmd.Execute(ctx, s, []string{"am", "-3", "../series.mbox"})
+	require.Error(t, err)
+	_, err = cmd.Execute(ctx, s, []string{"am", "--abort"})
+	require.NoError(t, err)
+	head, _ = repo.Head()
+	assert.Equal(t, origHead.Hash(), head.Hash())
+	readme, _ = util.ReadFile(w.Filesystem, "README.md")
+	assert.Equal(t, "howdy\n", string(readme))
+}
@@ -0,0 +1,113 @@
+package commands
+
+// fsck.go - Simulated Git Fsck Command
+//
+// Verifies the connectivity of the object graph: every object a ref, the
+// index or the reflog leads to must exist. Objects nothing leads to are
+// reported as dangling, which makes the commits left behind by amend, reset
+// or a deleted branch visible.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("fsck", func() git.Command { return &FsckCommand{} })
+}
+
+type FsckCommand struct{}
+
+// Ensure FsckCommand implements git.Command
+var _ git.Command = (*FsckCommand)(nil)
+
+type FsckOptions struct {
+	NoDangling  bool // --no-dangling: do not list dangling objects
+	Unreachable bool // --unreachable: list every unreachable object, not only the dangling ones
+	NoReflogs   bool // --no-reflogs: do not treat reflog entries as roots
+}
+
+func (c *FsckCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "fsck"), nil
+		}
+		return "", err
+	}
+
+	if s.GetRepo() == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	report := s.Fsck(strings.TrimPrefix(s.CurrentDir, "/"), !opts.NoReflogs)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Checking objects: %d, done.\n", report.Checked))
+	for _, link := range report.Missing {
+		if link.From != nil {
+			sb.WriteString(fmt.Sprintf("broken link from %7s %s\n", link.From.Type, link.From.Hash))
+			sb.WriteString(fmt.Sprintf("              to %7s %s\n", link.To.Type, link.To.Hash))
+		}
+		sb.WriteString(fmt.Sprintf("missing %s %s\n", link.To.Type, link.To.Hash))
+	}
+	switch {
+	case opts.Unreachable:
+		for _, obj := range report.Unreachable {
+			sb.WriteString(fmt.Sprintf("unreachable %s %s\n", obj.Type, obj.Hash))
+		}
+	case !opts.NoDangling:
+		for _, obj := range report.Dangling {
+			sb.WriteString(fmt.Sprintf("dangling %s %s\n", obj.Type, obj.Hash))
+		}
+	}
+	return sb.String(), nil
+}
+
+func (c *FsckCommand) parseArgs(args []string) (*FsckOptions, error) {
+	opts := &FsckOptions{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "--no-dangling":
+			opts.NoDangling = true
+		case "--dangling":
+			opts.NoDangling = false
+		case "--unreachable":
+			opts.Unreachable = true
+		case "--no-reflogs":
+			opts.NoReflogs = true
+		case "--full", "--strict", "--connectivity-only", "--no-progress":
+			// Every object is always checked
+		default:
+			return nil, fmt.Errorf("error: unknown option '%s'\nusage: git fsck [<options>]", strings.TrimLeft(arg, "-"))
+		}
+	}
+	return opts, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *FsckCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--full"}, Usage: "Check every object"},
+			{Flags: []string{"--strict"}, Usage: "Check objects strictly"},
+			{Flags: []string{"--unreachable"}, Usage: "Show unreachable objects"},
+			{Flags: []string{"--dangling"}, Usage: "Show dangling objects"},
+			{Flags: []string{"--no-dangling"}, Usage: "Hide dangling objects"},
+			{Flags: []string{"--no-reflogs"}, Usage: "Do not count reflogs as references"},
+			{Flags: []string{"--connectivity-only"}, Usage: "Check only connectivity"},
+			{Flags: []string{"--no-progress"}, Usage: "Do not show progress"},
+		},
+	}
+}
+
+func (c *FsckCommand) Help() string {
+	return git.CommandHelp(context.Background(), "fsck")
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fsckSession creates a repo with two commits of a.txt and returns their hashes.
+func fsckSession(t *testing.T) (*git.Session, []string) {
+	t.Helper()
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-fsck")
+	ctx := context.Background()
+	_, err := s.InitRepo("repo")
+	require.NoError(t, err)
+	s.CurrentDir = "/repo"
+
+	var hashes []string
+	for _, content := range []string{"first\n", "second\n"} {
+		require.NoError(t, util.WriteFile(s.Filesystem, "/repo/a.txt", []byte(content), 0644))
+		_, err = git.Dispatch(ctx, s, "add", []string{"add", "a.txt"})
+		require.NoError(t, err)
+		_, err = git.Dispatch(ctx, s, "commit", []string{"commit", "-m", "write " + strings.TrimSpace(content)})
+		require.NoError(t, err)
+		head, err := s.GetRepo().Head()
+		require.NoError(t, err)
+		hashes = append(hashes, head.Hash().String())
+	}
+	return s, hashes
+}
+
+func TestFsckCommand_Dangling(t *testing.T) {
+	s, hashes := fsckSession(t)
+	ctx := context.Background()
+	fsck := &FsckCommand{}
+
+	out, err := fsck.Execute(ctx, s, []string{"fsck"})
+	require.NoError(t, err)
+	assert.NotContains(t, out, "dangling")
+
+	_, err = git.Dispatch(ctx, s, "reset", []string{"reset", "--hard", "HEAD~1"})
+	require.NoError(t, err)
+
+	out, err = fsck.Execute(ctx, s, []string{"fsck"})
+	require.NoError(t, err)
+	assert.NotContains(t, out, "dangling", "the reflog still reaches the reset commit")
+
+	out, err = fsck.Execute(ctx, s, []string{"fsck", "--no-reflogs"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "dangling commit "+hashes[1]+"\n")
+	assert.Equal(t, 1, strings.Count(out, "dangling"), "the tree and blob of the commit are not dangling")
+
+	out, err = fsck.Execute(ctx, s, []string{"fsck", "--no-reflogs", "--unreachable"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "unreachable commit "+hashes[1]+"\n")
+	assert.Contains(t, out, "unreachable blob ")
+	assert.Contains(t, out, "unreachable tree ")
+}
+
+func TestCatFileCommand(t *testing.T) {
+	s, hashes := fsckSession(t)
+	ctx := context.Background()
+	cat := &CatFileCommand{}
+
+	out, err := cat.Execute(ctx, s, []string{"cat-file", "-t", hashes[1][:7]})
+	require.NoError(t, err)
+	assert.Equal(t, "commit\n", out)
+
+	out, err = cat.Execute(ctx, s, []string{"cat-file", "-p", "HEAD"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "parent "+hashes[0]+"\n")
+	assert.Contains(t, out, "\n\nwrite second")
+
+	out, err = cat.Execute(ctx, s, []string{"cat-file", "-p", "HEAD^{tree}"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^100644 blob [0-9a-f]{40}\ta\.txt\n$`, out)
+
+	out, err = cat.Execute(ctx, s, []string{"cat-file", "-p", "HEAD~1:a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", out)
+
+	out, err = cat.Execute(ctx, s, []string{"cat-file", "-s", ":a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "7\n", out)
+
+	out, err = cat.Execute(ctx, s, []string{"cat-file", "blob", "HEAD:a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", out)
+
+	_, err = cat.Execute(ctx, s, []string{"cat-file", "tree", "HEAD:a.txt"})
+	assert.ErrorContains(t, err, "bad file")
+
+	_, err = cat.Execute(ctx, s, []string{"cat-file", "-e", "deadbeef"})
+	assert.ErrorContains(t, err, "does not exist")
+
+	_, err = cat.Execute(ctx, s, []string{"cat-file", "HEAD"})
+	assert.ErrorContains(t, err, "usage")
+}
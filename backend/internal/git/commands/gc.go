@@ -0,0 +1,134 @@
+package commands
+
+// gc.go - Simulated Git GC Command
+//
+// Commands never delete objects themselves: amending, rebasing or deleting a
+// branch leaves the old commits behind. git gc drops the objects that no ref,
+// reflog entry, undo snapshot or operation in progress can reach any more,
+// and reports the object count before and after.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("gc", func() git.Command { return &GCCommand{} })
+}
+
+type GCCommand struct{}
+
+// Ensure GCCommand implements git.Command
+var _ git.Command = (*GCCommand)(nil)
+
+type GCOptions struct {
+	NoPrune bool // --no-prune: report unreachable objects but keep them
+	Auto    bool // --auto: only run when enough objects are unreachable
+	Quiet   bool
+}
+
+func (c *GCCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "gc"), nil
+		}
+		return "", err
+	}
+
+	if s.GetRepo() == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	path := strings.TrimPrefix(s.CurrentDir, "/")
+
+	if opts.Auto && !gcRecommended(s, path) {
+		return "", nil
+	}
+
+	result, err := s.CollectGarbage(path, opts.NoPrune)
+	if err != nil {
+		return "", fmt.Errorf("fatal: gc failed: %v", err)
+	}
+	if opts.Quiet {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Enumerating objects: %d, done.\n", result.Before.Count))
+	if opts.NoPrune {
+		sb.WriteString(fmt.Sprintf("Unreachable objects kept (--no-prune): %d\n", result.Pruned))
+	} else {
+		sb.WriteString(fmt.Sprintf("Removing unreachable objects: %d, done.\n", result.Pruned))
+	}
+	sb.WriteString(fmt.Sprintf("before: %s\n", formatObjectCount(result.Before)))
+	sb.WriteString(fmt.Sprintf("after:  %s\n", formatObjectCount(result.After)))
+	return sb.String(), nil
+}
+
+func (c *GCCommand) parseArgs(args []string) (*GCOptions, error) {
+	opts := &GCOptions{}
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--no-prune":
+			opts.NoPrune = true
+		case arg == "--prune" || strings.HasPrefix(arg, "--prune="):
+			// Objects have no age in the simulation: every date prunes now
+			opts.NoPrune = false
+		case arg == "--auto":
+			opts.Auto = true
+		case arg == "-q" || arg == "--quiet":
+			opts.Quiet = true
+		case arg == "--aggressive":
+			// Nothing to repack
+		default:
+			return nil, fmt.Errorf("error: unknown option '%s'\nusage: git gc [<options>]", strings.TrimLeft(arg, "-"))
+		}
+	}
+	return opts, nil
+}
+
+// gcRecommended reports whether enough objects of the repository at path are
+// unreachable for git gc --auto to run, as the maintenance report decides it.
+func gcRecommended(s *git.Session, path string) bool {
+	for _, repo := range git.BuildMaintenanceReport(s).Repos {
+		if repo.Path == path {
+			return repo.GCRecommended
+		}
+	}
+	return false
+}
+
+// formatObjectCount renders a count like the first line of git count-objects.
+func formatObjectCount(count git.ObjectCount) string {
+	return fmt.Sprintf("%d objects, %d kilobytes", count.Count, kilobytes(count.Size))
+}
+
+// kilobytes rounds a byte size up to whole KiB.
+func kilobytes(size int64) int64 {
+	return (size + 1023) / 1024
+}
+
+// Spec implements git.SpecProvider.
+func (c *GCCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--aggressive"}, Usage: "Optimize more thoroughly"},
+			{Flags: []string{"--auto"}, Usage: "Run only when needed"},
+			{Flags: []string{"--prune"}, Usage: "Prune unreachable objects"},
+			{Flags: []string{"--no-prune"}, Usage: "Keep unreachable objects"},
+			{Flags: []string{"-q", "--quiet"}, Usage: "Print nothing"},
+		},
+	}
+}
+
+func (c *GCCommand) Help() string {
+	return git.CommandHelp(context.Background(), "gc")
+}
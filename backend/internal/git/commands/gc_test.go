@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCCommand_PrunesReplacedBlob(t *testing.T) {
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-gc")
+	ctx := context.Background()
+
+	_, err := s.InitRepo("repo")
+	require.NoError(t, err)
+	s.CurrentDir = "/repo"
+
+	require.NoError(t, util.WriteFile(s.Filesystem, "/repo/a.txt", []byte("first\n"), 0644))
+	_, err = (&AddCommand{}).Execute(ctx, s, []string{"add", "a.txt"})
+	require.NoError(t, err)
+	// Re-staging leaves the first blob unreachable
+	require.NoError(t, util.WriteFile(s.Filesystem, "/repo/a.txt", []byte("second\n"), 0644))
+	_, err = (&AddCommand{}).Execute(ctx, s, []string{"add", "a.txt"})
+	require.NoError(t, err)
+
+	count := &CountObjectsCommand{}
+	out, err := count.Execute(ctx, s, []string{"count-objects"})
+	require.NoError(t, err)
+	assert.Equal(t, "2 objects, 1 kilobytes\n", out)
+
+	gc := &GCCommand{}
+	out, err = gc.Execute(ctx, s, []string{"gc", "--no-prune"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "Unreachable objects kept (--no-prune): 1\n")
+
+	out, err = gc.Execute(ctx, s, []string{"gc", "--auto"})
+	require.NoError(t, err)
+	assert.Empty(t, out, "--auto does nothing below the threshold")
+
+	out, err = gc.Execute(ctx, s, []string{"gc", "--prune=now"})
+	require.NoError(t, err)
+	assert.Equal(t, "Enumerating objects: 2, done.\n"+
+		"Removing unreachable objects: 1, done.\n"+
+		"before: 2 objects, 1 kilobytes\n"+
+		"after:  1 objects, 1 kilobytes\n", out)
+
+	out, err = count.Execute(ctx, s, []string{"count-objects", "-v"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "count: 1\nsize: 1\nin-pack: 0\n")
+
+	_, err = gc.Execute(ctx, s, []string{"gc", "--bogus"})
+	assert.Error(t, err)
+}
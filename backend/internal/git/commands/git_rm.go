@@ -127,6 +127,18 @@ func (c *GitRmCommand) executeGitRm(s *git.Session, opts *GitRmOptions) (string,
 	return strings.Join(removed, "\n"), nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *GitRmCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--cached"}, Usage: "Remove only from the index"},
+			{Flags: []string{"-f"}, Usage: "Remove modified files too"},
+			{Flags: []string{"-r"}, Usage: "Remove directories recursively"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
 func (c *GitRmCommand) Help() string {
-	return "usage: git rm <file>...\n\nRemove files from the working tree and from the index."
+	return git.CommandHelp(context.Background(), "git-rm")
 }
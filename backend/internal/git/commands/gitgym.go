@@ -0,0 +1,262 @@
+package commands
+
+// gitgym.go - "gitgym status", "gitgym undo", "gitgym redo", "gitgym save",
+// "gitgym restore", "gitgym recover" and "gitgym changelog"
+//
+// Shows what normally stays invisible: which storage backend each repository
+// uses, how many objects a gc would prune, cache sizes and persistence
+// snapshots. Useful for debugging and for an "under the hood" lesson.
+//
+// undo and redo step through the session's undo history: unlike "git undo",
+// which reverses the last git operation the way a Git user would, they put
+// refs, index and files back exactly as they were before any command.
+// save, saves and restore keep named states to come back to later.
+//
+// recover lists the commits the reflog remembers that no branch reaches any
+// more, such as commits made on a detached HEAD, and suggests a branch for each.
+//
+// changelog groups the commits between two tags by Conventional Commits type.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("gitgym", func() git.Command { return &GitGymCommand{} })
+}
+
+type GitGymCommand struct{}
+
+// Ensure GitGymCommand implements git.Command
+var _ git.Command = (*GitGymCommand)(nil)
+
+func (c *GitGymCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	sub := "status"
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	switch sub {
+	case "-h", "--help", "help":
+		return git.CommandHelp(ctx, "gitgym"), nil
+	case "status", "maintenance":
+		s.RLock()
+		defer s.RUnlock()
+		return formatMaintenanceReport(git.BuildMaintenanceReport(s)), nil
+	case "undo":
+		s.Lock()
+		defer s.Unlock()
+		snap, err := s.Undo()
+		if err != nil {
+			return "", fmt.Errorf("gitgym: %w", err)
+		}
+		return fmt.Sprintf("Undid: %s\nhint: Run 'gitgym redo' to bring it back.", snap.Command), nil
+	case "redo":
+		s.Lock()
+		defer s.Unlock()
+		snap, err := s.Redo()
+		if err != nil {
+			return "", fmt.Errorf("gitgym: %w", err)
+		}
+		return fmt.Sprintf("Redid: %s", snap.Command), nil
+	case "save":
+		s.Lock()
+		defer s.Unlock()
+		return savePoint(s, args[2:])
+	case "saves":
+		s.RLock()
+		defer s.RUnlock()
+		return formatSavePoints(s.SavePoints()), nil
+	case "restore":
+		if len(args) != 3 {
+			return "", fmt.Errorf("usage: gitgym restore <name>")
+		}
+		s.Lock()
+		defer s.Unlock()
+		sp, err := s.RestoreSavePoint(args[2])
+		if err != nil {
+			return "", fmt.Errorf("gitgym: %w", err)
+		}
+		return fmt.Sprintf("Restored save point '%s' (%s)\nhint: Run 'gitgym undo' to go back to where you were.", sp.Name, sp.CreatedAt.Format(time.RFC3339)), nil
+	case "history":
+		s.RLock()
+		defer s.RUnlock()
+		undo, redo := s.UndoHistory()
+		return formatUndoHistory(undo, redo), nil
+	case "recover":
+		s.RLock()
+		defer s.RUnlock()
+		if s.GetRepo() == nil {
+			return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+		}
+		return formatLostCommits(git.LostCommits(s)), nil
+	case "changelog":
+		s.RLock()
+		defer s.RUnlock()
+		repo := s.GetRepo()
+		if repo == nil {
+			return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+		}
+		if len(args) > 4 {
+			return "", fmt.Errorf("usage: gitgym changelog [<from> [<to>]]")
+		}
+		var from, to string
+		if len(args) > 2 {
+			from = args[2]
+		}
+		if len(args) > 3 {
+			to = args[3]
+		}
+		return changelog(repo, from, to)
+	default:
+		return "", fmt.Errorf("gitgym: '%s' is not a gitgym command\nhint: Supported: status, undo, redo, history, save, saves, restore, recover, changelog", sub)
+	}
+}
+
+// savePoint runs "gitgym save [<name>]" and "gitgym save -d <name>". Caller
+// holds the session lock.
+func savePoint(s *git.Session, args []string) (string, error) {
+	if len(args) == 2 && (args[0] == "-d" || args[0] == "--delete") {
+		if err := s.DeleteSavePoint(args[1]); err != nil {
+			return "", fmt.Errorf("gitgym: %w", err)
+		}
+		return fmt.Sprintf("Deleted save point '%s'", args[1]), nil
+	}
+	if len(args) > 1 || (len(args) == 1 && strings.HasPrefix(args[0], "-")) {
+		return "", fmt.Errorf("usage: gitgym save [<name>]\n   or: gitgym save -d <name>")
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	sp, err := s.CreateSavePoint(name)
+	if err != nil {
+		return "", fmt.Errorf("gitgym: %w", err)
+	}
+	arg := sp.Name
+	if strings.Contains(arg, " ") {
+		arg = `"` + arg + `"`
+	}
+	return fmt.Sprintf("Saved '%s'\nhint: Run 'gitgym restore %s' to come back here.", sp.Name, arg), nil
+}
+
+func formatSavePoints(points []git.SavePoint) string {
+	if len(points) == 0 {
+		return "No save points yet.\nhint: Run 'gitgym save <name>' to keep the current state."
+	}
+	var sb strings.Builder
+	sb.WriteString("Save points (oldest first):\n")
+	for i, sp := range points {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("  %s  %s", sp.CreatedAt.Format(time.RFC3339), sp.Name))
+		if sp.Auto && sp.Command != "" {
+			sb.WriteString(fmt.Sprintf(" (before: %s)", sp.Command))
+		}
+	}
+	return sb.String()
+}
+
+func formatUndoHistory(undo, redo []string) string {
+	var sb strings.Builder
+	sb.WriteString("Can be undone (most recent first):\n")
+	if len(undo) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for i, cmd := range undo {
+		sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, cmd))
+	}
+	sb.WriteString("\nCan be redone:\n")
+	if len(redo) == 0 {
+		sb.WriteString("  (none)")
+	}
+	for i, cmd := range redo {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("  %d. %s", i+1, cmd))
+	}
+	return sb.String()
+}
+
+func formatLostCommits(lost []git.LostCommit) string {
+	if len(lost) == 0 {
+		return "No lost commits: every commit in the reflog is reachable from a branch or tag."
+	}
+	var sb strings.Builder
+	sb.WriteString("Commits no branch reaches any more (most recent first):\n\n")
+	for i, c := range lost {
+		short := c.Hash.String()[:7]
+		sb.WriteString(fmt.Sprintf("  %s %s\n", short, c.Subject))
+		sb.WriteString(fmt.Sprintf("      reflog: %s (%s)\n", c.Reflog, c.When.Format(time.RFC3339)))
+		sb.WriteString(fmt.Sprintf("      keep it: git branch recovered-%d %s\n", i+1, short))
+	}
+	sb.WriteString("\nhint: Commits nothing points at are removed by 'git gc' once the reflog forgets them.")
+	return sb.String()
+}
+
+func formatMaintenanceReport(r git.MaintenanceReport) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Session %s (age %s)\n", r.SessionID, r.GeneratedAt.Sub(r.CreatedAt).Round(time.Second)))
+
+	sb.WriteString("\nRepositories:\n")
+	if len(r.Repos) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, repo := range r.Repos {
+		sb.WriteString(fmt.Sprintf("  %s\n", repo.Path))
+		sb.WriteString(fmt.Sprintf("    storage:      %s\n", repo.Storage))
+		if repo.Storage == "hybrid" {
+			sb.WriteString("    objects:      shared with remote (not counted)\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("    objects:      %d (%d packs)\n", repo.Objects, repo.Packs))
+		}
+		sb.WriteString(fmt.Sprintf("    refs:         %d\n", repo.Refs))
+		gc := "none"
+		if repo.UnreachableObjects > 0 {
+			gc = fmt.Sprintf("%d unreachable objects", repo.UnreachableObjects)
+			if repo.GCRecommended {
+				gc += " (gc recommended)"
+			}
+		}
+		sb.WriteString(fmt.Sprintf("    pending gc:   %s\n", gc))
+	}
+
+	sb.WriteString("\nCaches:\n")
+	dirty := ""
+	if r.Caches.FileCacheDirty {
+		dirty = ", dirty"
+	}
+	sb.WriteString(fmt.Sprintf("  file listing:  %d entries (%dms old%s)\n", r.Caches.FileCacheEntries, r.Caches.FileCacheAgeMs, dirty))
+	sb.WriteString(fmt.Sprintf("  lfs objects:   %d (%d bytes)\n", r.Caches.LFSObjects, r.Caches.LFSBytes))
+	sb.WriteString(fmt.Sprintf("  reflog:        %d entries\n", r.Caches.ReflogEntries))
+	sb.WriteString(fmt.Sprintf("  lineage:       %d rewritten commits\n", r.Caches.LineageLinks))
+	sb.WriteString(fmt.Sprintf("  search index:  %d commits\n", r.Caches.SearchIndexed))
+
+	sb.WriteString("\nSnapshots:\n")
+	if !r.Snapshots.PersistenceEnabled {
+		sb.WriteString("  persistence disabled (set GITGYM_PERSIST_SESSIONS=true)")
+	} else if r.Snapshots.LastSavedAt == nil {
+		sb.WriteString("  persistence enabled, not saved yet")
+	} else {
+		sb.WriteString(fmt.Sprintf("  %d saves, last at %s", r.Snapshots.Saves, r.Snapshots.LastSavedAt.Format(time.RFC3339)))
+	}
+	return sb.String()
+}
+
+// Spec implements git.SpecProvider.
+func (c *GitGymCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell:       true,
+		Subcommands: []string{"changelog", "history", "maintenance", "recover", "redo", "restore", "save", "saves", "status", "undo"},
+	}
+}
+
+func (c *GitGymCommand) Help() string {
+	return git.CommandHelp(context.Background(), "gitgym")
+}
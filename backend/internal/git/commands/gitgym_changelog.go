@@ -0,0 +1,170 @@
+package commands
+
+// gitgym_changelog.go - "gitgym changelog"
+//
+// Groups the commits between two tags by their Conventional Commits type into
+// a Markdown changelog, the way tools like conventional-changelog do. Commits
+// that do not follow the format are listed under "Other Changes".
+
+import (
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// changelogSections are the changelog headings, in order, and the commit types under each.
+var changelogSections = []struct {
+	Title string
+	Types []string
+}{
+	{"Features", []string{"feat"}},
+	{"Bug Fixes", []string{"fix"}},
+	{"Performance Improvements", []string{"perf"}},
+	{"Reverts", []string{"revert"}},
+	{"Documentation", []string{"docs"}},
+	{"Code Refactoring", []string{"refactor", "style"}},
+	{"Tests", []string{"test"}},
+	{"Build System", []string{"build", "ci"}},
+	{"Chores", []string{"chore"}},
+}
+
+// changelog renders the commits in from..to. An empty from means the latest
+// tag before to, or the whole history when there is none.
+func changelog(repo *gogit.Repository, from, to string) (string, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+	toHash, err := git.ResolveRevision(repo, to)
+	if err != nil {
+		return "", fmt.Errorf("fatal: bad revision '%s'", to)
+	}
+	tags := tagsByCommit(repo)
+
+	r := &git.RevisionRange{Include: []plumbing.Hash{*toHash}}
+	if from == "" {
+		from = previousTag(repo, *toHash, tags)
+	}
+	if from != "" {
+		fromHash, err := git.ResolveRevision(repo, from)
+		if err != nil {
+			return "", fmt.Errorf("fatal: bad revision '%s'", from)
+		}
+		r.Exclude = []plumbing.Hash{*fromHash}
+	}
+	commits, err := r.Commits(repo)
+	if err != nil {
+		return "", err
+	}
+
+	title := "Unreleased"
+	if name, ok := tags[*toHash]; ok {
+		title = name
+	} else if to != "HEAD" {
+		title = to
+	}
+
+	var breaking []string
+	entries := make(map[string][]string)
+	var other []string
+	// Newest first, like git log
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		if commit.NumParents() > 1 {
+			continue
+		}
+		short := commit.Hash.String()[:7]
+		cc, err := git.ParseConventionalCommit(commit.Message)
+		if err != nil {
+			subject := strings.TrimSpace(strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0])
+			other = append(other, fmt.Sprintf("- %s (%s)", subject, short))
+			continue
+		}
+		entry := fmt.Sprintf("- %s (%s)", cc.Description, short)
+		if cc.Scope != "" {
+			entry = fmt.Sprintf("- **%s:** %s (%s)", cc.Scope, cc.Description, short)
+		}
+		entries[cc.Type] = append(entries[cc.Type], entry)
+		if cc.Breaking {
+			breaking = append(breaking, entry)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## " + title + "\n")
+	if len(commits) == 0 {
+		sb.WriteString("\nNo changes.\n")
+		return strings.TrimRight(sb.String(), "\n"), nil
+	}
+	writeSection := func(heading string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		sb.WriteString("\n### " + heading + "\n\n")
+		sb.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	writeSection("BREAKING CHANGES", breaking)
+	for _, section := range changelogSections {
+		var lines []string
+		for _, t := range section.Types {
+			lines = append(lines, entries[t]...)
+		}
+		writeSection(section.Title, lines)
+	}
+	writeSection("Other Changes", other)
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// tagsByCommit maps each tagged commit to a tag name, peeling annotated tags.
+func tagsByCommit(repo *gogit.Repository) map[plumbing.Hash]string {
+	tags := make(map[plumbing.Hash]string)
+	iter, err := repo.Tags()
+	if err != nil {
+		return tags
+	}
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil // Tag of a tree or blob
+			}
+			hash = commit.Hash
+		}
+		if name, ok := tags[hash]; !ok || ref.Name().Short() < name {
+			tags[hash] = ref.Name().Short()
+		}
+		return nil
+	})
+	return tags
+}
+
+// previousTag returns the name of the nearest tagged commit reachable from
+// to, to itself excluded, like "git describe --tags --abbrev=0 <to>^".
+func previousTag(repo *gogit.Repository, to plumbing.Hash, tags map[plumbing.Hash]string) string {
+	commit, err := repo.CommitObject(to)
+	if err != nil {
+		return ""
+	}
+	seen := make(map[plumbing.Hash]bool)
+	queue := append([]plumbing.Hash(nil), commit.ParentHashes...)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if name, ok := tags[hash]; ok {
+			return name
+		}
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			continue // Shallow boundary
+		}
+		queue = append(queue, c.ParentHashes...)
+	}
+	return ""
+}
@@ -0,0 +1,359 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestGitGymStatus_ReportsStorageAndPendingGC(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-status")
+	ctx := context.Background()
+
+	// Amending twice leaves the original commit unreachable once the reflog no longer
+	// mentions it (ORIG_HEAD only keeps the previous one)
+	if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "second"}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	for _, msg := range []string{"reworded", "reworded again"} {
+		if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "--amend", "-m", msg}); err != nil {
+			t.Fatalf("Amend failed: %v", err)
+		}
+	}
+
+	s.RLock()
+	report := git.BuildMaintenanceReport(s)
+	s.RUnlock()
+	if report.Repos[0].UnreachableObjects != 0 {
+		t.Errorf("Expected reflog entries to keep amended commits reachable, got %d unreachable", report.Repos[0].UnreachableObjects)
+	}
+
+	// As after "git reflog expire --expire=now --all"
+	s.Lock()
+	s.Reflog = nil
+	report = git.BuildMaintenanceReport(s)
+	s.Unlock()
+
+	if len(report.Repos) != 1 {
+		t.Fatalf("Expected 1 repo in report, got %d", len(report.Repos))
+	}
+	repo := report.Repos[0]
+	if repo.Storage != "memory" {
+		t.Errorf("Expected memory storage, got %q", repo.Storage)
+	}
+	if repo.UnreachableObjects == 0 || repo.UnreachableObjects >= repo.Objects {
+		t.Errorf("Expected some but not all objects unreachable, got %d of %d", repo.UnreachableObjects, repo.Objects)
+	}
+	if report.Snapshots.PersistenceEnabled {
+		t.Error("Expected persistence to be disabled by default")
+	}
+
+	out, err := (&GitGymCommand{}).Execute(ctx, s, []string{"gitgym", "status"})
+	if err != nil {
+		t.Fatalf("gitgym status failed: %v", err)
+	}
+	for _, want := range []string{"storage:      memory", "unreachable objects", "persistence disabled"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestGitGymUndo_RestoresSnapshots(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-undo")
+	ctx := context.Background()
+
+	run := func(input string) (string, error) {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		return result.Stdout, err
+	}
+	mustRun := func(input string) string {
+		out, err := run(input)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return out
+	}
+	head := func() string {
+		ref, err := s.GetRepo().Head()
+		if err != nil {
+			t.Fatalf("HEAD: %v", err)
+		}
+		return ref.Hash().String()
+	}
+	fileExists := func(name string) bool {
+		_, err := s.Filesystem.Stat(name)
+		return err == nil
+	}
+
+	mustRun("touch second.txt")
+	mustRun("git add second.txt")
+	mustRun("git commit -m second")
+	second := head()
+	// An untracked draft: its content is kept in the snapshot itself
+	if err := util.WriteFile(s.Filesystem, "/testrepo/draft.txt", []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The learner wrecks the sandbox
+	mustRun("rm draft.txt")
+	mustRun("git reset --hard HEAD~1")
+	mustRun("git status") // Changes nothing, so it is not recorded
+	if fileExists("/testrepo/second.txt") {
+		t.Fatal("Expected reset --hard to remove second.txt")
+	}
+
+	out := mustRun("gitgym undo")
+	if !strings.Contains(out, "reset --hard HEAD~1") {
+		t.Errorf("Expected the undone command in the output, got %q", out)
+	}
+	if head() != second || !fileExists("/testrepo/second.txt") {
+		t.Errorf("Expected HEAD and files back at the second commit, got HEAD %s", head())
+	}
+
+	mustRun("gitgym undo")
+	data, err := util.ReadFile(s.Filesystem, "/testrepo/draft.txt")
+	if err != nil || string(data) != "keep me" {
+		t.Errorf("Expected the untracked draft back, got %q (%v)", data, err)
+	}
+
+	mustRun("gitgym redo")
+	if fileExists("/testrepo/draft.txt") {
+		t.Error("Expected redo to remove the draft again")
+	}
+	if out := mustRun("gitgym history"); !strings.Contains(out, "1. reset --hard HEAD~1") || !strings.Contains(out, "1. rm draft.txt") {
+		t.Errorf("Expected history to list rm to undo and reset to redo, got:\n%s", out)
+	}
+
+	// A new change forgets what could be redone
+	mustRun("git branch feature")
+	if _, err := run("gitgym redo"); err == nil {
+		t.Error("Expected nothing to redo after a new command")
+	}
+
+	// The reflog records the moves made by undo and redo
+	if out := mustRun("git reflog"); !strings.Contains(out, "gitgym: undo") {
+		t.Errorf("Expected gitgym undo in the reflog, got:\n%s", out)
+	}
+}
+
+func TestGitGymSave_RestoresSavePoints(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-save")
+	ctx := context.Background()
+
+	run := func(input string) (string, error) {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		return result.Stdout, err
+	}
+	mustRun := func(input string) string {
+		out, err := run(input)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return out
+	}
+	head := func() string {
+		ref, err := s.GetRepo().Head()
+		if err != nil {
+			t.Fatalf("HEAD: %v", err)
+		}
+		return ref.Hash().String()
+	}
+
+	start := head()
+	if out := mustRun("gitgym save \"before experiments\""); !strings.Contains(out, `gitgym restore "before experiments"`) {
+		t.Errorf("Expected a restore hint, got %q", out)
+	}
+	if _, err := run("gitgym save \"before experiments\""); err == nil {
+		t.Error("Expected a second save point of the same name to fail")
+	}
+
+	// Save points outlive any number of commands
+	for _, name := range []string{"a", "b", "c"} {
+		mustRun("touch " + name + ".txt")
+		mustRun("git add " + name + ".txt")
+		mustRun("git commit -m " + name)
+	}
+	mustRun("gitgym save")
+	mustRun("git reset --hard HEAD~3")
+	if out := mustRun("gitgym saves"); !strings.Contains(out, "before experiments") || !strings.Contains(out, "save-1") {
+		t.Errorf("Expected both save points listed, got:\n%s", out)
+	}
+
+	mustRun("gitgym restore save-1")
+	if _, err := s.Filesystem.Stat("/testrepo/c.txt"); err != nil {
+		t.Errorf("Expected c.txt back after restoring save-1: %v", err)
+	}
+	mustRun("gitgym restore \"before experiments\"")
+	if head() != start {
+		t.Errorf("Expected HEAD back at %s, got %s", start, head())
+	}
+	if _, err := s.Filesystem.Stat("/testrepo/a.txt"); err == nil {
+		t.Error("Expected a.txt gone after restoring the first save point")
+	}
+
+	// A restore is undone like a command
+	mustRun("gitgym undo")
+	if _, err := s.Filesystem.Stat("/testrepo/c.txt"); err != nil {
+		t.Errorf("Expected undo to bring back the state of save-1: %v", err)
+	}
+
+	if _, err := run("gitgym restore nope"); err == nil || !strings.Contains(err.Error(), "no such save point") {
+		t.Errorf("Expected an unknown save point to fail, got %v", err)
+	}
+	mustRun("gitgym save -d save-1")
+	if out := mustRun("gitgym saves"); strings.Contains(out, "save-1") {
+		t.Errorf("Expected save-1 deleted, got:\n%s", out)
+	}
+}
+
+func TestGitGymSave_PolicyTakesAutomaticSavePoints(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-autosave")
+	ctx := context.Background()
+	s.CommandPolicy = &git.CommandPolicy{SaveBefore: []string{"reset --hard", "branch -D"}}
+
+	for _, input := range []string{
+		"git commit --allow-empty -m second",
+		"git reset HEAD~1", // Not --hard: no save point
+		"git reset --hard ORIG_HEAD",
+	} {
+		name, args := git.ParseCommand(input)
+		if _, err := git.Dispatch(ctx, s, name, args); err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+	}
+
+	points := s.SavePoints()
+	if len(points) != 1 {
+		t.Fatalf("Expected 1 automatic save point, got %+v", points)
+	}
+	if sp := points[0]; !sp.Auto || sp.Name != "auto-1" || sp.Command != "reset --hard ORIG_HEAD" {
+		t.Errorf("Unexpected save point %+v", sp)
+	}
+}
+
+func TestGitGymRecover_FindsCommitsLeftOnDetachedHead(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-recover")
+	ctx := context.Background()
+
+	mustRun := func(input string) string {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return result.Stdout
+	}
+	headRef, err := s.GetRepo().Head()
+	if err != nil {
+		t.Fatalf("HEAD: %v", err)
+	}
+	branch := headRef.Name().Short()
+
+	if out := mustRun("gitgym recover"); !strings.Contains(out, "No lost commits") {
+		t.Errorf("Expected no lost commits yet, got %q", out)
+	}
+
+	mustRun("git switch --detach HEAD")
+	mustRun("git commit --allow-empty -m experiment")
+	mustRun("git commit --allow-empty -m experiment-2")
+	ref, _ := s.GetRepo().Head()
+	tip := ref.Hash().String()[:7]
+
+	out := mustRun("git switch " + branch)
+	for _, want := range []string{"leaving 2 commits behind", "experiment-2", "git branch <new-branch-name> " + tip} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected switch output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	out = mustRun("gitgym recover")
+	if !strings.Contains(out, tip+" experiment-2") || !strings.Contains(out, "git branch recovered-1 "+tip) {
+		t.Errorf("Expected the lost tip and a branch suggestion, got:\n%s", out)
+	}
+	if strings.Contains(out, " experiment\n") {
+		t.Errorf("Expected only the tip of the lost history, got:\n%s", out)
+	}
+
+	mustRun("git branch rescued " + tip)
+	if out := mustRun("gitgym recover"); !strings.Contains(out, "No lost commits") {
+		t.Errorf("Expected the branch to make the commits reachable again, got %q", out)
+	}
+}
+
+func TestGitGymChangelog_GroupsConventionalCommitsBetweenTags(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-gitgym-changelog")
+	ctx := context.Background()
+
+	if _, err := (&ConfigCommand{}).Execute(ctx, s, []string{"config", "commit.lint", "conventional"}); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+	commit := func(msg string, extra ...string) error {
+		_, err := (&CommitCommand{}).Execute(ctx, s, append([]string{"commit", "--allow-empty", "-m", msg}, extra...))
+		return err
+	}
+
+	// The lint rejects a non-conventional message unless --no-verify is given
+	err := commit("Update stuff.")
+	if err == nil || !strings.Contains(err.Error(), "does not follow Conventional Commits") || !strings.Contains(err.Error(), "--no-verify") {
+		t.Fatalf("Expected a lint error with hints, got %v", err)
+	}
+	if err := commit("Update stuff", "--no-verify"); err != nil {
+		t.Fatalf("commit --no-verify failed: %v", err)
+	}
+	if _, err := (&TagCommand{}).Execute(ctx, s, []string{"tag", "v1.0.0"}); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+
+	for _, msg := range []string{"feat(auth): add login form", "fix: handle empty password", "docs: describe login", "feat!: drop legacy sessions"} {
+		if err := commit(msg); err != nil {
+			t.Fatalf("commit %q failed: %v", msg, err)
+		}
+	}
+	if _, err := (&TagCommand{}).Execute(ctx, s, []string{"tag", "v1.1.0"}); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+
+	out, err := (&GitGymCommand{}).Execute(ctx, s, []string{"gitgym", "changelog"})
+	if err != nil {
+		t.Fatalf("gitgym changelog failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "## v1.1.0\n") {
+		t.Errorf("Expected the changelog to be titled after the tag at HEAD, got:\n%s", out)
+	}
+	var order []int
+	for _, want := range []string{"### BREAKING CHANGES", "### Features", "- **auth:** add login form (", "### Bug Fixes", "- handle empty password (", "### Documentation"} {
+		i := strings.Index(out, want)
+		if i < 0 {
+			t.Fatalf("Expected %q in changelog, got:\n%s", want, out)
+		}
+		order = append(order, i)
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] < order[i-1] {
+			t.Errorf("Expected sections in conventional order, got:\n%s", out)
+		}
+	}
+	if strings.Contains(out, "Update stuff") || strings.Contains(out, "Other Changes") {
+		t.Errorf("Expected commits before v1.0.0 to be left out, got:\n%s", out)
+	}
+
+	out, err = (&GitGymCommand{}).Execute(ctx, s, []string{"gitgym", "changelog", "main~5", "v1.0.0"})
+	if err != nil {
+		t.Fatalf("gitgym changelog <from> <to> failed: %v", err)
+	}
+	if !strings.Contains(out, "### Other Changes\n\n- Update stuff (") {
+		t.Errorf("Expected non-conventional commits under Other Changes, got:\n%s", out)
+	}
+}
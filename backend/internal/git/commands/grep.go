@@ -0,0 +1,238 @@
+package commands
+
+// grep.go - Simulated Git Grep Command
+//
+// Searches the contents of tracked files, either in the working tree or as
+// they were at a given revision.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("grep", func() git.Command { return &GrepCommand{} })
+}
+
+type GrepCommand struct{}
+
+// Ensure GrepCommand implements git.Command
+var _ git.Command = (*GrepCommand)(nil)
+
+type GrepOptions struct {
+	LineNumbers bool // -n: prefix matches with their line number
+	IgnoreCase  bool // -i: match regardless of case
+	Pattern     string
+	Revision    string   // Search this revision instead of the working tree
+	Paths       []string // Limit the search to these files or directories
+}
+
+func (c *GrepCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "grep"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	expr := opts.Pattern
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("fatal: command line, '%s': %v", opts.Pattern, err)
+	}
+
+	var hash *plumbing.Hash
+	if opts.Revision != "" {
+		if hash, err = git.ResolveRevision(repo, opts.Revision); err != nil {
+			// "git grep foo README.md": the argument is a path, not a revision
+			if !matchesAnyPath(git.TrackedPaths(repo), opts.Revision) {
+				return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", opts.Revision)
+			}
+			opts.Paths = append([]string{opts.Revision}, opts.Paths...)
+			opts.Revision = ""
+		}
+	}
+
+	// Each file is read lazily, so only the files that are searched get loaded
+	files := make(map[string]func() (string, error))
+	prefix := ""
+	if hash != nil {
+		commit, err := repo.CommitObject(*hash)
+		if err != nil {
+			return "", err
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return "", err
+		}
+		err = tree.Files().ForEach(func(f *object.File) error {
+			files[f.Name] = f.Contents
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		prefix = opts.Revision + ":"
+	} else {
+		w, err := repo.Worktree()
+		if err != nil {
+			return "", err
+		}
+		for name := range git.TrackedPaths(repo) {
+			files[name] = func() (string, error) {
+				f, err := w.Filesystem.Open(name)
+				if err != nil {
+					return "", err
+				}
+				defer f.Close()
+				data, err := io.ReadAll(f)
+				return string(data), err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if matchesPathspec(name, opts.Paths) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		content, err := files[name]()
+		if err != nil {
+			// Deleted from the working tree but still tracked: nothing to search
+			continue
+		}
+		if strings.ContainsRune(content, 0) {
+			if re.MatchString(content) {
+				sb.WriteString(fmt.Sprintf("Binary file %s%s matches\n", prefix, name))
+			}
+			continue
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if opts.LineNumbers {
+				sb.WriteString(fmt.Sprintf("%s%s:%d:%s\n", prefix, name, i+1, line))
+			} else {
+				sb.WriteString(fmt.Sprintf("%s%s:%s\n", prefix, name, line))
+			}
+		}
+	}
+	// Like git, finding nothing prints nothing
+	return sb.String(), nil
+}
+
+// matchesPathspec reports whether name is one of paths or inside one of them.
+// An empty list matches every file.
+func matchesPathspec(name string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "./"), "/")
+		if p == "" || p == "." || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyPath reports whether pathspec names one of the tracked files or a
+// directory containing some.
+func matchesAnyPath(tracked map[string]bool, pathspec string) bool {
+	for name := range tracked {
+		if matchesPathspec(name, []string{pathspec}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *GrepCommand) parseArgs(args []string) (*GrepOptions, error) {
+	opts := &GrepOptions{}
+	var positional []string
+	patternSet := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--":
+			opts.Paths = append(opts.Paths, args[i+1:]...)
+			i = len(args)
+		case arg == "-n" || arg == "--line-number":
+			opts.LineNumbers = true
+		case arg == "-i" || arg == "--ignore-case":
+			opts.IgnoreCase = true
+		case arg == "-e":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("error: switch `e' requires a value")
+			}
+			i++
+			opts.Pattern, patternSet = args[i], true
+		case len(arg) > 2 && arg[0] == '-' && strings.Trim(arg[1:], "ni") == "":
+			// Combined short flags such as -ni
+			opts.LineNumbers = opts.LineNumbers || strings.Contains(arg, "n")
+			opts.IgnoreCase = opts.IgnoreCase || strings.Contains(arg, "i")
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			return nil, fmt.Errorf("error: unknown option '%s'", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if !patternSet {
+		if len(positional) == 0 {
+			return nil, fmt.Errorf("fatal: no pattern given")
+		}
+		opts.Pattern, positional = positional[0], positional[1:]
+	}
+	if len(positional) > 0 {
+		// The first argument after the pattern is a revision unless it turns
+		// out to be a path (checked against the repository in Execute)
+		opts.Revision, positional = positional[0], positional[1:]
+	}
+	opts.Paths = append(positional, opts.Paths...)
+	return opts, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *GrepCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-e"}, Arg: git.ArgText, Usage: "Pattern to search for"},
+			{Flags: []string{"-i", "--ignore-case"}, Usage: "Ignore case"},
+			{Flags: []string{"-n", "--line-number"}, Usage: "Show line numbers"},
+		},
+		Args: []string{git.ArgText, git.ArgPath},
+	}
+}
+
+func (c *GrepCommand) Help() string {
+	return git.CommandHelp(context.Background(), "grep")
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrep(t *testing.T) {
+	fs := memfs.New()
+	r, _ := gogit.Init(memory.NewStorage(), fs)
+	w, _ := r.Worktree()
+	author := &object.Signature{Name: "Tester", Email: "test@example.com", When: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	commit := func(files map[string]string, msg string) {
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(fs, name, []byte(content), 0644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+		_, err := w.Commit(msg, &gogit.CommitOptions{Author: author})
+		require.NoError(t, err)
+	}
+	commit(map[string]string{"README.md": "Hello world\nTODO: write docs\n", "src/main.go": "package main\n// TODO fix\n"}, "First")
+	commit(map[string]string{"README.md": "Hello again\n"}, "Second")
+	// Untracked files are not searched
+	require.NoError(t, util.WriteFile(fs, "notes.txt", []byte("TODO untracked\n"), 0644))
+
+	session := &git.Session{
+		ID:         "test-session",
+		Filesystem: fs,
+		Repos:      map[string]*gogit.Repository{"repo": r},
+		CurrentDir: "/repo",
+	}
+	grep := func(args ...string) (string, error) {
+		return (&GrepCommand{}).Execute(context.Background(), session, append([]string{"grep"}, args...))
+	}
+
+	t.Run("worktree", func(t *testing.T) {
+		out, err := grep("TODO")
+		require.NoError(t, err)
+		assert.Equal(t, "src/main.go:// TODO fix\n", out)
+	})
+
+	t.Run("line numbers and case", func(t *testing.T) {
+		out, err := grep("-n", "-i", "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "README.md:1:Hello again\n", out)
+
+		out, err = grep("-ni", "todo")
+		require.NoError(t, err)
+		assert.Equal(t, "src/main.go:2:// TODO fix\n", out)
+	})
+
+	t.Run("revision", func(t *testing.T) {
+		out, err := grep("-n", "TODO", "HEAD~1")
+		require.NoError(t, err)
+		assert.Equal(t, "HEAD~1:README.md:2:TODO: write docs\nHEAD~1:src/main.go:2:// TODO fix\n", out)
+
+		_, err = grep("TODO", "nope")
+		assert.Error(t, err)
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		out, err := grep("TODO", "HEAD~1", "--", "src")
+		require.NoError(t, err)
+		assert.Equal(t, "HEAD~1:src/main.go:// TODO fix\n", out)
+
+		// Without a revision, a tracked path is taken as a path
+		out, err = grep("Hello", "README.md")
+		require.NoError(t, err)
+		assert.Equal(t, "README.md:Hello again\n", out)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		out, err := grep("absent")
+		require.NoError(t, err)
+		assert.Empty(t, out)
+
+		_, err = grep()
+		assert.Error(t, err)
+	})
+}
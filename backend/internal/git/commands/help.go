@@ -18,73 +18,91 @@ type HelpCommand struct{}
 // Ensure HelpCommand implements git.Command
 var _ git.Command = (*HelpCommand)(nil)
 
-// Command metadata for help display
-type cmdMeta struct {
-	Category string
-	Desc     string
-}
-
-// Categories
+// Categories of the command index; the locale has their heading as category.<id>
 const (
-	CatStart    = "Start a working area"
-	CatWork     = "Work on the current change"
-	CatHistory  = "Examine the history and state"
-	CatGrow     = "Grow, mark and tweak your common history"
-	CatCollab   = "Collaborate"
-	CatShell    = "Shell & Utilities"
-	CatInternal = "Internal" // Hidden
+	CatStart    = "start"
+	CatWork     = "work"
+	CatHistory  = "history"
+	CatGrow     = "grow"
+	CatCollab   = "collab"
+	CatShell    = "shell"
+	CatInternal = "internal" // Hidden
 )
 
-var commandMetadata = map[string]cmdMeta{
+// commandCategories places each command in the command index. The index shows
+// the summary.<command> message of the locale next to the name.
+var commandCategories = map[string]string{
 	// Start
-	"clone":    {CatStart, "Clone a repository into a new directory"},
-	"init":     {CatStart, "Create an empty Git repository (not supported checking out new projects yet)"},
-	"worktree": {CatStart, "Manage multiple working trees (not supported in current UI)"},
+	"clone":           CatStart,
+	"init":            CatStart,
+	"sparse-checkout": CatStart,
+	"worktree":        CatStart,
 
 	// Work
-	"add":     {CatWork, "Add file contents to the index"},
-	"clean":   {CatWork, "Remove untracked files from the working tree"},
-	"restore": {CatWork, "Restore working tree files"},
-	"rm":      {CatWork, "Remove files from the working tree and from the index"},
+	"add":          CatWork,
+	"check-ignore": CatWork,
+	"clean":        CatWork,
+	"restore":      CatWork,
+	"rm":           CatWork,
 
 	// History
-	"blame":  {CatHistory, "Show what revision and author last modified each line of a file"},
-	"diff":   {CatHistory, "Show changes between commits, commit and working tree, etc"},
-	"log":    {CatHistory, "Show commit logs"},
-	"reflog": {CatHistory, "Manage reflog information"},
-	"show":   {CatHistory, "Show various types of objects"},
-	"status": {CatHistory, "Show the working tree status"},
+	"blame":         CatHistory,
+	"cat-file":      CatHistory,
+	"count-objects": CatHistory,
+	"diff":          CatHistory,
+	"fsck":          CatHistory,
+	"grep":          CatHistory,
+	"log":           CatHistory,
+	"reflog":        CatHistory,
+	"rev-list":      CatHistory,
+	"show":          CatHistory,
+	"status":        CatHistory,
+	"verify-commit": CatHistory,
+	"verify-tag":    CatHistory,
 
 	// Grow
-	"branch":      {CatGrow, "List, create, or delete branches"},
-	"checkout":    {CatGrow, "Switch branches or restore working tree files"},
-	"cherry-pick": {CatGrow, "Apply the changes introduced by some existing commits"},
-	"commit":      {CatGrow, "Record changes to the repository"},
-	"merge":       {CatGrow, "Join two or more development histories together"},
-	"rebase":      {CatGrow, "Reapply commits on top of another base tip"},
-	"reset":       {CatGrow, "Reset current HEAD to the specified state"},
-	"revert":      {CatGrow, "Revert some existing commits"},
-	"stash":       {CatGrow, "Stash the changes in a dirty working directory away"},
-	"switch":      {CatGrow, "Switch branches"},
-	"tag":         {CatGrow, "Create, list, delete or verify a tag object"},
+	"branch":      CatGrow,
+	"checkout":    CatGrow,
+	"cherry-pick": CatGrow,
+	"commit":      CatGrow,
+	"gc":          CatGrow,
+	"merge":       CatGrow,
+	"rebase":      CatGrow,
+	"rerere":      CatGrow,
+	"reset":       CatGrow,
+	"revert":      CatGrow,
+	"squash":      CatGrow,
+	"stash":       CatGrow,
+	"switch":      CatGrow,
+	"tag":         CatGrow,
+	"undo":        CatGrow,
 
 	// Collab
-	"fetch":  {CatCollab, "Download objects and refs from another repository"},
-	"pull":   {CatCollab, "Fetch from and integrate with another repository or a local branch"},
-	"push":   {CatCollab, "Update remote refs along with associated objects (simulated)"},
-	"remote": {CatCollab, "Manage set of tracked repositories"},
+	"am":           CatCollab,
+	"apply":        CatCollab,
+	"archive":      CatCollab,
+	"bundle":       CatCollab,
+	"fetch":        CatCollab,
+	"format-patch": CatCollab,
+	"pull":         CatCollab,
+	"push":         CatCollab,
+	"remote":       CatCollab,
+	"lfs":          CatCollab,
 
 	// Shell
-	"cd":      {CatShell, "Change the current directory"},
-	"ls":      {CatShell, "List directory contents"},
-	"pwd":     {CatShell, "Print name of current/working directory"},
-	"touch":   {CatShell, "Change file access and modification times"},
-	"help":    {CatShell, "Display help information"},
-	"version": {CatShell, "Show version info"},
+	"cd":       CatShell,
+	"cat":      CatShell,
+	"ls":       CatShell,
+	"pwd":      CatShell,
+	"touch":    CatShell,
+	"help":     CatShell,
+	"version":  CatShell,
+	"gitgym":   CatShell,
+	"simulate": CatShell,
 
 	// Internal / Hidden (Marked but filtered later)
-	"simulate-commit": {CatInternal, "Simulate a commit"},
-	"merge-pr":        {CatInternal, "Merge a pull request"},
+	"simulate-commit": CatInternal,
+	"merge-pr":        CatInternal,
 }
 
 // Order of categories for display
@@ -100,13 +118,13 @@ var categoryOrder = []string{
 func (c *HelpCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	if len(args) > 1 {
 		subcmd := args[1]
-		helpStr, err := git.GetCommandHelp(subcmd)
+		helpStr, err := git.GetCommandHelp(ctx, subcmd)
 		if err != nil {
 			// Fallback if not found in metadata or registry
-			if meta, ok := commandMetadata[subcmd]; ok {
-				return fmt.Sprintf("%s: %s\n", subcmd, meta.Desc), nil
+			if _, ok := commandCategories[subcmd]; ok {
+				return fmt.Sprintf("%s: %s\n", subcmd, git.T(ctx, "summary."+subcmd)), nil
 			}
-			return fmt.Sprintf("git help: unknown command '%s'", subcmd), nil
+			return git.T(ctx, "index.unknown", subcmd), nil
 		}
 		return helpStr, nil
 	}
@@ -119,11 +137,11 @@ func (c *HelpCommand) Execute(ctx context.Context, s *git.Session, args []string
 	maxLen := 0
 
 	for _, cmd := range cmds {
-		meta, ok := commandMetadata[cmd]
-		if !ok || meta.Category == CatInternal {
+		cat, ok := commandCategories[cmd]
+		if !ok || cat == CatInternal {
 			continue // Skip hidden or unknown
 		}
-		grouped[meta.Category] = append(grouped[meta.Category], cmd)
+		grouped[cat] = append(grouped[cat], cmd)
 		if len(cmd) > maxLen {
 			maxLen = len(cmd)
 		}
@@ -131,8 +149,8 @@ func (c *HelpCommand) Execute(ctx context.Context, s *git.Session, args []string
 
 	// 2. Build Output
 	var sb strings.Builder
-	sb.WriteString("usage: git [--version] [--help] <command> [<args>]\n\n")
-	sb.WriteString("These are common Git commands used in various situations:\n")
+	sb.WriteString(git.T(ctx, "index.usage") + "\n\n")
+	sb.WriteString(git.T(ctx, "index.intro") + "\n")
 
 	for _, cat := range categoryOrder {
 		list, ok := grouped[cat]
@@ -141,35 +159,24 @@ func (c *HelpCommand) Execute(ctx context.Context, s *git.Session, args []string
 		}
 		sort.Strings(list)
 
-		sb.WriteString(fmt.Sprintf("\n%s:\n", cat))
+		sb.WriteString(fmt.Sprintf("\n%s:\n", git.T(ctx, "category."+cat)))
 		for _, cmd := range list {
-			meta := commandMetadata[cmd]
 			padding := strings.Repeat(" ", maxLen-len(cmd)+3)
-			sb.WriteString(fmt.Sprintf("   %s%s%s\n", cmd, padding, meta.Desc))
+			sb.WriteString(fmt.Sprintf("   %s%s%s\n", cmd, padding, git.T(ctx, "summary."+cmd)))
 		}
 	}
 
-	sb.WriteString("\nType 'git help <command>' for more information about a specific command.")
+	sb.WriteString("\n" + git.T(ctx, "index.footer"))
 	return sb.String(), nil
 }
 
-func (c *HelpCommand) Help() string {
-	return `📘 GIT-HELP (1)                                         Git Manual
-
- 💡 DESCRIPTION
-    Gitコマンドの使い方やオプションを確認します。
-    困った時はまずこれを使ってみてください。
-    引数なしで実行すると、利用可能な主要コマンド一覧を表示します。
-
- 📋 SYNOPSIS
-    git help [-a] [<command>]
-
- ⚙️  COMMON OPTIONS
-    -a, --all
-        全てのコマンドを表示します。
+// Spec implements git.SpecProvider.
+func (c *HelpCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Args: []string{git.ArgCommand},
+	}
+}
 
- 🛠  EXAMPLES
-    1. コマンドの使い方を調べる
-       $ git help commit
-`
+func (c *HelpCommand) Help() string {
+	return git.CommandHelp(context.Background(), "help")
 }
@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHelpCommand(t *testing.T) {
@@ -35,3 +38,45 @@ func TestHelpCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestHelp_Localized(t *testing.T) {
+	for _, name := range git.GetSupportedCommands() {
+		for _, lang := range i18n.Languages() {
+			assert.True(t, i18n.Has(lang, "help."+name), "help.%s missing from %s", name, lang)
+		}
+	}
+	for name, cat := range commandCategories {
+		for _, lang := range i18n.Languages() {
+			if cat != CatInternal {
+				assert.True(t, i18n.Has(lang, "summary."+name), "summary.%s missing from %s", name, lang)
+			}
+		}
+	}
+
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-help-i18n")
+	dispatch := func(ctx context.Context, input string) *git.CommandResult {
+		name, args := git.ParseCommand(input)
+		result, _ := git.Dispatch(ctx, s, name, args)
+		require.NotNil(t, result, input)
+		return result
+	}
+	en := context.Background()
+	ja := git.WithLang(en, "ja")
+
+	assert.Contains(t, dispatch(en, "git help commit").Stdout, "Record (save) the changes in the staging area")
+	assert.Contains(t, dispatch(ja, "git help commit").Stdout, "ステージングエリアにある変更を記録する")
+	assert.Contains(t, dispatch(ja, "git commit -h").Stdout, "ステージングエリアにある変更を記録する")
+	assert.Contains(t, dispatch(ja, "git help").Stdout, "履歴と状態を調べる:")
+	assert.Contains(t, dispatch(en, "git help").Stdout, "Examine the history and state:")
+
+	res := dispatch(ja, "git comit -m wip")
+	assert.Equal(t, git.ExitUnknownCommand, res.ExitCode)
+	assert.Contains(t, res.Stderr, "コマンドとして認識されません")
+	assert.Equal(t, "'commit' のことですか？", res.Suggestion.Message)
+
+	// Without a language of its own, a command follows the session locale
+	s.Locale = "ja"
+	assert.Contains(t, dispatch(en, "git status -h").Stdout, "現在のブランチや状況を確認する")
+	assert.Contains(t, dispatch(git.WithLang(en, "en"), "git status -h").Stdout, "See the current branch")
+}
@@ -68,7 +68,7 @@ func TestReflogCommand(t *testing.T) {
 	s.InitRepo("testrepo")
 	s.CurrentDir = "/testrepo"
 
-	s.Reflog = append(s.Reflog, git.ReflogEntry{Hash: "abc1234", Message: "checkout: moving", Timestamp: time.Now()})
+	s.Reflog = append(s.Reflog, git.ReflogEntry{Context: "/testrepo", Ref: "HEAD", Hash: "abc1234", Message: "checkout: moving", Timestamp: time.Now()})
 
 	cmd := &ReflogCommand{}
 	res, err := cmd.Execute(context.Background(), s, []string{"reflog"})
@@ -118,3 +118,70 @@ func TestDiffCommand(t *testing.T) {
 		t.Errorf("Diff missing change: %s", res)
 	}
 }
+
+func TestDiffCommand_WorktreeIndexAndPaths(t *testing.T) {
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-diff-worktree")
+	s.InitRepo("testrepo")
+	s.CurrentDir = "/testrepo"
+	repo := s.GetRepo()
+	w, _ := repo.Worktree()
+
+	write := func(name, content string) {
+		f, _ := w.Filesystem.Create(name)
+		f.Write([]byte(content))
+		f.Close()
+	}
+	write("a.txt", "one\n")
+	write("docs/b.txt", "two\n")
+	w.Add(".")
+	w.Commit("base", &gogit.CommitOptions{Author: &object.Signature{Name: "User", When: time.Now()}})
+
+	// a.txt: staged change plus a further unstaged one; docs/b.txt: unstaged only
+	write("a.txt", "one\nstaged\n")
+	w.Add("a.txt")
+	write("a.txt", "one\nstaged\nunstaged\n")
+	write("docs/b.txt", "two\nmore\n")
+	write("untracked.txt", "ignored by git diff\n")
+
+	cmd := &DiffCommand{}
+	run := func(args ...string) string {
+		t.Helper()
+		res, err := cmd.Execute(context.Background(), s, append([]string{"diff"}, args...))
+		if err != nil {
+			t.Fatalf("diff %v failed: %v", args, err)
+		}
+		return res
+	}
+
+	res := run()
+	for _, want := range []string{"diff --git a/a.txt b/a.txt", " staged", "+unstaged", "+more"} {
+		if !strings.Contains(res, want) {
+			t.Errorf("git diff: missing %q in:\n%s", want, res)
+		}
+	}
+	if strings.Contains(res, "+staged") || strings.Contains(res, "untracked.txt") {
+		t.Errorf("git diff should only show unstaged changes to tracked files:\n%s", res)
+	}
+
+	res = run("--cached")
+	if !strings.Contains(res, "+staged") || strings.Contains(res, "unstaged") || strings.Contains(res, "b.txt") {
+		t.Errorf("git diff --cached should only show the staged change:\n%s", res)
+	}
+
+	res = run("HEAD")
+	if !strings.Contains(res, "+staged") || !strings.Contains(res, "+unstaged") {
+		t.Errorf("git diff HEAD should show staged and unstaged changes:\n%s", res)
+	}
+
+	if res = run("--name-only", "--", "docs"); res != "docs/b.txt\n" {
+		t.Errorf("git diff -- docs: got %q", res)
+	}
+	if res = run("--name-only", "a.txt"); res != "a.txt\n" {
+		t.Errorf("git diff a.txt: got %q", res)
+	}
+
+	if _, err := cmd.Execute(context.Background(), s, []string{"diff", "nope"}); err == nil || !strings.Contains(err.Error(), "ambiguous argument") {
+		t.Errorf("Expected ambiguous argument error, got %v", err)
+	}
+}
@@ -89,6 +89,13 @@ func (c *InitCommand) checkNestedRepoConflicts(s *git.Session, targetPath string
 	return nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *InitCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Args: []string{git.ArgPath},
+	}
+}
+
 func (c *InitCommand) Help() string {
-	return "usage: git init [directory]\n\nCreate an empty Git repository or reinitialize an existing one."
+	return git.CommandHelp(context.Background(), "init")
 }
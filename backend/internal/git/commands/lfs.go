@@ -0,0 +1,182 @@
+package commands
+
+// lfs.go - Simulated Git LFS
+//
+// Teaches how Git LFS works without a real LFS server:
+//   - "git lfs track" writes filter=lfs patterns to .gitattributes
+//   - "git add" stores matching (or very large) files as pointer blobs
+//   - "git push" uploads the real content to the simulated LFS server
+//   - checkouts in other sessions download it again
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("lfs", func() git.Command { return &LFSCommand{} })
+}
+
+type LFSCommand struct{}
+
+// Ensure LFSCommand implements git.Command
+var _ git.Command = (*LFSCommand)(nil)
+
+type LFSOptions struct {
+	SubCmd   string
+	Patterns []string
+}
+
+func (c *LFSCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "lfs"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	switch opts.SubCmd {
+	case "track":
+		return c.track(w, opts.Patterns)
+	case "untrack":
+		return c.untrack(w, opts.Patterns)
+	case "ls-files":
+		return c.lsFiles(repo), nil
+	default:
+		return "", fmt.Errorf("git lfs: '%s' is not a supported subcommand\nhint: Supported: track, untrack, ls-files", opts.SubCmd)
+	}
+}
+
+func (c *LFSCommand) parseArgs(args []string) (*LFSOptions, error) {
+	opts := &LFSOptions{}
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case opts.SubCmd == "":
+			opts.SubCmd = arg
+		default:
+			opts.Patterns = append(opts.Patterns, arg)
+		}
+	}
+	if opts.SubCmd == "" {
+		return nil, fmt.Errorf("help requested")
+	}
+	return opts, nil
+}
+
+func (c *LFSCommand) track(w *gogit.Worktree, patterns []string) (string, error) {
+	existing := git.LFSPatterns(w.Filesystem)
+	if len(patterns) == 0 {
+		if len(existing) == 0 {
+			return "Listing tracked patterns\n(none)", nil
+		}
+		var sb strings.Builder
+		sb.WriteString("Listing tracked patterns\n")
+		for _, p := range existing {
+			sb.WriteString(fmt.Sprintf("    %s (.gitattributes)\n", p))
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	content, _ := util.ReadFile(w.Filesystem, ".gitattributes")
+	attrs := string(content)
+	if attrs != "" && !strings.HasSuffix(attrs, "\n") {
+		attrs += "\n"
+	}
+
+	var out []string
+	for _, p := range patterns {
+		if containsString(existing, p) {
+			out = append(out, fmt.Sprintf("\"%s\" already supported", p))
+			continue
+		}
+		attrs += p + " filter=lfs diff=lfs merge=lfs -text\n"
+		existing = append(existing, p)
+		out = append(out, fmt.Sprintf("Tracking \"%s\"", p))
+	}
+	if err := util.WriteFile(w.Filesystem, ".gitattributes", []byte(attrs), 0644); err != nil {
+		return "", err
+	}
+	out = append(out, "hint: Commit .gitattributes so everyone stores these files via LFS.")
+	return strings.Join(out, "\n"), nil
+}
+
+func (c *LFSCommand) untrack(w *gogit.Worktree, patterns []string) (string, error) {
+	if len(patterns) == 0 {
+		return "", fmt.Errorf("usage: git lfs untrack <pattern>...")
+	}
+	content, err := util.ReadFile(w.Filesystem, ".gitattributes")
+	if err != nil {
+		return "", fmt.Errorf("no .gitattributes file found")
+	}
+
+	var kept, out []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && containsString(patterns, fields[0]) && strings.Contains(line, "filter=lfs") {
+			out = append(out, fmt.Sprintf("Untracking \"%s\"", fields[0]))
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	attrs := strings.Join(kept, "\n")
+	if attrs != "" {
+		attrs += "\n"
+	}
+	if err := util.WriteFile(w.Filesystem, ".gitattributes", []byte(attrs), 0644); err != nil {
+		return "", err
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func (c *LFSCommand) lsFiles(repo *gogit.Repository) string {
+	entries, pointers := git.LFSIndexEntries(repo)
+	lines := make([]string, 0, len(entries))
+	for i, e := range entries {
+		lines = append(lines, fmt.Sprintf("%s * %s", pointers[i].OID[:10], e.Name))
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][13:] < lines[j][13:] })
+	return strings.Join(lines, "\n")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec implements git.SpecProvider.
+func (c *LFSCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"ls-files", "track", "untrack"},
+		Args:        []string{git.ArgPath},
+	}
+}
+
+func (c *LFSCommand) Help() string {
+	return git.CommandHelp(context.Background(), "lfs")
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestLFS_TrackAddCommitPush(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-lfs")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	w, _ := repo.Worktree()
+
+	out, err := (&LFSCommand{}).Execute(ctx, s, []string{"lfs", "track", "*.psd"})
+	if err != nil || !strings.Contains(out, `Tracking "*.psd"`) {
+		t.Fatalf("lfs track failed: %v %s", err, out)
+	}
+
+	content := []byte("pretend this is a huge design file")
+	if err := util.WriteFile(w.Filesystem, "design.psd", content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = (&AddCommand{}).Execute(ctx, s, []string{"add", ".gitattributes", "design.psd"})
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if !strings.Contains(out, "LFS") {
+		t.Errorf("Expected add to mention LFS conversion, got: %s", out)
+	}
+
+	// The worktree keeps the real content while status stays clean after commit
+	if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Add design"}); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	status, _ := git.LFSAwareStatus(repo, w)
+	if !status.IsClean() {
+		t.Errorf("Expected clean status after committing LFS file, got: %v", status)
+	}
+
+	// The committed blob is a pointer, not the content
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	file, err := commit.File("design.psd")
+	if err != nil {
+		t.Fatalf("design.psd missing from commit: %v", err)
+	}
+	blob, _ := file.Contents()
+	if _, ok := git.ParseLFSPointer([]byte(blob)); !ok {
+		t.Errorf("Expected committed blob to be an LFS pointer, got: %q", blob)
+	}
+
+	out, _ = (&LFSCommand{}).Execute(ctx, s, []string{"lfs", "ls-files"})
+	if !strings.Contains(out, "* design.psd") {
+		t.Errorf("Expected ls-files to list design.psd, got: %s", out)
+	}
+
+	// Pushing uploads the content to the simulated LFS server
+	out, err = (&PushCommand{}).Execute(ctx, s, []string{"push", "origin", "master"})
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if !strings.Contains(out, "Uploading LFS objects") || !sm.HasLFSObjects() {
+		t.Errorf("Expected LFS upload on push, got: %s", out)
+	}
+
+	// A checkout without the content leaves the pointer; smudging restores it
+	_ = util.WriteFile(w.Filesystem, "design.psd", []byte(blob), 0644)
+	s.LFSObjects = nil
+	git.SmudgeLFSFiles(s, repo)
+	restored, _ := util.ReadFile(w.Filesystem, "design.psd")
+	if string(restored) != string(content) {
+		t.Errorf("Expected smudge to download content from the LFS server, got: %q", restored)
+	}
+}
@@ -1,15 +1,18 @@
 package commands
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -23,11 +26,18 @@ type LogCommand struct{}
 var _ git.Command = (*LogCommand)(nil)
 
 type LogOptions struct {
-	Oneline bool
-	Graph   bool
-	Limit   int
-	Author  string
-	Args    []string // Revisions or paths
+	Graph      bool
+	All        bool
+	Limit      int
+	Author     string
+	Grep       string
+	IgnoreCase bool      // -i: --author and --grep match case-insensitively
+	Since      time.Time // Zero when not given
+	Until      time.Time // Zero when not given
+	Format     string    // "oneline", "short", "medium", "full" or a "format:" template
+	Args       []string  // Revisions, or paths when they do not resolve
+	Paths      []string  // Paths given after "--"
+	Signatures bool      // --show-signature: print the gpg check of signed commits
 }
 
 func (c *LogCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -36,6 +46,9 @@ func (c *LogCommand) Execute(ctx context.Context, s *git.Session, args []string)
 
 	opts, err := c.parseArgs(args)
 	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "log"), nil
+		}
 		return "", err
 	}
 
@@ -48,259 +61,737 @@ func (c *LogCommand) Execute(ctx context.Context, s *git.Session, args []string)
 }
 
 func (c *LogCommand) parseArgs(args []string) (*LogOptions, error) {
-	opts := &LogOptions{}
+	opts := &LogOptions{Format: "medium"}
+	now := time.Now()
 	cmdArgs := args[1:]
+
+	// value returns the value of "--flag=value" or "--flag value"
+	value := func(i *int, flag string) (string, error) {
+		arg := cmdArgs[*i]
+		if strings.HasPrefix(arg, flag+"=") {
+			return strings.TrimPrefix(arg, flag+"="), nil
+		}
+		if *i+1 >= len(cmdArgs) {
+			return "", fmt.Errorf("fatal: option '%s' requires a value", strings.TrimLeft(flag, "-"))
+		}
+		*i++
+		return cmdArgs[*i], nil
+	}
+	hasFlag := func(arg string, flags ...string) bool {
+		for _, f := range flags {
+			if arg == f || strings.HasPrefix(arg, f+"=") {
+				return true
+			}
+		}
+		return false
+	}
+	count := func(v string) (int, error) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("fatal: '%s': not an integer", v)
+		}
+		return n, nil
+	}
+
 	for i := 0; i < len(cmdArgs); i++ {
 		arg := cmdArgs[i]
+		var err error
 		switch {
+		case arg == "--":
+			opts.Paths = append(opts.Paths, cmdArgs[i+1:]...)
+			i = len(cmdArgs)
 		case arg == "--oneline":
-			opts.Oneline = true
+			opts.Format = "oneline"
 		case arg == "--graph":
 			opts.Graph = true
+		case arg == "--all":
+			opts.All = true
+		case arg == "--show-signature":
+			opts.Signatures = true
 		case arg == "-h" || arg == "--help":
 			return nil, fmt.Errorf("help requested")
-		case arg == "-n":
-			if i+1 < len(cmdArgs) {
-				i++
-				var n int
-				_, err := fmt.Sscanf(cmdArgs[i], "%d", &n)
-				if err != nil || n < 1 {
-					return nil, fmt.Errorf("fatal: -n requires a positive integer")
-				}
-				opts.Limit = n
+		case arg == "-i" || arg == "--regexp-ignore-case":
+			opts.IgnoreCase = true
+		case arg == "-n" || hasFlag(arg, "--max-count"):
+			var v string
+			if v, err = value(&i, strings.SplitN(arg, "=", 2)[0]); err == nil {
+				opts.Limit, err = count(v)
 			}
 		case strings.HasPrefix(arg, "-n"):
-			// Handle -n5 format
-			var n int
-			_, err := fmt.Sscanf(arg[2:], "%d", &n)
-			if err != nil || n < 1 {
-				return nil, fmt.Errorf("fatal: -n requires a positive integer")
+			// -n5
+			opts.Limit, err = count(arg[2:])
+		case len(arg) > 1 && arg[0] == '-' && arg[1] >= '0' && arg[1] <= '9':
+			// -5
+			opts.Limit, err = count(arg[1:])
+		case hasFlag(arg, "--author"):
+			opts.Author, err = value(&i, "--author")
+		case hasFlag(arg, "--grep"):
+			opts.Grep, err = value(&i, "--grep")
+		case hasFlag(arg, "--since", "--after"):
+			var v string
+			if v, err = value(&i, strings.SplitN(arg, "=", 2)[0]); err == nil {
+				opts.Since, err = parseLogDate(v, now)
 			}
-			opts.Limit = n
-		case arg == "--author":
-			if i+1 < len(cmdArgs) {
-				i++
-				opts.Author = cmdArgs[i]
+		case hasFlag(arg, "--until", "--before"):
+			var v string
+			if v, err = value(&i, strings.SplitN(arg, "=", 2)[0]); err == nil {
+				opts.Until, err = parseLogDate(v, now)
 			}
-		case strings.HasPrefix(arg, "--author="):
-			opts.Author = strings.TrimPrefix(arg, "--author=")
+		case arg == "--pretty":
+			opts.Format = "medium"
+		case strings.HasPrefix(arg, "--pretty=") || strings.HasPrefix(arg, "--format="):
+			opts.Format, err = parseLogFormat(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("fatal: unrecognized argument: %s", arg)
 		default:
 			opts.Args = append(opts.Args, arg)
 		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Limit == 0 && hasLimitFlag(cmdArgs) {
+		opts.Limit = -1 // -n 0 shows nothing
 	}
 	return opts, nil
 }
 
-func (c *LogCommand) executeLog(_ *git.Session, repo *gogit.Repository, opts *LogOptions) (string, error) {
-	// executeLog performs the log operation with optional graph rendering.
-	// This implementation attempts a simplified ASCII graph.
+// hasLimitFlag reports whether a count was given explicitly, so -n 0 can be told from no limit.
+func hasLimitFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if strings.HasPrefix(arg, "-n") || strings.HasPrefix(arg, "--max-count") ||
+			(len(arg) > 1 && arg[0] == '-' && arg[1] >= '0' && arg[1] <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLogFormat validates a --pretty/--format value.
+func parseLogFormat(v string) (string, error) {
+	switch {
+	case v == "oneline" || v == "short" || v == "medium" || v == "full":
+		return v, nil
+	case strings.HasPrefix(v, "format:"), strings.HasPrefix(v, "tformat:"):
+		return "format:" + v[strings.Index(v, ":")+1:], nil
+	case strings.Contains(v, "%"):
+		// A bare template is treated as tformat:, as git does
+		return "format:" + v, nil
+	default:
+		return "", fmt.Errorf("fatal: invalid --pretty format: %s", v)
+	}
+}
 
-	logOpts := &gogit.LogOptions{
-		All: false,
+var logRelativeDate = regexp.MustCompile(`^(\d+)[ .]*(second|minute|hour|day|week|month|year)s?([ .]*ago)?$`)
+
+// parseLogDate understands the --since/--until values learners usually type:
+// ISO dates, "yesterday" and "<n> <unit>s ago" (or git's "2.weeks.ago").
+func parseLogDate(v string, now time.Time) (time.Time, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	switch v {
+	case "now", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
 	}
+	if m := logRelativeDate.FindStringSubmatch(v); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("fatal: invalid date '%s'", v)
+}
 
-	// Handle arguments (revisions)
-	if len(opts.Args) > 0 {
-		// Try to resolve the first argument as a revision
-		hash, err := repo.ResolveRevision(plumbing.Revision(opts.Args[0]))
+func (c *LogCommand) executeLog(s *git.Session, repo *gogit.Repository, opts *LogOptions) (string, error) {
+	var starts, excludes []plumbing.Hash
+	paths := opts.Paths
+	for _, arg := range opts.Args {
+		// A..B and A...B, or ^A to leave out what A reaches
+		if r, ok, err := git.ParseRevisionRange(repo, arg); ok && (err == nil || !logPathExists(repo, arg)) {
+			if err != nil {
+				return "", err
+			}
+			starts = append(starts, r.Include...)
+			excludes = append(excludes, r.Exclude...)
+			continue
+		}
+		if rev, ok := strings.CutPrefix(arg, "^"); ok {
+			if hash, err := git.ResolveRevision(repo, rev); err == nil {
+				excludes = append(excludes, *hash)
+				continue
+			}
+		}
+		hash, err := git.ResolveRevision(repo, arg)
 		if err == nil {
-			logOpts.From = *hash
+			starts = append(starts, *hash)
+			continue
+		}
+		if !logPathExists(repo, arg) {
+			return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", arg)
 		}
-		// If multiple args or complex pathspecs are needed, we'd need more logic.
-		// For now, support "git log <branch>" simplistic usage.
+		paths = append(paths, arg)
+	}
+	if opts.All {
+		starts = append(starts, git.RefTips(repo)...)
+	}
+	if len(starts) == 0 && len(excludes) > 0 {
+		// "git log ^main" alone shows nothing, like git
+		return "", nil
+	}
+	if len(starts) == 0 {
+		head, err := repo.Head()
+		if err != nil {
+			if opts.All {
+				return "", nil
+			}
+			branch := "main"
+			if ref, rerr := repo.Reference(plumbing.HEAD, false); rerr == nil && ref.Type() == plumbing.SymbolicReference {
+				branch = ref.Target().Short()
+			}
+			return "", fmt.Errorf("fatal: your current branch '%s' does not have any commits yet", branch)
+		}
+		starts = append(starts, head.Hash())
 	}
 
-	cIter, err := repo.Log(logOpts)
+	commits, err := logTopoOrder(repo, starts)
 	if err != nil {
 		return "", err
 	}
 
+	match, err := opts.matcher(repo, paths)
+	if err != nil {
+		return "", err
+	}
+	excluded := git.ReachableCommits(repo, excludes)
+	var shown []*object.Commit
+	for _, commit := range commits {
+		if opts.Limit != 0 && len(shown) >= opts.Limit {
+			break
+		}
+		if !excluded[commit.Hash] && match(commit) {
+			shown = append(shown, commit)
+		}
+	}
+
+	var decorations map[plumbing.Hash]string
+	if strings.Contains(opts.Format, "%d") || strings.Contains(opts.Format, "%D") {
+		decorations = logDecorations(repo)
+	}
+
+	// With filters, the graph joins each shown commit to its nearest shown ancestors
+	parentsOf := func(commit *object.Commit) []plumbing.Hash { return commit.ParentHashes }
+	if len(shown) != len(commits) {
+		parentsOf = logRewrittenParents(commits, shown)
+	}
+
 	var sb strings.Builder
+	graph := &logGraph{}
+	for i, commit := range shown {
+		lines := formatLogCommit(commit, opts.Format, decorations[commit.Hash])
+		if opts.Signatures {
+			if lines, err = withSignatureLines(s, commit, lines, opts.Format); err != nil {
+				return "", err
+			}
+		}
+		// Multi-line formats separate commits with a blank line
+		if opts.Format != "oneline" && !strings.HasPrefix(opts.Format, "format:") && i < len(shown)-1 {
+			lines = append(lines, "")
+		}
+		if !opts.Graph {
+			for _, line := range lines {
+				sb.WriteString(line + "\n")
+			}
+			continue
+		}
 
-	// Graph state
-	// columns tracks the commit hashes currently "active" in vertical lines
-	var columns []string
-	var count int
+		before, row, after := graph.next(commit.Hash, parentsOf(commit))
+		for _, line := range before {
+			sb.WriteString(line + "\n")
+		}
+		prefixes := append([]string{row}, after...)
+		width := len(graph.bars())
+		for _, p := range prefixes {
+			if len(p) > width {
+				width = len(p)
+			}
+		}
+		for j := 0; j < len(lines) || j < len(prefixes); j++ {
+			prefix := graph.bars()
+			if j < len(prefixes) {
+				prefix = prefixes[j]
+			}
+			if j >= len(lines) {
+				sb.WriteString(prefix + "\n")
+				continue
+			}
+			sb.WriteString(strings.TrimRight(fmt.Sprintf("%-*s %s", width, prefix, lines[j]), " ") + "\n")
+		}
+	}
+	return sb.String(), nil
+}
 
-	err = cIter.ForEach(func(c *object.Commit) error {
-		var graphLine string
-		hash := c.Hash.String()
+// matcher builds the --author/--grep/--since/--until/path filter.
+func (opts *LogOptions) matcher(repo *gogit.Repository, paths []string) (func(*object.Commit) bool, error) {
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		flags := "(?m)" // Like git, ^ and $ match at every line of the message
+		if opts.IgnoreCase {
+			flags = "(?mi)"
+		}
+		re, err := regexp.Compile(flags + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: invalid regular expression '%s'", pattern)
+		}
+		return re, nil
+	}
+	var author, grep *regexp.Regexp
+	var err error
+	if opts.Author != "" {
+		if author, err = compile(opts.Author); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Grep != "" {
+		if grep, err = compile(opts.Grep); err != nil {
+			return nil, err
+		}
+	}
 
-		if opts.Graph {
-			// 1. Determine column for current commit
-			colIndex := -1
+	return func(commit *object.Commit) bool {
+		when := commit.Committer.When
+		switch {
+		case !opts.Since.IsZero() && when.Before(opts.Since):
+			return false
+		case !opts.Until.IsZero() && when.After(opts.Until):
+			return false
+		case author != nil && !author.MatchString(fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)):
+			return false
+		case grep != nil && !grep.MatchString(commit.Message):
+			return false
+		case len(paths) > 0 && !git.CommitTouchesPaths(commit, paths):
+			return false
+		}
+		return true
+	}, nil
+}
 
-			// Check if this commit connects to an existing column (is a parent of a previous commit)
-			for i, h := range columns {
-				if h == hash {
-					colIndex = i
-					break
-				}
+// logPathExists reports whether arg names something in the worktree or in HEAD.
+func logPathExists(repo *gogit.Repository, arg string) bool {
+	if w, err := repo.Worktree(); err == nil {
+		if _, err := w.Filesystem.Stat(arg); err == nil {
+			return true
+		}
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return false
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return false
+	}
+	_, err = tree.FindEntry(strings.Trim(arg, "/"))
+	return err == nil
+}
+
+// logTopoOrder returns every commit reachable from starts, newest first, never
+// showing a commit before all of its children (git log --date-order).
+func logTopoOrder(repo *gogit.Repository, starts []plumbing.Hash) ([]*object.Commit, error) {
+	commits := make(map[plumbing.Hash]*object.Commit)
+	children := make(map[plumbing.Hash]int)
+	queue := append([]plumbing.Hash(nil), starts...)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if _, ok := commits[hash]; ok {
+			continue
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			if len(commits) == 0 {
+				return nil, fmt.Errorf("fatal: bad object %s", hash)
 			}
+			continue // Shallow boundary
+		}
+		commits[hash] = commit
+		for _, p := range commit.ParentHashes {
+			children[p]++
+			queue = append(queue, p)
+		}
+	}
 
-			// If not found in columns, it's the start of the log traverse (HEAD), so it goes in a new column (or 0)
-			if colIndex == -1 {
-				// For the very first commit (HEAD), let's put it in column 0
-				if len(columns) == 0 {
-					colIndex = 0
-					columns = append(columns, hash)
-				} else {
-					// This case implies a commit appeared that was not a parent of anyone seen so far?
-					// In standard git log traversal starting from HEAD, this shouldn't happen for the first commit,
-					// but might happen for disjoint histories or strict topo order oddities.
-					// We'll append it.
-					colIndex = len(columns)
-					columns = append(columns, hash)
-				}
+	ready := &logCommitHeap{}
+	for hash, commit := range commits {
+		if children[hash] == 0 {
+			heap.Push(ready, commit)
+		}
+	}
+	ordered := make([]*object.Commit, 0, len(commits))
+	for ready.Len() > 0 {
+		commit := heap.Pop(ready).(*object.Commit)
+		ordered = append(ordered, commit)
+		for _, p := range commit.ParentHashes {
+			parent, ok := commits[p]
+			if !ok {
+				continue
+			}
+			if children[p]--; children[p] == 0 {
+				heap.Push(ready, parent)
 			}
+		}
+	}
+	return ordered, nil
+}
 
-			// 2. Prepare graph characters
-			// Basic logic:
-			// '*' at colIndex
-			// '|' for other columns
+// logCommitHeap pops the most recently committed commit first.
+type logCommitHeap []*object.Commit
 
-			// We need to construct the line for THIS commit row
-			// And then update columns for the NEXT row (parents)
+func (h logCommitHeap) Len() int { return len(h) }
+func (h logCommitHeap) Less(i, j int) bool {
+	if h[i].Committer.When.Equal(h[j].Committer.When) {
+		return h[i].Hash.String() < h[j].Hash.String()
+	}
+	return h[i].Committer.When.After(h[j].Committer.When)
+}
+func (h logCommitHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *logCommitHeap) Push(x any)   { *h = append(*h, x.(*object.Commit)) }
+func (h *logCommitHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
 
-			lineChars := make([]string, len(columns))
-			for i := range columns {
-				if i == colIndex {
-					lineChars[i] = "*"
-				} else {
-					lineChars[i] = "|"
-				}
-			}
+// logRewrittenParents maps each shown commit to its nearest shown ancestors,
+// so the graph stays connected when filters hide commits in between.
+func logRewrittenParents(all, shown []*object.Commit) func(*object.Commit) []plumbing.Hash {
+	byHash := make(map[plumbing.Hash]*object.Commit, len(all))
+	for _, c := range all {
+		byHash[c.Hash] = c
+	}
+	visible := make(map[plumbing.Hash]bool, len(shown))
+	for _, c := range shown {
+		visible[c.Hash] = true
+	}
 
-			// Colorize asterisk (if we supported color, but simple for now)
-			graphLine = strings.Join(lineChars, " ")
-
-			// Indent message to align past the graph
-			// Standard git aligns nicely. simpler here: just space.
-			// indent = "" // handled by graphLine placement
-
-			// 3. Update columns for next row (Process Parents)
-			// Replace current commit hash in columns with its parents.
-			// If 1 parent: swap.
-			// If 2 parents: swap first, append second? (roughly)
-			// If 0 parents: remove column.
-
-			parents := c.ParentHashes
-			if len(parents) == 0 {
-				// Root commit: remove this column
-				// We strip it from the slice.
-				// To keep alignment valid for others, we usually want empty space or collapse?
-				// "git log" collapses.
-				columns = append(columns[:colIndex], columns[colIndex+1:]...)
-			} else {
-				// Replace current with Parent 0
-				columns[colIndex] = parents[0].String()
-
-				// If Merge (multiple parents), add others
-				if len(parents) > 1 {
-					for _, p := range parents[1:] {
-						// Insert or append?
-						// Git graph usually inserts next to it to show fork.
-						// Simplified: append to end.
-						found := false
-						pStr := p.String()
-						for _, existing := range columns {
-							if existing == pStr {
-								found = true
-								break
-							}
-						}
-						if !found {
-							columns = append(columns, pStr)
-						}
-					}
-				}
+	memo := make(map[plumbing.Hash][]plumbing.Hash)
+	var nearest func(hash plumbing.Hash) []plumbing.Hash
+	nearest = func(hash plumbing.Hash) []plumbing.Hash {
+		if visible[hash] {
+			return []plumbing.Hash{hash}
+		}
+		if r, ok := memo[hash]; ok {
+			return r
+		}
+		memo[hash] = nil
+		var result []plumbing.Hash
+		if c, ok := byHash[hash]; ok {
+			for _, p := range c.ParentHashes {
+				result = appendUniqueHashes(result, nearest(p)...)
 			}
 		}
+		memo[hash] = result
+		return result
+	}
 
-		// Rendering
-		msgFirstCheck := strings.Split(c.Message, "\n")[0]
+	return func(c *object.Commit) []plumbing.Hash {
+		var parents []plumbing.Hash
+		for _, p := range c.ParentHashes {
+			parents = appendUniqueHashes(parents, nearest(p)...)
+		}
+		return parents
+	}
+}
 
-		if opts.Oneline {
-			prefix := ""
-			if opts.Graph {
-				prefix = fmt.Sprintf("%-10s", graphLine) // min width padding?
-				// Better: just space
-				prefix = graphLine + " "
+func appendUniqueHashes(list []plumbing.Hash, hashes ...plumbing.Hash) []plumbing.Hash {
+	for _, h := range hashes {
+		found := false
+		for _, existing := range list {
+			if existing == h {
+				found = true
+				break
 			}
-			sb.WriteString(fmt.Sprintf("%s%s %s\n", prefix, hash[:7], msgFirstCheck))
+		}
+		if !found {
+			list = append(list, h)
+		}
+	}
+	return list
+}
+
+// logDecorations returns the "HEAD -> main, origin/main, tag: v1" label of each
+// decorated commit, plus "grafted" on the boundary of a shallow clone.
+func logDecorations(repo *gogit.Repository) map[plumbing.Hash]string {
+	labels := make(map[plumbing.Hash][]string)
+	headTarget := ""
+	if head, err := repo.Reference(plumbing.HEAD, false); err == nil {
+		if head.Type() == plumbing.SymbolicReference {
+			headTarget = head.Target().String()
 		} else {
-			// Multiline graph is hard to render correctly without line-by-line tracking.
-			// Fallback: Just show graph on first line, indent others.
-			prefix := ""
-			indentStr := ""
-			if opts.Graph {
-				prefix = graphLine + " "
-				indentStr = strings.Repeat("| ", len(columns)) // approximation
-				if len(columns) == 0 {
-					indentStr = "  "
+			labels[head.Hash()] = append(labels[head.Hash()], "HEAD")
+		}
+	}
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() != plumbing.HashReference {
+				return nil
+			}
+			name := ref.Name()
+			hash := ref.Hash()
+			var label string
+			switch {
+			case name.String() == headTarget:
+				label = "HEAD -> " + name.Short()
+			case name.IsBranch(), name.IsRemote():
+				label = name.Short()
+			case name.IsTag():
+				label = "tag: " + name.Short()
+				if tag, err := repo.TagObject(hash); err == nil {
+					if commit, err := tag.Commit(); err == nil {
+						hash = commit.Hash
+					}
 				}
+			default:
+				return nil
 			}
+			labels[hash] = append(labels[hash], label)
+			return nil
+		})
+	}
+	// History ends here in a shallow clone
+	for hash := range git.ShallowBoundary(repo) {
+		labels[hash] = append(labels[hash], "grafted")
+	}
 
-			sb.WriteString(fmt.Sprintf("%scommit %s\n%sAuthor: %s <%s>\n%sDate:   %s\n\n%s    %s\n\n",
-				prefix,
-				hash,
-				indentStr,
-				c.Author.Name,
-				c.Author.Email,
-				indentStr,
-				c.Author.When.Format(time.RFC3339),
-				indentStr,
-				strings.TrimSpace(c.Message),
-			))
-		}
-
-		count++
-		if opts.Limit > 0 && count >= opts.Limit {
-			return storer.ErrStop
-		}
-		return nil
-	})
-	if err != nil {
-		return "", err
+	decorations := make(map[plumbing.Hash]string, len(labels))
+	for hash, l := range labels {
+		// HEAD first, as git prints it
+		sort.SliceStable(l, func(i, j int) bool {
+			return strings.HasPrefix(l[i], "HEAD") && !strings.HasPrefix(l[j], "HEAD")
+		})
+		decorations[hash] = strings.Join(l, ", ")
 	}
-	return sb.String(), nil
+	return decorations
 }
 
-func (c *LogCommand) Help() string {
-	return `📘 GIT-LOG (1)                                          Git Manual
+// formatLogCommit renders one commit in the given --pretty format.
+func formatLogCommit(c *object.Commit, format, decoration string) []string {
+	hash := c.Hash.String()
+	subject := strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
 
- 💡 DESCRIPTION
-    ・これまでのコミット履歴（いつ、誰が、何をしたか）を表示する
-    ・プロジェクトの歴史を遡って確認する
+	if strings.HasPrefix(format, "format:") {
+		return strings.Split(expandLogFormat(strings.TrimPrefix(format, "format:"), c, decoration), "\n")
+	}
 
- 📋 SYNOPSIS
-    git log [options] [<revision>]
+	indented := func() []string {
+		var lines []string
+		for _, line := range strings.Split(strings.TrimRight(c.Message, "\n"), "\n") {
+			lines = append(lines, strings.TrimRight("    "+line, " "))
+		}
+		return lines
+	}
 
- ⚙️  COMMON OPTIONS
-    --oneline
-        各コミットを1行（ハッシュの一部とメッセージのみ）で表示します。
+	switch format {
+	case "oneline":
+		return []string{hash[:7] + " " + subject}
+	case "short":
+		lines := []string{"commit " + hash}
+		lines = append(lines, logMergeLine(c)...)
+		return append(lines, fmt.Sprintf("Author: %s <%s>", c.Author.Name, c.Author.Email), "", "    "+subject)
+	case "full":
+		lines := []string{"commit " + hash}
+		lines = append(lines, logMergeLine(c)...)
+		lines = append(lines,
+			fmt.Sprintf("Author: %s <%s>", c.Author.Name, c.Author.Email),
+			fmt.Sprintf("Commit: %s <%s>", c.Committer.Name, c.Committer.Email),
+			"")
+		return append(lines, indented()...)
+	default: // medium
+		lines := []string{"commit " + hash}
+		lines = append(lines, logMergeLine(c)...)
+		lines = append(lines,
+			fmt.Sprintf("Author: %s <%s>", c.Author.Name, c.Author.Email),
+			"Date:   "+c.Author.When.Format(time.RFC3339),
+			"")
+		return append(lines, indented()...)
+	}
+}
 
-    --graph
-        履歴をグラフ（ASCIIアート）として表示します。
+// withSignatureLines adds the gpg check of a signed commit, after the
+// "commit <hash>" line like git, or before the line of one-line formats.
+func withSignatureLines(s *git.Session, c *object.Commit, lines []string, format string) ([]string, error) {
+	check, err := s.VerifyCommit(c)
+	if err != nil {
+		return nil, err
+	}
+	gpg := check.Lines()
+	if len(gpg) == 0 {
+		return lines, nil
+	}
+	if format == "oneline" || strings.HasPrefix(format, "format:") {
+		return append(gpg, lines...), nil
+	}
+	return append(append([]string{lines[0]}, gpg...), lines[1:]...), nil
+}
 
-    -n <number>
-        指定した件数のコミットのみ表示します。
-        -n5 のように続けて書くこともできます。
+func logMergeLine(c *object.Commit) []string {
+	if c.NumParents() < 2 {
+		return nil
+	}
+	parents := make([]string, len(c.ParentHashes))
+	for i, p := range c.ParentHashes {
+		parents[i] = p.String()[:7]
+	}
+	return []string{"Merge: " + strings.Join(parents, " ")}
+}
 
-    --author <pattern>
-        指定したパターンに一致する作者のコミットのみ表示します。
+// expandLogFormat expands the --pretty=format: placeholders learners use most.
+// Unknown placeholders are printed as is, like git does.
+func expandLogFormat(tmpl string, c *object.Commit, decoration string) string {
+	message := strings.TrimSpace(c.Message)
+	parts := strings.SplitN(message, "\n", 2)
+	subject, body := parts[0], ""
+	if len(parts) > 1 {
+		body = strings.TrimSpace(parts[1])
+	}
+	parentHashes := func(short bool) string {
+		var list []string
+		for _, p := range c.ParentHashes {
+			if short {
+				list = append(list, p.String()[:7])
+			} else {
+				list = append(list, p.String())
+			}
+		}
+		return strings.Join(list, " ")
+	}
+
+	placeholders := map[string]func() string{
+		"H":  func() string { return c.Hash.String() },
+		"h":  func() string { return c.Hash.String()[:7] },
+		"T":  func() string { return c.TreeHash.String() },
+		"t":  func() string { return c.TreeHash.String()[:7] },
+		"P":  func() string { return parentHashes(false) },
+		"p":  func() string { return parentHashes(true) },
+		"an": func() string { return c.Author.Name },
+		"ae": func() string { return c.Author.Email },
+		"ad": func() string { return c.Author.When.Format(time.RFC3339) },
+		"ar": func() string { return relativeLogDate(c.Author.When, time.Now()) },
+		"at": func() string { return strconv.FormatInt(c.Author.When.Unix(), 10) },
+		"cn": func() string { return c.Committer.Name },
+		"ce": func() string { return c.Committer.Email },
+		"cd": func() string { return c.Committer.When.Format(time.RFC3339) },
+		"cr": func() string { return relativeLogDate(c.Committer.When, time.Now()) },
+		"ct": func() string { return strconv.FormatInt(c.Committer.When.Unix(), 10) },
+		"s":  func() string { return subject },
+		"b":  func() string { return body },
+		"B":  func() string { return message },
+		"n":  func() string { return "\n" },
+		"%":  func() string { return "%" },
+		"D":  func() string { return decoration },
+		"d": func() string {
+			if decoration == "" {
+				return ""
+			}
+			return " (" + decoration + ")"
+		},
+	}
 
- 🛠  EXAMPLES
-    1. 最新の5件を表示
-       $ git log -n 5
+	var sb strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' || i+1 >= len(tmpl) {
+			sb.WriteByte(tmpl[i])
+			continue
+		}
+		if i+2 < len(tmpl) {
+			if f, ok := placeholders[tmpl[i+1:i+3]]; ok {
+				sb.WriteString(f())
+				i += 2
+				continue
+			}
+		}
+		if f, ok := placeholders[tmpl[i+1:i+2]]; ok {
+			sb.WriteString(f())
+			i++
+			continue
+		}
+		sb.WriteByte('%')
+	}
+	return sb.String()
+}
 
-    2. 簡潔なログを表示
-       $ git log --oneline
+// relativeLogDate formats t like git's %ar ("3 days ago").
+func relativeLogDate(t, now time.Time) string {
+	d := now.Sub(t)
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", name)
+		}
+		return fmt.Sprintf("%d %ss ago", n, name)
+	}
+	switch {
+	case d < time.Minute:
+		return unit(int64(d/time.Second), "second")
+	case d < time.Hour:
+		return unit(int64(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int64(d/time.Hour), "hour")
+	case d < 14*24*time.Hour:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d < 60*24*time.Hour:
+		return unit(int64(d/(7*24*time.Hour)), "week")
+	case d < 365*24*time.Hour:
+		return unit(int64(d/(30*24*time.Hour)), "month")
+	default:
+		return unit(int64(d/(365*24*time.Hour)), "year")
+	}
+}
 
-    3. グラフ付きで表示
-       $ git log --oneline --graph
+// Spec implements git.SpecProvider.
+func (c *LogCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--oneline"}, Usage: "One line per commit"},
+			{Flags: []string{"--graph"}, Usage: "Draw the history graph"},
+			{Flags: []string{"--all"}, Usage: "Show every branch"},
+			{Flags: []string{"-n", "--max-count"}, Arg: git.ArgText, Usage: "Show at most n commits"},
+			{Flags: []string{"--author"}, Arg: git.ArgText, Usage: "Only commits by this author"},
+			{Flags: []string{"--grep"}, Arg: git.ArgText, Usage: "Only commits whose message matches"},
+			{Flags: []string{"-i", "--regexp-ignore-case"}, Usage: "Match --grep and --author ignoring case"},
+			{Flags: []string{"--since", "--after"}, Arg: git.ArgText, Usage: "Only commits after a date"},
+			{Flags: []string{"--until", "--before"}, Arg: git.ArgText, Usage: "Only commits before a date"},
+			{Flags: []string{"--pretty", "--format"}, Arg: git.ArgText, Usage: "Output format"},
+			{Flags: []string{"--show-signature"}, Usage: "Show signature checks"},
+		},
+		Args: []string{git.ArgRef, git.ArgPath},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-log
-`
+func (c *LogCommand) Help() string {
+	return git.CommandHelp(context.Background(), "log")
 }
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// logGraph draws the lanes of git log --graph, one commit at a time.
+// Commits must be fed in topological order (children before parents).
+type logGraph struct {
+	columns []plumbing.Hash // Commit each lane is waiting for
+}
+
+// graphEdge is a line leaving lane From towards the lane of Hash on the next row.
+type graphEdge struct {
+	From int
+	Hash plumbing.Hash
+}
+
+// next places a commit and returns the rows to print before it (lanes
+// converging on it), its own row, and the rows leading to the next commit
+// (merges opening lanes, lanes ending or shifting).
+func (g *logGraph) next(hash plumbing.Hash, parents []plumbing.Hash) (before []string, row string, after []string) {
+	idx := -1
+	waiting := 0
+	for i, h := range g.columns {
+		if h == hash {
+			if idx == -1 {
+				idx = i
+			}
+			waiting++
+		}
+	}
+	if idx == -1 {
+		g.columns = append(g.columns, hash)
+		idx = len(g.columns) - 1
+	}
+
+	// Several children in different lanes: join them before drawing the commit
+	if waiting > 1 {
+		edges := make([]graphEdge, len(g.columns))
+		for i, h := range g.columns {
+			edges[i] = graphEdge{From: i, Hash: h}
+		}
+		before = g.transition(edges)
+	}
+
+	chars := make([]string, len(g.columns))
+	for i := range g.columns {
+		chars[i] = "|"
+	}
+	chars[idx] = "*"
+	row = strings.Join(chars, " ")
+
+	var edges []graphEdge
+	for i, h := range g.columns {
+		if i != idx {
+			edges = append(edges, graphEdge{From: i, Hash: h})
+			continue
+		}
+		for _, p := range parents {
+			edges = append(edges, graphEdge{From: i, Hash: p})
+		}
+	}
+	after = g.transition(edges)
+	return before, row, after
+}
+
+// bars is the prefix of lines between commits: one '|' per open lane.
+func (g *logGraph) bars() string {
+	return strings.TrimRight(strings.Repeat("| ", len(g.columns)), " ")
+}
+
+// transition moves every edge to the lane of its hash, one column per row,
+// and makes the deduplicated hashes the new lanes.
+func (g *logGraph) transition(edges []graphEdge) []string {
+	var columns []plumbing.Hash
+	target := make(map[plumbing.Hash]int)
+	for _, e := range edges {
+		if _, ok := target[e.Hash]; !ok {
+			target[e.Hash] = len(columns)
+			columns = append(columns, e.Hash)
+		}
+	}
+
+	width := len(g.columns)
+	if len(columns) > width {
+		width = len(columns)
+	}
+	pos := make([]int, len(edges))
+	for i, e := range edges {
+		pos[i] = e.From
+	}
+
+	var rows []string
+	for {
+		moving := false
+		for i, e := range edges {
+			if pos[i] != target[e.Hash] {
+				moving = true
+			}
+		}
+		if !moving {
+			break
+		}
+		row := []byte(strings.Repeat(" ", 2*width))
+		for i, e := range edges {
+			switch t := target[e.Hash]; {
+			case pos[i] < t:
+				row[2*pos[i]+1] = '\\'
+				pos[i]++
+			case pos[i] > t:
+				row[2*pos[i]-1] = '/'
+				pos[i]--
+			default:
+				row[2*pos[i]] = '|'
+			}
+		}
+		rows = append(rows, strings.TrimRight(string(row), " "))
+	}
+
+	g.columns = columns
+	return rows
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
@@ -90,3 +91,96 @@ func TestLogCommand_Options(t *testing.T) {
 		}
 	})
 }
+
+func TestLogCommand_FiltersAndFormats(t *testing.T) {
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-log-filters")
+	s.InitRepo("testrepo")
+	s.CurrentDir = "/testrepo"
+
+	repo := s.GetRepo()
+	w, _ := repo.Worktree()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	commit := func(file, msg, author string, day int, parents ...plumbing.Hash) plumbing.Hash {
+		f, _ := w.Filesystem.Create(file)
+		f.Write([]byte(msg))
+		f.Close()
+		w.Add(file)
+		sig := &object.Signature{Name: author, Email: strings.ToLower(author) + "@example.com", When: start.AddDate(0, 0, day)}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig, Committer: sig, Parents: parents})
+		if err != nil {
+			t.Fatalf("commit %q: %v", msg, err)
+		}
+		return hash
+	}
+
+	commit("README.md", "Initial commit", "Alice", 0)
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true})
+	feature := commit("feature.txt", "Add feature", "Bob", 1)
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")})
+	main := commit("README.md", "Fix typo in README", "Alice", 2)
+	commit("README.md", "Merge branch 'feature'", "Alice", 3, main, feature)
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("experiment"), Create: true})
+	commit("exp.txt", "Try something", "Carol", 4)
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")})
+
+	cmd := &LogCommand{}
+	ctx := context.Background()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := cmd.Execute(ctx, s, append([]string{"log"}, args...))
+		if err != nil {
+			t.Fatalf("log %v failed: %v", args, err)
+		}
+		return out
+	}
+	subjects := func(out string) string {
+		var list []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			list = append(list, line[strings.Index(line, " ")+1:])
+		}
+		return strings.Join(list, "|")
+	}
+
+	graph := run("--oneline", "--graph")
+	want := []string{"*  ", "|\\", "* | ", "| * ", "|/", "* "}
+	lines := strings.Split(strings.TrimRight(graph, "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d graph lines, got:\n%s", len(want), graph)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("Graph line %d: expected prefix %q, got:\n%s", i, prefix, graph)
+		}
+	}
+
+	if out := run("--oneline", "--all", "-n", "1"); !strings.Contains(out, "Try something") {
+		t.Errorf("Expected --all to include other branches, got:\n%s", out)
+	}
+	if got := subjects(run("--oneline", "--author=alice", "-i")); got != "Merge branch 'feature'|Fix typo in README|Initial commit" {
+		t.Errorf("Unexpected --author result: %s", got)
+	}
+	if got := subjects(run("--oneline", "--grep", "^Fix|feature")); got != "Merge branch 'feature'|Fix typo in README|Add feature" {
+		t.Errorf("Unexpected --grep result: %s", got)
+	}
+	if got := subjects(run("--oneline", "--since=2024-01-02", "--until=2024-01-03 12:00")); got != "Fix typo in README|Add feature" {
+		t.Errorf("Unexpected --since/--until result: %s", got)
+	}
+	if got := subjects(run("--oneline", "-2")); got != "Merge branch 'feature'|Fix typo in README" {
+		t.Errorf("Unexpected -2 result: %s", got)
+	}
+	if got := subjects(run("--oneline", "--", "feature.txt")); got != "Add feature" {
+		t.Errorf("Unexpected path filter result: %s", got)
+	}
+
+	out := run("--pretty=format:%h %an <%ae>%d: %s", "-n1")
+	if want := " Alice <alice@example.com> (HEAD -> main): Merge branch 'feature'"; !strings.HasSuffix(strings.TrimSpace(out), want) {
+		t.Errorf("Unexpected format output: %q", out)
+	}
+
+	for _, args := range [][]string{{"--bogus"}, {"--pretty=weird"}, {"--since=someday"}, {"no-such-rev"}} {
+		if _, err := cmd.Execute(ctx, s, append([]string{"log"}, args...)); err == nil {
+			t.Errorf("Expected log %v to fail", args)
+		}
+	}
+}
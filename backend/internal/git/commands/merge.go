@@ -3,13 +3,16 @@ package commands
 // merge.go - Simulated Git Merge Command
 //
 // Joins two or more development histories together.
-// Supports --squash and --dry-run flags.
-// This is a simulation and creates merge commits in-memory.
+// Supports --squash, --dry-run, --abort and --continue flags.
+// Changes are combined with a 3-way merge; on conflicts the merge stops with
+// conflict markers in the worktree and is recorded in the session until it
+// is committed or aborted.
 
 import (
 	"context"
 	"fmt"
-	"os"
+	"sort"
+	"strings"
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
@@ -28,10 +31,15 @@ type MergeCommand struct{}
 var _ git.Command = (*MergeCommand)(nil)
 
 type MergeOptions struct {
-	Target string
-	Squash bool
-	DryRun bool
-	NoFF   bool
+	Target   string
+	Message  string // -m: merge commit message
+	Edit     bool   // Let the user edit the merge commit message first
+	Squash   bool
+	DryRun   bool
+	NoFF     bool
+	NoEdit   bool
+	Abort    bool // Roll back a merge stopped on conflicts
+	Continue bool // Commit a merge whose conflicts were resolved
 }
 
 type mergeContext struct {
@@ -54,10 +62,24 @@ func (c *MergeCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "merge"), nil
 		}
 		return "", err
 	}
+	if msg, ok := git.EditedMessage(ctx); ok {
+		opts.Message = msg
+	}
+	opts.Edit = opts.Message == "" && !opts.NoEdit && git.EditorAvailable(ctx)
+
+	switch {
+	case opts.Abort:
+		return c.abortMerge(s, repo)
+	case opts.Continue:
+		return c.continueMerge(s, repo)
+	}
+	if m := s.MergeInProgress(); m != nil {
+		return "", unfinishedMergeError(repo, m)
+	}
 
 	// 2. Resolve Context
 	mCtx, err := c.resolveContext(repo, opts)
@@ -74,27 +96,39 @@ func (c *MergeCommand) Execute(ctx context.Context, s *git.Session, args []strin
 
 func (c *MergeCommand) parseArgs(args []string) (*MergeOptions, error) {
 	opts := &MergeOptions{}
-	cmdArgs := args[1:]
-	for i := 0; i < len(cmdArgs); i++ {
-		arg := cmdArgs[i]
-		switch arg {
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range parsed.Flags {
+		switch f.Name {
 		case "--squash":
 			opts.Squash = true
 		case "--no-ff":
 			opts.NoFF = true
-		case "--dry-run", "-n":
+		case "-m":
+			opts.Message = f.Value
+		case "--no-edit":
+			opts.NoEdit = true
+		case "-e":
+			opts.NoEdit = false
+		case "-n":
 			opts.DryRun = true
-		case "--help", "-h":
-			return nil, fmt.Errorf("help requested")
-		default:
-			if opts.Target == "" {
-				opts.Target = arg
-			}
+		case "--abort":
+			opts.Abort = true
+		case "--continue":
+			opts.Continue = true
 		}
 	}
+	if len(parsed.Positional) > 0 {
+		opts.Target = parsed.Positional[0]
+	}
 
+	if opts.Abort || opts.Continue {
+		return opts, nil
+	}
 	if opts.Target == "" {
-		return nil, fmt.Errorf("usage: git merge [--no-ff] [--squash] [--dry-run] <branch>")
+		return nil, fmt.Errorf("usage: git merge [--no-ff] [--squash] [--dry-run] [-m <msg>] [--no-edit] <branch>\n   or: git merge (--continue | --abort)")
 	}
 	return opts, nil
 }
@@ -134,59 +168,83 @@ func (c *MergeCommand) resolveContext(repo *gogit.Repository, opts *MergeOptions
 func (c *MergeCommand) performMerge(s *git.Session, repo *gogit.Repository, mCtx *mergeContext, opts *MergeOptions) (string, error) {
 	w, _ := repo.Worktree() // Error unlikely if repo exists
 
+	// Analyze Ancestry
+	var base *object.Commit
+	if bases, err := mCtx.TargetCommit.MergeBase(mCtx.HeadCommit); err == nil && len(bases) > 0 {
+		base = bases[0]
+	}
+
+	// Already up to date
+	if base != nil && base.Hash == mCtx.TargetCommit.Hash {
+		return "Already up to date.", nil
+	}
+
+	if !opts.DryRun {
+		blockers, err := mergeBlockers(repo, w, mCtx.HeadCommit.Hash, mCtx.TargetCommit.Hash)
+		if err != nil {
+			return "", err
+		}
+		if len(blockers) > 0 {
+			return "", localChangesError(blockers)
+		}
+	}
+
 	// --- SQUASH HANDLING ---
 	if opts.Squash {
 		if opts.DryRun {
 			return fmt.Sprintf("[dry-run] Would squash-merge %s into current branch (worktree would be updated but no commit created)", opts.Target), nil
 		}
-		// Apply changes from target (Simplified: Overwrite/Add)
-		// Note: This logic overwrites files. Real squash merge handles deletions and conflicts.
-		if err := c.applyTree(w, mCtx.TargetCommit); err != nil {
+		// Changes are staged but neither HEAD nor MERGE_HEAD are updated
+		if err := git.Merge3Way(w, base, mCtx.HeadCommit, mCtx.TargetCommit, opts.Target); err != nil {
+			if err == git.ErrConflict {
+				conflicts, _ := conflictedPaths(w)
+				return "", fmt.Errorf("Squash commit -- not updating HEAD\n%s", conflictReport(conflicts, git.Rerere(s, repo, w, conflicts)))
+			}
 			return "", err
 		}
-
-		return "Squash merge -- not committed", nil
+		return "Squash commit -- not updating HEAD", nil
 	}
 
-	// 3. Analyze Ancestry
-	base, err := mCtx.TargetCommit.MergeBase(mCtx.HeadCommit)
-	if err == nil && len(base) > 0 {
-		// Already up to date
-		if base[0].Hash == mCtx.TargetCommit.Hash {
-			return "Already up to date.", nil
+	// Fast-Forward Check
+	// If base is head, then head is ancestor of target -> Fast Forward possible
+	if base != nil && base.Hash == mCtx.HeadCommit.Hash && !opts.NoFF {
+		if opts.DryRun {
+			return fmt.Sprintf("[dry-run] Would perform fast-forward merge of %s", opts.Target), nil
 		}
-
-		// Fast-Forward Check
-		// If base is head, then head is ancestor of target -> Fast Forward possible
-		if base[0].Hash == mCtx.HeadCommit.Hash {
-			// Fast-Forward allowed if NoFF is false
-			if !opts.NoFF {
-				if opts.DryRun {
-					return fmt.Sprintf("[dry-run] Would perform fast-forward merge of %s", opts.Target), nil
-				}
-				s.UpdateOrigHead() // Ensure checked before mutation
-
-				if mCtx.HeadRef.Name().IsBranch() {
-					err = w.Reset(&gogit.ResetOptions{
-						Commit: mCtx.TargetCommit.Hash,
-						Mode:   gogit.HardReset,
-					})
-					if err != nil {
-						return "", err
-					}
-					return fmt.Sprintf("Updating %s..%s\nFast-forward", mCtx.HeadCommit.Hash.String()[:7], mCtx.TargetCommit.Hash.String()[:7]), nil
-				} else {
-					// Detached HEAD
-					err = w.Checkout(&gogit.CheckoutOptions{
-						Hash: mCtx.TargetCommit.Hash,
-					})
-					if err != nil {
-						return "", err
-					}
-					return fmt.Sprintf("Fast-forward to %s", opts.Target), nil
-				}
+		if mCtx.HeadRef.Name().IsBranch() {
+			// Files the merge does not touch keep their local changes, as
+			// with reset --merge
+			plan, err := planSafeReset(repo, w, mCtx.TargetCommit.Hash, &ResetOptions{ModeName: resetMerge, Target: opts.Target})
+			if err != nil {
+				return "", err
 			}
+			// ORIG_HEAD and the branch move together, then the worktree follows
+			err = git.NewRefTransaction(repo).
+				Set(plumbing.NewHashReference(git.OrigHead, mCtx.HeadCommit.Hash)).
+				Update(plumbing.NewHashReference(mCtx.HeadRef.Name(), mCtx.TargetCommit.Hash), mCtx.HeadCommit.Hash).
+				Commit()
+			if err != nil {
+				return "", err
+			}
+			if err := plan.apply(repo, w, mCtx.TargetCommit.Hash); err != nil {
+				return "", err
+			}
+			s.RecordReflog(fmt.Sprintf("merge %s: Fast-forward", opts.Target))
+			return fmt.Sprintf("Updating %s..%s\nFast-forward", mCtx.HeadCommit.Hash.String()[:7], mCtx.TargetCommit.Hash.String()[:7]), nil
+		}
+		// Detached HEAD
+		s.UpdateOrigHead()
+		err := w.Checkout(&gogit.CheckoutOptions{
+			Hash: mCtx.TargetCommit.Hash,
+		})
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("Fast-forward to %s", opts.Target), nil
+	}
+
+	if base == nil {
+		return "", fmt.Errorf("fatal: refusing to merge unrelated histories")
 	}
 
 	if opts.DryRun {
@@ -203,83 +261,244 @@ func (c *MergeCommand) performMerge(s *git.Session, repo *gogit.Repository, mCtx
 	}
 
 	// 4. Merge Commit
-	// Apply changes from target to worktree (Simulation: Overwrite/Add from Target "Theirs")
-	if err := c.applyTree(w, mCtx.TargetCommit); err != nil {
-		return "", err
-	}
-
 	msg := fmt.Sprintf("Merge branch '%s'", opts.Target)
-	parents := []plumbing.Hash{mCtx.HeadCommit.Hash, mCtx.TargetCommit.Hash}
-
+	if opts.Message != "" {
+		msg = opts.Message
+	}
 	s.UpdateOrigHead()
 
+	if err := git.Merge3Way(w, base, mCtx.HeadCommit, mCtx.TargetCommit, opts.Target); err != nil {
+		if err != git.ErrConflict {
+			return "", err
+		}
+		// Stop and let the user resolve: the merge is concluded by commit or --continue
+		conflicts, _ := conflictedPaths(w)
+		s.StartMerge(&git.MergeState{
+			MergeHead: mCtx.TargetCommit.Hash.String(),
+			OrigHead:  mCtx.HeadCommit.Hash.String(),
+			Message:   msg,
+			Conflicts: conflicts,
+		})
+		s.RecordReflog(fmt.Sprintf("merge %s: stopped on conflicts", opts.Target))
+		return "", fmt.Errorf("%s", conflictReport(conflicts, git.Rerere(s, repo, w, conflicts)))
+	}
+	if opts.Edit {
+		// Like git, the merged tree waits for the message as an unfinished merge
+		s.StartMerge(&git.MergeState{
+			MergeHead: mCtx.TargetCommit.Hash.String(),
+			OrigHead:  mCtx.HeadCommit.Hash.String(),
+			Message:   msg,
+		})
+		return "", mergeEditorRequest(s.MergeInProgress())
+	}
+
 	newCommitHash, err := w.Commit(msg, &gogit.CommitOptions{
-		Parents:           parents,
-		Author:            git.GetDefaultSignature(),
-		Committer:         git.GetDefaultSignature(),
+		Parents:           []plumbing.Hash{mCtx.HeadCommit.Hash, mCtx.TargetCommit.Hash},
+		Author:            s.Signature(),
+		Committer:         s.Signature(),
 		AllowEmptyCommits: true, // Merge commits should always be created even without tree changes
 	})
 	if err != nil {
 		return "", err
 	}
+	s.RecordReflog(fmt.Sprintf("merge %s: Merge made by the 'ort' strategy.", opts.Target))
 
 	return fmt.Sprintf("Merge made by the 'ort' strategy.\n %s", newCommitHash.String()), nil
 }
 
-func (c *MergeCommand) applyTree(w *gogit.Worktree, commit *object.Commit) error {
-	tree, err := commit.Tree()
+// continueMerge commits a merge whose conflicts have been resolved and staged.
+func (c *MergeCommand) continueMerge(s *git.Session, repo *gogit.Repository) (string, error) {
+	if s.MergeInProgress() == nil {
+		return "", fmt.Errorf("fatal: There is no merge in progress (MERGE_HEAD missing).")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	hash, err := concludeMerge(s, repo, "")
+	if err != nil {
+		return "", err
+	}
+	recorded := git.RerereRecordResolutions(s, repo, w)
+
+	label := "HEAD"
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		label = head.Name().Short()
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", err
+	}
+	return prependLines(recorded, fmt.Sprintf("[%s %s] %s", label, hash.String()[:7], strings.SplitN(commit.Message, "\n", 2)[0])), nil
+}
+
+// abortMerge rolls the branch, index and worktree back to before the merge.
+// Like git, it is a reset --merge: only the paths the merge wrote go back,
+// and local changes to the files it left alone survive.
+func (c *MergeCommand) abortMerge(s *git.Session, repo *gogit.Repository) (string, error) {
+	m := s.MergeInProgress()
+	if m == nil {
+		return "", fmt.Errorf("fatal: There is no merge to abort (MERGE_HEAD missing).")
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if err := resetMergeTo(repo, w, plumbing.NewHash(m.OrigHead), m.Conflicts); err != nil {
+		return "", err
+	}
+
+	s.ClearMerge()
+	git.RerereClear(s)
+	s.RecordReflog("merge --abort: returning to ORIG_HEAD")
+	return "", nil
+}
+
+// resetMergeTo moves HEAD, the index and the paths an unfinished merge,
+// cherry-pick or revert wrote back to orig, like "git reset --merge orig".
+// conflicts are the paths it left conflict markers in: their index entry did
+// not change, so they are named to be put back too.
+func resetMergeTo(repo *gogit.Repository, w *gogit.Worktree, orig plumbing.Hash, conflicts []string) error {
+	plan, err := planSafeReset(repo, w, orig, &ResetOptions{ModeName: resetMerge, Target: "ORIG_HEAD"})
 	if err != nil {
 		return err
 	}
+	for _, p := range conflicts {
+		delete(plan.keep, p)
+	}
+	return plan.apply(repo, w, orig)
+}
 
-	return tree.Files().ForEach(func(f *object.File) error {
-		content, contentErr := f.Contents()
-		if contentErr != nil {
-			return contentErr
-		}
-		path := f.Name
-		fsFile, openErr := w.Filesystem.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
-		if openErr != nil {
-			return openErr
-		}
-		defer fsFile.Close()
-		if _, writeErr := fsFile.Write([]byte(content)); writeErr != nil {
-			return writeErr
+// mergeBlockers lists the local changes merging target into head would
+// overwrite: changed files the merge writes, and anything staged, which an
+// abort could not give back.
+func mergeBlockers(repo *gogit.Repository, w *gogit.Worktree, head, target plumbing.Hash) ([]string, error) {
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := overwrittenPaths(repo, status, head, target)
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		listed[p] = true
+	}
+	for p, fs := range status {
+		if fs.Staging != gogit.Unmodified && fs.Staging != gogit.Untracked && !listed[p] {
+			paths = append(paths, p)
 		}
-		_, err := w.Add(path)
-		return err
-	})
+	}
+	sort.Strings(paths)
+	return paths, nil
 }
 
-func (c *MergeCommand) Help() string {
-	return `📘 GIT-MERGE (1)                                        Git Manual
+// localChangesError is git's refusal to merge over local changes.
+func localChangesError(paths []string) error {
+	return fmt.Errorf("error: Your local changes to the following files would be overwritten by merge:\n\t%s\nPlease commit your changes or stash them before you merge.\nAborting", strings.Join(paths, "\n\t"))
+}
 
- 💡 DESCRIPTION
-    ・別のブランチの変更を、現在のブランチに取り込む
-    ・2つの異なる開発履歴を1つに統合する
-    通常は「マージコミット」が自動的に作成されます。
+// concludeMerge creates the merge commit of the unfinished merge, refusing
+// while conflicts are unresolved. An empty message uses the prepared one.
+func concludeMerge(s *git.Session, repo *gogit.Repository, message string) (plumbing.Hash, error) {
+	m := s.MergeInProgress()
 
- 📋 SYNOPSIS
-    git merge [--no-ff] [--squash] <branch>
+	w, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if unresolved, err := unresolvedPaths(w, m.Conflicts); err != nil {
+		return plumbing.ZeroHash, err
+	} else if len(unresolved) > 0 {
+		return plumbing.ZeroHash, unmergedFilesError(unresolved, "fatal: Exiting because of an unresolved conflict.")
+	}
 
- ⚙️  COMMON OPTIONS
-    --no-ff
-        Fast-forward 可能な場合でも、強制的にマージコミットを作成します。
-        履歴上に「ここで統合した」という事実を明確に残したい場合に使います。
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if message == "" {
+		message = m.Message
+	}
 
-    --squash
-        マージコミットを作成せず、変更内容のみをワーキングツリーに取り込みます。
-        あとで自分でコミットする場合に使用します。
+	hash, err := w.Commit(message, &gogit.CommitOptions{
+		Parents:           []plumbing.Hash{head.Hash(), plumbing.NewHash(m.MergeHead)},
+		Author:            s.Signature(),
+		Committer:         s.Signature(),
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
 
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: featureブランチをマージ
-       $ git merge feature/login
+	s.ClearMerge()
+	s.RecordReflog(fmt.Sprintf("commit (merge): %s", strings.SplitN(message, "\n", 2)[0]))
+	return hash, nil
+}
 
-    2. 実践: マージコミットを必ず作る (Recommended)
-       単なるポインタ移動(Fast-forward)ではなく、コミットを残します。
-       $ git merge --no-ff feature/login
+// mergeEditorRequest asks for the message of the merge commit concluding m.
+// The edited message is committed by "git commit".
+func mergeEditorRequest(m *git.MergeState) error {
+	return &git.EditorRequest{
+		File: git.MergeEditFile,
+		Template: m.Message + "\n\n" + git.CommentLines(
+			"Please enter a commit message to explain why this merge is necessary,",
+			"especially if it merges an updated upstream into a topic branch.",
+			"",
+			"Lines starting with '#' will be ignored, and an empty message aborts",
+			"the commit.",
+		),
+		Resume: []string{"commit"},
+		Abort:  "Not committing merge; use 'git commit' to complete the merge.",
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-merge
-`
+// unfinishedMergeError explains why a new merge cannot start while m is unfinished.
+func unfinishedMergeError(repo *gogit.Repository, m *git.MergeState) error {
+	if w, err := repo.Worktree(); err == nil {
+		if unresolved, _ := unresolvedPaths(w, m.Conflicts); len(unresolved) > 0 {
+			return fmt.Errorf("error: Merging is not possible because you have unmerged files.\n" +
+				"hint: Fix them up in the work tree, and then use 'git add/rm <file>'\n" +
+				"hint: as appropriate to mark resolution and make a commit.\n" +
+				"fatal: Exiting because of an unresolved conflict.")
+		}
+	}
+	return fmt.Errorf("fatal: You have not concluded your merge (MERGE_HEAD exists).\nPlease, commit your changes before you merge.")
+}
+
+// conflictReport is what git prints when an automatic merge stops on
+// conflicts, with rerere's lines about them.
+func conflictReport(conflicts, rerere []string) string {
+	var sb strings.Builder
+	for _, path := range conflicts {
+		sb.WriteString(fmt.Sprintf("Auto-merging %s\nCONFLICT (content): Merge conflict in %s\n", path, path))
+	}
+	for _, line := range rerere {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("Automatic merge failed; fix conflicts and then commit the result.")
+	return sb.String()
+}
+
+// Spec implements git.SpecProvider.
+func (c *MergeCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-m"}, Arg: git.ArgText, Usage: "Merge commit message"},
+			{Flags: []string{"--no-ff"}, Usage: "Always create a merge commit"},
+			{Flags: []string{"--squash"}, Usage: "Stage the changes without committing"},
+			{Flags: []string{"-e", "--edit"}, Usage: "Edit the merge message"},
+			{Flags: []string{"--no-edit"}, Usage: "Keep the default merge message"},
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would happen"},
+			{Flags: []string{"--continue"}, Usage: "Continue after resolving conflicts"},
+			{Flags: []string{"--abort"}, Usage: "Cancel and go back"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *MergeCommand) Help() string {
+	return git.CommandHelp(context.Background(), "merge")
 }
@@ -1,10 +1,17 @@
 package commands
 
+// merge_pr.go - Merges a pull request on the shared remote
+//
+// Like GitHub's merge button, the merge happens on the server (the shared bare
+// remote), with one of three strategies: a merge commit, a squash commit, or
+// the head branch's commits rebased onto the base branch.
+
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
@@ -20,6 +27,8 @@ func init() {
 type MergePRCommand struct {
 	prID       int
 	remoteName string
+	strategy   string // git.PRMergeCommit, git.PRMergeSquash or git.PRMergeRebase
+	mergedBy   string
 
 	pr     *git.PullRequest
 	repo   *gogit.Repository
@@ -37,45 +46,68 @@ func (c *MergePRCommand) Execute(ctx context.Context, s *git.Session, args []str
 		return "", err
 	}
 
+	// The merge writes objects and moves the base branch like a push does
+	defer c.engine.Manager.LockRemote(c.repo)()
 	return c.performAction(ctx)
 }
 
 func (c *MergePRCommand) parseArgs(args []string) error {
-	if len(args) < 3 {
-		return fmt.Errorf("usage: merge-pr <pr-id> <remote-name>")
+	var positional []string
+	c.strategy = git.PRMergeCommit
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--strategy", "--merged-by":
+			if i+1 >= len(args) {
+				return fmt.Errorf("option '%s' requires a value", arg)
+			}
+			i++
+			if arg == "--strategy" {
+				c.strategy = args[i]
+			} else {
+				c.mergedBy = args[i]
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 2 {
+		return fmt.Errorf("usage: merge-pr <pr-id> <remote-name> [--strategy merge|squash|rebase] [--merged-by <name>]")
+	}
+	switch c.strategy {
+	case git.PRMergeCommit, git.PRMergeSquash, git.PRMergeRebase:
+	default:
+		return fmt.Errorf("unknown merge strategy %q (want merge, squash or rebase)", c.strategy)
 	}
 
-	prID, err := strconv.Atoi(args[1])
+	prID, err := strconv.Atoi(positional[0])
 	if err != nil {
-		return fmt.Errorf("invalid PR ID %q: %w", args[1], err)
+		return fmt.Errorf("invalid PR ID %q: %w", positional[0], err)
 	}
 
 	c.prID = prID
-	c.remoteName = args[2]
+	c.remoteName = positional[1]
 	return nil
 }
 
 func (c *MergePRCommand) resolveContext(_ context.Context) error {
 	sm := c.engine.Manager
-	sm.RLock()
-	defer sm.RUnlock()
 
 	// 1. Find Pull Request
-	var foundPR *git.PullRequest
-	for _, p := range sm.PullRequests {
-		if p.ID == c.prID {
-			foundPR = p
-			break
-		}
+	pr, err := sm.GetPullRequest(c.prID)
+	if err != nil {
+		return err
 	}
-	if foundPR == nil {
-		return fmt.Errorf("pull request #%d not found", c.prID)
+	if pr.State != git.PRStateOpen {
+		return fmt.Errorf("pull request #%d is not OPEN (current state: %s)", c.prID, pr.State)
 	}
-
-	if foundPR.State != "OPEN" {
-		return fmt.Errorf("pull request #%d is not OPEN (current state: %s)", c.prID, foundPR.State)
+	// Configured checks are required, as with GitHub's branch protection
+	switch pr.ChecksState {
+	case git.CheckPending:
+		return fmt.Errorf("pull request #%d cannot be merged yet: checks are still running\nhint: Wait for the checks to finish (see /api/checks?pr=%d).", c.prID, c.prID)
+	case git.CheckFailure:
+		return fmt.Errorf("pull request #%d cannot be merged: some checks failed\nhint: Fix the problems on %s and push again to re-run the checks.", c.prID, pr.HeadRef)
 	}
-	c.pr = foundPR
+	c.pr = pr
 
 	// 2. Resolve Remote Repository
 	// Use the remote name from the PR itself as the source of truth if available and not "origin"
@@ -84,10 +116,10 @@ func (c *MergePRCommand) resolveContext(_ context.Context) error {
 		targetRemote = c.pr.RemoteName
 	}
 
-	repo, ok := sm.SharedRemotes[targetRemote]
+	repo, ok := sm.GetSharedRemote(targetRemote)
 	if !ok {
 		// Fallback to the requested one if PR remote not found? No, let's be strict if we have a mismatch.
-		repo, ok = sm.SharedRemotes[c.remoteName]
+		repo, ok = sm.GetSharedRemote(c.remoteName)
 		if !ok {
 			return fmt.Errorf("remote repository %q not found (PR expected %q)", c.remoteName, c.pr.RemoteName)
 		}
@@ -98,86 +130,186 @@ func (c *MergePRCommand) resolveContext(_ context.Context) error {
 	return nil
 }
 
-func (c *MergePRCommand) performAction(_ context.Context) (string, error) {
-	log.Printf("MergePRCommand: Merging PR #%d (%s -> %s) on remote %q", c.prID, c.pr.HeadRef, c.pr.BaseRef, c.remoteName)
+func (c *MergePRCommand) performAction(ctx context.Context) (string, error) {
+	slog.InfoContext(ctx, "merging pull request", "remote", c.remoteName, "pr", c.prID, "head", c.pr.HeadRef, "base", c.pr.BaseRef, "strategy", c.strategy)
 
 	// Resolve references
-	baseRefName := plumbing.ReferenceName("refs/heads/" + c.pr.BaseRef)
-	headRefName := plumbing.ReferenceName("refs/heads/" + c.pr.HeadRef)
+	baseRefName := plumbing.NewBranchReferenceName(c.pr.BaseRef)
+	headRefName := plumbing.NewBranchReferenceName(c.pr.HeadRef)
 
-	log.Printf("MergePRCommand: Resolving base ref: %s", baseRefName)
 	baseRef, err := c.repo.Reference(baseRefName, true)
 	if err != nil {
-		log.Printf("MergePRCommand: Base branch %q NOT found: %v", c.pr.BaseRef, err)
 		return "", fmt.Errorf("base branch %q not found in remote: %w", c.pr.BaseRef, err)
 	}
-
-	log.Printf("MergePRCommand: Resolving source ref: %s", headRefName)
 	headRef, err := c.repo.Reference(headRefName, true)
 	if err != nil {
-		log.Printf("MergePRCommand: Source branch %q NOT found: %v", c.pr.HeadRef, err)
 		return "", fmt.Errorf("source branch %q not found in remote: %w", c.pr.HeadRef, err)
 	}
 
-	log.Printf("MergePRCommand: Found base %s and head %s", baseRef.Hash(), headRef.Hash())
-
-	// 1. Get Base Commit
 	baseCommit, err := c.repo.CommitObject(baseRef.Hash())
 	if err != nil {
 		return "", fmt.Errorf("failed to retrieve base commit %s: %w", baseRef.Hash(), err)
 	}
-	// 2. Get Head Commit
 	headCommit, err := c.repo.CommitObject(headRef.Hash())
 	if err != nil {
 		return "", fmt.Errorf("failed to retrieve source commit %s: %w", headRef.Hash(), err)
 	}
 
-	// 3. Create Merge Commit using "Theirs" tree (Head's tree snapshot)
-	mergeCommit := &object.Commit{
-		Author: object.Signature{
-			Name:  "GitGym Merge Bot",
-			Email: "bot@gitgym.com",
-			When:  time.Now(),
-		},
-		Committer: object.Signature{
-			Name:  "GitGym Merge Bot",
-			Email: "bot@gitgym.com",
-			When:  time.Now(),
-		},
-		Message:  fmt.Sprintf("Merge pull request #%d from %s\n\n%s", c.prID, c.pr.HeadRef, c.pr.Title),
-		TreeHash: headCommit.TreeHash,
-		ParentHashes: []plumbing.Hash{
-			baseCommit.Hash,
-			headCommit.Hash,
-		},
-	}
-
-	obj := c.repo.Storer.NewEncodedObject()
-	if err := mergeCommit.Encode(obj); err != nil {
-		return "", fmt.Errorf("failed to encode merge commit: %w", err)
-	}
-
-	newHash, err := c.repo.Storer.SetEncodedObject(obj)
+	// Commits on the head branch that the base branch does not have yet
+	commits, err := (&git.RevisionRange{Include: []plumbing.Hash{headCommit.Hash}, Exclude: []plumbing.Hash{baseCommit.Hash}}).Commits(c.repo)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("nothing to merge: %s is already part of %s", c.pr.HeadRef, c.pr.BaseRef)
+	}
+	bases, err := git.MergeBases(c.repo, baseCommit.Hash, headCommit.Hash)
+	if err != nil {
+		return "", err
+	}
+	if len(bases) == 0 {
+		return "", fmt.Errorf("%s and %s have entirely different commit histories", c.pr.BaseRef, c.pr.HeadRef)
+	}
+	mergeBase, err := c.repo.CommitObject(bases[0])
+	if err != nil {
+		return "", err
+	}
+
+	var newHash plumbing.Hash
+	switch c.strategy {
+	case git.PRMergeRebase:
+		newHash, err = c.rebaseCommits(baseCommit, commits)
+	case git.PRMergeSquash:
+		var tree plumbing.Hash
+		if tree, err = c.mergeTrees(mergeBase, baseCommit, headCommit); err == nil {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("%s (#%d)\n", c.pr.Title, c.prID))
+			for _, commit := range commits {
+				sb.WriteString("\n* " + strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0] + "\n")
+			}
+			newHash, err = c.writeCommit(tree, sb.String(), nil, baseCommit.Hash)
+		}
+	default:
+		var tree plumbing.Hash
+		if tree, err = c.mergeTrees(mergeBase, baseCommit, headCommit); err == nil {
+			message := fmt.Sprintf("Merge pull request #%d from %s\n\n%s", c.prID, c.pr.HeadRef, c.pr.Title)
+			newHash, err = c.writeCommit(tree, message, nil, baseCommit.Hash, headCommit.Hash)
+		}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to store merge commit: %w", err)
+		return "", err
 	}
 
-	// 4. Update Remote Reference
-	log.Printf("MergePRCommand: Updating %s to %s", baseRefName, newHash)
-	newRef := plumbing.NewHashReference(baseRefName, newHash)
-	if err := c.repo.Storer.SetReference(newRef); err != nil {
+	// Update Remote Reference
+	slog.DebugContext(ctx, "updating base branch", "ref", baseRefName, "hash", newHash)
+	if err := c.repo.Storer.SetReference(plumbing.NewHashReference(baseRefName, newHash)); err != nil {
 		return "", fmt.Errorf("failed to update remote branch %q: %w", c.pr.BaseRef, err)
 	}
 
-	// 5. Update PR State
-	c.engine.Manager.Lock()
-	c.pr.State = "MERGED"
-	c.engine.Manager.Unlock()
+	// Update PR State
+	if _, err := c.engine.Manager.MarkPullRequestMerged(c.prID, c.strategy, newHash.String(), c.mergedBy); err != nil {
+		return "", err
+	}
+	// The merged commits may fix issues, as if pushed to the base branch
+	c.engine.Manager.CloseLinkedIssues(c.repo, c.pr.BaseRef, newHash)
 
-	log.Printf("MergePRCommand: PR #%d merged successfully", c.prID)
-	return fmt.Sprintf("Successfully merged PR #%d into %s", c.prID, c.pr.BaseRef), nil
+	slog.InfoContext(ctx, "pull request merged", "remote", c.remoteName, "pr", c.prID)
+	return fmt.Sprintf("Successfully merged PR #%d into %s (%s: %s)", c.prID, c.pr.BaseRef, c.strategy, newHash.String()[:7]), nil
+}
+
+// rebaseCommits replays commits, oldest first, on top of onto and returns the new tip.
+func (c *MergePRCommand) rebaseCommits(onto *object.Commit, commits []*object.Commit) (plumbing.Hash, error) {
+	current := onto
+	for _, commit := range commits {
+		if commit.NumParents() != 1 {
+			return plumbing.ZeroHash, fmt.Errorf("pull request #%d cannot be rebased: %s is a merge commit", c.prID, commit.Hash.String()[:7])
+		}
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree, err := c.mergeTrees(parent, current, commit)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		author := commit.Author
+		hash, err := c.writeCommit(tree, commit.Message, &author, current.Hash)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if current, err = c.repo.CommitObject(hash); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	return current.Hash, nil
+}
+
+// mergeTrees combines the changes from base to ours and from base to theirs,
+// file by file. The server cannot stop for conflict resolution, so a file
+// changed differently on both sides fails the merge.
+func (c *MergePRCommand) mergeTrees(base, ours, theirs *object.Commit) (plumbing.Hash, error) {
+	conflicts, err := git.ConflictingPaths(base, ours, theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if len(conflicts) > 0 {
+		return plumbing.ZeroHash, fmt.Errorf("pull request #%d has conflicts that must be resolved: %s\nhint: Merge %s into %s locally, fix the conflicts and push again.",
+			c.prID, strings.Join(conflicts, ", "), c.pr.BaseRef, c.pr.HeadRef)
+	}
+
+	var sides [3]map[string]treeFile
+	for i, commit := range []*object.Commit{base, ours, theirs} {
+		files, err := commitFiles(commit)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		sides[i] = files
+	}
+	b, o, t := sides[0], sides[1], sides[2]
+
+	merged := make(map[string]treeFile, len(o))
+	for name, f := range o {
+		merged[name] = f
+	}
+	for name := range changedPaths(b, t) {
+		if o[name] != b[name] {
+			continue // Both sides made the same change
+		}
+		if f, ok := t[name]; ok {
+			merged[name] = f
+		} else {
+			delete(merged, name)
+		}
+	}
+	return writeTree(c.repo, merged)
+}
+
+// writeCommit stores a commit made by the merge bot. author defaults to the bot.
+func (c *MergePRCommand) writeCommit(tree plumbing.Hash, message string, author *object.Signature, parents ...plumbing.Hash) (plumbing.Hash, error) {
+	bot := object.Signature{
+		Name:  "GitGym Merge Bot",
+		Email: "bot@gitgym.com",
+		When:  time.Now(),
+	}
+	if author == nil {
+		author = &bot
+	}
+	return storeObject(c.repo, &object.Commit{
+		Author:       *author,
+		Committer:    bot,
+		Message:      message,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	})
+}
+
+// Spec implements git.SpecProvider.
+func (c *MergePRCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Hidden: true,
+	}
 }
 
 func (c *MergePRCommand) Help() string {
-	return "usage: merge-pr <pr-id> <remote-name>"
+	return git.CommandHelp(context.Background(), "merge-pr")
 }
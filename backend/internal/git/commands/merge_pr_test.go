@@ -7,10 +7,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMergePRCommand(t *testing.T) {
@@ -76,8 +81,8 @@ func TestMergePRCommand(t *testing.T) {
 	t.Log(output)
 
 	// Verify PR status
-	if pr.State != "MERGED" {
-		t.Errorf("PR state is %s, expected MERGED", pr.State)
+	if merged, err := sm.GetPullRequest(pr.ID); err != nil || merged.State != "MERGED" {
+		t.Errorf("PR state is %v (%v), expected MERGED", merged, err)
 	}
 
 	// Verify Commit on Remote 'master'
@@ -105,3 +110,129 @@ func TestMergePRCommand(t *testing.T) {
 		t.Errorf("Expected 2 parents for merge commit, got %d", len(mergeCommit.ParentHashes))
 	}
 }
+
+func TestMergePRCommand_Strategies(t *testing.T) {
+	// setup builds a remote where main moved on after feature branched off:
+	// main: base - main change, feature: base - feature one - feature two
+	setup := func(t *testing.T, mainFile string) (*git.SessionManager, *git.Session, *gogit.Repository, *git.PullRequest) {
+		sm := git.NewSessionManager()
+		repo, _ := gogit.Init(memory.NewStorage(), memfs.New())
+		w, _ := repo.Worktree()
+		day := 0
+		commit := func(file, content, msg string) {
+			require.NoError(t, util.WriteFile(w.Filesystem, file, []byte(content), 0644))
+			_, err := w.Add(file)
+			require.NoError(t, err)
+			day++
+			sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)}
+			_, err = w.Commit(msg, &gogit.CommitOptions{Author: sig, Committer: sig})
+			require.NoError(t, err)
+		}
+		commit("README.md", "base\n", "Base")
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+		commit("feature.txt", "one\n", "Feature one")
+		commit("README.md", "base\nfeature docs\n", "Feature two")
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}))
+		commit(mainFile, "main change\n", "Main change")
+
+		sm.SharedRemotes["origin"] = repo
+		pr, _ := sm.CreatePullRequest("Feat", "Desc", "feature", "master", "Dev", "origin")
+		session, _ := sm.CreateSession("test-merge-pr")
+		return sm, session, repo, pr
+	}
+	merge := func(s *git.Session, pr *git.PullRequest, strategy string) (string, error) {
+		return (&MergePRCommand{}).Execute(context.Background(), s, []string{"merge-pr", strconv.Itoa(pr.ID), "origin", "--strategy", strategy, "--merged-by", "Reviewer"})
+	}
+	tip := func(repo *gogit.Repository) *object.Commit {
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+		require.NoError(t, err)
+		c, err := repo.CommitObject(ref.Hash())
+		require.NoError(t, err)
+		return c
+	}
+	content := func(c *object.Commit, name string) string {
+		f, err := c.File(name)
+		require.NoError(t, err, name)
+		s, _ := f.Contents()
+		return s
+	}
+
+	t.Run("merge commit keeps both sides", func(t *testing.T) {
+		sm, s, repo, pr := setup(t, "main.txt")
+		_, err := merge(s, pr, "merge")
+		require.NoError(t, err)
+		c := tip(repo)
+		assert.Len(t, c.ParentHashes, 2)
+		assert.Equal(t, "main change\n", content(c, "main.txt"))
+		assert.Equal(t, "base\nfeature docs\n", content(c, "README.md"))
+		got, _ := sm.GetPullRequest(pr.ID)
+		assert.Equal(t, git.PRStateMerged, got.State)
+		assert.Equal(t, c.Hash.String(), got.MergeCommit)
+		assert.Equal(t, "Reviewer", got.MergedBy)
+	})
+
+	t.Run("squash", func(t *testing.T) {
+		_, s, repo, pr := setup(t, "main.txt")
+		_, err := merge(s, pr, "squash")
+		require.NoError(t, err)
+		c := tip(repo)
+		require.Len(t, c.ParentHashes, 1)
+		parent, _ := c.Parent(0)
+		assert.Equal(t, "Main change", parent.Message)
+		assert.Equal(t, "Feat (#1)\n\n* Feature one\n\n* Feature two\n", c.Message)
+		assert.Equal(t, "one\n", content(c, "feature.txt"))
+		assert.Equal(t, "main change\n", content(c, "main.txt"))
+	})
+
+	t.Run("rebase", func(t *testing.T) {
+		_, s, repo, pr := setup(t, "main.txt")
+		_, err := merge(s, pr, "rebase")
+		require.NoError(t, err)
+		var messages []string
+		for c := tip(repo); ; {
+			messages = append(messages, c.Message)
+			if c.NumParents() == 0 {
+				break
+			}
+			assert.Equal(t, 1, c.NumParents(), "rebase keeps history linear")
+			c, _ = c.Parent(0)
+		}
+		assert.Equal(t, []string{"Feature two", "Feature one", "Main change", "Base"}, messages)
+		assert.Equal(t, "Dev", tip(repo).Author.Name, "rebased commits keep their author")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		sm, s, repo, pr := setup(t, "README.md")
+		before := tip(repo).Hash
+		_, err := merge(s, pr, "merge")
+		assert.ErrorContains(t, err, "conflicts that must be resolved: README.md")
+		assert.Equal(t, before, tip(repo).Hash)
+		got, _ := sm.GetPullRequest(pr.ID)
+		assert.Equal(t, git.PRStateOpen, got.State)
+	})
+
+	t.Run("failing checks block the merge", func(t *testing.T) {
+		sm, s, repo, pr := setup(t, "main.txt")
+		sm.CheckDelay = 0
+		require.NoError(t, sm.SetRemoteCheckRules("origin", []git.CheckRule{{Name: "changelog", Kind: git.CheckFileExists, Path: "CHANGELOG.md"}}))
+		head, err := repo.Reference(plumbing.NewBranchReferenceName("feature"), true)
+		require.NoError(t, err)
+		sm.BranchPushed(repo, "feature", head.Hash())
+		sm.WaitForChecks()
+
+		_, err = merge(s, pr, "merge")
+		assert.ErrorContains(t, err, "some checks failed")
+		got, _ := sm.GetPullRequest(pr.ID)
+		assert.Equal(t, git.PRStateOpen, got.State)
+	})
+
+	t.Run("invalid strategy and closed PR", func(t *testing.T) {
+		sm, s, _, pr := setup(t, "main.txt")
+		_, err := merge(s, pr, "octopus")
+		assert.Error(t, err)
+		_, err = sm.ClosePullRequest(pr.ID)
+		require.NoError(t, err)
+		_, err = merge(s, pr, "merge")
+		assert.ErrorContains(t, err, "not OPEN")
+	})
+}
@@ -4,8 +4,17 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMergeCommand_Basic(t *testing.T) {
@@ -63,3 +72,186 @@ func TestMergeCommand_Basic(t *testing.T) {
 		}
 	})
 }
+
+func TestMergeConflictContinueAndAbort(t *testing.T) {
+	setup := func(t *testing.T) (*git.Session, *gogit.Repository, plumbing.Hash, plumbing.Hash) {
+		fs := memfs.New()
+		r, _ := gogit.Init(memory.NewStorage(), fs)
+		w, _ := r.Worktree()
+		sig := &object.Signature{Name: "User", Email: "u@t.com", When: time.Now()}
+
+		writeAndAdd := func(name, content string) {
+			require.NoError(t, util.WriteFile(fs, name, []byte(content), 0644))
+			_, _ = w.Add(name)
+		}
+
+		writeAndAdd("file.txt", "base\n")
+		writeAndAdd("other.txt", "untouched\n")
+		_, _ = w.Commit("Base", &gogit.CommitOptions{Author: sig})
+
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+		writeAndAdd("file.txt", "base\nfeature\n")
+		featureHash, _ := w.Commit("Feature", &gogit.CommitOptions{Author: sig})
+
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master}))
+		writeAndAdd("file.txt", "base\nmaster\n")
+		masterHash, _ := w.Commit("Master", &gogit.CommitOptions{Author: sig})
+
+		session := &git.Session{
+			ID:         "test-session",
+			Filesystem: fs,
+			Repos:      map[string]*gogit.Repository{"repo": r},
+			CurrentDir: "/repo",
+		}
+		return session, r, masterHash, featureHash
+	}
+
+	t.Run("continue after resolving", func(t *testing.T) {
+		session, r, masterHash, featureHash := setup(t)
+		cmd := &MergeCommand{}
+
+		_, err := cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CONFLICT (content): Merge conflict in file.txt")
+		assert.Contains(t, err.Error(), "Automatic merge failed")
+
+		merge := session.MergeInProgress()
+		require.NotNil(t, merge)
+		assert.Equal(t, featureHash.String(), merge.MergeHead)
+		assert.Equal(t, []string{"file.txt"}, merge.Conflicts)
+
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "<<<<<<< HEAD\nbase\nmaster\n=======\nbase\nfeature\n>>>>>>> feature\n", string(content), "the markers name the merged branch")
+
+		status, err := (&StatusCommand{}).Execute(context.Background(), session, []string{"status"})
+		require.NoError(t, err)
+		assert.Contains(t, status, "You have unmerged paths.")
+		assert.Contains(t, status, "both modified:  file.txt")
+
+		// Neither a new merge nor --continue is possible while the conflict is unresolved
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		assert.ErrorContains(t, err, "Merging is not possible because you have unmerged files.")
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "--continue"})
+		assert.ErrorContains(t, err, "Exiting because of an unresolved conflict.")
+
+		w, _ := r.Worktree()
+		require.NoError(t, util.WriteFile(session.Filesystem, "file.txt", []byte("base\nmaster\nfeature\n"), 0644))
+		_, _ = w.Add("file.txt")
+
+		out, err := cmd.Execute(context.Background(), session, []string{"merge", "--continue"})
+		require.NoError(t, err)
+		assert.Contains(t, out, "Merge branch 'feature'")
+		assert.Nil(t, session.MergeInProgress())
+
+		head, _ := r.Head()
+		headCommit, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, []plumbing.Hash{masterHash, featureHash}, headCommit.ParentHashes)
+	})
+
+	t.Run("commit concludes the merge", func(t *testing.T) {
+		session, r, _, featureHash := setup(t)
+
+		_, err := (&MergeCommand{}).Execute(context.Background(), session, []string{"merge", "feature"})
+		require.Error(t, err)
+
+		w, _ := r.Worktree()
+		require.NoError(t, util.WriteFile(session.Filesystem, "file.txt", []byte("resolved\n"), 0644))
+		_, _ = w.Add("file.txt")
+
+		_, err = (&CommitCommand{}).Execute(context.Background(), session, []string{"commit"})
+		require.NoError(t, err)
+		assert.Nil(t, session.MergeInProgress())
+
+		head, _ := r.Head()
+		headCommit, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Merge branch 'feature'", headCommit.Message)
+		assert.Equal(t, featureHash, headCommit.ParentHashes[1])
+	})
+
+	t.Run("abort restores HEAD", func(t *testing.T) {
+		session, r, masterHash, _ := setup(t)
+		cmd := &MergeCommand{}
+
+		_, err := cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		require.Error(t, err)
+
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "--abort"})
+		require.NoError(t, err)
+		assert.Nil(t, session.MergeInProgress())
+
+		head, _ := r.Head()
+		assert.Equal(t, masterHash, head.Hash())
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "base\nmaster\n", string(content))
+
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "--abort"})
+		assert.ErrorContains(t, err, "There is no merge to abort (MERGE_HEAD missing).")
+	})
+
+	t.Run("abort keeps local changes to other files", func(t *testing.T) {
+		session, _, _, _ := setup(t)
+		cmd := &MergeCommand{}
+		require.NoError(t, util.WriteFile(session.Filesystem, "other.txt", []byte("work in progress\n"), 0644))
+
+		_, err := cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		require.ErrorContains(t, err, "CONFLICT (content): Merge conflict in file.txt")
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "--abort"})
+		require.NoError(t, err)
+
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "base\nmaster\n", string(content))
+		content, _ = util.ReadFile(session.Filesystem, "other.txt")
+		assert.Equal(t, "work in progress\n", string(content), "the unrelated change survives the abort")
+	})
+
+	t.Run("refuses to overwrite local changes", func(t *testing.T) {
+		session, r, masterHash, _ := setup(t)
+		cmd := &MergeCommand{}
+		require.NoError(t, util.WriteFile(session.Filesystem, "file.txt", []byte("base\nmaster\nedited\n"), 0644))
+
+		_, err := cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		require.ErrorContains(t, err, "Your local changes to the following files would be overwritten by merge:\n\tfile.txt")
+		assert.Nil(t, session.MergeInProgress())
+		head, _ := r.Head()
+		assert.Equal(t, masterHash, head.Hash())
+		content, _ := util.ReadFile(session.Filesystem, "file.txt")
+		assert.Equal(t, "base\nmaster\nedited\n", string(content))
+
+		// Staged changes block the merge too, since an abort would drop them
+		require.NoError(t, util.WriteFile(session.Filesystem, "file.txt", []byte("base\nmaster\n"), 0644))
+		require.NoError(t, util.WriteFile(session.Filesystem, "other.txt", []byte("staged\n"), 0644))
+		w, _ := r.Worktree()
+		_, _ = w.Add("other.txt")
+		_, err = cmd.Execute(context.Background(), session, []string{"merge", "feature"})
+		assert.ErrorContains(t, err, "\tother.txt")
+	})
+}
+
+func TestMergeFastForwardKeepsLocalChanges(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-merge-ff-dirty")
+	ctx := context.Background()
+	run := func(input string) {
+		name, args := git.ParseCommand(input)
+		_, err := git.Dispatch(ctx, s, name, args)
+		require.NoError(t, err, input)
+	}
+
+	run("touch notes.txt")
+	run("git add notes.txt")
+	run("git commit -m notes")
+	head, err := s.GetRepo().Head()
+	require.NoError(t, err)
+	run("git switch -c feature")
+	run("touch feature.txt")
+	run("git add feature.txt")
+	run("git commit -m feature")
+	run("git switch " + head.Name().Short())
+	require.NoError(t, util.WriteFile(s.Filesystem, "/testrepo/notes.txt", []byte("draft\n"), 0644))
+
+	run("git merge feature")
+	_, err = s.Filesystem.Stat("/testrepo/feature.txt")
+	assert.NoError(t, err)
+	content, _ := util.ReadFile(s.Filesystem, "/testrepo/notes.txt")
+	assert.Equal(t, "draft\n", string(content))
+}
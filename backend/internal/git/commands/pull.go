@@ -9,6 +9,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
@@ -27,16 +28,60 @@ var _ git.Command = (*PullCommand)(nil)
 
 type PullOptions struct {
 	DryRun bool
-	Remote string
-	Branch string // Optional
+	Remote string // Defaults to the current branch's upstream remote, then origin
+	Branch string // Optional; defaults to the current branch's upstream branch
+	Rebase *bool  // --rebase / --no-rebase; nil falls back to pull.rebase
+	FF     string // "only" (--ff-only), "true" (--ff) or "false" (--no-ff); "" falls back to pull.ff
+
+	Autostash *bool // --autostash / --no-autostash; nil falls back to rebase.autostash or merge.autostash
 }
 
+// How a pull reconciles diverged histories
+const (
+	pullMerge  = "merge"
+	pullRebase = "rebase"
+	pullFFOnly = "ff-only"
+
+	// pullUnconfigured: neither pull.rebase, pull.ff nor a flag picked a mode
+	pullUnconfigured = "unconfigured"
+)
+
+// divergentBranchesHint is the guidance git prints when a pull meets diverged
+// branches and nothing says how to reconcile them.
+const divergentBranchesHint = `hint: You have divergent branches and need to specify how to reconcile them.
+hint: You can do so by running one of the following commands sometime before
+hint: your next pull:
+hint:
+hint:   git config pull.rebase false  # merge
+hint:   git config pull.rebase true   # rebase
+hint:   git config pull.ff only       # fast-forward only
+hint:
+hint: You can replace "git config" with "git config --global" to set a default
+hint: preference for all repositories. You can also pass --rebase, --no-rebase,
+hint: or --ff-only on the command line to override the configured default per
+hint: invocation.
+fatal: Need to specify how to reconcile divergent branches.`
+
+// divergingFFOnlyHint is what git prints when a fast-forward-only pull meets diverged branches.
+const divergingFFOnlyHint = `hint: Diverging branches can't be fast-forwarded, you need to either:
+hint:
+hint: 	git merge --no-ff
+hint:
+hint: or:
+hint:
+hint: 	git rebase
+hint:
+hint: Disable this message with "git config advice.diverging false"
+fatal: Not possible to fast-forward, aborting.`
+
 type pullContext struct {
 	FetchOutput  string
 	Repo         *gogit.Repository
 	HeadRef      *plumbing.Reference
 	MergeRef     *plumbing.Reference // The remote ref to merge
 	MergeRefName string
+	Branch       string // Name of the merged branch on the remote, e.g. "main"
+	RemoteURL    string // URL of the remote, or its name when it has none
 }
 
 func (c *PullCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -44,12 +89,17 @@ func (c *PullCommand) Execute(ctx context.Context, s *git.Session, args []string
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "pull"), nil
 		}
 		return "", err
 	}
 
-	// 2. Fetch (Delegate to FetchCommand)
+	// 2. Without arguments, pull from the current branch's upstream
+	if err := c.resolveUpstream(s, opts); err != nil {
+		return "", err
+	}
+
+	// 3. Fetch (Delegate to FetchCommand)
 	fetchOutput, err := c.executeFetch(ctx, s, opts)
 	if err != nil {
 		return "", fmt.Errorf("pull (fetch failed): %w", err)
@@ -59,20 +109,18 @@ func (c *PullCommand) Execute(ctx context.Context, s *git.Session, args []string
 		return fmt.Sprintf("%s\n[dry-run] Pull would continue with merge/rebase.", fetchOutput), nil
 	}
 
-	// 3. Resolve Context (Identify Merge Target)
+	// 4. Resolve Context (Identify Merge Target)
 	pCtx, err := c.resolveContext(s, opts, fetchOutput)
 	if err != nil {
 		return "", err
 	}
 
-	// 4. Perform Merge
-	return c.performPullMerge(s, pCtx)
+	// 5. Integrate the fetched branch
+	return c.performPullMerge(ctx, s, pCtx, opts)
 }
 
 func (c *PullCommand) parseArgs(args []string) (*PullOptions, error) {
-	opts := &PullOptions{
-		Remote: "origin",
-	}
+	opts := &PullOptions{}
 	var cleanArgs []string
 	cmdArgs := args[1:]
 
@@ -80,6 +128,24 @@ func (c *PullCommand) parseArgs(args []string) (*PullOptions, error) {
 		switch arg {
 		case "-n", "--dry-run":
 			opts.DryRun = true
+		case "-r", "--rebase":
+			rebase := true
+			opts.Rebase = &rebase
+		case "--no-rebase":
+			rebase := false
+			opts.Rebase = &rebase
+		case "--ff-only":
+			opts.FF = "only"
+		case "--ff":
+			opts.FF = "true"
+		case "--no-ff":
+			opts.FF = "false"
+		case "--autostash":
+			autostash := true
+			opts.Autostash = &autostash
+		case "--no-autostash":
+			autostash := false
+			opts.Autostash = &autostash
 		case "-h", "--help":
 			return nil, fmt.Errorf("help requested")
 		default:
@@ -100,13 +166,42 @@ func (c *PullCommand) parseArgs(args []string) (*PullOptions, error) {
 	return opts, nil
 }
 
+// resolveUpstream fills in the remote and branch a bare pull integrates: the
+// current branch's upstream when configured, otherwise the branch of the same
+// name on origin.
+func (c *PullCommand) resolveUpstream(s *git.Session, opts *PullOptions) error {
+	if opts.Remote != "" {
+		return nil
+	}
+	opts.Remote = "origin"
+
+	s.Lock()
+	defer s.Unlock()
+	repo := s.GetRepo()
+	if repo == nil {
+		return fmt.Errorf("fatal: not a git repository")
+	}
+	head, err := repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return nil
+	}
+	if remote, merge, ok := git.Upstream(repo, head.Name().Short()); ok {
+		opts.Remote, opts.Branch = remote, merge
+	}
+	return nil
+}
+
 func (c *PullCommand) executeFetch(ctx context.Context, s *git.Session, opts *PullOptions) (string, error) {
 	fetchArgs := []string{"fetch"}
 	if opts.DryRun {
 		fetchArgs = append(fetchArgs, "--dry-run")
 	}
-	// Always fetch the specified remote (or default origin)
+	// Always fetch the specified remote (or default origin); naming the branch
+	// points FETCH_HEAD at it
 	fetchArgs = append(fetchArgs, opts.Remote)
+	if opts.Branch != "" {
+		fetchArgs = append(fetchArgs, opts.Branch)
+	}
 
 	fetchCmd := &FetchCommand{}
 	return fetchCmd.Execute(ctx, s, fetchArgs)
@@ -142,28 +237,76 @@ func (c *PullCommand) resolveContext(s *git.Session, opts *PullOptions, fetchOut
 
 	// Verify merge ref exists
 	mergeRef, err := repo.Reference(plumbing.ReferenceName(mergeRefName), true)
+	if err != nil && opts.Branch == "" {
+		// Neither an upstream nor a namesake on the remote to fall back to
+		branch := headRef.Name().Short()
+		return nil, fmt.Errorf(`There is no tracking information for the current branch.
+Please specify which branch you want to merge with.
+
+    git pull <remote> <branch>
+
+If you wish to set tracking information for this branch you can do so with:
+
+    git branch --set-upstream-to=%s/<branch> %s`, opts.Remote, branch)
+	}
 	if err != nil {
 		// If dry run, we might not care, but we are past dry run here.
 		return nil, fmt.Errorf("ref %s not found (fetch might have failed to update it?)", mergeRefName)
 	}
 
+	remoteURL := opts.Remote
+	if remote, err := repo.Remote(opts.Remote); err == nil && len(remote.Config().URLs) > 0 {
+		remoteURL = remote.Config().URLs[0]
+	}
+
 	return &pullContext{
 		FetchOutput:  fetchOutput,
 		Repo:         repo,
 		HeadRef:      headRef,
 		MergeRef:     mergeRef,
 		MergeRefName: mergeRefName,
+		Branch:       strings.TrimPrefix(mergeRefName, "refs/remotes/"+opts.Remote+"/"),
+		RemoteURL:    remoteURL,
 	}, nil
 }
 
-func (c *PullCommand) performPullMerge(_ *git.Session, pCtx *pullContext) (string, error) {
-	// Need lock for repo operations?
-	// s.GetRepo() returns pointer. Operations on repo are usually thread-safe or s is locked?
-	// Legacy Execute locked s during resolve. Here we unlocked.
-	// Should lock again or locking is fine?
-	// Standard practice: if interacting with Session state, lock. Repo state might have its own locks.
-	// But simple read/write to repo is fine.
+// reconcileMode decides how diverged branches are integrated: command line
+// flags win over pull.rebase and pull.ff. When none of them is given the mode
+// is pullUnconfigured, which merges a fast-forward and refuses anything else.
+// The second result reports whether a merge commit is forced (--no-ff).
+func (c *PullCommand) reconcileMode(repo *gogit.Repository, opts *PullOptions) (string, bool) {
+	var rebaseCfg, ffCfg string
+	if cfg, err := repo.Config(); err == nil {
+		rebaseCfg = strings.ToLower(cfg.Raw.Section("pull").Option("rebase"))
+		ffCfg = strings.ToLower(cfg.Raw.Section("pull").Option("ff"))
+	}
+
+	rebase := rebaseCfg != "" && rebaseCfg != "false"
+	if opts.Rebase != nil {
+		rebase = *opts.Rebase
+	} else if opts.FF != "" {
+		// An explicit --ff/--no-ff/--ff-only overrides a configured rebase
+		rebase = false
+	}
+
+	ff := ffCfg
+	if opts.FF != "" {
+		ff = opts.FF
+	}
+
+	switch {
+	case rebase:
+		return pullRebase, false
+	case ff == "only":
+		return pullFFOnly, false
+	case rebaseCfg == "" && ff == "" && opts.Rebase == nil:
+		return pullUnconfigured, false
+	default:
+		return pullMerge, ff == "false"
+	}
+}
 
+func (c *PullCommand) performPullMerge(ctx context.Context, s *git.Session, pCtx *pullContext, opts *PullOptions) (string, error) {
 	repo := pCtx.Repo
 	headRef := pCtx.HeadRef
 	mergeRef := pCtx.MergeRef
@@ -171,114 +314,263 @@ func (c *PullCommand) performPullMerge(_ *git.Session, pCtx *pullContext) (strin
 	headHash := headRef.Hash()
 	targetHash := mergeRef.Hash()
 
+	// Nothing to integrate when the fetched commit is already part of HEAD
+	upToDate, err := git.IsFastForward(repo, targetHash, headHash)
+	if err != nil {
+		return "", err
+	}
+	if upToDate {
+		return fmt.Sprintf("%s\nAlready up to date.", pCtx.FetchOutput), nil
+	}
+
+	mode, noFF := c.reconcileMode(repo, opts)
+
 	// Check Fast-Forward
 	isFF, err := git.IsFastForward(repo, headHash, targetHash)
 	if err != nil {
 		return "", err
 	}
 
-	if isFF {
-		// FF Update
-		newRef := plumbing.NewHashReference(headRef.Name(), targetHash)
-		err = repo.Storer.SetReference(newRef)
+	ff := isFF && !noFF
+	switch {
+	case !ff && mode == pullFFOnly:
+		return "", fmt.Errorf("%s", divergingFFOnlyHint)
+	case !ff && mode == pullUnconfigured:
+		return "", fmt.Errorf("%s", divergentBranchesHint)
+	case !ff && mode == pullRebase:
+		// The rebase stashes local changes itself, and records ORIG_HEAD
+		args := []string{"rebase", plumbing.ReferenceName(pCtx.MergeRefName).Short()}
+		if opts.Autostash != nil && *opts.Autostash {
+			args = append(args, "--autostash")
+		} else if opts.Autostash != nil {
+			args = append(args, "--no-autostash")
+		}
+		rebaseCmd := &RebaseCommand{}
+		out, err := rebaseCmd.Execute(ctx, s, args)
 		if err != nil {
 			return "", err
 		}
+		return fmt.Sprintf("%s\n%s", pCtx.FetchOutput, out), nil
+	}
+
+	// Local changes are carried over the fast-forward or merge in a stash
+	autostash := c.autostash(repo, opts, mode)
+	stash, err := c.stashLocalChanges(repo, headHash, targetHash, autostash)
+	if err != nil {
+		return "", err
+	}
+	out, stopped, err := c.integrate(s, pCtx, ff)
+	switch {
+	case stash.IsZero():
+	case !autostash:
+		// The changes touch no file the pull changed, so they apply again as they were
+		_ = applyAutostash(repo, stash)
+	case err != nil:
+		err = fmt.Errorf("%s", afterAutostash(repo, stash, err.Error()))
+	case stopped:
+		// The changes stay stashed until the conflicts are resolved
+		out = autostashLine(stash) + "\n" + out + "\nYour local changes are safe in the stash.\nYou can run \"git stash pop\" or \"git stash drop\" at any time."
+	default:
+		out = autostashLine(stash) + "\n" + afterAutostash(repo, stash, out)
+	}
+	if err != nil {
+		return "", err
+	}
+	return pCtx.FetchOutput + "\n" + out, nil
+}
+
+// autostash reports whether local changes are stashed for the pull:
+// --autostash and --no-autostash win over rebase.autostash for a pull that
+// rebases and merge.autostash for one that merges.
+func (c *PullCommand) autostash(repo *gogit.Repository, opts *PullOptions, mode string) bool {
+	if opts.Autostash != nil {
+		return *opts.Autostash
+	}
+	if mode == pullRebase {
+		return autostashConfigured(repo, "rebase")
+	}
+	return autostashConfigured(repo, "merge")
+}
+
+// stashLocalChanges stashes the local changes before the worktree moves from
+// head to target. Without autostash, changes to files the pull does not touch
+// are still carried over, as git keeps them; changes it would overwrite
+// make the pull refuse.
+func (c *PullCommand) stashLocalChanges(repo *gogit.Repository, head, target plumbing.Hash, autostash bool) (plumbing.Hash, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if !hasTrackedChanges(status) {
+		return plumbing.ZeroHash, nil
+	}
+	if !autostash {
+		overwritten, err := overwrittenPaths(repo, status, head, target)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if len(overwritten) > 0 {
+			return plumbing.ZeroHash, localChangesError(overwritten)
+		}
+	}
+	return createAutostash(repo)
+}
+
+// overwrittenPaths lists the locally changed files that differ between head and target.
+func overwrittenPaths(repo *gogit.Repository, status gogit.Status, head, target plumbing.Hash) ([]string, error) {
+	headCommit, err := repo.CommitObject(head)
+	if err != nil {
+		return nil, err
+	}
+	targetCommit, err := repo.CommitObject(target)
+	if err != nil {
+		return nil, err
+	}
+	headFiles, err := commitFiles(headCommit)
+	if err != nil {
+		return nil, err
+	}
+	targetFiles, err := commitFiles(targetCommit)
+	if err != nil {
+		return nil, err
+	}
+	changed := changedPaths(headFiles, targetFiles)
+
+	var paths []string
+	for path, fs := range status {
+		if fs.Staging == gogit.Untracked || !changed[path] {
+			continue
+		}
+		if fs.Staging != gogit.Unmodified || fs.Worktree != gogit.Unmodified {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// integrate fast-forwards HEAD to the fetched commit, or merges it, and
+// returns what it did. stopped reports a merge that stopped on conflicts.
+func (c *PullCommand) integrate(s *git.Session, pCtx *pullContext, ff bool) (string, bool, error) {
+	repo := pCtx.Repo
+	headRef := pCtx.HeadRef
+	headHash := headRef.Hash()
+	targetHash := pCtx.MergeRef.Hash()
+
+	if ff {
+		// FF Update: ORIG_HEAD and the branch move together
+		err := git.NewRefTransaction(repo).
+			Set(plumbing.NewHashReference(git.OrigHead, headHash)).
+			Update(plumbing.NewHashReference(headRef.Name(), targetHash), headHash).
+			Commit()
+		if err != nil {
+			return "", false, err
+		}
 
 		w, wErr := repo.Worktree()
 		if wErr != nil {
-			return "", wErr
+			return "", false, wErr
 		}
 		err = w.Reset(&gogit.ResetOptions{
 			Commit: targetHash,
 			Mode:   gogit.HardReset,
 		})
 		if err != nil {
-			return "", fmt.Errorf("failed to update worktree: %w", err)
+			return "", false, fmt.Errorf("failed to update worktree: %w", err)
 		}
 
-		return fmt.Sprintf("%s\nUpdating %s..%s\nFast-forward", pCtx.FetchOutput, headHash.String()[:7], targetHash.String()[:7]), nil
+		return fmt.Sprintf("Updating %s..%s\nFast-forward", headHash.String()[:7], targetHash.String()[:7]), false, nil
 	}
 
+	s.Lock()
+	s.UpdateOrigHead()
+	s.Unlock()
+
 	// 3-Way Merge
 	headCommit, err := repo.CommitObject(headHash)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	targetCommit, err := repo.CommitObject(targetHash)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	mergeBases, err := headCommit.MergeBase(targetCommit)
 	if err != nil {
-		return "", fmt.Errorf("failed to calculate merge base: %w", err)
+		return "", false, fmt.Errorf("failed to calculate merge base: %w", err)
 	}
 	if len(mergeBases) == 0 {
-		return "", fmt.Errorf("refusing to merge unrelated histories")
+		return "", false, fmt.Errorf("refusing to merge unrelated histories")
 	}
 	baseCommit := mergeBases[0]
 
 	w, err := repo.Worktree()
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	err = git.Merge3Way(w, baseCommit, headCommit, targetCommit)
+	message := fmt.Sprintf("Merge branch '%s' of %s", pCtx.Branch, pCtx.RemoteURL)
+	err = git.Merge3Way(w, baseCommit, headCommit, targetCommit, "")
 	if err != nil {
 		if err == git.ErrConflict {
-			return fmt.Sprintf("%s\nCONFLICT (content): Merge conflict detected.\nAutomatic merge failed; fix conflicts and then commit the result.", pCtx.FetchOutput), nil
+			conflicts, _ := conflictedPaths(w)
+			// Concluded by git commit or git merge --continue, like a merge
+			s.Lock()
+			s.StartMerge(&git.MergeState{
+				MergeHead: targetHash.String(),
+				OrigHead:  headHash.String(),
+				Message:   message,
+				Conflicts: conflicts,
+			})
+			rerere := git.Rerere(s, repo, w, conflicts)
+			s.Unlock()
+			return conflictReport(conflicts, rerere), true, nil
 		}
-		return "", fmt.Errorf("merge failed: %w", err)
+		return "", false, fmt.Errorf("merge failed: %w", err)
 	}
 
 	// Stage changes (simplified)
 	_, err = w.Add(".")
 	if err != nil {
-		return "", fmt.Errorf("failed to stage changes: %w", err)
+		return "", false, fmt.Errorf("failed to stage changes: %w", err)
 	}
 
-	message := fmt.Sprintf("Merge branch '%s' into %s", pCtx.MergeRefName, headRef.Name().Short())
-
 	mergeCommit, err := w.Commit(message, &gogit.CommitOptions{
 		Parents:   []plumbing.Hash{headHash, targetHash},
-		Author:    git.GetDefaultSignature(),
-		Committer: git.GetDefaultSignature(),
+		Author:    s.Signature(),
+		Committer: s.Signature(),
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create merge commit: %w", err)
+		return "", false, fmt.Errorf("failed to create merge commit: %w", err)
 	}
 
-	return fmt.Sprintf("%s\nMerge made by the 'ort' strategy.\n%s", pCtx.FetchOutput, mergeCommit.String()[:7]), nil
+	return fmt.Sprintf("Merge made by the 'ort' strategy.\n%s", mergeCommit.String()[:7]), false, nil
 }
 
-func (c *PullCommand) Help() string {
-	return `📘 GIT-PULL (1)                                         Git Manual
-
- 💡 DESCRIPTION
-    ・リモートリポジトリから最新の変更をダウンロードする（fetch）
-    ・ダウンロードした変更を現在のブランチに取り込む（merge）
-    （fetch と merge を一度に行うコマンドです）
-
- 📋 SYNOPSIS
-    git pull [<remote>] [<branch>] [--rebase]
-
- ⚙️  COMMON OPTIONS
-    --rebase
-        (現在未実装) マージコミットを作らずに、履歴を一直線にして取り込みます。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: リモートの更新を取り込む
-       $ git pull
-
-    2. 実践: リベースで取り込む (Recommended)
-       「マージコミットだらけで履歴が汚い...」
-       そんな時はリベースを使います。履歴をきれいに一直線に保てます。
-       $ git pull --rebase
+// Spec implements git.SpecProvider.
+func (c *PullCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-r", "--rebase"}, Usage: "Rebase instead of merging"},
+			{Flags: []string{"--no-rebase"}, Usage: "Merge instead of rebasing"},
+			{Flags: []string{"--ff"}, Usage: "Fast-forward when possible"},
+			{Flags: []string{"--ff-only"}, Usage: "Refuse to merge unless fast-forward"},
+			{Flags: []string{"--no-ff"}, Usage: "Always create a merge commit"},
+			{Flags: []string{"--autostash"}, Usage: "Stash local changes and reapply them afterwards"},
+			{Flags: []string{"--no-autostash"}, Usage: "Do not stash local changes"},
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would happen"},
+		},
+		Args: []string{git.ArgRemote, git.ArgBranch},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-pull
-`
+func (c *PullCommand) Help() string {
+	return git.CommandHelp(context.Background(), "pull")
 }
 
 // isFastForward moved to utils.go
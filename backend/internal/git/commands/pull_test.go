@@ -88,7 +88,7 @@ func TestPull_Merge(t *testing.T) {
 
 	// 4. Pull
 	cmd := &PullCommand{}
-	output, err := cmd.Execute(context.Background(), session, []string{"pull", "origin"}) // default merges origin/master
+	output, err := cmd.Execute(context.Background(), session, []string{"pull", "--no-rebase", "origin"}) // merges origin/master
 	if err != nil {
 		t.Fatalf("pull failed: %v", err)
 	}
@@ -113,6 +113,9 @@ func TestPull_Merge(t *testing.T) {
 	if len(headCommit.ParentHashes) != 2 {
 		t.Errorf("Expected 2 parents for merge commit, got %d", len(headCommit.ParentHashes))
 	}
+	if want := "Merge branch 'master' of " + remoteURL; headCommit.Message != want {
+		t.Errorf("Expected merge message %q, got %q", want, headCommit.Message)
+	}
 }
 
 func TestPull_Conflict(t *testing.T) {
@@ -149,7 +152,7 @@ func TestPull_Conflict(t *testing.T) {
 
 	// 4. Pull
 	cmd := &PullCommand{}
-	output, err := cmd.Execute(context.Background(), session, []string{"pull"})
+	output, err := cmd.Execute(context.Background(), session, []string{"pull", "--no-rebase"})
 	if err != nil {
 		t.Fatalf("pull execution returned error (should handle conflict gracefully?): %v", err)
 	}
@@ -171,3 +174,272 @@ func TestPull_Conflict(t *testing.T) {
 		t.Errorf("Conflict markers missing in file.txt: %s", fileStr)
 	}
 }
+
+// setupDivergedPull clones a remote and gives both sides one new commit.
+func setupDivergedPull(t *testing.T, id string) (*git.Session, *gogit.Repository) {
+	remoteRepo, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	commitFile(t, remoteRepo, "base.txt", "base content", "Initial commit")
+
+	sm := git.NewSessionManager()
+	sm.DataDir = t.TempDir()
+	remoteURL := "https://example.com/" + id + ".git"
+	sm.SharedRemotes[remoteURL] = remoteRepo
+
+	session, _ := sm.CreateSession(id)
+	if _, err := (&CloneCommand{}).Execute(context.Background(), session, []string{"clone", remoteURL}); err != nil {
+		t.Fatalf("setup: clone failed: %v", err)
+	}
+	localRepo := session.GetRepo()
+
+	commitFile(t, remoteRepo, "remote_file.txt", "remote content", "Remote commit")
+	commitFile(t, localRepo, "local_file.txt", "local content", "Local commit")
+	return session, localRepo
+}
+
+func TestPull_DivergedReconcileModes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unconfigured pull refuses with guidance", func(t *testing.T) {
+		session, localRepo := setupDivergedPull(t, "pull-unconfigured")
+		before, _ := localRepo.Head()
+
+		_, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"})
+		if err == nil {
+			t.Fatal("expected pull to fail on diverged branches")
+		}
+		for _, want := range []string{
+			"hint: You have divergent branches and need to specify how to reconcile them.",
+			"hint:   git config pull.rebase false  # merge",
+			"hint:   git config pull.ff only       # fast-forward only",
+			"fatal: Need to specify how to reconcile divergent branches.",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in error, got: %s", want, err.Error())
+			}
+		}
+
+		after, _ := localRepo.Head()
+		if after.Hash() != before.Hash() {
+			t.Error("branch must not move when the pull is refused")
+		}
+	})
+
+	t.Run("pull.rebase false merges", func(t *testing.T) {
+		session, localRepo := setupDivergedPull(t, "pull-rebase-false")
+
+		if _, err := (&ConfigCommand{}).Execute(ctx, session, []string{"config", "pull.rebase", "false"}); err != nil {
+			t.Fatalf("config failed: %v", err)
+		}
+		if _, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"}); err != nil {
+			t.Fatalf("pull failed: %v", err)
+		}
+
+		head, _ := localRepo.Head()
+		headCommit, _ := localRepo.CommitObject(head.Hash())
+		if headCommit.NumParents() != 2 {
+			t.Errorf("expected a merge commit, got %q with %d parents", headCommit.Message, headCommit.NumParents())
+		}
+	})
+
+	t.Run("pull.ff only refuses with guidance", func(t *testing.T) {
+		session, localRepo := setupDivergedPull(t, "pull-ff-only")
+		before, _ := localRepo.Head()
+
+		if _, err := (&ConfigCommand{}).Execute(ctx, session, []string{"config", "pull.ff", "only"}); err != nil {
+			t.Fatalf("config failed: %v", err)
+		}
+		_, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"})
+		if err == nil {
+			t.Fatal("expected pull to fail on diverged branches")
+		}
+		for _, want := range []string{
+			"hint: Diverging branches can't be fast-forwarded, you need to either:",
+			"hint: \tgit merge --no-ff",
+			"hint: \tgit rebase",
+			"fatal: Not possible to fast-forward, aborting.",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in error, got: %s", want, err.Error())
+			}
+		}
+
+		after, _ := localRepo.Head()
+		if after.Hash() != before.Hash() {
+			t.Error("branch must not move when the pull is refused")
+		}
+
+		// An explicit --no-ff on the command line overrides pull.ff=only
+		if _, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--no-ff"}); err != nil {
+			t.Fatalf("pull --no-ff failed: %v", err)
+		}
+	})
+
+	t.Run("pull.rebase true keeps history linear", func(t *testing.T) {
+		session, localRepo := setupDivergedPull(t, "pull-rebase")
+
+		if _, err := (&ConfigCommand{}).Execute(ctx, session, []string{"config", "pull.rebase", "true"}); err != nil {
+			t.Fatalf("config failed: %v", err)
+		}
+		output, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"})
+		if err != nil {
+			t.Fatalf("pull failed: %v", err)
+		}
+		if !strings.Contains(output, "Successfully rebased") {
+			t.Errorf("expected rebase output, got: %s", output)
+		}
+
+		head, _ := localRepo.Head()
+		headCommit, _ := localRepo.CommitObject(head.Hash())
+		if headCommit.NumParents() != 1 || strings.TrimSpace(headCommit.Message) != "Local commit" {
+			t.Fatalf("expected the local commit replayed on top, got %q with %d parents", headCommit.Message, headCommit.NumParents())
+		}
+		parent, _ := headCommit.Parent(0)
+		if strings.TrimSpace(parent.Message) != "Remote commit" {
+			t.Errorf("expected remote commit below the local one, got %q", parent.Message)
+		}
+	})
+
+	t.Run("up to date after pulling", func(t *testing.T) {
+		session, _ := setupDivergedPull(t, "pull-up-to-date")
+
+		if _, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--no-rebase"}); err != nil {
+			t.Fatalf("pull failed: %v", err)
+		}
+		output, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--ff-only"})
+		if err != nil {
+			t.Fatalf("second pull failed: %v", err)
+		}
+		if !strings.Contains(output, "Already up to date.") {
+			t.Errorf("expected up-to-date message, got: %s", output)
+		}
+	})
+}
+
+// setupFastForwardPull clones a remote and adds a remote commit changing file
+// on top, leaving the clone one fast-forward behind.
+func setupFastForwardPull(t *testing.T, id, file string) (*git.Session, *gogit.Repository) {
+	remoteRepo, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	commitFile(t, remoteRepo, "base.txt", "base content", "Initial commit")
+
+	sm := git.NewSessionManager()
+	sm.DataDir = t.TempDir()
+	remoteURL := "https://example.com/" + id + ".git"
+	sm.SharedRemotes[remoteURL] = remoteRepo
+
+	session, _ := sm.CreateSession(id)
+	if _, err := (&CloneCommand{}).Execute(context.Background(), session, []string{"clone", remoteURL}); err != nil {
+		t.Fatalf("setup: clone failed: %v", err)
+	}
+	commitFile(t, remoteRepo, file, "remote content", "Remote commit")
+	return session, session.GetRepo()
+}
+
+// editFile overwrites a worktree file without staging it.
+func editFile(t *testing.T, r *gogit.Repository, filename, content string) {
+	w, _ := r.Worktree()
+	f, err := w.Filesystem.Create(filename)
+	if err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	f.Write([]byte(content))
+	f.Close()
+}
+
+func readWorktree(t *testing.T, r *gogit.Repository, filename string) string {
+	w, _ := r.Worktree()
+	f, err := w.Filesystem.Open(filename)
+	if err != nil {
+		t.Fatalf("open %s failed: %v", filename, err)
+	}
+	defer f.Close()
+	buf := make([]byte, 256)
+	n, _ := f.Read(buf)
+	return string(buf[:n])
+}
+
+func TestPull_Autostash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("untouched local changes are kept", func(t *testing.T) {
+		session, localRepo := setupFastForwardPull(t, "pull-keep-changes", "remote_file.txt")
+		editFile(t, localRepo, "base.txt", "local edit")
+
+		output, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"})
+		if err != nil {
+			t.Fatalf("pull failed: %v", err)
+		}
+		if !strings.Contains(output, "Fast-forward") || strings.Contains(output, "autostash") {
+			t.Errorf("expected a quiet fast-forward, got: %s", output)
+		}
+		if got := readWorktree(t, localRepo, "base.txt"); got != "local edit" {
+			t.Errorf("local change lost: base.txt is %q", got)
+		}
+		if got := readWorktree(t, localRepo, "remote_file.txt"); got != "remote content" {
+			t.Errorf("remote change missing: remote_file.txt is %q", got)
+		}
+		if entries, _ := stashEntries(localRepo); len(entries) != 0 {
+			t.Errorf("expected no stash entry left, got %d", len(entries))
+		}
+	})
+
+	t.Run("overwritten changes refuse unless autostashed", func(t *testing.T) {
+		session, localRepo := setupFastForwardPull(t, "pull-autostash", "base.txt")
+		before, _ := localRepo.Head()
+		editFile(t, localRepo, "base.txt", "local edit")
+
+		_, err := (&PullCommand{}).Execute(ctx, session, []string{"pull"})
+		if err == nil || !strings.Contains(err.Error(), "would be overwritten by merge:\n\tbase.txt") {
+			t.Fatalf("expected the pull to refuse, got: %v", err)
+		}
+		after, _ := localRepo.Head()
+		if after.Hash() != before.Hash() || readWorktree(t, localRepo, "base.txt") != "local edit" {
+			t.Fatal("a refused pull must change nothing")
+		}
+
+		// Both sides changed base.txt, so the stash no longer applies cleanly
+		output, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--autostash"})
+		if err != nil {
+			t.Fatalf("pull --autostash failed: %v", err)
+		}
+		for _, want := range []string{"Created autostash: ", "Fast-forward", "Applying autostash resulted in conflicts."} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output, got: %s", want, output)
+			}
+		}
+		if !strings.Contains(readWorktree(t, localRepo, "base.txt"), "<<<<<<< HEAD") {
+			t.Error("expected conflict markers in base.txt")
+		}
+		if entries, _ := stashEntries(localRepo); len(entries) != 1 {
+			t.Errorf("the conflicting autostash must stay in the stash list, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("rebase.autostash for a rebasing pull", func(t *testing.T) {
+		session, localRepo := setupDivergedPull(t, "pull-rebase-autostash")
+		editFile(t, localRepo, "local_file.txt", "local edit")
+
+		_, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--rebase"})
+		if err == nil || !strings.Contains(err.Error(), "cannot rebase: You have unstaged changes.") {
+			t.Fatalf("expected the rebase to refuse, got: %v", err)
+		}
+
+		if _, err := (&ConfigCommand{}).Execute(ctx, session, []string{"config", "rebase.autostash", "true"}); err != nil {
+			t.Fatalf("config failed: %v", err)
+		}
+		output, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--rebase"})
+		if err != nil {
+			t.Fatalf("pull --rebase failed: %v", err)
+		}
+		for _, want := range []string{"Created autostash: ", "Successfully rebased", "Applied autostash."} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output, got: %s", want, output)
+			}
+		}
+		if got := readWorktree(t, localRepo, "local_file.txt"); got != "local edit" {
+			t.Errorf("local change lost: local_file.txt is %q", got)
+		}
+		if entries, _ := stashEntries(localRepo); len(entries) != 0 {
+			t.Errorf("expected the autostash dropped, got %d entries", len(entries))
+		}
+	})
+}
@@ -11,12 +11,13 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -30,17 +31,24 @@ type PushCommand struct{}
 var _ git.Command = (*PushCommand)(nil)
 
 type PushOptions struct {
-	Remote  string
-	Refspec string
-	Force   bool
-	DryRun  bool
+	Remote      string // Defaults to the current branch's upstream remote, then origin
+	Refspec     string // [+]<src>[:<dst>]
+	Force       bool
+	Lease       *pushLease // --force-with-lease; nil when not given
+	DryRun      bool
+	Mirror      bool
+	All         bool // --all: push every local branch
+	Tags        bool // --tags: push every local tag
+	SetUpstream bool // -u: make the pushed branch track its remote namesake
 }
 
 type pushContext struct {
 	TargetRepo *gogit.Repository
 	RemoteName string
 	RemoteURL  string
-	Ref        *plumbing.Reference // The local ref to push (HEAD or specific branch/tag)
+	Ref        *plumbing.Reference    // The local ref to push (HEAD or specific branch/tag)
+	Dst        plumbing.ReferenceName // The remote ref to update
+	Force      bool                   // --force or a "+" refspec
 }
 
 func (c *PushCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -56,52 +64,110 @@ func (c *PushCommand) Execute(ctx context.Context, s *git.Session, args []string
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "push"), nil
 		}
 		return "", err
 	}
 
-	// if opts.DryRun {
-	// 	// Quick check before resolution? Or resolved dry run?
-	// 	// Logic below does full resolution then prints matches.
-	// }
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+		if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+			branch := head.Name().Short()
+			remote, _, ok := git.Upstream(repo, branch)
+			if !ok && !opts.Mirror && !opts.All && !opts.Tags {
+				return "", fmt.Errorf("fatal: The current branch %s has no upstream branch.\nTo push the current branch and set the remote as upstream, use\n\n    git push --set-upstream origin %s", branch, branch)
+			}
+			if ok {
+				opts.Remote = remote
+			}
+		}
+	}
+
+	// 2-3. Resolve and push: every branch/tag (--mirror), every branch (--all),
+	// every tag (--tags), a deletion (":<ref>") or a single ref
+	var out string
+	switch {
+	case opts.Mirror:
+		out, err = c.performMirror(ctx, s, repo, opts)
+	case opts.All:
+		out, err = c.pushAll(ctx, s, repo, opts)
+	case opts.Tags:
+		out, err = c.pushTags(ctx, s, repo, opts)
+	case strings.HasPrefix(opts.Refspec, ":"):
+		out, err = c.deleteRemoteRef(s, repo, opts)
+	default:
+		out, err = c.pushSingleRef(ctx, s, repo, opts)
+	}
+	if err != nil || opts.DryRun {
+		return out, err
+	}
 
-	// 2. Resolve Context (Remote, TargetRepo, RefToPush)
+	// 4. LFS content travels separately from the pointer blobs
+	if n := s.UploadLFSObjects(); n > 0 {
+		out = fmt.Sprintf("Uploading LFS objects: 100%% (%d/%d), done.\n", n, n) + out
+	}
+	return out, nil
+}
+
+func (c *PushCommand) pushSingleRef(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *PushOptions) (string, error) {
+	// Resolve Context (Remote, TargetRepo, RefToPush)
 	pCtx, err := c.resolveContext(s, repo, opts)
 	if err != nil {
 		return "", err
 	}
+	defer lockRemote(s, pCtx.TargetRepo)()
+
+	if err := c.checkRemotePolicy(s, pCtx); err != nil {
+		return "", err
+	}
+
+	out, err := c.performPush(ctx, s, repo, pCtx, opts)
+	if err != nil || opts.DryRun || !pCtx.Dst.IsBranch() {
+		return out, err
+	}
 
-	// 3. Execution (Perform Push)
-	return c.performPush(repo, pCtx, opts)
+	if opts.SetUpstream && pCtx.Ref.Name().IsBranch() {
+		branch, merge := pCtx.Ref.Name().Short(), pCtx.Dst.Short()
+		if err := git.SetUpstream(repo, branch, pCtx.RemoteName, merge); err != nil {
+			return out, err
+		}
+		out += fmt.Sprintf("\nbranch '%s' set up to track '%s/%s'.", branch, pCtx.RemoteName, merge)
+	}
+	if s.Manager == nil {
+		return out, nil
+	}
+
+	// The remote's CI picks up the pushed branch
+	if checks := s.Manager.BranchPushed(pCtx.TargetRepo, pCtx.Dst.Short(), pCtx.Ref.Hash()); len(checks) > 0 {
+		out += fmt.Sprintf("\nremote: Running %d check(s) on %s. See /api/checks for the results.", len(checks), pCtx.Dst.Short())
+	}
+	return out, nil
 }
 
 func (c *PushCommand) parseArgs(args []string) (*PushOptions, error) {
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
 	opts := &PushOptions{
-		Remote: "origin", // Default
-	}
-	var positional []string
-
-	cmdArgs := args[1:]
-	for _, arg := range cmdArgs {
-		switch arg {
-		case "-f", "--force":
-			opts.Force = true
-		case "-n", "--dry-run":
-			opts.DryRun = true
-		case "-h", "--help":
-			return nil, fmt.Errorf("help requested")
-		default:
-			if strings.HasPrefix(arg, "-") {
-				// Ignore unknown flags or error? Legacy ignored.
-				// Let's stick to simple "ignore" or strict?
-				// Legacy: switch default: if strings.HasPrefix(arg, "-") { ignore } else { positional... }
-			} else {
-				positional = append(positional, arg)
-			}
-		}
+		Force:       parsed.Has("--force"),
+		DryRun:      parsed.Has("--dry-run"),
+		Mirror:      parsed.Has("--mirror"),
+		All:         parsed.Has("--all"),
+		Tags:        parsed.Has("--tags"),
+		SetUpstream: parsed.Has("--set-upstream"),
+	}
+	if parsed.Has("--force-with-lease") {
+		opts.Lease = parsePushLease(parsed.Value("--force-with-lease"))
+	}
+	switch {
+	case opts.All && opts.Mirror:
+		return nil, fmt.Errorf("fatal: --all and --mirror are incompatible")
+	case opts.All && opts.Tags:
+		return nil, fmt.Errorf("fatal: --all and --tags are incompatible")
 	}
 
+	positional := append(parsed.Positional, parsed.Rest...)
 	if len(positional) > 0 {
 		opts.Remote = positional[0]
 	}
@@ -113,15 +179,41 @@ func (c *PushCommand) parseArgs(args []string) (*PushOptions, error) {
 }
 
 func (c *PushCommand) resolveContext(s *git.Session, repo *gogit.Repository, opts *PushOptions) (*pushContext, error) {
-	// Resolve Remote URL
-	rem, err := repo.Remote(opts.Remote)
+	targetRepo, url, err := c.resolveRemoteRepo(s, repo, opts.Remote)
 	if err != nil {
-		return nil, fmt.Errorf("fatal: '%s' does not appear to be a git repository", opts.Remote)
+		return nil, err
+	}
+
+	spec := parsePushRefspec(opts.Refspec)
+	refToPush, err := resolvePushSource(repo, spec.Src)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := pushDestination(targetRepo, refToPush, spec.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("%w\nerror: failed to push some refs to '%s'", err, url)
+	}
+
+	return &pushContext{
+		TargetRepo: targetRepo,
+		RemoteName: opts.Remote,
+		RemoteURL:  url,
+		Ref:        refToPush,
+		Dst:        dst,
+		Force:      opts.Force || spec.Force,
+	}, nil
+}
+
+// resolveRemoteRepo finds the simulated repository behind the named remote's URL.
+func (c *PushCommand) resolveRemoteRepo(s *git.Session, repo *gogit.Repository, remoteName string) (*gogit.Repository, string, error) {
+	rem, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, "", fmt.Errorf("fatal: '%s' does not appear to be a git repository", remoteName)
 	}
 
 	cfg := rem.Config()
 	if len(cfg.URLs) == 0 {
-		return nil, fmt.Errorf("remote %s has no URL defined", opts.Remote)
+		return nil, "", fmt.Errorf("remote %s has no URL defined", remoteName)
 	}
 	url := cfg.URLs[0]
 
@@ -135,11 +227,11 @@ func (c *PushCommand) resolveContext(s *git.Session, repo *gogit.Repository, opt
 	targetRepo, ok = s.Repos[lookupKey]
 	if !ok && s.Manager != nil {
 		// Check Shared Remotes
-		targetRepo, ok = s.Manager.SharedRemotes[lookupKey] // e.g. "repo.git"
+		targetRepo, ok = s.Manager.GetSharedRemote(lookupKey) // e.g. "repo.git"
 
 		// Fallback: Check using full URL
 		if !ok {
-			targetRepo, ok = s.Manager.SharedRemotes[url]
+			targetRepo, ok = s.Manager.GetSharedRemote(url)
 		}
 	}
 
@@ -157,169 +249,203 @@ func (c *PushCommand) resolveContext(s *git.Session, repo *gogit.Repository, opt
 	}
 
 	if !ok {
-		return nil, fmt.Errorf("remote repository '%s' not found (only local simulation supported)", url)
+		return nil, "", fmt.Errorf("remote repository '%s' not found (only local simulation supported)", url)
 	}
+	if err := checkPushPermission(s, targetRepo, url); err != nil {
+		return nil, "", err
+	}
+	return targetRepo, url, nil
+}
 
-	// Determined Ref to Push
-	var refToPush *plumbing.Reference
+// checkPushPermission refuses a push to a read-only shared remote, or to an
+// owner-only one from another session, the way GitHub answers over HTTPS.
+func checkPushPermission(s *git.Session, targetRepo *gogit.Repository, url string) error {
+	if s.Manager == nil {
+		return nil
+	}
+	perm := s.Manager.RemotePermissionForRepo(targetRepo)
+	if perm.CanPush(s.ID) {
+		return nil
+	}
+	// owner/repo of https://github.com/owner/repo.git
+	repoName := url
+	if i := strings.Index(repoName, "://"); i >= 0 {
+		repoName = repoName[i+3:]
+		repoName = repoName[strings.Index(repoName, "/")+1:]
+	}
+	repoName = strings.TrimSuffix(strings.Trim(repoName, "/"), ".git")
+	reason := "the repository is read-only"
+	if perm.Access == git.RemoteAccessOwner {
+		reason = "only its owner can push to it"
+	}
+	return fmt.Errorf("remote: Permission to %s.git denied to %s.\nfatal: unable to access '%s/': The requested URL returned error: 403\nhint: You can't push here: %s.\nhint: Fork it (or create your own remote) and push there instead.",
+		repoName, s.Identity().Name, strings.TrimSuffix(url, "/"), reason)
+}
 
-	if opts.Refspec != "" {
-		// Try to resolve refspec (Branch or Tag)
-		ref, refErr := repo.Reference(plumbing.ReferenceName(opts.Refspec), true)
-		if refErr == nil {
-			refToPush = ref
-		} else {
-			// Try refs/heads/
-			ref, err = repo.Reference(plumbing.ReferenceName("refs/heads/"+opts.Refspec), true)
-			if err == nil {
-				refToPush = ref
-			} else {
-				// Try refs/tags/
-				ref, err = repo.Reference(plumbing.ReferenceName("refs/tags/"+opts.Refspec), true)
-				if err == nil {
-					refToPush = ref
-				} else {
-					return nil, fmt.Errorf("src refspec '%s' does not match any", opts.Refspec)
-				}
-			}
-		}
-	} else {
-		// Default: Push HEAD
-		headRef, headErr := repo.Head()
-		if headErr != nil {
-			return nil, fmt.Errorf("failed to get HEAD: %w", headErr)
-		}
-		if !headRef.Name().IsBranch() {
-			return nil, fmt.Errorf("HEAD is not on a branch (detached?)")
-		}
-		refToPush = headRef
+// lockRemote write-locks target, when it is a shared remote, until the
+// returned function is called, so that pushes from other sessions cannot
+// interleave their object copies and ref updates with this one's.
+func lockRemote(s *git.Session, target *gogit.Repository) func() {
+	if s.Manager == nil {
+		return func() {}
 	}
+	return s.Manager.LockRemote(target)
+}
 
-	return &pushContext{
-		TargetRepo: targetRepo,
-		RemoteName: opts.Remote,
-		RemoteURL:  url,
-		Ref:        refToPush,
-	}, nil
+// checkRemotePolicy enforces the shared remote's branch naming policy when a push
+// would create a new branch there. Existing branches (e.g. main) are always accepted.
+func (c *PushCommand) checkRemotePolicy(s *git.Session, pCtx *pushContext) error {
+	refName := pCtx.Dst
+	if s.Manager == nil || !refName.IsBranch() {
+		return nil
+	}
+	if _, err := pCtx.TargetRepo.Reference(refName, true); err == nil {
+		return nil
+	}
+	policy := s.Manager.BranchPolicyForRepo(pCtx.TargetRepo)
+	if err := policy.Validate(refName.Short()); err != nil {
+		return fmt.Errorf("! [remote rejected] %s -> %s (branch naming policy)\n%w", refName.Short(), refName.Short(), err)
+	}
+	return nil
 }
 
-func (c *PushCommand) performPush(repo *gogit.Repository, pCtx *pushContext, opts *PushOptions) (string, error) {
-	refName := pCtx.Ref.Name()
+func (c *PushCommand) performPush(ctx context.Context, s *git.Session, repo *gogit.Repository, pCtx *pushContext, opts *PushOptions) (string, error) {
+	refName := pCtx.Dst
 	targetRepo := pCtx.TargetRepo
+	hashToSync := pCtx.Ref.Hash()
+
+	// The remote ref as this push found it; the update expects it unchanged
+	var oldTip plumbing.Hash
+	if current, err := targetRepo.Reference(refName, true); err == nil {
+		oldTip = current.Hash()
+	}
+
+	// A lease forces the update only while the remote ref is where we last saw it
+	lease, leased, err := opts.Lease.expected(repo, pCtx.RemoteName, refName)
+	if err != nil {
+		return "", err
+	}
+	forced := pCtx.Force || leased
+	if leased && !pCtx.Force && lease != oldTip {
+		return "", rejectStaleLease(pCtx.RemoteURL, pCtx.Ref.Name().Short(), refName.Short())
+	}
 
 	// Check Fast-Forward (only for branches)
-	if refName.IsBranch() && !opts.Force {
-		targetRef, targetErr := targetRepo.Reference(refName, true)
-		if targetErr == nil {
-			isFF, gitErr := git.IsFastForward(repo, targetRef.Hash(), pCtx.Ref.Hash())
-			if gitErr != nil {
-				return "", gitErr
-			}
-			if !isFF {
-				return "", fmt.Errorf("non-fast-forward update rejected (use --force to override)")
-			}
+	fastForward := true
+	if refName.IsBranch() && !oldTip.IsZero() {
+		isFF, gitErr := git.IsFastForward(repo, oldTip, hashToSync)
+		if errors.Is(gitErr, plumbing.ErrObjectNotFound) {
+			// The remote has commits we never fetched, so this cannot be a fast-forward
+			isFF, gitErr = false, nil
+		}
+		if gitErr != nil {
+			return "", gitErr
 		}
-	} else if refName.IsTag() {
-		_, tagRefErr := targetRepo.Reference(refName, true)
-		if tagRefErr == nil && !opts.Force {
-			return "", fmt.Errorf("tag '%s' already exists (use --force to override)", refName.Short())
+		if !isFF && !forced {
+			return "", c.rejectNonFastForward(s, pCtx, oldTip)
 		}
+		fastForward = isFF
+	} else if refName.IsTag() && !oldTip.IsZero() && !forced {
+		return "", fmt.Errorf("tag '%s' already exists (use --force to override)", refName.Short())
 	}
 
 	if opts.DryRun {
-		return fmt.Sprintf("[dry-run] Would push %s to %s at %s", refName.Short(), pCtx.RemoteName, pCtx.RemoteURL), nil
+		return fmt.Sprintf("[dry-run] Would push %s to %s at %s", pCtx.Ref.Name().Short(), pCtx.RemoteName, pCtx.RemoteURL), nil
 	}
 
 	// SIMULATE PUSH: Copy Objects + Update Ref
-	hashToSync := pCtx.Ref.Hash()
-
-	// Check object type
-	obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, hashToSync)
-	if err != nil {
+	if err := copyRefObjects(ctx, repo, targetRepo, pCtx.RemoteName, hashToSync); err != nil {
 		return "", err
 	}
 
-	if obj.Type() == plumbing.TagObject {
-		// Annotated tag logic
-		if !git.HasObject(targetRepo, hashToSync) {
-			_, err = targetRepo.Storer.SetEncodedObject(obj)
-			if err != nil {
-				return "", err
-			}
-		}
-		// Decode tag to find target commit
-		tagObj, decodeErr := object.DecodeTag(repo.Storer, obj)
-		if decodeErr != nil {
-			return "", decodeErr
-		}
-		if copyErr := git.CopyCommitRecursive(repo, targetRepo, tagObj.Target); copyErr != nil {
-			return "", copyErr
-		}
-	} else if obj.Type() == plumbing.CommitObject {
-		if copyErr := git.CopyCommitRecursive(repo, targetRepo, hashToSync); copyErr != nil {
-			return "", copyErr
-		}
-	} else {
-		return "", fmt.Errorf("unsupported object type to push: %s", obj.Type())
-	}
-
 	// Update Remote Reference
-	err = targetRepo.Storer.SetReference(pCtx.Ref)
-	if err != nil {
+	tx := git.NewRefTransaction(targetRepo).Update(plumbing.NewHashReference(refName, hashToSync), oldTip)
+	if err := tx.Commit(); err != nil {
 		return "", err
 	}
 
 	// Update Local Remote-Tracking Reference (ONLY for branches)
 	if refName.IsBranch() {
-		localRemoteRefName := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/%s", pCtx.RemoteName, refName.Short()))
+		// The remote remembers who pushed what, for collaborative sessions
+		_ = git.RecordUserPush(targetRepo, s.Identity(), refName.Short(), hashToSync)
+
+		localRemoteRefName := plumbing.NewRemoteReferenceName(pCtx.RemoteName, refName.Short())
 		newLocalRemoteRef := plumbing.NewHashReference(localRemoteRefName, hashToSync)
 		_ = repo.Storer.SetReference(newLocalRemoteRef)
 	}
 
-	// Get old hash for display (if updating existing ref)
+	if !fastForward {
+		return fmt.Sprintf("To %s\n + %s...%s %s -> %s/%s (forced update)", pCtx.RemoteURL, oldTip.String()[:7], hashToSync.String()[:7], pCtx.Ref.Name().Short(), pCtx.RemoteName, refName.Short()), nil
+	}
+
+	// Old hash for display (if updating existing ref)
 	oldHashStr := "0000000"
-	if refName.IsBranch() {
-		existingRef, refErr := targetRepo.Reference(refName, true)
-		if refErr == nil {
-			oldHashStr = existingRef.Hash().String()[:7]
-		}
+	if old := tx.Log()[0].Old; old != "" && refName.IsBranch() {
+		oldHashStr = old[:7]
 	}
 
-	return fmt.Sprintf("To %s\n   %s..%s  %s -> %s/%s", pCtx.RemoteURL, oldHashStr, hashToSync.String()[:7], refName.Short(), pCtx.RemoteName, refName.Short()), nil
+	return fmt.Sprintf("To %s\n   %s..%s  %s -> %s/%s", pCtx.RemoteURL, oldHashStr, hashToSync.String()[:7], pCtx.Ref.Name().Short(), pCtx.RemoteName, refName.Short()), nil
 }
 
-func (c *PushCommand) Help() string {
-	return `📘 GIT-PUSH (1)                                         Git Manual
-
- 💡 DESCRIPTION
-    ・自分のコミットをリモートリポジトリにアップロードする
-    ・ローカルのブランチをリモートに公開する
-    
-    ※ GitGymではシミュレーションであり、実際のネットワーク送信は行われません。
-
- 📋 SYNOPSIS
-    git push [<remote>] [<branch>] [--force] [--force-with-lease]
-
- ⚙️  COMMON OPTIONS
-    -u, --set-upstream
-        (現在未実装) リモートブランチとローカルブランチの関連付け(追跡設定)を行います。
-
-    -f, --force
-        強制的にプッシュします（リモートの履歴を上書きするので注意）。
-
-    --force-with-lease
-        (現在未実装) より安全な強制プッシュです。他人の更新がないか確認してから上書きします。
+// rejectNonFastForward explains a push rejected because the remote branch moved
+// on. When another simulated user pushed it, the conflict is named and recorded.
+func (c *PushCommand) rejectNonFastForward(s *git.Session, pCtx *pushContext, remoteTip plumbing.Hash) error {
+	err := fmt.Errorf("non-fast-forward update rejected (use --force to override)")
+	if s.Manager == nil {
+		return err
+	}
+	branch := pCtx.Dst.Short()
+	user := s.Identity()
+	pusher, ok := s.Manager.LastPusher(pCtx.TargetRepo, branch, remoteTip, user)
+	if !ok {
+		return err
+	}
+	s.Manager.RecordPushConflict(pCtx.TargetRepo, git.PushConflict{
+		Branch:    branch,
+		User:      user,
+		PushedBy:  pusher,
+		Commit:    pCtx.Ref.Hash().String(),
+		RemoteTip: remoteTip.String(),
+		CreatedAt: time.Now(),
+	})
+	return fmt.Errorf("%w\n ! [rejected]        %s -> %s (fetch first)\nhint: %s <%s> pushed %s to '%s' since you last fetched.\nhint: Integrate their work first ('git pull' or 'git pull --rebase'), then push again.",
+		err, pCtx.Ref.Name().Short(), branch, pusher.Name, pusher.Email, remoteTip.String()[:7], branch)
+}
 
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: リモートに送信
-       $ git push origin main
+// copyRefObjects copies the commits (or annotated tags and their commits) at
+// hashes, with the history targetRepo lacks, to targetRepo in one transfer.
+// Besides the refs of targetRepo, the remote-tracking refs of remoteName tell
+// where that history starts.
+func copyRefObjects(ctx context.Context, repo, targetRepo *gogit.Repository, remoteName string, hashes ...plumbing.Hash) error {
+	for _, hash := range hashes {
+		obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return err
+		}
+		if t := obj.Type(); t != plumbing.TagObject && t != plumbing.CommitObject {
+			return fmt.Errorf("unsupported object type to push: %s", t)
+		}
+	}
+	haves := append(git.RefHaves(targetRepo, ""), git.RefHaves(repo, "refs/remotes/"+remoteName+"/")...)
+	return git.TransferObjects(ctx, repo, targetRepo, hashes, haves)
+}
 
-    2. 実践: 履歴書き換え時の安全な強制プッシュ (Recommended)
-       commit --amend や rebase で履歴を書き換えた後は強制プッシュが必要です。
-       しかし --force は危険なので、現場では「競合がない時だけ強制する」このオプションを使います。
-       $ git push --force-with-lease
+// Spec implements git.SpecProvider.
+func (c *PushCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-u", "--set-upstream"}, Usage: "Set the upstream branch"},
+			{Flags: []string{"-f", "--force"}, Usage: "Overwrite the remote branch"},
+			{Flags: []string{"--force-with-lease"}, Arg: git.ArgRef, Optional: true, Usage: "Overwrite only if the remote ref is where you last saw it"},
+			{Flags: []string{"--all"}, Usage: "Push every branch"},
+			{Flags: []string{"--tags"}, Usage: "Push every tag"},
+			{Flags: []string{"--mirror"}, Usage: "Push every ref"},
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would be pushed"},
+		},
+		Args: []string{git.ArgRemote, git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-push
-`
+func (c *PushCommand) Help() string {
+	return git.CommandHelp(context.Background(), "push")
 }
@@ -0,0 +1,121 @@
+package commands
+
+// push_all.go - "git push --all"
+//
+// Pushes every local branch to the remote branch of the same name in one go.
+// Like a single push, a branch whose remote namesake moved on is rejected
+// unless forced (--force, or --force-with-lease while the lease holds); the
+// other branches are pushed regardless.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func (c *PushCommand) pushAll(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *PushOptions) (string, error) {
+	if opts.Refspec != "" {
+		return "", fmt.Errorf("fatal: --all can't be combined with refspecs")
+	}
+	targetRepo, url, err := c.resolveRemoteRepo(s, repo, opts.Remote)
+	if err != nil {
+		return "", err
+	}
+	defer lockRemote(s, targetRepo)()
+
+	remote := mirroredRefs(targetRepo)
+	var updates []mirrorUpdate
+	var rejected []string // Report lines
+	var stale bool
+	for name, ref := range mirroredRefs(repo) {
+		if !name.IsBranch() {
+			continue
+		}
+		old := remote[name]
+		if old != nil && old.Hash() == ref.Hash() {
+			continue
+		}
+		update := mirrorUpdate{Ref: ref, Name: name, OldRef: old}
+		if old == nil && s.Manager != nil {
+			if err := s.Manager.BranchPolicyForRepo(targetRepo).Validate(name.Short()); err != nil {
+				return "", fmt.Errorf("! [remote rejected] %s -> %s (branch naming policy)\n%w", name.Short(), name.Short(), err)
+			}
+		}
+
+		lease, leased, err := opts.Lease.expected(repo, opts.Remote, name)
+		if err != nil {
+			return "", err
+		}
+		var oldHash plumbing.Hash
+		if old != nil {
+			oldHash = old.Hash()
+		}
+		if leased && !opts.Force && lease != oldHash {
+			rejected = append(rejected, staleLeaseLine(name.Short(), name.Short()))
+			stale = true
+			continue
+		}
+		if old != nil {
+			isFF, err := git.IsFastForward(repo, oldHash, ref.Hash())
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				isFF, err = false, nil
+			}
+			if err != nil {
+				return "", err
+			}
+			if !isFF && !opts.Force && !leased {
+				rejected = append(rejected, fmt.Sprintf(" ! %-18s %s -> %s (non-fast-forward)\n", "[rejected]", name.Short(), name.Short()))
+				continue
+			}
+			update.FastForward = isFF
+		}
+		updates = append(updates, update)
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+	sort.Strings(rejected)
+
+	if len(updates) == 0 && len(rejected) == 0 {
+		return "Everything up-to-date", nil
+	}
+
+	var sb strings.Builder
+	if opts.DryRun {
+		sb.WriteString("[dry-run] ")
+	}
+	sb.WriteString(fmt.Sprintf("To %s\n", url))
+	if !opts.DryRun {
+		if err := copyRefObjects(ctx, repo, targetRepo, opts.Remote, updatedHashes(updates)...); err != nil {
+			return "", err
+		}
+		if err := remoteRefTransaction(targetRepo, updates).Commit(); err != nil {
+			return "", err
+		}
+	}
+	for _, u := range updates {
+		sb.WriteString(formatMirrorUpdate(u))
+		if !opts.DryRun {
+			branchPushed(s, repo, targetRepo, opts.Remote, u.Ref)
+		}
+	}
+	if len(rejected) == 0 {
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	for _, line := range rejected {
+		sb.WriteString(line)
+	}
+	sb.WriteString(fmt.Sprintf("error: failed to push some refs to '%s'\n", url))
+	if stale {
+		sb.WriteString(staleLeaseHint)
+	} else {
+		sb.WriteString("hint: Updates were rejected because a pushed branch tip is behind its remote counterpart.\n")
+		sb.WriteString("hint: Integrate the remote changes (e.g. 'git pull ...') before pushing again.")
+	}
+	return "", fmt.Errorf("%s", sb.String())
+}
@@ -0,0 +1,209 @@
+package commands
+
+// push_mirror.go - "git push --mirror"
+//
+// Makes the remote's branches and tags an exact copy of the local ones:
+// every local branch/tag is created or force-updated, and remote
+// branches/tags that no longer exist locally are deleted. This is the final
+// step of migrating a project to a new host after "git remote set-url".
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// mirrorUpdate is one line of the mirror push report.
+type mirrorUpdate struct {
+	Ref         *plumbing.Reference // Local ref to push; nil when deleting
+	Name        plumbing.ReferenceName
+	OldRef      *plumbing.Reference // Remote ref before the push, if any
+	FastForward bool                // Reported as a fast-forward rather than a forced update
+}
+
+func (c *PushCommand) performMirror(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *PushOptions) (string, error) {
+	if opts.Refspec != "" {
+		return "", fmt.Errorf("fatal: --mirror can't be combined with refspecs")
+	}
+
+	targetRepo, url, err := c.resolveRemoteRepo(s, repo, opts.Remote)
+	if err != nil {
+		return "", err
+	}
+	defer lockRemote(s, targetRepo)()
+
+	local := mirroredRefs(repo)
+	remote := mirroredRefs(targetRepo)
+
+	var updates []mirrorUpdate
+	for name, ref := range local {
+		old := remote[name]
+		if old != nil && old.Hash() == ref.Hash() {
+			continue
+		}
+		if old == nil && name.IsBranch() && s.Manager != nil {
+			policy := s.Manager.BranchPolicyForRepo(targetRepo)
+			if err := policy.Validate(name.Short()); err != nil {
+				return "", fmt.Errorf("! [remote rejected] %s -> %s (branch naming policy)\n%w", name.Short(), name.Short(), err)
+			}
+		}
+		updates = append(updates, mirrorUpdate{Ref: ref, Name: name, OldRef: old})
+	}
+	for name, old := range remote {
+		if _, ok := local[name]; !ok {
+			updates = append(updates, mirrorUpdate{Name: name, OldRef: old})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+
+	if len(updates) == 0 {
+		return "Everything up-to-date", nil
+	}
+
+	var sb strings.Builder
+	if opts.DryRun {
+		sb.WriteString("[dry-run] ")
+	}
+	sb.WriteString(fmt.Sprintf("To %s\n", url))
+	if !opts.DryRun {
+		if err := copyRefObjects(ctx, repo, targetRepo, opts.Remote, updatedHashes(updates)...); err != nil {
+			return "", err
+		}
+		// The remote gets every update or none
+		if err := remoteRefTransaction(targetRepo, updates).Commit(); err != nil {
+			return "", err
+		}
+	}
+
+	for _, u := range updates {
+		sb.WriteString(formatMirrorUpdate(u))
+		if opts.DryRun {
+			continue
+		}
+
+		if u.Ref == nil {
+			if u.Name.IsBranch() {
+				_ = repo.Storer.RemoveReference(plumbing.NewRemoteReferenceName(opts.Remote, u.Name.Short()))
+			}
+			continue
+		}
+		if u.Name.IsBranch() {
+			branchPushed(s, repo, targetRepo, opts.Remote, u.Ref)
+		}
+	}
+
+	if hint := unpushedTrackingHint(repo, local); hint != "" {
+		sb.WriteString(hint)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// branchPushed records a branch pushed along with others: its remote-tracking
+// ref, who pushed it, and the remote's checks.
+func branchPushed(s *git.Session, repo, targetRepo *gogit.Repository, remote string, ref *plumbing.Reference) {
+	branch := ref.Name().Short()
+	_ = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName(remote, branch), ref.Hash()))
+	_ = git.RecordUserPush(targetRepo, s.Identity(), branch, ref.Hash())
+	if s.Manager != nil {
+		s.Manager.BranchPushed(targetRepo, branch, ref.Hash())
+	}
+}
+
+// remoteRefTransaction stages updates on the remote, each expecting the
+// remote ref where the push found it.
+func remoteRefTransaction(targetRepo *gogit.Repository, updates []mirrorUpdate) *git.RefTransaction {
+	tx := git.NewRefTransaction(targetRepo)
+	for _, u := range updates {
+		var old plumbing.Hash
+		if u.OldRef != nil {
+			old = u.OldRef.Hash()
+		}
+		if u.Ref == nil {
+			tx.Delete(u.Name, old)
+		} else {
+			tx.Update(u.Ref, old)
+		}
+	}
+	return tx
+}
+
+// updatedHashes returns what the updates that are not deletions push.
+func updatedHashes(updates []mirrorUpdate) []plumbing.Hash {
+	var hashes []plumbing.Hash
+	for _, u := range updates {
+		if u.Ref != nil {
+			hashes = append(hashes, u.Ref.Hash())
+		}
+	}
+	return hashes
+}
+
+// mirroredRefs returns the branches and tags that --mirror keeps in sync.
+func mirroredRefs(repo *gogit.Repository) map[plumbing.ReferenceName]*plumbing.Reference {
+	refs := make(map[plumbing.ReferenceName]*plumbing.Reference)
+	iter, err := repo.References()
+	if err != nil {
+		return refs
+	}
+	_ = iter.ForEach(func(r *plumbing.Reference) error {
+		if r.Type() == plumbing.HashReference && (r.Name().IsBranch() || r.Name().IsTag()) {
+			refs[r.Name()] = r
+		}
+		return nil
+	})
+	return refs
+}
+
+func formatMirrorUpdate(u mirrorUpdate) string {
+	short := u.Name.Short()
+	kind := "branch"
+	if u.Name.IsTag() {
+		kind = "tag"
+	}
+
+	switch {
+	case u.Ref == nil:
+		return fmt.Sprintf(" - %-18s %s\n", "[deleted]", short)
+	case u.OldRef == nil:
+		return fmt.Sprintf(" * %-18s %s -> %s\n", "[new "+kind+"]", short, short)
+	case u.FastForward:
+		return fmt.Sprintf("   %s..%s  %s -> %s\n", u.OldRef.Hash().String()[:7], u.Ref.Hash().String()[:7], short, short)
+	default:
+		return fmt.Sprintf(" + %s...%s %s -> %s (forced update)\n", u.OldRef.Hash().String()[:7], u.Ref.Hash().String()[:7], short, short)
+	}
+}
+
+// unpushedTrackingHint explains that remote-tracking branches without a local
+// branch are not part of a mirror push, which surprises people migrating from a normal clone.
+func unpushedTrackingHint(repo *gogit.Repository, local map[plumbing.ReferenceName]*plumbing.Reference) string {
+	iter, err := repo.References()
+	if err != nil {
+		return ""
+	}
+	var missing []string
+	_ = iter.ForEach(func(r *plumbing.Reference) error {
+		if !r.Name().IsRemote() || r.Type() != plumbing.HashReference {
+			return nil
+		}
+		short := strings.TrimPrefix(r.Name().String(), "refs/remotes/")
+		parts := strings.SplitN(short, "/", 2)
+		if len(parts) != 2 || parts[1] == "HEAD" {
+			return nil
+		}
+		if _, ok := local[plumbing.NewBranchReferenceName(parts[1])]; !ok {
+			missing = append(missing, short)
+		}
+		return nil
+	})
+	if len(missing) == 0 {
+		return ""
+	}
+	sort.Strings(missing)
+	return fmt.Sprintf("hint: Remote-tracking branches are not mirrored: %s\nhint: Create local branches first (e.g. 'git branch %s %s') if they should move too.\n",
+		strings.Join(missing, ", "), strings.SplitN(missing[0], "/", 2)[1], missing[0])
+}
@@ -0,0 +1,145 @@
+package commands
+
+// push_refspec.go - "git push <remote> [+]<src>[:<dst>]" and "--force-with-lease"
+//
+// A refspec names the local ref (or any commit) to send and the remote ref it
+// updates: "feature:review" pushes feature to the remote's review branch, and
+// a leading "+" forces that one update. A short destination is the remote
+// branch or tag of that name, or else a ref of the same kind as the source.
+//
+// --force-with-lease forces an update only while the remote ref is still at
+// the commit we last saw there, which is its remote-tracking ref unless
+// "=<ref>:<expect>" says otherwise. Someone else's push in between makes
+// the lease stale and the push is rejected instead of discarding their work.
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// pushRefspec is one parsed "[+]<src>[:<dst>]".
+type pushRefspec struct {
+	Src   string // Empty pushes the current branch
+	Dst   string // Empty pushes to the source's name
+	Force bool   // "+": update even if it is not a fast-forward
+}
+
+func parsePushRefspec(spec string) pushRefspec {
+	var rs pushRefspec
+	if strings.HasPrefix(spec, "+") {
+		rs.Force = true
+		spec = spec[1:]
+	}
+	rs.Src, rs.Dst, _ = strings.Cut(spec, ":")
+	return rs
+}
+
+// resolvePushSource finds the local ref or commit to push. Empty means the
+// current branch.
+func resolvePushSource(repo *gogit.Repository, src string) (*plumbing.Reference, error) {
+	if src == "" {
+		headRef, err := repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		if !headRef.Name().IsBranch() {
+			return nil, fmt.Errorf("HEAD is not on a branch (detached?)")
+		}
+		return headRef, nil
+	}
+
+	for _, name := range []string{src, "refs/heads/" + src, "refs/tags/" + src} {
+		if ref, err := repo.Reference(plumbing.ReferenceName(name), true); err == nil {
+			return ref, nil
+		}
+	}
+	// Any other commit, e.g. "git push origin HEAD~1:main"
+	if hash, err := repo.ResolveRevision(plumbing.Revision(src)); err == nil {
+		return plumbing.NewHashReference(plumbing.ReferenceName(src), *hash), nil
+	}
+	return nil, fmt.Errorf("src refspec '%s' does not match any", src)
+}
+
+// pushDestination names the remote ref that src updates. A short dst is the
+// remote branch or tag of that name, or a new ref of the kind of src.
+func pushDestination(targetRepo *gogit.Repository, src *plumbing.Reference, dst string) (plumbing.ReferenceName, error) {
+	kind := src.Name()
+	switch {
+	case dst == "" && (kind.IsBranch() || kind.IsTag()):
+		return kind, nil
+	case strings.HasPrefix(dst, "refs/"):
+		return plumbing.ReferenceName(dst), nil
+	case dst != "":
+		for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(dst), plumbing.NewTagReferenceName(dst)} {
+			if _, err := targetRepo.Reference(name, false); err == nil {
+				return name, nil
+			}
+		}
+		if kind.IsBranch() {
+			return plumbing.NewBranchReferenceName(dst), nil
+		}
+		if kind.IsTag() {
+			return plumbing.NewTagReferenceName(dst), nil
+		}
+	}
+	return "", fmt.Errorf("error: The destination you provided is not a full refname (i.e., starting with \"refs/\").\nhint: Name the remote ref in full, e.g. 'git push <remote> %s:refs/heads/<branch>'", src.Name())
+}
+
+// pushLease is --force-with-lease[=<ref>[:<expect>]].
+type pushLease struct {
+	Ref       string // Only this remote ref is leased; every pushed one when empty
+	Expect    string // Where the remote ref must be; its remote-tracking ref when unset
+	HasExpect bool   // "<ref>:" alone expects the ref not to exist yet
+}
+
+func parsePushLease(value string) *pushLease {
+	lease := &pushLease{}
+	lease.Ref, lease.Expect, lease.HasExpect = strings.Cut(value, ":")
+	return lease
+}
+
+// expected returns where the lease expects the remote ref dst to be, a zero
+// hash meaning it must not exist, and whether the lease covers dst at all.
+func (l *pushLease) expected(repo *gogit.Repository, remote string, dst plumbing.ReferenceName) (plumbing.Hash, bool, error) {
+	if l == nil || (l.Ref != "" && l.Ref != dst.String() && l.Ref != dst.Short()) {
+		return plumbing.ZeroHash, false, nil
+	}
+	if l.HasExpect {
+		if l.Expect == "" {
+			return plumbing.ZeroHash, true, nil
+		}
+		hash, err := repo.ResolveRevision(plumbing.Revision(l.Expect))
+		if err != nil {
+			return plumbing.ZeroHash, false, fmt.Errorf("fatal: cannot parse expected object name '%s'", l.Expect)
+		}
+		return *hash, true, nil
+	}
+	if !dst.IsBranch() {
+		return plumbing.ZeroHash, true, nil
+	}
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, dst.Short()), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, true, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+	return ref.Hash(), true, nil
+}
+
+// staleLeaseLine is the report line of a push refused by its lease.
+func staleLeaseLine(src, dst string) string {
+	return fmt.Sprintf(" ! %-18s %s -> %s (stale info)\n", "[rejected]", src, dst)
+}
+
+// rejectStaleLease refuses a forced push whose lease no longer holds.
+func rejectStaleLease(url, src, dst string) error {
+	return fmt.Errorf("To %s\n%serror: failed to push some refs to '%s'\n%s", url, staleLeaseLine(src, dst), url, staleLeaseHint)
+}
+
+const staleLeaseHint = "hint: The remote ref is no longer where you last fetched it: someone else pushed in between.\n" +
+	"hint: Fetch and look at their commits first ('git fetch'), then integrate them or force again."
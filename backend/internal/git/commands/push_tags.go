@@ -0,0 +1,125 @@
+package commands
+
+// push_tags.go - "git push --tags" and "git push <remote> :<ref>"
+//
+// Tags are not pushed with their branch: "--tags" sends every local tag the
+// remote does not have yet. A tag the remote already has at another object is
+// rejected unless forced, since others may have fetched it. An empty source
+// in a refspec (":refs/tags/v1.0") deletes the ref on the remote.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func (c *PushCommand) pushTags(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *PushOptions) (string, error) {
+	if opts.Refspec != "" {
+		return "", fmt.Errorf("fatal: --tags can't be combined with refspecs")
+	}
+	targetRepo, url, err := c.resolveRemoteRepo(s, repo, opts.Remote)
+	if err != nil {
+		return "", err
+	}
+	defer lockRemote(s, targetRepo)()
+
+	remote := mirroredRefs(targetRepo)
+	var updates []mirrorUpdate
+	var rejected []string
+	for name, ref := range mirroredRefs(repo) {
+		if !name.IsTag() {
+			continue
+		}
+		old := remote[name]
+		if old != nil && old.Hash() == ref.Hash() {
+			continue
+		}
+		if old != nil && !opts.Force {
+			rejected = append(rejected, name.Short())
+			continue
+		}
+		updates = append(updates, mirrorUpdate{Ref: ref, Name: name, OldRef: old})
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+	sort.Strings(rejected)
+
+	if len(updates) == 0 && len(rejected) == 0 {
+		return "Everything up-to-date", nil
+	}
+
+	var sb strings.Builder
+	if opts.DryRun {
+		sb.WriteString("[dry-run] ")
+	}
+	sb.WriteString(fmt.Sprintf("To %s\n", url))
+	if !opts.DryRun {
+		if err := copyRefObjects(ctx, repo, targetRepo, opts.Remote, updatedHashes(updates)...); err != nil {
+			return "", err
+		}
+		if err := remoteRefTransaction(targetRepo, updates).Commit(); err != nil {
+			return "", err
+		}
+	}
+	for _, u := range updates {
+		sb.WriteString(formatMirrorUpdate(u))
+	}
+	if len(rejected) == 0 {
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	for _, name := range rejected {
+		sb.WriteString(fmt.Sprintf(" ! %-18s %s -> %s (already exists)\n", "[rejected]", name, name))
+	}
+	sb.WriteString(fmt.Sprintf("error: failed to push some refs to '%s'\n", url))
+	sb.WriteString("hint: Updates were rejected because the tag already exists in the remote.\n")
+	sb.WriteString("hint: Use 'git push --force' only if nobody has fetched the old tag yet.")
+	return "", fmt.Errorf("%s", sb.String())
+}
+
+// deleteRemoteRef removes the branch or tag named after the colon of opts.Refspec
+// from the remote. A short name is looked up as a branch first, then as a tag.
+func (c *PushCommand) deleteRemoteRef(s *git.Session, repo *gogit.Repository, opts *PushOptions) (string, error) {
+	targetRepo, url, err := c.resolveRemoteRepo(s, repo, opts.Remote)
+	if err != nil {
+		return "", err
+	}
+	defer lockRemote(s, targetRepo)()
+
+	dst := strings.TrimPrefix(opts.Refspec, ":")
+	candidates := []plumbing.ReferenceName{plumbing.ReferenceName(dst)}
+	if !strings.HasPrefix(dst, "refs/") {
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(dst), plumbing.NewTagReferenceName(dst)}
+	}
+	var old *plumbing.Reference
+	for _, name := range candidates {
+		if ref, err := targetRepo.Reference(name, false); err == nil {
+			old = ref
+			break
+		}
+	}
+	if old == nil {
+		return "", fmt.Errorf("error: unable to delete '%s': remote ref does not exist\nerror: failed to push some refs to '%s'", dst, url)
+	}
+	if old.Name().IsBranch() {
+		if head, err := targetRepo.Reference(plumbing.HEAD, false); err == nil && head.Target() == old.Name() {
+			return "", fmt.Errorf("! [remote rejected] %s (refusing to delete the current branch: %s)\nerror: failed to push some refs to '%s'", old.Name().Short(), old.Name(), url)
+		}
+	}
+
+	line := formatMirrorUpdate(mirrorUpdate{Name: old.Name(), OldRef: old})
+	if opts.DryRun {
+		return fmt.Sprintf("[dry-run] To %s\n%s", url, strings.TrimSuffix(line, "\n")), nil
+	}
+	if err := git.NewRefTransaction(targetRepo).Delete(old.Name(), old.Hash()).Commit(); err != nil {
+		return "", err
+	}
+	if old.Name().IsBranch() {
+		_ = repo.Storer.RemoveReference(plumbing.NewRemoteReferenceName(opts.Remote, old.Name().Short()))
+	}
+	return fmt.Sprintf("To %s\n%s", url, strings.TrimSuffix(line, "\n")), nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/go-git/go-billy/v5/memfs"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
@@ -160,3 +161,296 @@ func TestPushCommand_NoRemote(t *testing.T) {
 		t.Error("Expected error for missing remote")
 	}
 }
+
+func TestPushMirror_CopiesAndPrunesRefs(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-mirror")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	remoteRepo := sm.SharedRemotes["remoterepo"]
+
+	head, _ := repo.Head()
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", head.Hash()))
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1.0", head.Hash()))
+	// A stale branch only on the remote must be deleted by --mirror
+	_ = remoteRepo.Storer.SetReference(plumbing.NewHashReference("refs/heads/stale", head.Hash()))
+
+	out, err := (&PushCommand{}).Execute(ctx, s, []string{"push", "--mirror", "origin"})
+	if err != nil {
+		t.Fatalf("push --mirror failed: %v", err)
+	}
+
+	for _, name := range []string{"refs/heads/master", "refs/heads/feature", "refs/tags/v1.0"} {
+		ref, err := remoteRepo.Reference(plumbing.ReferenceName(name), false)
+		if err != nil || ref.Hash() != head.Hash() {
+			t.Errorf("Expected %s to be mirrored, got %v", name, err)
+		}
+	}
+	if _, err := remoteRepo.Reference("refs/heads/stale", false); err == nil {
+		t.Error("Expected stale remote branch to be deleted")
+	}
+	if !strings.Contains(out, "[new tag]") || !strings.Contains(out, "[deleted]") {
+		t.Errorf("Unexpected output: %s", out)
+	}
+
+	if _, err := (&PushCommand{}).Execute(ctx, s, []string{"push", "--mirror", "origin", "master"}); err == nil {
+		t.Error("Expected --mirror with a refspec to fail")
+	}
+}
+
+func TestPushTags_PushesAndDeletesRemoteTags(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-tags")
+	ctx := context.Background()
+	remote := s.Repos["remoterepo"]
+	tag := &TagCommand{}
+	push := &PushCommand{}
+
+	for _, args := range [][]string{{"tag", "v1.0"}, {"tag", "-a", "v2.0", "-m", "two"}} {
+		if _, err := tag.Execute(ctx, s, args); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	out, err := push.Execute(ctx, s, []string{"push", "origin", "--tags"})
+	if err != nil {
+		t.Fatalf("push --tags failed: %v", err)
+	}
+	if !strings.Contains(out, "[new tag]") || !strings.Contains(out, "v2.0 -> v2.0") {
+		t.Errorf("Expected new tags in the output, got:\n%s", out)
+	}
+	for _, name := range []string{"v1.0", "v2.0"} {
+		if _, err := remote.Tag(name); err != nil {
+			t.Errorf("Expected %s on the remote: %v", name, err)
+		}
+	}
+	if out, _ := push.Execute(ctx, s, []string{"push", "origin", "--tags"}); out != "Everything up-to-date" {
+		t.Errorf("Expected nothing left to push, got %q", out)
+	}
+
+	// A moved tag is rejected unless forced
+	if _, err := tag.Execute(ctx, s, []string{"tag", "-f", "-a", "v1.0", "-m", "moved"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", "--tags"}); err == nil || !strings.Contains(err.Error(), "v1.0 -> v1.0 (already exists)") {
+		t.Errorf("Expected the moved tag to be rejected, got %v", err)
+	}
+	if out, err := push.Execute(ctx, s, []string{"push", "origin", "--tags", "--force"}); err != nil || !strings.Contains(out, "forced update") {
+		t.Errorf("Expected a forced tag update, got %q, %v", out, err)
+	}
+
+	out, err = push.Execute(ctx, s, []string{"push", "origin", ":refs/tags/v2.0"})
+	if err != nil || !strings.Contains(out, "[deleted]") {
+		t.Fatalf("Expected the remote tag to be deleted, got %q, %v", out, err)
+	}
+	if _, err := remote.Tag("v2.0"); err == nil {
+		t.Error("Expected v2.0 to be gone from the remote")
+	}
+	if _, err := s.GetRepo().Tag("v2.0"); err != nil {
+		t.Error("Expected the local tag to be kept")
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", ":v2.0"}); err == nil {
+		t.Error("Expected deleting a missing remote ref to fail")
+	}
+}
+
+func TestPushRefspec_SourceAndDestination(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-refspec")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	remote := s.Repos["remoterepo"]
+	push := &PushCommand{}
+	head, _ := repo.Head()
+
+	out, err := push.Execute(ctx, s, []string{"push", "-u", "origin", "master:review"})
+	if err != nil {
+		t.Fatalf("push master:review failed: %v", err)
+	}
+	if !strings.Contains(out, "master -> origin/review") || !strings.Contains(out, "set up to track 'origin/review'") {
+		t.Errorf("Unexpected output: %s", out)
+	}
+	if ref, err := remote.Reference("refs/heads/review", false); err != nil || ref.Hash() != head.Hash() {
+		t.Errorf("Expected review on the remote at %s, got %v", head.Hash(), err)
+	}
+	if _, err := remote.Reference("refs/heads/master", false); err == nil {
+		t.Error("Expected master to stay unpushed")
+	}
+	if _, err := repo.Reference("refs/remotes/origin/review", false); err != nil {
+		t.Errorf("Expected the remote-tracking ref origin/review: %v", err)
+	}
+
+	// A rewritten branch needs a "+" to replace the remote one
+	w, _ := repo.Worktree()
+	rewritten, _ := w.Commit("Rewritten", &gogit.CommitOptions{
+		Author:            &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+		AllowEmptyCommits: true,
+	})
+	_ = remote.Storer.SetReference(plumbing.NewHashReference("refs/heads/review", plumbing.NewHash("1111111111111111111111111111111111111111")))
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", "master:review"}); err == nil {
+		t.Error("Expected a non-fast-forward push to be rejected")
+	}
+	_ = remote.Storer.SetReference(plumbing.NewHashReference("refs/heads/review", head.Hash()))
+	if out, err := push.Execute(ctx, s, []string{"push", "origin", "HEAD~1:refs/heads/old"}); err != nil || !strings.Contains(out, "-> origin/old") {
+		t.Errorf("Expected a commit pushed to a full refname, got %q, %v", out, err)
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", "HEAD~1:elsewhere"}); err == nil || !strings.Contains(err.Error(), "not a full refname") {
+		t.Errorf("Expected a short destination for a commit to be refused, got %v", err)
+	}
+	if out, err := push.Execute(ctx, s, []string{"push", "origin", "+master:review"}); err != nil || !strings.Contains(out, rewritten.String()[:7]) {
+		t.Errorf("Expected review to move to the rewritten commit, got %q, %v", out, err)
+	}
+}
+
+func TestPushAll_PushesEveryBranch(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-all")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	remote := s.Repos["remoterepo"]
+	push := &PushCommand{}
+
+	head, _ := repo.Head()
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", head.Hash()))
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1.0", head.Hash()))
+
+	out, err := push.Execute(ctx, s, []string{"push", "--all", "origin"})
+	if err != nil {
+		t.Fatalf("push --all failed: %v", err)
+	}
+	if strings.Count(out, "[new branch]") != 2 {
+		t.Errorf("Expected two new branches, got:\n%s", out)
+	}
+	if _, err := remote.Reference("refs/tags/v1.0", false); err == nil {
+		t.Error("Expected --all to leave tags alone")
+	}
+	if _, err := repo.Reference("refs/remotes/origin/feature", false); err != nil {
+		t.Errorf("Expected the remote-tracking ref origin/feature: %v", err)
+	}
+
+	// feature moves on, while master is rewritten behind the remote's back
+	w, _ := repo.Worktree()
+	_ = w.Checkout(&gogit.CheckoutOptions{Branch: "refs/heads/feature"})
+	next, _ := w.Commit("Next", &gogit.CommitOptions{
+		Author:            &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+		AllowEmptyCommits: true,
+	})
+	_ = remote.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", plumbing.NewHash("1111111111111111111111111111111111111111")))
+
+	_, err = push.Execute(ctx, s, []string{"push", "--all", "origin"})
+	if err == nil || !strings.Contains(err.Error(), "master -> master (non-fast-forward)") {
+		t.Errorf("Expected master to be rejected, got %v", err)
+	}
+	if ref, _ := remote.Reference("refs/heads/feature", false); ref == nil || ref.Hash() != next {
+		t.Error("Expected feature to be pushed despite the rejected master")
+	}
+
+	if _, err := push.Execute(ctx, s, []string{"push", "--all", "origin", "master"}); err == nil {
+		t.Error("Expected --all with a refspec to fail")
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "--all", "--tags", "origin"}); err == nil {
+		t.Error("Expected --all with --tags to fail")
+	}
+}
+
+func TestPushForceWithLease(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-lease")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	remote := s.Repos["remoterepo"]
+	push := &PushCommand{}
+	w, _ := repo.Worktree()
+	commit := func(msg string) plumbing.Hash {
+		h, _ := w.Commit(msg, &gogit.CommitOptions{
+			Author:            &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+			AllowEmptyCommits: true,
+		})
+		return h
+	}
+	remoteTip := func() plumbing.Hash {
+		ref, _ := remote.Reference("refs/heads/master", false)
+		return ref.Hash()
+	}
+
+	base, _ := repo.Head()
+	if _, err := push.Execute(ctx, s, []string{"push", "-u", "origin", "master"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else pushes on top, and we have not fetched it
+	theirs := commit("Theirs")
+	if _, err := push.Execute(ctx, s, []string{"push"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/master", base.Hash()))
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", base.Hash()))
+	ours := commit("Ours")
+
+	_, err := push.Execute(ctx, s, []string{"push", "--force-with-lease"})
+	if err == nil || !strings.Contains(err.Error(), "master -> master (stale info)") {
+		t.Errorf("Expected a stale lease, got %v", err)
+	}
+	if remoteTip() != theirs {
+		t.Error("Expected their commit to stay on the remote")
+	}
+	_, err = push.Execute(ctx, s, []string{"push", "--force-with-lease=master:" + base.Hash().String(), "origin", "master"})
+	if err == nil || !strings.Contains(err.Error(), "stale info") {
+		t.Errorf("Expected an explicit stale expectation to be rejected, got %v", err)
+	}
+
+	// After a fetch the lease holds and replaces their commit
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/master", theirs))
+	out, err := push.Execute(ctx, s, []string{"push", "--force-with-lease"})
+	if err != nil || !strings.Contains(out, "(forced update)") {
+		t.Fatalf("Expected a forced update, got %q, %v", out, err)
+	}
+	if remoteTip() != ours {
+		t.Error("Expected our commit on the remote")
+	}
+
+	// A lease on another ref does not force this one
+	_ = remote.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", theirs))
+	_ = repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/master", theirs))
+	if _, err := push.Execute(ctx, s, []string{"push", "--force-with-lease=feature", "origin", "master"}); err == nil {
+		t.Error("Expected a non-fast-forward push outside the lease to be rejected")
+	}
+}
+
+func TestPush_RemotePermissions(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupPushTestSession(t, sm, "test-push-permissions")
+	ctx := context.Background()
+	push := &PushCommand{}
+
+	if err := sm.SetRemotePermission("remoterepo", git.RemotePermission{Access: git.RemoteAccessReadOnly}); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"push", "origin", "master"}, {"push", "--all", "origin"}, {"push", "origin", ":master"}} {
+		_, err := push.Execute(ctx, s, args)
+		if err == nil || !strings.Contains(err.Error(), "remote: Permission to remoterepo.git denied to User.") || !strings.Contains(err.Error(), "error: 403") {
+			t.Errorf("%v: expected a permission error, got %v", args, err)
+		}
+	}
+	if _, err := sm.SharedRemotes["remoterepo"].Reference("refs/heads/master", false); err == nil {
+		t.Error("Expected nothing pushed to the read-only remote")
+	}
+
+	// An owner-only remote accepts its owner's pushes alone
+	if err := sm.SetRemotePermission("remoterepo", git.RemotePermission{Access: git.RemoteAccessOwner, Owner: "someone-else"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", "master"}); err == nil || !strings.Contains(err.Error(), "only its owner") {
+		t.Errorf("Expected another session's remote to refuse the push, got %v", err)
+	}
+	if err := sm.SetRemotePermission("remoterepo", git.RemotePermission{Owner: s.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := push.Execute(ctx, s, []string{"push", "origin", "master"}); err != nil {
+		t.Errorf("Expected the owner's push to succeed, got %v", err)
+	}
+
+	if err := sm.SetRemotePermission("remoterepo", git.RemotePermission{Access: "admin"}); err == nil {
+		t.Error("Expected an unknown access level to be refused")
+	}
+}
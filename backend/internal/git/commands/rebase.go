@@ -21,18 +21,30 @@ type RebaseCommand struct{}
 // Ensure RebaseCommand implements git.Command
 var _ git.Command = (*RebaseCommand)(nil)
 
+// Ensure RebaseCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*RebaseCommand)(nil)
+
+// SupportsDryRun marks rebase as simulated by the engine on --dry-run.
+func (c *RebaseCommand) SupportsDryRun() {}
+
 type RebaseOptions struct {
-	Upstream string
-	Branch   string
-	Onto     string
-	Root     bool
-	Preserve bool
+	Upstream    string
+	Branch      string
+	Onto        string
+	Root        bool
+	Preserve    bool
+	Interactive bool  // Hand out a todo list instead of replaying right away
+	Continue    bool  // Replay the submitted plan, or go on after a resolved conflict
+	Skip        bool  // Drop the commit the rebase stopped on and go on
+	Abort       bool  // Cancel the rebase
+	Autostash   *bool // --autostash / --no-autostash; nil falls back to rebase.autostash
 }
 
 type rebaseContext struct {
 	targetHash      *plumbing.Hash
 	commitsToReplay []*object.Commit
 	headRef         *plumbing.Reference // Needed for success message
+	autostash       plumbing.Hash       // Stash of the local changes, if any
 }
 
 func (c *RebaseCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -47,29 +59,88 @@ func (c *RebaseCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	// 1. Parse Arguments
 	opts, err := c.parseArgs(args)
 	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "rebase"), nil
+		}
 		return "", err
 	}
 
-	// 2. Checkout Branch if provided
+	switch {
+	case opts.Continue:
+		return c.continueRebase(ctx, s, repo)
+	case opts.Skip:
+		return c.skipRebase(ctx, s, repo)
+	case opts.Abort:
+		return c.abortRebase(s, repo)
+	}
+	if rb := s.RebaseInProgress(); rb != nil {
+		if rb.Phase == git.RebaseStopped {
+			return "", fmt.Errorf("fatal: a rebase is already in progress\nhint: resolve the conflicts and run \"git rebase --continue\", or use \"git rebase --skip\" or \"git rebase --abort\"")
+		}
+		return "", fmt.Errorf("fatal: an interactive rebase is already in progress\nhint: submit the rebase plan, or run \"git rebase --abort\" to cancel it")
+	}
+
+	// 2. The replay needs a clean worktree: stash the local changes or refuse
+	dirty, err := hasLocalChanges(repo)
+	if err != nil {
+		return "", err
+	}
+	if !dirty {
+		return c.start(ctx, s, repo, opts, plumbing.ZeroHash)
+	}
+	if !c.autostash(repo, opts) {
+		return "", fmt.Errorf("error: cannot rebase: You have unstaged changes.\nerror: Please commit or stash them.")
+	}
+	stash, err := createAutostash(repo)
+	if err != nil {
+		return "", err
+	}
+	created := []string{autostashLine(stash)}
+	out, err := c.start(ctx, s, repo, opts, stash)
+	if err != nil {
+		return "", fmt.Errorf("%s", prependLines(created, err.Error()))
+	}
+	return prependLines(created, out), nil
+}
+
+// autostash reports whether local changes are stashed for the rebase:
+// --autostash and --no-autostash win over rebase.autostash.
+func (c *RebaseCommand) autostash(repo *gogit.Repository, opts *RebaseOptions) bool {
+	if opts.Autostash != nil {
+		return *opts.Autostash
+	}
+	return autostashConfigured(repo, "rebase")
+}
+
+// start begins the rebase described by opts. A rebase that ends before
+// replaying anything applies the autostash right away; otherwise the
+// rebase carries it until it finishes or is aborted.
+func (c *RebaseCommand) start(ctx context.Context, s *git.Session, repo *gogit.Repository, opts *RebaseOptions, autostash plumbing.Hash) (string, error) {
+	// Checkout Branch if provided
 	if opts.Branch != "" {
 		if err := c.checkoutBranch(repo, opts.Branch); err != nil {
-			return "", err
+			return "", fmt.Errorf("%s", afterAutostash(repo, autostash, err.Error()))
 		}
 	}
 
 	// Update ORIG_HEAD before rebase starts
 	s.UpdateOrigHead()
 
-	// 3. Prepare Rebase Context (resolve revisions, find commits)
+	// Prepare Rebase Context (resolve revisions, find commits)
 	rbCtx, err := c.prepareRebaseContext(repo, opts)
 	if err != nil {
 		if err == ErrUpToDate {
-			return "Current branch is up to date.", nil
+			return afterAutostash(repo, autostash, "Current branch is up to date."), nil
 		}
-		return "", err
+		return "", fmt.Errorf("%s", afterAutostash(repo, autostash, err.Error()))
 	}
+	rbCtx.autostash = autostash
 
-	// 4. Perform Rebase
+	if opts.Interactive {
+		return c.startInteractive(s, repo, rbCtx)
+	}
+
+	// Perform Rebase
 	return c.performRebase(ctx, s, repo, rbCtx, opts.Preserve)
 }
 
@@ -77,41 +148,51 @@ var ErrUpToDate = fmt.Errorf("up to date")
 
 func (c *RebaseCommand) parseArgs(args []string) (*RebaseOptions, error) {
 	opts := &RebaseOptions{}
-	cmdArgs := args[1:]
-	for i := 0; i < len(cmdArgs); i++ {
-		arg := cmdArgs[i]
-		switch arg {
-		case "--onto":
-			if i+1 >= len(cmdArgs) {
-				return nil, fmt.Errorf("fatal: option '--onto' requires a value")
-			}
-			opts.Onto = cmdArgs[i+1]
-			i++
-		case "-r", "--rebase-merges":
-			opts.Preserve = true
-		case "--root":
-			opts.Root = true
-		case "-h", "--help":
-			// Handled by calling Help() at higher level usually, but here checking arg
-			return nil, fmt.Errorf("help requested") // Should effectively show help if strictly followed, but standard is different. Logic in Execute handles it? No, Execute returns string/error.
-			// Let's assume help is handled before Execute or if we return specific error?
-			// Existing logic called c.Help() directly.
-		default:
-			if strings.HasPrefix(arg, "-") {
-				continue // ignore unknown flags
-			}
-			if opts.Upstream == "" {
-				opts.Upstream = arg
-			} else if opts.Branch == "" {
-				opts.Branch = arg
-			} else {
-				return nil, fmt.Errorf("fatal: too many arguments")
-			}
-		}
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	opts.Onto = parsed.Value("--onto")
+	opts.Preserve = parsed.Has("--rebase-merges")
+	opts.Root = parsed.Has("--root")
+	opts.Interactive = parsed.Has("--interactive")
+	opts.Continue = parsed.Has("--continue")
+	opts.Skip = parsed.Has("--skip")
+	opts.Abort = parsed.Has("--abort")
+	if parsed.Has("--autostash") {
+		autostash := true
+		opts.Autostash = &autostash
+	}
+	if parsed.Has("--no-autostash") {
+		autostash := false
+		opts.Autostash = &autostash
+	}
+
+	positional := append(parsed.Positional, parsed.Rest...)
+	if len(positional) > 2 {
+		return nil, fmt.Errorf("fatal: too many arguments")
+	}
+	if len(positional) > 0 {
+		opts.Upstream = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Branch = positional[1]
 	}
 
+	resume := 0
+	for _, set := range []bool{opts.Continue, opts.Skip, opts.Abort} {
+		if set {
+			resume++
+		}
+	}
+	if resume > 1 {
+		return nil, fmt.Errorf("error: --continue, --skip and --abort are mutually exclusive")
+	}
+	if resume == 1 {
+		return opts, nil
+	}
 	if opts.Upstream == "" && !opts.Root && opts.Onto == "" {
-		return nil, fmt.Errorf("usage: git rebase [--onto <newbase>] <upstream> [<branch>]")
+		return nil, fmt.Errorf("usage: git rebase [-i] [--onto <newbase>] <upstream> [<branch>]\n   or: git rebase (--continue | --skip | --abort)")
 	}
 	return opts, nil
 }
@@ -213,8 +294,8 @@ func (c *RebaseCommand) prepareRebaseContext(repo *gogit.Repository, opts *Rebas
 		}
 		base := mergeBases[0]
 
-		// Check for up-to-date
-		if opts.Onto == "" {
+		// Check for up-to-date (an interactive rebase may still rewrite the commits)
+		if opts.Onto == "" && !opts.Interactive {
 			if base.Hash == upstreamCommit.Hash {
 				return nil, ErrUpToDate
 			}
@@ -248,63 +329,359 @@ func (c *RebaseCommand) prepareRebaseContext(repo *gogit.Repository, opts *Rebas
 	}, nil
 }
 
-func (c *RebaseCommand) performRebase(_ context.Context, s *git.Session, repo *gogit.Repository, rbCtx *rebaseContext, _ bool) (string, error) {
-	// Hard Reset to Target (NewBase)
+func (c *RebaseCommand) performRebase(ctx context.Context, s *git.Session, repo *gogit.Repository, rbCtx *rebaseContext, _ bool) (string, error) {
+	// Replay on a detached HEAD at the new base; the branch moves at the end
 	w, _ := repo.Worktree()
-	if resetErr := w.Reset(&gogit.ResetOptions{Commit: *rbCtx.targetHash, Mode: gogit.HardReset}); resetErr != nil {
-		return "", fmt.Errorf("failed to reset to newbase: %v", resetErr)
+	if resetErr := w.Checkout(&gogit.CheckoutOptions{Hash: *rbCtx.targetHash, Force: true}); resetErr != nil {
+		return "", fmt.Errorf("%s", afterAutostash(repo, rbCtx.autostash, fmt.Sprintf("failed to reset to newbase: %v", resetErr)))
 	}
+	return c.replay(ctx, s, repo, w, newRebaseState(rbCtx))
+}
 
-	// Replay Commits
-	replayedCount := 0
-	for _, c := range rbCtx.commitsToReplay {
-		if applyErr := git.ApplyCommitChanges(w, c); applyErr != nil {
-			return "", fmt.Errorf("failed to apply commit %s: %v", c.Hash.String()[:7], applyErr)
+// newRebaseState builds the rebase of rbCtx, picking every commit to replay.
+func newRebaseState(rbCtx *rebaseContext) *git.RebaseState {
+	headName := "HEAD"
+	if rbCtx.headRef.Name().IsBranch() {
+		headName = rbCtx.headRef.Name().Short()
+	}
+	rb := &git.RebaseState{
+		HeadName: headName,
+		OrigHead: rbCtx.headRef.Hash().String(),
+		Onto:     rbCtx.targetHash.String(),
+	}
+	if !rbCtx.autostash.IsZero() {
+		rb.Autostash = rbCtx.autostash.String()
+	}
+	for _, commit := range rbCtx.commitsToReplay {
+		rb.Todo = append(rb.Todo, git.RebaseStep{
+			Action:  git.RebasePick,
+			Commit:  commit.Hash.String(),
+			Subject: strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0],
+		})
+	}
+	return rb
+}
+
+// startInteractive records the rebase in the session and returns its todo list.
+// Nothing is rewritten until the edited plan is submitted and --continue runs.
+func (c *RebaseCommand) startInteractive(s *git.Session, repo *gogit.Repository, rbCtx *rebaseContext) (string, error) {
+	if len(rbCtx.commitsToReplay) == 0 {
+		return afterAutostash(repo, rbCtx.autostash, "Current branch is up to date."), nil
+	}
+
+	rb := newRebaseState(rbCtx)
+	rb.Interactive = true
+	s.StartRebase(rb)
+
+	var sb strings.Builder
+	for _, step := range rb.Todo {
+		sb.WriteString(fmt.Sprintf("%s %s %s\n", step.Action, step.Commit[:7], step.Subject))
+	}
+	sb.WriteString(fmt.Sprintf("\n# Rebase %s onto %s (%d commands)\n", rb.HeadName, rb.Onto[:7], len(rb.Todo)))
+	sb.WriteString("#\n# Commands:\n")
+	sb.WriteString("# p, pick <commit> = use commit\n")
+	sb.WriteString("# r, reword <commit> = use commit, but edit the commit message\n")
+	sb.WriteString("# s, squash <commit> = use commit, but meld into previous commit\n")
+	sb.WriteString("# d, drop <commit> = remove commit\n")
+	sb.WriteString("#\n# Edit and submit the plan to replay the commits.\n")
+	sb.WriteString("# Run \"git rebase --abort\" to cancel the rebase.")
+	return sb.String(), nil
+}
+
+// replay merges the todo steps of rb one by one onto the detached HEAD. On a
+// conflict it stops, recording rb in the session for --continue, --skip or
+// --abort; once every step is replayed the branch is moved to the result.
+// A canceled ctx abandons the rebase between steps, as any other failure does.
+func (c *RebaseCommand) replay(ctx context.Context, s *git.Session, repo *gogit.Repository, w *gogit.Worktree, rb *git.RebaseState) (string, error) {
+	for len(rb.Todo) > 0 {
+		if err := ctx.Err(); err != nil {
+			return "", abandonReplay(s, repo, w, rb, err)
 		}
+		step := rb.Todo[0]
+		commit, err := repo.CommitObject(plumbing.NewHash(step.Commit))
+		if err != nil {
+			return "", abandonReplay(s, repo, w, rb, err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			return "", abandonReplay(s, repo, w, rb, err)
+		}
+		ours, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return "", abandonReplay(s, repo, w, rb, err)
+		}
+		var base *object.Commit
+		if commit.NumParents() > 0 {
+			base, _ = commit.Parent(0)
+		}
+
+		if err := git.Merge3Way(w, base, ours, commit, ""); err != nil {
+			if err == git.ErrConflict {
+				return "", c.stopOnConflict(s, repo, w, rb, commit)
+			}
+			return "", abandonReplay(s, repo, w, rb, fmt.Errorf("failed to apply commit %s: %v", commit.Hash.String()[:7], err))
+		}
+		if err := c.commitStep(s, repo, w, rb, step, commit); err != nil {
+			return "", abandonReplay(s, repo, w, rb, err)
+		}
+		rb.Todo = rb.Todo[1:]
+	}
 
-		// Ensure timestamp distinctness
-		time.Sleep(10 * time.Millisecond)
+	if err := finishReplay(repo, rebaseBranch(rb.HeadName), plumbing.NewHash(rb.OrigHead)); err != nil {
+		return "", abandonReplay(s, repo, w, rb, err)
+	}
+	s.ClearRebase()
+	if rb.Interactive {
+		s.RecordReflog(fmt.Sprintf("rebase -i (finish): returning to %s", rb.HeadName))
+	} else {
+		s.RecordReflog(fmt.Sprintf("rebase: finished rebase onto %s", rb.Onto))
+	}
+	out := fmt.Sprintf("Successfully rebased and updated %s.\nReplayed %d commits.", rb.HeadName, rb.Replayed)
+	return afterAutostash(repo, plumbing.NewHash(rb.Autostash), out), nil
+}
 
-		_, err := w.Commit(c.Message, &gogit.CommitOptions{
-			Author:            git.GetDefaultSignature(),
-			AllowEmptyCommits: true,
-		})
+// commitStep commits the staged result of replaying commit as step says.
+func (c *RebaseCommand) commitStep(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, rb *git.RebaseState, step git.RebaseStep, commit *object.Commit) error {
+	// Ensure timestamp distinctness
+	time.Sleep(10 * time.Millisecond)
+
+	commitOpts := &gogit.CommitOptions{
+		Author:            s.Signature(),
+		AllowEmptyCommits: true,
+	}
+	message := commit.Message
+	original := commit.Hash
+	switch {
+	case step.Action == git.RebaseReword:
+		message = step.Message
+	case step.Action == git.RebaseSquash && rb.LastOrig != "":
+		// Meld into the previous commit by recommitting on its parents. When
+		// the commit it melds into was skipped there is nothing to meld into.
+		head, err := repo.Head()
 		if err != nil {
-			return "", fmt.Errorf("failed to commit replayed change: %v", err)
+			return err
 		}
-		replayedCount++
+		last, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return err
+		}
+		message = strings.TrimRight(last.Message, "\n") + "\n\n" + commit.Message
+		commitOpts.Parents = last.ParentHashes
+		original = plumbing.NewHash(rb.LastOrig)
 	}
 
-	s.RecordReflog(fmt.Sprintf("rebase: finished rebase onto %s", rbCtx.targetHash.String()))
-	return fmt.Sprintf("Successfully rebased and updated %s.\nReplayed %d commits.", rbCtx.headRef.Name().Short(), replayedCount), nil
+	newHash, err := w.Commit(message, commitOpts)
+	if err != nil {
+		return fmt.Errorf("failed to commit replayed change: %v", err)
+	}
+	s.RecordLineage(original, newHash, git.RewriteRebase)
+	rb.LastOrig = original.String()
+	rb.Replayed++
+	return nil
 }
 
-func (c *RebaseCommand) Help() string {
-	return `📘 GIT-REBASE (1)                                       Git Manual
+// stopOnConflict records the stopped rebase in the session and builds git's
+// conflict report. HEAD stays detached at the commits replayed so far.
+func (c *RebaseCommand) stopOnConflict(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, rb *git.RebaseState, current *object.Commit) error {
+	conflicts, err := conflictedPaths(w)
+	if err != nil {
+		return err
+	}
+	rb.Conflicts = conflicts
+	s.StopRebase(rb)
+
+	var sb strings.Builder
+	for _, path := range conflicts {
+		sb.WriteString(fmt.Sprintf("CONFLICT (content): Merge conflict in %s\n", path))
+	}
+	subject := strings.SplitN(strings.TrimSpace(current.Message), "\n", 2)[0]
+	sb.WriteString(fmt.Sprintf("error: could not apply %s... %s\n", current.Hash.String()[:7], subject))
+	sb.WriteString("hint: Resolve all conflicts manually, mark them as resolved with\n")
+	sb.WriteString("hint: \"git add/rm <conflicted_files>\", then run \"git rebase --continue\".\n")
+	sb.WriteString("hint: You can instead skip this commit: run \"git rebase --skip\".\n")
+	sb.WriteString("hint: To abort and get back to the state before \"git rebase\", run \"git rebase --abort\".")
+	for _, line := range git.Rerere(s, repo, w, conflicts) {
+		sb.WriteString("\n" + line)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// continueRebase replays the submitted plan onto the rebase target, or
+// commits the resolved conflict of a stopped rebase and replays the rest.
+func (c *RebaseCommand) continueRebase(ctx context.Context, s *git.Session, repo *gogit.Repository) (string, error) {
+	rb := s.RebaseInProgress()
+	if rb == nil {
+		return "", fmt.Errorf("fatal: No rebase in progress?")
+	}
+	if rb.Phase == git.RebasePlanning {
+		return "", fmt.Errorf("error: the rebase plan has not been submitted yet\nhint: submit the edited todo list, or run \"git rebase --abort\" to cancel the rebase")
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if rb.Phase == git.RebaseApplying {
+		if resetErr := w.Checkout(&gogit.CheckoutOptions{Hash: plumbing.NewHash(rb.Onto), Force: true}); resetErr != nil {
+			return "", fmt.Errorf("failed to reset to newbase: %v", resetErr)
+		}
+		return c.replay(ctx, s, repo, w, rb)
+	}
+
+	if unresolved, err := unresolvedPaths(w, rb.Conflicts); err != nil {
+		return "", err
+	} else if len(unresolved) > 0 {
+		return "", unmergedFilesError(unresolved, "fatal: Exiting because of an unresolved conflict.")
+	}
+	step := rb.Todo[0]
+	commit, err := repo.CommitObject(plumbing.NewHash(step.Commit))
+	if err != nil {
+		return "", err
+	}
+	if err := c.commitStep(s, repo, w, rb, step, commit); err != nil {
+		return "", err
+	}
+	recorded := git.RerereRecordResolutions(s, repo, w)
+	rb.Todo = rb.Todo[1:]
+	rb.Conflicts = nil
+
+	out, err := c.replay(ctx, s, repo, w, rb)
+	if err != nil {
+		if len(recorded) > 0 {
+			return "", fmt.Errorf("%s", prependLines(recorded, err.Error()))
+		}
+		return "", err
+	}
+	return prependLines(recorded, out), nil
+}
+
+// skipRebase throws away the commit the rebase stopped on and replays the rest.
+func (c *RebaseCommand) skipRebase(ctx context.Context, s *git.Session, repo *gogit.Repository) (string, error) {
+	rb := s.RebaseInProgress()
+	if rb == nil || rb.Phase != git.RebaseStopped {
+		return "", fmt.Errorf("fatal: No rebase in progress?")
+	}
 
- 💡 DESCRIPTION
-    ・ブランチの派生元（親コミット）を付け替える
-    ・コミットの履歴を整形して、一直線にする
-    （歴史を書き換えるため、共有されているブランチでの使用は注意が必要です）
-    
-    ⚠️ 注意: 既に公開（プッシュ）したコミットをリベースすることは推奨されません。
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: head.Hash(), Mode: gogit.HardReset}); err != nil {
+		return "", err
+	}
+	git.RerereClear(s)
+	rb.Todo = rb.Todo[1:]
+	rb.Conflicts = nil
+	return c.replay(ctx, s, repo, w, rb)
+}
 
- 📋 SYNOPSIS
-    git rebase [--onto <newbase>] <upstream> [<branch>]
-    git rebase --root
+// abortRebase cancels the rebase and returns the branch to where it started.
+func (c *RebaseCommand) abortRebase(s *git.Session, repo *gogit.Repository) (string, error) {
+	rb := s.RebaseInProgress()
+	if rb == nil {
+		return "", fmt.Errorf("fatal: No rebase in progress?")
+	}
 
- ⚙️  COMMON OPTIONS
-    --onto <newbase>
-        新しいベース地点を明示的に指定します。
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	origHead := plumbing.NewHash(rb.OrigHead)
+	if rb.HeadName == "HEAD" {
+		err = w.Checkout(&gogit.CheckoutOptions{Hash: origHead, Force: true})
+	} else {
+		branch := rebaseBranch(rb.HeadName)
+		err = git.NewRefTransaction(repo).
+			Set(plumbing.NewHashReference(branch, origHead)).
+			Set(plumbing.NewSymbolicReference(plumbing.HEAD, branch)).
+			Commit()
+		if err == nil {
+			err = w.Checkout(&gogit.CheckoutOptions{Branch: branch, Force: true})
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to abort rebase: %v", err)
+	}
 
-    --root
-        ルートコミット（最初のコミット）まで遡ってリベースします。
+	s.ClearRebase()
+	git.RerereClear(s)
+	if rb.Interactive {
+		s.RecordReflog(fmt.Sprintf("rebase -i (abort): returning to %s", rb.HeadName))
+	} else {
+		s.RecordReflog(fmt.Sprintf("rebase (abort): returning to %s", rb.HeadName))
+	}
+	return afterAutostash(repo, plumbing.NewHash(rb.Autostash), ""), nil
+}
+
+// rebaseBranch returns the ref of the branch a rebase state names, or HEAD
+// when the rebase started on a detached HEAD.
+func rebaseBranch(headName string) plumbing.ReferenceName {
+	if headName == "HEAD" {
+		return plumbing.HEAD
+	}
+	return plumbing.NewBranchReferenceName(headName)
+}
+
+// finishReplay points the rebased branch at the tip the replay reached on
+// the detached HEAD, provided it is still at origHead, and attaches HEAD to
+// it again, both in one ref transaction. A rebase of a detached HEAD leaves
+// HEAD at the tip.
+func finishReplay(repo *gogit.Repository, branch plumbing.ReferenceName, origHead plumbing.Hash) error {
+	if !branch.IsBranch() {
+		return nil
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	return git.NewRefTransaction(repo).
+		Update(plumbing.NewHashReference(branch, head.Hash()), origHead).
+		Set(plumbing.NewSymbolicReference(plumbing.HEAD, branch)).
+		Commit()
+}
 
- 🛠  EXAMPLES
-    1. 現在のブランチをmainの最新に追従させる
-       $ git rebase main
+// abandonReplay gives up a rebase whose replay failed other than on a
+// conflict and returns to where it started. The branch never moved, so only
+// HEAD and the worktree go back, with the autostash; err is returned with
+// any error doing so.
+func abandonReplay(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, rb *git.RebaseState, err error) error {
+	s.ClearRebase()
+	branch := rebaseBranch(rb.HeadName)
+	opts := &gogit.CheckoutOptions{Branch: branch, Force: true}
+	if !branch.IsBranch() {
+		opts = &gogit.CheckoutOptions{Hash: plumbing.NewHash(rb.OrigHead), Force: true}
+	}
+	if restoreErr := w.Checkout(opts); restoreErr != nil {
+		return fmt.Errorf("%w\nerror: could not return to %s: %v", err, branch.Short(), restoreErr)
+	}
+	if line := applyAutostash(repo, plumbing.NewHash(rb.Autostash)); line != "" {
+		return fmt.Errorf("%w\n%s", err, line)
+	}
+	return err
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-rebase
-`
+// Spec implements git.SpecProvider.
+func (c *RebaseCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-i", "--interactive"}, Usage: "Edit the list of commits"},
+			{Flags: []string{"--onto"}, Arg: git.ArgRef, Usage: "Rebase onto another base"},
+			{Flags: []string{"--root"}, Usage: "Rebase every commit"},
+			{Flags: []string{"-r", "--rebase-merges"}, Usage: "Keep merge commits"},
+			{Flags: []string{"--continue"}, Usage: "Continue after resolving conflicts"},
+			{Flags: []string{"--skip"}, Usage: "Skip the commit that conflicted"},
+			{Flags: []string{"--abort"}, Usage: "Cancel and go back"},
+			{Flags: []string{"--autostash"}, Usage: "Stash local changes and reapply them afterwards"},
+			{Flags: []string{"--no-autostash"}, Usage: "Refuse to rebase a dirty worktree"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *RebaseCommand) Help() string {
+	return git.CommandHelp(context.Background(), "rebase")
 }
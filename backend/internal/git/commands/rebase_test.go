@@ -12,6 +12,7 @@ import (
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRebaseOnto(t *testing.T) {
@@ -203,3 +204,340 @@ func TestRebaseDisjoint(t *testing.T) {
 	_, err = fs.Stat("b.txt")
 	assert.NoError(t, err)
 }
+
+func TestRebaseInteractive(t *testing.T) {
+	setup := func(t *testing.T) (*git.Session, *gogit.Repository, []plumbing.Hash) {
+		fs := memfs.New()
+		r, _ := gogit.Init(memory.NewStorage(), fs)
+		w, _ := r.Worktree()
+		sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+
+		var hashes []plumbing.Hash
+		for _, name := range []string{"base", "one", "two", "three"} {
+			f, _ := fs.Create(name + ".txt")
+			_, _ = f.Write([]byte(name))
+			_ = f.Close()
+			_, _ = w.Add(name + ".txt")
+			h, err := w.Commit("Add "+name, &gogit.CommitOptions{Author: sig})
+			require.NoError(t, err)
+			hashes = append(hashes, h)
+		}
+
+		session := &git.Session{
+			ID:         "test-session",
+			Filesystem: fs,
+			Repos:      map[string]*gogit.Repository{"repo": r},
+			CurrentDir: "/repo",
+		}
+		return session, r, hashes
+	}
+
+	t.Run("plan is replayed", func(t *testing.T) {
+		session, r, hashes := setup(t)
+		cmd := &RebaseCommand{}
+
+		output, err := cmd.Execute(context.Background(), session, []string{"rebase", "-i", hashes[0].String()})
+		require.NoError(t, err)
+		assert.Contains(t, output, "pick "+hashes[1].String()[:7]+" Add one")
+		assert.Contains(t, output, "pick "+hashes[3].String()[:7]+" Add three")
+
+		// Nothing is rewritten before the plan is submitted
+		head, _ := r.Head()
+		assert.Equal(t, hashes[3], head.Hash())
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		assert.ErrorContains(t, err, "has not been submitted")
+
+		err = session.SetRebasePlan([]git.RebaseStep{
+			{Action: "reword", Commit: hashes[1].String()[:7], Message: "Add first file"},
+			{Action: "s", Commit: hashes[2].String()},
+			{Action: "drop", Commit: hashes[3].String()},
+		})
+		require.NoError(t, err)
+
+		output, err = cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "Successfully rebased and updated master")
+		assert.Nil(t, session.RebaseInProgress())
+
+		head, _ = r.Head()
+		assert.Equal(t, "refs/heads/master", head.Name().String())
+		squashed, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Add first file\n\nAdd two", squashed.Message)
+		require.Equal(t, 1, squashed.NumParents())
+		assert.Equal(t, hashes[0], squashed.ParentHashes[0])
+
+		_, err = squashed.File("two.txt")
+		assert.NoError(t, err)
+		_, err = squashed.File("three.txt")
+		assert.Error(t, err, "dropped commit must not be replayed")
+	})
+
+	t.Run("invalid plans are rejected", func(t *testing.T) {
+		session, _, hashes := setup(t)
+		cmd := &RebaseCommand{}
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", "-i", hashes[0].String()})
+		require.NoError(t, err)
+
+		assert.ErrorContains(t, session.SetRebasePlan([]git.RebaseStep{{Action: "squash", Commit: hashes[1].String()}}), "without a previous commit")
+		assert.ErrorContains(t, session.SetRebasePlan([]git.RebaseStep{{Action: "pick", Commit: hashes[0].String()}}), "not part of this rebase")
+		assert.ErrorContains(t, session.SetRebasePlan([]git.RebaseStep{{Action: "edit", Commit: hashes[1].String()}}), "unknown action")
+		assert.Equal(t, git.RebasePlanning, session.RebaseInProgress().Phase)
+	})
+
+	t.Run("abort restores the branch", func(t *testing.T) {
+		session, r, hashes := setup(t)
+		cmd := &RebaseCommand{}
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", "-i", hashes[1].String()})
+		require.NoError(t, err)
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "-i", hashes[0].String()})
+		assert.ErrorContains(t, err, "already in progress")
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--abort"})
+		require.NoError(t, err)
+		assert.Nil(t, session.RebaseInProgress())
+
+		head, _ := r.Head()
+		assert.Equal(t, "refs/heads/master", head.Name().String())
+		assert.Equal(t, hashes[3], head.Hash())
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--abort"})
+		assert.ErrorContains(t, err, "No rebase in progress")
+	})
+}
+
+func TestRebaseConflict(t *testing.T) {
+	// feature has A (conflicts with main's B) and C; rebasing it onto main stops on A
+	setup := func(t *testing.T) (*git.Session, *gogit.Repository, []plumbing.Hash, plumbing.Hash) {
+		fs := memfs.New()
+		r, _ := gogit.Init(memory.NewStorage(), fs)
+		w, _ := r.Worktree()
+		sig := &object.Signature{Name: "User", Email: "u@t.com", When: time.Now()}
+
+		writeAndAdd := func(name, content string) {
+			f, _ := fs.Create(name)
+			_, _ = f.Write([]byte(content))
+			_ = f.Close()
+			_, _ = w.Add(name)
+		}
+
+		writeAndAdd("file.txt", "base\n")
+		baseHash, _ := w.Commit("Base", &gogit.CommitOptions{Author: sig})
+		writeAndAdd("file.txt", "base\nchangeB\n")
+		bHash, _ := w.Commit("Commit B", &gogit.CommitOptions{Author: sig})
+
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Hash: baseHash, Branch: "refs/heads/feature", Create: true}))
+		writeAndAdd("file.txt", "base\nchangeA\n")
+		aHash, _ := w.Commit("Commit A", &gogit.CommitOptions{Author: sig})
+		writeAndAdd("c.txt", "C\n")
+		cHash, _ := w.Commit("Commit C", &gogit.CommitOptions{Author: sig})
+
+		session := &git.Session{
+			ID:         "test-session",
+			Filesystem: fs,
+			Repos:      map[string]*gogit.Repository{"repo": r},
+			CurrentDir: "/repo",
+		}
+		return session, r, []plumbing.Hash{aHash, cHash}, bHash
+	}
+	readFile := func(t *testing.T, session *git.Session, name string) string {
+		f, err := session.Filesystem.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		content := make([]byte, 200)
+		n, _ := f.Read(content)
+		return string(content[:n])
+	}
+	feature := plumbing.NewBranchReferenceName("feature")
+
+	t.Run("continue after resolving", func(t *testing.T) {
+		session, r, picks, onto := setup(t)
+		cmd := &RebaseCommand{}
+
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", onto.String()})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CONFLICT (content): Merge conflict in file.txt")
+		assert.Contains(t, err.Error(), "could not apply "+picks[0].String()[:7])
+
+		rb := session.RebaseInProgress()
+		require.NotNil(t, rb)
+		assert.Equal(t, git.RebaseStopped, rb.Phase)
+		assert.Equal(t, []string{"file.txt"}, rb.Conflicts)
+		require.Len(t, rb.Todo, 2)
+		assert.Equal(t, picks[0].String(), rb.Todo[0].Commit)
+		rebaseHead, err := r.Reference(git.RebaseHead, false)
+		require.NoError(t, err)
+		assert.Equal(t, picks[0], rebaseHead.Hash())
+
+		// The branch has not moved; HEAD is detached at the new base
+		branch, _ := r.Reference(feature, false)
+		assert.Equal(t, picks[1], branch.Hash())
+		head, _ := r.Head()
+		assert.Equal(t, plumbing.HEAD, head.Name())
+		assert.Equal(t, onto, head.Hash())
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", onto.String()})
+		assert.ErrorContains(t, err, "already in progress")
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		assert.ErrorContains(t, err, "unmerged files")
+
+		w, _ := r.Worktree()
+		f, _ := session.Filesystem.Create("file.txt")
+		_, _ = f.Write([]byte("base\nchangeB\nchangeA\n"))
+		_ = f.Close()
+		_, _ = w.Add("file.txt")
+
+		output, err := cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "Successfully rebased and updated feature")
+		assert.Contains(t, output, "Replayed 2 commits")
+		assert.Nil(t, session.RebaseInProgress())
+		_, err = r.Reference(git.RebaseHead, false)
+		assert.Error(t, err, "REBASE_HEAD is removed once the rebase finishes")
+
+		head, _ = r.Head()
+		assert.Equal(t, feature, head.Name())
+		tip, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Commit C", tip.Message)
+		resolved, _ := tip.Parents().Next()
+		assert.Equal(t, "Commit A", resolved.Message)
+		assert.Equal(t, []plumbing.Hash{onto}, resolved.ParentHashes)
+		assert.Equal(t, "base\nchangeB\nchangeA\n", readFile(t, session, "file.txt"))
+	})
+
+	t.Run("skip drops the conflicting commit", func(t *testing.T) {
+		session, r, _, onto := setup(t)
+		cmd := &RebaseCommand{}
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", onto.String()})
+		require.Error(t, err)
+
+		output, err := cmd.Execute(context.Background(), session, []string{"rebase", "--skip"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "Replayed 1 commits")
+
+		head, _ := r.Head()
+		assert.Equal(t, feature, head.Name())
+		tip, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Commit C", tip.Message)
+		assert.Equal(t, []plumbing.Hash{onto}, tip.ParentHashes)
+		assert.Equal(t, "base\nchangeB\n", readFile(t, session, "file.txt"))
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--skip"})
+		assert.ErrorContains(t, err, "No rebase in progress")
+	})
+
+	t.Run("abort restores the branch", func(t *testing.T) {
+		session, r, picks, onto := setup(t)
+		cmd := &RebaseCommand{}
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", onto.String()})
+		require.Error(t, err)
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--abort"})
+		require.NoError(t, err)
+		assert.Nil(t, session.RebaseInProgress())
+
+		head, _ := r.Head()
+		assert.Equal(t, feature, head.Name())
+		assert.Equal(t, picks[1], head.Hash())
+		assert.Equal(t, "base\nchangeA\n", readFile(t, session, "file.txt"))
+	})
+
+	t.Run("autostash waits for the rebase to end", func(t *testing.T) {
+		session, r, picks, onto := setup(t)
+		cmd := &RebaseCommand{}
+		f, _ := session.Filesystem.Create("c.txt")
+		_, _ = f.Write([]byte("local edit\n"))
+		_ = f.Close()
+
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", onto.String()})
+		assert.ErrorContains(t, err, "cannot rebase: You have unstaged changes")
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--autostash", onto.String()})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Created autostash: ")
+		assert.Contains(t, err.Error(), "Merge conflict in file.txt")
+		rb := session.RebaseInProgress()
+		require.NotNil(t, rb)
+		assert.NotEmpty(t, rb.Autostash)
+
+		output, err := cmd.Execute(context.Background(), session, []string{"rebase", "--abort"})
+		require.NoError(t, err)
+		assert.Equal(t, "Applied autostash.", output)
+		head, _ := r.Head()
+		assert.Equal(t, picks[1], head.Hash())
+		assert.Equal(t, "local edit\n", readFile(t, session, "c.txt"))
+		entries, _ := stashEntries(r)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("interactive plan stops too", func(t *testing.T) {
+		session, r, picks, onto := setup(t)
+		cmd := &RebaseCommand{}
+		_, err := cmd.Execute(context.Background(), session, []string{"rebase", "-i", onto.String()})
+		require.NoError(t, err)
+		require.NoError(t, session.SetRebasePlan([]git.RebaseStep{
+			{Action: "pick", Commit: picks[1].String()},
+			{Action: "squash", Commit: picks[0].String()},
+		}))
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Merge conflict in file.txt")
+
+		w, _ := r.Worktree()
+		f, _ := session.Filesystem.Create("file.txt")
+		_, _ = f.Write([]byte("base\nchangeA\n"))
+		_ = f.Close()
+		_, _ = w.Add("file.txt")
+
+		_, err = cmd.Execute(context.Background(), session, []string{"rebase", "--continue"})
+		require.NoError(t, err)
+		head, _ := r.Head()
+		squashed, _ := r.CommitObject(head.Hash())
+		assert.Equal(t, "Commit C\n\nCommit A", squashed.Message)
+		assert.Equal(t, []plumbing.Hash{onto}, squashed.ParentHashes)
+	})
+	t.Run("status shows the stopped rebase", func(t *testing.T) {
+		session, r, _, onto := setup(t)
+		_, err := (&RebaseCommand{}).Execute(context.Background(), session, []string{"rebase", onto.String()})
+		require.Error(t, err)
+
+		status := &StatusCommand{}
+		out, err := status.Execute(context.Background(), session, []string{"status"})
+		require.NoError(t, err)
+		assert.Contains(t, out, "rebase in progress; onto "+onto.String()[:7])
+		assert.NotContains(t, out, "interactive rebase")
+		assert.NotContains(t, out, "HEAD detached")
+		assert.Contains(t, out, "You are currently rebasing branch 'feature' on '"+onto.String()[:7]+"'.")
+		assert.Contains(t, out, `(fix conflicts and then run "git rebase --continue")`)
+		assert.Contains(t, out, `(use "git rebase --skip" to skip this patch)`)
+		assert.Contains(t, out, `(use "git rebase --abort" to check out the original branch)`)
+		assert.Contains(t, out, "Unmerged paths:")
+		assert.Contains(t, out, "both modified:  file.txt")
+
+		out, err = status.Execute(context.Background(), session, []string{"status", "-s"})
+		require.NoError(t, err)
+		assert.Contains(t, out, "UU file.txt")
+
+		w, _ := r.Worktree()
+		f, _ := session.Filesystem.Create("file.txt")
+		_, _ = f.Write([]byte("base\nchangeB\nchangeA\n"))
+		_ = f.Close()
+		_, _ = w.Add("file.txt")
+
+		out, err = status.Execute(context.Background(), session, []string{"status"})
+		require.NoError(t, err)
+		assert.Contains(t, out, `(all conflicts fixed: run "git rebase --continue")`)
+		assert.NotContains(t, out, "Unmerged paths:")
+	})
+
+	t.Run("status names an interactive rebase", func(t *testing.T) {
+		session, _, _, onto := setup(t)
+		_, err := (&RebaseCommand{}).Execute(context.Background(), session, []string{"rebase", "-i", onto.String()})
+		require.NoError(t, err)
+
+		out, err := (&StatusCommand{}).Execute(context.Background(), session, []string{"status"})
+		require.NoError(t, err)
+		assert.Contains(t, out, "interactive rebase in progress; onto "+onto.String()[:7])
+	})
+}
@@ -18,63 +18,90 @@ type ReflogCommand struct{}
 var _ git.Command = (*ReflogCommand)(nil)
 
 func (c *ReflogCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "reflog"), nil
+		}
+		return "", err
+	}
 
 	repo := s.GetRepo()
 	if repo == nil {
 		return "", fmt.Errorf("fatal: not a git repository")
 	}
 
-	// Parse flags
-	cmdArgs := args[1:]
-	for _, arg := range cmdArgs {
-		switch arg {
-		case "-h", "--help":
-			return c.Help(), nil
-		default:
-			// reflog usually takes subcommand "show", "expire", "delete", "exists"
-			// default is "show"
-			// implementing simple loop for help support
+	entries, err := s.RefReflog(opts.Ref)
+	if opts.Exists {
+		if err != nil || len(entries) == 0 {
+			return "", fmt.Errorf("error: reflog for '%s' does not exist", opts.Ref)
 		}
+		return "", nil
+	}
+	if err != nil {
+		return "", err
 	}
 
+	// Newest first: <ref>@{0} is the current value
 	var sb strings.Builder
-	// Git reflog shows newest first (HEAD@{0} is current)
-	count := len(s.Reflog)
-	for i := count - 1; i >= 0; i-- {
-		entry := s.Reflog[i]
-		// index 0 is oldest, so HEAD@{count-1-i} ?? No.
-		// Standard: HEAD@{0} is the most recent (last appended).
-		// So i (which is index in slice) corresponds to what?
-		// if slice is [A, B, C], C is newest (HEAD@{0}).
-		// i=2 (C) -> 0
-		// i=1 (B) -> 1
-		// i=0 (A) -> 2
-		// Formula: count - 1 - i
-
-		refIndex := count - 1 - i
-
-		sb.WriteString(fmt.Sprintf("%s HEAD@{%d}: %s\n", entry.Hash[:7], refIndex, entry.Message))
+	for i, entry := range entries {
+		sb.WriteString(fmt.Sprintf("%s %s@{%d}: %s\n", entry.Hash[:7], opts.Ref, i, entry.Message))
 	}
 	return sb.String(), nil
 }
 
-func (c *ReflogCommand) Help() string {
-	return `📘 GIT-REFLOG (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ・HEAD（現在の場所）の移動履歴を表示する
-    ・間違ってリセットしてしまった場合の復元ポイントを探す
+// ReflogOptions holds the parsed arguments of git reflog.
+type ReflogOptions struct {
+	Ref    string // Ref whose log is shown, HEAD by default
+	Exists bool   // "git reflog exists <ref>": only check that the log exists
+}
 
- 📋 SYNOPSIS
-    git reflog
+func (c *ReflogCommand) parseArgs(args []string) (*ReflogOptions, error) {
+	opts := &ReflogOptions{}
+	cmdArgs := args[1:]
+	if len(cmdArgs) > 0 {
+		switch cmdArgs[0] {
+		case "show":
+			cmdArgs = cmdArgs[1:]
+		case "exists":
+			opts.Exists = true
+			cmdArgs = cmdArgs[1:]
+		case "expire", "delete":
+			return nil, fmt.Errorf("git reflog %s is not supported", cmdArgs[0])
+		}
+	}
+	for _, arg := range cmdArgs {
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("error: unknown option '%s'", arg)
+		case opts.Ref == "":
+			opts.Ref = arg
+		default:
+			return nil, fmt.Errorf("fatal: too many arguments")
+		}
+	}
+	if opts.Exists && opts.Ref == "" {
+		return nil, fmt.Errorf("fatal: reflog exists requires a ref")
+	}
+	if opts.Ref == "" {
+		opts.Ref = "HEAD"
+	}
+	return opts, nil
+}
 
- 🛠  EXAMPLES
-    1. HEADの履歴を表示
-       $ git reflog
+// Spec implements git.SpecProvider.
+func (c *ReflogCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"delete", "exists", "expire", "show"},
+		Args:        []string{git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-reflog
-`
+func (c *ReflogCommand) Help() string {
+	return git.CommandHelp(context.Background(), "reflog")
 }
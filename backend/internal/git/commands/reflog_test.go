@@ -53,3 +53,75 @@ func TestReflogCommand_Basic(t *testing.T) {
 		}
 	})
 }
+
+func TestReflog_RefLogsAndRecovery(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-reflog-refs")
+	ctx := context.Background()
+
+	run := func(cmdline string) string {
+		t.Helper()
+		name, args := git.ParseCommand(cmdline)
+		result, err := git.Dispatch(ctx, s, name, args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", cmdline, err)
+		}
+		return result.Stdout
+	}
+	head := func() string {
+		t.Helper()
+		ref, err := s.GetRepo().Head()
+		if err != nil {
+			t.Fatalf("HEAD: %v", err)
+		}
+		return ref.Hash().String()
+	}
+
+	run("git commit --allow-empty -m lost")
+	lost := head()
+	run("git reset --hard HEAD~1")
+
+	// HEAD and the branch both record the reset with old and new values
+	entries, err := s.RefReflog("main")
+	if err != nil || len(entries) < 2 {
+		t.Fatalf("Expected main reflog, got %v (%v)", entries, err)
+	}
+	if entries[0].OldHash != lost || !strings.HasPrefix(entries[0].Message, "reset:") {
+		t.Errorf("Expected reset entry from %s, got %+v", lost, entries[0])
+	}
+	out := run("git reflog show main")
+	if !strings.Contains(out, "main@{1}: commit: lost") {
+		t.Errorf("Expected branch reflog, got:\n%s", out)
+	}
+
+	// A branch moved by a command that does not log itself is still recorded
+	run("git branch side")
+	run("git update-ref refs/heads/side " + lost)
+	out = run("git reflog side")
+	if !strings.Contains(out, lost[:7]+" side@{0}: update-ref:") {
+		t.Errorf("Expected update-ref entry, got:\n%s", out)
+	}
+
+	// The reset commit is recoverable through HEAD@{1}
+	run("git checkout HEAD@{1}")
+	if head() != lost {
+		t.Errorf("Expected HEAD@{1} to be %s, got %s", lost, head())
+	}
+	run("git checkout main")
+	run("git reset --hard main@{1}")
+	if head() != lost {
+		t.Errorf("Expected main@{1} to be %s, got %s", lost, head())
+	}
+
+	name, args := git.ParseCommand("git checkout HEAD@{99}")
+	if _, err := git.Dispatch(ctx, s, name, args); err == nil || !strings.Contains(err.Error(), "only has") {
+		t.Errorf("Expected out of range error, got %v", err)
+	}
+
+	// Deleting a branch deletes its log
+	run("git branch -D side")
+	name, args = git.ParseCommand("git reflog exists side")
+	if _, err := git.Dispatch(ctx, s, name, args); err == nil {
+		t.Error("Expected side reflog to be gone")
+	}
+}
@@ -3,10 +3,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -23,7 +25,13 @@ type RemoteOptions struct {
 	SubCmd  string
 	Name    string
 	URL     string
+	Args    []string // Positional arguments after the subcommand
 	Verbose bool
+	Add     bool // set-url --add
+	Delete  bool // set-url --delete, set-head --delete
+	Auto    bool // set-head --auto
+	NoQuery bool // show -n: do not look at the remote itself
+	DryRun  bool // prune --dry-run
 }
 
 func (c *RemoteCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -33,7 +41,7 @@ func (c *RemoteCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "remote"), nil
 		}
 		return "", err
 	}
@@ -50,20 +58,36 @@ func (c *RemoteCommand) parseArgs(args []string) (*RemoteOptions, error) {
 	opts := &RemoteOptions{}
 	cmdArgs := args[1:]
 
-	// Pre-scan structure: git remote [-v] [subcmd [args]]
+	// Pre-scan structure: git remote [-v] [subcmd [options] [args]]
 	var positional []string
 	for _, arg := range cmdArgs {
-		if arg == "-v" || arg == "--verbose" {
+		switch arg {
+		case "-v", "--verbose":
 			opts.Verbose = true
-		} else if arg == "-h" || arg == "--help" {
+		case "-h", "--help":
 			return nil, fmt.Errorf("help requested")
-		} else if !strings.HasPrefix(arg, "-") {
-			positional = append(positional, arg)
+		case "--add":
+			opts.Add = true
+		case "--delete", "-d":
+			opts.Delete = true
+		case "-a", "--auto":
+			opts.Auto = true
+		case "-n":
+			// show -n skips the query; prune -n is a dry run
+			opts.NoQuery = true
+			opts.DryRun = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if !strings.HasPrefix(arg, "-") {
+				positional = append(positional, arg)
+			}
 		}
 	}
 
 	if len(positional) > 0 {
 		opts.SubCmd = positional[0]
+		opts.Args = positional[1:]
 	}
 	if len(positional) > 1 {
 		opts.Name = positional[1]
@@ -72,20 +96,15 @@ func (c *RemoteCommand) parseArgs(args []string) (*RemoteOptions, error) {
 		opts.URL = positional[2]
 	}
 
-	// For debugging: if it's 'add' but URL is missing, log the positional args
-	// if opts.SubCmd == "add" && opts.URL == "" {
-	// 	fmt.Printf("DEBUG: remote add detected with missing URL. Positional: %v\n", positional)
-	// }
-
 	return opts, nil
 }
 
-func (c *RemoteCommand) executeRemote(_ *git.Session, repo *gogit.Repository, opts *RemoteOptions) (string, error) {
-	if opts.SubCmd == "" {
+func (c *RemoteCommand) executeRemote(s *git.Session, repo *gogit.Repository, opts *RemoteOptions) (string, error) {
+	switch opts.SubCmd {
+	case "":
 		return listRemotes(repo, opts.Verbose)
-	}
 
-	if opts.SubCmd == "add" {
+	case "add":
 		if opts.Name == "" || opts.URL == "" {
 			return "", fmt.Errorf("usage: git remote add <name> <url>")
 		}
@@ -97,102 +116,526 @@ func (c *RemoteCommand) executeRemote(_ *git.Session, repo *gogit.Repository, op
 			return "", err
 		}
 		return "", nil
-	}
 
-	if opts.SubCmd == "remove" || opts.SubCmd == "rm" {
+	case "remove", "rm":
 		if opts.Name == "" {
 			return "", fmt.Errorf("usage: git remote remove <name>")
 		}
-		err := repo.DeleteRemote(opts.Name)
-		if err != nil {
-			return "", err
-		}
-		return "", nil
-	}
+		return "", removeRemote(repo, opts.Name)
 
-	if opts.SubCmd == "rename" {
+	case "rename":
 		if opts.Name == "" || opts.URL == "" {
 			return "", fmt.Errorf("usage: git remote rename <old> <new>")
 		}
-		oldName := opts.Name
-		newName := opts.URL // URL field holds the new name in this context
+		// URL field holds the new name in this context
+		return "", renameRemote(repo, opts.Name, opts.URL)
+
+	case "set-url":
+		if opts.Name == "" || opts.URL == "" {
+			return "", fmt.Errorf("usage: git remote set-url [--add | --delete] <name> <newurl> [<oldurl>]")
+		}
+		return "", setRemoteURL(repo, opts)
 
-		// Get old remote config
-		remote, err := repo.Remote(oldName)
+	case "get-url":
+		if opts.Name == "" {
+			return "", fmt.Errorf("usage: git remote get-url <name>")
+		}
+		remote, err := repo.Remote(opts.Name)
 		if err != nil {
-			return "", fmt.Errorf("error: No such remote: '%s'", oldName)
+			return "", fmt.Errorf("error: No such remote '%s'", opts.Name)
 		}
 		cfg := remote.Config()
+		if opts.Verbose {
+			return strings.Join(cfg.URLs, "\n"), nil
+		}
+		if len(cfg.URLs) > 0 {
+			return cfg.URLs[0], nil
+		}
+		return "", nil
 
-		// Create new remote with same URLs
-		_, err = repo.CreateRemote(&config.RemoteConfig{
-			Name: newName,
-			URLs: cfg.URLs,
-		})
-		if err != nil {
-			return "", err
+	case "show":
+		if len(opts.Args) == 0 {
+			return listRemotes(repo, opts.Verbose)
 		}
+		var out []string
+		for _, name := range opts.Args {
+			res, err := showRemote(s, repo, name, opts.NoQuery)
+			if err != nil {
+				return strings.Join(out, "\n"), err
+			}
+			out = append(out, res)
+		}
+		return strings.Join(out, "\n"), nil
 
-		// Delete old remote
-		err = repo.DeleteRemote(oldName)
-		if err != nil {
-			return "", err
+	case "set-head":
+		modes := 0
+		for _, set := range []bool{opts.Auto, opts.Delete, opts.URL != ""} {
+			if set {
+				modes++
+			}
+		}
+		if opts.Name == "" || modes != 1 {
+			return "", fmt.Errorf("usage: git remote set-head <name> (-a | --auto | -d | --delete | <branch>)")
 		}
+		// URL field holds the branch in this context
+		return setRemoteHead(s, repo, opts.Name, opts.URL, opts)
 
-		return "", nil
+	case "prune":
+		if len(opts.Args) == 0 {
+			return "", fmt.Errorf("usage: git remote prune [-n | --dry-run] <name>...")
+		}
+		var out []string
+		for _, name := range opts.Args {
+			res, err := pruneRemote(s, repo, name, opts.DryRun)
+			if err != nil {
+				return strings.Join(out, "\n"), err
+			}
+			out = append(out, res)
+		}
+		return strings.Join(out, "\n"), nil
 	}
 
-	if opts.SubCmd == "set-url" {
-		if opts.Name == "" || opts.URL == "" {
-			return "", fmt.Errorf("usage: git remote set-url <name> <newurl>")
+	return "", fmt.Errorf("unknown subcommand: %s", opts.SubCmd)
+}
+
+// remoteTrackingPrefix returns the ref namespace holding name's remote-tracking branches.
+func remoteTrackingPrefix(name string) string {
+	return "refs/remotes/" + name + "/"
+}
+
+// removeRemote deletes a remote together with its remote-tracking branches and
+// the upstream settings of the branches that tracked it.
+func removeRemote(repo *gogit.Repository, name string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Remotes[name]; !ok {
+		return fmt.Errorf("error: No such remote: '%s'", name)
+	}
+	delete(cfg.Remotes, name)
+	for _, b := range cfg.Branches {
+		if b.Remote == name {
+			b.Remote = ""
+			b.Merge = ""
 		}
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return err
+	}
 
-		// Get current remote config
-		remote, err := repo.Remote(opts.Name)
+	refs, err := trackingRefs(repo, name)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if err := repo.Storer.RemoveReference(ref.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameRemote renames a remote, moving its remote-tracking branches and
+// rewriting its fetch refspecs and the branches that track it.
+func renameRemote(repo *gogit.Repository, oldName, newName string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	remote, ok := cfg.Remotes[oldName]
+	if !ok {
+		return fmt.Errorf("error: No such remote: '%s'", oldName)
+	}
+	if _, exists := cfg.Remotes[newName]; exists {
+		return fmt.Errorf("error: remote %s already exists.", newName)
+	}
+
+	oldPrefix, newPrefix := remoteTrackingPrefix(oldName), remoteTrackingPrefix(newName)
+	renamed := &config.RemoteConfig{Name: newName, URLs: remote.URLs, Mirror: remote.Mirror}
+	for _, spec := range remote.Fetch {
+		renamed.Fetch = append(renamed.Fetch, config.RefSpec(strings.Replace(spec.String(), ":"+oldPrefix, ":"+newPrefix, 1)))
+	}
+	if err := renamed.Validate(); err != nil {
+		return fmt.Errorf("fatal: '%s' is not a valid remote name", newName)
+	}
+	delete(cfg.Remotes, oldName)
+	cfg.Remotes[newName] = renamed
+	for _, b := range cfg.Branches {
+		if b.Remote == oldName {
+			b.Remote = newName
+		}
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return err
+	}
+
+	refs, err := trackingRefs(repo, oldName)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		newRef := plumbing.ReferenceName(newPrefix + strings.TrimPrefix(ref.Name().String(), oldPrefix))
+		if ref.Type() == plumbing.SymbolicReference {
+			target := plumbing.ReferenceName(strings.Replace(ref.Target().String(), oldPrefix, newPrefix, 1))
+			err = repo.Storer.SetReference(plumbing.NewSymbolicReference(newRef, target))
+		} else {
+			err = repo.Storer.SetReference(plumbing.NewHashReference(newRef, ref.Hash()))
+		}
 		if err != nil {
-			return "", fmt.Errorf("error: No such remote '%s'", opts.Name)
+			return err
+		}
+		if err := repo.Storer.RemoveReference(ref.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setRemoteURL replaces, adds (--add) or deletes (--delete) URLs of a remote.
+// Without --add, <oldurl> picks the URL to replace instead of the first one.
+func setRemoteURL(repo *gogit.Repository, opts *RemoteOptions) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	remote, ok := cfg.Remotes[opts.Name]
+	if !ok {
+		return fmt.Errorf("error: No such remote '%s'", opts.Name)
+	}
+
+	switch {
+	case opts.Add && opts.Delete:
+		return fmt.Errorf("fatal: --add --delete doesn't make sense")
+	case opts.Add:
+		remote.URLs = append(remote.URLs, opts.URL)
+	case opts.Delete:
+		var kept []string
+		for _, u := range remote.URLs {
+			if u != opts.URL {
+				kept = append(kept, u)
+			}
+		}
+		if len(kept) == len(remote.URLs) {
+			return fmt.Errorf("fatal: No such URL found: %s", opts.URL)
+		}
+		if len(kept) == 0 {
+			return fmt.Errorf("fatal: Will not delete all non-push URLs")
+		}
+		remote.URLs = kept
+	default:
+		idx := 0
+		if len(opts.Args) > 2 {
+			idx = -1
+			for i, u := range remote.URLs {
+				if u == opts.Args[2] {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return fmt.Errorf("fatal: No such URL found: %s", opts.Args[2])
+			}
+		}
+		if len(remote.URLs) == 0 {
+			remote.URLs = []string{opts.URL}
+		} else {
+			remote.URLs[idx] = opts.URL
+		}
+	}
+	return repo.Storer.SetConfig(cfg)
+}
+
+// trackingRefs returns the remote-tracking refs of a remote, sorted by name.
+func trackingRefs(repo *gogit.Repository, name string) ([]*plumbing.Reference, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	prefix := remoteTrackingPrefix(name)
+	var refs []*plumbing.Reference
+	_ = iter.ForEach(func(r *plumbing.Reference) error {
+		if strings.HasPrefix(r.Name().String(), prefix) {
+			refs = append(refs, r)
+		}
+		return nil
+	})
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+	return refs, nil
+}
+
+// remoteBranchState compares a remote's branches with the remote-tracking
+// branches kept for it locally.
+type remoteBranchState struct {
+	URL      string
+	Source   *gogit.Repository
+	Branches map[string]plumbing.Hash // Branches on the remote
+	Tracked  map[string]bool          // Remote-tracking branches kept locally
+}
+
+// queryRemote looks up the simulated repository behind a remote and its branches.
+func queryRemote(s *git.Session, repo *gogit.Repository, name string) (*remoteBranchState, error) {
+	rem, err := repo.Remote(name)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: '%s' does not appear to be a git repository", name)
+	}
+	cfg := rem.Config()
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("remote %s has no URL defined", name)
+	}
+	state := &remoteBranchState{
+		URL:      cfg.URLs[0],
+		Branches: make(map[string]plumbing.Hash),
+		Tracked:  make(map[string]bool),
+	}
+	src, err := (&FetchCommand{}).resolveSimulatedRemote(s, state.URL)
+	if err != nil {
+		return nil, err
+	}
+	state.Source = src
+	srcRefs, err := src.References()
+	if err != nil {
+		return nil, err
+	}
+	_ = srcRefs.ForEach(func(r *plumbing.Reference) error {
+		if r.Name().IsBranch() {
+			state.Branches[r.Name().Short()] = r.Hash()
 		}
-		oldCfg := remote.Config()
+		return nil
+	})
 
-		// Delete and recreate with new URL
-		err = repo.DeleteRemote(opts.Name)
+	refs, err := trackingRefs(repo, name)
+	if err != nil {
+		return nil, err
+	}
+	prefix := remoteTrackingPrefix(name)
+	for _, r := range refs {
+		if r.Type() == plumbing.HashReference {
+			state.Tracked[strings.TrimPrefix(r.Name().String(), prefix)] = true
+		}
+	}
+	return state, nil
+}
+
+// Stale returns the remote-tracking branches whose branch is gone from the remote.
+func (st *remoteBranchState) Stale() []string {
+	var stale []string
+	for b := range st.Tracked {
+		if _, ok := st.Branches[b]; !ok {
+			stale = append(stale, b)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// showRemote describes a remote like git remote show <name>. With noQuery only
+// the locally known remote-tracking branches are listed.
+func showRemote(s *git.Session, repo *gogit.Repository, name string, noQuery bool) (string, error) {
+	rem, err := repo.Remote(name)
+	if err != nil {
+		return "", fmt.Errorf("fatal: '%s' does not appear to be a git repository", name)
+	}
+	cfg := rem.Config()
+	url := ""
+	if len(cfg.URLs) > 0 {
+		url = cfg.URLs[0]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("* remote %s\n", name))
+	sb.WriteString(fmt.Sprintf("  Fetch URL: %s\n", url))
+	for _, u := range cfg.URLs {
+		sb.WriteString(fmt.Sprintf("  Push  URL: %s\n", u))
+	}
+
+	if noQuery {
+		sb.WriteString("  HEAD branch: (not queried)\n")
+		refs, err := trackingRefs(repo, name)
 		if err != nil {
 			return "", err
 		}
+		var branches []string
+		for _, r := range refs {
+			if r.Type() == plumbing.HashReference {
+				branches = append(branches, strings.TrimPrefix(r.Name().String(), remoteTrackingPrefix(name)))
+			}
+		}
+		if len(branches) > 0 {
+			sb.WriteString("  Remote branches: (status not queried)\n")
+			for _, b := range branches {
+				sb.WriteString("    " + b + "\n")
+			}
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	st, err := queryRemote(s, repo, name)
+	if err != nil {
+		return "", err
+	}
+	head := "(unknown)"
+	if ref, err := st.Source.Reference(plumbing.HEAD, false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		head = ref.Target().Short()
+	}
+	sb.WriteString(fmt.Sprintf("  HEAD branch: %s\n", head))
+
+	// Remote branches: tracked, new (not fetched yet) or stale (gone from the remote)
+	names := make([]string, 0, len(st.Branches))
+	for b := range st.Branches {
+		names = append(names, b)
+	}
+	stale := st.Stale()
+	names = append(names, stale...)
+	sort.Strings(names)
+	if len(names) > 0 {
+		width := 0
+		for _, b := range names {
+			width = max(width, len(b))
+		}
+		sb.WriteString(plural(len(names), "  Remote branch:\n", "  Remote branches:\n"))
+		for _, b := range names {
+			status := "tracked"
+			if _, onRemote := st.Branches[b]; !onRemote {
+				status = "stale (use 'git remote prune' to remove)"
+			} else if !st.Tracked[b] {
+				status = fmt.Sprintf("new (next fetch will store in remotes/%s)", name)
+			}
+			sb.WriteString(fmt.Sprintf("    %-*s %s\n", width, b, status))
+		}
+	}
 
-		newURLs := []string{opts.URL}
-		// Keep additional URLs if any
-		if len(oldCfg.URLs) > 1 {
-			newURLs = append(newURLs, oldCfg.URLs[1:]...)
+	// Local branches that pull from this remote
+	repoCfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	var pulls []string
+	for _, b := range repoCfg.Branches {
+		if b.Remote == name && b.Merge != "" {
+			pulls = append(pulls, fmt.Sprintf("%s merges with remote %s", b.Name, b.Merge.Short()))
+		}
+	}
+	sort.Strings(pulls)
+	if len(pulls) > 0 {
+		sb.WriteString(plural(len(pulls), "  Local branch configured for 'git pull':\n", "  Local branches configured for 'git pull':\n"))
+		for _, p := range pulls {
+			sb.WriteString("    " + p + "\n")
 		}
+	}
 
-		_, err = repo.CreateRemote(&config.RemoteConfig{
-			Name: opts.Name,
-			URLs: newURLs,
+	// Local branches that have a namesake on the remote, and how a push would go
+	var pushes []string
+	if iter, err := repo.Branches(); err == nil {
+		_ = iter.ForEach(func(r *plumbing.Reference) error {
+			b := r.Name().Short()
+			remoteHash, ok := st.Branches[b]
+			if !ok {
+				return nil
+			}
+			pushes = append(pushes, fmt.Sprintf("%s pushes to %s (%s)", b, b, pushStatus(repo, r.Hash(), remoteHash)))
+			return nil
 		})
-		if err != nil {
-			return "", err
+	}
+	sort.Strings(pushes)
+	if len(pushes) > 0 {
+		sb.WriteString(plural(len(pushes), "  Local ref configured for 'git push':\n", "  Local refs configured for 'git push':\n"))
+		for _, p := range pushes {
+			sb.WriteString("    " + p + "\n")
 		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// pushStatus tells whether pushing local over remote would be a no-op, a fast-forward or rejected.
+func pushStatus(repo *gogit.Repository, local, remote plumbing.Hash) string {
+	if local == remote {
+		return "up to date"
+	}
+	remoteCommit, err := repo.CommitObject(remote)
+	if err != nil {
+		return "local out of date"
+	}
+	localCommit, err := repo.CommitObject(local)
+	if err != nil {
+		return "local out of date"
+	}
+	if ok, err := remoteCommit.IsAncestor(localCommit); err == nil && ok {
+		return "fast-forwardable"
+	}
+	return "local out of date"
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
 
+// pruneRemote deletes the remote-tracking branches whose branch is gone from the remote.
+func pruneRemote(s *git.Session, repo *gogit.Repository, name string, dryRun bool) (string, error) {
+	st, err := queryRemote(s, repo, name)
+	if err != nil {
+		return "", err
+	}
+	stale := st.Stale()
+	if len(stale) == 0 {
 		return "", nil
 	}
 
-	if opts.SubCmd == "get-url" {
-		if opts.Name == "" {
-			return "", fmt.Errorf("usage: git remote get-url <name>")
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Pruning %s\nURL: %s\n", name, st.URL))
+	for _, b := range stale {
+		if dryRun {
+			sb.WriteString(fmt.Sprintf(" * [would prune] %s/%s\n", name, b))
+			continue
 		}
-		remote, err := repo.Remote(opts.Name)
-		if err != nil {
-			return "", fmt.Errorf("error: No such remote '%s'", opts.Name)
+		if err := repo.Storer.RemoveReference(plumbing.ReferenceName(remoteTrackingPrefix(name) + b)); err != nil {
+			return "", err
 		}
-		cfg := remote.Config()
-		if len(cfg.URLs) > 0 {
-			return cfg.URLs[0], nil
+		sb.WriteString(fmt.Sprintf(" * [pruned] %s/%s\n", name, b))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// setRemoteHead sets refs/remotes/<name>/HEAD, the branch "<name>" alone
+// refers to, to branch, to the remote's own default branch with --auto, or
+// deletes it with --delete.
+func setRemoteHead(s *git.Session, repo *gogit.Repository, name, branch string, opts *RemoteOptions) (string, error) {
+	if _, err := repo.Remote(name); err != nil {
+		return "", fmt.Errorf("error: No such remote '%s'", name)
+	}
+	headRef := plumbing.ReferenceName(remoteTrackingPrefix(name) + "HEAD")
+	if opts.Delete {
+		if _, err := repo.Storer.Reference(headRef); err != nil {
+			return "", fmt.Errorf("error: Not a valid ref: %s", headRef)
 		}
-		return "", nil
+		return "", repo.Storer.RemoveReference(headRef)
 	}
 
-	return "", fmt.Errorf("unknown subcommand: %s", opts.SubCmd)
+	if opts.Auto {
+		st, err := queryRemote(s, repo, name)
+		if err != nil {
+			return "", err
+		}
+		head, err := st.Source.Storer.Reference(plumbing.HEAD)
+		if err != nil || head.Type() != plumbing.SymbolicReference {
+			return "", fmt.Errorf("error: Cannot determine remote HEAD")
+		}
+		branch = head.Target().Short()
+	}
+	target := plumbing.ReferenceName(remoteTrackingPrefix(name) + branch)
+	if _, err := repo.Storer.Reference(target); err != nil {
+		return "", fmt.Errorf("error: Not a valid ref: %s", target)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(headRef, target)); err != nil {
+		return "", err
+	}
+	if opts.Auto {
+		return fmt.Sprintf("%s/HEAD set to %s", name, branch), nil
+	}
+	return "", nil
 }
 
 func listRemotes(repo *gogit.Repository, verbose bool) (string, error) {
@@ -230,43 +673,20 @@ func listRemotes(repo *gogit.Repository, verbose bool) (string, error) {
 	return sb.String(), nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *RemoteCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"add", "get-url", "prune", "remove", "rename", "set-head", "set-url", "show"},
+		Options: []git.Option{
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show the URLs"},
+			{Flags: []string{"-a", "--auto"}, Usage: "set-head: use the remote's default branch"},
+			{Flags: []string{"-d", "--delete"}, Usage: "set-head: delete <name>/HEAD"},
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would be pruned"},
+		},
+		Args: []string{git.ArgRemote, git.ArgText},
+	}
+}
+
 func (c *RemoteCommand) Help() string {
-	return `📘 GIT-REMOTE (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    リモートリポジトリ（外部の接続先）に関する以下の操作を行います：
-    ・登録されている接続先の一覧を表示する（引数なし）
-    ・新しい接続先を追加する（add）
-    ・不要な接続先を削除する（remove）
-    ・接続先の名前を変更する（rename）
-    ・接続先のURLを変更する（set-url）
-
- 📋 SYNOPSIS
-    git remote [-v]
-    git remote add <name> <url>
-    git remote remove <name>
-    git remote rename <old> <new>
-    git remote set-url <name> <newurl>
-    git remote get-url <name>
-
- ⚙️  COMMON OPTIONS
-    -v, --verbose
-        URLも含めて詳細に表示します。
-
- 🛠  EXAMPLES
-    1. リモート一覧を表示
-       $ git remote -v
-
-    2. 新しいリモートを追加
-       $ git remote add origin https://github.com/user/repo.git
-
-    3. リモートの名前を変更
-       $ git remote rename origin upstream
-
-    4. リモートのURLを変更
-       $ git remote set-url origin https://github.com/user/new-repo.git
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-remote
-`
+	return git.CommandHelp(context.Background(), "remote")
 }
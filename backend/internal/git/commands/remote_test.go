@@ -70,6 +70,158 @@ func TestRemoteCommand(t *testing.T) {
 	})
 }
 
+func TestRemoteCommand_Management(t *testing.T) {
+	sm := git.NewSessionManager()
+	remoteRepo, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remoteRepo.Worktree()
+	f, _ := rw.Filesystem.Create("README.md")
+	f.Close()
+	rw.Add("README.md")
+	initial, err := rw.Commit("Initial", &gogit.CommitOptions{Author: &object.Signature{Name: "Remote", When: time.Now()}})
+	if err != nil {
+		t.Fatalf("Remote setup commit failed: %v", err)
+	}
+	remoteRepo.Storer.SetReference(plumbing.NewHashReference("refs/heads/old-feature", initial))
+	sm.SharedRemotes["origin"] = remoteRepo
+
+	ctx := context.Background()
+	s, _ := sm.CreateSession("test-remote-management")
+	if _, err := (&CloneCommand{}).Execute(ctx, s, []string{"clone", "origin", "project"}); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	s.CurrentDir = "/project"
+	repo := s.GetRepo()
+	cmd := &RemoteCommand{}
+	run := func(args ...string) string {
+		t.Helper()
+		res, err := cmd.Execute(ctx, s, append([]string{"remote"}, args...))
+		if err != nil {
+			t.Fatalf("git remote %v failed: %v", args, err)
+		}
+		return res
+	}
+
+	// The remote deletes one branch and gains another
+	remoteRepo.Storer.RemoveReference("refs/heads/old-feature")
+	remoteRepo.Storer.SetReference(plumbing.NewHashReference("refs/heads/new-feature", initial))
+
+	t.Run("Show", func(t *testing.T) {
+		res := run("show", "origin")
+		for _, want := range []string{
+			"* remote origin",
+			"Fetch URL: origin",
+			"HEAD branch: master",
+			"master      tracked",
+			"new-feature new (next fetch will store in remotes/origin)",
+			"old-feature stale (use 'git remote prune' to remove)",
+			"master merges with remote master",
+			"master pushes to master (up to date)",
+		} {
+			if !strings.Contains(res, want) {
+				t.Errorf("Expected %q in remote show output:\n%s", want, res)
+			}
+		}
+		if res := run("show", "-n", "origin"); !strings.Contains(res, "HEAD branch: (not queried)") || !strings.Contains(res, "    old-feature") {
+			t.Errorf("Unexpected remote show -n output:\n%s", res)
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		if res := run("prune", "--dry-run", "origin"); !strings.Contains(res, " * [would prune] origin/old-feature") {
+			t.Errorf("Unexpected dry-run output: %s", res)
+		}
+		if _, err := repo.Reference("refs/remotes/origin/old-feature", true); err != nil {
+			t.Error("A dry run should not delete anything")
+		}
+		if res := run("prune", "origin"); !strings.Contains(res, " * [pruned] origin/old-feature") {
+			t.Errorf("Unexpected prune output: %s", res)
+		}
+		if _, err := repo.Reference("refs/remotes/origin/old-feature", true); err == nil {
+			t.Error("Stale remote-tracking branch should be pruned")
+		}
+		if _, err := repo.Reference("refs/remotes/origin/master", true); err != nil {
+			t.Error("Tracked branches must survive a prune")
+		}
+		if res := run("prune", "origin"); res != "" {
+			t.Errorf("Nothing should be left to prune, got %q", res)
+		}
+	})
+
+	t.Run("Set head", func(t *testing.T) {
+		run("set-head", "origin", "master")
+		head, err := repo.Reference("refs/remotes/origin/HEAD", false)
+		if err != nil || head.Target() != "refs/remotes/origin/master" {
+			t.Fatalf("origin/HEAD should point at origin/master, got %v (%v)", head, err)
+		}
+		run("set-head", "origin", "--delete")
+		if _, err := repo.Reference("refs/remotes/origin/HEAD", false); err == nil {
+			t.Error("origin/HEAD should be deleted")
+		}
+		if res := run("set-head", "origin", "-a"); res != "origin/HEAD set to master" {
+			t.Errorf("Unexpected set-head --auto output: %q", res)
+		}
+
+		// The remote's default branch moves to a branch not fetched yet
+		remoteRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/new-feature"))
+		defer remoteRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master"))
+		if _, err := cmd.Execute(ctx, s, []string{"remote", "set-head", "origin", "-a"}); err == nil || !strings.Contains(err.Error(), "Not a valid ref: refs/remotes/origin/new-feature") {
+			t.Errorf("set-head --auto to an unfetched branch should fail, got %v", err)
+		}
+		for _, args := range [][]string{{"set-head", "origin"}, {"set-head", "origin", "-a", "master"}, {"set-head", "origin", "missing"}} {
+			if _, err := cmd.Execute(ctx, s, append([]string{"remote"}, args...)); err == nil {
+				t.Errorf("git remote %v should fail", args)
+			}
+		}
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		if _, err := cmd.Execute(ctx, s, []string{"remote", "rename", "missing", "other"}); err == nil {
+			t.Error("Renaming an unknown remote should fail")
+		}
+		run("rename", "origin", "upstream")
+		if _, err := repo.Reference("refs/remotes/upstream/master", true); err != nil {
+			t.Error("Remote-tracking branches should move with the remote")
+		}
+		if _, err := repo.Reference("refs/remotes/origin/master", true); err == nil {
+			t.Error("Old remote-tracking branches should be gone")
+		}
+		cfg, _ := repo.Config()
+		if got := cfg.Remotes["upstream"].Fetch[0].String(); got != "+refs/heads/*:refs/remotes/upstream/*" {
+			t.Errorf("Fetch refspec not rewritten: %s", got)
+		}
+		if cfg.Branches["master"].Remote != "upstream" {
+			t.Errorf("Branch upstream not rewritten: %s", cfg.Branches["master"].Remote)
+		}
+	})
+
+	t.Run("Set URL", func(t *testing.T) {
+		run("set-url", "--add", "upstream", "mirror")
+		if res := run("get-url", "-v", "upstream"); res != "origin\nmirror" {
+			t.Errorf("Unexpected URLs after --add: %q", res)
+		}
+		run("set-url", "upstream", "backup", "mirror")
+		run("set-url", "--delete", "upstream", "origin")
+		if res := run("get-url", "-v", "upstream"); res != "backup" {
+			t.Errorf("Unexpected URLs after replace and --delete: %q", res)
+		}
+		if _, err := cmd.Execute(ctx, s, []string{"remote", "set-url", "--delete", "upstream", "backup"}); err == nil {
+			t.Error("Deleting the last URL should fail")
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		run("remove", "upstream")
+		refs, _ := trackingRefs(repo, "upstream")
+		if len(refs) != 0 {
+			t.Errorf("Remote-tracking branches should be removed, %d left", len(refs))
+		}
+		cfg, _ := repo.Config()
+		if cfg.Branches["master"].Remote != "" {
+			t.Error("Branches should no longer track the removed remote")
+		}
+	})
+}
+
 func TestFetchCommand(t *testing.T) {
 	// Setup: Session with a local repo and a simulated remote
 	sm := git.NewSessionManager()
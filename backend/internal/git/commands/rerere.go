@@ -0,0 +1,120 @@
+package commands
+
+// rerere.go - Simulated Git Rerere Command
+//
+// Rerere ("reuse recorded resolution") works on its own once rerere.enabled
+// is set: merge, pull, cherry-pick, revert and am record the conflicts they
+// stop on, and resolve a conflict seen before the way it was resolved last
+// time. This command shows what it tracks and takes back a resolution that
+// turned out wrong.
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("rerere", func() git.Command { return &RerereCommand{} })
+}
+
+type RerereCommand struct{}
+
+// Ensure RerereCommand implements git.Command
+var _ git.Command = (*RerereCommand)(nil)
+
+func (c *RerereCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, arg := range args[1:] {
+		if arg == "-h" || arg == "--help" {
+			return git.CommandHelp(ctx, "rerere"), nil
+		}
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if !git.RerereEnabled(repo) {
+		return "hint: rerere is disabled; enable it with 'git config rerere.enabled true'", nil
+	}
+
+	sub := ""
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	rr := s.RerereCache()
+	switch sub {
+	case "":
+		// Record the resolutions of the conflicts resolved so far
+		return strings.Join(git.RerereRecordResolutions(s, repo, w), "\n"), nil
+	case "status":
+		return strings.Join(slices.Sorted(maps.Keys(rr.Pending)), "\n"), nil
+	case "forget":
+		if len(args) < 3 {
+			return "", fmt.Errorf("usage: git rerere forget <pathspec>...")
+		}
+		return c.forget(s, w.Filesystem, args[2:])
+	case "clear":
+		git.RerereClear(s)
+		return "", nil
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: `%s'\nusage: git rerere [clear | forget <pathspec>... | status]", sub)
+	}
+}
+
+// forget drops the recorded resolutions of the tracked conflicts matching
+// pathspecs, keeping their preimages, and puts the conflict markers back in
+// the worktree (what "git checkout -m <path>" would do after forgetting) so
+// the conflict can be resolved again.
+func (c *RerereCommand) forget(s *git.Session, fs billy.Filesystem, pathspecs []string) (string, error) {
+	rr := s.RerereCache()
+	var out []string
+	for _, spec := range pathspecs {
+		spec = strings.TrimSuffix(spec, "/")
+		matched := false
+		for _, path := range slices.Sorted(maps.Keys(rr.Pending)) {
+			if spec != "." && path != spec && !strings.HasPrefix(path, spec+"/") {
+				continue
+			}
+			matched = true
+			res := rr.Resolutions[rr.Pending[path]]
+			if res == nil || !res.Resolved {
+				return "", fmt.Errorf("error: no remembered resolution for '%s'", path)
+			}
+			res.Postimage, res.Resolved = "", false
+			if err := util.WriteFile(fs, path, []byte(res.Conflict), 0644); err != nil {
+				return "", err
+			}
+			out = append(out, fmt.Sprintf("Updated preimage for '%s'", path), fmt.Sprintf("Forgot resolution for '%s'", path))
+		}
+		if !matched {
+			return "", fmt.Errorf("fatal: pathspec '%s' did not match any conflicted file", spec)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *RerereCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"clear", "forget", "status"},
+		Args:        []string{git.ArgPath},
+	}
+}
+
+func (c *RerereCommand) Help() string {
+	return git.CommandHelp(context.Background(), "rerere")
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRerere_RecordsAndReusesResolutions(t *testing.T) {
+	s, err := git.NewSessionManager().CreateSession("test-rerere")
+	require.NoError(t, err)
+	repo, err := s.InitRepo("repo")
+	require.NoError(t, err)
+	s.CurrentDir = "/repo"
+	ctx := context.Background()
+	w, _ := repo.Worktree()
+	commit := func(content string) plumbing.Hash {
+		t.Helper()
+		require.NoError(t, util.WriteFile(w.Filesystem, "app.txt", []byte(content), 0644))
+		_, err := w.Add("app.txt")
+		require.NoError(t, err)
+		hash, err := w.Commit("change", &gogit.CommitOptions{Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}})
+		require.NoError(t, err)
+		return hash
+	}
+	commit("base\n")
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("topic"), Create: true}))
+	topic := commit("topic\n")
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Main}))
+	main := commit("main\n")
+
+	out, err := (&RerereCommand{}).Execute(ctx, s, []string{"rerere", "status"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "rerere is disabled")
+	_, err = (&ConfigCommand{}).Execute(ctx, s, []string{"config", "rerere.enabled", "true"})
+	require.NoError(t, err)
+
+	// The first time, the conflict is recorded and its resolution committed
+	_, err = (&MergeCommand{}).Execute(ctx, s, []string{"merge", "topic"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Recorded preimage for 'app.txt'\nAutomatic merge failed")
+	out, err = (&RerereCommand{}).Execute(ctx, s, []string{"rerere", "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "app.txt", out)
+
+	require.NoError(t, util.WriteFile(w.Filesystem, "app.txt", []byte("main and topic\n"), 0644))
+	_, _ = w.Add("app.txt")
+	out, err = (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Merge topic"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "Recorded resolution for 'app.txt'.")
+	out, _ = (&RerereCommand{}).Execute(ctx, s, []string{"rerere", "status"})
+	assert.Empty(t, out)
+
+	// Redoing the merge, even the other way round, reuses the resolution
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("topic")}))
+	_, err = (&MergeCommand{}).Execute(ctx, s, []string{"merge", main.String()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Resolved 'app.txt' using previous resolution.")
+	content, _ := util.ReadFile(w.Filesystem, "app.txt")
+	assert.Equal(t, "main and topic\n", string(content))
+	_, err = (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Merge main"})
+	require.Error(t, err, "the reused resolution still has to be added")
+
+	// forget puts the conflict back to be resolved again
+	out, err = (&RerereCommand{}).Execute(ctx, s, []string{"rerere", "forget", "app.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "Updated preimage for 'app.txt'\nForgot resolution for 'app.txt'", out)
+	content, _ = util.ReadFile(w.Filesystem, "app.txt")
+	assert.Contains(t, string(content), "<<<<<<< HEAD\ntopic\n=======\nmain\n>>>>>>>")
+	_, err = (&RerereCommand{}).Execute(ctx, s, []string{"rerere", "forget", "app.txt"})
+	assert.ErrorContains(t, err, "no remembered resolution for 'app.txt'")
+
+	// An aborted merge drops the preimage that never got a resolution
+	_, err = (&MergeCommand{}).Execute(ctx, s, []string{"merge", "--abort"})
+	require.NoError(t, err)
+	head, _ := repo.Head()
+	assert.Equal(t, topic, head.Hash())
+	assert.Empty(t, s.RerereCache().Resolutions)
+	assert.Empty(t, s.RerereCache().Pending)
+}
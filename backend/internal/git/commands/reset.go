@@ -3,9 +3,17 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -18,30 +26,52 @@ type ResetCommand struct{}
 // Ensure ResetCommand implements git.Command
 var _ git.Command = (*ResetCommand)(nil)
 
+// Ensure ResetCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*ResetCommand)(nil)
+
+// SupportsDryRun marks reset as simulated by the engine on --dry-run.
+func (c *ResetCommand) SupportsDryRun() {}
+
+// Reset modes besides go-git's soft, mixed and hard
+const (
+	resetMerge = "merge"
+	resetKeep  = "keep"
+)
+
 type ResetOptions struct {
-	Mode   gogit.ResetMode
-	Target string
+	Mode     gogit.ResetMode
+	ModeName string // "soft", "mixed", "hard", "merge" or "keep"
+	Target   string
+	Paths    []string // Pathspecs: only these index entries are reset and HEAD stays
+	Dashed   bool     // Paths came after "--", so they need not exist
 }
 
 func (c *ResetCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository")
+	}
+
 	// 1. Parse Args
-	opts, err := c.parseArgs(args)
+	opts, err := c.parseArgs(repo, args)
 	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "reset"), nil
+		}
 		return "", err
 	}
 
-	repo := s.GetRepo()
-	if repo == nil {
-		return "", fmt.Errorf("fatal: not a git repository")
+	if len(opts.Paths) > 0 {
+		return c.resetPaths(repo, opts)
 	}
 
 	// 2. Resolve Context
-	targetHash, err := repo.ResolveRevision(plumbing.Revision(opts.Target))
+	targetHash, err := git.ResolveRevision(repo, opts.Target)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", opts.Target)
 	}
 
 	w, err := repo.Worktree()
@@ -53,79 +83,401 @@ func (c *ResetCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	return c.executeReset(s, w, targetHash, opts)
 }
 
-func (c *ResetCommand) parseArgs(args []string) (*ResetOptions, error) {
-	opts := &ResetOptions{
-		Mode:   gogit.MixedReset,
-		Target: "HEAD",
+// parseArgs splits the command line into the mode, the commit and the paths.
+// Without "--", the first argument is the commit if it names one, and paths
+// must exist, so a typo is reported rather than taken as a path.
+func (c *ResetCommand) parseArgs(repo *gogit.Repository, args []string) (*ResetOptions, error) {
+	parsed, err := c.Spec().Parse(args[1:])
+	if err != nil {
+		return nil, err
 	}
-	cmdArgs := args[1:]
-
-	for i := 0; i < len(cmdArgs); i++ {
-		arg := cmdArgs[i]
-		switch arg {
+	opts := &ResetOptions{Mode: gogit.MixedReset, ModeName: "mixed", Target: "HEAD", Dashed: parsed.Dashed}
+	modes := 0
+	for _, f := range parsed.Flags {
+		switch f.Name {
 		case "--soft":
-			opts.Mode = gogit.SoftReset
+			opts.Mode, opts.ModeName = gogit.SoftReset, "soft"
 		case "--mixed":
-			opts.Mode = gogit.MixedReset
+			opts.Mode, opts.ModeName = gogit.MixedReset, "mixed"
 		case "--hard":
-			opts.Mode = gogit.HardReset
-		case "-h", "--help":
-			return nil, fmt.Errorf("help requested")
+			opts.Mode, opts.ModeName = gogit.HardReset, "hard"
+		case "--merge":
+			opts.ModeName = resetMerge
+		case "--keep":
+			opts.ModeName = resetKeep
 		default:
-			opts.Target = arg
+			continue
 		}
+		modes++
+	}
+	if modes > 1 {
+		return nil, fmt.Errorf("fatal: --soft, --mixed, --hard, --merge and --keep are mutually exclusive")
+	}
+
+	positional := parsed.Positional
+	if len(positional) > 0 {
+		if _, err := git.ResolveRevision(repo, positional[0]); err == nil {
+			opts.Target = positional[0]
+			positional = positional[1:]
+		} else if parsed.Dashed {
+			return nil, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", positional[0])
+		}
+	}
+	for _, p := range positional {
+		if !resetPathExists(repo, p) {
+			return nil, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.\nUse '--' to separate paths from revisions, like this:\n'git <command> [<revision>...] -- [<file>...]'", p)
+		}
+	}
+	opts.Paths = append(positional, parsed.Rest...)
+
+	if len(opts.Paths) > 0 && opts.ModeName != "mixed" {
+		return nil, fmt.Errorf("fatal: Cannot do %s reset with paths.", opts.ModeName)
 	}
 	return opts, nil
 }
 
+// resetPathExists reports whether p names something in the index, HEAD or the working tree.
+func resetPathExists(repo *gogit.Repository, p string) bool {
+	if idx, err := repo.Storer.Index(); err == nil {
+		for _, e := range idx.Entries {
+			if matchesPathspec(e.Name, []string{p}) {
+				return true
+			}
+		}
+	}
+	return logPathExists(repo, p)
+}
+
+// resetPaths copies the entries of paths from the commit to the index, like
+// git restore --staged --source=<commit>. HEAD and the working tree stay.
+func (c *ResetCommand) resetPaths(repo *gogit.Repository, opts *ResetOptions) (string, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return "", err
+	}
+	restore := &RestoreCommand{}
+	source, _, err := restore.readSource(repo, idx, &RestoreOptions{Source: opts.Target, Staged: true})
+	if err != nil {
+		return "", err
+	}
+
+	candidates := make(map[string]bool)
+	for name := range source {
+		candidates[name] = true
+	}
+	for _, e := range idx.Entries {
+		candidates[e.Name] = true
+	}
+	var targets []string
+	for name := range candidates {
+		if matchesPathspec(name, opts.Paths) {
+			targets = append(targets, name)
+		}
+	}
+	sort.Strings(targets)
+
+	restore.restoreIndex(idx, source, targets)
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return "", err
+	}
+	return unstagedChangesReport(repo), nil
+}
+
+// unstagedChangesReport lists the tracked files whose working tree copy differs
+// from the index, as git reset prints after touching the index.
+func unstagedChangesReport(repo *gogit.Repository) string {
+	w, err := repo.Worktree()
+	if err != nil {
+		return ""
+	}
+	status, err := w.Status()
+	if err != nil {
+		return ""
+	}
+	var lines []string
+	for name, st := range status {
+		switch st.Worktree {
+		case gogit.Modified:
+			lines = append(lines, "M\t"+name)
+		case gogit.Deleted:
+			lines = append(lines, "D\t"+name)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	return "Unstaged changes after reset:\n" + strings.Join(lines, "\n")
+}
+
 func (c *ResetCommand) executeReset(s *git.Session, w *gogit.Worktree, targetHash *plumbing.Hash, opts *ResetOptions) (string, error) {
+	repo := s.GetRepo()
+	var safe *resetPlan
+	if opts.ModeName == resetMerge || opts.ModeName == resetKeep {
+		var err error
+		if safe, err = planSafeReset(repo, w, *targetHash, opts); err != nil {
+			return "", err
+		}
+	}
+
 	// Update ORIG_HEAD before reset
 	s.UpdateOrigHead()
 
-	if err := w.Reset(&gogit.ResetOptions{
+	if safe != nil {
+		if err := safe.apply(repo, w, *targetHash); err != nil {
+			return "", err
+		}
+	} else if err := w.Reset(&gogit.ResetOptions{
 		Commit: *targetHash,
 		Mode:   opts.Mode,
 	}); err != nil {
 		return "", err
 	}
+	if opts.Mode != gogit.SoftReset {
+		if _, err := git.ApplySparseCheckout(repo); err != nil {
+			return "", err
+		}
+	}
 	s.RecordReflog(fmt.Sprintf("reset: moving to %s", opts.Target))
 
 	return fmt.Sprintf("HEAD is now at %s", targetHash.String()[:7]), nil
 }
 
-func (c *ResetCommand) Help() string {
-	return `📘 GIT-RESET (1)                                        Git Manual
+// resetEntry is the content of a path on one side of a reset; the zero value
+// means the path is absent.
+type resetEntry struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
 
- 💡 DESCRIPTION
-    ・コミットをなかったことにして、過去の状態に戻る（HEADを移動する）
-    ・ステージングした変更を取り消す（Unstage）
-    ・作業中の変更をすべて破棄して元に戻す（Hard Reset）
-    オプションによって、インデックスやワーキングツリーの状態をどう扱うかが変わります。
+// resetPlan is what a --merge or --keep reset writes: the target content of
+// every path, and the paths whose working tree copy is left alone.
+type resetPlan struct {
+	target map[string]resetEntry
+	keep   map[string]bool
+}
+
+// planSafeReset decides, path by path, what --merge or --keep does, and
+// refuses when local changes would be lost. For each path W, I, H and T are
+// its working tree, index, HEAD and target versions:
+//
+//	--merge: unstaged changes (W != I) survive when I == H == T, and make the
+//	         reset fail otherwise; anything else becomes T, staged changes
+//	         included. Unmerged paths become T.
+//	--keep:  local changes (W or I != H) survive when H == T, and make the
+//	         reset fail otherwise; anything else becomes T.
+func planSafeReset(repo *gogit.Repository, w *gogit.Worktree, target plumbing.Hash, opts *ResetOptions) (*resetPlan, error) {
+	head := make(map[string]resetEntry)
+	if ref, err := repo.Head(); err == nil {
+		if err := readTreeEntries(repo, ref.Hash(), head); err != nil {
+			return nil, err
+		}
+	}
+	plan := &resetPlan{target: make(map[string]resetEntry), keep: make(map[string]bool)}
+	if err := readTreeEntries(repo, target, plan.target); err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	staged := make(map[string]resetEntry)
+	unmerged := make(map[string]bool)
+	for _, e := range idx.Entries {
+		if e.Stage != 0 {
+			unmerged[e.Name] = true
+			continue
+		}
+		staged[e.Name] = resetEntry{hash: e.Hash, mode: e.Mode}
+	}
 
- 📋 SYNOPSIS
-    git reset [--soft | --mixed | --hard] <commit>
+	paths := make(map[string]bool)
+	for _, m := range []map[string]resetEntry{head, plan.target, staged} {
+		for name := range m {
+			paths[name] = true
+		}
+	}
+	for name := range unmerged {
+		paths[name] = true
+	}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
- ⚙️  COMMON OPTIONS
-    --soft
-        HEADのみを移動します。インデックスとワーキングツリーは変更しません。
-        （戻った分のコミット内容は「ステージング済み」として残ります）
+	var problems []string
+	for _, name := range names {
+		h, t, i := head[name], plan.target[name], staged[name]
+		if unmerged[name] {
+			if opts.ModeName == resetKeep {
+				problems = append(problems, fmt.Sprintf("error: Entry '%s' would be overwritten by merge. Cannot merge.", name))
+			}
+			continue
+		}
+		wt, err := worktreeEntry(w, name)
+		if err != nil {
+			return nil, err
+		}
+		untracked := i.hash.IsZero() && !wt.hash.IsZero()
 
-    --mixed (default)
-        HEADとインデックスを移動します。ワーキングツリーは変更しません。
-        （戻った分のコミット内容は「未ステージ」として残ります）
+		switch opts.ModeName {
+		case resetMerge:
+			if wt.hash == i.hash {
+				continue
+			}
+			if i.hash == h.hash && h.hash == t.hash {
+				plan.keep[name] = true
+				continue
+			}
+		case resetKeep:
+			if wt.hash == h.hash && i.hash == h.hash {
+				continue
+			}
+			if h.hash == t.hash {
+				plan.keep[name] = true
+				continue
+			}
+		}
+		switch {
+		case untracked:
+			problems = append(problems, fmt.Sprintf("error: Untracked working tree file '%s' would be overwritten by merge.", name))
+		case opts.ModeName == resetMerge:
+			problems = append(problems, fmt.Sprintf("error: Entry '%s' not uptodate. Cannot merge.", name))
+		default:
+			problems = append(problems, fmt.Sprintf("error: Entry '%s' would be overwritten by merge. Cannot merge.", name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s\nfatal: Could not reset index file to revision '%s'.", strings.Join(problems, "\n"), opts.Target)
+	}
+	return plan, nil
+}
 
-    --hard
-        HEAD、インデックス、ワーキングツリーすべてを強制的に移動します。
-        未コミットの変更はすべて破棄されます。
+// apply moves HEAD to target, makes the index match it and writes the target
+// content of every path not kept to the working tree.
+func (p *resetPlan) apply(repo *gogit.Repository, w *gogit.Worktree, target plumbing.Hash) error {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	var old []string
+	for _, e := range idx.Entries {
+		old = append(old, e.Name)
+	}
 
- 🛠  EXAMPLES
-    1. 直前のコミットを取り消す（変更はそのまま残す）
-       $ git reset HEAD~1
+	if err := w.Reset(&gogit.ResetOptions{Commit: target, Mode: gogit.SoftReset}); err != nil {
+		return err
+	}
 
-    2. 全てを強制的に以前の状態に戻す（危険）
-       $ git reset --hard HEAD~1
+	names := make([]string, 0, len(p.target))
+	for name := range p.target {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]*index.Entry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, &index.Entry{Name: name, Hash: p.target[name].hash, Mode: p.target[name].mode})
+	}
+	idx.Entries = entries
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return err
+	}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-reset
-`
+	for _, name := range old {
+		if _, ok := p.target[name]; !ok && !p.keep[name] {
+			if err := w.Filesystem.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	for _, name := range names {
+		if p.keep[name] {
+			continue
+		}
+		if err := writeBlob(repo, w, name, p.target[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTreeEntries adds the files of the tree of commit hash to entries.
+func readTreeEntries(repo *gogit.Repository, hash plumbing.Hash, entries map[string]resetEntry) error {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(f *object.File) error {
+		entries[f.Name] = resetEntry{hash: f.Hash, mode: f.Mode}
+		return nil
+	})
+}
+
+// worktreeEntry hashes the working tree copy of name, or returns the zero
+// entry when there is none.
+func worktreeEntry(w *gogit.Worktree, name string) (resetEntry, error) {
+	f, err := w.Filesystem.Open(name)
+	if err != nil {
+		return resetEntry{}, nil // Missing, or a directory where the file was
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return resetEntry{}, err
+	}
+	return resetEntry{hash: plumbing.ComputeHash(plumbing.BlobObject, content), mode: filemode.Regular}, nil
+}
+
+// writeBlob writes the blob of entry to name in the working tree.
+func writeBlob(repo *gogit.Repository, w *gogit.Worktree, name string, entry resetEntry) error {
+	blob, err := repo.BlobObject(entry.hash)
+	if err != nil {
+		return fmt.Errorf("failed to read blob %s: %w", entry.hash, err)
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if dir := path.Dir(name); dir != "." {
+		if err := w.Filesystem.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	perm := os.FileMode(0644)
+	if entry.mode == filemode.Executable {
+		perm = 0755
+	}
+	f, err := w.Filesystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Spec implements git.SpecProvider.
+func (c *ResetCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--soft"}, Usage: "Keep the index and working tree"},
+			{Flags: []string{"--mixed"}, Usage: "Reset the index, keep the working tree"},
+			{Flags: []string{"--hard"}, Usage: "Reset the index and working tree"},
+			{Flags: []string{"--merge"}, Usage: "Reset, keeping unstaged changes; refuse if they would be lost"},
+			{Flags: []string{"--keep"}, Usage: "Reset, keeping local changes; refuse if they would be lost"},
+		},
+		Args: []string{git.ArgRef, git.ArgPath},
+	}
+}
+
+func (c *ResetCommand) Help() string {
+	return git.CommandHelp(context.Background(), "reset")
 }
@@ -70,3 +70,184 @@ func TestResetCommand(t *testing.T) {
 		}
 	})
 }
+
+// resetTestFile writes content to name in the worktree of s.
+func resetTestFile(t *testing.T, s *git.Session, name, content string) {
+	t.Helper()
+	w, _ := s.GetRepo().Worktree()
+	f, err := w.Filesystem.Create(name)
+	if err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	f.Write([]byte(content))
+	f.Close()
+}
+
+// resetTestRead returns the worktree content of name, or "" when missing.
+func resetTestRead(s *git.Session, name string) string {
+	w, _ := s.GetRepo().Worktree()
+	f, err := w.Filesystem.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var b strings.Builder
+	buf := make([]byte, 512)
+	for {
+		n, err := f.Read(buf)
+		b.Write(buf[:n])
+		if err != nil {
+			return b.String()
+		}
+	}
+}
+
+func TestReset_Pathspec(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-reset-paths")
+	repo := s.GetRepo()
+	ctx := context.Background()
+	commitFile(t, repo, "a.txt", "a1", "add a")
+	head, _ := repo.Head()
+
+	resetTestFile(t, s, "a.txt", "a2")
+	resetTestFile(t, s, "b.txt", "b")
+	w, _ := repo.Worktree()
+	w.Add("a.txt")
+	w.Add("b.txt")
+
+	out, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "a.txt"})
+	if err != nil {
+		t.Fatalf("reset a.txt: %v", err)
+	}
+	if !strings.Contains(out, "Unstaged changes after reset:\nM\ta.txt") {
+		t.Errorf("unexpected output: %q", out)
+	}
+	status, _ := w.Status()
+	if st := status.File("a.txt"); st.Staging != gogit.Unmodified || st.Worktree != gogit.Modified {
+		t.Errorf("a.txt should be unstaged but modified, got %c%c", st.Staging, st.Worktree)
+	}
+	if st := status.File("b.txt"); st.Staging != gogit.Added {
+		t.Errorf("b.txt should stay staged, got %c", st.Staging)
+	}
+	if resetTestRead(s, "a.txt") != "a2" {
+		t.Error("the working tree copy of a.txt should be left alone")
+	}
+	if now, _ := repo.Head(); now.Hash() != head.Hash() {
+		t.Error("HEAD should not move when resetting paths")
+	}
+
+	// A newly added file is dropped from the index
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--", "b.txt"}); err != nil {
+		t.Fatalf("reset -- b.txt: %v", err)
+	}
+	status, _ = w.Status()
+	if st := status.File("b.txt"); st.Staging != gogit.Untracked {
+		t.Errorf("b.txt should be untracked, got %c", st.Staging)
+	}
+
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "nope.txt"}); err == nil || !strings.Contains(err.Error(), "ambiguous argument 'nope.txt'") {
+		t.Errorf("expected an ambiguous argument error, got %v", err)
+	}
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--hard", "HEAD", "a.txt"}); err == nil || !strings.Contains(err.Error(), "Cannot do hard reset with paths.") {
+		t.Errorf("expected a mode-with-paths error, got %v", err)
+	}
+}
+
+func TestReset_CommitPathspec(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-reset-commit-paths")
+	repo := s.GetRepo()
+	commitFile(t, repo, "a.txt", "old", "add a")
+	commitFile(t, repo, "a.txt", "new", "change a")
+
+	if _, err := (&ResetCommand{}).Execute(context.Background(), s, []string{"reset", "HEAD~1", "--", "a.txt"}); err != nil {
+		t.Fatalf("reset HEAD~1 -- a.txt: %v", err)
+	}
+	w, _ := repo.Worktree()
+	status, _ := w.Status()
+	if st := status.File("a.txt"); st.Staging != gogit.Modified || st.Worktree != gogit.Modified {
+		t.Errorf("a.txt should be staged back to the old content, got %c%c", st.Staging, st.Worktree)
+	}
+	if resetTestRead(s, "a.txt") != "new" {
+		t.Error("the working tree copy of a.txt should be left alone")
+	}
+}
+
+func TestReset_Keep(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-reset-keep")
+	repo := s.GetRepo()
+	ctx := context.Background()
+	commitFile(t, repo, "a.txt", "a1", "add a")
+	commitFile(t, repo, "a.txt", "a2", "change a")
+	head, _ := repo.Head()
+
+	// A local change to a file the reset would rewrite is refused
+	resetTestFile(t, s, "a.txt", "mine")
+	_, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--keep", "HEAD~1"})
+	if err == nil || !strings.Contains(err.Error(), "error: Entry 'a.txt' would be overwritten by merge. Cannot merge.") {
+		t.Fatalf("expected --keep to refuse, got %v", err)
+	}
+	if now, _ := repo.Head(); now.Hash() != head.Hash() || resetTestRead(s, "a.txt") != "mine" {
+		t.Fatal("a refused reset should change nothing")
+	}
+
+	// A local change to a file the reset leaves alone is kept
+	resetTestFile(t, s, "a.txt", "a2")
+	resetTestFile(t, s, "file.txt", "edited")
+	out, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--keep", "HEAD~1"})
+	if err != nil {
+		t.Fatalf("reset --keep: %v", err)
+	}
+	if !strings.Contains(out, "HEAD is now at") {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if got := resetTestRead(s, "a.txt"); got != "a1" {
+		t.Errorf("a.txt should be reset to a1, got %q", got)
+	}
+	if got := resetTestRead(s, "file.txt"); got != "edited" {
+		t.Errorf("the local change to file.txt should be kept, got %q", got)
+	}
+}
+
+func TestReset_Merge(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-reset-merge")
+	repo := s.GetRepo()
+	ctx := context.Background()
+	commitFile(t, repo, "a.txt", "a1", "add a")
+	commitFile(t, repo, "a.txt", "a2", "change a")
+	commitFile(t, repo, "b.txt", "b", "add b")
+
+	// An unstaged change to a file the reset would rewrite is refused
+	resetTestFile(t, s, "a.txt", "mine")
+	_, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--merge", "HEAD~2"})
+	if err == nil || !strings.Contains(err.Error(), "error: Entry 'a.txt' not uptodate. Cannot merge.") ||
+		!strings.Contains(err.Error(), "fatal: Could not reset index file to revision 'HEAD~2'.") {
+		t.Fatalf("expected --merge to refuse, got %v", err)
+	}
+
+	// Staged changes are thrown away, unstaged ones to untouched files kept
+	resetTestFile(t, s, "a.txt", "a2")
+	resetTestFile(t, s, "b.txt", "staged")
+	w, _ := repo.Worktree()
+	w.Add("b.txt")
+	resetTestFile(t, s, "file.txt", "edited")
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--merge", "HEAD~2"}); err != nil {
+		t.Fatalf("reset --merge: %v", err)
+	}
+	if got := resetTestRead(s, "a.txt"); got != "a1" {
+		t.Errorf("a.txt should be reset to a1, got %q", got)
+	}
+	if got := resetTestRead(s, "b.txt"); got != "" {
+		t.Errorf("b.txt is not in the target and should be removed, got %q", got)
+	}
+	if got := resetTestRead(s, "file.txt"); got != "edited" {
+		t.Errorf("the unstaged change to file.txt should be kept, got %q", got)
+	}
+	status, _ := w.Status()
+	if st := status.File("file.txt"); st.Staging != gogit.Unmodified || st.Worktree != gogit.Modified {
+		t.Errorf("file.txt should stay modified and unstaged, got %c%c", st.Staging, st.Worktree)
+	}
+}
@@ -3,12 +3,16 @@ package commands
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -21,279 +25,263 @@ type RestoreCommand struct{}
 // Ensure RestoreCommand implements git.Command
 var _ git.Command = (*RestoreCommand)(nil)
 
+type RestoreOptions struct {
+	Source   string // --source: tree-ish to restore from; empty means the default
+	Staged   bool   // Restore the index
+	Worktree bool   // Restore the working tree (the default when neither is given)
+	Paths    []string
+}
+
+// restoreEntry is the content of one path in the restore source.
+type restoreEntry struct {
+	Hash plumbing.Hash
+	Mode filemode.FileMode
+}
+
 func (c *RestoreCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "restore"), nil
+		}
+		return "", err
+	}
+
 	repo := s.GetRepo()
 	if repo == nil {
 		return "", fmt.Errorf("fatal: not a git repository")
 	}
-
-	staged := false
-	var files []string
-
-	// Basic parsing
-	for _, arg := range args {
-		if arg == "restore" {
-			continue
-		}
-		if arg == "--staged" {
-			staged = true
-			continue
-		}
-		if strings.HasPrefix(arg, "-") {
-			continue // ignore other flags
-		}
-		files = append(files, arg)
-	}
-
-	if len(files) == 0 {
-		return "", fmt.Errorf("fatal: you must specify path(s) to restore")
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return "", err
 	}
 
-	// 1. Expand Pathspecs
-	targets, err := c.expandPathspecs(repo, files)
+	source, sourceName, err := c.readSource(repo, idx, opts)
 	if err != nil {
 		return "", err
 	}
-	if len(targets) == 0 {
-		// If original files contained ".", it means we wanted everything but found nothing
-		for _, f := range files {
-			if f == "." {
-				return "Nothing to restore (no tracked files found)", nil
-			}
+
+	// Paths to restore: those in the source, plus the tracked ones, which are
+	// removed when a commit source does not have them
+	candidates := make(map[string]bool)
+	for name := range source {
+		candidates[name] = true
+	}
+	if sourceName != "index" {
+		for _, e := range idx.Entries {
+			candidates[e.Name] = true
 		}
 	}
-
-	// 2. Dispatch
-	if staged {
-		return c.restoreStaged(repo, targets, len(targets) > len(files)) // heuristics for "all" message
-	} else {
-		return c.restoreWorktree(repo, targets, len(targets) > len(files))
+	var targets []string
+	for _, p := range opts.Paths {
+		matched := false
+		for name := range candidates {
+			if matchesPathspec(name, []string{p}) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", fmt.Errorf("error: pathspec '%s' did not match any file(s) known to git", p)
+		}
 	}
-}
-
-// expandPathspecs resolves "." to all files in index, otherwise returns files as-is
-func (c *RestoreCommand) expandPathspecs(repo *gogit.Repository, files []string) ([]string, error) {
-	idx, err := repo.Storer.Index()
-	if err != nil {
-		return nil, err
+	for name := range candidates {
+		if matchesPathspec(name, opts.Paths) {
+			targets = append(targets, name)
+		}
 	}
+	sort.Strings(targets)
 
-	containDot := false
-	for _, f := range files {
-		if f == "." {
-			containDot = true
-			break
+	if opts.Staged {
+		c.restoreIndex(idx, source, targets)
+		if err := repo.Storer.SetIndex(idx); err != nil {
+			return "", err
 		}
 	}
-
-	if containDot {
-		var targets []string
-		// In GitGym, operations are generally at repo root.
-		// If we support subdirectories later, we need to calculate path relative to Repo Root.
-		// For now, assume '.' implies everything in the repo (recursive).
-		for _, e := range idx.Entries {
-			targets = append(targets, e.Name)
+	if opts.Worktree {
+		if err := c.restoreWorktree(repo, source, targets); err != nil {
+			return "", err
 		}
-		return targets, nil
 	}
-
-	return files, nil
+	return c.summary(opts, sourceName, len(targets)), nil
 }
 
-func (c *RestoreCommand) restoreStaged(repo *gogit.Repository, files []string, isMassOperation bool) (string, error) {
-	headRef, err := repo.Head()
-	if err != nil {
-		// No HEAD (initial commit?), unstaging means removing from index
-		idx, idxErr := repo.Storer.Index()
-		if idxErr != nil {
-			return "", idxErr
+func (c *RestoreCommand) parseArgs(args []string) (*RestoreOptions, error) {
+	opts := &RestoreOptions{}
+	cmdArgs := args[1:]
+	for i := 0; i < len(cmdArgs); i++ {
+		arg := cmdArgs[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--":
+			opts.Paths = append(opts.Paths, cmdArgs[i+1:]...)
+			i = len(cmdArgs)
+		case arg == "-S" || arg == "--staged":
+			opts.Staged = true
+		case arg == "-W" || arg == "--worktree":
+			opts.Worktree = true
+		case arg == "-SW" || arg == "-WS":
+			opts.Staged, opts.Worktree = true, true
+		case arg == "-s" || arg == "--source":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("error: option `source' requires a value")
+			}
+			i++
+			opts.Source = cmdArgs[i]
+		case strings.HasPrefix(arg, "--source="):
+			opts.Source = strings.TrimPrefix(arg, "--source=")
+		case strings.HasPrefix(arg, "-s") && len(arg) > 2:
+			opts.Source = arg[2:]
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, fmt.Errorf("error: unknown option `%s'\nusage: git restore [<options>] [--source=<branch>] <file>...", strings.TrimLeft(arg, "-"))
+		default:
+			opts.Paths = append(opts.Paths, arg)
 		}
+	}
+	if !opts.Staged && !opts.Worktree {
+		opts.Worktree = true
+	}
+	if len(opts.Paths) == 0 {
+		return nil, fmt.Errorf("fatal: you must specify path(s) to restore")
+	}
+	return opts, nil
+}
 
-		count := 0
-		for _, file := range files {
-			// Remove file from index entries
-			// Note: This is O(N*M) naive implementation.
-			newEntries := make([]*index.Entry, 0, len(idx.Entries))
-			found := false
-			for _, e := range idx.Entries {
-				if e.Name != file {
-					newEntries = append(newEntries, e)
-				} else {
-					found = true
-				}
-			}
-			if found {
-				idx.Entries = newEntries
-				count++
+// readSource lists the files of the restore source: --source if given, HEAD
+// when the index is restored, the index otherwise. An unborn HEAD is empty.
+func (c *RestoreCommand) readSource(repo *gogit.Repository, idx *index.Index, opts *RestoreOptions) (map[string]restoreEntry, string, error) {
+	source := make(map[string]restoreEntry)
+	name := opts.Source
+	if name == "" && !opts.Staged {
+		for _, e := range idx.Entries {
+			if e.Stage == 0 { // Conflict stages are left alone; go-git's index.Merged is not 0
+				source[e.Name] = restoreEntry{Hash: e.Hash, Mode: e.Mode}
 			}
 		}
-		_ = repo.Storer.SetIndex(idx)
-		return fmt.Sprintf("Unstaged files from initial commit (%d files)", count), nil
+		return source, "index", nil
+	}
+	if name == "" {
+		name = "HEAD"
+		if _, err := repo.Head(); err != nil {
+			return source, name, nil
+		}
 	}
 
-	// HEAD exists
-	commit, err := repo.CommitObject(headRef.Hash())
+	hash, err := git.ResolveRevision(repo, name)
 	if err != nil {
-		return "", err
+		return nil, "", fmt.Errorf("fatal: could not resolve %s", name)
 	}
-
-	tree, err := commit.Tree()
+	commit, err := repo.CommitObject(*hash)
 	if err != nil {
-		return "", err
+		return nil, "", fmt.Errorf("fatal: reference is not a tree: %s", name)
 	}
-
-	idx, err := repo.Storer.Index()
+	tree, err := commit.Tree()
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		source[f.Name] = restoreEntry{Hash: f.Hash, Mode: f.Mode}
+		return nil
+	})
+	return source, name, err
+}
 
-	successCount := 0
-	for _, file := range files {
-		// 1. Check if file exists in HEAD
-		entry, err := tree.File(file)
-		if err != nil {
-			// File not in HEAD (new file). Remove from Index.
-			newEntries := make([]*index.Entry, 0, len(idx.Entries))
-			found := false
-			for _, e := range idx.Entries {
-				if e.Name != file {
-					newEntries = append(newEntries, e)
-				} else {
-					found = true
-				}
-			}
-			if found {
-				idx.Entries = newEntries
-				successCount++
-			}
-			continue
-		}
-
-		// 2. File exists in HEAD. Update Index.
-		foundInIndex := false
-		for i, e := range idx.Entries {
-			if e.Name == file {
-				e.Hash = entry.Hash
-				e.Mode = entry.Mode
-				idx.Entries[i] = e
-				foundInIndex = true
-				successCount++
-				break
-			}
-		}
-		if !foundInIndex {
-			// Add back to index if missing
-			idx.Entries = append(idx.Entries, &index.Entry{
-				Name: file,
-				Hash: entry.Hash,
-				Mode: entry.Mode,
-			})
-			successCount++
-		}
+// restoreIndex makes the index entries of targets match the source.
+func (c *RestoreCommand) restoreIndex(idx *index.Index, source map[string]restoreEntry, targets []string) {
+	restored := make(map[string]bool, len(targets))
+	for _, name := range targets {
+		restored[name] = true
 	}
-
-	err = repo.Storer.SetIndex(idx)
-	if err != nil {
-		return "", err
+	entries := make([]*index.Entry, 0, len(idx.Entries))
+	for _, e := range idx.Entries {
+		if !restored[e.Name] {
+			entries = append(entries, e)
+		}
 	}
-
-	if isMassOperation {
-		return fmt.Sprintf("Unstaged all files in current directory (%d files)", successCount), nil
+	for _, name := range targets {
+		if entry, ok := source[name]; ok {
+			entries = append(entries, &index.Entry{Name: name, Hash: entry.Hash, Mode: entry.Mode})
+		}
 	}
-	return "Unstaged files", nil
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	idx.Entries = entries
 }
 
-func (c *RestoreCommand) restoreWorktree(repo *gogit.Repository, files []string, isMassOperation bool) (string, error) {
+// restoreWorktree writes the source content of targets to the working tree
+// and removes the targets the source does not have.
+func (c *RestoreCommand) restoreWorktree(repo *gogit.Repository, source map[string]restoreEntry, targets []string) error {
 	w, err := repo.Worktree()
 	if err != nil {
-		return "", err
-	}
-
-	idx, err := repo.Storer.Index()
-	if err != nil {
-		return "", err
+		return err
 	}
-
-	restoredCount := 0
-	for _, file := range files {
-		var entry *index.Entry
-		for _, e := range idx.Entries {
-			if e.Name == file {
-				entry = e
-				break
-			}
-		}
-
-		if entry == nil {
-			// If explicitly requested but not in index, error
-			if !isMassOperation {
-				return "", fmt.Errorf("pathspec '%s' did not match any file(s) known to git", file)
+	for _, name := range targets {
+		entry, ok := source[name]
+		if !ok {
+			if err := w.Filesystem.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
 			}
 			continue
 		}
-
-		blob, err := repo.BlobObject(entry.Hash)
+		blob, err := repo.Storer.EncodedObject(plumbing.BlobObject, entry.Hash)
 		if err != nil {
-			return "", fmt.Errorf("failed to read blob %s: %w", entry.Hash, err)
+			return fmt.Errorf("failed to read blob %s: %w", entry.Hash, err)
 		}
-		reader, err := blob.Reader()
+		content, err := readObject(blob)
 		if err != nil {
-			return "", err
+			return err
 		}
-		defer reader.Close()
-
-		f, err := w.Filesystem.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-		if err != nil {
-			return "", err
+		perm := os.FileMode(0644)
+		if entry.Mode == filemode.Executable {
+			perm = 0755
 		}
-
-		if _, err := io.Copy(f, reader); err != nil {
-			_ = f.Close()
-			return "", err
+		if dir := path.Dir(name); dir != "." {
+			if err := w.Filesystem.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		f, err := w.Filesystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+		if err != nil {
+			return err
 		}
+		_, err = f.Write([]byte(content))
 		_ = f.Close()
-		restoredCount++
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if isMassOperation {
-		return fmt.Sprintf("Restored all tracked files in current directory (%d files)", restoredCount), nil
+// summary describes what was restored; git itself prints nothing.
+func (c *RestoreCommand) summary(opts *RestoreOptions, sourceName string, count int) string {
+	switch {
+	case opts.Staged && opts.Worktree:
+		return fmt.Sprintf("Restored %d file(s) in the index and working tree from %s", count, sourceName)
+	case opts.Staged && opts.Source == "":
+		return fmt.Sprintf("Unstaged %d file(s)", count)
+	case opts.Staged:
+		return fmt.Sprintf("Restored %d file(s) in the index from %s", count, sourceName)
+	default:
+		return fmt.Sprintf("Restored %d file(s) in the working tree from %s", count, sourceName)
 	}
-	return "Restored files in worktree", nil
 }
 
-func (c *RestoreCommand) Help() string {
-	return `📘 GIT-RESTORE (1)                                      Git Manual
-
- 💡 DESCRIPTION
-    ・ファイルの変更を破棄して、元の状態に戻す
-    ・ステージングした変更を取り消す（--staged）
-    
-    「編集をやり直したい」時や「addを取り消したい」時に使います。
-
- 📋 SYNOPSIS
-    git restore [<options>] <pathspec>...
-
- ⚙️  COMMON OPTIONS
-    --staged
-        ワーキングツリーではなく、インデックス（ステージングエリア）を復元します。
-        ` + "`git add`" + ` した内容を取り消す際によく使用します。
-
- 🛠  EXAMPLES
-    1. ワーキングツリーの変更を破棄する（元に戻す）
-       $ git restore README.md
-
-    2. ステージングした変更を取り消す（Unstage）
-       $ git restore --staged README.md
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-restore
+// Spec implements git.SpecProvider.
+func (c *RestoreCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-S", "--staged"}, Usage: "Restore the index"},
+			{Flags: []string{"-W", "--worktree"}, Usage: "Restore the working tree"},
+			{Flags: []string{"-s", "--source"}, Arg: git.ArgRef, Usage: "Restore from this commit"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
 
- 💡 TIPS
-    ` + "`" + `git restore .` + "`" + ` を実行すると、現在のディレクトリ以下の
-    「まだaddしていない変更」をすべて破棄します（Untrackedなファイルは消えません）。
-    「実験的にいろいろいじったけど、全部なかったことにしてスッキリしたい」時に便利です。
-`
+func (c *RestoreCommand) Help() string {
+	return git.CommandHelp(context.Background(), "restore")
 }
@@ -7,9 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRestoreCommand(t *testing.T) {
@@ -154,3 +157,80 @@ func TestRestoreCommand(t *testing.T) {
 		t.Errorf("Expected a.txt worktree to be Modified, got %c", sStat.Worktree)
 	}
 }
+
+func TestRestoreCommand_Source(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, _ := sm.CreateSession("test-restore-source")
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+	w, _ := repo.Worktree()
+	ctx := context.Background()
+
+	write := func(name, content string) {
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+	}
+	read := func(name string) string {
+		content, err := util.ReadFile(w.Filesystem, name)
+		require.NoError(t, err, name)
+		return string(content)
+	}
+	sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()}
+
+	write("src/a.go", "a1")
+	write("src/lib/b.go", "b1")
+	write("README.md", "r1")
+	_, err = w.Commit("v1", &gogit.CommitOptions{Author: sig})
+	require.NoError(t, err)
+	write("src/a.go", "a2")
+	write("src/lib/b.go", "b2")
+	write("src/new.go", "new")
+	write("README.md", "r2")
+	_, err = w.Commit("v2", &gogit.CommitOptions{Author: sig})
+	require.NoError(t, err)
+
+	cmd := &RestoreCommand{}
+
+	// A directory pathspec restores every file under it, only in the working tree
+	out, err := cmd.Execute(ctx, session, []string{"restore", "--source=HEAD~1", "src/"})
+	require.NoError(t, err)
+	assert.Equal(t, "Restored 3 file(s) in the working tree from HEAD~1", out)
+	assert.Equal(t, "a1", read("src/a.go"))
+	assert.Equal(t, "b1", read("src/lib/b.go"))
+	assert.Equal(t, "r2", read("README.md"), "outside the pathspec")
+	_, err = w.Filesystem.Stat("src/new.go")
+	assert.True(t, os.IsNotExist(err), "tracked files missing from the source are removed")
+	status, _ := w.Status()
+	assert.Equal(t, gogit.Unmodified, status.File("src/a.go").Staging, "the index is untouched")
+
+	// Back to the index content
+	_, err = cmd.Execute(ctx, session, []string{"restore", "src"})
+	require.NoError(t, err)
+	assert.Equal(t, "a2", read("src/a.go"))
+	assert.Equal(t, "new", read("src/new.go"))
+
+	// --staged --worktree restores both
+	out, err = cmd.Execute(ctx, session, []string{"restore", "-s", "HEAD~1", "--staged", "--worktree", "src/lib", "README.md"})
+	require.NoError(t, err)
+	assert.Equal(t, "Restored 2 file(s) in the index and working tree from HEAD~1", out)
+	assert.Equal(t, "b1", read("src/lib/b.go"))
+	assert.Equal(t, "r1", read("README.md"))
+	status, _ = w.Status()
+	assert.Equal(t, gogit.Modified, status.File("README.md").Staging)
+	assert.Equal(t, gogit.Unmodified, status.File("README.md").Worktree)
+
+	// --staged alone restores the index from HEAD
+	out, err = cmd.Execute(ctx, session, []string{"restore", "--staged", "README.md"})
+	require.NoError(t, err)
+	assert.Equal(t, "Unstaged 1 file(s)", out)
+	status, _ = w.Status()
+	assert.Equal(t, gogit.Unmodified, status.File("README.md").Staging)
+	assert.Equal(t, gogit.Modified, status.File("README.md").Worktree)
+
+	_, err = cmd.Execute(ctx, session, []string{"restore", "--source=HEAD~1", "nope"})
+	assert.ErrorContains(t, err, "pathspec 'nope' did not match")
+	_, err = cmd.Execute(ctx, session, []string{"restore", "--source=missing", "src"})
+	assert.ErrorContains(t, err, "could not resolve missing")
+}
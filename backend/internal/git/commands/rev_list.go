@@ -0,0 +1,159 @@
+package commands
+
+// rev_list.go - Simulated Git Rev-List Command
+//
+// Lists commit hashes reachable from the given revisions, newest first.
+// Together with ranges this answers questions such as "how many commits
+// have I not pushed yet?" (git rev-list --count @{u}..HEAD).
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("rev-list", func() git.Command { return &RevListCommand{} })
+}
+
+type RevListCommand struct{}
+
+// Ensure RevListCommand implements git.Command
+var _ git.Command = (*RevListCommand)(nil)
+
+type RevListOptions struct {
+	Count bool // --count: print the number of commits instead
+	All   bool
+	Limit int      // -n / --max-count; 0 means unlimited
+	Revs  []string // Revisions, ranges (A..B, A...B) and exclusions (^A)
+}
+
+func (c *RevListCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "rev-list"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	var starts, excludes []plumbing.Hash
+	for _, arg := range opts.Revs {
+		if r, ok, err := git.ParseRevisionRange(repo, arg); ok {
+			if err != nil {
+				return "", err
+			}
+			starts = append(starts, r.Include...)
+			excludes = append(excludes, r.Exclude...)
+			continue
+		}
+		rev, exclude := strings.CutPrefix(arg, "^")
+		hash, err := git.ResolveRevision(repo, rev)
+		if err != nil {
+			return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", arg)
+		}
+		if exclude {
+			excludes = append(excludes, *hash)
+		} else {
+			starts = append(starts, *hash)
+		}
+	}
+	if opts.All {
+		starts = append(starts, git.RefTips(repo)...)
+	}
+	if len(starts) == 0 && len(excludes) == 0 {
+		return "", fmt.Errorf("usage: git rev-list [<options>] <commit>... [--]")
+	}
+
+	commits, err := logTopoOrder(repo, starts)
+	if err != nil {
+		return "", err
+	}
+	excluded := git.ReachableCommits(repo, excludes)
+	var hashes []string
+	for _, commit := range commits {
+		if opts.Limit > 0 && len(hashes) >= opts.Limit {
+			break
+		}
+		if !excluded[commit.Hash] {
+			hashes = append(hashes, commit.Hash.String())
+		}
+	}
+
+	if opts.Count {
+		return fmt.Sprintf("%d\n", len(hashes)), nil
+	}
+	if len(hashes) == 0 {
+		return "", nil
+	}
+	return strings.Join(hashes, "\n") + "\n", nil
+}
+
+func (c *RevListCommand) parseArgs(args []string) (*RevListOptions, error) {
+	opts := &RevListOptions{}
+	cmdArgs := args[1:]
+	for i := 0; i < len(cmdArgs); i++ {
+		arg := cmdArgs[i]
+		limit := ""
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "--":
+			i = len(cmdArgs)
+		case arg == "--count":
+			opts.Count = true
+		case arg == "--all":
+			opts.All = true
+		case arg == "-n" || arg == "--max-count":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("fatal: option '%s' requires a value", strings.TrimLeft(arg, "-"))
+			}
+			i++
+			limit = cmdArgs[i]
+		case strings.HasPrefix(arg, "--max-count="):
+			limit = strings.TrimPrefix(arg, "--max-count=")
+		case strings.HasPrefix(arg, "-n"):
+			limit = arg[2:]
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			return nil, fmt.Errorf("fatal: unrecognized argument: %s", arg)
+		default:
+			opts.Revs = append(opts.Revs, arg)
+		}
+		if limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("fatal: '%s': not an integer", limit)
+			}
+			opts.Limit = n
+		}
+	}
+	return opts, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *RevListCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--all"}, Usage: "List every ref"},
+			{Flags: []string{"--count"}, Usage: "Print only the number of commits"},
+			{Flags: []string{"-n", "--max-count"}, Arg: git.ArgText, Usage: "List at most n commits"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *RevListCommand) Help() string {
+	return git.CommandHelp(context.Background(), "rev-list")
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestRevisionRanges(t *testing.T) {
+	sm := git.NewSessionManager()
+	s, _ := sm.CreateSession("test-revision-ranges")
+	s.InitRepo("testrepo")
+	s.CurrentDir = "/testrepo"
+
+	repo := s.GetRepo()
+	w, _ := repo.Worktree()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	day := 0
+	commit := func(file, msg string) plumbing.Hash {
+		f, _ := w.Filesystem.Create(file)
+		f.Write([]byte(msg))
+		f.Close()
+		w.Add(file)
+		day++
+		sig := &object.Signature{Name: "Alice", Email: "alice@example.com", When: start.AddDate(0, 0, day)}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig, Committer: sig})
+		if err != nil {
+			t.Fatalf("commit %q: %v", msg, err)
+		}
+		return hash
+	}
+
+	// main: Initial - Second on main, feature: Initial - Feature one - Feature two
+	initial := commit("README.md", "Initial")
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true})
+	featureOne := commit("one.txt", "Feature one")
+	commit("two.txt", "Feature two")
+	w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")})
+	commit("README.md", "Second on main")
+	repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/main", initial))
+
+	ctx := context.Background()
+	run := func(cmd git.Command, args ...string) string {
+		t.Helper()
+		out, err := cmd.Execute(ctx, s, args)
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return strings.TrimSpace(out)
+	}
+	subjects := func(out string) string {
+		var list []string
+		for _, line := range strings.Split(out, "\n") {
+			list = append(list, line[strings.Index(line, " ")+1:])
+		}
+		return strings.Join(list, "|")
+	}
+
+	for _, tc := range []struct{ args, want string }{
+		{"--count main..feature", "2"},
+		{"--count feature ^main", "2"},
+		{"--count main...feature", "3"},
+		{"--count feature..", "1"},
+		{"--count @{u}..HEAD", "1"},
+		{"--count main@{upstream}..feature", "2"},
+		{"--count --all", "4"},
+	} {
+		if got := run(&RevListCommand{}, append([]string{"rev-list"}, strings.Fields(tc.args)...)...); got != tc.want {
+			t.Errorf("rev-list %s: expected %s, got %s", tc.args, tc.want, got)
+		}
+	}
+
+	if got := subjects(run(&LogCommand{}, "log", "--oneline", "main..feature")); got != "Feature two|Feature one" {
+		t.Errorf("Unexpected log main..feature: %s", got)
+	}
+	if got := subjects(run(&LogCommand{}, "log", "--oneline", "main...feature")); got != "Second on main|Feature two|Feature one" {
+		t.Errorf("Unexpected log main...feature: %s", got)
+	}
+
+	// Single revisions
+	if hash, err := git.ResolveRevision(repo, ":/Feature one"); err != nil || *hash != featureOne {
+		t.Errorf("Expected :/ to find the commit by message, got %v, %v", hash, err)
+	}
+	if hash, err := git.ResolveRevision(repo, "@{u}"); err != nil || *hash != initial {
+		t.Errorf("Expected @{u} to resolve to origin/main, got %v, %v", hash, err)
+	}
+	for _, rev := range []string{":/no such message", "feature@{u}", "main..feature"} {
+		if _, err := git.ResolveRevision(repo, rev); err == nil {
+			t.Errorf("Expected %s not to resolve to a single commit", rev)
+		}
+	}
+
+	// cherry-pick replays the range oldest first
+	run(&CherryPickCommand{}, "cherry-pick", "main..feature")
+	if got := subjects(run(&LogCommand{}, "log", "--oneline", "-n", "3")); got != "Feature two|Feature one|Second on main" {
+		t.Errorf("Unexpected history after cherry-pick: %s", got)
+	}
+}
@@ -27,6 +27,7 @@ func (c *RevertCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	// Parse flags and arguments
 	var rev string
 	var mainline int
+	noEdit := false
 
 	for i := 1; i < len(args); i++ {
 		arg := args[i]
@@ -39,13 +40,17 @@ func (c *RevertCommand) Execute(ctx context.Context, s *git.Session, args []stri
 				return "", fmt.Errorf("invalid mainline parent number: %s", args[i+1])
 			}
 			i++ // skip value
+		} else if arg == "--no-edit" {
+			noEdit = true
+		} else if arg == "-e" || arg == "--edit" {
+			noEdit = false
 		} else {
 			rev = arg
 		}
 	}
 
 	if rev == "" {
-		return "", fmt.Errorf("usage: git revert [-m parent-number] <commit>")
+		return "", fmt.Errorf("usage: git revert [--no-edit] [-m parent-number] <commit>")
 	}
 
 	repo := s.GetRepo()
@@ -128,17 +133,35 @@ func (c *RevertCommand) Execute(ctx context.Context, s *git.Session, args []stri
 		return "", fmt.Errorf("reverting a root commit is not yet supported in this simulation")
 	}
 
-	err = git.Merge3Way(w, targetCommit, headCommit, parentCommit)
+	// Standard git revert message, edited first when the client has an editor
+	msg := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", strings.TrimSpace(targetCommit.Message), targetCommit.Hash.String())
+	if edited, ok := git.EditedMessage(ctx); ok {
+		msg = edited
+	} else if !noEdit && git.EditorAvailable(ctx) {
+		return "", &git.EditorRequest{
+			File: git.CommitEditFile,
+			Template: msg + "\n\n" + git.CommentLines(
+				"Please enter the commit message for your changes. Lines starting",
+				"with '#' will be ignored, and an empty message aborts the commit.",
+			),
+			Abort: "Aborting commit due to empty commit message.",
+		}
+	}
+
+	err = git.Merge3Way(w, targetCommit, headCommit, parentCommit, "")
 	if err != nil {
 		if err == git.ErrConflict {
-			return "", fmt.Errorf("error: could not revert %s... %s\nhint: after resolving conflicts, commit result", hash.String()[:7], targetCommit.Message)
+			conflicts, _ := conflictedPaths(w)
+			report := fmt.Sprintf("error: could not revert %s... %s\nhint: after resolving conflicts, commit result", hash.String()[:7], targetCommit.Message)
+			for _, line := range git.Rerere(s, repo, w, conflicts) {
+				report += "\n" + line
+			}
+			return "", fmt.Errorf("%s", report)
 		}
 		return "", fmt.Errorf("failed to revert: %v", err)
 	}
 
 	// 5. Commit
-	// Standard git revert message
-	msg := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", strings.TrimSpace(targetCommit.Message), targetCommit.Hash.String())
 
 	// Resolve Author from config
 	authorName := "GitGym User"
@@ -166,31 +189,18 @@ func (c *RevertCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	return fmt.Sprintf("Revert successful. New commit %s", newHash.String()[:7]), nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *RevertCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-m"}, Arg: git.ArgText, Usage: "Parent to revert a merge against"},
+			{Flags: []string{"-e", "--edit"}, Usage: "Edit the message"},
+			{Flags: []string{"--no-edit"}, Usage: "Keep the default message"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
 func (c *RevertCommand) Help() string {
-	return `📘 GIT-REVERT (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ・既存のコミットを「打ち消す」新しいコミットを作成します。
-    ・履歴を改変せず（resetと異なり）、安全に過去の変更を取り消せます。
-
- 📋 SYNOPSIS
-    git revert [-m parent-number] <commit>
-
- ⚙️  OPTIONS
-    -m parent-number
-        マージコミットを打ち消す場合に、どの親を「残す」かを指定します。
-        通常、親番号は以下の通りです：
-        1: 元いたブランチ（Mainline）
-        2: マージされたブランチ
-
- 🛠  EXAMPLES
-    1. 直前のコミットを取り消す
-       $ git revert HEAD
-       
-    2. マージコミットを取り消す（メインラインを残す）
-       $ git revert -m 1 <commit>
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-revert
-`
+	return git.CommandHelp(context.Background(), "revert")
 }
@@ -0,0 +1,82 @@
+package commands
+
+// shell_cat.go - Shell Command: Print File Contents
+//
+// This is a SHELL COMMAND (not a git command).
+// Prints files from the simulated filesystem, or its input when used in a pipe.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("cat", func() git.Command { return &CatCommand{} })
+}
+
+type CatCommand struct{}
+
+// Ensure CatCommand implements git.Command
+var _ git.Command = (*CatCommand)(nil)
+
+func (c *CatCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	files := args[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var sb strings.Builder
+	for _, name := range files {
+		if name == "-h" || name == "--help" {
+			return git.CommandHelp(ctx, "cat"), nil
+		}
+		if name == "-" {
+			input, _ := git.Stdin(ctx)
+			sb.WriteString(input)
+			continue
+		}
+
+		target := name
+		if !strings.HasPrefix(target, "/") {
+			target = path.Join(s.CurrentDir, target)
+		}
+		fi, err := s.Filesystem.Stat(target)
+		if err != nil {
+			return "", fmt.Errorf("cat: %s: No such file or directory", name)
+		}
+		if fi.IsDir() {
+			return "", fmt.Errorf("cat: %s: Is a directory", name)
+		}
+		f, err := s.Filesystem.Open(target)
+		if err != nil {
+			return "", fmt.Errorf("cat: %s: %v", name, err)
+		}
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			return "", fmt.Errorf("cat: %s: %v", name, err)
+		}
+		sb.Write(data)
+	}
+	return sb.String(), nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *CatCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Args:  []string{git.ArgPath},
+	}
+}
+
+func (c *CatCommand) Help() string {
+	return git.CommandHelp(context.Background(), "cat")
+}
@@ -71,21 +71,14 @@ func (c *CdCommand) Execute(ctx context.Context, s *git.Session, args []string)
 	return "", nil
 }
 
-func (c *CdCommand) Help() string {
-	return `📘 CD (1)                                               Shell Manual
-
- 💡 DESCRIPTION
-    ・作業フォルダを移動する
-    （` + "`" + `..` + "`" + ` で一つ上の階層へ移動できます）
-
- 📋 SYNOPSIS
-    cd <path>
-
- 🛠  EXAMPLES
-    1. ディレクトリへ移動
-       $ cd my-repo
+// Spec implements git.SpecProvider.
+func (c *CdCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Args:  []string{git.ArgPath},
+	}
+}
 
-    2. 上の階層へ
-       $ cd ..
-`
+func (c *CdCommand) Help() string {
+	return git.CommandHelp(context.Background(), "cd")
 }
@@ -138,24 +138,18 @@ func (c *LsCommand) executeLs(s *git.Session, opts *LsOptions) (string, error) {
 	return strings.Join(output, "\n"), nil
 }
 
-func (c *LsCommand) Help() string {
-	return `📘 LS (1)                                               Shell Manual
-
- 💡 DESCRIPTION
-    ・現在のフォルダにあるファイルやフォルダの一覧を表示する
-
- 📋 SYNOPSIS
-    ls [-la] [<path>]
-
- 🛠  OPTIONS
-    -a    隠しファイル（.で始まるファイル）も表示
-    -l    詳細表示（未実装）
-
- 🛠  EXAMPLES
-    1. 現在の場所を表示
-       $ ls
+// Spec implements git.SpecProvider.
+func (c *LsCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Options: []git.Option{
+			{Flags: []string{"-a"}, Usage: "Show hidden files"},
+			{Flags: []string{"-l"}, Usage: "Long listing"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
 
-    2. 隠しファイルも表示
-       $ ls -a
-`
+func (c *LsCommand) Help() string {
+	return git.CommandHelp(context.Background(), "ls")
 }
@@ -73,19 +73,17 @@ func (c *MkdirCommand) Execute(ctx context.Context, s *git.Session, args []strin
 	return "", nil // Success, no output
 }
 
-func (c *MkdirCommand) Help() string {
-	return `📘 MKDIR (1)                                             Shell Manual
-
- 💡 DESCRIPTION
-    ・新しいディレクトリ（フォルダ）を作成する
-
- 📋 SYNOPSIS
-    mkdir <directory>
+// Spec implements git.SpecProvider.
+func (c *MkdirCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Options: []git.Option{
+			{Flags: []string{"-p"}, Usage: "Create parent directories"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
 
- 🛠  EXAMPLES
-    1. 新しいリポジトリ用のディレクトリを作成
-       $ mkdir my-project
-       $ cd my-project
-       $ git init
-`
+func (c *MkdirCommand) Help() string {
+	return git.CommandHelp(context.Background(), "mkdir")
 }
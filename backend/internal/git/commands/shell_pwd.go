@@ -26,17 +26,13 @@ func (c *PwdCommand) Execute(ctx context.Context, s *git.Session, args []string)
 	return dir, nil
 }
 
-func (c *PwdCommand) Help() string {
-	return `📘 PWD (1)                                              Shell Manual
-
- 💡 DESCRIPTION
-    ・「今どこにいるか」（現在のフォルダのパス）を表示する
-
- 📋 SYNOPSIS
-    pwd
+// Spec implements git.SpecProvider.
+func (c *PwdCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+	}
+}
 
- 🛠  EXAMPLES
-    $ pwd
-    /gitgym/repo
-`
+func (c *PwdCommand) Help() string {
+	return git.CommandHelp(context.Background(), "pwd")
 }
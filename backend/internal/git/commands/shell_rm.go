@@ -131,28 +131,18 @@ func (c *RmCommand) executeRm(s *git.Session, opts *RmOptions) (string, error) {
 	return "", nil
 }
 
+// Spec implements git.SpecProvider.
+func (c *RmCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Options: []git.Option{
+			{Flags: []string{"-r"}, Usage: "Remove directories recursively"},
+			{Flags: []string{"-f"}, Usage: "Ignore missing files"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
 func (c *RmCommand) Help() string {
-	return `📘 RM (1)                                               Shell Manual
-
- 💡 DESCRIPTION
-    ・ファイルやフォルダを削除する（復元できません）
-    
-    ⚠️ 注意: これは ` + "`git rm`" + ` ではなく、シェルの ` + "`rm`" + ` コマンド相当です。
-    インデックス（ステージングエリア）からの削除は行われません。
-    追跡対象のファイルを削除した場合は、その後 ` + "`git add`" + ` で削除を記録する必要があります。
-
- 📋 SYNOPSIS
-    rm [-rf] <path>
-
- ⚙️  COMMON OPTIONS
-    (暗黙的) -rf
-        ディレクトリの場合は再帰的に、強制的に削除します。
-
- 🛠  EXAMPLES
-    1. ファイルを削除
-       $ rm file.txt
-    
-    2. ディレクトリを削除
-       $ rm dir/
-`
+	return git.CommandHelp(context.Background(), "rm")
 }
@@ -126,18 +126,14 @@ func (c *TouchCommand) executeTouch(s *git.Session, opts *TouchOptions) (string,
 	return "", nil
 }
 
-func (c *TouchCommand) Help() string {
-	return `📘 TOUCH (1)                                            Shell Manual
-
- 💡 DESCRIPTION
-    ・空のファイルを新規作成する
-    ・ファイルの最終更新日時を更新する
-
- 📋 SYNOPSIS
-    touch <file>...
+// Spec implements git.SpecProvider.
+func (c *TouchCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell: true,
+		Args:  []string{git.ArgPath},
+	}
+}
 
- 🛠  EXAMPLES
-    1. 新しいファイルを作成
-       $ touch newfile.txt
-`
+func (c *TouchCommand) Help() string {
+	return git.CommandHelp(context.Background(), "touch")
 }
@@ -218,30 +218,19 @@ func (c *ShowCommand) executeShow(_ *git.Session, repo *gogit.Repository, opts *
 	return sb.String(), nil
 }
 
-func (c *ShowCommand) Help() string {
-	return `📘 GIT-SHOW (1)                                         Git Manual
-
- 💡 DESCRIPTION
-    ・特定のコミットの変更内容やメッセージを詳しく表示する
-    ・コミットの内容を詳細に確認する
-
- 📋 SYNOPSIS
-    git show [<commit>] [--name-status]
-
- ⚙️  COMMON OPTIONS
-    --name-status
-        変更内容の差分テキストではなく、変更されたファイル名と状態（A/M/D）のみを表示します。
-
- 🛠  EXAMPLES
-    1. 最新のコミットを表示
-       $ git show
-
-    2. 特定のコミットの変更ファイル一覧を表示
-       $ git show --name-status e5a3b21
+// Spec implements git.SpecProvider.
+func (c *ShowCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"--name-status"}, Usage: "Show only the changed paths and how"},
+			{Flags: []string{"--format"}, Arg: git.ArgText, Usage: "Output format"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-show
-`
+func (c *ShowCommand) Help() string {
+	return git.CommandHelp(context.Background(), "show")
 }
 
 func listRootChanges(tree *object.Tree) (string, error) {
@@ -0,0 +1,163 @@
+package commands
+
+// simulate.go - Scripted teammate activity on shared remotes
+//
+// Plays teammate scenarios (see state/teammates.go) so that the remote changes
+// while the user works: commits to fetch, branches to check out, force-pushes
+// to recover from and pull requests to review. "simulate sync" has an ingested
+// remote follow its real upstream instead (see simulate_sync.go).
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("simulate", func() git.Command { return &SimulateCommand{} })
+}
+
+type SimulateCommand struct{}
+
+var _ git.Command = (*SimulateCommand)(nil)
+
+func (c *SimulateCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	if s.Manager == nil {
+		return "", fmt.Errorf("simulate: no shared remotes in this sandbox")
+	}
+	sm := s.Manager
+
+	var positional []string
+	manual, help := false, false
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--manual":
+			manual = true
+		case "-h", "--help":
+			help = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if help || len(positional) == 0 {
+		return git.CommandHelp(ctx, "simulate"), nil
+	}
+	sub, rest := positional[0], positional[1:]
+	remoteArg := func(i int) string {
+		if len(rest) > i {
+			return rest[i]
+		}
+		return "origin"
+	}
+
+	switch sub {
+	case "list":
+		names := sm.ListTeammateScenarios()
+		if len(names) == 0 {
+			return "No teammate scenarios found.", nil
+		}
+		var sb strings.Builder
+		for _, name := range names {
+			sc, err := sm.LoadTeammateScenario(name)
+			if err != nil {
+				sb.WriteString(fmt.Sprintf("%-24s (invalid: %v)\n", name, err))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%-24s %d step(s)  %s\n", name, len(sc.Steps), sc.Description))
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+
+	case "start", "run":
+		if len(rest) == 0 {
+			return "", fmt.Errorf("usage: simulate %s <scenario> [<remote>]", sub)
+		}
+		sc, err := sm.LoadTeammateScenario(rest[0])
+		if err != nil {
+			return "", err
+		}
+		remote := remoteArg(1)
+		timed := sub == "start" && !manual
+		run, err := sm.StartTeammateScenario(remote, sc, timed)
+		if err != nil {
+			return "", err
+		}
+		if sub == "start" {
+			if timed {
+				return fmt.Sprintf("Teammates are at work on %s: scenario %q, %d step(s).\nRun 'git fetch' from time to time to see what they push.", remote, sc.Name, run.Total), nil
+			}
+			return fmt.Sprintf("Scenario %q is ready on %s: %d step(s). Run 'simulate next' to play each one.", sc.Name, remote, run.Total), nil
+		}
+		for !run.Done() {
+			if run, err = sm.AdvanceTeammateScenario(remote); err != nil {
+				return strings.Join(append(run.Log, err.Error()), "\n"), err
+			}
+		}
+		return strings.Join(run.Log, "\n"), nil
+
+	case "next":
+		run, err := sm.AdvanceTeammateScenario(remoteArg(0))
+		if err != nil {
+			return "", err
+		}
+		out := run.Log[len(run.Log)-1]
+		if run.Done() {
+			out += fmt.Sprintf("\nScenario %q finished.", run.Scenario)
+		}
+		return out, nil
+
+	case "status":
+		run, ok := sm.TeammateScenarioStatus(remoteArg(0))
+		if !ok {
+			return fmt.Sprintf("No teammate scenario is running on %s.", remoteArg(0)), nil
+		}
+		return formatTeammateRun(run), nil
+
+	case "sync":
+		return simulateSync(ctx, s, rest)
+
+	case "stop":
+		if err := sm.StopTeammateScenario(remoteArg(0)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Stopped the teammate scenario on %s.", remoteArg(0)), nil
+	}
+	return "", fmt.Errorf("simulate: unknown subcommand '%s'\n%s", sub, git.CommandHelp(ctx, "simulate"))
+}
+
+// formatTeammateRun describes the progress of a run.
+func formatTeammateRun(run *git.TeammateRun) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Scenario %q on %s: %d/%d step(s) played", run.Scenario, run.Remote, run.Next, run.Total))
+	switch {
+	case run.Error != "":
+		sb.WriteString(" (stopped: " + run.Error + ")")
+	case run.Done():
+		sb.WriteString(" (finished)")
+	case run.NextAt != nil:
+		sb.WriteString(fmt.Sprintf(" (next at %s)", run.NextAt.Format("15:04:05")))
+	default:
+		sb.WriteString(" (waiting for 'simulate next')")
+	}
+	for _, line := range run.Log {
+		sb.WriteString("\n  " + line)
+	}
+	return sb.String()
+}
+
+// Spec implements git.SpecProvider.
+func (c *SimulateCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Shell:       true,
+		Subcommands: []string{"list", "next", "run", "start", "status", "stop"},
+		Options: []git.Option{
+			{Flags: []string{"--manual"}, Usage: "Advance one event at a time"},
+		},
+		Args: []string{git.ArgText},
+	}
+}
+
+func (c *SimulateCommand) Help() string {
+	return git.CommandHelp(context.Background(), "simulate")
+}
@@ -3,11 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
-	gogit "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -15,6 +12,8 @@ func init() {
 	git.RegisterCommand("simulate-commit", func() git.Command { return &SimulateCommitCommand{} })
 }
 
+// SimulateCommitCommand makes a teammate commit a new file to a shared remote.
+// It is the single-step form of the simulate command.
 type SimulateCommitCommand struct{}
 
 // Ensure SimulateCommitCommand implements git.Command
@@ -27,99 +26,34 @@ func (c *SimulateCommitCommand) Execute(ctx context.Context, s *git.Session, arg
 	}
 
 	remoteName := args[1]
-	message := args[2]
-	authorName := "Simulated User"
-	authorEmail := "simulated@example.com"
-
-	if len(args) >= 5 {
-		authorName = args[3]
-		authorEmail = args[4]
-	}
-
-	sm := s.Manager
-	sm.Lock()
-	defer sm.Unlock()
-
-	// repo var not needed if using temp clone
-	_, ok := sm.SharedRemotes[remoteName]
-	if !ok {
-		return "", fmt.Errorf("remote %s not found", remoteName)
-	}
-
-	// Handle Bare Repos by cloning to temp, committing, and pushing back
-	// This is heavier but robust for simulation on bare shared repos.
-
-	// 1. Create Temp Dir
-	tempDir, err := os.MkdirTemp("", "gitgym-sim-*")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp dir: %w", err)
-	}
-	defer os.RemoveAll(tempDir) // Clean up
-
-	// 2. Clone (Local)
-	// We clone from the SharedRemote path
-	remotePath, ok := sm.SharedRemotePaths[remoteName]
-	if !ok {
-		// Fallback to iterating if key mismatch, but should match
-		return "", fmt.Errorf("remote path for %s not found", remoteName)
-	}
-
-	tempRepo, err := gogit.PlainClone(tempDir, false, &gogit.CloneOptions{
-		URL: remotePath,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to clone for simulation: %w", err)
+	action := git.TeammateAction{
+		Action:  git.TeammateCommit,
+		Message: args[2],
+		Files:   map[string]string{fmt.Sprintf("simulated_%d.txt", time.Now().Unix()): "Simulated content"},
 	}
-
-	w, err := tempRepo.Worktree()
-	if err != nil {
-		return "", fmt.Errorf("temp worktree error: %v", err)
+	if len(args) >= 5 {
+		action.Author = args[3]
+		action.Email = args[4]
 	}
 
-	// 3. Make Change
-	filename := fmt.Sprintf("simulated_%d.txt", time.Now().Unix())
-	file, err := w.Filesystem.Create(filename)
-	if err != nil {
+	if _, err := s.Manager.ApplyTeammateAction(remoteName, action); err != nil {
 		return "", err
 	}
-	if _, writeErr := file.Write([]byte("Simulated content")); writeErr != nil {
-		return "", writeErr
-	}
-	_ = file.Close()
-
-	if _, addErr := w.Add(filename); addErr != nil {
-		return "", addErr
-	}
-
-	if authorName == "" {
-		authorName = "Simulated User"
-	}
-	if authorEmail == "" {
-		authorEmail = "simulated@example.com"
-	}
-
-	hash, err := w.Commit(message, &gogit.CommitOptions{
-		Author: &object.Signature{
-			Name:  authorName,
-			Email: authorEmail,
-			When:  time.Now(),
-		},
-	})
+	repo, _ := s.Manager.GetSharedRemote(remoteName)
+	head, err := repo.Head()
 	if err != nil {
 		return "", err
 	}
+	return fmt.Sprintf("Simulated commit created: %s", head.Hash().String()), nil
+}
 
-	// 4. Push back to Shared Remote
-	err = tempRepo.Push(&gogit.PushOptions{
-		RemoteName: "origin",
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to push simulation: %w", err)
+// Spec implements git.SpecProvider.
+func (c *SimulateCommitCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Hidden: true,
 	}
-
-	return fmt.Sprintf("Simulated commit created: %s", hash.String()), nil
 }
 
 func (c *SimulateCommitCommand) Help() string {
-	return "usage: simulate-commit <remote-name> <message> [<author-name> <author-email>]"
+	return git.CommandHelp(context.Background(), "simulate-commit")
 }
@@ -0,0 +1,137 @@
+package commands
+
+// simulate_sync.go - "simulate sync": an ingested remote follows its upstream
+//
+// An ingested remote is a snapshot of a real repository. Syncing it fetches
+// the upstream again on an interval (see state/remote_sync.go), so the remote
+// moves on while the user works, the way a busy project's main branch does.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// simulateSync runs "simulate sync [<remote>] [<interval>|now|off]".
+func simulateSync(ctx context.Context, s *git.Session, rest []string) (string, error) {
+	remote, arg := "origin", ""
+	switch {
+	case len(rest) == 1 && isSyncArg(rest[0]):
+		arg = rest[0]
+	case len(rest) >= 1:
+		remote = rest[0]
+		if len(rest) > 1 {
+			arg = rest[1]
+		}
+	}
+	name, err := resolveIngestedRemote(s, remote)
+	if err != nil {
+		return "", err
+	}
+	sm := s.Manager
+
+	status, err := sm.RemoteSyncStatus(name)
+	if err != nil {
+		return "", err
+	}
+
+	switch arg {
+	case "":
+		return formatRemoteSync(status), nil
+	case "now":
+		event, err := sm.SyncRemote(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("simulate: sync of %s failed: %w", name, err)
+		}
+		if event == nil {
+			return fmt.Sprintf("%s is up to date with %s.", name, status.URL), nil
+		}
+		return formatUpstreamEvent(event) + "\nRun 'git fetch' to see what changed upstream.", nil
+	case "off":
+		if _, err := sm.SetRemoteSync(name, 0); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Stopped syncing %s.", name), nil
+	}
+
+	interval, err := time.ParseDuration(arg)
+	if err != nil {
+		return "", fmt.Errorf("simulate: invalid sync interval '%s' (e.g. 30s, 5m)", arg)
+	}
+	if status, err = sm.SetRemoteSync(name, interval); err != nil {
+		return "", fmt.Errorf("simulate: %w", err)
+	}
+	return fmt.Sprintf("%s now follows %s every %s.\nRun 'git fetch' from time to time to see what upstream pushed.", name, status.URL, interval), nil
+}
+
+// isSyncArg reports whether arg is an interval, now or off rather than a remote.
+func isSyncArg(arg string) bool {
+	if arg == "now" || arg == "off" {
+		return true
+	}
+	_, err := time.ParseDuration(arg)
+	return err == nil
+}
+
+// resolveIngestedRemote finds the ingested remote that remote names: a shared
+// remote name, or a remote of the current repository whose URL is one.
+func resolveIngestedRemote(s *git.Session, remote string) (string, error) {
+	sm := s.Manager
+	if name, ok := sm.IngestedRemoteName(remote); ok {
+		return name, nil
+	}
+	s.RLock()
+	defer s.RUnlock()
+	if repo := s.GetRepo(); repo != nil {
+		if r, err := repo.Remote(remote); err == nil {
+			for _, url := range r.Config().URLs {
+				if name, ok := sm.IngestedRemoteName(url); ok {
+					return name, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("simulate: '%s' is not an ingested remote", remote)
+}
+
+// formatRemoteSync describes how a remote follows its upstream.
+func formatRemoteSync(status git.RemoteSyncStatus) string {
+	var sb strings.Builder
+	if status.Interval == "" {
+		sb.WriteString(fmt.Sprintf("%s does not follow %s.", status.Remote, status.URL))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s follows %s every %s.", status.Remote, status.URL, status.Interval))
+	}
+	if status.LastSync != nil {
+		sb.WriteString(fmt.Sprintf("\nLast sync: %s", status.LastSync.Format("15:04:05")))
+		if status.LastError != "" {
+			sb.WriteString(" (failed: " + status.LastError + ")")
+		}
+	}
+	if status.NextSync != nil {
+		sb.WriteString(fmt.Sprintf("\nNext sync: %s", status.NextSync.Format("15:04:05")))
+	}
+	return sb.String()
+}
+
+// formatUpstreamEvent lists the branches a sync moved.
+func formatUpstreamEvent(event *git.UpstreamEvent) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Upstream %s moved:", event.URL))
+	for _, b := range event.Branches {
+		switch {
+		case b.From == "":
+			sb.WriteString(fmt.Sprintf("\n * [new branch] %s (%d commit(s))", b.Branch, b.Commits))
+		case b.To == "":
+			sb.WriteString(fmt.Sprintf("\n - [deleted] %s", b.Branch))
+		case b.Forced:
+			sb.WriteString(fmt.Sprintf("\n + %s...%s %s (forced update)", b.From[:7], b.To[:7], b.Branch))
+		default:
+			sb.WriteString(fmt.Sprintf("\n   %s..%s %s (%d new commit(s))", b.From[:7], b.To[:7], b.Branch, b.Commits))
+		}
+	}
+	return sb.String()
+}
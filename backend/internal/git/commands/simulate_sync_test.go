@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestSimulateSync(t *testing.T) {
+	tempDir := t.TempDir()
+	remotePath := filepath.Join(tempDir, "upstream")
+	r, _ := gogit.PlainInit(remotePath, false)
+	w, _ := r.Worktree()
+	commit := func(msg string) {
+		if err := os.WriteFile(filepath.Join(remotePath, "readme.txt"), []byte(msg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w.Add("readme.txt")
+		if _, err := w.Commit(msg, &gogit.CommitOptions{Author: &object.Signature{Name: "Me", Email: "me@me.com", When: time.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("Init")
+
+	sm := git.NewSessionManager()
+	if err := sm.IngestRemote(context.Background(), "upstream", remotePath, 0); err != nil {
+		t.Fatal(err)
+	}
+	session, _ := sm.CreateSession("test-session")
+	cmd := &SimulateCommand{}
+	run := func(args ...string) (string, error) {
+		return cmd.Execute(context.Background(), session, append([]string{"simulate", "sync"}, args...))
+	}
+
+	out, err := run("upstream", "5m")
+	if err != nil {
+		t.Fatalf("sync 5m failed: %v", err)
+	}
+	if !strings.Contains(out, "every 5m0s") {
+		t.Errorf("expected the interval to be confirmed, got %q", out)
+	}
+	if out, _ = run("upstream"); !strings.Contains(out, "follows "+remotePath+" every 5m0s") || !strings.Contains(out, "Next sync:") {
+		t.Errorf("expected the sync status, got %q", out)
+	}
+	if _, err := run("upstream", "1s"); err == nil {
+		t.Error("expected an interval below the minimum to be refused")
+	}
+	if _, err := run("nowhere", "now"); err == nil || !strings.Contains(err.Error(), "not an ingested remote") {
+		t.Errorf("expected an unknown remote to be refused, got %v", err)
+	}
+
+	if out, err = run("upstream", "now"); err != nil || !strings.Contains(out, "up to date") {
+		t.Errorf("expected no upstream change yet, got %q, %v", out, err)
+	}
+	commit("Upstream work")
+	out, err = run("upstream", "now")
+	if err != nil {
+		t.Fatalf("sync now failed: %v", err)
+	}
+	if !strings.Contains(out, "master (1 new commit(s))") {
+		t.Errorf("expected the new upstream commit to be reported, got %q", out)
+	}
+
+	if out, err = run("upstream", "off"); err != nil || !strings.Contains(out, "Stopped syncing upstream") {
+		t.Errorf("expected syncing to stop, got %q, %v", out, err)
+	}
+	if out, _ = run("upstream"); !strings.Contains(out, "does not follow") {
+		t.Errorf("expected syncing to be off, got %q", out)
+	}
+}
@@ -0,0 +1,121 @@
+package commands
+
+// sparse_checkout.go - "git sparse-checkout init/set/add/list/disable"
+//
+// Sparse checkout keeps only some directories of a large repository in the
+// working tree. The other files stay in the index and in every commit, they
+// are just not written out, so status and commit ignore them.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("sparse-checkout", func() git.Command { return &SparseCheckoutCommand{} })
+}
+
+type SparseCheckoutCommand struct{}
+
+// Ensure SparseCheckoutCommand implements git.Command
+var _ git.Command = (*SparseCheckoutCommand)(nil)
+
+func (c *SparseCheckoutCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: git sparse-checkout (init | set | add | list | disable) [<directory>...]")
+	}
+	sub := args[1]
+	var dirs []string
+	for _, arg := range args[2:] {
+		switch arg {
+		case "-h", "--help":
+			return git.CommandHelp(ctx, "sparse-checkout"), nil
+		case "--cone":
+			// Cone mode is the only mode
+		case "--no-cone":
+			return "", fmt.Errorf("fatal: only cone mode sparse checkouts are supported")
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return "", fmt.Errorf("error: unknown option `%s'", arg)
+			}
+			dirs = append(dirs, arg)
+		}
+	}
+	if sub == "-h" || sub == "--help" {
+		return git.CommandHelp(ctx, "sparse-checkout"), nil
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	current, enabled := git.SparseCheckoutDirs(repo)
+
+	switch sub {
+	case "init":
+		if enabled {
+			dirs = current
+		}
+		return c.set(repo, dirs)
+	case "set":
+		return c.set(repo, dirs)
+	case "add":
+		if !enabled {
+			return "", fmt.Errorf("fatal: no sparse-checkout to add to")
+		}
+		return c.set(repo, append(current, dirs...))
+	case "list":
+		if !enabled {
+			return "", fmt.Errorf("fatal: this worktree is not sparse")
+		}
+		if len(current) == 0 {
+			return "", nil
+		}
+		return strings.Join(current, "\n"), nil
+	case "disable":
+		if err := git.DisableSparseCheckout(repo); err != nil {
+			return "", err
+		}
+		return "Sparse checkout disabled: all tracked files are checked out", nil
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: `%s'", sub)
+	}
+}
+
+// set enables sparse checkout of dirs and reports how much of the tree is left.
+func (c *SparseCheckoutCommand) set(repo *gogit.Repository, dirs []string) (string, error) {
+	warnings, err := git.SetSparseCheckout(repo, dirs)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, w := range warnings {
+		sb.WriteString(w + "\n")
+	}
+	present, total := git.SparseCheckoutStats(repo)
+	sb.WriteString(fmt.Sprintf("Sparse checkout: %d of %d tracked files present", present, total))
+	return sb.String(), nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *SparseCheckoutCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"add", "disable", "init", "list", "set"},
+		Options: []git.Option{
+			{Flags: []string{"--cone"}, Usage: "Match whole directories"},
+			{Flags: []string{"--no-cone"}, Usage: "Match gitignore-style patterns"},
+		},
+		Args: []string{git.ArgPath},
+	}
+}
+
+func (c *SparseCheckoutCommand) Help() string {
+	return git.CommandHelp(context.Background(), "sparse-checkout")
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestSparseCheckout_SetLimitsWorktreeAcrossCheckouts(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-sparse")
+	ctx := context.Background()
+
+	mustRun := func(input string) string {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return result.Stdout
+	}
+	exists := func(path string) bool {
+		_, err := s.Filesystem.Stat("/testrepo/" + path)
+		return err == nil
+	}
+	for _, path := range []string{"src/app.go", "src/lib/util.go", "docs/guide.md", "docs/api/index.md"} {
+		if err := util.WriteFile(s.Filesystem, "/testrepo/"+path, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRun("git add .")
+	mustRun("git commit -m layout")
+
+	out := mustRun("git sparse-checkout set src")
+	if !strings.Contains(out, "3 of 5 tracked files present") {
+		t.Errorf("Unexpected set output: %q", out)
+	}
+	if !exists("file.txt") || !exists("src/lib/util.go") {
+		t.Error("Expected top-level files and the src cone to stay")
+	}
+	if exists("docs/guide.md") || exists("docs") {
+		t.Error("Expected docs to be removed from the working tree")
+	}
+	if got := mustRun("git sparse-checkout list"); got != "src" {
+		t.Errorf("Expected list to show src, got %q", got)
+	}
+
+	status := mustRun("git status")
+	if !strings.Contains(status, "sparse checkout with 60% of tracked files present") || !strings.Contains(status, "nothing to commit") {
+		t.Errorf("Unexpected status: %q", status)
+	}
+
+	// A commit touching docs on another branch must not bring docs back on checkout
+	mustRun("git sparse-checkout disable")
+	mustRun("git checkout -b docs-update")
+	if err := util.WriteFile(s.Filesystem, "/testrepo/docs/guide.md", []byte("updated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustRun("git add docs/guide.md")
+	mustRun("git commit -m update-docs")
+	mustRun("git checkout main")
+	mustRun("git sparse-checkout set src")
+	mustRun("git checkout docs-update")
+	if exists("docs/guide.md") {
+		t.Error("Expected docs to stay out of the sparse working tree after checkout")
+	}
+	if status := mustRun("git status"); !strings.Contains(status, "nothing to commit") {
+		t.Errorf("Expected a clean status after checkout, got %q", status)
+	}
+
+	// Widening the cone writes the files of the checked-out commit
+	mustRun("git sparse-checkout add docs")
+	data, err := util.ReadFile(s.Filesystem, "/testrepo/docs/guide.md")
+	if err != nil || string(data) != "updated" {
+		t.Errorf("Expected docs/guide.md from docs-update, got %q (%v)", data, err)
+	}
+	if !exists("docs/api/index.md") {
+		t.Error("Expected the whole docs cone to be checked out")
+	}
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// specialRef returns the commit a pseudo-ref points at, or the zero hash.
+func specialRef(s *git.Session, name plumbing.ReferenceName) plumbing.Hash {
+	ref, err := s.GetRepo().Storer.Reference(name)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return ref.Hash()
+}
+
+func TestSpecialRefs_ResetToOrigHead(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-orig-head")
+	ctx := context.Background()
+	repo := s.GetRepo()
+
+	(&TouchCommand{}).Execute(ctx, s, []string{"touch", "second.txt"})
+	(&AddCommand{}).Execute(ctx, s, []string{"add", "second.txt"})
+	(&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Second"})
+	second, _ := repo.Head()
+
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--hard", "HEAD~1"}); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	if got := specialRef(s, git.OrigHead); got != second.Hash() {
+		t.Fatalf("Expected ORIG_HEAD at %s, got %s", second.Hash(), got)
+	}
+
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--hard", "ORIG_HEAD"}); err != nil {
+		t.Fatalf("reset to ORIG_HEAD failed: %v", err)
+	}
+	head, _ := repo.Head()
+	if head.Hash() != second.Hash() {
+		t.Errorf("Expected HEAD back at the second commit")
+	}
+
+	state, err := sm.GetGraphState("test-orig-head")
+	if err != nil {
+		t.Fatalf("graph state failed: %v", err)
+	}
+	if state.References["ORIG_HEAD"] == "" {
+		t.Errorf("Expected ORIG_HEAD in the graph references, got %v", state.References)
+	}
+}
+
+func TestSpecialRefs_MergeHead(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-merge-head")
+	ctx := context.Background()
+	repo := s.GetRepo()
+	w, _ := repo.Worktree()
+
+	commit := func(content, msg string) {
+		t.Helper()
+		if err := util.WriteFile(w.Filesystem, "file.txt", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		(&AddCommand{}).Execute(ctx, s, []string{"add", "file.txt"})
+		if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", msg}); err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+	}
+	(&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "-b", "feature"})
+	commit("feature\n", "Feature change")
+	feature, _ := repo.Head()
+	(&CheckoutCommand{}).Execute(ctx, s, []string{"checkout", "main"})
+	commit("main\n", "Main change")
+
+	if _, err := (&MergeCommand{}).Execute(ctx, s, []string{"merge", "feature"}); err == nil {
+		t.Fatal("Expected the merge to stop on a conflict")
+	}
+	if got := specialRef(s, git.MergeHead); got != feature.Hash() {
+		t.Fatalf("Expected MERGE_HEAD at %s, got %s", feature.Hash(), got)
+	}
+	if hash, err := git.ResolveRevision(repo, "MERGE_HEAD"); err != nil || *hash != feature.Hash() {
+		t.Errorf("Expected MERGE_HEAD to resolve as a revision, got %v, %v", hash, err)
+	}
+
+	if _, err := (&MergeCommand{}).Execute(ctx, s, []string{"merge", "--abort"}); err != nil {
+		t.Fatalf("merge --abort failed: %v", err)
+	}
+	if got := specialRef(s, git.MergeHead); !got.IsZero() {
+		t.Errorf("Expected MERGE_HEAD removed after --abort, got %s", got)
+	}
+}
+
+func TestSpecialRefs_FetchAndPull(t *testing.T) {
+	ctx := context.Background()
+	session, localRepo := setupDivergedPull(t, "pull-special-refs")
+	localHead, _ := localRepo.Head()
+
+	if _, err := (&FetchCommand{}).Execute(ctx, session, []string{"fetch", "origin"}); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	tracking, err := localRepo.Reference(plumbing.NewRemoteReferenceName("origin", "master"), true)
+	if err != nil {
+		t.Fatalf("remote-tracking branch missing: %v", err)
+	}
+	if got := specialRef(session, git.FetchHead); got != tracking.Hash() {
+		t.Errorf("Expected FETCH_HEAD at origin/master %s, got %s", tracking.Hash(), got)
+	}
+	if _, err := (&FetchCommand{}).Execute(ctx, session, []string{"fetch", "origin", "nope"}); err == nil {
+		t.Error("Expected fetching a missing branch to fail")
+	}
+
+	if _, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--no-rebase", "origin", "master"}); err != nil {
+		t.Fatalf("pull failed: %v", err)
+	}
+	if got := specialRef(session, git.OrigHead); got != localHead.Hash() {
+		t.Errorf("Expected ORIG_HEAD at the pre-pull HEAD %s, got %s", localHead.Hash(), got)
+	}
+}
+
+func TestSpecialRefs_PullConflictConcludedByCommit(t *testing.T) {
+	ctx := context.Background()
+	session, localRepo := setupDivergedPull(t, "pull-conflict-merge-head")
+	remoteRepo := session.Manager.SharedRemotes["https://example.com/pull-conflict-merge-head.git"]
+	commitFile(t, remoteRepo, "local_file.txt", "theirs", "Remote conflicting commit")
+
+	if _, err := (&PullCommand{}).Execute(ctx, session, []string{"pull", "--no-rebase", "origin", "master"}); err != nil {
+		t.Fatalf("pull failed: %v", err)
+	}
+	tracking, _ := localRepo.Reference(plumbing.NewRemoteReferenceName("origin", "master"), true)
+	if got := specialRef(session, git.MergeHead); got != tracking.Hash() {
+		t.Fatalf("Expected MERGE_HEAD at %s after the conflicted pull, got %s", tracking.Hash(), got)
+	}
+
+	w, _ := localRepo.Worktree()
+	if err := util.WriteFile(w.Filesystem, "local_file.txt", []byte("resolved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	(&AddCommand{}).Execute(ctx, session, []string{"add", "local_file.txt"})
+	if _, err := (&CommitCommand{}).Execute(ctx, session, []string{"commit", "-m", "Resolve"}); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	head, _ := localRepo.Head()
+	commit, _ := localRepo.CommitObject(head.Hash())
+	if commit.NumParents() != 2 {
+		t.Errorf("Expected the commit to conclude the merge with 2 parents, got %d", commit.NumParents())
+	}
+	if got := specialRef(session, git.MergeHead); !got.IsZero() {
+		t.Errorf("Expected MERGE_HEAD removed once the merge is committed, got %s", got)
+	}
+}
@@ -0,0 +1,212 @@
+package commands
+
+// squash.go - GitGym convenience command (not part of real Git)
+//
+// Squashes the last N commits of the current branch into a single commit.
+// This is what "git rebase -i HEAD~N" + marking commits as "squash" achieves,
+// but without the editor round-trip that beginners find intimidating.
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("squash", func() git.Command { return &SquashCommand{} })
+}
+
+type SquashCommand struct{}
+
+// Ensure SquashCommand implements git.Command
+var _ git.Command = (*SquashCommand)(nil)
+
+type SquashOptions struct {
+	Count   int
+	Message string
+}
+
+func (c *SquashCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "squash"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	headRef, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("fatal: no commits yet")
+	}
+	if !headRef.Name().IsBranch() {
+		return "", fmt.Errorf("fatal: HEAD is detached. Switch to a branch before squashing")
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return "", err
+	}
+	if !status.IsClean() {
+		return "", fmt.Errorf("fatal: cannot squash with uncommitted changes\nhint: Commit or stash your changes first ('git stash')")
+	}
+
+	commits, base, err := c.collectCommits(repo, headRef.Hash(), opts.Count)
+	if err != nil {
+		return "", err
+	}
+
+	if published := c.findPublishedRef(repo, commits[len(commits)-1].Hash); published != "" {
+		return "", fmt.Errorf("fatal: refusing to squash commits that are already on '%s'\nhint: Rewriting pushed history forces your teammates to recover. Squash only local commits.", published)
+	}
+
+	message := opts.Message
+	if message == "" {
+		message = c.combinedMessage(commits)
+	}
+
+	// Soft reset keeps the index at HEAD's tree, so a single commit on top of
+	// base reproduces the final snapshot of the squashed range.
+	s.UpdateOrigHead()
+	if err := w.Reset(&gogit.ResetOptions{Commit: base.Hash, Mode: gogit.SoftReset}); err != nil {
+		return "", err
+	}
+	newHash, err := w.Commit(message, &gogit.CommitOptions{
+		Author:            s.Signature(),
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.RecordReflog(fmt.Sprintf("squash: squashed %d commits into %s", len(commits), newHash.String()[:7]))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Squashed %d commits on '%s' into %s\n", len(commits), headRef.Name().Short(), newHash.String()[:7]))
+	for i := len(commits) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("  - %s %s\n", commits[i].Hash.String()[:7], firstLine(commits[i].Message)))
+	}
+	sb.WriteString(fmt.Sprintf("\nWhat happened:\n  1. git reset --soft %s   (move the branch back, keep all changes staged)\n  2. git commit              (record them again as one commit)\n", base.Hash.String()[:7]))
+	sb.WriteString("The old commits are still reachable via 'git reflog' if you need them back.")
+	return sb.String(), nil
+}
+
+func (c *SquashCommand) parseArgs(args []string) (*SquashOptions, error) {
+	opts := &SquashOptions{}
+	cmdArgs := args[1:]
+
+	for i := 0; i < len(cmdArgs); i++ {
+		arg := cmdArgs[i]
+		switch arg {
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		case "-m", "--message":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("fatal: option '-m' requires a value")
+			}
+			opts.Message = cmdArgs[i+1]
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("unknown option: %s", arg)
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("fatal: '%s' is not a number of commits", arg)
+			}
+			opts.Count = n
+		}
+	}
+
+	if opts.Count < 2 {
+		return nil, fmt.Errorf("usage: git squash <n> [-m <message>]  (n must be 2 or more)")
+	}
+	return opts, nil
+}
+
+// collectCommits walks first parents from head and returns the newest-first
+// list of n commits plus the commit they will be squashed onto.
+func (c *SquashCommand) collectCommits(repo *gogit.Repository, head plumbing.Hash, n int) ([]*object.Commit, *object.Commit, error) {
+	current, err := repo.CommitObject(head)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var commits []*object.Commit
+	for len(commits) < n {
+		if current.NumParents() > 1 {
+			return nil, nil, fmt.Errorf("fatal: commit %s is a merge commit; squash only works on linear history", current.Hash.String()[:7])
+		}
+		commits = append(commits, current)
+		if current.NumParents() == 0 {
+			return nil, nil, fmt.Errorf("fatal: only %d commits on this branch; at least one must remain as the base", len(commits))
+		}
+		current, err = current.Parent(0)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return commits, current, nil
+}
+
+// findPublishedRef returns the remote-tracking branch containing hash, or "" if none does.
+func (c *SquashCommand) findPublishedRef(repo *gogit.Repository, hash plumbing.Hash) string {
+	refs, err := repo.References()
+	if err != nil {
+		return ""
+	}
+	var found string
+	_ = refs.ForEach(func(r *plumbing.Reference) error {
+		if found != "" || !r.Name().IsRemote() || r.Type() != plumbing.HashReference {
+			return nil
+		}
+		if ok, err := git.IsFastForward(repo, hash, r.Hash()); err == nil && ok {
+			found = r.Name().Short()
+		}
+		return nil
+	})
+	return found
+}
+
+func (c *SquashCommand) combinedMessage(commits []*object.Commit) string {
+	var parts []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		parts = append(parts, strings.TrimSpace(commits[i].Message))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func firstLine(msg string) string {
+	return strings.Split(strings.TrimSpace(msg), "\n")[0]
+}
+
+// Spec implements git.SpecProvider.
+func (c *SquashCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-m", "--message"}, Arg: git.ArgText, Usage: "Message of the squashed commit"},
+		},
+	}
+}
+
+func (c *SquashCommand) Help() string {
+	return git.CommandHelp(context.Background(), "squash")
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestSquashCommand_SquashesLastCommits(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-squash")
+	ctx := context.Background()
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		(&TouchCommand{}).Execute(ctx, s, []string{"touch", name})
+		(&AddCommand{}).Execute(ctx, s, []string{"add", name})
+		if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "add " + name}); err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+	}
+
+	repo := s.GetRepo()
+	before, _ := repo.Head()
+	beforeCommit, _ := repo.CommitObject(before.Hash())
+	beforeTree := beforeCommit.TreeHash
+
+	res, err := (&SquashCommand{}).Execute(ctx, s, []string{"squash", "3", "-m", "add three files"})
+	if err != nil {
+		t.Fatalf("squash failed: %v", err)
+	}
+	if !strings.Contains(res, "Squashed 3 commits") {
+		t.Errorf("Expected explanation, got: %s", res)
+	}
+
+	after, _ := repo.Head()
+	afterCommit, _ := repo.CommitObject(after.Hash())
+	if afterCommit.TreeHash != beforeTree {
+		t.Errorf("Expected tree to be preserved")
+	}
+	if afterCommit.Message != "add three files" {
+		t.Errorf("Unexpected message: %q", afterCommit.Message)
+	}
+	parent, _ := afterCommit.Parent(0)
+	if parent.Message != "Initial commit" {
+		t.Errorf("Expected squashed commit to sit on the initial commit, got parent %q", parent.Message)
+	}
+}
+
+func TestSquashCommand_Guards(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-squash-guards")
+	ctx := context.Background()
+
+	(&TouchCommand{}).Execute(ctx, s, []string{"touch", "a.txt"})
+	(&AddCommand{}).Execute(ctx, s, []string{"add", "a.txt"})
+	(&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "add a"})
+	(&TouchCommand{}).Execute(ctx, s, []string{"touch", "b.txt"})
+	(&AddCommand{}).Execute(ctx, s, []string{"add", "b.txt"})
+	(&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "add b"})
+
+	cmd := &SquashCommand{}
+
+	t.Run("Too few commits", func(t *testing.T) {
+		if _, err := cmd.Execute(ctx, s, []string{"squash", "5"}); err == nil {
+			t.Error("Expected error when squashing more commits than exist")
+		}
+	})
+
+	t.Run("Published commits", func(t *testing.T) {
+		repo := s.GetRepo()
+		head, _ := repo.Head()
+		ref := plumbing.NewHashReference("refs/remotes/origin/main", head.Hash())
+		_ = repo.Storer.SetReference(ref)
+		defer repo.Storer.RemoveReference(ref.Name())
+
+		_, err := cmd.Execute(ctx, s, []string{"squash", "2"})
+		if err == nil || !strings.Contains(err.Error(), "origin/main") {
+			t.Errorf("Expected refusal for pushed commits, got: %v", err)
+		}
+	})
+
+	t.Run("Dirty worktree", func(t *testing.T) {
+		(&TouchCommand{}).Execute(ctx, s, []string{"touch", "dirty.txt"})
+		if _, err := cmd.Execute(ctx, s, []string{"squash", "2"}); err == nil {
+			t.Error("Expected error with uncommitted changes")
+		}
+	})
+}
@@ -1,13 +1,25 @@
 package commands
 
+// stash.go - Simulated Git Stash Command
+//
+// Stash entries are laid out like git's: a stash is a commit W holding the
+// working tree, with HEAD as first parent and a commit I holding the index as
+// second parent, so the staged/unstaged split survives a push/pop round trip.
+// Instead of a reflog on refs/stash, each W links to the previous entry
+// through a third parent; refs/stash points at stash@{0}.
+
 import (
 	"context"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
@@ -21,15 +33,32 @@ type StashCommand struct{}
 // Ensure StashCommand implements git.Command
 var _ git.Command = (*StashCommand)(nil)
 
+// Ensure StashCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*StashCommand)(nil)
+
+// SupportsDryRun marks stash as simulated by the engine on --dry-run.
+func (c *StashCommand) SupportsDryRun() {}
+
 const StashRefName = "refs/stash"
 
+type StashOptions struct {
+	Op      string // push, pop, apply, drop, list or show
+	Message string // push -m / save <message>
+	Entry   int    // n of stash@{n}
+	Index   bool   // pop/apply --index: restore the staged changes as staged
+	Patch   bool   // show -p: print the diff instead of a diffstat
+}
+
 func (c *StashCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	op := "push" // default
-	if len(args) > 1 {
-		op = args[1]
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "stash"), nil
+		}
+		return "", err
 	}
 
 	repo := s.GetRepo()
@@ -37,36 +66,165 @@ func (c *StashCommand) Execute(ctx context.Context, s *git.Session, args []strin
 		return "", fmt.Errorf("fatal: not a git repository")
 	}
 
-	switch op {
-	case "push", "save":
-		return c.executePush(repo, args)
+	switch opts.Op {
+	case "push":
+		return c.executePush(repo, opts)
+	case "apply":
+		return c.executeApply(repo, opts)
 	case "pop":
-		return c.executePop(repo)
+		out, err := c.executeApply(repo, opts)
+		if err != nil {
+			return "", err
+		}
+		dropped, err := c.executeDrop(repo, opts)
+		if err != nil {
+			return "", err
+		}
+		return out + dropped, nil
+	case "drop":
+		return c.executeDrop(repo, opts)
 	case "list":
 		return c.executeList(repo)
-	case "apply":
-		return "", fmt.Errorf("stash apply is not yet supported (use pop)")
-	case "drop":
-		return "", fmt.Errorf("stash drop is not yet supported")
 	default:
-		// If arg is not a known subcommand, it might be 'git stash -m "msg"' which implies push
-		// For simplicity, treat unknown as push options or error
-		return c.executePush(repo, args)
+		return c.executeShow(repo, opts)
+	}
+}
+
+func (c *StashCommand) parseArgs(args []string) (*StashOptions, error) {
+	opts := &StashOptions{Op: "push"}
+	rest := args[1:]
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		opts.Op, rest = rest[0], rest[1:]
+	}
+	if opts.Op == "save" {
+		// Deprecated form: git stash save [<message>]
+		opts.Op = "push"
+		var words []string
+		for _, arg := range rest {
+			if arg == "-h" || arg == "--help" {
+				return nil, fmt.Errorf("help requested")
+			}
+			words = append(words, arg)
+		}
+		opts.Message = strings.Join(words, " ")
+		return opts, nil
+	}
+	switch opts.Op {
+	case "push", "pop", "apply", "drop", "list", "show":
+	default:
+		return nil, fmt.Errorf("error: unknown subcommand: %s\nusage: git stash [push [-m <message>] | list | show [-p] | pop | apply [--index] | drop] [<stash>]", opts.Op)
+	}
+
+	var positional []string
+	for i := 0; i < len(rest); i++ {
+		arg := rest[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case opts.Op == "push" && (arg == "-m" || arg == "--message"):
+			if i+1 >= len(rest) {
+				return nil, fmt.Errorf("error: switch `m' requires a value")
+			}
+			i++
+			opts.Message = rest[i]
+		case opts.Op == "push" && strings.HasPrefix(arg, "--message="):
+			opts.Message = strings.TrimPrefix(arg, "--message=")
+		case (opts.Op == "pop" || opts.Op == "apply") && arg == "--index":
+			opts.Index = true
+		case opts.Op == "show" && (arg == "-p" || arg == "--patch"):
+			opts.Patch = true
+		case opts.Op == "show" && arg == "--stat":
+			opts.Patch = false
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("error: unknown option '%s'", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	switch {
+	case len(positional) == 0:
+	case opts.Op == "push":
+		return nil, fmt.Errorf("fatal: pathspecs are not supported by git stash push in GitGym")
+	case opts.Op == "list" || len(positional) > 1:
+		return nil, fmt.Errorf("error: too many arguments")
+	default:
+		n, err := parseStashEntry(positional[0])
+		if err != nil {
+			return nil, err
+		}
+		opts.Entry = n
+	}
+	return opts, nil
+}
+
+// parseStashEntry reads stash@{n}, refs/stash@{n} or a bare n.
+func parseStashEntry(rev string) (int, error) {
+	s := rev
+	for _, prefix := range []string{"refs/stash@{", "stash@{"} {
+		if strings.HasPrefix(s, prefix) && strings.HasSuffix(s, "}") {
+			s = strings.TrimSuffix(strings.TrimPrefix(s, prefix), "}")
+			break
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("error: '%s' is not a stash-like commit", rev)
+	}
+	return n, nil
+}
+
+// stashEntries returns the stash stack, stash@{0} first.
+func stashEntries(repo *gogit.Repository) ([]*object.Commit, error) {
+	ref, err := repo.Reference(plumbing.ReferenceName(StashRefName), true)
+	if err != nil {
+		return nil, nil
+	}
+	var entries []*object.Commit
+	cursor := ref.Hash()
+	for {
+		commit, err := repo.CommitObject(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt stash entry %s: %w", cursor.String()[:7], err)
+		}
+		entries = append(entries, commit)
+		if commit.NumParents() < 3 {
+			return entries, nil
+		}
+		cursor = commit.ParentHashes[2]
+	}
+}
+
+// stashEntry returns stash@{n}.
+func stashEntry(repo *gogit.Repository, n int) (*object.Commit, []*object.Commit, error) {
+	entries, err := stashEntries(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("No stash entries found.")
+	}
+	if n >= len(entries) {
+		return nil, nil, fmt.Errorf("error: stash@{%d} is not a valid reference", n)
+	}
+	if entries[n].NumParents() < 2 {
+		return nil, nil, fmt.Errorf("error: stash@{%d} is not a stash-like commit", n)
 	}
+	return entries[n], entries, nil
 }
 
-func (c *StashCommand) executePush(repo *gogit.Repository, _ []string) (string, error) {
+func (c *StashCommand) executePush(repo *gogit.Repository, opts *StashOptions) (string, error) {
 	w, err := repo.Worktree()
 	if err != nil {
 		return "", err
 	}
 
 	// 1. Check if there are changes to stash
-	status, err := w.Status()
+	status, err := git.LFSAwareStatus(repo, w)
 	if err != nil {
 		return "", err
 	}
-	if status.IsClean() {
+	if !hasTrackedChanges(status) {
 		return "No local changes to save", nil
 	}
 
@@ -75,92 +233,121 @@ func (c *StashCommand) executePush(repo *gogit.Repository, _ []string) (string,
 	if err != nil {
 		return "", fmt.Errorf("you do not have the initial commit yet")
 	}
-
-	// 3. Resolve Previous Stash (if any) to use as 2nd parent
-	var parents []plumbing.Hash
-	parents = append(parents, headRef.Hash()) // 1st Parent: HEAD
-
-	prevStashRef, err := repo.Reference(plumbing.ReferenceName(StashRefName), true)
-	if err == nil {
-		parents = append(parents, prevStashRef.Hash()) // 2nd Parent: Old Stash
-	}
-
-	// 4. Create Stash Commit
-	// We want to commit the current worktree state.
-	// NOTE: Real git stash distinguishes Index vs Worktree. We flatten them for MVP.
-	// We add everything to index to capture the snapshot.
-	_, err = w.Add(".")
+	headCommit, err := repo.CommitObject(headRef.Hash())
 	if err != nil {
-		return "", fmt.Errorf("failed to add files for stash: %v", err)
+		return "", err
+	}
+	branch := "(no branch)"
+	if headRef.Name().IsBranch() {
+		branch = headRef.Name().Short()
+	}
+	subject := strings.SplitN(strings.TrimSpace(headCommit.Message), "\n", 2)[0]
+	headDesc := fmt.Sprintf("%s: %s %s", branch, headRef.Hash().String()[:7], subject)
+	stashMsg := "WIP on " + headDesc
+	if opts.Message != "" {
+		stashMsg = fmt.Sprintf("On %s: %s", branch, opts.Message)
 	}
 
-	stashMsg := "WIP on " + headRef.Name().Short() + ": " + time.Now().Format("15:04:05")
-	// If User provided a message (e.g. git stash push -m "msg"), parse it?
-	// Skipping detailed arg parsing for now.
-
-	stashHash, err := w.Commit(stashMsg, &gogit.CommitOptions{
-		Parents: parents,
-		Author: &object.Signature{
-			Name:  "GitGym Stash",
-			Email: "stash@gitgym.local",
-			When:  time.Now(),
-		},
-	})
+	// 3. Record the index (I) and the working tree of tracked files (W)
+	idx, err := repo.Storer.Index()
 	if err != nil {
-		// Try to rollback index?
-		if resetErr := w.Reset(&gogit.ResetOptions{Mode: gogit.MixedReset}); resetErr != nil {
-			return "", fmt.Errorf("failed to create stash commit: %v (rollback also failed: %v)", err, resetErr)
+		return "", err
+	}
+	staged := make(map[string]treeFile)
+	for _, e := range idx.Entries {
+		if e.Stage != 0 { // Conflict stages; go-git's index.Merged is not 0
+			return "", fmt.Errorf("error: could not save the current state: you have unmerged paths\nhint: Fix them up in the work tree, and then use 'git add <file>'")
 		}
-		return "", fmt.Errorf("failed to create stash commit: %v", err)
+		staged[e.Name] = treeFile{hash: e.Hash, mode: e.Mode}
+	}
+	worktree := make(map[string]treeFile, len(staged))
+	for name, f := range staged {
+		data, err := readWorktreeFile(w, name)
+		if err != nil {
+			continue // Deleted in the working tree
+		}
+		hash, err := storeBlob(repo, data)
+		if err != nil {
+			return "", err
+		}
+		worktree[name] = treeFile{hash: hash, mode: f.mode}
 	}
 
-	// 5. Update refs/stash
-	stashRef := plumbing.NewHashReference(plumbing.ReferenceName(StashRefName), stashHash)
-	err = repo.Storer.SetReference(stashRef)
+	indexTree, err := writeTree(repo, staged)
+	if err != nil {
+		return "", err
+	}
+	indexCommit, err := writeCommit(repo, indexTree, "index on "+headDesc, headRef.Hash())
+	if err != nil {
+		return "", err
+	}
+	worktreeTree, err := writeTree(repo, worktree)
+	if err != nil {
+		return "", err
+	}
+	parents := []plumbing.Hash{headRef.Hash(), indexCommit}
+	if prev, err := repo.Reference(plumbing.ReferenceName(StashRefName), true); err == nil {
+		parents = append(parents, prev.Hash()) // Link to the previous stash@{0}
+	}
+	stashHash, err := writeCommit(repo, worktreeTree, stashMsg, parents...)
 	if err != nil {
 		return "", err
 	}
 
-	// 6. Reset Worktree to HEAD (Hard Reset) to clear changes
-	err = w.Reset(&gogit.ResetOptions{Mode: gogit.HardReset, Commit: headRef.Hash()})
+	// 4. Update refs/stash
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(StashRefName), stashHash)); err != nil {
+		return "", err
+	}
+
+	// 5. Reset the index and working tree to HEAD; staged new files go too
+	headTree, err := headCommit.Tree()
 	if err != nil {
+		return "", err
+	}
+	if err := w.Reset(&gogit.ResetOptions{Mode: gogit.HardReset, Commit: headRef.Hash()}); err != nil {
 		return "", fmt.Errorf("failed to reset worktree: %v", err)
 	}
+	for name := range staged {
+		if _, err := headTree.File(name); err != nil {
+			_ = w.Filesystem.Remove(name)
+		}
+	}
 
 	return fmt.Sprintf("Saved working directory and index state %s", stashMsg), nil
 }
 
-func (c *StashCommand) executePop(repo *gogit.Repository) (string, error) {
-	// 1. Resolve Stash
-	stashRef, err := repo.Reference(plumbing.ReferenceName(StashRefName), true)
-	if err != nil {
-		return "No stash entries found.", nil
+// hasTrackedChanges reports whether the index or a tracked file differs from HEAD.
+// Untracked files are not stashed, like git stash without -u.
+func hasTrackedChanges(status gogit.Status) bool {
+	for _, s := range status {
+		if s.Staging == gogit.Untracked {
+			continue
+		}
+		if s.Staging != gogit.Unmodified || s.Worktree != gogit.Unmodified {
+			return true
+		}
 	}
-	stashCommit, err := repo.CommitObject(stashRef.Hash())
+	return false
+}
+
+func (c *StashCommand) executeApply(repo *gogit.Repository, opts *StashOptions) (string, error) {
+	stash, _, err := stashEntry(repo, opts.Entry)
 	if err != nil {
 		return "", err
 	}
-
 	w, err := repo.Worktree()
 	if err != nil {
 		return "", err
 	}
 
-	// 2. Apply Stash (Merge)
-	// We merge the Stash Commit into current Worktree.
-	// Stash Commit has HEAD (at time of stash) as Parent 1.
-	// Current HEAD might be different.
-	// We use Merge3Way logic: Base=Parent1(OriginalHEAD), Ours=CurrentHEAD, Theirs=StashCommit.
-
-	if stashCommit.NumParents() == 0 {
-		return "", fmt.Errorf("invalid stash commit (no parents)")
-	}
-	baseHash := stashCommit.ParentHashes[0]
-	baseCommit, err := repo.CommitObject(baseHash)
+	base, err := stash.Parent(0)
 	if err != nil {
 		return "", fmt.Errorf("could not resolve stash base: %v", err)
 	}
-
+	indexCommit, err := stash.Parent(1)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve stash index: %v", err)
+	}
 	headRef, err := repo.Head()
 	if err != nil {
 		return "", err
@@ -170,90 +357,301 @@ func (c *StashCommand) executePop(repo *gogit.Repository) (string, error) {
 		return "", err
 	}
 
-	// Attempt Merge
-	err = git.Merge3Way(w, baseCommit, headCommit, stashCommit)
+	baseFiles, err := commitFiles(base)
 	if err != nil {
+		return "", err
+	}
+	stashFiles, err := commitFiles(stash)
+	if err != nil {
+		return "", err
+	}
+	indexFiles, err := commitFiles(indexCommit)
+	if err != nil {
+		return "", err
+	}
+	headFiles, err := commitFiles(headCommit)
+	if err != nil {
+		return "", err
+	}
+	// Paths the stash changes, in the working tree or only in the index
+	touched := changedPaths(baseFiles, stashFiles)
+	for name := range changedPaths(baseFiles, indexFiles) {
+		touched[name] = true
+	}
+
+	// 1. Refuse to overwrite local changes, like git
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return "", err
+	}
+	var dirty []string
+	for name := range touched {
+		if s, ok := status[name]; ok && (s.Staging != gogit.Unmodified || s.Worktree != gogit.Unmodified) {
+			dirty = append(dirty, name)
+		}
+	}
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return "", fmt.Errorf("error: Your local changes to the following files would be overwritten by merge:\n\t%s\nPlease commit your changes or stash them before you merge.\nAborting", strings.Join(dirty, "\n\t"))
+	}
+
+	// 2. Merge the stashed working tree into the current one
+	if err := git.Merge3Way(w, base, headCommit, stash, ""); err != nil {
 		if err == git.ErrConflict {
-			return "error: conflicts detected during stash pop.\nThe stash was NOT dropped.", nil
+			return "", fmt.Errorf("error: conflicts detected while applying stash@{%d}.\nThe stash entry is kept in case you need it again.", opts.Entry)
 		}
-		return "", fmt.Errorf("failed to pop stash: %v", err)
-	}
-
-	// Merge3Way likely staged the changes (or modified worktree).
-	// We want them to be Mixed (unstaged) typically, or Soft (staged).
-	// Standard `stash pop` tries to restore index state if `--index` is used, otherwise mixed?
-	// Actually `pop` merges changes. If we want them to feel like "work in progress", we usually leave them as modified files.
-	// Let's do a Mixed Reset to HEAD (keeping changes in Worktree, but unstaging them).
-	// This mimics the "restore work" feel best.
-	w.Reset(&gogit.ResetOptions{Mode: gogit.MixedReset})
-
-	// 3. Drop Stash (Move refs/stash to Parent 2)
-	// If Parent 2 exists, that is the previous stash.
-	if len(stashCommit.ParentHashes) > 1 {
-		prevStashHash := stashCommit.ParentHashes[1]
-		newRef := plumbing.NewHashReference(plumbing.ReferenceName(StashRefName), prevStashHash)
-		repo.Storer.SetReference(newRef)
-	} else {
-		// Empty stack
-		repo.Storer.RemoveReference(plumbing.ReferenceName(StashRefName))
+		return "", fmt.Errorf("failed to apply stash: %v", err)
+	}
+
+	// 3. Put the index back: staged changes stay staged with --index; otherwise
+	//    everything is left unstaged except new files, as git does
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return "", err
+	}
+	for name := range touched {
+		target, ok := headFiles[name]
+		switch {
+		case opts.Index && indexFiles[name] != baseFiles[name]:
+			target, ok = indexFiles[name], indexFiles[name] != (treeFile{})
+		case !opts.Index && baseFiles[name] == (treeFile{}) && stashFiles[name] != (treeFile{}):
+			target, ok = stashFiles[name], true
+		}
+		if !ok {
+			_, _ = idx.Remove(name)
+			continue
+		}
+		e, err := idx.Entry(name)
+		if err != nil {
+			e = idx.Add(name)
+		}
+		e.Hash, e.Mode, e.ModifiedAt = target.hash, target.mode, time.Now()
+		if size, err := blobSize(repo, target.hash); err == nil {
+			e.Size = uint32(size)
+		}
+	}
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return "", err
+	}
+
+	status, err = git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return "", err
+	}
+	return (&StatusCommand{}).formatShortInfo(repo, status, false, nil, nil)
+}
+
+func (c *StashCommand) executeDrop(repo *gogit.Repository, opts *StashOptions) (string, error) {
+	stash, entries, err := stashEntry(repo, opts.Entry)
+	if err != nil {
+		return "", err
 	}
 
-	return fmt.Sprintf("Dropped %s (%s)", StashRefName, stashCommit.Hash.String()[:7]), nil
+	// Entries above the dropped one are rewritten to link past it
+	var prev plumbing.Hash
+	if opts.Entry+1 < len(entries) {
+		prev = entries[opts.Entry+1].Hash
+	}
+	for i := opts.Entry - 1; i >= 0; i-- {
+		e := entries[i]
+		parents := append([]plumbing.Hash(nil), e.ParentHashes[:2]...)
+		if !prev.IsZero() {
+			parents = append(parents, prev)
+		}
+		rewritten := &object.Commit{
+			Author:       e.Author,
+			Committer:    e.Committer,
+			Message:      e.Message,
+			TreeHash:     e.TreeHash,
+			ParentHashes: parents,
+		}
+		if prev, err = storeObject(repo, rewritten); err != nil {
+			return "", err
+		}
+	}
+
+	name := plumbing.ReferenceName(StashRefName)
+	if prev.IsZero() {
+		err = repo.Storer.RemoveReference(name)
+	} else {
+		err = repo.Storer.SetReference(plumbing.NewHashReference(name, prev))
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Dropped refs/stash@{%d} (%s)", opts.Entry, stash.Hash.String()), nil
 }
 
 func (c *StashCommand) executeList(repo *gogit.Repository) (string, error) {
-	stashRef, err := repo.Reference(plumbing.ReferenceName(StashRefName), true)
+	entries, err := stashEntries(repo)
 	if err != nil {
-		return "", nil // Empty list
+		return "", err
 	}
-
 	var sb strings.Builder
+	for i, commit := range entries {
+		sb.WriteString(fmt.Sprintf("stash@{%d}: %s\n", i, strings.TrimSpace(commit.Message)))
+	}
+	return sb.String(), nil
+}
 
-	// Iterate backwards using 2nd parent
-	cursor := stashRef.Hash()
-	i := 0
-	for {
-		commit, err := repo.CommitObject(cursor)
+func (c *StashCommand) executeShow(repo *gogit.Repository, opts *StashOptions) (string, error) {
+	stash, _, err := stashEntry(repo, opts.Entry)
+	if err != nil {
+		return "", err
+	}
+	base, err := stash.Parent(0)
+	if err != nil {
+		return "", err
+	}
+	patch, err := base.Patch(stash)
+	if err != nil {
+		return "", err
+	}
+	if opts.Patch {
+		return patch.String(), nil
+	}
+	return patch.Stats().String(), nil
+}
+
+// treeFile is a blob and its mode, as listed in a tree or the index.
+type treeFile struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
+
+// commitFiles lists the files of a commit's tree by path.
+func commitFiles(commit *object.Commit) (map[string]treeFile, error) {
+	files := make(map[string]treeFile)
+	iter, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(f *object.File) error {
+		files[f.Name] = treeFile{hash: f.Hash, mode: f.Mode}
+		return nil
+	})
+	return files, err
+}
+
+// changedPaths returns the paths whose blob differs between two file lists.
+func changedPaths(from, to map[string]treeFile) map[string]bool {
+	changed := make(map[string]bool)
+	for name, f := range from {
+		if to[name] != f {
+			changed[name] = true
+		}
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			changed[name] = true
+		}
+	}
+	return changed
+}
+
+// writeTree stores the trees for a flat list of files and returns the root tree hash.
+func writeTree(repo *gogit.Repository, files map[string]treeFile) (plumbing.Hash, error) {
+	subdirs := make(map[string]map[string]treeFile)
+	tree := &object.Tree{}
+	for name, f := range files {
+		if dir, rest, nested := strings.Cut(name, "/"); nested {
+			if subdirs[dir] == nil {
+				subdirs[dir] = make(map[string]treeFile)
+			}
+			subdirs[dir][rest] = f
+			continue
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: f.mode, Hash: f.hash})
+	}
+	for dir, sub := range subdirs {
+		hash, err := writeTree(repo, sub)
 		if err != nil {
-			break
+			return plumbing.ZeroHash, err
 		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+	// Git orders entries as if directory names ended with '/'
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j]) })
+	return storeObject(repo, tree)
+}
 
-		// stash@{n}: Message
-		sb.WriteString(fmt.Sprintf("stash@{%d}: %s\n", i, strings.TrimSpace(commit.Message)))
+func writeCommit(repo *gogit.Repository, tree plumbing.Hash, message string, parents ...plumbing.Hash) (plumbing.Hash, error) {
+	sig := object.Signature{Name: "GitGym Stash", Email: "stash@gitgym.local", When: time.Now()}
+	return storeObject(repo, &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      message,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	})
+}
 
-		if len(commit.ParentHashes) < 2 {
-			break // No more previous stashes
-		}
-		cursor = commit.ParentHashes[1]
-		i++
+// storeObject encodes a tree or commit into the repository.
+func storeObject(repo *gogit.Repository, o interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
 	}
+	return repo.Storer.SetEncodedObject(obj)
+}
 
-	return sb.String(), nil
+func storeBlob(repo *gogit.Repository, data []byte) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(data)))
+	wr, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := wr.Write(data); err != nil {
+		_ = wr.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := wr.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+func blobSize(repo *gogit.Repository, hash plumbing.Hash) (int64, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return 0, err
+	}
+	return blob.Size, nil
+}
+
+func readWorktreeFile(w *gogit.Worktree, name string) ([]byte, error) {
+	f, err := w.Filesystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Spec implements git.SpecProvider.
+func (c *StashCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"apply", "drop", "list", "pop", "push", "show"},
+		Options: []git.Option{
+			{Flags: []string{"-m", "--message"}, Arg: git.ArgText, Usage: "Stash message"},
+			{Flags: []string{"-p", "--patch"}, Usage: "Show the diff"},
+			{Flags: []string{"--stat"}, Usage: "Show a summary of changes"},
+			{Flags: []string{"--index"}, Usage: "Restore the index too"},
+		},
+		Args: []string{git.ArgText},
+	}
 }
 
 func (c *StashCommand) Help() string {
-	return `📘 GIT-STASH (1)                                        Git Manual
-
- 💡 DESCRIPTION
-    ・作業中の変更（コミットしていない内容）を一時的に退避します。
-    ・別のブランチに切り替えたいが、今の作業をコミットしたくない時に使います。
-
- 📋 SYNOPSIS
-    git stash [push]
-    git stash pop
-    git stash list
-
- 🛠  EXAMPLES
-    1. 作業を退避する
-       $ git stash
-       
-    2. 退避したリストを見る
-       $ git stash list
-       
-    3. 最新の退避を復元して消す
-       $ git stash pop
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-stash
-`
+	return git.CommandHelp(context.Background(), "stash")
 }
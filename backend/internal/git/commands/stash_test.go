@@ -7,11 +7,13 @@ import (
 	"time"
 
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStash(t *testing.T) {
@@ -136,3 +138,92 @@ func TestStashStack(t *testing.T) {
 	assert.Contains(t, output, "stash@{0}")
 	assert.NotContains(t, output, "stash@{1}")
 }
+
+func TestStash_IndexAndNamedEntries(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-stash-index")
+	ctx := context.Background()
+	cmd := &StashCommand{}
+	stash := func(args ...string) (string, error) {
+		return cmd.Execute(ctx, s, append([]string{"stash"}, args...))
+	}
+	write := func(name, content string) {
+		require.NoError(t, util.WriteFile(s.Filesystem, "/testrepo/"+name, []byte(content), 0644))
+	}
+	status := func() gogit.Status {
+		w, _ := s.GetRepo().Worktree()
+		st, err := w.Status()
+		require.NoError(t, err)
+		return st
+	}
+
+	// A staged new file and an unstaged modification
+	write("staged.txt", "new\n")
+	_, err := (&AddCommand{}).Execute(ctx, s, []string{"add", "staged.txt"})
+	require.NoError(t, err)
+	write("file.txt", "modified\n")
+
+	out, err := stash("push", "-m", "first change")
+	require.NoError(t, err)
+	assert.Contains(t, out, "On main: first change")
+	assert.True(t, status().IsClean(), "staged new files are stashed too")
+
+	write("file.txt", "second\n")
+	_, err = stash()
+	require.NoError(t, err)
+
+	out, err = stash("list")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "stash@{0}: WIP on main: "))
+	assert.Equal(t, "stash@{1}: On main: first change", lines[1])
+
+	out, err = stash("show", "-p", "stash@{1}")
+	require.NoError(t, err)
+	assert.Contains(t, out, "+modified")
+	assert.Contains(t, out, "staged.txt")
+	out, err = stash("show")
+	require.NoError(t, err)
+	assert.Contains(t, out, "file.txt")
+	assert.NotContains(t, out, "staged.txt")
+
+	// apply keeps the entry; --index restores the staged/unstaged split
+	_, err = stash("apply", "--index", "1")
+	require.NoError(t, err)
+	st := status()
+	assert.Equal(t, gogit.Added, st.File("staged.txt").Staging)
+	assert.Equal(t, gogit.Unmodified, st.File("file.txt").Staging)
+	assert.Equal(t, gogit.Modified, st.File("file.txt").Worktree)
+	out, _ = stash("list")
+	assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 2)
+
+	// Applying over local changes to the same files is refused
+	_, err = stash("apply")
+	assert.ErrorContains(t, err, "would be overwritten")
+
+	// Drop stash@{1}: stash@{0} is kept and still applies
+	_, err = (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--hard"})
+	require.NoError(t, err)
+	_ = s.Filesystem.Remove("/testrepo/staged.txt")
+	out, err = stash("drop", "stash@{1}")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Dropped refs/stash@{1}")
+	out, _ = stash("list")
+	lines = strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "stash@{0}: WIP on main")
+
+	_, err = stash("pop")
+	require.NoError(t, err)
+	data, _ := util.ReadFile(s.Filesystem, "/testrepo/file.txt")
+	assert.Equal(t, "second\n", string(data))
+	assert.Equal(t, gogit.Unmodified, status().File("file.txt").Staging, "pop without --index leaves changes unstaged")
+	out, _ = stash("list")
+	assert.Empty(t, out)
+
+	_, err = stash("drop")
+	assert.Error(t, err)
+	_, err = stash("frobnicate")
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -20,8 +21,10 @@ type StatusCommand struct{}
 var _ git.Command = (*StatusCommand)(nil)
 
 type StatusOptions struct {
-	Short  bool
-	Branch bool
+	Short     bool
+	Branch    bool
+	Ignored   bool
+	Porcelain bool // --porcelain[=v1]: the short format, stable for scripts
 }
 
 func (c *StatusCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
@@ -31,7 +34,7 @@ func (c *StatusCommand) Execute(ctx context.Context, s *git.Session, args []stri
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "status"), nil
 		}
 		return "", err
 	}
@@ -53,12 +56,23 @@ func (c *StatusCommand) parseArgs(args []string) (*StatusOptions, error) {
 			opts.Short = true
 		case "-b", "--branch":
 			opts.Branch = true
+		case "--ignored":
+			opts.Ignored = true
+		case "--porcelain", "--porcelain=v1":
+			opts.Short = true
+			opts.Porcelain = true
+		case "--long":
+			opts.Short = false
+			opts.Porcelain = false
 		case "-sb", "-bs":
 			opts.Short = true
 			opts.Branch = true
 		case "-h", "--help":
 			return nil, fmt.Errorf("help requested")
 		default:
+			if strings.HasPrefix(arg, "--porcelain=") {
+				return nil, fmt.Errorf("fatal: unsupported porcelain version '%s'", strings.TrimPrefix(arg, "--porcelain="))
+			}
 			if strings.HasPrefix(arg, "-") {
 				return nil, fmt.Errorf("error: unknown option `%s`", arg)
 			}
@@ -67,40 +81,106 @@ func (c *StatusCommand) parseArgs(args []string) (*StatusOptions, error) {
 	return opts, nil
 }
 
-func (c *StatusCommand) executeStatus(_ *git.Session, repo *gogit.Repository, opts *StatusOptions) (string, error) {
+func (c *StatusCommand) executeStatus(s *git.Session, repo *gogit.Repository, opts *StatusOptions) (string, error) {
 	w, err := repo.Worktree()
 	if err != nil {
 		return "", err
 	}
-	status, err := w.Status()
+	status, err := git.LFSAwareStatus(repo, w)
 	if err != nil {
 		return "", err
 	}
 
+	// Paths of a stopped merge or rebase whose resolution is not staged yet are unmerged
+	merge := s.MergeInProgress()
+	rebase := s.RebaseInProgress()
+	var conflicts []string
+	if merge != nil {
+		conflicts = append(conflicts, merge.Conflicts...)
+	}
+	if rebase != nil {
+		conflicts = append(conflicts, rebase.Conflicts...)
+	}
+	paths, err := unresolvedPaths(w, conflicts)
+	if err != nil {
+		return "", err
+	}
+	unmerged := make(map[string]bool)
+	for _, path := range paths {
+		unmerged[path] = true
+	}
+
+	var ignored []string
+	if opts.Ignored {
+		ignored = git.LoadIgnoreRules(w.Filesystem).IgnoredPaths(w.Filesystem, git.TrackedPaths(repo))
+	}
+
 	if opts.Short {
-		return c.formatShortInfo(repo, status, opts.Branch)
+		return c.formatShortInfo(repo, status, opts.Branch, unmerged, ignored)
 	}
 
-	return c.formatLongInfo(repo, status)
+	return c.formatLongInfo(repo, status, s.CherryPickInProgress(), s.AmInProgress(), merge, rebase, unmerged, ignored)
 }
 
-func (c *StatusCommand) formatLongInfo(repo *gogit.Repository, status gogit.Status) (string, error) {
+func (c *StatusCommand) formatLongInfo(repo *gogit.Repository, status gogit.Status, cherryPick *git.CherryPickState, am *git.AmState, merge *git.MergeState, rebase *git.RebaseState, unmerged map[string]bool, ignored []string) (string, error) {
 	var sb strings.Builder
 
 	// 1. Branch Info
 	head, err := repo.Head()
-	if err == nil {
-		if head.Name().IsBranch() {
+	unborn := err != nil
+	if !unborn {
+		if rebase != nil {
+			// HEAD is detached at the commits replayed so far
+			sb.WriteString(rebaseHeader(rebase))
+		} else if head.Name().IsBranch() {
 			sb.WriteString(fmt.Sprintf("On branch %s\n", head.Name().Short()))
+			sb.WriteString(upstreamStatus(repo, head.Name().Short(), head.Hash()))
 		} else {
 			sb.WriteString(fmt.Sprintf("HEAD detached at %s\n", head.Hash().String()[:7]))
 		}
 	} else {
-		sb.WriteString("No commits yet\n")
+		if branch := unbornBranch(repo); branch != "" {
+			sb.WriteString(fmt.Sprintf("On branch %s\n", branch))
+		}
+		sb.WriteString("\nNo commits yet\n")
+	}
+
+	if _, sparse := git.SparseCheckoutDirs(repo); sparse {
+		if present, total := git.SparseCheckoutStats(repo); total > 0 {
+			sb.WriteString(fmt.Sprintf("\nYou are in a sparse checkout with %d%% of tracked files present.\n\n", present*100/total))
+		}
+	}
+
+	if cherryPick != nil {
+		sb.WriteString(fmt.Sprintf("You are currently cherry-picking commit %s.\n", cherryPick.Current[:7]))
+		sb.WriteString("  (fix conflicts and run \"git cherry-pick --continue\")\n")
+		sb.WriteString("  (use \"git cherry-pick --abort\" to cancel the cherry-pick operation)\n\n")
+	}
+
+	if am != nil {
+		sb.WriteString("You are in the middle of an am session.\n")
+		sb.WriteString("  (fix conflicts and then run \"git am --continue\")\n")
+		sb.WriteString("  (use \"git am --skip\" to skip this patch)\n")
+		sb.WriteString("  (use \"git am --abort\" to restore the original branch)\n\n")
+	}
+
+	if rebase != nil {
+		sb.WriteString(rebaseProgress(rebase, len(unmerged) > 0))
+	}
+
+	if merge != nil {
+		if len(unmerged) > 0 {
+			sb.WriteString("You have unmerged paths.\n")
+			sb.WriteString("  (fix conflicts and run \"git commit\")\n")
+			sb.WriteString("  (use \"git merge --abort\" to abort the merge)\n")
+		} else {
+			sb.WriteString("All conflicts fixed but you are still merging.\n")
+			sb.WriteString("  (use \"git commit\" to conclude merge)\n")
+		}
 	}
 
 	// 2. Classify Files
-	var staged, unstaged, untracked []string
+	var staged, unstaged, untracked, conflicted []string
 
 	paths := make([]string, 0, len(status))
 	for path := range status {
@@ -111,6 +191,11 @@ func (c *StatusCommand) formatLongInfo(repo *gogit.Repository, status gogit.Stat
 	for _, path := range paths {
 		s := status[path]
 
+		if unmerged[path] {
+			conflicted = append(conflicted, fmt.Sprintf("%-16s%s", "both modified:", path))
+			continue
+		}
+
 		// Untracked
 		if s.Staging == gogit.Untracked {
 			untracked = append(untracked, path)
@@ -133,13 +218,26 @@ func (c *StatusCommand) formatLongInfo(repo *gogit.Repository, status gogit.Stat
 
 	// 3. Print Staged
 	if len(staged) > 0 {
-		sb.WriteString("\nChanges to be committed:\n  (use \"git restore --staged <file>...\" to unstage)\n")
+		unstageHint := "git restore --staged <file>..."
+		if unborn {
+			unstageHint = "git rm --cached <file>..."
+		}
+		sb.WriteString(fmt.Sprintf("\nChanges to be committed:\n  (use \"%s\" to unstage)\n", unstageHint))
 		for _, line := range staged {
 			sb.WriteString(fmt.Sprintf("\t\x1b[32m%s\x1b[0m\n", line)) // Green
 		}
 		hasChanges = true
 	}
 
+	// Print Unmerged
+	if len(conflicted) > 0 {
+		sb.WriteString("\nUnmerged paths:\n  (use \"git add <file>...\" to mark resolution)\n")
+		for _, line := range conflicted {
+			sb.WriteString(fmt.Sprintf("\t\x1b[31m%s\x1b[0m\n", line)) // Red
+		}
+		hasChanges = true
+	}
+
 	// 4. Print Unstaged
 	if len(unstaged) > 0 {
 		sb.WriteString("\nChanges not staged for commit:\n  (use \"git add <file>...\" to update what will be committed)\n  (use \"git restore <file>...\" to discard changes in working directory)\n")
@@ -158,13 +256,120 @@ func (c *StatusCommand) formatLongInfo(repo *gogit.Repository, status gogit.Stat
 		hasChanges = true
 	}
 
-	if !hasChanges {
-		sb.WriteString("nothing to commit, working tree clean\n")
+	// 6. Print Ignored (only with --ignored)
+	if len(ignored) > 0 {
+		sb.WriteString("\nIgnored files:\n  (use \"git add -f <file>...\" to include in what will be committed)\n")
+		for _, line := range ignored {
+			sb.WriteString(fmt.Sprintf("\t\x1b[31m%s\x1b[0m\n", line)) // Red
+		}
+	}
+
+	// 7. Summary, as git words it when nothing is staged
+	switch {
+	case len(staged) > 0 || len(conflicted) > 0:
+	case len(unstaged) > 0:
+		sb.WriteString("\nno changes added to commit (use \"git add\" and/or \"git commit -a\")\n")
+	case len(untracked) > 0:
+		sb.WriteString("\nnothing added to commit but untracked files present (use \"git add\" to track)\n")
+	case !hasChanges && unborn:
+		sb.WriteString("\nnothing to commit (create/copy files and use \"git add\" to track)\n")
+	case !hasChanges:
+		sb.WriteString("\nnothing to commit, working tree clean\n")
 	}
 
 	return sb.String(), nil
 }
 
+// rebaseHeader is the first line of the long format during a rebase, in
+// place of the detached HEAD.
+func rebaseHeader(rb *git.RebaseState) string {
+	kind := "rebase"
+	if rb.Interactive {
+		kind = "interactive rebase"
+	}
+	return fmt.Sprintf("%s in progress; onto %s\n", kind, rb.Onto[:7])
+}
+
+// rebaseProgress tells what a rebase waits for and how to go on, as git's
+// long format does.
+func rebaseProgress(rb *git.RebaseState, conflicted bool) string {
+	var sb strings.Builder
+	if rb.HeadName == "HEAD" {
+		sb.WriteString("You are currently rebasing.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("You are currently rebasing branch '%s' on '%s'.\n", rb.HeadName, rb.Onto[:7]))
+	}
+	switch {
+	case rb.Phase == git.RebasePlanning:
+		sb.WriteString("  (submit the edited todo list to start the rebase)\n")
+		sb.WriteString("  (use \"git rebase --abort\" to check out the original branch)\n")
+	case conflicted:
+		sb.WriteString("  (fix conflicts and then run \"git rebase --continue\")\n")
+		sb.WriteString("  (use \"git rebase --skip\" to skip this patch)\n")
+		sb.WriteString("  (use \"git rebase --abort\" to check out the original branch)\n")
+	default:
+		sb.WriteString("  (all conflicts fixed: run \"git rebase --continue\")\n")
+	}
+	return sb.String()
+}
+
+// unbornBranch returns the branch HEAD points at before its first commit.
+func unbornBranch(repo *gogit.Repository) string {
+	if ref, err := repo.Reference(plumbing.HEAD, false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		return ref.Target().Short()
+	}
+	return ""
+}
+
+// upstreamStatus describes how branch relates to its upstream in the long format.
+// It is empty when branch does not track anything.
+func upstreamStatus(repo *gogit.Repository, branch string, tip plumbing.Hash) string {
+	ref, ok := git.UpstreamRef(repo, branch)
+	if !ok {
+		return ""
+	}
+	upstream := ref.Short()
+	remoteRef, err := repo.Reference(ref, true)
+	if err != nil {
+		return fmt.Sprintf("Your branch is based on '%s', but the upstream is gone.\n  (use \"git branch --unset-upstream\" to fixup)\n", upstream)
+	}
+	ahead, behind := git.AheadBehind(repo, tip, remoteRef.Hash())
+	switch {
+	case ahead == 0 && behind == 0:
+		return fmt.Sprintf("Your branch is up to date with '%s'.\n", upstream)
+	case behind == 0:
+		return fmt.Sprintf("Your branch is ahead of '%s' by %d %s.\n  (use \"git push\" to publish your local commits)\n", upstream, ahead, plural(ahead, "commit", "commits"))
+	case ahead == 0:
+		return fmt.Sprintf("Your branch is behind '%s' by %d %s, and can be fast-forwarded.\n  (use \"git pull\" to update your local branch)\n", upstream, behind, plural(behind, "commit", "commits"))
+	default:
+		return fmt.Sprintf("Your branch and '%s' have diverged,\nand have %d and %d different commits each, respectively.\n  (use \"git pull\" if you want to integrate the remote branch with yours)\n", upstream, ahead, behind)
+	}
+}
+
+// shortBranchHeader renders the "## " line of the short format, e.g.
+// "main...origin/main [ahead 1, behind 2]".
+func shortBranchHeader(repo *gogit.Repository, branch string, tip plumbing.Hash) string {
+	ref, ok := git.UpstreamRef(repo, branch)
+	if !ok {
+		return branch
+	}
+	header := branch + "..." + ref.Short()
+	remoteRef, err := repo.Reference(ref, true)
+	if err != nil {
+		return header + " [gone]"
+	}
+	ahead, behind := git.AheadBehind(repo, tip, remoteRef.Hash())
+	switch {
+	case ahead > 0 && behind > 0:
+		header += fmt.Sprintf(" [ahead %d, behind %d]", ahead, behind)
+	case ahead > 0:
+		header += fmt.Sprintf(" [ahead %d]", ahead)
+	case behind > 0:
+		header += fmt.Sprintf(" [behind %d]", behind)
+	}
+	return header
+}
+
 func mapStatus(s gogit.StatusCode) string {
 	switch s {
 	case gogit.Modified:
@@ -184,19 +389,19 @@ func mapStatus(s gogit.StatusCode) string {
 	}
 }
 
-func (c *StatusCommand) formatShortInfo(repo *gogit.Repository, status gogit.Status, showBranch bool) (string, error) {
+func (c *StatusCommand) formatShortInfo(repo *gogit.Repository, status gogit.Status, showBranch bool, unmerged map[string]bool, ignored []string) (string, error) {
 	var sb strings.Builder
 
 	if showBranch {
 		head, err := repo.Head()
 		if err == nil {
 			if head.Name().IsBranch() {
-				sb.WriteString(fmt.Sprintf("## %s\n", head.Name().Short()))
+				sb.WriteString(fmt.Sprintf("## %s\n", shortBranchHeader(repo, head.Name().Short(), head.Hash())))
 			} else {
-				sb.WriteString(fmt.Sprintf("## HEAD (detached at %s)\n", head.Hash().String()[:7]))
+				sb.WriteString("## HEAD (no branch)\n")
 			}
 		} else {
-			sb.WriteString("## No commits yet\n")
+			sb.WriteString(fmt.Sprintf("## No commits yet on %s\n", unbornBranch(repo)))
 		}
 	}
 
@@ -216,7 +421,10 @@ func (c *StatusCommand) formatShortInfo(repo *gogit.Repository, status gogit.Sta
 		// X (Staging status), Y (Worktree status)
 		var x, y byte
 
-		if s.Staging == gogit.Untracked {
+		if unmerged[path] {
+			x = 'U'
+			y = 'U'
+		} else if s.Staging == gogit.Untracked {
 			x = '?'
 			y = '?'
 		} else {
@@ -227,6 +435,10 @@ func (c *StatusCommand) formatShortInfo(repo *gogit.Repository, status gogit.Sta
 		sb.WriteString(fmt.Sprintf("%c%c %s\n", x, y, path))
 	}
 
+	for _, path := range ignored {
+		sb.WriteString(fmt.Sprintf("!! %s\n", path))
+	}
+
 	return sb.String(), nil
 }
 
@@ -252,36 +464,19 @@ func getStatusCodeChar(c gogit.StatusCode) byte {
 	}
 }
 
+// Spec implements git.SpecProvider.
+func (c *StatusCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-s", "--short"}, Usage: "Short format"},
+			{Flags: []string{"-b", "--branch"}, Usage: "Show the branch in short format"},
+			{Flags: []string{"--porcelain"}, Usage: "Machine-readable format"},
+			{Flags: []string{"--long"}, Usage: "Long format"},
+			{Flags: []string{"--ignored"}, Usage: "Show ignored files"},
+		},
+	}
+}
+
 func (c *StatusCommand) Help() string {
-	return `📘 GIT-STATUS (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ・「どのファイルが変更されたか」を確認する
-    ・「どのファイルがコミット準備できているか」を確認する
-    ・現在のブランチや状況を確認する
-    困ったら、まずこれを打つのが基本です。
-
- 📋 SYNOPSIS
-    git status [-s|--short] [-b|--branch]
-
- ⚙️  COMMON OPTIONS
-    -s, --short
-        変更ファイルだけを簡易表示します。
-    -b, --branch
-        ショート形式(-s)の際にもブランチ情報を表示します。
-        （通常表示ではデフォルトで表示されるため、主に -s と組み合わせて使用します）
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: 現状を確認する
-       手が止まったらとりあえず打って、状況を把握します。
-       $ git status
-
-    2. 実践: 情報を絞って見る (Recommended)
-       ブランチ名と変更ファイルだけを1行ずつ簡易表示します。
-       情報量が絞られて見やすいため、現場ではこれをエイリアス(stなど)に登録して多用します。
-       $ git status -sb
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-status
-`
+	return git.CommandHelp(context.Background(), "status")
 }
@@ -5,7 +5,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatusCommand(t *testing.T) {
@@ -49,3 +55,70 @@ func TestStatusCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestStatusCommand_Formats(t *testing.T) {
+	sm := git.NewSessionManager()
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remote.Worktree()
+	require.NoError(t, util.WriteFile(rw.Filesystem, "README.md", []byte("shared\n"), 0644))
+	_, _ = rw.Add("README.md")
+	_, err := rw.Commit("Initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+	sm.SharedRemotes["origin"] = remote
+
+	ctx := context.Background()
+	s, _ := sm.CreateSession("status-formats")
+	run := func(args ...string) string {
+		t.Helper()
+		res, err := git.Dispatch(ctx, s, args[0], args)
+		require.NoError(t, err, "%v", args)
+		return res.Output()
+	}
+
+	// Unborn branch
+	{
+		s.InitRepo("fresh")
+		s.CurrentDir = "/fresh"
+		out := run("status")
+		assert.Contains(t, out, "\n\nNo commits yet\n")
+		assert.Contains(t, out, "nothing to commit (create/copy files and use \"git add\" to track)")
+		assert.True(t, strings.HasPrefix(run("status", "-sb"), "## No commits yet on "))
+
+		w, _ := s.GetRepo().Worktree()
+		require.NoError(t, util.WriteFile(w.Filesystem, "new.txt", []byte("new"), 0644))
+		assert.Contains(t, run("status"), "nothing added to commit but untracked files present")
+		run("add", "new.txt")
+		assert.Contains(t, run("status"), "(use \"git rm --cached <file>...\" to unstage)")
+		assert.Equal(t, "A  new.txt\n", run("status", "--porcelain"))
+	}
+
+	// Divergence from the upstream
+	{
+		run("clone", "origin", "project")
+		out := run("status")
+		assert.Contains(t, out, "On branch master\nYour branch is up to date with 'origin/master'.\n\nnothing to commit, working tree clean")
+		assert.Equal(t, "## master...origin/master\n", run("status", "--porcelain=v1", "-b"))
+
+		w, _ := s.GetRepo().Worktree()
+		require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("local\n"), 0644))
+		assert.Contains(t, run("status"), "no changes added to commit (use \"git add\" and/or \"git commit -a\")")
+		assert.Equal(t, " M README.md\n", run("status", "--porcelain"))
+		run("add", "README.md")
+		run("commit", "-m", "Local change")
+		assert.Contains(t, run("status"), "Your branch is ahead of 'origin/master' by 1 commit.\n  (use \"git push\" to publish your local commits)")
+
+		_, err := sm.ApplyTeammateAction("origin", git.TeammateAction{Action: git.TeammateCommit, Files: map[string]string{"a.txt": "a"}})
+		require.NoError(t, err)
+		_, err = sm.ApplyTeammateAction("origin", git.TeammateAction{Action: git.TeammateCommit, Files: map[string]string{"b.txt": "b"}})
+		require.NoError(t, err)
+		run("fetch")
+		assert.Contains(t, run("status"), "Your branch and 'origin/master' have diverged,\nand have 1 and 2 different commits each, respectively.")
+		assert.Equal(t, "## master...origin/master [ahead 1, behind 2]\n", run("status", "-sb"))
+
+		run("reset", "--hard", "origin/master~1")
+		assert.Contains(t, run("status"), "Your branch is behind 'origin/master' by 1 commit, and can be fast-forwarded.")
+	}
+
+	_, err = git.Dispatch(ctx, s, "status", []string{"status", "--porcelain=v2"})
+	assert.ErrorContains(t, err, "unsupported porcelain version")
+}
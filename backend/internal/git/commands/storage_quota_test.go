@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageQuota(t *testing.T) {
+	sm := git.NewSessionManager()
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remote.Worktree()
+	remoteCommit := func(i int) {
+		name := fmt.Sprintf("file%d.txt", i)
+		require.NoError(t, util.WriteFile(rw.Filesystem, name, []byte(name), 0644))
+		_, _ = rw.Add(name)
+		_, err := rw.Commit(name, &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+		require.NoError(t, err)
+	}
+	// 3 commits of one new file each: 3 commits, 3 trees, 3 blobs
+	for i := 1; i <= 3; i++ {
+		remoteCommit(i)
+	}
+	sm.SharedRemotes["origin"] = remote
+
+	ctx := context.Background()
+	s, _ := sm.CreateSession("quota")
+	run := func(args ...string) (string, error) {
+		res, err := git.Dispatch(ctx, s, args[0], args)
+		return res.Output(), err
+	}
+
+	// Too many objects: the clone is refused and leaves nothing behind
+	sm.StorageQuota = git.StorageQuota{MaxObjects: 5}
+	_, err := run("clone", "origin", "project")
+	var quotaErr *git.QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, "objects", quotaErr.Resource)
+	assert.Contains(t, err.Error(), "storage quota exceeded")
+	assert.Empty(t, s.Repos)
+	assert.Equal(t, "/", s.CurrentDir)
+	_, statErr := s.Filesystem.Stat("project")
+	assert.Error(t, statErr)
+
+	// Too many checked-out files
+	sm.StorageQuota = git.StorageQuota{MaxFiles: 2}
+	_, err = run("clone", "origin", "project")
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, "files", quotaErr.Resource)
+	assert.Empty(t, s.Repos)
+
+	sm.StorageQuota = git.StorageQuota{}
+	_, err = run("clone", "origin", "project")
+	require.NoError(t, err)
+	usage := s.StorageUsage()
+	assert.Equal(t, 9, usage.Objects)
+	assert.Equal(t, int64(len("file1.txt")*3), usage.BlobBytes)
+	assert.Equal(t, 3, usage.Files)
+
+	// Commit: the staged blob fits, the tree and commit do not
+	sm.StorageQuota = git.StorageQuota{MaxObjects: 10}
+	w, _ := s.GetRepo().Worktree()
+	require.NoError(t, util.WriteFile(w.Filesystem, "local.txt", []byte("local"), 0644))
+	_, err = run("add", "local.txt")
+	require.NoError(t, err)
+	_, err = run("commit", "-m", "local")
+	require.ErrorAs(t, err, &quotaErr)
+
+	// Fetch counts only the objects the session is missing
+	remoteCommit(4)
+	sm.StorageQuota = git.StorageQuota{MaxObjects: 12}
+	_, err = run("fetch")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage quota exceeded")
+	sm.StorageQuota = git.StorageQuota{MaxObjects: 13}
+	out, err := run("fetch")
+	require.NoError(t, err)
+	assert.Contains(t, out, "origin/master")
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatch_Suggestions(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-suggest")
+	ctx := context.Background()
+
+	dispatch := func(input string) *git.CommandResult {
+		name, args := git.ParseCommand(input)
+		result, _ := git.Dispatch(ctx, s, name, args)
+		require.NotNil(t, result, input)
+		return result
+	}
+	require.Equal(t, git.ExitOK, dispatch("git branch feature").ExitCode)
+
+	tests := []struct {
+		input string
+		want  *git.Suggestion
+	}{
+		{"git comit -m 'first try'", &git.Suggestion{Message: "did you mean 'commit'?", Command: `git commit -m "first try"`}},
+		{"sl", &git.Suggestion{Message: "did you mean 'ls'?", Command: "ls"}},
+		{"gti status", &git.Suggestion{Message: "did you mean 'git'?", Command: "git status"}},
+		{"git lo", &git.Suggestion{Message: "did you mean one of these? 'log', 'ls'"}},
+		{"git branch --delte feature", &git.Suggestion{Message: "did you mean '--delete'?", Command: "git branch --delete feature"}},
+		{"git checkout featrue", &git.Suggestion{Message: "did you mean 'feature'?", Command: "git checkout feature"}},
+		{"git checkout topic", &git.Suggestion{Message: "there is no branch 'topic': create it with 'git checkout -b'", Command: "git checkout -b topic"}},
+		{"git switch topic", &git.Suggestion{Message: "there is no branch 'topic': create it with 'git switch -c'", Command: "git switch -c topic"}},
+		{"git merge featur", &git.Suggestion{Message: "did you mean 'feature'?", Command: "git merge feature"}},
+		{"git push", &git.Suggestion{Message: "push the branch and make it track the remote one", Command: "git push --set-upstream origin main"}},
+		{"git fetch origin", &git.Suggestion{Message: "this repository has no remotes yet: add one first", Command: "git remote add origin <url>"}},
+		{"git status", nil},
+		{"git log --oneline --all", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, dispatch(tt.input).Suggestion)
+		})
+	}
+
+	require.Equal(t, git.ExitOK, dispatch("git remote add origin https://example.com/repo.git").ExitCode)
+	assert.Equal(t, &git.Suggestion{Message: "did you mean 'origin'?", Command: "git fetch origin"}, dispatch("git fetch orign").Suggestion)
+}
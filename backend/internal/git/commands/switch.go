@@ -3,10 +3,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/git/commands/checkout"
 )
 
 func init() {
@@ -19,11 +21,11 @@ type SwitchCommand struct{}
 // Ensure SwitchCommand implements git.Command
 var _ git.Command = (*SwitchCommand)(nil)
 
-type SwitchOptions struct {
-	CreateBranch string
-	TargetBranch string
-	Detach       bool
-}
+// Ensure SwitchCommand opts into the engine-level --dry-run
+var _ git.DryRunner = (*SwitchCommand)(nil)
+
+// SupportsDryRun marks switch as simulated by the engine on --dry-run.
+func (c *SwitchCommand) SupportsDryRun() {}
 
 func (c *SwitchCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.Lock()
@@ -31,119 +33,243 @@ func (c *SwitchCommand) Execute(ctx context.Context, s *git.Session, args []stri
 
 	opts, err := c.parseArgs(args)
 	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "switch"), nil
+		}
 		return "", err
 	}
 
 	repo := s.GetRepo()
 	if repo == nil {
-		return "", fmt.Errorf("fatal: not a git repository")
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
 	}
-	w, err := repo.Worktree()
+
+	for _, name := range []string{opts.NewBranch, opts.ForceNewBranch, opts.OrphanBranch} {
+		if name == "" {
+			continue
+		}
+		if err := git.CheckBranchPolicy(s, name); err != nil {
+			return "", err
+		}
+	}
+
+	cCtx, err := c.resolveContext(repo, opts)
 	if err != nil {
 		return "", err
 	}
 
-	return c.executeSwitch(s, repo, w, opts)
+	// Switch never restores paths, so every mode maps onto a checkout strategy
+	var strategy checkout.Strategy
+	switch cCtx.Mode {
+	case checkout.ModeOrphan:
+		strategy = orphanStrategy
+	case checkout.ModeNewBranch:
+		strategy = branchStrategy
+	case checkout.ModeRefOrPath:
+		strategy = refStrategy
+	default:
+		return "", fmt.Errorf("internal error: unknown switch mode")
+	}
+	if err := checkout.CheckBranchInOtherWorktree(s, cCtx, opts); err != nil {
+		return "", err
+	}
+	oldHead, detached := checkout.DetachedHead(repo)
+	out, err := strategy.Execute(s, cCtx, opts)
+	if err != nil {
+		return out, err
+	}
+	sparse, err := checkout.ApplySparse(repo)
+	if err != nil {
+		return "", err
+	}
+	if detached {
+		out = checkout.LeavingDetachedHead(repo, oldHead) + out
+	}
+	return sparse + out, nil
 }
 
-func (c *SwitchCommand) parseArgs(args []string) (*SwitchOptions, error) {
-	if len(args) < 2 {
-		return nil, fmt.Errorf("usage: git switch [-c] <branch>")
-	}
-	opts := &SwitchOptions{}
+// parseArgs maps the switch flags onto checkout options: -c is checkout -b,
+// -C is checkout -B, and a second positional argument is the start point.
+func (c *SwitchCommand) parseArgs(args []string) (*checkout.Options, error) {
+	opts := &checkout.Options{}
 	cmdArgs := args[1:]
+	var positional []string
 
 	for i := 0; i < len(cmdArgs); i++ {
 		arg := cmdArgs[i]
 		switch arg {
-		case "-c", "--create":
-			if i+1 < len(cmdArgs) {
-				opts.CreateBranch = cmdArgs[i+1]
-				i++
+		case "-c", "--create", "-C", "--force-create", "--orphan":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("error: switch `%s' requires a value", strings.TrimLeft(arg, "-"))
+			}
+			i++
+			switch arg {
+			case "-c", "--create":
+				opts.NewBranch = cmdArgs[i]
+			case "-C", "--force-create":
+				opts.ForceNewBranch = cmdArgs[i]
+			default:
+				opts.OrphanBranch = cmdArgs[i]
 			}
 		case "-d", "--detach":
 			opts.Detach = true
+		case "-f", "--force", "--discard-changes":
+			opts.Force = true
+		case "-t", "--track":
+			opts.NoTrack = false
+		case "--no-track":
+			opts.NoTrack = true
+		case "--guess":
+			opts.NoGuess = false
+		case "--no-guess":
+			opts.NoGuess = true
 		case "-h", "--help":
 			return nil, fmt.Errorf("help requested")
 		default:
-			opts.TargetBranch = arg
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				return nil, fmt.Errorf("error: unknown option `%s'\nusage: git switch [<options>] [<branch>]", strings.TrimLeft(arg, "-"))
+			}
+			positional = append(positional, arg)
 		}
 	}
+
+	modes := 0
+	for _, set := range []bool{opts.NewBranch != "", opts.ForceNewBranch != "", opts.OrphanBranch != "", opts.Detach} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, fmt.Errorf("fatal: options '-c', '-C', '--orphan' and '--detach' cannot be used together")
+	}
+	if len(positional) > 1 {
+		return nil, fmt.Errorf("fatal: only one reference expected, %d given.", len(positional))
+	}
+	if len(positional) == 1 {
+		opts.Target = positional[0]
+	}
 	return opts, nil
 }
 
-func (c *SwitchCommand) executeSwitch(s *git.Session, repo *gogit.Repository, w *gogit.Worktree, opts *SwitchOptions) (string, error) {
-	if opts.CreateBranch != "" {
-		// logic for create
-		checkoutOpts := &gogit.CheckoutOptions{
-			Create: true,
-			Branch: plumbing.ReferenceName("refs/heads/" + opts.CreateBranch),
+// resolveContext works out what git switch does, with the same checks as
+// CheckoutCommand.resolveContext but without falling back to paths or
+// detaching HEAD unless --detach is given.
+func (c *SwitchCommand) resolveContext(repo *gogit.Repository, opts *checkout.Options) (*checkout.Context, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	ctx := &checkout.Context{Worktree: w, Repo: repo}
+
+	if opts.Target == "-" {
+		return nil, fmt.Errorf("fatal: switching to the previous branch with '-' is not supported; name the branch instead")
+	}
+
+	if opts.OrphanBranch != "" {
+		if opts.Target != "" {
+			return nil, fmt.Errorf("fatal: '--orphan' cannot take <start-point>")
 		}
-		if err := w.Checkout(checkoutOpts); err != nil {
-			return "", err
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(opts.OrphanBranch), true); err == nil {
+			return nil, fmt.Errorf("fatal: a branch named '%s' already exists", opts.OrphanBranch)
 		}
-		s.RecordReflog(fmt.Sprintf("switch: moving to %s", opts.CreateBranch))
-		return fmt.Sprintf("Switched to a new branch '%s'", opts.CreateBranch), nil
+		ctx.Mode = checkout.ModeOrphan
+		ctx.OrphanBranch = opts.OrphanBranch
+		ctx.ClearWorktree = true
+		return ctx, nil
 	}
 
-	if opts.TargetBranch == "" {
-		return "", fmt.Errorf("missing branch name")
+	if opts.NewBranch != "" || opts.ForceNewBranch != "" {
+		ctx.Mode = checkout.ModeNewBranch
+		ctx.NewBranch = opts.NewBranch
+		if opts.ForceNewBranch != "" {
+			ctx.NewBranch = opts.ForceNewBranch
+			ctx.ForceCreate = true
+		}
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(ctx.NewBranch), true); err == nil && !ctx.ForceCreate {
+			return nil, fmt.Errorf("fatal: a branch named '%s' already exists", ctx.NewBranch)
+		}
+
+		startPoint := opts.Target
+		if startPoint == "" {
+			startPoint = "HEAD"
+		}
+		hash, err := git.ResolveRevision(repo, startPoint)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: invalid reference: %s", startPoint)
+		}
+		ctx.StartPointHash = hash
+		if track, ok := checkout.RemoteTrackingRef(repo, startPoint); ok && !opts.NoTrack {
+			ctx.Track = track
+		}
+		return ctx, nil
 	}
 
-	// Detached HEAD mode
+	ctx.Mode = checkout.ModeRefOrPath
+
 	if opts.Detach {
-		hash, err := repo.ResolveRevision(plumbing.Revision(opts.TargetBranch))
-		if err != nil {
-			return "", fmt.Errorf("fatal: invalid reference: %s", opts.TargetBranch)
+		target := opts.Target
+		if target == "" {
+			target = "HEAD"
 		}
-		err = w.Checkout(&gogit.CheckoutOptions{
-			Hash: *hash,
-		})
+		hash, err := git.ResolveRevision(repo, target)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("fatal: invalid reference: %s", target)
+		}
+		if _, err := repo.CommitObject(*hash); err != nil {
+			return nil, fmt.Errorf("fatal: reference is not a tree: %s", target)
 		}
-		s.RecordReflog(fmt.Sprintf("switch: moving to %s", opts.TargetBranch))
-		return fmt.Sprintf("HEAD is now at %s\n\nYou are in 'detached HEAD' state.", hash.String()[:7]), nil
+		ctx.TargetHash = hash
+		ctx.IsDetached = true
+		return ctx, nil
 	}
 
-	err := w.Checkout(&gogit.CheckoutOptions{
-		Branch: plumbing.ReferenceName("refs/heads/" + opts.TargetBranch),
-	})
-	if err != nil {
-		return "", err
+	if opts.Target == "" {
+		return nil, fmt.Errorf("fatal: missing branch or commit argument")
+	}
+
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName(opts.Target), true); err == nil {
+		ctx.TargetRef = plumbing.NewBranchReferenceName(opts.Target)
+		return ctx, nil
+	}
+	if !opts.NoGuess {
+		if remoteRef, h, ok := checkout.GuessRemoteBranch(repo, opts.Target); ok {
+			ctx.TargetRef = remoteRef
+			ctx.TargetHash = &h
+			return ctx, nil
+		}
+	}
+
+	// Anything else would detach HEAD, which switch only does when asked
+	if _, err := git.ResolveRevision(repo, opts.Target); err == nil {
+		kind := "commit"
+		if _, err := repo.Reference(plumbing.NewTagReferenceName(opts.Target), true); err == nil {
+			kind = "tag"
+		} else if _, ok := checkout.RemoteTrackingRef(repo, opts.Target); ok {
+			kind = "remote branch"
+		}
+		return nil, fmt.Errorf("fatal: a branch is expected, got %s '%s'\nhint: If you want to detach HEAD at the commit, try again with the --detach option.", kind, opts.Target)
+	}
+	return nil, fmt.Errorf("fatal: invalid reference: %s", opts.Target)
+}
+
+// Spec implements git.SpecProvider.
+func (c *SwitchCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-c", "--create"}, Arg: git.ArgText, Usage: "Create a branch and switch to it"},
+			{Flags: []string{"-C", "--force-create"}, Arg: git.ArgText, Usage: "Create or reset a branch and switch to it"},
+			{Flags: []string{"--orphan"}, Arg: git.ArgText, Usage: "Create a branch with no history"},
+			{Flags: []string{"-d", "--detach"}, Usage: "Detach HEAD at the commit"},
+			{Flags: []string{"-f", "--force", "--discard-changes"}, Usage: "Throw away local changes"},
+			{Flags: []string{"-t", "--track"}, Usage: "Set up tracking"},
+			{Flags: []string{"--no-track"}, Usage: "Do not set up tracking"},
+			{Flags: []string{"--guess"}, Usage: "Create a branch from a matching remote branch"},
+			{Flags: []string{"--no-guess"}, Usage: "Do not guess a remote branch"},
+		},
+		Args: []string{git.ArgBranch, git.ArgRef},
 	}
-	s.RecordReflog(fmt.Sprintf("switch: moving to %s", opts.TargetBranch))
-	return fmt.Sprintf("Switched to branch '%s'", opts.TargetBranch), nil
 }
 
 func (c *SwitchCommand) Help() string {
-	return `📘 GIT-SWITCH (1)                                       Git Manual
-
- 💡 DESCRIPTION
-    ・作業するブランチを切り替える
-    ・新しいブランチを作成して、そのまま切り替える（-c）
-    (checkout コマンドから「ブランチ切り替え」機能だけを取り出した分かりやすいコマンドです)
-
- 📋 SYNOPSIS
-    git switch <branch>
-    git switch -c <new-branch>
-
- ⚙️  COMMON OPTIONS
-    -c, --create <new-branch>
-        新しいブランチを作成して切り替えます（` + "`" + `git checkout -b` + "`" + ` 相当）。
-    
-    -d, --detach
-        ブランチではなく、特定のコミットに直接切り替えます（Detached HEAD状態）。
-
- 🛠  PRACTICAL EXAMPLES
-    1. 基本: ブランチを切り替え
-       $ git switch main
-
-    2. 実践: 作成して切り替え (Recommended)
-       「あ、これ新しいブランチで作業したいな」と思ったらこれを使います。
-       $ git switch -c feature/new-idea
-
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-switch
-`
+	return git.CommandHelp(context.Background(), "switch")
 }
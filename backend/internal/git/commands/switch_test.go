@@ -2,12 +2,18 @@ package commands
 
 import (
 	"context"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSwitchCommand(t *testing.T) {
@@ -79,3 +85,91 @@ func TestSwitchCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestSwitchCommand_Parity(t *testing.T) {
+	sm := git.NewSessionManager()
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remote.Worktree()
+	require.NoError(t, util.WriteFile(rw.Filesystem, "README.md", []byte("shared\n"), 0644))
+	_, _ = rw.Add("README.md")
+	_, err := rw.Commit("Initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+	require.NoError(t, rw.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("topic"), Create: true}))
+	require.NoError(t, util.WriteFile(rw.Filesystem, "topic.txt", []byte("topic\n"), 0644))
+	_, _ = rw.Add("topic.txt")
+	topic, err := rw.Commit("Topic", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+	require.NoError(t, rw.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}))
+	sm.SharedRemotes["origin"] = remote
+
+	ctx := context.Background()
+	s, _ := sm.CreateSession("switch-parity")
+	run := func(args ...string) (string, error) {
+		res, err := git.Dispatch(ctx, s, args[0], args)
+		return res.Output(), err
+	}
+	mustRun := func(args ...string) string {
+		t.Helper()
+		out, err := run(args...)
+		require.NoError(t, err, "%v", args)
+		return out
+	}
+	mustRun("clone", "origin", "project")
+	repo := s.GetRepo()
+	headName := func() string {
+		head, err := repo.Head()
+		require.NoError(t, err)
+		return head.Name().Short()
+	}
+
+	// A branch that only exists on the remote is created and tracks it
+	out := mustRun("switch", "topic")
+	assert.Equal(t, "branch 'topic' set up to track 'origin/topic'.\nSwitched to a new branch 'topic'", out)
+	_, merge, ok := git.Upstream(repo, "topic")
+	require.True(t, ok)
+	assert.Equal(t, "topic", merge)
+
+	// -c with a remote-tracking start point tracks it too
+	out = mustRun("switch", "-c", "review", "origin/topic")
+	assert.Contains(t, out, "branch 'review' set up to track 'origin/topic'.")
+	head, _ := repo.Head()
+	assert.Equal(t, topic, head.Hash())
+	mustRun("switch", "--no-track", "-c", "scratch", "origin/topic")
+	_, _, ok = git.Upstream(repo, "scratch")
+	assert.False(t, ok)
+
+	// -C resets an existing branch to the start point
+	out = mustRun("switch", "-C", "review", "master")
+	assert.Equal(t, "Reset branch 'review'", out)
+	assert.Equal(t, "review", headName())
+
+	// Commits and tags need --detach
+	_, err = run("switch", topic.String()[:7])
+	assert.ErrorContains(t, err, "a branch is expected, got commit")
+	_, err = run("switch", "origin/topic")
+	assert.ErrorContains(t, err, "got remote branch 'origin/topic'")
+	out = mustRun("switch", "--detach", "origin/topic")
+	assert.Contains(t, out, "detached HEAD")
+	head, _ = repo.Head()
+	assert.Equal(t, plumbing.HEAD, head.Name())
+	assert.Equal(t, topic, head.Hash())
+
+	// --no-guess does not create branches from the remote
+	_, err = run("switch", "--no-guess", "master2")
+	assert.ErrorContains(t, err, "invalid reference")
+
+	// --orphan starts from an empty index and working tree
+	mustRun("switch", "master")
+	mustRun("switch", "--orphan", "pages")
+	headRef, err := repo.Storer.Reference(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.NewBranchReferenceName("pages"), headRef.Target())
+	_, err = s.Filesystem.Stat("/project/README.md")
+	assert.True(t, os.IsNotExist(err), "tracked files are removed")
+	idx, err := repo.Storer.Index()
+	require.NoError(t, err)
+	assert.Empty(t, idx.Entries)
+
+	_, err = run("switch", "-c", "a", "-C", "b")
+	assert.ErrorContains(t, err, "cannot be used together")
+}
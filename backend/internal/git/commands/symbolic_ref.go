@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -31,14 +32,24 @@ func (c *SymbolicRefCommand) Execute(ctx context.Context, s *git.Session, args [
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "symbolic-ref"), nil
 		}
 		return "", err
 	}
 
+	// Delete mode: git symbolic-ref --delete <name>
+	if opts.Delete {
+		return c.deleteSymbolicRef(repo.Storer, opts.Name, opts.Quiet)
+	}
+
 	// Read mode: git symbolic-ref <name>
 	if opts.Target == "" {
-		return c.readSymbolicRef(repo.Storer, opts.Name, opts.Short)
+		out, err := c.readSymbolicRef(repo.Storer, opts.Name, opts.Short)
+		if err != nil && opts.Quiet {
+			// -q fails silently, for scripts telling a detached HEAD apart
+			return "", errors.New("")
+		}
+		return out, err
 	}
 
 	// Write mode: git symbolic-ref <name> <ref>
@@ -50,6 +61,7 @@ type symbolicRefOptions struct {
 	Target string
 	Short  bool
 	Quiet  bool
+	Delete bool
 }
 
 func (c *SymbolicRefCommand) parseArgs(args []string) (*symbolicRefOptions, error) {
@@ -67,7 +79,7 @@ func (c *SymbolicRefCommand) parseArgs(args []string) (*symbolicRefOptions, erro
 		case "-q", "--quiet":
 			opts.Quiet = true
 		case "-d", "--delete":
-			return nil, fmt.Errorf("error: --delete is not supported in GitGym")
+			opts.Delete = true
 		default:
 			if strings.HasPrefix(arg, "-") {
 				return nil, fmt.Errorf("error: unknown option: %s", arg)
@@ -81,6 +93,9 @@ func (c *SymbolicRefCommand) parseArgs(args []string) (*symbolicRefOptions, erro
 	}
 
 	opts.Name = positional[0]
+	if opts.Delete && len(positional) > 1 {
+		return nil, fmt.Errorf("usage: git symbolic-ref --delete [-q] <name>")
+	}
 	if len(positional) >= 2 {
 		opts.Target = positional[1]
 	}
@@ -118,6 +133,29 @@ func (c *SymbolicRefCommand) readSymbolicRef(storer interface {
 	return target.String(), nil
 }
 
+// deleteSymbolicRef removes a symbolic ref such as refs/remotes/origin/HEAD.
+// HEAD itself cannot be deleted, as a repository always needs one.
+func (c *SymbolicRefCommand) deleteSymbolicRef(storer interface {
+	Reference(plumbing.ReferenceName) (*plumbing.Reference, error)
+	RemoveReference(plumbing.ReferenceName) error
+}, name string, quiet bool) (string, error) {
+	if name == plumbing.HEAD.String() {
+		return "", fmt.Errorf("fatal: deleting '%s' is not allowed", name)
+	}
+	refName := plumbing.ReferenceName(name)
+	ref, err := storer.Reference(refName)
+	if err != nil || ref.Type() != plumbing.SymbolicReference {
+		if quiet {
+			return "", errors.New("")
+		}
+		return "", fmt.Errorf("fatal: Cannot delete %s, not a symbolic ref", name)
+	}
+	if err := storer.RemoveReference(refName); err != nil {
+		return "", fmt.Errorf("error: unable to delete %s: %w", name, err)
+	}
+	return "", nil
+}
+
 func (c *SymbolicRefCommand) writeSymbolicRef(storer interface {
 	SetReference(*plumbing.Reference) error
 }, name string, target string) (string, error) {
@@ -137,38 +175,18 @@ func (c *SymbolicRefCommand) writeSymbolicRef(storer interface {
 	return "", nil
 }
 
-func (c *SymbolicRefCommand) Help() string {
-	return `📘 GIT-SYMBOLIC-REF (1)                                  Git Manual
-
- 💡 DESCRIPTION
-    シンボリック参照を読み書きするための低レベル（Plumbing）コマンドです。
-    主にHEADが指しているブランチを確認・変更するのに使います。
-
- 📋 SYNOPSIS
-    git symbolic-ref <name>
-    git symbolic-ref <name> <ref>
-
- ⚙️  COMMON OPTIONS
-    --short
-        結果を短縮形式で表示します。
-        例: "refs/heads/main" → "main"
-
-    -q, --quiet
-        エラーメッセージを抑制します。
-
- 🛠  PRACTICAL EXAMPLES
-    1. HEADが指しているブランチを確認
-       $ git symbolic-ref HEAD
-       refs/heads/main
-
-    2. 短縮形式で表示
-       $ git symbolic-ref --short HEAD
-       main
-
-    3. HEADを別のブランチに向ける（上級者向け）
-       $ git symbolic-ref HEAD refs/heads/feature
+// Spec implements git.SpecProvider.
+func (c *SymbolicRefCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-d", "--delete"}, Usage: "Delete the symbolic ref"},
+			{Flags: []string{"-q", "--quiet"}, Usage: "Do not complain about detached HEAD"},
+			{Flags: []string{"--short"}, Usage: "Shorten the ref name"},
+		},
+		Args: []string{git.ArgText, git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-symbolic-ref
-`
+func (c *SymbolicRefCommand) Help() string {
+	return git.CommandHelp(context.Background(), "symbolic-ref")
 }
@@ -126,4 +126,31 @@ func TestSymbolicRefCommand(t *testing.T) {
 			t.Errorf("expected refs/heads/master, got: %s", result)
 		}
 	})
+	t.Run("Quiet on non-symbolic ref", func(t *testing.T) {
+		_, err := cmd.Execute(ctx, s, []string{"symbolic-ref", "-q", "refs/heads/feature"})
+		if err == nil || err.Error() != "" {
+			t.Errorf("expected a silent failure, got: %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if _, err := cmd.Execute(ctx, s, []string{"symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/master"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := cmd.Execute(ctx, s, []string{"symbolic-ref", "--delete", "refs/remotes/origin/HEAD"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := cmd.Execute(ctx, s, []string{"symbolic-ref", "refs/remotes/origin/HEAD"}); err == nil {
+			t.Error("expected the symbolic ref to be deleted")
+		}
+
+		_, err := cmd.Execute(ctx, s, []string{"symbolic-ref", "-d", "HEAD"})
+		if err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("deleting HEAD should be refused, got: %v", err)
+		}
+		_, err = cmd.Execute(ctx, s, []string{"symbolic-ref", "-d", "refs/heads/feature"})
+		if err == nil || !strings.Contains(err.Error(), "not a symbolic ref") {
+			t.Errorf("deleting a branch should be refused, got: %v", err)
+		}
+	})
 }
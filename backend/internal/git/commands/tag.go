@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,11 +27,22 @@ type TagOptions struct {
 	List      bool
 	Delete    bool
 	Annotated bool
+	Sign      bool // -s: annotated tag signed with the session key
+	Verify    bool // -v: check the signature of the named tags
+	Force     bool // -f: replace an existing tag
+	Lines     int  // -n<N>: lines of the annotation to show when listing
+	Sort      string
 	Message   string
+	Edit      bool // Let the user write the annotation in the editor
 	TagName   string
 	Commit    string
+	Limit     int    // --limit: maximum number of tags to list
+	After     string // --after: continue listing after this tag name
 }
 
+// tagSortKeys are the --sort keys the listing understands; "-" reverses them.
+var tagSortKeys = map[string]bool{"refname": true, "creatordate": true, "taggerdate": true, "version:refname": true, "v:refname": true}
+
 func (c *TagCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -37,7 +50,7 @@ func (c *TagCommand) Execute(ctx context.Context, s *git.Session, args []string)
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "tag"), nil
 		}
 		return "", err
 	}
@@ -50,10 +63,20 @@ func (c *TagCommand) Execute(ctx context.Context, s *git.Session, args []string)
 	if opts.Delete {
 		return c.deleteTag(repo, opts)
 	}
-	if opts.TagName != "" {
-		return c.createTag(repo, opts)
+	if opts.Verify {
+		if opts.TagName == "" {
+			return "", fmt.Errorf("fatal: tag name required")
+		}
+		return verifyTags(s, repo, []string{opts.TagName})
+	}
+	if opts.TagName != "" && !opts.List {
+		if msg, ok := git.EditedMessage(ctx); ok {
+			opts.Message = msg
+		}
+		opts.Edit = opts.Annotated && opts.Message == "" && git.EditorAvailable(ctx)
+		return c.createTag(s, repo, opts)
 	}
-	return c.listTags(repo)
+	return c.listTags(repo, opts)
 }
 
 func (c *TagCommand) parseArgs(args []string) (*TagOptions, error) {
@@ -67,14 +90,61 @@ func (c *TagCommand) parseArgs(args []string) (*TagOptions, error) {
 			opts.Delete = true
 		case "-a", "--annotate":
 			opts.Annotated = true
+		case "-s", "--sign":
+			opts.Sign = true
+			opts.Annotated = true
+		case "-v", "--verify":
+			opts.Verify = true
+		case "-f", "--force":
+			opts.Force = true
 		case "-m", "--message":
 			if i+1 < len(cmdArgs) {
 				opts.Message = cmdArgs[i+1]
 				i++
 			}
+		case "-l", "--list":
+			opts.List = true
+		case "--limit":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("fatal: option '--limit' requires a value")
+			}
+			n, err := strconv.Atoi(cmdArgs[i+1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("fatal: invalid --limit value: %s", cmdArgs[i+1])
+			}
+			opts.Limit = n
+			opts.List = true
+			i++
+		case "--after":
+			if i+1 >= len(cmdArgs) {
+				return nil, fmt.Errorf("fatal: option '--after' requires a value")
+			}
+			opts.After = cmdArgs[i+1]
+			opts.List = true
+			i++
 		case "-h", "--help":
 			return nil, fmt.Errorf("help requested")
 		default:
+			if strings.HasPrefix(arg, "-n") {
+				opts.Lines = 1
+				if arg != "-n" {
+					n, err := strconv.Atoi(arg[2:])
+					if err != nil || n < 0 {
+						return nil, fmt.Errorf("error: switch `n' expects a numerical value")
+					}
+					opts.Lines = n
+				}
+				opts.List = true
+				continue
+			}
+			if key, ok := strings.CutPrefix(arg, "--sort="); ok {
+				if !tagSortKeys[strings.TrimPrefix(key, "-")] {
+					return nil, fmt.Errorf("fatal: unsupported sort key '%s'\nhint: Supported: refname, creatordate, taggerdate, version:refname", key)
+				}
+				opts.Sort = key
+				opts.List = true
+				continue
+			}
 			if opts.TagName == "" {
 				opts.TagName = arg
 			} else if opts.Commit == "" {
@@ -85,20 +155,157 @@ func (c *TagCommand) parseArgs(args []string) (*TagOptions, error) {
 	return opts, nil
 }
 
-func (c *TagCommand) listTags(repo *gogit.Repository) (string, error) {
+func (c *TagCommand) listTags(repo *gogit.Repository, opts *TagOptions) (string, error) {
 	tags, err := repo.Tags()
 	if err != nil {
 		return "", err
 	}
-	var sb strings.Builder
+	var names []string
 	err = tags.ForEach(func(r *plumbing.Reference) error {
-		sb.WriteString(r.Name().Short() + "\n")
+		names = append(names, r.Name().Short())
 		return nil
 	})
 	if err != nil {
 		return "", err
 	}
-	return sb.String(), nil
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	// In list mode the positional argument is a glob pattern, e.g. "v1.*"
+	pattern := ""
+	if opts.List {
+		pattern = opts.TagName
+	}
+	if opts.Sort != "" {
+		if opts.Limit > 0 || opts.After != "" {
+			return "", fmt.Errorf("fatal: --sort cannot be combined with --limit or --after")
+		}
+		// Filter and order by name first, then apply the requested order
+		listed, err := formatRefListing(names, pattern, "", 0, "tags")
+		if err != nil || listed == "" {
+			return "", err
+		}
+		names = strings.Split(listed, "\n")
+		sortTags(repo, names, opts.Sort)
+		return formatTagLines(repo, names, opts.Lines) + "\n", nil
+	}
+	out, err := formatRefListing(names, pattern, opts.After, opts.Limit, "tags")
+	if err != nil {
+		return "", err
+	}
+	if opts.Lines > 0 {
+		page, more, _ := strings.Cut(out, "\n... ")
+		out = formatTagLines(repo, strings.Split(page, "\n"), opts.Lines)
+		if more != "" {
+			out += "\n... " + more
+		}
+	}
+	return out + "\n", nil
+}
+
+// sortTags orders names (already sorted by name) by a --sort key such as "-creatordate".
+func sortTags(repo *gogit.Repository, names []string, key string) {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+	less := func(i, j int) bool { return names[i] < names[j] }
+	switch key {
+	case "creatordate", "taggerdate":
+		dates := make(map[string]time.Time, len(names))
+		for _, n := range names {
+			dates[n] = tagDate(repo, n, key == "creatordate")
+		}
+		less = func(i, j int) bool { return dates[names[i]].Before(dates[names[j]]) }
+	case "version:refname", "v:refname":
+		less = func(i, j int) bool { return versionLess(names[i], names[j]) }
+	}
+	if desc {
+		sort.SliceStable(names, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(names, less)
+	}
+}
+
+// tagDate returns when the tag was made: the tagger date of an annotated tag,
+// or for a lightweight tag the committer date of its commit (creatordate only).
+func tagDate(repo *gogit.Repository, name string, creator bool) time.Time {
+	ref, err := repo.Tag(name)
+	if err != nil {
+		return time.Time{}
+	}
+	if tag, err := repo.TagObject(ref.Hash()); err == nil {
+		return tag.Tagger.When
+	}
+	if !creator {
+		return time.Time{}
+	}
+	if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+		return commit.Committer.When
+	}
+	return time.Time{}
+}
+
+// versionLess compares tag names treating runs of digits as numbers, so v1.10 sorts after v1.9.
+func versionLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, _ := strconv.Atoi(da)
+			nb, _ := strconv.Atoi(db)
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// formatTagLines lists names with the first lines of their annotation, like
+// "git tag -n": lightweight tags show the subject of their commit instead.
+func formatTagLines(repo *gogit.Repository, names []string, lines int) string {
+	if lines <= 0 {
+		return strings.Join(names, "\n")
+	}
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		var message string
+		if ref, err := repo.Tag(name); err == nil {
+			if tag, err := repo.TagObject(ref.Hash()); err == nil {
+				message = tag.Message
+			} else if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+				message = commit.Message
+			}
+		}
+		msgLines := strings.Split(strings.TrimSpace(message), "\n")
+		if len(msgLines) > lines {
+			msgLines = msgLines[:lines]
+		}
+		sb.WriteString(fmt.Sprintf("%-15s %s", name, strings.TrimSpace(msgLines[0])))
+		for _, l := range msgLines[1:] {
+			sb.WriteString("\n")
+			if l = strings.TrimSpace(l); l != "" {
+				sb.WriteString(strings.Repeat(" ", 16) + l)
+			}
+		}
+	}
+	return sb.String()
 }
 
 func (c *TagCommand) deleteTag(repo *gogit.Repository, opts *TagOptions) (string, error) {
@@ -111,7 +318,7 @@ func (c *TagCommand) deleteTag(repo *gogit.Repository, opts *TagOptions) (string
 	return "Deleted tag " + opts.TagName, nil
 }
 
-func (c *TagCommand) createTag(repo *gogit.Repository, opts *TagOptions) (string, error) {
+func (c *TagCommand) createTag(s *git.Session, repo *gogit.Repository, opts *TagOptions) (string, error) {
 	var targetRef *plumbing.Reference
 	var err error
 
@@ -132,65 +339,132 @@ func (c *TagCommand) createTag(repo *gogit.Repository, opts *TagOptions) (string
 		}
 	}
 
+	// An existing tag is only replaced with -f, and the old target is
+	// reported instead of the new tag, as git does
+	refName := plumbing.NewTagReferenceName(opts.TagName)
+	replaced := ""
+	report := func(created string) string {
+		if replaced != "" {
+			return replaced
+		}
+		return created
+	}
+	existing, errExisting := repo.Reference(refName, false)
+	if errExisting == nil && !opts.Force {
+		return "", fmt.Errorf("fatal: tag '%s' already exists", opts.TagName)
+	}
+	if opts.Edit {
+		return "", &git.EditorRequest{
+			File: git.TagEditFile,
+			Template: "\n" + git.CommentLines(
+				"Write a message for tag:",
+				"  "+opts.TagName,
+				"Lines starting with '#' will be ignored.",
+			),
+			Abort: "fatal: no tag message?",
+		}
+	}
+	if errExisting == nil {
+		if err := repo.Storer.RemoveReference(refName); err != nil {
+			return "", err
+		}
+		replaced = fmt.Sprintf("Updated tag '%s' (was %s)", opts.TagName, existing.Hash().String()[:7])
+	}
+
 	if opts.Annotated {
 		msg := opts.Message
 		if msg == "" {
 			msg = "Tag message"
 		}
+		tagger := s.Signature()
+		if opts.Sign {
+			if err := createSignedTag(s, repo, opts.TagName, targetRef.Hash(), msg, tagger); err != nil {
+				return "", err
+			}
+			return report("Created signed tag " + opts.TagName), nil
+		}
 		_, err = repo.CreateTag(opts.TagName, targetRef.Hash(), &gogit.CreateTagOptions{
 			Message: msg,
-			Tagger: &object.Signature{
-				Name:  "User",
-				Email: "user@example.com",
-				When:  time.Now(),
-			},
+			Tagger:  tagger,
 		})
 		if err != nil {
 			return "", err
 		}
-		return "Created annotated tag " + opts.TagName, nil
+		return report("Created annotated tag " + opts.TagName), nil
 	}
 
 	// Lightweight
-	refName := plumbing.ReferenceName("refs/tags/" + opts.TagName)
 	ref := plumbing.NewHashReference(refName, targetRef.Hash())
 	if err := repo.Storer.SetReference(ref); err != nil {
 		return "", err
 	}
-	return "Created tag " + opts.TagName, nil
+	return report("Created tag " + opts.TagName), nil
 }
 
-func (c *TagCommand) Help() string {
-	return `📘 GIT-TAG (1)                                          Git Manual
-
- 💡 DESCRIPTION
-    タグ（コミットにつける名前・目印）に関する以下の操作を行います：
-    ・タグの一覧を表示する（引数なし）
-    ・新しいタグを作成する
-    ・不要なタグを削除する（-d）
-
- 📋 SYNOPSIS
-    git tag [-a] [-m <msg>] <tagname> [<commit>]
-    git tag -d <tagname>
-
- ⚙️  COMMON OPTIONS
-    -a
-        注釈付き（Annotated）タグを作成します。作成者や日時などの情報を含めます。
-
-    -m <msg>
-        タグのメッセージを指定します。
+// createSignedTag stores an annotated tag signed with the session key.
+// go-git only signs tags with real OpenPGP keys, so the object is built here.
+func createSignedTag(s *git.Session, repo *gogit.Repository, name string, target plumbing.Hash, message string, tagger *object.Signature) error {
+	refName := plumbing.NewTagReferenceName(name)
+	if _, err := repo.Reference(refName, false); err == nil {
+		return gogit.ErrTagExists
+	}
+	targetObj, err := repo.Storer.EncodedObject(plumbing.AnyObject, target)
+	if err != nil {
+		return err
+	}
 
-    -d
-        タグを削除します。
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     *tagger,
+		Message:    strings.TrimSpace(message) + "\n",
+		TargetType: targetObj.Type(),
+		Target:     target,
+	}
+	payload := &plumbing.MemoryObject{}
+	if err := tag.EncodeWithoutSignature(payload); err != nil {
+		return err
+	}
+	r, err := payload.Reader()
+	if err != nil {
+		return err
+	}
+	sig, err := s.SigningKey().Sign(r)
+	if err != nil {
+		return err
+	}
+	tag.PGPSignature = string(sig)
 
- 🛠  EXAMPLES
-    1. 軽量タグを作成（現在のHEADに）
-       $ git tag v1.0
+	obj := repo.Storer.NewEncodedObject()
+	if err := tag.Encode(obj); err != nil {
+		return err
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(refName, hash))
+}
 
-    2. 注釈付きタグを作成
-       $ git tag -a v1.0 -m "Release version 1.0"
+// Spec implements git.SpecProvider.
+func (c *TagCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-a", "--annotate"}, Usage: "Create an annotated tag"},
+			{Flags: []string{"-m", "--message"}, Arg: git.ArgText, Usage: "Tag message"},
+			{Flags: []string{"-s", "--sign"}, Usage: "Sign the tag"},
+			{Flags: []string{"-d", "--delete"}, Usage: "Delete tags"},
+			{Flags: []string{"-f", "--force"}, Usage: "Replace a tag that exists"},
+			{Flags: []string{"-l", "--list"}, Usage: "List tags"},
+			{Flags: []string{"-v", "--verify"}, Usage: "Check tag signatures"},
+			{Flags: []string{"-n"}, Usage: "Show tag messages"},
+			{Flags: []string{"--sort"}, Arg: git.ArgText, Usage: "Sort the list"},
+			{Flags: []string{"--limit"}, Arg: git.ArgText, Usage: "List at most n tags"},
+			{Flags: []string{"--after"}, Arg: git.ArgTag, Usage: "List tags after this one"},
+		},
+		Args: []string{git.ArgTag, git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-tag
-`
+func (c *TagCommand) Help() string {
+	return git.CommandHelp(context.Background(), "tag")
 }
@@ -89,3 +89,57 @@ func TestTagCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestTagCommand_ListSortAndForce(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-tag-list-sort")
+	ctx := context.Background()
+	cmd := &TagCommand{}
+
+	run := func(args ...string) string {
+		out, err := cmd.Execute(ctx, s, append([]string{"tag"}, args...))
+		if err != nil {
+			t.Fatalf("tag %v failed: %v", args, err)
+		}
+		return out
+	}
+
+	run("-a", "v1.9", "-m", "Release 1.9\n\nSecond line")
+	run("v1.10")
+	run("-a", "v1.2", "-m", "Release 1.2")
+
+	if out := run("-n"); !strings.Contains(out, "v1.10           Initial commit") || !strings.Contains(out, "v1.9            Release 1.9\n") {
+		t.Errorf("Expected one annotation line per tag, got:\n%s", out)
+	}
+	if out := run("-n3", "-l", "v1.9"); !strings.Contains(out, "Release 1.9\n\n                Second line") {
+		t.Errorf("Expected the later annotation lines, got:\n%s", out)
+	}
+	if out := run("--sort=version:refname"); out != "v1.2\nv1.9\nv1.10\n" {
+		t.Errorf("Expected version order, got %q", out)
+	}
+	if out := run("--sort=-refname", "v1.1*"); out != "v1.10\n" {
+		t.Errorf("Expected the pattern to apply with --sort, got %q", out)
+	}
+	if _, err := cmd.Execute(ctx, s, []string{"tag", "--sort=size"}); err == nil {
+		t.Error("Expected an unsupported sort key to fail")
+	}
+
+	if _, err := cmd.Execute(ctx, s, []string{"tag", "v1.10"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing tag to be refused, got %v", err)
+	}
+	if _, err := (&CommitCommand{}).Execute(ctx, s, []string{"commit", "--allow-empty", "-m", "second"}); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := s.GetRepo().Tag("v1.10")
+	if out, want := run("-f", "v1.10"), "Updated tag 'v1.10' (was "+old.Hash().String()[:7]+")"; out != want {
+		t.Errorf("Expected only %q, got %q", want, out)
+	}
+	head, _ := s.GetRepo().Head()
+	ref, _ := s.GetRepo().Tag("v1.10")
+	if ref.Hash() != head.Hash() {
+		t.Errorf("Expected v1.10 to move to HEAD")
+	}
+	if out := run("-n", "--sort=-creatordate"); !strings.HasPrefix(out, "v1.10           second") {
+		t.Errorf("Expected the moved tag first, got:\n%s", out)
+	}
+}
@@ -0,0 +1,244 @@
+package commands
+
+// undo.go - GitGym convenience command (not part of real Git)
+//
+// Looks at the most recent operation in the session reflog and performs the
+// inverse that an experienced Git user would reach for:
+//
+//	commit            -> git reset --soft HEAD~1
+//	commit --amend    -> git reset --soft ORIG_HEAD
+//	merge/rebase/...  -> git reset --hard ORIG_HEAD
+//	reset             -> git reset ORIG_HEAD
+//	branch -d/-D      -> git branch <name> <old-hash>
+//
+// The output always spells out the equivalent real command so learners can
+// repeat it outside the gym.
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("undo", func() git.Command { return &UndoCommand{} })
+}
+
+// reflogBranchDeletePrefix is the reflog message prefix written by "git branch -d".
+const reflogBranchDeletePrefix = "branch: deleted "
+
+var branchDeletePattern = regexp.MustCompile(`^branch: deleted (\S+) \(was ([0-9a-f]{40})\)$`)
+
+type UndoCommand struct{}
+
+// Ensure UndoCommand implements git.Command
+var _ git.Command = (*UndoCommand)(nil)
+
+type UndoOptions struct {
+	DryRun bool
+}
+
+// undoPlan describes the inverse of a recorded operation.
+type undoPlan struct {
+	Description string // What is being undone, for the user
+	Equivalent  string // The real git command that achieves the same
+	apply       func() error
+}
+
+func (c *UndoCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	opts, err := c.parseArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "undo"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	entry, ok := c.lastEntry(s)
+	if !ok {
+		return "", fmt.Errorf("nothing to undo: no operations recorded in this repository yet")
+	}
+
+	plan, err := c.planFor(repo, entry)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.DryRun {
+		return fmt.Sprintf("[dry-run] Would undo: %s\n  equivalent: %s", plan.Description, plan.Equivalent), nil
+	}
+
+	if err := plan.apply(); err != nil {
+		return "", fmt.Errorf("undo failed: %w", err)
+	}
+	s.RecordReflog("undo: " + entry.Message)
+
+	return fmt.Sprintf("Undid: %s\n  equivalent: %s\nhint: Run 'git reflog' to see every position HEAD has been at.", plan.Description, plan.Equivalent), nil
+}
+
+func (c *UndoCommand) parseArgs(args []string) (*UndoOptions, error) {
+	opts := &UndoOptions{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-n", "--dry-run":
+			opts.DryRun = true
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		default:
+			return nil, fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// lastEntry returns the most recent HEAD reflog entry of the current repository.
+func (c *UndoCommand) lastEntry(s *git.Session) (git.ReflogEntry, bool) {
+	entries, err := s.RefReflog("HEAD")
+	if err != nil || len(entries) == 0 {
+		return git.ReflogEntry{}, false
+	}
+	return entries[0], true
+}
+
+func (c *UndoCommand) planFor(repo *gogit.Repository, entry git.ReflogEntry) (*undoPlan, error) {
+	msg := entry.Message
+
+	if strings.HasPrefix(msg, "undo: ") {
+		return nil, fmt.Errorf("nothing to undo: the last operation was already an undo\nhint: Use 'git reflog' and 'git reset' to move to any earlier state.")
+	}
+
+	if m := branchDeletePattern.FindStringSubmatch(msg); m != nil {
+		name, hash := m[1], plumbing.NewHash(m[2])
+		return &undoPlan{
+			Description: fmt.Sprintf("delete of branch '%s'", name),
+			Equivalent:  fmt.Sprintf("git branch %s %s", name, hash.String()[:7]),
+			apply: func() error {
+				refName := plumbing.NewBranchReferenceName(name)
+				if _, err := repo.Reference(refName, false); err == nil {
+					return fmt.Errorf("a branch named '%s' already exists", name)
+				}
+				return repo.Storer.SetReference(plumbing.NewHashReference(refName, hash))
+			},
+		}, nil
+	}
+
+	// Every remaining inverse moves HEAD, so refuse if HEAD has moved since the entry was recorded.
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("nothing to undo: HEAD does not point to a commit")
+	}
+	if entry.Hash != head.Hash().String() {
+		return nil, fmt.Errorf("cannot undo '%s': HEAD has moved since then\nhint: Use 'git reflog' to find the state you want and 'git reset' to return to it.", msg)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasPrefix(msg, "commit (amend):"):
+		orig, err := c.origHead(repo)
+		if err != nil {
+			return nil, err
+		}
+		return &undoPlan{
+			Description: "commit --amend (amended changes are left staged)",
+			Equivalent:  "git reset --soft ORIG_HEAD",
+			apply:       c.reset(w, orig, gogit.SoftReset),
+		}, nil
+
+	case strings.HasPrefix(msg, "commit:"):
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if commit.NumParents() == 0 {
+			return nil, fmt.Errorf("cannot undo the initial commit: there is no earlier commit to return to")
+		}
+		return &undoPlan{
+			Description: fmt.Sprintf("commit %s (changes are left staged)", head.Hash().String()[:7]),
+			Equivalent:  "git reset --soft HEAD~1",
+			apply:       c.reset(w, commit.ParentHashes[0], gogit.SoftReset),
+		}, nil
+
+	case strings.HasPrefix(msg, "reset:"):
+		orig, err := c.origHead(repo)
+		if err != nil {
+			return nil, err
+		}
+		mode, modeName := gogit.MixedReset, ""
+		if clean, _ := isWorktreeClean(repo, w); clean {
+			mode, modeName = gogit.HardReset, "--hard "
+		}
+		return &undoPlan{
+			Description: strings.TrimPrefix(msg, "reset: "),
+			Equivalent:  fmt.Sprintf("git reset %sORIG_HEAD", modeName),
+			apply:       c.reset(w, orig, mode),
+		}, nil
+
+	case strings.HasPrefix(msg, "merge "), strings.HasPrefix(msg, "rebase"), strings.HasPrefix(msg, "squash:"):
+		orig, err := c.origHead(repo)
+		if err != nil {
+			return nil, err
+		}
+		if clean, _ := isWorktreeClean(repo, w); !clean {
+			return nil, fmt.Errorf("cannot undo '%s' with uncommitted changes\nhint: Commit or stash your changes first ('git stash')", msg)
+		}
+		return &undoPlan{
+			Description: msg,
+			Equivalent:  "git reset --hard ORIG_HEAD",
+			apply:       c.reset(w, orig, gogit.HardReset),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("cannot undo '%s' automatically\nhint: Use 'git reflog' to find the state you want and 'git reset' to return to it.", msg)
+}
+
+func (c *UndoCommand) origHead(repo *gogit.Repository) (plumbing.Hash, error) {
+	ref, err := repo.Reference("ORIG_HEAD", true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot undo: ORIG_HEAD is not set")
+	}
+	return ref.Hash(), nil
+}
+
+func (c *UndoCommand) reset(w *gogit.Worktree, target plumbing.Hash, mode gogit.ResetMode) func() error {
+	return func() error {
+		return w.Reset(&gogit.ResetOptions{Commit: target, Mode: mode})
+	}
+}
+
+func isWorktreeClean(repo *gogit.Repository, w *gogit.Worktree) (bool, error) {
+	status, err := git.LFSAwareStatus(repo, w)
+	if err != nil {
+		return false, err
+	}
+	return status.IsClean(), nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *UndoCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-n", "--dry-run"}, Usage: "Only show what would be undone"},
+		},
+	}
+}
+
+func (c *UndoCommand) Help() string {
+	return git.CommandHelp(context.Background(), "undo")
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestUndoCommand_Commit(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-undo-commit")
+	ctx := context.Background()
+	repo := s.GetRepo()
+
+	initial, _ := repo.Head()
+
+	(&TouchCommand{}).Execute(ctx, s, []string{"touch", "second.txt"})
+	(&AddCommand{}).Execute(ctx, s, []string{"add", "second.txt"})
+	(&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Second"})
+
+	res, err := (&UndoCommand{}).Execute(ctx, s, []string{"undo"})
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if !strings.Contains(res, "git reset --soft HEAD~1") {
+		t.Errorf("Expected equivalent command in output, got: %s", res)
+	}
+
+	head, _ := repo.Head()
+	if head.Hash() != initial.Hash() {
+		t.Errorf("Expected HEAD back at initial commit")
+	}
+
+	w, _ := repo.Worktree()
+	status, _ := w.Status()
+	if status.File("second.txt").Staging == ' ' {
+		t.Errorf("Expected second.txt to remain staged")
+	}
+
+	if _, err := (&UndoCommand{}).Execute(ctx, s, []string{"undo"}); err == nil {
+		t.Error("Expected undo of an undo to be refused")
+	}
+}
+
+func TestUndoCommand_Reset(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-undo-reset")
+	ctx := context.Background()
+	repo := s.GetRepo()
+
+	(&TouchCommand{}).Execute(ctx, s, []string{"touch", "second.txt"})
+	(&AddCommand{}).Execute(ctx, s, []string{"add", "second.txt"})
+	(&CommitCommand{}).Execute(ctx, s, []string{"commit", "-m", "Second"})
+	before, _ := repo.Head()
+
+	if _, err := (&ResetCommand{}).Execute(ctx, s, []string{"reset", "--hard", "HEAD~1"}); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+
+	if _, err := (&UndoCommand{}).Execute(ctx, s, []string{"undo"}); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	head, _ := repo.Head()
+	if head.Hash() != before.Hash() {
+		t.Errorf("Expected HEAD restored to %s, got %s", before.Hash(), head.Hash())
+	}
+}
+
+func TestUndoCommand_BranchDelete(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-undo-branch")
+	ctx := context.Background()
+	repo := s.GetRepo()
+
+	(&BranchCommand{}).Execute(ctx, s, []string{"branch", "topic"})
+	if _, err := (&BranchCommand{}).Execute(ctx, s, []string{"branch", "-D", "topic"}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	res, err := (&UndoCommand{}).Execute(ctx, s, []string{"undo", "--dry-run"})
+	if err != nil || !strings.Contains(res, "[dry-run]") {
+		t.Fatalf("dry-run failed: %v %s", err, res)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("topic"), true); err == nil {
+		t.Fatal("dry-run must not recreate the branch")
+	}
+
+	if _, err := (&UndoCommand{}).Execute(ctx, s, []string{"undo"}); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("topic"), true); err != nil {
+		t.Errorf("Expected branch 'topic' to be recreated")
+	}
+}
@@ -32,7 +32,7 @@ func (c *UpdateRefCommand) Execute(ctx context.Context, s *git.Session, args []s
 	opts, err := c.parseArgs(args)
 	if err != nil {
 		if err.Error() == "help requested" {
-			return c.Help(), nil
+			return git.CommandHelp(ctx, "update-ref"), nil
 		}
 		return "", err
 	}
@@ -140,42 +140,18 @@ func (c *UpdateRefCommand) deleteRef(repo *gogit.Repository, refName string) (st
 	return "", nil
 }
 
-func (c *UpdateRefCommand) Help() string {
-	return `📘 GIT-UPDATE-REF (1)                                    Git Manual
-
- 💡 DESCRIPTION
-    参照（ブランチやタグ）が指すコミットを直接更新する低レベル（Plumbing）コマンドです。
-    主にブランチのポインタを特定のコミットに移動するのに使います。
-
- 📋 SYNOPSIS
-    git update-ref <ref> <newvalue>
-    git update-ref -d <ref>
-
- ⚙️  COMMON OPTIONS
-    -d, --delete
-        指定した参照を削除します。
-
-    --no-deref
-        シンボリックリンクを辿らず、参照を直接更新します。
-
-    -m <reason>
-        reflogに記録するメッセージを指定します。
-
- 🛠  PRACTICAL EXAMPLES
-    1. ブランチを特定のコミットに移動
-       $ git update-ref refs/heads/main abc1234
-
-    2. 短縮形式（refs/heads/が自動補完）
-       $ git update-ref main HEAD~3
-
-    3. ブランチの削除
-       $ git update-ref -d refs/heads/old-branch
-
- 💡 TIPS
-    - git reset --soft を使わずに、ブランチポインタだけを移動したい場合に便利
-    - インデックスやワーキングツリーは変更されません
+// Spec implements git.SpecProvider.
+func (c *UpdateRefCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-d", "--delete"}, Usage: "Delete the ref"},
+			{Flags: []string{"-m"}, Arg: git.ArgText, Usage: "Reflog message"},
+			{Flags: []string{"--no-deref"}, Usage: "Update the symbolic ref itself"},
+		},
+		Args: []string{git.ArgText, git.ArgRef},
+	}
+}
 
- 🔗 REFERENCE
-    Full documentation: https://git-scm.com/docs/git-update-ref
-`
+func (c *UpdateRefCommand) Help() string {
+	return git.CommandHelp(context.Background(), "update-ref")
 }
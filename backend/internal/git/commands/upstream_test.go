@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamTracking(t *testing.T) {
+	sm := git.NewSessionManager()
+	remote, _ := gogit.Init(memory.NewStorage(), memfs.New())
+	rw, _ := remote.Worktree()
+	require.NoError(t, util.WriteFile(rw.Filesystem, "README.md", []byte("shared\n"), 0644))
+	_, _ = rw.Add("README.md")
+	_, err := rw.Commit("Initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+	sm.SharedRemotes["origin"] = remote
+
+	ctx := context.Background()
+	s, _ := sm.CreateSession("upstream")
+	run := func(args ...string) (string, error) {
+		res, err := git.Dispatch(ctx, s, args[0], args)
+		return res.Output(), err
+	}
+	mustRun := func(args ...string) string {
+		t.Helper()
+		out, err := run(args...)
+		require.NoError(t, err, "%v", args)
+		return out
+	}
+	commit := func(file, msg string) {
+		t.Helper()
+		w, _ := s.GetRepo().Worktree()
+		require.NoError(t, util.WriteFile(w.Filesystem, file, []byte(msg), 0644))
+		mustRun("add", file)
+		mustRun("commit", "-m", msg)
+	}
+
+	mustRun("clone", "origin", "project")
+	repo := s.GetRepo()
+	remoteName, merge, ok := git.Upstream(repo, "master")
+	require.True(t, ok, "clone tracks the default branch")
+	assert.Equal(t, "origin", remoteName)
+	assert.Equal(t, "master", merge)
+
+	// push -u records the upstream of a new branch
+	mustRun("checkout", "-b", "feature")
+	commit("feature.txt", "Add feature")
+	assert.Contains(t, mustRun("branch", "-vv"), "* feature", "untracked branches list without an upstream")
+	out := mustRun("push", "-u", "origin", "feature")
+	assert.Contains(t, out, "branch 'feature' set up to track 'origin/feature'.")
+	_, merge, ok = git.Upstream(repo, "feature")
+	require.True(t, ok)
+	assert.Equal(t, "feature", merge)
+
+	commit("feature.txt", "Polish feature")
+	assert.Contains(t, mustRun("branch", "-vv"), "[origin/feature: ahead 1] Polish feature")
+	assert.NotContains(t, mustRun("branch", "-v"), "origin/feature")
+
+	// A teammate moves master; a bare pull uses the configured upstream
+	_, err = sm.ApplyTeammateAction("origin", git.TeammateAction{Action: git.TeammateCommit, Files: map[string]string{"team.txt": "team"}})
+	require.NoError(t, err)
+	mustRun("checkout", "master")
+	mustRun("fetch")
+	assert.Contains(t, mustRun("branch", "-vv"), "[origin/master: behind 1]")
+	assert.Contains(t, mustRun("pull"), "Fast-forward")
+	assert.Contains(t, mustRun("branch", "-vv"), "* master  ")
+
+	// --set-upstream-to and --unset-upstream
+	mustRun("checkout", "-b", "topic")
+	_, err = run("pull")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "There is no tracking information for the current branch.")
+	_, err = run("branch", "--set-upstream-to=origin/missing")
+	assert.ErrorContains(t, err, "the requested upstream branch 'origin/missing' does not exist")
+	assert.Equal(t, "branch 'topic' set up to track 'origin/master'.", mustRun("branch", "--set-upstream-to=origin/master"))
+	assert.Contains(t, mustRun("pull"), "Already up to date.")
+	mustRun("branch", "--unset-upstream", "topic")
+	_, _, ok = git.Upstream(repo, "topic")
+	assert.False(t, ok)
+
+	// Renaming and deleting branches carry their tracking configuration along
+	mustRun("checkout", "master")
+	mustRun("branch", "-m", "feature", "feature-renamed")
+	_, merge, ok = git.Upstream(repo, "feature-renamed")
+	require.True(t, ok)
+	assert.Equal(t, "feature", merge)
+	mustRun("branch", "-D", "feature-renamed")
+	_, _, ok = git.Upstream(repo, "feature-renamed")
+	assert.False(t, ok)
+}
@@ -0,0 +1,178 @@
+package commands
+
+// verify_commit.go - Simulated Git Verify-Commit / Verify-Tag Commands
+//
+// Checks the simulated GPG signatures made by commit -S and tag -s against the
+// session's signing key.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func init() {
+	git.RegisterCommand("verify-commit", func() git.Command { return &VerifyCommitCommand{} })
+	git.RegisterCommand("verify-tag", func() git.Command { return &VerifyTagCommand{} })
+}
+
+type VerifyCommitCommand struct{}
+
+// Ensure VerifyCommitCommand implements git.Command
+var _ git.Command = (*VerifyCommitCommand)(nil)
+
+func (c *VerifyCommitCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	revs, err := parseVerifyArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "verify-commit"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+
+	var out []string
+	for _, rev := range revs {
+		hash, err := git.ResolveRevision(repo, rev)
+		if err != nil {
+			return "", fmt.Errorf("error: commit '%s' not found.", rev)
+		}
+		commit, err := repo.CommitObject(*hash)
+		if err != nil {
+			return "", fmt.Errorf("error: %s: cannot verify a non-commit object", rev)
+		}
+		check, err := s.VerifyCommit(commit)
+		if err != nil {
+			return "", err
+		}
+		if err := signatureError(check, "commit "+commit.Hash.String()[:7], out); err != nil {
+			return "", err
+		}
+		out = append(out, check.Lines()...)
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *VerifyCommitCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show the commit"},
+			{Flags: []string{"--raw"}, Usage: "Show raw status output"},
+		},
+		Args: []string{git.ArgRef},
+	}
+}
+
+func (c *VerifyCommitCommand) Help() string {
+	return git.CommandHelp(context.Background(), "verify-commit")
+}
+
+type VerifyTagCommand struct{}
+
+// Ensure VerifyTagCommand implements git.Command
+var _ git.Command = (*VerifyTagCommand)(nil)
+
+func (c *VerifyTagCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	names, err := parseVerifyArgs(args)
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "verify-tag"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	return verifyTags(s, repo, names)
+}
+
+// verifyTags checks the signatures of the named tags, for verify-tag and tag -v.
+func verifyTags(s *git.Session, repo *gogit.Repository, names []string) (string, error) {
+	var out []string
+	for _, name := range names {
+		ref, err := repo.Reference(plumbing.NewTagReferenceName(name), true)
+		if err != nil {
+			return "", fmt.Errorf("error: tag '%s' not found.", name)
+		}
+		tag, err := repo.TagObject(ref.Hash())
+		if err != nil {
+			return "", fmt.Errorf("error: %s: cannot verify a non-tag object of type commit.", name)
+		}
+		check, err := s.VerifyTag(tag)
+		if err != nil {
+			return "", err
+		}
+		if err := signatureError(check, "tag "+name, out); err != nil {
+			return "", err
+		}
+		out = append(out, check.Lines()...)
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// signatureError turns a failed check into an error carrying the gpg output so
+// far, like git exiting non-zero after printing it.
+func signatureError(check *git.SignatureCheck, what string, previous []string) error {
+	var msg string
+	switch check.Status {
+	case git.SignatureGood:
+		return nil
+	case git.SignatureNone:
+		msg = fmt.Sprintf("error: no signature found in %s\nhint: Sign it with 'git commit -S' or 'git tag -s'.", what)
+	default:
+		msg = strings.Join(check.Lines(), "\n")
+	}
+	return fmt.Errorf("%s", strings.Join(append(append([]string{}, previous...), msg), "\n"))
+}
+
+func parseVerifyArgs(args []string) ([]string, error) {
+	var names []string
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "-h" || arg == "--help":
+			return nil, fmt.Errorf("help requested")
+		case arg == "-v" || arg == "--verbose" || arg == "--raw":
+			// Accepted for compatibility; the gpg lines are always shown
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("error: unknown option '%s'", arg)
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("usage: %s <object>...", args[0])
+	}
+	return names, nil
+}
+
+// Spec implements git.SpecProvider.
+func (c *VerifyTagCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Options: []git.Option{
+			{Flags: []string{"-v", "--verbose"}, Usage: "Show the tag"},
+			{Flags: []string{"--raw"}, Usage: "Show raw status output"},
+		},
+		Args: []string{git.ArgTag},
+	}
+}
+
+func (c *VerifyTagCommand) Help() string {
+	return git.CommandHelp(context.Background(), "verify-tag")
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestSigning_CommitTagAndVerify(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-signing")
+	ctx := context.Background()
+	commands := map[string]git.Command{
+		"commit":        &CommitCommand{},
+		"config":        &ConfigCommand{},
+		"log":           &LogCommand{},
+		"tag":           &TagCommand{},
+		"verify-commit": &VerifyCommitCommand{},
+		"verify-tag":    &VerifyTagCommand{},
+	}
+	run := func(args ...string) (string, error) {
+		return commands[args[0]].Execute(ctx, s, args)
+	}
+
+	// The setup commit is not signed
+	if _, err := run("verify-commit", "HEAD"); err == nil || !strings.Contains(err.Error(), "no signature found") {
+		t.Errorf("Expected unsigned commit to fail verification, got %v", err)
+	}
+
+	if _, err := run("commit", "--allow-empty", "-S", "-m", "signed"); err != nil {
+		t.Fatalf("commit -S failed: %v", err)
+	}
+	out, err := run("verify-commit", "HEAD")
+	if err != nil {
+		t.Fatalf("verify-commit failed: %v", err)
+	}
+	keyID := s.SigningKey().ID
+	if !strings.Contains(out, `Good signature from "User <user@example.com>"`) || !strings.Contains(out, keyID) {
+		t.Errorf("Unexpected verify-commit output:\n%s", out)
+	}
+
+	out, err = run("log", "--show-signature", "-n", "2")
+	if err != nil {
+		t.Fatalf("log failed: %v", err)
+	}
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[0], "commit ") || !strings.HasPrefix(lines[1], "gpg: Signature made") {
+		t.Errorf("Expected gpg lines after the commit line, got:\n%s", out)
+	}
+	if strings.Count(out, "gpg: Good signature") != 1 {
+		t.Errorf("Expected only the signed commit to show a signature, got:\n%s", out)
+	}
+
+	// commit.gpgsign signs every commit unless --no-gpg-sign is given
+	if _, err := run("config", "commit.gpgsign", "true"); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+	if _, err := run("commit", "--allow-empty", "-m", "auto"); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if _, err := run("verify-commit", "HEAD"); err != nil {
+		t.Errorf("Expected commit.gpgsign to sign the commit: %v", err)
+	}
+	if _, err := run("commit", "--allow-empty", "--no-gpg-sign", "-m", "unsigned"); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if _, err := run("verify-commit", "HEAD"); err == nil {
+		t.Error("Expected --no-gpg-sign to override commit.gpgsign")
+	}
+
+	// Rewriting a signed commit without re-signing breaks the signature
+	repo := s.GetRepo()
+	signed, err := repo.CommitObject(*mustResolve(t, s, "HEAD~1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.Message = "tampered\n"
+	obj := repo.Storer.NewEncodedObject()
+	if err := signed.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	forged, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/forged", forged)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("verify-commit", "forged"); err == nil || !strings.Contains(err.Error(), "BAD signature") {
+		t.Errorf("Expected tampered commit to fail verification, got %v", err)
+	}
+
+	// Another session does not know this session's key
+	other, _ := sm.CreateSession("test-signing-other")
+	commit, _ := repo.CommitObject(*mustResolve(t, s, "HEAD~1"))
+	check, err := other.VerifyCommit(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Status != git.SignatureUnknown || !strings.Contains(strings.Join(check.Lines(), "\n"), "No public key") {
+		t.Errorf("Expected unknown key, got %+v", check)
+	}
+
+	// Signed tags
+	if _, err := run("tag", "-s", "v1.0", "-m", "Release 1.0"); err != nil {
+		t.Fatalf("tag -s failed: %v", err)
+	}
+	if out, err := run("verify-tag", "v1.0"); err != nil || !strings.Contains(out, "Good signature") {
+		t.Errorf("verify-tag: %q, %v", out, err)
+	}
+	if out, err := run("tag", "-v", "v1.0"); err != nil || !strings.Contains(out, "Good signature") {
+		t.Errorf("tag -v: %q, %v", out, err)
+	}
+	if _, err := run("tag", "-a", "v0.9", "-m", "Unsigned"); err != nil {
+		t.Fatalf("tag -a failed: %v", err)
+	}
+	if _, err := run("verify-tag", "v0.9"); err == nil {
+		t.Error("Expected unsigned tag to fail verification")
+	}
+}
+
+func mustResolve(t *testing.T, s *git.Session, rev string) *plumbing.Hash {
+	t.Helper()
+	hash, err := git.ResolveRevision(s.GetRepo(), rev)
+	if err != nil {
+		t.Fatalf("resolve %s: %v", rev, err)
+	}
+	return hash
+}
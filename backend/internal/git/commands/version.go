@@ -20,13 +20,11 @@ func (c *VersionCommand) Execute(ctx context.Context, s *git.Session, args []str
 	return "git version 2.47.1 (GitGym)", nil
 }
 
-func (c *VersionCommand) Help() string {
-	return `📘 GIT-VERSION (1)                                      Git Manual
-
- 💡 DESCRIPTION
-    GitGymシミュレータのバージョンを表示します。
+// Spec implements git.SpecProvider.
+func (c *VersionCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{}
+}
 
- 📋 SYNOPSIS
-    git version
-`
+func (c *VersionCommand) Help() string {
+	return git.CommandHelp(context.Background(), "version")
 }
@@ -1,9 +1,21 @@
 package commands
 
+// worktree.go - "git worktree add/list/remove/prune"
+//
+// A linked worktree checks out a second branch of the same repository in its
+// own directory: commits and branches made in either are visible in both,
+// while each has its own HEAD, index and files. A branch can only be checked
+// out in one worktree at a time.
+
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
 
@@ -16,10 +28,356 @@ type WorktreeCommand struct{}
 // Ensure WorktreeCommand implements git.Command
 var _ git.Command = (*WorktreeCommand)(nil)
 
+type WorktreeOptions struct {
+	NewBranch      string // -b: create this branch for the worktree
+	ForceNewBranch string // -B: create or reset this branch for the worktree
+	Detach         bool
+	Force          bool
+	Porcelain      bool
+	Path           string
+	CommitIsh      string
+}
+
 func (c *WorktreeCommand) Execute(ctx context.Context, s *git.Session, args []string) (string, error) {
-	return "", fmt.Errorf("git worktree is not supported in GitGym's current UI")
+	s.Lock()
+	defer s.Unlock()
+
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: git worktree add [-b <new-branch>] <path> [<commit-ish>]\n   or: git worktree list\n   or: git worktree remove [-f] <worktree>\n   or: git worktree prune")
+	}
+	sub := args[1]
+	if sub == "-h" || sub == "--help" {
+		return git.CommandHelp(ctx, "worktree"), nil
+	}
+
+	opts, err := c.parseArgs(args[2:])
+	if err != nil {
+		if err.Error() == "help requested" {
+			return git.CommandHelp(ctx, "worktree"), nil
+		}
+		return "", err
+	}
+
+	repo := s.GetRepo()
+	if repo == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): .git")
+	}
+	current := strings.TrimPrefix(s.CurrentDir, "/")
+
+	switch sub {
+	case "add":
+		return c.add(s, repo, current, opts)
+	case "list":
+		return c.list(s, current, opts), nil
+	case "remove":
+		return c.remove(s, current, opts)
+	case "prune":
+		return c.prune(s, current), nil
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: `%s'", sub)
+	}
+}
+
+func (c *WorktreeCommand) parseArgs(args []string) (*WorktreeOptions, error) {
+	opts := &WorktreeOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-b", "-B":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("error: switch `%s' requires a value", strings.TrimPrefix(arg, "-"))
+			}
+			if arg == "-b" {
+				opts.NewBranch = args[i+1]
+			} else {
+				opts.ForceNewBranch = args[i+1]
+			}
+			i++
+		case "--detach", "-d":
+			opts.Detach = true
+		case "-f", "--force":
+			opts.Force = true
+		case "--porcelain":
+			opts.Porcelain = true
+		case "-h", "--help":
+			return nil, fmt.Errorf("help requested")
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("error: unknown option `%s'", strings.TrimLeft(arg, "-"))
+			}
+			if opts.Path == "" {
+				opts.Path = arg
+			} else if opts.CommitIsh == "" {
+				opts.CommitIsh = arg
+			} else {
+				return nil, fmt.Errorf("fatal: too many arguments")
+			}
+		}
+	}
+	return opts, nil
+}
+
+// resolvePath turns a worktree path relative to the current directory into a
+// session path without the leading slash, e.g. "../wt" from /project is "wt".
+func (c *WorktreeCommand) resolvePath(s *git.Session, arg string) (string, error) {
+	p := arg
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(s.CurrentDir, p)
+	}
+	p = strings.TrimPrefix(path.Clean(p), "/")
+	if p == "" || p == "." {
+		return "", fmt.Errorf("fatal: '%s' is not a valid worktree path", arg)
+	}
+	for _, part := range strings.Split(p, "/") {
+		if !SafeRepoNameRegex.MatchString(part) {
+			return "", fmt.Errorf("fatal: invalid worktree path '%s': must contain only alphanumeric characters, underscores, or hyphens", arg)
+		}
+	}
+	return p, nil
+}
+
+func (c *WorktreeCommand) add(s *git.Session, repo *gogit.Repository, current string, opts *WorktreeOptions) (string, error) {
+	if opts.Path == "" {
+		return "", fmt.Errorf("usage: git worktree add [-b <new-branch>] <path> [<commit-ish>]")
+	}
+	wtPath, err := c.resolvePath(s, opts.Path)
+	if err != nil {
+		return "", err
+	}
+	if _, exists := s.Repos[wtPath]; exists {
+		return "", fmt.Errorf("fatal: '%s' already exists", opts.Path)
+	}
+	if entries, err := s.Filesystem.ReadDir(wtPath); err == nil && len(entries) > 0 {
+		return "", fmt.Errorf("fatal: '%s' already exists", opts.Path)
+	}
+
+	head, commit, preparing, err := c.resolveHead(s, repo, wtPath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	wtRepo, err := s.AddWorktree(s.MainWorktree(current), wtPath, head)
+	if err != nil {
+		return "", err
+	}
+	w, err := wtRepo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	checkoutOpts := &gogit.CheckoutOptions{Hash: commit.Hash, Force: true}
+	if head.Type() == plumbing.SymbolicReference {
+		checkoutOpts = &gogit.CheckoutOptions{Branch: head.Target(), Force: true}
+	}
+	if err := w.Checkout(checkoutOpts); err != nil {
+		_ = s.RemoveWorktree(wtPath)
+		return "", fmt.Errorf("failed to check out the worktree: %w", err)
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	return fmt.Sprintf("Preparing worktree (%s)\nHEAD is now at %s %s", preparing, commit.Hash.String()[:7], subject), nil
+}
+
+// resolveHead works out what the new worktree checks out: a new branch (-b/-B,
+// or one named after the directory), an existing branch, or a detached commit.
+// It creates the branch if needed and describes the choice like git does.
+func (c *WorktreeCommand) resolveHead(s *git.Session, repo *gogit.Repository, wtPath string, opts *WorktreeOptions) (*plumbing.Reference, *object.Commit, string, error) {
+	newBranch, force := opts.NewBranch, false
+	if opts.ForceNewBranch != "" {
+		newBranch, force = opts.ForceNewBranch, true
+	}
+
+	startPoint := opts.CommitIsh
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+
+	// "git worktree add <path> <branch>" checks out an existing local branch
+	if newBranch == "" && !opts.Detach && opts.CommitIsh != "" {
+		branch := plumbing.NewBranchReferenceName(opts.CommitIsh)
+		if ref, err := repo.Reference(branch, true); err == nil {
+			if err := c.checkBranchFree(s, branch, opts.Force); err != nil {
+				return nil, nil, "", err
+			}
+			commit, err := repo.CommitObject(ref.Hash())
+			if err != nil {
+				return nil, nil, "", err
+			}
+			return plumbing.NewSymbolicReference(plumbing.HEAD, branch), commit, fmt.Sprintf("checking out '%s'", opts.CommitIsh), nil
+		}
+		// Guess a new branch tracking origin/<name>, as git does
+		if ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", opts.CommitIsh), true); err == nil {
+			commit, err := repo.CommitObject(ref.Hash())
+			if err != nil {
+				return nil, nil, "", err
+			}
+			if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, commit.Hash)); err != nil {
+				return nil, nil, "", err
+			}
+			_ = git.SetUpstream(repo, opts.CommitIsh, "origin", opts.CommitIsh)
+			return plumbing.NewSymbolicReference(plumbing.HEAD, branch), commit, fmt.Sprintf("new branch '%s'", opts.CommitIsh), nil
+		}
+	}
+
+	hash, err := git.ResolveRevision(repo, startPoint)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("fatal: invalid reference: %s", startPoint)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("fatal: invalid reference: %s", startPoint)
+	}
+
+	// Any other commit-ish without -b is checked out detached
+	if opts.Detach || (newBranch == "" && opts.CommitIsh != "") {
+		return plumbing.NewHashReference(plumbing.HEAD, commit.Hash), commit, fmt.Sprintf("detached HEAD %s", commit.Hash.String()[:7]), nil
+	}
+
+	if newBranch == "" {
+		// No branch given: one named after the new directory, like git
+		newBranch = path.Base(wtPath)
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(newBranch), true); err == nil {
+			branch := plumbing.NewBranchReferenceName(newBranch)
+			if err := c.checkBranchFree(s, branch, opts.Force); err != nil {
+				return nil, nil, "", err
+			}
+			ref, _ := repo.Reference(branch, true)
+			existing, err := repo.CommitObject(ref.Hash())
+			if err != nil {
+				return nil, nil, "", err
+			}
+			return plumbing.NewSymbolicReference(plumbing.HEAD, branch), existing, fmt.Sprintf("checking out '%s'", newBranch), nil
+		}
+	}
+
+	if err := git.CheckBranchPolicy(s, newBranch); err != nil {
+		return nil, nil, "", err
+	}
+	branch := plumbing.NewBranchReferenceName(newBranch)
+	if _, err := repo.Reference(branch, true); err == nil {
+		if !force {
+			return nil, nil, "", fmt.Errorf("fatal: a branch named '%s' already exists", newBranch)
+		}
+		if err := c.checkBranchFree(s, branch, false); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, commit.Hash)); err != nil {
+		return nil, nil, "", err
+	}
+	return plumbing.NewSymbolicReference(plumbing.HEAD, branch), commit, fmt.Sprintf("new branch '%s'", newBranch), nil
+}
+
+// checkBranchFree refuses a branch that the current worktree or another one has checked out.
+func (c *WorktreeCommand) checkBranchFree(s *git.Session, branch plumbing.ReferenceName, force bool) error {
+	if force {
+		return nil
+	}
+	if head, err := s.GetRepo().Storer.Reference(plumbing.HEAD); err == nil && head.Type() == plumbing.SymbolicReference && head.Target() == branch {
+		return fmt.Errorf("fatal: '%s' is already used by worktree at '%s'", branch.Short(), s.CurrentDir)
+	}
+	if wt, ok := s.BranchWorktree(branch); ok {
+		return fmt.Errorf("fatal: '%s' is already used by worktree at '/%s'", branch.Short(), wt)
+	}
+	return nil
+}
+
+func (c *WorktreeCommand) list(s *git.Session, current string, opts *WorktreeOptions) string {
+	var sb strings.Builder
+	for i, wt := range s.ListWorktrees(current) {
+		hash := plumbing.ZeroHash
+		if ref, err := s.Repos[wt.Path].Head(); err == nil {
+			hash = ref.Hash()
+		}
+		if opts.Porcelain {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(fmt.Sprintf("worktree /%s\nHEAD %s\n", wt.Path, hash))
+			if wt.Head != nil && wt.Head.Type() == plumbing.SymbolicReference {
+				sb.WriteString(fmt.Sprintf("branch %s\n", wt.Head.Target()))
+			} else {
+				sb.WriteString("detached\n")
+			}
+			continue
+		}
+		label := "(detached HEAD)"
+		if wt.Head != nil && wt.Head.Type() == plumbing.SymbolicReference {
+			label = "[" + wt.Head.Target().Short() + "]"
+		}
+		sb.WriteString(fmt.Sprintf("%-20s %s %s\n", "/"+wt.Path, hash.String()[:7], label))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (c *WorktreeCommand) remove(s *git.Session, current string, opts *WorktreeOptions) (string, error) {
+	if opts.Path == "" {
+		return "", fmt.Errorf("usage: git worktree remove [-f] <worktree>")
+	}
+	wtPath, err := c.resolvePath(s, opts.Path)
+	if err != nil {
+		return "", err
+	}
+	main := s.MainWorktree(current)
+	if wtPath == main {
+		return "", fmt.Errorf("fatal: '%s' is a main working tree", opts.Path)
+	}
+	if _, linked := s.Worktrees[wtPath]; !linked || s.Worktrees[wtPath] != main {
+		return "", fmt.Errorf("fatal: '%s' is not a working tree", opts.Path)
+	}
+	if wtPath == current {
+		return "", fmt.Errorf("fatal: cannot remove the current working tree; cd to another worktree first")
+	}
+
+	if !opts.Force {
+		w, err := s.Repos[wtPath].Worktree()
+		if err != nil {
+			return "", err
+		}
+		status, err := w.Status()
+		if err != nil {
+			return "", err
+		}
+		if !status.IsClean() {
+			return "", fmt.Errorf("fatal: '%s' contains modified or untracked files, use --force to delete it", opts.Path)
+		}
+	}
+	if err := s.RemoveWorktree(wtPath); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// prune forgets linked worktrees whose directory was deleted without "git worktree remove".
+func (c *WorktreeCommand) prune(s *git.Session, current string) string {
+	var pruned []string
+	for _, wt := range s.ListWorktrees(current) {
+		if !wt.Linked {
+			continue
+		}
+		if _, err := s.Filesystem.Stat(wt.Path); err == nil {
+			continue
+		}
+		_ = s.RemoveWorktree(wt.Path)
+		pruned = append(pruned, fmt.Sprintf("Removing worktrees/%s: gitdir file points to non-existent location", path.Base(wt.Path)))
+	}
+	return strings.Join(pruned, "\n")
+}
+
+// Spec implements git.SpecProvider.
+func (c *WorktreeCommand) Spec() git.CommandSpec {
+	return git.CommandSpec{
+		Subcommands: []string{"add", "list", "prune", "remove"},
+		Options: []git.Option{
+			{Flags: []string{"-b"}, Arg: git.ArgText, Usage: "Create a branch for the worktree"},
+			{Flags: []string{"-B"}, Arg: git.ArgText, Usage: "Create or reset a branch for the worktree"},
+			{Flags: []string{"-d", "--detach"}, Usage: "Detach HEAD in the worktree"},
+			{Flags: []string{"-f", "--force"}, Usage: "Remove a dirty worktree"},
+			{Flags: []string{"--porcelain"}, Usage: "Machine-readable list"},
+		},
+		Args: []string{git.ArgPath, git.ArgRef},
+	}
 }
 
 func (c *WorktreeCommand) Help() string {
-	return "usage: git worktree\n\n(Not supported in GitGym)"
+	return git.CommandHelp(context.Background(), "worktree")
 }
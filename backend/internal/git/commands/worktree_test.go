@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestWorktree_AddCommitAndRemove(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := setupBranchTestSession(t, sm, "test-worktree")
+	ctx := context.Background()
+
+	run := func(input string) (string, error) {
+		name, args := git.ParseCommand(input)
+		result, err := git.Dispatch(ctx, s, name, args)
+		return result.Stdout, err
+	}
+	mustRun := func(input string) string {
+		out, err := run(input)
+		if err != nil {
+			t.Fatalf("%s failed: %v", input, err)
+		}
+		return out
+	}
+	mainHead, _ := s.GetRepo().Head()
+
+	out := mustRun("git worktree add -b hotfix ../hotfix")
+	if !strings.Contains(out, "Preparing worktree (new branch 'hotfix')") {
+		t.Errorf("Unexpected add output: %q", out)
+	}
+	if _, err := s.Filesystem.Stat("/hotfix/file.txt"); err != nil {
+		t.Fatalf("Expected the worktree to be checked out: %v", err)
+	}
+
+	// A commit in the worktree moves the shared branch, not the main HEAD
+	mustRun("cd ../hotfix")
+	if err := util.WriteFile(s.Filesystem, "/hotfix/fix.txt", []byte("fix"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustRun("git add fix.txt")
+	mustRun("git commit -m fix")
+	mustRun("cd ../testrepo")
+
+	main := s.GetRepo()
+	branch, err := main.Reference(plumbing.NewBranchReferenceName("hotfix"), true)
+	if err != nil || branch.Hash() == mainHead.Hash() {
+		t.Fatalf("Expected hotfix to move in the main repository, got %v", err)
+	}
+	if head, _ := main.Head(); head.Hash() != mainHead.Hash() || head.Name() != mainHead.Name() {
+		t.Errorf("Expected the main worktree to stay on %s", mainHead.Name())
+	}
+	if _, err := s.Filesystem.Stat("/testrepo/fix.txt"); err == nil {
+		t.Error("Expected the main worktree files to be untouched")
+	}
+
+	// The branch belongs to the worktree
+	if _, err := run("git checkout hotfix"); err == nil || !strings.Contains(err.Error(), "already used by worktree at '/hotfix'") {
+		t.Errorf("Expected checkout of the worktree's branch to fail, got %v", err)
+	}
+	if _, err := run("git branch -D hotfix"); err == nil || !strings.Contains(err.Error(), "used by worktree") {
+		t.Errorf("Expected deleting the worktree's branch to fail, got %v", err)
+	}
+	if _, err := run("git worktree add ../other hotfix"); err == nil {
+		t.Error("Expected a second worktree on the same branch to fail")
+	}
+
+	out = mustRun("git worktree list")
+	if !strings.Contains(out, "/testrepo") || !strings.Contains(out, "/hotfix") || !strings.Contains(out, "[hotfix]") {
+		t.Errorf("Unexpected list output:\n%s", out)
+	}
+
+	// A forked session keeps the worktree linked to the forked repository
+	fork, err := s.Fork()
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if head, err := fork.Repos["hotfix"].Head(); err != nil || head.Name().Short() != "hotfix" {
+		t.Errorf("Expected the forked worktree on hotfix, got %v, %v", head, err)
+	}
+
+	if err := util.WriteFile(s.Filesystem, "/hotfix/draft.txt", []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("git worktree remove ../hotfix"); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected removing a dirty worktree to fail, got %v", err)
+	}
+	mustRun("git worktree remove --force ../hotfix")
+	if _, ok := s.Repos["hotfix"]; ok {
+		t.Error("Expected the worktree to be gone")
+	}
+	if _, err := s.Filesystem.Stat("/hotfix"); err == nil {
+		t.Error("Expected the worktree directory to be deleted")
+	}
+	if _, err := main.Reference(plumbing.NewBranchReferenceName("hotfix"), true); err != nil {
+		t.Error("Expected the branch to outlive the worktree")
+	}
+	mustRun("git checkout hotfix")
+}
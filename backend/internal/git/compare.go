@@ -0,0 +1,152 @@
+package git
+
+// compare.go - Comparing two revisions the way a pull request does
+//
+// CompareRevisions answers what a hosting service's compare view shows: the
+// commits head has that base lacks (ahead), how many base has that head lacks
+// (behind), and the files head changed since the two diverged, i.e. the
+// "three-dot" diff from their merge base. It powers the "Files changed" tab of
+// the simulated pull requests.
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxCompareCommits caps how many of the commits ahead a Comparison lists.
+const maxCompareCommits = 250
+
+// Comparison is the difference between a base and a head revision.
+type Comparison struct {
+	Base      string          `json:"base"` // As requested
+	Head      string          `json:"head"`
+	BaseID    string          `json:"baseId"`
+	HeadID    string          `json:"headId"`
+	MergeBase string          `json:"mergeBase,omitempty"` // Empty for unrelated histories
+	Ahead     int             `json:"ahead"`               // Commits in head but not in base
+	Behind    int             `json:"behind"`              // Commits in base but not in head
+	Commits   []CommitSummary `json:"commits"`             // The commits ahead, newest first, at most maxCompareCommits
+	Files     []FileStat      `json:"files"`               // Files changed from the merge base to head
+	Additions int             `json:"additions"`
+	Deletions int             `json:"deletions"`
+}
+
+// CompareRevisions compares head against base in repo.
+func CompareRevisions(repo *gogit.Repository, base, head string) (*Comparison, error) {
+	cmp, _, err := compareRevisions(repo, base, head)
+	return cmp, err
+}
+
+// compareRevisions is CompareRevisions, also returning the patch from the
+// merge base to head.
+func compareRevisions(repo *gogit.Repository, base, head string) (*Comparison, diff.Patch, error) {
+	baseCommit, err := compareCommit(repo, base)
+	if err != nil {
+		return nil, nil, err
+	}
+	headCommit, err := compareCommit(repo, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmp := &Comparison{
+		Base:    base,
+		Head:    head,
+		BaseID:  baseCommit.Hash.String(),
+		HeadID:  headCommit.Hash.String(),
+		Commits: []CommitSummary{},
+		Files:   []FileStat{},
+	}
+
+	// Everything the merge bases reach is shared; the rest is ahead or behind
+	bases, err := headCommit.MergeBase(baseCommit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find merge base: %w", err)
+	}
+	shared := make(map[plumbing.Hash]bool)
+	for _, mb := range bases {
+		_ = object.NewCommitPreorderIter(mb, shared, nil).ForEach(func(c *object.Commit) error {
+			shared[c.Hash] = true
+			return nil
+		})
+	}
+	var ahead []*object.Commit
+	_ = object.NewCommitPreorderIter(headCommit, shared, nil).ForEach(func(c *object.Commit) error {
+		ahead = append(ahead, c)
+		return nil
+	})
+	_ = object.NewCommitPreorderIter(baseCommit, shared, nil).ForEach(func(c *object.Commit) error {
+		cmp.Behind++
+		return nil
+	})
+	cmp.Ahead = len(ahead)
+	sort.SliceStable(ahead, func(i, j int) bool {
+		return ahead[i].Committer.When.After(ahead[j].Committer.When)
+	})
+	if len(ahead) > maxCompareCommits {
+		ahead = ahead[:maxCompareCommits]
+	}
+	for _, c := range ahead {
+		summary := CommitSummary{
+			ID:      c.Hash.String(),
+			Parents: []string{},
+			Author:  c.Author.Name,
+			Email:   c.Author.Email,
+			Date:    c.Author.When.Format(time.RFC3339),
+			Message: c.Message,
+		}
+		for _, p := range c.ParentHashes {
+			summary.Parents = append(summary.Parents, p.String())
+		}
+		cmp.Commits = append(cmp.Commits, summary)
+	}
+
+	// Unrelated histories: everything in head counts as added
+	from := ContentSnapshot{}
+	if len(bases) > 0 {
+		cmp.MergeBase = bases[0].Hash.String()
+		tree, err := bases[0].Tree()
+		if err != nil {
+			return nil, nil, err
+		}
+		if from, err = TreeSnapshot(tree); err != nil {
+			return nil, nil, err
+		}
+	}
+	tree, err := headCommit.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := TreeSnapshot(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	patch, err := DiffContent(from, to, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute diff: %w", err)
+	}
+	for _, fp := range patch.FilePatches() {
+		stat := newFileStat(fp)
+		cmp.Additions += stat.Additions
+		cmp.Deletions += stat.Deletions
+		cmp.Files = append(cmp.Files, stat)
+	}
+	return cmp, patch, nil
+}
+
+func compareCommit(repo *gogit.Repository, rev string) (*object.Commit, error) {
+	hash, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: '%s' is not a commit", rev)
+	}
+	return commit, nil
+}
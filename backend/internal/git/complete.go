@@ -0,0 +1,407 @@
+package git
+
+// complete.go - Command line completion
+//
+// Commands describe their arguments with a CommandSpec (see SpecProvider):
+// subcommands, flags and the kind of each positional argument. Complete uses
+// these to offer the words that fit at the end of a partial command line:
+// command names, subcommands, flags, branch, tag and remote names of the
+// current repository, and paths of the session filesystem.
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Kinds of values an argument takes
+const (
+	ArgText    = "text"    // Free text such as a message; nothing to complete
+	ArgBranch  = "branch"  // Local branch
+	ArgTag     = "tag"     // Tag
+	ArgRef     = "ref"     // Branch, remote-tracking branch, tag or HEAD
+	ArgRemote  = "remote"  // Remote name
+	ArgPath    = "path"    // File or directory of the session filesystem
+	ArgCommand = "command" // Command name, as for "git help <command>"
+)
+
+// Option is a flag a command accepts.
+type Option struct {
+	Flags    []string // Spellings, e.g. {"-m", "--message"}
+	Arg      string   // Kind of the value the flag takes; empty for switches
+	Optional bool     // The value is only given attached, as in --force-with-lease[=<ref>]
+	Usage    string   // One-line description
+}
+
+// CommandSpec describes the arguments of a command, for completion and for
+// parsing with Parse.
+type CommandSpec struct {
+	Shell       bool     // Shell builtin (cd, ls), run without "git"
+	Hidden      bool     // Internal command, never offered
+	Subcommands []string // Words the first positional argument is chosen from
+	Options     []Option
+	Args        []string // Kind of each positional argument after the subcommand; the last one repeats
+}
+
+// SpecProvider is implemented by commands that describe their arguments.
+type SpecProvider interface {
+	Spec() CommandSpec
+}
+
+// CommandSpecOf returns the spec of a registered command.
+func CommandSpecOf(name string) (CommandSpec, bool) {
+	factory, ok := registry[name]
+	if !ok {
+		return CommandSpec{}, false
+	}
+	if p, ok := factory().(SpecProvider); ok {
+		return p.Spec(), true
+	}
+	return CommandSpec{}, true
+}
+
+// Completion is one word that fits where the cursor is.
+type Completion struct {
+	Value       string `json:"value"`                 // Replaces the word being completed
+	Kind        string `json:"kind"`                  // command, subcommand, flag, branch, tag, remote, ref, file or directory
+	Description string `json:"description,omitempty"` // Usage of flags
+}
+
+// CompletionResult lists the completions of the last word of a line.
+type CompletionResult struct {
+	Start       int          `json:"start"` // Byte offset of the word being completed
+	Word        string       `json:"word"`  // The partial word, unquoted
+	Completions []Completion `json:"completions"`
+}
+
+// maxCompletions caps how many completions Complete returns.
+const maxCompletions = 100
+
+// Complete returns the completions of the last word of line, which may be
+// empty to complete a new word. Caller holds at least the session's read lock.
+func Complete(s *Session, line string) *CompletionResult {
+	words, start := completionWords(line)
+	word := words[len(words)-1]
+	res := &CompletionResult{Start: start, Word: word, Completions: []Completion{}}
+	c := &completer{s: s, word: word, seen: make(map[string]bool)}
+
+	prev := words[:len(words)-1]
+	switch {
+	case len(prev) > 0 && strings.HasPrefix(prev[len(prev)-1], ">"), len(prev) > 0 && prev[len(prev)-1] == "<":
+		c.values(ArgPath)
+	case len(prev) == 0:
+		c.add("git", "command", "")
+		for _, name := range sortedCommands() {
+			if spec, _ := CommandSpecOf(name); spec.Shell {
+				c.add(name, "command", "")
+			}
+		}
+	case prev[0] == "git" && len(prev) == 1:
+		var names []string
+		for _, name := range sortedCommands() {
+			if spec, _ := CommandSpecOf(name); !spec.Shell && !spec.Hidden {
+				names = append(names, strings.TrimPrefix(name, "git-")) // "git rm" runs git-rm
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c.add(name, "command", "")
+		}
+	default:
+		name, args := ResolveCommand(prev)
+		spec, ok := CommandSpecOf(name)
+		if !ok {
+			c.values(ArgPath)
+			break
+		}
+		c.arguments(spec, args[1:])
+	}
+
+	res.Completions = c.out
+	if len(res.Completions) > maxCompletions {
+		res.Completions = res.Completions[:maxCompletions]
+	}
+	return res
+}
+
+// completer collects the completions matching word.
+type completer struct {
+	s    *Session
+	word string
+	out  []Completion
+	seen map[string]bool
+}
+
+func (c *completer) add(value, kind, description string) {
+	if !strings.HasPrefix(value, c.word) || c.seen[value] {
+		return
+	}
+	c.seen[value] = true
+	c.out = append(c.out, Completion{Value: value, Kind: kind, Description: description})
+}
+
+// arguments completes the word after args, the arguments already typed.
+func (c *completer) arguments(spec CommandSpec, args []string) {
+	// Find what the word is: a flag, the value of a flag, or a positional argument
+	positional := 0
+	afterDashes := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case afterDashes || arg == "-" || !strings.HasPrefix(arg, "-"):
+			positional++
+		case arg == "--":
+			afterDashes = true
+		default:
+			if opt := spec.option(arg); opt != nil && opt.Arg != "" && !opt.Optional && !strings.Contains(arg, "=") {
+				if i == len(args)-1 {
+					c.values(opt.Arg)
+					return
+				}
+				i++
+			}
+		}
+	}
+
+	if !afterDashes && strings.HasPrefix(c.word, "-") {
+		if name, value, ok := strings.Cut(c.word, "="); ok {
+			if opt := spec.option(name); opt != nil && opt.Arg != "" {
+				c.word = value
+				prefix := name + "="
+				before := len(c.out)
+				c.values(opt.Arg)
+				for i := before; i < len(c.out); i++ {
+					c.out[i].Value = prefix + c.out[i].Value
+				}
+			}
+			return
+		}
+		for _, opt := range spec.Options {
+			for _, flag := range opt.Flags {
+				c.add(flag, "flag", opt.Usage)
+			}
+		}
+		return
+	}
+	if afterDashes {
+		c.values(ArgPath)
+		return
+	}
+
+	if len(spec.Subcommands) > 0 {
+		if positional == 0 {
+			for _, sub := range spec.Subcommands {
+				c.add(sub, "subcommand", "")
+			}
+			return
+		}
+		positional--
+	}
+	if len(spec.Args) == 0 {
+		return
+	}
+	if positional >= len(spec.Args) {
+		positional = len(spec.Args) - 1
+	}
+	c.values(spec.Args[positional])
+}
+
+// option returns the option spelled flag, if the command has it.
+func (spec CommandSpec) option(flag string) *Option {
+	for i := range spec.Options {
+		for _, f := range spec.Options[i].Flags {
+			if f == flag {
+				return &spec.Options[i]
+			}
+		}
+	}
+	return nil
+}
+
+// values completes a value of the given kind.
+func (c *completer) values(kind string) {
+	switch kind {
+	case ArgPath:
+		c.paths()
+		return
+	case ArgCommand:
+		for _, name := range sortedCommands() {
+			if spec, _ := CommandSpecOf(name); !spec.Hidden {
+				c.add(name, "command", "")
+			}
+		}
+		return
+	}
+
+	repo := c.s.GetRepo()
+	if repo == nil {
+		return
+	}
+	if kind == ArgRemote {
+		if cfg, err := repo.Config(); err == nil {
+			names := make([]string, 0, len(cfg.Remotes))
+			for name := range cfg.Remotes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				c.add(name, "remote", "")
+			}
+		}
+		return
+	}
+
+	var branches, remoteBranches, tags []string
+	if iter, err := repo.References(); err == nil {
+		_ = iter.ForEach(func(ref *plumbing.Reference) error {
+			switch name := ref.Name(); {
+			case name.IsBranch():
+				branches = append(branches, name.Short())
+			case name.IsRemote() && !strings.HasSuffix(name.String(), "/HEAD"):
+				remoteBranches = append(remoteBranches, name.Short())
+			case name.IsTag():
+				tags = append(tags, name.Short())
+			}
+			return nil
+		})
+	}
+	sort.Strings(branches)
+	sort.Strings(remoteBranches)
+	sort.Strings(tags)
+	switch kind {
+	case ArgBranch:
+		for _, b := range branches {
+			c.add(b, "branch", "")
+		}
+	case ArgTag:
+		for _, t := range tags {
+			c.add(t, "tag", "")
+		}
+	case ArgRef:
+		c.add("HEAD", "ref", "")
+		for _, name := range SpecialRefs {
+			if _, err := repo.Storer.Reference(name); err == nil {
+				c.add(name.String(), "ref", "")
+			}
+		}
+		for _, b := range branches {
+			c.add(b, "branch", "")
+		}
+		for _, b := range remoteBranches {
+			c.add(b, "ref", "")
+		}
+		for _, t := range tags {
+			c.add(t, "tag", "")
+		}
+	}
+}
+
+// paths completes a file or directory name, relative to the current directory
+// unless the word is absolute. Directories end with "/".
+func (c *completer) paths() {
+	dir, base := path.Split(c.word)
+	abs := dir
+	if !strings.HasPrefix(abs, "/") {
+		abs = path.Join(c.s.CurrentDir, dir)
+	}
+	entries, err := c.s.Filesystem.ReadDir(abs)
+	if err != nil {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		name := e.Name()
+		if name == ".git" || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			c.add(dir+name+"/", "directory", "")
+		} else {
+			c.add(dir+name, "file", "")
+		}
+	}
+}
+
+// completionWords splits the last command of line into unquoted words. The
+// last word is the one being completed, empty after a space; start is its
+// offset in line.
+func completionWords(line string) ([]string, int) {
+	var words []string
+	var current strings.Builder
+	start := 0
+	inWord := false
+	var quote rune
+	escaped := false
+
+	runes := []rune(line)
+	offset := 0
+	for i, r := range runes {
+		pos := offset
+		offset += len(string(r))
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+			continue
+		case r == '\\':
+			escaped = true
+		case r == '"' || r == '\'':
+			quote = r
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+			continue
+		case r == ';' || r == '|' || r == '&':
+			// A new command starts after ;, |, || and &&
+			words = nil
+			current.Reset()
+			inWord = false
+			continue
+		case r == '>' || r == '<':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+			}
+			op := string(r)
+			if r == '>' && i+1 < len(runes) && runes[i+1] == '>' {
+				continue // The second > of >> ends the operator
+			}
+			if r == '>' && i > 0 && runes[i-1] == '>' {
+				op = ">>"
+			}
+			words = append(words, op)
+			inWord = false
+			continue
+		default:
+			current.WriteRune(r)
+		}
+		if !inWord {
+			inWord = true
+			start = pos
+		}
+	}
+	if !inWord {
+		start = len(line)
+	}
+	return append(words, current.String()), start
+}
+
+// sortedCommands returns the registered command names in order.
+func sortedCommands() []string {
+	names := GetSupportedCommands()
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,299 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Engine-level --dry-run
+//
+// Commands that implement DryRunner get --dry-run for free: the engine runs
+// them against a fork of the session and reports the refs, index entries and
+// files that differ afterwards. Commits the command would create are shown
+// in the graph as potential commits, like the merge --dry-run simulation.
+
+// DryRunFlag is the flag that asks the engine to simulate a command.
+const DryRunFlag = "--dry-run"
+
+// maxPotentialCommits caps how many simulated commits are sent to the graph.
+const maxPotentialCommits = 50
+
+// DryRunner is implemented by mutating commands whose --dry-run is simulated by the engine.
+// Commands with a --dry-run of their own (merge, fetch, push, ...) must not implement it.
+type DryRunner interface {
+	Command
+	// SupportsDryRun only marks the command; the engine never calls it.
+	SupportsDryRun()
+}
+
+// stripDryRunFlag removes --dry-run from args and reports whether it was present.
+func stripDryRunFlag(args []string) ([]string, bool) {
+	found := false
+	stripped := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == DryRunFlag {
+			found = true
+			continue
+		}
+		stripped = append(stripped, arg)
+	}
+	return stripped, found
+}
+
+// runDryRun executes cmd against a fork of session and describes what it would change.
+func runDryRun(ctx context.Context, session *Session, cmd Command, args []string) (string, error) {
+	session.RLock()
+	fork, err := session.Fork()
+	repo := session.GetRepo()
+	session.RUnlock()
+	if err != nil {
+		return "", fmt.Errorf("dry-run: %w", err)
+	}
+
+	out, err := cmd.Execute(ctx, fork, args)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[dry-run] git %s\n", strings.Join(args, " ")))
+	if out = strings.TrimSpace(out); out != "" {
+		for _, line := range strings.Split(out, "\n") {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+
+	forkRepo := fork.GetRepo()
+	if repo == nil || forkRepo == nil {
+		sb.WriteString("No changes were made.")
+		return sb.String(), nil
+	}
+
+	changed := false
+	if lines := diffRefs(repo, forkRepo); len(lines) > 0 {
+		changed = true
+		sb.WriteString("Refs that would change:\n")
+		for _, line := range lines {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	if lines := diffIndex(repo, forkRepo); len(lines) > 0 {
+		changed = true
+		sb.WriteString("Index entries that would change:\n")
+		for _, line := range lines {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	if lines := diffWorktreeFiles(repo, forkRepo); len(lines) > 0 {
+		changed = true
+		sb.WriteString("Files that would change:\n")
+		for _, line := range lines {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	if !changed {
+		sb.WriteString("Nothing would change.\n")
+	}
+	sb.WriteString("No changes were made.")
+
+	session.Lock()
+	session.PotentialCommits = potentialCommits(repo, forkRepo)
+	session.Unlock()
+
+	return sb.String(), nil
+}
+
+// describeRef renders a ref value for the dry-run report.
+func describeRef(ref *plumbing.Reference) string {
+	if ref == nil {
+		return "(none)"
+	}
+	if ref.Type() == plumbing.SymbolicReference {
+		return "-> " + ref.Target().String()
+	}
+	return ref.Hash().String()[:7]
+}
+
+// collectRefs returns every ref of repo, HEAD included, keyed by name.
+func collectRefs(repo *gogit.Repository) map[string]*plumbing.Reference {
+	refs := make(map[string]*plumbing.Reference)
+	if iter, err := repo.Storer.IterReferences(); err == nil {
+		_ = iter.ForEach(func(ref *plumbing.Reference) error {
+			refs[ref.Name().String()] = ref
+			return nil
+		})
+	}
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil {
+		refs[plumbing.HEAD.String()] = head
+	}
+	return refs
+}
+
+// diffRefs lists the refs that were created, moved or deleted between before and after.
+func diffRefs(before, after *gogit.Repository) []string {
+	oldRefs := collectRefs(before)
+	newRefs := collectRefs(after)
+
+	names := make(map[string]struct{})
+	for name := range oldRefs {
+		names[name] = struct{}{}
+	}
+	for name := range newRefs {
+		names[name] = struct{}{}
+	}
+
+	var lines []string
+	for name := range names {
+		oldRef, newRef := oldRefs[name], newRefs[name]
+		oldDesc, newDesc := describeRef(oldRef), describeRef(newRef)
+		switch {
+		case oldRef == nil:
+			lines = append(lines, fmt.Sprintf("created  %s %s", name, newDesc))
+		case newRef == nil:
+			lines = append(lines, fmt.Sprintf("deleted  %s (was %s)", name, oldDesc))
+		case oldRef.String() != newRef.String():
+			lines = append(lines, fmt.Sprintf("updated  %s %s => %s", name, oldDesc, newDesc))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// diffIndex lists the staging area entries that were added, changed or removed.
+func diffIndex(before, after *gogit.Repository) []string {
+	entries := func(repo *gogit.Repository) map[string]plumbing.Hash {
+		result := make(map[string]plumbing.Hash)
+		if idx, err := repo.Storer.Index(); err == nil {
+			for _, e := range idx.Entries {
+				result[e.Name] = e.Hash
+			}
+		}
+		return result
+	}
+	return diffHashes(entries(before), entries(after))
+}
+
+// diffWorktreeFiles lists the working tree files that were added, modified or deleted.
+func diffWorktreeFiles(before, after *gogit.Repository) []string {
+	files := func(repo *gogit.Repository) map[string][]byte {
+		result := make(map[string][]byte)
+		w, err := repo.Worktree()
+		if err != nil {
+			return result
+		}
+		_ = util.Walk(w.Filesystem, "/", func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := strings.TrimPrefix(path, "/")
+			if info.IsDir() {
+				if name == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if content, readErr := util.ReadFile(w.Filesystem, path); readErr == nil {
+				result[name] = content
+			}
+			return nil
+		})
+		return result
+	}
+
+	oldFiles, newFiles := files(before), files(after)
+	var lines []string
+	for name, content := range newFiles {
+		old, ok := oldFiles[name]
+		switch {
+		case !ok:
+			lines = append(lines, "added     "+name)
+		case !bytes.Equal(old, content):
+			lines = append(lines, "modified  "+name)
+		}
+	}
+	for name := range oldFiles {
+		if _, ok := newFiles[name]; !ok {
+			lines = append(lines, "deleted   "+name)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][10:] < lines[j][10:] })
+	return lines
+}
+
+func diffHashes(before, after map[string]plumbing.Hash) []string {
+	var lines []string
+	for name, hash := range after {
+		old, ok := before[name]
+		switch {
+		case !ok:
+			lines = append(lines, "added     "+name)
+		case old != hash:
+			lines = append(lines, "modified  "+name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			lines = append(lines, "removed   "+name)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][10:] < lines[j][10:] })
+	return lines
+}
+
+// potentialCommits returns the commits reachable from after's refs that do not exist in before.
+func potentialCommits(before, after *gogit.Repository) []Commit {
+	seen := make(map[plumbing.Hash]bool)
+	var result []Commit
+
+	var visit func(hash plumbing.Hash)
+	visit = func(hash plumbing.Hash) {
+		if seen[hash] || len(result) >= maxPotentialCommits || HasObject(before, hash) {
+			return
+		}
+		seen[hash] = true
+		c, err := after.CommitObject(hash)
+		if err != nil {
+			return
+		}
+		result = append(result, simulatedCommit(c))
+		for _, p := range c.ParentHashes {
+			visit(p)
+		}
+	}
+
+	for _, ref := range collectRefs(after) {
+		if ref.Type() == plumbing.HashReference {
+			visit(ref.Hash())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp < result[j].Timestamp })
+	return result
+}
+
+func simulatedCommit(c *object.Commit) Commit {
+	commit := Commit{
+		ID:        c.Hash.String(),
+		Message:   strings.TrimSpace(c.Message),
+		Timestamp: c.Committer.When.Format(time.RFC3339),
+		Author:    c.Author.Name,
+		TreeID:    c.TreeHash.String(),
+	}
+	if len(c.ParentHashes) > 0 {
+		commit.ParentID = c.ParentHashes[0].String()
+	}
+	if len(c.ParentHashes) > 1 {
+		commit.SecondParentID = c.ParentHashes[1].String()
+	}
+	return commit
+}
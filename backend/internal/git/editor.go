@@ -0,0 +1,147 @@
+package git
+
+// editor.go - Editor round-trip for commands that need a message
+//
+// git opens $EDITOR when commit, merge, revert or tag -a get no message. When
+// the client can host an editor, these commands return an *EditorRequest
+// instead: Dispatch keeps it on the session as a PendingEditor and hands its
+// template to the client, and CompleteEditor runs the command again with the
+// message the user wrote.
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Files git opens in the editor, shown to the user as the file being edited.
+const (
+	CommitEditFile = ".git/COMMIT_EDITMSG"
+	MergeEditFile  = ".git/MERGE_MSG"
+	TagEditFile    = ".git/TAG_EDITMSG"
+)
+
+type editorKey struct{}
+type editedMessageKey struct{}
+
+// WithEditor returns a context whose commands may ask the client for a message.
+func WithEditor(ctx context.Context) context.Context {
+	return context.WithValue(ctx, editorKey{}, true)
+}
+
+// EditorAvailable reports whether the client can edit a message for the command.
+func EditorAvailable(ctx context.Context) bool {
+	ok, _ := ctx.Value(editorKey{}).(bool)
+	return ok
+}
+
+// EditedMessage returns the message the user wrote, when the command is resumed from the editor.
+func EditedMessage(ctx context.Context) (string, bool) {
+	msg, ok := ctx.Value(editedMessageKey{}).(string)
+	return msg, ok
+}
+
+// WithEditedMessage returns a context that resumes a command with the
+// message msg, as if the user wrote it in the editor. Trace replays use it.
+func WithEditedMessage(ctx context.Context, msg string) context.Context {
+	return context.WithValue(ctx, editedMessageKey{}, msg)
+}
+
+// EditorRequest is returned by a command that needs the user to write a message.
+type EditorRequest struct {
+	File     string   // One of the *EditFile constants
+	Template string   // Initial text, usually ending with "#" comment lines
+	Resume   []string // Command to run with the message; nil runs the same command again
+	Abort    string   // Error reported when the message is empty
+}
+
+func (e *EditorRequest) Error() string {
+	return "hint: Waiting for your editor to close the file..."
+}
+
+// CommentLines turns lines into the "#" comments of an editor template.
+func CommentLines(lines ...string) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		if line == "" || strings.HasPrefix(line, "\t") {
+			sb.WriteString("#" + line + "\n")
+			continue
+		}
+		sb.WriteString("# " + line + "\n")
+	}
+	return sb.String()
+}
+
+// CleanupMessage drops comment lines, trailing spaces and surrounding blank
+// lines from an edited message, and collapses runs of blank lines, like git's
+// default "strip" cleanup.
+func CleanupMessage(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// openEditor records the request of a command as the session's pending
+// editor. The caller must hold the session lock.
+func openEditor(session *Session, args []string, req *EditorRequest) (*PendingEditor, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to open the editor: %w", err)
+	}
+	resume := req.Resume
+	if resume == nil {
+		resume = args
+	}
+	abort := req.Abort
+	if abort == "" {
+		abort = "Aborting due to empty message."
+	}
+	session.Editor = &PendingEditor{
+		ID:       hex.EncodeToString(b),
+		Command:  strings.Join(args, " "),
+		File:     req.File,
+		Template: req.Template,
+		Resume:   append([]string(nil), resume...),
+		Abort:    abort,
+	}
+	return session.Editor, nil
+}
+
+// CompleteEditor closes the pending editor id with message and runs the
+// command waiting for it. An empty message (once comments are dropped) or
+// cancel aborts the command instead.
+func CompleteEditor(ctx context.Context, session *Session, id, message string, cancel bool) (*CommandResult, error) {
+	session.Lock()
+	pending := session.Editor
+	if pending == nil || pending.ID != id {
+		session.Unlock()
+		return nil, fmt.Errorf("no editor session '%s' is open", id)
+	}
+	session.Editor = nil
+	session.Unlock()
+
+	message = CleanupMessage(message)
+	if cancel || message == "" {
+		err := fmt.Errorf("%s", pending.Abort)
+		recordAudit(session, pending.Resume[0], pending.Resume, err)
+		return newCommandResult(pending.Resume, pending.Resume[0], "", err), err
+	}
+	return Dispatch(WithEditedMessage(ctx, message), session, pending.Resume[0], pending.Resume)
+}
@@ -2,19 +2,27 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+	"github.com/kurobon/gitgym/backend/internal/logging"
 )
 
 // Command defines the interface for all git commands
 type Command interface {
 	Execute(ctx context.Context, session *Session, args []string) (string, error)
-	Help() string
+	Help() string // Help in the default language; see CommandHelp
 }
 
+// ErrCommandTimeout is the error of commands whose context expired: the
+// server bounds how long a command line may run.
+var ErrCommandTimeout = errors.New("fatal: command timed out")
+
 // CommandFactory allows creating new instances of commands
 type CommandFactory func() Command
 
@@ -25,26 +33,231 @@ func RegisterCommand(name string, factory CommandFactory) {
 	registry[name] = factory
 }
 
-// Global dispatcher
-func Dispatch(ctx context.Context, session *Session, cmdName string, args []string) (string, error) {
-	log.Printf("Dispatch: %s %v", cmdName, args)
+// Dispatch runs a command in the session. The result always describes the
+// outcome, failures included; err is the command's error, if any.
+func Dispatch(ctx context.Context, session *Session, cmdName string, args []string) (*CommandResult, error) {
+	ctx = logging.WithCommand(logging.WithSession(ctx, session.ID), cmdName)
+	slog.DebugContext(ctx, "dispatch", "args", args)
+	session.RLock()
+	dir := session.CurrentDir
+	ctx = sessionLang(ctx, session)
+	session.RUnlock()
+
 	// All commands (git and shell) are registered in the same registry
 	factory, ok := registry[cmdName]
 	if !ok {
-		return "", fmt.Errorf("'%s' is not a recognized command. See 'help'", cmdName)
+		err := &unknownCommandError{name: cmdName, lang: i18n.Lang(ctx)}
+		recordAudit(session, cmdName, args, err)
+		result := newCommandResult(args, cmdName, "", err)
+		result.Suggestion = suggest(ctx, session, cmdName, args, err)
+		recordTrace(ctx, session, dir, args, cmdName, result)
+		return result, err
+	}
+
+	// A mission can keep commands out; refuse before anything changes
+	if err := checkPolicy(ctx, session, cmdName, args); err != nil {
+		recordAudit(session, cmdName, args, err)
+		result := newCommandResult(args, cmdName, "", err)
+		recordTrace(ctx, session, dir, args, cmdName, result)
+		return result, err
+	}
+
+	// Nothing more starts once the command line ran out of time or the
+	// server is shutting down; shutdown waits for the commands that did start
+	done, err := beginDispatch(ctx, session)
+	if err != nil {
+		recordAudit(session, cmdName, args, err)
+		result := newCommandResult(args, cmdName, "", err)
+		recordTrace(ctx, session, dir, args, cmdName, result)
+		return result, err
 	}
+	defer done()
 
-	// Clear any simulation/potential commits from previous dry-runs
+	// Clear any simulation/potential commits from previous dry-runs, and
+	// close an editor left open: its command no longer runs on the same state
 	session.Lock()
 	session.PotentialCommits = nil
+	session.Editor = nil
 	session.Unlock()
 
+	if reflogRevisionCommands[cmdName] {
+		session.RLock()
+		expanded, err := expandReflogRevisions(session, args)
+		session.RUnlock()
+		if err != nil {
+			recordAudit(session, cmdName, args, err)
+			result := newCommandResult(args, cmdName, "", err)
+			recordTrace(ctx, session, dir, args, cmdName, result)
+			return result, err
+		}
+		args = expanded
+	}
+
 	cmd := factory()
 	start := time.Now()
+	if _, ok := cmd.(DryRunner); ok {
+		if stripped, dryRun := stripDryRunFlag(args); dryRun {
+			out, err := runDryRun(ctx, session, cmd, stripped)
+			logDispatch(ctx, "dispatched (dry-run)", time.Since(start), err)
+			recordAudit(session, cmdName, args, err)
+			result := newCommandResult(args, cmdName, out, err)
+			result.Payload = &CommandPayload{DryRun: true}
+			result.Suggestion = suggest(ctx, session, cmdName, stripped, err)
+			recordTrace(ctx, session, dir, args, cmdName, result)
+			return result, err
+		}
+	}
+
+	session.RLock()
+	before := takeSnapshot(session)
+	var undoBefore *UndoSnapshot
+	if !undoExemptCommands[cmdName] {
+		var err error
+		if undoBefore, err = session.TakeUndoSnapshot(strings.Join(args, " ")); err != nil {
+			slog.WarnContext(ctx, "no undo snapshot", "err", err)
+		}
+	}
+	session.RUnlock()
+
 	out, err := cmd.Execute(ctx, session, args)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Commands stop at their next check of ctx; what they did so far stays
+		err = ErrCommandTimeout
+	}
+	session.Lock()
+	var editor *PendingEditor
+	var editorReq *EditorRequest
+	if errors.As(err, &editorReq) {
+		// Not a failure: the command resumes once the client posts the message
+		if editor, err = openEditor(session, args, editorReq); err == nil {
+			out = editorReq.Error()
+		}
+	}
+	if err == nil {
+		// LFS smudge filter: checkout/reset/merge may have written pointer files,
+		// and LF copies of eol=crlf files
+		if repo := session.GetRepo(); repo != nil {
+			SmudgeLFSFiles(session, repo)
+			SmudgeLineEndings(repo)
+		}
+	}
+	// Log ref updates the command made without recording them itself; failed
+	// commands can still have moved refs (e.g. a merge stopping on conflicts)
+	session.SyncReflog(reflogMessage(cmdName, args))
+	if undoBefore != nil && session.RecordUndoSnapshot(undoBefore) && savesBefore(session.CommandPolicy, cmdName, args) {
+		if _, err := session.AddAutoSavePoint(undoBefore); err != nil {
+			slog.WarnContext(ctx, "no automatic save point", "err", err)
+		}
+	}
+	after := takeSnapshot(session)
+	session.Unlock()
 	duration := time.Since(start)
-	log.Printf("Dispatch: %s completed in %v. Error: %v", cmdName, duration, err)
-	return out, err
+	logDispatch(ctx, "dispatched", duration, err)
+	recordAudit(session, cmdName, args, err)
+
+	result := newCommandResult(args, cmdName, out, err)
+	result.Payload = before.payload(after, start)
+	result.Editor = editor
+	result.Suggestion = suggest(ctx, session, cmdName, args, err)
+	recordTrace(ctx, session, dir, args, cmdName, result)
+	return result, err
+}
+
+// beginDispatch registers a command about to run with the session manager.
+func beginDispatch(ctx context.Context, session *Session) (done func(), err error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if session.Manager == nil {
+		return func() {}, nil
+	}
+	return session.Manager.BeginOperation()
+}
+
+// contextError returns the error of a command started with an expired context.
+func contextError(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrCommandTimeout
+	default:
+		return fmt.Errorf("fatal: command canceled: %w", err)
+	}
+}
+
+// logDispatch logs how long a command took and its error, if any. Successful
+// commands are logged at debug level, which the session log keeps; failures
+// stay at info level rather than warn: they are the learner's mistakes more
+// often than the server's.
+func logDispatch(ctx context.Context, msg string, duration time.Duration, err error) {
+	if err == nil {
+		slog.DebugContext(ctx, msg, "duration", duration)
+		return
+	}
+	slog.InfoContext(ctx, msg, "duration", duration, "err", err)
+}
+
+// undoExemptCommands are not recorded in the undo history: gitgym undo and
+// redo move through it themselves.
+var undoExemptCommands = map[string]bool{"gitgym": true}
+
+// reflogRevisionCommands accept revisions, so <ref>@{n} arguments are resolved
+// against the session reflog before they run.
+var reflogRevisionCommands = map[string]bool{
+	"blame": true, "branch": true, "checkout": true, "cherry-pick": true, "diff": true,
+	"grep": true, "log": true, "merge": true, "rebase": true, "reset": true, "restore": true,
+	"revert": true, "show": true, "switch": true, "tag": true, "update-ref": true,
+	"rev-list": true, "verify-commit": true, "cat-file": true,
+}
+
+// expandReflogRevisions replaces every <ref>@{n} argument with the commit it names.
+// go-git cannot resolve these itself since the reflog lives in the session.
+func expandReflogRevisions(session *Session, args []string) ([]string, error) {
+	var expanded []string
+	for i, arg := range args {
+		resolved, ok, err := session.ResolveReflogRevision(arg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if expanded == nil {
+				expanded = append([]string(nil), args...)
+			}
+			expanded[i] = resolved
+		}
+	}
+	if expanded == nil {
+		return args, nil
+	}
+	return expanded, nil
+}
+
+// reflogMessage describes a command in reflog entries it did not word itself, e.g. "cherry-pick: abc1234".
+func reflogMessage(cmdName string, args []string) string {
+	if len(args) > 1 {
+		return cmdName + ": " + strings.Join(args[1:], " ")
+	}
+	return cmdName
+}
+
+// recordAudit appends the command to the manager's audit log, if the session
+// has one, and counts it in the analytics.
+func recordAudit(session *Session, cmdName string, args []string, err error) {
+	if session.Manager == nil {
+		return
+	}
+	command := strings.Join(args, " ")
+	if command == "" {
+		command = cmdName
+	}
+	session.Manager.RecordAudit(session.ID, command, err)
+
+	var unknown *unknownCommandError
+	if errors.As(err, &unknown) {
+		cmdName = UnknownCommandStat
+	}
+	session.Manager.RecordCommand(session.ID, cmdName, err)
 }
 
 // GetSupportedCommands returns all registered commands
@@ -56,14 +269,12 @@ func GetSupportedCommands() []string {
 	return cmds
 }
 
-// GetCommandHelp returns the help string for a command
-func GetCommandHelp(name string) (string, error) {
-	factory, ok := registry[name]
-	if !ok {
+// GetCommandHelp returns the help of a command in the language of ctx
+func GetCommandHelp(ctx context.Context, name string) (string, error) {
+	if _, ok := registry[name]; !ok {
 		return "", fmt.Errorf("command not found")
 	}
-	cmd := factory()
-	return cmd.Help(), nil
+	return CommandHelp(ctx, name), nil
 }
 
 // ParseCommand parses the raw input string and returns the resolved command name and arguments.
@@ -72,7 +283,16 @@ func GetCommandHelp(name string) (string, error) {
 func ParseCommand(input string) (string, []string) {
 	// Parse command line respecting quotes
 	parts, err := parseCommandLine(input)
-	if err != nil || len(parts) == 0 {
+	if err != nil {
+		return "", nil
+	}
+	return ResolveCommand(parts)
+}
+
+// ResolveCommand maps the words of an already split command line to the
+// registered command name and its arguments, like ParseCommand.
+func ResolveCommand(parts []string) (string, []string) {
+	if len(parts) == 0 {
 		return "", nil
 	}
 
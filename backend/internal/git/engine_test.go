@@ -1,9 +1,13 @@
 package git
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseCommand(t *testing.T) {
@@ -31,3 +35,42 @@ func TestParseCommand(t *testing.T) {
 		})
 	}
 }
+
+// slowCommand runs until its context expires.
+type slowCommand struct{ ran *bool }
+
+func (c slowCommand) Execute(ctx context.Context, _ *Session, _ []string) (string, error) {
+	*c.ran = true
+	<-ctx.Done()
+	return "", fmt.Errorf("interrupted: %w", ctx.Err())
+}
+
+func (slowCommand) Help() string { return "" }
+
+func TestDispatchCommandTimeout(t *testing.T) {
+	var ran bool
+	RegisterCommand("test-slow", func() Command { return slowCommand{ran: &ran} })
+	t.Cleanup(func() { delete(registry, "test-slow") })
+
+	s, err := NewSessionManager().CreateSession("timeout")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result, err := Dispatch(ctx, s, "test-slow", []string{"test-slow"})
+	assert.ErrorIs(t, err, ErrCommandTimeout)
+	assert.True(t, ran)
+	assert.Equal(t, ErrCommandTimeout.Error(), result.Stderr)
+
+	// An expired context does not start the command at all
+	ran = false
+	_, err = Dispatch(ctx, s, "test-slow", []string{"test-slow"})
+	assert.ErrorIs(t, err, ErrCommandTimeout)
+	assert.False(t, ran)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Dispatch(canceled, s, "test-slow", []string{"test-slow"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ran)
+}
@@ -0,0 +1,253 @@
+package git
+
+// file_history.go - History of a single path and its content at a revision
+//
+// Backs the "view file history" panel: the commits that changed a path, each
+// optionally with its patch for that path, and the file as it was at any of
+// them, without going through git log or git show in the terminal.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// FileHistoryEntry is one commit that changed the path.
+type FileHistoryEntry struct {
+	Commit  string    `json:"commit"`
+	Parents []string  `json:"parents"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    string    `json:"date"` // RFC3339
+	Message string    `json:"message"`
+	Diff    *FileDiff `json:"diff,omitempty"` // Change to the path against the first parent, when asked for
+}
+
+// FileHistoryResult lists the commits that changed a path, newest first.
+type FileHistoryResult struct {
+	Path     string             `json:"path"`
+	Revision string             `json:"revision"` // Full hash of the commit the walk started from
+	Commits  []FileHistoryEntry `json:"commits"`
+}
+
+// FileHistory lists the commits reachable from rev that changed path (a file
+// or directory relative to the repository root), as git log -- <path> does.
+// With patch set each entry carries its diff of the path; limit > 0 caps the
+// number of entries.
+func FileHistory(repo *gogit.Repository, rev, path string, patch bool, limit int) (*FileHistoryResult, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+	if _, err := repo.CommitObject(*hash); err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+
+	path = strings.Trim(path, "/")
+	iter, err := repo.Log(&gogit.LogOptions{From: *hash, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	result := &FileHistoryResult{Path: path, Revision: hash.String(), Commits: []FileHistoryEntry{}}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !CommitTouchesPaths(c, []string{path}) {
+			return nil
+		}
+		entry := FileHistoryEntry{
+			Commit:  c.Hash.String(),
+			Parents: []string{},
+			Author:  c.Author.Name,
+			Email:   c.Author.Email,
+			Date:    c.Author.When.Format(time.RFC3339),
+			Message: c.Message,
+		}
+		for _, p := range c.ParentHashes {
+			entry.Parents = append(entry.Parents, p.String())
+		}
+		if patch {
+			if entry.Diff, err = commitPathDiff(c, path); err != nil {
+				return err
+			}
+		}
+		result.Commits = append(result.Commits, entry)
+		if limit > 0 && len(result.Commits) >= limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Commits) == 0 && !pathEverExisted(repo, *hash, path) {
+		return nil, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", path)
+	}
+	return result, nil
+}
+
+// commitPathDiff diffs path between the first parent of c (or nothing, for a
+// root commit) and c. A directory yields the diff of all files below it.
+func commitPathDiff(c *object.Commit, path string) (*FileDiff, error) {
+	from := ContentSnapshot{}
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+		if from, err = TreeSnapshot(tree); err != nil {
+			return nil, err
+		}
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	to, err := TreeSnapshot(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := DiffContent(from, to, []string{path})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff: %w", err)
+	}
+	files := patch.FilePatches()
+	if len(files) == 0 {
+		return nil, nil
+	}
+	fd := newFileDiff(files[0])
+	for _, fp := range files[1:] {
+		more := newFileDiff(fp)
+		fd.Additions += more.Additions
+		fd.Deletions += more.Deletions
+		fd.Patch += more.Patch
+	}
+	if len(files) > 1 {
+		fd.Path, fd.OldPath, fd.Status = path, "", "modified"
+	}
+	return &fd, nil
+}
+
+// pathEverExisted reports whether path is in the tree of the commit at hash or
+// any of its ancestors, so a typo can be told from a path with no history.
+func pathEverExisted(repo *gogit.Repository, hash plumbing.Hash, path string) bool {
+	if path == "" {
+		return true
+	}
+	iter, err := repo.Log(&gogit.LogOptions{From: hash})
+	if err != nil {
+		return false
+	}
+	defer iter.Close()
+	found := false
+	_ = iter.ForEach(func(c *object.Commit) error {
+		if tree, err := c.Tree(); err == nil {
+			if _, err := tree.FindEntry(path); err == nil {
+				found = true
+				return storer.ErrStop
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// CommitTouchesPaths reports whether commit changes any of paths compared to
+// every parent (a merge taking a path unchanged from one side does not count).
+func CommitTouchesPaths(commit *object.Commit, paths []string) bool {
+	entryHash := func(c *object.Commit, path string) plumbing.Hash {
+		tree, err := c.Tree()
+		if err != nil {
+			return plumbing.ZeroHash
+		}
+		if path = strings.Trim(path, "/"); path == "" || path == "." {
+			return tree.Hash
+		}
+		entry, err := tree.FindEntry(path)
+		if err != nil {
+			return plumbing.ZeroHash
+		}
+		return entry.Hash
+	}
+
+	for _, path := range paths {
+		own := entryHash(commit, path)
+		if commit.NumParents() == 0 {
+			if !own.IsZero() {
+				return true
+			}
+			continue
+		}
+		changed := true
+		_ = commit.Parents().ForEach(func(p *object.Commit) error {
+			if entryHash(p, path) == own {
+				changed = false
+			}
+			return nil
+		})
+		if changed {
+			return true
+		}
+	}
+	return false
+}
+
+// FileContent is a file as it was at a revision.
+type FileContent struct {
+	Path     string `json:"path"`
+	Revision string `json:"revision"` // Full hash of the commit
+	Blob     string `json:"blob"`
+	Size     int64  `json:"size"`
+	Binary   bool   `json:"binary,omitempty"`
+	Content  string `json:"content"` // Empty for binary files
+}
+
+// FileAt returns the content of path at rev, as git show <rev>:<path> does.
+func FileAt(repo *gogit.Repository, rev, path string) (*FileContent, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+
+	path = strings.Trim(path, "/")
+	file, err := commit.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: path '%s' does not exist in '%s'", path, rev)
+	}
+	result := &FileContent{Path: path, Revision: commit.Hash.String(), Blob: file.Hash.String(), Size: file.Size}
+	if result.Binary, err = file.IsBinary(); err != nil {
+		return nil, err
+	}
+	if !result.Binary {
+		content, err := file.Contents()
+		if err != nil {
+			return nil, err
+		}
+		result.Binary = !utf8.ValidString(content)
+		if !result.Binary {
+			result.Content = content
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package git
+
+// flags.go - Parsing command lines against a CommandSpec
+//
+// Commands declare their options once, in Spec(), and parse with
+// CommandSpec.Parse, so completion and parsing agree and every command treats
+// flags alike: combined short flags (-fd), values attached or separate (-mmsg,
+// -m msg, --onto=main, --onto main), optional values that only come attached
+// (--force-with-lease, --force-with-lease=main), "--" ending the flags, and
+// git's errors for unknown options and missing values.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParsedFlag is one flag given on the command line.
+type ParsedFlag struct {
+	Name  string // First spelling of the option in the spec, e.g. "-d" for --delete
+	Value string // Value of an option that takes one
+}
+
+// ParsedArgs is a command line split into flags and arguments.
+type ParsedArgs struct {
+	Flags      []ParsedFlag // In command line order; a flag given twice appears twice
+	Positional []string     // Arguments before "--" that are not flags
+	Dashed     bool         // "--" was given
+	Rest       []string     // Arguments after "--"
+
+	spec CommandSpec
+}
+
+// Parse splits args, the arguments after the command name, into flags and
+// positional arguments. Flags and arguments may come in any order. -h and
+// --help return the "help requested" error commands answer with their help.
+func (spec CommandSpec) Parse(args []string) (*ParsedArgs, error) {
+	p := &ParsedArgs{spec: spec}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			p.Dashed = true
+			p.Rest = append(p.Rest, args[i+1:]...)
+			return p, nil
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			p.Positional = append(p.Positional, arg)
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			opt := spec.option(name)
+			if opt == nil {
+				if name == "--help" {
+					return nil, fmt.Errorf("help requested")
+				}
+				return nil, fmt.Errorf("error: unknown option `%s'", name[2:])
+			}
+			switch {
+			case opt.Arg == "" && hasValue:
+				return nil, fmt.Errorf("error: option `%s' takes no value", name[2:])
+			case opt.Arg != "" && !hasValue && !opt.Optional:
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("error: option `%s' requires a value", name[2:])
+				}
+				i++
+				value = args[i]
+			}
+			p.Flags = append(p.Flags, ParsedFlag{Name: opt.Flags[0], Value: value})
+		default:
+			consumed, err := p.shortFlags(arg, args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += consumed
+		}
+	}
+	return p, nil
+}
+
+// shortFlags parses a cluster of short flags such as -fd or -bfeature and
+// returns how many of the following arguments it used as a value.
+func (p *ParsedArgs) shortFlags(arg string, next []string) (int, error) {
+	for j := 1; j < len(arg); j++ {
+		name := "-" + arg[j:j+1]
+		opt := p.spec.option(name)
+		if opt == nil {
+			if name == "-h" {
+				return 0, fmt.Errorf("help requested")
+			}
+			return 0, fmt.Errorf("error: unknown switch `%s'", name[1:])
+		}
+		if opt.Arg == "" {
+			p.Flags = append(p.Flags, ParsedFlag{Name: opt.Flags[0]})
+			continue
+		}
+		// The rest of the cluster is the value, or else the next argument
+		if value := arg[j+1:]; value != "" {
+			p.Flags = append(p.Flags, ParsedFlag{Name: opt.Flags[0], Value: value})
+			return 0, nil
+		}
+		if len(next) == 0 {
+			return 0, fmt.Errorf("error: switch `%s' requires a value", name[1:])
+		}
+		p.Flags = append(p.Flags, ParsedFlag{Name: opt.Flags[0], Value: next[0]})
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// name returns the name flags parse to for any spelling of an option.
+func (p *ParsedArgs) name(flag string) string {
+	if opt := p.spec.option(flag); opt != nil {
+		return opt.Flags[0]
+	}
+	return flag
+}
+
+// Has reports whether the option spelled flag was given.
+func (p *ParsedArgs) Has(flag string) bool {
+	return p.Count(flag) > 0
+}
+
+// Count returns how many times the option spelled flag was given, e.g. 2 for -vv.
+func (p *ParsedArgs) Count(flag string) int {
+	name := p.name(flag)
+	n := 0
+	for _, f := range p.Flags {
+		if f.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// Value returns the value the option spelled flag was last given, or "".
+func (p *ParsedArgs) Value(flag string) string {
+	name := p.name(flag)
+	value := ""
+	for _, f := range p.Flags {
+		if f.Name == name {
+			value = f.Value
+		}
+	}
+	return value
+}
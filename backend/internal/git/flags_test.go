@@ -0,0 +1,73 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandSpecParse(t *testing.T) {
+	spec := CommandSpec{
+		Options: []Option{
+			{Flags: []string{"-f", "--force"}},
+			{Flags: []string{"-d"}},
+			{Flags: []string{"-v", "--verbose"}},
+			{Flags: []string{"-b"}, Arg: ArgText},
+			{Flags: []string{"--onto"}, Arg: ArgRef},
+			{Flags: []string{"--lease"}, Arg: ArgRef, Optional: true},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		flags      []ParsedFlag
+		positional []string
+		rest       []string
+	}{
+		{"long and short spellings", []string{"--force", "main", "-d"}, []ParsedFlag{{Name: "-f"}, {Name: "-d"}}, []string{"main"}, nil},
+		{"combined short flags", []string{"-fdv"}, []ParsedFlag{{Name: "-f"}, {Name: "-d"}, {Name: "-v"}}, nil, nil},
+		{"separate value", []string{"-b", "feature", "main"}, []ParsedFlag{{Name: "-b", Value: "feature"}}, []string{"main"}, nil},
+		{"attached value", []string{"-fbfeature"}, []ParsedFlag{{Name: "-f"}, {Name: "-b", Value: "feature"}}, nil, nil},
+		{"long value after =", []string{"--onto=main", "topic"}, []ParsedFlag{{Name: "--onto", Value: "main"}}, []string{"topic"}, nil},
+		{"long value", []string{"--onto", "main"}, []ParsedFlag{{Name: "--onto", Value: "main"}}, nil, nil},
+		{"values may start with a dash", []string{"-b", "-x"}, []ParsedFlag{{Name: "-b", Value: "-x"}}, nil, nil},
+		{"double dash ends flags", []string{"main", "--", "-f", "a.txt"}, nil, []string{"main"}, []string{"-f", "a.txt"}},
+		{"lone dash is an argument", []string{"-"}, nil, []string{"-"}, nil},
+		{"optional value left out", []string{"--lease", "main"}, []ParsedFlag{{Name: "--lease"}}, []string{"main"}, nil},
+		{"optional value attached", []string{"--lease=main"}, []ParsedFlag{{Name: "--lease", Value: "main"}}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := spec.Parse(tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.flags, p.Flags)
+			assert.Equal(t, tt.positional, p.Positional)
+			assert.Equal(t, tt.rest, p.Rest)
+		})
+	}
+
+	p, err := spec.Parse([]string{"-vv", "--verbose", "-b", "one", "-btwo"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, p.Count("--verbose"))
+	assert.True(t, p.Has("-v"))
+	assert.False(t, p.Has("--force"))
+	assert.Equal(t, "two", p.Value("-b"))
+	assert.Equal(t, "", p.Value("--onto"))
+
+	errors := map[string][]string{
+		"error: unknown option `bogus'":         {"--bogus"},
+		"error: unknown switch `x'":             {"-fx"},
+		"error: switch `b' requires a value":    {"-b"},
+		"error: option `onto' requires a value": {"--onto"},
+		"error: option `force' takes no value":  {"--force=yes"},
+		"help requested":                        {"-h"},
+	}
+	for want, args := range errors {
+		_, err := spec.Parse(args)
+		assert.EqualError(t, err, want, args)
+	}
+	_, err = spec.Parse([]string{"--help"})
+	assert.EqualError(t, err, "help requested")
+}
@@ -4,14 +4,16 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
-// GetDefaultSignature returns the default author/committer signature for operations.
-// In a real application, this should retrieve user configuration.
+// GetDefaultSignature returns the signature of the default simulated user.
+// Commands running in a session use Session.Signature, which honours the
+// identity the session picked.
 func GetDefaultSignature() *object.Signature {
 	return &object.Signature{
-		Name:  "User",
-		Email: "user@example.com",
+		Name:  state.DefaultUser.Name,
+		Email: state.DefaultUser.Email,
 		When:  time.Now(),
 	}
 }
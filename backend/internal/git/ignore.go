@@ -0,0 +1,29 @@
+package git
+
+import (
+	"github.com/go-git/go-billy/v5"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// IgnoreRule is one pattern of a .gitignore or .git/info/exclude file.
+type IgnoreRule = state.IgnoreRule
+
+// IgnoreRules are the ignore patterns of a worktree.
+type IgnoreRules = state.IgnoreRules
+
+// LoadIgnoreRules reads the ignore files of a worktree.
+// Wrapper around state.LoadIgnoreRules
+func LoadIgnoreRules(fs billy.Filesystem) *IgnoreRules {
+	return state.LoadIgnoreRules(fs)
+}
+
+// TrackedPaths returns the paths staged in the index.
+func TrackedPaths(repo *gogit.Repository) map[string]bool {
+	return state.TrackedPaths(repo)
+}
+
+// IsDirPath reports whether name is a directory in fs, or is written as one.
+func IsDirPath(fs billy.Filesystem, name string) bool {
+	return state.IsDirPath(fs, name)
+}
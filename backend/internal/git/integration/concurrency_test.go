@@ -0,0 +1,206 @@
+package integration_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// newConcurrencyRemote registers a shared remote with one commit on main and
+// clones it into each of the given sessions.
+func newConcurrencyRemote(t *testing.T, name string, sessionIDs []string) *gogit.Repository {
+	t.Helper()
+	remote, err := gogit.InitWithOptions(memory.NewStorage(), memfs.New(), gogit.InitOptions{DefaultBranch: plumbing.Main})
+	if err != nil {
+		t.Fatalf("Failed to init remote: %v", err)
+	}
+	w, _ := remote.Worktree()
+	if err := util.WriteFile(w.Filesystem, "README.md", []byte("shared\n"), 0644); err != nil {
+		t.Fatalf("Failed to write README: %v", err)
+	}
+	_, _ = w.Add("README.md")
+	if _, err := w.Commit("Initial commit", &gogit.CommitOptions{Author: &object.Signature{Name: "Owner", Email: "owner@example.com", When: time.Now()}}); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	url := "https://github.com/gitgym/" + name + ".git"
+	testSessionManager.Lock()
+	testSessionManager.SharedRemotes[url] = remote
+	testSessionManager.SharedRemotes[name] = remote
+	testSessionManager.Unlock()
+
+	for _, id := range sessionIDs {
+		if err := InitSession(id); err != nil {
+			t.Fatalf("Failed to init session: %v", err)
+		}
+		if _, err := ExecuteGitCommand(id, []string{"clone", url}); err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+	}
+	return remote
+}
+
+// commitFile commits a new file in the session's clone and returns the new HEAD.
+func commitFile(sessionID, file string) (plumbing.Hash, error) {
+	session, err := GetSession(sessionID)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := TouchFile(sessionID, session.CurrentDir+"/"+file); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := ExecuteGitCommand(sessionID, []string{"add", file}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := ExecuteGitCommand(sessionID, []string{"commit", "-m", "Add " + file}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	head, err := session.GetRepo().Head()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return head.Hash(), nil
+}
+
+// Sessions pushing their own branches while others fetch must neither race
+// (run with -race) nor lose a push.
+func TestConcurrentPushAndFetch(t *testing.T) {
+	const pushers, fetchers, rounds = 4, 3, 5
+	var ids []string
+	for i := 0; i < pushers+fetchers; i++ {
+		ids = append(ids, fmt.Sprintf("concurrent-push-fetch-%d", i))
+	}
+	remote := newConcurrencyRemote(t, "concurrent-push-fetch", ids)
+
+	tips := make([]plumbing.Hash, pushers)
+	errs := make(chan error, (pushers+fetchers)*rounds)
+	var wg sync.WaitGroup
+	for i := 0; i < pushers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, branch := ids[i], fmt.Sprintf("user-%d", i)
+			if _, err := ExecuteGitCommand(id, []string{"checkout", "-b", branch}); err != nil {
+				errs <- err
+				return
+			}
+			for r := 0; r < rounds; r++ {
+				hash, err := commitFile(id, fmt.Sprintf("file-%d-%d.txt", i, r))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := ExecuteGitCommand(id, []string{"push", "-u", "origin", branch}); err != nil {
+					errs <- fmt.Errorf("push from %s: %w", id, err)
+					return
+				}
+				tips[i] = hash
+			}
+		}(i)
+	}
+	for i := pushers; i < pushers+fetchers; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				if _, err := ExecuteGitCommand(id, []string{"fetch", "origin"}); err != nil {
+					errs <- fmt.Errorf("fetch from %s: %w", id, err)
+					return
+				}
+			}
+		}(ids[i])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for i, tip := range tips {
+		ref, err := remote.Reference(plumbing.NewBranchReferenceName(fmt.Sprintf("user-%d", i)), true)
+		if err != nil {
+			t.Fatalf("branch user-%d missing on the remote: %v", i, err)
+		}
+		if ref.Hash() != tip {
+			t.Errorf("user-%d is at %s on the remote, want %s", i, ref.Hash(), tip)
+		}
+		if _, err := remote.CommitObject(tip); err != nil {
+			t.Errorf("remote lacks the pushed commit %s: %v", tip, err)
+		}
+	}
+
+	// A fetch after the pushes sees every branch
+	if _, err := ExecuteGitCommand(ids[pushers], []string{"fetch", "origin"}); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	session, _ := GetSession(ids[pushers])
+	for i, tip := range tips {
+		ref, err := session.GetRepo().Reference(plumbing.NewRemoteReferenceName("origin", fmt.Sprintf("user-%d", i)), true)
+		if err != nil || ref.Hash() != tip {
+			t.Errorf("origin/user-%d not fetched at %s: %v", i, tip, err)
+		}
+	}
+}
+
+// Sessions racing to push to the same branch: exactly one push wins, and the
+// others are rejected as non-fast-forward instead of overwriting it.
+func TestConcurrentPushesToSameBranch(t *testing.T) {
+	const pushers = 6
+	var ids []string
+	for i := 0; i < pushers; i++ {
+		ids = append(ids, fmt.Sprintf("concurrent-same-branch-%d", i))
+	}
+	remote := newConcurrencyRemote(t, "concurrent-same-branch", ids)
+
+	hashes := make([]plumbing.Hash, pushers)
+	for i, id := range ids {
+		hash, err := commitFile(id, fmt.Sprintf("file-%d.txt", i))
+		if err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+		hashes[i] = hash
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var won []int
+	var rejected int
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			_, err := ExecuteGitCommand(id, []string{"push", "origin", "main"})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				won = append(won, i)
+			case strings.Contains(err.Error(), "non-fast-forward"):
+				rejected++
+			default:
+				t.Errorf("push from %s failed: %v", id, err)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(won) != 1 || rejected != pushers-1 {
+		t.Fatalf("want exactly one push to win, got %d winners and %d rejections", len(won), rejected)
+	}
+	main, err := remote.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("main missing on the remote: %v", err)
+	}
+	if main.Hash() != hashes[won[0]] {
+		t.Errorf("remote main is at %s, want the winning push %s", main.Hash(), hashes[won[0]])
+	}
+}
@@ -33,11 +33,12 @@ func ExecuteGitCommand(sessionID string, args []string) (string, error) {
 	cmdName := args[0]
 	cmdArgs := args
 
-	return git.Dispatch(context.Background(), session, cmdName, cmdArgs)
+	result, err := git.Dispatch(context.Background(), session, cmdName, cmdArgs)
+	return result.Stdout, err
 }
 
-func GetGraphState(sessionID string, showAll bool) (*state.GraphState, error) {
-	return testSessionManager.GetGraphState(sessionID, showAll)
+func GetGraphState(sessionID string) (*state.GraphState, error) {
+	return testSessionManager.GetGraphState(sessionID)
 }
 
 func TouchFile(sessionID, filename string) error {
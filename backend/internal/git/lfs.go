@@ -0,0 +1,181 @@
+package git
+
+// lfs.go - Simulated Git LFS filters
+//
+// ApplyLFSCleanFilter plays the role of git-lfs's "clean" filter: after
+// staging, large or LFS-tracked files are replaced in the index by pointer
+// blobs and their content moves to the session's LFS cache.
+// SmudgeLFSFiles plays the "smudge" filter: pointer files written to the
+// worktree by checkout/reset/merge are replaced with their real content.
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// LFSPointer identifies large-file content stored outside the object database.
+type LFSPointer = state.LFSPointer
+
+// ParseLFSPointer reports whether data is an LFS pointer file and decodes it.
+// Wrapper around state.ParseLFSPointer
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	return state.ParseLFSPointer(data)
+}
+
+// LFSAwareStatus returns the worktree status with LFS-tracked files compared by content.
+// Wrapper around state.LFSAwareStatus
+func LFSAwareStatus(repo *gogit.Repository, w *gogit.Worktree) (gogit.Status, error) {
+	return state.LFSAwareStatus(repo, w)
+}
+
+// LFSPatterns returns the patterns marked "filter=lfs" in the worktree's .gitattributes.
+// Wrapper around state.LFSPatterns
+func LFSPatterns(fs billy.Filesystem) []string {
+	return state.LFSPatterns(fs)
+}
+
+// IndexHashes snapshots the blob hash of every index entry, keyed by path.
+func IndexHashes(repo *gogit.Repository) map[string]plumbing.Hash {
+	hashes := make(map[string]plumbing.Hash)
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return hashes
+	}
+	for _, e := range idx.Entries {
+		hashes[e.Name] = e.Hash
+	}
+	return hashes
+}
+
+// ApplyLFSCleanFilter converts index entries staged since before into LFS pointers
+// when they match a .gitattributes LFS pattern or exceed state.LFSSizeThreshold.
+// It returns the paths that were converted.
+func ApplyLFSCleanFilter(s *Session, repo *gogit.Repository, w *gogit.Worktree, before map[string]plumbing.Hash) ([]string, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := state.LFSPatterns(w.Filesystem)
+	var converted []string
+	for _, e := range idx.Entries {
+		if prev, ok := before[e.Name]; ok && prev == e.Hash {
+			continue
+		}
+		content, err := readBlob(repo, e.Hash)
+		if err != nil {
+			continue
+		}
+		if _, isPointer := state.ParseLFSPointer(content); isPointer {
+			continue
+		}
+		if !state.MatchesLFSPattern(patterns, e.Name) && len(content) <= state.LFSSizeThreshold {
+			continue
+		}
+
+		pointer := s.StoreLFSObject(content)
+		hash, err := writeBlob(repo, []byte(pointer.String()))
+		if err != nil {
+			return nil, err
+		}
+		e.Hash = hash
+		e.Size = uint32(len(pointer.String()))
+		converted = append(converted, e.Name)
+	}
+
+	if len(converted) == 0 {
+		return nil, nil
+	}
+	return converted, repo.Storer.SetIndex(idx)
+}
+
+// SmudgeLFSFiles replaces pointer files in the worktree with their content
+// from the session cache or the simulated LFS server. Pointers whose content
+// is unavailable are left as-is, just like a checkout without LFS installed.
+func SmudgeLFSFiles(s *Session, repo *gogit.Repository) {
+	if len(s.LFSObjects) == 0 && (s.Manager == nil || !s.Manager.HasLFSObjects()) {
+		return
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return
+	}
+
+	for _, e := range idx.Entries {
+		if e.Size > 1024 {
+			continue
+		}
+		data, err := util.ReadFile(w.Filesystem, e.Name)
+		if err != nil {
+			continue
+		}
+		pointer, ok := state.ParseLFSPointer(data)
+		if !ok {
+			continue
+		}
+		if content, ok := s.LFSObject(pointer.OID); ok {
+			_ = util.WriteFile(w.Filesystem, e.Name, content, 0644)
+		}
+	}
+}
+
+// LFSIndexEntries returns the index entries stored as LFS pointers, with their pointers.
+func LFSIndexEntries(repo *gogit.Repository) ([]*index.Entry, []LFSPointer) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, nil
+	}
+	var entries []*index.Entry
+	var pointers []LFSPointer
+	for _, e := range idx.Entries {
+		content, err := readBlob(repo, e.Hash)
+		if err != nil {
+			continue
+		}
+		if pointer, ok := state.ParseLFSPointer(content); ok {
+			entries = append(entries, e)
+			pointers = append(pointers, pointer)
+		}
+	}
+	return entries, pointers
+}
+
+func readBlob(repo *gogit.Repository, hash plumbing.Hash) ([]byte, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func writeBlob(repo *gogit.Repository, content []byte) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.Copy(w, bytes.NewReader(content)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
@@ -0,0 +1,37 @@
+package git
+
+// locale.go - Language of help and messages
+//
+// Commands answer in the language the request asks for or, when it asks for
+// none, in the locale the session last asked for (see Session.Locale). Their
+// help and GitGym's own messages come from the locale files of package i18n.
+
+import (
+	"context"
+
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+)
+
+// WithLang returns a context whose commands answer in lang.
+func WithLang(ctx context.Context, lang string) context.Context {
+	return i18n.WithLang(ctx, lang)
+}
+
+// T returns the message key in the language of ctx, formatted with args.
+func T(ctx context.Context, key string, args ...any) string {
+	return i18n.T(i18n.Lang(ctx), key, args...)
+}
+
+// CommandHelp returns the help of a registered command in the language of ctx.
+func CommandHelp(ctx context.Context, name string) string {
+	return T(ctx, "help."+name)
+}
+
+// sessionLang returns ctx, asking for the session's locale unless it already
+// asks for a language. Caller holds at least the session's read lock.
+func sessionLang(ctx context.Context, session *Session) context.Context {
+	if i18n.Lang(ctx) != "" || session.Locale == "" {
+		return ctx
+	}
+	return i18n.WithLang(ctx, session.Locale)
+}
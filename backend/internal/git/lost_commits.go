@@ -0,0 +1,147 @@
+package git
+
+// lost_commits.go - Commits no ref reaches any more
+//
+// Commits made on a detached HEAD, or left behind by reset and branch -D,
+// stay in the repository but nothing points at them. Git warns when HEAD
+// leaves such commits behind; the session reflog still remembers them, so
+// they can be found again and given a branch.
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// LostCommit is a commit the reflog remembers that no ref reaches any more.
+type LostCommit struct {
+	Hash    plumbing.Hash
+	Subject string
+	Reflog  string    // Message of the newest reflog entry that moved a ref to the commit
+	When    time.Time // Time of that entry
+}
+
+// RefTips returns the commits of HEAD, branches, remote-tracking branches and tags.
+func RefTips(repo *gogit.Repository) []plumbing.Hash {
+	var tips []plumbing.Hash
+	if head, err := repo.Head(); err == nil {
+		tips = append(tips, head.Hash())
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return tips
+	}
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if ref.Type() != plumbing.HashReference || !(name.IsBranch() || name.IsRemote() || name.IsTag()) {
+			return nil
+		}
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			if commit, err := tag.Commit(); err == nil {
+				hash = commit.Hash
+			}
+		}
+		if _, err := repo.CommitObject(hash); err == nil {
+			tips = append(tips, hash)
+		}
+		return nil
+	})
+	return tips
+}
+
+// LeftBehind returns the commits reachable from from that HEAD and the refs
+// no longer reach, newest first.
+func LeftBehind(repo *gogit.Repository, from plumbing.Hash) []*object.Commit {
+	reachable := ReachableCommits(repo, RefTips(repo))
+	var lost []*object.Commit
+	seen := make(map[plumbing.Hash]bool)
+	queue := []plumbing.Hash{from}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if seen[hash] || reachable[hash] {
+			continue
+		}
+		seen[hash] = true
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+		lost = append(lost, commit)
+		queue = append(queue, commit.ParentHashes...)
+	}
+	sortNewestFirst(lost)
+	return lost
+}
+
+// LostCommits lists the commits the reflog of the active repository mentions
+// that no ref reaches any more. Only the tips of lost histories are listed:
+// a commit whose lost descendant is listed is left out. Newest entry first.
+// The caller must hold the session lock.
+func LostCommits(s *Session) []LostCommit {
+	repo := s.GetRepo()
+	if repo == nil {
+		return nil
+	}
+	reachable := ReachableCommits(repo, RefTips(repo))
+
+	entries := make(map[plumbing.Hash]ReflogEntry)
+	for _, e := range s.Reflog {
+		if e.Context != s.CurrentDir {
+			continue
+		}
+		for _, h := range []string{e.OldHash, e.Hash} {
+			hash := plumbing.NewHash(h)
+			if hash.IsZero() || reachable[hash] {
+				continue
+			}
+			if prev, ok := entries[hash]; !ok || !e.Timestamp.Before(prev.Timestamp) {
+				entries[hash] = e
+			}
+		}
+	}
+
+	// Drop ancestors of other lost commits
+	var candidates []plumbing.Hash
+	for hash := range entries {
+		candidates = append(candidates, hash)
+	}
+	covered := make(map[plumbing.Hash]bool)
+	for _, hash := range candidates {
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+		for h := range ReachableCommits(repo, commit.ParentHashes) {
+			covered[h] = true
+		}
+	}
+
+	var lost []LostCommit
+	for hash, e := range entries {
+		commit, err := repo.CommitObject(hash)
+		if err != nil || covered[hash] {
+			continue
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		lost = append(lost, LostCommit{Hash: hash, Subject: subject, Reflog: e.Message, When: e.Timestamp})
+	}
+	sort.Slice(lost, func(i, j int) bool {
+		if !lost[i].When.Equal(lost[j].When) {
+			return lost[i].When.After(lost[j].When)
+		}
+		return lost[i].Hash.String() < lost[j].Hash.String()
+	})
+	return lost
+}
+
+func sortNewestFirst(commits []*object.Commit) {
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
+}
@@ -0,0 +1,495 @@
+package git
+
+// mail_patch.go - Patches as email, for format-patch, apply and am
+//
+// FormatPatch writes a commit the way "git format-patch" does: an mbox
+// message whose headers carry the author and subject, whose body is the rest
+// of the commit message, followed by a diffstat and the unified diff.
+// ParsePatches reads such messages back, or a plain diff as "git diff"
+// prints it, and ApplyFilePatch replays one file's hunks onto its content.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// mboxMagic ends the "From <commit>" line separating the messages of an mbox,
+// git's fixed date telling it apart from a real mailbox.
+const mboxMagic = " Mon Sep 17 00:00:00 2001"
+
+// MailPatch is one commit sent as a patch.
+type MailPatch struct {
+	Commit  string // Hash from the "From <commit>" separator; empty for a plain diff
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string // Without the "[PATCH n/m]" prefix
+	Body    string // The rest of the commit message
+	Files   []*FilePatch
+}
+
+// Message returns the commit message the patch was made from.
+func (p *MailPatch) Message() string {
+	if p.Body == "" {
+		return p.Subject + "\n"
+	}
+	return p.Subject + "\n\n" + p.Body + "\n"
+}
+
+// FilePatch is the change a patch makes to one file.
+type FilePatch struct {
+	OldPath string // Empty for an added file
+	NewPath string // Empty for a deleted file
+	OldHash string // Blob before the change, from the "index" line; may be abbreviated
+	Binary  bool   // "Binary files differ": there is nothing to apply
+	Hunks   []Hunk
+}
+
+// Path returns the path the patch applies to.
+func (fp *FilePatch) Path() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// Hunk is one "@@ -a,b +c,d @@" section of a file patch.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []string // Each with its ' ', '-' or '+' prefix and, unless at a missing end-of-file newline, "\n"
+}
+
+// FormatPatch renders commit as message n of total of a patch series.
+func FormatPatch(commit *object.Commit, n, total int) (string, error) {
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return "", err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return "", err
+		}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute patch: %w", err)
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute patch: %w", err)
+	}
+
+	subject, body, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	prefix := "[PATCH]"
+	if total > 1 {
+		prefix = fmt.Sprintf("[PATCH %d/%d]", n, total)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("From %s%s\n", commit.Hash, mboxMagic))
+	sb.WriteString(fmt.Sprintf("From: %s <%s>\n", commit.Author.Name, commit.Author.Email))
+	sb.WriteString(fmt.Sprintf("Date: %s\n", commit.Author.When.Format(time.RFC1123Z)))
+	sb.WriteString(fmt.Sprintf("Subject: %s %s\n\n", prefix, strings.TrimSpace(subject)))
+	if body = strings.TrimSpace(body); body != "" {
+		sb.WriteString(body + "\n")
+	}
+	sb.WriteString("---\n")
+	sb.WriteString(patchStat(patch.Stats()))
+	sb.WriteString("\n")
+	if err := diff.NewUnifiedEncoder(&sb, diff.DefaultContextLines).Encode(patch); err != nil {
+		return "", err
+	}
+	sb.WriteString("-- \ngitgym\n\n")
+	return sb.String(), nil
+}
+
+// patchStat is the diffstat between the message and the diff.
+func patchStat(stats object.FileStats) string {
+	var sb strings.Builder
+	width := 0
+	for _, s := range stats {
+		width = max(width, len(s.Name))
+	}
+	added, deleted := 0, 0
+	for _, s := range stats {
+		sb.WriteString(fmt.Sprintf(" %-*s | %d %s%s\n", width, s.Name, s.Addition+s.Deletion, strings.Repeat("+", min(s.Addition, 40)), strings.Repeat("-", min(s.Deletion, 40))))
+		added += s.Addition
+		deleted += s.Deletion
+	}
+	sb.WriteString(fmt.Sprintf(" %d file%s changed", len(stats), plural(len(stats))))
+	if added > 0 {
+		sb.WriteString(fmt.Sprintf(", %d insertion%s(+)", added, plural(added)))
+	}
+	if deleted > 0 {
+		sb.WriteString(fmt.Sprintf(", %d deletion%s(-)", deleted, plural(deleted)))
+	}
+	return sb.String() + "\n"
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+var nonFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._]+`)
+
+// PatchFileName names message n of a series like format-patch:
+// "0001-Fix-the-typo.patch".
+func PatchFileName(n int, subject string) string {
+	name := strings.Trim(nonFileNameChars.ReplaceAllString(subject, "-"), "-.")
+	if len(name) > 52 {
+		name = strings.TrimRight(name[:52], "-.")
+	}
+	return fmt.Sprintf("%04d-%s.patch", n, name)
+}
+
+var (
+	hunkHeader    = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	subjectPrefix = regexp.MustCompile(`^\[PATCH[^\]]*\]\s*`)
+)
+
+// SplitMbox splits an mbox written by format-patch into its messages. Text
+// without a "From <commit>" line is a single message, a plain diff.
+func SplitMbox(data []byte) ([]string, error) {
+	messages, err := splitMbox(data)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(messages))
+	for i, lines := range messages {
+		texts[i] = strings.Join(lines, "\n") + "\n"
+	}
+	return texts, nil
+}
+
+func splitMbox(data []byte) ([][]string, error) {
+	var messages [][]string
+	var current []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") && strings.HasSuffix(line, mboxMagic) {
+			if current != nil {
+				messages = append(messages, current)
+			}
+			current = []string{}
+		}
+		current = append(current, line)
+	}
+	if current != nil {
+		messages = append(messages, current)
+	}
+	return messages, scanner.Err()
+}
+
+// ParsePatches reads the messages of an mbox written by format-patch, or a
+// plain diff as a single message without headers.
+func ParsePatches(data []byte) ([]*MailPatch, error) {
+	messages, err := splitMbox(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var patches []*MailPatch
+	for _, lines := range messages {
+		p, err := parseMessage(lines)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, p)
+	}
+	if len(patches) == 0 || (len(patches[0].Files) == 0 && patches[0].Commit == "") {
+		return nil, fmt.Errorf("error: No valid patches in input")
+	}
+	return patches, nil
+}
+
+// parseMessage reads one message: headers and body for an email, then the diff.
+func parseMessage(lines []string) (*MailPatch, error) {
+	p := &MailPatch{}
+	i := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "From ") && strings.HasSuffix(lines[0], mboxMagic) {
+		p.Commit = strings.TrimSuffix(strings.TrimPrefix(lines[0], "From "), mboxMagic)
+		i = 1
+		var header string
+		for ; i < len(lines) && lines[i] != ""; i++ {
+			line := lines[i]
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				// Folded header line
+				if header == "subject" {
+					p.Subject += " " + strings.TrimSpace(line)
+				}
+				continue
+			}
+			name, value, _ := strings.Cut(line, ":")
+			header = strings.ToLower(name)
+			value = strings.TrimSpace(value)
+			switch header {
+			case "from":
+				if addr, err := mail.ParseAddress(value); err == nil {
+					p.Author, p.Email = addr.Name, addr.Address
+				} else {
+					p.Author = value
+				}
+			case "date":
+				if date, err := mail.ParseDate(value); err == nil {
+					p.Date = date
+				}
+			case "subject":
+				p.Subject = value
+			}
+		}
+		p.Subject = subjectPrefix.ReplaceAllString(p.Subject, "")
+
+		var body []string
+		for i++; i < len(lines) && lines[i] != "---" && !strings.HasPrefix(lines[i], "diff --git "); i++ {
+			body = append(body, lines[i])
+		}
+		p.Body = strings.TrimSpace(strings.Join(body, "\n"))
+	}
+
+	files, err := parseDiff(lines[i:])
+	if err != nil {
+		return nil, err
+	}
+	p.Files = files
+	return p, nil
+}
+
+// parseDiff reads the file patches of a unified diff, skipping any text
+// around them (a diffstat, an email signature).
+func parseDiff(lines []string) ([]*FilePatch, error) {
+	var files []*FilePatch
+	var fp *FilePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			a, b, _ := strings.Cut(strings.TrimPrefix(line, "diff --git "), " b/")
+			fp = &FilePatch{OldPath: strings.TrimPrefix(a, "a/"), NewPath: b}
+			files = append(files, fp)
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if fp == nil || len(fp.Hunks) > 0 {
+				// A plain diff without "diff --git" lines
+				fp = &FilePatch{}
+				files = append(files, fp)
+			}
+			fp.OldPath = patchPath(strings.TrimPrefix(line, "--- "), "a/")
+			fp.NewPath = patchPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+			i++
+		case fp == nil:
+			continue
+		case strings.HasPrefix(line, "new file mode "):
+			fp.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode "):
+			fp.NewPath = ""
+		case strings.HasPrefix(line, "index "):
+			hashes, _, _ := strings.Cut(strings.TrimPrefix(line, "index "), " ")
+			fp.OldHash, _, _ = strings.Cut(hashes, "..")
+		case strings.HasPrefix(line, "Binary files "):
+			fp.Binary = true
+		case strings.HasPrefix(line, "@@ "):
+			h, n, err := parseHunk(lines[i:])
+			if err != nil {
+				return nil, fmt.Errorf("error: corrupt patch for %s: %v", fp.Path(), err)
+			}
+			fp.Hunks = append(fp.Hunks, h)
+			i += n - 1
+		}
+	}
+	return files, nil
+}
+
+// patchPath strips the a/ or b/ prefix of a ---/+++ line; /dev/null is no file.
+func patchPath(name, prefix string) string {
+	name, _, _ = strings.Cut(name, "\t")
+	if name == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// parseHunk reads the hunk starting at lines[0] and returns it with the
+// number of lines it spans.
+func parseHunk(lines []string) (Hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[0])
+	if m == nil {
+		return Hunk{}, 0, fmt.Errorf("bad hunk header %q", lines[0])
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h := Hunk{OldLines: count(m[2]), NewLines: count(m[4])}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.NewStart, _ = strconv.Atoi(m[3])
+
+	removed, added := 0, 0
+	n := 1
+	for ; n < len(lines) && (removed < h.OldLines || added < h.NewLines); n++ {
+		line := lines[n]
+		if line == "" {
+			line = " " // Some mailers strip the space of empty context lines
+		}
+		switch line[0] {
+		case ' ':
+			removed++
+			added++
+		case '-':
+			removed++
+		case '+':
+			added++
+		case '\\':
+			// "\ No newline at end of file" of the line before
+			if len(h.Lines) == 0 {
+				return Hunk{}, 0, fmt.Errorf("unexpected line %q", line)
+			}
+			h.Lines[len(h.Lines)-1] = strings.TrimSuffix(h.Lines[len(h.Lines)-1], "\n")
+			continue
+		default:
+			return Hunk{}, 0, fmt.Errorf("unexpected line %q", line)
+		}
+		h.Lines = append(h.Lines, line+"\n")
+	}
+	if removed != h.OldLines || added != h.NewLines {
+		return Hunk{}, 0, fmt.Errorf("truncated hunk")
+	}
+	if n < len(lines) && strings.HasPrefix(lines[n], `\ No newline at end of file`) {
+		h.Lines[len(h.Lines)-1] = strings.TrimSuffix(h.Lines[len(h.Lines)-1], "\n")
+		n++
+	}
+	return h, n, nil
+}
+
+// ApplyFilePatch applies the hunks of fp to content. Like git apply, a hunk
+// whose context moved is looked for elsewhere in the file, but its context
+// must match exactly.
+func ApplyFilePatch(content string, fp *FilePatch) (string, error) {
+	if fp.Binary {
+		return "", fmt.Errorf("error: cannot apply binary patch to '%s' without full index line", fp.Path())
+	}
+	lines := splitLines(content)
+	var result []string
+	cursor := 0
+	for _, h := range fp.Hunks {
+		var before, after []string
+		for _, line := range h.Lines {
+			switch line[0] {
+			case ' ':
+				before = append(before, line[1:])
+				after = append(after, line[1:])
+			case '-':
+				before = append(before, line[1:])
+			case '+':
+				after = append(after, line[1:])
+			}
+		}
+
+		want := h.OldStart - 1
+		if h.OldLines == 0 {
+			want = h.OldStart
+		}
+		at := findLines(lines, before, cursor, want)
+		if at < 0 {
+			return "", fmt.Errorf("error: patch failed: %s:%d", fp.Path(), h.OldStart)
+		}
+		result = append(result, lines[cursor:at]...)
+		result = append(result, after...)
+		cursor = at + len(before)
+	}
+	result = append(result, lines[cursor:]...)
+	return strings.Join(result, ""), nil
+}
+
+// findLines returns where want appears in lines at or after from, trying
+// the positions nearest to near first, or -1.
+func findLines(lines, want []string, from, near int) int {
+	matches := func(at int) bool {
+		if at < from || at+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; near-d >= from || near+d <= len(lines); d++ {
+		if matches(near + d) {
+			return near + d
+		}
+		if d > 0 && matches(near-d) {
+			return near - d
+		}
+	}
+	return -1
+}
+
+// PatchPreimage returns the content fp was made against, from the blob its
+// index line names, for a three-way merge. ok is false when the repository
+// does not have that blob.
+func PatchPreimage(repo *gogit.Repository, fp *FilePatch) (content string, ok bool) {
+	if fp.OldPath == "" {
+		return "", true
+	}
+	if len(fp.OldHash) < 7 || strings.Trim(fp.OldHash, "0") == "" {
+		return "", false
+	}
+	hash := plumbing.NewHash(fp.OldHash)
+	if len(fp.OldHash) < 40 {
+		iter, err := repo.BlobObjects()
+		if err != nil {
+			return "", false
+		}
+		found := false
+		_ = iter.ForEach(func(b *object.Blob) error {
+			if strings.HasPrefix(b.Hash.String(), fp.OldHash) {
+				hash, found = b.Hash, true
+			}
+			return nil
+		})
+		if !found {
+			return "", false
+		}
+	}
+	data, err := readBlob(repo, hash)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// ConflictMarkers renders a file both sides changed, as Merge3Way does.
+func ConflictMarkers(ours, theirs, label string) string {
+	if ours != "" && !strings.HasSuffix(ours, "\n") {
+		ours += "\n"
+	}
+	if theirs != "" && !strings.HasSuffix(theirs, "\n") {
+		theirs += "\n"
+	}
+	return fmt.Sprintf("<<<<<<< HEAD\n%s=======\n%s>>>>>>> %s\n", ours, theirs, label)
+}
@@ -0,0 +1,357 @@
+package git
+
+// object_transfer.go - Have/want negotiation for push, fetch and pull
+//
+// Instead of copying history commit by commit and asking the destination
+// about every object on the way, a transfer is planned first, as git's pack
+// protocol does: the receiving side names the commits it has (its refs, and
+// the remote-tracking refs that record what it last saw of the other side),
+// the walk from the wanted tips stops at those, and the trees of the commits
+// it stops at mark the files both sides already share. What is left is
+// copied in one batch: a single packfile when the destination can take one,
+// loose objects below transferUnpackLimit like git's transfer.unpackLimit.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// transferUnpackLimit is the number of objects below which a transfer is
+// stored as loose objects rather than a packfile, as with git's default
+// transfer.unpackLimit.
+const transferUnpackLimit = 100
+
+// ObjectTransfer is the result of a have/want negotiation: the objects dst
+// lacks to have the wanted tips, in the order they can be stored (blobs and
+// trees before the commits using them, parents before children).
+type ObjectTransfer struct {
+	Objects   int   // Number of objects to copy
+	BlobBytes int64 // Size of the blobs among them
+
+	src, dst *gogit.Repository
+	order    []plumbing.Hash
+}
+
+// negotiation is the state of one NegotiateTransfer walk.
+type negotiation struct {
+	ctx      context.Context
+	src, dst *gogit.Repository
+	common   map[plumbing.Hash]bool // Commits dst has, with their history
+	known    map[plumbing.Hash]bool // Trees and blobs dst has
+	queued   map[plumbing.Hash]bool // Objects already in the transfer
+	t        *ObjectTransfer
+}
+
+// NegotiateTransfer works out which objects reachable from wants src has to
+// send dst. haves are commits dst is believed to have, with their history;
+// those dst does not actually have are ignored, so a stale remote-tracking
+// ref only costs a lookup. A want that src lacks is an error wrapping
+// plumbing.ErrObjectNotFound; a canceled ctx stops the walk with ctx.Err().
+func NegotiateTransfer(ctx context.Context, src, dst *gogit.Repository, wants, haves []plumbing.Hash) (*ObjectTransfer, error) {
+	n := &negotiation{
+		ctx:    ctx,
+		src:    src,
+		dst:    dst,
+		common: make(map[plumbing.Hash]bool),
+		known:  make(map[plumbing.Hash]bool),
+		queued: make(map[plumbing.Hash]bool),
+		t:      &ObjectTransfer{src: src, dst: dst},
+	}
+	for _, h := range haves {
+		if !h.IsZero() && HasObject(dst, h) {
+			n.common[h] = true
+		}
+	}
+
+	var tags []plumbing.Hash
+	var commits []plumbing.Hash
+	for _, want := range wants {
+		// Annotated tags are sent along with what they point at
+		for {
+			if n.queued[want] || HasObject(dst, want) {
+				want = plumbing.ZeroHash
+				break
+			}
+			obj, err := src.Storer.EncodedObject(plumbing.AnyObject, want)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", plumbing.ErrObjectNotFound, want)
+			}
+			if obj.Type() != plumbing.TagObject {
+				break
+			}
+			tag, err := object.DecodeTag(src.Storer, obj)
+			if err != nil {
+				return nil, err
+			}
+			n.queued[want] = true
+			tags = append(tags, want)
+			want = tag.Target
+		}
+		if want.IsZero() {
+			continue
+		}
+		obj, err := src.Storer.EncodedObject(plumbing.AnyObject, want)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", plumbing.ErrObjectNotFound, want)
+		}
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			commits = append(commits, want)
+		case plumbing.TreeObject:
+			if err := n.addTree(want); err != nil {
+				return nil, err
+			}
+		default:
+			n.addBlob(obj)
+		}
+	}
+
+	newCommits, edges, err := n.walkCommits(commits)
+	if err != nil {
+		return nil, err
+	}
+	// Everything in the trees of the commits the walk stopped at is shared
+	for _, edge := range edges {
+		if commit, err := src.CommitObject(edge); err == nil {
+			n.markKnown(commit.TreeHash)
+		}
+	}
+	for _, commit := range newCommits {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := n.addTree(commit.TreeHash); err != nil {
+			return nil, err
+		}
+		n.add(commit.Hash)
+	}
+	// Tags last, outermost last
+	for i := len(tags) - 1; i >= 0; i-- {
+		n.t.order = append(n.t.order, tags[i])
+	}
+	n.t.Objects = len(n.t.order)
+	return n.t, nil
+}
+
+// walkCommits returns the commits reachable from tips that dst lacks,
+// parents before children, and the commits dst has that the walk stopped at.
+func (n *negotiation) walkCommits(tips []plumbing.Hash) ([]*object.Commit, []plumbing.Hash, error) {
+	type frame struct {
+		commit   *object.Commit
+		expanded bool
+	}
+	visited := make(map[plumbing.Hash]bool)
+	edges := make(map[plumbing.Hash]bool)
+	var edgeList []plumbing.Hash
+	var ordered []*object.Commit
+	var stack []frame
+
+	push := func(hash plumbing.Hash) error {
+		if visited[hash] {
+			return nil
+		}
+		visited[hash] = true
+		if n.common[hash] || HasObject(n.dst, hash) {
+			if !edges[hash] {
+				edges[hash] = true
+				edgeList = append(edgeList, hash)
+			}
+			return nil
+		}
+		commit, err := n.src.CommitObject(hash)
+		if err != nil {
+			return fmt.Errorf("%w: commit %s", plumbing.ErrObjectNotFound, hash)
+		}
+		stack = append(stack, frame{commit: commit})
+		return nil
+	}
+
+	for _, tip := range tips {
+		if err := push(tip); err != nil {
+			return nil, nil, err
+		}
+		for len(stack) > 0 {
+			if err := n.ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			top := &stack[len(stack)-1]
+			if top.expanded {
+				ordered = append(ordered, top.commit)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			top.expanded = true
+			commit := top.commit
+			for _, p := range commit.ParentHashes {
+				if err := push(p); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	return ordered, edgeList, nil
+}
+
+// markKnown records the trees and blobs under the tree at hash as present in dst.
+func (n *negotiation) markKnown(hash plumbing.Hash) {
+	if n.known[hash] {
+		return
+	}
+	n.known[hash] = true
+	tree, err := object.GetTree(n.src.Storer, hash)
+	if err != nil {
+		return
+	}
+	for _, e := range tree.Entries {
+		switch {
+		case e.Mode == 0160000:
+		case e.Mode.IsFile():
+			n.known[e.Hash] = true
+		default:
+			n.markKnown(e.Hash)
+		}
+	}
+}
+
+// addTree queues the tree at hash and what dst lacks under it.
+func (n *negotiation) addTree(hash plumbing.Hash) error {
+	if n.known[hash] || n.queued[hash] {
+		return nil
+	}
+	if HasObject(n.dst, hash) {
+		n.known[hash] = true
+		return nil
+	}
+	tree, err := object.GetTree(n.src.Storer, hash)
+	if err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		switch {
+		case e.Mode == 0160000:
+			// Submodule commits live in another repository
+		case e.Mode.IsFile():
+			if n.known[e.Hash] || n.queued[e.Hash] || HasObject(n.dst, e.Hash) {
+				continue
+			}
+			obj, err := n.src.Storer.EncodedObject(plumbing.BlobObject, e.Hash)
+			if err != nil {
+				return err
+			}
+			n.addBlob(obj)
+		default:
+			if err := n.addTree(e.Hash); err != nil {
+				return err
+			}
+		}
+	}
+	n.add(hash)
+	return nil
+}
+
+func (n *negotiation) addBlob(obj plumbing.EncodedObject) {
+	if n.queued[obj.Hash()] {
+		return
+	}
+	n.t.BlobBytes += obj.Size()
+	n.add(obj.Hash())
+}
+
+func (n *negotiation) add(hash plumbing.Hash) {
+	n.queued[hash] = true
+	n.t.order = append(n.t.order, hash)
+}
+
+// Apply copies the negotiated objects to dst in one batch. A canceled ctx
+// stops the copy; a batch is rolled back, loose objects already stored stay.
+func (t *ObjectTransfer) Apply(ctx context.Context) error {
+	if len(t.order) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if pw, ok := t.dst.Storer.(storer.PackfileWriter); ok && len(t.order) >= transferUnpackLimit {
+		return t.writePack(pw)
+	}
+	if tx, ok := t.dst.Storer.(storer.Transactioner); ok {
+		batch := tx.Begin()
+		for _, hash := range t.order {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(err, batch.Rollback())
+			}
+			obj, err := t.src.Storer.EncodedObject(plumbing.AnyObject, hash)
+			if err != nil {
+				return errors.Join(err, batch.Rollback())
+			}
+			if _, err := batch.SetEncodedObject(obj); err != nil {
+				return errors.Join(err, batch.Rollback())
+			}
+		}
+		return batch.Commit()
+	}
+	for _, hash := range t.order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		obj, err := t.src.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return err
+		}
+		if _, err := t.dst.Storer.SetEncodedObject(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePack sends the objects to dst as a single packfile.
+func (t *ObjectTransfer) writePack(pw storer.PackfileWriter) error {
+	w, err := pw.PackfileWriter()
+	if err != nil {
+		return err
+	}
+	if _, err := packfile.NewEncoder(w, t.src.Storer, false).Encode(t.order, 0); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// TransferObjects negotiates and applies a transfer of wants from src to dst.
+func TransferObjects(ctx context.Context, src, dst *gogit.Repository, wants, haves []plumbing.Hash) error {
+	t, err := NegotiateTransfer(ctx, src, dst, wants, haves)
+	if err != nil {
+		return err
+	}
+	return t.Apply(ctx)
+}
+
+// RefHaves returns the tips of the refs of repo whose names start with
+// prefix: the commits to offer as haves when repo, or the repository its
+// remote-tracking refs mirror, receives objects.
+func RefHaves(repo *gogit.Repository, prefix string) []plumbing.Hash {
+	refs, err := repo.References()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[plumbing.Hash]bool)
+	var haves []plumbing.Hash
+	_ = refs.ForEach(func(r *plumbing.Reference) error {
+		if r.Type() != plumbing.HashReference || seen[r.Hash()] || !strings.HasPrefix(r.Name().String(), prefix) {
+			return nil
+		}
+		seen[r.Hash()] = true
+		haves = append(haves, r.Hash())
+		return nil
+	})
+	return haves
+}
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateTransfer(t *testing.T) {
+	src, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	commit := func(files map[string]string) plumbing.Hash {
+		t.Helper()
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+		hash, err := w.Commit("change", &gogit.CommitOptions{Author: sig})
+		require.NoError(t, err)
+		return hash
+	}
+	first := commit(map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n"})
+	second := commit(map[string]string{"a.txt": "a2\n"})
+	tag, err := src.CreateTag("v1", second, &gogit.CreateTagOptions{Tagger: sig, Message: "v1"})
+	require.NoError(t, err)
+
+	dst, err := gogit.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// A canceled command copies nothing
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = NegotiateTransfer(canceled, src, dst, []plumbing.Hash{first}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Everything is missing: two blobs, two trees and the commit
+	transfer, err := NegotiateTransfer(ctx, src, dst, []plumbing.Hash{first}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, transfer.Objects)
+	assert.Equal(t, int64(4), transfer.BlobBytes)
+	assert.ErrorIs(t, transfer.Apply(canceled), context.Canceled)
+	assert.False(t, HasObject(dst, first))
+	require.NoError(t, transfer.Apply(ctx))
+
+	// Only what changed since the have: the new blob, root tree and commit.
+	// A have dst lacks is ignored.
+	stale := plumbing.NewHash("1111111111111111111111111111111111111111")
+	transfer, err = NegotiateTransfer(ctx, src, dst, []plumbing.Hash{tag.Hash()}, []plumbing.Hash{first, stale})
+	require.NoError(t, err)
+	assert.Equal(t, 4, transfer.Objects, "the tag is sent with its commit")
+	require.NoError(t, transfer.Apply(ctx))
+	for _, hash := range []plumbing.Hash{second, tag.Hash()} {
+		assert.True(t, HasObject(dst, hash))
+	}
+
+	transfer, err = NegotiateTransfer(ctx, src, dst, []plumbing.Hash{tag.Hash()}, nil)
+	require.NoError(t, err)
+	assert.Zero(t, transfer.Objects)
+
+	_, err = NegotiateTransfer(ctx, src, dst, []plumbing.Hash{stale}, nil)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+}
+
+func TestTransferObjects_LargeTransfersArePacked(t *testing.T) {
+	src, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	for i := 0; i < transferUnpackLimit; i++ {
+		name := fmt.Sprintf("file-%03d.txt", i)
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(name), 0644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := w.Commit("many files", &gogit.CommitOptions{Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}})
+	require.NoError(t, err)
+
+	st := filesystem.NewStorage(memfs.New(), cache.NewObjectLRUDefault())
+	dst, err := gogit.Init(st, nil)
+	require.NoError(t, err)
+	require.NoError(t, TransferObjects(context.Background(), src, dst, []plumbing.Hash{hash}, nil))
+
+	packs, err := st.ObjectPacks()
+	require.NoError(t, err)
+	assert.Len(t, packs, 1)
+	commit, err := dst.CommitObject(hash)
+	require.NoError(t, err)
+	files, err := commit.Files()
+	require.NoError(t, err)
+	count := 0
+	require.NoError(t, files.ForEach(func(*object.File) error { count++; return nil }))
+	assert.Equal(t, transferUnpackLimit, count)
+}
@@ -141,7 +141,9 @@ func applyPatchToWorktree(w *gogit.Worktree, commit *object.Commit, patch *objec
 }
 
 // ResolveRevision resolves a revision string (branch, tag, commit hash, short hash)
-// to a full commit hash. Supports abbreviated commit hashes (>= 4 characters).
+// to a full commit hash. Supports abbreviated commit hashes (>= 4 characters),
+// <branch>@{u} for the upstream branch and :/<text> to search commit messages.
+// Ranges (A..B, A...B) name several commits; see ParseRevisionRange.
 //
 // Parameters:
 //   - repo: The repository to resolve against
@@ -150,6 +152,18 @@ func applyPatchToWorktree(w *gogit.Worktree, commit *object.Commit, patch *objec
 // Returns the resolved hash or an error if not found.
 func ResolveRevision(repo *gogit.Repository, rev string) (*plumbing.Hash, error) {
 	rev = strings.TrimSpace(rev)
+	if IsRevisionRange(rev) {
+		return nil, fmt.Errorf("revision range '%s' does not name a single commit", rev)
+	}
+	if text, ok := strings.CutPrefix(rev, ":/"); ok && text != "" {
+		return resolveMessageSearch(repo, text)
+	}
+	if expanded, ok, err := expandUpstream(repo, rev); ok {
+		if err != nil {
+			return nil, err
+		}
+		rev = expanded
+	}
 	// 1. Try standard resolution (branch, tag, full hash)
 	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
 	if err == nil {
@@ -205,8 +219,17 @@ var ErrConflict = fmt.Errorf("merge conflict")
 // - Base != Ours && Base != Theirs && Ours == Theirs -> Keep Ours (Both made same change)
 // - Base != Ours && Base != Theirs && Ours != Theirs -> CONFLICT
 //
-// In case of conflict, it writes conflict markers to the file and returns ErrConflict.
-func Merge3Way(w *gogit.Worktree, base, ours, theirs *object.Commit) error {
+// In case of conflict, it writes conflict markers to the file and returns ErrConflict,
+// unless the file's .gitattributes merge driver resolves it: merge=ours keeps
+// Ours, merge=union keeps the lines of both sides. theirsLabel names Theirs on
+// the ">>>>>>>" marker, as the branch being merged does; when empty the
+// abbreviated hash of Theirs is used.
+func Merge3Way(w *gogit.Worktree, base, ours, theirs *object.Commit, theirsLabel string) error {
+	if theirsLabel == "" && theirs != nil {
+		theirsLabel = theirs.Hash.String()[:7]
+	}
+	attrs := LoadAttributes(w.Filesystem)
+
 	// 1. Collect all file paths from all 3 trees
 	paths := make(map[string]struct{})
 
@@ -255,7 +278,7 @@ func Merge3Way(w *gogit.Worktree, base, ours, theirs *object.Commit) error {
 			return f.Hash, content, nil
 		}
 
-		baseH, _, err := getHashAndContent(base)
+		baseH, baseContent, err := getHashAndContent(base)
 		if err != nil {
 			return err
 		}
@@ -299,9 +322,19 @@ func Merge3Way(w *gogit.Worktree, base, ours, theirs *object.Commit) error {
 				// Theirs didn't change. Keep Ours. (No-op)
 			} else {
 				// Both changed from Base, and Ours != Theirs.
+				switch driver := attrs.Get(path, "merge"); {
+				case driver == "ours":
+					continue
+				case driver == "union" && oursH != plumbing.ZeroHash && theirsH != plumbing.ZeroHash:
+					if err := writeFile(w, path, unionMerge(baseContent, oursContent, theirsContent)); err != nil {
+						return err
+					}
+					_, _ = w.Add(path)
+					continue
+				}
 				// CONFLICT.
 				hasConflict = true
-				conflictContent := fmt.Sprintf("<<<<<<< HEAD\n%s=======\n%s>>>>>>> %s\n", oursContent, theirsContent, theirs.Hash.String()[:7])
+				conflictContent := fmt.Sprintf("<<<<<<< HEAD\n%s=======\n%s>>>>>>> %s\n", oursContent, theirsContent, theirsLabel)
 				if err := writeFile(w, path, conflictContent); err != nil {
 					return err
 				}
@@ -0,0 +1,139 @@
+package git
+
+// policy.go - Enforcing the command policy of a session
+//
+// Dispatch refuses commands the session's CommandPolicy does not allow,
+// before they run, with a message that says what is off limits and why,
+// rather than an error that looks like git failing. Commands the policy lists
+// in SaveBefore run, and the state before them is kept as a save point.
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+)
+
+// policyError is returned by Dispatch for a command the session's policy blocks.
+type policyError struct {
+	message string
+}
+
+func (e *policyError) Error() string {
+	return e.message
+}
+
+// policyRule is a rule of a CommandPolicy, resolved to a registered command.
+type policyRule struct {
+	command string // Registered name, e.g. "push" or "git-rm"
+	flag    string // Flag the rule is limited to; empty matches any use
+}
+
+// parsePolicyRule resolves a rule such as "git push --force" or "status".
+func parsePolicyRule(rule string) policyRule {
+	words := strings.Fields(rule)
+	name, args := ResolveCommand(words)
+	r := policyRule{command: name}
+	if len(args) > 1 {
+		r.flag = args[1]
+	}
+	return r
+}
+
+// PolicyRuleCommand returns the registered command a policy rule names, so
+// rules can be checked when a policy is written.
+func PolicyRuleCommand(rule string) string {
+	return parsePolicyRule(rule).command
+}
+
+// matches reports whether the rule covers running cmdName with args.
+func (r policyRule) matches(cmdName string, args []string) bool {
+	if r.command != cmdName {
+		return false
+	}
+	if r.flag == "" {
+		return true
+	}
+	// Every spelling of the flag counts: -f as well as --force, -fu, --force=...
+	spellings := []string{r.flag}
+	spec, _ := CommandSpecOf(cmdName)
+	if opt := spec.option(r.flag); opt != nil {
+		spellings = opt.Flags
+		if parsed, err := spec.Parse(args[1:]); err == nil {
+			return parsed.Has(r.flag)
+		}
+	}
+	for _, arg := range args[1:] {
+		if arg == "--" {
+			break
+		}
+		for _, flag := range spellings {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkPolicy returns the error a command fails with when the session's
+// command policy blocks it, or nil. Shell commands and help are never kept
+// out by Allow, since a lesson is not playable without them.
+func checkPolicy(ctx context.Context, session *Session, cmdName string, args []string) error {
+	session.RLock()
+	policy := session.CommandPolicy
+	session.RUnlock()
+	if policy.IsEmpty() {
+		return nil
+	}
+	lang := i18n.Lang(ctx)
+	spec, _ := CommandSpecOf(cmdName)
+	display := cmdName
+	if !spec.Shell {
+		display = "git " + strings.TrimPrefix(cmdName, "git-")
+	}
+	refuse := func(message string) error {
+		if policy.Reason != "" {
+			message += "\nhint: " + policy.Reason
+		}
+		if len(policy.Allow) > 0 {
+			message += "\nhint: " + i18n.T(lang, "policy.allowed", strings.Join(policy.Allow, ", "))
+		}
+		return &policyError{message: message}
+	}
+
+	for _, rule := range policy.Forbid {
+		r := parsePolicyRule(rule)
+		if !r.matches(cmdName, args) {
+			continue
+		}
+		if r.flag != "" {
+			return refuse(i18n.T(lang, "policy.blocked_flag", r.flag, display))
+		}
+		return refuse(i18n.T(lang, "policy.blocked_command", display))
+	}
+
+	if len(policy.Allow) == 0 || spec.Shell || cmdName == "help" {
+		return nil
+	}
+	for _, rule := range policy.Allow {
+		if parsePolicyRule(rule).matches(cmdName, args) {
+			return nil
+		}
+	}
+	return refuse(i18n.T(lang, "policy.blocked_command", display))
+}
+
+// savesBefore reports whether the policy takes a save point before cmdName
+// runs with args.
+func savesBefore(policy *CommandPolicy, cmdName string, args []string) bool {
+	if policy == nil {
+		return false
+	}
+	for _, rule := range policy.SaveBefore {
+		if parsePolicyRule(rule).matches(cmdName, args) {
+			return true
+		}
+	}
+	return false
+}
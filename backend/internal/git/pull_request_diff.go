@@ -0,0 +1,156 @@
+package git
+
+// pull_request_diff.go - The commits and changes of a simulated pull request
+//
+// A pull request's tabs are computed from the branches of its shared remote,
+// base...head, like on GitHub: the commits the head branch adds, the files it
+// changed since it left the base branch, and whether merge-pr could merge it
+// now. The merge happens on the server, file by file, so a file both branches
+// changed differently makes the pull request unmergeable.
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// PullRequestComparison is base...head of a pull request.
+type PullRequestComparison struct {
+	ID        int      `json:"id"`
+	Base      string   `json:"base"` // Base branch
+	Head      string   `json:"head"` // Head branch
+	BaseID    string   `json:"baseId"`
+	HeadID    string   `json:"headId"`
+	MergeBase string   `json:"mergeBase,omitempty"` // Empty for unrelated histories
+	Ahead     int      `json:"ahead"`
+	Behind    int      `json:"behind"`
+	Mergeable bool     `json:"mergeable"`           // Open, ahead of its base, related to it and free of conflicts
+	Conflicts []string `json:"conflicts,omitempty"` // Files both branches changed differently since the merge base
+}
+
+// PullRequestCommits backs the Commits tab of a pull request.
+type PullRequestCommits struct {
+	PullRequestComparison
+	Commits []CommitSummary `json:"commits"` // Oldest first, at most maxCompareCommits (the newest)
+}
+
+// PullRequestDiff backs the "Files changed" tab of a pull request.
+type PullRequestDiff struct {
+	PullRequestComparison
+	Files     []FileDiff `json:"files"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+}
+
+// ListPullRequestCommits returns the commits pr adds to its base branch.
+// repo is the pull request's shared remote.
+func ListPullRequestCommits(repo *gogit.Repository, pr *PullRequest) (*PullRequestCommits, error) {
+	summary, cmp, _, err := comparePullRequest(repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	commits := slices.Clone(cmp.Commits)
+	slices.Reverse(commits)
+	return &PullRequestCommits{PullRequestComparison: *summary, Commits: commits}, nil
+}
+
+// DiffPullRequest returns the files pr changed since it left its base branch.
+// repo is the pull request's shared remote.
+func DiffPullRequest(repo *gogit.Repository, pr *PullRequest) (*PullRequestDiff, error) {
+	summary, cmp, patch, err := comparePullRequest(repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	d := &PullRequestDiff{PullRequestComparison: *summary, Files: []FileDiff{}, Additions: cmp.Additions, Deletions: cmp.Deletions}
+	for _, fp := range patch.FilePatches() {
+		d.Files = append(d.Files, newFileDiff(fp))
+	}
+	return d, nil
+}
+
+func comparePullRequest(repo *gogit.Repository, pr *PullRequest) (*PullRequestComparison, *Comparison, diff.Patch, error) {
+	base := plumbing.NewBranchReferenceName(pr.BaseRef).String()
+	head := plumbing.NewBranchReferenceName(pr.HeadRef).String()
+	if _, err := repo.Reference(plumbing.ReferenceName(base), true); err != nil {
+		return nil, nil, nil, fmt.Errorf("base branch %q not found in remote", pr.BaseRef)
+	}
+	if _, err := repo.Reference(plumbing.ReferenceName(head), true); err != nil {
+		return nil, nil, nil, fmt.Errorf("source branch %q not found in remote", pr.HeadRef)
+	}
+	cmp, patch, err := compareRevisions(repo, base, head)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	summary := &PullRequestComparison{
+		ID:        pr.ID,
+		Base:      pr.BaseRef,
+		Head:      pr.HeadRef,
+		BaseID:    cmp.BaseID,
+		HeadID:    cmp.HeadID,
+		MergeBase: cmp.MergeBase,
+		Ahead:     cmp.Ahead,
+		Behind:    cmp.Behind,
+	}
+	if cmp.MergeBase != "" {
+		commits := make([]*object.Commit, 3)
+		for i, id := range []string{cmp.MergeBase, cmp.BaseID, cmp.HeadID} {
+			if commits[i], err = repo.CommitObject(plumbing.NewHash(id)); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if summary.Conflicts, err = ConflictingPaths(commits[0], commits[1], commits[2]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	summary.Mergeable = pr.State == PRStateOpen && cmp.Ahead > 0 && cmp.MergeBase != "" && len(summary.Conflicts) == 0
+	return summary, cmp, patch, nil
+}
+
+// ConflictingPaths lists, sorted, the files that ours and theirs both changed
+// since base, each in its own way. These fail a merge that cannot stop for
+// conflict resolution, like the one merge-pr does on the server.
+func ConflictingPaths(base, ours, theirs *object.Commit) ([]string, error) {
+	type file struct {
+		hash plumbing.Hash
+		mode filemode.FileMode
+	}
+	var sides [3]map[string]file
+	for i, commit := range []*object.Commit{base, ours, theirs} {
+		sides[i] = make(map[string]file)
+		iter, err := commit.Files()
+		if err != nil {
+			return nil, err
+		}
+		err = iter.ForEach(func(f *object.File) error {
+			sides[i][f.Name] = file{hash: f.Hash, mode: f.Mode}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	b, o, t := sides[0], sides[1], sides[2]
+
+	var conflicts []string
+	check := func(name string) {
+		if t[name] != b[name] && o[name] != b[name] && o[name] != t[name] {
+			conflicts = append(conflicts, name)
+		}
+	}
+	for name := range b {
+		check(name)
+	}
+	for name := range t {
+		if _, ok := b[name]; !ok {
+			check(name)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
@@ -0,0 +1,164 @@
+package git
+
+// ref_transaction.go - Atomic multi-ref updates
+//
+// An operation that moves several refs (a rebase moving its branch and
+// reattaching HEAD, a mirror push updating every branch of the remote) must
+// not stop half way. A RefTransaction stages the updates, checks them all
+// (the refs expected at a value still are), and only then writes them; when
+// a write fails, the refs already written are put back, like git's ref
+// transactions ("git update-ref --stdin" with start/commit).
+
+import (
+	"errors"
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// RefTransaction stages ref updates of one repository to apply together.
+type RefTransaction struct {
+	repo      *gogit.Repository
+	updates   []refUpdate
+	committed bool
+	log       []RefUpdate
+}
+
+// refUpdate is one staged update.
+type refUpdate struct {
+	name   plumbing.ReferenceName
+	ref    *plumbing.Reference // New value; nil deletes the ref
+	old    plumbing.Hash       // Value the ref must have, when verify is set; zero means it must not exist
+	verify bool
+}
+
+// NewRefTransaction starts a transaction on repo.
+func NewRefTransaction(repo *gogit.Repository) *RefTransaction {
+	return &RefTransaction{repo: repo}
+}
+
+// Set stages pointing ref's name at ref's target, whatever its current value.
+func (tx *RefTransaction) Set(ref *plumbing.Reference) *RefTransaction {
+	tx.updates = append(tx.updates, refUpdate{name: ref.Name(), ref: ref})
+	return tx
+}
+
+// Update stages pointing ref's name at ref's target, provided the ref is at
+// old when the transaction commits. A zero old means the ref must not exist.
+func (tx *RefTransaction) Update(ref *plumbing.Reference, old plumbing.Hash) *RefTransaction {
+	tx.updates = append(tx.updates, refUpdate{name: ref.Name(), ref: ref, old: old, verify: true})
+	return tx
+}
+
+// Delete stages removing name, provided it is at old when the transaction
+// commits. A zero old deletes the ref whatever its value.
+func (tx *RefTransaction) Delete(name plumbing.ReferenceName, old plumbing.Hash) *RefTransaction {
+	tx.updates = append(tx.updates, refUpdate{name: name, old: old, verify: !old.IsZero()})
+	return tx
+}
+
+// Len returns the number of staged updates.
+func (tx *RefTransaction) Len() int {
+	return len(tx.updates)
+}
+
+// Commit applies the staged updates. Nothing is written unless every
+// expected value holds; a write that fails rolls the earlier ones back.
+func (tx *RefTransaction) Commit() error {
+	if tx.committed {
+		return fmt.Errorf("ref transaction already committed")
+	}
+	tx.committed = true
+
+	// Prepare: remember each ref's value for the log and a rollback
+	previous := make([]*plumbing.Reference, len(tx.updates))
+	seen := make(map[plumbing.ReferenceName]bool)
+	for i, u := range tx.updates {
+		if seen[u.name] {
+			return fmt.Errorf("fatal: multiple updates for ref '%s' not allowed", u.name)
+		}
+		seen[u.name] = true
+		current, err := tx.repo.Storer.Reference(u.name)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return err
+		}
+		previous[i] = current
+		if !u.verify {
+			continue
+		}
+		at := tx.resolve(current)
+		if at != u.old {
+			if u.old.IsZero() {
+				return fmt.Errorf("cannot lock ref '%s': reference already exists", u.name)
+			}
+			if current == nil {
+				return fmt.Errorf("cannot lock ref '%s': unable to resolve reference '%s'", u.name, u.name)
+			}
+			return fmt.Errorf("cannot lock ref '%s': is at %s but expected %s", u.name, at, u.old)
+		}
+	}
+
+	for i, u := range tx.updates {
+		var err error
+		if u.ref != nil {
+			err = tx.repo.Storer.SetReference(u.ref)
+		} else if previous[i] != nil {
+			err = tx.repo.Storer.RemoveReference(u.name)
+		}
+		if err != nil {
+			return errors.Join(fmt.Errorf("cannot update ref '%s': %w", u.name, err), tx.rollback(previous[:i], tx.updates[:i]))
+		}
+	}
+
+	for i, u := range tx.updates {
+		entry := RefUpdate{Name: u.name.String()}
+		if old := tx.resolve(previous[i]); !old.IsZero() {
+			entry.Old = old.String()
+		}
+		if u.ref != nil {
+			entry.New = tx.resolve(u.ref).String()
+		}
+		tx.log = append(tx.log, entry)
+	}
+	return nil
+}
+
+// rollback restores the refs written before a failed write, last first.
+func (tx *RefTransaction) rollback(previous []*plumbing.Reference, updates []refUpdate) error {
+	var errs []error
+	for i := len(updates) - 1; i >= 0; i-- {
+		var err error
+		if previous[i] != nil {
+			err = tx.repo.Storer.SetReference(previous[i])
+		} else if updates[i].ref != nil {
+			err = tx.repo.Storer.RemoveReference(updates[i].name)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rollback of '%s': %w", updates[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the commit ref points at, following a symbolic ref; zero
+// for a missing ref or an unborn branch.
+func (tx *RefTransaction) resolve(ref *plumbing.Reference) plumbing.Hash {
+	if ref == nil {
+		return plumbing.ZeroHash
+	}
+	if ref.Type() == plumbing.HashReference {
+		return ref.Hash()
+	}
+	resolved, err := tx.repo.Reference(ref.Target(), true)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return resolved.Hash()
+}
+
+// Log returns the updates the committed transaction made, in order. A
+// symbolic ref is logged with the commit it resolves to.
+func (tx *RefTransaction) Log() []RefUpdate {
+	return tx.log
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactionRepo(t *testing.T) (*gogit.Repository, plumbing.Hash, plumbing.Hash) {
+	t.Helper()
+	repo, err := gogit.InitWithOptions(memory.NewStorage(), memfs.New(), gogit.InitOptions{DefaultBranch: plumbing.Main})
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	first, err := w.Commit("first", &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	require.NoError(t, err)
+	second, err := w.Commit("second", &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	require.NoError(t, err)
+	return repo, first, second
+}
+
+func refHash(t *testing.T, repo *gogit.Repository, name plumbing.ReferenceName) plumbing.Hash {
+	t.Helper()
+	ref, err := repo.Reference(name, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash
+	}
+	require.NoError(t, err)
+	return ref.Hash()
+}
+
+func TestRefTransaction(t *testing.T) {
+	repo, first, second := newTransactionRepo(t)
+	main := plumbing.NewBranchReferenceName("main")
+	topic := plumbing.NewBranchReferenceName("topic")
+
+	tx := NewRefTransaction(repo).
+		Update(plumbing.NewHashReference(main, first), second).
+		Update(plumbing.NewHashReference(topic, second), plumbing.ZeroHash).
+		Set(plumbing.NewSymbolicReference(plumbing.HEAD, topic))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, first, refHash(t, repo, main))
+	assert.Equal(t, second, refHash(t, repo, plumbing.HEAD))
+	assert.Equal(t, []RefUpdate{
+		{Name: "refs/heads/main", Old: second.String(), New: first.String()},
+		{Name: "refs/heads/topic", New: second.String()},
+		{Name: "HEAD", Old: first.String(), New: second.String()},
+	}, tx.Log())
+	assert.Error(t, tx.Commit(), "a transaction commits once")
+
+	// One stale expectation and nothing is written
+	err := NewRefTransaction(repo).
+		Delete(topic, plumbing.ZeroHash).
+		Update(plumbing.NewHashReference(main, second), second).
+		Commit()
+	assert.ErrorContains(t, err, "is at "+first.String()+" but expected "+second.String())
+	assert.Equal(t, second, refHash(t, repo, topic))
+
+	err = NewRefTransaction(repo).Update(plumbing.NewHashReference(topic, first), plumbing.ZeroHash).Commit()
+	assert.ErrorContains(t, err, "reference already exists")
+
+	err = NewRefTransaction(repo).
+		Set(plumbing.NewHashReference(main, second)).
+		Delete(main, plumbing.ZeroHash).
+		Commit()
+	assert.ErrorContains(t, err, "multiple updates")
+	assert.Equal(t, first, refHash(t, repo, main))
+}
+
+// failingStorer fails writes of one ref.
+type failingStorer struct {
+	storage.Storer
+	fail plumbing.ReferenceName
+}
+
+func (s *failingStorer) SetReference(ref *plumbing.Reference) error {
+	if ref.Name() == s.fail {
+		return errors.New("disk full")
+	}
+	return s.Storer.SetReference(ref)
+}
+
+func TestRefTransaction_RollsBackOnWriteFailure(t *testing.T) {
+	repo, first, second := newTransactionRepo(t)
+	main := plumbing.NewBranchReferenceName("main")
+	topic := plumbing.NewBranchReferenceName("topic")
+	broken := plumbing.NewBranchReferenceName("broken")
+	repo.Storer = &failingStorer{Storer: repo.Storer, fail: broken}
+
+	err := NewRefTransaction(repo).
+		Update(plumbing.NewHashReference(main, first), second).
+		Set(plumbing.NewHashReference(topic, first)).
+		Set(plumbing.NewHashReference(broken, first)).
+		Commit()
+	assert.ErrorContains(t, err, "cannot update ref 'refs/heads/broken': disk full")
+	assert.Equal(t, second, refHash(t, repo, main))
+	assert.True(t, refHash(t, repo, topic).IsZero(), "a created ref is removed again")
+}
@@ -0,0 +1,180 @@
+package git
+
+// rerere.go - Reuse recorded resolution
+//
+// With rerere.enabled, commands that stop on conflicts record each conflict
+// (the "preimage") and, when the conflict is committed resolved, how it was
+// resolved (the "postimage"). When the same conflict shows up again, in any
+// merge, cherry-pick, revert or am, the recorded resolution is written to the
+// worktree right away. The conflict ID hashes the two sides of each conflict
+// hunk, sorted, so the direction of the merge and the marker labels do not
+// matter.
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// RerereCache is a repository's record of conflicts and their resolutions.
+type RerereCache = state.RerereCache
+
+// RerereResolution is one recorded conflict.
+type RerereResolution = state.RerereResolution
+
+// RerereEnabled reports whether rerere.enabled is set for repo.
+func RerereEnabled(repo *gogit.Repository) bool {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Raw.Section("rerere").Option("enabled"), "true")
+}
+
+// rerereAutoUpdate reports whether rerere.autoupdate is set, staging reused resolutions.
+func rerereAutoUpdate(repo *gogit.Repository) bool {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Raw.Section("rerere").Option("autoupdate"), "true")
+}
+
+// Rerere handles the conflicts a command just stopped on: a conflict seen
+// and resolved before gets its recorded resolution, a new one has its
+// preimage recorded. It returns the lines git prints for them.
+func Rerere(s *Session, repo *gogit.Repository, w *gogit.Worktree, conflicts []string) []string {
+	if !RerereEnabled(repo) {
+		return nil
+	}
+	rr := s.RerereCache()
+	clear(rr.Pending)
+
+	var out []string
+	for _, path := range conflicts {
+		content, err := util.ReadFile(w.Filesystem, path)
+		if err != nil {
+			continue
+		}
+		id, preimage, ok := RerereConflictID(string(content))
+		if !ok {
+			continue
+		}
+		rr.Pending[path] = id
+
+		res := rr.Resolutions[id]
+		if res == nil || !res.Resolved {
+			rr.Resolutions[id] = &RerereResolution{Path: path, Preimage: preimage, Conflict: string(content), RecordedAt: time.Now()}
+			out = append(out, fmt.Sprintf("Recorded preimage for '%s'", path))
+			continue
+		}
+		if res.Preimage != preimage {
+			// Same hunks in a different file: the recorded resolution covers the whole file
+			continue
+		}
+		if err := util.WriteFile(w.Filesystem, path, []byte(res.Postimage), 0644); err != nil {
+			continue
+		}
+		res.Path, res.Conflict = path, string(content)
+		if rerereAutoUpdate(repo) {
+			_, _ = w.Add(path)
+			out = append(out, fmt.Sprintf("Staged '%s' using previous resolution.", path))
+		} else {
+			out = append(out, fmt.Sprintf("Resolved '%s' using previous resolution.", path))
+		}
+	}
+	return out
+}
+
+// RerereRecordResolutions records the postimage of each tracked conflict
+// whose file no longer has conflict markers, as git does when the stopped
+// operation is committed, and stops tracking it. Conflicts that still have
+// markers stay tracked.
+func RerereRecordResolutions(s *Session, repo *gogit.Repository, w *gogit.Worktree) []string {
+	if !RerereEnabled(repo) {
+		return nil
+	}
+	rr := s.RerereCache()
+	var out []string
+	for _, path := range slices.Sorted(maps.Keys(rr.Pending)) {
+		res := rr.Resolutions[rr.Pending[path]]
+		if res != nil && !res.Resolved {
+			content, err := util.ReadFile(w.Filesystem, path)
+			if err == nil && hasConflictMarkers(string(content)) {
+				continue
+			}
+			if err == nil {
+				res.Postimage, res.Resolved, res.RecordedAt = string(content), true, time.Now()
+				out = append(out, fmt.Sprintf("Recorded resolution for '%s'.", path))
+			}
+		}
+		delete(rr.Pending, path)
+	}
+	return out
+}
+
+// RerereClear stops tracking the conflicts of an aborted operation, dropping
+// the preimages that never got a resolution.
+func RerereClear(s *Session) {
+	if s.Rerere == nil {
+		return
+	}
+	rr := s.RerereCache()
+	for _, id := range rr.Pending {
+		if res := rr.Resolutions[id]; res != nil && !res.Resolved {
+			delete(rr.Resolutions, id)
+		}
+	}
+	clear(rr.Pending)
+}
+
+// RerereConflictID parses the conflict hunks of content. It returns the
+// conflict ID and the normalized preimage: marker labels dropped and the
+// sides of each hunk sorted. ok is false when content has no conflicts.
+func RerereConflictID(content string) (id, preimage string, ok bool) {
+	h := sha1.New()
+	var pre strings.Builder
+	var sides [2]strings.Builder
+	side := -1 // -1 outside a hunk, 0 ours, 1 theirs
+	for _, line := range splitLines(content) {
+		switch {
+		case side == -1 && strings.HasPrefix(line, "<<<<<<<"):
+			side = 0
+			sides[0].Reset()
+			sides[1].Reset()
+		case side == 0 && strings.HasPrefix(line, "======="):
+			side = 1
+		case side == 1 && strings.HasPrefix(line, ">>>>>>>"):
+			a, b := sides[0].String(), sides[1].String()
+			if a > b {
+				a, b = b, a
+			}
+			h.Write([]byte(a + "\x00" + b + "\x00"))
+			pre.WriteString("<<<<<<<\n" + a + "=======\n" + b + ">>>>>>>\n")
+			side = -1
+			ok = true
+		case side >= 0:
+			sides[side].WriteString(line)
+		default:
+			pre.WriteString(line)
+		}
+	}
+	if !ok || side != -1 {
+		return "", "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), pre.String(), true
+}
+
+// hasConflictMarkers reports whether content still has a conflict hunk.
+func hasConflictMarkers(content string) bool {
+	_, _, ok := RerereConflictID(content)
+	return ok
+}
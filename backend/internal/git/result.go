@@ -0,0 +1,191 @@
+package git
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+)
+
+// Exit codes of dispatched commands, following git and the shell.
+const (
+	ExitOK             = 0
+	ExitError          = 1   // The command failed
+	ExitUnknownCommand = 127 // No such command
+	ExitFatal          = 128 // git's "fatal:" errors
+)
+
+// CommandResult is the outcome of a dispatched command. Stdout is the text the
+// terminal panel prints; Payload tells clients what the command changed, so
+// they do not have to parse that text.
+type CommandResult struct {
+	Command    string          `json:"command"`
+	Stdout     string          `json:"stdout"`
+	Stderr     string          `json:"stderr,omitempty"`
+	ExitCode   int             `json:"exitCode"`
+	Payload    *CommandPayload `json:"payload,omitempty"`
+	Editor     *PendingEditor  `json:"editor,omitempty"`     // Set when the command waits for a message from the client's editor
+	Suggestion *Suggestion     `json:"suggestion,omitempty"` // How to fix a failed command, when a likely fix is known
+}
+
+// CommandPayload is the machine-readable effect of a command, found by
+// comparing the current repository before and after it ran.
+type CommandPayload struct {
+	Head           string      `json:"head,omitempty"`           // Commit HEAD points to after the command
+	PreviousHead   string      `json:"previousHead,omitempty"`   // Commit HEAD pointed to before, when it moved
+	Branch         string      `json:"branch,omitempty"`         // Checked-out branch after the command; empty when detached
+	PreviousBranch string      `json:"previousBranch,omitempty"` // Checked-out branch before, when it changed
+	Switched       bool        `json:"switched,omitempty"`       // HEAD now names another branch, or was detached
+	CreatedCommit  string      `json:"createdCommit,omitempty"`  // Commit the command made and moved HEAD to
+	RefUpdates     []RefUpdate `json:"refUpdates,omitempty"`     // Refs created, moved or deleted, sorted by name
+	Directory      string      `json:"directory,omitempty"`      // Working directory, when the command changed it
+	DryRun         bool        `json:"dryRun,omitempty"`         // Nothing was changed (--dry-run)
+}
+
+// RefUpdate is one ref changed by a command.
+type RefUpdate struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"` // Empty for created refs
+	New  string `json:"new,omitempty"` // Empty for deleted refs
+}
+
+// Output returns the text a terminal shows for the result: stdout, or the
+// error message when the command failed.
+func (r *CommandResult) Output() string {
+	if r.ExitCode != ExitOK && r.Stderr != "" {
+		return r.Stderr
+	}
+	return r.Stdout
+}
+
+// newCommandResult wraps the output and error of a command.
+func newCommandResult(args []string, cmdName, out string, err error) *CommandResult {
+	command := strings.Join(args, " ")
+	if command == "" {
+		command = cmdName
+	}
+	result := &CommandResult{Command: command, Stdout: out, ExitCode: ExitOK}
+	if err != nil {
+		result.Stderr = err.Error()
+		result.ExitCode = exitCode(err)
+	}
+	return result
+}
+
+func exitCode(err error) int {
+	var unknown *unknownCommandError
+	switch {
+	case errors.As(err, &unknown):
+		return ExitUnknownCommand
+	case strings.HasPrefix(err.Error(), "fatal:"):
+		return ExitFatal
+	default:
+		return ExitError
+	}
+}
+
+// unknownCommandError is returned by Dispatch for commands not in the registry.
+type unknownCommandError struct {
+	name string
+	lang string // Language of the message
+}
+
+func (e *unknownCommandError) Error() string {
+	return i18n.T(e.lang, "error.unknown_command", e.name)
+}
+
+// repoSnapshot is the state of the current repository a payload is derived from.
+type repoSnapshot struct {
+	dir    string
+	repo   *gogit.Repository
+	head   string // Commit HEAD points to
+	branch string // Checked-out branch; empty when detached
+	refs   map[string]string
+}
+
+// takeSnapshot records HEAD and the refs of the session's current repository.
+// The caller must hold the session lock.
+func takeSnapshot(session *Session) repoSnapshot {
+	snap := repoSnapshot{dir: session.CurrentDir, repo: session.GetRepo()}
+	if snap.repo == nil {
+		return snap
+	}
+	snap.refs = make(map[string]string)
+	for name, ref := range collectRefs(snap.repo) {
+		if ref.Type() == plumbing.HashReference && name != plumbing.HEAD.String() {
+			snap.refs[name] = ref.Hash().String()
+		}
+	}
+	if head, err := snap.repo.Storer.Reference(plumbing.HEAD); err == nil {
+		if head.Type() == plumbing.SymbolicReference {
+			snap.branch = head.Target().Short()
+			snap.head = snap.refs[head.Target().String()]
+		} else {
+			snap.head = head.Hash().String()
+		}
+	}
+	return snap
+}
+
+// payload describes what changed between before and after. It is nil when
+// there is nothing to describe (no repository and no directory change).
+func (before repoSnapshot) payload(after repoSnapshot, start time.Time) *CommandPayload {
+	if after.repo == nil && before.dir == after.dir {
+		return nil
+	}
+	p := &CommandPayload{Head: after.head, Branch: after.branch}
+	if before.dir != after.dir {
+		p.Directory = after.dir
+	}
+	if after.repo == nil || before.repo != after.repo {
+		// Another repository (cd, init, clone): nothing to compare with
+		return p
+	}
+
+	if before.head != after.head {
+		p.PreviousHead = before.head
+	}
+	if before.branch != after.branch {
+		p.PreviousBranch = before.branch
+		p.Switched = true
+	}
+
+	names := make(map[string]struct{})
+	for name := range before.refs {
+		names[name] = struct{}{}
+	}
+	for name := range after.refs {
+		names[name] = struct{}{}
+	}
+	for name := range names {
+		if old, updated := before.refs[name], after.refs[name]; old != updated {
+			p.RefUpdates = append(p.RefUpdates, RefUpdate{Name: name, Old: old, New: updated})
+		}
+	}
+	sort.Slice(p.RefUpdates, func(i, j int) bool { return p.RefUpdates[i].Name < p.RefUpdates[j].Name })
+
+	if p.PreviousHead != "" && after.head != "" && isNewCommit(after, before, start) {
+		p.CreatedCommit = after.head
+	}
+	return p
+}
+
+// isNewCommit reports whether after's HEAD is a commit made while the command
+// ran: no ref pointed to it before, and it was committed after the command started.
+func isNewCommit(after, before repoSnapshot, start time.Time) bool {
+	for _, hash := range before.refs {
+		if hash == after.head {
+			return false
+		}
+	}
+	commit, err := after.repo.CommitObject(plumbing.NewHash(after.head))
+	if err != nil {
+		return false
+	}
+	// Commit times only have second precision
+	return !commit.Committer.When.Before(start.Truncate(time.Second))
+}
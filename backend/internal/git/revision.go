@@ -0,0 +1,335 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// RevisionRange is the set of commits named by "A..B" (reachable from B but
+// not from A) or "A...B" (reachable from either but not from both).
+type RevisionRange struct {
+	From, To  plumbing.Hash // An omitted side means HEAD
+	Symmetric bool          // A...B
+	Include   []plumbing.Hash
+	Exclude   []plumbing.Hash // A for A..B, the merge bases for A...B
+}
+
+// IsRevisionRange reports whether rev is written as A..B or A...B.
+func IsRevisionRange(rev string) bool {
+	from, to, ok := strings.Cut(rev, "..")
+	return ok && from+to != "" && from+to != "."
+}
+
+// ParseRevisionRange resolves an A..B or A...B range. ok is false when rev is
+// not a range, so callers can fall back to ResolveRevision.
+func ParseRevisionRange(repo *gogit.Repository, rev string) (r *RevisionRange, ok bool, err error) {
+	if !IsRevisionRange(rev) {
+		return nil, false, nil
+	}
+	from, to, _ := strings.Cut(rev, "..")
+	r = &RevisionRange{}
+	if strings.HasPrefix(to, ".") {
+		r.Symmetric = true
+		to = strings.TrimPrefix(to, ".")
+	}
+	for _, side := range []struct {
+		rev  string
+		hash *plumbing.Hash
+	}{{from, &r.From}, {to, &r.To}} {
+		if side.rev == "" {
+			side.rev = "HEAD"
+		}
+		hash, err := ResolveRevision(repo, side.rev)
+		if err != nil {
+			return nil, true, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", rev)
+		}
+		*side.hash = *hash
+	}
+
+	if !r.Symmetric {
+		r.Include = []plumbing.Hash{r.To}
+		r.Exclude = []plumbing.Hash{r.From}
+		return r, true, nil
+	}
+	bases, err := MergeBases(repo, r.From, r.To)
+	if err != nil {
+		return nil, true, err
+	}
+	r.Include = []plumbing.Hash{r.From, r.To}
+	r.Exclude = bases
+	return r, true, nil
+}
+
+// MergeBase returns the best common ancestor of A...B, as diff A...B compares against.
+func (r *RevisionRange) MergeBase(repo *gogit.Repository) (plumbing.Hash, error) {
+	bases, err := MergeBases(repo, r.From, r.To)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if len(bases) == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("fatal: %s...%s: no merge base", r.From.String()[:7], r.To.String()[:7])
+	}
+	return bases[0], nil
+}
+
+// Commits returns the commits in the range, oldest first, the order
+// cherry-pick applies them in.
+func (r *RevisionRange) Commits(repo *gogit.Repository) ([]*object.Commit, error) {
+	excluded := ReachableCommits(repo, r.Exclude)
+	// Depth-first, parents before children, so history is replayed in order
+	seen := make(map[plumbing.Hash]bool)
+	var commits []*object.Commit
+	var visit func(hash plumbing.Hash)
+	visit = func(hash plumbing.Hash) {
+		if seen[hash] || excluded[hash] {
+			return
+		}
+		seen[hash] = true
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return // Shallow boundary
+		}
+		for _, p := range commit.ParentHashes {
+			visit(p)
+		}
+		commits = append(commits, commit)
+	}
+	for _, hash := range r.Include {
+		visit(hash)
+	}
+	return commits, nil
+}
+
+// MergeBases returns the best common ancestors of two commits.
+func MergeBases(repo *gogit.Repository, a, b plumbing.Hash) ([]plumbing.Hash, error) {
+	ca, err := repo.CommitObject(a)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := repo.CommitObject(b)
+	if err != nil {
+		return nil, err
+	}
+	bases, err := ca.MergeBase(cb)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]plumbing.Hash, len(bases))
+	for i, c := range bases {
+		hashes[i] = c.Hash
+	}
+	return hashes, nil
+}
+
+// ReachableCommits returns every commit reachable from starts, starts included.
+func ReachableCommits(repo *gogit.Repository, starts []plumbing.Hash) map[plumbing.Hash]bool {
+	reachable := make(map[plumbing.Hash]bool)
+	queue := append([]plumbing.Hash(nil), starts...)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if reachable[hash] {
+			continue
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			continue // Shallow boundary
+		}
+		reachable[hash] = true
+		queue = append(queue, commit.ParentHashes...)
+	}
+	return reachable
+}
+
+// upstreamPattern matches "<branch>@{upstream}" and its "@{u}" shorthand,
+// followed by any ~ or ^ suffix.
+var upstreamPattern = regexp.MustCompile(`^(.*?)@\{(?i:u|upstream)\}(.*)$`)
+
+// expandUpstream rewrites <branch>@{u} to the remote-tracking branch it
+// follows. ok is false when rev does not mention an upstream.
+func expandUpstream(repo *gogit.Repository, rev string) (expanded string, ok bool, err error) {
+	m := upstreamPattern.FindStringSubmatch(rev)
+	if m == nil {
+		return rev, false, nil
+	}
+	branch := m[1]
+	if branch == "" || branch == "HEAD" || branch == "@" {
+		head, err := repo.Reference(plumbing.HEAD, false)
+		if err != nil || head.Type() != plumbing.SymbolicReference {
+			return "", true, fmt.Errorf("fatal: HEAD does not point to a branch")
+		}
+		branch = head.Target().Short()
+	}
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+
+	// A configured upstream wins; otherwise assume the branch of the same name
+	// on origin, the convention pull follows
+	tracking, ok := UpstreamRef(repo, branch)
+	if !ok {
+		tracking = plumbing.NewRemoteReferenceName("origin", branch)
+	}
+	if _, err := repo.Reference(tracking, true); err != nil {
+		return "", true, fmt.Errorf("fatal: no upstream configured for branch '%s'", branch)
+	}
+	return tracking.String() + m[2], true, nil
+}
+
+// resolveMessageSearch resolves :/<text>, the youngest commit reachable from
+// any ref whose message matches the regular expression text.
+func resolveMessageSearch(repo *gogit.Repository, text string) (*plumbing.Hash, error) {
+	re, err := regexp.Compile(text)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: invalid regular expression '%s'", text)
+	}
+	var tips []plumbing.Hash
+	if head, err := repo.Head(); err == nil {
+		tips = append(tips, head.Hash())
+	}
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() != plumbing.HashReference || !(ref.Name().IsBranch() || ref.Name().IsRemote() || ref.Name().IsTag()) {
+				return nil
+			}
+			hash := ref.Hash()
+			if tag, err := repo.TagObject(hash); err == nil {
+				hash = tag.Target
+			}
+			tips = append(tips, hash)
+			return nil
+		})
+	}
+	var found *object.Commit
+	for hash := range ReachableCommits(repo, tips) {
+		commit, err := repo.CommitObject(hash)
+		if err != nil || !re.MatchString(commit.Message) {
+			continue
+		}
+		if found == nil || commit.Committer.When.After(found.Committer.When) ||
+			(commit.Committer.When.Equal(found.Committer.When) && commit.Hash.String() < found.Hash.String()) {
+			found = commit
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("fatal: no commit message matches '%s'", text)
+	}
+	return &found.Hash, nil
+}
+
+// ResolveObject resolves an object name of any type, as git cat-file takes
+// it: a full or abbreviated object hash, <rev>:<path> for an entry of a
+// commit's tree, :<path> for a staged blob, <rev>^{tree} and <rev>^{commit},
+// a tag name (the tag object itself when annotated) or any revision
+// ResolveRevision accepts.
+func ResolveObject(repo *gogit.Repository, name string) (plumbing.Hash, error) {
+	invalid := fmt.Errorf("fatal: Not a valid object name %s", name)
+
+	if rev, path, ok := strings.Cut(name, ":"); ok && !strings.HasPrefix(name, ":/") {
+		path = strings.Trim(path, "/")
+		if rev == "" {
+			idx, err := repo.Storer.Index()
+			if err != nil {
+				return plumbing.ZeroHash, invalid
+			}
+			entry, err := idx.Entry(path)
+			if err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("fatal: path '%s' is not in the index", path)
+			}
+			return entry.Hash, nil
+		}
+		tree, err := revisionTree(repo, rev)
+		if err != nil {
+			return plumbing.ZeroHash, invalid
+		}
+		if path == "" {
+			return tree.Hash, nil
+		}
+		entry, err := tree.FindEntry(path)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("fatal: path '%s' does not exist in '%s'", path, rev)
+		}
+		return entry.Hash, nil
+	}
+	if rev, ok := strings.CutSuffix(name, "^{tree}"); ok {
+		tree, err := revisionTree(repo, rev)
+		if err != nil {
+			return plumbing.ZeroHash, invalid
+		}
+		return tree.Hash, nil
+	}
+	if rev, ok := strings.CutSuffix(name, "^{commit}"); ok {
+		name = rev
+	} else if ref, err := repo.Reference(plumbing.NewTagReferenceName(name), true); err == nil {
+		return ref.Hash(), nil
+	}
+
+	if isHexPrefix(name) {
+		if hash, ok, err := objectByPrefix(repo, name); ok {
+			return hash, err
+		}
+	}
+	hash, err := ResolveRevision(repo, name)
+	if err != nil {
+		return plumbing.ZeroHash, invalid
+	}
+	return *hash, nil
+}
+
+// revisionTree returns the tree of the commit rev names.
+func revisionTree(repo *gogit.Repository, rev string) (*object.Tree, error) {
+	hash, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// isHexPrefix reports whether s could be an abbreviated or full object hash.
+func isHexPrefix(s string) bool {
+	if len(s) < 4 || len(s) > 40 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectByPrefix finds the object whose hash starts with prefix. ok is false
+// when no object matches.
+func objectByPrefix(repo *gogit.Repository, prefix string) (hash plumbing.Hash, ok bool, err error) {
+	if len(prefix) == 40 {
+		hash = plumbing.NewHash(prefix)
+		if _, err := repo.Storer.EncodedObject(plumbing.AnyObject, hash); err != nil {
+			return plumbing.ZeroHash, false, nil
+		}
+		return hash, true, nil
+	}
+	iter, err := repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return plumbing.ZeroHash, false, nil
+	}
+	_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		if !strings.HasPrefix(obj.Hash().String(), prefix) || obj.Hash() == hash {
+			return nil
+		}
+		if ok {
+			err = fmt.Errorf("error: short object ID %s is ambiguous", prefix)
+			return storer.ErrStop
+		}
+		hash, ok = obj.Hash(), true
+		return nil
+	})
+	return hash, ok, err
+}
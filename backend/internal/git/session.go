@@ -1,6 +1,10 @@
 package git
 
 import (
+	"io"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
@@ -12,9 +16,392 @@ type SessionManager = state.SessionManager
 type ReflogEntry = state.ReflogEntry
 type Commit = state.Commit
 type PullRequest = state.PullRequest
+type PullRequestReview = state.PullRequestReview
+type PullRequestComment = state.PullRequestComment
+type Issue = state.Issue
+type BranchPolicy = state.BranchPolicy
+type IngestPolicy = state.IngestPolicy
+type RemotePermission = state.RemotePermission
+type CommandPolicy = state.CommandPolicy
+type ConventionalCommit = state.ConventionalCommit
+type ArchiveOptions = state.ArchiveOptions
+type Bundle = state.Bundle
+type RefFilter = state.RefFilter
+type MaintenanceReport = state.MaintenanceReport
+type Store = state.Store
+type CherryPickState = state.CherryPickState
+type RebaseState = state.RebaseState
+type RebaseStep = state.RebaseStep
+type MergeState = state.MergeState
+type AmState = state.AmState
+type PendingEditor = state.PendingEditor
+type StateUpdate = state.StateUpdate
+type UndoSnapshot = state.UndoSnapshot
+type SavePoint = state.SavePoint
+type Trace = state.Trace
+type TraceEntry = state.TraceEntry
+type Timeline = state.Timeline
+type TimelineBase = state.TimelineBase
+type TimelineFrame = state.TimelineFrame
+type SigningKey = state.SigningKey
+type SignatureCheck = state.SignatureCheck
+type CheckRule = state.CheckRule
+type UserIdentity = state.UserIdentity
+type PushConflict = state.PushConflict
+type Presence = state.Presence
+type TeammateAction = state.TeammateAction
+type TeammateScenario = state.TeammateScenario
+type TeammateRun = state.TeammateRun
+type UpstreamEvent = state.UpstreamEvent
+type UpstreamBranchUpdate = state.UpstreamBranchUpdate
+type RemoteSyncStatus = state.RemoteSyncStatus
+type BareRepoOptions = state.BareRepoOptions
+type BranchRename = state.BranchRename
+type AnalyticsReport = state.AnalyticsReport
+type CheckStatus = state.CheckStatus
+type StorageUsage = state.StorageUsage
+type StorageQuota = state.StorageQuota
+type WorktreeStorer = state.WorktreeStorer
+type ObjectCount = state.ObjectCount
+type GCResult = state.GCResult
+type FsckReport = state.FsckReport
+type FsckObject = state.FsckObject
+type FsckBrokenLink = state.FsckBrokenLink
+type ObjectGraph = state.ObjectGraph
+type GraphObject = state.GraphObject
+type ObjectEdge = state.ObjectEdge
+type QuotaError = state.QuotaError
+type CommitQuery = state.CommitQuery
+type CommitSummary = state.CommitSummary
+type CommitSearchResult = state.CommitSearchResult
+
+// Kinds of history rewriting recorded with Session.RecordLineage
+const (
+	RewriteAmend      = state.RewriteAmend
+	RewriteRebase     = state.RewriteRebase
+	RewriteCherryPick = state.RewriteCherryPick
+)
+
+// Rebase todo actions and phases
+const (
+	RebasePick     = state.RebasePick
+	RebaseReword   = state.RebaseReword
+	RebaseSquash   = state.RebaseSquash
+	RebaseDrop     = state.RebaseDrop
+	RebasePlanning = state.RebasePlanning
+	RebaseApplying = state.RebaseApplying
+	RebaseStopped  = state.RebaseStopped
+)
+
+// Outcomes of Session.VerifyCommit and Session.VerifyTag
+const (
+	SignatureGood    = state.SignatureGood
+	SignatureBad     = state.SignatureBad
+	SignatureUnknown = state.SignatureUnknown
+	SignatureNone    = state.SignatureNone
+)
+
+// Who may push to a shared remote, see RemotePermission
+const (
+	RemoteAccessOpen     = state.RemoteAccessOpen
+	RemoteAccessOwner    = state.RemoteAccessOwner
+	RemoteAccessReadOnly = state.RemoteAccessReadOnly
+)
+
+// Issue states
+const (
+	IssueStateOpen   = state.IssueStateOpen
+	IssueStateClosed = state.IssueStateClosed
+)
+
+// Pull request states, merge strategies and review verdicts
+const (
+	PRStateOpen            = state.PRStateOpen
+	PRStateClosed          = state.PRStateClosed
+	PRStateMerged          = state.PRStateMerged
+	PRMergeCommit          = state.PRMergeCommit
+	PRMergeSquash          = state.PRMergeSquash
+	PRMergeRebase          = state.PRMergeRebase
+	ReviewApproved         = state.ReviewApproved
+	ReviewChangesRequested = state.ReviewChangesRequested
+	ReviewCommented        = state.ReviewCommented
+)
+
+// Simulated CI check kinds and states
+const (
+	CheckFileExists    = state.CheckFileExists
+	CheckFileContains  = state.CheckFileContains
+	CheckCommitMessage = state.CheckCommitMessage
+	CheckPending       = state.CheckPending
+	CheckSuccess       = state.CheckSuccess
+	CheckFailure       = state.CheckFailure
+)
+
+// Archive formats
+const (
+	ArchiveTar   = state.ArchiveTar
+	ArchiveTarGz = state.ArchiveTarGz
+	ArchiveZip   = state.ArchiveZip
+)
+
+// Scripted teammate actions
+const (
+	TeammateCommit      = state.TeammateCommit
+	TeammateBranch      = state.TeammateBranch
+	TeammateForcePush   = state.TeammateForcePush
+	TeammatePullRequest = state.TeammatePullRequest
+)
+
+// ErrPullRequestNotFound is returned for an unknown pull request ID.
+var ErrPullRequestNotFound = state.ErrPullRequestNotFound
+
+// ErrIssueNotFound is returned for an unknown issue number.
+var ErrIssueNotFound = state.ErrIssueNotFound
+
+// ErrShuttingDown is returned for commands started while the server drains.
+var ErrShuttingDown = state.ErrShuttingDown
+
+// Environment variables configuring the session lifecycle
+const (
+	PersistSessionsEnv = state.PersistSessionsEnv
+	SessionTTLEnv      = state.SessionTTLEnv
+	StoreEnv           = state.StoreEnv
+)
+
+// Errors of Session.Undo and Session.Redo when there is nothing to restore
+var (
+	ErrNothingToUndo = state.ErrNothingToUndo
+	ErrNothingToRedo = state.ErrNothingToRedo
+)
+
+// Errors of the save point operations
+var (
+	ErrSavePointNotFound = state.ErrSavePointNotFound
+	ErrSavePointExists   = state.ErrSavePointExists
+	ErrTooManySavePoints = state.ErrTooManySavePoints
+)
+
+// Pseudo-refs written by commands
+const (
+	OrigHead       = state.OrigHead
+	MergeHead      = state.MergeHead
+	CherryPickHead = state.CherryPickHead
+	RebaseHead     = state.RebaseHead
+	FetchHead      = state.FetchHead
+)
+
+// SpecialRefs lists the pseudo-refs, in the order they are shown.
+var SpecialRefs = state.SpecialRefs
+
+// TraceVersion is the format version of exported session traces.
+const TraceVersion = state.TraceVersion
+
+// DefaultSessionTTL is how long a session may stay idle before it is evicted.
+const DefaultSessionTTL = state.DefaultSessionTTL
+
+// Graceful shutdown, see SessionManager.Drain
+const (
+	DefaultShutdownTimeout = state.DefaultShutdownTimeout
+	ShutdownTimeoutEnv     = state.ShutdownTimeoutEnv
+)
+
+// Background garbage collection of unreachable objects
+const (
+	DefaultGCInterval = state.DefaultGCInterval
+	GCIntervalEnv     = state.GCIntervalEnv
+)
+
+// MinRemoteSyncInterval is the shortest interval an ingested remote can be synced at.
+const MinRemoteSyncInterval = state.MinRemoteSyncInterval
+
+// UnknownCommandStat is the command name analytics count unregistered commands under.
+const UnknownCommandStat = state.UnknownCommandStat
+
+// OpenStore opens the metadata store backend named kind ("memory" or "file").
+// Wrapper around state.OpenStore
+func OpenStore(kind, path string) (Store, error) {
+	return state.OpenStore(kind, path)
+}
+
+// IngestPolicyFromEnv reads the limits of remote ingests from the GITGYM_INGEST_* variables.
+// Wrapper around state.IngestPolicyFromEnv
+func IngestPolicyFromEnv() (IngestPolicy, error) {
+	return state.IngestPolicyFromEnv()
+}
+
+// NewSessionID returns a cryptographically random session ID.
+// Wrapper around state.NewSessionID
+func NewSessionID() (string, error) {
+	return state.NewSessionID()
+}
+
+// IsIssuedSessionID reports whether id has the form NewSessionID issues.
+// Wrapper around state.IsIssuedSessionID
+func IsIssuedSessionID(id string) bool {
+	return state.IsIssuedSessionID(id)
+}
+
+// OwnsSession reports whether the session id belongs to the session owner.
+// Wrapper around state.OwnsSession
+func OwnsSession(owner, id string) bool {
+	return state.OwnsSession(owner, id)
+}
+
+// RecordUserPush updates the user's tracking ref for branch on a shared remote.
+// Wrapper around state.RecordUserPush
+func RecordUserPush(repo *gogit.Repository, user UserIdentity, branch string, hash plumbing.Hash) error {
+	return state.RecordUserPush(repo, user, branch, hash)
+}
+
+// AheadBehind counts the commits local has that upstream lacks, and the reverse.
+// Wrapper around state.AheadBehind
+func AheadBehind(repo *gogit.Repository, local, upstream plumbing.Hash) (int, int) {
+	return state.AheadBehind(repo, local, upstream)
+}
 
 // NewSessionManager creates a new session manager
 // Wrapper around state.NewSessionManager
 func NewSessionManager() *SessionManager {
 	return state.NewSessionManager()
 }
+
+// PaginateRefNames returns one sorted page of ref names and the cursor for the next page.
+// Wrapper around state.PaginateRefNames
+func PaginateRefNames(names []string, f RefFilter) ([]string, string) {
+	return state.PaginateRefNames(names, f)
+}
+
+// ShallowBoundary returns the commits whose parents a shallow clone left out.
+// Wrapper around state.ShallowBoundary
+func ShallowBoundary(repo *gogit.Repository) map[plumbing.Hash]bool {
+	return state.ShallowBoundary(repo)
+}
+
+// SparseCheckoutDirs returns the sparse checkout directories and whether sparse checkout is on.
+// Wrapper around state.SparseCheckoutDirs
+func SparseCheckoutDirs(repo *gogit.Repository) ([]string, bool) {
+	return state.SparseCheckoutDirs(repo)
+}
+
+// SetSparseCheckout limits the working tree to dirs.
+// Wrapper around state.SetSparseCheckout
+func SetSparseCheckout(repo *gogit.Repository, dirs []string) ([]string, error) {
+	return state.SetSparseCheckout(repo, dirs)
+}
+
+// DisableSparseCheckout turns sparse checkout off and restores every file.
+// Wrapper around state.DisableSparseCheckout
+func DisableSparseCheckout(repo *gogit.Repository) error {
+	return state.DisableSparseCheckout(repo)
+}
+
+// ApplySparseCheckout updates the working tree to the sparse directories after HEAD moved.
+// Wrapper around state.ApplySparseCheckout
+func ApplySparseCheckout(repo *gogit.Repository) ([]string, error) {
+	return state.ApplySparseCheckout(repo)
+}
+
+// SparseCheckoutStats counts the tracked files present in the working tree.
+// Wrapper around state.SparseCheckoutStats
+func SparseCheckoutStats(repo *gogit.Repository) (present, total int) {
+	return state.SparseCheckoutStats(repo)
+}
+
+// LintCommitMessage lints message when the repository requires Conventional Commits.
+// Wrapper around state.LintCommitMessage
+func LintCommitMessage(repo *gogit.Repository, message string) error {
+	if !state.CommitLintEnabled(repo) {
+		return nil
+	}
+	return state.LintCommitMessage(message)
+}
+
+// ParseConventionalCommit splits a commit message into its Conventional Commit parts.
+// Wrapper around state.ParseConventionalCommit
+func ParseConventionalCommit(message string) (*ConventionalCommit, error) {
+	return state.ParseConventionalCommit(message)
+}
+
+// ArchiveFormatFor returns the archive format matching a file name's extension.
+// Wrapper around state.ArchiveFormatFor
+func ArchiveFormatFor(file string) string {
+	return state.ArchiveFormatFor(file)
+}
+
+// WriteArchive writes the tree of a commit, tag or tree as a tar, tar.gz or zip archive.
+// Wrapper around state.WriteArchive
+func WriteArchive(repo *gogit.Repository, rev plumbing.Hash, opts ArchiveOptions, w io.Writer) error {
+	return state.WriteArchive(repo, rev, opts, w)
+}
+
+// WriteBundle writes every ref of a repository and the objects they reach as a git bundle.
+// Wrapper around state.WriteBundle
+func WriteBundle(repo *gogit.Repository, w io.Writer) error {
+	return state.WriteBundle(repo, w)
+}
+
+// AllBundleRefs returns the refs "git bundle create --all" records.
+// Wrapper around state.AllBundleRefs
+func AllBundleRefs(repo *gogit.Repository) ([]*plumbing.Reference, error) {
+	return state.AllBundleRefs(repo)
+}
+
+// CreateBundle writes refs and their objects as a bundle, without what the prerequisites reach.
+// Wrapper around state.CreateBundle
+func CreateBundle(repo *gogit.Repository, refs []*plumbing.Reference, prerequisites []plumbing.Hash, w io.Writer) error {
+	return state.CreateBundle(repo, refs, prerequisites, w)
+}
+
+// IsBundle reports whether data starts like a bundle file.
+// Wrapper around state.IsBundle
+func IsBundle(data []byte) bool {
+	return state.IsBundle(data)
+}
+
+// OpenBundle unpacks a bundle into an in-memory repository.
+// Wrapper around state.OpenBundle
+func OpenBundle(data []byte) (*gogit.Repository, *Bundle, error) {
+	return state.OpenBundle(data)
+}
+
+// MissingPrerequisites returns the prerequisite commits of a bundle a repository lacks.
+// Wrapper around state.MissingPrerequisites
+func MissingPrerequisites(repo *gogit.Repository, b *Bundle) []plumbing.Hash {
+	return state.MissingPrerequisites(repo, b)
+}
+
+// RefState returns the refs of a repository by name, HEAD included.
+// Wrapper around state.RefState
+func RefState(repo *gogit.Repository) map[string]string {
+	return state.RefState(repo)
+}
+
+// BuildObjectGraph returns the objects of a repository and the links between them.
+// Wrapper around state.BuildObjectGraph
+func BuildObjectGraph(repo *gogit.Repository, limit int) ObjectGraph {
+	return state.BuildObjectGraph(repo, limit)
+}
+
+// CountObjects counts the objects a repository stores itself.
+// Wrapper around state.CountObjects
+func CountObjects(repo *gogit.Repository) ObjectCount {
+	return state.CountObjects(repo)
+}
+
+// BuildMaintenanceReport describes the storage, gc and cache state behind a session.
+// Wrapper around state.BuildMaintenanceReport
+func BuildMaintenanceReport(s *Session) MaintenanceReport {
+	return state.BuildMaintenanceReport(s)
+}
+
+// CheckBranchPolicy validates a new branch name against the session's naming policy.
+// Sessions without a policy accept any name.
+func CheckBranchPolicy(s *Session, name string) error {
+	return s.BranchPolicy.Validate(name)
+}
+
+// SetSpecialRef points a pseudo-ref at a commit.
+// Wrapper around state.SetSpecialRef
+func SetSpecialRef(repo *gogit.Repository, name plumbing.ReferenceName, hash plumbing.Hash) error {
+	return state.SetSpecialRef(repo, name, hash)
+}
@@ -8,40 +8,52 @@ import (
 
 // ObjectUtils provides helpers for simulating git object transfer between repositories (in-memory).
 
-// CopyCommitRecursive copies a commit and all its dependencies (parents, trees, blobs) from src to dst.
-func CopyCommitRecursive(src, dst *gogit.Repository, hash plumbing.Hash) error {
-	// Check if dst has it
-	if HasObject(dst, hash) {
-		return nil
-	}
-
-	// Get from Source
-	obj, err := src.Storer.EncodedObject(plumbing.CommitObject, hash)
-	if err != nil {
-		return err
-	}
-
-	// Write to Dest
-	_, err = dst.Storer.SetEncodedObject(obj)
-	if err != nil {
-		return err
-	}
-
-	// Decode to parse tree/parents
-	commit, err := object.DecodeCommit(src.Storer, obj)
-	if err != nil {
-		return err
+// CopyCommitsShallow copies the last depth commits of each tip (a tip is at
+// depth 1), with their trees and blobs, from src to dst. It returns the copied
+// commits whose parents were left out: the shallow boundary.
+func CopyCommitsShallow(src, dst *gogit.Repository, tips []plumbing.Hash, depth int) ([]plumbing.Hash, error) {
+	included := make(map[plumbing.Hash]bool)
+	var commits []*object.Commit
+	level := tips
+	for d := 1; d <= depth && len(level) > 0; d++ {
+		var next []plumbing.Hash
+		for _, hash := range level {
+			if included[hash] {
+				continue
+			}
+			commit, err := src.CommitObject(hash)
+			if err != nil {
+				return nil, err
+			}
+			included[hash] = true
+			commits = append(commits, commit)
+			next = append(next, commit.ParentHashes...)
+		}
+		level = next
 	}
 
-	// Recurse parents
-	for _, p := range commit.ParentHashes {
-		if err := CopyCommitRecursive(src, dst, p); err != nil {
-			return err
+	var boundary []plumbing.Hash
+	for _, commit := range commits {
+		if !HasObject(dst, commit.Hash) {
+			obj, err := src.Storer.EncodedObject(plumbing.CommitObject, commit.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := dst.Storer.SetEncodedObject(obj); err != nil {
+				return nil, err
+			}
+		}
+		if err := CopyTreeRecursive(src, dst, commit.TreeHash); err != nil {
+			return nil, err
+		}
+		for _, p := range commit.ParentHashes {
+			if !included[p] {
+				boundary = append(boundary, commit.Hash)
+				break
+			}
 		}
 	}
-
-	// Copy Tree
-	return CopyTreeRecursive(src, dst, commit.TreeHash)
+	return boundary, nil
 }
 
 // CopyTreeRecursive copies a tree and all its entries (blobs, subtrees) from src to dst.
@@ -0,0 +1,135 @@
+package git
+
+// snapshot_diff.go - Structured diff between two repository snapshots
+//
+// A snapshot is either a revision (branch, tag, hash, HEAD~n, ...), the
+// staging area ("index") or the working tree ("worktree"). The result is a
+// per-file list suitable for the graph inspector, so the UI does not have to
+// parse raw `git diff` output.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// Special snapshot names accepted by SnapshotDiff in addition to revisions.
+const (
+	SnapshotWorktree = "worktree"
+	SnapshotIndex    = "index"
+)
+
+// FileStat summarizes how a single file differs between two snapshots.
+type FileStat struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"oldPath,omitempty"` // Set for renames
+	Status    string `json:"status"`            // "added", "deleted", "modified" or "renamed"
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// FileDiff describes how a single file differs between two snapshots.
+type FileDiff struct {
+	FileStat
+	Patch string `json:"patch"` // Unified diff for this file only
+}
+
+// SnapshotDiff compares the snapshots from and to and returns one entry per changed file.
+// Empty from defaults to HEAD; empty to defaults to the working tree.
+func SnapshotDiff(s *Session, repo *gogit.Repository, from, to string) ([]FileDiff, error) {
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = SnapshotWorktree
+	}
+
+	fromSnap, err := resolveSnapshot(repo, from)
+	if err != nil {
+		return nil, err
+	}
+	toSnap, err := resolveSnapshot(repo, to)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := DiffContent(fromSnap, toSnap, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff: %w", err)
+	}
+
+	files := []FileDiff{}
+	for _, fp := range patch.FilePatches() {
+		files = append(files, newFileDiff(fp))
+	}
+	return files, nil
+}
+
+func resolveSnapshot(repo *gogit.Repository, name string) (ContentSnapshot, error) {
+	switch name {
+	case SnapshotWorktree:
+		snap, err := WorktreeSnapshot(repo, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read worktree: %w", err)
+		}
+		return snap, nil
+	case SnapshotIndex:
+		snap, err := IndexSnapshot(repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index: %w", err)
+		}
+		return snap, nil
+	}
+	return CommitSnapshot(repo, name)
+}
+
+func newFileDiff(fp diff.FilePatch) FileDiff {
+	fd := FileDiff{FileStat: newFileStat(fp)}
+	var buf bytes.Buffer
+	if err := diff.NewUnifiedEncoder(&buf, diff.DefaultContextLines).Encode(singleFilePatch{fp}); err == nil {
+		fd.Patch = buf.String()
+	}
+	return fd
+}
+
+func newFileStat(fp diff.FilePatch) FileStat {
+	from, to := fp.Files()
+	fd := FileStat{Binary: fp.IsBinary()}
+
+	switch {
+	case from == nil:
+		fd.Path, fd.Status = to.Path(), "added"
+	case to == nil:
+		fd.Path, fd.Status = from.Path(), "deleted"
+	case from.Path() != to.Path():
+		fd.Path, fd.OldPath, fd.Status = to.Path(), from.Path(), "renamed"
+	default:
+		fd.Path, fd.Status = to.Path(), "modified"
+	}
+
+	for _, chunk := range fp.Chunks() {
+		lines := strings.Count(chunk.Content(), "\n")
+		if !strings.HasSuffix(chunk.Content(), "\n") && chunk.Content() != "" {
+			lines++
+		}
+		switch chunk.Type() {
+		case diff.Add:
+			fd.Additions += lines
+		case diff.Delete:
+			fd.Deletions += lines
+		}
+	}
+	return fd
+}
+
+// singleFilePatch adapts one FilePatch to diff.Patch so it can be encoded on its own.
+type singleFilePatch struct {
+	fp diff.FilePatch
+}
+
+func (p singleFilePatch) FilePatches() []diff.FilePatch { return []diff.FilePatch{p.fp} }
+func (p singleFilePatch) Message() string               { return "" }
@@ -0,0 +1,17 @@
+package git
+
+import "context"
+
+type stdinKey struct{}
+
+// WithStdin returns a context whose commands read input from stdin, as the
+// shell does for the right-hand side of a pipe or a "<" redirection.
+func WithStdin(ctx context.Context, input string) context.Context {
+	return context.WithValue(ctx, stdinKey{}, input)
+}
+
+// Stdin returns the input given to the command, if any.
+func Stdin(ctx context.Context) (string, bool) {
+	input, ok := ctx.Value(stdinKey{}).(string)
+	return input, ok
+}
@@ -0,0 +1,283 @@
+package git
+
+// suggest.go - Suggestions for failed commands
+//
+// A failed command may come back with a Suggestion: the command that was
+// probably meant, found by edit distance over the registry, the options of the
+// command or the refs of the repository, or a fix from the hint table below
+// for mistakes learners often make.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+)
+
+// Suggestion tells the learner how to fix a failed command.
+type Suggestion struct {
+	Message string `json:"message"`           // e.g. "did you mean 'commit'?"
+	Command string `json:"command,omitempty"` // Command line that does what was probably meant, when there is one
+}
+
+// maxSuggestionDistance is how many edits a typo may be away from what was meant.
+const maxSuggestionDistance = 2
+
+// errorHint suggests a fix for errors matching pattern. The submatches of the
+// pattern are passed on; lang is the language of the message.
+type errorHint struct {
+	pattern *regexp.Regexp
+	suggest func(s *Session, lang, cmdName string, args []string, match []string) *Suggestion
+}
+
+// errorHints is the curated table of common mistakes.
+var errorHints = []errorHint{
+	{regexp.MustCompile("^error: unknown (?:option|switch) `([^']+)'"), suggestOption},
+	{regexp.MustCompile(`^error: pathspec '([^']+)' did not match any file\(s\) known to git`), func(s *Session, lang, cmdName string, args []string, m []string) *Suggestion {
+		if cmdName != "checkout" {
+			return nil
+		}
+		return suggestBranch(s, lang, args, m[1], "checkout -b")
+	}},
+	{regexp.MustCompile(`^fatal: invalid reference: (.+)`), func(s *Session, lang, cmdName string, args []string, m []string) *Suggestion {
+		if cmdName != "switch" || len(args) != 2 {
+			return nil // Only "git switch <branch>": with -c the start point is what is missing
+		}
+		return suggestBranch(s, lang, args, m[1], "switch -c")
+	}},
+	{regexp.MustCompile(`^merge: (.+) - not something we can merge`), func(s *Session, lang, _ string, args []string, m []string) *Suggestion {
+		return suggestRef(s, lang, args, m[1])
+	}},
+	{regexp.MustCompile(`^fatal: The current branch (.+) has no upstream branch`), func(s *Session, lang, _ string, _ []string, m []string) *Suggestion {
+		remote := "origin"
+		if names := remoteNames(s); len(names) == 1 {
+			remote = names[0]
+		}
+		return &Suggestion{
+			Message: i18n.T(lang, "suggest.set_upstream"),
+			Command: fmt.Sprintf("git push --set-upstream %s %s", remote, m[1]),
+		}
+	}},
+	{regexp.MustCompile(`^fatal: '([^']+)' does not appear to be a git repository`), func(s *Session, lang, _ string, args []string, m []string) *Suggestion {
+		names := remoteNames(s)
+		if len(names) == 0 {
+			return &Suggestion{Message: i18n.T(lang, "suggest.add_remote"), Command: fmt.Sprintf("git remote add %s <url>", m[1])}
+		}
+		return suggestReplacement(lang, args, m[1], closest(m[1], names))
+	}},
+}
+
+// suggest returns a suggestion for the error a command failed with, or nil.
+// The message is in the language of ctx.
+func suggest(ctx context.Context, s *Session, cmdName string, args []string, err error) *Suggestion {
+	if err == nil {
+		return nil
+	}
+	lang := i18n.Lang(ctx)
+	var unknown *unknownCommandError
+	if errors.As(err, &unknown) {
+		return suggestCommand(lang, cmdName, args)
+	}
+	s.RLock()
+	defer s.RUnlock()
+	for _, h := range errorHints {
+		if m := h.pattern.FindStringSubmatch(err.Error()); m != nil {
+			return h.suggest(s, lang, cmdName, args, m)
+		}
+	}
+	return nil
+}
+
+// suggestCommand finds registered commands close to a mistyped name.
+func suggestCommand(lang, name string, args []string) *Suggestion {
+	names := []string{"git"}
+	for _, n := range sortedCommands() {
+		if spec, _ := CommandSpecOf(n); !spec.Hidden {
+			names = append(names, strings.TrimPrefix(n, "git-"))
+		}
+	}
+	matches := closest(name, names)
+	if len(matches) == 0 {
+		return nil
+	}
+	s := &Suggestion{Message: didYouMean(lang, matches)}
+	if len(matches) == 1 && len(args) > 0 {
+		words := append([]string{matches[0]}, args[1:]...)
+		if spec, ok := CommandSpecOf(matches[0]); ok && !spec.Shell {
+			words = append([]string{"git"}, words...)
+		}
+		s.Command = joinCommandLine(words)
+	}
+	return s
+}
+
+// suggestOption finds the options of the command close to an unknown one.
+func suggestOption(_ *Session, lang, cmdName string, args []string, m []string) *Suggestion {
+	spec, _ := CommandSpecOf(cmdName)
+	typed := m[1]
+	var flags []string
+	for _, opt := range spec.Options {
+		for _, f := range opt.Flags {
+			flags = append(flags, strings.TrimLeft(f, "-"))
+		}
+	}
+	matches := closest(typed, flags)
+	if len(matches) == 0 {
+		return nil
+	}
+	for i, f := range matches {
+		if len(f) == 1 {
+			matches[i] = "-" + f
+		} else {
+			matches[i] = "--" + f
+		}
+	}
+	dashes := "--"
+	if len(typed) == 1 {
+		dashes = "-"
+	}
+	return suggestReplacement(lang, args, dashes+typed, matches)
+}
+
+// suggestBranch suggests a branch close to a missing one, or creating it.
+func suggestBranch(s *Session, lang string, args []string, name, create string) *Suggestion {
+	if sug := suggestRef(s, lang, args, name); sug != nil {
+		return sug
+	}
+	return &Suggestion{
+		Message: i18n.T(lang, "suggest.create_branch", name, "git "+create),
+		Command: fmt.Sprintf("git %s %s", create, name),
+	}
+}
+
+// suggestRef suggests the branches and tags close to a missing ref.
+func suggestRef(s *Session, lang string, args []string, name string) *Suggestion {
+	repo := s.GetRepo()
+	if repo == nil {
+		return nil
+	}
+	var refs []string
+	if iter, err := repo.References(); err == nil {
+		_ = iter.ForEach(func(ref *plumbing.Reference) error {
+			if n := ref.Name(); n.IsBranch() || n.IsTag() || (n.IsRemote() && !strings.HasSuffix(n.String(), "/HEAD")) {
+				refs = append(refs, n.Short())
+			}
+			return nil
+		})
+	}
+	return suggestReplacement(lang, args, name, closest(name, refs))
+}
+
+// suggestReplacement suggests the matches in place of the argument typed.
+func suggestReplacement(lang string, args []string, typed string, matches []string) *Suggestion {
+	if len(matches) == 0 {
+		return nil
+	}
+	s := &Suggestion{Message: didYouMean(lang, matches)}
+	if len(matches) == 1 {
+		fixed := append([]string{}, args...)
+		for i, arg := range fixed {
+			if arg == typed {
+				fixed[i] = matches[0]
+				s.Command = joinCommandLine(append([]string{"git"}, fixed...))
+				break
+			}
+		}
+	}
+	return s
+}
+
+func remoteNames(s *Session) []string {
+	repo := s.GetRepo()
+	if repo == nil {
+		return nil
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func didYouMean(lang string, matches []string) string {
+	if len(matches) == 1 {
+		return i18n.T(lang, "suggest.did_you_mean", matches[0])
+	}
+	return i18n.T(lang, "suggest.did_you_mean_one_of", "'"+strings.Join(matches, "', '")+"'")
+}
+
+// closest returns the candidates nearest to word, within
+// maxSuggestionDistance edits and closer than retyping it.
+func closest(word string, candidates []string) []string {
+	best := maxSuggestionDistance + 1
+	var matches []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c == word || seen[c] {
+			continue
+		}
+		seen[c] = true
+		d := editDistance(word, c)
+		if d >= len(word) || d >= len(c) {
+			continue
+		}
+		switch {
+		case d < best:
+			best = d
+			matches = []string{c}
+		case d == best:
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// joinCommandLine joins words into a line the shell splits back into them.
+func joinCommandLine(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w == "" || strings.ContainsAny(w, " \t\"'\\|&;<>") {
+			w = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(w) + `"`
+		}
+		quoted[i] = w
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"commit", "commit", 0},
+		{"comit", "commit", 1},
+		{"sl", "ls", 1}, // Swapped characters count once
+		{"stauts", "status", 1},
+		{"chekcout", "checkout", 1},
+		{"brnch", "branch", 1},
+		{"pul", "push", 2},
+		{"", "add", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance(tt.a, tt.b), "%s -> %s", tt.a, tt.b)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"feature", "features", "fix", "main", "master"}
+	assert.Equal(t, []string{"feature"}, closest("featur", candidates))
+	assert.Equal(t, []string{"main"}, closest("mian", candidates))
+	assert.Equal(t, []string{"feature", "features"}, closest("featurse", candidates))
+	assert.Empty(t, closest("release", candidates))
+	// A word as short as its distance is a guess, not a typo
+	assert.Empty(t, closest("xi", []string{"fix"}))
+}
+
+func TestJoinCommandLine(t *testing.T) {
+	assert.Equal(t, `git commit -m "fix the \"bug\""`, joinCommandLine([]string{"git", "commit", "-m", `fix the "bug"`}))
+	assert.Equal(t, `git add ""`, joinCommandLine([]string{"git", "add", ""}))
+}
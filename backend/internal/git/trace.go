@@ -0,0 +1,67 @@
+package git
+
+// trace.go - Recording dispatched commands into the session trace
+//
+// Dispatch appends every command to the session trace (see state/trace.go)
+// with the refs it left. The shell marks the commands of one command line
+// with WithCommandLine, so a replay can run the line again as typed, with its
+// redirections and pipes.
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type commandLineKey struct{}
+
+// commandLine is the shell line a command belongs to. seq is the trace
+// number of its first command, once one was recorded.
+type commandLine struct {
+	text string
+	seq  int
+}
+
+// WithCommandLine returns a context whose dispatched commands are traced as
+// parts of the shell line text.
+func WithCommandLine(ctx context.Context, text string) context.Context {
+	return context.WithValue(ctx, commandLineKey{}, &commandLine{text: text})
+}
+
+// recordTrace appends a dispatched command to the session trace. dir is the
+// directory the command ran in.
+func recordTrace(ctx context.Context, session *Session, dir string, args []string, cmdName string, result *CommandResult) {
+	entry := TraceEntry{
+		Command:  cmdName,
+		Args:     append([]string(nil), args...),
+		Dir:      dir,
+		ExitCode: result.ExitCode,
+		Editor:   result.Editor != nil,
+		Time:     time.Now(),
+	}
+	if msg, ok := EditedMessage(ctx); ok {
+		entry.Message = msg
+	}
+	line, _ := ctx.Value(commandLineKey{}).(*commandLine)
+	if line != nil {
+		entry.Line, entry.LineSeq = line.text, line.seq
+	}
+
+	session.Lock()
+	entry.Refs = RefState(session.GetRepo())
+	seq := session.RecordTrace(entry)
+	session.RecordTimelineFrame(seq, commandText(cmdName, args), result.ExitCode)
+	session.Unlock()
+	if line != nil && line.seq == 0 {
+		line.seq = seq
+	}
+}
+
+// commandText renders a dispatched command as the learner would type it.
+func commandText(cmdName string, args []string) string {
+	text := strings.Join(args, " ")
+	if spec, _ := CommandSpecOf(cmdName); !spec.Shell {
+		text = "git " + text
+	}
+	return text
+}
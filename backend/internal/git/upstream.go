@@ -0,0 +1,112 @@
+package git
+
+// upstream.go - branch.<name>.remote / branch.<name>.merge tracking configuration
+//
+// A branch's upstream is the remote branch it pushes to and pulls from by default.
+// It is set by push -u, branch --set-upstream-to and clone, and read by pull,
+// branch -vv and <branch>@{u}.
+
+import (
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Upstream returns the remote and remote branch that branch tracks, if configured.
+func Upstream(repo *gogit.Repository, branch string) (remote, merge string, ok bool) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", "", false
+	}
+	b, found := cfg.Branches[branch]
+	if !found || b.Remote == "" || b.Merge == "" {
+		return "", "", false
+	}
+	return b.Remote, b.Merge.Short(), true
+}
+
+// UpstreamRef returns the remote-tracking branch that branch tracks, if configured.
+func UpstreamRef(repo *gogit.Repository, branch string) (plumbing.ReferenceName, bool) {
+	remote, merge, ok := Upstream(repo, branch)
+	if !ok {
+		return "", false
+	}
+	return plumbing.NewRemoteReferenceName(remote, merge), true
+}
+
+// SetUpstream makes branch track the branch merge of remote.
+func SetUpstream(repo *gogit.Repository, branch, remote, merge string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Remotes[remote]; !ok {
+		return fmt.Errorf("fatal: '%s' does not appear to be a git repository", remote)
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok {
+		b = &config.Branch{Name: branch}
+		cfg.Branches[branch] = b
+	}
+	b.Remote = remote
+	b.Merge = plumbing.NewBranchReferenceName(merge)
+	return repo.Storer.SetConfig(cfg)
+}
+
+// UnsetUpstream removes the tracking configuration of branch.
+func UnsetUpstream(repo *gogit.Repository, branch string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok || b.Remote == "" {
+		return fmt.Errorf("fatal: branch '%s' has no upstream information", branch)
+	}
+	b.Remote, b.Merge = "", ""
+	return repo.Storer.SetConfig(cfg)
+}
+
+// RenameBranchConfig moves the tracking configuration of a renamed branch.
+func RenameBranchConfig(repo *gogit.Repository, oldName, newName string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	b, ok := cfg.Branches[oldName]
+	if !ok {
+		return nil
+	}
+	delete(cfg.Branches, oldName)
+	if newName != "" {
+		cfg.Branches[newName] = &config.Branch{Name: newName, Remote: b.Remote, Merge: b.Merge, Rebase: b.Rebase, Description: b.Description}
+	}
+	return repo.Storer.SetConfig(cfg)
+}
+
+// TrackingSummary describes branch's relation to its upstream the way
+// branch -vv does, e.g. "[origin/main: ahead 1, behind 2]". It is empty when
+// branch has no upstream.
+func TrackingSummary(repo *gogit.Repository, branch string, tip plumbing.Hash) string {
+	ref, ok := UpstreamRef(repo, branch)
+	if !ok {
+		return ""
+	}
+	upstream, err := repo.Reference(ref, true)
+	if err != nil {
+		return fmt.Sprintf("[%s: gone]", ref.Short())
+	}
+	ahead, behind := AheadBehind(repo, tip, upstream.Hash())
+	switch {
+	case ahead == 0 && behind == 0:
+		return fmt.Sprintf("[%s]", ref.Short())
+	case behind == 0:
+		return fmt.Sprintf("[%s: ahead %d]", ref.Short(), ahead)
+	case ahead == 0:
+		return fmt.Sprintf("[%s: behind %d]", ref.Short(), behind)
+	default:
+		return fmt.Sprintf("[%s: ahead %d, behind %d]", ref.Short(), ahead, behind)
+	}
+}
@@ -0,0 +1,282 @@
+package git
+
+// worktree_diff.go - Content diffs between commits, the index and the working tree
+//
+// go-git only diffs two tree objects. Instead of writing temporary commits to
+// turn the index or the working tree into trees, each side is read into a
+// snapshot of path -> blob hash (working tree files are hashed in memory) and
+// only the paths whose hashes differ are diffed line by line.
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	utildiff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ContentSnapshot maps the paths of a commit, the index or the working tree to their blobs.
+type ContentSnapshot map[string]snapshotFile
+
+type snapshotFile struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+	read func() ([]byte, error)
+}
+
+// TreeSnapshot lists the files of a tree.
+func TreeSnapshot(tree *object.Tree) (ContentSnapshot, error) {
+	snap := make(ContentSnapshot)
+	err := tree.Files().ForEach(func(f *object.File) error {
+		file := f
+		snap[f.Name] = snapshotFile{hash: f.Hash, mode: f.Mode, read: func() ([]byte, error) {
+			content, err := file.Contents()
+			return []byte(content), err
+		}}
+		return nil
+	})
+	return snap, err
+}
+
+// CommitSnapshot lists the files of the commit rev resolves to.
+func CommitSnapshot(repo *gogit.Repository, rev string) (ContentSnapshot, error) {
+	hash, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: bad revision '%s'", rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: '%s' is not a commit", rev)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return TreeSnapshot(tree)
+}
+
+// HeadSnapshot lists the files of HEAD, or nothing on an unborn branch.
+func HeadSnapshot(repo *gogit.Repository) (ContentSnapshot, error) {
+	if _, err := repo.Head(); err != nil {
+		return ContentSnapshot{}, nil
+	}
+	return CommitSnapshot(repo, "HEAD")
+}
+
+// IndexSnapshot lists the staged files. A conflicted path shows our side, as
+// the working tree holds the conflict markers.
+func IndexSnapshot(repo *gogit.Repository) (ContentSnapshot, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	snap := make(ContentSnapshot)
+	for _, e := range idx.Entries {
+		// Regular entries decode with stage 0, which is not index.Merged
+		if e.Stage != 0 && e.Stage != index.OurMode {
+			continue
+		}
+		hash := e.Hash
+		snap[e.Name] = snapshotFile{hash: hash, mode: e.Mode, read: func() ([]byte, error) {
+			return readBlob(repo, hash)
+		}}
+	}
+	return snap, nil
+}
+
+// WorktreeSnapshot lists the working tree copies of the files tracked in the
+// index, as git diff sees them. With untracked set, files not yet added (and
+// not ignored) are listed too.
+func WorktreeSnapshot(repo *gogit.Repository, untracked bool) (ContentSnapshot, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	staged, err := IndexSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]filemode.FileMode, len(staged))
+	for name, entry := range staged {
+		names[name] = entry.mode
+	}
+	if untracked {
+		patterns, _ := gitignore.ReadPatterns(w.Filesystem, nil)
+		ignored := gitignore.NewMatcher(patterns)
+		err := util.Walk(w.Filesystem, "", func(name string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			parts := strings.Split(filepath.ToSlash(name), "/")
+			if info.IsDir() {
+				if name == ".git" || (name != "" && ignored.Match(parts, true)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := names[name]; !ok && !ignored.Match(parts, false) {
+				names[filepath.ToSlash(name)] = filemode.Regular
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	snap := make(ContentSnapshot, len(names))
+	for name, mode := range names {
+		info, err := w.Filesystem.Lstat(name)
+		if err != nil || info.IsDir() {
+			continue // Deleted in the working tree
+		}
+		data, err := util.ReadFile(w.Filesystem, name)
+		if err != nil {
+			return nil, err
+		}
+		snap[name] = snapshotFile{
+			hash: plumbing.ComputeHash(plumbing.BlobObject, data),
+			mode: mode,
+			read: func() ([]byte, error) { return data, nil },
+		}
+	}
+	return snap, nil
+}
+
+// MatchPathspec reports whether path is selected by any of specs: the path
+// itself, a directory above it, or a glob such as "*.txt". No specs match everything.
+func MatchPathspec(name string, specs []string) bool {
+	if len(specs) == 0 {
+		return true
+	}
+	for _, spec := range specs {
+		spec = strings.Trim(strings.TrimPrefix(spec, "./"), "/")
+		if spec == "" || spec == "." || name == spec || strings.HasPrefix(name, spec+"/") {
+			return true
+		}
+		if strings.ContainsAny(spec, "*?[") {
+			if ok, _ := path.Match(spec, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(spec, path.Base(name)); ok && !strings.Contains(spec, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiffContent compares two snapshots, limited to the paths matching specs.
+// The result encodes with diff.NewUnifiedEncoder like an object.Patch.
+func DiffContent(from, to ContentSnapshot, specs []string) (diff.Patch, error) {
+	names := make([]string, 0, len(from)+len(to))
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var patch contentPatch
+	for _, name := range names {
+		if !MatchPathspec(name, specs) {
+			continue
+		}
+		a, inFrom := from[name]
+		b, inTo := to[name]
+		if inFrom && inTo && a.hash == b.hash && a.mode == b.mode {
+			continue
+		}
+
+		fp := &contentFilePatch{}
+		var src, dst []byte
+		var err error
+		if inFrom {
+			fp.from = contentFile{path: name, hash: a.hash, mode: a.mode}
+			if src, err = a.read(); err != nil {
+				return nil, err
+			}
+		}
+		if inTo {
+			fp.to = contentFile{path: name, hash: b.hash, mode: b.mode}
+			if dst, err = b.read(); err != nil {
+				return nil, err
+			}
+		}
+
+		if isBinaryContent(src) || isBinaryContent(dst) {
+			fp.binary = true
+		} else if a.hash != b.hash {
+			for _, d := range utildiff.Do(string(src), string(dst)) {
+				op := diff.Equal
+				switch d.Type {
+				case diffmatchpatch.DiffInsert:
+					op = diff.Add
+				case diffmatchpatch.DiffDelete:
+					op = diff.Delete
+				}
+				fp.chunks = append(fp.chunks, contentChunk{content: d.Text, op: op})
+			}
+		}
+		patch = append(patch, fp)
+	}
+	return patch, nil
+}
+
+// isBinaryContent uses git's heuristic: a NUL byte in the first 8000 bytes.
+func isBinaryContent(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+type contentPatch []diff.FilePatch
+
+func (p contentPatch) FilePatches() []diff.FilePatch { return p }
+func (p contentPatch) Message() string               { return "" }
+
+type contentFilePatch struct {
+	from, to diff.File // nil for added and deleted files
+	chunks   []diff.Chunk
+	binary   bool
+}
+
+func (fp *contentFilePatch) IsBinary() bool              { return fp.binary }
+func (fp *contentFilePatch) Files() (from, to diff.File) { return fp.from, fp.to }
+func (fp *contentFilePatch) Chunks() []diff.Chunk        { return fp.chunks }
+
+type contentFile struct {
+	path string
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
+
+func (f contentFile) Hash() plumbing.Hash     { return f.hash }
+func (f contentFile) Mode() filemode.FileMode { return f.mode }
+func (f contentFile) Path() string            { return f.path }
+
+type contentChunk struct {
+	content string
+	op      diff.Operation
+}
+
+func (c contentChunk) Content() string      { return c.content }
+func (c contentChunk) Type() diff.Operation { return c.op }
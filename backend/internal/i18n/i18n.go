@@ -0,0 +1,136 @@
+package i18n
+
+// i18n.go - Translations of the text GitGym writes itself
+//
+// Command help, the command index, suggestions and GitGym's own messages are
+// looked up by key in the locale files under locales/, one file per language
+// (locales/en.yaml, locales/ja.yaml). Keys missing from a locale fall back to
+// English, so a partial translation still works. The messages of git itself
+// stay in English, as git prints them.
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// Default is the language used when the learner has not asked for one.
+const Default = "en"
+
+// catalogs holds the messages of each language, keyed by message key.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+	catalogs := make(map[string]map[string]string)
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		var messages map[string]string
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", e.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(e.Name(), ".yaml")] = messages
+	}
+	return catalogs
+}
+
+// Languages returns the languages that have a locale file, in order.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Keys returns the message keys of a language, in order.
+func Keys(lang string) []string {
+	keys := make([]string, 0, len(catalogs[lang]))
+	for key := range catalogs[lang] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has reports whether the locale file of lang defines key.
+func Has(lang, key string) bool {
+	_, ok := catalogs[lang][key]
+	return ok
+}
+
+// T returns the message key in lang, formatted with args as by fmt.Sprintf.
+// A key lang does not define falls back to Default, then to the key itself.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = catalogs[Default][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Match picks the language with a locale file that best fits an
+// Accept-Language header such as "ja-JP,ja;q=0.9,en;q=0.8", or returns ""
+// when none fits.
+func Match(acceptLanguage string) string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var prefs []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		base, _, _ = strings.Cut(base, "_")
+		prefs = append(prefs, weighted{base, q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	for _, p := range prefs {
+		if _, ok := catalogs[p.lang]; ok {
+			return p.lang
+		}
+	}
+	return ""
+}
+
+type langKey struct{}
+
+// WithLang returns a context that asks for messages in lang.
+func WithLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, langKey{}, lang)
+}
+
+// Lang returns the language ctx asks for, or "" when it asks for none.
+func Lang(ctx context.Context) string {
+	lang, _ := ctx.Value(langKey{}).(string)
+	return lang
+}
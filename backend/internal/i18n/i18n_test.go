@@ -0,0 +1,46 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocales_SameKeys(t *testing.T) {
+	for _, lang := range Languages() {
+		assert.Equal(t, Keys(Default), Keys(lang), "locales/%s.yaml", lang)
+	}
+	assert.Contains(t, Languages(), "ja")
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "did you mean 'commit'?", T("en", "suggest.did_you_mean", "commit"))
+	assert.Equal(t, "'commit' のことですか？", T("ja", "suggest.did_you_mean", "commit"))
+	assert.Equal(t, T("en", "index.intro"), T("fr", "index.intro"), "unknown languages fall back to English")
+	assert.Equal(t, T("en", "index.intro"), T("", "index.intro"))
+	assert.Equal(t, "no.such.key", T("ja", "no.such.key"))
+}
+
+func TestMatch(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"ja":                      "ja",
+		"ja-JP,ja;q=0.9,en;q=0.8": "ja",
+		"en-US,en;q=0.9,ja;q=0.8": "en",
+		"fr-FR,fr;q=0.9,ja;q=0.5": "ja",
+		"fr, de":                  "",
+		"en;q=0.5, ja_JP":         "ja",
+		"ja;q=0, en":              "en",
+		"*":                       "",
+	}
+	for header, want := range tests {
+		assert.Equal(t, want, Match(header), header)
+	}
+}
+
+func TestLang(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", Lang(ctx))
+	assert.Equal(t, "ja", Lang(WithLang(ctx, "ja")))
+}
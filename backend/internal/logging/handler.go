@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler adds the request ID, session ID and command name of the context to
+// every record before passing it on, and copies the records of a session to
+// its session log. In a logger with groups the context attributes land in
+// the group like the others.
+type Handler struct {
+	out      slog.Handler
+	level    slog.Leveler
+	sessions *SessionLogs
+
+	attrs   []slog.Attr // Added with WithAttrs, keys prefixed with their groups, for session logs
+	group   string      // Prefix of the keys of later attributes, e.g. "job."
+	session string      // Session ID given with WithAttrs
+}
+
+// Enabled reports whether a record is written out or kept in a session log.
+// Session logs keep debug records even when the output does not.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() || (h.sessions != nil && (Session(ctx) != "" || h.session != ""))
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var given string // Session ID passed as an attribute of the record
+	if h.group == "" {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == SessionKey {
+				given = a.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+	session := Session(ctx)
+	fromCtx := session != "" && h.session == "" && given == "" // Not logged yet
+	if session == "" {
+		session = h.session
+	}
+	if session == "" {
+		session = given
+	}
+	if session != "" && h.sessions != nil {
+		h.sessions.add(session, h.entry(ctx, r))
+	}
+
+	if r.Level < h.level.Level() {
+		return nil
+	}
+	r = r.Clone()
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if fromCtx {
+		r.AddAttrs(slog.String(SessionKey, session))
+	}
+	if cmd := Command(ctx); cmd != "" {
+		r.AddAttrs(slog.String(CommandKey, cmd))
+	}
+	return h.out.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.out = h.out.WithAttrs(attrs)
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if a.Key == SessionKey && h.group == "" {
+			h2.session = a.Value.String()
+		}
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.group + a.Key, Value: a.Value})
+	}
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.out = h.out.WithGroup(name)
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// entry converts a record to a session log entry.
+func (h *Handler) entry(ctx context.Context, r slog.Record) Entry {
+	e := Entry{
+		Time:      r.Time,
+		Level:     r.Level.String(),
+		Message:   r.Message,
+		RequestID: RequestID(ctx),
+		Command:   Command(ctx),
+	}
+	add := func(key string, v slog.Value) {
+		if key == SessionKey {
+			return
+		}
+		if e.Attrs == nil {
+			e.Attrs = make(map[string]string)
+		}
+		e.Attrs[key] = v.Resolve().String()
+	}
+	for _, a := range h.attrs {
+		add(a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(h.group+a.Key, a.Value)
+		return true
+	})
+	return e
+}
@@ -0,0 +1,122 @@
+// Package logging sets up the structured logger of the GitGym backend.
+//
+// Every record carries the request ID, session ID and command name found in
+// its context, so one request or one learner's session can be followed
+// through the handlers and git.Dispatch. Records of a session are also kept
+// in a bounded per-session log, at debug level whatever the configured level,
+// which support can dump when a learner reports a problem.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	LevelEnv  = "GITGYM_LOG_LEVEL"  // debug, info (default), warn or error
+	FormatEnv = "GITGYM_LOG_FORMAT" // text (default) or json
+)
+
+// Attribute keys added from the context
+const (
+	RequestIDKey = "request_id"
+	SessionKey   = "session"
+	CommandKey   = "command"
+)
+
+// Config selects the level and format of the log output.
+type Config struct {
+	Level  slog.Level
+	Format string // "text" or "json"
+}
+
+// ConfigFromEnv reads the configuration from GITGYM_LOG_LEVEL and
+// GITGYM_LOG_FORMAT. An invalid value is reported and left at its default.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Level: slog.LevelInfo, Format: "text"}
+	var errs []error
+	if v := os.Getenv(LevelEnv); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			cfg.Level = slog.LevelInfo
+			errs = append(errs, fmt.Errorf("invalid %s %q (want debug, info, warn or error)", LevelEnv, v))
+		}
+	}
+	switch v := strings.ToLower(os.Getenv(FormatEnv)); v {
+	case "", "text":
+	case "json":
+		cfg.Format = "json"
+	default:
+		errs = append(errs, fmt.Errorf("invalid %s %q (want text or json)", FormatEnv, v))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// Setup makes a logger writing to w the default one, for slog and for the
+// standard log package alike.
+func Setup(w io.Writer, cfg Config) *slog.Logger {
+	logger := New(w, cfg)
+	slog.SetDefault(logger)
+	// slog.SetDefault routes the log package through the handler at info level
+	log.SetFlags(0)
+	return logger
+}
+
+// New returns a logger writing to w that adds the context attributes and
+// keeps session logs.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // Handler filters by cfg.Level itself
+	var out slog.Handler
+	if cfg.Format == "json" {
+		out = slog.NewJSONHandler(w, opts)
+	} else {
+		out = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(&Handler{out: out, level: cfg.Level, sessions: sessionLogs})
+}
+
+type ctxKey int
+
+const (
+	requestIDCtxKey ctxKey = iota
+	sessionCtxKey
+	commandCtxKey
+)
+
+// WithRequestID returns a context whose records carry the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, id)
+}
+
+// WithSession returns a context whose records carry the session ID.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionCtxKey, id)
+}
+
+// WithCommand returns a context whose records carry the command name.
+func WithCommand(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, commandCtxKey, name)
+}
+
+// RequestID returns the request ID of the context, or "".
+func RequestID(ctx context.Context) string { return ctxString(ctx, requestIDCtxKey) }
+
+// Session returns the session ID of the context, or "".
+func Session(ctx context.Context) string { return ctxString(ctx, sessionCtxKey) }
+
+// Command returns the command name of the context, or "".
+func Command(ctx context.Context) string { return ctxString(ctx, commandCtxKey) }
+
+func ctxString(ctx context.Context, key ctxKey) string {
+	if ctx == nil {
+		return ""
+	}
+	s, _ := ctx.Value(key).(string)
+	return s
+}
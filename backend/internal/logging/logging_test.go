@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(LevelEnv, "")
+	t.Setenv(FormatEnv, "")
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{Level: slog.LevelInfo, Format: "text"}, cfg)
+
+	t.Setenv(LevelEnv, "debug")
+	t.Setenv(FormatEnv, "JSON")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{Level: slog.LevelDebug, Format: "json"}, cfg)
+
+	t.Setenv(LevelEnv, "loud")
+	t.Setenv(FormatEnv, "xml")
+	cfg, err = ConfigFromEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), LevelEnv)
+	assert.Contains(t, err.Error(), FormatEnv)
+	assert.Equal(t, Config{Level: slog.LevelInfo, Format: "text"}, cfg)
+}
+
+func newTestLogger(out *bytes.Buffer, level slog.Level) (*slog.Logger, *SessionLogs) {
+	logs := &SessionLogs{}
+	h := &Handler{out: slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}), level: level, sessions: logs}
+	return slog.New(h), logs
+}
+
+func decodeLines(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var recs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestHandlerAddsContext(t *testing.T) {
+	var out bytes.Buffer
+	logger, logs := newTestLogger(&out, slog.LevelInfo)
+
+	ctx := WithCommand(WithSession(WithRequestID(context.Background(), "req-1"), "s1"), "commit")
+	logger.InfoContext(ctx, "dispatched", "duration", "1ms")
+	logger.DebugContext(ctx, "dispatch", "args", "-m x")
+	logger.Info("no context")
+
+	recs := decodeLines(t, &out)
+	require.Len(t, recs, 2, "debug record is not written at info level")
+	assert.Equal(t, "req-1", recs[0][RequestIDKey])
+	assert.Equal(t, "s1", recs[0][SessionKey])
+	assert.Equal(t, "commit", recs[0][CommandKey])
+	assert.NotContains(t, recs[1], RequestIDKey)
+
+	// The session log keeps the debug record as well
+	entries := logs.Get("s1")
+	require.Len(t, entries, 2)
+	assert.Equal(t, "INFO", entries[0].Level)
+	assert.Equal(t, "DEBUG", entries[1].Level)
+	assert.Equal(t, "req-1", entries[1].RequestID)
+	assert.Equal(t, "commit", entries[1].Command)
+	assert.Equal(t, map[string]string{"args": "-m x"}, entries[1].Attrs)
+	assert.Equal(t, `dispatch request_id=req-1 command=commit args="-m x"`, strings.SplitN(entries[1].String(), " ", 3)[2])
+}
+
+func TestHandlerSessionFromAttrs(t *testing.T) {
+	var out bytes.Buffer
+	logger, logs := newTestLogger(&out, slog.LevelWarn)
+
+	logger.With(SessionKey, "s2").WithGroup("gc").Info("swept", "objects", 3)
+	logger.Warn("failed to persist session", SessionKey, "s3", "err", "disk full")
+
+	recs := decodeLines(t, &out)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "s3", recs[0][SessionKey], "session attribute is not duplicated")
+
+	entries := logs.Get("s2")
+	require.Len(t, entries, 1)
+	assert.Equal(t, map[string]string{"gc.objects": "3"}, entries[0].Attrs)
+	assert.Len(t, logs.Get("s3"), 1)
+	assert.Empty(t, logs.Get("unknown"))
+}
+
+func TestSessionLogsBounded(t *testing.T) {
+	logs := &SessionLogs{}
+	for i := 0; i < MaxSessionLogEntries+10; i++ {
+		logs.add("s", Entry{Message: fmt.Sprint(i)})
+	}
+	entries := logs.Get("s")
+	require.Len(t, entries, MaxSessionLogEntries)
+	assert.Equal(t, "10", entries[0].Message)
+	assert.Equal(t, fmt.Sprint(MaxSessionLogEntries+9), entries[len(entries)-1].Message)
+
+	for i := 0; i < maxLoggedSessions; i++ {
+		logs.add(fmt.Sprint("other-", i), Entry{Message: "x"})
+	}
+	assert.Empty(t, logs.Get("s"), "least recently written session is evicted")
+	assert.Len(t, logs.Get("other-0"), 1)
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session logs are bounded twice: each keeps its latest records, and only the
+// sessions that logged most recently are kept.
+const (
+	MaxSessionLogEntries = 500
+	maxLoggedSessions    = 1000
+)
+
+// Entry is one record of a session log.
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Message   string            `json:"msg"`
+	RequestID string            `json:"requestId,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+// String formats the entry as one line, attributes sorted by key.
+func (e Entry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s", e.Time.Format(time.RFC3339Nano), e.Level, e.Message)
+	if e.RequestID != "" {
+		fmt.Fprintf(&sb, " %s=%s", RequestIDKey, e.RequestID)
+	}
+	if e.Command != "" {
+		fmt.Fprintf(&sb, " %s=%s", CommandKey, e.Command)
+	}
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%q", k, e.Attrs[k])
+	}
+	return sb.String()
+}
+
+// SessionLogs keeps the latest records of each session. The zero value is ready to use.
+type SessionLogs struct {
+	mu   sync.Mutex
+	logs map[string]*sessionLog
+}
+
+type sessionLog struct {
+	entries []Entry // Ring buffer of up to MaxSessionLogEntries
+	next    int     // Where the next entry goes once the buffer is full
+	last    time.Time
+}
+
+// sessionLogs collects the records of the loggers made by New.
+var sessionLogs = &SessionLogs{}
+
+func (l *SessionLogs) add(session string, e Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.logs == nil {
+		l.logs = make(map[string]*sessionLog)
+	}
+	log, ok := l.logs[session]
+	if !ok {
+		if len(l.logs) >= maxLoggedSessions {
+			l.evictOldest()
+		}
+		log = &sessionLog{}
+		l.logs[session] = log
+	}
+	log.last = time.Now()
+	if len(log.entries) < MaxSessionLogEntries {
+		log.entries = append(log.entries, e)
+		return
+	}
+	log.entries[log.next] = e
+	log.next = (log.next + 1) % MaxSessionLogEntries
+}
+
+// evictOldest drops the session that logged least recently. Caller holds l.mu.
+func (l *SessionLogs) evictOldest() {
+	var oldest string
+	var at time.Time
+	for id, log := range l.logs {
+		if oldest == "" || log.last.Before(at) {
+			oldest, at = id, log.last
+		}
+	}
+	delete(l.logs, oldest)
+}
+
+// Get returns the kept records of a session, oldest first.
+func (l *SessionLogs) Get(session string) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	log, ok := l.logs[session]
+	if !ok {
+		return []Entry{}
+	}
+	entries := make([]Entry, 0, len(log.entries))
+	entries = append(entries, log.entries[log.next:]...)
+	return append(entries, log.entries[:log.next]...)
+}
+
+// SessionLog returns the kept records of a session, oldest first.
+func SessionLog(session string) []Entry {
+	return sessionLogs.Get(session)
+}
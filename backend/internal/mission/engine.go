@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/shell"
 	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
@@ -26,8 +31,13 @@ func NewEngine(loader *Loader, manager *state.SessionManager) *Engine {
 	}
 }
 
-// StartMission initializes a temporary session for the mission.
-func (e *Engine) StartMission(ctx context.Context, missionID string) (string, error) {
+// missionStartSavePoint names the save point of the state a mission starts from.
+const missionStartSavePoint = "start"
+
+// StartMission initializes a temporary session for the mission. With a
+// learner the session belongs to the learner's session, so each learner gets
+// their own; without one it is shared.
+func (e *Engine) StartMission(ctx context.Context, learner, missionID string) (string, error) {
 	m, err := e.Loader.LoadMission(missionID)
 	if err != nil {
 		return "", err
@@ -41,6 +51,9 @@ func (e *Engine) StartMission(ctx context.Context, missionID string) (string, er
 	// We'll generate a random suffix or use a fixed one if debugging.
 	// Let's use "mission-<missionID>" for now.
 	sessionID := fmt.Sprintf("mission-%s", missionID)
+	if learner != "" {
+		sessionID = state.OwnedSessionID(learner, sessionID)
+	}
 	// Force recreate
 	// Note: SessionManager.CreateSession reuses if exists. We want a fresh state.
 	// We should probably remove it first if it exists?
@@ -68,13 +81,67 @@ func (e *Engine) StartMission(ctx context.Context, missionID string) (string, er
 	if err := e.cleanWorkspace(sess); err != nil {
 		return "", fmt.Errorf("failed to clean workspace: %w", err)
 	}
+	// The trace of the session starts over with the mission's setup, which
+	// runs unrestricted: the policy only applies to the learner
+	sess.Lock()
+	sess.ClearTrace()
+	sess.CommandPolicy = nil
+	sess.Unlock()
 	// Re-create root if needed? MemFS handles it.
+	// 2. Run Setup Commands
+	if _, err := e.runSetup(ctx, sess, m); err != nil {
+		return "", err
+	}
+	// The setup is the starting point: gitgym undo must not take it apart,
+	// the timeline starts from it, and the "start" save point goes back to it
+	sess.Lock()
+	sess.ClearUndoHistory()
+	sess.ClearTimeline()
+	sess.ClearSavePoints()
+	_, err = sess.CreateSavePoint(missionStartSavePoint)
+	sess.CommandPolicy = m.Policy
+	sess.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to save the mission start: %w", err)
+	}
+
+	// Do NOT Reset Reflog here, so user can see what happened during setup (e.g. init, commit)
+	// sess.Reflog = nil
+
+	return sessionID, nil
+}
+
+// runSetup writes the fixtures of a mission in a fresh session directory,
+// installs its repository fixture and runs its setup commands.
+// On failure it returns the index of the failing command.
+func (e *Engine) runSetup(ctx context.Context, sess *state.Session, m *Mission) (int, error) {
 	// We use /project as the default directory to avoid "cannot init repo at root" errors
 	_ = sess.Filesystem.MkdirAll("/project", 0755)
 	sess.CurrentDir = "/project"
 
-	// 2. Run Setup Commands
-	for _, cmdStr := range m.Setup {
+	names := make([]string, 0, len(m.fixtureData))
+	for name := range m.fixtureData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := util.WriteFile(sess.Filesystem, path.Join("/project", name), m.fixtureData[name], 0644); err != nil {
+			return 0, fmt.Errorf("setup failed to write fixture '%s': %w", name, err)
+		}
+	}
+
+	if m.repository != nil {
+		dir := path.Join("/project", m.Repository.Path)
+		sess.Lock()
+		_, err := sess.InstallRepository(dir, m.repository)
+		sess.Unlock()
+		if err != nil {
+			return 0, fmt.Errorf("setup failed to install repository fixture '%s': %w", m.Repository.Fixture, err)
+		}
+		sess.CurrentDir = dir
+	}
+
+	for i, cmdStr := range m.Setup {
 		ignoreError := false
 		if strings.HasPrefix(cmdStr, "!") {
 			ignoreError = true
@@ -84,16 +151,12 @@ func (e *Engine) StartMission(ctx context.Context, missionID string) (string, er
 
 		if err := e.runCommand(ctx, sess, cmdStr); err != nil {
 			if !ignoreError {
-				return "", fmt.Errorf("setup failed at '%s': %w", cmdStr, err)
+				return i, fmt.Errorf("setup failed at '%s': %w", cmdStr, err)
 			}
 			// Log checking?
 		}
 	}
-
-	// Do NOT Reset Reflog here, so user can see what happened during setup (e.g. init, commit)
-	// sess.Reflog = nil
-
-	return sessionID, nil
+	return 0, nil
 }
 
 // cleanWorkspace removes all files and directories in the root of the session filesystem
@@ -117,129 +180,26 @@ func (e *Engine) cleanWorkspace(sess *state.Session) error {
 	return nil
 }
 
-// runCommand handles git commands and basic shell simulation (echo, mkdir, cd, redirection)
+// runCommand runs a setup line through the shell interpreter, so missions can
+// use the same quoting, chaining and redirection as the terminal.
 func (e *Engine) runCommand(ctx context.Context, session *state.Session, cmdStr string) error {
-	cmdStr = strings.TrimSpace(cmdStr)
-	if cmdStr == "" {
-		return nil
-	}
-
-	// 1. Handle "mkdir"
-	if strings.HasPrefix(cmdStr, "mkdir ") {
-		dirName := strings.TrimSpace(strings.TrimPrefix(cmdStr, "mkdir "))
-		dirName = strings.Trim(dirName, "\"'")
-		return session.Filesystem.MkdirAll(dirName, 0755)
-	}
-
-	// 2. Handle "cd"
-	if strings.HasPrefix(cmdStr, "cd ") {
-		dirName := strings.TrimSpace(strings.TrimPrefix(cmdStr, "cd "))
-		dirName = strings.Trim(dirName, "\"'")
-		// Update session's current directory
-		if dirName == "/" {
-			session.CurrentDir = "/"
-		} else if strings.HasPrefix(dirName, "/") {
-			session.CurrentDir = dirName
-		} else {
-			if session.CurrentDir == "/" {
-				session.CurrentDir = "/" + dirName
-			} else {
-				session.CurrentDir = session.CurrentDir + "/" + dirName
-			}
-		}
-		return nil
-	}
-
-	// 3. Handle "echo" with redirection
-	if strings.HasPrefix(cmdStr, "echo ") {
-		parts := strings.SplitN(cmdStr, ">", 2)
-		if len(parts) == 2 {
-			content := strings.Trim(parts[0], " \"'") // simplistic trim
-			content = strings.TrimPrefix(content, "echo ")
-			content = strings.Trim(content, "\"'")
-
-			// Handle ">>" vs ">"
-			appendMode := false
-			targetFile := strings.TrimSpace(parts[1])
-			if strings.HasPrefix(targetFile, ">") {
-				appendMode = true
-				targetFile = strings.TrimSpace(strings.TrimPrefix(targetFile, ">"))
-			}
-
-			// Resolve path relative to CurrentDir
-			if !strings.HasPrefix(targetFile, "/") {
-				if session.CurrentDir == "/" {
-					targetFile = "/" + targetFile
-				} else {
-					targetFile = session.CurrentDir + "/" + targetFile
-				}
-			}
-
-			// Write to file
-			// We can't use os constants directly with billy sometimes depending on impl,
-			// but usually they map.
-			// Simpler: Use util helper or just WriteFile if overwrite.
-
-			if !appendMode {
-				// Overwrite
-				f, err := session.Filesystem.Create(targetFile)
-				if err != nil {
-					return err
-				}
-				_, err = f.Write([]byte(content + "\n"))
-				_ = f.Close()
-				return err
-			} else {
-				// Append
-
-				// Read existing content safely
-				var builder strings.Builder
-				f, err := session.Filesystem.OpenFile(targetFile, os.O_RDWR|os.O_CREATE, 0644)
-				if err == nil {
-					buf := make([]byte, 1024)
-					for {
-						n, readErr := f.Read(buf)
-						if n > 0 {
-							builder.Write(buf[:n])
-						}
-						if readErr == io.EOF {
-							break
-						}
-						if readErr != nil {
-							// Handle other errors or just break?
-							break
-						}
-					}
-					f.Close()
-				}
-				builder.WriteString(content + "\n")
-
-				// Re-open/Create to overwrite with full content (MemFS might not support efficient append seek)
-				f, err = session.Filesystem.Create(targetFile)
-				if err != nil {
-					return err
-				}
-				_, err = f.Write([]byte(builder.String()))
-				_ = f.Close()
-				return err
-			}
-		}
-	}
-
-	// 2. Handle Git Commands
-	name, args := git.ParseCommand(cmdStr)
-	if name == "" {
-		return nil // empty line
-	}
-
-	// Use git.Dispatch
-	_, err := git.Dispatch(ctx, (*git.Session)(session), name, args)
+	_, err := shell.Run(ctx, (*git.Session)(session), cmdStr)
 	return err
 }
 
 type VerificationResult struct {
-	Success   bool          `json:"success"`
-	MissionID string        `json:"missionId"`
+	Success     bool          `json:"success"`
+	MissionID   string        `json:"missionId"`
+	Progress    []CheckResult `json:"progress"`        // Every check: those of the steps first, then the validation checks
+	Steps       []StepResult  `json:"steps,omitempty"` // One entry per declared step
+	CurrentStep int           `json:"currentStep"`     // Index of the first unfinished step, TotalSteps when all are done
+	TotalSteps  int           `json:"totalSteps"`
+}
+
+// StepResult is the outcome of one mission step.
+type StepResult struct {
+	Title     string        `json:"title"`
+	Completed bool          `json:"completed"`
 	Progress  []CheckResult `json:"progress"`
 }
 
@@ -262,156 +222,357 @@ func (e *Engine) VerifyMission(sessionID string, missionID string) (*Verificatio
 	sess.RLock() // Read lock
 	defer sess.RUnlock()
 
+	return verify(sess, m), nil
+}
+
+// verify runs the checks of every step, then the mission's own validation checks.
+func verify(sess *state.Session, m *Mission) *VerificationResult {
+	result := &VerificationResult{MissionID: m.ID, TotalSteps: len(m.Steps), Progress: []CheckResult{}}
+
 	repo := sess.GetRepo() // Assuming root repo
 	if repo == nil {
 		// If setup failed or no repo, fail all
-		return &VerificationResult{Success: false, MissionID: missionID}, nil
+		return result
 	}
 
-	var results []CheckResult
 	allPassed := true
-
-	for _, check := range m.Validation.Checks {
-		passed := false
-		switch check.Type {
-		case "no_conflict":
-			// Check status
-			w, _ := repo.Worktree()
-			status, _ := w.Status()
-			passed = true
-			for _, s := range status {
-				if s.Staging == 'U' || s.Worktree == 'U' {
-					passed = false
-					break
-				}
+	run := func(checks []Check) ([]CheckResult, bool) {
+		results := make([]CheckResult, 0, len(checks))
+		stepPassed := true
+		for _, check := range checks {
+			passed := evaluateCheck(sess, repo, check)
+			results = append(results, CheckResult{Description: check.Description, Passed: passed})
+			if !passed {
+				stepPassed = false
 			}
+		}
+		result.Progress = append(result.Progress, results...)
+		if !stepPassed {
+			allPassed = false
+		}
+		return results, stepPassed
+	}
 
-		case "commit_exists":
-			// Search log for commits. If MessagePattern is empty, just check if any commit exists.
-			iter, iterErr := repo.Log(&gogit.LogOptions{})
-			if iterErr == nil {
-				_ = iter.ForEach(func(c *object.Commit) error {
-					if check.MessagePattern == "" {
-						// Any commit passes
-						passed = true
-					} else if strings.Contains(c.Message, check.MessagePattern) {
-						passed = true
-					}
-					return nil
-				})
-			}
+	// A step only counts once every step before it is done
+	previousDone := true
+	for _, step := range m.Steps {
+		progress, passed := run(step.Checks)
+		done := previousDone && passed
+		result.Steps = append(result.Steps, StepResult{Title: step.Title, Completed: done, Progress: progress})
+		if done {
+			result.CurrentStep++
+		}
+		previousDone = done
+	}
+	run(m.Validation.Checks)
 
-		case "file_content":
-			// Resolve path relative to CurrentDir
-			targetPath := check.Path
-			if !strings.HasPrefix(targetPath, "/") {
-				// Avoid double slash if CurrentDir is "/"
-				if sess.CurrentDir == "/" {
-					targetPath = "/" + targetPath
-				} else {
-					targetPath = sess.CurrentDir + "/" + targetPath
-				}
+	result.Success = allPassed && result.CurrentStep == len(m.Steps)
+	return result
+}
+
+// evaluateCheck reports whether a single validation check passes.
+func evaluateCheck(sess *state.Session, repo *gogit.Repository, check Check) bool {
+	passed := false
+	switch check.Type {
+	case "no_conflict":
+		// Check status
+		w, _ := repo.Worktree()
+		status, _ := w.Status()
+		// A merge stopped on conflicts is unfinished until it is committed
+		passed = sess.MergeInProgress() == nil
+		for _, s := range status {
+			if s.Staging == 'U' || s.Worktree == 'U' {
+				passed = false
+				break
 			}
+		}
 
-			// Check file content
-			f, err := sess.Filesystem.Open(targetPath)
-			if err == nil {
-				// Read content
-				contentBytes, readErr := io.ReadAll(f)
-				if readErr != nil {
-					// Handle read error
-				}
-				content := string(contentBytes)
-				f.Close()
-
-				matchAll := true
-				for _, substr := range check.Contains {
-					if !strings.Contains(content, substr) {
-						matchAll = false
-						break
-					}
+	case "commit_exists":
+		// Search log for commits. If MessagePattern is empty, just check if any commit exists.
+		iter, iterErr := repo.Log(&gogit.LogOptions{})
+		if iterErr == nil {
+			_ = iter.ForEach(func(c *object.Commit) error {
+				if check.MessagePattern == "" {
+					// Any commit passes
+					passed = true
+				} else if strings.Contains(c.Message, check.MessagePattern) {
+					passed = true
 				}
-				passed = matchAll
+				return nil
+			})
+		}
+
+	case "file_content":
+		// Check file content
+		f, err := sess.Filesystem.Open(sessionPath(sess, check.Path))
+		if err == nil {
+			// Read content
+			contentBytes, readErr := io.ReadAll(f)
+			if readErr != nil {
+				// Handle read error
 			}
+			content := string(contentBytes)
+			f.Close()
 
-		case "file_tracked":
-			// Check if a file is tracked by git (exists in HEAD commit)
-			// A file is "tracked" if it's in the HEAD commit's tree
-			headRef, hErr := repo.Head()
-			if hErr == nil {
-				commit, cErr := repo.CommitObject(headRef.Hash())
-				if cErr == nil {
-					tree, tErr := commit.Tree()
-					if tErr == nil {
-						_, fErr := tree.File(check.Path)
-						passed = (fErr == nil)
-					}
+			matchAll := true
+			for _, substr := range check.Contains {
+				if !strings.Contains(content, substr) {
+					matchAll = false
+					break
 				}
 			}
+			passed = matchAll
+		}
 
-		case "clean_working_tree":
-			// Check if working tree is clean (no unstaged or uncommitted changes)
-			w, wErr := repo.Worktree()
-			if wErr == nil {
-				status, sErr := w.Status()
-				if sErr == nil {
-					passed = status.IsClean()
+	case "file_tracked":
+		// Check if a file is tracked by git (exists in HEAD commit)
+		// A file is "tracked" if it's in the HEAD commit's tree
+		headRef, hErr := repo.Head()
+		if hErr == nil {
+			commit, cErr := repo.CommitObject(headRef.Hash())
+			if cErr == nil {
+				tree, tErr := commit.Tree()
+				if tErr == nil {
+					_, fErr := tree.File(check.Path)
+					passed = (fErr == nil)
 				}
 			}
+		}
 
-		case "branch_exists":
-			// Check if a branch with the given name exists
-			refs, rErr := repo.References()
-			if rErr == nil {
-				_ = refs.ForEach(func(ref *plumbing.Reference) error {
-					if ref.Name().IsBranch() && ref.Name().Short() == check.Name {
-						passed = true
-					}
-					return nil
-				})
+	case "file_absent":
+		// Check that the file is gone from the working tree (tracked or not)
+		_, sErr := sess.Filesystem.Lstat(sessionPath(sess, check.Path))
+		passed = os.IsNotExist(sErr)
+
+	case "clean_working_tree":
+		// Check if working tree is clean (no unstaged or uncommitted changes)
+		w, wErr := repo.Worktree()
+		if wErr == nil {
+			status, sErr := w.Status()
+			if sErr == nil {
+				passed = status.IsClean()
 			}
+		}
 
-		case "current_branch":
-			// Check if current HEAD is on the specified branch
-			headRef, hErr := repo.Head()
-			if hErr == nil && headRef.Name().IsBranch() {
-				passed = headRef.Name().Short() == check.Name
-			}
+	case "branch_exists":
+		// Check if a branch with the given name exists
+		refs, rErr := repo.References()
+		if rErr == nil {
+			_ = refs.ForEach(func(ref *plumbing.Reference) error {
+				if ref.Name().IsBranch() && ref.Name().Short() == check.Name {
+					passed = true
+				}
+				return nil
+			})
+		}
+
+	case "remote_branch_exists":
+		// Check that a remote-tracking branch ("origin/feature") exists
+		_, rErr := repo.Reference(plumbing.ReferenceName("refs/remotes/"+check.Name), false)
+		passed = rErr == nil
+
+	case "tag_exists":
+		// Check that a tag (lightweight or annotated) with the given name exists
+		_, tErr := repo.Tag(check.Name)
+		passed = tErr == nil
+
+	case "current_branch":
+		// Check if current HEAD is on the specified branch
+		headRef, hErr := repo.Head()
+		if hErr == nil && headRef.Name().IsBranch() {
+			passed = headRef.Name().Short() == check.Name
+		}
 
-		case "head_commit_message":
-			// Check if HEAD commit message matches the pattern
-			headRef, hErr := repo.Head()
-			if hErr == nil {
-				commit, cErr := repo.CommitObject(headRef.Hash())
-				if cErr == nil {
-					if check.MessagePattern == "" {
-						passed = true
-					} else {
-						// Simple contains check, or exact match? 'pattern' usually implies contains/regex.
-						// Using strings.Contains like commit_exists
-						passed = strings.Contains(commit.Message, check.MessagePattern)
-					}
+	case "head_detached":
+		// Check that HEAD points at a commit instead of a branch
+		headRef, hErr := repo.Reference(plumbing.HEAD, false)
+		passed = hErr == nil && headRef.Type() == plumbing.HashReference
+
+	case "head_commit_message":
+		// Check if HEAD commit message matches the pattern
+		headRef, hErr := repo.Head()
+		if hErr == nil {
+			commit, cErr := repo.CommitObject(headRef.Hash())
+			if cErr == nil {
+				if check.MessagePattern == "" {
+					passed = true
+				} else {
+					// Simple contains check, or exact match? 'pattern' usually implies contains/regex.
+					// Using strings.Contains like commit_exists
+					passed = strings.Contains(commit.Message, check.MessagePattern)
 				}
 			}
 		}
 
-		// Handle Negation
-		if check.Negate {
-			passed = !passed
+	case "remote_url":
+		// Check that a remote (default origin) points at the expected URL
+		if remote, rErr := repo.Remote(remoteNameOrDefault(check.Name)); rErr == nil {
+			urls := remote.Config().URLs
+			passed = len(urls) > 0 && urls[0] == check.URL
 		}
 
-		results = append(results, CheckResult{
-			Description: check.Description,
-			Passed:      passed,
-		})
-		if !passed {
-			allPassed = false
+	case "contains_commit":
+		// Check that the commit is HEAD or one of its ancestors
+		headRef, hErr := repo.Head()
+		if hErr == nil {
+			head, cErr := repo.CommitObject(headRef.Hash())
+			target, tErr := repo.CommitObject(plumbing.NewHash(check.Commit))
+			if cErr == nil && tErr == nil {
+				passed, _ = target.IsAncestor(head)
+			}
+		}
+
+	case "ancestor_of":
+		// Check that Commit is reachable from Ref (default HEAD), e.g. that a rebase kept main's tip
+		ancestor, aErr := resolveCheckCommit(repo, check.Commit)
+		descendant, dErr := resolveCheckCommit(repo, check.Ref)
+		if aErr == nil && dErr == nil {
+			passed, _ = ancestor.IsAncestor(descendant)
+			passed = passed || ancestor.Hash == descendant.Hash
+		}
+
+	case "commit_count":
+		// Check the number of commits reachable from Ref (default HEAD), e.g. after squashing
+		if count, cErr := countCommits(repo, check.Ref); cErr == nil {
+			passed = count == check.Count
+		}
+
+	case "linear_history":
+		// Check that no merge commit is reachable from Ref (default HEAD)
+		if merges, mErr := hasMergeCommits(repo, check.Ref); mErr == nil {
+			passed = !merges
+		}
+
+	case "stash_empty":
+		// Check that no stash entries are left
+		_, sErr := repo.Reference(plumbing.ReferenceName("refs/stash"), false)
+		passed = sErr != nil
+
+	case "refs_mirrored":
+		// Check that every local branch and tag exists on the remote with the same hash
+		if target := resolveRemoteRepo(sess, repo, remoteNameOrDefault(check.Name)); target != nil {
+			passed = refsMirrored(repo, target)
+		}
+
+	case "issue_closed":
+		// Check that an issue of the remote was closed, e.g. by pushing a "Fixes #1" commit
+		if target := resolveRemoteRepo(sess, repo, remoteNameOrDefault(check.Name)); target != nil && sess.Manager != nil {
+			passed = sess.Manager.IssueClosed(target, check.Issue)
+		}
+	}
+
+	// Handle Negation
+	if check.Negate {
+		passed = !passed
+	}
+	return passed
+}
+
+// sessionPath resolves a mission path relative to the session's current directory.
+func sessionPath(sess *state.Session, path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	// Avoid double slash if CurrentDir is "/"
+	if sess.CurrentDir == "/" {
+		return "/" + path
+	}
+	return sess.CurrentDir + "/" + path
+}
+
+// resolveCheckCommit resolves a revision of a check, defaulting to HEAD.
+func resolveCheckCommit(repo *gogit.Repository, rev string) (*object.Commit, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := git.ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(*hash)
+}
+
+// countCommits counts the commits reachable from rev (default HEAD).
+func countCommits(repo *gogit.Repository, rev string) (int, error) {
+	start, err := resolveCheckCommit(repo, rev)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = object.NewCommitPreorderIter(start, nil, nil).ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// hasMergeCommits reports whether any commit reachable from rev (default HEAD) has several parents.
+func hasMergeCommits(repo *gogit.Repository, rev string) (bool, error) {
+	start, err := resolveCheckCommit(repo, rev)
+	if err != nil {
+		return false, err
+	}
+	merges := false
+	err = object.NewCommitPreorderIter(start, nil, nil).ForEach(func(c *object.Commit) error {
+		if c.NumParents() > 1 {
+			merges = true
+			return storer.ErrStop
 		}
+		return nil
+	})
+	return merges, err
+}
+
+func remoteNameOrDefault(name string) string {
+	if name == "" {
+		return "origin"
 	}
+	return name
+}
 
-	return &VerificationResult{
-		Success:   allPassed,
-		MissionID: missionID,
-		Progress:  results,
-	}, nil
+// resolveRemoteRepo finds the simulated repository behind a remote's URL,
+// looking in the session's own repos first and then the shared remotes.
+func resolveRemoteRepo(sess *state.Session, repo *gogit.Repository, remoteName string) *gogit.Repository {
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil
+	}
+	url := remote.Config().URLs[0]
+	key := strings.TrimPrefix(url, "/")
+
+	if r, ok := sess.Repos[key]; ok {
+		return r
+	}
+	if sess.Manager == nil {
+		return nil
+	}
+	sess.Manager.RLock()
+	defer sess.Manager.RUnlock()
+	if r, ok := sess.Manager.SharedRemotes[key]; ok {
+		return r
+	}
+	return sess.Manager.SharedRemotes[url]
+}
+
+// refsMirrored reports whether every local branch and tag exists on target at the same hash.
+func refsMirrored(repo, target *gogit.Repository) bool {
+	refs, err := repo.References()
+	if err != nil {
+		return false
+	}
+	checked := 0
+	mirrored := true
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !(ref.Name().IsBranch() || ref.Name().IsTag()) {
+			return nil
+		}
+		checked++
+		remoteRef, rErr := target.Reference(ref.Name(), false)
+		if rErr != nil || remoteRef.Hash() != ref.Hash() {
+			mirrored = false
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return mirrored && checked > 0
 }
@@ -0,0 +1,358 @@
+package mission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const refChecksMission = `id: "ref-checks"
+title: "Ref checks"
+setup:
+  - "git init"
+  - "echo 'one' > a.txt"
+  - "git add a.txt"
+  - "git commit -m 'First'"
+  - "git tag v1"
+  - "echo 'two' > b.txt"
+  - "git add b.txt"
+  - "git commit -m 'Second'"
+validation:
+  checks:
+    - type: "tag_exists"
+      name: "v1"
+      description: "tag"
+    - type: "remote_branch_exists"
+      name: "origin/main"
+      description: "remote branch"
+    - type: "commit_count"
+      count: 2
+      description: "count"
+    - type: "linear_history"
+      description: "linear"
+    - type: "head_detached"
+      description: "detached"
+    - type: "ancestor_of"
+      commit: "v1"
+      ref: "main"
+      description: "ancestor"
+    - type: "stash_empty"
+      description: "stash"
+    - type: "file_absent"
+      path: "b.txt"
+      description: "absent"
+`
+
+func TestVerifyMission_RefAndGraphChecks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ref-checks.yaml"), []byte(refChecksMission), 0644))
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader(dir), sm)
+
+	ctx := context.Background()
+	sessionID, err := e.StartMission(ctx, "", "ref-checks")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+
+	verify := func() map[string]bool {
+		result, err := e.VerifyMission(sessionID, "ref-checks")
+		require.NoError(t, err)
+		passed := make(map[string]bool)
+		for _, p := range result.Progress {
+			passed[p.Description] = p.Passed
+		}
+		return passed
+	}
+	run := func(cmd string) {
+		name, args := git.ParseCommand(cmd)
+		_, err := git.Dispatch(ctx, (*git.Session)(sess), name, args)
+		require.NoError(t, err, cmd)
+	}
+
+	assert.Equal(t, map[string]bool{
+		"tag": true, "remote branch": false, "count": true, "linear": true,
+		"detached": false, "ancestor": true, "stash": true, "absent": false,
+	}, verify())
+
+	// A merge commit breaks linear history and changes the count
+	run("git switch -c side")
+	require.NoError(t, util.WriteFile(sess.Filesystem, sessionPath(sess, "c.txt"), []byte("three\n"), 0644))
+	run("git add c.txt")
+	run("git commit -m Third")
+	run("git switch main")
+	run("git merge --no-ff side -m Merge")
+	passed := verify()
+	assert.False(t, passed["linear"])
+	assert.False(t, passed["count"])
+
+	// Detaching HEAD, stashing and deleting a file
+	run("git checkout v1")
+	require.NoError(t, util.WriteFile(sess.Filesystem, sessionPath(sess, "a.txt"), []byte("changed\n"), 0644))
+	run("git stash")
+	passed = verify()
+	assert.True(t, passed["detached"])
+	assert.False(t, passed["stash"])
+	assert.True(t, passed["absent"], "b.txt does not exist at v1")
+	assert.True(t, passed["linear"], "v1 has no merges")
+}
+
+func TestConflictMission_StepsAndHints(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "001-conflict-crisis")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+
+	result, err := e.VerifyMission(sessionID, "001-conflict-crisis")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, 0, result.CurrentStep)
+	assert.Equal(t, 2, result.TotalSteps)
+	require.Len(t, result.Steps, 2)
+	assert.False(t, result.Steps[0].Completed, "conflict markers are still in README.md")
+
+	hint, err := e.NextHint(sessionID, "001-conflict-crisis", "en", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "step", hint.Source)
+	assert.Equal(t, "Resolve the conflict in README.md", hint.StepTitle)
+
+	hint, err = e.NextHint(sessionID, "001-conflict-crisis", "ja", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "README.md のコンフリクトを解決する", hint.StepTitle)
+
+	require.NoError(t, util.WriteFile(sess.Filesystem, sessionPath(sess, "README.md"), []byte("Version 1.0 - Feature Update + Hotfix\n"), 0644))
+	result, err = e.VerifyMission(sessionID, "001-conflict-crisis")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.CurrentStep)
+	assert.True(t, result.Steps[0].Completed)
+
+	hint, err = e.NextHint(sessionID, "001-conflict-crisis", "en", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Complete the merge", hint.StepTitle)
+	assert.Contains(t, hint.Hint, "git add README.md")
+
+	for _, cmd := range []string{"git add README.md", "git commit -m Merge"} {
+		name, args := git.ParseCommand(cmd)
+		_, err := git.Dispatch(ctx, (*git.Session)(sess), name, args)
+		require.NoError(t, err, cmd)
+	}
+	result, err = e.VerifyMission(sessionID, "001-conflict-crisis")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 2, result.CurrentStep)
+
+	hint, err = e.NextHint(sessionID, "001-conflict-crisis", "en", 0)
+	require.NoError(t, err)
+	assert.True(t, hint.Completed)
+	assert.Empty(t, hint.Hint)
+}
+
+func TestNextHint_CheckHintsAndMissionHints(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hinted.yaml"), []byte(`id: "hinted"
+setup:
+  - "git init"
+validation:
+  checks:
+    - type: "branch_exists"
+      name: "feature"
+      description: "feature branch"
+      hint: "Create it with git branch feature."
+    - type: "tag_exists"
+      name: "v1"
+      description: "tag"
+hints:
+  - "First general hint"
+  - "Second general hint"
+`), 0644))
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader(dir), sm)
+	sessionID, err := e.StartMission(context.Background(), "", "hinted")
+	require.NoError(t, err)
+
+	hint, err := e.NextHint(sessionID, "hinted", "en", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "check", hint.Source)
+	assert.Equal(t, "Create it with git branch feature.", hint.Hint)
+
+	// Without a check hint, the general hints are revealed one by one
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hinted.yaml"), []byte(`id: "hinted"
+validation:
+  checks:
+    - type: "tag_exists"
+      name: "v1"
+      description: "tag"
+hints:
+  - "First general hint"
+  - "Second general hint"
+`), 0644))
+	for used, want := range []string{"First general hint", "Second general hint", "Second general hint"} {
+		hint, err := e.NextHint(sessionID, "hinted", "en", used)
+		require.NoError(t, err)
+		assert.Equal(t, "mission", hint.Source)
+		assert.Equal(t, want, hint.Hint)
+	}
+}
+
+func TestConventionalCommitsMission_LintsCommitMessages(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "105-conventional-commits")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+
+	name, args := git.ParseCommand("git commit -m 'Add login form'")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.Error(t, err, "setup enables commit.lint")
+	assert.Contains(t, err.Error(), "Conventional Commits")
+
+	name, args = git.ParseCommand("git commit -m 'feat(auth): add login form'")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.NoError(t, err)
+
+	result, err := e.VerifyMission(sessionID, "105-conventional-commits")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+}
+
+func TestRevertMission_PolicyBlocksReset(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "203-revert-commit")
+	require.NoError(t, err, "setup is not restricted by the policy")
+	sess, _ := sm.GetSession(sessionID)
+
+	name, args := git.ParseCommand("git reset --hard HEAD~1")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'git reset' is not available in this mission.")
+
+	name, args = git.ParseCommand("git revert --no-edit HEAD")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.NoError(t, err)
+
+	result, err := e.VerifyMission(sessionID, "203-revert-commit")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+}
+
+func TestRebaseMission_SavesBeforeRebase(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "302-rebase-basic")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+	require.Len(t, sess.SavePoints(), 1)
+	assert.Equal(t, "start", sess.SavePoints()[0].Name)
+
+	name, args := git.ParseCommand("git rebase main")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.NoError(t, err)
+	result, err := e.VerifyMission(sessionID, "302-rebase-basic")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	points := sess.SavePoints()
+	require.Len(t, points, 2, "the policy took a save point before the rebase")
+	assert.True(t, points[1].Auto)
+	assert.Equal(t, "rebase main", points[1].Command)
+
+	name, args = git.ParseCommand("gitgym restore start")
+	_, err = git.Dispatch(ctx, (*git.Session)(sess), name, args)
+	require.NoError(t, err)
+	result, err = e.VerifyMission(sessionID, "302-rebase-basic")
+	require.NoError(t, err)
+	assert.False(t, result.Success, "back at the start, the rebase is still to do")
+
+	// Starting over forgets the save points of the last attempt
+	_, err = e.StartMission(ctx, "", "302-rebase-basic")
+	require.NoError(t, err)
+	assert.Len(t, sess.SavePoints(), 1)
+}
+
+func TestBundleMission_ClonesPrebuiltHistory(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "405-bundle-handoff")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+
+	result, err := e.VerifyMission(sessionID, "405-bundle-handoff")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	for _, cmd := range []string{"git merge origin/feature/export -m 'Merge feature/export'", "git bundle create reply.bundle main", "git bundle verify reply.bundle"} {
+		name, args := git.ParseCommand(cmd)
+		_, err := git.Dispatch(ctx, (*git.Session)(sess), name, args)
+		require.NoError(t, err, cmd)
+	}
+	result, err = e.VerifyMission(sessionID, "405-bundle-handoff")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+}
+
+func TestRepositoryFixture_StartsFromIdenticalHistory(t *testing.T) {
+	sm := state.NewSessionManager()
+	e := NewEngine(NewLoader("../../missions"), sm)
+	ctx := context.Background()
+
+	sessionID, err := e.StartMission(ctx, "", "306-revert-regression")
+	require.NoError(t, err)
+	sess, _ := sm.GetSession(sessionID)
+	assert.Equal(t, "/project", sess.CurrentDir)
+
+	repo := sess.GetRepo()
+	require.NotNil(t, repo)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+	assert.Equal(t, "4ebe2c90fd78378b78093d866f2b473cb5d4c9d7", head.Hash().String())
+	tag, err := repo.Tag("v1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "f4df4a851436e4bd3cf25585beb0a8095bc65231", tag.Hash().String())
+
+	// The fixture is checked out and clean
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	status, err := w.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status.String())
+
+	result, err := e.VerifyMission(sessionID, "306-revert-regression")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	for _, cmd := range []string{"git blame sync.conf", "git diff v1.0 -- sync.conf", "git revert 20b2abd"} {
+		name, args := git.ParseCommand(cmd)
+		_, err := git.Dispatch(ctx, (*git.Session)(sess), name, args)
+		require.NoError(t, err, cmd)
+	}
+	result, err = e.VerifyMission(sessionID, "306-revert-regression")
+	require.NoError(t, err)
+	assert.True(t, result.Success, "%+v", result.Progress)
+
+	// A restart installs the same history again
+	sessionID, err = e.StartMission(ctx, "", "306-revert-regression")
+	require.NoError(t, err)
+	sess, _ = sm.GetSession(sessionID)
+	head, err = sess.GetRepo().Head()
+	require.NoError(t, err)
+	assert.Equal(t, "4ebe2c90fd78378b78093d866f2b473cb5d4c9d7", head.Hash().String())
+}
@@ -0,0 +1,123 @@
+package mission
+
+import "fmt"
+
+// HintResult is the next hint for a learner, chosen from what currently fails.
+type HintResult struct {
+	MissionID   string `json:"missionId"`
+	Hint        string `json:"hint"`
+	Source      string `json:"source"`              // "check", "step" or "mission"; empty when nothing is left to hint
+	StepTitle   string `json:"stepTitle,omitempty"` // Title of the current step, if the mission has steps
+	CurrentStep int    `json:"currentStep"`
+	TotalSteps  int    `json:"totalSteps"`
+	Completed   bool   `json:"completed"`
+	HintsUsed   int    `json:"hintsUsed"` // Filled in by the caller that records hint usage
+}
+
+// Localized returns a copy of the mission with the texts of lang, falling back
+// to the authored ones for anything not translated.
+func (m *Mission) Localized(lang string) *Mission {
+	val := *m
+	localized := &val
+
+	trans, ok := m.Translations[lang]
+	if !ok {
+		return localized
+	}
+	if trans.Title != "" {
+		localized.Title = trans.Title
+	}
+	if trans.Description != "" {
+		localized.Description = trans.Description
+	}
+	if len(trans.Hints) > 0 {
+		localized.Hints = trans.Hints
+	}
+	if len(trans.Steps) > 0 {
+		localized.Steps = make([]Step, len(m.Steps))
+		copy(localized.Steps, m.Steps)
+		for i := range localized.Steps {
+			if i >= len(trans.Steps) {
+				break
+			}
+			if trans.Steps[i].Title != "" {
+				localized.Steps[i].Title = trans.Steps[i].Title
+			}
+			if trans.Steps[i].Hint != "" {
+				localized.Steps[i].Hint = trans.Steps[i].Hint
+			}
+		}
+	}
+	return localized
+}
+
+// NextHint picks the most specific hint for the current state of a mission
+// session: the hint of the first failing check of the current step, then the
+// step's own hint, then the hint of a failing validation check. Missions
+// without such hints fall back to their general hints, the used-th one (0-based)
+// so that asking again reveals the next.
+func (e *Engine) NextHint(sessionID, missionID, lang string, used int) (*HintResult, error) {
+	loaded, err := e.Loader.LoadMission(missionID)
+	if err != nil {
+		return nil, err
+	}
+	m := loaded.Localized(lang)
+
+	sess, ok := e.Manager.GetSession(sessionID)
+	if !ok {
+		return nil, fmt.Errorf("session not found")
+	}
+	sess.RLock()
+	result := verify(sess, m)
+	sess.RUnlock()
+
+	hint := &HintResult{
+		MissionID:   missionID,
+		CurrentStep: result.CurrentStep,
+		TotalSteps:  result.TotalSteps,
+		Completed:   result.Success,
+	}
+	if result.Success {
+		return hint, nil
+	}
+
+	if result.CurrentStep < len(m.Steps) {
+		step := m.Steps[result.CurrentStep]
+		hint.StepTitle = step.Title
+		if h := failingCheckHint(step.Checks, result.Steps[result.CurrentStep].Progress); h != "" {
+			hint.Hint, hint.Source = h, "check"
+			return hint, nil
+		}
+		if step.Hint != "" {
+			hint.Hint, hint.Source = step.Hint, "step"
+			return hint, nil
+		}
+	} else {
+		validation := result.Progress[len(result.Progress)-len(m.Validation.Checks):]
+		if h := failingCheckHint(m.Validation.Checks, validation); h != "" {
+			hint.Hint, hint.Source = h, "check"
+			return hint, nil
+		}
+	}
+
+	if len(m.Hints) > 0 {
+		if used >= len(m.Hints) {
+			used = len(m.Hints) - 1
+		}
+		if used < 0 {
+			used = 0
+		}
+		hint.Hint, hint.Source = m.Hints[used], "mission"
+	}
+	return hint, nil
+}
+
+// failingCheckHint returns the hint of the first failing check that has one.
+func failingCheckHint(checks []Check, progress []CheckResult) string {
+	for i, check := range checks {
+		if i < len(progress) && !progress[i].Passed && check.Hint != "" {
+			return check.Hint
+		}
+	}
+	return ""
+}
@@ -0,0 +1,461 @@
+package mission
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+)
+
+// Lesson generation
+//
+// GenerateLessons walks the history of an ingested remote looking for
+// teachable moments: merges whose conflicts were resolved by hand, reverts,
+// and branches that lived long next to the default branch. Each moment is
+// turned into a mission scaffold that clones the remote, rewinds to just
+// before the moment and checks that the learner reproduces what really
+// happened. Authors review and polish the YAML before adding it to missions/.
+
+// Kinds of teachable moments
+const (
+	MomentMergeConflict   = "merge_conflict"
+	MomentRevert          = "revert"
+	MomentLongLivedBranch = "long_lived_branch"
+)
+
+// AnalyzeOptions bounds the history analysis.
+type AnalyzeOptions struct {
+	MaxCommits    int // Commits to inspect (default 1000)
+	MaxMoments    int // Moments to return (default 20)
+	LongLivedDays int // Minimum age of a long-lived branch (default 14)
+}
+
+func (o AnalyzeOptions) withDefaults() AnalyzeOptions {
+	if o.MaxCommits <= 0 {
+		o.MaxCommits = 1000
+	}
+	if o.MaxMoments <= 0 {
+		o.MaxMoments = 20
+	}
+	if o.LongLivedDays <= 0 {
+		o.LongLivedDays = 14
+	}
+	return o
+}
+
+// Moment is a point in real history worth turning into a mission.
+type Moment struct {
+	Kind     string              `json:"kind"`
+	Commit   string              `json:"commit"`             // Historical commit: the merge, the revert or the branch tip
+	Start    string              `json:"start"`              // Commit the learner starts from
+	Incoming string              `json:"incoming,omitempty"` // Merged-in parent, reverted commit, or default branch tip
+	Branch   string              `json:"branch,omitempty"`   // Long-lived branch name
+	Summary  string              `json:"summary"`
+	Expected map[string][]string `json:"expected,omitempty"` // Lines each file must contain afterwards
+	Removed  []string            `json:"removed,omitempty"`  // Files that must no longer be tracked
+	Days     int                 `json:"days,omitempty"`     // Age of a long-lived branch
+	Commits  int                 `json:"commits,omitempty"`  // Commits on a long-lived branch
+}
+
+// Lesson is a moment together with the mission generated from it.
+type Lesson struct {
+	Moment  Moment   `json:"moment"`
+	Mission *Mission `json:"mission"`
+	YAML    string   `json:"yaml"` // Mission file content, ready to be reviewed
+}
+
+// maxExpectedLines caps how many lines are checked per file.
+const maxExpectedLines = 3
+
+// maxExpectedFiles caps how many files are checked per mission.
+const maxExpectedFiles = 3
+
+var revertTrailer = regexp.MustCompile(`This reverts commit ([0-9a-f]{40})`)
+
+// GenerateLessons analyzes the shared remote name and scaffolds a mission per moment.
+func (e *Engine) GenerateLessons(name string, opts AnalyzeOptions) ([]Lesson, error) {
+	repo, ok := e.Manager.GetSharedRemote(name)
+	if !ok {
+		return nil, fmt.Errorf("remote '%s' not found", name)
+	}
+
+	moments, err := AnalyzeRepository(repo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons := make([]Lesson, 0, len(moments))
+	for _, m := range moments {
+		mission := Scaffold(name, m)
+		data, err := yaml.Marshal(mission)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, Lesson{Moment: m, Mission: mission, YAML: string(data)})
+	}
+	return lessons, nil
+}
+
+// AnalyzeRepository finds teachable moments in the history of repo, newest first.
+func AnalyzeRepository(repo *gogit.Repository, opts AnalyzeOptions) ([]Moment, error) {
+	opts = opts.withDefaults()
+
+	iter, err := repo.Log(&gogit.LogOptions{All: true, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer iter.Close()
+
+	var moments []Moment
+	for seen := 0; seen < opts.MaxCommits && len(moments) < opts.MaxMoments; seen++ {
+		c, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var m *Moment
+		switch c.NumParents() {
+		case 2:
+			m = analyzeMerge(c)
+		case 1:
+			m = analyzeRevert(c)
+		}
+		if m != nil {
+			moments = append(moments, *m)
+		}
+	}
+
+	for _, m := range longLivedBranches(repo, opts) {
+		if len(moments) >= opts.MaxMoments {
+			break
+		}
+		moments = append(moments, m)
+	}
+	return moments, nil
+}
+
+// analyzeMerge reports a merge in which files changed on both sides were
+// resolved by hand, i.e. the result matches neither side.
+func analyzeMerge(c *object.Commit) *Moment {
+	ours, err1 := c.Parent(0)
+	theirs, err2 := c.Parent(1)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	bases, err := ours.MergeBase(theirs)
+	if err != nil || len(bases) == 0 {
+		return nil
+	}
+
+	oursChanged := changedFiles(bases[0], ours)
+	theirsChanged := changedFiles(bases[0], theirs)
+
+	expected := make(map[string][]string)
+	for path, oursHash := range oursChanged {
+		theirsHash, ok := theirsChanged[path]
+		if !ok || oursHash == theirsHash || oursHash.IsZero() || theirsHash.IsZero() {
+			continue
+		}
+		merged := fileContent(c, path)
+		oursContent, theirsContent := fileContent(ours, path), fileContent(theirs, path)
+		if merged == "" || merged == oursContent || merged == theirsContent {
+			continue
+		}
+		if lines := distinctLines(merged, oursContent, theirsContent); len(lines) > 0 {
+			expected[path] = lines
+		}
+	}
+	if len(expected) == 0 {
+		return nil
+	}
+
+	paths := sortedKeys(expected)
+	return &Moment{
+		Kind:     MomentMergeConflict,
+		Commit:   c.Hash.String(),
+		Start:    ours.Hash.String(),
+		Incoming: theirs.Hash.String(),
+		Summary:  fmt.Sprintf("%s: conflict in %s resolved by hand", subject(c), strings.Join(paths, ", ")),
+		Expected: limitFiles(expected),
+	}
+}
+
+// analyzeRevert reports a commit created by git revert.
+func analyzeRevert(c *object.Commit) *Moment {
+	if !strings.HasPrefix(c.Message, "Revert \"") {
+		return nil
+	}
+	match := revertTrailer.FindStringSubmatch(c.Message)
+	if match == nil {
+		return nil
+	}
+	parent, err := c.Parent(0)
+	if err != nil {
+		return nil
+	}
+
+	expected := make(map[string][]string)
+	var removed []string
+	for path, hash := range changedFiles(parent, c) {
+		if hash.IsZero() {
+			removed = append(removed, path)
+			continue
+		}
+		if lines := distinctLines(fileContent(c, path), fileContent(parent, path)); len(lines) > 0 {
+			expected[path] = lines
+		}
+	}
+	sort.Strings(removed)
+
+	return &Moment{
+		Kind:     MomentRevert,
+		Commit:   c.Hash.String(),
+		Start:    parent.Hash.String(),
+		Incoming: match[1],
+		Summary:  fmt.Sprintf("%s (reverts %s)", subject(c), match[1][:7]),
+		Expected: limitFiles(expected),
+		Removed:  removed,
+	}
+}
+
+// longLivedBranches reports unmerged branches that diverged from the default
+// branch at least LongLivedDays before either of them last moved.
+func longLivedBranches(repo *gogit.Repository, opts AnalyzeOptions) []Moment {
+	head, err := repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return nil
+	}
+	mainTip, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil
+	}
+
+	refs, err := repo.Branches()
+	if err != nil {
+		return nil
+	}
+	var moments []Moment
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() == head.Name() {
+			return nil
+		}
+		tip, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil
+		}
+		bases, err := tip.MergeBase(mainTip)
+		if err != nil || len(bases) == 0 || bases[0].Hash == tip.Hash {
+			return nil // Unrelated or already merged
+		}
+		base := bases[0]
+
+		latest := tip.Committer.When
+		if mainTip.Committer.When.After(latest) {
+			latest = mainTip.Committer.When
+		}
+		days := int(latest.Sub(base.Committer.When) / (24 * time.Hour))
+		if days < opts.LongLivedDays {
+			return nil
+		}
+
+		commits := 0
+		iter := object.NewCommitPreorderIter(tip, nil, []plumbing.Hash{base.Hash})
+		_ = iter.ForEach(func(*object.Commit) error {
+			commits++
+			return nil
+		})
+
+		branch := ref.Name().Short()
+		moments = append(moments, Moment{
+			Kind:     MomentLongLivedBranch,
+			Commit:   tip.Hash.String(),
+			Start:    tip.Hash.String(),
+			Incoming: mainTip.Hash.String(),
+			Branch:   branch,
+			Summary:  fmt.Sprintf("%s lived %d days next to %s with %d commits", branch, days, head.Name().Short(), commits),
+			Days:     days,
+			Commits:  commits,
+		})
+		return nil
+	})
+	sort.Slice(moments, func(i, j int) bool { return moments[i].Days > moments[j].Days })
+	return moments
+}
+
+// Scaffold turns a moment found in the remote name into a mission draft.
+func Scaffold(name string, m Moment) *Mission {
+	short := m.Commit[:7]
+	mission := &Mission{
+		ID:         fmt.Sprintf("gen-%s-%s", strings.ReplaceAll(m.Kind, "_", "-"), short),
+		Difficulty: Difficulty{Level: "intermediate", Stars: 3},
+		Setup: []string{
+			fmt.Sprintf("git clone %s lesson", name),
+			"cd /lesson",
+		},
+		Scoring: Scoring{TimeBonus: true, HintPenalty: 10},
+	}
+
+	var checks []Check
+	switch m.Kind {
+	case MomentMergeConflict:
+		mission.Title = fmt.Sprintf("Real Conflict: %s", short)
+		mission.Description = fmt.Sprintf("This merge really happened in %s and its conflicts were resolved by hand. Resolve them the same way and finish the merge.\n\n%s", name, m.Summary)
+		mission.Skill = "merge"
+		mission.Setup = append(mission.Setup,
+			fmt.Sprintf("git checkout -b lesson %s", m.Start),
+			fmt.Sprintf("git branch incoming %s", m.Incoming),
+			"!git merge incoming",
+		)
+		checks = append(checks,
+			Check{Type: "no_conflict", Description: "No merge conflicts remain"},
+			Check{Type: "contains_commit", Commit: m.Incoming, Description: "The incoming branch is merged"},
+		)
+		mission.Hints = []string{
+			"Run `git status` to see which files are in conflict.",
+			"Keep the parts of both sides that belong in the result, then `git add` each file.",
+			"Finish with `git commit` (or `git merge --continue`).",
+		}
+
+	case MomentRevert:
+		mission.Title = fmt.Sprintf("Real Revert: %s", short)
+		mission.Description = fmt.Sprintf("In %s, commit %s had to be undone. Undo it the same way without rewriting history.\n\n%s", name, m.Incoming[:7], m.Summary)
+		mission.Skill = "revert"
+		mission.Setup = append(mission.Setup, fmt.Sprintf("git checkout -b lesson %s", m.Start))
+		checks = append(checks,
+			Check{Type: "head_commit_message", MessagePattern: "Revert", Description: "A revert commit was created"},
+			Check{Type: "contains_commit", Commit: m.Start, Description: "History was not rewritten"},
+		)
+		for _, path := range m.Removed {
+			checks = append(checks, Check{Type: "file_tracked", Path: path, Negate: true, Description: fmt.Sprintf("%s is removed again", path)})
+		}
+		mission.Hints = []string{
+			fmt.Sprintf("Look at what the commit changed: `git show %s`", m.Incoming[:7]),
+			fmt.Sprintf("`git revert %s` creates a commit that undoes it.", m.Incoming[:7]),
+		}
+
+	case MomentLongLivedBranch:
+		mission.Title = fmt.Sprintf("Catching Up: %s", m.Branch)
+		mission.Description = fmt.Sprintf("The branch %s lived for %d days while the default branch moved on. Bring the latest default branch into it.\n\n%s", m.Branch, m.Days, m.Summary)
+		mission.Skill = "merge"
+		mission.Setup = append(mission.Setup, fmt.Sprintf("git checkout -b lesson %s", m.Start))
+		checks = append(checks,
+			Check{Type: "current_branch", Name: "lesson", Description: "You are on the lesson branch"},
+			Check{Type: "contains_commit", Commit: m.Incoming, Description: "The latest default branch is included"},
+			Check{Type: "no_conflict", Description: "No merge conflicts remain"},
+			Check{Type: "clean_working_tree", Description: "All changes are committed"},
+		)
+		mission.Hints = []string{
+			"Compare the branches with `git log --oneline --graph --all`.",
+			"Use `git merge` or `git rebase` with the default branch.",
+		}
+	}
+
+	for _, path := range sortedKeys(m.Expected) {
+		checks = append(checks, Check{
+			Type:        "file_content",
+			Path:        path,
+			Contains:    m.Expected[path],
+			Description: fmt.Sprintf("%s matches the real resolution", path),
+		})
+	}
+	mission.Validation = Validation{Checks: checks}
+	return mission
+}
+
+// changedFiles maps the paths that differ between from and to onto their hash in to
+// (the zero hash for deleted files).
+func changedFiles(from, to *object.Commit) map[string]plumbing.Hash {
+	result := make(map[string]plumbing.Hash)
+	fromTree, err1 := from.Tree()
+	toTree, err2 := to.Tree()
+	if err1 != nil || err2 != nil {
+		return result
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return result
+	}
+	for _, ch := range changes {
+		if ch.To.Name != "" {
+			result[ch.To.Name] = ch.To.TreeEntry.Hash
+		} else {
+			result[ch.From.Name] = plumbing.ZeroHash
+		}
+	}
+	return result
+}
+
+// fileContent returns the content of path in c, or "" when it is missing or binary.
+func fileContent(c *object.Commit, path string) string {
+	f, err := c.File(path)
+	if err != nil {
+		return ""
+	}
+	if binary, err := f.IsBinary(); err != nil || binary {
+		return ""
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return ""
+	}
+	return content
+}
+
+// distinctLines returns up to maxExpectedLines non-blank lines of content
+// that appear in none of others: the lines that prove the intended result.
+func distinctLines(content string, others ...string) []string {
+	known := make(map[string]bool)
+	for _, other := range others {
+		for _, line := range strings.Split(other, "\n") {
+			known[strings.TrimSpace(line)] = true
+		}
+	}
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || known[trimmed] {
+			continue
+		}
+		known[trimmed] = true
+		lines = append(lines, trimmed)
+		if len(lines) == maxExpectedLines {
+			break
+		}
+	}
+	return lines
+}
+
+// limitFiles keeps the expected lines of the first maxExpectedFiles paths.
+func limitFiles(expected map[string][]string) map[string][]string {
+	if len(expected) <= maxExpectedFiles {
+		return expected
+	}
+	limited := make(map[string][]string, maxExpectedFiles)
+	for _, path := range sortedKeys(expected)[:maxExpectedFiles] {
+		limited[path] = expected[path]
+	}
+	return limited
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func subject(c *object.Commit) string {
+	return strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+}
@@ -0,0 +1,147 @@
+package mission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	_ "github.com/kurobon/gitgym/backend/internal/git/commands" // Register commands
+	"github.com/kurobon/gitgym/backend/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildHistory creates a remote with a hand-resolved merge, a revert and a
+// branch that was left behind for a month.
+func buildHistory(t *testing.T) *gogit.Repository {
+	fs := memfs.New()
+	r, err := gogit.Init(memory.NewStorage(), fs)
+	require.NoError(t, err)
+	w, _ := r.Worktree()
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	commit := func(msg string, day int, files map[string]string, parents ...plumbing.Hash) plumbing.Hash {
+		for name, content := range files {
+			if content == "" {
+				_, err := w.Remove(name)
+				require.NoError(t, err)
+				continue
+			}
+			require.NoError(t, util.WriteFile(fs, name, []byte(content), 0644))
+			_, _ = w.Add(name)
+		}
+		sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: start.AddDate(0, 0, day)}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig, Committer: sig, Parents: parents})
+		require.NoError(t, err)
+		return hash
+	}
+
+	base := commit("Base", 0, map[string]string{"app.txt": "greeting\nfooter\n"})
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	feature := commit("Feature greeting", 1, map[string]string{"app.txt": "hello from feature\nfooter\n"})
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("legacy"), Hash: base, Create: true}))
+	commit("Legacy tweak", 2, map[string]string{"legacy.txt": "old\n"})
+
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master, Force: true}))
+	main := commit("Main greeting", 3, map[string]string{"app.txt": "hello from main\nfooter\n"})
+	commit("Merge branch 'feature'", 4, map[string]string{"app.txt": "hello from main and feature\nfooter\n"}, main, feature)
+
+	notes := commit("Add notes", 10, map[string]string{"notes.txt": "draft\n"})
+	commit("Revert \"Add notes\"\n\nThis reverts commit "+notes.String()+".", 30, map[string]string{"notes.txt": ""})
+	return r
+}
+
+func TestAnalyzeRepository(t *testing.T) {
+	moments, err := AnalyzeRepository(buildHistory(t), AnalyzeOptions{})
+	require.NoError(t, err)
+
+	byKind := make(map[string]Moment)
+	for _, m := range moments {
+		byKind[m.Kind] = m
+	}
+	require.Len(t, byKind, 3)
+
+	merge := byKind[MomentMergeConflict]
+	assert.Equal(t, map[string][]string{"app.txt": {"hello from main and feature"}}, merge.Expected)
+
+	revert := byKind[MomentRevert]
+	assert.Equal(t, []string{"notes.txt"}, revert.Removed)
+
+	legacy := byKind[MomentLongLivedBranch]
+	assert.Equal(t, "legacy", legacy.Branch)
+	assert.Equal(t, 1, legacy.Commits)
+	assert.Equal(t, 30, legacy.Days)
+}
+
+func TestGenerateLessons_ScaffoldsPlayableMissions(t *testing.T) {
+	sm := state.NewSessionManager()
+	sm.SharedRemotes["upstream"] = buildHistory(t)
+	sm.SharedRemotePaths["upstream"] = "upstream"
+
+	dir := t.TempDir()
+	e := NewEngine(NewLoader(dir), sm)
+
+	lessons, err := e.GenerateLessons("upstream", AnalyzeOptions{})
+	require.NoError(t, err)
+	require.Len(t, lessons, 3)
+	for _, l := range lessons {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, l.Mission.ID+".yaml"), []byte(l.YAML), 0644))
+	}
+
+	play := func(kind string, solve []string, files map[string]string) *VerificationResult {
+		var lesson Lesson
+		for _, l := range lessons {
+			if l.Moment.Kind == kind {
+				lesson = l
+			}
+		}
+		ctx := context.Background()
+		sessionID, err := e.StartMission(ctx, "", lesson.Mission.ID)
+		require.NoError(t, err)
+		sess, _ := sm.GetSession(sessionID)
+
+		// Nothing is solved right after setup
+		result, err := e.VerifyMission(sessionID, lesson.Mission.ID)
+		require.NoError(t, err)
+		assert.False(t, result.Success, "%s should not pass before solving", kind)
+
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(sess.Filesystem, "/lesson/"+name, []byte(content), 0644))
+		}
+		for _, cmd := range solve {
+			name, args := git.ParseCommand(cmd)
+			_, err := git.Dispatch(ctx, (*git.Session)(sess), name, args)
+			require.NoError(t, err, cmd)
+		}
+
+		result, err = e.VerifyMission(sessionID, lesson.Mission.ID)
+		require.NoError(t, err)
+		return result
+	}
+
+	result := play(MomentMergeConflict,
+		[]string{"git add app.txt", "git commit"},
+		map[string]string{"app.txt": "hello from main and feature\nfooter\n"})
+	assert.True(t, result.Success, "%+v", result.Progress)
+
+	var revertedCommit string
+	for _, l := range lessons {
+		if l.Moment.Kind == MomentRevert {
+			revertedCommit = l.Moment.Incoming
+		}
+	}
+	result = play(MomentRevert, []string{"git revert " + revertedCommit}, nil)
+	assert.True(t, result.Success, "%+v", result.Progress)
+
+	result = play(MomentLongLivedBranch, []string{"git merge master"}, nil)
+	assert.True(t, result.Success, "%+v", result.Progress)
+}
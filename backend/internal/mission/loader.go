@@ -1,30 +1,247 @@
 package mission
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/kurobon/gitgym/backend/internal/state"
 	"gopkg.in/yaml.v3"
 )
 
+// StrictMissionsEnv makes the loader refuse missions with validation errors
+// instead of serving them ("true").
+const StrictMissionsEnv = "GITGYM_MISSIONS_STRICT"
+
+// WatchMissionsEnv makes the server re-validate mission files whenever they
+// change and log the result ("true"), so authors see mistakes while editing.
+const WatchMissionsEnv = "GITGYM_MISSIONS_WATCH"
+
+// missionExtensions are the file types a mission can be written in, in lookup
+// order. JSON is a subset of YAML, so both go through the YAML decoder.
+var missionExtensions = []string{".yaml", ".yml", ".json"}
+
 // Loader handles loading missions from the filesystem.
+//
+// Parsed missions are cached by modification time and size, so edited files
+// are picked up on the next request without restarting the server.
 type Loader struct {
 	MissionDir string
+	Strict     bool // Refuse missions whose file has validation errors
+
+	mu    sync.Mutex
+	cache map[string]cachedMission // Keyed by file path
+}
+
+type cachedMission struct {
+	modTime time.Time
+	size    int64
+	mission *Mission
+	issues  []ValidationIssue
+	err     error
 }
 
 func NewLoader(dir string) *Loader {
-	return &Loader{MissionDir: dir}
+	return &Loader{MissionDir: dir, cache: make(map[string]cachedMission)}
 }
 
 // LoadMission loads a single mission by ID (filename without extension).
 func (l *Loader) LoadMission(id string) (*Mission, error) {
-	path := filepath.Join(l.MissionDir, id+".yaml")
+	path, err := l.missionFile(id)
+	if err != nil {
+		return nil, err
+	}
+	entry := l.load(path)
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	if l.Strict && hasErrors(entry.issues) {
+		return nil, fmt.Errorf("mission %s is invalid: %s", id, entry.issues[0].Message)
+	}
+	return entry.mission, nil
+}
+
+// ListMissions returns all available missions.
+func (l *Loader) ListMissions() ([]*Mission, error) {
+	paths, err := l.missionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var missions []*Mission
+	for _, path := range paths {
+		entry := l.load(path)
+		if entry.err != nil {
+			// Log error but continue? For now, skip invalid files.
+			continue
+		}
+		if l.Strict && hasErrors(entry.issues) {
+			continue
+		}
+		missions = append(missions, entry.mission)
+	}
+	return missions, nil
+}
+
+// Validate checks every mission file without replaying setups; see
+// Engine.ValidateMissions for the full check.
+func (l *Loader) Validate() (*ValidationReport, error) {
+	paths, err := l.missionFiles()
+	if err != nil {
+		return nil, err
+	}
+	report := &ValidationReport{Issues: []ValidationIssue{}}
+	ids := make(map[string]string)
+	for _, path := range paths {
+		entry := l.load(path)
+		report.Issues = append(report.Issues, entry.issues...)
+		if entry.mission == nil {
+			continue
+		}
+		report.Missions++
+		if other, ok := ids[entry.mission.ID]; ok {
+			report.Issues = append(report.Issues, ValidationIssue{
+				File:     filepath.Base(path),
+				Mission:  entry.mission.ID,
+				Field:    "id",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("duplicate mission id, also used by %s", other),
+			})
+		}
+		ids[entry.mission.ID] = filepath.Base(path)
+	}
+	report.Valid = !hasErrors(report.Issues)
+	return report, nil
+}
+
+// Watch polls the mission directory and calls onChange with a fresh report
+// whenever a mission file is added, edited or removed. It returns when ctx is done.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration, onChange func(*ValidationReport)) {
+	last := l.fingerprint()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := l.fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+		report, err := l.Validate()
+		if err != nil {
+			slog.Warn("missions: reload failed", "dir", l.MissionDir, "err", err)
+			continue
+		}
+		onChange(report)
+	}
+}
+
+// fingerprint summarizes the names, sizes and modification times of the mission files.
+func (l *Loader) fingerprint() string {
+	paths, _ := l.missionFiles()
+	var sb strings.Builder
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+// load parses and validates a mission file, reusing the cached result while
+// the file is unchanged.
+func (l *Loader) load(path string) cachedMission {
+	info, err := os.Stat(path)
+	if err != nil {
+		return cachedMission{err: fmt.Errorf("failed to read mission file: %w", err)}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cache == nil {
+		l.cache = make(map[string]cachedMission)
+	}
+	if entry, ok := l.cache[path]; ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry
+	}
+
+	entry := cachedMission{modTime: info.ModTime(), size: info.Size()}
+	file := filepath.Base(path)
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read mission file: %w", err)
+		entry.err = fmt.Errorf("failed to read mission file: %w", err)
+	} else if m, parseErr := parseMission(data, strings.TrimSuffix(file, filepath.Ext(file))); parseErr != nil {
+		entry.err = parseErr
+		entry.issues = []ValidationIssue{{File: file, Severity: SeverityError, Message: parseErr.Error()}}
+	} else {
+		entry.mission = m
+		entry.issues = append(unknownFields(data, m, file), loadFixtures(m, filepath.Dir(path), file)...)
+		entry.issues = append(entry.issues, loadRepositoryFixture(m, filepath.Dir(path), file)...)
+		entry.issues = append(entry.issues, ValidateMission(m, file)...)
 	}
+	l.cache[path] = entry
+	return entry
+}
 
+// loadFixtures reads the fixture files of m, relative to dir. Fixtures are
+// read with the mission file, so editing only a fixture needs the mission
+// file touched to be picked up.
+func loadFixtures(m *Mission, dir, file string) []ValidationIssue {
+	var issues []ValidationIssue
+	for name, rel := range m.Fixtures {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			issues = append(issues, ValidationIssue{
+				File:     file,
+				Mission:  m.ID,
+				Field:    "fixtures." + name,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("cannot read fixture %q: %v", rel, err),
+			})
+			continue
+		}
+		if m.fixtureData == nil {
+			m.fixtureData = make(map[string][]byte)
+		}
+		m.fixtureData[name] = data
+	}
+	return issues
+}
+
+// loadRepositoryFixture reads the repository fixture of m, relative to dir,
+// once per load: every start of the mission copies the parsed objects.
+func loadRepositoryFixture(m *Mission, dir, file string) []ValidationIssue {
+	if m.Repository == nil || m.Repository.Fixture == "" {
+		return nil
+	}
+	st, err := state.ReadRepositoryFixture(filepath.Join(dir, filepath.FromSlash(m.Repository.Fixture)))
+	if err != nil {
+		return []ValidationIssue{{
+			File:     file,
+			Mission:  m.ID,
+			Field:    "repository.fixture",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("cannot load repository fixture %q: %v", m.Repository.Fixture, err),
+		}}
+	}
+	m.repository = st
+	return nil
+}
+
+// parseMission decodes a mission document, defaulting its ID to id.
+func parseMission(data []byte, id string) (*Mission, error) {
 	var m Mission
 	if err := yaml.Unmarshal(data, &m); err != nil {
 		return nil, fmt.Errorf("failed to parse mission yaml: %w", err)
@@ -38,24 +255,56 @@ func (l *Loader) LoadMission(id string) (*Mission, error) {
 	return &m, nil
 }
 
-// ListMissions returns all available missions.
-func (l *Loader) ListMissions() ([]*Mission, error) {
+// unknownFields reports keys the mission format does not have. They are
+// ignored when loading, which usually means a typo or an outdated format.
+func unknownFields(data []byte, m *Mission, file string) []ValidationIssue {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var strict Mission
+	var typeErr *yaml.TypeError
+	if err := dec.Decode(&strict); !errors.As(err, &typeErr) {
+		return nil
+	}
+	var issues []ValidationIssue
+	for _, msg := range typeErr.Errors {
+		issues = append(issues, ValidationIssue{
+			File:     file,
+			Mission:  m.ID,
+			Severity: SeverityWarning,
+			Message:  msg + " (ignored)",
+		})
+	}
+	return issues
+}
+
+// missionFile finds the file of a mission in any of the supported formats.
+func (l *Loader) missionFile(id string) (string, error) {
+	for _, ext := range missionExtensions {
+		path := filepath.Join(l.MissionDir, id+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("failed to read mission file: %w", os.ErrNotExist)
+}
+
+// missionFiles lists the mission files of the directory, sorted by name.
+func (l *Loader) missionFiles() ([]string, error) {
 	files, err := os.ReadDir(l.MissionDir)
 	if err != nil {
 		return nil, err
 	}
-
-	var missions []*Mission
+	var paths []string
 	for _, f := range files {
-		if filepath.Ext(f.Name()) == ".yaml" {
-			id := f.Name()[0 : len(f.Name())-len(".yaml")]
-			m, err := l.LoadMission(id)
-			if err != nil {
-				// Log error but continue? For now, skip invalid files.
-				continue
+		if f.IsDir() {
+			continue
+		}
+		for _, ext := range missionExtensions {
+			if filepath.Ext(f.Name()) == ext {
+				paths = append(paths, filepath.Join(l.MissionDir, f.Name()))
 			}
-			missions = append(missions, m)
 		}
 	}
-	return missions, nil
+	sort.Strings(paths)
+	return paths, nil
 }
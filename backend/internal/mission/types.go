@@ -1,5 +1,10 @@
 package mission
 
+import (
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
 // Mission defines the structure of a practice mission loaded from YAML.
 type Mission struct {
 	ID           string                        `yaml:"id" json:"id"`
@@ -7,17 +12,46 @@ type Mission struct {
 	Description  string                        `yaml:"description" json:"description"`
 	Difficulty   Difficulty                    `yaml:"difficulty" json:"difficulty"`
 	Skill        string                        `yaml:"skill" json:"skill"`
-	Setup        []string                      `yaml:"setup" json:"-"`         // Commands to run for setup
-	Validation   Validation                    `yaml:"validation" json:"-"`    // Validation rules
-	Hints        []string                      `yaml:"hints" json:"hints"`     // Hints for the user
-	Scoring      Scoring                       `yaml:"scoring" json:"scoring"` // Scoring rules
-	Translations map[string]MissionTranslation `yaml:"translations" json:"-"`  // Localized content
+	Fixtures     map[string]string             `yaml:"fixtures,omitempty" json:"-"`              // Files put in the setup directory first, e.g. a bundle of prebuilt history: name -> path relative to the mission file
+	Repository   *RepositoryFixture            `yaml:"repository,omitempty" json:"-"`            // Prebuilt repository installed before the setup commands run
+	Setup        []string                      `yaml:"setup" json:"-"`                           // Commands to run for setup
+	Steps        []Step                        `yaml:"steps,omitempty" json:"steps,omitempty"`   // Ordered stages, each with its own checks
+	Validation   Validation                    `yaml:"validation" json:"-"`                      // Validation rules
+	Policy       *state.CommandPolicy          `yaml:"policy,omitempty" json:"policy,omitempty"` // Commands the learner may or may not run
+	Hints        []string                      `yaml:"hints" json:"hints"`                       // Hints for the user
+	Scoring      Scoring                       `yaml:"scoring" json:"scoring"`                   // Scoring rules
+	Translations map[string]MissionTranslation `yaml:"translations,omitempty" json:"-"`          // Localized content
+
+	fixtureData map[string][]byte // Contents of Fixtures, read by the loader
+	repository  *memory.Storage   // Objects and refs of Repository, read by the loader
+}
+
+// RepositoryFixture is a prebuilt repository a mission starts from. It is
+// installed as is, so every start has the same commit hashes, and loading it
+// is much faster than replaying the commands that built it.
+type RepositoryFixture struct {
+	Fixture string `yaml:"fixture"`        // Bundle file or bare repository directory, relative to the mission file
+	Path    string `yaml:"path,omitempty"` // Directory under /project to install it in; empty installs it in /project itself
 }
 
 type MissionTranslation struct {
-	Title       string   `yaml:"title" json:"title"`
-	Description string   `yaml:"description" json:"description"`
-	Hints       []string `yaml:"hints" json:"hints"`
+	Title       string            `yaml:"title" json:"title"`
+	Description string            `yaml:"description" json:"description"`
+	Hints       []string          `yaml:"hints" json:"hints"`
+	Steps       []StepTranslation `yaml:"steps,omitempty" json:"steps,omitempty"` // Same order as Mission.Steps
+}
+
+type StepTranslation struct {
+	Title string `yaml:"title" json:"title"`
+	Hint  string `yaml:"hint" json:"hint"`
+}
+
+// Step is one stage of a mission. Steps are completed in order: a step only
+// counts as done when its checks and those of every earlier step pass.
+type Step struct {
+	Title  string  `yaml:"title" json:"title"`
+	Hint   string  `yaml:"hint,omitempty" json:"-"` // Served by the hint endpoint while the step is current
+	Checks []Check `yaml:"checks" json:"-"`
 }
 
 type Difficulty struct {
@@ -30,13 +64,19 @@ type Validation struct {
 }
 
 type Check struct {
-	Type           string   `yaml:"type"`            // no_conflict, commit_exists, file_content, file_tracked, clean_working_tree, branch_exists, current_branch
-	Description    string   `yaml:"description"`     // User facing description
-	MessagePattern string   `yaml:"message_pattern"` // For log checks
-	Path           string   `yaml:"path"`            // For file checks
-	Contains       []string `yaml:"contains"`        // For file content checks
-	Name           string   `yaml:"name"`            // For branch checks (branch_exists, current_branch)
-	Negate         bool     `yaml:"negate"`          // If true, inverts the pass condition
+	Type           string   `yaml:"type"`                      // no_conflict, commit_exists, file_content, file_tracked, file_absent, clean_working_tree, branch_exists, remote_branch_exists, tag_exists, current_branch, head_detached, remote_url, refs_mirrored, contains_commit, ancestor_of, commit_count, linear_history, stash_empty, issue_closed
+	Description    string   `yaml:"description"`               // User facing description
+	MessagePattern string   `yaml:"message_pattern,omitempty"` // For log checks
+	Path           string   `yaml:"path,omitempty"`            // For file checks
+	Contains       []string `yaml:"contains,omitempty"`        // For file content checks
+	Name           string   `yaml:"name,omitempty"`            // For branch checks (branch_exists, current_branch), tag_exists, remote_branch_exists ("origin/main") and remote checks (remote_url, refs_mirrored)
+	URL            string   `yaml:"url,omitempty"`             // For remote_url checks
+	Commit         string   `yaml:"commit,omitempty"`          // For contains_commit checks: full hash that HEAD must contain; for ancestor_of: revision that must be reachable from Ref
+	Ref            string   `yaml:"ref,omitempty"`             // For ancestor_of, commit_count and linear_history: revision to start from (default HEAD)
+	Count          int      `yaml:"count,omitempty"`           // For commit_count: exact number of commits reachable from Ref
+	Issue          int      `yaml:"issue,omitempty"`           // For issue_closed: number of the issue on the remote Name (default origin)
+	Negate         bool     `yaml:"negate,omitempty"`          // If true, inverts the pass condition
+	Hint           string   `yaml:"hint,omitempty"`            // Shown by the hint endpoint while this check fails
 }
 
 type Scoring struct {
@@ -0,0 +1,345 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/shell"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+const (
+	SeverityError   = "error"   // The mission cannot be played as written
+	SeverityWarning = "warning" // Playable, but probably not what the author meant
+)
+
+// ValidationIssue is one problem found in a mission file.
+type ValidationIssue struct {
+	File     string `json:"file"`
+	Mission  string `json:"missionId,omitempty"`
+	Field    string `json:"field,omitempty"` // Location in the document, e.g. "validation.checks[2].name"
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the result of validating one or more mission files.
+type ValidationReport struct {
+	Valid    bool              `json:"valid"` // No issue has SeverityError
+	Missions int               `json:"missions"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+// checkFields lists the fields each check type needs, as named in YAML.
+var checkFields = map[string][]string{
+	"no_conflict":          nil,
+	"commit_exists":        nil,
+	"file_content":         {"path", "contains"},
+	"file_tracked":         {"path"},
+	"file_absent":          {"path"},
+	"clean_working_tree":   nil,
+	"branch_exists":        {"name"},
+	"remote_branch_exists": {"name"},
+	"tag_exists":           {"name"},
+	"current_branch":       {"name"},
+	"head_detached":        nil,
+	"head_commit_message":  nil,
+	"remote_url":           {"url"},
+	"refs_mirrored":        nil,
+	"contains_commit":      {"commit"},
+	"ancestor_of":          {"commit"},
+	"commit_count":         {"count"},
+	"linear_history":       nil,
+	"stash_empty":          nil,
+	"issue_closed":         {"issue"},
+}
+
+var fullHashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ValidateMission checks a parsed mission for mistakes that can be found
+// without running it: unknown check types, missing fields, unknown setup
+// commands and malformed ref names. file is used to label the issues.
+func ValidateMission(m *Mission, file string) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(severity, field, format string, args ...any) {
+		issues = append(issues, ValidationIssue{
+			File:     file,
+			Mission:  m.ID,
+			Field:    field,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if base := strings.TrimSuffix(file, filepath.Ext(file)); file != "" && m.ID != base {
+		add(SeverityWarning, "id", "id %q differs from the file name; missions are started by file name (%q)", m.ID, base)
+	}
+	if m.Title == "" {
+		add(SeverityWarning, "title", "title is empty")
+	}
+
+	if len(m.Setup) == 0 && m.Repository == nil {
+		add(SeverityError, "setup", "no setup commands; a mission needs at least \"git init\" or a repository fixture")
+	}
+	if r := m.Repository; r != nil {
+		if r.Fixture == "" {
+			add(SeverityError, "repository.fixture", "repository needs a fixture: a bundle file or a bare repository directory")
+		}
+		if r.Path != "" && (r.Path != path.Base(r.Path) || r.Path == "." || r.Path == "..") {
+			add(SeverityError, "repository.path", "repository path must be a plain directory name")
+		}
+	}
+	for name := range m.Fixtures {
+		if name == "" || name != path.Base(name) || name == "." || name == ".." {
+			add(SeverityError, "fixtures."+name, "fixture name must be a plain file name")
+		}
+	}
+	known := make(map[string]bool)
+	for _, name := range git.GetSupportedCommands() {
+		known[name] = true
+	}
+	for i, line := range m.Setup {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "!"))
+		script, err := shell.Parse(line)
+		if err != nil {
+			add(SeverityError, fmt.Sprintf("setup[%d]", i), "cannot parse %q: %v", line, err)
+			continue
+		}
+		for _, cmd := range script.Commands() {
+			if len(cmd.Words) == 0 {
+				continue
+			}
+			if name, _ := git.ResolveCommand(cmd.Words); !known[name] {
+				add(SeverityError, fmt.Sprintf("setup[%d]", i), "unknown command %q", name)
+			}
+		}
+	}
+
+	if m.Policy != nil {
+		for i, rule := range m.Policy.Allow {
+			if name := git.PolicyRuleCommand(rule); !known[name] {
+				add(SeverityError, fmt.Sprintf("policy.allow[%d]", i), "unknown command %q", name)
+			}
+		}
+		for i, rule := range m.Policy.Forbid {
+			if name := git.PolicyRuleCommand(rule); !known[name] {
+				add(SeverityError, fmt.Sprintf("policy.forbid[%d]", i), "unknown command %q", name)
+			}
+		}
+		for i, rule := range m.Policy.SaveBefore {
+			if name := git.PolicyRuleCommand(rule); !known[name] {
+				add(SeverityError, fmt.Sprintf("policy.save_before[%d]", i), "unknown command %q", name)
+			}
+		}
+	}
+
+	checks := 0
+	for i, step := range m.Steps {
+		field := fmt.Sprintf("steps[%d]", i)
+		if step.Title == "" {
+			add(SeverityWarning, field+".title", "step has no title")
+		}
+		if len(step.Checks) == 0 {
+			add(SeverityError, field+".checks", "step has no checks, so it can never be completed")
+		}
+		for j, check := range step.Checks {
+			validateCheck(check, fmt.Sprintf("%s.checks[%d]", field, j), add)
+		}
+		checks += len(step.Checks)
+	}
+	for i, check := range m.Validation.Checks {
+		validateCheck(check, fmt.Sprintf("validation.checks[%d]", i), add)
+	}
+	checks += len(m.Validation.Checks)
+	if checks == 0 {
+		add(SeverityError, "validation.checks", "mission has no checks, so it can never be verified")
+	}
+
+	langs := make([]string, 0, len(m.Translations))
+	for lang := range m.Translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if n := len(m.Translations[lang].Steps); n > len(m.Steps) {
+			add(SeverityWarning, "translations."+lang+".steps", "%d translated steps but the mission has %d", n, len(m.Steps))
+		}
+	}
+	return issues
+}
+
+func validateCheck(check Check, field string, add func(severity, field, format string, args ...any)) {
+	fields, ok := checkFields[check.Type]
+	if !ok {
+		types := make([]string, 0, len(checkFields))
+		for t := range checkFields {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		add(SeverityError, field+".type", "unknown check type %q (known: %s)", check.Type, strings.Join(types, ", "))
+		return
+	}
+	if check.Description == "" {
+		add(SeverityWarning, field+".description", "check has no description to show learners")
+	}
+
+	for _, name := range fields {
+		missing := false
+		switch name {
+		case "path":
+			missing = check.Path == ""
+		case "contains":
+			missing = len(check.Contains) == 0
+		case "name":
+			missing = check.Name == ""
+		case "url":
+			missing = check.URL == ""
+		case "commit":
+			missing = check.Commit == ""
+		case "count":
+			missing = check.Count <= 0
+		case "issue":
+			missing = check.Issue <= 0
+		}
+		if missing {
+			add(SeverityError, field+"."+name, "%s check needs %s", check.Type, name)
+		}
+	}
+
+	// Ref names must be valid, or the check can never pass
+	var ref plumbing.ReferenceName
+	switch check.Type {
+	case "branch_exists", "current_branch":
+		ref = plumbing.NewBranchReferenceName(check.Name)
+	case "tag_exists":
+		ref = plumbing.NewTagReferenceName(check.Name)
+	case "remote_branch_exists":
+		if check.Name != "" && !strings.Contains(check.Name, "/") {
+			add(SeverityError, field+".name", "remote branch %q must be written as <remote>/<branch>", check.Name)
+		}
+		ref = plumbing.ReferenceName("refs/remotes/" + check.Name)
+	}
+	if ref != "" && check.Name != "" {
+		if err := ref.Validate(); err != nil {
+			add(SeverityError, field+".name", "invalid ref name %q", check.Name)
+		}
+	}
+	if check.Type == "contains_commit" && check.Commit != "" && !fullHashPattern.MatchString(check.Commit) {
+		add(SeverityError, field+".commit", "contains_commit needs a full 40-character hash, got %q", check.Commit)
+	}
+}
+
+// ValidateMissions validates every mission file and replays each setup in a
+// scratch session, so failing setup commands and refs that do not exist after
+// setup are reported too.
+func (e *Engine) ValidateMissions(ctx context.Context) (*ValidationReport, error) {
+	report, err := e.Loader.Validate()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := e.Loader.missionFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		entry := e.Loader.load(path)
+		if entry.mission == nil || hasErrors(entry.issues) {
+			continue
+		}
+		report.Issues = append(report.Issues, replayMission(ctx, entry.mission, filepath.Base(path))...)
+	}
+	report.Valid = !hasErrors(report.Issues)
+	return report, nil
+}
+
+// ValidateDocument validates a mission that has not been saved yet, as sent by
+// an authoring tool: parse, static checks, then a setup replay.
+func (e *Engine) ValidateDocument(ctx context.Context, data []byte) *ValidationReport {
+	report := &ValidationReport{Issues: []ValidationIssue{}}
+	m, err := parseMission(data, "")
+	if err != nil {
+		report.Issues = append(report.Issues, ValidationIssue{Severity: SeverityError, Message: err.Error()})
+		return report
+	}
+	report.Missions = 1
+	report.Issues = append(report.Issues, ValidateMission(m, "")...)
+	if !hasErrors(report.Issues) {
+		report.Issues = append(report.Issues, replayMission(ctx, m, "")...)
+	}
+	report.Valid = !hasErrors(report.Issues)
+	return report
+}
+
+// replayMission runs the setup of m in a throwaway session manager and checks
+// that the revisions its checks start from resolve afterwards.
+func replayMission(ctx context.Context, m *Mission, file string) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(severity, field, format string, args ...any) {
+		issues = append(issues, ValidationIssue{
+			File:     file,
+			Mission:  m.ID,
+			Field:    field,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	scratch := NewEngine(nil, state.NewSessionManager())
+	sess, err := scratch.Manager.CreateSession("validate-" + m.ID)
+	if err != nil {
+		add(SeverityError, "setup", "cannot create a session: %v", err)
+		return issues
+	}
+	if i, err := scratch.runSetup(ctx, sess, m); err != nil {
+		add(SeverityError, fmt.Sprintf("setup[%d]", i), "%v", err)
+		return issues
+	}
+
+	repo := sess.GetRepo()
+	if repo == nil {
+		add(SeverityError, "setup", "setup does not leave a repository in %s", sess.CurrentDir)
+		return issues
+	}
+	checkRefs := func(checks []Check, prefix string) {
+		for j, check := range checks {
+			field := fmt.Sprintf("%s.checks[%d]", prefix, j)
+			switch check.Type {
+			case "ancestor_of", "commit_count", "linear_history":
+				if check.Ref != "" {
+					if _, err := resolveCheckCommit(repo, check.Ref); err != nil {
+						add(SeverityError, field+".ref", "ref %q does not resolve after setup", check.Ref)
+					}
+				}
+			}
+			if check.Type == "ancestor_of" && check.Commit != "" {
+				if _, err := resolveCheckCommit(repo, check.Commit); err != nil {
+					add(SeverityError, field+".commit", "commit %q does not resolve after setup", check.Commit)
+				}
+			}
+		}
+	}
+	for i, step := range m.Steps {
+		checkRefs(step.Checks, fmt.Sprintf("steps[%d]", i))
+	}
+	checkRefs(m.Validation.Checks, "validation")
+
+	if verify(sess, m).Success {
+		add(SeverityWarning, "validation", "mission is already solved right after setup")
+	}
+	return issues
+}
+
+func hasErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package mission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const brokenMission = `id: "broken"
+title: "Broken"
+setup:
+  - "git init"
+  - "git frobnicate"
+validation:
+  checks:
+    - type: "branch_exist"
+      name: "feature"
+      description: "typo in type"
+    - type: "branch_exists"
+      name: "bad..name"
+      description: "bad ref"
+    - type: "file_content"
+      path: "a.txt"
+      description: "missing contains"
+    - type: "remote_branch_exists"
+      name: "main"
+      description: "no remote"
+policy:
+  forbid: ["git frobnicate --hard"]
+objectives:
+  - title: "old format"
+`
+
+func issueFields(report *ValidationReport) map[string]string {
+	fields := make(map[string]string)
+	for _, issue := range report.Issues {
+		fields[issue.Field] = issue.Severity
+	}
+	return fields
+}
+
+func TestValidateDocument_StaticIssues(t *testing.T) {
+	e := NewEngine(NewLoader(t.TempDir()), state.NewSessionManager())
+	report := e.ValidateDocument(context.Background(), []byte(brokenMission))
+
+	assert.False(t, report.Valid)
+	fields := issueFields(report)
+	assert.Equal(t, SeverityError, fields["setup[1]"], "unknown command")
+	assert.Equal(t, SeverityError, fields["validation.checks[0].type"], "unknown check type")
+	assert.Equal(t, SeverityError, fields["validation.checks[1].name"], "invalid ref")
+	assert.Equal(t, SeverityError, fields["validation.checks[2].contains"], "missing field")
+	assert.Equal(t, SeverityError, fields["validation.checks[3].name"], "remote branch without remote")
+	assert.Equal(t, SeverityError, fields["policy.forbid[0]"], "unknown command in policy")
+
+	report = e.ValidateDocument(context.Background(), []byte("id: [unclosed"))
+	assert.False(t, report.Valid)
+	require.Len(t, report.Issues, 1)
+	assert.Contains(t, report.Issues[0].Message, "failed to parse")
+}
+
+func TestValidateDocument_ReplaysSetup(t *testing.T) {
+	e := NewEngine(NewLoader(t.TempDir()), state.NewSessionManager())
+	doc := `id: "replay"
+title: "Replay"
+setup:
+  - "git init"
+  - "echo 'one' > a.txt"
+  - "git add a.txt"
+  - "git commit -m 'First'"
+validation:
+  checks:
+    - type: "ancestor_of"
+      commit: "v1"
+      description: "tag is never created"
+    - type: "commit_count"
+      count: 1
+      description: "already true"
+`
+	report := e.ValidateDocument(context.Background(), []byte(doc))
+	assert.False(t, report.Valid)
+	assert.Equal(t, SeverityError, issueFields(report)["validation.checks[0].commit"])
+
+	// A mission that passes right after setup is only worth a warning
+	doc = `id: "solved"
+title: "Solved"
+setup:
+  - "git init"
+validation:
+  checks:
+    - type: "clean_working_tree"
+      description: "nothing to do"
+`
+	report = e.ValidateDocument(context.Background(), []byte(doc))
+	assert.True(t, report.Valid)
+	assert.Equal(t, SeverityWarning, issueFields(report)["validation"])
+}
+
+func TestLoader_HotReloadJSONAndStrict(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "json-mission.json")
+	write := func(title, checkType string) {
+		doc := `{"title": "` + title + `", "setup": ["git init"], "validation": {"checks": [{"type": "` + checkType + `", "description": "d"}]}}`
+		require.NoError(t, os.WriteFile(path, []byte(doc), 0644))
+	}
+	l := NewLoader(dir)
+
+	write("First", "clean_working_tree")
+	m, err := l.LoadMission("json-mission")
+	require.NoError(t, err)
+	assert.Equal(t, "json-mission", m.ID)
+	assert.Equal(t, "First", m.Title)
+
+	// Edits are picked up without a new loader
+	write("Second title", "no_conflict")
+	m, err = l.LoadMission("json-mission")
+	require.NoError(t, err)
+	assert.Equal(t, "Second title", m.Title)
+
+	// Invalid missions are still served unless the loader is strict
+	write("Third", "not_a_check")
+	_, err = l.LoadMission("json-mission")
+	assert.NoError(t, err)
+	l.Strict = true
+	_, err = l.LoadMission("json-mission")
+	assert.Error(t, err)
+	missions, err := l.ListMissions()
+	require.NoError(t, err)
+	assert.Empty(t, missions)
+
+	report, err := l.Validate()
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.Missions)
+}
+
+func TestLoader_WatchReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLoader(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reports := make(chan *ValidationReport, 1)
+	go l.Watch(ctx, 10*time.Millisecond, func(r *ValidationReport) { reports <- r })
+	time.Sleep(30 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.yaml"), []byte("title: New\nsetup: [\"git init\"]\n"), 0644))
+	select {
+	case report := <-reports:
+		assert.Equal(t, 1, report.Missions)
+		assert.False(t, report.Valid, "mission without checks")
+	case <-time.After(2 * time.Second):
+		t.Fatal("no report after adding a mission file")
+	}
+}
+
+func TestShippedMissionsAreValid(t *testing.T) {
+	e := NewEngine(NewLoader("../../missions"), state.NewSessionManager())
+	report, err := e.ValidateMissions(context.Background())
+	require.NoError(t, err)
+	for _, issue := range report.Issues {
+		assert.NotEqual(t, SeverityError, issue.Severity, "%s %s: %s", issue.File, issue.Field, issue.Message)
+	}
+	assert.True(t, report.Valid)
+}
+
+func TestLoader_ReportsMissingAndBadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	doc := `{"title": "t", "fixtures": {"../escape.bundle": "x.bundle", "missing.bundle": "fixtures/missing.bundle"}, "setup": ["git init"], "validation": {"checks": [{"type": "clean_working_tree", "description": "d"}]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixture-mission.json"), []byte(doc), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x.bundle"), []byte("data"), 0644))
+
+	report, err := NewLoader(dir).Validate()
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	fields := make(map[string]string)
+	for _, issue := range report.Issues {
+		fields[issue.Field] = issue.Message
+	}
+	assert.Contains(t, fields["fixtures.missing.bundle"], "cannot read fixture")
+	assert.Contains(t, fields["fixtures.../escape.bundle"], "plain file name")
+}
+
+func TestLoader_ReportsBadRepositoryFixture(t *testing.T) {
+	dir := t.TempDir()
+	doc := `{"title": "t", "repository": {"fixture": "notes.txt", "path": "a/b"}, "validation": {"checks": [{"type": "clean_working_tree", "description": "d"}]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repo-mission.json"), []byte(doc), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a bundle"), 0644))
+
+	report, err := NewLoader(dir).Validate()
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	fields := make(map[string]string)
+	for _, issue := range report.Issues {
+		fields[issue.Field] = issue.Message
+	}
+	assert.Contains(t, fields["repository.fixture"], "neither a bundle nor a repository directory")
+	assert.Contains(t, fields["repository.path"], "plain directory name")
+	assert.NotContains(t, fields, "setup", "a repository fixture replaces git init")
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
 	"github.com/kurobon/gitgym/backend/internal/mission"
@@ -11,6 +12,12 @@ type Server struct {
 	SessionManager *git.SessionManager
 	MissionEngine  *mission.Engine
 	Mux            *http.ServeMux
+	Limits         Limits // May be changed before serving
+
+	ipLimiter      *rateLimiter
+	sessionLimiter *rateLimiter
+	closing        chan struct{} // Closed by CloseStreams
+	closeOnce      sync.Once
 }
 
 func NewServer(sm *git.SessionManager, me *mission.Engine) *Server {
@@ -18,6 +25,10 @@ func NewServer(sm *git.SessionManager, me *mission.Engine) *Server {
 		SessionManager: sm,
 		MissionEngine:  me,
 		Mux:            http.NewServeMux(),
+		Limits:         DefaultLimits(),
+		ipLimiter:      newRateLimiter(),
+		sessionLimiter: newRateLimiter(),
+		closing:        make(chan struct{}),
 	}
 	s.routes()
 	return s
@@ -27,35 +38,104 @@ func (s *Server) routes() {
 	s.Mux.HandleFunc("/ping", s.handlePing)
 	s.Mux.HandleFunc("/api/session/init", s.handleInitSession)
 	s.Mux.HandleFunc("/api/command", s.handleExecCommand)
+	s.Mux.HandleFunc("/api/complete", s.handleComplete)
 	s.Mux.HandleFunc("/api/state", s.handleGetGraphState)
+	s.Mux.HandleFunc("/api/state/stream", s.handleStateStream)
+	s.Mux.HandleFunc("/api/state/delta", s.handleGetStateDelta)
 	s.Mux.HandleFunc("/api/remote/state", s.handleGetRemoteState)
 	s.Mux.HandleFunc("/api/strategies", s.handleGetStrategies)
+	s.Mux.HandleFunc("/api/diff", s.handleGetDiff)
+	s.Mux.HandleFunc("/api/compare", s.handleCompare)
+	s.Mux.HandleFunc("/api/blame", s.handleGetBlame)
+	s.Mux.HandleFunc("/api/search", s.handleSearchCommits)
+	s.Mux.HandleFunc("/api/objects", s.handleGetObjectGraph)
+	s.Mux.HandleFunc("/api/session/maintenance", s.handleGetMaintenanceReport)
+	s.Mux.HandleFunc("/api/session/usage", s.handleGetSessionUsage)
+	s.Mux.HandleFunc("/api/session/audit", s.handleGetAuditLog)
+	s.Mux.HandleFunc("/api/session/logs", s.handleGetSessionLogs)
+	s.Mux.HandleFunc("/api/session/import", s.handleImportRepository)
+	s.Mux.HandleFunc("/api/session/export", s.handleExportRepository)
+	s.Mux.HandleFunc("/api/session/trace", s.handleGetTrace)
+	s.Mux.HandleFunc("/api/session/trace/replay", s.handleReplayTrace)
+	s.Mux.HandleFunc("/api/session/timeline", s.handleGetTimeline)
+	s.Mux.HandleFunc("/api/session/undo", s.handleUndo)
+	s.Mux.HandleFunc("/api/session/redo", s.handleRedo)
+	s.Mux.HandleFunc("/api/session/snapshot", s.handleSavePoints)
+	s.Mux.HandleFunc("/api/session/restore", s.handleRestoreSavePoint)
+	s.Mux.HandleFunc("/api/session/user", s.handleSessionUser)
+	s.Mux.HandleFunc("/api/rebase/plan", s.handleRebasePlan)
+	s.Mux.HandleFunc("/api/editor/complete", s.handleEditorComplete)
 
 	// Remote / Simulation
 	s.Mux.HandleFunc("/api/remote/ingest", s.handleIngestRemote)
 	s.Mux.HandleFunc("/api/remote/simulate-commit", s.handleSimulateRemoteCommit)
+	s.Mux.HandleFunc("/api/remote/simulate", s.handleRemoteSimulate)
 	s.Mux.HandleFunc("/api/remote/pull-requests", s.handleGetPullRequests)
 	s.Mux.HandleFunc("/api/remote/pull-requests/create", s.handleCreatePullRequest)
 	s.Mux.HandleFunc("/api/remote/pull-requests/merge", s.handleMergePullRequest)
+	s.Mux.HandleFunc("/api/remote/pull-requests/close", s.handleClosePullRequest)
+	s.Mux.HandleFunc("/api/remote/pull-requests/reopen", s.handleReopenPullRequest)
+	s.Mux.HandleFunc("/api/remote/pull-requests/review", s.handleReviewPullRequest)
+	s.Mux.HandleFunc("/api/remote/pull-requests/comment", s.handleCommentPullRequest)
 	s.Mux.HandleFunc("/api/remote/pull-requests/delete", s.handleDeletePullRequest)
+	s.Mux.HandleFunc("/api/pr/{id}/commits", s.handlePullRequestCommits)
+	s.Mux.HandleFunc("/api/pr/{id}/diff", s.handlePullRequestDiff)
+	s.Mux.HandleFunc("/api/remote/issues", s.handleGetIssues)
+	s.Mux.HandleFunc("/api/remote/issues/create", s.handleCreateIssue)
+	s.Mux.HandleFunc("/api/remote/issues/close", s.handleCloseIssue)
+	s.Mux.HandleFunc("/api/remote/issues/reopen", s.handleReopenIssue)
 	s.Mux.HandleFunc("/api/remote/reset", s.handleResetRemote)
 	s.Mux.HandleFunc("/api/remote/info", s.handleGetRemoteInfo)
 	s.Mux.HandleFunc("/api/remote/create", s.handleCreateRemote)
+	s.Mux.HandleFunc("/api/remote/templates", s.handleRepoTemplates)
 	s.Mux.HandleFunc("/api/remote/list", s.handleListRemotes)
+	s.Mux.HandleFunc("/api/remote/ingests", s.handleListIngests)
+	s.Mux.HandleFunc("/api/remote/branch-policy", s.handleRemoteBranchPolicy)
+	s.Mux.HandleFunc("/api/remote/permission", s.handleRemotePermission)
+	s.Mux.HandleFunc("/api/remote/default-branch", s.handleRemoteDefaultBranch)
+	s.Mux.HandleFunc("/api/remote/branches/rename", s.handleRenameRemoteBranch)
+	s.Mux.HandleFunc("/api/remote/sync", s.handleRemoteSync)
+	s.Mux.HandleFunc("/api/remote/sync/events", s.handleUpstreamEvents)
+	s.Mux.HandleFunc("/api/remote/presence", s.handleRemotePresence)
+	s.Mux.HandleFunc("/api/checks", s.handleGetChecks)
+	s.Mux.HandleFunc("/api/checks/rules", s.handleCheckRules)
+
+	// Background jobs (remote ingests)
+	s.Mux.HandleFunc("/api/jobs", s.handleListJobs)
+	s.Mux.HandleFunc("/api/jobs/{id}", s.handleGetJob)
+	s.Mux.HandleFunc("/api/jobs/{id}/events", s.handleJobEvents)
+	s.Mux.HandleFunc("/api/jobs/{id}/cancel", s.handleCancelJob)
 
 	// Mission
 	s.Mux.HandleFunc("/api/mission/list", s.handleListMissions)
 	s.Mux.HandleFunc("/api/mission/start", s.handleStartMission)
 	s.Mux.HandleFunc("/api/mission/verify", s.handleVerifyMission)
+	s.Mux.HandleFunc("/api/mission/hint", s.handleMissionHint)
+	s.Mux.HandleFunc("/api/mission/progress", s.handleGetMissionProgress)
+	s.Mux.HandleFunc("/api/mission/generate", s.handleGenerateLessons)
+	s.Mux.HandleFunc("/api/missions/validate", s.handleValidateMissions)
+
+	// Instructor analytics
+	s.Mux.HandleFunc("/api/analytics", s.handleGetAnalytics)
+	s.Mux.HandleFunc("/metrics", s.handleMetrics)
 
 	// Workspace
 	s.Mux.HandleFunc("/api/workspace/tree", s.handleGetWorkspaceTree)
 	s.Mux.HandleFunc("/api/file/read", s.handleReadFile)
 	s.Mux.HandleFunc("/api/file/write", s.handleWriteFile)
+	s.Mux.HandleFunc("/api/file/history", s.handleGetFileHistory)
+	s.Mux.HandleFunc("/api/file/at", s.handleGetFileAt)
+}
+
+// CloseStreams ends the event streams. Register it with
+// http.Server.RegisterOnShutdown: streams never go idle, so Shutdown would
+// otherwise wait for them until its deadline.
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() { close(s.closing) })
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Apply global middleware: CORS -> Logger -> Recoverer -> Mux
-	handler := Chain(s.Mux, CORS, Logger, Recoverer)
+	// Apply global middleware: CORS -> RequestContext -> Logger -> Recoverer -> Guard -> Mux
+	handler := Chain(s.Mux, CORS, RequestContext, Logger, Recoverer, s.Guard)
 	handler.ServeHTTP(w, r)
 }
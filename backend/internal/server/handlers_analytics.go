@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetAnalytics reports command usage, error rates and mission outcomes
+// for instructors, across every session or for one.
+// GET /api/analytics[?sessionId=...]
+func (s *Server) handleGetAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.SessionManager.Analytics(r.URL.Query().Get("sessionId")))
+}
+
+// handleMetrics exposes the analytics in the Prometheus text format.
+// GET /metrics
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, s.SessionManager.Analytics(""))
+}
+
+// writeMetrics writes the report as Prometheus metrics. Sessions are left
+// out: one series per learner would grow without bound.
+func writeMetrics(w io.Writer, report git.AnalyticsReport) {
+	metric := func(name, kind, help string, samples func(emit func(labels string, value float64))) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		samples(func(labels string, value float64) {
+			fmt.Fprintf(w, "%s%s %g\n", name, labels, value)
+		})
+	}
+	command := func(name string) string { return fmt.Sprintf("{command=%s}", metricLabel(name)) }
+	mission := func(id string) string { return fmt.Sprintf("{mission=%s}", metricLabel(id)) }
+
+	metric("gitgym_commands_total", "counter", "Commands run, by command.", func(emit func(string, float64)) {
+		for _, c := range report.Commands {
+			emit(command(c.Command), float64(c.Runs))
+		}
+	})
+	metric("gitgym_command_errors_total", "counter", "Commands that failed, by command.", func(emit func(string, float64)) {
+		for _, c := range report.Commands {
+			emit(command(c.Command), float64(c.Errors))
+		}
+	})
+	metric("gitgym_mission_learners", "gauge", "Learners who started or verified a mission.", func(emit func(string, float64)) {
+		for _, m := range report.Missions {
+			emit(mission(m.MissionID), float64(m.Learners))
+		}
+	})
+	metric("gitgym_mission_attempts_total", "counter", "Mission verifications.", func(emit func(string, float64)) {
+		for _, m := range report.Missions {
+			emit(mission(m.MissionID), float64(m.Attempts))
+		}
+	})
+	metric("gitgym_mission_completions_total", "counter", "Learners who completed a mission.", func(emit func(string, float64)) {
+		for _, m := range report.Missions {
+			emit(mission(m.MissionID), float64(m.Completions))
+		}
+	})
+	metric("gitgym_mission_hints_total", "counter", "Hints shown for a mission.", func(emit func(string, float64)) {
+		for _, m := range report.Missions {
+			emit(mission(m.MissionID), float64(m.HintsUsed))
+		}
+	})
+	metric("gitgym_mission_completion_seconds", "summary", "Time from starting a mission to completing it.", func(emit func(string, float64)) {
+		for _, m := range report.Missions {
+			if m.TimedCompletions == 0 {
+				continue
+			}
+			emit(fmt.Sprintf("{mission=%s,quantile=\"0.5\"}", metricLabel(m.MissionID)), m.MedianCompletionSeconds)
+		}
+	})
+	for _, m := range report.Missions {
+		if m.TimedCompletions == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "gitgym_mission_completion_seconds_sum%s %g\n", mission(m.MissionID), m.CompletionSecondsSum)
+		fmt.Fprintf(w, "gitgym_mission_completion_seconds_count%s %d\n", mission(m.MissionID), m.TimedCompletions)
+	}
+}
+
+// metricLabel quotes a label value as the Prometheus text format wants it.
+func metricLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleAnalyticsAndMetrics(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	run := func(command string) {
+		payload, _ := json.Marshal(map[string]string{"sessionId": id, "command": command})
+		resp, err := ts.Client().Post(ts.URL+"/api/command", "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	run("git init repo")
+	run("git status")
+	run("git comit -m typo")
+	sm.RecordMissionStart(id, "first-commit")
+	sm.RecordMissionProgress(id, "first-commit", 1, 1)
+
+	resp, err := ts.Client().Get(ts.URL + "/api/analytics?sessionId=" + id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report git.AnalyticsReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, 3, report.Runs)
+	commands := make(map[string]int)
+	for _, c := range report.Commands {
+		commands[c.Command] = c.Errors
+	}
+	assert.Contains(t, commands, "init")
+	assert.Equal(t, 1, commands[git.UnknownCommandStat])
+	require.Len(t, report.Missions, 1)
+	assert.Equal(t, 1, report.Missions[0].Completions)
+
+	resp, err = ts.Client().Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	for _, want := range []string{
+		"# TYPE gitgym_commands_total counter",
+		`gitgym_commands_total{command="init"} 1`,
+		`gitgym_command_errors_total{command="(unknown)"} 1`,
+		`gitgym_mission_completions_total{mission="first-commit"} 1`,
+		`gitgym_mission_completion_seconds_count{mission="first-commit"} 1`,
+	} {
+		assert.Contains(t, string(body), want)
+	}
+}
+
+func TestMetricLabel(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c\n"`, metricLabel("a\"b\\c\n"))
+}
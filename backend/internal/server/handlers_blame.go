@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetBlame attributes each line of a file to the commit that last changed it.
+// GET /api/blame?sessionId=...&path=<file>&rev=HEAD&start=<line>&end=<line>
+func (s *Server) handleGetBlame(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	path := q.Get("path")
+	if path == "" {
+		http.Error(w, "path required", http.StatusBadRequest)
+		return
+	}
+
+	var lines [2]int
+	for i, key := range []string{"start", "end"} {
+		if v := q.Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid "+key, http.StatusBadRequest)
+				return
+			}
+			lines[i] = n
+		}
+	}
+
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	defer session.RUnlock()
+
+	repo := session.GetRepo()
+	if repo == nil {
+		http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+		return
+	}
+
+	result, err := git.BlameFile(repo, q.Get("rev"), path, lines[0], lines[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
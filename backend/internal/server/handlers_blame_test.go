@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleGetBlame(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte("one\ntwo\nthree\n"), 0644))
+	_, err = w.Add("a.txt")
+	require.NoError(t, err)
+	first, err := w.Commit("initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte("one\n2\nthree\n"), 0644))
+	_, err = w.Add("a.txt")
+	require.NoError(t, err)
+	second, err := w.Commit("change two", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/blame?sessionId="+id+"&path=a.txt&start=2", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var result git.BlameResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(t, 3, result.TotalLines)
+	require.Len(t, result.Lines, 2)
+	assert.Equal(t, 2, result.Lines[0].Line)
+	assert.Equal(t, second.String(), result.Lines[0].Commit)
+	assert.Equal(t, "2", result.Lines[0].Text)
+	assert.Equal(t, first.String(), result.Lines[1].Commit)
+	assert.Equal(t, "three", result.Lines[1].Text)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/blame?sessionId="+id+"&path=nope.txt", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
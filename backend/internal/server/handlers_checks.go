@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// handleGetChecks returns the simulated CI statuses of a commit (?commit=),
+// a pull request's head (?pr=) or a branch of a shared remote (?remote=&ref=),
+// together with their combined state.
+func (s *Server) handleGetChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	var commit string
+	var statuses []state.CheckStatus
+	switch {
+	case q.Get("pr") != "":
+		id, err := strconv.Atoi(q.Get("pr"))
+		if err != nil {
+			http.Error(w, "invalid pr", http.StatusBadRequest)
+			return
+		}
+		pr, err := s.SessionManager.GetPullRequest(id)
+		if err != nil {
+			writePullRequestError(w, err)
+			return
+		}
+		commit = pr.ChecksCommit
+		statuses, _ = s.SessionManager.PullRequestChecks(id)
+	case q.Get("commit") != "":
+		commit = q.Get("commit")
+		statuses = s.SessionManager.CommitChecks(commit)
+	case q.Get("remote") != "" && q.Get("ref") != "":
+		hash, err := s.remoteBranchHash(q.Get("remote"), q.Get("ref"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		commit = hash.String()
+		for _, st := range s.SessionManager.CommitChecks(commit) {
+			if st.Remote == q.Get("remote") {
+				statuses = append(statuses, st)
+			}
+		}
+	default:
+		http.Error(w, "commit, pr or remote and ref required", http.StatusBadRequest)
+		return
+	}
+	if statuses == nil {
+		statuses = []state.CheckStatus{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"commit":   commit,
+		"state":    state.CombinedCheckState(statuses),
+		"statuses": statuses,
+	})
+}
+
+// remoteBranchHash resolves a branch of a shared remote to its commit.
+func (s *Server) remoteBranchHash(remote, branch string) (plumbing.Hash, error) {
+	sm := s.SessionManager
+	sm.RLock()
+	defer sm.RUnlock()
+	repo, ok := sm.SharedRemotes[remote]
+	if !ok {
+		return plumbing.ZeroHash, fmt.Errorf("remote '%s' not found", remote)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("branch '%s' not found on remote '%s'", branch, remote)
+	}
+	return ref.Hash(), nil
+}
+
+// handleCheckRules gets (GET ?name=) or sets (POST) the checks a shared remote runs on push
+func (s *Server) handleCheckRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		rules := s.SessionManager.GetRemoteCheckRules(name)
+		if rules == nil {
+			rules = []state.CheckRule{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rules)
+	case http.MethodPost:
+		var req struct {
+			Name  string            `json:"name"`
+			Rules []state.CheckRule `json:"rules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		for _, rule := range req.Rules {
+			if err := rule.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.SessionManager.SetRemoteCheckRules(req.Name, req.Rules); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
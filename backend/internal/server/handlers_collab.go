@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleSessionUser gets (GET) or sets (POST) the simulated user a session acts as.
+// Sessions attached to the same shared remote with different users collaborate on it.
+func (s *Server) handleSessionUser(w http.ResponseWriter, r *http.Request) {
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var user git.UserIdentity
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.SessionManager.SetSessionUser(session, user); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session.RLock()
+	user := session.Identity()
+	session.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(user)
+}
+
+// handleRemotePresence lists the users working on a shared remote (GET ?name=)
+// and the pushes of theirs that collided.
+func (s *Server) handleRemotePresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	presence, err := s.SessionManager.Presence(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(presence)
+}
@@ -1,16 +1,41 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/i18n"
+	"github.com/kurobon/gitgym/backend/internal/shell"
+	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
 type CommandRequest struct {
 	SessionID string `json:"sessionId"`
 	Command   string `json:"command"`
+	Editor    bool   `json:"editor,omitempty"` // The client can edit messages: commands without -m open its editor
+}
+
+// CommandResponse is the reply of /api/command. Output and Error keep the plain
+// text for the terminal panel; Result is the structured outcome of the last
+// command run, and Results that of every command of a chained command line.
+type CommandResponse struct {
+	Output  string               `json:"output,omitempty"`
+	Error   string               `json:"error,omitempty"`
+	Result  *git.CommandResult   `json:"result,omitempty"`
+	Results []*git.CommandResult `json:"results,omitempty"`
+}
+
+// newCommandResponse builds the reply for a dispatched command.
+func newCommandResponse(result *git.CommandResult, err error) CommandResponse {
+	if err != nil {
+		return CommandResponse{Error: err.Error(), Result: result}
+	}
+	return CommandResponse{Output: result.Stdout, Result: result}
 }
 
 func (s *Server) handleExecCommand(w http.ResponseWriter, r *http.Request) {
@@ -21,17 +46,22 @@ func (s *Server) handleExecCommand(w http.ResponseWriter, r *http.Request) {
 
 	var req CommandRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		status := http.StatusBadRequest
+		if isBodyTooLarge(err) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-
-	if req.SessionID == "" {
-		req.SessionID = "user-session-1" // Default for testing
+	if err := s.commandTooLong(req.Command); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
 	}
 
-	// 1. Parse Command & Resolve Aliases
-	cmdName, args := git.ParseCommand(req.Command)
-	if cmdName == "" {
+	req.SessionID = resolveSessionID(r, req.SessionID)
+
+	// 1. Skip empty command lines
+	if strings.TrimSpace(req.Command) == "" {
 		// Empty command
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{"output": ""})
@@ -41,27 +71,48 @@ func (s *Server) handleExecCommand(w http.ResponseWriter, r *http.Request) {
 	// 2. Get Session
 	session, ok := s.SessionManager.GetSession(req.SessionID)
 	if !ok {
-		log.Printf("Session %s not found (likely backend restart). Recreating...", req.SessionID)
-		var createErr error
-		session, createErr = s.SessionManager.CreateSession(req.SessionID)
-		if createErr != nil {
-			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to restore session: " + createErr.Error()})
-			return
-		}
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
 	}
 
-	// 3. Dispatch Command
-	// This now handles 'touch', 'ls', 'cd', 'rm' and all 'git' commands uniformly
-	output, err := git.Dispatch(r.Context(), session, cmdName, args)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-		return
+	// 3. Follow the learner's language: it becomes the session locale, used
+	// whenever a request does not state one
+	if lang := i18n.Match(r.Header.Get("Accept-Language")); lang != "" {
+		session.Lock()
+		session.Locale = lang
+		session.Unlock()
 	}
 
+	// 4. Run the command line
+	// The shell handles quoting, chaining, pipes and redirection; every command is dispatched through the registry
+	// Commands see the deadline through ctx; a line that runs out of time stops
+	// before its next command
+	ctx := r.Context()
+	if timeout := s.Limits.CommandTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if req.Editor {
+		ctx = git.WithEditor(ctx)
+	}
+	res, err := shell.Run(ctx, session, req.Command)
+
+	// 5. Schedule a snapshot of the session (no-op unless persistence is enabled)
+	s.SessionManager.ScheduleSave(session)
+
+	// 6. Push the new state to subscribed clients, even after an error: failed commands can still change the repo
+	s.SessionManager.PublishState(req.SessionID)
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"output": output})
+	resp := CommandResponse{Output: res.Output, Result: res.Last()}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	if len(res.Commands) > 1 {
+		resp.Results = res.Commands
+	}
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleGetGraphState(w http.ResponseWriter, r *http.Request) {
@@ -70,27 +121,78 @@ func (s *Server) handleGetGraphState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID := r.URL.Query().Get("sessionId")
-	if sessionID == "" {
-		sessionID = "user-session-1" // Default
-	}
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
 
-	showAll := r.URL.Query().Get("showAll") == "true"
+	// Optional commit pagination for large (e.g. ingested) repositories
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	state, err := s.SessionManager.GetGraphState(sessionID, showAll)
+	state, err := s.SessionManager.GetGraphStatePage(sessionID, filter)
 	if err != nil {
-		if err.Error() == "session not found" {
-			// Auto-restore session for graph view as well
-			_, _ = s.SessionManager.CreateSession(sessionID)
-			state, err = s.SessionManager.GetGraphState(sessionID, showAll)
+		status := http.StatusInternalServerError
+		if err.Error() == "session not found" || strings.HasPrefix(err.Error(), "unknown commit cursor") {
+			// A cursor commit that was rewritten or dropped: the client should start over
+			status = http.StatusNotFound
 		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
 
+// handleGetStateDelta returns what changed in the graph state since the version
+// passed as ?since=. Clients poll it instead of /api/state and keep the last
+// version they saw; since=0 (or omitted) returns a full delta.
+func (s *Server) handleGetStateDelta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	var since uint64
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, "invalid since: "+v, http.StatusBadRequest)
 			return
 		}
+		since = n
+	}
+
+	delta, err := s.SessionManager.StateDelta(sessionID, since)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "session not found" {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(state)
+	_ = json.NewEncoder(w).Encode(delta)
+}
+
+// parseCommitFilter reads the limit, offset, after and depth query parameters.
+func parseCommitFilter(r *http.Request) (state.CommitFilter, error) {
+	q := r.URL.Query()
+	filter := state.CommitFilter{After: q.Get("after")}
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset, "depth": &filter.MaxDepth} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("invalid %s: %s", name, v)
+		}
+		*dst = n
+	}
+	return filter, nil
 }
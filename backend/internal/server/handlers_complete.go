@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleComplete returns completions for the last word of a partial command
+// line: GET /api/complete?sessionId=...&line=git%20checkout%20fe
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	result := git.Complete(session, r.URL.Query().Get("line"))
+	session.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
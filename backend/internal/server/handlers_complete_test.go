@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleComplete(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	_, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	body := `{"sessionId":"` + id + `","command":"touch a.txt && git add a.txt && git commit -m init && git branch feature"}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/command", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	complete := func(line string) git.CompletionResult {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/complete?sessionId="+id+"&line="+url.QueryEscape(line), nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res git.CompletionResult
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return res
+	}
+
+	res := complete("git checkout fe")
+	assert.Equal(t, 13, res.Start)
+	assert.Equal(t, "fe", res.Word)
+	assert.Equal(t, []git.Completion{{Value: "feature", Kind: "branch"}}, res.Completions)
+
+	res = complete("git diff --st")
+	require.Len(t, res.Completions, 2)
+	assert.Equal(t, "flag", res.Completions[0].Kind)
+
+	res = complete("cat a")
+	assert.Equal(t, []git.Completion{{Value: "a.txt", Kind: "file"}}, res.Completions)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/complete?sessionId=missing&line=git", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
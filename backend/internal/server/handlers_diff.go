@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// DiffResponse is the payload of /api/diff
+type DiffResponse struct {
+	From  string         `json:"from"`
+	To    string         `json:"to"`
+	Files []git.FileDiff `json:"files"`
+}
+
+// handleGetDiff compares two snapshots of the current repository.
+// GET /api/diff?sessionId=...&from=<rev>&to=worktree|index|<rev>
+func (s *Server) handleGetDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = "HEAD"
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = git.SnapshotWorktree
+	}
+
+	// Building the index snapshot touches the repository, so take the write lock.
+	session.Lock()
+	defer session.Unlock()
+
+	repo := session.GetRepo()
+	if repo == nil {
+		http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+		return
+	}
+
+	files, err := git.SnapshotDiff(session, repo, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(DiffResponse{From: from, To: to, Files: files})
+}
+
+// handleCompare compares two revisions like a pull request: commits ahead and
+// behind, and the files changed since they diverged. Without remote the
+// revisions are resolved in the session's current repository, where
+// remote-tracking refs such as origin/main work as usual; with remote they
+// name branches of that shared remote, with or without the "<remote>/" prefix.
+// GET /api/compare?sessionId=...&base=<rev>&head=<rev>[&remote=<name>]
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	base, head := q.Get("base"), q.Get("head")
+	if base == "" || head == "" {
+		http.Error(w, "base and head required", http.StatusBadRequest)
+		return
+	}
+
+	var cmp *git.Comparison
+	var err error
+	if remote := q.Get("remote"); remote != "" {
+		repo, ok := s.SessionManager.GetSharedRemote(remote)
+		if !ok {
+			http.Error(w, fmt.Sprintf("remote '%s' not found", remote), http.StatusNotFound)
+			return
+		}
+		cmp, err = git.CompareRevisions(repo, remoteRevision(repo, remote, base), remoteRevision(repo, remote, head))
+		if cmp != nil {
+			cmp.Base, cmp.Head = base, head
+		}
+	} else {
+		sessionID := resolveSessionID(r, q.Get("sessionId"))
+		session, ok := s.SessionManager.GetSession(sessionID)
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		session.RLock()
+		repo := session.GetRepo()
+		if repo == nil {
+			session.RUnlock()
+			http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+			return
+		}
+		cmp, err = git.CompareRevisions(repo, base, head)
+		session.RUnlock()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cmp)
+}
+
+// remoteRevision maps a remote-view ref such as "origin/main" to the branch
+// of the shared remote it stands for, unless the remote resolves it itself.
+func remoteRevision(repo *gogit.Repository, remote, rev string) string {
+	if _, err := git.ResolveRevision(repo, rev); err == nil {
+		return rev
+	}
+	if branch, ok := strings.CutPrefix(rev, remote+"/"); ok {
+		return branch
+	}
+	return rev
+}
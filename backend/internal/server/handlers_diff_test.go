@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleGetDiff(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, sessionID := newTestSession(t, sm)
+	s := asSession(NewServer(sm, nil), sessionID)
+
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte("one\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "b.txt", []byte("keep\n"), 0644))
+	_, err = w.Add(".")
+	require.NoError(t, err)
+	_, err = w.Commit("initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+
+	// Modify one file and add another, without staging
+	require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte("one\ntwo\n"), 0644))
+	require.NoError(t, util.WriteFile(w.Filesystem, "c.txt", []byte("new\n"), 0644))
+
+	t.Run("HEAD vs worktree", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/api/diff?sessionId="+sessionID+"&from=HEAD&to=worktree", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp DiffResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+		byPath := make(map[string]git.FileDiff)
+		for _, f := range resp.Files {
+			byPath[f.Path] = f
+		}
+		require.Len(t, byPath, 2)
+		assert.Equal(t, "modified", byPath["a.txt"].Status)
+		assert.Equal(t, 1, byPath["a.txt"].Additions)
+		assert.Contains(t, byPath["a.txt"].Patch, "+two")
+		assert.Equal(t, "added", byPath["c.txt"].Status)
+	})
+
+	t.Run("HEAD vs index is empty before staging", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/api/diff?sessionId="+sessionID+"&to=index", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp DiffResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Empty(t, resp.Files)
+	})
+
+	t.Run("Bad revision", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/api/diff?sessionId="+sessionID+"&from=nope", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandleCompare(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(name, content string) {
+		t.Helper()
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+		_, err = w.Commit("update "+name, &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+		require.NoError(t, err)
+	}
+	commit("a.txt", "one\n")
+	head, err := repo.Head()
+	require.NoError(t, err)
+	main := head.Name()
+
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	commit("a.txt", "one\ntwo\n")
+	commit("b.txt", "new\n")
+	require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: main}))
+	commit("c.txt", "only on main\n")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/api/compare?"+query, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("sessionId=" + id + "&base=" + main.Short() + "&head=feature")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var cmp git.Comparison
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&cmp))
+	assert.Equal(t, 2, cmp.Ahead)
+	assert.Equal(t, 1, cmp.Behind)
+	require.Len(t, cmp.Commits, 2)
+	assert.Equal(t, "update b.txt", cmp.Commits[0].Message)
+	require.Len(t, cmp.Files, 2, "changes on the base since the merge base are not part of the diff")
+	assert.Equal(t, "a.txt", cmp.Files[0].Path)
+	assert.Equal(t, "modified", cmp.Files[0].Status)
+	assert.Equal(t, "added", cmp.Files[1].Status)
+	assert.Equal(t, 2, cmp.Additions)
+
+	// A shared remote resolves remote-view refs to its own branches
+	sm.SharedRemotes["origin"] = repo
+	rec = get("remote=origin&base=origin/" + main.Short() + "&head=feature")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&cmp))
+	assert.Equal(t, "origin/"+main.Short(), cmp.Base)
+	assert.Equal(t, 2, cmp.Ahead)
+
+	assert.Equal(t, http.StatusBadRequest, get("sessionId="+id+"&base=nope&head=feature").Code)
+	assert.Equal(t, http.StatusBadRequest, get("sessionId="+id+"&head=feature").Code)
+	assert.Equal(t, http.StatusNotFound, get("remote=upstream&base=main&head=feature").Code)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// EditorCompleteRequest closes the editor a command opened (result.editor of /api/command).
+type EditorCompleteRequest struct {
+	SessionID string `json:"sessionId"`
+	ID        string `json:"id"`      // result.editor.id
+	Message   string `json:"message"` // Edited text; '#' lines are dropped
+	Cancel    bool   `json:"cancel"`  // Close the editor without saving: the command is aborted
+}
+
+// handleEditorComplete resumes the command waiting for the editor with the
+// message the user wrote, and replies like /api/command.
+// POST /api/editor/complete
+func (s *Server) handleEditorComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req EditorCompleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sessionID := resolveSessionID(r, req.SessionID)
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := git.CompleteEditor(r.Context(), session, req.ID, req.Message, req.Cancel)
+	if result == nil {
+		// No such editor: it was closed by another command or already completed
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	s.SessionManager.ScheduleSave(session)
+	s.SessionManager.PublishState(sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newCommandResponse(result, err))
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleEditorComplete_CommitRoundTrip(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	post := func(path string, body any) (int, CommandResponse) {
+		payload, _ := json.Marshal(body)
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res CommandResponse
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		}
+		return resp.StatusCode, res
+	}
+
+	_, res := post("/api/command", map[string]any{"sessionId": id, "command": "git init repo && cd repo && touch a.txt && git add a.txt"})
+	require.Empty(t, res.Error)
+
+	// Without an editor, a commit without -m still fails
+	_, res = post("/api/command", map[string]any{"sessionId": id, "command": "git commit"})
+	assert.Contains(t, res.Error, "message is required")
+
+	_, res = post("/api/command", map[string]any{"sessionId": id, "command": "git commit", "editor": true})
+	require.Empty(t, res.Error)
+	require.NotNil(t, res.Result.Editor)
+	editor := res.Result.Editor
+	assert.Equal(t, git.CommitEditFile, editor.File)
+	assert.Contains(t, editor.Template, "#\tnew file:   a.txt")
+
+	_, res = post("/api/editor/complete", map[string]any{"sessionId": id, "id": editor.ID, "message": "Add a.txt\n\nBody line\n" + editor.Template})
+	require.Empty(t, res.Error)
+	assert.Contains(t, res.Output, "Commit created")
+
+	session, _ := sm.GetSession(id)
+	head, err := session.GetRepo().Head()
+	require.NoError(t, err)
+	commit, err := session.GetRepo().CommitObject(head.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Add a.txt\n\nBody line", commit.Message)
+
+	// The editor closes once used
+	code, _ := post("/api/editor/complete", map[string]any{"sessionId": id, "id": editor.ID, "message": "again"})
+	assert.Equal(t, http.StatusConflict, code)
+}
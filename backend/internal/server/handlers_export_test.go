@@ -0,0 +1,68 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleExportRepository_BundleAndArchive(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	payload, _ := json.Marshal(map[string]any{"sessionId": id, "command": "git init repo && cd repo && echo hello > a.txt && git add a.txt && git commit -m first && git tag v1"})
+	resp, err := ts.Client().Post(ts.URL+"/api/command", "application/json", bytes.NewReader(payload))
+	require.NoError(t, err)
+	var res CommandResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	resp.Body.Close()
+	require.Empty(t, res.Error)
+
+	get := func(query string) (*http.Response, []byte) {
+		resp, err := ts.Client().Get(ts.URL + "/api/session/export?sessionId=" + id + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	session, _ := sm.GetSession(id)
+	head, err := session.GetRepo().Head()
+	require.NoError(t, err)
+
+	resp, body := get("")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, `attachment; filename="repo.bundle"`, resp.Header.Get("Content-Disposition"))
+	header, pack, ok := bytes.Cut(body, []byte("\n\n"))
+	require.True(t, ok)
+	assert.Equal(t, "# v2 git bundle\n"+head.Hash().String()+" HEAD\n"+
+		head.Hash().String()+" refs/heads/main\n"+
+		head.Hash().String()+" refs/tags/v1", string(header))
+	assert.True(t, bytes.HasPrefix(pack, []byte("PACK")), "a packfile follows the refs")
+
+	resp, body = get("&repo=repo&format=zip&ref=v1")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash().String(), zr.Comment)
+	require.Len(t, zr.File, 1)
+	assert.Equal(t, "a.txt", zr.File[0].Name)
+
+	resp, _ = get("&format=rar")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = get("&repo=missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetFileHistory lists the commits that changed a path, newest first.
+// GET /api/file/history?sessionId=...&path=<file>&rev=HEAD&patch=true&limit=<n>
+func (s *Server) handleGetFileHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	path := q.Get("path")
+	if path == "" {
+		http.Error(w, "path required", http.StatusBadRequest)
+		return
+	}
+	patch := q.Get("patch") == "true" || q.Get("patch") == "1"
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	defer session.RUnlock()
+
+	repo := session.GetRepo()
+	if repo == nil {
+		http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+		return
+	}
+
+	result, err := git.FileHistory(repo, q.Get("rev"), path, patch, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleGetFileAt returns the content of a file at a revision.
+// GET /api/file/at?sessionId=...&path=<file>&rev=HEAD
+func (s *Server) handleGetFileAt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	path := q.Get("path")
+	if path == "" {
+		http.Error(w, "path required", http.StatusBadRequest)
+		return
+	}
+
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	defer session.RUnlock()
+
+	repo := session.GetRepo()
+	if repo == nil {
+		http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+		return
+	}
+
+	result, err := git.FileAt(repo, q.Get("rev"), path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleGetFileHistory(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(name, content, msg string) string {
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+		require.NoError(t, err)
+		return hash.String()
+	}
+	first := commit("a.txt", "one\n", "add a")
+	commit("b.txt", "other\n", "add b")
+	third := commit("a.txt", "one\ntwo\n", "extend a")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/file/history?sessionId="+id+"&path=a.txt&patch=true", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var history git.FileHistoryResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&history))
+	require.Len(t, history.Commits, 2)
+	assert.Equal(t, third, history.Commits[0].Commit)
+	assert.Equal(t, first, history.Commits[1].Commit)
+	require.NotNil(t, history.Commits[0].Diff)
+	assert.Equal(t, "modified", history.Commits[0].Diff.Status)
+	assert.Equal(t, 1, history.Commits[0].Diff.Additions)
+	assert.Contains(t, history.Commits[0].Diff.Patch, "+two")
+	require.NotNil(t, history.Commits[1].Diff)
+	assert.Equal(t, "added", history.Commits[1].Diff.Status)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/file/history?sessionId="+id+"&path=a.txt&limit=1", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	history = git.FileHistoryResult{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&history))
+	require.Len(t, history.Commits, 1)
+	assert.Nil(t, history.Commits[0].Diff)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/file/history?sessionId="+id+"&path=nope.txt", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/file/at?sessionId="+id+"&path=a.txt&rev="+first, nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var file git.FileContent
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&file))
+	assert.Equal(t, "one\n", file.Content)
+	assert.Equal(t, first, file.Revision)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/file/at?sessionId="+id+"&path=b.txt&rev="+first, nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
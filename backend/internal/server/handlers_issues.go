@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetIssues lists the issues of a shared remote, optionally only the
+// open or closed ones.
+// GET /api/remote/issues?name=origin[&state=open|closed]
+func (s *Server) handleGetIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	issues, err := s.SessionManager.GetIssues(name, r.URL.Query().Get("state"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(issues)
+}
+
+// handleCreateIssue opens an issue on a shared remote.
+// POST /api/remote/issues/create {"remoteName": "origin", "title": "Typo in README", "body": "...", "creator": "alice"}
+func (s *Server) handleCreateIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		RemoteName string `json:"remoteName"`
+		Title      string `json:"title"`
+		Body       string `json:"body"`
+		Creator    string `json:"creator"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.SessionManager.GetSharedRemote(req.RemoteName); !ok {
+		http.Error(w, fmt.Sprintf("remote '%s' not found", req.RemoteName), http.StatusNotFound)
+		return
+	}
+	issue, err := s.SessionManager.CreateIssue(req.RemoteName, req.Title, req.Body, req.Creator)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(issue)
+}
+
+// handleCloseIssue closes an open issue.
+// POST /api/remote/issues/close {"remoteName": "origin", "id": 1, "closedBy": "alice"}
+func (s *Server) handleCloseIssue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ClosedBy string `json:"closedBy"`
+	}
+	s.updateIssue(w, r, func(remote string, id int) (*git.Issue, error) {
+		return s.SessionManager.CloseIssue(remote, id, req.ClosedBy)
+	}, &req)
+}
+
+// handleReopenIssue reopens a closed issue.
+// POST /api/remote/issues/reopen {"remoteName": "origin", "id": 1}
+func (s *Server) handleReopenIssue(w http.ResponseWriter, r *http.Request) {
+	s.updateIssue(w, r, func(remote string, id int) (*git.Issue, error) {
+		return s.SessionManager.ReopenIssue(remote, id)
+	})
+}
+
+// updateIssue decodes the request body into the remote, the issue number and
+// any extra fields, applies update and responds with the updated issue.
+// Unknown remotes and issues are 404, refused transitions 409.
+func (s *Server) updateIssue(w http.ResponseWriter, r *http.Request, update func(remote string, id int) (*git.Issue, error), fields ...any) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		RemoteName string `json:"remoteName"`
+		ID         int    `json:"id"`
+	}
+	for _, target := range append([]any{&req}, fields...) {
+		if err := json.Unmarshal(body, target); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, ok := s.SessionManager.GetSharedRemote(req.RemoteName); !ok {
+		http.Error(w, fmt.Sprintf("remote '%s' not found", req.RemoteName), http.StatusNotFound)
+		return
+	}
+
+	issue, err := update(req.RemoteName, req.ID)
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, git.ErrIssueNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(issue)
+}
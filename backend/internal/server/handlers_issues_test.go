@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleIssues(t *testing.T) {
+	sm := git.NewSessionManager()
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	sm.SharedRemotes["origin"] = repo
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	post := func(path string, body any) (int, *git.Issue) {
+		payload, _ := json.Marshal(body)
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var issue git.Issue
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&issue))
+		return resp.StatusCode, &issue
+	}
+
+	code, issue := post("/api/remote/issues/create", map[string]string{"remoteName": "origin", "title": "Typo in README", "creator": "alice"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, issue.ID)
+	code, _ = post("/api/remote/issues/create", map[string]string{"remoteName": "origin", "title": ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = post("/api/remote/issues/create", map[string]string{"remoteName": "upstream", "title": "Typo"})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, issue = post("/api/remote/issues/close", map[string]any{"remoteName": "origin", "id": 1, "closedBy": "bob"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, git.IssueStateClosed, issue.State)
+	assert.Equal(t, "bob", issue.ClosedBy)
+	code, _ = post("/api/remote/issues/close", map[string]any{"remoteName": "origin", "id": 1})
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = post("/api/remote/issues/reopen", map[string]any{"remoteName": "origin", "id": 2})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	resp, err := ts.Client().Get(ts.URL + "/api/remote/issues?name=origin&state=closed")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var issues []git.Issue
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&issues))
+	require.Len(t, issues, 1)
+	assert.Equal(t, "Typo in README", issues[0].Title)
+}
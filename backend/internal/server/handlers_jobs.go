@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleListJobs returns the queued, running and recently finished jobs, oldest first.
+// GET /api/jobs
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.SessionManager.Jobs())
+}
+
+// handleGetJob returns one job.
+// GET /api/jobs/{id}
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	job, ok := s.SessionManager.GetJob(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// handleCancelJob drops a queued job or stops a running one.
+// POST /api/jobs/{id}/cancel
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+	if _, ok := s.SessionManager.GetJob(id); !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if err := s.SessionManager.CancelJob(id); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	job, _ := s.SessionManager.GetJob(id)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// handleJobEvents streams a job as server-sent "job" events, each carrying its
+// latest snapshot, and ends after the one that finishes it.
+// GET /api/jobs/{id}/events
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub, err := s.SessionManager.SubscribeJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer sub.Close()
+
+	// An ingest can run longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(stateStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-sub.Ready():
+			job, ok := sub.Next()
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(job)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: job\ndata: %s\n\n", job.Seq, data); err != nil {
+				return
+			}
+			if job.Finished() {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
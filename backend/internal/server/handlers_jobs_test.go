@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// readJobEvent reads the next "job" event of a stream, skipping comments.
+func readJobEvent(t *testing.T, r *bufio.Reader) state.Job {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event == "job" {
+				var job state.Job
+				require.NoError(t, json.Unmarshal([]byte(data), &job))
+				return job
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestHandleIngestRemote_RunsAsJob(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", filepath.Join(tmp, "data"))
+
+	srcPath := filepath.Join(tmp, "upstream")
+	src, err := gogit.PlainInit(srcPath, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "README.md"), []byte("hi"), 0644))
+	w, _ := src.Worktree()
+	_, _ = w.Add("README.md")
+	_, err = w.Commit("Init", &gogit.CommitOptions{Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()}})
+	require.NoError(t, err)
+
+	sm := git.NewSessionManager()
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	body, _ := json.Marshal(map[string]string{"name": "upstream", "url": srcPath, "access": "read-only"})
+	res, err := ts.Client().Post(ts.URL+"/api/remote/ingest", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var started struct {
+		JobID string    `json:"jobId"`
+		Job   state.Job `json:"job"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&started))
+	res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, state.JobIngest, started.Job.Kind)
+	assert.Equal(t, "upstream", started.Job.Target)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/jobs/"+started.JobID+"/events", nil)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewReader(resp.Body)
+	var job state.Job
+	for !job.Finished() {
+		job = readJobEvent(t, events)
+	}
+	require.Equal(t, state.JobSucceeded, job.State, job.Error)
+	_, ok := sm.GetSharedRemote("upstream")
+	assert.True(t, ok)
+	assert.Equal(t, state.RemoteAccessReadOnly, sm.GetRemotePermission("upstream").Access)
+
+	// Finished jobs stay listed, and cannot be cancelled any more
+	res, err = ts.Client().Get(ts.URL + "/api/jobs")
+	require.NoError(t, err)
+	var jobs []state.Job
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&jobs))
+	res.Body.Close()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, started.JobID, jobs[0].ID)
+
+	res, err = ts.Client().Post(ts.URL+"/api/jobs/"+started.JobID+"/cancel", "application/json", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	res, err = ts.Client().Get(ts.URL + "/api/jobs/job-404")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// A bad access level is refused before any job starts
+	body, _ = json.Marshal(map[string]string{"name": "x", "url": srcPath, "access": "admin"})
+	res, err = ts.Client().Post(ts.URL+"/api/remote/ingest", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestHandleIngestRemote_RefusesDisallowedHost(t *testing.T) {
+	sm := git.NewSessionManager()
+	sm.IngestPolicy = state.IngestPolicy{AllowedHosts: []string{"github.com"}}
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	body, _ := json.Marshal(map[string]string{"name": "internal", "url": "https://git.internal.test/secret.git"})
+	res, err := ts.Client().Post(ts.URL+"/api/remote/ingest", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Empty(t, sm.Jobs(), "a refused ingest queues no job")
+}
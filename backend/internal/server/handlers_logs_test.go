@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/logging"
+)
+
+func TestRequestIDAndSessionLogs(t *testing.T) {
+	prev := slog.Default()
+	var out bytes.Buffer
+	logging.Setup(&out, logging.Config{Level: slog.LevelInfo, Format: "json"})
+	defer slog.SetDefault(prev)
+
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	run := func(requestID, command string) *http.Response {
+		payload, _ := json.Marshal(map[string]string{"sessionId": id, "command": command})
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/command", bytes.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// A valid client ID is kept, an invalid one replaced
+	resp := run("ticket-42", "git init repo")
+	assert.Equal(t, "ticket-42", resp.Header.Get(RequestIDHeader))
+	resp = run(strings.Repeat("x", 100), "git status")
+	generated := resp.Header.Get(RequestIDHeader)
+	assert.Len(t, generated, 16)
+	run("", "git comit -m typo")
+	run("ticket-43", "git commit -m nothing")
+
+	// Only failed commands reach the info output, with the request, session
+	// and command of the dispatch
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		if rec["msg"] != "dispatched" {
+			continue
+		}
+		assert.NotEqual(t, "ticket-42", rec[logging.RequestIDKey], "a successful command is logged at info level")
+		if rec[logging.RequestIDKey] == "ticket-43" {
+			found = true
+			assert.Equal(t, id, rec[logging.SessionKey])
+			assert.Equal(t, "commit", rec[logging.CommandKey])
+			assert.NotEmpty(t, rec["err"])
+		}
+	}
+	assert.True(t, found, "no dispatch record for ticket-43 in %s", out.String())
+
+	// The session log keeps debug records too
+	resp, err := ts.Client().Get(ts.URL + "/api/session/logs?sessionId=" + id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var entries []logging.Entry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+	var levels, requests []string
+	for _, e := range entries {
+		levels = append(levels, e.Level)
+		requests = append(requests, e.RequestID)
+	}
+	assert.Contains(t, levels, "DEBUG")
+	assert.Contains(t, requests, "ticket-42")
+	assert.Contains(t, requests, generated)
+
+	resp, err = ts.Client().Get(ts.URL + "/api/session/logs?sessionId=" + id + "&format=text")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "request_id=ticket-42 command=init")
+
+	// The logs of other sessions are not the caller's to read
+	resp, err = ts.Client().Get(ts.URL + "/api/session/logs?sessionId=someone-else")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetMaintenanceReport exposes the engine internals behind a session.
+// GET /api/session/maintenance?sessionId=...
+func (s *Server) handleGetMaintenanceReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	report := git.BuildMaintenanceReport(session)
+	session.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// handleGetSessionUsage reports what a session stores against its storage quota.
+// GET /api/session/usage?sessionId=...
+func (s *Server) handleGetSessionUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	usage := session.StorageUsage()
+	session.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(usage)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleGetObjectGraph returns the commits, trees, blobs and tags of the
+// current repository and the links between them.
+// GET /api/objects?sessionId=...&limit=<max objects>
+func (s *Server) handleGetObjectGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	defer session.RUnlock()
+
+	repo := session.GetRepo()
+	if repo == nil {
+		http.Error(w, "fatal: not a git repository", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(git.BuildObjectGraph(repo, limit))
+}
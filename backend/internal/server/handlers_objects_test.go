@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleGetObjectGraph(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte("one\n"), 0644))
+	_, err = w.Add("a.txt")
+	require.NoError(t, err)
+	commit, err := w.Commit("initial", &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/objects?sessionId="+id, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var graph git.ObjectGraph
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&graph))
+	require.Len(t, graph.Objects, 3)
+	assert.Equal(t, git.GraphObject{ID: commit.String(), Type: "commit", Size: graph.Objects[0].Size, Label: "initial", Reachable: true}, graph.Objects[0])
+	assert.Equal(t, []string{"commit", "tree", "blob"}, []string{graph.Objects[0].Type, graph.Objects[1].Type, graph.Objects[2].Type})
+	assert.Len(t, graph.Edges, 2)
+	assert.Equal(t, commit.String(), graph.Refs["HEAD"])
+
+	req = httptest.NewRequest(http.MethodGet, "/api/objects?sessionId="+id+"&limit=0", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/objects?sessionId="+id, nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
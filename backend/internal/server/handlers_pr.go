@@ -2,8 +2,13 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+
+	gogit "github.com/go-git/go-git/v5"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
 )
@@ -48,33 +53,125 @@ func (s *Server) handleMergePullRequest(w http.ResponseWriter, r *http.Request)
 	var req struct {
 		ID         int    `json:"id"`
 		RemoteName string `json:"remoteName"`
+		Strategy   string `json:"strategy"` // "merge" (default), "squash" or "rebase"
+		MergedBy   string `json:"mergedBy"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if req.Strategy == "" {
+		req.Strategy = git.PRMergeCommit
+	}
 
-	// Resolve Session (Use Default "user-session-1" for now as explained)
-	sessionID := "user-session-1"
+	// Resolve Session
+	sessionID := resolveSessionID(r, "")
 	session, ok := s.SessionManager.GetSession(sessionID)
 	if !ok {
-		var err error
-		session, err = s.SessionManager.CreateSession(sessionID)
-		if err != nil {
-			http.Error(w, "failed to create session: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
 	}
 
 	// Dispatch "merge-pr"
-	// Output is ignored for now, checking error
-	_, err := git.Dispatch(r.Context(), session, "merge-pr", []string{"merge-pr", fmt.Sprintf("%d", req.ID), req.RemoteName})
+	args := []string{"merge-pr", fmt.Sprintf("%d", req.ID), req.RemoteName, "--strategy", req.Strategy}
+	if req.MergedBy != "" {
+		args = append(args, "--merged-by", req.MergedBy)
+	}
+	if _, err := git.Dispatch(r.Context(), session, "merge-pr", args); err != nil {
+		writePullRequestError(w, err)
+		return
+	}
+	pr, err := s.SessionManager.GetPullRequest(req.ID)
+	if err != nil {
+		writePullRequestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pr)
+}
+
+// handleClosePullRequest closes an open pull request without merging it.
+// POST /api/remote/pull-requests/close {"id": 1}
+func (s *Server) handleClosePullRequest(w http.ResponseWriter, r *http.Request) {
+	s.updatePullRequest(w, r, func(id int) (*git.PullRequest, error) {
+		return s.SessionManager.ClosePullRequest(id)
+	})
+}
+
+// handleReopenPullRequest reopens a closed pull request.
+// POST /api/remote/pull-requests/reopen {"id": 1}
+func (s *Server) handleReopenPullRequest(w http.ResponseWriter, r *http.Request) {
+	s.updatePullRequest(w, r, func(id int) (*git.PullRequest, error) {
+		return s.SessionManager.ReopenPullRequest(id)
+	})
+}
 
+// handleReviewPullRequest records an approval, a request for changes or a comment review.
+// POST /api/remote/pull-requests/review {"id": 1, "author": "bob", "state": "APPROVED", "body": "LGTM"}
+func (s *Server) handleReviewPullRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Author string `json:"author"`
+		State  string `json:"state"`
+		Body   string `json:"body"`
+	}
+	s.updatePullRequest(w, r, func(id int) (*git.PullRequest, error) {
+		return s.SessionManager.ReviewPullRequest(id, req.Author, req.State, req.Body)
+	}, &req)
+}
+
+// handleCommentPullRequest adds a comment, optionally on a line of a file.
+// POST /api/remote/pull-requests/comment {"id": 1, "author": "bob", "body": "typo", "path": "README.md", "line": 3}
+func (s *Server) handleCommentPullRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Author string `json:"author"`
+		Body   string `json:"body"`
+		Path   string `json:"path"`
+		Line   int    `json:"line"`
+	}
+	s.updatePullRequest(w, r, func(id int) (*git.PullRequest, error) {
+		return s.SessionManager.CommentOnPullRequest(id, req.Author, req.Body, req.Path, req.Line)
+	}, &req)
+}
+
+// updatePullRequest decodes the request body into the pull request ID and any
+// extra fields, applies update and responds with the updated pull request.
+func (s *Server) updatePullRequest(w http.ResponseWriter, r *http.Request, update func(id int) (*git.PullRequest, error), fields ...any) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	var req struct {
+		ID int `json:"id"`
+	}
+	for _, target := range append([]any{&req}, fields...) {
+		if err := json.Unmarshal(body, target); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pr, err := update(req.ID)
+	if err != nil {
+		writePullRequestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pr)
+}
+
+// writePullRequestError reports unknown pull requests as 404 and refused
+// transitions (merging a closed PR, approving your own, conflicts) as 409.
+func writePullRequestError(w http.ResponseWriter, err error) {
+	status := http.StatusConflict
+	if errors.Is(err, git.ErrPullRequestNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
 }
 
 func (s *Server) handleDeletePullRequest(w http.ResponseWriter, r *http.Request) {
@@ -95,3 +192,52 @@ func (s *Server) handleDeletePullRequest(w http.ResponseWriter, r *http.Request)
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// handlePullRequestCommits lists the commits a pull request adds, oldest
+// first, computed from its shared remote.
+// GET /api/pr/{id}/commits
+func (s *Server) handlePullRequestCommits(w http.ResponseWriter, r *http.Request) {
+	s.servePullRequestComparison(w, r, func(repo *gogit.Repository, pr *git.PullRequest) (any, error) {
+		return git.ListPullRequestCommits(repo, pr)
+	})
+}
+
+// handlePullRequestDiff returns the files a pull request changed since it
+// left its base branch, and whether it can be merged.
+// GET /api/pr/{id}/diff
+func (s *Server) handlePullRequestDiff(w http.ResponseWriter, r *http.Request) {
+	s.servePullRequestComparison(w, r, func(repo *gogit.Repository, pr *git.PullRequest) (any, error) {
+		return git.DiffPullRequest(repo, pr)
+	})
+}
+
+// servePullRequestComparison resolves the pull request of the path and its
+// shared remote, and encodes what compare returns for them.
+func (s *Server) servePullRequestComparison(w http.ResponseWriter, r *http.Request, compare func(*gogit.Repository, *git.PullRequest) (any, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid pull request id", http.StatusBadRequest)
+		return
+	}
+	pr, err := s.SessionManager.GetPullRequest(id)
+	if err != nil {
+		writePullRequestError(w, err)
+		return
+	}
+	repo, ok := s.SessionManager.GetSharedRemote(pr.RemoteName)
+	if !ok {
+		http.Error(w, fmt.Sprintf("remote '%s' not found", pr.RemoteName), http.StatusNotFound)
+		return
+	}
+	result, err := compare(repo, pr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
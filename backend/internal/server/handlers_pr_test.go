@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandlePullRequestLifecycle(t *testing.T) {
+	sm := git.NewSessionManager()
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	post := func(path string, body any) (int, *git.PullRequest) {
+		payload, _ := json.Marshal(body)
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var pr git.PullRequest
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&pr))
+		return resp.StatusCode, &pr
+	}
+
+	code, pr := post("/api/remote/pull-requests/create", map[string]string{
+		"title": "Add feature", "sourceBranch": "feature", "targetBranch": "main", "creator": "alice", "remoteName": "origin",
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = post("/api/remote/pull-requests/review", map[string]any{"id": pr.ID, "author": "alice", "state": "APPROVED"})
+	assert.Equal(t, http.StatusConflict, code, "authors cannot approve their own pull request")
+
+	code, got := post("/api/remote/pull-requests/review", map[string]any{"id": pr.ID, "author": "bob", "state": "APPROVED", "body": "LGTM"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, git.ReviewApproved, got.ReviewDecision)
+
+	code, got = post("/api/remote/pull-requests/comment", map[string]any{"id": pr.ID, "author": "bob", "body": "typo", "path": "README.md", "line": 2})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, got.Comments, 1)
+	assert.Equal(t, 2, got.Comments[0].Line)
+
+	code, got = post("/api/remote/pull-requests/close", map[string]any{"id": pr.ID})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, git.PRStateClosed, got.State)
+	code, _ = post("/api/remote/pull-requests/close", map[string]any{"id": pr.ID})
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, got = post("/api/remote/pull-requests/reopen", map[string]any{"id": pr.ID})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, git.PRStateOpen, got.State)
+
+	code, _ = post("/api/remote/pull-requests/close", map[string]any{"id": 999})
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandlePullRequestCommitsAndDiff(t *testing.T) {
+	sm := git.NewSessionManager()
+	s := NewServer(sm, nil)
+
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(name, content string) {
+		t.Helper()
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+		_, err = w.Commit("update "+name, &gogit.CommitOptions{Author: git.GetDefaultSignature()})
+		require.NoError(t, err)
+	}
+	branch := func(name string, create bool) {
+		t.Helper()
+		require.NoError(t, w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(name), Create: create}))
+	}
+	commit("README.md", "hello\n")
+	branch("main", true)
+	branch("feature", true)
+	commit("a.txt", "one\n")
+	commit("README.md", "hello feature\n")
+	branch("main", false)
+	commit("b.txt", "main\n")
+	sm.SharedRemotes["origin"] = repo
+
+	pr, err := sm.CreatePullRequest("Add feature", "", "feature", "main", "alice", "origin")
+	require.NoError(t, err)
+	get := func(path string, out any) int {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(out))
+		}
+		return rec.Code
+	}
+
+	var commits git.PullRequestCommits
+	require.Equal(t, http.StatusOK, get(fmt.Sprintf("/api/pr/%d/commits", pr.ID), &commits))
+	assert.Equal(t, 2, commits.Ahead)
+	assert.Equal(t, 1, commits.Behind)
+	assert.NotEmpty(t, commits.MergeBase)
+	require.Len(t, commits.Commits, 2)
+	assert.Equal(t, "update a.txt", commits.Commits[0].Message, "oldest first")
+	assert.True(t, commits.Mergeable)
+
+	var diff git.PullRequestDiff
+	require.Equal(t, http.StatusOK, get(fmt.Sprintf("/api/pr/%d/diff", pr.ID), &diff))
+	require.Len(t, diff.Files, 2)
+	assert.Equal(t, "README.md", diff.Files[0].Path)
+	assert.Contains(t, diff.Files[0].Patch, "+hello feature")
+	assert.True(t, diff.Mergeable)
+
+	// The base branch changing the same file makes the pull request unmergeable
+	commit("README.md", "hello main\n")
+	require.Equal(t, http.StatusOK, get(fmt.Sprintf("/api/pr/%d/diff", pr.ID), &diff))
+	assert.False(t, diff.Mergeable)
+	assert.Equal(t, []string{"README.md"}, diff.Conflicts)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/pr/999/diff", &diff))
+	assert.Equal(t, http.StatusBadRequest, get("/api/pr/abc/commits", &commits))
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleRebasePlan drives an interactive rebase started with "git rebase -i".
+// GET /api/rebase/plan?sessionId=...  returns the todo list waiting to be edited.
+// POST /api/rebase/plan {sessionId, steps} submits the edited plan and replays it.
+func (s *Server) handleRebasePlan(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGetRebasePlan(w, r)
+	case http.MethodPost:
+		s.handleSubmitRebasePlan(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleGetRebasePlan(w http.ResponseWriter, r *http.Request) {
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	var rebase *git.RebaseState
+	if rb := session.RebaseInProgress(); rb != nil {
+		copied := *rb
+		rebase = &copied
+	}
+	session.RUnlock()
+
+	if rebase == nil {
+		http.Error(w, "No rebase in progress", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rebase)
+}
+
+func (s *Server) handleSubmitRebasePlan(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SessionID string           `json:"sessionId"`
+		Steps     []git.RebaseStep `json:"steps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.SessionID = resolveSessionID(r, req.SessionID)
+
+	session, ok := s.SessionManager.GetSession(req.SessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.Lock()
+	err := session.SetRebasePlan(req.Steps)
+	session.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Replay through the regular command path so auditing and LFS handling apply
+	result, err := git.Dispatch(r.Context(), session, "rebase", []string{"rebase", "--continue"})
+
+	s.SessionManager.ScheduleSave(session)
+	s.SessionManager.PublishState(req.SessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newCommandResponse(result, err))
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
@@ -36,9 +39,11 @@ func (s *Server) handleGetRemoteState(w http.ResponseWriter, r *http.Request) {
 	// Passing true (ShowAll) ensures we see everything if BFS misses something,
 	// but strictly BFS from refs (false) is cleaner for "reachable".
 	// However, to debug "missing tags", let's enable ShowAll=true for Remote View.
-	stateObj := state.BuildGraphState(repo, true)
+	stateObj := state.BuildGraphState(repo)
 	// Add logic to populate shared remotes
 	stateObj.SharedRemotes = []string{name} // The requested one is definitely there.
+	perm := s.SessionManager.GetRemotePermission(name)
+	stateObj.RemotePermission = &perm
 
 	// CLEANUP FOR VISUALIZATION:
 	// The "Remote View" represents the server state.
@@ -63,6 +68,23 @@ func (s *Server) handleGetRemoteState(w http.ResponseWriter, r *http.Request) {
 	// stateObj.Remotes = []state.Remote{}               // Do not clear.
 	stateObj.RemoteBranches = make(map[string]string) // Clear remote tracking branches
 
+	// Optional ref pagination for remotes with thousands of branches/tags
+	if filter, paginate, err := parseRefFilter(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if paginate {
+		page := &state.RefPagination{}
+		branchFilter := filter
+		branchFilter.After = r.URL.Query().Get("branchCursor")
+		stateObj.Branches, page.NextBranchCursor, page.TotalBranches = state.PaginateRefMap(stateObj.Branches, branchFilter)
+		tagFilter := filter
+		tagFilter.After = r.URL.Query().Get("tagCursor")
+		stateObj.Tags, page.NextTagCursor, page.TotalTags = state.PaginateRefMap(stateObj.Tags, tagFilter)
+		stateObj.RefPagination = page
+	}
+	// Grouped after paging so the groups list only the branches returned
+	state.PopulateBranchGroups(stateObj)
+
 	// If no remotes (created bare repo), inject self as origin for UI display
 	// [FIX] Do NOT auto-inject 'origin' with pseudo-URL. This confuses users into thinking
 	// 'git remote add' succeeded with a default URL.
@@ -80,6 +102,22 @@ func (s *Server) handleGetRemoteState(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(stateObj)
 }
 
+// parseRefFilter reads the refPrefix, refLimit, branchCursor and tagCursor query parameters.
+// The boolean result reports whether any of them was supplied.
+func parseRefFilter(r *http.Request) (state.RefFilter, bool, error) {
+	q := r.URL.Query()
+	filter := state.RefFilter{Prefix: q.Get("refPrefix")}
+	if limit := q.Get("refLimit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return filter, false, fmt.Errorf("invalid refLimit: %s", limit)
+		}
+		filter.Limit = n
+	}
+	paginate := filter.Prefix != "" || filter.Limit > 0 || q.Get("branchCursor") != "" || q.Get("tagCursor") != ""
+	return filter, paginate, nil
+}
+
 func (s *Server) handleSimulateRemoteCommit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -107,15 +145,11 @@ func (s *Server) handleSimulateRemoteCommit(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Resolve Session
-	sessionID := "user-session-1"
+	sessionID := resolveSessionID(r, "")
 	session, ok := s.SessionManager.GetSession(sessionID)
 	if !ok {
-		var err error
-		session, err = s.SessionManager.CreateSession(sessionID)
-		if err != nil {
-			http.Error(w, "failed to create session: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
 	}
 
 	// Dispatch simulate-commit
@@ -135,26 +169,53 @@ func (s *Server) handleSimulateRemoteCommit(w http.ResponseWriter, r *http.Reque
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleIngestRemote starts ingesting a remote as a background job and returns
+// its ID right away; progress streams over /api/jobs/{id}/events.
 func (s *Server) handleIngestRemote(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	var req struct {
-		Name  string `json:"name"`
-		URL   string `json:"url"`
-		Depth int    `json:"depth"` // Optional: 0 means full clone
+		Name   string             `json:"name"`
+		URL    string             `json:"url"`
+		Depth  int                `json:"depth"`  // Optional: 0 means full clone
+		Access state.RemoteAccess `json:"access"` // Optional: e.g. "read-only" for an upstream to fork from
+		Token  string             `json:"token"`  // Optional: access token of a private repository
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	// Propagate Context
-	if err := s.SessionManager.IngestRemote(r.Context(), req.Name, req.URL, req.Depth); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if req.Name == "" || req.URL == "" {
+		http.Error(w, "name and url required", http.StatusBadRequest)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	if err := s.SessionManager.IngestPolicy.CheckURL(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if req.Access != "" {
+		if err := (state.RemotePermission{Access: req.Access}).Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The job outlives this request: it has its own context, cancelled through /api/jobs/{id}/cancel
+	job := s.SessionManager.SubmitJob(state.JobIngest, req.Name, func(ctx context.Context, progress *state.JobProgress) error {
+		opts := state.IngestOptions{Depth: req.Depth, Token: req.Token, Progress: progress}
+		if err := s.SessionManager.IngestRemoteWith(ctx, req.Name, req.URL, opts); err != nil {
+			return err
+		}
+		if req.Access == "" {
+			return nil
+		}
+		return s.SessionManager.SetRemotePermission(req.Name, state.RemotePermission{Access: req.Access})
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"jobId": job.ID, "job": job})
 }
 
 func (s *Server) handleResetRemote(w http.ResponseWriter, r *http.Request) {
@@ -213,6 +274,7 @@ func (s *Server) handleGetStrategies(w http.ResponseWriter, r *http.Request) {
 // CreateRemoteRequest structure
 type CreateRemoteRequest struct {
 	Name string `json:"name"`
+	state.BareRepoOptions
 }
 
 // handleCreateRemote creates a new bare repository
@@ -248,8 +310,13 @@ func (s *Server) handleCreateRemote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := req.BareRepoOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// 2. Create Repository
-	if err := s.SessionManager.CreateBareRepository(r.Context(), sessionID, req.Name); err != nil {
+	if err := s.SessionManager.CreateBareRepositoryWith(r.Context(), sessionID, req.Name, req.BareRepoOptions); err != nil {
 		if err.Error() == "invalid repository name: only alphanumeric, hyphen and underscore allowed" {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -268,6 +335,17 @@ func (s *Server) handleCreateRemote(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleRepoTemplates lists the .gitignore templates and licenses a created repository can start with.
+// GET /api/remote/templates
+func (s *Server) handleRepoTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state.RepoTemplates())
+}
+
 // handleListRemotes returns the list of currently registered shared remotes
 func (s *Server) handleListRemotes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -298,3 +376,188 @@ func (s *Server) handleListRemotes(w http.ResponseWriter, r *http.Request) {
 		"remotes": names,
 	})
 }
+
+// handleListIngests returns the ingest state of every shared remote
+func (s *Server) handleListIngests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.SessionManager.IngestStatuses())
+}
+
+// handleRemoteBranchPolicy gets (GET ?name=) or sets (POST) the branch naming policy of a shared remote
+func (s *Server) handleRemoteBranchPolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		policy := s.SessionManager.GetRemoteBranchPolicy(name)
+		if policy == nil {
+			policy = &state.BranchPolicy{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(policy)
+	case http.MethodPost:
+		var req struct {
+			Name   string             `json:"name"`
+			Policy state.BranchPolicy `json:"policy"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		if req.Policy.Pattern != "" {
+			if _, err := regexp.Compile(req.Policy.Pattern); err != nil {
+				http.Error(w, fmt.Sprintf("invalid pattern: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.SessionManager.SetRemoteBranchPolicy(req.Name, &req.Policy); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRemotePermission gets (GET ?name=) or sets (POST) who may push to a shared remote
+func (s *Server) handleRemotePermission(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.SessionManager.GetSharedRemote(name); !ok {
+			http.Error(w, "remote not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.SessionManager.GetRemotePermission(name))
+	case http.MethodPost:
+		var req struct {
+			Name       string                 `json:"name"`
+			Permission state.RemotePermission `json:"permission"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.SessionManager.GetSharedRemote(req.Name); !ok {
+			http.Error(w, "remote not found", http.StatusNotFound)
+			return
+		}
+		if err := s.SessionManager.SetRemotePermission(req.Name, req.Permission); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.SessionManager.GetRemotePermission(req.Name))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRemoteDefaultBranch reports and changes the branch HEAD of a shared remote points to.
+// GET /api/remote/default-branch?name=<remote>
+// POST /api/remote/default-branch {"name": "...", "branch": "main"}
+func (s *Server) handleRemoteDefaultBranch(w http.ResponseWriter, r *http.Request) {
+	var name string
+	switch r.Method {
+	case http.MethodGet:
+		name = r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.SessionManager.GetSharedRemote(name); !ok {
+			http.Error(w, "remote not found", http.StatusNotFound)
+			return
+		}
+	case http.MethodPost:
+		var req struct {
+			Name   string `json:"name"`
+			Branch string `json:"branch"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" || req.Branch == "" {
+			http.Error(w, "name and branch required", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.SessionManager.GetSharedRemote(req.Name); !ok {
+			http.Error(w, "remote not found", http.StatusNotFound)
+			return
+		}
+		if err := s.SessionManager.SetRemoteDefaultBranch(req.Name, req.Branch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name = req.Name
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	branch, err := s.SessionManager.RemoteDefaultBranch(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"name": name, "defaultBranch": branch})
+}
+
+// handleRenameRemoteBranch renames a branch of a shared remote, moving HEAD
+// and retargeting open pull requests with it.
+// POST /api/remote/branches/rename {"name": "...", "from": "master", "to": "main"}
+func (s *Server) handleRenameRemoteBranch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.From == "" || req.To == "" {
+		http.Error(w, "name, from and to required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.SessionManager.GetSharedRemote(req.Name); !ok {
+		http.Error(w, "remote not found", http.StatusNotFound)
+		return
+	}
+	rename, err := s.SessionManager.RenameRemoteBranch(req.Name, req.From, req.To)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rename)
+}
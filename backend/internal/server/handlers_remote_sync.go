@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// handleRemoteSync reports and configures how ingested remotes follow their upstream.
+// GET /api/remote/sync[?name=<remote>]
+// POST /api/remote/sync {"name": "...", "interval": "5m", "now": true}
+func (s *Server) handleRemoteSync(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		w.Header().Set("Content-Type", "application/json")
+		if name == "" {
+			statuses := s.SessionManager.RemoteSyncStatuses()
+			if statuses == nil {
+				statuses = []state.RemoteSyncStatus{}
+			}
+			_ = json.NewEncoder(w).Encode(statuses)
+			return
+		}
+		status, err := s.SessionManager.RemoteSyncStatus(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	case http.MethodPost:
+		var req struct {
+			Name     string  `json:"name"`
+			Interval *string `json:"interval"` // Go duration; "" or "0" stops syncing; absent keeps it
+			Now      bool    `json:"now"`      // Also sync right away, as a job
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "name required", http.StatusBadRequest)
+			return
+		}
+		status, err := s.SessionManager.RemoteSyncStatus(req.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if req.Interval != nil {
+			var interval time.Duration
+			if *req.Interval != "" {
+				if interval, err = time.ParseDuration(*req.Interval); err != nil {
+					http.Error(w, fmt.Sprintf("invalid interval %q", *req.Interval), http.StatusBadRequest)
+					return
+				}
+			}
+			if status, err = s.SessionManager.SetRemoteSync(req.Name, interval); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !req.Now {
+			_ = json.NewEncoder(w).Encode(status)
+			return
+		}
+		job := s.SessionManager.SubmitJob(state.JobSync, req.Name, func(ctx context.Context, progress *state.JobProgress) error {
+			progress.Phase(state.IngestFetching)
+			_, err := s.SessionManager.SyncRemote(ctx, req.Name)
+			return err
+		})
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jobId": job.ID, "job": job, "sync": status})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUpstreamEvents streams server-sent "upstream" events whenever a sync
+// finds that the upstream of an ingested remote moved.
+// GET /api/remote/sync/events
+func (s *Server) handleUpstreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := s.SessionManager.SubscribeUpstream()
+	defer sub.Close()
+
+	// The stream stays open for as long as the client listens
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(stateStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-sub.Ready():
+			for _, event := range sub.Next() {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: upstream\ndata: %s\n\n", event.Seq, data); err != nil {
+					return
+				}
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+func TestHandleRemoteSync(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", filepath.Join(tmp, "data"))
+
+	srcPath := filepath.Join(tmp, "upstream")
+	src, err := gogit.PlainInit(srcPath, false)
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	commit := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(srcPath, "README.md"), []byte(content), 0644))
+		_, _ = w.Add("README.md")
+		_, err := w.Commit(content, &gogit.CommitOptions{Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()}})
+		require.NoError(t, err)
+	}
+	commit("v1")
+
+	sm := git.NewSessionManager()
+	require.NoError(t, sm.IngestRemote(context.Background(), "upstream", srcPath, 0))
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	post := func(body map[string]interface{}) *http.Response {
+		data, _ := json.Marshal(body)
+		res, err := ts.Client().Post(ts.URL+"/api/remote/sync", "application/json", bytes.NewReader(data))
+		require.NoError(t, err)
+		return res
+	}
+
+	res := post(map[string]interface{}{"name": "upstream", "interval": "soon"})
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = post(map[string]interface{}{"name": "missing", "interval": "5m"})
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res = post(map[string]interface{}{"name": "upstream", "interval": "5m"})
+	var status state.RemoteSyncStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "5m0s", status.Interval)
+
+	res, err = ts.Client().Get(ts.URL + "/api/remote/sync")
+	require.NoError(t, err)
+	var statuses []state.RemoteSyncStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&statuses))
+	res.Body.Close()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "upstream", statuses[0].Remote)
+
+	// Syncing now runs as a job and publishes the upstream move
+	sub := sm.SubscribeUpstream()
+	defer sub.Close()
+	commit("v2")
+	res = post(map[string]interface{}{"name": "upstream", "now": true})
+	var started struct {
+		JobID string    `json:"jobId"`
+		Job   state.Job `json:"job"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&started))
+	res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, state.JobSync, started.Job.Kind)
+
+	select {
+	case <-sub.Ready():
+		events := sub.Next()
+		require.Len(t, events, 1)
+		assert.Equal(t, "upstream", events[0].Remote)
+		assert.Equal(t, 1, events[0].Branches[0].Commits)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no upstream event after syncing")
+	}
+}
@@ -2,17 +2,20 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
 	"github.com/kurobon/gitgym/backend/internal/mission"
+	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
 func TestHandleCreateRemote(t *testing.T) {
@@ -153,3 +156,172 @@ func TestHandleListRemotes(t *testing.T) {
 		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
 	})
 }
+
+func TestHandleRemotePermission(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", tmpDir)
+
+	sm := git.NewSessionManager()
+	s := NewServer(sm, mission.NewEngine(mission.NewLoader(tmpDir), sm))
+	_, err := sm.CreateSession("owner-session")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepository(t.Context(), "owner-session", "guarded"))
+
+	get := func() git.RemotePermission {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/remote/permission?name=guarded", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var perm git.RemotePermission
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&perm))
+		return perm
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/remote/permission", bytes.NewBufferString(body)))
+		return w
+	}
+
+	assert.Equal(t, git.RemotePermission{Access: git.RemoteAccessOpen, Owner: "owner-session"}, get(), "the creator owns a new remote")
+
+	w := post(`{"name":"guarded","permission":{"access":"owner"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, git.RemotePermission{Access: git.RemoteAccessOwner, Owner: "owner-session"}, get())
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"name":"guarded","permission":{"access":"admin"}}`).Code)
+	assert.Equal(t, http.StatusNotFound, post(`{"name":"missing","permission":{"access":"read-only"}}`).Code)
+
+	// The remote view carries the permission
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/remote/state?name=guarded", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var view struct {
+		RemotePermission git.RemotePermission `json:"remotePermission"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&view))
+	assert.Equal(t, git.RemoteAccessOwner, view.RemotePermission.Access)
+}
+
+func TestHandleCreateRemote_WithTemplates(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", tmpDir)
+
+	sm := git.NewSessionManager()
+	s := NewServer(sm, mission.NewEngine(mission.NewLoader(tmpDir), sm))
+	_, err := sm.CreateSession("creator")
+	require.NoError(t, err)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/remote/create", bytes.NewBufferString(body))
+		req.Header.Set("X-Session-ID", "creator")
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"name":"starter","readme":true,"gitignore":"Node","license":"ISC","defaultBranch":"develop"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	repo, ok := sm.GetSharedRemote("starter")
+	require.True(t, ok)
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("develop"), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	for _, name := range []string{"README.md", ".gitignore", "LICENSE"} {
+		_, err := commit.File(name)
+		assert.NoError(t, err, name)
+	}
+
+	w = create(`{"name":"broken","gitignore":"Cobol"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown .gitignore template")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/remote/templates", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var templates state.RepoTemplateList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&templates))
+	assert.Contains(t, templates.Gitignore, "Node")
+	assert.Contains(t, templates.Licenses, "ISC")
+}
+
+func TestHandleRemoteDefaultBranchAndRename(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", tmpDir)
+
+	sm := git.NewSessionManager()
+	s := NewServer(sm, mission.NewEngine(mission.NewLoader(tmpDir), sm))
+	_, err := sm.CreateSession("creator")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepositoryWith(context.Background(), "creator", "legacy", state.BareRepoOptions{DefaultBranch: "master", README: true}))
+	pr, err := sm.CreatePullRequest("Docs", "", "docs", "master", "creator", "legacy")
+	require.NoError(t, err)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+	defaultBranch := func() string {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/remote/default-branch?name=legacy", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp map[string]string
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp["defaultBranch"]
+	}
+	assert.Equal(t, "master", defaultBranch())
+
+	w := post("/api/remote/branches/rename", `{"name":"legacy","from":"master","to":"main"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rename state.BranchRename
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&rename))
+	assert.True(t, rename.DefaultBranch)
+	assert.Equal(t, []int{pr.ID}, rename.RetargetedPRs)
+	assert.Equal(t, "main", defaultBranch())
+
+	w = post("/api/remote/branches/rename", `{"name":"legacy","from":"master","to":"trunk"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("/api/remote/branches/rename", `{"name":"missing","from":"master","to":"main"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = post("/api/remote/default-branch", `{"name":"legacy","branch":"develop"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "does not exist")
+	require.NoError(t, sm.CreateBareRepository(context.Background(), "creator", "fresh"))
+	w = post("/api/remote/default-branch", `{"name":"fresh","branch":"trunk"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"defaultBranch":"trunk"`)
+}
+
+func TestHandleGetRemoteState_BranchGroupsFollowPage(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GITGYM_DATA_ROOT", tmpDir)
+
+	sm := git.NewSessionManager()
+	s := NewServer(sm, mission.NewEngine(mission.NewLoader(tmpDir), sm))
+	_, err := sm.CreateSession("owner-session")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepositoryWith(t.Context(), "owner-session", "paged", state.BareRepoOptions{README: true}))
+	repo, ok := sm.GetSharedRemote("paged")
+	require.True(t, ok)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	for _, name := range []string{"feature/a", "feature/b", "feature/c", "release/1", "release/2"} {
+		require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), head.Hash())))
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/remote/state?name=paged&refLimit=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var view state.GraphState
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&view))
+
+	require.Len(t, view.Branches, 2)
+	var grouped []string
+	for _, g := range view.BranchGroups {
+		assert.Equal(t, len(g.Branches), g.Count)
+		grouped = append(grouped, g.Branches...)
+	}
+	assert.Equal(t, []string{"feature/a", "feature/b"}, grouped, "groups list only the branches on the page")
+	assert.Equal(t, 6, view.RefPagination.TotalBranches)
+}
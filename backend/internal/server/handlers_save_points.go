@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// savePointRequest is the body of the save point endpoints.
+type savePointRequest struct {
+	Name string `json:"name"`
+}
+
+// handleSavePoints lists, takes and deletes the named save points of a
+// session, like "gitgym saves", "gitgym save <name>" and "gitgym save -d <name>".
+// GET    /api/session/snapshot?sessionId=...
+// POST   /api/session/snapshot?sessionId=...  {"name": "before rebase"} (name optional)
+// DELETE /api/session/snapshot?sessionId=...&name=...
+func (s *Server) handleSavePoints(w http.ResponseWriter, r *http.Request) {
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session.RLock()
+		points := session.SavePoints()
+		session.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]git.SavePoint{"snapshots": points})
+	case http.MethodPost:
+		var req savePointRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		session.Lock()
+		sp, err := session.CreateSavePoint(req.Name)
+		session.Unlock()
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, git.ErrSavePointExists) || errors.Is(err, git.ErrTooManySavePoints) {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(sp)
+	case http.MethodDelete:
+		session.Lock()
+		err := session.DeleteSavePoint(r.URL.Query().Get("name"))
+		session.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRestoreSavePoint rolls the session back to a save point by
+// dispatching "gitgym restore <name>", so it can be undone like a command.
+// POST /api/session/restore?sessionId=...  {"name": "before rebase"}
+func (s *Server) handleRestoreSavePoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	var req savePointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := git.Dispatch(r.Context(), session, "gitgym", []string{"gitgym", "restore", req.Name})
+	if err == nil {
+		s.SessionManager.ScheduleSave(session)
+		s.SessionManager.PublishState(sessionID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, git.ErrSavePointNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+	} else if err != nil {
+		w.WriteHeader(http.StatusConflict)
+	}
+	_ = json.NewEncoder(w).Encode(newCommandResponse(result, err))
+}
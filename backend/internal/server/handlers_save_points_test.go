@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleSavePoints(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	send := func(method, path string, body any) *http.Response {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(payload))
+		require.NoError(t, err)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	run := func(command string) {
+		resp := send(http.MethodPost, "/api/command", map[string]string{"sessionId": id, "command": command})
+		require.Equal(t, http.StatusOK, resp.StatusCode, command)
+	}
+
+	run("git init repo")
+	resp := send(http.MethodPost, "/api/session/snapshot?sessionId="+id, map[string]string{"name": "empty repo"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created git.SavePoint
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "empty repo", created.Name)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	resp = send(http.MethodPost, "/api/session/snapshot?sessionId="+id, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "the name is optional")
+	resp = send(http.MethodPost, "/api/session/snapshot?sessionId="+id, map[string]string{"name": "empty repo"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = send(http.MethodGet, "/api/session/snapshot?sessionId="+id, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Snapshots []git.SavePoint `json:"snapshots"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Snapshots, 2)
+	assert.Equal(t, "empty repo", list.Snapshots[0].Name)
+	assert.Equal(t, "save-1", list.Snapshots[1].Name)
+
+	run("cd repo")
+	run("touch a.txt")
+	run("git add a.txt")
+	run("git commit -m first")
+
+	resp = send(http.MethodPost, "/api/session/restore?sessionId="+id, map[string]string{"name": "empty repo"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var res CommandResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Contains(t, res.Output, "Restored save point 'empty repo'")
+	session, _ := sm.GetSession(id)
+	_, err := session.Filesystem.Stat("/repo/a.txt")
+	assert.Error(t, err, "the commit and its file are gone")
+
+	resp = send(http.MethodPost, "/api/session/restore?sessionId="+id, map[string]string{"name": "nope"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = send(http.MethodPost, "/api/session/restore?sessionId="+id, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = send(http.MethodDelete, "/api/session/snapshot?sessionId="+id+"&name=save-1", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = send(http.MethodDelete, "/api/session/snapshot?sessionId="+id+"&name=save-1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleSearchCommits finds the commits of the session repository matching a query.
+// GET /api/search?sessionId=...&message=<text>&regex=true&ignoreCase=true&author=<name or email>&path=<file or dir>&since=<date>&until=<date>&limit=<n>
+// Dates are RFC3339 or YYYY-MM-DD; a bare until date includes the whole day.
+func (s *Server) handleSearchCommits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := git.CommitQuery{
+		Message:    q.Get("message"),
+		Regex:      q.Get("regex") == "true",
+		IgnoreCase: q.Get("ignoreCase") == "true",
+		Author:     q.Get("author"),
+		Path:       q.Get("path"),
+	}
+	for _, key := range []string{"since", "until"} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, v); err == nil && key == "until" {
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+		}
+		if err != nil {
+			http.Error(w, "invalid "+key, http.StatusBadRequest)
+			return
+		}
+		if key == "since" {
+			query.Since = t
+		} else {
+			query.Until = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = n
+	}
+
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	defer session.RUnlock()
+
+	result, err := session.SearchCommits(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleSearchCommits(t *testing.T) {
+	sm := git.NewSessionManager()
+	session, id := newTestSession(t, sm)
+	h := asSession(NewServer(sm, nil).Mux, id)
+	repo, err := session.InitRepo("repo")
+	require.NoError(t, err)
+	session.CurrentDir = "/repo"
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	var hashes []string
+	for i, msg := range []string{"first", "second"} {
+		require.NoError(t, util.WriteFile(w.Filesystem, "a.txt", []byte(msg), 0644))
+		_, err = w.Add("a.txt")
+		require.NoError(t, err)
+		sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Date(2024, 3, 1+i, 12, 0, 0, 0, time.UTC)}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig})
+		require.NoError(t, err)
+		hashes = append(hashes, hash.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?sessionId="+id+"&path=a.txt&until=2024-03-01", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var result git.CommitSearchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	require.Len(t, result.Commits, 1)
+	assert.Equal(t, hashes[0], result.Commits[0].ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/search?sessionId="+id+"&since=yesterday", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -1,12 +1,63 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/logging"
+	"github.com/kurobon/gitgym/backend/internal/shell"
+	"github.com/kurobon/gitgym/backend/internal/state"
+)
+
+// Session identification
+//
+// /api/session/init issues a random session ID both as a cookie and as the
+// X-Session-ID response header; it is the only place a client gets a session.
+// Later requests belong to the session in the cookie or, for clients without
+// one, the X-Session-ID header. An explicit sessionId query/body field may
+// only name that session or one it owns, such as the session of a mission
+// the learner started.
+const (
+	SessionHeader = "X-Session-ID"
+	SessionCookie = "gitgym_session"
 )
 
+// resolveSessionID returns the session a request belongs to. explicit is the
+// sessionId given in the query string or request body, if any. It returns ""
+// when the request has no session or names one it may not use.
+func resolveSessionID(r *http.Request, explicit string) string {
+	caller := callerSessionID(r)
+	if caller == "" || explicit == "" || explicit == caller {
+		return caller
+	}
+	if git.OwnsSession(caller, explicit) {
+		return explicit
+	}
+	return ""
+}
+
+// callerSessionID returns the session ID in the cookie, then the
+// X-Session-ID header, of r; IDs not of the form /api/session/init issues
+// are ignored.
+func callerSessionID(r *http.Request) string {
+	if c, err := r.Cookie(SessionCookie); err == nil && git.IsIssuedSessionID(c.Value) {
+		return c.Value
+	}
+	if id := r.Header.Get(SessionHeader); git.IsIssuedSessionID(id) {
+		return id
+	}
+	return ""
+}
+
 func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
@@ -20,17 +71,312 @@ func (s *Server) handleInitSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	// Generate complex ID
-	sessionID := fmt.Sprintf("session-%d", time.Now().UnixNano())
 
-	if _, err := s.SessionManager.CreateSession(sessionID); err != nil {
+	// Resume the caller's session if it is still alive (e.g. a page reload)
+	status := "session resumed"
+	sessionID := ""
+	if id := callerSessionID(r); id != "" {
+		if _, ok := s.SessionManager.GetSession(id); ok {
+			sessionID = id
+		}
+	}
+
+	if sessionID == "" {
+		id, err := git.NewSessionID()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := s.SessionManager.CreateSession(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sessionID = id
+		status = "session created"
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set(SessionHeader, sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":    status,
+		"sessionId": sessionID,
+	})
+}
+
+// handleGetAuditLog returns the most recent commands executed in a session.
+// GET /api/session/audit?sessionId=...&limit=100
+func (s *Server) handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// An empty ID would list the audit log of every session
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.SessionManager.AuditLog(sessionID, limit))
+}
+
+// handleGetSessionLogs dumps the recent log records of a session, debug ones
+// included, to attach to a support ticket.
+// GET /api/session/logs?sessionId=...[&format=text]
+func (s *Server) handleGetSessionLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	entries := logging.SessionLog(sessionID)
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, e := range entries {
+			fmt.Fprintln(w, e.String())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+// handleImportRepository loads a user's own repository, uploaded as a zipped
+// .git directory in the request body, into the session.
+// POST /api/session/import?sessionId=...&name=myrepo
+func (s *Server) handleImportRepository(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, state.MaxImportArchiveSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("archive too large (limit %d MB)", state.MaxImportArchiveSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session.Lock()
+	result, err := session.ImportRepository(name, archive)
+	session.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.SessionManager.ScheduleSave(session)
+	s.SessionManager.PublishState(sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleExportRepository downloads a repository of the session, so learners
+// can continue with real Git: format=bundle (the default) holds the whole
+// history for "git clone <repo>.bundle", while zip, tar and tar.gz hold the
+// files of ref (HEAD by default) like git archive.
+// GET /api/session/export?sessionId=...&repo=myrepo&format=bundle&ref=HEAD
+func (s *Server) handleExportRepository(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	sessionID := resolveSessionID(r, q.Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = "bundle"
+	}
+	var ext, contentType string
+	switch format {
+	case "bundle":
+		ext, contentType = ".bundle", "application/x-git-bundle"
+	case git.ArchiveZip:
+		ext, contentType = ".zip", "application/zip"
+	case git.ArchiveTar:
+		ext, contentType = ".tar", "application/x-tar"
+	case git.ArchiveTarGz, "tgz":
+		ext, contentType = ".tar.gz", "application/gzip"
+	default:
+		http.Error(w, fmt.Sprintf("unknown format '%s' (want bundle, zip, tar or tar.gz)", format), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	session.RLock()
+	name := q.Get("repo")
+	if name == "" {
+		name = strings.TrimPrefix(session.CurrentDir, "/")
+	}
+	repo, ok := session.Repos[name]
+	var err error
+	if ok {
+		if format == "bundle" {
+			err = git.WriteBundle(repo, &buf)
+		} else {
+			ref := q.Get("ref")
+			if ref == "" {
+				ref = "HEAD"
+			}
+			var hash plumbing.Hash
+			if hash, err = git.ResolveObject(repo, ref); err == nil {
+				err = git.WriteArchive(repo, hash, git.ArchiveOptions{Format: format}, &buf)
+			}
+		}
+	}
+	session.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("repository '%s' not found", name), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)+ext))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = w.Write(buf.Bytes())
+}
+
+// maxTraceSize caps the body of a trace replay request.
+const maxTraceSize = 8 << 20
+
+// handleGetTrace returns the commands of a session and the refs each left,
+// for attaching to a bug report.
+// GET /api/session/trace?sessionId=...
+func (s *Server) handleGetTrace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	trace := session.Trace()
+	session.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "gitgym-trace.json"))
+	_ = json.NewEncoder(w).Encode(trace)
+}
+
+// handleGetTimeline returns the graph frames recorded for a session, so the
+// frontend can scrub back through it and animate what each command changed.
+// GET /api/session/timeline?sessionId=...
+func (s *Server) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	session.RLock()
+	timeline := session.Timeline()
+	session.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(timeline)
+}
+
+// handleReplayTrace replays a trace, as returned by /api/session/trace, into a
+// fresh session and reports where the replay differs from the recording.
+// POST /api/session/trace/replay
+func (s *Server) handleReplayTrace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var trace git.Trace
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTraceSize)).Decode(&trace); err != nil {
+		http.Error(w, "Invalid trace: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := git.NewSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	session, err := s.SessionManager.CreateSession(sessionID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	report, err := shell.Replay(r.Context(), session, &trace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.SessionManager.ScheduleSave(session)
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"status":    "session created",
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"sessionId": sessionID,
+		"matches":   report.Matches(),
+		"report":    report,
 	})
 }
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// simulateRequest drives the teammate scenario of a shared remote.
+type simulateRequest struct {
+	Remote   string                `json:"remote"`
+	Action   string                `json:"action"`             // start (default), run, next or stop
+	Scenario string                `json:"scenario,omitempty"` // Name of a file in the scenario directory
+	Inline   *git.TeammateScenario `json:"inline,omitempty"`   // Scenario given in the request instead
+	Manual   bool                  `json:"manual,omitempty"`   // start: wait for next instead of playing on the delays
+}
+
+// handleRemoteSimulate plays scripted teammate activity on a shared remote.
+// GET lists the available scenarios, or with ?remote= returns the run on that remote.
+// POST starts, runs through, advances or stops a run.
+func (s *Server) handleRemoteSimulate(w http.ResponseWriter, r *http.Request) {
+	sm := s.SessionManager
+	switch r.Method {
+	case http.MethodGet:
+		remote := r.URL.Query().Get("remote")
+		if remote == "" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string][]string{"scenarios": sm.ListTeammateScenarios()})
+			return
+		}
+		run, ok := sm.TeammateScenarioStatus(remote)
+		if !ok {
+			http.Error(w, "no teammate scenario is running on "+remote, http.StatusNotFound)
+			return
+		}
+		writeTeammateRun(w, run)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req simulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Remote == "" {
+		req.Remote = "origin"
+	}
+
+	switch req.Action {
+	case "", "start", "run":
+		sc := req.Inline
+		if sc == nil {
+			if req.Scenario == "" {
+				http.Error(w, "scenario or inline required", http.StatusBadRequest)
+				return
+			}
+			var err error
+			if sc, err = sm.LoadTeammateScenario(req.Scenario); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		}
+		timed := req.Action != "run" && !req.Manual
+		run, err := sm.StartTeammateScenario(req.Remote, sc, timed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for req.Action == "run" && !run.Done() {
+			if run, err = sm.AdvanceTeammateScenario(req.Remote); err != nil {
+				break
+			}
+		}
+		writeTeammateRun(w, run)
+	case "next":
+		run, err := sm.AdvanceTeammateScenario(req.Remote)
+		if run == nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeTeammateRun(w, run)
+	case "stop":
+		if err := sm.StopTeammateScenario(req.Remote); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+	default:
+		http.Error(w, "unknown action: "+req.Action, http.StatusBadRequest)
+	}
+}
+
+// writeTeammateRun encodes a run; a failed step is reported in its error field.
+func writeTeammateRun(w http.ResponseWriter, run *git.TeammateRun) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(run)
+}
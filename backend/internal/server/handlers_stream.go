@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// stateStreamKeepAlive is how often an idle state stream sends a comment, so
+// proxies do not close the connection.
+const stateStreamKeepAlive = 20 * time.Second
+
+// handleStateStream pushes the graph state of a session as server-sent events,
+// so the client no longer polls /api/state after every command. The first
+// "state" event carries the whole state; later ones only the fields that changed.
+// GET /api/state/stream?sessionId=...
+func (s *Server) handleStateStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	if _, ok := s.SessionManager.GetSession(sessionID); !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	sub, err := s.SessionManager.SubscribeState(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer sub.Close()
+
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(stateStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-sub.Ready():
+			update, ok := sub.Next()
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(update)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: state\ndata: %s\n\n", update.Seq, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
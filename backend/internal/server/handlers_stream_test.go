@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// readStateEvent reads the next "state" event of a stream, skipping comments.
+func readStateEvent(t *testing.T, r *bufio.Reader) git.StateUpdate {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event == "state" {
+				var update git.StateUpdate
+				require.NoError(t, json.Unmarshal([]byte(data), &update))
+				return update
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestHandleStateStream_PushesChanges(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/state/stream?sessionId="+id, nil)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewReader(resp.Body)
+	first := readStateEvent(t, events)
+	assert.True(t, first.Full)
+	assert.Contains(t, first.Changes, "commits")
+	assert.Equal(t, 1, sm.StateSubscribers(id))
+
+	// Writing a file pushes the new listing, and only what changed
+	body, _ := json.Marshal(map[string]string{"sessionId": id, "path": "hello.txt", "content": "hi"})
+	res, err := ts.Client().Post(ts.URL+"/api/file/write", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	update := readStateEvent(t, events)
+	assert.False(t, update.Full)
+	assert.Greater(t, update.Seq, first.Seq)
+	assert.Contains(t, string(update.Changes["files"]), "hello.txt")
+	assert.NotContains(t, update.Changes, "commits")
+}
+
+func TestCloseStreams_EndsStreamsForShutdown(t *testing.T) {
+	sm := git.NewSessionManager()
+	srv := NewServer(sm, nil)
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(srv, id))
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/api/state/stream?sessionId="+id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	readStateEvent(t, events)
+
+	srv.CloseStreams()
+	srv.CloseStreams() // Shutdown may run the hooks more than once
+	ended := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(events)
+		ended <- err
+	}()
+	select {
+	case err := <-ended:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after CloseStreams")
+	}
+
+	// A draining manager refuses commands
+	require.NoError(t, sm.Drain(context.Background()))
+	payload, _ := json.Marshal(map[string]string{"sessionId": id, "command": "git status"})
+	cmdResp, err := ts.Client().Post(ts.URL+"/api/command", "application/json", bytes.NewReader(payload))
+	require.NoError(t, err)
+	defer cmdResp.Body.Close()
+	var res CommandResponse
+	require.NoError(t, json.NewDecoder(cmdResp.Body).Decode(&res))
+	assert.Equal(t, git.ErrShuttingDown.Error(), res.Error)
+}
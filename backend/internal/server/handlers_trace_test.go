@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/kurobon/gitgym/backend/internal/shell"
+)
+
+func TestHandleTraceExportAndReplay(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	post := func(path string, body any) *http.Response {
+		payload, _ := json.Marshal(body)
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		return resp
+	}
+	for _, cmd := range []string{"git init repo && cd repo", "echo hello > a.txt && git add a.txt && git commit -m 'Add a'"} {
+		resp := post("/api/command", map[string]string{"sessionId": id, "command": cmd})
+		resp.Body.Close()
+	}
+	resp := post("/api/file/write", map[string]string{"sessionId": id, "path": "a.txt", "content": "edited\n"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err := ts.Client().Get(ts.URL + "/api/session/trace?sessionId="+id)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var trace git.Trace
+	require.NoError(t, json.Unmarshal(data, &trace))
+	require.Len(t, trace.Entries, 6)
+	assert.Equal(t, "/repo/a.txt", trace.Entries[5].File)
+
+	resp, err = ts.Client().Post(ts.URL+"/api/session/trace/replay", "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var replay struct {
+		SessionID string             `json:"sessionId"`
+		Matches   bool               `json:"matches"`
+		Report    shell.ReplayReport `json:"report"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&replay))
+	assert.True(t, replay.Matches, "%+v", replay.Report.Divergences)
+	assert.NotEqual(t, id, replay.SessionID)
+
+	session, ok := sm.GetSession(replay.SessionID)
+	require.True(t, ok, "the replay runs in a new session")
+	f, err := session.Filesystem.Open("/repo/a.txt")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "edited\n", string(content))
+
+	resp, err = ts.Client().Post(ts.URL+"/api/session/trace/replay", "application/json", bytes.NewReader([]byte(`{"version": 99, "entries": []}`)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHandleGetTimeline(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	for _, cmd := range []string{"git init repo && cd repo", "git commit --allow-empty -m first"} {
+		payload, _ := json.Marshal(map[string]string{"sessionId": id, "command": cmd})
+		resp, err := ts.Client().Post(ts.URL+"/api/command", "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/api/session/timeline?sessionId="+id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var timeline git.Timeline
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&timeline))
+	require.NotEmpty(t, timeline.Frames)
+	last := timeline.Frames[len(timeline.Frames)-1]
+	assert.Equal(t, "git commit --allow-empty -m first", last.Command)
+	assert.Equal(t, "repo", last.Repo)
+	require.Len(t, last.NewCommits, 1)
+	assert.Equal(t, last.NewCommits[0].ID, last.Refs["refs/heads/main"])
+
+	resp, err = ts.Client().Get(ts.URL + "/api/session/timeline?sessionId=missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
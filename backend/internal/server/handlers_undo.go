@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// handleUndo restores the session to the state before the last command that
+// changed it, like "gitgym undo" in the terminal.
+// POST /api/session/undo?sessionId=...
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	s.runUndoCommand(w, r, "undo")
+}
+
+// handleRedo brings back the state the last undo replaced, like "gitgym redo".
+// POST /api/session/redo?sessionId=...
+func (s *Server) handleRedo(w http.ResponseWriter, r *http.Request) {
+	s.runUndoCommand(w, r, "redo")
+}
+
+// runUndoCommand dispatches "gitgym <sub>", so the reflog, audit log and the
+// result payload are the same as when it is typed in the terminal.
+func (s *Server) runUndoCommand(w http.ResponseWriter, r *http.Request, sub string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	session, ok := s.SessionManager.GetSession(sessionID)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := git.Dispatch(r.Context(), session, "gitgym", []string{"gitgym", sub})
+	if err == nil {
+		s.SessionManager.ScheduleSave(session)
+		s.SessionManager.PublishState(sessionID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+	}
+	_ = json.NewEncoder(w).Encode(newCommandResponse(result, err))
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestHandleUndoRedo(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	ts := httptest.NewServer(asSession(NewServer(sm, nil), id))
+	defer ts.Close()
+
+	post := func(path string, body any) (int, CommandResponse) {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res CommandResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return resp.StatusCode, res
+	}
+
+	_, res := post("/api/command", map[string]string{"sessionId": id, "command": "git init repo"})
+	require.Empty(t, res.Error)
+
+	code, res := post("/api/session/undo?sessionId="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, res.Output, "init repo")
+	session, _ := sm.GetSession(id)
+	assert.NotContains(t, session.Repos, "repo")
+
+	code, _ = post("/api/session/redo?sessionId="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, session.Repos, "repo")
+
+	code, res = post("/api/session/redo?sessionId="+id, nil)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, res.Error, "nothing to redo")
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/kurobon/gitgym/backend/internal/git"
@@ -29,10 +30,7 @@ func (s *Server) handleGetWorkspaceTree(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	sessionID := r.URL.Query().Get("session")
-	if sessionID == "" {
-		sessionID = "default"
-	}
+	sessionID := resolveSessionID(r, r.URL.Query().Get("session"))
 
 	session, exists := s.SessionManager.GetSession(sessionID)
 	if !exists {
@@ -176,10 +174,7 @@ func (s *Server) handleReadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID := r.URL.Query().Get("session")
-	if sessionID == "" {
-		sessionID = "default"
-	}
+	sessionID := resolveSessionID(r, r.URL.Query().Get("session"))
 
 	filePath := r.URL.Query().Get("path")
 	if filePath == "" {
@@ -241,9 +236,7 @@ func (s *Server) handleWriteFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.SessionID == "" {
-		req.SessionID = "default"
-	}
+	req.SessionID = resolveSessionID(r, req.SessionID)
 	if req.Path == "" {
 		http.Error(w, "path field required", http.StatusBadRequest)
 		return
@@ -255,6 +248,8 @@ func (s *Server) handleWriteFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deferred before the unlock below, so it runs after it
+	defer s.SessionManager.PublishState(req.SessionID)
 	session.Lock()
 	defer session.Unlock()
 
@@ -278,6 +273,13 @@ func (s *Server) handleWriteFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to write file: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	session.RecordTrace(git.TraceEntry{
+		File:    absPath,
+		Content: req.Content,
+		Dir:     session.CurrentDir,
+		Refs:    git.RefState(session.GetRepo()),
+		Time:    time.Now(),
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
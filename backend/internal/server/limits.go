@@ -0,0 +1,203 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Environment variables read by LimitsFromEnv
+const (
+	RateLimitSessionEnv = "GITGYM_RATE_LIMIT_SESSION" // Requests per second of one session, 0 for no limit
+	RateLimitIPEnv      = "GITGYM_RATE_LIMIT_IP"      // Requests per second from one client address, 0 for no limit
+	MaxBodyEnv          = "GITGYM_MAX_BODY_KB"        // Size of a request body
+	MaxCommandEnv       = "GITGYM_MAX_COMMAND_LENGTH" // Characters of a command line
+	CommandTimeoutEnv   = "GITGYM_COMMAND_TIMEOUT"    // How long a command line may run, e.g. 30s
+)
+
+// Limits protects the API from clients sending too much. Rates allow bursts
+// of twice the rate; a zero rate, size or timeout disables that limit.
+type Limits struct {
+	SessionRate      float64
+	IPRate           float64 // A classroom behind one NAT shares it, so it is higher than SessionRate
+	MaxBodySize      int64   // Uploads check their own, larger limit
+	MaxCommandLength int
+	CommandTimeout   time.Duration
+}
+
+// DefaultLimits are generous enough for a learner typing and the frontend polling.
+func DefaultLimits() Limits {
+	return Limits{
+		SessionRate:      20,
+		IPRate:           200,
+		MaxBodySize:      1 << 20,
+		MaxCommandLength: 4096,
+		CommandTimeout:   30 * time.Second,
+	}
+}
+
+// LimitsFromEnv reads the limits from the environment. An invalid value is
+// reported and left at its default.
+func LimitsFromEnv() (Limits, error) {
+	limits := DefaultLimits()
+	var errs []error
+	number := func(env string, set func(float64)) {
+		v := os.Getenv(env)
+		if v == "" {
+			return
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+			errs = append(errs, fmt.Errorf("invalid %s %q", env, v))
+			return
+		}
+		set(n)
+	}
+	number(RateLimitSessionEnv, func(n float64) { limits.SessionRate = n })
+	number(RateLimitIPEnv, func(n float64) { limits.IPRate = n })
+	number(MaxBodyEnv, func(n float64) { limits.MaxBodySize = int64(n * 1024) })
+	number(MaxCommandEnv, func(n float64) { limits.MaxCommandLength = int(n) })
+	if v := os.Getenv(CommandTimeoutEnv); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			limits.CommandTimeout = d
+		} else {
+			errs = append(errs, fmt.Errorf("invalid %s %q", CommandTimeoutEnv, v))
+		}
+	}
+	return limits, errors.Join(errs...)
+}
+
+// ownBodyLimit lists the uploads whose handlers cap the body themselves.
+var ownBodyLimit = map[string]bool{
+	"/api/session/import":       true, // state.MaxImportArchiveSize
+	"/api/session/trace/replay": true, // maxTraceSize
+}
+
+// Guard rejects requests over the rate limits with 429 Too Many Requests and
+// bodies over MaxBodySize with 413 Request Entity Too Large.
+func (s *Server) Guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits := s.Limits
+		if r.URL.Path != "/ping" {
+			if wait := s.ipLimiter.take(clientIP(r), limits.IPRate); wait > 0 {
+				tooManyRequests(w, wait)
+				return
+			}
+			if session := requestSession(r); session != "" && !s.allowSession(w, session) {
+				return
+			}
+		}
+		if limits.MaxBodySize > 0 && r.Body != nil && !ownBodyLimit[r.URL.Path] {
+			if r.ContentLength > limits.MaxBodySize {
+				http.Error(w, fmt.Sprintf("request body too large (limit %d KB)", limits.MaxBodySize>>10), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBodySize)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowSession spends a request of the session, or answers 429 and returns
+// false. Handlers taking the session from the body call it themselves.
+func (s *Server) allowSession(w http.ResponseWriter, session string) bool {
+	if wait := s.sessionLimiter.take(session, s.Limits.SessionRate); wait > 0 {
+		tooManyRequests(w, wait)
+		return false
+	}
+	return true
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+// clientIP is the address the request came from. X-Forwarded-For is not
+// trusted: any client could set it to get a fresh budget.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter keeps a token bucket per key. Buckets that filled up again are
+// dropped, so keys of clients that left do not pile up.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time // Replaced in tests
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// take spends a token of key at rate per second and returns 0, or how long
+// to wait for the next token when none is left.
+func (l *rateLimiter) take(key string, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	burst := math.Max(1, 2*rate)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.swept) > time.Minute {
+		l.sweep(now, rate, burst)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// sweep drops the buckets that are full by now. Caller holds l.mu.
+func (l *rateLimiter) sweep(now time.Time, rate, burst float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// commandTooLong reports a command line over MaxCommandLength.
+func (s *Server) commandTooLong(command string) error {
+	if limit := s.Limits.MaxCommandLength; limit > 0 {
+		if n := utf8.RuneCountInString(command); n > limit {
+			return fmt.Errorf("command too long (%d characters, limit %d)", n, limit)
+		}
+	}
+	return nil
+}
+
+// isBodyTooLarge reports whether reading a body failed on MaxBodySize.
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
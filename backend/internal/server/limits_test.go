@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+func TestLimitsFromEnv(t *testing.T) {
+	for _, env := range []string{RateLimitSessionEnv, RateLimitIPEnv, MaxBodyEnv, MaxCommandEnv, CommandTimeoutEnv} {
+		t.Setenv(env, "")
+	}
+	limits, err := LimitsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLimits(), limits)
+
+	t.Setenv(RateLimitSessionEnv, "0")
+	t.Setenv(RateLimitIPEnv, "2.5")
+	t.Setenv(MaxBodyEnv, "64")
+	t.Setenv(CommandTimeoutEnv, "5s")
+	limits, err = LimitsFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, limits.SessionRate)
+	assert.Equal(t, 2.5, limits.IPRate)
+	assert.Equal(t, int64(64<<10), limits.MaxBodySize)
+	assert.Equal(t, 5*time.Second, limits.CommandTimeout)
+
+	t.Setenv(MaxCommandEnv, "-1")
+	t.Setenv(CommandTimeoutEnv, "soon")
+	limits, err = LimitsFromEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), MaxCommandEnv)
+	assert.Contains(t, err.Error(), CommandTimeoutEnv)
+	assert.Equal(t, DefaultLimits().MaxCommandLength, limits.MaxCommandLength)
+	assert.Equal(t, DefaultLimits().CommandTimeout, limits.CommandTimeout)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	// A burst of twice the rate, then one request per 1/rate
+	for i := 0; i < 4; i++ {
+		assert.Zero(t, l.take("a", 2), "request %d", i)
+	}
+	assert.Equal(t, 500*time.Millisecond, l.take("a", 2))
+	assert.Zero(t, l.take("b", 2), "keys have their own bucket")
+	now = now.Add(500 * time.Millisecond)
+	assert.Zero(t, l.take("a", 2))
+	assert.Positive(t, l.take("a", 2))
+	assert.Zero(t, l.take("a", 0), "a zero rate does not limit")
+
+	// Full buckets are dropped
+	now = now.Add(2 * time.Minute)
+	l.take("c", 2)
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestGuard(t *testing.T) {
+	sm := git.NewSessionManager()
+	srv := NewServer(sm, nil)
+	srv.Limits = Limits{SessionRate: 1, IPRate: 100, MaxBodySize: 256, MaxCommandLength: 20, CommandTimeout: time.Second}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	send := func(session, method, path string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, body)
+		require.NoError(t, err)
+		req.Header.Set(SessionHeader, session)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	command := func(session, line string) *http.Response {
+		payload, _ := json.Marshal(map[string]string{"command": line})
+		return send(session, http.MethodPost, "/api/command", bytes.NewReader(payload))
+	}
+	_, hammer := newTestSession(t, sm)
+	_, someoneElse := newTestSession(t, sm)
+	_, poller := newTestSession(t, sm)
+
+	t.Run("Session rate", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, command(hammer, "git init").StatusCode)
+		assert.Equal(t, http.StatusOK, command(hammer, "git status").StatusCode)
+		resp := command(hammer, "git status")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, command(someoneElse, "git status").StatusCode)
+
+		// Sessions are limited before the handler runs
+		for i := 0; i < 2; i++ {
+			resp := send(poller, http.MethodGet, "/api/session/audit", nil)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+		resp = send(poller, http.MethodGet, "/api/session/audit", nil)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, command(someoneElse, "git commit -m "+strings.Repeat("x", 30)).StatusCode)
+
+		body := `{"command":"git status","pad":"` + strings.Repeat("x", 300) + `"}`
+		resp, err := ts.Client().Post(ts.URL+"/api/command", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("IP rate", func(t *testing.T) {
+		strict := NewServer(sm, nil)
+		strict.Limits.IPRate = 0.5
+		ts := httptest.NewServer(strict)
+		defer ts.Close()
+
+		var statuses []int
+		for i := 0; i < 3; i++ {
+			resp, err := ts.Client().Get(ts.URL + "/api/remote/list")
+			require.NoError(t, err)
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+		}
+		assert.Contains(t, statuses, http.StatusTooManyRequests)
+
+		resp, err := ts.Client().Get(ts.URL + "/ping")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "health checks are not limited")
+	})
+}
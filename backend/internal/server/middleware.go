@@ -1,12 +1,20 @@
 package server
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"time"
+
+	"github.com/kurobon/gitgym/backend/internal/logging"
 )
 
+// RequestIDHeader carries the ID a request is logged under. A client may
+// send its own, e.g. to match its logs with ours; it is echoed back.
+const RequestIDHeader = "X-Request-ID"
+
 // Middleware type definition
 type Middleware func(http.Handler) http.Handler
 
@@ -23,7 +31,7 @@ func Recoverer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("PANIC: %v\n%s", err, debug.Stack())
+				slog.ErrorContext(r.Context(), "panic", "path", r.URL.Path, "err", err, "stack", string(debug.Stack()))
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -31,15 +39,87 @@ func Recoverer(next http.Handler) http.Handler {
 	})
 }
 
-// Logger logs the request details
+// RequestContext gives every request an ID and puts it, with the session the
+// request names, in the request context, so everything logged while serving
+// it can be told apart.
+func RequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logging.WithRequestID(r.Context(), id)
+		if session := requestSession(r); session != "" {
+			ctx = logging.WithSession(ctx, session)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestSession returns the session a request uses, as resolveSessionID
+// finds it from its cookie, header and sessionId query field.
+func requestSession(r *http.Request) string {
+	return resolveSessionID(r, r.URL.Query().Get("sessionId"))
+}
+
+// validRequestID accepts short IDs of printable ASCII, so a client cannot
+// inject arbitrary text into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Logger logs each request with its status and duration
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
 	})
 }
 
+// statusRecorder remembers the status a handler wrote. Unwrap lets
+// http.ResponseController reach the flusher and deadlines of streams.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Flush keeps streams working for handlers that assert http.Flusher directly.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // CORS adds Cross-Origin Resource Sharing headers
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,7 +127,8 @@ func CORS(next http.Handler) http.Handler {
 		// For local dev/electron, allowing * is often acceptable but strictly we should check origin.
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-ID, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Session-ID, X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -2,9 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
-	"strings"
 
+	"github.com/kurobon/gitgym/backend/internal/i18n"
 	"github.com/kurobon/gitgym/backend/internal/mission"
 )
 
@@ -22,6 +23,14 @@ type VerifyMissionRequest struct {
 	MissionID string `json:"missionId"`
 }
 
+// requestLang picks the mission language from Accept-Language.
+func requestLang(r *http.Request) string {
+	if lang := i18n.Match(r.Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return i18n.Default
+}
+
 func (s *Server) handleListMissions(w http.ResponseWriter, r *http.Request) {
 	missions, err := s.MissionEngine.Loader.ListMissions()
 	if err != nil {
@@ -29,33 +38,10 @@ func (s *Server) handleListMissions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Localization Logic
-	lang := "en" // default
-	acceptLang := r.Header.Get("Accept-Language")
-	// Simple detection for Japanese. For production, consider using x/text/language.
-	if strings.Contains(strings.ToLower(acceptLang), "ja") {
-		lang = "ja"
-	}
-
-	if lang != "en" {
+	if lang := requestLang(r); lang != "en" {
 		localizedMissions := make([]*mission.Mission, len(missions))
 		for i, m := range missions {
-			// Copy the mission struct by dereferencing
-			val := *m
-			localized := &val
-
-			if trans, ok := m.Translations[lang]; ok {
-				if trans.Title != "" {
-					localized.Title = trans.Title
-				}
-				if trans.Description != "" {
-					localized.Description = trans.Description
-				}
-				if len(trans.Hints) > 0 {
-					localized.Hints = trans.Hints
-				}
-			}
-			localizedMissions[i] = localized
+			localizedMissions[i] = m.Localized(lang)
 		}
 		missions = localizedMissions
 	}
@@ -76,11 +62,17 @@ func (s *Server) handleStartMission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID, err := s.MissionEngine.StartMission(r.Context(), req.MissionID)
+	learner := resolveSessionID(r, "")
+	if _, ok := s.SessionManager.GetSession(learner); !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	sessionID, err := s.MissionEngine.StartMission(r.Context(), learner, req.MissionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.SessionManager.RecordMissionStart(learner, req.MissionID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(StartMissionResponse{
@@ -101,12 +93,159 @@ func (s *Server) handleVerifyMission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.MissionEngine.VerifyMission(req.SessionID, req.MissionID)
+	sessionID := resolveSessionID(r, req.SessionID)
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	result, err := s.MissionEngine.VerifyMission(sessionID, req.MissionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Progress belongs to the learner's own session, not the temporary mission session
+	passed := 0
+	for _, p := range result.Progress {
+		if p.Passed {
+			passed++
+		}
+	}
+	learner := resolveSessionID(r, "")
+	s.SessionManager.RecordMissionProgress(learner, req.MissionID, passed, len(result.Progress))
+	if result.TotalSteps > 0 {
+		s.SessionManager.RecordMissionSteps(learner, req.MissionID, result.CurrentStep, result.TotalSteps)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
+
+// handleMissionHint returns the next hint for a mission session, based on the
+// checks that currently fail, and counts it in the learner's progress.
+// POST /api/mission/hint {sessionId, missionId}
+func (s *Server) handleMissionHint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req VerifyMissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sessionID := resolveSessionID(r, req.SessionID)
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	learner := resolveSessionID(r, "")
+	used := 0
+	for _, p := range s.SessionManager.MissionProgressFor(learner) {
+		if p.MissionID == req.MissionID {
+			used = p.HintsUsed
+		}
+	}
+
+	hint, err := s.MissionEngine.NextHint(sessionID, req.MissionID, requestLang(r), used)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hint.HintsUsed = used
+	if hint.Hint != "" {
+		hint.HintsUsed = s.SessionManager.RecordMissionHint(learner, req.MissionID).HintsUsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hint)
+}
+
+// handleValidateMissions reports problems in mission files for course authors.
+// GET validates every mission on disk, replaying their setups;
+// POST validates the mission document (YAML or JSON) in the request body.
+// GET/POST /api/missions/validate
+func (s *Server) handleValidateMissions(w http.ResponseWriter, r *http.Request) {
+	var report *mission.ValidationReport
+	switch r.Method {
+	case http.MethodGet:
+		var err error
+		report, err = s.MissionEngine.ValidateMissions(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxMissionDocumentSize))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		report = s.MissionEngine.ValidateDocument(r.Context(), data)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// maxMissionDocumentSize caps mission documents posted for validation.
+const maxMissionDocumentSize = 1 << 20
+
+// handleGetMissionProgress lists the mission progress recorded for a session.
+// GET /api/mission/progress?sessionId=...
+func (s *Server) handleGetMissionProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := resolveSessionID(r, r.URL.Query().Get("sessionId"))
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.SessionManager.MissionProgressFor(sessionID))
+}
+
+// handleGenerateLessons scaffolds missions from the history of an ingested remote.
+// POST /api/mission/generate {remote, maxCommits, maxMoments, longLivedDays}
+func (s *Server) handleGenerateLessons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Remote        string `json:"remote"`
+		MaxCommits    int    `json:"maxCommits"`
+		MaxMoments    int    `json:"maxMoments"`
+		LongLivedDays int    `json:"longLivedDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Remote == "" {
+		http.Error(w, "remote required", http.StatusBadRequest)
+		return
+	}
+
+	lessons, err := s.MissionEngine.GenerateLessons(req.Remote, mission.AnalyzeOptions{
+		MaxCommits:    req.MaxCommits,
+		MaxMoments:    req.MaxMoments,
+		LongLivedDays: req.LongLivedDays,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lessons)
+}
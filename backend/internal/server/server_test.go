@@ -3,12 +3,15 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/kurobon/gitgym/backend/internal/git"
+	_ "github.com/kurobon/gitgym/backend/internal/git/commands" // Register commands
 	"github.com/kurobon/gitgym/backend/internal/state"
 )
 
@@ -19,8 +22,10 @@ func TestServerEndpoints(t *testing.T) {
 	ts := httptest.NewServer(srv)
 	defer ts.Close()
 
-	client := ts.Client()
-	sessionID := "user-session-1" // Matches hardcoded ID in handlers
+	// The session cookie of /api/session/init identifies the client from then on
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	var sessionID string
 
 	// 1. Ping
 	t.Run("Ping", func(t *testing.T) {
@@ -83,12 +88,12 @@ func TestServerEndpoints(t *testing.T) {
 		}
 		defer resp.Body.Close()
 
-		var res map[string]string
+		var res CommandResponse
 		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
 			t.Fatalf("Failed to decode response: %v", err)
 		}
 
-		output := res["output"]
+		output := res.Output
 		if !strings.Contains(output, "On branch main") && !strings.Contains(output, "No commits yet") {
 			// Exact output depends on git version/implementation but checking basics
 			// "On branch main" or "master"
@@ -96,6 +101,30 @@ func TestServerEndpoints(t *testing.T) {
 		}
 	})
 
+	// 4b. Exec a chained command line
+	t.Run("Chained Command", func(t *testing.T) {
+		reqBody, _ := json.Marshal(map[string]string{
+			"sessionId": sessionID,
+			"command":   "echo 'hello world' > a.txt && cat a.txt",
+		})
+		resp, err := client.Post(ts.URL+"/api/command", "application/json", bytes.NewBuffer(reqBody))
+		if err != nil {
+			t.Fatalf("Failed to exec command: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var res CommandResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if res.Output != "hello world" || res.Error != "" {
+			t.Errorf("Expected the file content, got output %q error %q", res.Output, res.Error)
+		}
+		if len(res.Results) != 2 {
+			t.Errorf("Expected 2 command results, got %d", len(res.Results))
+		}
+	})
+
 	// 5. Get Graph State
 	t.Run("Get Graph State", func(t *testing.T) {
 		resp, err := client.Get(ts.URL + "/api/state?sessionId=" + sessionID)
@@ -117,6 +146,60 @@ func TestServerEndpoints(t *testing.T) {
 		}
 	})
 
+	// 5b. Paged graph state and state deltas
+	t.Run("Paged State And Delta", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/api/state?limit=-1&sessionId=" + sessionID)
+		if err != nil {
+			t.Fatalf("GET state failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a negative limit, got %d", resp.StatusCode)
+		}
+
+		resp, err = client.Get(ts.URL + "/api/state?limit=10&depth=5&sessionId=" + sessionID)
+		if err != nil {
+			t.Fatalf("GET state failed: %v", err)
+		}
+		var stateObj state.GraphState
+		err = json.NewDecoder(resp.Body).Decode(&stateObj)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode graph state: %v", err)
+		}
+		if stateObj.CommitPagination == nil {
+			t.Error("Expected commit pagination info in a paged state")
+		}
+
+		resp, err = client.Get(ts.URL + "/api/state/delta?sessionId=" + sessionID)
+		if err != nil {
+			t.Fatalf("GET delta failed: %v", err)
+		}
+		var delta state.StateDelta
+		err = json.NewDecoder(resp.Body).Decode(&delta)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode state delta: %v", err)
+		}
+		if !delta.Full || delta.Version == 0 {
+			t.Errorf("Expected a full delta with a version, got full=%v version=%d", delta.Full, delta.Version)
+		}
+
+		resp, err = client.Get(fmt.Sprintf("%s/api/state/delta?since=%d&sessionId=%s", ts.URL, delta.Version, sessionID))
+		if err != nil {
+			t.Fatalf("GET delta failed: %v", err)
+		}
+		var next state.StateDelta
+		err = json.NewDecoder(resp.Body).Decode(&next)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode state delta: %v", err)
+		}
+		if next.Full || next.Version != delta.Version || len(next.Changes) != 0 {
+			t.Errorf("Expected an empty delta at version %d, got %+v", delta.Version, next)
+		}
+	})
+
 	// 6. Invalid Method
 	t.Run("Invalid Method", func(t *testing.T) {
 		resp, err := client.Get(ts.URL + "/api/command") // Should be POST
@@ -129,3 +212,186 @@ func TestServerEndpoints(t *testing.T) {
 		}
 	})
 }
+
+func TestSessionIsolation_CookieRouting(t *testing.T) {
+	sm := git.NewSessionManager()
+	ts := httptest.NewServer(NewServer(sm, nil))
+	defer ts.Close()
+
+	newClient := func() (*http.Client, string) {
+		jar, _ := cookiejar.New(nil)
+		client := &http.Client{Jar: jar}
+		resp, err := client.Post(ts.URL+"/api/session/init", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to init session: %v", err)
+		}
+		defer resp.Body.Close()
+		id := resp.Header.Get(SessionHeader)
+		if !strings.HasPrefix(id, "session-") || len(id) < 40 {
+			t.Fatalf("Expected a random session id, got %q", id)
+		}
+		return client, id
+	}
+	alice, aliceID := newClient()
+	bob, bobID := newClient()
+	if aliceID == bobID {
+		t.Fatal("Expected distinct session ids")
+	}
+
+	// No sessionId anywhere: the cookie decides which session is used
+	reqBody, _ := json.Marshal(map[string]string{"path": "notes.txt", "content": "alice"})
+	resp, err := alice.Post(ts.URL+"/api/file/write", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 writing to the cookie's session, got %d", resp.StatusCode)
+	}
+
+	readStatus := func(client *http.Client) int {
+		resp, err := client.Get(ts.URL + "/api/file/read?path=notes.txt")
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := readStatus(alice); got != http.StatusOK {
+		t.Errorf("Expected the file in alice's session, got %d", got)
+	}
+	if got := readStatus(bob); got != http.StatusNotFound {
+		t.Errorf("Expected bob's session to be unaffected, got %d", got)
+	}
+
+	reqBody, _ = json.Marshal(map[string]string{"command": "pwd"})
+	resp, err = bob.Post(ts.URL+"/api/command", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to exec command: %v", err)
+	}
+	resp.Body.Close()
+	if _, ok := sm.GetSession("user-session-1"); ok {
+		t.Error("Expected commands to be routed by cookie, not to a default session")
+	}
+
+	// Naming another session does not override the cookie
+	for _, target := range []string{aliceID, "user-session-1", ".."} {
+		resp, err = bob.Get(ts.URL + "/api/file/read?path=notes.txt&sessionId=" + target)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected bob to be refused session %q, got %d", target, resp.StatusCode)
+		}
+		reqBody, _ = json.Marshal(map[string]string{"sessionId": target, "command": "touch bob.txt"})
+		resp, err = http.Post(ts.URL+"/api/command", "application/json", bytes.NewBuffer(reqBody))
+		if err != nil {
+			t.Fatalf("Failed to exec command: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected a request without a session to be refused %q, got %d", target, resp.StatusCode)
+		}
+	}
+	for _, target := range []string{"user-session-1", ".."} {
+		if _, ok := sm.GetSession(target); ok {
+			t.Errorf("Expected only /api/session/init to create sessions, found %q", target)
+		}
+	}
+
+	// A session alice owns, like the session of her mission, may be named
+	owned := state.OwnedSessionID(aliceID, "mission-101-first-commit")
+	if _, err := sm.CreateSession(owned); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	auditStatus := func(client *http.Client, query string) int {
+		resp, err := client.Get(ts.URL + "/api/session/audit" + query)
+		if err != nil {
+			t.Fatalf("Failed to get audit log: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := auditStatus(alice, "?sessionId="+owned); got != http.StatusOK {
+		t.Errorf("Expected alice to use her own mission session, got %d", got)
+	}
+	if got := auditStatus(bob, "?sessionId="+owned); got != http.StatusNotFound {
+		t.Errorf("Expected bob to be refused alice's mission session, got %d", got)
+	}
+	if got := auditStatus(http.DefaultClient, ""); got != http.StatusNotFound {
+		t.Errorf("Expected a request without a session to be refused the audit log, got %d", got)
+	}
+
+	// Re-initializing with the cookie resumes the same session
+	resp, err = alice.Post(ts.URL+"/api/session/init", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to re-init session: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get(SessionHeader); got != aliceID {
+		t.Errorf("Expected session %s to be resumed, got %s", aliceID, got)
+	}
+}
+
+func TestExecCommand_Language(t *testing.T) {
+	sm := git.NewSessionManager()
+	_, id := newTestSession(t, sm)
+	s := NewServer(sm, nil)
+
+	exec := func(command, acceptLanguage string) CommandResponse {
+		body, _ := json.Marshal(CommandRequest{SessionID: id, Command: command})
+		req := httptest.NewRequest(http.MethodPost, "/api/command", bytes.NewReader(body))
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		req.Header.Set(SessionHeader, id)
+		rec := httptest.NewRecorder()
+		s.Mux.ServeHTTP(rec, req)
+		var resp CommandResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if out := exec("git help", "").Output; !strings.Contains(out, "Start a working area") {
+		t.Errorf("Expected the English index by default, got: %s", out)
+	}
+	if out := exec("git help", "ja-JP,ja;q=0.9,en;q=0.8").Output; !strings.Contains(out, "作業場所を用意する") {
+		t.Errorf("Expected the Japanese index, got: %s", out)
+	}
+	// The session keeps the language for requests that do not state one
+	if resp := exec("git comit", ""); resp.Result == nil || resp.Result.Suggestion == nil || resp.Result.Suggestion.Message != "'commit' のことですか？" {
+		t.Errorf("Expected a Japanese suggestion, got: %+v", resp.Result)
+	}
+	if out := exec("git help", "en-US,en").Output; !strings.Contains(out, "Start a working area") {
+		t.Errorf("Expected the English index again, got: %s", out)
+	}
+}
+
+// newTestSession creates a session under an ID of the form /api/session/init
+// issues, the only IDs requests are routed to.
+func newTestSession(t *testing.T, sm *git.SessionManager) (*git.Session, string) {
+	t.Helper()
+	id, err := git.NewSessionID()
+	if err != nil {
+		t.Fatalf("Failed to generate session id: %v", err)
+	}
+	session, err := sm.CreateSession(id)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	return session, id
+}
+
+// asSession serves h as if every request came from a client of the session
+// id: requests without an X-Session-ID header get id.
+func asSession(h http.Handler, id string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(SessionHeader) == "" {
+			r.Header.Set(SessionHeader, id)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
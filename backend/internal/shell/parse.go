@@ -0,0 +1,227 @@
+// Package shell interprets the command lines typed in the terminal and listed
+// in mission setups: quoting, ";", "&&" and "||" chaining, "|" pipelines and
+// ">", ">>" and "<" redirection around the registered commands.
+//
+// It is a small subset of POSIX sh. There are no variables, globs, subshells
+// or background jobs; every command name is resolved through the git command
+// registry, so shell builtins (cd, cat, rm, ...) and git commands behave the
+// same whether they come from a learner or a mission.
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Operators joining pipelines.
+const (
+	OpSeq = ";"  // Run the next pipeline regardless
+	OpAnd = "&&" // Run the next pipeline if this one succeeded
+	OpOr  = "||" // Run the next pipeline if this one failed
+)
+
+// Script is a parsed command line.
+type Script struct {
+	Items []Item
+}
+
+// Item is a pipeline and the operator joining it to the next item.
+type Item struct {
+	Pipeline []SimpleCommand
+	Op       string // OpSeq, OpAnd or OpOr; empty for the last item
+}
+
+// SimpleCommand is one command with its arguments and redirections.
+type SimpleCommand struct {
+	Words     []string
+	Redirects []Redirect
+}
+
+// Redirect sends the output of a command to a file (">" truncates, ">>"
+// appends) or feeds a file to its input ("<").
+type Redirect struct {
+	Op     string
+	Target string
+}
+
+// Commands returns every simple command of the script, in order.
+func (s *Script) Commands() []SimpleCommand {
+	var cmds []SimpleCommand
+	for _, item := range s.Items {
+		cmds = append(cmds, item.Pipeline...)
+	}
+	return cmds
+}
+
+type token struct {
+	text string
+	op   bool // An unquoted operator rather than a word
+}
+
+// Parse splits a command line into pipelines and commands.
+func Parse(line string) (*Script, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+
+	script := &Script{}
+	var pipeline []SimpleCommand
+	var cmd SimpleCommand
+	empty := func(c SimpleCommand) bool { return len(c.Words) == 0 && len(c.Redirects) == 0 }
+	unexpected := func(op string) error {
+		return fmt.Errorf("syntax error near unexpected token `%s'", op)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if !tok.op {
+			cmd.Words = append(cmd.Words, tok.text)
+			continue
+		}
+		switch tok.text {
+		case ">", ">>", "<":
+			if i+1 >= len(tokens) || tokens[i+1].op {
+				if i+1 < len(tokens) {
+					return nil, unexpected(tokens[i+1].text)
+				}
+				return nil, fmt.Errorf("syntax error near unexpected token `newline'")
+			}
+			i++
+			cmd.Redirects = append(cmd.Redirects, Redirect{Op: tok.text, Target: tokens[i].text})
+		case "|":
+			if empty(cmd) {
+				return nil, unexpected(tok.text)
+			}
+			pipeline = append(pipeline, cmd)
+			cmd = SimpleCommand{}
+		case OpSeq, OpAnd, OpOr:
+			if empty(cmd) {
+				return nil, unexpected(tok.text)
+			}
+			pipeline = append(pipeline, cmd)
+			script.Items = append(script.Items, Item{Pipeline: pipeline, Op: tok.text})
+			pipeline, cmd = nil, SimpleCommand{}
+		}
+	}
+
+	if !empty(cmd) {
+		pipeline = append(pipeline, cmd)
+	} else if len(pipeline) > 0 {
+		return nil, fmt.Errorf("syntax error: unexpected end of input after `|'")
+	}
+	if len(pipeline) > 0 {
+		script.Items = append(script.Items, Item{Pipeline: pipeline})
+	} else if n := len(script.Items); n > 0 {
+		// A trailing ";" is fine, a trailing "&&" or "||" is not
+		if script.Items[n-1].Op != OpSeq {
+			return nil, fmt.Errorf("syntax error: unexpected end of input after `%s'", script.Items[n-1].Op)
+		}
+		script.Items[n-1].Op = ""
+	}
+	return script, nil
+}
+
+// tokenize splits a line into words and operators, removing quotes.
+// Single quotes keep everything literal; inside double quotes a backslash
+// only escapes '"' and '\'. An unquoted '#' starting a word begins a comment.
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	inWord := false // Distinguishes "" (an empty word) from no word
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+	operator := func(op string) {
+		flush()
+		tokens = append(tokens, token{text: op, op: true})
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("syntax error: unclosed quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("syntax error: unclosed quote")
+			}
+			inWord = true
+		case r == '\\':
+			if next != 0 {
+				i++
+				word.WriteRune(next)
+			} else {
+				word.WriteRune(r)
+			}
+			inWord = true
+		case r == '#' && !inWord:
+			flush()
+			return tokens, nil
+		case r == ';' || r == '\n':
+			operator(OpSeq)
+		case r == '&':
+			if next != '&' {
+				return nil, fmt.Errorf("background jobs (&) are not supported")
+			}
+			i++
+			operator(OpAnd)
+		case r == '|':
+			if next == '|' {
+				i++
+				operator(OpOr)
+			} else {
+				operator("|")
+			}
+		case r == '>':
+			if next == '>' {
+				i++
+				operator(">>")
+			} else {
+				operator(">")
+			}
+		case r == '<':
+			operator("<")
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Quoting(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`git commit -m "First commit"`, []string{"git", "commit", "-m", "First commit"}},
+		{`echo 'a "b" c'`, []string{"echo", `a "b" c`}},
+		{`echo "say \"hi\""`, []string{"echo", `say "hi"`}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo "" x`, []string{"echo", "", "x"}},
+		{`echo pre"mid"'post'`, []string{"echo", "premidpost"}},
+		{`echo 'a;b&&c|d'`, []string{"echo", "a;b&&c|d"}},
+		{`git log # a comment`, []string{"git", "log"}},
+		{`echo a#b`, []string{"echo", "a#b"}},
+	}
+	for _, tt := range tests {
+		script, err := Parse(tt.line)
+		require.NoError(t, err, tt.line)
+		cmds := script.Commands()
+		require.Len(t, cmds, 1, tt.line)
+		assert.Equal(t, tt.want, cmds[0].Words, tt.line)
+	}
+}
+
+func TestParse_Operators(t *testing.T) {
+	script, err := Parse("git init && echo hi > a.txt; cat < a.txt | cat || echo failed")
+	require.NoError(t, err)
+	require.Len(t, script.Items, 4)
+
+	assert.Equal(t, OpAnd, script.Items[0].Op)
+	assert.Equal(t, []string{"git", "init"}, script.Items[0].Pipeline[0].Words)
+
+	assert.Equal(t, OpSeq, script.Items[1].Op)
+	assert.Equal(t, []Redirect{{Op: ">", Target: "a.txt"}}, script.Items[1].Pipeline[0].Redirects)
+
+	assert.Equal(t, OpOr, script.Items[2].Op)
+	require.Len(t, script.Items[2].Pipeline, 2)
+	assert.Equal(t, []Redirect{{Op: "<", Target: "a.txt"}}, script.Items[2].Pipeline[0].Redirects)
+
+	assert.Equal(t, "", script.Items[3].Op)
+	assert.Len(t, script.Commands(), 5)
+
+	// A trailing ";" ends the line
+	script, err = Parse("git status;")
+	require.NoError(t, err)
+	require.Len(t, script.Items, 1)
+	assert.Equal(t, "", script.Items[0].Op)
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	for _, line := range []string{
+		`echo "unclosed`,
+		`echo 'unclosed`,
+		`&& git status`,
+		`git status &&`,
+		`git status | `,
+		`echo a > `,
+		`echo a > | cat`,
+		`git status ; ; git log`,
+		`git fetch &`,
+	} {
+		_, err := Parse(line)
+		assert.Error(t, err, line)
+	}
+}
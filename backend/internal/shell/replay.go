@@ -0,0 +1,223 @@
+package shell
+
+// replay.go - Re-running a recorded session trace
+//
+// Replay runs the commands of a trace (see state/trace.go) in a fresh session:
+// command lines go through the shell again, so redirections and pipes behave
+// as recorded. Commits get new hashes in the replay, since their timestamps
+// differ, so refs are compared through the commit each recorded hash turned
+// into, and hashes in command arguments are rewritten the same way.
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// ReplayReport is the outcome of a trace replay.
+type ReplayReport struct {
+	Commands    int          `json:"commands"`    // Commands and file writes replayed
+	Divergences []Divergence `json:"divergences"` // Where the replay differs from the recording, in order
+}
+
+// Divergence is a recorded command whose replay ended differently.
+type Divergence struct {
+	Seq     int    `json:"seq"` // Entry of the recorded trace
+	Command string `json:"command"`
+	Message string `json:"message"`
+}
+
+// Matches reports whether the replay reproduced the recording.
+func (r *ReplayReport) Matches() bool {
+	return len(r.Divergences) == 0
+}
+
+// abbreviatedHash matches words that may be (abbreviated) commit hashes.
+var abbreviatedHash = regexp.MustCompile(`\b[0-9a-f]{4,40}\b`)
+
+// replayer carries the state of one replay.
+type replayer struct {
+	s      *git.Session
+	hashes map[string]string // Recorded commit hash -> the hash it has in the replay
+	report *ReplayReport
+}
+
+// Replay runs the commands of t in s, which should be a fresh session, and
+// reports where the outcome differs from the recording. Commands that only
+// opened the client's editor are skipped: the trace also holds the command
+// the editor resumed, with its message.
+func Replay(ctx context.Context, s *git.Session, t *git.Trace) (*ReplayReport, error) {
+	if t.Version != git.TraceVersion {
+		return nil, fmt.Errorf("unsupported trace version %d (want %d)", t.Version, git.TraceVersion)
+	}
+	r := &replayer{s: s, hashes: make(map[string]string), report: &ReplayReport{Divergences: []Divergence{}}}
+
+	entries := make([]git.TraceEntry, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		if !e.Editor {
+			entries = append(entries, e)
+		}
+	}
+	for i := 0; i < len(entries); {
+		// The commands of one shell line run together
+		j := i + 1
+		for entries[i].LineSeq != 0 && j < len(entries) && entries[j].LineSeq == entries[i].LineSeq {
+			j++
+		}
+		if err := r.run(ctx, entries[i:j]); err != nil {
+			return r.report, err
+		}
+		i = j
+	}
+	return r.report, nil
+}
+
+// run replays one group of recorded entries and compares what it recorded in
+// the session with them.
+func (r *replayer) run(ctx context.Context, group []git.TraceEntry) error {
+	first := group[0]
+	r.s.RLock()
+	recorded := r.s.Trace().Entries
+	r.s.RUnlock()
+	lastSeq := 0
+	if len(recorded) > 0 {
+		lastSeq = recorded[len(recorded)-1].Seq
+	}
+
+	r.s.Lock()
+	if first.Dir != "" {
+		r.s.CurrentDir = first.Dir
+	}
+	r.s.Unlock()
+
+	switch {
+	case first.File != "":
+		r.s.Lock()
+		err := util.WriteFile(r.s.Filesystem, path.Clean(first.File), []byte(first.Content), 0644)
+		if err == nil {
+			r.s.RecordTrace(git.TraceEntry{File: first.File, Content: first.Content, Dir: r.s.CurrentDir, Refs: git.RefState(r.s.GetRepo())})
+		}
+		r.s.Unlock()
+		if err != nil {
+			return fmt.Errorf("replay failed to write %s: %w", first.File, err)
+		}
+	case first.Line != "":
+		_, _ = Run(ctx, r.s, r.rewrite(first.Line))
+	default:
+		args := make([]string, len(first.Args))
+		for i, arg := range first.Args {
+			args[i] = r.rewrite(arg)
+		}
+		runCtx := ctx
+		if first.Message != "" {
+			runCtx = git.WithEditedMessage(ctx, first.Message)
+		}
+		_, _ = git.Dispatch(runCtx, r.s, first.Command, args)
+	}
+	r.report.Commands += len(group)
+
+	r.s.RLock()
+	var replayed []git.TraceEntry
+	for _, e := range r.s.Trace().Entries {
+		if e.Seq > lastSeq {
+			replayed = append(replayed, e)
+		}
+	}
+	r.s.RUnlock()
+
+	if len(replayed) != len(group) {
+		r.diverge(first, "ran %d command(s), the recording has %d", len(replayed), len(group))
+	}
+	for i := 0; i < len(group) && i < len(replayed); i++ {
+		r.compare(group[i], replayed[i])
+	}
+	return nil
+}
+
+// compare checks a replayed entry against the recorded one, learning which
+// replayed commit each recorded hash became.
+func (r *replayer) compare(want, got git.TraceEntry) {
+	if want.ExitCode != got.ExitCode {
+		r.diverge(want, "exit code %d, recorded %d", got.ExitCode, want.ExitCode)
+	}
+	names := make([]string, 0, len(want.Refs))
+	for name := range want.Refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		old, ok := got.Refs[name]
+		if !ok {
+			r.diverge(want, "%s is missing", name)
+			continue
+		}
+		recorded := want.Refs[name]
+		if strings.HasPrefix(recorded, "ref: ") || strings.HasPrefix(old, "ref: ") {
+			if recorded != old {
+				r.diverge(want, "%s is %q, recorded %q", name, old, recorded)
+			}
+			continue
+		}
+		if mapped, ok := r.hashes[recorded]; ok {
+			if mapped != old {
+				r.diverge(want, "%s points to %s, which is not the recorded %s", name, short(old), short(recorded))
+			}
+			continue
+		}
+		r.hashes[recorded] = old
+	}
+	var extra []string
+	for name := range got.Refs {
+		if _, ok := want.Refs[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		r.diverge(want, "%s exists, but not in the recording", name)
+	}
+}
+
+// rewrite replaces recorded commit hashes in s with the commits they became,
+// keeping the length of abbreviations.
+func (r *replayer) rewrite(s string) string {
+	return abbreviatedHash.ReplaceAllStringFunc(s, func(word string) string {
+		var match string
+		for recorded, replayed := range r.hashes {
+			if strings.HasPrefix(recorded, word) {
+				if match != "" && match != replayed {
+					return word // Ambiguous
+				}
+				match = replayed
+			}
+		}
+		if match == "" {
+			return word
+		}
+		return match[:len(word)]
+	})
+}
+
+func (r *replayer) diverge(e git.TraceEntry, format string, args ...any) {
+	command := e.Line
+	if command == "" {
+		command = strings.Join(e.Args, " ")
+	}
+	if command == "" && e.File != "" {
+		command = "write " + e.File
+	}
+	r.report.Divergences = append(r.report.Divergences, Divergence{Seq: e.Seq, Command: command, Message: fmt.Sprintf(format, args...)})
+}
+
+func short(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
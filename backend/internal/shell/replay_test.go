@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_ReproducesRecordedSession(t *testing.T) {
+	s := newTestSession(t)
+	ctx := context.Background()
+	for _, line := range []string{
+		"mkdir repo && cd repo && git init",
+		"echo 'v1' > app.txt && git add app.txt && git commit -m 'First'",
+		"echo 'v2' >> app.txt",
+		"git add app.txt && git commit -m 'Second'",
+		"git switch -c topic",
+		"git frobnicate",
+	} {
+		_, _ = Run(ctx, s, line)
+	}
+	s.RLock()
+	head, err := s.GetRepo().Head()
+	s.RUnlock()
+	require.NoError(t, err)
+	// Arguments naming commits by hash are rewritten to the replayed commits
+	_, err = Run(ctx, s, "git revert --no-edit "+head.Hash().String()[:7])
+	require.NoError(t, err)
+
+	s.RLock()
+	trace := s.Trace()
+	s.RUnlock()
+	require.Len(t, trace.Entries, 12)
+	assert.Equal(t, "mkdir repo && cd repo && git init", trace.Entries[2].Line)
+	assert.Equal(t, trace.Entries[0].Seq, trace.Entries[2].LineSeq, "commands of a line share its number")
+	assert.Equal(t, "ref: refs/heads/topic", trace.Entries[11].Refs["HEAD"])
+	assert.Equal(t, git.ExitUnknownCommand, trace.Entries[10].ExitCode)
+
+	// Traces travel as JSON
+	data, err := json.Marshal(trace)
+	require.NoError(t, err)
+	var loaded git.Trace
+	require.NoError(t, json.Unmarshal(data, &loaded))
+
+	fresh, err := git.NewSessionManager().CreateSession("replay")
+	require.NoError(t, err)
+	report, err := Replay(ctx, fresh, &loaded)
+	require.NoError(t, err)
+	assert.True(t, report.Matches(), "%+v", report.Divergences)
+	assert.Equal(t, 12, report.Commands)
+	assert.Equal(t, "v1\n", readTestFile(t, fresh, "/repo/app.txt"))
+
+	// A replay that ends differently says where
+	loaded.Entries[11].ExitCode = git.ExitError
+	other, err := git.NewSessionManager().CreateSession("replay-2")
+	require.NoError(t, err)
+	report, err = Replay(ctx, other, &loaded)
+	require.NoError(t, err)
+	require.Len(t, report.Divergences, 1)
+	assert.Equal(t, loaded.Entries[11].Seq, report.Divergences[0].Seq)
+	assert.Contains(t, report.Divergences[0].Message, "exit code 0")
+}
+
+// TestReplay_RegressionTraces replays the traces of bug reports kept in
+// testdata/traces: each must still end the way it was recorded.
+func TestReplay_RegressionTraces(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "traces", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			var trace git.Trace
+			require.NoError(t, json.Unmarshal(data, &trace))
+
+			s, err := git.NewSessionManager().CreateSession("regression")
+			require.NoError(t, err)
+			report, err := Replay(context.Background(), s, &trace)
+			require.NoError(t, err)
+			assert.True(t, report.Matches(), "%+v", report.Divergences)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kurobon/gitgym/backend/internal/git"
+)
+
+// Result is the outcome of a command line.
+type Result struct {
+	Output   string               // What the terminal prints: every command's output, and the errors of commands that were not last
+	Commands []*git.CommandResult // One per command run, in order
+}
+
+// Last returns the result of the last command run, or nil if none ran.
+func (r *Result) Last() *git.CommandResult {
+	if len(r.Commands) == 0 {
+		return nil
+	}
+	return r.Commands[len(r.Commands)-1]
+}
+
+// Run parses and executes a command line in the session. The returned error is
+// the exit status of the line, i.e. the error of the last pipeline that ran;
+// Output is filled either way.
+func Run(ctx context.Context, s *git.Session, line string) (*Result, error) {
+	res := &Result{}
+	script, err := Parse(line)
+	if err != nil {
+		return res, err
+	}
+	ctx = git.WithCommandLine(ctx, line)
+
+	var out []string
+	var status error
+	for i, item := range script.Items {
+		if i > 0 {
+			// A skipped pipeline leaves the status as it was: in "a && b || c", c runs when a fails
+			prev := script.Items[i-1].Op
+			if (prev == OpAnd && status != nil) || (prev == OpOr && status == nil) {
+				continue
+			}
+		}
+		if status != nil {
+			// The error is not the final status, so it goes with the output
+			out = append(out, status.Error())
+		}
+		var text string
+		text, status = runPipeline(ctx, s, item.Pipeline, res)
+		if text = strings.TrimRight(text, "\n"); text != "" {
+			out = append(out, text)
+		}
+	}
+	res.Output = strings.Join(out, "\n")
+	return res, status
+}
+
+// runPipeline runs commands connected by pipes and returns the output of the
+// last one. Like sh without pipefail, the status is that of the last command.
+func runPipeline(ctx context.Context, s *git.Session, cmds []SimpleCommand, res *Result) (string, error) {
+	var stdin *string
+	var output string
+	var err error
+	var errs []string
+	for i, cmd := range cmds {
+		output, err = runSimple(ctx, s, cmd, stdin, res)
+		if i < len(cmds)-1 {
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			piped := output
+			stdin = &piped
+		}
+	}
+	if len(errs) > 0 {
+		// Errors of earlier commands reach the terminal, not the next command
+		output = strings.Join(append(errs, output), "\n")
+	}
+	return output, err
+}
+
+// runSimple runs one command with its redirections and returns its output,
+// which is empty when it went to a file.
+func runSimple(ctx context.Context, s *git.Session, cmd SimpleCommand, stdin *string, res *Result) (string, error) {
+	for _, r := range cmd.Redirects {
+		if r.Op == "<" {
+			data, err := readFile(s, r.Target)
+			if err != nil {
+				return "", err
+			}
+			stdin = &data
+		}
+	}
+
+	var output string
+	var cmdErr error
+	if len(cmd.Words) > 0 {
+		if stdin != nil {
+			ctx = git.WithStdin(ctx, *stdin)
+		}
+		name, args := git.ResolveCommand(cmd.Words)
+		var result *git.CommandResult
+		result, cmdErr = git.Dispatch(ctx, s, name, args)
+		res.Commands = append(res.Commands, result)
+		output = result.Stdout
+	}
+
+	// Every output redirection creates or truncates its file; the last one gets the output
+	var target *Redirect
+	for i, r := range cmd.Redirects {
+		if r.Op == ">" || r.Op == ">>" {
+			if err := writeFile(s, r.Target, "", r.Op == ">>"); err != nil {
+				return "", err
+			}
+			target = &cmd.Redirects[i]
+		}
+	}
+	if target == nil {
+		return output, cmdErr
+	}
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	if err := writeFile(s, target.Target, output, true); err != nil {
+		return "", err
+	}
+	return "", cmdErr
+}
+
+// resolvePath makes name absolute against the session's current directory.
+func resolvePath(s *git.Session, name string) string {
+	if strings.HasPrefix(name, "/") {
+		return path.Clean(name)
+	}
+	return path.Join(s.CurrentDir, name)
+}
+
+func readFile(s *git.Session, name string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	f, err := s.Filesystem.Open(resolvePath(s, name))
+	if err != nil {
+		return "", fmt.Errorf("%s: No such file or directory", name)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+func writeFile(s *git.Session, name, content string, appendMode bool) error {
+	s.Lock()
+	defer s.Unlock()
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := s.Filesystem.OpenFile(resolvePath(s, name), flag, 0644)
+	if err != nil {
+		return fmt.Errorf("%s: cannot open for writing: %w", name, err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(content)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
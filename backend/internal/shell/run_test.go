@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/kurobon/gitgym/backend/internal/git"
+	_ "github.com/kurobon/gitgym/backend/internal/git/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSession(t *testing.T) *git.Session {
+	s, err := git.NewSessionManager().CreateSession("shell-test")
+	require.NoError(t, err)
+	return s
+}
+
+func readTestFile(t *testing.T, s *git.Session, name string) string {
+	data, err := util.ReadFile(s.Filesystem, name)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRun_Chaining(t *testing.T) {
+	s := newTestSession(t)
+	ctx := context.Background()
+
+	res, err := Run(ctx, s, "mkdir repo && cd repo && git init")
+	require.NoError(t, err)
+	assert.Equal(t, "/repo", s.CurrentDir, "cd persists across the line")
+	require.Len(t, res.Commands, 3)
+	assert.Equal(t, git.ExitOK, res.Last().ExitCode)
+	require.NotNil(t, res.Last().Payload)
+	assert.Equal(t, "/repo", res.Commands[1].Payload.Directory)
+
+	// && stops at the first failure, || runs only after one
+	res, err = Run(ctx, s, "git frobnicate && echo skipped || echo recovered")
+	require.NoError(t, err)
+	assert.Contains(t, res.Output, "frobnicate")
+	assert.Contains(t, res.Output, "recovered")
+	assert.NotContains(t, res.Output, "skipped")
+	assert.Len(t, res.Commands, 2)
+
+	// ";" runs everything and the last command decides the status
+	res, err = Run(ctx, s, "echo one; git frobnicate")
+	require.Error(t, err)
+	assert.Equal(t, "one", res.Output)
+	assert.Equal(t, git.ExitUnknownCommand, res.Last().ExitCode)
+
+	_, err = Run(ctx, s, `echo "unclosed`)
+	assert.Error(t, err)
+}
+
+func TestRun_Redirection(t *testing.T) {
+	s := newTestSession(t)
+	ctx := context.Background()
+
+	res, err := Run(ctx, s, "echo 'first line' > notes.txt")
+	require.NoError(t, err)
+	assert.Empty(t, res.Output)
+	_, err = Run(ctx, s, "echo second >> notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nsecond\n", readTestFile(t, s, "/notes.txt"))
+
+	// Any command's output can be redirected, not only echo's
+	_, err = Run(ctx, s, "pwd > where.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "/\n", readTestFile(t, s, "/where.txt"))
+
+	res, err = Run(ctx, s, "cat notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nsecond\n", res.Output+"\n")
+
+	res, err = Run(ctx, s, "cat < where.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "/", res.Output)
+
+	_, err = Run(ctx, s, "cat < missing.txt")
+	assert.Error(t, err)
+}
+
+func TestRun_Pipes(t *testing.T) {
+	s := newTestSession(t)
+	ctx := context.Background()
+
+	res, err := Run(ctx, s, "echo piped | cat")
+	require.NoError(t, err)
+	assert.Equal(t, "piped", res.Output)
+	assert.Len(t, res.Commands, 2)
+
+	_, err = Run(ctx, s, "echo piped | cat > copy.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "piped\n", readTestFile(t, s, "/copy.txt"))
+
+	// Errors before the last command are shown; the last command decides the status
+	res, err = Run(ctx, s, "git frobnicate | cat")
+	require.NoError(t, err)
+	assert.Contains(t, res.Output, "frobnicate")
+}
@@ -5,7 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
@@ -16,8 +17,49 @@ import (
 	appconfig "github.com/kurobon/gitgym/backend/internal/config"
 )
 
-// IngestRemote creates a new shared remote repository from a URL (simulated clone)
+// IngestProgress follows an ingest: git's progress output, and the manifest
+// state (IngestCloning, IngestFetching) it moves through.
+type IngestProgress interface {
+	io.Writer
+	Phase(state string)
+}
+
+// logIngestProgress sends git's progress output to stdout, as ingests always did.
+type logIngestProgress struct{}
+
+func (logIngestProgress) Write(b []byte) (int, error) { return os.Stdout.Write(b) }
+func (logIngestProgress) Phase(string)                {}
+
+// IngestOptions are the optional settings of IngestRemoteWith.
+type IngestOptions struct {
+	Depth    int            // 0 clones the full history
+	Token    string         // Access token of a private repository; defaults to IngestPolicy.Tokens
+	Progress IngestProgress // Defaults to logging git's progress to stdout
+	// FetchOnly fails when the existing copy cannot be fetched into instead of
+	// cloning it again, so a sync during a network outage keeps the copy.
+	FetchOnly bool
+}
+
+// IngestRemote creates a new shared remote repository from a URL (simulated clone).
+// Progress is recorded in an ingest manifest so interrupted ingests can be recovered.
 func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, depth int) error {
+	return sm.IngestRemoteWith(ctx, name, url, IngestOptions{Depth: depth})
+}
+
+// IngestRemoteWith is IngestRemote with a token and progress reporting, as the
+// ingest jobs of /api/remote/ingest use. Cancelling ctx stops the clone or
+// fetch. The IngestPolicy decides whether url may be ingested at all.
+func (sm *SessionManager) IngestRemoteWith(ctx context.Context, name, url string, opts IngestOptions) (retErr error) {
+	policy := sm.IngestPolicy
+	if err := policy.CheckURL(url); err != nil {
+		return err
+	}
+	depth, progress := opts.Depth, opts.Progress
+	if progress == nil {
+		progress = logIngestProgress{}
+	}
+	auth := policy.auth(url, opts.Token)
+
 	// Define local path for persistence
 	baseDir := appconfig.Global.RemotesDir()
 
@@ -34,11 +76,33 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 	sm.ingestMu.Lock()
 	defer sm.ingestMu.Unlock()
 
+	// Refreshing a remote sessions are using rewrites its refs under them
+	if old, ok := sm.GetSharedRemote(name); ok {
+		defer sm.LockRemote(old)()
+	}
+
 	// 1. Ensure Base Directory exists
 	if err := os.MkdirAll(baseDir, 0750); err != nil {
 		return fmt.Errorf("failed to create base dir: %w", err)
 	}
 
+	manifest := &IngestManifest{Name: name, URL: url, Path: repoPath, Depth: depth, StartedAt: time.Now()}
+	sm.mu.RLock()
+	if prev, ok := sm.ingests[name]; ok && prev.URL == url {
+		// Ingesting the same upstream again keeps following it
+		manifest.SyncInterval = prev.SyncInterval
+	}
+	sm.mu.RUnlock()
+	defer func() {
+		if retErr != nil {
+			sm.setIngestState(manifest, IngestFailed, retErr)
+		}
+	}()
+
+	// Clones and fetches stop once the repository outgrows the policy
+	fetchCtx, oversized, stopSizeLimit := limitRepoSize(ctx, repoPath, policy.MaxBytes)
+	defer stopSizeLimit()
+
 	// 1.5. Capture Old Paths for Pruning Stale Workspaces - DISABLED
 	// sm.mu.Lock()
 	// oldPaths := make(map[string]bool)
@@ -55,7 +119,9 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 		// Try opening
 		r, errOpen := gogit.PlainOpen(repoPath)
 		if errOpen == nil {
-			log.Printf("IngestRemote: Repository already exists at %s. Fetching updates...", repoPath)
+			slog.Info("ingest: repository exists, fetching updates", "path", repoPath)
+			sm.setIngestState(manifest, IngestFetching, nil)
+			progress.Phase(IngestFetching)
 
 			// FIX: Ensure we are NOT in Mirror mode (which fetches all PR refs).
 			// If previously initialized with Mirror: true, the config will have fetch = +refs/*:refs/*.
@@ -84,26 +150,37 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 
 				if needsUpdate {
 					if errSet := r.SetConfig(cfg); errSet != nil {
-						log.Printf("IngestRemote: Failed to update config: %v", errSet)
+						slog.Warn("ingest: failed to update config", "err", errSet)
 					} else {
-						log.Printf("IngestRemote: Updated remote config for bare server simulation")
+						slog.Debug("ingest: updated remote config for bare server simulation")
 					}
 				}
 			}
 
 			// It exists. Fetch to update refs.
-			errFetch := r.Fetch(&gogit.FetchOptions{
-				Progress: os.Stdout,
+			errFetch := r.FetchContext(fetchCtx, &gogit.FetchOptions{
+				Auth:     auth,
+				Progress: progress,
 				Force:    true, // Force update refs
 				Tags:     gogit.AllTags,
 			})
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if oversized() {
+				_ = os.RemoveAll(repoPath)
+				return policy.sizeError()
+			}
+			if errFetch != nil && errFetch != gogit.NoErrAlreadyUpToDate && opts.FetchOnly {
+				return fmt.Errorf("failed to fetch remote: %w", errFetch)
+			}
 			if errFetch != nil && errFetch != gogit.NoErrAlreadyUpToDate {
-				log.Printf("IngestRemote: Fetch failed (%v), falling back to fresh clone", errFetch)
+				slog.Warn("ingest: fetch failed, falling back to fresh clone", "err", errFetch)
 				// Fallthrough to clone is risky if we have bad config, but we just fixed config.
 				// If fetch failed, maybe the repo is corrupt. Let's recreate.
 				repo = nil // Signal to re-clone
 			} else {
-				log.Printf("IngestRemote: Fetch successful or already up to date")
+				slog.Debug("ingest: fetch successful or already up to date")
 
 				// Cleanup stale refs/remotes/* entries that might exist from previous mirror clones.
 				// These cause duplicate labels ("main" and "origin/main") on the same commit.
@@ -120,7 +197,7 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 						_ = r.Storer.RemoveReference(refName)
 					}
 					if len(staleRefs) > 0 {
-						log.Printf("IngestRemote: Cleaned up %d stale remote refs", len(staleRefs))
+						slog.Debug("ingest: cleaned up stale remote refs", "refs", len(staleRefs))
 					}
 				}
 
@@ -130,25 +207,38 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 	}
 
 	// 3. Clone if not opened successfully
+	if repo == nil && opts.FetchOnly {
+		return fmt.Errorf("failed to fetch remote: no repository at %s", repoPath)
+	}
 	if repo == nil {
+		// Record the attempt before touching the directory so a crash leaves a trace
+		sm.setIngestState(manifest, IngestCloning, nil)
+		progress.Phase(IngestCloning)
+
 		// Clear directory to be safe
 		_ = os.RemoveAll(repoPath)
 		if errMkdir := os.MkdirAll(repoPath, 0750); errMkdir != nil {
 			return fmt.Errorf("failed to create remote dir: %w", errMkdir)
 		}
 
-		log.Printf("IngestRemote: Cloning %s into %s (Depth: %d)", url, repoPath, depth)
+		slog.Info("ingest: cloning", "url", url, "path", repoPath, "depth", depth)
 
 		// Setup clone options
 		cloneOpts := &gogit.CloneOptions{
 			URL:      url,
-			Progress: os.Stdout,
+			Auth:     auth,
+			Progress: progress,
 			Depth:    depth,
 			Tags:     gogit.AllTags,
 		}
 
-		r, errClone := gogit.PlainCloneContext(ctx, repoPath, true, cloneOpts)
+		r, errClone := gogit.PlainCloneContext(fetchCtx, repoPath, true, cloneOpts)
 		if errClone != nil {
+			// Do not leave a half-cloned directory behind
+			_ = os.RemoveAll(repoPath)
+			if oversized() {
+				return policy.sizeError()
+			}
 			return fmt.Errorf("failed to clone remote: %w", errClone)
 		}
 
@@ -161,38 +251,40 @@ func (sm *SessionManager) IngestRemote(ctx context.Context, name, url string, de
 			}
 			cfg.Remotes["origin"].Mirror = false
 			if errSet := r.SetConfig(cfg); errSet != nil {
-				log.Printf("IngestRemote: Failed to update config post-clone: %v", errSet)
+				slog.Warn("ingest: failed to update config after clone", "err", errSet)
 			}
 		}
 
 		// Force fetch with new refspecs
-		errFetch := r.Fetch(&gogit.FetchOptions{
+		errFetch := r.FetchContext(fetchCtx, &gogit.FetchOptions{
+			Auth:  auth,
 			Force: true,
 			Tags:  gogit.AllTags,
 		})
 		if errFetch != nil && errFetch != gogit.NoErrAlreadyUpToDate {
-			log.Printf("IngestRemote: Post-clone fetch failed: %v", errFetch)
+			slog.Warn("ingest: fetch after clone failed", "err", errFetch)
 		}
 
 		repo = r
-		log.Printf("IngestRemote: Clone and refspec fix successful")
+		slog.Debug("ingest: clone and refspec fix successful")
 	}
 
-	// 4. Update State - Needs LOCK
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	// Store under Name
-	sm.SharedRemotes[name] = repo
-	sm.SharedRemotePaths[name] = repoPath
-
-	// Store under URL (so git clone <url> works)
-	sm.SharedRemotes[url] = repo
-	sm.SharedRemotePaths[url] = repoPath
+	// A cancelled ingest does not replace the remote sessions are using
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := policy.checkRepo(ctx, repoPath, repo); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		_ = os.RemoveAll(repoPath)
+		return err
+	}
 
-	// Store under Internal Path (so fetches using internal path work)
-	sm.SharedRemotes[repoPath] = repo
-	sm.SharedRemotePaths[repoPath] = repoPath
+	// 4. Update State: register under name, URL (so git clone <url> works)
+	// and internal path (so fetches using the internal path work)
+	sm.registerSharedRemote(name, url, repoPath, repo)
+	sm.setIngestState(manifest, IngestReady, nil)
 
 	// 5. Prune Stale Workspaces - DISABLED
 	// go sm.pruneStaleWorkspaces(oldPaths)
@@ -206,25 +298,29 @@ func (sm *SessionManager) RemoveRemote(name string) error {
 	defer sm.mu.Unlock()
 
 	// Check if remote exists first
-	_, ok := sm.SharedRemotes[name]
+	repo, ok := sm.SharedRemotes[name]
 	if !ok {
 		return fmt.Errorf("remote '%s' not found", name)
 	}
+	sm.forgetRemoteLock(repo)
 
 	// 1. Resolve Path and Clean up disk if it exists
 	path, pathOk := sm.SharedRemotePaths[name]
 	if pathOk && path != "" {
 		err := os.RemoveAll(path)
 		if err != nil {
-			log.Printf("RemoveRemote: Failed to delete path %s: %v", path, err)
+			slog.Warn("remove remote: failed to delete path", "path", path, "err", err)
 		} else {
-			log.Printf("RemoveRemote: Deleted path %s", path)
+			slog.Info("remove remote: deleted path", "path", path)
 		}
 	}
 
+	sm.removeIngestManifestLocked(name, path)
+
 	// 2. Clear specific entries in SharedRemotes
 	delete(sm.SharedRemotes, name)
 	delete(sm.SharedRemotePaths, name)
+	delete(sm.RemotePermissions, name)
 
 	// Clean up related mappings (URL, Path aliases)
 	for k, v := range sm.SharedRemotePaths {
@@ -239,30 +335,32 @@ func (sm *SessionManager) RemoveRemote(name string) error {
 	for _, pr := range sm.PullRequests {
 		if pr.RemoteName != name {
 			keptPRs = append(keptPRs, pr)
+		} else {
+			sm.deletePullRequestLocked(pr.ID)
 		}
 	}
 	sm.PullRequests = keptPRs
+	sm.deleteIssuesLocked(name)
 
 	return nil
 }
 
-// GetPullRequests returns the list of pull requests
+// GetPullRequests returns the list of pull requests. Each one is a copy, since
+// reviews, comments and merges keep changing the originals.
 func (sm *SessionManager) GetPullRequests() []*PullRequest {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
-	// Return copy to be safe? Or just slice.
-	// Returning slice of pointers is fine for now but not thread-safe if modified later.
-	// Since PullRequest struct seems immutable after creation mainly, it's okay.
-	// But let's copy slice container at least.
 	result := make([]*PullRequest, len(sm.PullRequests))
-	copy(result, sm.PullRequests)
+	for i, pr := range sm.PullRequests {
+		result[i] = pr.clone()
+	}
 	return result
 }
 
-// CreatePullRequest creates a new pull request
+// CreatePullRequest creates a new pull request and starts its checks. It
+// returns a copy, since the checks keep updating the original.
 func (sm *SessionManager) CreatePullRequest(title, description, sourceBranch, targetBranch, creator, remoteName string) (*PullRequest, error) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
 
 	id := sm.NextPRID
 	sm.NextPRID++
@@ -271,14 +369,22 @@ func (sm *SessionManager) CreatePullRequest(title, description, sourceBranch, ta
 		Title:       title,
 		HeadRef:     sourceBranch,
 		BaseRef:     targetBranch,
-		State:       "OPEN",
+		State:       PRStateOpen,
 		Description: description,
 		Creator:     creator,
 		CreatedAt:   time.Now(),
 		RemoteName:  remoteName,
 	}
 	sm.PullRequests = append(sm.PullRequests, pr)
-	return pr, nil
+	sm.savePullRequestLocked(pr)
+	created := pr.clone()
+	sm.mu.Unlock()
+
+	sm.startPullRequestChecks(id)
+	if started, err := sm.GetPullRequest(id); err == nil {
+		return started, nil
+	}
+	return created, nil // Deleted in the meantime
 }
 
 // DeletePullRequest removes a pull request by ID
@@ -295,6 +401,7 @@ func (sm *SessionManager) DeletePullRequest(id int) error {
 			//
 			// Preserving order (better for UI stability):
 			sm.PullRequests = append(sm.PullRequests[:i], sm.PullRequests[i+1:]...)
+			sm.deletePullRequestLocked(id)
 			return nil
 		}
 	}
@@ -304,12 +411,28 @@ func (sm *SessionManager) DeletePullRequest(id int) error {
 // CreateBareRepository creates a new bare repository on the server
 // This only creates the remote repository - users must manually git clone or git init
 func (sm *SessionManager) CreateBareRepository(ctx context.Context, sessionID, name string) error {
+	return sm.CreateBareRepositoryWith(ctx, sessionID, name, BareRepoOptions{})
+}
+
+// CreateBareRepositoryWith is CreateBareRepository with initial content: a
+// README, .gitignore and license committed by the creating session's user on
+// the default branch (see repo_templates.go).
+func (sm *SessionManager) CreateBareRepositoryWith(ctx context.Context, sessionID, name string, opts BareRepoOptions) error {
 	// 1. Validate Name (Simple alphanumeric check)
 	for _, r := range name {
 		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_') {
 			return fmt.Errorf("invalid repository name: only alphanumeric, hyphen and underscore allowed")
 		}
 	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	user := DefaultUser
+	if s, ok := sm.GetSession(sessionID); ok {
+		s.mu.RLock()
+		user = s.Identity()
+		s.mu.RUnlock()
+	}
 
 	// Define local path for persistence
 	baseDir := appconfig.Global.RemotesDir()
@@ -344,22 +467,18 @@ func (sm *SessionManager) CreateBareRepository(ctx context.Context, sessionID, n
 	if err != nil {
 		return fmt.Errorf("failed to init bare repo: %w", err)
 	}
+	if err := initBareRepo(repo, name, opts, user); err != nil {
+		_ = os.RemoveAll(repoPath)
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
 
-	// 4. Update Session Manager State
-	sm.mu.Lock()
-
-	// Register under Name, PseudoURL, and Path
-	sm.SharedRemotes[name] = repo
-	sm.SharedRemotePaths[name] = repoPath
-
-	sm.SharedRemotes[pseudoURL] = repo
-	sm.SharedRemotePaths[pseudoURL] = repoPath
-
-	sm.SharedRemotes[repoPath] = repo
-	sm.SharedRemotePaths[repoPath] = repoPath
-	sm.mu.Unlock()
+	// 4. Update Session Manager State: register under Name, PseudoURL, and Path
+	sm.registerSharedRemote(name, pseudoURL, repoPath, repo)
+	sm.setIngestState(&IngestManifest{Name: name, URL: pseudoURL, Path: repoPath, StartedAt: time.Now()}, IngestReady, nil)
+	// The creator owns the remote; it stays open until they restrict it
+	_ = sm.SetRemotePermission(name, RemotePermission{Access: RemoteAccessOpen, Owner: sessionID})
 
-	log.Printf("Created bare repository: %s at %s", name, repoPath)
+	slog.Info("created bare repository", "remote", name, "path", repoPath)
 
 	return nil
 }
@@ -0,0 +1,31 @@
+package state
+
+// AmState records a "git am" that stopped on a patch that did not apply, so
+// that --continue, --skip or --abort can finish the series.
+type AmState struct {
+	Repo      string   `json:"repo"`      // Repository path the am runs in
+	OrigHead  string   `json:"origHead"`  // HEAD before the am started, restored by --abort
+	Patches   []string `json:"patches"`   // Messages still to apply, the stopped one first
+	Applied   int      `json:"applied"`   // Patches committed so far
+	ThreeWay  bool     `json:"threeWay"`  // Started with -3
+	Conflicts []string `json:"conflicts"` // Paths a three-way merge left with conflict markers
+}
+
+// AmInProgress returns the stopped am of the active repository, or nil.
+func (s *Session) AmInProgress() *AmState {
+	if s.Am == nil || s.Am.Repo != s.activeRepoPath() {
+		return nil
+	}
+	return s.Am
+}
+
+// StartAm records a stopped am for the active repository.
+func (s *Session) StartAm(am *AmState) {
+	am.Repo = s.activeRepoPath()
+	s.Am = am
+}
+
+// ClearAm forgets the stopped am, after the series was applied or aborted.
+func (s *Session) ClearAm() {
+	s.Am = nil
+}
@@ -0,0 +1,293 @@
+package state
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Analytics
+//
+// Instructors want to know where learners get stuck: which commands fail
+// most, which missions take many attempts or hints, and how long a mission
+// takes to complete. Command counts are kept per session and command in the
+// Store next to mission progress, so they survive a restart with a file
+// store; the report is aggregated from both on request.
+
+const bucketCommandStats = "command_stats"
+
+// UnknownCommandStat is the command name runs of unregistered commands are
+// counted under, so typos do not each get their own entry.
+const UnknownCommandStat = "(unknown)"
+
+// CommandCount is how often a session ran one command.
+type CommandCount struct {
+	SessionID string    `json:"sessionId"`
+	Command   string    `json:"command"`
+	Runs      int       `json:"runs"`
+	Errors    int       `json:"errors"`
+	FirstRun  time.Time `json:"firstRun"`
+	LastRun   time.Time `json:"lastRun"`
+}
+
+// CommandStats aggregates the runs of one command.
+type CommandStats struct {
+	Command   string  `json:"command"`
+	Runs      int     `json:"runs"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"errorRate"` // Errors / Runs
+	Sessions  int     `json:"sessions"`  // Sessions that ran it
+}
+
+// SessionStats describes the activity of one session.
+type SessionStats struct {
+	SessionID         string         `json:"sessionId"`
+	Runs              int            `json:"runs"`
+	Errors            int            `json:"errors"`
+	ErrorRate         float64        `json:"errorRate"`
+	FirstCommand      time.Time      `json:"firstCommand"`
+	LastCommand       time.Time      `json:"lastCommand"`
+	Commands          []CommandStats `json:"commands"` // Most run first
+	MissionsStarted   int            `json:"missionsStarted"`
+	MissionsCompleted int            `json:"missionsCompleted"`
+}
+
+// MissionStats aggregates the progress of every learner in one mission.
+type MissionStats struct {
+	MissionID   string  `json:"missionId"`
+	Learners    int     `json:"learners"`    // Sessions that started or verified it
+	Attempts    int     `json:"attempts"`    // Verifications
+	Completions int     `json:"completions"` // Learners who passed every check
+	SuccessRate float64 `json:"successRate"` // Completions / Learners
+	HintsUsed   int     `json:"hintsUsed"`
+	// Time from starting the mission to completing it, over the learners who completed it
+	MedianCompletionSeconds float64 `json:"medianCompletionSeconds,omitempty"`
+	MeanCompletionSeconds   float64 `json:"meanCompletionSeconds,omitempty"`
+	CompletionSecondsSum    float64 `json:"completionSecondsSum,omitempty"`
+	TimedCompletions        int     `json:"timedCompletions,omitempty"` // Completions with a known start time
+}
+
+// AnalyticsReport is what GET /api/analytics returns.
+type AnalyticsReport struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	SessionID   string         `json:"sessionId,omitempty"` // Set when the report covers one session
+	Runs        int            `json:"runs"`
+	Errors      int            `json:"errors"`
+	ErrorRate   float64        `json:"errorRate"`
+	Commands    []CommandStats `json:"commands"` // Highest error count first
+	Sessions    []SessionStats `json:"sessions"` // Most recently active first
+	Missions    []MissionStats `json:"missions"` // Lowest success rate first
+}
+
+// RecordCommand counts a command run by a session; cmdErr is its error, if any.
+func (sm *SessionManager) RecordCommand(sessionID, command string, cmdErr error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.Store == nil {
+		return
+	}
+
+	key := sessionID + "/" + command
+	var c CommandCount
+	_, _ = getJSON(sm.Store, bucketCommandStats, key, &c)
+	now := time.Now()
+	if c.Runs == 0 {
+		c.FirstRun = now
+	}
+	c.SessionID, c.Command, c.LastRun = sessionID, command, now
+	c.Runs++
+	if cmdErr != nil {
+		c.Errors++
+	}
+	if err := putJSON(sm.Store, bucketCommandStats, key, c); err != nil {
+		slog.Warn("store: failed to save command stats", "key", key, "err", err)
+	}
+}
+
+// RecordMissionStart notes that a session started a mission, so the time to
+// complete it can be measured. Restarting a mission keeps the first start.
+func (sm *SessionManager) RecordMissionStart(sessionID, missionID string) MissionProgress {
+	return sm.updateMissionProgress(sessionID, missionID, func(p *MissionProgress) {
+		if p.StartedAt == nil {
+			now := time.Now()
+			p.StartedAt = &now
+		}
+	})
+}
+
+// Analytics aggregates the recorded command counts and mission progress. An
+// empty sessionID covers every session.
+func (sm *SessionManager) Analytics(sessionID string) AnalyticsReport {
+	report := AnalyticsReport{
+		GeneratedAt: time.Now(),
+		SessionID:   sessionID,
+		Commands:    []CommandStats{},
+		Sessions:    []SessionStats{},
+		Missions:    []MissionStats{},
+	}
+	counts, progress := sm.analyticsRecords(sessionID)
+
+	commands := make(map[string]*CommandStats)
+	sessions := make(map[string]*SessionStats)
+	session := func(id string) *SessionStats {
+		st, ok := sessions[id]
+		if !ok {
+			st = &SessionStats{SessionID: id, Commands: []CommandStats{}}
+			sessions[id] = st
+		}
+		return st
+	}
+	for _, c := range counts {
+		cmd, ok := commands[c.Command]
+		if !ok {
+			cmd = &CommandStats{Command: c.Command}
+			commands[c.Command] = cmd
+		}
+		cmd.Runs += c.Runs
+		cmd.Errors += c.Errors
+		cmd.Sessions++
+
+		st := session(c.SessionID)
+		st.Runs += c.Runs
+		st.Errors += c.Errors
+		st.Commands = append(st.Commands, CommandStats{Command: c.Command, Runs: c.Runs, Errors: c.Errors, ErrorRate: rate(c.Errors, c.Runs), Sessions: 1})
+		if st.FirstCommand.IsZero() || c.FirstRun.Before(st.FirstCommand) {
+			st.FirstCommand = c.FirstRun
+		}
+		if c.LastRun.After(st.LastCommand) {
+			st.LastCommand = c.LastRun
+		}
+		report.Runs += c.Runs
+		report.Errors += c.Errors
+	}
+	report.ErrorRate = rate(report.Errors, report.Runs)
+
+	for _, cmd := range commands {
+		cmd.ErrorRate = rate(cmd.Errors, cmd.Runs)
+		report.Commands = append(report.Commands, *cmd)
+	}
+	sort.Slice(report.Commands, func(i, j int) bool {
+		a, b := report.Commands[i], report.Commands[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Command < b.Command
+	})
+
+	missions := make(map[string]*MissionStats)
+	durations := make(map[string][]float64)
+	for _, p := range progress {
+		m, ok := missions[p.MissionID]
+		if !ok {
+			m = &MissionStats{MissionID: p.MissionID}
+			missions[p.MissionID] = m
+		}
+		m.Learners++
+		m.Attempts += p.Attempts
+		m.HintsUsed += p.HintsUsed
+		st := session(p.SessionID)
+		st.MissionsStarted++
+		if p.Completed {
+			m.Completions++
+			st.MissionsCompleted++
+			if d, ok := p.TimeToComplete(); ok {
+				durations[p.MissionID] = append(durations[p.MissionID], d.Seconds())
+			}
+		}
+	}
+	for id, m := range missions {
+		m.SuccessRate = rate(m.Completions, m.Learners)
+		if secs := durations[id]; len(secs) > 0 {
+			sort.Float64s(secs)
+			for _, s := range secs {
+				m.CompletionSecondsSum += s
+			}
+			m.TimedCompletions = len(secs)
+			m.MeanCompletionSeconds = m.CompletionSecondsSum / float64(len(secs))
+			m.MedianCompletionSeconds = median(secs)
+		}
+		report.Missions = append(report.Missions, *m)
+	}
+	sort.Slice(report.Missions, func(i, j int) bool {
+		a, b := report.Missions[i], report.Missions[j]
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate < b.SuccessRate
+		}
+		return a.MissionID < b.MissionID
+	})
+
+	for _, st := range sessions {
+		st.ErrorRate = rate(st.Errors, st.Runs)
+		sort.Slice(st.Commands, func(i, j int) bool {
+			if st.Commands[i].Runs != st.Commands[j].Runs {
+				return st.Commands[i].Runs > st.Commands[j].Runs
+			}
+			return st.Commands[i].Command < st.Commands[j].Command
+		})
+		report.Sessions = append(report.Sessions, *st)
+	}
+	sort.Slice(report.Sessions, func(i, j int) bool {
+		a, b := report.Sessions[i], report.Sessions[j]
+		if !a.LastCommand.Equal(b.LastCommand) {
+			return a.LastCommand.After(b.LastCommand)
+		}
+		return a.SessionID < b.SessionID
+	})
+	return report
+}
+
+// analyticsRecords reads the command counts and mission progress of a
+// session, or of every session when sessionID is empty.
+func (sm *SessionManager) analyticsRecords(sessionID string) ([]CommandCount, []MissionProgress) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.Store == nil {
+		return nil, nil
+	}
+
+	var counts []CommandCount
+	keys, _ := sm.Store.List(bucketCommandStats)
+	for _, key := range keys {
+		var c CommandCount
+		if ok, _ := getJSON(sm.Store, bucketCommandStats, key, &c); ok && (sessionID == "" || c.SessionID == sessionID) {
+			counts = append(counts, c)
+		}
+	}
+	var progress []MissionProgress
+	keys, _ = sm.Store.List(bucketMissionProgress)
+	for _, key := range keys {
+		var p MissionProgress
+		if ok, _ := getJSON(sm.Store, bucketMissionProgress, key, &p); ok && (sessionID == "" || p.SessionID == sessionID) {
+			progress = append(progress, p)
+		}
+	}
+	return counts, progress
+}
+
+// TimeToComplete returns how long the session took to complete the mission,
+// when both its start and its completion were recorded.
+func (p MissionProgress) TimeToComplete() (time.Duration, bool) {
+	if p.StartedAt == nil || p.CompletedAt == nil || p.CompletedAt.Before(*p.StartedAt) {
+		return 0, false
+	}
+	return p.CompletedAt.Sub(*p.StartedAt), true
+}
+
+func rate(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
+
+// median returns the middle of sorted values.
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalytics_Commands(t *testing.T) {
+	sm := NewSessionManager()
+	failed := errors.New("fatal: not a git repository")
+	sm.RecordCommand("alice", "commit", nil)
+	sm.RecordCommand("alice", "commit", failed)
+	sm.RecordCommand("alice", "status", nil)
+	sm.RecordCommand("bob", "commit", failed)
+	sm.RecordCommand("bob", UnknownCommandStat, errors.New("comit: command not found"))
+
+	report := sm.Analytics("")
+	assert.Equal(t, 5, report.Runs)
+	assert.Equal(t, 3, report.Errors)
+	require.Len(t, report.Commands, 3)
+	// Most errors first
+	assert.Equal(t, CommandStats{Command: "commit", Runs: 3, Errors: 2, ErrorRate: 2.0 / 3, Sessions: 2}, report.Commands[0])
+	assert.Equal(t, UnknownCommandStat, report.Commands[1].Command)
+	assert.Equal(t, "status", report.Commands[2].Command)
+
+	require.Len(t, report.Sessions, 2)
+	// Most recently active first
+	assert.Equal(t, "bob", report.Sessions[0].SessionID)
+	alice := report.Sessions[1]
+	assert.Equal(t, 3, alice.Runs)
+	assert.InDelta(t, 1.0/3, alice.ErrorRate, 1e-9)
+	assert.Equal(t, "commit", alice.Commands[0].Command)
+	assert.False(t, alice.FirstCommand.After(alice.LastCommand))
+
+	one := sm.Analytics("alice")
+	assert.Equal(t, "alice", one.SessionID)
+	assert.Equal(t, 3, one.Runs)
+	require.Len(t, one.Sessions, 1)
+}
+
+func TestAnalytics_Missions(t *testing.T) {
+	sm := NewSessionManager()
+
+	// alice starts, fails once, then completes
+	sm.RecordMissionStart("alice", "basics")
+	sm.RecordMissionProgress("alice", "basics", 1, 2)
+	sm.RecordMissionHint("alice", "basics")
+	p := sm.RecordMissionProgress("alice", "basics", 2, 2)
+	require.NotNil(t, p.CompletedAt)
+	completedAt := *p.CompletedAt
+
+	// Verifying again keeps the first completion; restarting keeps the first start
+	sm.RecordMissionStart("alice", "basics")
+	p = sm.RecordMissionProgress("alice", "basics", 2, 2)
+	assert.True(t, completedAt.Equal(*p.CompletedAt))
+	d, ok := p.TimeToComplete()
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, d, time.Duration(0))
+
+	// bob never finishes; carol completes without a recorded start
+	sm.RecordMissionStart("bob", "basics")
+	sm.RecordMissionProgress("bob", "basics", 0, 2)
+	sm.RecordMissionProgress("carol", "basics", 2, 2)
+	sm.RecordMissionProgress("carol", "rebase", 3, 3)
+
+	report := sm.Analytics("")
+	require.Len(t, report.Missions, 2)
+	// Lowest success rate first
+	basics := report.Missions[0]
+	assert.Equal(t, "basics", basics.MissionID)
+	assert.Equal(t, 3, basics.Learners)
+	assert.Equal(t, 5, basics.Attempts)
+	assert.Equal(t, 2, basics.Completions)
+	assert.InDelta(t, 2.0/3, basics.SuccessRate, 1e-9)
+	assert.Equal(t, 1, basics.HintsUsed)
+	assert.Equal(t, 1, basics.TimedCompletions)
+	assert.Equal(t, basics.MeanCompletionSeconds, basics.MedianCompletionSeconds)
+
+	rebase := report.Missions[1]
+	assert.Equal(t, 1.0, rebase.SuccessRate)
+	assert.Zero(t, rebase.TimedCompletions)
+
+	for _, st := range report.Sessions {
+		if st.SessionID == "carol" {
+			assert.Equal(t, 2, st.MissionsStarted)
+			assert.Equal(t, 2, st.MissionsCompleted)
+		}
+	}
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 2.0, median([]float64{1, 2, 10}))
+	assert.Equal(t, 1.5, median([]float64{1, 2}))
+}
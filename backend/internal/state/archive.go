@@ -0,0 +1,215 @@
+package state
+
+// archive.go - "git archive"
+//
+// An archive holds the files of one tree, without history, as tar, tar.gz or
+// zip. The whole repository is exported as a bundle instead (see bundle.go).
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Archive formats, as named by "git archive --format"
+const (
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveFormatFor returns the format of an archive named file ("" when the
+// extension is not an archive), like git archive -o picks it.
+func ArchiveFormatFor(file string) string {
+	switch {
+	case strings.HasSuffix(file, ".tar.gz"), strings.HasSuffix(file, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(file, ".tar"):
+		return ArchiveTar
+	case strings.HasSuffix(file, ".zip"):
+		return ArchiveZip
+	}
+	return ""
+}
+
+// ArchiveOptions selects what WriteArchive writes.
+type ArchiveOptions struct {
+	Format string   // ArchiveTar, ArchiveTarGz (or "tgz") or ArchiveZip
+	Prefix string   // Prepended to every path, e.g. "project/"
+	Paths  []string // Only these files and directories; all when empty
+}
+
+// WriteArchive writes the tree of rev (a commit, tag or tree) to w. Files get
+// the commit time as their modification time, and the commit id is stored
+// like git does: as the zip comment, or a pax header of the tar.
+func WriteArchive(repo *gogit.Repository, rev plumbing.Hash, opts ArchiveOptions, w io.Writer) error {
+	tree, commit, err := archiveTree(repo, rev)
+	if err != nil {
+		return err
+	}
+	modTime := time.Now()
+	if commit != nil {
+		modTime = commit.Committer.When
+	}
+
+	var files []archiveFile
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !archiveIncludes(opts.Paths, f.Name) {
+			return nil
+		}
+		files = append(files, archiveFile{File: f, Name: opts.Prefix + f.Name})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(opts.Paths) > 0 && len(files) == 0 {
+		return fmt.Errorf("fatal: pathspec '%s' did not match any files", opts.Paths[0])
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	switch opts.Format {
+	case ArchiveZip:
+		return writeZipArchive(w, files, modTime, commit)
+	case ArchiveTar:
+		return writeTarArchive(w, files, modTime, commit)
+	case ArchiveTarGz, "tgz":
+		gz := gzip.NewWriter(w)
+		if err := writeTarArchive(gz, files, modTime, commit); err != nil {
+			return err
+		}
+		return gz.Close()
+	default:
+		return fmt.Errorf("fatal: Unknown archive format '%s'", opts.Format)
+	}
+}
+
+type archiveFile struct {
+	*object.File
+	Name string // Path in the archive, prefix included
+}
+
+// archiveTree peels rev to a tree, and to its commit unless rev names a tree.
+func archiveTree(repo *gogit.Repository, rev plumbing.Hash) (*object.Tree, *object.Commit, error) {
+	obj, err := repo.Object(plumbing.AnyObject, rev)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fatal: not a tree object: %s", rev)
+	}
+	for {
+		switch o := obj.(type) {
+		case *object.Tag:
+			if obj, err = o.Object(); err != nil {
+				return nil, nil, err
+			}
+		case *object.Commit:
+			tree, err := o.Tree()
+			return tree, o, err
+		case *object.Tree:
+			return o, nil, nil
+		default:
+			return nil, nil, fmt.Errorf("fatal: not a tree object: %s", rev)
+		}
+	}
+}
+
+// archiveIncludes reports whether name is one of paths or inside one of them.
+func archiveIncludes(paths []string, name string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.Trim(path.Clean("/"+p), "/")
+		if p == "" || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTarArchive(w io.Writer, files []archiveFile, modTime time.Time, commit *object.Commit) error {
+	tw := tar.NewWriter(w)
+	if commit != nil {
+		// git get-tar-commit-id reads the commit back from this header
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag:   tar.TypeXGlobalHeader,
+			PAXRecords: map[string]string{"comment": commit.Hash.String()},
+		}); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.Name, ModTime: modTime, Mode: 0644, Typeflag: tar.TypeReg}
+		content, err := archiveContent(f)
+		if err != nil {
+			return err
+		}
+		switch f.Mode {
+		case filemode.Executable:
+			hdr.Mode = 0755
+		case filemode.Symlink:
+			hdr.Typeflag, hdr.Linkname, hdr.Mode = tar.TypeSymlink, string(content), 0777
+			content = nil
+		}
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZipArchive(w io.Writer, files []archiveFile, modTime time.Time, commit *object.Commit) error {
+	zw := zip.NewWriter(w)
+	if commit != nil {
+		if err := zw.SetComment(commit.Hash.String()); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: modTime}
+		switch f.Mode {
+		case filemode.Executable:
+			hdr.SetMode(0755)
+		case filemode.Symlink:
+			hdr.SetMode(0777 | os.ModeSymlink)
+		default:
+			hdr.SetMode(0644)
+		}
+		content, err := archiveContent(f)
+		if err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func archiveContent(f archiveFile) ([]byte, error) {
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
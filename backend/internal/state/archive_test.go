@@ -0,0 +1,57 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteArchive_TarGzWithPrefixAndPaths(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	for name, content := range map[string]string{"README.md": "readme", "docs/guide.md": "guide", "src/main.go": "package main"} {
+		require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(content), 0644))
+		_, err = w.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := w.Commit("initial", &gogit.CommitOptions{Author: &object.Signature{Name: "a", Email: "a@example.com"}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(repo, hash, ArchiveOptions{Format: ArchiveTarGz, Prefix: "project/", Paths: []string{"docs", "README.md"}}, &buf))
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			assert.Equal(t, hash.String(), hdr.PAXRecords["comment"])
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{"project/README.md": "readme", "project/docs/guide.md": "guide"}, files)
+
+	err = WriteArchive(repo, hash, ArchiveOptions{Format: ArchiveZip, Paths: []string{"missing"}}, io.Discard)
+	assert.ErrorContains(t, err, "pathspec 'missing' did not match any files")
+}
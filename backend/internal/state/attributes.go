@@ -0,0 +1,178 @@
+package state
+
+// attributes.go - .gitattributes rules
+//
+// Only the attributes the simulator acts on are interpreted: "text" and "eol"
+// for line-ending normalization, and "merge" for the ours/union merge
+// drivers. Like LFSPatterns, only the .gitattributes at the worktree root is
+// read.
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Attribute states besides a value such as "crlf" in eol=crlf
+const (
+	AttrSet   = "set"   // "text"
+	AttrUnset = "unset" // "-text"
+)
+
+// attributeMacros expand like git's built-in "binary" macro.
+var attributeMacros = map[string][]string{
+	"binary": {"-text", "-diff", "-merge"},
+}
+
+type attributeRule struct {
+	pattern string
+	attrs   map[string]string // Attribute to value; "" removes it again ("!text")
+}
+
+// Attributes are the .gitattributes rules of a worktree. For each attribute,
+// the last line whose pattern matches a file decides its value, as in git.
+type Attributes struct {
+	rules []attributeRule
+}
+
+// LoadAttributes reads the worktree's .gitattributes.
+func LoadAttributes(fs billy.Filesystem) *Attributes {
+	a := &Attributes{}
+	data, err := readFile(fs, ".gitattributes")
+	if err != nil {
+		return a
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rule := attributeRule{pattern: fields[0], attrs: make(map[string]string)}
+		for _, attr := range fields[1:] {
+			for _, expanded := range append([]string{attr}, attributeMacros[attr]...) {
+				name, value := parseAttribute(expanded)
+				rule.attrs[name] = value
+			}
+		}
+		a.rules = append(a.rules, rule)
+	}
+	return a
+}
+
+func parseAttribute(attr string) (name, value string) {
+	switch {
+	case strings.HasPrefix(attr, "-"):
+		return attr[1:], AttrUnset
+	case strings.HasPrefix(attr, "!"):
+		return attr[1:], ""
+	}
+	if name, value, ok := strings.Cut(attr, "="); ok {
+		return name, value
+	}
+	return attr, AttrSet
+}
+
+// Empty reports whether there are no rules at all.
+func (a *Attributes) Empty() bool {
+	return len(a.rules) == 0
+}
+
+// Get returns the value of attr for file: AttrSet, AttrUnset, a value such as
+// "crlf", or "" when unspecified.
+func (a *Attributes) Get(file, attr string) string {
+	for i := len(a.rules) - 1; i >= 0; i-- {
+		if value, ok := a.rules[i].attrs[attr]; ok && matchesAttributePattern(a.rules[i].pattern, file) {
+			return value
+		}
+	}
+	return ""
+}
+
+// IsText reports whether file, with the given content, is a text file whose
+// line endings are normalized to LF in the index. Setting eol implies text;
+// text=auto leaves files that look binary alone. Without either attribute
+// nothing is converted, as with core.autocrlf unset.
+func (a *Attributes) IsText(file string, content []byte) bool {
+	switch a.Get(file, "text") {
+	case AttrSet:
+		return true
+	case AttrUnset:
+		return false
+	case "auto":
+		return !looksBinary(content)
+	}
+	eol := a.Get(file, "eol")
+	return eol == "lf" || eol == "crlf"
+}
+
+// CheckoutCRLF reports whether the text file's worktree copy uses CRLF (eol=crlf).
+func (a *Attributes) CheckoutCRLF(file string, content []byte) bool {
+	return a.Get(file, "eol") == "crlf" && a.IsText(file, content)
+}
+
+// matchesAttributePattern reports whether file matches a .gitattributes
+// pattern. Patterns without a slash match the base name, as in Git.
+func matchesAttributePattern(pattern, file string) bool {
+	target := file
+	if !strings.Contains(pattern, "/") {
+		target = path.Base(file)
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), target)
+	return ok
+}
+
+// ToLF converts CRLF line endings to LF.
+func ToLF(content []byte) []byte {
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// ToCRLF converts LF line endings to CRLF, leaving existing CRLFs alone.
+func ToCRLF(content []byte) []byte {
+	return bytes.ReplaceAll(ToLF(content), []byte("\n"), []byte("\r\n"))
+}
+
+// looksBinary uses git's heuristic: a NUL byte in the first 8000 bytes.
+func looksBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// dropNormalized treats modified files whose worktree content only differs
+// from the index in the line endings the text attribute normalizes as
+// unmodified, like git status does.
+func dropNormalized(repo *gogit.Repository, w *gogit.Worktree, status gogit.Status) {
+	attrs := LoadAttributes(w.Filesystem)
+	if attrs.Empty() {
+		return
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return
+	}
+	for _, e := range idx.Entries {
+		fs, ok := status[e.Name]
+		if !ok || fs.Worktree != gogit.Modified {
+			continue
+		}
+		content, err := readFile(w.Filesystem, e.Name)
+		if err != nil || !bytes.Contains(content, []byte("\r\n")) || !attrs.IsText(e.Name, content) {
+			continue
+		}
+		if plumbing.ComputeHash(plumbing.BlobObject, ToLF(content)) != e.Hash {
+			continue
+		}
+		if fs.Staging == gogit.Unmodified {
+			delete(status, e.Name)
+		} else {
+			fs.Worktree = gogit.Unmodified
+		}
+	}
+}
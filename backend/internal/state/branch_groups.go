@@ -0,0 +1,62 @@
+package state
+
+import (
+	"sort"
+	"strings"
+)
+
+// branchGroupCollapseThreshold is the group size above which the UI should
+// render a group collapsed by default.
+const branchGroupCollapseThreshold = 10
+
+// BranchGroup collects branches that share a path-style prefix (e.g. "feature/").
+type BranchGroup struct {
+	Prefix    string   `json:"prefix"`   // Includes the trailing slash, e.g. "feature/" or "origin/release/"
+	Branches  []string `json:"branches"` // Full branch names, sorted
+	Count     int      `json:"count"`
+	Collapsed bool     `json:"collapsed"` // Suggested initial state for the UI
+}
+
+// PopulateBranchGroups recomputes BranchGroups and RemoteBranchGroups from the
+// current Branches and RemoteBranches maps. Call it again after rewriting those maps.
+func PopulateBranchGroups(state *GraphState) {
+	state.BranchGroups = groupBranchesByPrefix(state.Branches, false)
+	state.RemoteBranchGroups = groupBranchesByPrefix(state.RemoteBranches, true)
+}
+
+// groupBranchesByPrefix groups names on their first path segment. For remote
+// branches the remote name ("origin/") is kept as part of the prefix so groups
+// of different remotes never merge. Branches without a prefix are not grouped.
+func groupBranchesByPrefix(branches map[string]string, remote bool) []BranchGroup {
+	byPrefix := make(map[string][]string)
+	for name := range branches {
+		rest := name
+		base := ""
+		if remote {
+			idx := strings.Index(rest, "/")
+			if idx < 0 {
+				continue
+			}
+			base, rest = rest[:idx+1], rest[idx+1:]
+		}
+		idx := strings.Index(rest, "/")
+		if idx <= 0 {
+			continue
+		}
+		prefix := base + rest[:idx+1]
+		byPrefix[prefix] = append(byPrefix[prefix], name)
+	}
+
+	groups := make([]BranchGroup, 0, len(byPrefix))
+	for prefix, names := range byPrefix {
+		sort.Strings(names)
+		groups = append(groups, BranchGroup{
+			Prefix:    prefix,
+			Branches:  names,
+			Count:     len(names),
+			Collapsed: len(names) > branchGroupCollapseThreshold,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Prefix < groups[j].Prefix })
+	return groups
+}
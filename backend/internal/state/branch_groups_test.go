@@ -0,0 +1,46 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupBranchesByPrefix(t *testing.T) {
+	branches := map[string]string{
+		"main":            "a",
+		"feature/login":   "b",
+		"feature/signup":  "c",
+		"release/1.0":     "d",
+		"feature/ui/menu": "e",
+	}
+
+	groups := groupBranchesByPrefix(branches, false)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "feature/", groups[0].Prefix)
+	assert.Equal(t, []string{"feature/login", "feature/signup", "feature/ui/menu"}, groups[0].Branches)
+	assert.Equal(t, 3, groups[0].Count)
+	assert.False(t, groups[0].Collapsed)
+
+	assert.Equal(t, "release/", groups[1].Prefix)
+}
+
+func TestGroupBranchesByPrefix_RemoteAndCollapse(t *testing.T) {
+	branches := map[string]string{"origin/main": "a", "upstream/feature/x": "b"}
+	for i := 0; i < branchGroupCollapseThreshold+1; i++ {
+		branches[fmt.Sprintf("origin/feature/f%02d", i)] = "c"
+	}
+
+	groups := groupBranchesByPrefix(branches, true)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "origin/feature/", groups[0].Prefix)
+	assert.Equal(t, branchGroupCollapseThreshold+1, groups[0].Count)
+	assert.True(t, groups[0].Collapsed)
+
+	assert.Equal(t, "upstream/feature/", groups[1].Prefix)
+	assert.Equal(t, 1, groups[1].Count)
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// BranchPolicy describes naming rules that new branch names must satisfy.
+// A zero-value policy accepts every name.
+type BranchPolicy struct {
+	Pattern          string   `json:"pattern,omitempty"`          // Regular expression the full name must match
+	RequiredPrefixes []string `json:"requiredPrefixes,omitempty"` // e.g. "feature/", "bugfix/"
+	MaxLength        int      `json:"maxLength,omitempty"`        // 0 means unlimited
+}
+
+// IsEmpty reports whether the policy has no rules configured.
+func (p *BranchPolicy) IsEmpty() bool {
+	return p == nil || (p.Pattern == "" && len(p.RequiredPrefixes) == 0 && p.MaxLength <= 0)
+}
+
+// Validate checks name against the policy and returns an educational error
+// describing which rule was violated and how to fix it.
+func (p *BranchPolicy) Validate(name string) error {
+	if p.IsEmpty() {
+		return nil
+	}
+
+	if p.MaxLength > 0 && len(name) > p.MaxLength {
+		return fmt.Errorf("fatal: branch name '%s' is too long (%d > %d characters)\nhint: Short, descriptive names are easier for your team to read.", name, len(name), p.MaxLength)
+	}
+
+	if len(p.RequiredPrefixes) > 0 {
+		matched := false
+		for _, prefix := range p.RequiredPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("fatal: branch name '%s' does not start with an allowed prefix (%s)\nhint: Prefixes tell others what kind of work a branch holds, e.g. '%s%s'.", name, strings.Join(p.RequiredPrefixes, ", "), p.RequiredPrefixes[0], name)
+		}
+	}
+
+	if p.Pattern != "" {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("fatal: invalid branch policy pattern '%s': %w", p.Pattern, err)
+		}
+		if !re.MatchString(name) {
+			return fmt.Errorf("fatal: branch name '%s' does not match the required pattern %s\nhint: Ask your team which naming convention is in use before creating branches.", name, p.Pattern)
+		}
+	}
+
+	return nil
+}
+
+// SetRemoteBranchPolicy configures the branch policy enforced when pushing to a shared remote.
+// Passing nil (or an empty policy) removes it.
+func (sm *SessionManager) SetRemoteBranchPolicy(name string, policy *BranchPolicy) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, ok := sm.SharedRemotes[name]; !ok {
+		return fmt.Errorf("remote '%s' not found", name)
+	}
+	if policy.IsEmpty() {
+		delete(sm.RemoteBranchPolicies, name)
+		return nil
+	}
+	sm.RemoteBranchPolicies[name] = policy
+	return nil
+}
+
+// GetRemoteBranchPolicy returns the policy configured for the named shared remote, if any.
+func (sm *SessionManager) GetRemoteBranchPolicy(name string) *BranchPolicy {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.RemoteBranchPolicies[name]
+}
+
+// BranchPolicyForRepo returns the policy of whichever shared remote alias maps to repo.
+// Shared remotes are registered under several keys (name, URL, path), so any alias may carry the policy.
+func (sm *SessionManager) BranchPolicyForRepo(repo *gogit.Repository) *BranchPolicy {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for key, r := range sm.SharedRemotes {
+		if r != repo {
+			continue
+		}
+		if policy, ok := sm.RemoteBranchPolicies[key]; ok {
+			return policy
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// BranchTracking relates a local branch to the remote-tracking branch it follows
+// (branch.<name>.remote / branch.<name>.merge), so the UI can say
+// "main is 2 ahead, 1 behind origin/main".
+type BranchTracking struct {
+	Upstream string `json:"upstream"`       // Remote-tracking branch, e.g. "origin/main"
+	Ahead    int    `json:"ahead"`          // Commits on the branch that the upstream lacks
+	Behind   int    `json:"behind"`         // Commits on the upstream that the branch lacks
+	Gone     bool   `json:"gone,omitempty"` // The upstream is configured but has no remote-tracking branch
+}
+
+// populateTracking fills Tracking for the local branches that have an upstream.
+func populateTracking(repo *gogit.Repository, state *GraphState) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return
+	}
+	for name, b := range cfg.Branches {
+		tip, ok := state.Branches[name]
+		if !ok || b.Remote == "" || b.Merge == "" {
+			continue
+		}
+		upstreamName := plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short())
+		tracking := BranchTracking{Upstream: upstreamName.Short()}
+		if upstream, err := repo.Reference(upstreamName, true); err != nil {
+			tracking.Gone = true
+		} else {
+			tracking.Ahead, tracking.Behind = AheadBehind(repo, plumbing.NewHash(tip), upstream.Hash())
+		}
+		state.Tracking[name] = tracking
+	}
+}
+
+// AheadBehind counts the commits reachable from local but not upstream (ahead)
+// and from upstream but not local (behind): everything above their merge bases.
+func AheadBehind(repo *gogit.Repository, local, upstream plumbing.Hash) (ahead, behind int) {
+	if local == upstream {
+		return 0, 0
+	}
+	localCommit, err := repo.CommitObject(local)
+	if err != nil {
+		return 0, 0
+	}
+	upstreamCommit, err := repo.CommitObject(upstream)
+	if err != nil {
+		return 0, 0
+	}
+	bases, err := localCommit.MergeBase(upstreamCommit)
+	if err != nil {
+		return 0, 0
+	}
+	starts := make([]plumbing.Hash, len(bases))
+	for i, base := range bases {
+		starts[i] = base.Hash
+	}
+	shared := reachableCommits(repo, starts, nil)
+	return len(reachableCommits(repo, []plumbing.Hash{local}, shared)), len(reachableCommits(repo, []plumbing.Hash{upstream}, shared))
+}
+
+// reachableCommits returns the commits reachable from starts without passing through stop.
+func reachableCommits(repo *gogit.Repository, starts []plumbing.Hash, stop map[plumbing.Hash]bool) map[plumbing.Hash]bool {
+	seen := make(map[plumbing.Hash]bool)
+	queue := append([]plumbing.Hash(nil), starts...)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if seen[hash] || stop[hash] {
+			continue
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			continue // Shallow boundary
+		}
+		seen[hash] = true
+		queue = append(queue, commit.ParentHashes...)
+	}
+	return seen
+}
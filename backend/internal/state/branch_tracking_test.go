@@ -0,0 +1,54 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGraphState_Tracking(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+	sig := &object.Signature{Name: "User", Email: "user@example.com"}
+	commit := func(msg string, parents ...plumbing.Hash) plumbing.Hash {
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true, Parents: parents})
+		require.NoError(t, err)
+		return hash
+	}
+
+	// base <- local1 <- local2 (master)
+	//      <- remote1 (origin/master)
+	base := commit("base")
+	local1 := commit("local 1", base)
+	local2 := commit("local 2", local1)
+	remote1 := commit("remote 1", base)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", local2)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/master", remote1)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/synced", base)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/synced", base)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/untracked", base)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/orphaned", base)))
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"origin"}})
+	require.NoError(t, err)
+	cfg, _ := repo.Config()
+	for _, name := range []string{"master", "synced", "orphaned"} {
+		cfg.Branches[name] = &config.Branch{Name: name, Remote: "origin", Merge: plumbing.NewBranchReferenceName(name)}
+	}
+	require.NoError(t, repo.Storer.SetConfig(cfg))
+
+	state := BuildGraphState(repo)
+	assert.Equal(t, map[string]BranchTracking{
+		"master":   {Upstream: "origin/master", Ahead: 2, Behind: 1},
+		"synced":   {Upstream: "origin/synced"},
+		"orphaned": {Upstream: "origin/orphaned", Gone: true},
+	}, state.Tracking)
+}
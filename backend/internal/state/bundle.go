@@ -0,0 +1,221 @@
+package state
+
+// bundle.go - Git bundles ("git bundle", "git clone <file>.bundle")
+//
+// A bundle is a file holding refs and the objects they reach: a v2 header
+// listing prerequisite commits ("-<hash> <subject>") and refs ("<hash>
+// <refname>"), a blank line, then a packfile. It moves history without a
+// remote: learners bundle a repository to carry it elsewhere, missions ship
+// prebuilt histories as bundles, and the export endpoint downloads one for
+// "git clone" with real Git.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+const (
+	bundleV2Signature = "# v2 git bundle"
+	bundleV3Signature = "# v3 git bundle"
+)
+
+// Bundle describes the header of a bundle file.
+type Bundle struct {
+	Refs          []*plumbing.Reference // HEAD first when the bundle has one
+	Prerequisites []plumbing.Hash       // Commits the receiving repository must already have
+}
+
+// IsBundle reports whether data starts like a bundle file.
+func IsBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(bundleV2Signature+"\n")) || bytes.HasPrefix(data, []byte(bundleV3Signature+"\n"))
+}
+
+// AllBundleRefs returns HEAD and every branch, tag and remote-tracking ref of
+// repo, the refs "git bundle create <file> --all" records.
+func AllBundleRefs(repo *gogit.Repository) ([]*plumbing.Reference, error) {
+	var refs []*plumbing.Reference
+	iter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && ref.Name() != plumbing.HEAD && !IsSpecialRef(ref.Name()) {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+	if head, err := repo.Head(); err == nil {
+		refs = append([]*plumbing.Reference{plumbing.NewHashReference(plumbing.HEAD, head.Hash())}, refs...)
+	}
+	return refs, nil
+}
+
+// WriteBundle writes every ref of repo and the objects they reach as a bundle.
+func WriteBundle(repo *gogit.Repository, w io.Writer) error {
+	refs, err := AllBundleRefs(repo)
+	if err != nil {
+		return err
+	}
+	return CreateBundle(repo, refs, nil, w)
+}
+
+// CreateBundle writes refs to w with the objects they reach, leaving out the
+// objects the prerequisite commits reach: the receiver must have those.
+func CreateBundle(repo *gogit.Repository, refs []*plumbing.Reference, prerequisites []plumbing.Hash, w io.Writer) error {
+	if len(refs) == 0 {
+		return fmt.Errorf("fatal: Refusing to create empty bundle.")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(bundleV2Signature + "\n")
+	for _, hash := range prerequisites {
+		subject := ""
+		if commit, err := repo.CommitObject(hash); err == nil {
+			subject = strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0]
+		}
+		sb.WriteString(fmt.Sprintf("-%s %s\n", hash, subject))
+	}
+	seen := make(map[plumbing.Hash]bool)
+	var wants []plumbing.Hash
+	for _, ref := range refs {
+		sb.WriteString(fmt.Sprintf("%s %s\n", ref.Hash(), ref.Name()))
+		if !seen[ref.Hash()] {
+			seen[ref.Hash()] = true
+			wants = append(wants, ref.Hash())
+		}
+	}
+	sb.WriteString("\n")
+
+	var ignore []plumbing.Hash
+	if len(prerequisites) > 0 {
+		var err error
+		if ignore, err = revlist.Objects(repo.Storer, prerequisites, nil); err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+	}
+	objects, err := revlist.Objects(repo.Storer, wants, ignore)
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return err
+	}
+	_, err = packfile.NewEncoder(w, repo.Storer, false).Encode(objects, 10)
+	return err
+}
+
+// ReadBundleHeader parses the header of a bundle and leaves r at its packfile.
+func ReadBundleHeader(r *bufio.Reader) (*Bundle, error) {
+	signature, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle file")
+	}
+	signature = strings.TrimSuffix(signature, "\n")
+	if signature != bundleV2Signature && signature != bundleV3Signature {
+		return nil, fmt.Errorf("not a bundle file")
+	}
+
+	b := &Bundle{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("bundle header is truncated")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return b, nil
+		case strings.HasPrefix(line, "@"):
+			// v3 capability, e.g. @object-format=sha1
+			if strings.HasPrefix(line, "@object-format=") && line != "@object-format=sha1" {
+				return nil, fmt.Errorf("unsupported bundle capability: %s", line)
+			}
+		case strings.HasPrefix(line, "-"):
+			hash, _, _ := strings.Cut(line[1:], " ")
+			if !plumbing.IsHash(hash) {
+				return nil, fmt.Errorf("bundle header has an invalid prerequisite: %s", line)
+			}
+			b.Prerequisites = append(b.Prerequisites, plumbing.NewHash(hash))
+		default:
+			hash, name, ok := strings.Cut(line, " ")
+			if !ok || !plumbing.IsHash(hash) {
+				return nil, fmt.Errorf("bundle header has an invalid ref: %s", line)
+			}
+			b.Refs = append(b.Refs, plumbing.NewHashReference(plumbing.ReferenceName(name), plumbing.NewHash(hash)))
+		}
+	}
+}
+
+// OpenBundle unpacks a bundle into an in-memory repository holding its refs
+// and objects. HEAD points at the branch the bundle's HEAD is on, when it
+// records one, so a clone checks that branch out. A bundle with
+// prerequisites unpacks fine but its history stops at them.
+func OpenBundle(data []byte) (*gogit.Repository, *Bundle, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	b, err := ReadBundleHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	st := memory.NewStorage()
+	if err := packfile.UpdateObjectStorage(st, r); err != nil {
+		return nil, nil, fmt.Errorf("bundle packfile is corrupt: %w", err)
+	}
+	repo, err := gogit.Init(st, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var head *plumbing.Reference
+	for _, ref := range b.Refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+			continue
+		}
+		if err := st.SetReference(ref); err != nil {
+			return nil, nil, err
+		}
+	}
+	if head != nil {
+		symbolic := plumbing.NewHashReference(plumbing.HEAD, head.Hash())
+		for _, ref := range b.Refs {
+			if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+				symbolic = plumbing.NewSymbolicReference(plumbing.HEAD, ref.Name())
+				break
+			}
+		}
+		if err := st.SetReference(symbolic); err != nil {
+			return nil, nil, err
+		}
+	}
+	return repo, b, nil
+}
+
+// MissingPrerequisites returns the prerequisites of b that repo lacks.
+func MissingPrerequisites(repo *gogit.Repository, b *Bundle) []plumbing.Hash {
+	var missing []plumbing.Hash
+	for _, hash := range b.Prerequisites {
+		if repo == nil {
+			missing = append(missing, hash)
+			continue
+		}
+		if _, err := repo.CommitObject(hash); err != nil {
+			missing = append(missing, hash)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,346 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Kinds of simulated CI checks
+const (
+	CheckFileExists    = "file-exists"    // Path must exist in the commit's tree
+	CheckFileContains  = "file-contains"  // Content of Path must match Pattern
+	CheckCommitMessage = "commit-message" // Commit message must match Pattern
+)
+
+// States of a check run, as reported by GitHub's commit status API
+const (
+	CheckPending = "pending"
+	CheckSuccess = "success"
+	CheckFailure = "failure"
+)
+
+// DefaultCheckDelay is how long simulated checks stay pending before they report.
+const DefaultCheckDelay = 2 * time.Second
+
+// CheckRule is a CI check a shared remote runs on every pushed branch and
+// pull request head.
+type CheckRule struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`              // CheckFileExists, CheckFileContains or CheckCommitMessage
+	Path    string `json:"path,omitempty"`    // File inspected by file checks
+	Pattern string `json:"pattern,omitempty"` // Regular expression for content and message checks
+}
+
+// Validate reports whether the rule is complete and its pattern compiles.
+func (r CheckRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("check name is required")
+	}
+	switch r.Kind {
+	case CheckFileExists, CheckFileContains:
+		if r.Path == "" {
+			return fmt.Errorf("check %q: path is required", r.Name)
+		}
+	case CheckCommitMessage:
+	default:
+		return fmt.Errorf("check %q: unknown kind %q (want %s, %s or %s)", r.Name, r.Kind, CheckFileExists, CheckFileContains, CheckCommitMessage)
+	}
+	if r.Kind != CheckFileExists {
+		if r.Pattern == "" {
+			return fmt.Errorf("check %q: pattern is required", r.Name)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("check %q: invalid pattern: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// evaluate runs the rule against commit and returns the resulting state and description.
+func (r CheckRule) evaluate(commit *object.Commit) (string, string) {
+	if r.Kind == CheckCommitMessage {
+		if regexp.MustCompile(r.Pattern).MatchString(commit.Message) {
+			return CheckSuccess, "Commit message matches " + r.Pattern
+		}
+		return CheckFailure, "Commit message does not match " + r.Pattern
+	}
+
+	file, err := commit.File(r.Path)
+	if err != nil {
+		return CheckFailure, fmt.Sprintf("%s is missing", r.Path)
+	}
+	if r.Kind == CheckFileExists {
+		return CheckSuccess, fmt.Sprintf("%s exists", r.Path)
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return CheckFailure, fmt.Sprintf("%s could not be read", r.Path)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return CheckFailure, fmt.Sprintf("%s could not be read", r.Path)
+	}
+	if regexp.MustCompile(r.Pattern).Match(content) {
+		return CheckSuccess, fmt.Sprintf("%s matches %s", r.Path, r.Pattern)
+	}
+	return CheckFailure, fmt.Sprintf("%s does not match %s", r.Path, r.Pattern)
+}
+
+// CheckStatus is the outcome of one check on one commit.
+type CheckStatus struct {
+	Name        string     `json:"name"`
+	State       string     `json:"state"` // CheckPending, CheckSuccess or CheckFailure
+	Description string     `json:"description,omitempty"`
+	Remote      string     `json:"remote"`
+	Ref         string     `json:"ref,omitempty"` // Branch the commit was pushed to
+	Commit      string     `json:"commit"`
+	StartedAt   time.Time  `json:"startedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// CombinedCheckState summarizes statuses like GitHub's combined status: any
+// failure fails, otherwise anything pending is pending. No checks yields "".
+func CombinedCheckState(statuses []CheckStatus) string {
+	state := ""
+	for _, st := range statuses {
+		switch st.State {
+		case CheckFailure:
+			return CheckFailure
+		case CheckPending:
+			state = CheckPending
+		case CheckSuccess:
+			if state == "" {
+				state = CheckSuccess
+			}
+		}
+	}
+	return state
+}
+
+// SetRemoteCheckRules configures the checks run on pushes to a shared remote.
+// Passing no rules removes them.
+func (sm *SessionManager) SetRemoteCheckRules(name string, rules []CheckRule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, ok := sm.SharedRemotes[name]; !ok {
+		return fmt.Errorf("remote '%s' not found", name)
+	}
+	if len(rules) == 0 {
+		delete(sm.RemoteCheckRules, name)
+		return nil
+	}
+	sm.RemoteCheckRules[name] = append([]CheckRule(nil), rules...)
+	return nil
+}
+
+// GetRemoteCheckRules returns the checks configured for the named shared remote.
+func (sm *SessionManager) GetRemoteCheckRules(name string) []CheckRule {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return append([]CheckRule(nil), sm.RemoteCheckRules[name]...)
+}
+
+// CheckRulesForRepo returns the name and checks of whichever shared remote alias
+// of repo has checks configured.
+func (sm *SessionManager) CheckRulesForRepo(repo *gogit.Repository) (string, []CheckRule) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for key, r := range sm.SharedRemotes {
+		if r != repo {
+			continue
+		}
+		if rules, ok := sm.RemoteCheckRules[key]; ok {
+			return key, append([]CheckRule(nil), rules...)
+		}
+	}
+	return "", nil
+}
+
+// RunChecks starts the configured checks of repo on commit, pushed to branch
+// ref. The statuses are pending until CheckDelay has passed, then report.
+// The outcomes are computed right away, while the caller still has the
+// repository to itself, so the background run never touches git objects.
+func (sm *SessionManager) RunChecks(repo *gogit.Repository, ref string, hash plumbing.Hash) []CheckStatus {
+	remote, rules := sm.CheckRulesForRepo(repo)
+	if len(rules) == 0 {
+		return nil
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	pending := make([]CheckStatus, len(rules))
+	results := make([]CheckStatus, len(rules))
+	for i, rule := range rules {
+		pending[i] = CheckStatus{
+			Name: rule.Name, State: CheckPending, Description: "Waiting for the check to complete",
+			Remote: remote, Ref: ref, Commit: hash.String(), StartedAt: now,
+		}
+		results[i] = pending[i]
+		results[i].State, results[i].Description = rule.evaluate(commit)
+	}
+
+	sm.mu.Lock()
+	sm.recordCheckStatusesLocked(pending)
+	delay := sm.CheckDelay
+	sm.mu.Unlock()
+
+	sm.checkRuns.Add(1)
+	go func() {
+		defer sm.checkRuns.Done()
+		time.Sleep(delay)
+		done := time.Now()
+		for i := range results {
+			results[i].CompletedAt = &done
+		}
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		sm.recordCheckStatusesLocked(results)
+	}()
+	return pending
+}
+
+// WaitForChecks blocks until every running check has reported.
+func (sm *SessionManager) WaitForChecks() {
+	sm.checkRuns.Wait()
+}
+
+// recordCheckStatusesLocked stores statuses of a single commit, replacing earlier
+// runs of the same checks, and refreshes the open pull requests whose head is
+// that commit. Caller holds sm.mu.
+func (sm *SessionManager) recordCheckStatusesLocked(statuses []CheckStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	commit, remote := statuses[0].Commit, statuses[0].Remote
+	existing := sm.checkStatuses[commit]
+	for _, st := range statuses {
+		i := 0
+		for i < len(existing) && (existing[i].Remote != st.Remote || existing[i].Name != st.Name) {
+			i++
+		}
+		switch {
+		case i == len(existing):
+			existing = append(existing, st)
+		case !existing[i].StartedAt.After(st.StartedAt):
+			// A stale run finishing late must not overwrite a newer one
+			existing[i] = st
+		}
+	}
+	sm.checkStatuses[commit] = existing
+
+	for _, pr := range sm.PullRequests {
+		if pr.State == PRStateOpen && pr.RemoteName == remote && pr.ChecksCommit == commit {
+			sm.refreshPullRequestChecksLocked(pr)
+		}
+	}
+}
+
+// refreshPullRequestChecksLocked recomputes the check state shown on a pull request. Caller holds sm.mu.
+func (sm *SessionManager) refreshPullRequestChecksLocked(pr *PullRequest) {
+	var statuses []CheckStatus
+	for _, st := range sm.checkStatuses[pr.ChecksCommit] {
+		if st.Remote == pr.RemoteName {
+			statuses = append(statuses, st)
+		}
+	}
+	pr.ChecksState = CombinedCheckState(statuses)
+	sm.savePullRequestLocked(pr)
+}
+
+// CommitChecks returns the statuses reported for a commit, sorted by check name.
+func (sm *SessionManager) CommitChecks(hash string) []CheckStatus {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	statuses := append([]CheckStatus(nil), sm.checkStatuses[hash]...)
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Remote != statuses[j].Remote {
+			return statuses[i].Remote < statuses[j].Remote
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// PullRequestChecks returns the statuses of the pull request's head commit on its remote.
+func (sm *SessionManager) PullRequestChecks(id int) ([]CheckStatus, error) {
+	pr, err := sm.GetPullRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []CheckStatus
+	for _, st := range sm.CommitChecks(pr.ChecksCommit) {
+		if st.Remote == pr.RemoteName {
+			statuses = append(statuses, st)
+		}
+	}
+	return statuses, nil
+}
+
+// startPullRequestChecks runs the remote's checks on the pull request's head
+// branch and attaches them to the pull request.
+func (sm *SessionManager) startPullRequestChecks(id int) {
+	sm.mu.Lock()
+	pr, err := sm.findPullRequestLocked(id)
+	if err != nil {
+		sm.mu.Unlock()
+		return
+	}
+	repo, ok := sm.SharedRemotes[pr.RemoteName]
+	if !ok {
+		sm.mu.Unlock()
+		return
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(pr.HeadRef), true)
+	if err != nil {
+		sm.mu.Unlock()
+		return
+	}
+	pr.ChecksCommit = ref.Hash().String()
+	sm.refreshPullRequestChecksLocked(pr)
+	sm.mu.Unlock()
+
+	sm.RunChecks(repo, pr.HeadRef, ref.Hash())
+}
+
+// BranchPushed runs the remote's checks on a branch that was pushed to repo and
+// points the open pull requests from that branch at the new commit. Pushes to
+// the default branch also close the issues the new commits fix. It returns
+// the pending statuses of the started checks.
+func (sm *SessionManager) BranchPushed(repo *gogit.Repository, branch string, hash plumbing.Hash) []CheckStatus {
+	sm.CloseLinkedIssues(repo, branch, hash)
+
+	remote, rules := sm.CheckRulesForRepo(repo)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	sm.mu.Lock()
+	for _, pr := range sm.PullRequests {
+		if pr.State == PRStateOpen && pr.RemoteName == remote && pr.HeadRef == branch {
+			pr.ChecksCommit = hash.String()
+			sm.refreshPullRequestChecksLocked(pr)
+		}
+	}
+	sm.mu.Unlock()
+
+	return sm.RunChecks(repo, branch, hash)
+}
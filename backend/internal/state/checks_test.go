@@ -0,0 +1,98 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecks_PushAndPullRequest(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	commit := func(file, content, msg string) plumbing.Hash {
+		f, _ := w.Filesystem.Create(file)
+		_, _ = f.Write([]byte(content))
+		_ = f.Close()
+		_, _ = w.Add(file)
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: &object.Signature{Name: "Alice", Email: "alice@example.com"}})
+		require.NoError(t, err)
+		return hash
+	}
+	first := commit("README.md", "# Project\n", "wip")
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", first)))
+
+	sm := NewSessionManager()
+	sm.SharedRemotes["origin"] = repo
+	sm.CheckDelay = 0
+
+	assert.Error(t, sm.SetRemoteCheckRules("origin", []CheckRule{{Name: "lint", Kind: "compile"}}))
+	assert.Error(t, sm.SetRemoteCheckRules("origin", []CheckRule{{Name: "msg", Kind: CheckCommitMessage, Pattern: "("}}))
+	assert.Error(t, sm.SetRemoteCheckRules("upstream", []CheckRule{{Name: "tests", Kind: CheckFileExists, Path: "test.sh"}}))
+	require.NoError(t, sm.SetRemoteCheckRules("origin", []CheckRule{
+		{Name: "tests", Kind: CheckFileExists, Path: "test.sh"},
+		{Name: "readme", Kind: CheckFileContains, Path: "README.md", Pattern: "^# "},
+		{Name: "conventional-commits", Kind: CheckCommitMessage, Pattern: `^(feat|fix): `},
+	}))
+
+	// Opening a pull request runs the checks on its head
+	pr, err := sm.CreatePullRequest("Feature", "", "feature", "main", "alice", "origin")
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), pr.ChecksCommit)
+	assert.Contains(t, []string{CheckPending, CheckFailure}, pr.ChecksState)
+	sm.WaitForChecks()
+
+	statuses, err := sm.PullRequestChecks(pr.ID)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	states := map[string]string{}
+	for _, st := range statuses {
+		states[st.Name] = st.State
+		assert.NotNil(t, st.CompletedAt)
+	}
+	assert.Equal(t, map[string]string{"tests": CheckFailure, "readme": CheckSuccess, "conventional-commits": CheckFailure}, states)
+	got, _ := sm.GetPullRequest(pr.ID)
+	assert.Equal(t, CheckFailure, got.ChecksState)
+
+	// Pushing a fix moves the pull request to the new head and re-runs the checks
+	fixed := commit("test.sh", "exit 0\n", "feat: add tests")
+	pending := sm.BranchPushed(repo, "feature", fixed)
+	require.Len(t, pending, 3)
+	assert.Equal(t, CheckPending, pending[0].State)
+	sm.WaitForChecks()
+
+	got, _ = sm.GetPullRequest(pr.ID)
+	assert.Equal(t, fixed.String(), got.ChecksCommit)
+	assert.Equal(t, CheckSuccess, got.ChecksState)
+	assert.Equal(t, CheckSuccess, CombinedCheckState(sm.CommitChecks(fixed.String())))
+	assert.Equal(t, CheckFailure, CombinedCheckState(sm.CommitChecks(first.String())), "old commits keep their results")
+
+	// Remotes without checks run nothing
+	require.NoError(t, sm.SetRemoteCheckRules("origin", nil))
+	assert.Empty(t, sm.BranchPushed(repo, "feature", fixed))
+}
+
+func TestCombinedCheckState(t *testing.T) {
+	for _, tc := range []struct {
+		states []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{CheckSuccess, CheckSuccess}, CheckSuccess},
+		{[]string{CheckSuccess, CheckPending}, CheckPending},
+		{[]string{CheckPending, CheckFailure, CheckSuccess}, CheckFailure},
+	} {
+		var statuses []CheckStatus
+		for _, s := range tc.states {
+			statuses = append(statuses, CheckStatus{State: s})
+		}
+		assert.Equal(t, tc.want, CombinedCheckState(statuses), "%v", tc.states)
+	}
+}
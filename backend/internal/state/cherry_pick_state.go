@@ -0,0 +1,45 @@
+package state
+
+import "strings"
+
+// CherryPickState records a cherry-pick that stopped on a conflict, so that
+// "git cherry-pick --continue" and "--abort" can pick up where it left off.
+type CherryPickState struct {
+	Repo      string   `json:"repo"`      // Repository path the cherry-pick runs in
+	OrigHead  string   `json:"origHead"`  // HEAD before the cherry-pick started, restored by --abort
+	Current   string   `json:"current"`   // Commit whose changes conflicted
+	Todo      []string `json:"todo"`      // Commits still to pick after Current, oldest first
+	Conflicts []string `json:"conflicts"` // Paths left with conflict markers
+	Picked    int      `json:"picked"`    // Commits picked so far
+}
+
+// activeRepoPath returns the Repos key of the repository in the current directory.
+func (s *Session) activeRepoPath() string {
+	return strings.TrimPrefix(s.CurrentDir, "/")
+}
+
+// CherryPickInProgress returns the stopped cherry-pick of the active repository, or nil.
+func (s *Session) CherryPickInProgress() *CherryPickState {
+	if s.CherryPick == nil || s.CherryPick.Repo != s.activeRepoPath() {
+		return nil
+	}
+	return s.CherryPick
+}
+
+// StartCherryPick records a stopped cherry-pick for the active repository.
+func (s *Session) StartCherryPick(cp *CherryPickState) {
+	if s.CherryPick != nil && s.CherryPick.Repo != s.activeRepoPath() {
+		s.setStateRef(s.CherryPick.Repo, CherryPickHead, "")
+	}
+	cp.Repo = s.activeRepoPath()
+	s.CherryPick = cp
+	s.setStateRef(cp.Repo, CherryPickHead, cp.Current)
+}
+
+// ClearCherryPick forgets the stopped cherry-pick, after it completed or was aborted.
+func (s *Session) ClearCherryPick() {
+	if s.CherryPick != nil {
+		s.setStateRef(s.CherryPick.Repo, CherryPickHead, "")
+	}
+	s.CherryPick = nil
+}
@@ -0,0 +1,290 @@
+package state
+
+// collaboration.go - Several sessions working on one shared remote
+//
+// Each browser session can act as a distinct simulated user. Pushes record
+// per-user tracking refs (refs/users/<user>/<branch>) on the shared remote,
+// so the remote knows who last pushed what, who is working on it, and who a
+// rejected push collided with.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// userRefPrefix is where shared remotes keep per-user tracking refs.
+const userRefPrefix = "refs/users/"
+
+// maxPushConflicts bounds the push conflicts remembered per manager.
+const maxPushConflicts = 50
+
+// UserIdentity is who a session's commits and pushes are attributed to.
+type UserIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// DefaultUser is the identity of sessions that did not pick one.
+var DefaultUser = UserIdentity{Name: "User", Email: "user@example.com"}
+
+// Slug returns the identity's name in a form usable as a ref path component.
+func (u UserIdentity) Slug() string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(u.Name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-"):
+			sb.WriteByte('-')
+		}
+	}
+	if slug := strings.Trim(sb.String(), "-"); slug != "" {
+		return slug
+	}
+	return "user"
+}
+
+// UserRefName returns the per-user tracking ref of branch on a shared remote.
+func UserRefName(user UserIdentity, branch string) plumbing.ReferenceName {
+	return plumbing.ReferenceName(userRefPrefix + user.Slug() + "/" + branch)
+}
+
+// Identity returns the user the session acts as. Caller holds the session lock
+// or otherwise knows the identity is not being changed.
+func (s *Session) Identity() UserIdentity {
+	if s.User == nil {
+		return DefaultUser
+	}
+	return *s.User
+}
+
+// Signature returns an author/committer signature for the session's user at the current time.
+func (s *Session) Signature() *object.Signature {
+	u := s.Identity()
+	return &object.Signature{Name: u.Name, Email: u.Email, When: time.Now()}
+}
+
+// SetSessionUser makes s act as user. The email defaults to <slug>@example.com.
+func (sm *SessionManager) SetSessionUser(s *Session, user UserIdentity) error {
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Name == "" {
+		return fmt.Errorf("user name is required")
+	}
+	if user.Email == "" {
+		user.Email = user.Slug() + "@example.com"
+	}
+	s.mu.Lock()
+	s.User = &user
+	s.mu.Unlock()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.registerUserLocked(user)
+	return nil
+}
+
+// registerUserLocked remembers user so tracking refs can be mapped back to it. Caller holds sm.mu.
+func (sm *SessionManager) registerUserLocked(user UserIdentity) {
+	if sm.users == nil {
+		sm.users = make(map[string]UserIdentity)
+	}
+	sm.users[user.Slug()] = user
+}
+
+// userBySlugLocked returns the identity behind a tracking ref path component. Caller holds sm.mu.
+func (sm *SessionManager) userBySlugLocked(slug string) UserIdentity {
+	if user, ok := sm.users[slug]; ok {
+		return user
+	}
+	if slug == DefaultUser.Slug() {
+		return DefaultUser
+	}
+	return UserIdentity{Name: slug}
+}
+
+// PushConflict records a push that was rejected because another user updated
+// the branch first.
+type PushConflict struct {
+	Remote    string       `json:"remote"`
+	Branch    string       `json:"branch"`
+	User      UserIdentity `json:"user"`      // Whose push was rejected
+	PushedBy  UserIdentity `json:"pushedBy"`  // Who updated the branch first
+	Commit    string       `json:"commit"`    // Commit the rejected push tried to publish
+	RemoteTip string       `json:"remoteTip"` // Commit the branch held instead
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// Collaborator is a user with a session attached to a shared remote.
+type Collaborator struct {
+	User       UserIdentity      `json:"user"`
+	Repo       string            `json:"repo"`             // Session repository cloned from the remote
+	Branch     string            `json:"branch,omitempty"` // Branch checked out there
+	LastActive time.Time         `json:"lastActive"`
+	Pushed     map[string]string `json:"pushed,omitempty"` // Branch -> commit from the user's tracking refs
+}
+
+// Presence describes who works on a shared remote and where they collided.
+type Presence struct {
+	Remote        string         `json:"remote"`
+	Collaborators []Collaborator `json:"collaborators"`
+	Conflicts     []PushConflict `json:"conflicts"`
+}
+
+// RecordUserPush updates the user's tracking ref for branch on repo.
+func RecordUserPush(repo *gogit.Repository, user UserIdentity, branch string, hash plumbing.Hash) error {
+	return repo.Storer.SetReference(plumbing.NewHashReference(UserRefName(user, branch), hash))
+}
+
+// LastPusher returns the user, other than except, whose tracking ref for
+// branch on repo is at hash, i.e. who published the commit a push collides with.
+func (sm *SessionManager) LastPusher(repo *gogit.Repository, branch string, hash plumbing.Hash, except UserIdentity) (UserIdentity, bool) {
+	refs, err := repo.References()
+	if err != nil {
+		return UserIdentity{}, false
+	}
+	var slugs []string
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		slug, refBranch, ok := splitUserRef(ref.Name())
+		if ok && refBranch == branch && slug != except.Slug() && ref.Hash() == hash {
+			slugs = append(slugs, slug)
+		}
+		return nil
+	})
+	if len(slugs) == 0 {
+		return UserIdentity{}, false
+	}
+	sort.Strings(slugs)
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.userBySlugLocked(slugs[0]), true
+}
+
+// splitUserRef splits refs/users/<slug>/<branch> into its slug and branch.
+func splitUserRef(name plumbing.ReferenceName) (string, string, bool) {
+	rest, ok := strings.CutPrefix(name.String(), userRefPrefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, "/")
+}
+
+// RecordPushConflict remembers a rejected push for the presence API.
+func (sm *SessionManager) RecordPushConflict(repo *gogit.Repository, conflict PushConflict) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if conflict.Remote == "" {
+		conflict.Remote = sm.remoteNameLocked(repo)
+	}
+	sm.pushConflicts = append(sm.pushConflicts, conflict)
+	if n := len(sm.pushConflicts); n > maxPushConflicts {
+		sm.pushConflicts = sm.pushConflicts[n-maxPushConflicts:]
+	}
+}
+
+// remoteNameLocked returns the shortest alias of repo among the shared remotes,
+// which is its plain name rather than its URL or path. Caller holds sm.mu.
+func (sm *SessionManager) remoteNameLocked(repo *gogit.Repository) string {
+	name := ""
+	for key, r := range sm.SharedRemotes {
+		if r == repo && (name == "" || len(key) < len(name) || (len(key) == len(name) && key < name)) {
+			name = key
+		}
+	}
+	return name
+}
+
+// Presence lists the users whose sessions have a repository attached to the
+// named shared remote, and the push conflicts that happened on it.
+func (sm *SessionManager) Presence(name string) (*Presence, error) {
+	sm.mu.RLock()
+	target, ok := sm.SharedRemotes[name]
+	if !ok {
+		sm.mu.RUnlock()
+		return nil, fmt.Errorf("remote '%s' not found", name)
+	}
+	aliases := make(map[string]bool)
+	for key, r := range sm.SharedRemotes {
+		if r == target {
+			aliases[key] = true
+		}
+	}
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		sessions = append(sessions, s)
+	}
+	presence := &Presence{Remote: name, Collaborators: []Collaborator{}, Conflicts: []PushConflict{}}
+	for _, c := range sm.pushConflicts {
+		if aliases[c.Remote] {
+			presence.Conflicts = append(presence.Conflicts, c)
+		}
+	}
+	sm.mu.RUnlock()
+
+	for _, s := range sessions {
+		s.mu.RLock()
+		user := s.Identity()
+		for path, repo := range s.Repos {
+			if !attachedTo(repo, aliases) {
+				continue
+			}
+			collaborator := Collaborator{User: user, Repo: path, LastActive: s.LastActive()}
+			if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+				collaborator.Branch = head.Name().Short()
+			}
+			presence.Collaborators = append(presence.Collaborators, collaborator)
+		}
+		s.mu.RUnlock()
+	}
+
+	// What each user last pushed, from their tracking refs on the remote
+	if refs, err := target.References(); err == nil {
+		pushed := make(map[string]map[string]string)
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			slug, branch, ok := splitUserRef(ref.Name())
+			if !ok || ref.Type() != plumbing.HashReference {
+				return nil
+			}
+			if pushed[slug] == nil {
+				pushed[slug] = make(map[string]string)
+			}
+			pushed[slug][branch] = ref.Hash().String()
+			return nil
+		})
+		for i := range presence.Collaborators {
+			presence.Collaborators[i].Pushed = pushed[presence.Collaborators[i].User.Slug()]
+		}
+	}
+
+	sort.Slice(presence.Collaborators, func(i, j int) bool {
+		a, b := presence.Collaborators[i], presence.Collaborators[j]
+		if a.User.Name != b.User.Name {
+			return a.User.Name < b.User.Name
+		}
+		return a.Repo < b.Repo
+	})
+	return presence, nil
+}
+
+// attachedTo reports whether any remote of repo points at one of the aliases of a shared remote.
+func attachedTo(repo *gogit.Repository, aliases map[string]bool) bool {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return false
+	}
+	for _, remote := range remotes {
+		for _, url := range remote.Config().URLs {
+			if aliases[url] || aliases[strings.TrimPrefix(url, "/")] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package state
+
+// CommandPolicy limits the commands a session may run, e.g. to the few a
+// lesson teaches or to keep "git push --force" out of a collaboration
+// mission. Rules are written as typed: "status", "git add", "push --force".
+// A rule with a flag blocks the command only when that flag is given.
+// A zero-value policy allows everything.
+//
+// SaveBefore does not limit anything: the commands it lists run, but a save
+// point of the state before them is taken first when they change something.
+type CommandPolicy struct {
+	Allow      []string `yaml:"allow,omitempty" json:"allow,omitempty"`            // Git commands that may run; empty allows all. Shell commands and help always may
+	Forbid     []string `yaml:"forbid,omitempty" json:"forbid,omitempty"`          // Commands, or a command and one of its flags, that may not run
+	Reason     string   `yaml:"reason,omitempty" json:"reason,omitempty"`          // Why, shown to the learner with the refusal
+	SaveBefore []string `yaml:"save_before,omitempty" json:"saveBefore,omitempty"` // Risky commands to take an automatic save point before
+}
+
+// IsEmpty reports whether the policy has no Allow or Forbid rules configured.
+func (p *CommandPolicy) IsEmpty() bool {
+	return p == nil || (len(p.Allow) == 0 && len(p.Forbid) == 0)
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Kinds of history rewriting recorded in the commit lineage.
+const (
+	RewriteAmend      = "amend"
+	RewriteRebase     = "rebase"
+	RewriteCherryPick = "cherry-pick"
+)
+
+// LineageLink records that a commit was produced by rewriting another one.
+type LineageLink struct {
+	Original string // Hash of the commit that was rewritten
+	Kind     string // RewriteAmend, RewriteRebase or RewriteCherryPick
+}
+
+// RecordLineage remembers that replacement was created from original by a
+// history-rewriting command, so the graph can show which commit became which.
+func (s *Session) RecordLineage(original, replacement plumbing.Hash, kind string) {
+	if original == replacement {
+		return
+	}
+	if s.Lineage == nil {
+		s.Lineage = make(map[string]LineageLink)
+	}
+	s.Lineage[replacement.String()] = LineageLink{Original: original.String(), Kind: kind}
+}
+
+// applyLineage annotates commits with the replaces/replacedBy links recorded in the session.
+func applyLineage(session *Session, state *GraphState) {
+	if len(session.Lineage) == 0 {
+		return
+	}
+
+	replacedBy := make(map[string][]string)
+	for replacement, link := range session.Lineage {
+		replacedBy[link.Original] = append(replacedBy[link.Original], replacement)
+	}
+
+	for i := range state.Commits {
+		c := &state.Commits[i]
+		if link, ok := session.Lineage[c.ID]; ok {
+			c.Replaces = link.Original
+			c.RewriteKind = link.Kind
+		}
+		if next, ok := replacedBy[c.ID]; ok {
+			sort.Strings(next)
+			c.ReplacedBy = next
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package state
+
+// commit_lint.go - Conventional Commits lint
+//
+// With "git config commit.lint conventional" (typically run by a mission's
+// setup), commit messages must follow https://www.conventionalcommits.org:
+//
+//	<type>[(scope)][!]: <description>
+//
+//	[body]
+//
+//	[BREAKING CHANGE: <footer>]
+//
+// Merge, revert, fixup! and squash! messages generated by git are exempt,
+// like commitlint does by default.
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// CommitLintConventional is the commit.lint value that turns the lint on.
+const CommitLintConventional = "conventional"
+
+// ConventionalTypes are the commit types the lint accepts, in changelog order.
+var ConventionalTypes = []string{"feat", "fix", "perf", "revert", "docs", "style", "refactor", "test", "build", "ci", "chore"}
+
+// maxHeaderLength is the longest accepted first line, as in commitlint's default config.
+const maxHeaderLength = 100
+
+var conventionalHeader = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?:(.*)$`)
+
+// ConventionalCommit is a commit message parsed as a Conventional Commit.
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool   // "!" after the type/scope, or a BREAKING CHANGE footer
+	Description string // Rest of the first line
+	Body        string // Everything after the blank line, footers included
+}
+
+// CommitLintEnabled reports whether the repository requires Conventional Commits.
+func CommitLintEnabled(repo *gogit.Repository) bool {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Raw.Section("commit").Option("lint"), CommitLintConventional)
+}
+
+// LintCommitMessage checks message against the Conventional Commits format and
+// returns an error saying what to change, or nil for an exempt message.
+func LintCommitMessage(message string) error {
+	if exemptFromLint(message) {
+		return nil
+	}
+	_, err := ParseConventionalCommit(message)
+	return err
+}
+
+// ParseConventionalCommit splits message into its Conventional Commit parts.
+func ParseConventionalCommit(message string) (*ConventionalCommit, error) {
+	message = strings.TrimSpace(message)
+	header, rest, _ := strings.Cut(message, "\n")
+	header = strings.TrimRight(header, " \t")
+
+	m := conventionalHeader.FindStringSubmatch(header)
+	if m == nil {
+		return nil, fmt.Errorf("error: commit message header '%s' does not follow Conventional Commits\nhint: Write it as '<type>[(scope)]: <description>', e.g. 'feat(auth): add login form'.\nhint: Types: %s", header, strings.Join(ConventionalTypes, ", "))
+	}
+	c := &ConventionalCommit{Type: m[1], Scope: m[2], Breaking: m[3] == "!", Description: strings.TrimSpace(m[4])}
+
+	if !isConventionalType(c.Type) {
+		if lower := strings.ToLower(c.Type); isConventionalType(lower) {
+			return nil, fmt.Errorf("error: commit type '%s' must be lower case\nhint: Use '%s'.", c.Type, lower)
+		}
+		return nil, fmt.Errorf("error: unknown commit type '%s'\nhint: Use one of: %s", c.Type, strings.Join(ConventionalTypes, ", "))
+	}
+	if strings.Contains(header, "()") {
+		return nil, fmt.Errorf("error: commit scope is empty\nhint: Drop the parentheses or name the part of the code you changed, e.g. '%s(api): ...'.", c.Type)
+	}
+	switch {
+	case c.Description == "":
+		return nil, fmt.Errorf("error: commit description is empty\nhint: Say what the change does after the colon, e.g. '%s: handle empty input'.", c.Type)
+	case !strings.HasPrefix(m[4], " "):
+		return nil, fmt.Errorf("error: commit description must follow ': ' with a space\nhint: Use '%s'.", strings.Replace(header, ":", ": ", 1))
+	case strings.HasSuffix(c.Description, "."):
+		return nil, fmt.Errorf("error: commit description must not end with a period\nhint: Use '%s'.", strings.TrimSuffix(header, "."))
+	case len(header) > maxHeaderLength:
+		return nil, fmt.Errorf("error: commit header is %d characters long (max %d)\nhint: Keep the first line short and move details to the body, after a blank line.", len(header), maxHeaderLength)
+	}
+
+	if rest != "" {
+		if first, _, _ := strings.Cut(rest, "\n"); strings.TrimSpace(first) != "" {
+			return nil, fmt.Errorf("error: commit header must be followed by a blank line\nhint: Put an empty line between '%s' and the body.", header)
+		}
+		c.Body = strings.TrimSpace(rest)
+	}
+	for _, line := range strings.Split(c.Body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			c.Breaking = true
+		}
+	}
+	return c, nil
+}
+
+func isConventionalType(t string) bool {
+	for _, known := range ConventionalTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// exemptFromLint reports whether message was written by git rather than the user.
+func exemptFromLint(message string) bool {
+	for _, prefix := range []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+	return false
+}
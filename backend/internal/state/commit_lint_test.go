@@ -0,0 +1,52 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	c, err := ParseConventionalCommit("feat(api)!: drop v1 endpoints\n\nBody text")
+	require.NoError(t, err)
+	assert.Equal(t, "feat", c.Type)
+	assert.Equal(t, "api", c.Scope)
+	assert.True(t, c.Breaking)
+	assert.Equal(t, "drop v1 endpoints", c.Description)
+	assert.Equal(t, "Body text", c.Body)
+
+	c, err = ParseConventionalCommit("fix: handle nil config\n\nBREAKING CHANGE: config is required")
+	require.NoError(t, err)
+	assert.True(t, c.Breaking)
+	assert.Empty(t, c.Scope)
+}
+
+func TestLintCommitMessage(t *testing.T) {
+	for _, msg := range []string{
+		"docs: update README",
+		"chore(deps): bump go-git",
+		"Merge branch 'feature' into main",
+		"Revert \"feat: add login\"",
+		"fixup! fix: typo",
+	} {
+		assert.NoError(t, LintCommitMessage(msg), msg)
+	}
+
+	for msg, want := range map[string]string{
+		"update README":           "does not follow Conventional Commits",
+		"Feat: add login":         "must be lower case",
+		"feature: add login":      "unknown commit type 'feature'",
+		"fix(): typo":             "commit scope is empty",
+		"fix: ":                   "commit description is empty",
+		"fix:typo":                "with a space",
+		"fix: typo.":              "must not end with a period",
+		"fix: typo\nmore details": "must be followed by a blank line",
+	} {
+		err := LintCommitMessage(msg)
+		if assert.Error(t, err, msg) {
+			assert.Contains(t, err.Error(), want, msg)
+			assert.Contains(t, err.Error(), "hint:", msg)
+		}
+	}
+}
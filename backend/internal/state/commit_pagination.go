@@ -0,0 +1,61 @@
+package state
+
+import "fmt"
+
+// CommitFilter selects which commits of the graph are returned. Ingested repos
+// can have thousands of commits, so the graph can be cut at a traversal depth
+// and served a page at a time, newest first.
+type CommitFilter struct {
+	MaxDepth int    // Parent hops followed from each ref; 0 means unlimited
+	Offset   int    // Commits skipped from the start of the (newest first) list
+	After    string // Continuation cursor: only commits listed after this commit ID
+	Limit    int    // Maximum commits per page; 0 means unlimited
+}
+
+// IsZero reports whether the filter returns the whole graph.
+func (f CommitFilter) IsZero() bool {
+	return f == CommitFilter{}
+}
+
+// CommitPagination describes how the Commits of a GraphState were paged.
+type CommitPagination struct {
+	Total      int    `json:"total"`                // Commits in the graph across all pages
+	Offset     int    `json:"offset"`               // Position of the first commit of this page
+	Limit      int    `json:"limit,omitempty"`      // Requested page size
+	NextCursor string `json:"nextCursor,omitempty"` // Pass as After to get the next page
+	Truncated  bool   `json:"truncated,omitempty"`  // History continues beyond MaxDepth
+}
+
+// PaginateCommits returns the page of commits selected by f together with its
+// pagination info. commits must already be in graph order. The After cursor
+// takes precedence over Offset; a cursor naming a commit that is no longer in
+// the graph (e.g. rewritten by a rebase) is an error, and the client should
+// start again from the first page.
+func PaginateCommits(commits []Commit, f CommitFilter) ([]Commit, *CommitPagination, error) {
+	start := f.Offset
+	if f.After != "" {
+		start = -1
+		for i, c := range commits {
+			if c.ID == f.After {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, nil, fmt.Errorf("unknown commit cursor: %s", f.After)
+		}
+	}
+	if start > len(commits) {
+		start = len(commits)
+	}
+
+	end := len(commits)
+	if f.Limit > 0 && start+f.Limit < end {
+		end = start + f.Limit
+	}
+	page := &CommitPagination{Total: len(commits), Offset: start, Limit: f.Limit}
+	if end < len(commits) && end > start {
+		page.NextCursor = commits[end-1].ID
+	}
+	return commits[start:end], page, nil
+}
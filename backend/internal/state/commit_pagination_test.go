@@ -0,0 +1,99 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// linearHistorySession creates a session whose repo /repo has n commits on master,
+// one minute apart. It returns the commit hashes oldest first.
+func linearHistorySession(t *testing.T, sm *SessionManager, id string, n int) (*Session, *gogit.Repository, []string) {
+	t.Helper()
+	sess, err := sm.CreateSession(id)
+	require.NoError(t, err)
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var hashes []string
+	for i := 0; i < n; i++ {
+		sig := &object.Signature{Name: "User", Email: "user@example.com", When: start.Add(time.Duration(i) * time.Minute)}
+		hash, err := w.Commit("commit", &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, hash.String())
+	}
+	sess.Repos["repo"] = repo
+	sess.CurrentDir = "/repo"
+	return sess, repo, hashes
+}
+
+func TestPaginateCommits(t *testing.T) {
+	commits := []Commit{{ID: "e"}, {ID: "d"}, {ID: "c"}, {ID: "b"}, {ID: "a"}}
+
+	page, info, err := PaginateCommits(commits, CommitFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []Commit{{ID: "e"}, {ID: "d"}}, page)
+	assert.Equal(t, &CommitPagination{Total: 5, Offset: 0, Limit: 2, NextCursor: "d"}, info)
+
+	page, info, err = PaginateCommits(commits, CommitFilter{Limit: 2, After: info.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []Commit{{ID: "c"}, {ID: "b"}}, page)
+	assert.Equal(t, 2, info.Offset)
+	assert.Equal(t, "b", info.NextCursor)
+
+	page, info, err = PaginateCommits(commits, CommitFilter{Offset: 4, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []Commit{{ID: "a"}}, page)
+	assert.Empty(t, info.NextCursor)
+
+	page, _, err = PaginateCommits(commits, CommitFilter{Offset: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	_, _, err = PaginateCommits(commits, CommitFilter{After: "gone"})
+	assert.Error(t, err)
+}
+
+func TestGetGraphStatePage(t *testing.T) {
+	sm := NewSessionManager()
+	_, repo, hashes := linearHistorySession(t, sm, "s1", 6)
+
+	// Unfiltered: every commit, no pagination info
+	state, err := sm.GetGraphState("s1")
+	require.NoError(t, err)
+	assert.Len(t, state.Commits, 6)
+	assert.Nil(t, state.CommitPagination)
+
+	// Newest first, one page at a time
+	state, err = sm.GetGraphStatePage("s1", CommitFilter{Limit: 4})
+	require.NoError(t, err)
+	require.Len(t, state.Commits, 4)
+	assert.Equal(t, hashes[5], state.Commits[0].ID)
+	assert.Equal(t, 6, state.CommitPagination.Total)
+	assert.Equal(t, hashes[2], state.CommitPagination.NextCursor)
+	assert.False(t, state.CommitPagination.Truncated)
+
+	// Depth 2 keeps the tip and two ancestors and reports the cut
+	state, err = sm.GetGraphStatePage("s1", CommitFilter{MaxDepth: 2})
+	require.NoError(t, err)
+	require.Len(t, state.Commits, 3)
+	assert.Equal(t, hashes[3], state.Commits[2].ID)
+	assert.True(t, state.CommitPagination.Truncated)
+	assert.False(t, state.Commits[2].Dangling)
+
+	// A ref pointing at the cut-off history brings it back within reach
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/old", plumbing.NewHash(hashes[2]))))
+	state, err = sm.GetGraphStatePage("s1", CommitFilter{MaxDepth: 2})
+	require.NoError(t, err)
+	assert.Len(t, state.Commits, 6)
+	assert.False(t, state.CommitPagination.Truncated)
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Draining
+//
+// On shutdown the server first stops taking requests, then drains the
+// manager: new commands and jobs are refused, while commands already running,
+// queued and running jobs and pending checks get until the deadline to
+// finish. Sessions are saved last, so the snapshots hold what finished. Jobs
+// still running at the deadline are cancelled; an interrupted ingest resumes
+// at the next start (see RecoverIngests).
+
+// DefaultShutdownTimeout is how long the server waits for requests and
+// operations to finish before it exits anyway.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeoutEnv overrides DefaultShutdownTimeout (Go duration syntax, e.g. "2m").
+const ShutdownTimeoutEnv = "GITGYM_SHUTDOWN_TIMEOUT"
+
+// ErrShuttingDown is the error of commands and jobs started while draining.
+var ErrShuttingDown = errors.New("fatal: the server is shutting down, try again in a moment")
+
+// drainer tracks the operations running in a SessionManager. The zero value is ready to use.
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	ops      sync.WaitGroup
+}
+
+// BeginOperation registers a command about to run. Drain waits until done is
+// called. Once draining, it refuses with ErrShuttingDown.
+func (sm *SessionManager) BeginOperation() (done func(), err error) {
+	d := &sm.drain
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, ErrShuttingDown
+	}
+	d.ops.Add(1)
+	var once sync.Once
+	return func() { once.Do(d.ops.Done) }, nil
+}
+
+// Draining reports whether Drain was called.
+func (sm *SessionManager) Draining() bool {
+	sm.drain.mu.Lock()
+	defer sm.drain.mu.Unlock()
+	return sm.drain.draining
+}
+
+// Drain refuses new operations, waits for the running ones until ctx is
+// done and saves every session. The error lists what did not finish in time
+// and the sessions that could not be saved.
+func (sm *SessionManager) Drain(ctx context.Context) error {
+	sm.drain.mu.Lock()
+	sm.drain.draining = true
+	sm.drain.mu.Unlock()
+	sm.jobRunner.beginDrain()
+
+	var errs []error
+	if err := waitContext(ctx, sm.drain.ops.Wait); err != nil {
+		errs = append(errs, fmt.Errorf("commands still running: %w", err))
+	}
+	if err := sm.jobRunner.waitDrained(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("jobs cancelled: %w", err))
+	}
+	if err := waitContext(ctx, sm.WaitForChecks); err != nil {
+		errs = append(errs, fmt.Errorf("checks still pending: %w", err))
+	}
+	if err := sm.SaveSessions(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// waitContext calls wait and returns when it does, or with the error of ctx
+// when ctx is done first.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain_WaitsForOperationsAndJobs(t *testing.T) {
+	sm := NewSessionManager()
+	dir := t.TempDir()
+	require.NoError(t, sm.EnablePersistence(dir))
+	_, err := sm.CreateSession("draining")
+	require.NoError(t, err)
+
+	done, err := sm.BeginOperation()
+	require.NoError(t, err)
+	release := make(chan struct{})
+	running := sm.SubmitJob(JobIngest, "slow", func(ctx context.Context, p *JobProgress) error {
+		<-release
+		return nil
+	})
+	queued := sm.SubmitJob(JobIngest, "queued", func(ctx context.Context, p *JobProgress) error { return nil })
+
+	drained := make(chan error, 1)
+	go func() { drained <- sm.Drain(context.Background()) }()
+	require.Eventually(t, sm.Draining, time.Second, time.Millisecond)
+
+	// New work is refused while the running work goes on
+	_, err = sm.BeginOperation()
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	late := sm.SubmitJob(JobSync, "late", func(ctx context.Context, p *JobProgress) error {
+		t.Error("a job submitted while draining must not run")
+		return nil
+	})
+	assert.Equal(t, JobFailed, late.State)
+	assert.Equal(t, ErrShuttingDown.Error(), late.Error)
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with a command still running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	done()
+	done() // Calling it twice is harmless
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with a job still running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case err := <-drained:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain never returned")
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		job, _ := sm.GetJob(id)
+		assert.Equal(t, JobSucceeded, job.State, id)
+	}
+
+	// The sessions were saved
+	_, err = os.Stat(filepath.Join(dir, "draining"))
+	assert.NoError(t, err)
+}
+
+func TestDrain_CancelsJobsAtDeadline(t *testing.T) {
+	sm := NewSessionManager()
+	stuck := sm.SubmitJob(JobIngest, "stuck", func(ctx context.Context, p *JobProgress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	queued := sm.SubmitJob(JobIngest, "queued", func(ctx context.Context, p *JobProgress) error {
+		t.Error("a queued job must not run after the deadline")
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := sm.Drain(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "jobs cancelled")
+
+	job, _ := sm.GetJob(queued.ID)
+	assert.Equal(t, JobCancelled, job.State)
+	require.Eventually(t, func() bool {
+		job, _ := sm.GetJob(stuck.ID)
+		return job.State == JobCancelled
+	}, time.Second, time.Millisecond)
+}
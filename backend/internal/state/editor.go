@@ -0,0 +1,14 @@
+package state
+
+// PendingEditor is a command waiting for the user to write a message in the
+// client's editor, like git blocking on $EDITOR. It plays the role of
+// .git/COMMIT_EDITMSG: the client shows Template, posts the edited text back
+// and Resume runs with it. Running any other command closes the editor.
+type PendingEditor struct {
+	ID       string   `json:"id"`
+	Command  string   `json:"command"`  // Command line that opened the editor
+	File     string   `json:"file"`     // File git would edit, e.g. ".git/COMMIT_EDITMSG"
+	Template string   `json:"template"` // Initial text; lines starting with '#' are dropped
+	Resume   []string `json:"-"`        // Command run with the edited message
+	Abort    string   `json:"-"`        // Error reported when the message is empty or the edit is cancelled
+}
@@ -1,7 +1,7 @@
 package state
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -51,6 +51,16 @@ func (fc *FileCache) Set(files []string) {
 	fc.dirty = false
 }
 
+// Stats returns the number of cached entries, the cache age in milliseconds and whether it is dirty.
+func (fc *FileCache) Stats() (entries int, ageMs int64, dirty bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	if !fc.cachedAt.IsZero() {
+		ageMs = time.Since(fc.cachedAt).Milliseconds()
+	}
+	return len(fc.files), ageMs, fc.dirty
+}
+
 // Invalidate marks the cache as needing refresh.
 func (fc *FileCache) Invalidate() {
 	fc.mu.Lock()
@@ -65,7 +75,7 @@ func WalkFilesystem(fs billy.Filesystem, startPath string, activeProject string)
 	count := 0
 	var files []string
 
-	log.Printf("Walking Filesystem: startPath=%s (ActiveProject=%s)", startPath, activeProject)
+	slog.Debug("walking filesystem", "path", startPath, "project", activeProject)
 
 	_ = util.Walk(fs, startPath, func(path string, fi os.FileInfo, err error) error {
 		if err != nil {
@@ -109,7 +119,7 @@ func WalkFilesystem(fs billy.Filesystem, startPath string, activeProject string)
 		return nil
 	})
 
-	log.Printf("Walking Filesystem: found %d files", len(files))
+	slog.Debug("walking filesystem: done", "files", len(files))
 	if count >= MaxFileCount {
 		files = append(files, "... (limit reached)")
 	}
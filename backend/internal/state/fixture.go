@@ -0,0 +1,158 @@
+package state
+
+// fixture.go - Prebuilt repositories for mission setups
+//
+// Replaying dozens of setup commands is slow and gives every start new commit
+// hashes. A mission can instead name a fixture repository: a bundle file, or
+// a bare repository directory holding packed objects. ReadRepositoryFixture
+// parses it once into memory, and Session.InstallRepository copies it into a
+// session, so every start has byte-identical objects and refs.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// ReadRepositoryFixture loads the bundle file or bare repository directory at
+// file into an in-memory storage holding its objects, refs and HEAD.
+func ReadRepositoryFixture(file string) (*memory.Storage, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !IsBundle(data) {
+			return nil, fmt.Errorf("'%s' is neither a bundle nor a repository directory", file)
+		}
+		repo, b, err := OpenBundle(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(b.Prerequisites) > 0 {
+			return nil, fmt.Errorf("bundle '%s' needs %d prerequisite commit(s); fixtures must hold the complete history", file, len(b.Prerequisites))
+		}
+		return repo.Storer.(*memory.Storage), nil
+	}
+
+	repo, err := gogit.PlainOpen(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture repository '%s': %w", file, err)
+	}
+	// Objects read from disk are loaded eagerly, so the fixture stays valid
+	// without the repository files
+	st := memory.NewStorage()
+	if err := copyObjectsAndRefs(repo.Storer, st, true); err != nil {
+		return nil, fmt.Errorf("failed to read fixture repository '%s': %w", file, err)
+	}
+	return st, nil
+}
+
+// InstallRepository creates a repository at dir (absolute, like "/project")
+// holding the objects and refs of fixture, and checks out its HEAD. Objects
+// are shared with fixture rather than copied: they are never modified.
+func (s *Session) InstallRepository(dir string, fixture storage.Storer) (*gogit.Repository, error) {
+	internalPath := path.Clean(dir)[1:]
+	if internalPath == "" {
+		return nil, fmt.Errorf("cannot install a repository at root")
+	}
+	if _, exists := s.Repos[internalPath]; exists {
+		return nil, fmt.Errorf("destination path '%s' already has a repository", dir)
+	}
+	if err := s.Filesystem.MkdirAll(internalPath, 0755); err != nil {
+		return nil, err
+	}
+	fs, err := s.Filesystem.Chroot(internalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	st := memory.NewStorage()
+	repo, err := gogit.Init(st, fs)
+	if err != nil {
+		return nil, err
+	}
+	if err := copyObjectsAndRefs(fixture, st, false); err != nil {
+		return nil, err
+	}
+	_ = fs.MkdirAll(".git", 0755)
+	s.Repos[internalPath] = repo
+
+	head, err := repo.Head()
+	if err != nil {
+		// A fixture without commits is an empty repository on main
+		return repo, st.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: head.Hash(), Mode: gogit.HardReset}); err != nil {
+		return nil, fmt.Errorf("failed to check out fixture HEAD: %w", err)
+	}
+	return repo, nil
+}
+
+// copyObjectsAndRefs copies the objects, refs and HEAD of src into dst,
+// leaving dst's config and index alone. With load, object contents are read
+// into memory instead of sharing src's objects.
+func copyObjectsAndRefs(src, dst storage.Storer, load bool) error {
+	objects, err := src.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return err
+	}
+	if err := objects.ForEach(func(obj plumbing.EncodedObject) error {
+		if load {
+			loaded, err := loadObject(obj)
+			if err != nil {
+				return err
+			}
+			obj = loaded
+		}
+		_, err := dst.SetEncodedObject(obj)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	refs, err := src.IterReferences()
+	if err != nil {
+		return err
+	}
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		return dst.SetReference(ref)
+	}); err != nil {
+		return err
+	}
+	// HEAD is not always part of IterReferences
+	if head, err := src.Reference(plumbing.HEAD); err == nil {
+		return dst.SetReference(head)
+	}
+	return nil
+}
+
+// loadObject reads obj into a plumbing.MemoryObject.
+func loadObject(obj plumbing.EncodedObject) (plumbing.EncodedObject, error) {
+	r, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	loaded := &plumbing.MemoryObject{}
+	loaded.SetType(obj.Type())
+	if _, err := io.Copy(loaded, r); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallRepository_FromBundleFixture(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "notes.txt", []byte("day 1\n"), 0644))
+	_, err = w.Add("notes.txt")
+	require.NoError(t, err)
+	hash, err := w.Commit("Day 1", &gogit.CommitOptions{Author: &object.Signature{Name: "a", Email: "a@example.com"}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteBundle(repo, &buf))
+	file := filepath.Join(t.TempDir(), "notes.bundle")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0644))
+
+	fixture, err := ReadRepositoryFixture(file)
+	require.NoError(t, err)
+
+	sm := NewSessionManager()
+	for _, id := range []string{"one", "two"} {
+		s, err := sm.CreateSession(id)
+		require.NoError(t, err)
+		installed, err := s.InstallRepository("/project/notes", fixture)
+		require.NoError(t, err)
+		assert.Same(t, installed, s.Repos["project/notes"])
+
+		head, err := installed.Head()
+		require.NoError(t, err)
+		assert.Equal(t, hash, head.Hash(), "every install has the same history")
+		content, err := util.ReadFile(s.Filesystem, "/project/notes/notes.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "day 1\n", string(content))
+
+		_, err = s.InstallRepository("/project/notes", fixture)
+		assert.Error(t, err, "a second install over the same path")
+	}
+
+	_, err = ReadRepositoryFixture(filepath.Join(t.TempDir(), "missing.bundle"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+package state
+
+import (
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Connectivity check
+//
+// Fsck walks the object graph from the refs, HEAD, the index and (unless
+// disabled) the reflog, like git fsck. Objects the walk cannot find are
+// reported as missing together with the object linking to them; objects it
+// never reaches are unreachable, and the unreachable objects no other
+// unreachable object points at are dangling (the tips of what a gc would drop).
+
+// FsckObject names an object and its type ("commit", "tree", "blob" or "tag").
+type FsckObject struct {
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+}
+
+// FsckBrokenLink is a link to an object that is not in the repository. From
+// is empty when a ref or the index points at the missing object.
+type FsckBrokenLink struct {
+	From *FsckObject `json:"from,omitempty"`
+	To   FsckObject  `json:"to"`
+}
+
+// FsckReport is the result of a connectivity check of one repository.
+type FsckReport struct {
+	Checked     int              `json:"checked"` // Objects stored by the repository
+	Missing     []FsckBrokenLink `json:"missing"`
+	Unreachable []FsckObject     `json:"unreachable"`
+	Dangling    []FsckObject     `json:"dangling"`
+}
+
+// fsckLink is an edge of the object graph still to be followed.
+type fsckLink struct {
+	from *FsckObject
+	to   plumbing.Hash
+	typ  plumbing.ObjectType
+	kind string // ObjectEdge kind: "tree", "parent", "entry" or "target"
+	name string // Entry name for tree entries
+}
+
+// Fsck checks the connectivity of the repository at path (relative to the
+// session root). With useReflog the reflog entries are roots, as in git fsck;
+// without it (git fsck --no-reflogs) commits only the reflog remembers show
+// up as dangling. The caller must hold the session lock.
+func (s *Session) Fsck(path string, useReflog bool) FsckReport {
+	report := FsckReport{Missing: []FsckBrokenLink{}, Unreachable: []FsckObject{}, Dangling: []FsckObject{}}
+	repo, ok := s.Repos[path]
+	if !ok {
+		return report
+	}
+
+	var queue []fsckLink
+	if useReflog {
+		for _, h := range s.reflogHashes("/" + path) {
+			queue = append(queue, fsckLink{to: h, typ: plumbing.CommitObject})
+		}
+	}
+	if head, err := repo.Head(); err == nil {
+		queue = append(queue, fsckLink{to: head.Hash(), typ: plumbing.AnyObject})
+	}
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			// Like git, pseudo-refs such as ORIG_HEAD are not roots
+			if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), "refs/") {
+				queue = append(queue, fsckLink{to: ref.Hash(), typ: plumbing.AnyObject})
+			}
+			return nil
+		})
+	}
+	if idx, err := repo.Storer.Index(); err == nil {
+		for _, e := range idx.Entries {
+			if e.Mode != filemode.Submodule {
+				queue = append(queue, fsckLink{to: e.Hash, typ: plumbing.BlobObject})
+			}
+		}
+	}
+
+	seen := make(map[plumbing.Hash]struct{})
+	missing := make(map[plumbing.Hash]struct{})
+	shallow := ShallowBoundary(repo)
+	for len(queue) > 0 {
+		link := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[link.to]; ok || link.to.IsZero() {
+			continue
+		}
+		obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, link.to)
+		if err != nil {
+			if link.kind == "parent" && shallow[plumbing.NewHash(link.from.Hash)] {
+				// A shallow clone left the parents out on purpose
+				continue
+			}
+			if _, reported := missing[link.to]; !reported {
+				missing[link.to] = struct{}{}
+				report.Missing = append(report.Missing, FsckBrokenLink{
+					From: link.from,
+					To:   FsckObject{Type: fsckTypeName(link.typ), Hash: link.to.String()},
+				})
+			}
+			continue
+		}
+		seen[link.to] = struct{}{}
+		queue = append(queue, objectLinks(repo, obj)...)
+	}
+
+	unreachable := make(map[plumbing.Hash]plumbing.ObjectType)
+	referenced := make(map[plumbing.Hash]struct{})
+	if iter, err := localObjectStorer(repo).IterEncodedObjects(plumbing.AnyObject); err == nil {
+		_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+			report.Checked++
+			if _, ok := seen[obj.Hash()]; ok {
+				return nil
+			}
+			unreachable[obj.Hash()] = obj.Type()
+			for _, link := range objectLinks(repo, obj) {
+				referenced[link.to] = struct{}{}
+			}
+			return nil
+		})
+	}
+	for h, typ := range unreachable {
+		o := FsckObject{Type: typ.String(), Hash: h.String()}
+		report.Unreachable = append(report.Unreachable, o)
+		if _, ok := referenced[h]; !ok {
+			report.Dangling = append(report.Dangling, o)
+		}
+	}
+
+	sortFsckObjects(report.Unreachable)
+	sortFsckObjects(report.Dangling)
+	sort.SliceStable(report.Missing, func(i, j int) bool { return report.Missing[i].To.Hash < report.Missing[j].To.Hash })
+	return report
+}
+
+// objectLinks lists the objects obj points at.
+func objectLinks(repo *gogit.Repository, obj plumbing.EncodedObject) []fsckLink {
+	from := &FsckObject{Type: obj.Type().String(), Hash: obj.Hash().String()}
+	var links []fsckLink
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		c, err := object.DecodeCommit(repo.Storer, obj)
+		if err != nil {
+			return nil
+		}
+		links = append(links, fsckLink{from: from, to: c.TreeHash, typ: plumbing.TreeObject, kind: "tree"})
+		for _, p := range c.ParentHashes {
+			links = append(links, fsckLink{from: from, to: p, typ: plumbing.CommitObject, kind: "parent"})
+		}
+	case plumbing.TreeObject:
+		t, err := object.DecodeTree(repo.Storer, obj)
+		if err != nil {
+			return nil
+		}
+		for _, e := range t.Entries {
+			switch {
+			case e.Mode == filemode.Submodule:
+			case e.Mode == filemode.Dir:
+				links = append(links, fsckLink{from: from, to: e.Hash, typ: plumbing.TreeObject, kind: "entry", name: e.Name})
+			default:
+				links = append(links, fsckLink{from: from, to: e.Hash, typ: plumbing.BlobObject, kind: "entry", name: e.Name})
+			}
+		}
+	case plumbing.TagObject:
+		t, err := object.DecodeTag(repo.Storer, obj)
+		if err != nil {
+			return nil
+		}
+		links = append(links, fsckLink{from: from, to: t.Target, typ: t.TargetType, kind: "target"})
+	}
+	return links
+}
+
+// fsckTypeName names the expected type of a missing object; refs may point at
+// any type, which git reports as a commit.
+func fsckTypeName(typ plumbing.ObjectType) string {
+	if typ == plumbing.AnyObject {
+		return plumbing.CommitObject.String()
+	}
+	return typ.String()
+}
+
+func sortFsckObjects(objects []FsckObject) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Hash < objects[j].Hash })
+}
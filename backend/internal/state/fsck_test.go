@@ -0,0 +1,40 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsckMissingParent(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "fsck", 2)
+
+	report := sess.Fsck("repo", true)
+	assert.Empty(t, report.Missing)
+	assert.Empty(t, report.Dangling)
+
+	st := repo.Storer.(*memory.Storage)
+	delete(st.Objects, plumbing.NewHash(hashes[0]))
+	delete(st.Commits, plumbing.NewHash(hashes[0]))
+
+	report = sess.Fsck("repo", true)
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, FsckBrokenLink{
+		From: &FsckObject{Type: "commit", Hash: hashes[1]},
+		To:   FsckObject{Type: "commit", Hash: hashes[0]},
+	}, report.Missing[0])
+}
+
+func TestFsckDanglingTips(t *testing.T) {
+	sm := NewSessionManager()
+	sess, _, hashes := linearHistorySession(t, sm, "fsck-dangling", 3)
+	rewindMaster(t, sess, hashes[0])
+
+	report := sess.Fsck("repo", false)
+	assert.Equal(t, []FsckObject{{Type: "commit", Hash: hashes[2]}}, report.Dangling, "only the tip of the lost chain dangles")
+	assert.Len(t, report.Unreachable, 2)
+}
@@ -0,0 +1,313 @@
+package state
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Garbage collection
+//
+// Objects are never removed by the commands themselves: a deleted branch, an
+// amended commit or a re-staged file leaves its objects behind. Like git gc,
+// CollectGarbage drops the objects that nothing can reach any more. Roots are
+// the refs, HEAD and index of the repository, its reflog, the undo and redo
+// snapshots of the session (so undo keeps working), and any merge, rebase or
+// cherry-pick in progress.
+//
+// The background sweeper gives objects a grace period instead of an age: an
+// object is only dropped when it was already unreachable at the previous sweep,
+// so the dangling commits left by a rebase stay visible for a while.
+
+// DefaultGCInterval is how often the background sweeper runs.
+const DefaultGCInterval = 10 * time.Minute
+
+// GCIntervalEnv overrides DefaultGCInterval (Go duration syntax, e.g. "5m");
+// "0" turns the background sweeper off.
+const GCIntervalEnv = "GITGYM_GC_INTERVAL"
+
+// ObjectCount is what git count-objects reports for a repository.
+type ObjectCount struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"` // Bytes of all objects
+}
+
+// GCResult describes one garbage collection of a repository.
+type GCResult struct {
+	Repo   string      `json:"repo"`
+	Before ObjectCount `json:"before"`
+	After  ObjectCount `json:"after"`
+	Pruned int         `json:"pruned"`
+}
+
+// CountObjects counts the objects a repository stores itself. Objects a hybrid
+// repo reads from its shared remote are not counted.
+func CountObjects(repo *gogit.Repository) ObjectCount {
+	var count ObjectCount
+	iter, err := localObjectStorer(repo).IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return count
+	}
+	_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		count.Count++
+		count.Size += obj.Size()
+		return nil
+	})
+	return count
+}
+
+// CollectGarbage removes the unreachable objects of the repository at path
+// (relative to the session root, e.g. "project"). With dryRun it only reports
+// what would be removed. The caller must hold the session lock.
+func (s *Session) CollectGarbage(path string, dryRun bool) (GCResult, error) {
+	// A linked worktree stores nothing itself: collect its repository's objects
+	path = s.MainWorktree(path)
+	result := GCResult{Repo: path}
+	repo, ok := s.Repos[path]
+	if !ok {
+		return result, nil
+	}
+	result.Before = CountObjects(repo)
+	unreachable := s.unreachableObjects(path, repo)
+	if dryRun {
+		result.Pruned = len(unreachable)
+		result.After = result.Before
+		return result, nil
+	}
+
+	pruned, err := deleteObjects(localObjectStorer(repo), unreachable)
+	result.Pruned = pruned
+	result.After = CountObjects(repo)
+	if s.gcCandidates != nil {
+		delete(s.gcCandidates, path)
+	}
+	return result, err
+}
+
+// sweepGarbage removes the objects of every repository that were already
+// unreachable at the previous sweep and remembers the ones unreachable now.
+// The caller must hold the session lock.
+func (s *Session) sweepGarbage() int {
+	previous := s.gcCandidates
+	s.gcCandidates = make(map[string]map[plumbing.Hash]struct{})
+	total := 0
+	for path, repo := range s.Repos {
+		if _, linked := s.Worktrees[path]; linked {
+			continue // Swept with the repository it belongs to
+		}
+		var expired []plumbing.Hash
+		candidates := make(map[plumbing.Hash]struct{})
+		for _, h := range s.unreachableObjects(path, repo) {
+			if _, seen := previous[path][h]; seen {
+				expired = append(expired, h)
+			} else {
+				candidates[h] = struct{}{}
+			}
+		}
+		pruned, err := deleteObjects(localObjectStorer(repo), expired)
+		if err != nil {
+			slog.Warn("gc: repository failed", "session", s.ID, "repo", path, "err", err)
+		}
+		total += pruned
+		if len(candidates) > 0 {
+			s.gcCandidates[path] = candidates
+		}
+	}
+	return total
+}
+
+// SweepGarbage runs the background sweep over every session and returns how
+// many objects were removed.
+func (sm *SessionManager) SweepGarbage() int {
+	sm.mu.RLock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		sessions = append(sessions, s)
+	}
+	sm.mu.RUnlock()
+
+	total := 0
+	for _, s := range sessions {
+		s.mu.Lock()
+		total += s.sweepGarbage()
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// StartGarbageCollection sweeps unreachable objects every interval until stop
+// is closed. A nil stop channel keeps it running for the lifetime of the process.
+func (sm *SessionManager) StartGarbageCollection(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if n := sm.SweepGarbage(); n > 0 {
+					slog.Info("gc: removed unreachable objects", "objects", n)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// unreachableObjects lists the objects stored by the repository at path that
+// no root reaches, sorted by hash.
+func (s *Session) unreachableObjects(path string, repo *gogit.Repository) []plumbing.Hash {
+	roots, blobs := s.gcRoots(path, repo)
+	reachable := reachableObjects(repo, roots)
+	for _, h := range blobs {
+		reachable[h] = struct{}{}
+	}
+
+	var unreachable []plumbing.Hash
+	iter, err := localObjectStorer(repo).IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return nil
+	}
+	_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		if _, ok := reachable[obj.Hash()]; !ok {
+			unreachable = append(unreachable, obj.Hash())
+		}
+		return nil
+	})
+	sort.Slice(unreachable, func(i, j int) bool { return unreachable[i].String() < unreachable[j].String() })
+	return unreachable
+}
+
+// gcRoots returns the commits (or tags) and the blobs that keep objects of the
+// repository at path alive besides its own refs, HEAD and index.
+func (s *Session) gcRoots(path string, repo *gogit.Repository) (commits, blobs []plumbing.Hash) {
+	commits = s.reflogHashes("/" + path)
+	addHash := func(h string) {
+		if hash := plumbing.NewHash(h); h != "" && !hash.IsZero() {
+			commits = append(commits, hash)
+		}
+	}
+
+	if m := s.Merge; m != nil && m.Repo == path {
+		addHash(m.MergeHead)
+		addHash(m.OrigHead)
+	}
+	if rb := s.Rebase; rb != nil && rb.Repo == path {
+		addHash(rb.OrigHead)
+		addHash(rb.Onto)
+		addHash(rb.Autostash)
+		for _, step := range rb.Todo {
+			addHash(step.Commit)
+		}
+	}
+	if am := s.Am; am != nil && am.Repo == path {
+		addHash(am.OrigHead)
+	}
+	if cp := s.CherryPick; cp != nil && cp.Repo == path {
+		addHash(cp.OrigHead)
+		addHash(cp.Current)
+		for _, h := range cp.Todo {
+			addHash(h)
+		}
+	}
+
+	// Linked worktrees share the objects, so their HEAD, index and reflog keep them alive too
+	for _, wt := range s.ListWorktrees(path) {
+		if wt.Path == path {
+			continue
+		}
+		other := s.Repos[wt.Path]
+		if head, err := other.Head(); err == nil {
+			commits = append(commits, head.Hash())
+		}
+		if idx, err := other.Storer.Index(); err == nil {
+			for _, e := range idx.Entries {
+				blobs = append(blobs, e.Hash)
+			}
+		}
+		commits = append(commits, s.reflogHashes("/"+wt.Path)...)
+	}
+
+	// Undo and redo snapshots and save points restore refs, index and files from the objects
+	snaps := append(append([]*UndoSnapshot(nil), s.undoStack...), s.redoStack...)
+	for _, sp := range s.savePoints {
+		snaps = append(snaps, sp.snap)
+	}
+	for _, snap := range snaps {
+		rs, ok := snap.repos[path]
+		if !ok || rs.repo != repo {
+			continue
+		}
+		for _, ref := range rs.refs {
+			if ref.Type() == plumbing.HashReference {
+				commits = append(commits, ref.Hash())
+			}
+		}
+		if len(rs.index) > 0 {
+			var idx index.Index
+			if err := index.NewDecoder(bytes.NewReader(rs.index)).Decode(&idx); err == nil {
+				for _, e := range idx.Entries {
+					blobs = append(blobs, e.Hash)
+				}
+			}
+		}
+		for _, f := range snap.files {
+			if f.repo == path {
+				blobs = append(blobs, f.hash)
+			}
+		}
+	}
+	return commits, blobs
+}
+
+// localObjectStorer returns the storer holding the repository's own objects.
+func localObjectStorer(repo *gogit.Repository) storage.Storer {
+	st := repo.Storer
+	if linked, ok := st.(*WorktreeStorer); ok {
+		st = linked.Storer
+	}
+	if hybrid, ok := st.(localStorerProvider); ok {
+		return hybrid.LocalStorer()
+	}
+	return st
+}
+
+// deleteObjects removes objects from a memory or filesystem storer and returns
+// how many were removed.
+func deleteObjects(st storage.Storer, hashes []plumbing.Hash) (int, error) {
+	removed := 0
+	switch st := st.(type) {
+	case *memory.Storage:
+		for _, h := range hashes {
+			if _, ok := st.Objects[h]; !ok {
+				continue
+			}
+			delete(st.Objects, h)
+			delete(st.Commits, h)
+			delete(st.Trees, h)
+			delete(st.Blobs, h)
+			delete(st.Tags, h)
+			removed++
+		}
+	case storer.LooseObjectStorer:
+		for _, h := range hashes {
+			if err := st.DeleteLooseObject(h); err != nil {
+				if os.IsNotExist(err) {
+					continue // Packed: left for a repack
+				}
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
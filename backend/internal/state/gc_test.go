@@ -0,0 +1,73 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewindMaster points master of repo back at hash, leaving later commits unreachable.
+func rewindMaster(t *testing.T, sess *Session, hash string) {
+	t.Helper()
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), plumbing.NewHash(hash))
+	require.NoError(t, sess.Repos["repo"].Storer.SetReference(ref))
+}
+
+func TestCollectGarbage(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "gc", 3)
+	rewindMaster(t, sess, hashes[0])
+
+	before := CountObjects(repo)
+	preview, err := sess.CollectGarbage("repo", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, preview.Pruned)
+	assert.Equal(t, before, preview.After, "dry run keeps every object")
+
+	result, err := sess.CollectGarbage("repo", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Pruned)
+	assert.Equal(t, before.Count-2, result.After.Count)
+	_, err = repo.CommitObject(plumbing.NewHash(hashes[2]))
+	assert.Error(t, err, "unreachable commit is gone")
+	_, err = repo.CommitObject(plumbing.NewHash(hashes[0]))
+	assert.NoError(t, err)
+}
+
+func TestCollectGarbageKeepsRoots(t *testing.T) {
+	sm := NewSessionManager()
+	sess, _, hashes := linearHistorySession(t, sm, "gc-roots", 3)
+
+	// Undo snapshots keep the rewound commits alive
+	snap, err := sess.TakeUndoSnapshot("git reset --hard HEAD~2")
+	require.NoError(t, err)
+	rewindMaster(t, sess, hashes[0])
+	require.True(t, sess.RecordUndoSnapshot(snap))
+
+	result, err := sess.CollectGarbage("repo", false)
+	require.NoError(t, err)
+	assert.Zero(t, result.Pruned)
+
+	// So does the reflog
+	sess.ClearUndoHistory()
+	sess.Reflog = append(sess.Reflog, ReflogEntry{Hash: hashes[1], Message: "commit: commit", Context: "/repo"})
+	result, err = sess.CollectGarbage("repo", false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pruned, "only the commit past the reflog entry is dropped")
+}
+
+func TestSweepGarbageGracePeriod(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "gc-sweep", 3)
+	rewindMaster(t, sess, hashes[0])
+
+	assert.Zero(t, sm.SweepGarbage(), "first sweep only remembers unreachable objects")
+	_, err := repo.CommitObject(plumbing.NewHash(hashes[2]))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, sm.SweepGarbage())
+	_, err = repo.CommitObject(plumbing.NewHash(hashes[2]))
+	assert.Error(t, err)
+}
@@ -2,7 +2,7 @@ package state
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"sort"
 	"strings"
 
@@ -11,7 +11,13 @@ import (
 )
 
 // GetGraphState returns the current state of the repository for frontend visualization
-func (sm *SessionManager) GetGraphState(sessionID string, showAll bool) (*GraphState, error) {
+func (sm *SessionManager) GetGraphState(sessionID string) (*GraphState, error) {
+	return sm.GetGraphStatePage(sessionID, CommitFilter{})
+}
+
+// GetGraphStatePage is GetGraphState with the commit list cut by f.
+// CommitPagination is set whenever f is not zero.
+func (sm *SessionManager) GetGraphStatePage(sessionID string, f CommitFilter) (*GraphState, error) {
 	session, ok := sm.GetSession(sessionID)
 	if !ok {
 		return nil, fmt.Errorf("session not found")
@@ -26,11 +32,12 @@ func (sm *SessionManager) GetGraphState(sessionID string, showAll bool) (*GraphS
 	// But we need to merge it with Session-specific data (Projects, proper Path)
 
 	// Create base structure from Session data
-	state := BuildGraphState(repo, showAll)
+	state := buildGraphState(repo, f.MaxDepth)
 
 	// Override/Augment with Session Data
 	state.PotentialCommits = session.PotentialCommits
 	state.CurrentPath = session.CurrentDir
+	applyLineage(session, state)
 
 	sm.mu.RLock()
 	for name := range sm.SharedRemotes {
@@ -48,22 +55,42 @@ func (sm *SessionManager) GetGraphState(sessionID string, showAll bool) (*GraphS
 	// 7. Projects - Session specific
 	populateProjects(session, state)
 
+	// 8. Commit page - after lineage, which needs to see every commit
+	if !f.IsZero() {
+		truncated := state.CommitPagination != nil && state.CommitPagination.Truncated
+		commits, page, err := PaginateCommits(state.Commits, f)
+		if err != nil {
+			return nil, err
+		}
+		page.Truncated = truncated
+		state.Commits, state.CommitPagination = commits, page
+	}
+
 	return state, nil
 }
 
 // BuildGraphState constructs a GraphState from a git.Repository.
 // It can be used for both local session repos and shared remotes.
-func BuildGraphState(repo *gogit.Repository, showAll bool) *GraphState {
+func BuildGraphState(repo *gogit.Repository) *GraphState {
+	return buildGraphState(repo, 0)
+}
+
+// buildGraphState builds the graph with commits at most maxDepth parent hops
+// from a ref (0 means unlimited). CommitPagination records whether history was cut.
+func buildGraphState(repo *gogit.Repository, maxDepth int) *GraphState {
 	state := &GraphState{
-		Commits:        []Commit{},
-		Branches:       make(map[string]string),
-		RemoteBranches: make(map[string]string),
-		Tags:           make(map[string]string),
-		References:     make(map[string]string),
-		FileStatuses:   make(map[string]string),
-		Remotes:        []Remote{},
-		SharedRemotes:  []string{},
-		Initialized:    (repo != nil),
+		Commits:            []Commit{},
+		Branches:           make(map[string]string),
+		RemoteBranches:     make(map[string]string),
+		Tracking:           make(map[string]BranchTracking),
+		BranchGroups:       []BranchGroup{},
+		RemoteBranchGroups: []BranchGroup{},
+		Tags:               make(map[string]string),
+		References:         make(map[string]string),
+		FileStatuses:       make(map[string]string),
+		Remotes:            []Remote{},
+		SharedRemotes:      []string{},
+		Initialized:        (repo != nil),
 	}
 
 	// 1. Get HEAD
@@ -72,20 +99,22 @@ func BuildGraphState(repo *gogit.Repository, showAll bool) *GraphState {
 	if repo != nil {
 		// 2. Get Branches & Tags
 		if err := populateBranchesAndTags(repo, state); err != nil {
-			log.Printf("BuildGraphState warning: %v", err)
+			slog.Warn("build graph state", "err", err)
 		}
+		PopulateBranchGroups(state)
+		populateTracking(repo, state)
 
 		// 3. Walk Commits
-		// Use BFS from Refs (if showAll=false) or iterate all objects (if showAll=true)
-		populateCommits(repo, state, showAll)
-		// Let's assume for Shared Remote we want to show everything we have.
-		// Actually, populateCommits logic for ancestors might be better.
-		// But for "Server View", showing the reachable history from branches is correct.
+		// BFS from Refs, plus a capped set of dangling commits flagged as such
+		if populateCommits(repo, state, maxDepth) {
+			state.CommitPagination = &CommitPagination{Total: len(state.Commits), Truncated: true}
+		}
+		assignLanes(state)
 
 		// 4. Git Status (Might be empty for bare repos, but harmless)
 		if err := populateGitStatus(repo, state); err != nil {
 			// Bare repos often fail Worktree(), ignore
-			log.Printf("populateGitStatus ignored error: %v", err)
+			slog.Debug("populate git status: ignored error", "err", err)
 		}
 
 		// 5. Remotes
@@ -146,7 +175,6 @@ func populateBranchesAndTags(repo *gogit.Repository, state *GraphState) error {
 		_ = refs.ForEach(func(r *plumbing.Reference) error {
 			if r.Name().IsRemote() {
 				state.RemoteBranches[r.Name().Short()] = r.Hash().String()
-				// log.Printf("Graph: Found Remote Branch %s -> %s", r.Name().Short(), r.Hash().String())
 			} else if r.Name().IsTag() {
 				hash := r.Hash().String()
 				// Check if it's an annotated tag
@@ -160,10 +188,9 @@ func populateBranchesAndTags(repo *gogit.Repository, state *GraphState) error {
 		})
 	}
 
-	// Get Special Refs (ORIG_HEAD)
-	origHeadRef, err := repo.Reference("ORIG_HEAD", true)
-	if err == nil {
-		state.References["ORIG_HEAD"] = origHeadRef.Hash().String()
+	// Pseudo-refs: ORIG_HEAD, MERGE_HEAD, CHERRY_PICK_HEAD, REBASE_HEAD, FETCH_HEAD
+	for name, hash := range ReadSpecialRefs(repo) {
+		state.References[name] = hash
 	}
 
 	return nil
@@ -227,7 +254,7 @@ func populateGitStatus(repo *gogit.Repository, state *GraphState) error {
 		return err
 	}
 
-	status, err := w.Status()
+	status, err := LFSAwareStatus(repo, w)
 	if err != nil {
 		return err
 	}
@@ -246,6 +273,7 @@ func populateGitStatus(repo *gogit.Repository, state *GraphState) error {
 		y := statusCodeToChar(s.Worktree)
 		state.FileStatuses[file] = string(x) + string(y)
 	}
+	state.Ignored = LoadIgnoreRules(w.Filesystem).IgnoredPaths(w.Filesystem, TrackedPaths(repo))
 	return nil
 }
 
@@ -275,7 +303,7 @@ func populateProjects(session *Session, state *GraphState) {
 	}
 
 	sort.Strings(state.Projects)
-	log.Printf("Population Projects: found %d projects: %v", len(state.Projects), state.Projects)
+	slog.Debug("graph state: found projects", "projects", len(state.Projects))
 }
 
 func statusCodeToChar(c gogit.StatusCode) rune {
@@ -0,0 +1,193 @@
+package state
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// Graph layout hints
+//
+// The graph view draws every commit in a column (lane) and colors it by the
+// first-parent chain it belongs to. Computing this server-side means the
+// frontend does not re-layout on every poll, and paged commits keep the lanes
+// they have in the full graph.
+//
+// Lanes are assigned in one sweep over the commits, newest first: a commit
+// takes the lane a child reserved for it, or the first free lane if it is a tip.
+// Its first parent is reserved in the same lane when possible, so first-parent
+// chains run straight down; merged-in parents get a lane of their own. Lane 0
+// is kept for the trunk (main, master or trunk, or their remote counterpart).
+//
+// Color groups follow the name of the ref a chain starts at rather than the
+// lane, so a branch keeps its color when other branches come and go.
+
+// layoutColorGroups is the number of color groups; it matches the size of the
+// frontend lane palette. Group 0 is the trunk's.
+const layoutColorGroups = 8
+
+// trunkCandidates are the branch names treated as the trunk, in order of preference.
+var trunkCandidates = []string{"main", "master", "trunk"}
+var remoteTrunkCandidates = []string{"origin/main", "origin/master", "origin/trunk", "upstream/main", "upstream/master"}
+
+// assignLanes sets Lane and ColorGroup of every commit. state.Commits must be
+// in graph order (newest first) and the refs of state populated.
+func assignLanes(state *GraphState) {
+	if len(state.Commits) == 0 {
+		return
+	}
+	index := make(map[string]int, len(state.Commits))
+	for i, c := range state.Commits {
+		index[c.ID] = i
+	}
+
+	// Trunk: the first-parent chain of the trunk branch
+	trunkName, trunkTip := findTrunk(state)
+	trunk := make(map[string]bool)
+	for id := trunkTip; id != ""; {
+		i, ok := index[id]
+		if !ok || trunk[id] {
+			break
+		}
+		trunk[id] = true
+		id = state.Commits[i].ParentID
+	}
+	hasTrunk := len(trunk) > 0
+
+	// lanes[i] is the commit expected next in lane i, "" when the lane is free
+	var lanes []string
+	freeLane := func() int {
+		start := 0
+		if hasTrunk {
+			start = 1
+		}
+		for i := start; i < len(lanes); i++ {
+			if lanes[i] == "" {
+				return i
+			}
+		}
+		if len(lanes) < start {
+			return start
+		}
+		return len(lanes)
+	}
+	reserve := func(lane int, id string) {
+		for len(lanes) <= lane {
+			lanes = append(lanes, "")
+		}
+		lanes[lane] = id
+	}
+	laneOf := func(id string) int {
+		for i, expected := range lanes {
+			if expected == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	names := chainNames(state, trunkName, trunkTip)
+	chain := make(map[string]string, len(state.Commits))
+
+	for i := range state.Commits {
+		c := &state.Commits[i]
+
+		lane := laneOf(c.ID)
+		if lane == -1 {
+			if trunk[c.ID] {
+				lane = 0
+			} else {
+				lane = freeLane()
+			}
+		}
+		reserve(lane, "")
+		c.Lane = lane
+
+		if _, ok := chain[c.ID]; !ok {
+			if name, ok := names[c.ID]; ok {
+				chain[c.ID] = name
+			} else {
+				chain[c.ID] = c.ID
+			}
+		}
+		if trunk[c.ID] {
+			c.ColorGroup = 0
+		} else {
+			c.ColorGroup = colorGroup(chain[c.ID])
+		}
+
+		for p, pid := range []string{c.ParentID, c.SecondParentID} {
+			if pid == "" || laneOf(pid) != -1 {
+				continue
+			}
+			if _, ok := index[pid]; !ok {
+				continue // Outside the graph (cut off by depth)
+			}
+			if _, ok := chain[pid]; !ok && p == 0 {
+				chain[pid] = chain[c.ID] // First parent continues the chain
+			}
+
+			var target int
+			switch {
+			case trunk[pid]:
+				target = 0
+			case p == 0 && lane != 0 && lanes[lane] == "":
+				target = lane
+			default:
+				target = freeLane()
+			}
+			if target < len(lanes) && lanes[target] != "" && lanes[target] != pid {
+				target = freeLane()
+			}
+			reserve(target, pid)
+		}
+	}
+}
+
+// findTrunk returns the name and tip of the trunk branch, if any.
+func findTrunk(state *GraphState) (string, string) {
+	for _, name := range trunkCandidates {
+		if tip, ok := state.Branches[name]; ok {
+			return name, tip
+		}
+	}
+	for _, name := range remoteTrunkCandidates {
+		if tip, ok := state.RemoteBranches[name]; ok {
+			return name, tip
+		}
+	}
+	return "", ""
+}
+
+// chainNames maps commits that refs point at to the name a chain starting
+// there is colored by: the trunk, the checked-out branch, then local
+// branches, remote-tracking branches and tags in name order.
+func chainNames(state *GraphState, trunkName, trunkTip string) map[string]string {
+	names := make(map[string]string)
+	claim := func(name, id string) {
+		if _, taken := names[id]; !taken && id != "" {
+			names[id] = name
+		}
+	}
+	claim(trunkName, trunkTip)
+	if state.HEAD.Type == "branch" && state.HEAD.Ref != "" {
+		claim(state.HEAD.Ref, state.Branches[state.HEAD.Ref])
+	}
+	for _, refs := range []map[string]string{state.Branches, state.RemoteBranches, state.Tags} {
+		sorted := make([]string, 0, len(refs))
+		for name := range refs {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			claim(name, refs[name])
+		}
+	}
+	return names
+}
+
+// colorGroup picks a stable non-trunk color group for a chain name.
+func colorGroup(name string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return 1 + int(h.Sum32()%(layoutColorGroups-1))
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGraphState_LayoutHints(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := 0
+	commit := func(msg string, parents ...plumbing.Hash) plumbing.Hash {
+		sig := &object.Signature{Name: "User", Email: "user@example.com", When: start.Add(time.Duration(minute) * time.Minute)}
+		minute++
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true, Parents: parents})
+		require.NoError(t, err)
+		return hash
+	}
+	setRef := func(name string, hash plumbing.Hash) {
+		require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)))
+	}
+
+	// A <- B <- C <- M (master, merging E)
+	//       \- D <- E (feature)
+	a := commit("A")
+	b := commit("B", a)
+	d := commit("D", b)
+	c := commit("C", b)
+	e := commit("E", d)
+	m := commit("M", c, e)
+	setRef("refs/heads/master", m)
+	setRef("refs/heads/feature", e)
+
+	layout := func() map[plumbing.Hash]Commit {
+		byID := make(map[plumbing.Hash]Commit)
+		for _, c := range BuildGraphState(repo).Commits {
+			byID[plumbing.NewHash(c.ID)] = c
+		}
+		return byID
+	}
+
+	got := layout()
+	for _, h := range []plumbing.Hash{a, b, c, m} {
+		assert.Equal(t, 0, got[h].Lane, "trunk commits stay in lane 0")
+		assert.Equal(t, 0, got[h].ColorGroup)
+	}
+	feature := colorGroup("feature")
+	for _, h := range []plumbing.Hash{d, e} {
+		assert.Equal(t, 1, got[h].Lane, "the merged branch runs in its own lane")
+		assert.Equal(t, feature, got[h].ColorGroup)
+	}
+
+	// A new branch elsewhere does not change the colors of existing ones
+	x := commit("X", a)
+	setRef("refs/heads/other", x)
+	setRef("refs/heads/master", m) // Committing moved master along
+	got = layout()
+	assert.Equal(t, colorGroup("other"), got[x].ColorGroup)
+	assert.NotEqual(t, 0, got[x].Lane)
+	assert.Equal(t, feature, got[e].ColorGroup)
+	assert.Equal(t, 0, got[m].Lane)
+}
@@ -7,6 +7,7 @@ import (
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/storage"
 )
 
@@ -17,94 +18,131 @@ type localStorerProvider interface {
 	LocalStorer() storage.Storer
 }
 
-func populateCommits(repo *gogit.Repository, state *GraphState, showAll bool) {
+// danglingCommitCap limits how many unreachable commits are included in the graph.
+// Dangling commits pile up quickly after amends and rebases; beyond this many they
+// stop being instructive and just slow down rendering.
+const danglingCommitCap = 200
+
+// populateCommits collects every commit reachable from HEAD, branches, remote-tracking
+// branches and tags. For non-hybrid repos it additionally includes unreachable
+// (dangling) commits, up to danglingCommitCap, flagged so the frontend can
+// render them differently instead of requiring a separate "show all" mode.
+// With maxDepth > 0 only commits at most maxDepth parent hops from a ref are
+// collected; the result reports whether older history was left out.
+func populateCommits(repo *gogit.Repository, state *GraphState, maxDepth int) (truncated bool) {
 	var collectedCommits []*object.Commit
 
 	// Check if this repo uses HybridStorer (which shares objects with remote).
 	// If so, we CANNOT use CommitObjects() as it would include remote-only commits.
 	_, isHybrid := repo.Storer.(localStorerProvider)
 
-	if showAll && !isHybrid {
-		// Scan ALL objects - only safe for non-hybrid repos (e.g., shared bare repo)
-		cIter, err := repo.CommitObjects()
-		if err == nil {
-			_ = cIter.ForEach(func(c *object.Commit) error {
-				collectedCommits = append(collectedCommits, c)
-				return nil
-			})
-		}
-	} else {
-		// Standard Graph Traversal (Reachable from Branches/Tags/HEAD only)
-		seen := make(map[string]bool)
-		var queue []plumbing.Hash
+	// Standard Graph Traversal (Reachable from Branches/Tags/HEAD only)
+	seen := make(map[string]bool)
+	var queue []plumbing.Hash
 
-		// 1. Seed with ALL Refs (HEAD, Branches, Tags, Remotes)
-		// This ensures we show "Active" branches even if they are not merged into HEAD.
+	// 1. Seed with ALL Refs (HEAD, Branches, Tags, Remotes)
+	// This ensures we show "Active" branches even if they are not merged into HEAD.
 
-		// HEAD
-		h, err := repo.Head()
-		if err == nil {
-			queue = append(queue, h.Hash())
-		}
+	// HEAD
+	h, err := repo.Head()
+	if err == nil {
+		queue = append(queue, h.Hash())
+	}
 
-		// Local Branches
-		bIter, err := repo.Branches()
-		if err == nil {
-			_ = bIter.ForEach(func(r *plumbing.Reference) error {
-				queue = append(queue, r.Hash())
-				return nil
-			})
-		}
+	// Local Branches
+	bIter, err := repo.Branches()
+	if err == nil {
+		_ = bIter.ForEach(func(r *plumbing.Reference) error {
+			queue = append(queue, r.Hash())
+			return nil
+		})
+	}
 
-		// Remote Branches
-		// Note: repo.References() includes everything, but we can filter or just add them.
-		// Adding all refs is safer for visibility.
-		refs, err := repo.References()
-		if err == nil {
-			_ = refs.ForEach(func(r *plumbing.Reference) error {
-				// We want remotes and tags specifically if not covered above
-				name := r.Name().String()
+	// Remote Branches
+	// Note: repo.References() includes everything, but we can filter or just add them.
+	// Adding all refs is safer for visibility.
+	refs, err := repo.References()
+	if err == nil {
+		_ = refs.ForEach(func(r *plumbing.Reference) error {
+			// We want remotes and tags specifically if not covered above
+			// Limit noise: Exclude ORIG_HEAD, FETCH_HEAD and the other pseudo-refs
+			if IsSpecialRef(r.Name()) {
+				return nil
+			}
 
-				// Limit noise: Exclude ORIG_HEAD, FETCH_HEAD
-				if name == "ORIG_HEAD" || name == "FETCH_HEAD" {
-					return nil
+			if r.Name().IsRemote() {
+				queue = append(queue, r.Hash())
+			} else if r.Name().IsTag() {
+				// Resolve annotated tag for seeding
+				hash := r.Hash()
+				tagObj, err := repo.TagObject(hash)
+				if err == nil {
+					hash = tagObj.Target
 				}
+				queue = append(queue, hash)
+			}
+			return nil
+		})
+	}
 
-				if r.Name().IsRemote() {
-					queue = append(queue, r.Hash())
-				} else if r.Name().IsTag() {
-					// Resolve annotated tag for seeding
-					hash := r.Hash()
-					tagObj, err := repo.TagObject(hash)
-					if err == nil {
-						hash = tagObj.Target
-					}
-					queue = append(queue, hash)
-				}
-				return nil
-			})
+	// BFS, breadth-first so that each commit is first reached at its smallest depth
+	depth := make(map[plumbing.Hash]int, len(queue))
+	var boundary []*object.Commit // Commits whose parents were not followed
+	for len(queue) > 0 {
+		if len(collectedCommits) >= 20000 {
+			break
 		}
+		current := queue[0]
+		queue = queue[1:]
 
-		// BFS
-		for len(queue) > 0 {
-			if len(collectedCommits) >= 20000 {
-				break
-			}
-			current := queue[0]
-			queue = queue[1:]
+		if seen[current.String()] {
+			continue
+		}
+		seen[current.String()] = true
 
-			if seen[current.String()] {
-				continue
-			}
-			seen[current.String()] = true
+		c, err := repo.CommitObject(current)
+		if err != nil {
+			continue
+		}
 
-			c, err := repo.CommitObject(current)
-			if err != nil {
-				continue
+		collectedCommits = append(collectedCommits, c)
+		d := depth[current]
+		if maxDepth > 0 && d >= maxDepth {
+			boundary = append(boundary, c)
+			continue
+		}
+		for _, p := range c.ParentHashes {
+			if _, ok := depth[p]; !ok {
+				depth[p] = d + 1
 			}
+		}
+		queue = append(queue, c.ParentHashes...)
+	}
+	for _, c := range boundary {
+		for _, p := range c.ParentHashes {
+			truncated = truncated || !seen[p.String()]
+		}
+	}
 
-			collectedCommits = append(collectedCommits, c)
-			queue = append(queue, c.ParentHashes...)
+	// 2. Dangling commits - only safe for non-hybrid repos, since a hybrid
+	// storer would also yield commits that exist only on the remote.
+	// Skipped when the walk stopped at maxDepth: the history beyond it would
+	// be mistaken for unreachable commits.
+	dangling := make(map[string]bool)
+	if !isHybrid && !truncated {
+		cIter, err := repo.CommitObjects()
+		if err == nil {
+			_ = cIter.ForEach(func(c *object.Commit) error {
+				if len(dangling) >= danglingCommitCap {
+					return storer.ErrStop
+				}
+				if seen[c.Hash.String()] {
+					return nil
+				}
+				dangling[c.Hash.String()] = true
+				collectedCommits = append(collectedCommits, c)
+				return nil
+			})
 		}
 	}
 
@@ -171,6 +209,7 @@ func populateCommits(repo *gogit.Repository, state *GraphState, showAll bool) {
 	})
 
 	// Convert to View Model
+	shallow := ShallowBoundary(repo)
 	for _, c := range collectedCommits {
 		parentID := ""
 		if len(c.ParentHashes) > 0 {
@@ -187,6 +226,9 @@ func populateCommits(repo *gogit.Repository, state *GraphState, showAll bool) {
 			SecondParentID: secondParentID,
 			Timestamp:      c.Committer.When.Format(time.RFC3339),
 			TreeID:         c.TreeHash.String(),
+			Dangling:       dangling[c.Hash.String()],
+			Shallow:        shallow[c.Hash],
 		})
 	}
+	return truncated
 }
@@ -46,7 +46,6 @@ func TestPopulateCommits_HybridStorer_SkipsObjectIteration(t *testing.T) {
 	localRepo, err := gogit.Init(hybridSt, memfs.New())
 	require.NoError(t, err)
 
-	// Build graph state with showAll=true
 	state := &GraphState{
 		Branches:       make(map[string]string),
 		RemoteBranches: make(map[string]string),
@@ -56,12 +55,12 @@ func TestPopulateCommits_HybridStorer_SkipsObjectIteration(t *testing.T) {
 
 	// This should NOT panic and should use BFS instead of object iteration
 	// Since local has no refs or commits, we expect no commits
-	populateCommits(localRepo, state, true)
+	populateCommits(localRepo, state, 0)
 
-	assert.Empty(t, state.Commits, "HybridStorer with showAll=true should not iterate shared objects")
+	assert.Empty(t, state.Commits, "HybridStorer should not iterate shared objects")
 }
 
-func TestPopulateCommits_NonHybrid_UsesObjectIteration(t *testing.T) {
+func TestPopulateCommits_NonHybrid_FlagsDanglingCommits(t *testing.T) {
 	// Create a normal (non-hybrid) repo
 	tmpDir, err := os.MkdirTemp("", "test-graph-*")
 	require.NoError(t, err)
@@ -88,12 +87,22 @@ func TestPopulateCommits_NonHybrid_UsesObjectIteration(t *testing.T) {
 	_, err = wt.Add("test.txt")
 	require.NoError(t, err)
 
-	_, err = wt.Commit("initial commit", &gogit.CommitOptions{
+	first, err := wt.Commit("initial commit", &gogit.CommitOptions{
 		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
 	})
 	require.NoError(t, err)
 
-	// Build graph state with showAll=true
+	// Create a second commit, then reset it away so it becomes unreachable
+	err = os.WriteFile(testFile, []byte("bye"), 0644)
+	require.NoError(t, err)
+	_, err = wt.Add("test.txt")
+	require.NoError(t, err)
+	second, err := wt.Commit("second commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, wt.Reset(&gogit.ResetOptions{Commit: first, Mode: gogit.HardReset}))
+
 	state := &GraphState{
 		Branches:       make(map[string]string),
 		RemoteBranches: make(map[string]string),
@@ -101,10 +110,18 @@ func TestPopulateCommits_NonHybrid_UsesObjectIteration(t *testing.T) {
 		References:     make(map[string]string),
 	}
 
-	populateCommits(repo, state, true)
-
-	// Non-hybrid repo should show the commit via object iteration
-	assert.Len(t, state.Commits, 1, "Non-hybrid repo with showAll=true should iterate all objects")
+	populateCommits(repo, state, 0)
+
+	// Non-hybrid repo should include the unreachable commit, flagged as dangling
+	require.Len(t, state.Commits, 2, "Non-hybrid repo should include dangling commits")
+	for _, c := range state.Commits {
+		switch c.ID {
+		case first.String():
+			assert.False(t, c.Dangling, "reachable commit must not be flagged")
+		case second.String():
+			assert.True(t, c.Dangling, "reset-away commit must be flagged dangling")
+		}
+	}
 }
 
 func TestPopulateCommits_BFSFromRefs(t *testing.T) {
@@ -147,7 +164,6 @@ func TestPopulateCommits_BFSFromRefs(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	// Build graph state with showAll=false (BFS mode)
 	state := &GraphState{
 		Branches:       make(map[string]string),
 		RemoteBranches: make(map[string]string),
@@ -155,7 +171,7 @@ func TestPopulateCommits_BFSFromRefs(t *testing.T) {
 		References:     make(map[string]string),
 	}
 
-	populateCommits(repo, state, false)
+	populateCommits(repo, state, 0)
 
 	// Should find both commits via BFS from HEAD
 	assert.Len(t, state.Commits, 2, "BFS should find all reachable commits")
@@ -197,7 +213,6 @@ func TestPopulateCommits_HybridStorer_BFSStillWorks(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	// Build graph state with showAll=true (but should still use BFS for hybrid)
 	state := &GraphState{
 		Branches:       make(map[string]string),
 		RemoteBranches: make(map[string]string),
@@ -205,8 +220,8 @@ func TestPopulateCommits_HybridStorer_BFSStillWorks(t *testing.T) {
 		References:     make(map[string]string),
 	}
 
-	populateCommits(repo, state, true)
+	populateCommits(repo, state, 0)
 
-	// Even with showAll=true, HybridStorer should use BFS and find the local commit
-	assert.Len(t, state.Commits, 1, "HybridStorer with showAll=true should still find local commits via BFS")
+	// HybridStorer should use BFS and find the local commit
+	assert.Len(t, state.Commits, 1, "HybridStorer should still find local commits via BFS")
 }
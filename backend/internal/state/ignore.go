@@ -0,0 +1,201 @@
+package state
+
+import (
+	"bufio"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreRule is one pattern line of a .gitignore or .git/info/exclude file.
+type IgnoreRule struct {
+	Source  string // File the pattern comes from, e.g. ".gitignore" or "src/.gitignore"
+	Line    int    // 1-based line number in Source
+	Pattern string // As written, including a leading "!" for negations
+	pattern gitignore.Pattern
+}
+
+// Negated reports whether the rule re-includes paths ("!keep.log").
+func (r *IgnoreRule) Negated() bool {
+	return strings.HasPrefix(r.Pattern, "!")
+}
+
+// IgnoreRules are the ignore patterns of a worktree in precedence order: a
+// later rule wins over an earlier one, and nested .gitignore files come after
+// the ones above them. It reads the same files as go-git's status, but keeps
+// where each pattern came from for git check-ignore -v.
+type IgnoreRules struct {
+	rules []*IgnoreRule
+}
+
+// LoadIgnoreRules reads .git/info/exclude and every .gitignore of the worktree.
+// Directories that are already ignored are not searched, as in git.
+func LoadIgnoreRules(fs billy.Filesystem) *IgnoreRules {
+	r := &IgnoreRules{}
+	r.readFile(fs, ".git/info/exclude", nil)
+	r.readDir(fs, nil)
+	return r
+}
+
+func (r *IgnoreRules) readDir(fs billy.Filesystem, dir []string) {
+	r.readFile(fs, path.Join(append(append([]string(nil), dir...), ".gitignore")...), dir)
+
+	entries, err := fs.ReadDir(path.Join(append([]string{"."}, dir...)...))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == ".git" {
+			continue
+		}
+		sub := append(append([]string(nil), dir...), e.Name())
+		if rule := r.match(sub, true); rule != nil && !rule.Negated() {
+			continue
+		}
+		r.readDir(fs, sub)
+	}
+}
+
+func (r *IgnoreRules) readFile(fs billy.Filesystem, name string, domain []string) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, "#") || strings.TrimSpace(text) == "" {
+			continue
+		}
+		r.rules = append(r.rules, &IgnoreRule{
+			Source:  name,
+			Line:    line,
+			Pattern: text,
+			pattern: gitignore.ParsePattern(text, domain),
+		})
+	}
+}
+
+// Empty reports whether there are no rules at all.
+func (r *IgnoreRules) Empty() bool {
+	return len(r.rules) == 0
+}
+
+// Match returns the rule deciding whether name is ignored, or nil when no rule
+// matches. The rule may be a negation, in which case name is not ignored.
+// A path inside an ignored directory is ignored by the directory's rule, since
+// git does not look inside excluded directories.
+func (r *IgnoreRules) Match(name string, isDir bool) *IgnoreRule {
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if rule := r.match(parts[:i], true); rule != nil && !rule.Negated() {
+			return rule
+		}
+	}
+	return r.match(parts, isDir)
+}
+
+func (r *IgnoreRules) match(parts []string, isDir bool) *IgnoreRule {
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		if r.rules[i].pattern.Match(parts, isDir) != gitignore.NoMatch {
+			return r.rules[i]
+		}
+	}
+	return nil
+}
+
+// IsIgnored reports whether name is excluded by the rules.
+func (r *IgnoreRules) IsIgnored(name string, isDir bool) bool {
+	rule := r.Match(name, isDir)
+	return rule != nil && !rule.Negated()
+}
+
+// IgnoredPaths lists the untracked paths of the worktree that the rules
+// exclude, sorted. An ignored directory without tracked files is listed once
+// with a trailing slash ("build/"), like git status --ignored.
+func (r *IgnoreRules) IgnoredPaths(fs billy.Filesystem, tracked map[string]bool) []string {
+	if r.Empty() {
+		return nil
+	}
+	hasTracked := func(dir string) bool {
+		for name := range tracked {
+			if strings.HasPrefix(name, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []string
+	var walk func(dir string, inIgnored bool)
+	walk = func(dir string, inIgnored bool) {
+		entries, err := fs.ReadDir(path.Join(".", dir))
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			name := path.Join(dir, e.Name())
+			if e.Name() == ".git" && dir == "" {
+				continue
+			}
+			ignored := inIgnored || r.IsIgnored(name, e.IsDir())
+			if e.IsDir() {
+				if ignored && !inIgnored && !hasTracked(name) {
+					result = append(result, name+"/")
+					continue
+				}
+				walk(name, ignored)
+				continue
+			}
+			if ignored && !tracked[name] {
+				result = append(result, name)
+			}
+		}
+	}
+	walk("", false)
+	sort.Strings(result)
+	return result
+}
+
+// IsDirPath reports whether name is a directory in fs, or is written as one ("build/").
+func IsDirPath(fs billy.Filesystem, name string) bool {
+	if strings.HasSuffix(name, "/") {
+		return true
+	}
+	info, err := fs.Lstat(name)
+	return err == nil && info.IsDir()
+}
+
+// TrackedPaths returns the paths staged in the index of repo.
+func TrackedPaths(repo *gogit.Repository) map[string]bool {
+	tracked := make(map[string]bool)
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return tracked
+	}
+	for _, e := range idx.Entries {
+		tracked[e.Name] = true
+	}
+	return tracked
+}
+
+// dropIgnored removes untracked entries excluded by rules go-git's status does
+// not read, such as .git/info/exclude.
+func dropIgnored(status gogit.Status, rules *IgnoreRules) {
+	if rules.Empty() {
+		return
+	}
+	for name, s := range status {
+		if s.Staging == gogit.Untracked && rules.IsIgnored(name, false) {
+			delete(status, name)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules_MatchAndIgnoredPaths(t *testing.T) {
+	fs := memfs.New()
+	write := func(name, content string) {
+		require.NoError(t, util.WriteFile(fs, name, []byte(content), 0644))
+	}
+	write(".gitignore", "# build output\n*.log\n!keep.log\nbuild/\n")
+	write("src/.gitignore", "*.tmp\n")
+	write(".git/info/exclude", "secret.txt\n")
+	write("debug.log", "x")
+	write("keep.log", "x")
+	write("build/app.bin", "x")
+	write("src/cache.tmp", "x")
+	write("src/main.go", "x")
+	write("secret.txt", "x")
+	write("tracked.log", "x")
+
+	rules := LoadIgnoreRules(fs)
+
+	rule := rules.Match("debug.log", false)
+	require.NotNil(t, rule)
+	assert.Equal(t, ".gitignore", rule.Source)
+	assert.Equal(t, 2, rule.Line)
+	assert.Equal(t, "*.log", rule.Pattern)
+
+	rule = rules.Match("keep.log", false)
+	require.NotNil(t, rule)
+	assert.True(t, rule.Negated())
+	assert.False(t, rules.IsIgnored("keep.log", false))
+
+	assert.True(t, rules.IsIgnored("build/app.bin", false), "files inside an ignored directory are ignored")
+	assert.True(t, rules.IsIgnored("src/cache.tmp", false))
+	assert.False(t, rules.IsIgnored("cache.tmp", false), "nested .gitignore only applies below its directory")
+	assert.Equal(t, ".git/info/exclude", rules.Match("secret.txt", false).Source)
+	assert.False(t, rules.IsIgnored("src/main.go", false))
+
+	ignored := rules.IgnoredPaths(fs, map[string]bool{"tracked.log": true})
+	assert.Equal(t, []string{"build/", "debug.log", "secret.txt", "src/cache.tmp"}, ignored)
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	appconfig "github.com/kurobon/gitgym/backend/internal/config"
+)
+
+// Ingest manifests
+//
+// Every shared remote directory <remotes>/<hash> gets a sibling manifest
+// <remotes>/<hash>.ingest.json recording how far its ingest got. The
+// manifest lives next to the directory rather than inside it because a
+// fresh clone wipes the directory first. If the server dies mid-ingest the
+// manifest stays in "cloning" or "fetching", and RecoverIngests resumes
+// or cleans it up on the next start.
+
+// Ingest states recorded in an IngestManifest
+const (
+	IngestCloning  = "cloning"
+	IngestFetching = "fetching"
+	IngestReady    = "ready"
+	IngestFailed   = "failed"
+)
+
+const ingestManifestSuffix = ".ingest.json"
+
+// IngestManifest describes the ingest of one shared remote.
+type IngestManifest struct {
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Path         string    `json:"path"`
+	Depth        int       `json:"depth,omitempty"`
+	SyncInterval string    `json:"syncInterval,omitempty"` // How often to fetch the upstream again, see remote_sync.go
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"startedAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// IngestRecovery summarizes what RecoverIngests did.
+type IngestRecovery struct {
+	Restored []string // Ready remotes registered again
+	Resumed  []string // Interrupted ingests completed
+	Cleaned  []string // Interrupted ingests removed because they could not be resumed
+}
+
+func ingestManifestPath(repoPath string) string {
+	return repoPath + ingestManifestSuffix
+}
+
+// setIngestState records the manifest on disk and in memory. Write errors are
+// logged: a missing manifest only costs recoverability, not the ingest itself.
+func (sm *SessionManager) setIngestState(m *IngestManifest, state string, cause error) {
+	m.State = state
+	m.UpdatedAt = time.Now()
+	m.Error = ""
+	if cause != nil {
+		m.Error = cause.Error()
+	}
+
+	if err := writeIngestManifest(m); err != nil {
+		slog.Warn("ingest: failed to write manifest", "path", m.Path, "err", err)
+	}
+
+	copied := *m
+	sm.mu.Lock()
+	if sm.ingests == nil {
+		sm.ingests = make(map[string]*IngestManifest)
+	}
+	sm.ingests[m.Name] = &copied
+	sm.mu.Unlock()
+}
+
+func writeIngestManifest(m *IngestManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := ingestManifestPath(m.Path)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readIngestManifest(path string) (*IngestManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m IngestManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("corrupt manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// removeIngestManifest forgets the manifest of a removed remote. Caller holds sm.mu.
+func (sm *SessionManager) removeIngestManifestLocked(name, repoPath string) {
+	delete(sm.ingests, name)
+	if repoPath != "" {
+		_ = os.Remove(ingestManifestPath(repoPath))
+	}
+}
+
+// IngestStatuses returns the latest ingest manifest of every known remote, ordered by name.
+func (sm *SessionManager) IngestStatuses() []IngestManifest {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	result := make([]IngestManifest, 0, len(sm.ingests))
+	for _, m := range sm.ingests {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// RecoverIngests scans the remotes directory on startup. Ready remotes are
+// registered again, interrupted ingests are resumed, and those that cannot be
+// resumed are removed so later opens do not trip over half-cloned directories.
+// A canceled ctx stops the scan and leaves the remaining ingests to the next
+// start.
+func (sm *SessionManager) RecoverIngests(ctx context.Context) (IngestRecovery, error) {
+	var rec IngestRecovery
+	baseDir := appconfig.Global.RemotesDir()
+
+	manifests, err := filepath.Glob(filepath.Join(baseDir, "*"+ingestManifestSuffix))
+	if err != nil {
+		return rec, err
+	}
+	sort.Strings(manifests)
+
+	for _, path := range manifests {
+		if err := ctx.Err(); err != nil {
+			return rec, err
+		}
+		m, err := readIngestManifest(path)
+		if err != nil {
+			slog.Warn("recover ingests", "err", err)
+			_ = os.RemoveAll(strings.TrimSuffix(path, ingestManifestSuffix))
+			_ = os.Remove(path)
+			continue
+		}
+
+		if m.State == IngestReady {
+			repo, err := gogit.PlainOpen(m.Path)
+			if err == nil {
+				sm.registerSharedRemote(m.Name, m.URL, m.Path, repo)
+				sm.mu.Lock()
+				if sm.ingests == nil {
+					sm.ingests = make(map[string]*IngestManifest)
+				}
+				sm.ingests[m.Name] = m
+				sm.mu.Unlock()
+				rec.Restored = append(rec.Restored, m.Name)
+				continue
+			}
+			slog.Warn("recover ingests: ready remote does not open, re-ingesting", "remote", m.Name, "err", err)
+		}
+
+		slog.Info("recover ingests: resuming interrupted ingest", "remote", m.Name, "url", m.URL, "state", m.State)
+		if err := sm.IngestRemote(ctx, m.Name, m.URL, m.Depth); err != nil {
+			if ctx.Err() != nil {
+				// Shutting down: the manifest stays for the next start to resume
+				return rec, ctx.Err()
+			}
+			slog.Warn("recover ingests: could not resume, cleaning up", "remote", m.Name, "err", err)
+			_ = os.RemoveAll(m.Path)
+			sm.mu.Lock()
+			sm.removeIngestManifestLocked(m.Name, m.Path)
+			sm.mu.Unlock()
+			rec.Cleaned = append(rec.Cleaned, m.Name)
+			continue
+		}
+		rec.Resumed = append(rec.Resumed, m.Name)
+	}
+	return rec, nil
+}
+
+// registerSharedRemote makes repo reachable under its name, URL and path.
+func (sm *SessionManager) registerSharedRemote(name, url, repoPath string, repo *gogit.Repository) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.SharedRemotes[name] = repo
+	sm.SharedRemotePaths[name] = repoPath
+	sm.SharedRemotes[url] = repo
+	sm.SharedRemotePaths[url] = repoPath
+	sm.SharedRemotes[repoPath] = repo
+	sm.SharedRemotePaths[repoPath] = repoPath
+}
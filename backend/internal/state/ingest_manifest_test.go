@@ -0,0 +1,89 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	appconfig "github.com/kurobon/gitgym/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverIngests_RestoresResumesAndCleans(t *testing.T) {
+	tmp := t.TempDir()
+	orig := appconfig.Global
+	appconfig.Global = &appconfig.Config{DataRoot: filepath.Join(tmp, "data")}
+	t.Cleanup(func() { appconfig.Global = orig })
+
+	// Upstream repository to ingest
+	srcPath := filepath.Join(tmp, "upstream")
+	src, err := gogit.PlainInit(srcPath, false)
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "README.md"), []byte("hi"), 0644))
+	_, _ = w.Add("README.md")
+	_, err = w.Commit("Init", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	sm := NewSessionManager()
+	require.NoError(t, sm.IngestRemote(context.Background(), "upstream", srcPath, 0))
+	statuses := sm.IngestStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, IngestReady, statuses[0].State)
+	repoPath := statuses[0].Path
+
+	// 1. After a clean restart the ready remote is registered again
+	restarted := NewSessionManager()
+	rec, err := restarted.RecoverIngests(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"upstream"}, rec.Restored)
+	_, ok := restarted.GetSharedRemote("upstream")
+	assert.True(t, ok)
+
+	// 2. A crash mid-clone leaves a "cloning" manifest and a broken directory; it is resumed
+	m, err := readIngestManifest(ingestManifestPath(repoPath))
+	require.NoError(t, err)
+	m.State = IngestCloning
+	require.NoError(t, writeIngestManifest(m))
+	require.NoError(t, os.RemoveAll(filepath.Join(repoPath, "objects")))
+
+	crashed := NewSessionManager()
+	rec, err = crashed.RecoverIngests(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"upstream"}, rec.Resumed)
+	resumed, ok := crashed.GetSharedRemote("upstream")
+	require.True(t, ok)
+	_, err = resumed.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, IngestReady, crashed.IngestStatuses()[0].State)
+
+	// 3. An interrupted ingest whose source is gone is cleaned up
+	goneDir := filepath.Join(appconfig.Global.RemotesDir(), "deadbeef")
+	require.NoError(t, os.MkdirAll(goneDir, 0750))
+	require.NoError(t, writeIngestManifest(&IngestManifest{
+		Name: "gone", URL: filepath.Join(tmp, "missing"), Path: goneDir, State: IngestFetching,
+	}))
+
+	// A shutdown during recovery leaves the interrupted ingest for the next start
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewSessionManager().RecoverIngests(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = os.Stat(ingestManifestPath(goneDir))
+	require.NoError(t, err)
+
+	rec, err = NewSessionManager().RecoverIngests(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone"}, rec.Cleaned)
+	_, err = os.Stat(goneDir)
+	assert.True(t, os.IsNotExist(err), "half-ingested directory should be removed")
+	_, err = os.Stat(ingestManifestPath(goneDir))
+	assert.True(t, os.IsNotExist(err), "manifest should be removed")
+}
@@ -0,0 +1,251 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Ingest policy
+//
+// The ingest endpoint copies whatever repository a client names onto the
+// server's disk. Operators exposing it restrict which hosts may be ingested
+// from, cap the size and commit count of what is copied (a clone growing
+// past the size cap is aborted), and give tokens for private repositories.
+
+// Environment variables read by IngestPolicyFromEnv
+const (
+	IngestAllowedHostsEnv = "GITGYM_INGEST_ALLOWED_HOSTS" // Comma-separated, e.g. "github.com,*.example.com"
+	IngestMaxSizeEnv      = "GITGYM_INGEST_MAX_SIZE_MB"
+	IngestMaxCommitsEnv   = "GITGYM_INGEST_MAX_COMMITS"
+	IngestTokensEnv       = "GITGYM_INGEST_TOKENS" // Comma-separated host=token or host=user:token
+)
+
+// ingestSizePollInterval is how often a running clone's size is checked against MaxBytes.
+var ingestSizePollInterval = 100 * time.Millisecond
+
+// IngestPolicy limits what IngestRemote may copy. The zero value allows everything.
+type IngestPolicy struct {
+	AllowedHosts []string          `json:"allowedHosts,omitempty"` // "*.example.com" matches subdomains; empty allows every URL
+	MaxBytes     int64             `json:"maxBytes,omitempty"`     // Largest repository on disk; 0 means unlimited
+	MaxCommits   int               `json:"maxCommits,omitempty"`   // 0 means unlimited
+	Tokens       map[string]string `json:"-"`                      // Access token by host, for private repositories
+}
+
+// IngestPolicyFromEnv reads the policy from the GITGYM_INGEST_* variables. An
+// invalid value is reported and left unlimited.
+func IngestPolicyFromEnv() (IngestPolicy, error) {
+	var p IngestPolicy
+	var errs []error
+	for _, host := range strings.Split(os.Getenv(IngestAllowedHostsEnv), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			p.AllowedHosts = append(p.AllowedHosts, host)
+		}
+	}
+	if v := os.Getenv(IngestMaxSizeEnv); v != "" {
+		if mb, err := strconv.ParseInt(v, 10, 64); err == nil && mb >= 0 {
+			p.MaxBytes = mb << 20
+		} else {
+			errs = append(errs, fmt.Errorf("invalid %s %q", IngestMaxSizeEnv, v))
+		}
+	}
+	if v := os.Getenv(IngestMaxCommitsEnv); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			p.MaxCommits = n
+		} else {
+			errs = append(errs, fmt.Errorf("invalid %s %q", IngestMaxCommitsEnv, v))
+		}
+	}
+	for _, entry := range strings.Split(os.Getenv(IngestTokensEnv), ",") {
+		host, token, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || host == "" || token == "" {
+			if entry != "" {
+				errs = append(errs, fmt.Errorf("invalid %s entry (want host=token)", IngestTokensEnv))
+			}
+			continue
+		}
+		if p.Tokens == nil {
+			p.Tokens = make(map[string]string)
+		}
+		p.Tokens[strings.ToLower(host)] = token
+	}
+	return p, errors.Join(errs...)
+}
+
+// ingestHost returns the host of a repository URL, including scp-like
+// "git@host:owner/repo.git", or "" for a local path.
+func ingestHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Scheme != "file" && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if isLocalURL(rawURL) {
+		return ""
+	}
+	user, rest, ok := strings.Cut(rawURL, "@")
+	if !ok || strings.Contains(user, "/") {
+		return ""
+	}
+	if host, _, ok := strings.Cut(rest, ":"); ok && host != "" && !strings.Contains(host, "/") {
+		return strings.ToLower(host)
+	}
+	return ""
+}
+
+// isLocalURL reports whether rawURL names a repository on the server's own
+// disk: a file:// URL or a path. Git reads anything with "://" as a URL and
+// anything else without a colon before its first slash as a path.
+func isLocalURL(rawURL string) bool {
+	if scheme, _, ok := strings.Cut(rawURL, "://"); ok {
+		return strings.EqualFold(scheme, "file")
+	}
+	if strings.HasPrefix(rawURL, "/") || strings.HasPrefix(rawURL, ".") || strings.HasPrefix(rawURL, "~") {
+		return true
+	}
+	colon := strings.Index(rawURL, ":")
+	return colon < 0 || strings.Contains(rawURL[:colon], "/")
+}
+
+// CheckURL refuses a URL whose host is not allowed. Local repositories are
+// refused whenever hosts are restricted.
+func (p IngestPolicy) CheckURL(rawURL string) error {
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+	if isLocalURL(rawURL) {
+		return fmt.Errorf("ingest refused: '%s' is a local repository; only hosts %s are allowed", rawURL, strings.Join(p.AllowedHosts, ", "))
+	}
+	host := ingestHost(rawURL)
+	if host == "" {
+		return fmt.Errorf("ingest refused: '%s' is not a URL of an allowed host (%s)", rawURL, strings.Join(p.AllowedHosts, ", "))
+	}
+	for _, allowed := range p.AllowedHosts {
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("ingest refused: host '%s' is not allowed (allowed: %s)", host, strings.Join(p.AllowedHosts, ", "))
+}
+
+// auth returns the credentials to ingest rawURL with: token when given, or
+// else the token configured for its host. A token may carry its user name as
+// "user:token".
+func (p IngestPolicy) auth(rawURL, token string) transport.AuthMethod {
+	if token == "" {
+		token = p.Tokens[ingestHost(rawURL)]
+	}
+	if token == "" || !strings.HasPrefix(rawURL, "http") {
+		return nil
+	}
+	user, password, ok := strings.Cut(token, ":")
+	if !ok {
+		// GitHub and most hosts accept any user name with a token
+		user, password = "x-access-token", token
+	}
+	return &githttp.BasicAuth{Username: user, Password: password}
+}
+
+// sizeError reports a repository beyond MaxBytes.
+func (p IngestPolicy) sizeError() error {
+	limit := fmt.Sprintf("%d MB", p.MaxBytes>>20)
+	if p.MaxBytes < 1<<20 {
+		limit = fmt.Sprintf("%d bytes", p.MaxBytes)
+	}
+	return fmt.Errorf("ingest aborted: the repository is larger than the limit of %s", limit)
+}
+
+// checkRepo enforces MaxBytes and MaxCommits on an ingested repository. The
+// commit count stops early when ctx is canceled.
+func (p IngestPolicy) checkRepo(ctx context.Context, path string, repo *gogit.Repository) error {
+	if p.MaxBytes > 0 && dirSize(path) > p.MaxBytes {
+		return p.sizeError()
+	}
+	if p.MaxCommits <= 0 {
+		return nil
+	}
+	iter, err := repo.CommitObjects()
+	if err != nil {
+		return err
+	}
+	n := 0
+	_ = iter.ForEach(func(*object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+		if n > p.MaxCommits {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n > p.MaxCommits {
+		return fmt.Errorf("ingest aborted: the repository has more than %d commits\nhint: Ingest a shallow copy with a depth instead", p.MaxCommits)
+	}
+	return nil
+}
+
+// limitRepoSize returns a context that is cancelled once the directory at
+// path grows beyond max bytes, and a function reporting whether it did.
+// Call stop when the clone is done.
+func limitRepoSize(ctx context.Context, path string, max int64) (limited context.Context, exceeded func() bool, stop func()) {
+	limited, cancel := context.WithCancel(ctx)
+	if max <= 0 {
+		return limited, func() bool { return false }, cancel
+	}
+	over := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ingestSizePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-limited.Done():
+				return
+			case <-ticker.C:
+				if dirSize(path) > max {
+					close(over)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	exceeded = func() bool {
+		select {
+		case <-over:
+			return true
+		default:
+			return false
+		}
+	}
+	return limited, exceeded, cancel
+}
+
+// dirSize sums the sizes of the files under path.
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
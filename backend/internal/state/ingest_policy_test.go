@@ -0,0 +1,120 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	appconfig "github.com/kurobon/gitgym/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestPolicy_CheckURL(t *testing.T) {
+	p := IngestPolicy{AllowedHosts: []string{"github.com", "*.example.com"}}
+	for url, allowed := range map[string]bool{
+		"https://github.com/octocat/Spoon-Knife.git": true,
+		"https://GitHub.com/octocat/Spoon-Knife":     true,
+		"git@github.com:octocat/Spoon-Knife.git":     true,
+		"https://git.example.com/team/repo.git":      true,
+		"https://example.com/team/repo.git":          false,
+		"https://github.com.evil.test/repo.git":      false,
+		"/srv/repos/private.git":                     false,
+		"file:///srv/repos/private.git":              false,
+		"/srv/secret@github.com:x":                   false,
+		"file:///etc@github.com:x":                   false,
+		"./repo@github.com:x":                        false,
+		"~/repo@github.com:x":                        false,
+		"srv/secret@github.com:x":                    false,
+		"FILE://github.com/octocat/Spoon-Knife.git":  false,
+		"ssh://git@github.com/octocat/Spoon-Knife":   true,
+	} {
+		err := p.CheckURL(url)
+		assert.Equal(t, allowed, err == nil, "%s: %v", url, err)
+	}
+	assert.NoError(t, IngestPolicy{}.CheckURL("/srv/repos/private.git"), "no allowlist allows everything")
+}
+
+func TestIngestHost(t *testing.T) {
+	for url, host := range map[string]string{
+		"https://GitHub.com/octocat/Spoon-Knife.git": "github.com",
+		"git@github.com:octocat/Spoon-Knife.git":     "github.com",
+		"ssh://git@github.com:22/octocat/repo.git":   "github.com",
+		"/srv/secret@github.com:x":                   "",
+		"file:///etc@github.com:x":                   "",
+		"file://github.com/etc/passwd":               "",
+		"../repo@github.com:x":                       "",
+		"~user/repo@github.com:x":                    "",
+		"srv/secret@github.com:x":                    "",
+		"/srv/repos/private.git":                     "",
+	} {
+		assert.Equal(t, host, ingestHost(url), url)
+	}
+}
+
+func TestIngestPolicyFromEnv(t *testing.T) {
+	t.Setenv(IngestAllowedHostsEnv, "github.com, GitLab.com,")
+	t.Setenv(IngestMaxSizeEnv, "50")
+	t.Setenv(IngestMaxCommitsEnv, "lots")
+	t.Setenv(IngestTokensEnv, "github.com=ghp_secret,git.example.com=deploy:pat")
+
+	p, err := IngestPolicyFromEnv()
+	assert.ErrorContains(t, err, IngestMaxCommitsEnv)
+	assert.Equal(t, []string{"github.com", "gitlab.com"}, p.AllowedHosts)
+	assert.Equal(t, int64(50<<20), p.MaxBytes)
+	assert.Zero(t, p.MaxCommits)
+
+	assert.Equal(t, &githttp.BasicAuth{Username: "x-access-token", Password: "ghp_secret"}, p.auth("https://github.com/org/private.git", ""))
+	assert.Equal(t, &githttp.BasicAuth{Username: "deploy", Password: "pat"}, p.auth("https://git.example.com/org/repo.git", ""))
+	assert.Equal(t, &githttp.BasicAuth{Username: "x-access-token", Password: "mine"}, p.auth("https://github.com/org/private.git", "mine"), "a token given with the ingest wins")
+	assert.Nil(t, p.auth("https://gitlab.com/org/repo.git", ""))
+}
+
+func TestIngestRemote_EnforcesPolicy(t *testing.T) {
+	tmp := t.TempDir()
+	orig := appconfig.Global
+	appconfig.Global = &appconfig.Config{DataRoot: filepath.Join(tmp, "data")}
+	t.Cleanup(func() { appconfig.Global = orig })
+
+	srcPath := filepath.Join(tmp, "upstream")
+	src, err := gogit.PlainInit(srcPath, false)
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(srcPath, "README.md"), []byte(fmt.Sprint(i)), 0644))
+		_, _ = w.Add("README.md")
+		_, err = w.Commit(fmt.Sprintf("Commit %d", i), &gogit.CommitOptions{
+			Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+
+	ingest := func(policy IngestPolicy) error {
+		sm := NewSessionManager()
+		sm.IngestPolicy = policy
+		err := sm.IngestRemote(context.Background(), "upstream", srcPath, 0)
+		if err != nil {
+			_, ok := sm.GetSharedRemote("upstream")
+			assert.False(t, ok, "a refused ingest registers nothing")
+		}
+		return err
+	}
+
+	assert.ErrorContains(t, ingest(IngestPolicy{AllowedHosts: []string{"github.com"}}), "ingest refused")
+	assert.ErrorContains(t, ingest(IngestPolicy{MaxCommits: 2}), "more than 2 commits")
+	assert.ErrorContains(t, ingest(IngestPolicy{MaxBytes: 64}), "larger than the limit of 64 bytes")
+	entries, _ := filepath.Glob(filepath.Join(appconfig.Global.RemotesDir(), "*"))
+	for _, entry := range entries {
+		info, err := os.Stat(entry)
+		require.NoError(t, err)
+		assert.False(t, info.IsDir(), "an oversized clone is removed: %s", entry)
+	}
+
+	assert.NoError(t, ingest(IngestPolicy{MaxCommits: 3, MaxBytes: 10 << 20}))
+}
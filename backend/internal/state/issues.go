@@ -0,0 +1,317 @@
+package state
+
+// issues.go - Issue tracker of the shared remotes
+//
+// Each shared remote has its own issues, numbered from 1 like on GitHub.
+// A commit that reaches the remote's default branch closes the issues its
+// message references with a closing keyword ("Fixes #3", "closes #4"), so
+// missions can teach linking work to tickets. Like pull requests, issues are
+// cached in the SessionManager and written through to its Store.
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Issue states
+const (
+	IssueStateOpen   = "OPEN"
+	IssueStateClosed = "CLOSED"
+)
+
+// ErrIssueNotFound is returned for an unknown issue number.
+var ErrIssueNotFound = errors.New("issue not found")
+
+const bucketIssues = "issues"
+
+// maxLinkedCommits caps how many commits one push scans for closing keywords.
+const maxLinkedCommits = 1000
+
+// closingKeyword matches GitHub's closing keywords followed by an issue number.
+var closingKeyword = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+#(\d+)\b`)
+
+// Issue is a ticket of a shared remote.
+type Issue struct {
+	ID         int        `json:"id"`         // Number within the remote
+	RemoteName string     `json:"remoteName"` // The shared remote this issue belongs to
+	Title      string     `json:"title"`
+	Body       string     `json:"body,omitempty"`
+	State      string     `json:"state"` // IssueStateOpen or IssueStateClosed
+	Creator    string     `json:"creator"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ClosedAt   *time.Time `json:"closedAt,omitempty"`
+	ClosedBy   string     `json:"closedBy,omitempty"`   // User who closed it, or the author of the closing commit
+	ClosingRef string     `json:"closingRef,omitempty"` // Commit whose message closed it
+}
+
+// issueTracker holds the issues of one shared remote.
+type issueTracker struct {
+	issues  []*Issue      // Oldest first
+	nextID  int           // Number of the next issue
+	scanned plumbing.Hash // Default branch tip whose history was already searched for closing keywords
+}
+
+// CreateIssue opens an issue on the named shared remote.
+func (sm *SessionManager) CreateIssue(remote, title, body, creator string) (*Issue, error) {
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("issue title is required")
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	repo, ok := sm.SharedRemotes[remote]
+	if !ok {
+		return nil, fmt.Errorf("remote '%s' not found", remote)
+	}
+	remote = sm.remoteNameLocked(repo)
+	t := sm.issueTrackerLocked(remote)
+	if len(t.issues) == 0 && t.scanned.IsZero() {
+		// Only commits pushed from now on can close issues
+		if ref, err := repo.Reference(plumbing.NewBranchReferenceName(defaultBranch(repo)), true); err == nil {
+			t.scanned = ref.Hash()
+		}
+	}
+	issue := &Issue{
+		ID:         t.nextID,
+		RemoteName: remote,
+		Title:      title,
+		Body:       body,
+		State:      IssueStateOpen,
+		Creator:    creator,
+		CreatedAt:  time.Now(),
+	}
+	t.nextID++
+	t.issues = append(t.issues, issue)
+	sm.saveIssueLocked(issue)
+	c := *issue
+	return &c, nil
+}
+
+// GetIssues lists copies of the issues of the named shared remote, oldest
+// first. state filters them unless empty.
+func (sm *SessionManager) GetIssues(remote, state string) ([]*Issue, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	repo, ok := sm.SharedRemotes[remote]
+	if !ok {
+		return nil, fmt.Errorf("remote '%s' not found", remote)
+	}
+	result := []*Issue{}
+	if t, ok := sm.issues[sm.remoteNameLocked(repo)]; ok {
+		for _, issue := range t.issues {
+			if state == "" || strings.EqualFold(issue.State, state) {
+				c := *issue
+				result = append(result, &c)
+			}
+		}
+	}
+	return result, nil
+}
+
+// IssueClosed reports whether issue id of the shared remote repo is closed.
+func (sm *SessionManager) IssueClosed(repo *gogit.Repository, id int) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	issue := sm.findIssueLocked(sm.remoteNameLocked(repo), id)
+	return issue != nil && issue.State == IssueStateClosed
+}
+
+// CloseIssue closes an open issue of the named shared remote.
+func (sm *SessionManager) CloseIssue(remote string, id int, closedBy string) (*Issue, error) {
+	return sm.updateIssue(remote, id, func(issue *Issue) error {
+		if issue.State != IssueStateOpen {
+			return fmt.Errorf("issue #%d is not OPEN (current state: %s)", id, issue.State)
+		}
+		now := time.Now()
+		issue.State = IssueStateClosed
+		issue.ClosedAt = &now
+		issue.ClosedBy = closedBy
+		return nil
+	})
+}
+
+// ReopenIssue reopens a closed issue of the named shared remote.
+func (sm *SessionManager) ReopenIssue(remote string, id int) (*Issue, error) {
+	return sm.updateIssue(remote, id, func(issue *Issue) error {
+		if issue.State != IssueStateClosed {
+			return fmt.Errorf("issue #%d is not CLOSED (current state: %s)", id, issue.State)
+		}
+		issue.State = IssueStateOpen
+		issue.ClosedAt = nil
+		issue.ClosedBy = ""
+		issue.ClosingRef = ""
+		return nil
+	})
+}
+
+// updateIssue applies fn to an issue and saves it when fn succeeds.
+func (sm *SessionManager) updateIssue(remote string, id int, fn func(issue *Issue) error) (*Issue, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	repo, ok := sm.SharedRemotes[remote]
+	if !ok {
+		return nil, fmt.Errorf("remote '%s' not found", remote)
+	}
+	remote = sm.remoteNameLocked(repo)
+	issue := sm.findIssueLocked(remote, id)
+	if issue == nil {
+		return nil, fmt.Errorf("%w: #%d on %s", ErrIssueNotFound, id, remote)
+	}
+	if err := fn(issue); err != nil {
+		return nil, err
+	}
+	sm.saveIssueLocked(issue)
+	c := *issue
+	return &c, nil
+}
+
+// CloseLinkedIssues closes the open issues referenced with a closing keyword
+// by the commits that branch of repo now has and did not have at the last
+// scan, provided branch is the default branch. It returns the numbers of the
+// closed issues.
+func (sm *SessionManager) CloseLinkedIssues(repo *gogit.Repository, branch string, hash plumbing.Hash) []int {
+	if branch != defaultBranch(repo) {
+		return nil
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	remote := sm.remoteNameLocked(repo)
+	t, ok := sm.issues[remote]
+	if !ok {
+		return nil // No issues to close
+	}
+	tip, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil
+	}
+	var stop []plumbing.Hash
+	if !t.scanned.IsZero() {
+		stop = append(stop, t.scanned)
+	}
+	t.scanned = hash
+	seen := reachableCommits(repo, stop, nil)
+
+	var closed []int
+	now := time.Now()
+	commits := 0
+	_ = object.NewCommitPreorderIter(tip, seen, nil).ForEach(func(c *object.Commit) error {
+		if commits++; commits > maxLinkedCommits {
+			return storer.ErrStop
+		}
+		for _, m := range closingKeyword.FindAllStringSubmatch(c.Message, -1) {
+			id, _ := strconv.Atoi(m[1])
+			issue := sm.findIssueLocked(remote, id)
+			if issue == nil || issue.State != IssueStateOpen {
+				continue
+			}
+			issue.State = IssueStateClosed
+			issue.ClosedAt = &now
+			issue.ClosedBy = c.Author.Name
+			issue.ClosingRef = c.Hash.String()
+			sm.saveIssueLocked(issue)
+			closed = append(closed, id)
+		}
+		return nil
+	})
+	sort.Ints(closed)
+	return closed
+}
+
+// issueTrackerLocked returns the issues of remote, creating the tracker.
+// Caller holds sm.mu.
+func (sm *SessionManager) issueTrackerLocked(remote string) *issueTracker {
+	if sm.issues == nil {
+		sm.issues = make(map[string]*issueTracker)
+	}
+	t, ok := sm.issues[remote]
+	if !ok {
+		t = &issueTracker{nextID: 1}
+		sm.issues[remote] = t
+	}
+	return t
+}
+
+// findIssueLocked returns issue id of remote, or nil. Caller holds sm.mu.
+func (sm *SessionManager) findIssueLocked(remote string, id int) *Issue {
+	if t, ok := sm.issues[remote]; ok {
+		for _, issue := range t.issues {
+			if issue.ID == id {
+				return issue
+			}
+		}
+	}
+	return nil
+}
+
+// saveIssueLocked writes an issue through to the store. Caller holds sm.mu.
+func (sm *SessionManager) saveIssueLocked(issue *Issue) {
+	if sm.Store == nil {
+		return
+	}
+	if err := putJSON(sm.Store, bucketIssues, issueKey(issue.RemoteName, issue.ID), issue); err != nil {
+		slog.Warn("store: failed to save issue", "remote", issue.RemoteName, "issue", issue.ID, "err", err)
+	}
+}
+
+// deleteIssuesLocked forgets the issues of remote. Caller holds sm.mu.
+func (sm *SessionManager) deleteIssuesLocked(remote string) {
+	t, ok := sm.issues[remote]
+	if !ok {
+		return
+	}
+	delete(sm.issues, remote)
+	if sm.Store == nil {
+		return
+	}
+	for _, issue := range t.issues {
+		if err := sm.Store.Delete(bucketIssues, issueKey(remote, issue.ID)); err != nil {
+			slog.Warn("store: failed to delete issue", "remote", remote, "issue", issue.ID, "err", err)
+		}
+	}
+}
+
+// loadIssues reads the issues kept in store.
+func loadIssues(store Store) (map[string]*issueTracker, error) {
+	keys, err := store.List(bucketIssues)
+	if err != nil {
+		return nil, err
+	}
+	trackers := make(map[string]*issueTracker)
+	for _, key := range keys {
+		var issue Issue
+		if ok, err := getJSON(store, bucketIssues, key, &issue); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		t, ok := trackers[issue.RemoteName]
+		if !ok {
+			t = &issueTracker{nextID: 1}
+			trackers[issue.RemoteName] = t
+		}
+		t.issues = append(t.issues, &issue)
+		if issue.ID >= t.nextID {
+			t.nextID = issue.ID + 1
+		}
+	}
+	for _, t := range trackers {
+		sort.Slice(t.issues, func(i, j int) bool { return t.issues[i].ID < t.issues[j].ID })
+	}
+	return trackers, nil
+}
+
+func issueKey(remote string, id int) string { return remote + "/" + prKey(id) }
@@ -0,0 +1,78 @@
+package state
+
+import (
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssues_ClosedByCommitsOnDefaultBranch(t *testing.T) {
+	sm, repo, _ := newTeammateRemote(t)
+	w, _ := repo.Worktree()
+	commit := func(message string) plumbing.Hash {
+		hash, err := w.Commit(message, &gogit.CommitOptions{Author: &object.Signature{Name: "Alice", Email: "alice@example.com"}, AllowEmptyCommits: true})
+		require.NoError(t, err)
+		return hash
+	}
+	// Commits from before the issue existed do not close it
+	commit("Fixes #1 before it was filed")
+
+	first, err := sm.CreateIssue("origin", "Typo in README", "", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	second, err := sm.CreateIssue("origin", "Missing docs", "", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+	_, err = sm.CreateIssue("origin", " ", "", "bob")
+	assert.Error(t, err)
+
+	// Not the default branch
+	hash := commit("Fix typo\n\nFixes #1")
+	assert.Empty(t, sm.CloseLinkedIssues(repo, "feature", hash))
+
+	commit("Unrelated")
+	sm.BranchPushed(repo, "master", hash)
+	issues, err := sm.GetIssues("origin", IssueStateClosed)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 1, issues[0].ID)
+	assert.Equal(t, "Alice", issues[0].ClosedBy)
+	assert.Equal(t, hash.String(), issues[0].ClosingRef)
+	assert.True(t, sm.IssueClosed(repo, 1))
+
+	// Only the commits new since the last push are searched
+	_, err = sm.ReopenIssue("origin", 1)
+	require.NoError(t, err)
+	hash = commit("Resolves: #2, see also #1")
+	assert.Equal(t, []int{2}, sm.CloseLinkedIssues(repo, "master", hash))
+
+	_, err = sm.CloseIssue("origin", 2, "bob")
+	assert.Error(t, err, "already closed")
+	_, err = sm.CloseIssue("origin", 9, "bob")
+	assert.ErrorIs(t, err, ErrIssueNotFound)
+}
+
+func TestIssues_Persisted(t *testing.T) {
+	sm, repo, _ := newTeammateRemote(t)
+	store := NewMemoryStore()
+	require.NoError(t, sm.UseStore(store))
+	_, err := sm.CreateIssue("origin", "Typo in README", "", "bob")
+	require.NoError(t, err)
+	_, err = sm.CloseIssue("origin", 1, "bob")
+	require.NoError(t, err)
+
+	restarted := NewSessionManager()
+	restarted.SharedRemotes["origin"] = repo
+	require.NoError(t, restarted.UseStore(store))
+	issues, err := restarted.GetIssues("origin", "")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, IssueStateClosed, issues[0].State)
+	next, err := restarted.CreateIssue("origin", "Another", "", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 2, next.ID)
+}
@@ -0,0 +1,422 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Background jobs
+//
+// Operations that can take minutes, like ingesting a large remote, run as
+// jobs so the HTTP request that starts one returns its ID right away. Jobs
+// run one at a time in submission order; the others wait in the queue. A job
+// reports its phase and object counts through JobProgress, subscribers follow
+// those changes, and a queued or running job can be cancelled.
+
+// Job kinds
+const (
+	JobIngest = "ingest"
+	JobSync   = "sync" // Fetch an ingested remote from its upstream, see remote_sync.go
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// maxFinishedJobs is how many finished jobs are kept for GET /api/jobs.
+const maxFinishedJobs = 50
+
+// Job is a snapshot of one background job.
+type Job struct {
+	ID           string     `json:"id"`
+	Kind         string     `json:"kind"`
+	Target       string     `json:"target"` // What the job works on, e.g. the remote being ingested
+	State        string     `json:"state"`
+	Phase        string     `json:"phase,omitempty"`        // e.g. "cloning", "Counting objects"
+	Objects      int        `json:"objects,omitempty"`      // Objects received so far
+	TotalObjects int        `json:"totalObjects,omitempty"` // 0 while unknown
+	Error        string     `json:"error,omitempty"`
+	Seq          uint64     `json:"seq"` // Bumped on every change
+	CreatedAt    time.Time  `json:"createdAt"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Finished reports whether the job will not change any more.
+func (j Job) Finished() bool {
+	return j.State == JobSucceeded || j.State == JobFailed || j.State == JobCancelled
+}
+
+// JobFunc does the work of a job. It should give up when ctx is cancelled.
+type JobFunc func(ctx context.Context, progress *JobProgress) error
+
+// jobRunner holds the jobs of a SessionManager. The zero value is ready to use.
+type jobRunner struct {
+	mu      sync.Mutex
+	jobs    map[string]*jobEntry
+	order   []*jobEntry // Oldest first
+	queue   []*jobEntry // Waiting to run, next first
+	running *jobEntry
+	nextID  int
+
+	draining bool          // Set by Drain: new jobs fail right away
+	drained  chan struct{} // Closed once draining and no job is left
+}
+
+type jobEntry struct {
+	job    Job
+	run    JobFunc
+	cancel context.CancelFunc // Set while running
+	subs   map[*JobSubscriber]struct{}
+}
+
+// SubmitJob queues run and returns the job right away. It starts as soon as
+// no other job is running.
+func (sm *SessionManager) SubmitJob(kind, target string, run JobFunc) Job {
+	r := &sm.jobRunner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jobs == nil {
+		r.jobs = make(map[string]*jobEntry)
+	}
+	r.nextID++
+	e := &jobEntry{
+		job:  Job{ID: "job-" + strconv.Itoa(r.nextID), Kind: kind, Target: target, State: JobQueued, CreatedAt: time.Now()},
+		run:  run,
+		subs: make(map[*JobSubscriber]struct{}),
+	}
+	r.jobs[e.job.ID] = e
+	r.order = append(r.order, e)
+	if r.draining {
+		r.finishLocked(e, ErrShuttingDown)
+		return e.job
+	}
+	r.queue = append(r.queue, e)
+	r.startNextLocked()
+	return e.job
+}
+
+// GetJob returns the job with the given ID.
+func (sm *SessionManager) GetJob(id string) (Job, bool) {
+	r := &sm.jobRunner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// Jobs returns every job still known, oldest first.
+func (sm *SessionManager) Jobs() []Job {
+	r := &sm.jobRunner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs := make([]Job, 0, len(r.order))
+	for _, e := range r.order {
+		jobs = append(jobs, e.job)
+	}
+	return jobs
+}
+
+// CancelJob drops a queued job or stops a running one. A running job is
+// reported cancelled once its function returns.
+func (sm *SessionManager) CancelJob(id string) error {
+	r := &sm.jobRunner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok {
+		return fmt.Errorf("job '%s' not found", id)
+	}
+	switch {
+	case e.job.Finished():
+		return fmt.Errorf("job '%s' already %s", id, e.job.State)
+	case e.cancel != nil:
+		e.cancel()
+	default:
+		for i, queued := range r.queue {
+			if queued == e {
+				r.queue = append(r.queue[:i], r.queue[i+1:]...)
+				break
+			}
+		}
+		r.finishLocked(e, context.Canceled)
+		r.startNextLocked()
+	}
+	return nil
+}
+
+// startNextLocked runs the next queued job unless one is running. Caller holds r.mu.
+func (r *jobRunner) startNextLocked() {
+	if r.running == nil && len(r.queue) == 0 && r.drained != nil {
+		close(r.drained)
+		r.drained = nil
+	}
+	if r.running != nil || len(r.queue) == 0 {
+		return
+	}
+	e := r.queue[0]
+	r.queue = r.queue[1:]
+	r.running = e
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	now := time.Now()
+	e.job.StartedAt = &now
+	e.job.State = JobRunning
+	r.publishLocked(e)
+
+	go func() {
+		err := e.run(ctx, &JobProgress{runner: r, entry: e})
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		cancel()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		e.cancel = nil
+		r.running = nil
+		r.finishLocked(e, err)
+		r.startNextLocked()
+	}()
+}
+
+// beginDrain makes new jobs fail; the queued ones still run.
+func (r *jobRunner) beginDrain() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return
+	}
+	r.draining = true
+	r.drained = make(chan struct{})
+	r.startNextLocked()
+}
+
+// waitDrained waits for the queued and running jobs to finish. When ctx is
+// done first, it cancels them and returns the error of ctx.
+func (r *jobRunner) waitDrained(ctx context.Context) error {
+	r.mu.Lock()
+	drained := r.drained
+	r.mu.Unlock()
+	if drained == nil {
+		return nil
+	}
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.queue {
+		r.finishLocked(e, context.Canceled)
+	}
+	r.queue = nil
+	if r.running != nil && r.running.cancel != nil {
+		r.running.cancel()
+	}
+	return ctx.Err()
+}
+
+// finishLocked records how a job ended and forgets the oldest finished jobs
+// beyond maxFinishedJobs. Caller holds r.mu.
+func (r *jobRunner) finishLocked(e *jobEntry, err error) {
+	now := time.Now()
+	e.job.FinishedAt = &now
+	switch {
+	case err == nil:
+		e.job.State = JobSucceeded
+		if e.job.TotalObjects > 0 {
+			e.job.Objects = e.job.TotalObjects
+		}
+	case errors.Is(err, context.Canceled):
+		e.job.State = JobCancelled
+	default:
+		e.job.State = JobFailed
+		e.job.Error = err.Error()
+	}
+	r.publishLocked(e)
+
+	finished := 0
+	for _, j := range r.order {
+		if j.job.Finished() {
+			finished++
+		}
+	}
+	kept := r.order[:0]
+	for _, j := range r.order {
+		if finished > maxFinishedJobs && j.job.Finished() {
+			finished--
+			delete(r.jobs, j.job.ID)
+			continue
+		}
+		kept = append(kept, j)
+	}
+	r.order = kept
+}
+
+// publishLocked bumps the job's sequence number and hands the snapshot to its
+// subscribers. Caller holds r.mu.
+func (r *jobRunner) publishLocked(e *jobEntry) {
+	e.job.Seq++
+	for sub := range e.subs {
+		sub.set(e.job)
+	}
+}
+
+// JobProgress is how a running job reports its progress. Git's progress
+// output can be written to it: lines like "Counting objects:  45% (450/1000)"
+// set the phase and object counts.
+type JobProgress struct {
+	runner  *jobRunner
+	entry   *jobEntry
+	partial []byte // Output after the last line break
+}
+
+// Phase records what the job is doing now.
+func (p *JobProgress) Phase(phase string) {
+	p.runner.mu.Lock()
+	defer p.runner.mu.Unlock()
+	if p.entry.job.Phase == phase {
+		return
+	}
+	p.entry.job.Phase = phase
+	p.runner.publishLocked(p.entry)
+}
+
+var (
+	progressCountLine = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z]+(?: [a-z]+)*):\s+(?:\d+% \((\d+)/(\d+)\)|(\d+))`)
+	progressTotalLine = regexp.MustCompile(`^(?:remote: )?Total (\d+)`)
+)
+
+// Write takes git's progress output. Git redraws a line with "\r", so both
+// "\r" and "\n" end one.
+func (p *JobProgress) Write(b []byte) (int, error) {
+	p.partial = append(p.partial, b...)
+	for {
+		i := indexLineBreak(p.partial)
+		if i < 0 {
+			break
+		}
+		p.progressLine(string(p.partial[:i]))
+		p.partial = p.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+func indexLineBreak(b []byte) int {
+	for i, c := range b {
+		if c == '\r' || c == '\n' {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *JobProgress) progressLine(line string) {
+	var phase string
+	var objects, total int
+	if m := progressCountLine.FindStringSubmatch(line); m != nil {
+		phase = m[1]
+		if m[2] != "" {
+			objects, _ = strconv.Atoi(m[2])
+			total, _ = strconv.Atoi(m[3])
+		} else {
+			total, _ = strconv.Atoi(m[4])
+		}
+	} else if m := progressTotalLine.FindStringSubmatch(line); m != nil {
+		total, _ = strconv.Atoi(m[1])
+		objects = total
+	} else {
+		return
+	}
+
+	p.runner.mu.Lock()
+	defer p.runner.mu.Unlock()
+	job := &p.entry.job
+	if phase != "" {
+		job.Phase = phase
+	}
+	job.Objects = objects
+	job.TotalObjects = total
+	p.runner.publishLocked(p.entry)
+}
+
+// JobSubscriber receives the changes of one job. Changes it has not read yet
+// are replaced by newer ones, so it always catches up to the latest snapshot.
+type JobSubscriber struct {
+	runner *jobRunner
+	entry  *jobEntry
+	ready  chan struct{} // Signalled (capacity 1) when a snapshot is pending
+
+	mu      sync.Mutex
+	pending *Job
+}
+
+// SubscribeJob starts following a job. Its current snapshot is pending right
+// away. Call Close when done.
+func (sm *SessionManager) SubscribeJob(id string) (*JobSubscriber, error) {
+	r := &sm.jobRunner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job '%s' not found", id)
+	}
+	sub := &JobSubscriber{runner: r, entry: e, ready: make(chan struct{}, 1)}
+	e.subs[sub] = struct{}{}
+	sub.set(e.job)
+	return sub, nil
+}
+
+func (s *JobSubscriber) set(job Job) {
+	s.mu.Lock()
+	s.pending = &job
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when Next has a snapshot.
+func (s *JobSubscriber) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Next returns the latest snapshot not read yet.
+func (s *JobSubscriber) Next() (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return Job{}, false
+	}
+	job := *s.pending
+	s.pending = nil
+	return job, true
+}
+
+// Close stops the subscription.
+func (s *JobSubscriber) Close() {
+	s.runner.mu.Lock()
+	defer s.runner.mu.Unlock()
+	delete(s.entry.subs, s)
+}
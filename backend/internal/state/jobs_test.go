@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitJob reads the subscriber until the job reaches state.
+func waitJob(t *testing.T, sub *JobSubscriber, state string) Job {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-sub.Ready():
+			if job, ok := sub.Next(); ok && job.State == state {
+				return job
+			}
+		case <-timeout:
+			t.Fatalf("job never reached %s", state)
+		}
+	}
+}
+
+func TestJobs_RunInOrderAndCancel(t *testing.T) {
+	sm := NewSessionManager()
+	release := make(chan struct{})
+	first := sm.SubmitJob(JobIngest, "first", func(ctx context.Context, p *JobProgress) error {
+		p.Phase(IngestCloning)
+		_, _ = p.Write([]byte("Counting objects:  50% (5/10)\rCounting objects: 100% (10/10), done.\nCompressing objects:  25% (1/4)"))
+		<-release
+		return nil
+	})
+	second := sm.SubmitJob(JobIngest, "second", func(ctx context.Context, p *JobProgress) error {
+		return errors.New("repository not found")
+	})
+	third := sm.SubmitJob(JobIngest, "third", func(ctx context.Context, p *JobProgress) error {
+		t.Error("a cancelled queued job must not run")
+		return nil
+	})
+
+	sub, err := sm.SubscribeJob(first.ID)
+	require.NoError(t, err)
+	defer sub.Close()
+	running := waitJob(t, sub, JobRunning)
+	assert.NotNil(t, running.StartedAt)
+
+	// Only one job runs at a time
+	queued, _ := sm.GetJob(second.ID)
+	assert.Equal(t, JobQueued, queued.State)
+	require.NoError(t, sm.CancelJob(third.ID))
+	cancelled, _ := sm.GetJob(third.ID)
+	assert.Equal(t, JobCancelled, cancelled.State)
+	assert.Error(t, sm.CancelJob(third.ID), "a finished job cannot be cancelled")
+
+	// Progress lines set the phase and counts; a redrawn line ends with \r
+	require.Eventually(t, func() bool {
+		job, _ := sm.GetJob(first.ID)
+		return job.Phase == "Counting objects" && job.Objects == 10 && job.TotalObjects == 10
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	done := waitJob(t, sub, JobSucceeded)
+	assert.NotNil(t, done.FinishedAt)
+
+	secondSub, err := sm.SubscribeJob(second.ID)
+	require.NoError(t, err)
+	defer secondSub.Close()
+	failed := waitJob(t, secondSub, JobFailed)
+	assert.Equal(t, "repository not found", failed.Error)
+
+	var ids []string
+	for _, job := range sm.Jobs() {
+		ids = append(ids, job.ID)
+	}
+	assert.Equal(t, []string{first.ID, second.ID, third.ID}, ids)
+}
+
+func TestJobs_CancelRunning(t *testing.T) {
+	sm := NewSessionManager()
+	job := sm.SubmitJob(JobIngest, "slow", func(ctx context.Context, p *JobProgress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	sub, err := sm.SubscribeJob(job.ID)
+	require.NoError(t, err)
+	defer sub.Close()
+	waitJob(t, sub, JobRunning)
+
+	require.NoError(t, sm.CancelJob(job.ID))
+	cancelled := waitJob(t, sub, JobCancelled)
+	assert.Empty(t, cancelled.Error)
+
+	_, err = sm.SubscribeJob("job-missing")
+	assert.Error(t, err)
+}
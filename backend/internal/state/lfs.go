@@ -0,0 +1,233 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// LFS simulation
+//
+// Large files are stored in Git as small pointer blobs while the real content
+// lives in a separate object store. Each session keeps its own LFS cache, and
+// SessionManager.LFSServer plays the role of the LFS server that push uploads to
+// and checkouts download from.
+
+const (
+	// LFSPointerVersion is the spec line every pointer file starts with.
+	LFSPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	// LFSSizeThreshold is the size above which files are stored via LFS even without a .gitattributes rule.
+	LFSSizeThreshold = 64 * 1024
+)
+
+// LFSPointer identifies large-file content by its SHA-256 and size.
+type LFSPointer struct {
+	OID  string
+	Size int64
+}
+
+// NewLFSPointer builds the pointer for content.
+func NewLFSPointer(content []byte) LFSPointer {
+	sum := sha256.Sum256(content)
+	return LFSPointer{OID: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+}
+
+// String renders the pointer file exactly as git-lfs writes it.
+func (p LFSPointer) String() string {
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", LFSPointerVersion, p.OID, p.Size)
+}
+
+// ParseLFSPointer reports whether data is a pointer file and decodes it.
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	if !bytes.HasPrefix(data, []byte(LFSPointerVersion+"\n")) || len(data) > 1024 {
+		return LFSPointer{}, false
+	}
+	var p LFSPointer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "oid sha256:"):
+			p.OID = strings.TrimPrefix(line, "oid sha256:")
+		case strings.HasPrefix(line, "size "):
+			size, err := strconv.ParseInt(strings.TrimPrefix(line, "size "), 10, 64)
+			if err != nil {
+				return LFSPointer{}, false
+			}
+			p.Size = size
+		}
+	}
+	return p, p.OID != ""
+}
+
+// StoreLFSObject saves content in the session's LFS cache and returns its pointer.
+func (s *Session) StoreLFSObject(content []byte) LFSPointer {
+	p := NewLFSPointer(content)
+	if s.LFSObjects == nil {
+		s.LFSObjects = make(map[string][]byte)
+	}
+	s.LFSObjects[p.OID] = content
+	return p
+}
+
+// LFSObject returns the content for oid, downloading it from the simulated
+// LFS server into the session cache when it is not available locally.
+func (s *Session) LFSObject(oid string) ([]byte, bool) {
+	if content, ok := s.LFSObjects[oid]; ok {
+		return content, true
+	}
+	if s.Manager == nil {
+		return nil, false
+	}
+
+	s.Manager.mu.RLock()
+	content, ok := s.Manager.LFSServer[oid]
+	s.Manager.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if s.LFSObjects == nil {
+		s.LFSObjects = make(map[string][]byte)
+	}
+	s.LFSObjects[oid] = content
+	return content, true
+}
+
+// UploadLFSObjects copies every object in the session cache to the simulated
+// LFS server and returns how many were not there yet.
+func (s *Session) UploadLFSObjects() int {
+	if s.Manager == nil || len(s.LFSObjects) == 0 {
+		return 0
+	}
+
+	s.Manager.mu.Lock()
+	defer s.Manager.mu.Unlock()
+
+	uploaded := 0
+	for oid, content := range s.LFSObjects {
+		if _, ok := s.Manager.LFSServer[oid]; !ok {
+			s.Manager.LFSServer[oid] = content
+			uploaded++
+		}
+	}
+	return uploaded
+}
+
+// HasLFSObjects reports whether anything has been uploaded to the simulated LFS server.
+func (sm *SessionManager) HasLFSObjects() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.LFSServer) > 0
+}
+
+// LFSPatterns returns the patterns marked "filter=lfs" in the worktree's .gitattributes.
+func LFSPatterns(fs billy.Filesystem) []string {
+	f, err := fs.Open(".gitattributes")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				patterns = append(patterns, fields[0])
+				break
+			}
+		}
+	}
+	return patterns
+}
+
+// MatchesLFSPattern reports whether file matches any .gitattributes LFS pattern.
+// Patterns without a slash match the base name, as in Git.
+func MatchesLFSPattern(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if matchesAttributePattern(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// LFSAwareStatus returns the worktree status, treating files whose index entry is
+// an LFS pointer to the current worktree content as unmodified (what git-lfs's
+// clean filter achieves in real Git). Files differing only in line endings
+// that .gitattributes normalizes are unmodified too.
+func LFSAwareStatus(repo *gogit.Repository, w *gogit.Worktree) (gogit.Status, error) {
+	status, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+	dropIgnored(status, LoadIgnoreRules(w.Filesystem))
+	dropNormalized(repo, w, status)
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return status, nil
+	}
+
+	for _, e := range idx.Entries {
+		fs, ok := status[e.Name]
+		if !ok || fs.Worktree != gogit.Modified {
+			continue
+		}
+		blob, err := repo.BlobObject(e.Hash)
+		if err != nil || blob.Size > 1024 {
+			continue
+		}
+		pointer, ok := readLFSPointerBlob(blob.Reader)
+		if !ok {
+			continue
+		}
+		content, err := readFile(w.Filesystem, e.Name)
+		if err != nil {
+			continue
+		}
+		if NewLFSPointer(content) != pointer {
+			continue
+		}
+		if fs.Staging == gogit.Unmodified {
+			delete(status, e.Name)
+		} else {
+			fs.Worktree = gogit.Unmodified
+		}
+	}
+	return status, nil
+}
+
+func readLFSPointerBlob(open func() (io.ReadCloser, error)) (LFSPointer, bool) {
+	r, err := open()
+	if err != nil {
+		return LFSPointer{}, false
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return LFSPointer{}, false
+	}
+	return ParseLFSPointer(data)
+}
+
+func readFile(fs billy.Filesystem, name string) ([]byte, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
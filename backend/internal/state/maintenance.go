@@ -0,0 +1,202 @@
+package state
+
+import (
+	"sort"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// gcPendingThreshold is the number of unreachable objects at which the
+// report recommends a gc. Real git uses gc.auto=6700 loose objects; sessions
+// here are tiny, so a much smaller number keeps the lesson visible.
+const gcPendingThreshold = 50
+
+// MaintenanceReport describes the engine internals behind a session:
+// where its objects live, what is waiting for gc, and what is cached.
+type MaintenanceReport struct {
+	SessionID   string                  `json:"sessionId"`
+	CreatedAt   time.Time               `json:"createdAt"`
+	GeneratedAt time.Time               `json:"generatedAt"`
+	Repos       []RepoMaintenanceReport `json:"repos"`
+	Caches      CacheStats              `json:"caches"`
+	Snapshots   SnapshotStats           `json:"snapshots"`
+}
+
+// RepoMaintenanceReport summarizes the object storage of one repository.
+type RepoMaintenanceReport struct {
+	Path               string `json:"path"`
+	Storage            string `json:"storage"` // "memory", "filesystem" or "hybrid"
+	Objects            int    `json:"objects"`
+	Packs              int    `json:"packs"`
+	Refs               int    `json:"refs"`
+	UnreachableObjects int    `json:"unreachableObjects"` // Objects a gc would prune
+	GCRecommended      bool   `json:"gcRecommended"`
+}
+
+// CacheStats summarizes the in-memory caches kept for a session.
+type CacheStats struct {
+	FileCacheEntries int   `json:"fileCacheEntries"`
+	FileCacheAgeMs   int64 `json:"fileCacheAgeMs"`
+	FileCacheDirty   bool  `json:"fileCacheDirty"`
+	LFSObjects       int   `json:"lfsObjects"`
+	LFSBytes         int64 `json:"lfsBytes"`
+	ReflogEntries    int   `json:"reflogEntries"`
+	LineageLinks     int   `json:"lineageLinks"`
+	SearchIndexed    int   `json:"searchIndexed"` // Commits in the search index
+}
+
+// SnapshotStats reports on-disk persistence of the session.
+type SnapshotStats struct {
+	PersistenceEnabled bool       `json:"persistenceEnabled"`
+	Saves              int        `json:"saves"`
+	LastSavedAt        *time.Time `json:"lastSavedAt,omitempty"`
+}
+
+// BuildMaintenanceReport inspects the session. The caller must hold the session lock.
+func BuildMaintenanceReport(s *Session) MaintenanceReport {
+	report := MaintenanceReport{
+		SessionID:   s.ID,
+		CreatedAt:   s.CreatedAt,
+		GeneratedAt: time.Now(),
+		Repos:       []RepoMaintenanceReport{},
+	}
+
+	paths := make([]string, 0, len(s.Repos))
+	for path := range s.Repos {
+		if _, linked := s.Worktrees[path]; !linked {
+			paths = append(paths, path) // Linked worktrees are counted with their repository
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		report.Repos = append(report.Repos, s.repoMaintenance(path, s.Repos[path]))
+	}
+
+	if s.FileCache != nil {
+		report.Caches.FileCacheEntries, report.Caches.FileCacheAgeMs, report.Caches.FileCacheDirty = s.FileCache.Stats()
+	}
+	for _, content := range s.LFSObjects {
+		report.Caches.LFSObjects++
+		report.Caches.LFSBytes += int64(len(content))
+	}
+	report.Caches.ReflogEntries = len(s.Reflog)
+	report.Caches.LineageLinks = len(s.Lineage)
+	if s.SearchIndex != nil {
+		report.Caches.SearchIndexed = s.SearchIndex.Stats()
+	}
+
+	if s.Manager != nil {
+		report.Snapshots = s.Manager.snapshotStats(s.ID)
+	}
+	return report
+}
+
+func (s *Session) repoMaintenance(path string, repo *gogit.Repository) RepoMaintenanceReport {
+	r := RepoMaintenanceReport{Path: path, Storage: storageBackendName(repo)}
+
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(*plumbing.Reference) error {
+			r.Refs++
+			return nil
+		})
+	}
+	if packer, ok := repo.Storer.(interface {
+		ObjectPacks() ([]plumbing.Hash, error)
+	}); ok {
+		if packs, err := packer.ObjectPacks(); err == nil {
+			r.Packs = len(packs)
+		}
+	}
+
+	// Hybrid repos share objects with the remote; counting them would be misleading.
+	if r.Storage == "hybrid" {
+		return r
+	}
+
+	r.Objects = CountObjects(repo).Count
+	r.UnreachableObjects = len(s.unreachableObjects(path, repo))
+	r.GCRecommended = r.UnreachableObjects >= gcPendingThreshold
+	return r
+}
+
+func storageBackendName(repo *gogit.Repository) string {
+	switch repo.Storer.(type) {
+	case *memory.Storage:
+		return "memory"
+	case *filesystem.Storage:
+		return "filesystem"
+	case localStorerProvider:
+		return "hybrid"
+	default:
+		return "custom"
+	}
+}
+
+// reachableObjects collects every object reachable from refs, HEAD, the index
+// and the given reflog entries, which gc treats as roots too.
+func reachableObjects(repo *gogit.Repository, reflog []plumbing.Hash) map[plumbing.Hash]struct{} {
+	seen := make(map[plumbing.Hash]struct{})
+
+	queue := append([]plumbing.Hash(nil), reflog...)
+	if head, err := repo.Head(); err == nil {
+		queue = append(queue, head.Hash())
+	}
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() == plumbing.HashReference {
+				queue = append(queue, ref.Hash())
+			}
+			return nil
+		})
+	}
+	if idx, err := repo.Storer.Index(); err == nil {
+		for _, e := range idx.Entries {
+			seen[e.Hash] = struct{}{}
+		}
+	}
+
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+
+		obj, err := repo.Object(plumbing.AnyObject, h)
+		if err != nil {
+			continue
+		}
+		switch o := obj.(type) {
+		case *object.Tag:
+			queue = append(queue, o.Target)
+		case *object.Commit:
+			queue = append(queue, o.ParentHashes...)
+			markTree(repo, o.TreeHash, seen)
+		}
+	}
+	return seen
+}
+
+func markTree(repo *gogit.Repository, hash plumbing.Hash, seen map[plumbing.Hash]struct{}) {
+	if _, ok := seen[hash]; ok {
+		return
+	}
+	seen[hash] = struct{}{}
+	tree, err := repo.TreeObject(hash)
+	if err != nil {
+		return
+	}
+	for _, e := range tree.Entries {
+		if e.Mode.IsFile() {
+			seen[e.Hash] = struct{}{}
+			continue
+		}
+		markTree(repo, e.Hash, seen)
+	}
+}
@@ -0,0 +1,39 @@
+package state
+
+// MergeState records a merge that stopped on conflicts. It plays the role of
+// MERGE_MSG and keeps the MERGE_HEAD ref of its repository set: while it is
+// set, "git commit" (or "git merge --continue") concludes the merge and
+// "git merge --abort" rolls it back.
+type MergeState struct {
+	Repo      string   `json:"repo"`      // Repository path the merge runs in
+	MergeHead string   `json:"mergeHead"` // Commit being merged in (MERGE_HEAD)
+	OrigHead  string   `json:"origHead"`  // HEAD before the merge, restored by --abort
+	Message   string   `json:"message"`   // Prepared merge commit message (MERGE_MSG)
+	Conflicts []string `json:"conflicts"` // Paths left with conflict markers
+}
+
+// MergeInProgress returns the unfinished merge of the active repository, or nil.
+func (s *Session) MergeInProgress() *MergeState {
+	if s.Merge == nil || s.Merge.Repo != s.activeRepoPath() {
+		return nil
+	}
+	return s.Merge
+}
+
+// StartMerge records an unfinished merge for the active repository.
+func (s *Session) StartMerge(m *MergeState) {
+	if s.Merge != nil && s.Merge.Repo != s.activeRepoPath() {
+		s.setStateRef(s.Merge.Repo, MergeHead, "")
+	}
+	m.Repo = s.activeRepoPath()
+	s.Merge = m
+	s.setStateRef(m.Repo, MergeHead, m.MergeHead)
+}
+
+// ClearMerge forgets the unfinished merge, after it was committed or aborted.
+func (s *Session) ClearMerge() {
+	if s.Merge != nil {
+		s.setStateRef(s.Merge.Repo, MergeHead, "")
+	}
+	s.Merge = nil
+}
@@ -0,0 +1,291 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Metadata records kept in the SessionManager's Store.
+//
+// Pull requests stay cached in SessionManager.PullRequests, and issues in
+// SessionManager.issues, for fast access but every change is written through
+// to the store, so they survive a restart together with mission progress and
+// the audit log.
+
+const (
+	bucketPullRequests    = "pull_requests"
+	bucketMissionProgress = "mission_progress"
+	bucketAudit           = "audit"
+	bucketMeta            = "meta"
+
+	metaNextPRID = "next_pr_id"
+
+	// auditRetention caps how many audit entries are kept.
+	auditRetention = 5000
+)
+
+// MissionProgress records how far a session got in a mission.
+type MissionProgress struct {
+	SessionID string    `json:"sessionId"`
+	MissionID string    `json:"missionId"`
+	Completed bool      `json:"completed"`
+	Passed    int       `json:"passed"` // Checks passed in the latest attempt
+	Total     int       `json:"total"`
+	Attempts  int       `json:"attempts"`
+	Step      int       `json:"step"`  // Steps completed in the latest attempt
+	Steps     int       `json:"steps"` // Steps the mission declares, 0 for single-stage missions
+	HintsUsed int       `json:"hintsUsed"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	StartedAt   *time.Time `json:"startedAt,omitempty"`   // First start of the mission, see RecordMissionStart
+	CompletedAt *time.Time `json:"completedAt,omitempty"` // First verification that passed every check
+}
+
+// AuditEntry records one command executed in a session.
+type AuditEntry struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"sessionId"`
+	Command   string    `json:"command"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// UseStore switches the manager to store and loads the pull requests and
+// issues kept in it.
+func (sm *SessionManager) UseStore(store Store) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	keys, err := store.List(bucketPullRequests)
+	if err != nil {
+		return err
+	}
+	prs := make([]*PullRequest, 0, len(keys))
+	for _, key := range keys {
+		var pr PullRequest
+		if ok, err := getJSON(store, bucketPullRequests, key, &pr); err != nil {
+			return err
+		} else if ok {
+			prs = append(prs, &pr)
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].ID < prs[j].ID })
+
+	nextID := 1
+	if _, err := getJSON(store, bucketMeta, metaNextPRID, &nextID); err != nil {
+		return err
+	}
+	for _, pr := range prs {
+		if pr.ID >= nextID {
+			nextID = pr.ID + 1
+		}
+	}
+
+	issues, err := loadIssues(store)
+	if err != nil {
+		return err
+	}
+
+	var auditSeq uint64
+	if keys, err := store.List(bucketAudit); err == nil && len(keys) > 0 {
+		auditSeq, _ = strconv.ParseUint(keys[len(keys)-1], 10, 64)
+	}
+
+	sm.Store = store
+	sm.PullRequests = prs
+	sm.NextPRID = nextID
+	sm.issues = issues
+	sm.auditMu.Lock()
+	sm.auditSeq = auditSeq
+	sm.auditMu.Unlock()
+	return nil
+}
+
+// SetPullRequestState changes the state of a pull request ("OPEN", "CLOSED", "MERGED").
+func (sm *SessionManager) SetPullRequestState(id int, state string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, pr := range sm.PullRequests {
+		if pr.ID == id {
+			pr.State = state
+			sm.savePullRequestLocked(pr)
+			return nil
+		}
+	}
+	return fmt.Errorf("pull request %d not found", id)
+}
+
+// savePullRequestLocked writes a pull request through to the store. Caller holds sm.mu.
+func (sm *SessionManager) savePullRequestLocked(pr *PullRequest) {
+	if sm.Store == nil {
+		return
+	}
+	if err := putJSON(sm.Store, bucketPullRequests, prKey(pr.ID), pr); err != nil {
+		slog.Warn("store: failed to save pull request", "pr", pr.ID, "err", err)
+	}
+	if err := putJSON(sm.Store, bucketMeta, metaNextPRID, sm.NextPRID); err != nil {
+		slog.Warn("store: failed to save next pull request ID", "err", err)
+	}
+}
+
+// deletePullRequestLocked removes a pull request from the store. Caller holds sm.mu.
+func (sm *SessionManager) deletePullRequestLocked(id int) {
+	if sm.Store == nil {
+		return
+	}
+	if err := sm.Store.Delete(bucketPullRequests, prKey(id)); err != nil {
+		slog.Warn("store: failed to delete pull request", "pr", id, "err", err)
+	}
+}
+
+// RecordMissionProgress stores the result of a mission verification and returns the updated record.
+func (sm *SessionManager) RecordMissionProgress(sessionID, missionID string, passed, total int) MissionProgress {
+	return sm.updateMissionProgress(sessionID, missionID, func(p *MissionProgress) {
+		p.Passed = passed
+		p.Total = total
+		p.Attempts++
+		if !p.Completed && total > 0 && passed == total {
+			now := time.Now()
+			p.Completed = true
+			p.CompletedAt = &now
+		}
+	})
+}
+
+// RecordMissionSteps stores how many of a mission's steps the latest verification completed.
+func (sm *SessionManager) RecordMissionSteps(sessionID, missionID string, completed, total int) MissionProgress {
+	return sm.updateMissionProgress(sessionID, missionID, func(p *MissionProgress) {
+		p.Step = completed
+		p.Steps = total
+	})
+}
+
+// RecordMissionHint counts a hint shown for a mission and returns the updated record.
+func (sm *SessionManager) RecordMissionHint(sessionID, missionID string) MissionProgress {
+	return sm.updateMissionProgress(sessionID, missionID, func(p *MissionProgress) {
+		p.HintsUsed++
+	})
+}
+
+func (sm *SessionManager) updateMissionProgress(sessionID, missionID string, update func(*MissionProgress)) MissionProgress {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	key := sessionID + "/" + missionID
+	var p MissionProgress
+	if sm.Store != nil {
+		_, _ = getJSON(sm.Store, bucketMissionProgress, key, &p)
+	}
+	p.SessionID = sessionID
+	p.MissionID = missionID
+	update(&p)
+	p.UpdatedAt = time.Now()
+
+	if sm.Store != nil {
+		if err := putJSON(sm.Store, bucketMissionProgress, key, p); err != nil {
+			slog.Warn("store: failed to save mission progress", "key", key, "err", err)
+		}
+	}
+	return p
+}
+
+// MissionProgressFor returns the progress recorded for a session, ordered by mission ID.
+func (sm *SessionManager) MissionProgressFor(sessionID string) []MissionProgress {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	result := []MissionProgress{}
+	if sm.Store == nil {
+		return result
+	}
+	keys, err := sm.Store.List(bucketMissionProgress)
+	if err != nil {
+		return result
+	}
+	for _, key := range keys {
+		var p MissionProgress
+		if ok, _ := getJSON(sm.Store, bucketMissionProgress, key, &p); ok && p.SessionID == sessionID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// RecordAudit appends a command to the audit log. Every command is audited,
+// so the write only serializes with other audit writes, not on sm.mu.
+func (sm *SessionManager) RecordAudit(sessionID, command string, cmdErr error) {
+	store := sm.metadataStore()
+	if store == nil {
+		return
+	}
+
+	sm.auditMu.Lock()
+	defer sm.auditMu.Unlock()
+	sm.auditSeq++
+	entry := AuditEntry{Seq: sm.auditSeq, Time: time.Now(), SessionID: sessionID, Command: command}
+	if cmdErr != nil {
+		entry.Error = cmdErr.Error()
+	}
+	if err := putJSON(store, bucketAudit, auditKey(entry.Seq), entry); err != nil {
+		slog.Warn("store: failed to append audit entry", "err", err)
+	}
+	if entry.Seq > auditRetention {
+		_ = store.Delete(bucketAudit, auditKey(entry.Seq-auditRetention))
+	}
+}
+
+// metadataStore returns the store the manager currently uses, or nil.
+func (sm *SessionManager) metadataStore() Store {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.Store
+}
+
+// AuditLog returns up to limit of the most recent audit entries for a session, oldest first.
+// An empty sessionID returns entries for all sessions.
+func (sm *SessionManager) AuditLog(sessionID string, limit int) []AuditEntry {
+	store := sm.metadataStore()
+	result := []AuditEntry{}
+	if store == nil {
+		return result
+	}
+	keys, err := store.List(bucketAudit)
+	if err != nil {
+		return result
+	}
+	for i := len(keys) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		var e AuditEntry
+		if ok, _ := getJSON(store, bucketAudit, keys[i], &e); ok && (sessionID == "" || e.SessionID == sessionID) {
+			result = append(result, e)
+		}
+	}
+	// Collected newest first; flip to chronological order
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// Keys are zero-padded so the store's lexical key order matches numeric order.
+func prKey(id int) string        { return fmt.Sprintf("%010d", id) }
+func auditKey(seq uint64) string { return fmt.Sprintf("%020d", seq) }
+
+func putJSON(store Store, bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return store.Put(bucket, key, data)
+}
+
+func getJSON(store Store, bucket, key string, v interface{}) (bool, error) {
+	data, ok, err := store.Get(bucket, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
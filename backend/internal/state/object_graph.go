@@ -0,0 +1,166 @@
+package state
+
+import (
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Object graph
+//
+// BuildGraphState only shows commits. The object graph exposes what Git
+// actually stores: commits pointing at trees and parents, trees pointing at
+// blobs and subtrees, and tags pointing at their target. It backs the
+// "Git is a DAG of objects" lesson.
+
+// DefaultObjectGraphLimit caps the objects returned when no limit is given.
+const DefaultObjectGraphLimit = 2000
+
+// ObjectGraph is the object database of a repository as nodes and edges.
+type ObjectGraph struct {
+	Objects   []GraphObject     `json:"objects"`
+	Edges     []ObjectEdge      `json:"edges"`
+	Refs      map[string]string `json:"refs"`      // Ref name (and HEAD) -> object it points at
+	Total     int               `json:"total"`     // Objects in the repository, including ones left out by the limit
+	Truncated bool              `json:"truncated"` // The limit left objects out
+}
+
+// GraphObject is one object of the object graph.
+type GraphObject struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"` // "commit", "tree", "blob" or "tag"
+	Size      int64  `json:"size"`
+	Label     string `json:"label,omitempty"` // Commit subject or tag name
+	Reachable bool   `json:"reachable"`       // Reached from a ref, HEAD or the index
+}
+
+// ObjectEdge links an object to an object it points at.
+type ObjectEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`           // "parent", "tree", "entry" or "target"
+	Name string `json:"name,omitempty"` // File or directory name of tree entries
+}
+
+// BuildObjectGraph walks the objects of repo from HEAD, its refs and its index,
+// then adds the objects it stores that nothing reaches. At most limit objects
+// are returned (0 means DefaultObjectGraphLimit); edges to objects left out
+// are dropped.
+func BuildObjectGraph(repo *gogit.Repository, limit int) ObjectGraph {
+	if limit <= 0 {
+		limit = DefaultObjectGraphLimit
+	}
+	graph := ObjectGraph{Objects: []GraphObject{}, Edges: []ObjectEdge{}, Refs: make(map[string]string)}
+
+	var queue []plumbing.Hash
+	if head, err := repo.Head(); err == nil {
+		graph.Refs["HEAD"] = head.Hash().String()
+		queue = append(queue, head.Hash())
+	}
+	if refs, err := repo.References(); err == nil {
+		var names []string
+		hashes := make(map[string]plumbing.Hash)
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), "refs/") {
+				names = append(names, ref.Name().String())
+				hashes[ref.Name().String()] = ref.Hash()
+			}
+			return nil
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			graph.Refs[name] = hashes[name].String()
+			queue = append(queue, hashes[name])
+		}
+	}
+	included := make(map[plumbing.Hash]struct{})
+	var links []fsckLink
+	add := func(obj plumbing.EncodedObject, reachable bool) []fsckLink {
+		objLinks := objectLinks(repo, obj)
+		graph.Total++
+		if len(graph.Objects) >= limit {
+			graph.Truncated = true
+			return objLinks
+		}
+		included[obj.Hash()] = struct{}{}
+		graph.Objects = append(graph.Objects, GraphObject{
+			ID:        obj.Hash().String(),
+			Type:      obj.Type().String(),
+			Size:      obj.Size(),
+			Label:     objectLabel(repo, obj),
+			Reachable: reachable,
+		})
+		links = append(links, objLinks...)
+		return objLinks
+	}
+
+	seen := make(map[plumbing.Hash]struct{})
+	walk := func(queue []plumbing.Hash) {
+		for len(queue) > 0 {
+			h := queue[0]
+			queue = queue[1:]
+			if _, ok := seen[h]; ok || h.IsZero() {
+				continue
+			}
+			seen[h] = struct{}{}
+			obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, h)
+			if err != nil {
+				continue
+			}
+			for _, link := range add(obj, true) {
+				queue = append(queue, link.to)
+			}
+		}
+	}
+	walk(queue)
+
+	// Staged blobs not committed yet
+	if idx, err := repo.Storer.Index(); err == nil {
+		var staged []plumbing.Hash
+		for _, e := range idx.Entries {
+			staged = append(staged, e.Hash)
+		}
+		walk(staged)
+	}
+
+	var unreachable []plumbing.EncodedObject
+	if iter, err := localObjectStorer(repo).IterEncodedObjects(plumbing.AnyObject); err == nil {
+		_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+			if _, ok := seen[obj.Hash()]; !ok {
+				unreachable = append(unreachable, obj)
+			}
+			return nil
+		})
+	}
+	sort.Slice(unreachable, func(i, j int) bool { return unreachable[i].Hash().String() < unreachable[j].Hash().String() })
+	for _, obj := range unreachable {
+		add(obj, false)
+	}
+
+	for _, link := range links {
+		if _, ok := included[link.to]; !ok {
+			continue
+		}
+		graph.Edges = append(graph.Edges, ObjectEdge{From: link.from.Hash, To: link.to.String(), Kind: link.kind, Name: link.name})
+	}
+	return graph
+}
+
+// objectLabel is the commit subject or tag name shown on a node.
+func objectLabel(repo *gogit.Repository, obj plumbing.EncodedObject) string {
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		if c, err := object.DecodeCommit(repo.Storer, obj); err == nil {
+			subject, _, _ := strings.Cut(c.Message, "\n")
+			return subject
+		}
+	case plumbing.TagObject:
+		if t, err := object.DecodeTag(repo.Storer, obj); err == nil {
+			return t.Name
+		}
+	}
+	return ""
+}
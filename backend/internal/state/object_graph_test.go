@@ -0,0 +1,70 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildObjectGraph(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+	sig := &object.Signature{Name: "User", Email: "user@example.com"}
+
+	require.NoError(t, util.WriteFile(w.Filesystem, "docs/a.txt", []byte("hello\n"), 0644))
+	_, err = w.Add("docs/a.txt")
+	require.NoError(t, err)
+	first, err := w.Commit("first\n\nbody", &gogit.CommitOptions{Author: sig})
+	require.NoError(t, err)
+	second, err := w.Commit("second", &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	require.NoError(t, err)
+	tag, err := repo.CreateTag("v1", second, &gogit.CreateTagOptions{Tagger: sig, Message: "v1"})
+	require.NoError(t, err)
+
+	graph := BuildObjectGraph(repo, 0)
+	// Two commits, one root tree, one subtree, one blob and the tag
+	assert.Equal(t, 6, graph.Total)
+	assert.Len(t, graph.Objects, 6)
+	assert.False(t, graph.Truncated)
+	assert.Equal(t, second.String(), graph.Refs["HEAD"])
+	assert.Equal(t, tag.Hash().String(), graph.Refs["refs/tags/v1"])
+
+	byID := make(map[string]GraphObject)
+	for _, o := range graph.Objects {
+		byID[o.ID] = o
+		assert.True(t, o.Reachable)
+	}
+	assert.Equal(t, "first", byID[first.String()].Label)
+	assert.Equal(t, "tag", byID[tag.Hash().String()].Type)
+
+	commit, err := repo.CommitObject(first)
+	require.NoError(t, err)
+	assert.Contains(t, graph.Edges, ObjectEdge{From: second.String(), To: first.String(), Kind: "parent"})
+	assert.Contains(t, graph.Edges, ObjectEdge{From: first.String(), To: commit.TreeHash.String(), Kind: "tree"})
+	assert.Contains(t, graph.Edges, ObjectEdge{From: tag.Hash().String(), To: second.String(), Kind: "target"})
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	assert.Contains(t, graph.Edges, ObjectEdge{From: tree.Hash.String(), To: tree.Entries[0].Hash.String(), Kind: "entry", Name: "docs"})
+
+	// Unreachable objects come last; limited graphs drop edges to left-out objects
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), first)))
+	require.NoError(t, repo.DeleteTag("v1"))
+	graph = BuildObjectGraph(repo, 2)
+	assert.True(t, graph.Truncated)
+	assert.Equal(t, 6, graph.Total)
+	require.Len(t, graph.Objects, 2)
+	assert.Equal(t, first.String(), graph.Objects[0].ID)
+	assert.Equal(t, []ObjectEdge{{From: first.String(), To: commit.TreeHash.String(), Kind: "tree"}}, graph.Edges)
+
+	graph = BuildObjectGraph(repo, 0)
+	last := graph.Objects[len(graph.Objects)-1]
+	assert.False(t, last.Reachable)
+}
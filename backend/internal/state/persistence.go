@@ -0,0 +1,535 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Session persistence
+//
+// Sessions normally live only in memory. When persistence is enabled
+// (GITGYM_PERSIST_SESSIONS=true), each session is snapshotted to
+// <dir>/<session-id>/ shortly after its commands (see ScheduleSave) and
+// rehydrated on startup:
+//
+//	meta.json   - current dir, reflog and other session metadata
+//	files/      - the session filesystem (worktrees included)
+//	repos/      - one on-disk git storage per repository
+
+// PersistSessionsEnv is the environment variable that turns session persistence on.
+const PersistSessionsEnv = "GITGYM_PERSIST_SESSIONS"
+
+// persistedSession is the JSON form of the session metadata.
+type persistedSession struct {
+	ID            string                  `json:"id"`
+	CurrentDir    string                  `json:"currentDir"`
+	CreatedAt     time.Time               `json:"createdAt"`
+	Reflog        []ReflogEntry           `json:"reflog"`
+	Repos         []string                `json:"repos"`
+	BranchPolicy  *BranchPolicy           `json:"branchPolicy,omitempty"`
+	CommandPolicy *CommandPolicy          `json:"commandPolicy,omitempty"`
+	User          *UserIdentity           `json:"user,omitempty"`
+	Lineage       map[string]LineageLink  `json:"lineage,omitempty"`
+	LFSObjects    map[string][]byte       `json:"lfsObjects,omitempty"`
+	CherryPick    *CherryPickState        `json:"cherryPick,omitempty"`
+	Rebase        *RebaseState            `json:"rebase,omitempty"`
+	Merge         *MergeState             `json:"merge,omitempty"`
+	Am            *AmState                `json:"am,omitempty"`
+	Rerere        map[string]*RerereCache `json:"rerere,omitempty"`
+	Locale        string                  `json:"locale,omitempty"`
+
+	Worktrees map[string]persistedWorktree `json:"worktrees,omitempty"`
+}
+
+// persistedWorktree is a linked worktree: the repository it shares, its own HEAD and index.
+type persistedWorktree struct {
+	Main  string `json:"main"`
+	Head  string `json:"head"` // "ref: refs/heads/<name>" or a commit hash
+	Index []byte `json:"index,omitempty"`
+}
+
+// EnablePersistence makes the manager snapshot sessions under dir.
+func (sm *SessionManager) EnablePersistence(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	sm.mu.Lock()
+	sm.PersistDir = dir
+	sm.mu.Unlock()
+	return nil
+}
+
+// persistDelay is how long ScheduleSave waits before it writes a snapshot,
+// so a burst of commands is saved once.
+var persistDelay = 2 * time.Second
+
+// persister coalesces the snapshots of a SessionManager. The zero value is ready to use.
+type persister struct {
+	mu      sync.Mutex
+	pending map[string]*Session // Sessions changed since their last snapshot, keyed by ID
+	timer   *time.Timer         // Fires flushPendingSaves; nil when nothing is pending
+	writeMu sync.Mutex          // Serializes writes and removals of snapshot directories
+}
+
+// sessionSnapshot is the state of a session to persist, copied in memory so
+// the session lock is not held while it is written to disk.
+type sessionSnapshot struct {
+	id    string
+	meta  []byte
+	files billy.Filesystem
+	repos map[string]storage.Storer // Keyed by repository path
+}
+
+// ScheduleSave saves the session after persistDelay; further calls before
+// then are coalesced into that one save. It is a no-op when persistence is
+// disabled. Errors are logged, as nobody waits for the save.
+func (sm *SessionManager) ScheduleSave(s *Session) {
+	sm.mu.RLock()
+	dir := sm.PersistDir
+	sm.mu.RUnlock()
+	if dir == "" {
+		return
+	}
+
+	p := &sm.persist
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[string]*Session)
+	}
+	p.pending[s.ID] = s
+	if p.timer == nil {
+		p.timer = time.AfterFunc(persistDelay, sm.flushPendingSaves)
+	}
+}
+
+// flushPendingSaves saves the sessions scheduled by ScheduleSave.
+func (sm *SessionManager) flushPendingSaves() {
+	for _, s := range sm.takePendingSaves() {
+		if err := sm.SaveSession(s); err != nil {
+			slog.Warn("failed to persist session", "session", s.ID, "err", err)
+		}
+	}
+}
+
+// takePendingSaves returns the scheduled sessions and forgets them.
+func (sm *SessionManager) takePendingSaves() []*Session {
+	p := &sm.persist
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	sessions := make([]*Session, 0, len(p.pending))
+	for _, s := range p.pending {
+		sessions = append(sessions, s)
+	}
+	p.pending = nil
+	return sessions
+}
+
+// SaveSession writes a snapshot of the session to disk right away. It is a
+// no-op when persistence is disabled. The session's read lock is only held
+// while its state is copied in memory.
+func (sm *SessionManager) SaveSession(s *Session) error {
+	sm.mu.RLock()
+	dir := sm.PersistDir
+	sm.mu.RUnlock()
+	if dir == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	snap, err := s.captureSnapshot()
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	sm.persist.writeMu.Lock()
+	defer sm.persist.writeMu.Unlock()
+	sm.mu.RLock()
+	current := sm.sessions[s.ID]
+	sm.mu.RUnlock()
+	if current != s {
+		// Deleted (or replaced) since: its snapshot must not come back
+		return nil
+	}
+	if err := writeSnapshot(dir, snap); err != nil {
+		return err
+	}
+
+	sm.mu.Lock()
+	if sm.snapshots == nil {
+		sm.snapshots = make(map[string]SnapshotStats)
+	}
+	stats := sm.snapshots[s.ID]
+	now := time.Now()
+	stats.PersistenceEnabled = true
+	stats.Saves++
+	stats.LastSavedAt = &now
+	sm.snapshots[s.ID] = stats
+	sm.mu.Unlock()
+	return nil
+}
+
+// captureSnapshot copies what SaveSession persists of s: files and
+// repositories into memory (objects are shared, not copied) and the
+// metadata as JSON. Caller holds at least the session's read lock.
+func (s *Session) captureSnapshot() (*sessionSnapshot, error) {
+	files := memfs.New()
+	if err := copyBillyDir(s.Filesystem, "/", files, "/"); err != nil {
+		return nil, fmt.Errorf("failed to save files: %w", err)
+	}
+
+	meta := persistedSession{
+		ID:            s.ID,
+		CurrentDir:    s.CurrentDir,
+		CreatedAt:     s.CreatedAt,
+		Reflog:        s.Reflog,
+		BranchPolicy:  s.BranchPolicy,
+		CommandPolicy: s.CommandPolicy,
+		User:          s.User,
+		Lineage:       s.Lineage,
+		LFSObjects:    s.LFSObjects,
+		CherryPick:    s.CherryPick,
+		Rebase:        s.Rebase,
+		Merge:         s.Merge,
+		Am:            s.Am,
+		Rerere:        s.Rerere,
+		Locale:        s.Locale,
+	}
+	heads, indexes := s.worktreeState()
+	for path, main := range s.Worktrees {
+		if head, ok := heads[path]; ok {
+			if meta.Worktrees == nil {
+				meta.Worktrees = make(map[string]persistedWorktree)
+			}
+			meta.Worktrees[path] = persistedWorktree{Main: main, Head: head.Strings()[1], Index: indexes[path]}
+		}
+	}
+	snap := &sessionSnapshot{id: s.ID, files: files, repos: make(map[string]storage.Storer, len(s.Repos))}
+	for path, repo := range s.Repos {
+		if _, linked := s.Worktrees[path]; linked {
+			continue
+		}
+		st := memory.NewStorage()
+		if err := copyStorage(repo.Storer, st); err != nil {
+			return nil, fmt.Errorf("failed to save repository '%s': %w", path, err)
+		}
+		snap.repos[path] = st
+		meta.Repos = append(meta.Repos, path)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	snap.meta = data
+	return snap, nil
+}
+
+// snapshotDir returns the directory of the snapshot of session id under dir.
+func snapshotDir(dir, id string) (string, error) {
+	if !validSessionID(id) {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(dir, id), nil
+}
+
+// writeSnapshot writes snap under dir. Caller holds persist.writeMu.
+func writeSnapshot(dir string, snap *sessionSnapshot) error {
+	target, err := snapshotDir(dir, snap.id)
+	if err != nil {
+		return err
+	}
+	// Write into a temporary directory first so a crash never leaves a half-written snapshot.
+	tmp := target + ".tmp"
+	_ = os.RemoveAll(tmp)
+
+	if err := copyBillyDir(snap.files, "/", osfs.New(filepath.Join(tmp, "files")), "/"); err != nil {
+		return fmt.Errorf("failed to save files: %w", err)
+	}
+	for path, st := range snap.repos {
+		dst := filesystem.NewStorage(osfs.New(filepath.Join(tmp, "repos", url.PathEscape(path))), cache.NewObjectLRUDefault())
+		if err := copyStorage(st, dst); err != nil {
+			return fmt.Errorf("failed to save repository '%s': %w", path, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "meta.json"), snap.meta, 0644); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// SaveSessions writes a snapshot of every session, e.g. before the server
+// exits. It is a no-op when persistence is disabled.
+func (sm *SessionManager) SaveSessions() error {
+	// Every session is saved below, scheduled or not
+	sm.takePendingSaves()
+
+	sm.mu.RLock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		sessions = append(sessions, s)
+	}
+	sm.mu.RUnlock()
+
+	var errs []error
+	for _, s := range sessions {
+		if err := sm.SaveSession(s); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshotStats returns the persistence statistics recorded for a session.
+func (sm *SessionManager) snapshotStats(id string) SnapshotStats {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	stats := sm.snapshots[id]
+	stats.PersistenceEnabled = sm.PersistDir != ""
+	return stats
+}
+
+// LoadPersistedSessions rehydrates every snapshot found in the persistence directory
+// and returns how many sessions were restored.
+func (sm *SessionManager) LoadPersistedSessions() (int, error) {
+	sm.mu.RLock()
+	dir := sm.PersistDir
+	sm.mu.RUnlock()
+	if dir == "" {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, e := range entries {
+		if !e.IsDir() || filepath.Ext(e.Name()) == ".tmp" {
+			continue
+		}
+		s, err := sm.loadSession(filepath.Join(dir, e.Name()))
+		if err != nil {
+			slog.Warn("load persisted sessions: skipping", "file", e.Name(), "err", err)
+			continue
+		}
+		sm.mu.Lock()
+		sm.sessions[s.ID] = s
+		if s.User != nil {
+			sm.registerUserLocked(*s.User)
+		}
+		sm.mu.Unlock()
+		restored++
+	}
+	return restored, nil
+}
+
+func (sm *SessionManager) loadSession(dir string) (*Session, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	var meta persistedSession
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	if !validSessionID(meta.ID) {
+		return nil, fmt.Errorf("invalid session id %q", meta.ID)
+	}
+
+	fs := memfs.New()
+	if err := copyBillyDir(osfs.New(filepath.Join(dir, "files")), "/", fs, "/"); err != nil {
+		return nil, fmt.Errorf("failed to restore files: %w", err)
+	}
+
+	s := &Session{
+		ID:            meta.ID,
+		Filesystem:    fs,
+		Repos:         make(map[string]*gogit.Repository),
+		CurrentDir:    meta.CurrentDir,
+		CreatedAt:     meta.CreatedAt,
+		Reflog:        meta.Reflog,
+		Manager:       sm,
+		FileCache:     &FileCache{},
+		SearchIndex:   &SearchIndex{},
+		BranchPolicy:  meta.BranchPolicy,
+		CommandPolicy: meta.CommandPolicy,
+		User:          meta.User,
+		Lineage:       meta.Lineage,
+		LFSObjects:    meta.LFSObjects,
+		CherryPick:    meta.CherryPick,
+		Rebase:        meta.Rebase,
+		Merge:         meta.Merge,
+		Am:            meta.Am,
+		Rerere:        meta.Rerere,
+		Locale:        meta.Locale,
+	}
+	// Restored sessions start a fresh idle period
+	s.Touch()
+
+	for _, path := range meta.Repos {
+		src := filesystem.NewStorage(osfs.New(filepath.Join(dir, "repos", url.PathEscape(path))), cache.NewObjectLRUDefault())
+		st := memory.NewStorage()
+		if err := copyStorage(src, st); err != nil {
+			return nil, fmt.Errorf("failed to restore repository '%s': %w", path, err)
+		}
+		worktree, err := fs.Chroot(path)
+		if err != nil {
+			return nil, err
+		}
+		repo, err := gogit.Open(st, worktree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open repository '%s': %w", path, err)
+		}
+		s.Repos[path] = repo
+	}
+
+	heads := make(map[string]*plumbing.Reference)
+	indexes := make(map[string][]byte)
+	for path, wt := range meta.Worktrees {
+		if s.Worktrees == nil {
+			s.Worktrees = make(map[string]string)
+		}
+		s.Worktrees[path] = wt.Main
+		heads[path] = plumbing.NewReferenceFromStrings(plumbing.HEAD.String(), wt.Head)
+		indexes[path] = wt.Index
+	}
+	if err := s.relinkWorktrees(heads, indexes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// copyStorage copies objects, references, config and index from src to dst.
+func copyStorage(src, dst storage.Storer) error {
+	objects, err := src.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return err
+	}
+	if err := objects.ForEach(func(obj plumbing.EncodedObject) error {
+		_, err := dst.SetEncodedObject(obj)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	refs, err := src.IterReferences()
+	if err != nil {
+		return err
+	}
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		return dst.SetReference(ref)
+	}); err != nil {
+		return err
+	}
+	// HEAD is not always part of IterReferences
+	if head, err := src.Reference(plumbing.HEAD); err == nil {
+		if err := dst.SetReference(head); err != nil {
+			return err
+		}
+	}
+
+	// Config and index are copied by value: memory storages keep the pointer
+	// they are given, so sharing it would let the copy modify the source.
+	if cfg, err := src.Config(); err == nil {
+		data, err := cfg.Marshal()
+		if err != nil {
+			return err
+		}
+		copied := config.NewConfig()
+		if err := copied.Unmarshal(data); err != nil {
+			return err
+		}
+		if err := dst.SetConfig(copied); err != nil {
+			return err
+		}
+	}
+	if idx, err := src.Index(); err == nil {
+		var buf bytes.Buffer
+		if err := index.NewEncoder(&buf).Encode(idx); err != nil {
+			return err
+		}
+		copied := &index.Index{}
+		if err := index.NewDecoder(&buf).Decode(copied); err != nil {
+			return err
+		}
+		if err := dst.SetIndex(copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyBillyDir recursively copies a directory tree between billy filesystems.
+func copyBillyDir(src billy.Filesystem, srcDir string, dst billy.Filesystem, dstDir string) error {
+	if err := dst.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	entries, err := src.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		from := src.Join(srcDir, e.Name())
+		to := dst.Join(dstDir, e.Name())
+		if e.IsDir() {
+			if err := copyBillyDir(src, from, dst, to); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyBillyFile(src, from, dst, to, e.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyBillyFile(src billy.Filesystem, from string, dst billy.Filesystem, to string, mode os.FileMode) error {
+	in, err := src.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := dst.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,191 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPersistence_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	sm := NewSessionManager()
+	require.NoError(t, sm.EnablePersistence(dir))
+
+	s, err := sm.CreateSession("persist-me")
+	require.NoError(t, err)
+	repo, err := s.InitRepo("repo")
+	require.NoError(t, err)
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("hello"), 0644))
+	_, err = w.Add("README.md")
+	require.NoError(t, err)
+	hash, err := w.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Tester", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	// Uncommitted work must survive too
+	require.NoError(t, util.WriteFile(w.Filesystem, "notes.txt", []byte("wip"), 0644))
+	s.CurrentDir = "/repo"
+	s.RecordReflog("git commit -m \"Initial commit\"")
+
+	require.NoError(t, sm.SaveSession(s))
+
+	// Simulate a restart with a fresh manager
+	restarted := NewSessionManager()
+	require.NoError(t, restarted.EnablePersistence(dir))
+	n, err := restarted.LoadPersistedSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	restored, ok := restarted.GetSession("persist-me")
+	require.True(t, ok)
+	assert.Equal(t, "/repo", restored.CurrentDir)
+	assert.Len(t, restored.Reflog, len(s.Reflog))
+
+	restoredRepo := restored.GetRepo()
+	require.NotNil(t, restoredRepo)
+	head, err := restoredRepo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, hash, head.Hash())
+
+	rw, err := restoredRepo.Worktree()
+	require.NoError(t, err)
+	notes, err := util.ReadFile(rw.Filesystem, "notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "wip", string(notes))
+
+	status, err := rw.Status()
+	require.NoError(t, err)
+	assert.Equal(t, gogit.Untracked, status.File("notes.txt").Worktree)
+	_, dirty := status["README.md"]
+	assert.False(t, dirty, "committed file should be clean after restore")
+}
+
+func TestSaveSession_DisabledIsNoop(t *testing.T) {
+	sm := NewSessionManager()
+	s, err := sm.CreateSession("ephemeral")
+	require.NoError(t, err)
+	assert.NoError(t, sm.SaveSession(s))
+}
+
+func TestSaveSession_RefusesUnsafeIDs(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "sessions")
+	sm := NewSessionManager()
+	require.NoError(t, sm.EnablePersistence(dir))
+	keep, err := sm.CreateSession("keep")
+	require.NoError(t, err)
+	require.NoError(t, sm.SaveSession(keep))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "remotes", "keep"), 0755))
+
+	for _, id := range []string{".", "..", "a/../..", "x.tmp", ""} {
+		s, err := sm.CreateSession(id)
+		require.NoError(t, err)
+		assert.Error(t, sm.SaveSession(s), "%q", id)
+	}
+	assert.DirExists(t, filepath.Join(root, "remotes", "keep"))
+	assert.DirExists(t, filepath.Join(dir, "keep"))
+}
+
+func TestScheduleSave_CoalescesSaves(t *testing.T) {
+	defer func(d time.Duration) { persistDelay = d }(persistDelay)
+	persistDelay = 20 * time.Millisecond
+
+	dir := t.TempDir()
+	sm := NewSessionManager()
+	require.NoError(t, sm.EnablePersistence(dir))
+	s, err := sm.CreateSession("scheduled")
+	require.NoError(t, err)
+	gone, err := sm.CreateSession("gone")
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		sm.ScheduleSave(s)
+	}
+	sm.ScheduleSave(gone)
+	sm.DeleteSession("gone")
+
+	require.Eventually(t, func() bool { return sm.snapshotStats("scheduled").Saves > 0 }, time.Second, 5*time.Millisecond)
+	time.Sleep(2 * persistDelay)
+	assert.Equal(t, 1, sm.snapshotStats("scheduled").Saves, "saves scheduled together are written once")
+	assert.DirExists(t, filepath.Join(dir, "scheduled"))
+	assert.NoDirExists(t, filepath.Join(dir, "gone"), "a deleted session must not be saved again")
+}
+
+func TestSessionPersistence_RestoresLinkedWorktrees(t *testing.T) {
+	dir := t.TempDir()
+
+	sm := NewSessionManager()
+	require.NoError(t, sm.EnablePersistence(dir))
+	s, err := sm.CreateSession("persist-worktree")
+	require.NoError(t, err)
+	repo, err := s.InitRepo("repo")
+	require.NoError(t, err)
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(w.Filesystem, "README.md", []byte("hello"), 0644))
+	_, err = w.Add("README.md")
+	require.NoError(t, err)
+	hash, err := w.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Tester", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	feature := plumbing.NewBranchReferenceName("feature")
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(feature, hash)))
+	wt, err := s.AddWorktree("repo", "feature-wt", plumbing.NewSymbolicReference(plumbing.HEAD, feature))
+	require.NoError(t, err)
+	ww, err := wt.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, ww.Checkout(&gogit.CheckoutOptions{Branch: feature, Force: true}))
+	// Staged in the worktree only
+	require.NoError(t, util.WriteFile(ww.Filesystem, "staged.txt", []byte("staged"), 0644))
+	_, err = ww.Add("staged.txt")
+	require.NoError(t, err)
+
+	require.NoError(t, sm.SaveSession(s))
+	restarted := NewSessionManager()
+	require.NoError(t, restarted.EnablePersistence(dir))
+	_, err = restarted.LoadPersistedSessions()
+	require.NoError(t, err)
+	restored, ok := restarted.GetSession("persist-worktree")
+	require.True(t, ok)
+
+	assert.Equal(t, "repo", restored.MainWorktree("feature-wt"))
+	linked := restored.Repos["feature-wt"]
+	require.NotNil(t, linked)
+	head, err := linked.Head()
+	require.NoError(t, err)
+	assert.Equal(t, feature, head.Name())
+	mainHead, err := restored.Repos["repo"].Head()
+	require.NoError(t, err)
+	assert.NotEqual(t, feature, mainHead.Name())
+
+	status, err := mustWorktree(t, linked).Status()
+	require.NoError(t, err)
+	assert.Equal(t, gogit.Added, status.File("staged.txt").Staging)
+
+	// Branches are shared again: a new one in the worktree shows up in the main repository
+	require.NoError(t, linked.Storer.SetReference(plumbing.NewHashReference("refs/heads/from-wt", hash)))
+	_, err = restored.Repos["repo"].Reference("refs/heads/from-wt", true)
+	assert.NoError(t, err)
+}
+
+func mustWorktree(t *testing.T, repo *gogit.Repository) *gogit.Worktree {
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	return w
+}
@@ -0,0 +1,204 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Pull request states
+const (
+	PRStateOpen   = "OPEN"
+	PRStateClosed = "CLOSED"
+	PRStateMerged = "MERGED"
+)
+
+// Ways a pull request can be merged, as offered by GitHub's merge button
+const (
+	PRMergeCommit = "merge"  // Merge commit joining the head branch into the base
+	PRMergeSquash = "squash" // One commit with all the changes, on top of the base
+	PRMergeRebase = "rebase" // Each commit replayed on top of the base
+)
+
+// Review verdicts
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewCommented        = "COMMENTED"
+)
+
+// ErrPullRequestNotFound is returned for an unknown pull request ID.
+var ErrPullRequestNotFound = errors.New("pull request not found")
+
+// PullRequestReview is a reviewer's verdict on a pull request.
+type PullRequestReview struct {
+	Author    string    `json:"author"`
+	State     string    `json:"state"` // APPROVED, CHANGES_REQUESTED or COMMENTED
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PullRequestComment is a comment on a pull request, optionally on a line of a file.
+type PullRequestComment struct {
+	ID        int       `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	Path      string    `json:"path,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// reviewDecision summarizes the latest review of each reviewer: any request
+// for changes wins over approvals, like GitHub's review status.
+func reviewDecision(reviews []PullRequestReview) string {
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		if r.State != ReviewCommented {
+			latest[r.Author] = r.State
+		}
+	}
+	decision := ""
+	for _, state := range latest {
+		if state == ReviewChangesRequested {
+			return ReviewChangesRequested
+		}
+		decision = ReviewApproved
+	}
+	return decision
+}
+
+// clone returns a copy of the pull request that shares no slices with it.
+func (pr *PullRequest) clone() *PullRequest {
+	c := *pr
+	c.Reviews = append([]PullRequestReview(nil), pr.Reviews...)
+	c.Comments = append([]PullRequestComment(nil), pr.Comments...)
+	return &c
+}
+
+// findPullRequestLocked returns the pull request with the given ID. Caller holds sm.mu.
+func (sm *SessionManager) findPullRequestLocked(id int) (*PullRequest, error) {
+	for _, pr := range sm.PullRequests {
+		if pr.ID == id {
+			return pr, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: #%d", ErrPullRequestNotFound, id)
+}
+
+// GetPullRequest returns a copy of the pull request with the given ID.
+func (sm *SessionManager) GetPullRequest(id int) (*PullRequest, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	pr, err := sm.findPullRequestLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	return pr.clone(), nil
+}
+
+// ClosePullRequest closes an open pull request without merging it.
+func (sm *SessionManager) ClosePullRequest(id int) (*PullRequest, error) {
+	return sm.updatePullRequest(id, func(pr *PullRequest) error {
+		if pr.State != PRStateOpen {
+			return fmt.Errorf("pull request #%d is not OPEN (current state: %s)", id, pr.State)
+		}
+		now := time.Now()
+		pr.State = PRStateClosed
+		pr.ClosedAt = &now
+		return nil
+	})
+}
+
+// ReopenPullRequest reopens a closed pull request. Merged ones stay merged.
+func (sm *SessionManager) ReopenPullRequest(id int) (*PullRequest, error) {
+	return sm.updatePullRequest(id, func(pr *PullRequest) error {
+		if pr.State != PRStateClosed {
+			return fmt.Errorf("pull request #%d is not CLOSED (current state: %s)", id, pr.State)
+		}
+		pr.State = PRStateOpen
+		pr.ClosedAt = nil
+		return nil
+	})
+}
+
+// ReviewPullRequest records a review. Authors cannot approve or request
+// changes on their own pull request, as on GitHub.
+func (sm *SessionManager) ReviewPullRequest(id int, author, verdict, body string) (*PullRequest, error) {
+	verdict = strings.ToUpper(verdict)
+	return sm.updatePullRequest(id, func(pr *PullRequest) error {
+		if pr.State != PRStateOpen {
+			return fmt.Errorf("pull request #%d is not OPEN (current state: %s)", id, pr.State)
+		}
+		if author == "" {
+			return fmt.Errorf("review author is required")
+		}
+		switch verdict {
+		case ReviewApproved, ReviewChangesRequested:
+			if author == pr.Creator {
+				return fmt.Errorf("%s cannot review their own pull request #%d", author, id)
+			}
+		case ReviewCommented:
+			if strings.TrimSpace(body) == "" {
+				return fmt.Errorf("a comment review needs a body")
+			}
+		default:
+			return fmt.Errorf("unknown review state %q (want %s, %s or %s)", verdict, ReviewApproved, ReviewChangesRequested, ReviewCommented)
+		}
+		pr.Reviews = append(pr.Reviews, PullRequestReview{Author: author, State: verdict, Body: body, CreatedAt: time.Now()})
+		pr.ReviewDecision = reviewDecision(pr.Reviews)
+		return nil
+	})
+}
+
+// CommentOnPullRequest adds a comment, on a line of a file when path is set.
+func (sm *SessionManager) CommentOnPullRequest(id int, author, body, path string, line int) (*PullRequest, error) {
+	return sm.updatePullRequest(id, func(pr *PullRequest) error {
+		if author == "" || strings.TrimSpace(body) == "" {
+			return fmt.Errorf("comment author and body are required")
+		}
+		if line < 0 || (line > 0 && path == "") {
+			return fmt.Errorf("a line comment needs a path and a positive line number")
+		}
+		nextID := 1
+		if n := len(pr.Comments); n > 0 {
+			nextID = pr.Comments[n-1].ID + 1
+		}
+		pr.Comments = append(pr.Comments, PullRequestComment{
+			ID: nextID, Author: author, Body: body, Path: path, Line: line, CreatedAt: time.Now(),
+		})
+		return nil
+	})
+}
+
+// MarkPullRequestMerged records that a pull request was merged into its base
+// branch with the given strategy, producing commit.
+func (sm *SessionManager) MarkPullRequestMerged(id int, strategy, commit, mergedBy string) (*PullRequest, error) {
+	return sm.updatePullRequest(id, func(pr *PullRequest) error {
+		if pr.State != PRStateOpen {
+			return fmt.Errorf("pull request #%d is not OPEN (current state: %s)", id, pr.State)
+		}
+		now := time.Now()
+		pr.State = PRStateMerged
+		pr.MergeStrategy = strategy
+		pr.MergeCommit = commit
+		pr.MergedBy = mergedBy
+		pr.MergedAt = &now
+		return nil
+	})
+}
+
+// updatePullRequest applies fn to a pull request and saves it when fn succeeds.
+func (sm *SessionManager) updatePullRequest(id int, fn func(pr *PullRequest) error) (*PullRequest, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	pr, err := sm.findPullRequestLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(pr); err != nil {
+		return nil, err
+	}
+	sm.savePullRequestLocked(pr)
+	return pr.clone(), nil
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestLifecycle(t *testing.T) {
+	sm := NewSessionManager()
+	pr, err := sm.CreatePullRequest("Add feature", "Desc", "feature", "main", "alice", "origin")
+	require.NoError(t, err)
+
+	t.Run("reviews", func(t *testing.T) {
+		_, err := sm.ReviewPullRequest(pr.ID, "alice", "approved", "")
+		assert.ErrorContains(t, err, "own pull request")
+		_, err = sm.ReviewPullRequest(pr.ID, "bob", "COMMENTED", "")
+		assert.Error(t, err, "comment reviews need a body")
+		_, err = sm.ReviewPullRequest(pr.ID, "bob", "MAYBE", "")
+		assert.Error(t, err)
+
+		got, err := sm.ReviewPullRequest(pr.ID, "bob", ReviewChangesRequested, "Please add tests")
+		require.NoError(t, err)
+		assert.Equal(t, ReviewChangesRequested, got.ReviewDecision)
+
+		got, err = sm.ReviewPullRequest(pr.ID, "carol", ReviewApproved, "")
+		require.NoError(t, err)
+		assert.Equal(t, ReviewChangesRequested, got.ReviewDecision, "an outstanding change request wins")
+
+		// Only each reviewer's latest verdict counts, and comments do not change it
+		_, err = sm.ReviewPullRequest(pr.ID, "bob", ReviewApproved, "Thanks")
+		require.NoError(t, err)
+		got, err = sm.ReviewPullRequest(pr.ID, "dave", ReviewCommented, "Nice")
+		require.NoError(t, err)
+		assert.Equal(t, ReviewApproved, got.ReviewDecision)
+		assert.Len(t, got.Reviews, 4)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		_, err := sm.CommentOnPullRequest(pr.ID, "bob", "Typo here", "", 3)
+		assert.Error(t, err, "a line comment needs a path")
+
+		_, err = sm.CommentOnPullRequest(pr.ID, "bob", "Looks good overall", "", 0)
+		require.NoError(t, err)
+		got, err := sm.CommentOnPullRequest(pr.ID, "alice", "Typo here", "README.md", 3)
+		require.NoError(t, err)
+		require.Len(t, got.Comments, 2)
+		assert.Equal(t, 2, got.Comments[1].ID)
+		assert.Equal(t, "README.md", got.Comments[1].Path)
+	})
+
+	t.Run("close, reopen and merge", func(t *testing.T) {
+		_, err := sm.ReopenPullRequest(pr.ID)
+		assert.Error(t, err, "an open pull request cannot be reopened")
+
+		got, err := sm.ClosePullRequest(pr.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PRStateClosed, got.State)
+		assert.NotNil(t, got.ClosedAt)
+		_, err = sm.MarkPullRequestMerged(pr.ID, PRMergeCommit, "abc", "bob")
+		assert.Error(t, err, "a closed pull request cannot be merged")
+
+		got, err = sm.ReopenPullRequest(pr.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PRStateOpen, got.State)
+
+		got, err = sm.MarkPullRequestMerged(pr.ID, PRMergeSquash, "abc", "bob")
+		require.NoError(t, err)
+		assert.Equal(t, PRStateMerged, got.State)
+		assert.Equal(t, PRMergeSquash, got.MergeStrategy)
+		_, err = sm.ClosePullRequest(pr.ID)
+		assert.Error(t, err)
+		_, err = sm.ReopenPullRequest(pr.ID)
+		assert.Error(t, err, "merged pull requests stay merged")
+	})
+
+	_, err = sm.ClosePullRequest(999)
+	assert.ErrorIs(t, err, ErrPullRequestNotFound)
+}
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions of an interactive rebase todo list
+const (
+	RebasePick   = "pick"
+	RebaseReword = "reword"
+	RebaseSquash = "squash"
+	RebaseDrop   = "drop"
+)
+
+// Phases of an interactive rebase
+const (
+	RebasePlanning = "planning" // Todo list handed out, waiting for the edited plan
+	RebaseApplying = "applying" // Plan accepted, commits are replayed on --continue
+	RebaseStopped  = "stopped"  // Replay stopped on a conflict; --continue, --skip or --abort
+)
+
+// RebaseStep is one line of an interactive rebase todo list.
+type RebaseStep struct {
+	Action  string `json:"action"`            // RebasePick, RebaseReword, RebaseSquash or RebaseDrop
+	Commit  string `json:"commit"`            // Full hash of the commit to replay
+	Subject string `json:"subject,omitempty"` // First line of the original message, for display
+	Message string `json:"message,omitempty"` // New message for reword
+}
+
+// RebaseState records a rebase that has to wait for the user: an interactive
+// rebase between "git rebase -i" and the submission of its plan, or any
+// rebase whose replay stopped on a conflict. It survives across requests.
+type RebaseState struct {
+	Repo        string       `json:"repo"`                  // Repository path the rebase runs in
+	Phase       string       `json:"phase"`                 // RebasePlanning, RebaseApplying or RebaseStopped
+	Interactive bool         `json:"interactive,omitempty"` // Started with -i
+	HeadName    string       `json:"headName"`              // Branch being rebased, e.g. "feature"
+	OrigHead    string       `json:"origHead"`              // HEAD before the rebase started, restored by --abort
+	Onto        string       `json:"onto"`                  // Commit the plan is replayed onto
+	Todo        []RebaseStep `json:"todo"`                  // Steps still to replay; when stopped, the stopped one first
+	Replayed    int          `json:"replayed,omitempty"`    // Commits replayed so far
+	LastOrig    string       `json:"lastOrig,omitempty"`    // Original commit the latest replayed one stands for, which a squash melds into
+	Conflicts   []string     `json:"conflicts,omitempty"`   // Paths the stopped step left with conflict markers
+	Autostash   string       `json:"autostash,omitempty"`   // Stash entry of the local changes, applied again when the rebase ends
+}
+
+// RebaseInProgress returns the rebase of the active repository, or nil.
+func (s *Session) RebaseInProgress() *RebaseState {
+	if s.Rebase == nil || s.Rebase.Repo != s.activeRepoPath() {
+		return nil
+	}
+	return s.Rebase
+}
+
+// StartRebase records a new interactive rebase for the active repository.
+func (s *Session) StartRebase(rb *RebaseState) {
+	rb.Repo = s.activeRepoPath()
+	rb.Phase = RebasePlanning
+	s.Rebase = rb
+}
+
+// StopRebase records that the replay of rb stopped on its first todo step,
+// which REBASE_HEAD then names.
+func (s *Session) StopRebase(rb *RebaseState) {
+	if s.Rebase != nil && s.Rebase.Repo != s.activeRepoPath() {
+		s.setStateRef(s.Rebase.Repo, RebaseHead, "")
+	}
+	rb.Repo = s.activeRepoPath()
+	rb.Phase = RebaseStopped
+	s.Rebase = rb
+	s.setStateRef(rb.Repo, RebaseHead, rb.Todo[0].Commit)
+}
+
+// ClearRebase forgets the rebase, after it finished or was aborted.
+func (s *Session) ClearRebase() {
+	if s.Rebase != nil {
+		s.setStateRef(s.Rebase.Repo, RebaseHead, "")
+	}
+	s.Rebase = nil
+}
+
+// SetRebasePlan replaces the todo list of the rebase waiting for its plan.
+// The plan may reorder or leave out commits of the original todo list
+// (a missing commit is dropped) but may not introduce new ones.
+func (s *Session) SetRebasePlan(plan []RebaseStep) error {
+	rb := s.RebaseInProgress()
+	if rb == nil {
+		return fmt.Errorf("no rebase in progress")
+	}
+	if rb.Phase != RebasePlanning {
+		return fmt.Errorf("the rebase plan was already submitted")
+	}
+
+	known := make(map[string]RebaseStep, len(rb.Todo))
+	for _, step := range rb.Todo {
+		known[step.Commit] = step
+	}
+
+	seen := make(map[string]bool, len(plan))
+	todo := make([]RebaseStep, 0, len(plan))
+	for i, step := range plan {
+		action := normalizeRebaseAction(step.Action)
+		if action == "" {
+			return fmt.Errorf("line %d: unknown action '%s'", i+1, step.Action)
+		}
+		original, ok := lookupRebaseCommit(known, step.Commit)
+		if !ok {
+			return fmt.Errorf("line %d: commit '%s' is not part of this rebase", i+1, step.Commit)
+		}
+		if seen[original.Commit] {
+			return fmt.Errorf("line %d: commit '%s' appears more than once", i+1, step.Commit)
+		}
+		seen[original.Commit] = true
+
+		if action == RebaseDrop {
+			continue
+		}
+		if action == RebaseSquash && len(todo) == 0 {
+			return fmt.Errorf("line %d: cannot 'squash' without a previous commit", i+1)
+		}
+		if action == RebaseReword && strings.TrimSpace(step.Message) == "" {
+			return fmt.Errorf("line %d: reword needs a new commit message", i+1)
+		}
+
+		todo = append(todo, RebaseStep{
+			Action:  action,
+			Commit:  original.Commit,
+			Subject: original.Subject,
+			Message: step.Message,
+		})
+	}
+
+	rb.Todo = todo
+	rb.Phase = RebaseApplying
+	return nil
+}
+
+// normalizeRebaseAction expands the one-letter abbreviations git accepts.
+func normalizeRebaseAction(action string) string {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "p", RebasePick:
+		return RebasePick
+	case "r", RebaseReword:
+		return RebaseReword
+	case "s", RebaseSquash:
+		return RebaseSquash
+	case "d", RebaseDrop:
+		return RebaseDrop
+	}
+	return ""
+}
+
+// lookupRebaseCommit finds a todo entry by full or abbreviated (at least 4 characters) hash.
+func lookupRebaseCommit(known map[string]RebaseStep, hash string) (RebaseStep, bool) {
+	if step, ok := known[hash]; ok {
+		return step, true
+	}
+	if len(hash) < 4 {
+		return RebaseStep{}, false
+	}
+	var found RebaseStep
+	matches := 0
+	for full, step := range known {
+		if strings.HasPrefix(full, hash) {
+			found = step
+			matches++
+		}
+	}
+	return found, matches == 1
+}
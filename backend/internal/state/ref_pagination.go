@@ -0,0 +1,60 @@
+package state
+
+import (
+	"sort"
+	"strings"
+)
+
+// RefFilter selects one page of ref names. Large ingested remotes can have
+// thousands of branches and tags, so listings are sorted and sliced by name.
+type RefFilter struct {
+	Prefix string // Only names starting with Prefix
+	After  string // Continuation cursor: only names sorting strictly after this one
+	Limit  int    // Maximum names per page; 0 means unlimited
+}
+
+// RefPagination describes how the Branches and Tags maps of a GraphState were paged.
+type RefPagination struct {
+	TotalBranches    int    `json:"totalBranches"`
+	TotalTags        int    `json:"totalTags"`
+	NextBranchCursor string `json:"nextBranchCursor,omitempty"`
+	NextTagCursor    string `json:"nextTagCursor,omitempty"`
+}
+
+// PaginateRefNames sorts names, applies the filter and returns the page together
+// with the cursor for the next page ("" when there are no more names).
+func PaginateRefNames(names []string, f RefFilter) ([]string, string) {
+	sorted := make([]string, 0, len(names))
+	for _, n := range names {
+		if strings.HasPrefix(n, f.Prefix) && (f.After == "" || n > f.After) {
+			sorted = append(sorted, n)
+		}
+	}
+	sort.Strings(sorted)
+
+	if f.Limit <= 0 || len(sorted) <= f.Limit {
+		return sorted, ""
+	}
+	page := sorted[:f.Limit]
+	return page, page[len(page)-1]
+}
+
+// PaginateRefMap returns the entries of refs that fall on the requested page,
+// the next cursor, and the number of entries matching the prefix across all pages.
+func PaginateRefMap(refs map[string]string, f RefFilter) (map[string]string, string, int) {
+	names := make([]string, 0, len(refs))
+	total := 0
+	for n := range refs {
+		names = append(names, n)
+		if strings.HasPrefix(n, f.Prefix) {
+			total++
+		}
+	}
+
+	page, next := PaginateRefNames(names, f)
+	result := make(map[string]string, len(page))
+	for _, n := range page {
+		result[n] = refs[n]
+	}
+	return result, next, total
+}
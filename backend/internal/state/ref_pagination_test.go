@@ -0,0 +1,32 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginateRefNames(t *testing.T) {
+	names := []string{"feature/c", "main", "feature/a", "feature/b", "release/1"}
+
+	page, next := PaginateRefNames(names, RefFilter{Prefix: "feature/", Limit: 2})
+	assert.Equal(t, []string{"feature/a", "feature/b"}, page)
+	assert.Equal(t, "feature/b", next)
+
+	page, next = PaginateRefNames(names, RefFilter{Prefix: "feature/", Limit: 2, After: next})
+	assert.Equal(t, []string{"feature/c"}, page)
+	assert.Empty(t, next)
+
+	page, next = PaginateRefNames(names, RefFilter{})
+	assert.Len(t, page, 5)
+	assert.Empty(t, next)
+}
+
+func TestPaginateRefMap(t *testing.T) {
+	refs := map[string]string{"v1.0": "a", "v1.1": "b", "v2.0": "c", "other": "d"}
+
+	page, next, total := PaginateRefMap(refs, RefFilter{Prefix: "v", Limit: 1})
+	assert.Equal(t, map[string]string{"v1.0": "a"}, page)
+	assert.Equal(t, "v1.0", next)
+	assert.Equal(t, 3, total)
+}
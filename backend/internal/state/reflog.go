@@ -0,0 +1,225 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ReflogEntry records one update of a ref, like a line of .git/logs/<ref>.
+type ReflogEntry struct {
+	Command   string
+	Timestamp time.Time
+	Context   string // Repository the ref belongs to, as a CurrentDir ("/repo1")
+	Ref       string // "HEAD" or a full ref name; empty in snapshots that predate per-ref logs, meaning HEAD
+	OldHash   string // Value before the update, the zero hash when the ref was created
+	Hash      string // Value after the update
+	Message   string
+}
+
+// refName returns the ref the entry belongs to.
+func (e ReflogEntry) refName() string {
+	if e.Ref == "" {
+		return "HEAD"
+	}
+	return e.Ref
+}
+
+// reflogRevisionPattern matches <ref>@{<n>} optionally followed by ~ and ^ suffixes.
+var reflogRevisionPattern = regexp.MustCompile(`^([^@{}\s]*)@\{(\d+)\}([~^][~^0-9]*)?$`)
+
+// RecordReflog logs the ref updates made by an operation in the active repository.
+// HEAD always gets an entry, even when it did not move (e.g. checkout of a branch
+// at the same commit); branches get one only when their tip changed.
+func (s *Session) RecordReflog(message string) {
+	repo := s.GetRepo()
+	if repo == nil {
+		return
+	}
+	ctx := "/" + s.activeRepoPath()
+	s.logRefUpdates(ctx, repo, s.loggedTips()[ctx], message, true)
+}
+
+// SyncReflog logs every HEAD and branch movement not recorded yet, in all
+// repositories of the session. It catches ref updates made by commands that do
+// not call RecordReflog themselves (cherry-pick, pull, update-ref, pushes into
+// local repositories, ...).
+func (s *Session) SyncReflog(message string) {
+	tips := s.loggedTips()
+	paths := make([]string, 0, len(s.Repos))
+	for path := range s.Repos {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		ctx := "/" + path
+		s.logRefUpdates(ctx, s.Repos[path], tips[ctx], message, false)
+	}
+}
+
+// loggedTips returns the last logged value of every ref, keyed by repository and ref name.
+func (s *Session) loggedTips() map[string]map[string]string {
+	tips := make(map[string]map[string]string)
+	for _, e := range s.Reflog {
+		if tips[e.Context] == nil {
+			tips[e.Context] = make(map[string]string)
+		}
+		tips[e.Context][e.refName()] = e.Hash
+	}
+	return tips
+}
+
+func (s *Session) logRefUpdates(ctx string, repo *gogit.Repository, logged map[string]string, message string, forceHead bool) {
+	now := time.Now()
+	appendEntry := func(ref, newHash string) {
+		old, ok := logged[ref]
+		if !ok {
+			old = plumbing.ZeroHash.String()
+		}
+		s.Reflog = append(s.Reflog, ReflogEntry{
+			Command:   message,
+			Timestamp: now,
+			Context:   ctx,
+			Ref:       ref,
+			OldHash:   old,
+			Hash:      newHash,
+			Message:   message,
+		})
+	}
+
+	branches := make(map[string]string)
+	if refs, err := repo.Branches(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			branches[ref.Name().String()] = ref.Hash().String()
+			return nil
+		})
+	}
+	names := make([]string, 0, len(branches))
+	for name := range branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if old, ok := logged[name]; !ok || old != branches[name] {
+			appendEntry(name, branches[name])
+		}
+	}
+
+	// Deleting a branch deletes its log, as in git
+	for name := range logged {
+		if _, ok := branches[name]; !ok && strings.HasPrefix(name, "refs/heads/") {
+			s.dropReflog(ctx, name)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return // Unborn HEAD has nothing to log
+	}
+	if old, ok := logged["HEAD"]; forceHead || !ok || old != head.Hash().String() {
+		appendEntry("HEAD", head.Hash().String())
+	}
+}
+
+func (s *Session) dropReflog(ctx, ref string) {
+	kept := s.Reflog[:0]
+	for _, e := range s.Reflog {
+		if e.Context != ctx || e.refName() != ref {
+			kept = append(kept, e)
+		}
+	}
+	s.Reflog = kept
+}
+
+// RefReflog returns the log of ref in the active repository, newest first.
+// ref may be "HEAD", a branch name or a full ref name.
+func (s *Session) RefReflog(ref string) ([]ReflogEntry, error) {
+	ctx := "/" + s.activeRepoPath()
+	full := s.reflogRefName(ref)
+
+	var entries []ReflogEntry
+	for i := len(s.Reflog) - 1; i >= 0; i-- {
+		if e := s.Reflog[i]; e.Context == ctx && e.refName() == full {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 && full != "HEAD" {
+		if repo := s.GetRepo(); repo != nil {
+			if _, err := repo.Reference(plumbing.ReferenceName(full), false); err != nil {
+				return nil, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", ref)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// reflogRefName maps the ref part of <ref>@{n} to a full ref name.
+// An empty ref means the current branch, or HEAD when detached.
+func (s *Session) reflogRefName(ref string) string {
+	switch {
+	case ref == "HEAD", strings.HasPrefix(ref, "refs/"):
+		return ref
+	case ref == "" || ref == "@":
+		if repo := s.GetRepo(); repo != nil {
+			if head, err := repo.Reference(plumbing.HEAD, false); err == nil && head.Type() == plumbing.SymbolicReference {
+				return head.Target().String()
+			}
+		}
+		return "HEAD"
+	default:
+		return plumbing.NewBranchReferenceName(ref).String()
+	}
+}
+
+// ResolveReflogRevision rewrites a <ref>@{<n>} revision (HEAD@{2}, main@{1}~1)
+// to the hash the ref had n updates ago, keeping any ~/^ suffix. Revisions in
+// other forms are returned unchanged with ok set to false. stash@{n} is left
+// to the stash command, which keeps its own list.
+func (s *Session) ResolveReflogRevision(rev string) (resolved string, ok bool, err error) {
+	m := reflogRevisionPattern.FindStringSubmatch(rev)
+	if m == nil || m[1] == "stash" || m[1] == "refs/stash" {
+		return rev, false, nil
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return rev, false, nil
+	}
+	entries, err := s.RefReflog(m[1])
+	if err != nil {
+		return "", true, err
+	}
+	name := m[1]
+	if name == "" {
+		name = "@"
+	}
+	if len(entries) == 0 {
+		return "", true, fmt.Errorf("fatal: log for '%s' is empty", name)
+	}
+	if n >= len(entries) {
+		return "", true, fmt.Errorf("fatal: log for '%s' only has %d entries", name, len(entries))
+	}
+	return entries[n].Hash + m[3], true, nil
+}
+
+// reflogHashes returns every commit the reflogs of a repository point at.
+// gc keeps these reachable, so old positions can still be checked out.
+func (s *Session) reflogHashes(ctx string) []plumbing.Hash {
+	var hashes []plumbing.Hash
+	for _, e := range s.Reflog {
+		if e.Context != ctx {
+			continue
+		}
+		for _, h := range []string{e.OldHash, e.Hash} {
+			if hash := plumbing.NewHash(h); h != "" && !hash.IsZero() {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Remote default branch and branch renames
+//
+// The default branch of a shared remote is where its HEAD symref points:
+// clones check it out and pull requests target it. Like on GitHub, it can be
+// changed, and a branch can be renamed on the remote, with HEAD and the open
+// pull requests following it, which makes "rename master to main" an exercise.
+
+// BranchRename describes a branch renamed on a shared remote.
+type BranchRename struct {
+	Remote        string `json:"remote"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	DefaultBranch bool   `json:"defaultBranch"`           // The branch was the default one, and HEAD moved with it
+	RetargetedPRs []int  `json:"retargetedPrs,omitempty"` // Open pull requests now using the new name
+}
+
+// RemoteDefaultBranch returns the branch HEAD of the named shared remote points to.
+func (sm *SessionManager) RemoteDefaultBranch(name string) (string, error) {
+	repo, ok := sm.GetSharedRemote(name)
+	if !ok {
+		return "", fmt.Errorf("remote '%s' not found", name)
+	}
+	defer sm.RLockRemote(repo)()
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference {
+		return "", fmt.Errorf("remote '%s' has no default branch", name)
+	}
+	return head.Target().Short(), nil
+}
+
+// SetRemoteDefaultBranch points HEAD of the named shared remote at branch,
+// which must exist unless the remote has no branches yet.
+func (sm *SessionManager) SetRemoteDefaultBranch(name, branch string) error {
+	repo, ok := sm.GetSharedRemote(name)
+	if !ok {
+		return fmt.Errorf("remote '%s' not found", name)
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if err := ref.Validate(); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", branch)
+	}
+
+	defer sm.LockRemote(repo)()
+	if _, err := repo.Storer.Reference(ref); err != nil && hasBranches(repo) {
+		return fmt.Errorf("branch '%s' does not exist on '%s'", branch, name)
+	}
+	return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref))
+}
+
+func hasBranches(repo *gogit.Repository) bool {
+	iter, err := repo.Branches()
+	if err != nil {
+		return false
+	}
+	defer iter.Close()
+	_, err = iter.Next()
+	return err == nil
+}
+
+// RenameRemoteBranch renames a branch of the named shared remote. HEAD
+// follows it when it was the default branch, and open pull requests from or
+// into it are retargeted.
+func (sm *SessionManager) RenameRemoteBranch(name, from, to string) (BranchRename, error) {
+	rename := BranchRename{Remote: name, From: from, To: to}
+	repo, ok := sm.GetSharedRemote(name)
+	if !ok {
+		return rename, fmt.Errorf("remote '%s' not found", name)
+	}
+	oldRef, newRef := plumbing.NewBranchReferenceName(from), plumbing.NewBranchReferenceName(to)
+	if err := newRef.Validate(); err != nil {
+		return rename, fmt.Errorf("'%s' is not a valid branch name", to)
+	}
+
+	unlock := sm.LockRemote(repo)
+	branch, err := repo.Storer.Reference(oldRef)
+	if err != nil {
+		unlock()
+		return rename, fmt.Errorf("branch '%s' does not exist on '%s'", from, name)
+	}
+	if _, err := repo.Storer.Reference(newRef); err == nil {
+		unlock()
+		return rename, fmt.Errorf("branch '%s' already exists on '%s'", to, name)
+	}
+	// The old name goes first, so "feature" can become "feature/cart"
+	if err := repo.Storer.RemoveReference(oldRef); err != nil {
+		unlock()
+		return rename, err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(newRef, branch.Hash())); err != nil {
+		_ = repo.Storer.SetReference(branch)
+		unlock()
+		return rename, err
+	}
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil && head.Type() == plumbing.SymbolicReference && head.Target() == oldRef {
+		rename.DefaultBranch = true
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, newRef)); err != nil {
+			unlock()
+			return rename, err
+		}
+	}
+	unlock()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, pr := range sm.PullRequests {
+		if pr.State != PRStateOpen || pr.RemoteName != name || (pr.BaseRef != from && pr.HeadRef != from) {
+			continue
+		}
+		if pr.BaseRef == from {
+			pr.BaseRef = to
+		}
+		if pr.HeadRef == from {
+			pr.HeadRef = to
+		}
+		sm.savePullRequestLocked(pr)
+		rename.RetargetedPRs = append(rename.RetargetedPRs, pr.ID)
+	}
+	return rename, nil
+}
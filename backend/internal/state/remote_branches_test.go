@@ -0,0 +1,127 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	appconfig "github.com/kurobon/gitgym/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRemoteBranchesTest(t *testing.T) *SessionManager {
+	t.Helper()
+	orig := appconfig.Global
+	appconfig.Global = &appconfig.Config{DataRoot: filepath.Join(t.TempDir(), "data")}
+	t.Cleanup(func() { appconfig.Global = orig })
+	sm := NewSessionManager()
+	_, err := sm.CreateSession("owner")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepositoryWith(context.Background(), "owner", "shop", BareRepoOptions{
+		DefaultBranch: "master",
+		README:        true,
+	}))
+	return sm
+}
+
+func TestSetRemoteDefaultBranch(t *testing.T) {
+	sm := setupRemoteBranchesTest(t)
+
+	branch, err := sm.RemoteDefaultBranch("shop")
+	require.NoError(t, err)
+	assert.Equal(t, "master", branch)
+
+	assert.ErrorContains(t, sm.SetRemoteDefaultBranch("shop", "develop"), "does not exist")
+	assert.ErrorContains(t, sm.SetRemoteDefaultBranch("shop", "bad..name"), "not a valid branch name")
+	assert.ErrorContains(t, sm.SetRemoteDefaultBranch("missing", "main"), "not found")
+
+	repo, _ := sm.GetSharedRemote("shop")
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), master.Hash())))
+
+	require.NoError(t, sm.SetRemoteDefaultBranch("shop", "develop"))
+	branch, err = sm.RemoteDefaultBranch("shop")
+	require.NoError(t, err)
+	assert.Equal(t, "develop", branch)
+}
+
+func TestSetRemoteDefaultBranch_EmptyRemote(t *testing.T) {
+	orig := appconfig.Global
+	appconfig.Global = &appconfig.Config{DataRoot: filepath.Join(t.TempDir(), "data")}
+	t.Cleanup(func() { appconfig.Global = orig })
+	sm := NewSessionManager()
+	_, err := sm.CreateSession("owner")
+	require.NoError(t, err)
+	require.NoError(t, sm.CreateBareRepository(context.Background(), "owner", "empty"))
+
+	// An empty remote has no branch yet; the first push creates it
+	require.NoError(t, sm.SetRemoteDefaultBranch("empty", "trunk"))
+	branch, err := sm.RemoteDefaultBranch("empty")
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+}
+
+func TestRenameRemoteBranch(t *testing.T) {
+	sm := setupRemoteBranchesTest(t)
+	repo, _ := sm.GetSharedRemote("shop")
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), master.Hash())))
+
+	into, err := sm.CreatePullRequest("Feature", "", "feature", "master", "owner", "shop")
+	require.NoError(t, err)
+	other, err := sm.CreatePullRequest("Elsewhere", "", "feature", "master", "owner", "other")
+	require.NoError(t, err)
+	closed, err := sm.CreatePullRequest("Old", "", "feature", "master", "owner", "shop")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetPullRequestState(closed.ID, PRStateClosed))
+
+	rename, err := sm.RenameRemoteBranch("shop", "master", "main")
+	require.NoError(t, err)
+	assert.True(t, rename.DefaultBranch)
+	assert.Equal(t, []int{into.ID}, rename.RetargetedPRs)
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+	assert.Error(t, err)
+	main, err := repo.Reference(plumbing.NewBranchReferenceName("main"), false)
+	require.NoError(t, err)
+	assert.Equal(t, master.Hash(), main.Hash())
+	branch, err := sm.RemoteDefaultBranch("shop")
+	require.NoError(t, err)
+	assert.Equal(t, "main", branch)
+
+	prs := make(map[int]*PullRequest)
+	for _, pr := range sm.GetPullRequests() {
+		prs[pr.ID] = pr
+	}
+	assert.Equal(t, "main", prs[into.ID].BaseRef)
+	assert.Equal(t, "master", prs[other.ID].BaseRef)
+	assert.Equal(t, "master", prs[closed.ID].BaseRef)
+
+	// A branch other than the default one leaves HEAD alone
+	rename, err = sm.RenameRemoteBranch("shop", "feature", "feature/cart")
+	require.NoError(t, err)
+	assert.False(t, rename.DefaultBranch)
+	assert.Equal(t, []int{into.ID}, rename.RetargetedPRs)
+	for _, pr := range sm.GetPullRequests() {
+		if pr.ID == into.ID {
+			assert.Equal(t, "feature/cart", pr.HeadRef)
+		}
+	}
+}
+
+func TestRenameRemoteBranch_Errors(t *testing.T) {
+	sm := setupRemoteBranchesTest(t)
+
+	_, err := sm.RenameRemoteBranch("shop", "develop", "main")
+	assert.ErrorContains(t, err, "does not exist")
+	_, err = sm.RenameRemoteBranch("shop", "master", "master")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = sm.RenameRemoteBranch("shop", "master", "bad..name")
+	assert.ErrorContains(t, err, "not a valid branch name")
+	_, err = sm.RenameRemoteBranch("missing", "master", "main")
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"sync"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// Shared remotes are bare repositories that every session pushes to and
+// fetches from. go-git storers are not safe for concurrent writers, and a
+// push is several steps (copying objects, then moving refs) that another
+// push must not interleave with, so each remote has its own lock: writers
+// (push, pull request merges, teammate actions, ingest fetches) take it
+// exclusively, readers (fetch, clone) shared.
+//
+// Lock order, outermost first:
+//
+//	Session.mu, teammateMu, ingestMu -> remote lock -> SessionManager.mu
+//
+// A remote lock is never taken while holding sm.mu: look the remote up
+// (GetSharedRemote), release sm.mu, then lock the remote. Manager methods
+// called while holding a remote lock (BranchPushed, RecordUserPush, ...)
+// take sm.mu themselves, which the order allows. remoteLocksMu only guards
+// the lock table and is held for nothing else.
+
+// remoteLock returns the lock of repo, creating it, or nil when repo is not
+// a shared remote: a session's own repositories, and those opened from disk,
+// are covered by the session lock. Locks are keyed by the repository rather
+// than its name, so a remote registered under its name, URL and path has a
+// single lock.
+func (sm *SessionManager) remoteLock(repo *gogit.Repository) *sync.RWMutex {
+	if !sm.isSharedRemote(repo) {
+		return nil
+	}
+	sm.remoteLocksMu.Lock()
+	defer sm.remoteLocksMu.Unlock()
+	if sm.remoteLocks == nil {
+		sm.remoteLocks = make(map[*gogit.Repository]*sync.RWMutex)
+	}
+	l := sm.remoteLocks[repo]
+	if l == nil {
+		l = &sync.RWMutex{}
+		sm.remoteLocks[repo] = l
+	}
+	return l
+}
+
+// isSharedRemote reports whether repo is registered as a shared remote.
+func (sm *SessionManager) isSharedRemote(repo *gogit.Repository) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, r := range sm.SharedRemotes {
+		if r == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// LockRemote locks the shared remote repo for writing and returns the
+// function that unlocks it.
+func (sm *SessionManager) LockRemote(repo *gogit.Repository) func() {
+	l := sm.remoteLock(repo)
+	if l == nil {
+		return func() {}
+	}
+	l.Lock()
+	return l.Unlock
+}
+
+// RLockRemote locks the shared remote repo for reading and returns the
+// function that unlocks it.
+func (sm *SessionManager) RLockRemote(repo *gogit.Repository) func() {
+	l := sm.remoteLock(repo)
+	if l == nil {
+		return func() {}
+	}
+	l.RLock()
+	return l.RUnlock
+}
+
+// forgetRemoteLock drops the lock of a removed remote.
+func (sm *SessionManager) forgetRemoteLock(repo *gogit.Repository) {
+	sm.remoteLocksMu.Lock()
+	defer sm.remoteLocksMu.Unlock()
+	delete(sm.remoteLocks, repo)
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// RemoteAccess says who may push to a shared remote.
+type RemoteAccess string
+
+const (
+	RemoteAccessOpen     RemoteAccess = "open"      // Every session may push (the default)
+	RemoteAccessOwner    RemoteAccess = "owner"     // Only the owning session may push
+	RemoteAccessReadOnly RemoteAccess = "read-only" // Nobody may push, e.g. an ingested upstream repository
+)
+
+// RemotePermission is the push permission of a shared remote.
+type RemotePermission struct {
+	Access RemoteAccess `json:"access"`
+	Owner  string       `json:"owner,omitempty"` // Session that created the remote, if any
+}
+
+// Validate checks that the access level is known and that an owner-only remote has an owner.
+func (p RemotePermission) Validate() error {
+	switch p.Access {
+	case RemoteAccessOpen, RemoteAccessReadOnly:
+		return nil
+	case RemoteAccessOwner:
+		if p.Owner == "" {
+			return fmt.Errorf("an owner-only remote needs an owner")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown access '%s' (want %s, %s or %s)", p.Access, RemoteAccessOpen, RemoteAccessOwner, RemoteAccessReadOnly)
+}
+
+// CanPush reports whether the session may push.
+func (p RemotePermission) CanPush(sessionID string) bool {
+	switch p.Access {
+	case RemoteAccessReadOnly:
+		return false
+	case RemoteAccessOwner:
+		return sessionID == p.Owner
+	}
+	return true
+}
+
+// SetRemotePermission configures who may push to the named shared remote.
+// An empty access level keeps the current one, so an owner can be recorded alone.
+func (sm *SessionManager) SetRemotePermission(name string, perm RemotePermission) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, ok := sm.SharedRemotes[name]; !ok {
+		return fmt.Errorf("remote '%s' not found", name)
+	}
+	current := sm.remotePermissionLocked(name)
+	if perm.Access == "" {
+		perm.Access = current.Access
+	}
+	if perm.Owner == "" {
+		perm.Owner = current.Owner
+	}
+	if err := perm.Validate(); err != nil {
+		return err
+	}
+	if sm.RemotePermissions == nil {
+		sm.RemotePermissions = make(map[string]RemotePermission)
+	}
+	sm.RemotePermissions[name] = perm
+	return nil
+}
+
+// GetRemotePermission returns the push permission of the named shared remote.
+// Remotes without one are open.
+func (sm *SessionManager) GetRemotePermission(name string) RemotePermission {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.remotePermissionLocked(name)
+}
+
+func (sm *SessionManager) remotePermissionLocked(name string) RemotePermission {
+	if perm, ok := sm.RemotePermissions[name]; ok {
+		return perm
+	}
+	return RemotePermission{Access: RemoteAccessOpen}
+}
+
+// RemotePermissionForRepo returns the permission of whichever shared remote alias maps to repo.
+func (sm *SessionManager) RemotePermissionForRepo(repo *gogit.Repository) RemotePermission {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for key, r := range sm.SharedRemotes {
+		if r != repo {
+			continue
+		}
+		if perm, ok := sm.RemotePermissions[key]; ok {
+			return perm
+		}
+	}
+	return RemotePermission{Access: RemoteAccessOpen}
+}
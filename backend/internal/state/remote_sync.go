@@ -0,0 +1,423 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Remote sync
+//
+// An ingested remote is a copy of its upstream taken once, so it goes stale
+// right away. Giving it a sync interval keeps it following the upstream: the
+// refresher started by StartRemoteSync fetches every remote that is due again
+// and, when upstream branches moved, publishes an UpstreamEvent. Missions turn
+// syncing on in their setup ("simulate sync origin 1m") so that upstream
+// moves while the learner works, as it would on a busy project.
+
+// MinRemoteSyncInterval keeps a short interval from hammering the upstream host.
+const MinRemoteSyncInterval = 10 * time.Second
+
+// remoteSyncTick is how often the refresher looks for remotes that are due.
+var remoteSyncTick = time.Second
+
+// maxUpstreamEvents is how many events are kept for late subscribers and
+// queued for a subscriber that does not read them.
+const maxUpstreamEvents = 20
+
+// UpstreamBranchUpdate is one branch an upstream sync moved.
+type UpstreamBranchUpdate struct {
+	Branch  string `json:"branch"`
+	From    string `json:"from,omitempty"`   // Empty for a new branch
+	To      string `json:"to,omitempty"`     // Empty for a deleted branch
+	Commits int    `json:"commits"`          // Commits on To that From did not have
+	Forced  bool   `json:"forced,omitempty"` // Upstream dropped commits of From
+}
+
+// UpstreamEvent reports that a sync found new upstream commits.
+type UpstreamEvent struct {
+	Seq      uint64                 `json:"seq"`
+	Remote   string                 `json:"remote"`
+	URL      string                 `json:"url"`
+	Branches []UpstreamBranchUpdate `json:"branches"`
+	At       time.Time              `json:"at"`
+}
+
+// RemoteSyncStatus describes how an ingested remote follows its upstream.
+type RemoteSyncStatus struct {
+	Remote    string     `json:"remote"`
+	URL       string     `json:"url"`
+	Interval  string     `json:"interval,omitempty"` // Go duration, e.g. "5m0s"; empty when not syncing
+	LastSync  *time.Time `json:"lastSync,omitempty"`
+	NextSync  *time.Time `json:"nextSync,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// remoteSyncer holds the sync state of a SessionManager. The zero value is ready to use.
+type remoteSyncer struct {
+	mu      sync.Mutex
+	runs    map[string]*remoteSyncRun // Keyed by remote name
+	events  []UpstreamEvent           // Recent events, oldest first
+	seq     uint64
+	subs    map[*UpstreamSubscriber]struct{}
+	running map[string]bool // Remotes being synced right now
+}
+
+type remoteSyncRun struct {
+	last      time.Time
+	lastError string
+}
+
+// SetRemoteSync makes the refresher fetch the named ingested remote every
+// interval. An interval of 0 stops syncing it. The interval is kept in the
+// ingest manifest, so it survives a restart.
+func (sm *SessionManager) SetRemoteSync(name string, interval time.Duration) (RemoteSyncStatus, error) {
+	if interval < 0 || (interval > 0 && interval < MinRemoteSyncInterval) {
+		return RemoteSyncStatus{}, fmt.Errorf("sync interval must be at least %v", MinRemoteSyncInterval)
+	}
+	if _, ok := sm.ingestedRemote(name); !ok {
+		return RemoteSyncStatus{}, fmt.Errorf("'%s' is not an ingested remote", name)
+	}
+	sm.mu.Lock()
+	m := sm.ingests[name]
+	m.SyncInterval = ""
+	if interval > 0 {
+		m.SyncInterval = interval.String()
+	}
+	copied := *m
+	sm.mu.Unlock()
+
+	if err := writeIngestManifest(&copied); err != nil {
+		slog.Warn("remote sync: failed to write manifest", "path", copied.Path, "err", err)
+	}
+	return sm.RemoteSyncStatus(name)
+}
+
+// RemoteSyncStatus returns how the named ingested remote follows its upstream.
+func (sm *SessionManager) RemoteSyncStatus(name string) (RemoteSyncStatus, error) {
+	m, ok := sm.ingestedRemote(name)
+	if !ok {
+		return RemoteSyncStatus{}, fmt.Errorf("'%s' is not an ingested remote", name)
+	}
+	return sm.remoteSyncStatus(m), nil
+}
+
+// RemoteSyncStatuses returns the sync status of every ingested remote, ordered by name.
+func (sm *SessionManager) RemoteSyncStatuses() []RemoteSyncStatus {
+	var statuses []RemoteSyncStatus
+	for _, m := range sm.IngestStatuses() {
+		if m, ok := sm.ingestedRemote(m.Name); ok {
+			statuses = append(statuses, sm.remoteSyncStatus(m))
+		}
+	}
+	return statuses
+}
+
+// IngestedRemoteName finds the ingested remote a name, upstream URL or
+// directory refers to, so a session's "origin" can be named by its URL.
+func (sm *SessionManager) IngestedRemoteName(ref string) (string, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for name, m := range sm.ingests {
+		if name == ref || m.URL == ref || m.Path == ref {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ingestedRemote returns the manifest of a registered remote that was
+// ingested. A failed sync leaves the manifest failed but the remote in place,
+// so it is still synced.
+func (sm *SessionManager) ingestedRemote(name string) (IngestManifest, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	m, ok := sm.ingests[name]
+	if !ok || (m.State != IngestReady && m.State != IngestFailed) {
+		return IngestManifest{}, false
+	}
+	if _, ok := sm.SharedRemotes[name]; !ok {
+		return IngestManifest{}, false
+	}
+	return *m, true
+}
+
+func (sm *SessionManager) remoteSyncStatus(m IngestManifest) RemoteSyncStatus {
+	status := RemoteSyncStatus{Remote: m.Name, URL: m.URL, Interval: m.SyncInterval}
+	r := &sm.remoteSync
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := m.UpdatedAt
+	if run, ok := r.runs[m.Name]; ok {
+		last = run.last
+		status.LastSync = &run.last
+		status.LastError = run.lastError
+	}
+	if interval := m.syncInterval(); interval > 0 {
+		next := last.Add(interval)
+		status.NextSync = &next
+	}
+	return status
+}
+
+// syncInterval parses SyncInterval; a broken value does not sync.
+func (m IngestManifest) syncInterval() time.Duration {
+	if m.SyncInterval == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(m.SyncInterval)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// SyncRemote fetches the named ingested remote from its upstream now. It
+// returns the event it published, or nil when no branch moved.
+func (sm *SessionManager) SyncRemote(ctx context.Context, name string) (*UpstreamEvent, error) {
+	m, ok := sm.ingestedRemote(name)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not an ingested remote", name)
+	}
+
+	r := &sm.remoteSync
+	r.mu.Lock()
+	if r.running[name] {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("'%s' is already syncing", name)
+	}
+	if r.running == nil {
+		r.running = make(map[string]bool)
+	}
+	r.running[name] = true
+	r.mu.Unlock()
+
+	before := sm.upstreamBranchHeads(name)
+	err := sm.IngestRemoteWith(ctx, name, m.URL, IngestOptions{Depth: m.Depth, Progress: quietIngestProgress{}, FetchOnly: true})
+
+	r.mu.Lock()
+	delete(r.running, name)
+	if r.runs == nil {
+		r.runs = make(map[string]*remoteSyncRun)
+	}
+	run := &remoteSyncRun{last: time.Now()}
+	if err != nil {
+		run.lastError = err.Error()
+	}
+	r.runs[name] = run
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, ok := sm.GetSharedRemote(name)
+	if !ok {
+		return nil, nil
+	}
+	updates := diffBranchHeads(repo, before, sm.upstreamBranchHeads(name))
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	event := sm.publishUpstreamEvent(UpstreamEvent{Remote: name, URL: m.URL, Branches: updates, At: run.last})
+	return &event, nil
+}
+
+// quietIngestProgress drops the progress of background syncs.
+type quietIngestProgress struct{}
+
+func (quietIngestProgress) Write(b []byte) (int, error) { return len(b), nil }
+func (quietIngestProgress) Phase(string)                {}
+
+// upstreamBranchHeads returns where each branch of the named shared remote points.
+func (sm *SessionManager) upstreamBranchHeads(name string) map[string]plumbing.Hash {
+	heads := make(map[string]plumbing.Hash)
+	repo, ok := sm.GetSharedRemote(name)
+	if !ok {
+		return heads
+	}
+	defer sm.RLockRemote(repo)()
+	refs, err := repo.Branches()
+	if err != nil {
+		return heads
+	}
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		heads[ref.Name().Short()] = ref.Hash()
+		return nil
+	})
+	return heads
+}
+
+// diffBranchHeads lists the branches that differ between before and after, by name.
+func diffBranchHeads(repo *gogit.Repository, before, after map[string]plumbing.Hash) []UpstreamBranchUpdate {
+	var updates []UpstreamBranchUpdate
+	var known map[plumbing.Hash]bool // Commits of the old branches, walked once a new branch shows up
+	for branch, to := range after {
+		from, existed := before[branch]
+		if existed && from == to {
+			continue
+		}
+		update := UpstreamBranchUpdate{Branch: branch, To: to.String()}
+		if existed {
+			update.From = from.String()
+			ahead, behind := AheadBehind(repo, to, from)
+			update.Commits, update.Forced = ahead, behind > 0
+		} else {
+			if known == nil {
+				starts := make([]plumbing.Hash, 0, len(before))
+				for _, hash := range before {
+					starts = append(starts, hash)
+				}
+				known = reachableCommits(repo, starts, nil)
+			}
+			update.Commits = len(reachableCommits(repo, []plumbing.Hash{to}, known))
+		}
+		updates = append(updates, update)
+	}
+	for branch, from := range before {
+		if _, ok := after[branch]; !ok {
+			updates = append(updates, UpstreamBranchUpdate{Branch: branch, From: from.String()})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Branch < updates[j].Branch })
+	return updates
+}
+
+// publishUpstreamEvent numbers the event, keeps it and hands it to the subscribers.
+func (sm *SessionManager) publishUpstreamEvent(event UpstreamEvent) UpstreamEvent {
+	r := &sm.remoteSync
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	event.Seq = r.seq
+	r.events = append(r.events, event)
+	if len(r.events) > maxUpstreamEvents {
+		r.events = r.events[len(r.events)-maxUpstreamEvents:]
+	}
+	for sub := range r.subs {
+		sub.push(event)
+	}
+	slog.Info("remote sync: upstream moved", "remote", event.Remote, "branches", describeUpstreamBranches(event.Branches))
+	return event
+}
+
+func describeUpstreamBranches(updates []UpstreamBranchUpdate) string {
+	parts := make([]string, len(updates))
+	for i, u := range updates {
+		switch {
+		case u.To == "":
+			parts[i] = u.Branch + " deleted"
+		case u.Forced:
+			parts[i] = u.Branch + " force-pushed"
+		default:
+			parts[i] = fmt.Sprintf("%s +%d", u.Branch, u.Commits)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// UpstreamEvents returns the recent upstream events, oldest first.
+func (sm *SessionManager) UpstreamEvents() []UpstreamEvent {
+	r := &sm.remoteSync
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]UpstreamEvent{}, r.events...)
+}
+
+// dueRemoteSyncs returns the ingested remotes whose interval has passed.
+func (sm *SessionManager) dueRemoteSyncs(now time.Time) []string {
+	var due []string
+	for _, status := range sm.RemoteSyncStatuses() {
+		if status.NextSync != nil && !now.Before(*status.NextSync) {
+			due = append(due, status.Remote)
+		}
+	}
+	return due
+}
+
+// StartRemoteSync syncs ingested remotes whenever their interval has passed,
+// until stop is closed. A nil stop channel keeps it running for the lifetime
+// of the process.
+func (sm *SessionManager) StartRemoteSync(stop <-chan struct{}) {
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ticker := time.NewTicker(remoteSyncTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				for _, name := range sm.dueRemoteSyncs(now) {
+					if _, err := sm.SyncRemote(ctx, name); err != nil {
+						slog.Warn("remote sync: sync failed", "remote", name, "err", err)
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// UpstreamSubscriber receives upstream events as they are published. Events
+// it does not read are queued, up to maxUpstreamEvents.
+type UpstreamSubscriber struct {
+	syncer *remoteSyncer
+	ready  chan struct{} // Signalled (capacity 1) when events are pending
+
+	mu      sync.Mutex
+	pending []UpstreamEvent
+}
+
+// SubscribeUpstream starts following upstream events. Call Close when done.
+func (sm *SessionManager) SubscribeUpstream() *UpstreamSubscriber {
+	r := &sm.remoteSync
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subs == nil {
+		r.subs = make(map[*UpstreamSubscriber]struct{})
+	}
+	sub := &UpstreamSubscriber{syncer: r, ready: make(chan struct{}, 1)}
+	r.subs[sub] = struct{}{}
+	return sub
+}
+
+func (s *UpstreamSubscriber) push(event UpstreamEvent) {
+	s.mu.Lock()
+	s.pending = append(s.pending, event)
+	if len(s.pending) > maxUpstreamEvents {
+		s.pending = s.pending[len(s.pending)-maxUpstreamEvents:]
+	}
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when Next has events.
+func (s *UpstreamSubscriber) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Next returns the events not read yet, oldest first.
+func (s *UpstreamSubscriber) Next() []UpstreamEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.pending
+	s.pending = nil
+	return events
+}
+
+// Close stops the subscription.
+func (s *UpstreamSubscriber) Close() {
+	s.syncer.mu.Lock()
+	defer s.syncer.mu.Unlock()
+	delete(s.syncer.subs, s)
+}
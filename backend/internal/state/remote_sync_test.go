@@ -0,0 +1,113 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	appconfig "github.com/kurobon/gitgym/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncRemote_PublishesUpstreamMoves(t *testing.T) {
+	tmp := t.TempDir()
+	orig := appconfig.Global
+	appconfig.Global = &appconfig.Config{DataRoot: filepath.Join(tmp, "data")}
+	t.Cleanup(func() { appconfig.Global = orig })
+
+	srcPath := filepath.Join(tmp, "upstream")
+	src, err := gogit.PlainInit(srcPath, false)
+	require.NoError(t, err)
+	w, _ := src.Worktree()
+	commit := func(content string) plumbing.Hash {
+		require.NoError(t, os.WriteFile(filepath.Join(srcPath, "README.md"), []byte(content), 0644))
+		_, _ = w.Add("README.md")
+		hash, err := w.Commit(content, &gogit.CommitOptions{
+			Author: &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return hash
+	}
+	first := commit("v1")
+
+	sm := NewSessionManager()
+	require.NoError(t, sm.IngestRemote(context.Background(), "upstream", srcPath, 0))
+
+	_, err = sm.SetRemoteSync("upstream", time.Second)
+	assert.ErrorContains(t, err, "at least")
+	_, err = sm.SetRemoteSync("elsewhere", time.Minute)
+	assert.ErrorContains(t, err, "not an ingested remote")
+	status, err := sm.SetRemoteSync("upstream", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "1m0s", status.Interval)
+	require.NotNil(t, status.NextSync)
+	assert.Empty(t, sm.dueRemoteSyncs(time.Now()))
+	assert.Equal(t, []string{"upstream"}, sm.dueRemoteSyncs(status.NextSync.Add(time.Second)))
+
+	// Nothing moved yet
+	event, err := sm.SyncRemote(context.Background(), "upstream")
+	require.NoError(t, err)
+	assert.Nil(t, event)
+
+	sub := sm.SubscribeUpstream()
+	defer sub.Close()
+
+	commit("v2")
+	head := commit("v3")
+	require.NoError(t, src.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", first)))
+	event, err = sm.SyncRemote(context.Background(), "upstream")
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, []UpstreamBranchUpdate{
+		{Branch: "feature", To: first.String(), Commits: 0},
+		{Branch: "master", From: first.String(), To: head.String(), Commits: 2},
+	}, event.Branches)
+
+	select {
+	case <-sub.Ready():
+		events := sub.Next()
+		require.Len(t, events, 1)
+		assert.Equal(t, event.Seq, events[0].Seq)
+	default:
+		t.Fatal("subscriber was not told about the upstream move")
+	}
+	assert.Len(t, sm.UpstreamEvents(), 1)
+
+	// Syncing keeps the interval, also in the manifest read on restart
+	status, err = sm.RemoteSyncStatus("upstream")
+	require.NoError(t, err)
+	assert.Equal(t, "1m0s", status.Interval)
+	assert.Empty(t, status.LastError)
+	m, err := readIngestManifest(ingestManifestPath(sm.IngestStatuses()[0].Path))
+	require.NoError(t, err)
+	assert.Equal(t, "1m0s", m.SyncInterval)
+
+	// A rewritten upstream branch is reported as forced
+	require.NoError(t, src.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", first)))
+	event, err = sm.SyncRemote(context.Background(), "upstream")
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, []UpstreamBranchUpdate{{Branch: "master", From: head.String(), To: first.String(), Forced: true}}, event.Branches)
+
+	// A failed fetch keeps the copy sessions are using
+	require.NoError(t, os.RemoveAll(srcPath))
+	_, err = sm.SyncRemote(context.Background(), "upstream")
+	assert.Error(t, err)
+	_, ok := sm.GetSharedRemote("upstream")
+	assert.True(t, ok)
+	status, err = sm.RemoteSyncStatus("upstream")
+	require.NoError(t, err)
+	assert.NotEmpty(t, status.LastError)
+	_, err = os.Stat(sm.IngestStatuses()[0].Path)
+	assert.NoError(t, err)
+
+	_, err = sm.SetRemoteSync("upstream", 0)
+	require.NoError(t, err)
+	assert.Empty(t, sm.dueRemoteSyncs(time.Now().Add(time.Hour)))
+}
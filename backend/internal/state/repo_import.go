@@ -0,0 +1,405 @@
+package state
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Repository import
+//
+// Learners can upload a zipped .git directory of a real repository to look
+// at (and repair) it in the visualizer. The archive is unpacked into the
+// session filesystem and opened with a filesystem storer; the working tree is
+// rebuilt from the index. Nothing of the upload may reach outside the session:
+// hooks are dropped, config that runs programs or carries credentials is
+// removed, and remotes are rewritten so fetch and push never leave the simulation.
+
+// Limits for uploaded repositories
+const (
+	MaxImportArchiveSize  = 50 << 20  // Compressed size of the uploaded zip
+	MaxImportUnpackedSize = 200 << 20 // Total size of the unpacked .git directory
+	MaxImportEntries      = 20000     // Files in the archive
+)
+
+// importedRemoteURL is the URL imported remotes are pointed at. It never
+// resolves, so fetch and push fail instead of contacting the original server.
+const importedRemoteURL = "imported://%s"
+
+// unsafeConfigSections are dropped from an imported config: they can run
+// programs, redirect URLs or carry credentials.
+var unsafeConfigSections = []string{"credential", "http", "https", "url", "include", "includeIf", "filter", "diff", "merge", "gpg", "submodule"}
+
+// unsafeCoreOptions are dropped from the core section for the same reason.
+var unsafeCoreOptions = []string{"hooksPath", "sshCommand", "fsmonitor", "askPass", "editor", "pager", "gitProxy", "worktree"}
+
+// ImportedRemote describes a remote of an imported repository after sanitizing.
+type ImportedRemote struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`       // Original URL without credentials, shown by git remote -v
+	Simulated bool   `json:"simulated"` // Whether the URL matches an ingested shared remote and stays usable
+}
+
+// ImportResult summarizes an imported repository.
+type ImportResult struct {
+	Repo          string           `json:"repo"`
+	Head          string           `json:"head"` // Branch name, or the abbreviated hash when detached
+	Branches      int              `json:"branches"`
+	Tags          int              `json:"tags"`
+	Remotes       []ImportedRemote `json:"remotes"`
+	RemovedConfig []string         `json:"removedConfig,omitempty"` // Config sections and options that were dropped
+	Unmerged      []string         `json:"unmerged,omitempty"`      // Conflicted paths, imported with "our" version
+}
+
+// ImportRepository unpacks a zipped .git directory into a new repository
+// named name and makes it the current directory. The archive may hold the
+// .git directory itself or its contents. Caller holds the session lock.
+func (s *Session) ImportRepository(name string, archive []byte) (*ImportResult, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." || name == ".git" {
+		return nil, fmt.Errorf("invalid repository name '%s'", name)
+	}
+	if _, err := s.Filesystem.Stat(name); err == nil {
+		return nil, fmt.Errorf("fatal: destination path '%s' already exists", name)
+	}
+
+	// Unpack into a scratch filesystem first so a bad archive leaves no trace
+	dotGit := memfs.New()
+	if err := unpackGitArchive(archive, dotGit); err != nil {
+		return nil, err
+	}
+	if _, err := gogit.Open(filesystem.NewStorage(dotGit, cache.NewObjectLRUDefault()), nil); err != nil {
+		return nil, fmt.Errorf("archive is not a git repository: %w", err)
+	}
+
+	if err := copyBillyDir(dotGit, "/", s.Filesystem, path.Join(name, ".git")); err != nil {
+		_ = s.RemoveAll(name)
+		return nil, fmt.Errorf("failed to unpack repository: %w", err)
+	}
+	repo, result, err := s.openImportedRepo(name)
+	if err != nil {
+		_ = s.RemoveAll(name)
+		return nil, err
+	}
+
+	s.Repos[name] = repo
+	s.CurrentDir = "/" + name
+	s.FileCache.Invalidate()
+	s.RecordReflog(fmt.Sprintf("import: %s", name))
+	return result, nil
+}
+
+// openImportedRepo opens the unpacked repository, sanitizes it and checks out its index.
+func (s *Session) openImportedRepo(name string) (*gogit.Repository, *ImportResult, error) {
+	repoFS, err := s.Filesystem.Chroot(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	dotGitFS, err := repoFS.Chroot(".git")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Hooks never run here, but there is no reason to keep foreign scripts around
+	_ = util.RemoveAll(dotGitFS, "hooks")
+
+	repo, err := gogit.Open(filesystem.NewStorage(dotGitFS, cache.NewObjectLRUDefault()), repoFS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("archive is not a git repository: %w", err)
+	}
+
+	// The config is sanitized last, so nothing written before can bring back what it removes
+	result := &ImportResult{Repo: name}
+	if err := checkoutImportedIndex(repo, repoFS, result); err != nil {
+		return nil, nil, err
+	}
+	if err := s.sanitizeImportedConfig(repo, result); err != nil {
+		return nil, nil, err
+	}
+
+	if head, err := repo.Head(); err == nil {
+		if head.Name().IsBranch() {
+			result.Head = head.Name().Short()
+		} else {
+			result.Head = head.Hash().String()[:7]
+		}
+	}
+	if refs, err := repo.References(); err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			switch {
+			case ref.Name().IsBranch():
+				result.Branches++
+			case ref.Name().IsTag():
+				result.Tags++
+			}
+			return nil
+		})
+	}
+	return repo, result, nil
+}
+
+// unpackGitArchive extracts the .git directory found in archive into dst.
+func unpackGitArchive(archive []byte, dst billy.Filesystem) error {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	if len(zr.File) > MaxImportEntries {
+		return fmt.Errorf("archive has too many entries (limit %d)", MaxImportEntries)
+	}
+
+	prefix, ok := gitDirPrefix(zr.File)
+	if !ok {
+		return fmt.Errorf("archive does not contain a .git directory")
+	}
+
+	var unpacked int64
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		rel := strings.TrimPrefix(f.Name, prefix)
+		if rel == "" || f.FileInfo().IsDir() {
+			continue
+		}
+		// Symlinks could point anywhere; a plain .git directory has none
+		if f.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		clean := path.Clean(rel)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(rel, `\`) {
+			return fmt.Errorf("archive entry '%s' escapes the .git directory", f.Name)
+		}
+
+		n, err := extractZipEntry(f, dst, clean, MaxImportUnpackedSize-unpacked)
+		if err != nil {
+			return err
+		}
+		unpacked += n
+	}
+	return nil
+}
+
+// gitDirPrefix finds the archive path the .git directory is stored under:
+// "[dir/].git/" when the directory itself was zipped, "" for its contents.
+func gitDirPrefix(files []*zip.File) (string, bool) {
+	prefix, found := "", false
+	for _, f := range files {
+		if f.Name != "HEAD" && !strings.HasSuffix(f.Name, "/HEAD") {
+			continue
+		}
+		dir := strings.TrimSuffix(f.Name, "HEAD")
+		if dir != "" && !strings.HasSuffix(dir, ".git/") {
+			continue
+		}
+		// Prefer the outermost repository over nested ones (e.g. modules/*)
+		if !found || len(dir) < len(prefix) {
+			prefix, found = dir, true
+		}
+	}
+	return prefix, found
+}
+
+// extractZipEntry writes f to name in dst, failing once more than budget bytes are written.
+func extractZipEntry(f *zip.File, dst billy.Filesystem, name string, budget int64) (int64, error) {
+	in, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read '%s': %w", f.Name, err)
+	}
+	defer in.Close()
+
+	if err := dst.MkdirAll(path.Dir(name), 0755); err != nil {
+		return 0, err
+	}
+	out, err := dst.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	// The declared size cannot be trusted, so the copy itself is capped
+	n, err := io.Copy(out, io.LimitReader(in, budget+1))
+	_ = out.Close()
+	if err != nil {
+		return n, fmt.Errorf("failed to unpack '%s': %w", f.Name, err)
+	}
+	if n > budget {
+		return n, fmt.Errorf("repository is too large once unpacked (limit %d MB)", MaxImportUnpackedSize>>20)
+	}
+	return n, nil
+}
+
+// sanitizeImportedConfig strips dangerous config and neutralizes remotes.
+// A remote keeps working only when its URL names a shared remote ingested into the simulation.
+func (s *Session) sanitizeImportedConfig(repo *gogit.Repository, result *ImportResult) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	for _, section := range unsafeConfigSections {
+		if cfg.Raw.HasSection(section) {
+			cfg.Raw.RemoveSection(section)
+			result.RemovedConfig = append(result.RemovedConfig, section)
+		}
+	}
+	core := cfg.Raw.Section("core")
+	for _, option := range unsafeCoreOptions {
+		if core.HasOption(option) {
+			core.RemoveOption(option)
+			result.RemovedConfig = append(result.RemovedConfig, "core."+option)
+		}
+	}
+	cfg.Submodules = nil
+
+	names := make([]string, 0, len(cfg.Remotes))
+	for remoteName := range cfg.Remotes {
+		names = append(names, remoteName)
+	}
+	sort.Strings(names)
+
+	for _, remoteName := range names {
+		remote := cfg.Remotes[remoteName]
+		original := ""
+		if len(remote.URLs) > 0 {
+			original = redactURL(remote.URLs[0])
+		}
+
+		imported := ImportedRemote{Name: remoteName, URL: original}
+		if s.isSharedRemote(original) {
+			imported.Simulated = true
+			remote.URLs = []string{original}
+		} else {
+			remote.URLs = []string{fmt.Sprintf(importedRemoteURL, remoteName)}
+		}
+		result.Remotes = append(result.Remotes, imported)
+
+		// Keep showing where the remote came from in git remote -v
+		sub := cfg.Raw.Section("remote").Subsection(remoteName)
+		sub.RemoveOption("pushurl")
+		sub.RemoveOption("proxy")
+		sub.RemoveOption("uploadpack")
+		sub.RemoveOption("receivepack")
+		if original != "" {
+			sub.SetOption("displayurl", original)
+		}
+	}
+
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write repository config: %w", err)
+	}
+	return nil
+}
+
+// isSharedRemote reports whether url names a remote ingested into the simulation.
+func (s *Session) isSharedRemote(url string) bool {
+	if s.Manager == nil || url == "" {
+		return false
+	}
+	_, ok := s.Manager.GetSharedRemote(url)
+	return ok
+}
+
+// redactURL removes user names and passwords from a remote URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+// checkoutImportedIndex rebuilds the working tree from the index, so staged
+// changes of the uploaded repository stay staged. Conflicted entries keep
+// "our" version, since go-git cannot work with an unmerged index. An entry
+// whose path git would refuse to check out fails the import before anything
+// is written.
+func checkoutImportedIndex(repo *gogit.Repository, worktree billy.Filesystem, result *ImportResult) error {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	// Regular entries decode with stage 0; go-git's index.Merged constant is 1
+	entries := make([]*index.Entry, 0, len(idx.Entries))
+	for _, e := range idx.Entries {
+		if !verifyIndexPath(e.Name) {
+			return fmt.Errorf("error: invalid path '%s' in the index", e.Name)
+		}
+		switch e.Stage {
+		case 0:
+		case index.OurMode:
+			e.Stage = 0
+			result.Unmerged = append(result.Unmerged, e.Name)
+		default:
+			continue
+		}
+		entries = append(entries, e)
+	}
+	idx.Entries = entries
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	for _, e := range idx.Entries {
+		if e.Mode == filemode.Submodule || e.Mode == filemode.Symlink {
+			continue
+		}
+		blob, err := repo.BlobObject(e.Hash)
+		if err != nil {
+			continue // Missing objects (e.g. partial clones) simply stay absent
+		}
+		r, err := blob.Reader()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+		perm := os.FileMode(0644)
+		if e.Mode == filemode.Executable {
+			perm = 0755
+		}
+		if err := util.WriteFile(worktree, e.Name, content, perm); err != nil {
+			return fmt.Errorf("failed to check out '%s': %w", e.Name, err)
+		}
+	}
+	sort.Strings(result.Unmerged)
+	return nil
+}
+
+// verifyIndexPath reports whether name is a path git would check out, as
+// git's verify_path does with core.protectNTFS: relative, without empty, "."
+// or ".." components and without a ".git" component, compared ignoring case
+// and the trailing dots and spaces NTFS drops (and its short name "git~1").
+func verifyIndexPath(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.ContainsAny(name, "\\\x00") {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".", "..":
+			return false
+		}
+		trimmed := strings.ToLower(strings.TrimRight(part, ". "))
+		if trimmed == ".git" || trimmed == "git~1" {
+			return false
+		}
+	}
+	return true
+}
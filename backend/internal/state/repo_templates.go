@@ -0,0 +1,166 @@
+package state
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// New repository templates
+//
+// A repository created empty has no branch to check out, which trips up
+// clone and confuses beginners. Like GitHub's "new repository" dialog,
+// CreateBareRepositoryWith can start it with an initial commit holding a
+// README, a .gitignore template and a license, on a chosen default branch.
+
+//go:embed templates/gitignore/*.gitignore templates/licenses/*.txt
+var repoTemplates embed.FS
+
+// DefaultInitialBranch is the branch an initialized repository starts on
+// when no default branch is given.
+const DefaultInitialBranch = "main"
+
+// BareRepoOptions describes the initial content of a created repository. The
+// zero value creates an empty repository.
+type BareRepoOptions struct {
+	DefaultBranch string `json:"defaultBranch,omitempty"` // Branch HEAD points to; DefaultInitialBranch when the repository gets content
+	Description   string `json:"description,omitempty"`   // Written under the title of the README
+	README        bool   `json:"readme,omitempty"`
+	Gitignore     string `json:"gitignore,omitempty"` // Template name, see RepoTemplates
+	License       string `json:"license,omitempty"`   // License key, see RepoTemplates
+}
+
+// RepoTemplateList names the .gitignore templates and licenses a repository can start with.
+type RepoTemplateList struct {
+	Gitignore []string `json:"gitignore"`
+	Licenses  []string `json:"licenses"`
+}
+
+// RepoTemplates lists the available templates, sorted by name.
+func RepoTemplates() RepoTemplateList {
+	return RepoTemplateList{
+		Gitignore: templateNames("templates/gitignore", ".gitignore"),
+		Licenses:  templateNames("templates/licenses", ".txt"),
+	}
+}
+
+func templateNames(dir, ext string) []string {
+	entries, _ := repoTemplates.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ext))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readTemplate returns a template by name, ignoring case as GitHub does.
+func readTemplate(dir, ext, kind, name string) ([]byte, error) {
+	for _, known := range templateNames(dir, ext) {
+		if strings.EqualFold(known, name) {
+			return repoTemplates.ReadFile(path.Join(dir, known+ext))
+		}
+	}
+	return nil, fmt.Errorf("unknown %s template '%s' (available: %s)", kind, name, strings.Join(templateNames(dir, ext), ", "))
+}
+
+// hasContent reports whether the options ask for an initial commit.
+func (o BareRepoOptions) hasContent() bool {
+	return o.README || o.Gitignore != "" || o.License != ""
+}
+
+// Validate checks the branch name and that the templates exist.
+func (o BareRepoOptions) Validate() error {
+	if o.DefaultBranch != "" {
+		if err := plumbing.NewBranchReferenceName(o.DefaultBranch).Validate(); err != nil {
+			return fmt.Errorf("invalid default branch '%s'", o.DefaultBranch)
+		}
+	}
+	if o.Gitignore != "" {
+		if _, err := readTemplate("templates/gitignore", ".gitignore", ".gitignore", o.Gitignore); err != nil {
+			return err
+		}
+	}
+	if o.License != "" {
+		if _, err := readTemplate("templates/licenses", ".txt", "license", o.License); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initBareRepo points HEAD of a freshly created repository at the default
+// branch and stores the initial commit the options ask for, authored by user.
+func initBareRepo(repo *gogit.Repository, name string, opts BareRepoOptions, user UserIdentity) error {
+	branch := opts.DefaultBranch
+	if branch == "" {
+		if !opts.hasContent() {
+			return nil
+		}
+		branch = DefaultInitialBranch
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref)); err != nil {
+		return err
+	}
+	if !opts.hasContent() {
+		return nil
+	}
+
+	files := make(map[string][]byte)
+	if opts.README {
+		readme := "# " + name + "\n"
+		if opts.Description != "" {
+			readme += "\n" + opts.Description + "\n"
+		}
+		files["README.md"] = []byte(readme)
+	}
+	if opts.Gitignore != "" {
+		data, err := readTemplate("templates/gitignore", ".gitignore", ".gitignore", opts.Gitignore)
+		if err != nil {
+			return err
+		}
+		files[".gitignore"] = data
+	}
+	if opts.License != "" {
+		data, err := readTemplate("templates/licenses", ".txt", "license", opts.License)
+		if err != nil {
+			return err
+		}
+		text := strings.NewReplacer("[year]", strconv.Itoa(time.Now().Year()), "[fullname]", user.Name).Replace(string(data))
+		files["LICENSE"] = []byte(text)
+	}
+
+	entries := make(map[string]object.TreeEntry, len(files))
+	for path, data := range files {
+		hash, err := storeBlobObject(repo, data)
+		if err != nil {
+			return err
+		}
+		entries[path] = object.TreeEntry{Name: path, Mode: filemode.Regular, Hash: hash}
+	}
+	tree, err := storeFlatTree(repo, entries)
+	if err != nil {
+		return err
+	}
+	sig := object.Signature{Name: user.Name, Email: user.Email, When: time.Now()}
+	commit, err := storeEncodable(repo, &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   "Initial commit\n",
+		TreeHash:  tree,
+	})
+	if err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(ref, commit))
+}
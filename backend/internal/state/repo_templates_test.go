@@ -0,0 +1,78 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBareRepositoryWith_InitialCommit(t *testing.T) {
+	t.Setenv("GITGYM_DATA_ROOT", t.TempDir())
+	sm := NewSessionManager()
+	s, err := sm.CreateSession("creator")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetSessionUser(s, UserIdentity{Name: "Alice", Email: "alice@example.com"}))
+
+	require.NoError(t, sm.CreateBareRepositoryWith(context.Background(), "creator", "starter", BareRepoOptions{
+		README:      true,
+		Description: "A place to practice",
+		Gitignore:   "go",
+		License:     "MIT",
+	}))
+	repo, ok := sm.GetSharedRemote("starter")
+	require.True(t, ok)
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.NewBranchReferenceName(DefaultInitialBranch), head.Target())
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(DefaultInitialBranch), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Initial commit\n", commit.Message)
+	assert.Equal(t, "Alice", commit.Author.Name)
+	assert.Empty(t, commit.ParentHashes)
+
+	readFile := func(name string) string {
+		f, err := commit.File(name)
+		require.NoError(t, err, name)
+		content, err := f.Contents()
+		require.NoError(t, err)
+		return content
+	}
+	assert.Equal(t, "# starter\n\nA place to practice\n", readFile("README.md"))
+	assert.Contains(t, readFile(".gitignore"), "go.work")
+	license := readFile("LICENSE")
+	assert.True(t, strings.HasPrefix(license, "MIT License"))
+	assert.Contains(t, license, "Copyright (c) "+strconv.Itoa(time.Now().Year())+" Alice")
+}
+
+func TestCreateBareRepositoryWith_Options(t *testing.T) {
+	t.Setenv("GITGYM_DATA_ROOT", t.TempDir())
+	sm := NewSessionManager()
+
+	// Only a default branch: still empty, but clones land on it once pushed to
+	require.NoError(t, sm.CreateBareRepositoryWith(context.Background(), "s", "empty", BareRepoOptions{DefaultBranch: "trunk"}))
+	repo, _ := sm.GetSharedRemote("empty")
+	head, err := repo.Reference(plumbing.HEAD, false)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.NewBranchReferenceName("trunk"), head.Target())
+	_, err = repo.Reference(head.Target(), true)
+	assert.Error(t, err, "no commit was made")
+
+	assert.ErrorContains(t, sm.CreateBareRepositoryWith(context.Background(), "s", "bad", BareRepoOptions{License: "GPL-9"}), "unknown license template 'GPL-9'")
+	assert.ErrorContains(t, sm.CreateBareRepositoryWith(context.Background(), "s", "bad", BareRepoOptions{DefaultBranch: "two..dots"}), "invalid default branch")
+	_, ok := sm.GetSharedRemote("bad")
+	assert.False(t, ok)
+
+	templates := RepoTemplates()
+	assert.Contains(t, templates.Gitignore, "Go")
+	assert.Contains(t, templates.Licenses, "MIT")
+}
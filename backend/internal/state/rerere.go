@@ -0,0 +1,51 @@
+package state
+
+import "time"
+
+// RerereCache is a repository's rr-cache: the conflicts git rerere has seen,
+// with their resolutions once recorded, and the conflicts of the stopped
+// operation it still tracks (git's MERGE_RR).
+type RerereCache struct {
+	Resolutions map[string]*RerereResolution `json:"resolutions"` // Keyed by conflict ID
+	Pending     map[string]string            `json:"pending"`     // Conflicted path -> conflict ID
+}
+
+// RerereResolution is a recorded conflict and, once known, how it was resolved.
+type RerereResolution struct {
+	Path       string    `json:"path"`                // Path the conflict was last seen at
+	Preimage   string    `json:"preimage"`            // Conflicted content, normalized
+	Conflict   string    `json:"conflict"`            // Conflicted content as last written to the worktree
+	Postimage  string    `json:"postimage,omitempty"` // Resolved content; empty until recorded
+	Resolved   bool      `json:"resolved"`            // A postimage has been recorded
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// RerereCache returns the rr-cache of the active repository, creating it.
+func (s *Session) RerereCache() *RerereCache {
+	if s.Rerere == nil {
+		s.Rerere = make(map[string]*RerereCache)
+	}
+	repo := s.activeRepoPath()
+	rr := s.Rerere[repo]
+	if rr == nil {
+		rr = &RerereCache{Resolutions: make(map[string]*RerereResolution), Pending: make(map[string]string)}
+		s.Rerere[repo] = rr
+	}
+	return rr
+}
+
+// clone returns a deep copy of the cache.
+func (rr *RerereCache) clone() *RerereCache {
+	c := &RerereCache{
+		Resolutions: make(map[string]*RerereResolution, len(rr.Resolutions)),
+		Pending:     make(map[string]string, len(rr.Pending)),
+	}
+	for id, res := range rr.Resolutions {
+		r := *res
+		c.Resolutions[id] = &r
+	}
+	for path, id := range rr.Pending {
+		c.Pending[path] = id
+	}
+	return c
+}
@@ -0,0 +1,187 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Save points
+//
+// A save point is a named UndoSnapshot the learner keeps: unlike the undo
+// history, later commands do not push it out, so a learner can try something
+// risky and come back to it as often as they like. Restoring one goes on the
+// undo stack like a command, so "gitgym undo" takes the restore back.
+//
+// Missions take save points themselves: one at the start, and one before each
+// command their policy lists in SaveBefore. Those are automatic and make room
+// for newer ones when the limit is reached. Like the undo history, save points
+// only live in memory.
+
+// maxSavePoints caps how many save points a session keeps.
+const maxSavePoints = 20
+
+// maxSavePointName is the longest name of a save point, in characters.
+const maxSavePointName = 64
+
+// Errors of the save point operations.
+var (
+	ErrSavePointNotFound = errors.New("no such save point")
+	ErrSavePointExists   = errors.New("a save point with that name already exists")
+	ErrTooManySavePoints = fmt.Errorf("too many save points (limit %d), delete one first", maxSavePoints)
+)
+
+// SavePoint is a named state of a session that can be restored.
+type SavePoint struct {
+	Name      string    `json:"name"`
+	Command   string    `json:"command,omitempty"` // Automatic save points: the command that followed
+	Auto      bool      `json:"auto,omitempty"`    // Taken by a mission rather than the learner
+	CreatedAt time.Time `json:"createdAt"`
+
+	snap *UndoSnapshot
+}
+
+// CreateSavePoint takes a save point of the current state. An empty name
+// picks the next free "save-N". Caller holds the session lock.
+func (s *Session) CreateSavePoint(name string) (SavePoint, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = s.nextSavePointName("save")
+	}
+	if err := validSavePointName(name); err != nil {
+		return SavePoint{}, err
+	}
+	if s.savePoint(name) != nil {
+		return SavePoint{}, fmt.Errorf("%w: '%s'", ErrSavePointExists, name)
+	}
+	snap, err := s.TakeUndoSnapshot("")
+	if err != nil {
+		return SavePoint{}, err
+	}
+	sp := &SavePoint{Name: name, CreatedAt: snap.TakenAt, snap: snap}
+	if err := s.addSavePoint(sp); err != nil {
+		return SavePoint{}, err
+	}
+	return *sp, nil
+}
+
+// AddAutoSavePoint keeps snap, taken before a risky command of a mission, as
+// an automatic save point "auto-N". Caller holds the session lock.
+func (s *Session) AddAutoSavePoint(snap *UndoSnapshot) (SavePoint, error) {
+	sp := &SavePoint{
+		Name:      s.nextSavePointName("auto"),
+		Command:   snap.Command,
+		Auto:      true,
+		CreatedAt: snap.TakenAt,
+		snap:      snap,
+	}
+	if err := s.addSavePoint(sp); err != nil {
+		return SavePoint{}, err
+	}
+	return *sp, nil
+}
+
+// addSavePoint appends sp, dropping the oldest automatic save point when the
+// session has as many as it may keep.
+func (s *Session) addSavePoint(sp *SavePoint) error {
+	if len(s.savePoints) >= maxSavePoints {
+		oldest := -1
+		for i, p := range s.savePoints {
+			if p.Auto {
+				oldest = i
+				break
+			}
+		}
+		if oldest < 0 {
+			return ErrTooManySavePoints
+		}
+		s.savePoints = append(s.savePoints[:oldest:oldest], s.savePoints[oldest+1:]...)
+	}
+	s.savePoints = append(s.savePoints, sp)
+	return nil
+}
+
+// RestoreSavePoint puts the session back in the state of the save point
+// name. The state it replaces goes on the undo stack. Caller holds the
+// session lock.
+func (s *Session) RestoreSavePoint(name string) (SavePoint, error) {
+	sp := s.savePoint(name)
+	if sp == nil {
+		return SavePoint{}, fmt.Errorf("%w: '%s'", ErrSavePointNotFound, name)
+	}
+	current, err := s.TakeUndoSnapshot("gitgym restore " + name)
+	if err != nil {
+		return SavePoint{}, err
+	}
+	if err := s.restoreSnapshot(sp.snap); err != nil {
+		return SavePoint{}, err
+	}
+	s.undoStack = pushSnapshot(s.undoStack, current)
+	s.redoStack = nil
+	return *sp, nil
+}
+
+// DeleteSavePoint forgets the save point name. Caller holds the session lock.
+func (s *Session) DeleteSavePoint(name string) error {
+	for i, sp := range s.savePoints {
+		if sp.Name == name {
+			s.savePoints = append(s.savePoints[:i:i], s.savePoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s'", ErrSavePointNotFound, name)
+}
+
+// SavePoints returns the save points of the session, oldest first. Caller
+// holds at least the session's read lock.
+func (s *Session) SavePoints() []SavePoint {
+	points := make([]SavePoint, len(s.savePoints))
+	for i, sp := range s.savePoints {
+		points[i] = *sp
+	}
+	return points
+}
+
+// ClearSavePoints forgets every save point, e.g. when a mission starts over.
+func (s *Session) ClearSavePoints() {
+	s.savePoints = nil
+}
+
+func (s *Session) savePoint(name string) *SavePoint {
+	for _, sp := range s.savePoints {
+		if sp.Name == name {
+			return sp
+		}
+	}
+	return nil
+}
+
+// nextSavePointName returns prefix-N for the lowest N not taken.
+func (s *Session) nextSavePointName(prefix string) string {
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s-%d", prefix, n)
+		if s.savePoint(name) == nil {
+			return name
+		}
+	}
+}
+
+// validSavePointName checks a name given by the learner. Names may contain
+// spaces, but may not start with "-", which would read as a flag in the terminal.
+func validSavePointName(name string) error {
+	if utf8.RuneCountInString(name) > maxSavePointName {
+		return fmt.Errorf("save point name too long (limit %d characters)", maxSavePointName)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid save point name '%s': it may not start with '-'", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("invalid save point name %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavePoints(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "save-points", 3)
+
+	sp, err := sess.CreateSavePoint("  all three ")
+	require.NoError(t, err)
+	assert.Equal(t, "all three", sp.Name)
+	_, err = sess.CreateSavePoint("all three")
+	assert.ErrorIs(t, err, ErrSavePointExists)
+	for _, name := range []string{"-d", strings.Repeat("x", maxSavePointName+1), "two\nlines"} {
+		_, err = sess.CreateSavePoint(name)
+		assert.Error(t, err, name)
+	}
+
+	// The save point keeps the rewound commits from garbage collection
+	rewindMaster(t, sess, hashes[0])
+	result, err := sess.CollectGarbage("repo", false)
+	require.NoError(t, err)
+	assert.Zero(t, result.Pruned)
+
+	_, err = sess.RestoreSavePoint("all three")
+	require.NoError(t, err)
+	head, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	assert.Equal(t, hashes[2], head.Hash().String())
+	undo, _ := sess.UndoHistory()
+	assert.Equal(t, []string{"gitgym restore all three"}, undo)
+
+	_, err = sess.RestoreSavePoint("missing")
+	assert.ErrorIs(t, err, ErrSavePointNotFound)
+	require.NoError(t, sess.DeleteSavePoint("all three"))
+	assert.ErrorIs(t, sess.DeleteSavePoint("all three"), ErrSavePointNotFound)
+	assert.Empty(t, sess.SavePoints())
+}
+
+func TestSavePoints_Limit(t *testing.T) {
+	sm := NewSessionManager()
+	sess, _, _ := linearHistorySession(t, sm, "save-points-limit", 1)
+
+	_, err := sess.CreateSavePoint("start")
+	require.NoError(t, err)
+	snap, err := sess.TakeUndoSnapshot("git rebase main")
+	require.NoError(t, err)
+	for i := 1; i < maxSavePoints; i++ {
+		_, err := sess.AddAutoSavePoint(snap)
+		require.NoError(t, err)
+	}
+
+	// Automatic save points make room, oldest first; the learner's own stay
+	sp, err := sess.AddAutoSavePoint(snap)
+	require.NoError(t, err)
+	assert.Equal(t, "auto-20", sp.Name)
+	points := sess.SavePoints()
+	require.Len(t, points, maxSavePoints)
+	assert.Equal(t, "start", points[0].Name)
+	assert.Equal(t, "auto-2", points[1].Name)
+	assert.Equal(t, "git rebase main", points[1].Command)
+
+	sess.ClearSavePoints()
+	for i := 0; i < maxSavePoints; i++ {
+		_, err := sess.CreateSavePoint(fmt.Sprintf("mine-%d", i))
+		require.NoError(t, err)
+	}
+	_, err = sess.CreateSavePoint("")
+	assert.ErrorIs(t, err, ErrTooManySavePoints)
+	_, err = sess.AddAutoSavePoint(snap)
+	assert.ErrorIs(t, err, ErrTooManySavePoints)
+}
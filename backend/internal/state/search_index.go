@@ -0,0 +1,363 @@
+package state
+
+// search_index.go - Commit search over the repositories of a session
+//
+// Searching by author or path would otherwise mean reading every commit and
+// diffing every tree on each query, which is slow on ingested repos. The index
+// keeps what a search needs in memory, with inverted lists from authors and
+// touched paths to commits. Commits never change, so it is updated
+// incrementally: each search first indexes only the commits reachable from the
+// refs that it has not seen yet. Reachability is checked at query time, so
+// commits dropped by a reset or rebase stop matching.
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CommitQuery selects commits by message, author, touched path and date.
+// Zero fields do not filter.
+type CommitQuery struct {
+	Message    string    // Substring of the message, or a regular expression with Regex
+	Regex      bool      // Message is a regular expression
+	IgnoreCase bool      // Match Message ignoring case
+	Author     string    // Substring of "Name <email>", ignoring case
+	Path       string    // File or directory, relative to the repository root, the commit changed
+	Since      time.Time // Authored at or after
+	Until      time.Time // Authored at or before
+	Limit      int       // Maximum commits returned; 0 means unlimited
+}
+
+// CommitSummary is a commit matched by a search.
+type CommitSummary struct {
+	ID      string   `json:"id"`
+	Parents []string `json:"parents"`
+	Author  string   `json:"author"`
+	Email   string   `json:"email"`
+	Date    string   `json:"date"` // RFC3339
+	Message string   `json:"message"`
+}
+
+// CommitSearchResult lists the matching commits, newest first.
+type CommitSearchResult struct {
+	Commits []CommitSummary `json:"commits"`
+	Total   int             `json:"total"`   // Matches before Limit was applied
+	Indexed int             `json:"indexed"` // Commits in the index of the repository
+}
+
+// SearchIndex indexes the commits of each repository of a session, keyed by
+// repository path. It has its own lock so searches can run under the session's
+// read lock.
+type SearchIndex struct {
+	mu    sync.Mutex
+	repos map[string]*commitIndex
+}
+
+type commitIndex struct {
+	commits  map[plumbing.Hash]*indexedCommit
+	byAuthor map[string][]plumbing.Hash // Lowercased "Name <email>" -> commits
+	byPath   map[string][]plumbing.Hash // Changed file, and each directory above it -> commits
+}
+
+type indexedCommit struct {
+	hash    plumbing.Hash
+	parents []plumbing.Hash
+	author  string
+	email   string
+	when    time.Time
+	message string
+}
+
+// Stats returns the number of commits indexed across all repositories.
+func (si *SearchIndex) Stats() (commits int) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	for _, idx := range si.repos {
+		commits += len(idx.commits)
+	}
+	return commits
+}
+
+// Search returns the commits reachable from the refs of repo that match q,
+// indexing the ones it has not seen first.
+func (si *SearchIndex) Search(repoPath string, repo *gogit.Repository, q CommitQuery) (*CommitSearchResult, error) {
+	var message *regexp.Regexp
+	if q.Message != "" {
+		pattern := q.Message
+		if !q.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if q.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		var err error
+		if message, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid message pattern: %v", err)
+		}
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if si.repos == nil {
+		si.repos = make(map[string]*commitIndex)
+	}
+	idx, ok := si.repos[repoPath]
+	if !ok {
+		idx = &commitIndex{
+			commits:  make(map[plumbing.Hash]*indexedCommit),
+			byAuthor: make(map[string][]plumbing.Hash),
+			byPath:   make(map[string][]plumbing.Hash),
+		}
+		si.repos[repoPath] = idx
+	}
+
+	tips, err := refTips(repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.update(repo, tips); err != nil {
+		return nil, err
+	}
+	reachable := idx.reachable(tips)
+
+	// Narrow down with the inverted lists, then check the rest on each candidate
+	var candidates map[plumbing.Hash]bool
+	narrow := func(hashes []plumbing.Hash) {
+		set := make(map[plumbing.Hash]bool, len(hashes))
+		for _, h := range hashes {
+			if candidates == nil || candidates[h] {
+				set[h] = true
+			}
+		}
+		candidates = set
+	}
+	if q.Author != "" {
+		author := strings.ToLower(q.Author)
+		var hashes []plumbing.Hash
+		for key, list := range idx.byAuthor {
+			if strings.Contains(key, author) {
+				hashes = append(hashes, list...)
+			}
+		}
+		narrow(hashes)
+	}
+	if p := strings.Trim(strings.TrimPrefix(q.Path, "./"), "/"); p != "" && p != "." {
+		narrow(idx.byPath[p])
+	}
+	if candidates == nil {
+		candidates = reachable
+	}
+
+	var matches []*indexedCommit
+	for h := range candidates {
+		c := idx.commits[h]
+		switch {
+		case !reachable[h]:
+		case message != nil && !message.MatchString(c.message):
+		case !q.Since.IsZero() && c.when.Before(q.Since):
+		case !q.Until.IsZero() && c.when.After(q.Until):
+		default:
+			matches = append(matches, c)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].when.Equal(matches[j].when) {
+			return matches[i].when.After(matches[j].when)
+		}
+		return matches[i].hash.String() < matches[j].hash.String()
+	})
+
+	result := &CommitSearchResult{Commits: []CommitSummary{}, Total: len(matches), Indexed: len(idx.commits)}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	for _, c := range matches {
+		summary := CommitSummary{
+			ID:      c.hash.String(),
+			Parents: []string{},
+			Author:  c.author,
+			Email:   c.email,
+			Date:    c.when.Format(time.RFC3339),
+			Message: c.message,
+		}
+		for _, p := range c.parents {
+			summary.Parents = append(summary.Parents, p.String())
+		}
+		result.Commits = append(result.Commits, summary)
+	}
+	return result, nil
+}
+
+// refTips returns the commits HEAD and every ref point at, tags peeled.
+func refTips(repo *gogit.Repository) ([]plumbing.Hash, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	var tips []plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || IsSpecialRef(ref.Name()) {
+			return nil
+		}
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			if commit, err := tag.Commit(); err == nil {
+				hash = commit.Hash
+			}
+		}
+		tips = append(tips, hash)
+		return nil
+	})
+	if head, err := repo.Head(); err == nil {
+		tips = append(tips, head.Hash())
+	}
+	return tips, err
+}
+
+// update indexes the commits reachable from tips that are not indexed yet.
+// The walk stops at indexed commits, as their ancestors are indexed too.
+func (idx *commitIndex) update(repo *gogit.Repository, tips []plumbing.Hash) error {
+	stack := append([]plumbing.Hash(nil), tips...)
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := idx.commits[h]; ok {
+			continue
+		}
+		commit, err := repo.CommitObject(h)
+		if err != nil {
+			continue // Not a commit (e.g. a tag on a tree), or cut off by a shallow clone
+		}
+		if err := idx.add(commit); err != nil {
+			return err
+		}
+		stack = append(stack, commit.ParentHashes...)
+	}
+	return nil
+}
+
+func (idx *commitIndex) add(commit *object.Commit) error {
+	c := &indexedCommit{
+		hash:    commit.Hash,
+		parents: commit.ParentHashes,
+		author:  commit.Author.Name,
+		email:   commit.Author.Email,
+		when:    commit.Author.When,
+		message: commit.Message,
+	}
+	paths, err := touchedPaths(commit)
+	if err != nil {
+		return err
+	}
+	idx.commits[c.hash] = c
+	author := strings.ToLower(fmt.Sprintf("%s <%s>", c.author, c.email))
+	idx.byAuthor[author] = append(idx.byAuthor[author], c.hash)
+	for _, p := range paths {
+		idx.byPath[p] = append(idx.byPath[p], c.hash)
+	}
+	return nil
+}
+
+// touchedPaths lists the files commit changed and the directories above them.
+// A merge only touches what differs from every parent, as for git log -- <path>.
+func touchedPaths(commit *object.Commit) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string]int
+	parents := 0
+	err = commit.Parents().ForEach(func(parent *object.Commit) error {
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return nil // Parent missing from a shallow clone
+		}
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return err
+		}
+		parents++
+		seen := make(map[string]bool)
+		for _, change := range changes {
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if name != "" && !seen[name] {
+					seen[name] = true
+					if files == nil {
+						files = make(map[string]int)
+					}
+					files[name]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	if parents == 0 {
+		err = tree.Files().ForEach(func(f *object.File) error {
+			changed = append(changed, f.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, n := range files {
+		if n == parents {
+			changed = append(changed, name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, name := range changed {
+		for p := name; p != "." && p != "/" && !seen[p]; p = path.Dir(p) {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// reachable returns the indexed commits reachable from tips.
+func (idx *commitIndex) reachable(tips []plumbing.Hash) map[plumbing.Hash]bool {
+	seen := make(map[plumbing.Hash]bool)
+	stack := append([]plumbing.Hash(nil), tips...)
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		c, ok := idx.commits[h]
+		if !ok || seen[h] {
+			continue
+		}
+		seen[h] = true
+		stack = append(stack, c.parents...)
+	}
+	return seen
+}
+
+// SearchCommits searches the commits of the repository at the current directory.
+func (s *Session) SearchCommits(q CommitQuery) (*CommitSearchResult, error) {
+	repo := s.GetRepo()
+	if repo == nil {
+		return nil, fmt.Errorf("fatal: not a git repository")
+	}
+	index := s.SearchIndex
+	if index == nil {
+		index = &SearchIndex{} // Session built without one; search unindexed
+	}
+	return index.Search(strings.TrimPrefix(s.CurrentDir, "/"), repo, q)
+}
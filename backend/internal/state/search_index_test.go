@@ -0,0 +1,89 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCommits(t *testing.T) {
+	sm := NewSessionManager()
+	sess, err := sm.CreateSession("search")
+	require.NoError(t, err)
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	sess.Repos["repo"] = repo
+	sess.CurrentDir = "/repo"
+	w, _ := repo.Worktree()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commit := func(i int, name, file, msg string) string {
+		require.NoError(t, util.WriteFile(w.Filesystem, file, []byte(msg), 0644))
+		_, err := w.Add(file)
+		require.NoError(t, err)
+		sig := &object.Signature{Name: name, Email: name + "@example.com", When: start.AddDate(0, 0, i)}
+		hash, err := w.Commit(msg, &gogit.CommitOptions{Author: sig})
+		require.NoError(t, err)
+		return hash.String()
+	}
+	ids := func(r *CommitSearchResult) []string {
+		var out []string
+		for _, c := range r.Commits {
+			out = append(out, c.ID)
+		}
+		return out
+	}
+
+	first := commit(0, "alice", "README.md", "Add readme")
+	second := commit(1, "bob", "src/main.go", "Fix build")
+	third := commit(2, "alice", "src/util.go", "fix typo in util")
+
+	r, err := sess.SearchCommits(CommitQuery{Author: "ALICE"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third, first}, ids(r))
+	assert.Equal(t, 3, r.Indexed)
+
+	r, err = sess.SearchCommits(CommitQuery{Path: "src"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third, second}, ids(r))
+
+	r, err = sess.SearchCommits(CommitQuery{Path: "src/main.go", Author: "alice"})
+	require.NoError(t, err)
+	assert.Empty(t, r.Commits)
+
+	r, err = sess.SearchCommits(CommitQuery{Message: "fix", IgnoreCase: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third}, ids(r))
+	assert.Equal(t, 2, r.Total)
+
+	r, err = sess.SearchCommits(CommitQuery{Message: "^Add", Regex: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, ids(r))
+
+	r, err = sess.SearchCommits(CommitQuery{Since: start.AddDate(0, 0, 1), Until: start.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{second}, ids(r))
+
+	_, err = sess.SearchCommits(CommitQuery{Message: "(", Regex: true})
+	assert.Error(t, err)
+
+	// New commits are indexed on the next search; reset-away ones stop matching
+	fourth := commit(3, "carol", "src/main.go", "Refactor main")
+	r, err = sess.SearchCommits(CommitQuery{Path: "src/main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{fourth, second}, ids(r))
+	assert.Equal(t, 4, r.Indexed)
+
+	require.NoError(t, w.Reset(&gogit.ResetOptions{Commit: plumbing.NewHash(third), Mode: gogit.HardReset}))
+	r, err = sess.SearchCommits(CommitQuery{Author: "carol"})
+	require.NoError(t, err)
+	assert.Empty(t, r.Commits)
+}
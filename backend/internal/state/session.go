@@ -2,13 +2,13 @@ package state
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
@@ -17,45 +17,96 @@ type Session struct {
 	ID               string
 	Filesystem       billy.Filesystem
 	Repos            map[string]*gogit.Repository // Map path (e.g., "repo1") to Repository
+	Worktrees        map[string]string            // Linked worktree path -> path of the repository it belongs to
 	CurrentDir       string                       // e.g., "/", "/repo1"
 	CreatedAt        time.Time
 	Reflog           []ReflogEntry
 	PotentialCommits []Commit
-	Manager          *SessionManager // Reference to manager for shared state
-	FileCache        *FileCache      // Cached file listing for performance
+	Manager          *SessionManager                       // Reference to manager for shared state
+	FileCache        *FileCache                            // Cached file listing for performance
+	SearchIndex      *SearchIndex                          // Commit search index, updated on each search
+	BranchPolicy     *BranchPolicy                         // Naming rules for branches created in this session
+	CommandPolicy    *CommandPolicy                        // Commands the session may run, e.g. during a mission
+	User             *UserIdentity                         // Simulated user the session acts as; nil means DefaultUser
+	Lineage          map[string]LineageLink                // Rewritten commit hash -> the commit it replaces
+	LFSObjects       map[string][]byte                     // Simulated local LFS cache, keyed by SHA-256 oid
+	CherryPick       *CherryPickState                      // Cherry-pick stopped on a conflict, if any
+	Rebase           *RebaseState                          // Rebase waiting for its plan or stopped on a conflict, if any
+	Merge            *MergeState                           // Merge stopped on conflicts, if any
+	Am               *AmState                              // Patch series stopped on a patch that did not apply, if any
+	Rerere           map[string]*RerereCache               // Recorded conflict resolutions, keyed by repo path
+	Editor           *PendingEditor                        // Command waiting for the client's editor, if any
+	Locale           string                                // Language the learner last asked for ("en", "ja"); empty until one does
+	undoStack        []*UndoSnapshot                       // States to go back to, oldest first
+	redoStack        []*UndoSnapshot                       // States replaced by undo, oldest first
+	savePoints       []*SavePoint                          // Named states to go back to, oldest first
+	trace            []TraceEntry                          // Commands dispatched in this session, oldest first
+	traceSeq         int                                   // Seq of the last traced command
+	traceDropped     bool                                  // The trace lost its oldest entries to maxTraceEntries
+	timeline         timeline                              // Graph changes of the recent commands, see Timeline
+	lastActive       atomic.Int64                          // Unix nanoseconds of the last access, for idle eviction
+	gcCandidates     map[string]map[plumbing.Hash]struct{} // Objects unreachable at the last background sweep, by repo path
 	mu               sync.RWMutex
 }
 
 // SessionManager handles concurrent access to sessions
 type SessionManager struct {
-	sessions          map[string]*Session
-	SharedRemotes     map[string]*gogit.Repository // Share repositories across all sessions
-	SharedRemotePaths map[string]string            // Maps remote name to local filesystem path
-	PullRequests      []*PullRequest
-	NextPRID          int
-	DataDir           string
-	mu                sync.RWMutex
-	ingestMu          sync.Mutex // Serializes ingestion operations
-}
-
-// ReflogEntry records a command executed in the session
-type ReflogEntry struct {
-	Command   string
-	Timestamp time.Time
-	Context   string // CurrentDir, Branch etc
-	Hash      string
-	Message   string
+	sessions             map[string]*Session
+	SharedRemotes        map[string]*gogit.Repository // Share repositories across all sessions
+	SharedRemotePaths    map[string]string            // Maps remote name to local filesystem path
+	RemoteBranchPolicies map[string]*BranchPolicy     // Branch naming rules enforced on push, keyed by remote name
+	RemotePermissions    map[string]RemotePermission  // Who may push, keyed by remote name; open when missing
+	RemoteCheckRules     map[string][]CheckRule       // Simulated CI checks run on push, keyed by remote name
+	CheckDelay           time.Duration                // How long checks stay pending before reporting
+	StorageQuota         StorageQuota                 // Storage limits of each session
+	IngestPolicy         IngestPolicy                 // What IngestRemote may copy; see ingest_policy.go
+	LFSServer            map[string][]byte            // Simulated LFS server content, keyed by SHA-256 oid
+	PullRequests         []*PullRequest
+	NextPRID             int
+	issues               map[string]*issueTracker   // Issues of each shared remote, keyed by remote name
+	Store                Store                      // Metadata store (PRs, mission progress, audit log)
+	auditSeq             uint64                     // Last audit entry sequence number, guarded by auditMu
+	auditMu              sync.Mutex                 // Serializes audit writes, which stay off mu
+	ingests              map[string]*IngestManifest // Latest ingest manifest per remote name
+	DataDir              string
+	PersistDir           string                   // Session snapshot directory; empty disables persistence
+	snapshots            map[string]SnapshotStats // Persistence statistics keyed by session ID
+	persist              persister                // Scheduled snapshots, see persistence.go
+	mu                   sync.RWMutex
+	ingestMu             sync.Mutex                // Serializes ingestion operations
+	streams              map[string]*stateStream   // State streams keyed by session ID
+	deltas               map[string]*stateVersions // State version trackers keyed by session ID, guarded by streamMu
+	streamMu             sync.Mutex
+	checkStatuses        map[string][]CheckStatus            // CI check statuses keyed by commit hash
+	checkRuns            sync.WaitGroup                      // Checks still pending
+	pushConflicts        []PushConflict                      // Recent pushes rejected because another user pushed first
+	users                map[string]UserIdentity             // Identities picked by sessions, keyed by slug
+	ScenarioDir          string                              // Directory of teammate scenario files; empty means DefaultScenarioDir
+	teammateRuns         map[string]*TeammateRun             // Teammate scenario being played, keyed by remote name
+	teammateMu           sync.Mutex                          // Serializes teammate runs; taken before mu
+	remoteLocks          map[*gogit.Repository]*sync.RWMutex // Per-remote locks, see remote_lock.go
+	remoteLocksMu        sync.Mutex                          // Guards remoteLocks
+	jobRunner            jobRunner                           // Background jobs, see jobs.go
+	remoteSync           remoteSyncer                        // Upstream syncs of ingested remotes, see remote_sync.go
+	drain                drainer                             // Commands running and shutdown, see drain.go
 }
 
 // Commit represents a commit structure for visualization/API
 type Commit struct {
-	ID             string `json:"id"`
-	Message        string `json:"message"`
-	ParentID       string `json:"parentId"`
-	SecondParentID string `json:"secondParentId,omitempty"` // For merge commits
-	Timestamp      string `json:"timestamp"`
-	Author         string `json:"author,omitempty"`
-	TreeID         string `json:"treeId,omitempty"`
+	ID             string   `json:"id"`
+	Message        string   `json:"message"`
+	ParentID       string   `json:"parentId"`
+	SecondParentID string   `json:"secondParentId,omitempty"` // For merge commits
+	Timestamp      string   `json:"timestamp"`
+	Author         string   `json:"author,omitempty"`
+	TreeID         string   `json:"treeId,omitempty"`
+	Dangling       bool     `json:"dangling,omitempty"`    // Not reachable from any ref (e.g. replaced by amend/rebase)
+	Shallow        bool     `json:"shallow,omitempty"`     // Parents were left out by a shallow clone
+	Replaces       string   `json:"replaces,omitempty"`    // Commit this one was rewritten from
+	ReplacedBy     []string `json:"replacedBy,omitempty"`  // Commits rewritten from this one
+	RewriteKind    string   `json:"rewriteKind,omitempty"` // "amend", "rebase" or "cherry-pick"
+	Lane           int      `json:"lane"`                  // Graph column, see assignLanes
+	ColorGroup     int      `json:"colorGroup"`            // Color of the first-parent chain; 0 is the trunk
 }
 
 // PullRequest structure
@@ -63,7 +114,7 @@ type PullRequest struct {
 	ID          int       `json:"id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
-	State       string    `json:"status"`       // "OPEN", "CLOSED", "MERGED"
+	State       string    `json:"status"`       // PRStateOpen, PRStateClosed or PRStateMerged
 	RemoteName  string    `json:"remoteName"`   // The shared remote this PR belongs to
 	HeadRepo    string    `json:"headRepo"`     // simulating fork
 	HeadRef     string    `json:"sourceBranch"` // branch
@@ -71,17 +122,36 @@ type PullRequest struct {
 	BaseRef     string    `json:"targetBranch"`
 	Creator     string    `json:"creator"`
 	CreatedAt   time.Time `json:"createdAt"`
+
+	Reviews        []PullRequestReview  `json:"reviews,omitempty"`
+	ReviewDecision string               `json:"reviewDecision,omitempty"` // APPROVED or CHANGES_REQUESTED once reviewed
+	Comments       []PullRequestComment `json:"comments,omitempty"`
+	MergeStrategy  string               `json:"mergeStrategy,omitempty"` // "merge", "squash" or "rebase"
+	MergeCommit    string               `json:"mergeCommit,omitempty"`   // Commit the base branch moved to
+	MergedBy       string               `json:"mergedBy,omitempty"`
+	MergedAt       *time.Time           `json:"mergedAt,omitempty"`
+	ClosedAt       *time.Time           `json:"closedAt,omitempty"`
+	ChecksCommit   string               `json:"checksCommit,omitempty"` // Head commit the checks ran on
+	ChecksState    string               `json:"checksState,omitempty"`  // Combined CI status: pending, success or failure
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager() *SessionManager {
 	return &SessionManager{
-		sessions:          make(map[string]*Session),
-		SharedRemotes:     make(map[string]*gogit.Repository),
-		SharedRemotePaths: make(map[string]string),
-		PullRequests:      []*PullRequest{},
-		NextPRID:          1,
-		DataDir:           ".gitgym-data/remotes",
+		sessions:             make(map[string]*Session),
+		SharedRemotes:        make(map[string]*gogit.Repository),
+		SharedRemotePaths:    make(map[string]string),
+		RemoteBranchPolicies: make(map[string]*BranchPolicy),
+		RemotePermissions:    make(map[string]RemotePermission),
+		RemoteCheckRules:     make(map[string][]CheckRule),
+		CheckDelay:           DefaultCheckDelay,
+		StorageQuota:         DefaultStorageQuota,
+		checkStatuses:        make(map[string][]CheckStatus),
+		LFSServer:            make(map[string][]byte),
+		PullRequests:         []*PullRequest{},
+		NextPRID:             1,
+		Store:                NewMemoryStore(),
+		DataDir:              ".gitgym-data/remotes",
 	}
 }
 
@@ -96,14 +166,16 @@ func (sm *SessionManager) CreateSession(id string) (*Session, error) {
 
 	fs := memfs.New()
 	s := &Session{
-		ID:         id,
-		Filesystem: fs,
-		Repos:      make(map[string]*gogit.Repository),
-		CurrentDir: "/",
-		CreatedAt:  time.Now(),
-		Manager:    sm,
-		FileCache:  &FileCache{},
+		ID:          id,
+		Filesystem:  fs,
+		Repos:       make(map[string]*gogit.Repository),
+		CurrentDir:  "/",
+		CreatedAt:   time.Now(),
+		Manager:     sm,
+		FileCache:   &FileCache{},
+		SearchIndex: &SearchIndex{},
 	}
+	s.Touch()
 	sm.sessions[id] = s
 	return s, nil
 }
@@ -113,6 +185,9 @@ func (sm *SessionManager) GetSession(id string) (*Session, bool) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 	s, ok := sm.sessions[id]
+	if ok {
+		s.Touch()
+	}
 	return s, ok
 }
 
@@ -176,22 +251,6 @@ func (s *Session) GetRepo() *gogit.Repository {
 	return nil
 }
 
-// RecordReflog adds an entry to the session reflog
-func (s *Session) RecordReflog(cmd string) {
-	s.Reflog = append(s.Reflog, ReflogEntry{
-		Command:   cmd,
-		Timestamp: time.Now(),
-		Context:   s.CurrentDir,
-		Hash:      "0000000",
-		Message:   cmd,
-	})
-}
-
-// UpdateOrigHead updates the ORIG_HEAD reference (simplified for now)
-func (s *Session) UpdateOrigHead() {
-	// Implementation placeholder - logic moved from session.go
-}
-
 // Helper: RemoveAll (Recursive delete for memfs/billy)
 func (s *Session) RemoveAll(path string) error {
 	fi, err := s.Filesystem.Stat(path)
@@ -261,115 +320,3 @@ func (s *Session) InitRepo(name string) (*gogit.Repository, error) {
 	s.Repos[path] = repo
 	return repo, nil
 }
-
-// GetIndexTree returns a tree object reflecting the current state of the index.
-func (s *Session) GetIndexTree(repo *gogit.Repository) (*object.Tree, error) {
-	// For simulation purposes, we can't easily convert Index to Tree without writing objects.
-	// However, we can use a temporary commit to generate the tree, or manually build it.
-	// Since we are in-memory, we can afford a temporary commit if we roll it back or use a side storage.
-
-	// A simpler way: use the current Worktree to build a tree if we assume Index is clean.
-	// But Index might have staged changes that are NOT in HEAD.
-
-	// Better: Use go-git's internal tree builder logic.
-	// Since we don't want to pollute history, we'll use a temporary storer or just a temporary commit.
-
-	w, err := repo.Worktree()
-	if err != nil {
-		return nil, err
-	}
-
-	// For now, let's use a "dirty" approach:
-	// 1. Get current HEAD commit tree
-	// 2. Overlay index changes
-	// This is hard.
-
-	// Let's try the "Temporary Commit" approach:
-	// 1. Save current HEAD
-	// 2. Commit (staging all)
-	// 3. Get that commit's tree
-	// 4. Restore HEAD
-
-	// But we only want to commit what is STAGED.
-	head, headErr := repo.Head()
-
-	// We use git commit --allow-empty --no-verify -m "temp"
-	// and then hash the tree.
-
-	// Since we are in state package, we don't have access to github.com/kurobon/gitgym/backend/internal/git
-	// But we can call go-git directly.
-
-	commitHash, err := w.Commit("temp index tree", &gogit.CommitOptions{
-		Author: &object.Signature{
-			Name:  "System",
-			Email: "system@gitgym.io",
-			When:  time.Now(),
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	commit, err := repo.CommitObject(commitHash)
-	if err != nil {
-		return nil, err
-	}
-
-	tree, err := commit.Tree()
-	if err != nil {
-		return nil, err
-	}
-
-	// Restore HEAD
-	if headErr == nil {
-		// Restore to previous HEAD
-		err = repo.Storer.SetReference(head)
-	} else {
-		// No previous HEAD (empty repo), remove it?
-		// go-git doesn't have an easy way to "un-init" HEAD.
-		// We'll leave it for now or use a detached head if possible.
-	}
-
-	return tree, nil
-}
-
-// GetWorktreeTree returns a tree object reflecting the current state of the filesystem.
-func (s *Session) GetWorktreeTree(repo *gogit.Repository) (*object.Tree, error) {
-	// To get a tree from the filesystem, we must stage everything first.
-	w, err := repo.Worktree()
-	if err != nil {
-		return nil, err
-	}
-
-	// Save current Index?
-	// Billy Filesystem doesn't easily support index backup.
-
-	// Let's use a side-repository in memory with the same FS to avoid messing with the main repo's index.
-	storage := memory.NewStorage()
-	fs := w.Filesystem // Reuse same filesystem!
-
-	tempRepo, err := gogit.Init(storage, fs)
-	if err != nil {
-		return nil, err
-	}
-
-	tempW, _ := tempRepo.Worktree()
-	_, err = tempW.Add(".")
-	if err != nil {
-		return nil, err
-	}
-
-	commitHash, err := tempW.Commit("temp worktree tree", &gogit.CommitOptions{
-		Author: &object.Signature{
-			Name:  "System",
-			Email: "system@gitgym.io",
-			When:  time.Now(),
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	commit, _ := tempRepo.CommitObject(commitHash)
-	return commit.Tree()
-}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Fork returns an independent copy of the session: filesystem, repositories
+// and history metadata are copied, so commands run against the fork leave
+// the original untouched. The fork shares the manager (and with it the shared
+// remotes), so it is meant for local commands only. Caller holds at least the
+// session's read lock.
+func (s *Session) Fork() (*Session, error) {
+	fs := memfs.New()
+	if err := copyBillyDir(s.Filesystem, "/", fs, "/"); err != nil {
+		return nil, fmt.Errorf("failed to copy files: %w", err)
+	}
+
+	fork := &Session{
+		ID:            s.ID,
+		Filesystem:    fs,
+		Repos:         make(map[string]*gogit.Repository, len(s.Repos)),
+		CurrentDir:    s.CurrentDir,
+		CreatedAt:     s.CreatedAt,
+		Reflog:        append([]ReflogEntry(nil), s.Reflog...),
+		Manager:       s.Manager,
+		FileCache:     &FileCache{},
+		SearchIndex:   &SearchIndex{},
+		BranchPolicy:  s.BranchPolicy,
+		CommandPolicy: s.CommandPolicy,
+		Lineage:       make(map[string]LineageLink, len(s.Lineage)),
+		LFSObjects:    make(map[string][]byte, len(s.LFSObjects)),
+	}
+	for k, v := range s.Lineage {
+		fork.Lineage[k] = v
+	}
+	for k, v := range s.LFSObjects {
+		fork.LFSObjects[k] = v
+	}
+	if s.CherryPick != nil {
+		cp := *s.CherryPick
+		fork.CherryPick = &cp
+	}
+	if s.Rebase != nil {
+		rb := *s.Rebase
+		fork.Rebase = &rb
+	}
+	if s.Merge != nil {
+		m := *s.Merge
+		fork.Merge = &m
+	}
+	if s.Am != nil {
+		am := *s.Am
+		fork.Am = &am
+	}
+	if s.Rerere != nil {
+		fork.Rerere = make(map[string]*RerereCache, len(s.Rerere))
+		for path, rr := range s.Rerere {
+			fork.Rerere[path] = rr.clone()
+		}
+	}
+
+	for path, repo := range s.Repos {
+		if _, linked := s.Worktrees[path]; linked {
+			continue
+		}
+		st := memory.NewStorage()
+		if err := copyStorage(repo.Storer, st); err != nil {
+			return nil, fmt.Errorf("failed to copy repository '%s': %w", path, err)
+		}
+		worktree, err := fs.Chroot(path)
+		if err != nil {
+			return nil, err
+		}
+		forked, err := gogit.Open(st, worktree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open repository '%s': %w", path, err)
+		}
+		fork.Repos[path] = forked
+	}
+
+	// Linked worktrees share the forked repository instead of copying it
+	fork.Worktrees = make(map[string]string, len(s.Worktrees))
+	for wt, main := range s.Worktrees {
+		fork.Worktrees[wt] = main
+	}
+	heads, indexes := s.worktreeState()
+	if err := fork.relinkWorktrees(heads, indexes); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
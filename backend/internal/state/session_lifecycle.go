@@ -0,0 +1,146 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Session lifecycle
+//
+// Each browser gets its own session, identified by a random ID issued by
+// /api/session/init. Sessions that stay idle longer than the TTL are evicted
+// so abandoned workspaces do not accumulate in memory.
+
+// DefaultSessionTTL is how long a session may stay idle before it is evicted.
+const DefaultSessionTTL = 2 * time.Hour
+
+// SessionTTLEnv overrides DefaultSessionTTL (Go duration syntax, e.g. "30m").
+const SessionTTLEnv = "GITGYM_SESSION_TTL"
+
+// NewSessionID returns a cryptographically random session ID.
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return "session-" + hex.EncodeToString(b), nil
+}
+
+// IsIssuedSessionID reports whether id has the form NewSessionID issues:
+// "session-" and 32 lowercase hex digits.
+func IsIssuedSessionID(id string) bool {
+	digits, ok := strings.CutPrefix(id, "session-")
+	if !ok || len(digits) != 32 || strings.ToLower(digits) != digits {
+		return false
+	}
+	_, err := hex.DecodeString(digits)
+	return err == nil
+}
+
+// OwnedSessionID returns the ID of the session name that belongs to the
+// session owner, e.g. the session of a mission the learner started.
+func OwnedSessionID(owner, name string) string {
+	return owner + "-" + name
+}
+
+// OwnsSession reports whether the session id belongs to the session owner,
+// as one named by OwnedSessionID does.
+func OwnsSession(owner, id string) bool {
+	return IsIssuedSessionID(owner) && strings.HasPrefix(id, owner+"-") && validSessionID(id)
+}
+
+// maxSessionIDLen is the longest session ID a snapshot is written for.
+const maxSessionIDLen = 128
+
+// validSessionID reports whether id is a plain name of letters, digits, '-'
+// and '_'. Snapshots are stored in a directory named after the session, so
+// any other ID, "." and ".." among them, never touches the disk.
+func validSessionID(id string) bool {
+	if id == "" || len(id) > maxSessionIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Touch marks the session as used now.
+func (s *Session) Touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// LastActive returns when the session was last used.
+func (s *Session) LastActive() time.Time {
+	if ns := s.lastActive.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return s.CreatedAt
+}
+
+// DeleteSession removes a session and its persisted snapshot, if any.
+func (sm *SessionManager) DeleteSession(id string) {
+	sm.mu.Lock()
+	delete(sm.sessions, id)
+	delete(sm.snapshots, id)
+	dir := sm.PersistDir
+	sm.mu.Unlock()
+	sm.dropStateVersions(id)
+
+	sm.persist.mu.Lock()
+	delete(sm.persist.pending, id)
+	sm.persist.mu.Unlock()
+	// A session whose ID is not a plain name never had a snapshot
+	if target, err := snapshotDir(dir, id); dir != "" && err == nil {
+		sm.persist.writeMu.Lock()
+		if err := os.RemoveAll(target); err != nil {
+			slog.Warn("delete session: failed to remove snapshot", "session", id, "err", err)
+		}
+		sm.persist.writeMu.Unlock()
+	}
+}
+
+// EvictIdleSessions deletes sessions idle for longer than ttl and returns their IDs.
+func (sm *SessionManager) EvictIdleSessions(ttl time.Duration) []string {
+	sm.mu.RLock()
+	var idle []string
+	for id, s := range sm.sessions {
+		if time.Since(s.LastActive()) > ttl {
+			idle = append(idle, id)
+		}
+	}
+	sm.mu.RUnlock()
+
+	for _, id := range idle {
+		sm.DeleteSession(id)
+	}
+	return idle
+}
+
+// StartEviction evicts idle sessions every interval until stop is closed.
+// A nil stop channel keeps it running for the lifetime of the process.
+func (sm *SessionManager) StartEviction(ttl, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if evicted := sm.EvictIdleSessions(ttl); len(evicted) > 0 {
+					slog.Info("evicted idle sessions", "count", len(evicted), "sessions", evicted)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictIdleSessions(t *testing.T) {
+	sm := NewSessionManager()
+	idle, err := sm.CreateSession("idle")
+	require.NoError(t, err)
+	_, err = sm.CreateSession("active")
+	require.NoError(t, err)
+
+	idle.lastActive.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	evicted := sm.EvictIdleSessions(30 * time.Minute)
+	assert.Equal(t, []string{"idle"}, evicted)
+
+	_, ok := sm.GetSession("idle")
+	assert.False(t, ok)
+	_, ok = sm.GetSession("active")
+	assert.True(t, ok)
+}
+
+func TestNewSessionID_IsUnique(t *testing.T) {
+	a, err := NewSessionID()
+	require.NoError(t, err)
+	b, err := NewSessionID()
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, len("session-")+32)
+}
+
+func TestDeleteSession_KeepsDataDirForUnsafeIDs(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "sessions")
+	sm := NewSessionManager()
+	require.NoError(t, sm.EnablePersistence(dir))
+	keep, err := sm.CreateSession("keep")
+	require.NoError(t, err)
+	require.NoError(t, sm.SaveSession(keep))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "remotes", "keep"), 0755))
+
+	for _, id := range []string{".", "..", "../sessions"} {
+		_, err := sm.CreateSession(id)
+		require.NoError(t, err)
+		sm.DeleteSession(id)
+	}
+	assert.DirExists(t, filepath.Join(root, "remotes", "keep"))
+	assert.DirExists(t, filepath.Join(dir, "keep"))
+
+	sm.DeleteSession("keep")
+	assert.NoDirExists(t, filepath.Join(dir, "keep"))
+}
+
+func TestOwnsSession(t *testing.T) {
+	owner, err := NewSessionID()
+	require.NoError(t, err)
+	assert.True(t, IsIssuedSessionID(owner))
+	for _, id := range []string{"user-session-1", "session-XYZ", strings.ToUpper(owner), owner + "x", ".."} {
+		assert.False(t, IsIssuedSessionID(id), id)
+	}
+
+	assert.True(t, OwnsSession(owner, OwnedSessionID(owner, "mission-101")))
+	assert.False(t, OwnsSession(owner, owner))
+	assert.False(t, OwnsSession(owner, OwnedSessionID(owner, "../..")))
+	assert.False(t, OwnsSession("user", OwnedSessionID("user", "mission-101")), "only issued sessions own others")
+}
@@ -0,0 +1,20 @@
+package state
+
+import (
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ShallowBoundary returns the commits whose parents a shallow clone left out,
+// as recorded in .git/shallow. It is empty for a complete repository.
+func ShallowBoundary(repo *gogit.Repository) map[plumbing.Hash]bool {
+	boundary := make(map[plumbing.Hash]bool)
+	hashes, err := repo.Storer.Shallow()
+	if err != nil {
+		return boundary
+	}
+	for _, h := range hashes {
+		boundary[h] = true
+	}
+	return boundary
+}
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Simulated signatures look like armored PGP signatures, but the body is an
+// HMAC of the signed object keyed by a per-session secret. That is enough to
+// show learners what signing proves: who signed, and that nothing changed since.
+const (
+	signatureHeader  = "-----BEGIN PGP SIGNATURE-----"
+	signatureFooter  = "-----END PGP SIGNATURE-----"
+	signatureKeyLine = "Key-ID: "
+)
+
+// SigningKey is the simulated GPG key a session signs commits and tags with.
+type SigningKey struct {
+	ID     string // 16 hex digits, like a long GPG key ID
+	Name   string
+	Email  string
+	secret []byte
+}
+
+// SigningKey returns the session's key. It is derived from the session ID, so
+// it stays the same across restarts and other sessions cannot forge it.
+func (s *Session) SigningKey() *SigningKey {
+	secret := sha256.Sum256([]byte("gitgym-signing-key:" + s.ID))
+	id := sha256.Sum256(secret[:])
+	user := s.Identity()
+	return &SigningKey{
+		ID:     strings.ToUpper(hex.EncodeToString(id[:8])),
+		Name:   user.Name,
+		Email:  user.Email,
+		secret: secret[:],
+	}
+}
+
+// Sign returns the armored signature of message. It implements go-git's
+// Signer, so it can be passed as CommitOptions.Signer.
+func (k *SigningKey) Sign(message io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s\n%s%s\n\n%s\n%s\n",
+		signatureHeader, signatureKeyLine, k.ID, k.mac(data), signatureFooter)), nil
+}
+
+func (k *SigningKey) mac(data []byte) string {
+	h := hmac.New(sha256.New, k.secret)
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignatureStatus is the outcome of checking a signature, as in git's %G?.
+type SignatureStatus string
+
+const (
+	SignatureGood    SignatureStatus = "G" // Made by the session's key over this exact content
+	SignatureBad     SignatureStatus = "B" // Made by the session's key, but the content changed
+	SignatureUnknown SignatureStatus = "E" // Made by a key this session does not know
+	SignatureNone    SignatureStatus = "N" // Not signed
+)
+
+// SignatureCheck describes a verified signature.
+type SignatureCheck struct {
+	Status SignatureStatus
+	KeyID  string
+	Signer string    // "Name <email>" of the key, when known
+	When   time.Time // When the object was signed
+}
+
+// Lines renders the check the way gpg reports it, e.g. for log --show-signature.
+func (c *SignatureCheck) Lines() []string {
+	if c.Status == SignatureNone {
+		return nil
+	}
+	lines := []string{
+		"gpg: Signature made " + c.When.Format("Mon Jan 2 15:04:05 2006 MST"),
+		"gpg:                using RSA key " + c.KeyID,
+	}
+	switch c.Status {
+	case SignatureGood:
+		lines = append(lines, fmt.Sprintf("gpg: Good signature from \"%s\" [ultimate]", c.Signer))
+	case SignatureBad:
+		lines = append(lines, fmt.Sprintf("gpg: BAD signature from \"%s\" [ultimate]", c.Signer))
+	default:
+		lines = append(lines, "gpg: Can't check signature: No public key")
+	}
+	return lines
+}
+
+// VerifyCommit checks the signature of commit against the session's key.
+func (s *Session) VerifyCommit(commit *object.Commit) (*SignatureCheck, error) {
+	return s.verify(commit.PGPSignature, commit.Committer.When, commit.EncodeWithoutSignature)
+}
+
+// VerifyTag checks the signature of an annotated tag against the session's key.
+func (s *Session) VerifyTag(tag *object.Tag) (*SignatureCheck, error) {
+	return s.verify(tag.PGPSignature, tag.Tagger.When, tag.EncodeWithoutSignature)
+}
+
+func (s *Session) verify(signature string, when time.Time, encode func(plumbing.EncodedObject) error) (*SignatureCheck, error) {
+	if signature == "" {
+		return &SignatureCheck{Status: SignatureNone}, nil
+	}
+	keyID, mac, ok := parseSignature(signature)
+	if !ok {
+		return nil, fmt.Errorf("error: malformed signature")
+	}
+
+	check := &SignatureCheck{Status: SignatureUnknown, KeyID: keyID, When: when}
+	key := s.SigningKey()
+	if keyID != key.ID {
+		return check, nil
+	}
+	check.Signer = fmt.Sprintf("%s <%s>", key.Name, key.Email)
+
+	obj := &plumbing.MemoryObject{}
+	if err := encode(obj); err != nil {
+		return nil, err
+	}
+	r, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if hmac.Equal([]byte(mac), []byte(key.mac(data))) {
+		check.Status = SignatureGood
+	} else {
+		check.Status = SignatureBad
+	}
+	return check, nil
+}
+
+// parseSignature extracts the key ID and body of an armored signature.
+func parseSignature(signature string) (keyID, body string, ok bool) {
+	lines := strings.Split(strings.TrimSpace(signature), "\n")
+	if len(lines) < 4 || lines[0] != signatureHeader || lines[len(lines)-1] != signatureFooter {
+		return "", "", false
+	}
+	inBody := false
+	for _, line := range lines[1 : len(lines)-1] {
+		switch {
+		case inBody:
+			body += line
+		case line == "":
+			inBody = true
+		case strings.HasPrefix(line, signatureKeyLine):
+			keyID = strings.TrimPrefix(line, signatureKeyLine)
+		}
+	}
+	return keyID, body, keyID != "" && body != ""
+}
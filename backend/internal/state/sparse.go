@@ -0,0 +1,251 @@
+package state
+
+// sparse.go - Sparse checkout ("git sparse-checkout")
+//
+// Like git's cone mode, a sparse checkout keeps the files at the top of the
+// repository, everything under the listed directories and the files directly
+// inside their parent directories. The other index entries get the
+// skip-worktree bit and their files are removed from the working tree, so
+// status and commit leave them alone. The directories are stored in the
+// repository config, which travels with forks and persisted sessions.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const sparseSection = "sparse"
+
+// SparseCheckoutDirs returns the directories of the sparse checkout and whether
+// sparse checkout is enabled for the repository.
+func SparseCheckoutDirs(repo *gogit.Repository) ([]string, bool) {
+	cfg, err := repo.Config()
+	if err != nil || cfg.Raw.Section("core").Option("sparseCheckout") != "true" {
+		return nil, false
+	}
+	return cfg.Raw.Section(sparseSection).Options.GetAll("dir"), true
+}
+
+// SetSparseCheckout enables sparse checkout limited to dirs (only the top-level
+// files when dirs is empty) and updates the working tree. It returns a warning
+// for every file left in place because it has local changes.
+func SetSparseCheckout(repo *gogit.Repository, dirs []string) ([]string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	core := cfg.Raw.Section("core")
+	core.SetOption("sparseCheckout", "true")
+	core.SetOption("sparseCheckoutCone", "true")
+	cfg.Raw.RemoveSection(sparseSection)
+	section := cfg.Raw.Section(sparseSection)
+	for _, dir := range normalizeSparseDirs(dirs) {
+		section.AddOption("dir", dir)
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return ApplySparseCheckout(repo)
+}
+
+// DisableSparseCheckout turns sparse checkout off and restores every file.
+func DisableSparseCheckout(repo *gogit.Repository) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	core := cfg.Raw.Section("core")
+	core.RemoveOption("sparseCheckout")
+	core.RemoveOption("sparseCheckoutCone")
+	cfg.Raw.RemoveSection(sparseSection)
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return err
+	}
+	_, err = applySparse(repo, func(string) bool { return true })
+	return err
+}
+
+// ApplySparseCheckout brings the working tree and the skip-worktree bits back
+// in line with the sparse directories, after a checkout or reset moved HEAD.
+// It does nothing when sparse checkout is disabled.
+func ApplySparseCheckout(repo *gogit.Repository) ([]string, error) {
+	dirs, enabled := SparseCheckoutDirs(repo)
+	if !enabled {
+		return nil, nil
+	}
+	return applySparse(repo, func(name string) bool { return inSparseCone(dirs, name) })
+}
+
+// SparseCheckoutStats counts the tracked files present in the working tree
+// against all tracked files.
+func SparseCheckoutStats(repo *gogit.Repository) (present, total int) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return 0, 0
+	}
+	for _, e := range idx.Entries {
+		if e.Stage != 0 { // Conflict stages; go-git's index.Merged is not 0
+			continue
+		}
+		total++
+		if !e.SkipWorktree {
+			present++
+		}
+	}
+	return present, total
+}
+
+// normalizeSparseDirs trims slashes, drops duplicates and sorts dirs.
+func normalizeSparseDirs(dirs []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, dir := range dirs {
+		dir = strings.Trim(path.Clean("/"+dir), "/")
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		out = append(out, dir)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// inSparseCone reports whether the file name belongs to the cone of dirs.
+func inSparseCone(dirs []string, name string) bool {
+	parent := path.Dir(name)
+	if parent == "." {
+		return true
+	}
+	for _, dir := range dirs {
+		if strings.HasPrefix(name, dir+"/") || dir == parent || strings.HasPrefix(dir, parent+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// applySparse sets the skip-worktree bit of the index entries outside the
+// cone and removes their files, and writes back the files of entries that came
+// into it. Skipped entries are refreshed from HEAD, since checkouts and resets
+// never look at them.
+func applySparse(repo *gogit.Repository, included func(string) bool) ([]string, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	var tree *object.Tree
+	if head, err := repo.Head(); err == nil {
+		if commit, err := repo.CommitObject(head.Hash()); err == nil {
+			tree, _ = commit.Tree()
+		}
+	}
+
+	var warnings []string
+	entries := idx.Entries[:0]
+	for _, e := range idx.Entries {
+		if e.Stage != 0 {
+			entries = append(entries, e)
+			continue
+		}
+		if e.SkipWorktree && tree != nil {
+			te, err := tree.FindEntry(e.Name)
+			if err != nil {
+				continue // Deleted in HEAD
+			}
+			e.Hash, e.Mode = te.Hash, te.Mode
+		}
+		switch {
+		case included(e.Name) && e.SkipWorktree:
+			if err := writeIndexEntry(repo, w.Filesystem, e); err != nil {
+				return nil, err
+			}
+			e.SkipWorktree = false
+		case !included(e.Name) && !e.SkipWorktree:
+			if modified, err := fileDiffers(w.Filesystem, e); err != nil {
+				return nil, err
+			} else if modified {
+				warnings = append(warnings, fmt.Sprintf("warning: not removing '%s': it has local changes", e.Name))
+				break
+			}
+			if err := w.Filesystem.Remove(e.Name); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			removeEmptyParents(w.Filesystem, e.Name)
+			e.SkipWorktree = true
+		}
+		entries = append(entries, e)
+	}
+	idx.Entries = entries
+	if idx.Version < 3 {
+		// Skip-worktree is an extended flag
+		idx.Version = 3
+	}
+	return warnings, repo.Storer.SetIndex(idx)
+}
+
+// writeIndexEntry writes the blob of e to the working tree.
+func writeIndexEntry(repo *gogit.Repository, fs billy.Filesystem, e *index.Entry) error {
+	blob, err := repo.BlobObject(e.Hash)
+	if err != nil {
+		return err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(path.Dir(e.Name), 0755); err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if e.Mode == filemode.Executable {
+		perm = 0755
+	}
+	return util.WriteFile(fs, e.Name, data, perm)
+}
+
+// fileDiffers reports whether the file of e exists with other content than the index.
+func fileDiffers(fs billy.Filesystem, e *index.Entry) (bool, error) {
+	data, err := util.ReadFile(fs, e.Name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, data) != e.Hash, nil
+}
+
+// removeEmptyParents removes the directories above name left empty.
+func removeEmptyParents(fs billy.Filesystem, name string) {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		infos, err := fs.ReadDir(dir)
+		if err != nil || len(infos) > 0 {
+			return
+		}
+		if err := fs.Remove(dir); err != nil {
+			return
+		}
+	}
+}
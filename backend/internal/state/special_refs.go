@@ -0,0 +1,89 @@
+package state
+
+// special_refs.go - ORIG_HEAD, MERGE_HEAD, CHERRY_PICK_HEAD, REBASE_HEAD and FETCH_HEAD
+//
+// Git keeps these pseudo-refs next to HEAD rather than under refs/. They name
+// commits an operation cares about (where HEAD was before a reset, the commit
+// being merged, the tip last fetched), so they resolve in revision syntax
+// ("git reset --hard ORIG_HEAD") like any branch. Unlike branches they do not
+// keep commits alive and are not transferred by push or bundle.
+
+import (
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Pseudo-refs written by commands.
+const (
+	OrigHead       plumbing.ReferenceName = "ORIG_HEAD"        // HEAD before the last reset, merge, rebase, pull or amend
+	MergeHead      plumbing.ReferenceName = "MERGE_HEAD"       // Commit being merged while a merge is unfinished
+	CherryPickHead plumbing.ReferenceName = "CHERRY_PICK_HEAD" // Commit being picked while a cherry-pick is stopped
+	RebaseHead     plumbing.ReferenceName = "REBASE_HEAD"      // Commit being replayed while a rebase is stopped
+	FetchHead      plumbing.ReferenceName = "FETCH_HEAD"       // Branch tip the last fetch brought in for merging
+)
+
+// SpecialRefs lists the pseudo-refs, in the order they are shown.
+var SpecialRefs = []plumbing.ReferenceName{OrigHead, MergeHead, CherryPickHead, RebaseHead, FetchHead}
+
+// IsSpecialRef reports whether name is one of the pseudo-refs.
+func IsSpecialRef(name plumbing.ReferenceName) bool {
+	for _, ref := range SpecialRefs {
+		if name == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSpecialRef points a pseudo-ref at a commit.
+func SetSpecialRef(repo *gogit.Repository, name plumbing.ReferenceName, hash plumbing.Hash) error {
+	return repo.Storer.SetReference(plumbing.NewHashReference(name, hash))
+}
+
+// RemoveSpecialRef deletes a pseudo-ref; a missing one is not an error.
+func RemoveSpecialRef(repo *gogit.Repository, name plumbing.ReferenceName) error {
+	if _, err := repo.Storer.Reference(name); err != nil {
+		return nil
+	}
+	return repo.Storer.RemoveReference(name)
+}
+
+// ReadSpecialRefs returns the pseudo-refs set in repo, by name.
+func ReadSpecialRefs(repo *gogit.Repository) map[string]string {
+	refs := make(map[string]string)
+	for _, name := range SpecialRefs {
+		if ref, err := repo.Storer.Reference(name); err == nil && ref.Type() == plumbing.HashReference {
+			refs[name.String()] = ref.Hash().String()
+		}
+	}
+	return refs
+}
+
+// UpdateOrigHead points ORIG_HEAD at the current HEAD commit.
+// Commands that move HEAD drastically (reset, merge, rebase, pull, amend)
+// call this before mutating so the previous position can be restored.
+func (s *Session) UpdateOrigHead() {
+	repo := s.GetRepo()
+	if repo == nil {
+		return
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return
+	}
+	_ = SetSpecialRef(repo, OrigHead, head.Hash())
+}
+
+// setStateRef points name at hash in the repository at path, or removes it
+// when hash is empty.
+func (s *Session) setStateRef(path string, name plumbing.ReferenceName, hash string) {
+	repo, ok := s.Repos[path]
+	if !ok {
+		return
+	}
+	if hash == "" {
+		_ = RemoveSpecialRef(repo, name)
+		return
+	}
+	_ = SetSpecialRef(repo, name, plumbing.NewHash(hash))
+}
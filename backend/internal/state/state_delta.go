@@ -0,0 +1,232 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// State deltas
+//
+// Polling clients that cannot keep a stream open ask for what changed since the
+// state version they last saw. Each session has a tracker that remembers the
+// version at which every top-level GraphState field and every commit last
+// changed, so a poll returns only those entries instead of the whole graph.
+// Removed commits are remembered as tombstones; once there are too many of them
+// the oldest versions can no longer be diffed against and get a full delta.
+
+// maxCommitTombstones bounds how many removed commits a tracker remembers.
+const maxCommitTombstones = 5000
+
+// StateDelta is the reply to a delta poll. Changes holds the JSON encoding of
+// each GraphState field (other than commits) that changed since the requested
+// version, or null for a field that disappeared. Commits holds the commits
+// added or changed, RemovedCommits the IDs of those that left the graph, and
+// CommitOrder the full newest-first order of commit IDs when it changed.
+// A Full delta carries everything and replaces what the client had.
+type StateDelta struct {
+	Version        uint64                     `json:"version"`
+	Full           bool                       `json:"full"`
+	Changes        map[string]json.RawMessage `json:"changes"`
+	Commits        []Commit                   `json:"commits"`
+	RemovedCommits []string                   `json:"removedCommits,omitempty"`
+	CommitOrder    []string                   `json:"commitOrder,omitempty"`
+}
+
+type versionedField struct {
+	data    json.RawMessage
+	version uint64
+}
+
+type versionedCommit struct {
+	commit  Commit
+	version uint64
+}
+
+// stateVersions tracks the versions of one session's state.
+type stateVersions struct {
+	mu           sync.Mutex
+	version      uint64
+	base         uint64 // Deltas can only be computed from this version onward
+	fields       map[string]versionedField
+	commits      map[string]versionedCommit
+	removed      map[string]uint64 // Tombstones: commit ID -> version it was removed at
+	order        []string
+	orderVersion uint64
+}
+
+// StateDelta returns what changed in the graph state of a session since version
+// since. A since of 0, or a version the tracker cannot diff against (from before
+// a backend restart or older than the remembered tombstones), yields a full delta.
+func (sm *SessionManager) StateDelta(sessionID string, since uint64) (*StateDelta, error) {
+	state, err := sm.freshGraphState(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	commits := state.Commits
+	state.Commits = nil
+	fields, err := graphStateFields(state)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "commits")
+
+	tracker := sm.stateVersions(sessionID)
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.update(fields, commits)
+	return tracker.delta(since), nil
+}
+
+// stateVersions returns the version tracker of a session, creating it if needed.
+func (sm *SessionManager) stateVersions(sessionID string) *stateVersions {
+	sm.streamMu.Lock()
+	defer sm.streamMu.Unlock()
+	if sm.deltas == nil {
+		sm.deltas = make(map[string]*stateVersions)
+	}
+	tracker, ok := sm.deltas[sessionID]
+	if !ok {
+		tracker = &stateVersions{
+			fields:  make(map[string]versionedField),
+			commits: make(map[string]versionedCommit),
+			removed: make(map[string]uint64),
+		}
+		sm.deltas[sessionID] = tracker
+	}
+	return tracker
+}
+
+// dropStateVersions forgets the version tracker of a deleted session.
+func (sm *SessionManager) dropStateVersions(sessionID string) {
+	sm.streamMu.Lock()
+	delete(sm.deltas, sessionID)
+	sm.streamMu.Unlock()
+}
+
+// update records the latest state, bumping the version once if anything
+// changed. t.mu must be held.
+func (t *stateVersions) update(fields map[string]json.RawMessage, commits []Commit) {
+	next := t.version + 1
+	changed := false
+
+	for key, value := range fields {
+		if old, ok := t.fields[key]; !ok || !bytes.Equal(old.data, value) {
+			t.fields[key] = versionedField{data: value, version: next}
+			changed = true
+		}
+	}
+	for key, old := range t.fields {
+		if _, ok := fields[key]; !ok && old.data != nil {
+			t.fields[key] = versionedField{version: next}
+			changed = true
+		}
+	}
+
+	order := make([]string, len(commits))
+	present := make(map[string]bool, len(commits))
+	for i, c := range commits {
+		order[i] = c.ID
+		present[c.ID] = true
+		if old, ok := t.commits[c.ID]; !ok || !reflect.DeepEqual(old.commit, c) {
+			t.commits[c.ID] = versionedCommit{commit: c, version: next}
+			delete(t.removed, c.ID)
+			changed = true
+		}
+	}
+	for id := range t.commits {
+		if !present[id] {
+			delete(t.commits, id)
+			t.removed[id] = next
+			changed = true
+		}
+	}
+	if !equalStrings(t.order, order) {
+		t.order, t.orderVersion = order, next
+		changed = true
+	}
+
+	if !changed {
+		return
+	}
+	t.version = next
+	if len(t.removed) > maxCommitTombstones {
+		t.removed = make(map[string]uint64)
+		t.base = t.version
+	}
+}
+
+// delta collects the entries that changed after version since. t.mu must be held.
+func (t *stateVersions) delta(since uint64) *StateDelta {
+	full := since == 0 || since > t.version || since < t.base
+	if full {
+		since = 0
+	}
+	d := &StateDelta{Version: t.version, Full: full, Changes: make(map[string]json.RawMessage), Commits: []Commit{}}
+
+	for key, f := range t.fields {
+		if f.version <= since {
+			continue
+		}
+		if f.data == nil {
+			if !full {
+				d.Changes[key] = json.RawMessage("null")
+			}
+			continue
+		}
+		d.Changes[key] = f.data
+	}
+	for _, id := range t.order {
+		if c := t.commits[id]; c.version > since {
+			d.Commits = append(d.Commits, c.commit)
+		}
+	}
+	if !full {
+		for id, v := range t.removed {
+			if v > since {
+				d.RemovedCommits = append(d.RemovedCommits, id)
+			}
+		}
+	}
+	if t.orderVersion > since {
+		d.CommitOrder = t.order
+	}
+	return d
+}
+
+// freshGraphState computes the graph state of a session with a freshly walked
+// file listing. The cache is not invalidated by commands, and a pushed or
+// polled listing would otherwise stay stale until the next change.
+func (sm *SessionManager) freshGraphState(sessionID string) (*GraphState, error) {
+	if sess, ok := sm.GetSession(sessionID); ok {
+		sess.FileCache.Invalidate()
+	}
+	return sm.GetGraphState(sessionID)
+}
+
+// graphStateFields splits the JSON encoding of state into its top-level fields.
+func graphStateFields(state *GraphState) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode graph state: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode graph state: %w", err)
+	}
+	return fields, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
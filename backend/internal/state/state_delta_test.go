@@ -0,0 +1,86 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDelta(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "s1", 3)
+
+	// First poll: everything
+	delta, err := sm.StateDelta("s1", 0)
+	require.NoError(t, err)
+	assert.True(t, delta.Full)
+	assert.Equal(t, uint64(1), delta.Version)
+	assert.Len(t, delta.Commits, 3)
+	assert.Equal(t, []string{hashes[2], hashes[1], hashes[0]}, delta.CommitOrder)
+	assert.Contains(t, delta.Changes, "branches")
+	assert.NotContains(t, delta.Changes, "commits")
+
+	// Nothing changed: same version, empty delta
+	delta, err = sm.StateDelta("s1", 1)
+	require.NoError(t, err)
+	assert.False(t, delta.Full)
+	assert.Equal(t, uint64(1), delta.Version)
+	assert.Empty(t, delta.Changes)
+	assert.Empty(t, delta.Commits)
+	assert.Nil(t, delta.CommitOrder)
+
+	// A new commit: only it and the fields it touched
+	w, _ := repo.Worktree()
+	sig := &object.Signature{Name: "User", Email: "user@example.com", When: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	added, err := w.Commit("next", &gogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	require.NoError(t, err)
+
+	delta, err = sm.StateDelta("s1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), delta.Version)
+	require.Len(t, delta.Commits, 1)
+	assert.Equal(t, added.String(), delta.Commits[0].ID)
+	assert.Contains(t, delta.Changes, "branches")
+	assert.NotContains(t, delta.Changes, "remotes")
+	assert.Equal(t, added.String(), delta.CommitOrder[0])
+
+	// Resetting master away leaves the commit dangling: it changes, it is not removed
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", plumbing.NewHash(hashes[2]))))
+	delta, err = sm.StateDelta("s1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), delta.Version)
+	require.Len(t, delta.Commits, 1)
+	assert.True(t, delta.Commits[0].Dangling)
+	assert.Empty(t, delta.RemovedCommits)
+
+	// Leaving the repo removes every commit from the graph
+	sess.CurrentDir = "/"
+	delta, err = sm.StateDelta("s1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), delta.Version)
+	assert.Empty(t, delta.Commits)
+	assert.ElementsMatch(t, append(hashes, added.String()), delta.RemovedCommits)
+
+	// A client two versions behind sees both the addition and the removal collapse
+	delta, err = sm.StateDelta("s1", 1)
+	require.NoError(t, err)
+	assert.Len(t, delta.RemovedCommits, 4)
+	assert.Empty(t, delta.Commits)
+
+	// Unknown versions get a full delta; deleting the session forgets the tracker
+	delta, err = sm.StateDelta("s1", 99)
+	require.NoError(t, err)
+	assert.True(t, delta.Full)
+
+	sm.DeleteSession("s1")
+	_, err = sm.StateDelta("s1", 0)
+	assert.Error(t, err)
+	sm.streamMu.Lock()
+	assert.NotContains(t, sm.deltas, "s1")
+	sm.streamMu.Unlock()
+}
@@ -0,0 +1,221 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// State streams
+//
+// Clients subscribe to a session to be pushed its GraphState instead of
+// polling /api/state after every command. Each session has one stream that
+// remembers the last state it published; PublishState recomputes the state and
+// sends only the top-level fields that changed. Updates a slow subscriber has
+// not read yet are merged, so it always catches up to the latest state.
+
+// StateUpdate is one message of a session's state stream. Changes holds the
+// JSON encoding of each GraphState field that changed (keyed by its JSON name),
+// or null for a field that disappeared. The first update a subscriber receives
+// is Full and carries every field.
+type StateUpdate struct {
+	Seq     uint64                     `json:"seq"`
+	Full    bool                       `json:"full"`
+	Changes map[string]json.RawMessage `json:"changes"`
+}
+
+// StateSubscriber receives the state updates of one session.
+type StateSubscriber struct {
+	stream *stateStream
+	ready  chan struct{} // Signalled (capacity 1) when an update is pending
+
+	mu      sync.Mutex
+	pending map[string]json.RawMessage
+	full    bool
+	seq     uint64
+}
+
+type stateStream struct {
+	manager   *SessionManager
+	sessionID string
+	mu        sync.Mutex // Serializes publishes so updates are sent in order
+	last      map[string]json.RawMessage
+	seq       uint64
+	subs      map[*StateSubscriber]struct{}
+}
+
+// SubscribeState starts streaming the graph state of a session. The current
+// state is pending right away as a full update. Call Close when done.
+func (sm *SessionManager) SubscribeState(sessionID string) (*StateSubscriber, error) {
+	for {
+		stream := sm.stateStream(sessionID)
+		stream.mu.Lock()
+		if !sm.isCurrentStream(stream) {
+			// Dropped by the last subscriber closing meanwhile
+			stream.mu.Unlock()
+			continue
+		}
+		fields, err := sm.stateFields(sessionID)
+		if err != nil {
+			sm.dropStreamIfIdle(stream)
+			stream.mu.Unlock()
+			return nil, err
+		}
+		// Existing subscribers get whatever changed without being published
+		stream.update(fields)
+
+		sub := &StateSubscriber{stream: stream, ready: make(chan struct{}, 1)}
+		stream.subs[sub] = struct{}{}
+		sub.merge(stream.last, stream.seq, true)
+		stream.mu.Unlock()
+		return sub, nil
+	}
+}
+
+// PublishState recomputes the graph state of a session and sends what changed
+// to its subscribers. It is a no-op when nobody is subscribed. Callers must not
+// hold the session lock.
+func (sm *SessionManager) PublishState(sessionID string) {
+	sm.streamMu.Lock()
+	stream, ok := sm.streams[sessionID]
+	sm.streamMu.Unlock()
+	if !ok {
+		return
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if len(stream.subs) == 0 {
+		return
+	}
+	if fields, err := sm.stateFields(sessionID); err == nil {
+		stream.update(fields)
+	}
+}
+
+// update records fields as the latest state and sends the fields that differ
+// from the previous one to every subscriber. stream.mu must be held.
+func (stream *stateStream) update(fields map[string]json.RawMessage) {
+	changes := make(map[string]json.RawMessage)
+	for key, value := range fields {
+		if !bytes.Equal(stream.last[key], value) {
+			changes[key] = value
+		}
+	}
+	for key := range stream.last {
+		if _, ok := fields[key]; !ok {
+			changes[key] = json.RawMessage("null")
+		}
+	}
+	stream.last = fields
+	if len(changes) == 0 {
+		return
+	}
+
+	stream.seq++
+	for sub := range stream.subs {
+		sub.merge(changes, stream.seq, false)
+	}
+}
+
+// StateSubscribers returns how many clients are subscribed to a session.
+func (sm *SessionManager) StateSubscribers(sessionID string) int {
+	sm.streamMu.Lock()
+	stream, ok := sm.streams[sessionID]
+	sm.streamMu.Unlock()
+	if !ok {
+		return 0
+	}
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return len(stream.subs)
+}
+
+// stateFields computes the graph state of a session, split into its JSON fields.
+func (sm *SessionManager) stateFields(sessionID string) (map[string]json.RawMessage, error) {
+	state, err := sm.freshGraphState(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return graphStateFields(state)
+}
+
+// stateStream returns the stream of a session, creating it if needed.
+func (sm *SessionManager) stateStream(sessionID string) *stateStream {
+	sm.streamMu.Lock()
+	defer sm.streamMu.Unlock()
+	if sm.streams == nil {
+		sm.streams = make(map[string]*stateStream)
+	}
+	stream, ok := sm.streams[sessionID]
+	if !ok {
+		stream = &stateStream{manager: sm, sessionID: sessionID, subs: make(map[*StateSubscriber]struct{})}
+		sm.streams[sessionID] = stream
+	}
+	return stream
+}
+
+func (sm *SessionManager) isCurrentStream(stream *stateStream) bool {
+	sm.streamMu.Lock()
+	defer sm.streamMu.Unlock()
+	return sm.streams[stream.sessionID] == stream
+}
+
+// dropStreamIfIdle forgets a stream without subscribers, so the next
+// subscriber starts from a freshly computed state. stream.mu must be held.
+func (sm *SessionManager) dropStreamIfIdle(stream *stateStream) {
+	if len(stream.subs) > 0 {
+		return
+	}
+	sm.streamMu.Lock()
+	if sm.streams[stream.sessionID] == stream {
+		delete(sm.streams, stream.sessionID)
+	}
+	sm.streamMu.Unlock()
+}
+
+// Ready is signalled whenever an update is pending; call Next to take it.
+func (sub *StateSubscriber) Ready() <-chan struct{} {
+	return sub.ready
+}
+
+// Next takes the pending update, with every change since the previous call
+// merged into one. ok is false when nothing is pending.
+func (sub *StateSubscriber) Next() (update StateUpdate, ok bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.pending == nil {
+		return StateUpdate{}, false
+	}
+	update = StateUpdate{Seq: sub.seq, Full: sub.full, Changes: sub.pending}
+	sub.pending = nil
+	sub.full = false
+	return update, true
+}
+
+// Close stops the subscription.
+func (sub *StateSubscriber) Close() {
+	stream := sub.stream
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	delete(stream.subs, sub)
+	stream.manager.dropStreamIfIdle(stream)
+}
+
+func (sub *StateSubscriber) merge(changes map[string]json.RawMessage, seq uint64, full bool) {
+	sub.mu.Lock()
+	if sub.pending == nil {
+		sub.pending = make(map[string]json.RawMessage, len(changes))
+	}
+	for key, value := range changes {
+		sub.pending[key] = value
+	}
+	sub.full = sub.full || full
+	sub.seq = seq
+	sub.mu.Unlock()
+
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStream_SendsChangedFieldsAndMergesPending(t *testing.T) {
+	sm := NewSessionManager()
+	sess, err := sm.CreateSession("s1")
+	require.NoError(t, err)
+
+	sub, err := sm.SubscribeState("s1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, sm.StateSubscribers("s1"))
+
+	// The current state is pending right away
+	select {
+	case <-sub.Ready():
+	default:
+		t.Fatal("no initial update")
+	}
+	update, ok := sub.Next()
+	require.True(t, ok)
+	assert.True(t, update.Full)
+	assert.Contains(t, update.Changes, "commits")
+	assert.Contains(t, update.Changes, "currentPath")
+
+	// Nothing changed, nothing sent
+	sm.PublishState("s1")
+	_, ok = sub.Next()
+	assert.False(t, ok)
+
+	// Two changes before the client reads are merged into one update
+	sess.CurrentDir = "/work"
+	sm.PublishState("s1")
+	require.NoError(t, util.WriteFile(sess.Filesystem, "/work/notes.txt", []byte("hi"), 0644))
+	sm.PublishState("s1")
+
+	update, ok = sub.Next()
+	require.True(t, ok)
+	assert.False(t, update.Full)
+	assert.Equal(t, `"/work"`, string(update.Changes["currentPath"]))
+	assert.Contains(t, update.Changes, "files")
+	assert.NotContains(t, update.Changes, "commits")
+	assert.Equal(t, uint64(3), update.Seq)
+
+	sub.Close()
+	assert.Equal(t, 0, sm.StateSubscribers("s1"))
+	sm.PublishState("s1") // No subscribers: no-op
+}
+
+func TestStateStream_UnknownSession(t *testing.T) {
+	sm := NewSessionManager()
+	_, err := sm.SubscribeState("missing")
+	assert.Error(t, err)
+	assert.Equal(t, 0, sm.StateSubscribers("missing"))
+}
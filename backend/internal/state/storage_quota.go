@@ -0,0 +1,150 @@
+package state
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Storage quotas
+//
+// Sessions keep their repositories and working trees in memory, so a learner
+// cloning a huge ingested remote or committing in a loop drives server memory
+// up. Each session is held to a quota on the objects stored in its
+// repositories, the bytes of their blobs and the files in its filesystem.
+// Commands that add objects (commit, clone, fetch) check the quota before they
+// grow the session and fail with a QuotaError instead.
+
+// StorageQuota limits what one session may store. A zero field means unlimited.
+type StorageQuota struct {
+	MaxObjects   int   `json:"maxObjects"`
+	MaxBlobBytes int64 `json:"maxBlobBytes"`
+	MaxFiles     int   `json:"maxFiles"`
+}
+
+// DefaultStorageQuota is generous for exercises but stops runaway sessions.
+var DefaultStorageQuota = StorageQuota{
+	MaxObjects:   100000,
+	MaxBlobBytes: 200 << 20,
+	MaxFiles:     20000,
+}
+
+// StorageUsage is what a session stores, in total and per repository.
+type StorageUsage struct {
+	Objects   int                `json:"objects"`
+	BlobBytes int64              `json:"blobBytes"`
+	Files     int                `json:"files"` // Working tree files, excluding .git
+	Quota     StorageQuota       `json:"quota"`
+	Repos     []RepoStorageUsage `json:"repos"`
+}
+
+// RepoStorageUsage is the object storage of one repository of a session.
+type RepoStorageUsage struct {
+	Path      string `json:"path"`
+	Objects   int    `json:"objects"`
+	BlobBytes int64  `json:"blobBytes"`
+}
+
+// QuotaError reports that a session would exceed one of its storage limits.
+type QuotaError struct {
+	Resource string // "objects", "blob bytes" or "files"
+	Used     int64  // Usage the operation would reach
+	Limit    int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("fatal: storage quota exceeded: this would use %d %s, the limit for a session is %d.\nhint: remove repositories you no longer need (rm -rf <dir>) or start a new session",
+		e.Used, e.Resource, e.Limit)
+}
+
+// StorageQuota returns the quota the session is held to.
+func (s *Session) StorageQuota() StorageQuota {
+	if s.Manager == nil {
+		return StorageQuota{}
+	}
+	return s.Manager.StorageQuota
+}
+
+// StorageUsage measures what the session stores. The caller must hold the session lock.
+func (s *Session) StorageUsage() StorageUsage {
+	usage := StorageUsage{Quota: s.StorageQuota(), Repos: []RepoStorageUsage{}}
+
+	paths := make([]string, 0, len(s.Repos))
+	for p := range s.Repos {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		objects, blobBytes := objectUsage(s.Repos[p])
+		usage.Repos = append(usage.Repos, RepoStorageUsage{Path: p, Objects: objects, BlobBytes: blobBytes})
+		usage.Objects += objects
+		usage.BlobBytes += blobBytes
+	}
+	usage.Files = countFiles(s, "/")
+	return usage
+}
+
+// CheckStorageQuota reports a QuotaError if the session, with the given
+// amounts added to its current usage, would exceed its quota. The caller must
+// hold the session lock.
+func (s *Session) CheckStorageQuota(addObjects int, addBlobBytes int64, addFiles int) error {
+	quota := s.StorageQuota()
+	if quota == (StorageQuota{}) {
+		return nil
+	}
+	return s.StorageUsage().Check(addObjects, addBlobBytes, addFiles)
+}
+
+// Check reports a QuotaError if the given amounts added to u would exceed u.Quota.
+func (u StorageUsage) Check(addObjects int, addBlobBytes int64, addFiles int) error {
+	q := u.Quota
+	if n := u.Objects + addObjects; q.MaxObjects > 0 && n > q.MaxObjects {
+		return &QuotaError{Resource: "objects", Used: int64(n), Limit: int64(q.MaxObjects)}
+	}
+	if n := u.BlobBytes + addBlobBytes; q.MaxBlobBytes > 0 && n > q.MaxBlobBytes {
+		return &QuotaError{Resource: "blob bytes", Used: n, Limit: q.MaxBlobBytes}
+	}
+	if n := u.Files + addFiles; q.MaxFiles > 0 && n > q.MaxFiles {
+		return &QuotaError{Resource: "files", Used: int64(n), Limit: int64(q.MaxFiles)}
+	}
+	return nil
+}
+
+// objectUsage counts the objects a repository stores itself and the bytes of
+// its blobs. Objects a hybrid repo reads from its shared remote are not counted.
+func objectUsage(repo *gogit.Repository) (objects int, blobBytes int64) {
+	iter, err := localObjectStorer(repo).IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return 0, 0
+	}
+	_ = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		objects++
+		if obj.Type() == plumbing.BlobObject {
+			blobBytes += obj.Size()
+		}
+		return nil
+	})
+	return objects, blobBytes
+}
+
+// countFiles counts the files under dir, skipping .git directories.
+func countFiles(s *Session, dir string) int {
+	entries, err := s.Filesystem.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		switch {
+		case e.IsDir() && e.Name() == ".git":
+		case e.IsDir():
+			n += countFiles(s, path.Join(dir, e.Name()))
+		default:
+			n++
+		}
+	}
+	return n
+}
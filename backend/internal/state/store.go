@@ -0,0 +1,296 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store is a bucketed key/value store for metadata that should survive a
+// restart: pull requests, mission progress and the audit log. The interface
+// mirrors embedded databases such as BoltDB so another backend can be
+// plugged in without touching SessionManager.
+type Store interface {
+	Put(bucket, key string, value []byte) error
+	Get(bucket, key string) ([]byte, bool, error)
+	Delete(bucket, key string) error
+	// List returns the keys of a bucket in ascending order.
+	List(bucket string) ([]string, error)
+	Close() error
+}
+
+// StoreEnv selects the metadata store backend: "memory" (default) or "file".
+const StoreEnv = "GITGYM_STORE"
+
+// OpenStore opens the store backend named kind. path is only used by file-backed stores.
+func OpenStore(kind, path string) (Store, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return OpenFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q (supported: memory, file)", kind)
+	}
+}
+
+// MemoryStore keeps everything in process memory. It is the default and loses data on restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryStore) Put(bucket, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(bucket, key, value)
+	return nil
+}
+
+func (m *MemoryStore) put(bucket, key string, value []byte) {
+	b, ok := m.buckets[bucket]
+	if !ok {
+		b = make(map[string][]byte)
+		m.buckets[bucket] = b
+	}
+	b[key] = append([]byte(nil), value...)
+}
+
+func (m *MemoryStore) Get(bucket, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.buckets[bucket][key]
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), v...), true, nil
+}
+
+func (m *MemoryStore) Delete(bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *MemoryStore) List(bucket string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.buckets[bucket]))
+	for k := range m.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
+
+// FileStore is an embedded, dependency-free store backed by a single
+// append-only journal file. Every write appends one JSON record; on open the
+// journal is replayed into memory and compacted. It is compacted again once
+// it has grown past compactThreshold and to twice its compacted size, so
+// overwritten and deleted records (the audit log rotates constantly) do not
+// pile up while the server runs.
+type FileStore struct {
+	MemoryStore
+	path      string
+	file      *os.File
+	size      int64 // Bytes in the journal
+	compacted int64 // Bytes in the journal right after the last compaction
+}
+
+// compactThreshold is the journal size below which FileStore never compacts while open.
+const compactThreshold = 4 << 20
+
+// journalRecord is one line of the FileStore journal.
+type journalRecord struct {
+	Op     string `json:"op"` // "put" or "del"
+	Bucket string `json:"b"`
+	Key    string `json:"k"`
+	Value  []byte `json:"v,omitempty"`
+}
+
+// OpenFileStore opens (or creates) the journal at path.
+func OpenFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	fs := &FileStore{MemoryStore: MemoryStore{buckets: make(map[string]map[string][]byte)}, path: path}
+	if err := fs.replay(); err != nil {
+		return nil, fmt.Errorf("failed to read store %s: %w", path, err)
+	}
+	if err := fs.compact(); err != nil {
+		return nil, fmt.Errorf("failed to compact store %s: %w", path, err)
+	}
+	return fs, nil
+}
+
+func (f *FileStore) replay() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	var torn error
+	for scanner.Scan() {
+		line++
+		if torn != nil {
+			// Only the final line can be torn by a crash; a bad line with records after it is corruption
+			return fmt.Errorf("line %d: %w", line-1, torn)
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			torn = err
+			continue
+		}
+		switch rec.Op {
+		case "put":
+			f.put(rec.Bucket, rec.Key, rec.Value)
+		case "del":
+			delete(f.buckets[rec.Bucket], rec.Key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if torn != nil {
+		// A torn final line from a crash mid-write; everything before it is intact.
+		slog.Warn("store: dropping torn final record", "path", f.path, "line", line, "err", torn)
+	}
+	return nil
+}
+
+// compact rewrites the journal with one record per live key.
+func (f *FileStore) compact() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	tmp := f.path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	counter := &countingWriter{w: out}
+	w := bufio.NewWriter(counter)
+	enc := json.NewEncoder(w)
+	for bucket, entries := range f.buckets {
+		for key, value := range entries {
+			if err := enc.Encode(journalRecord{Op: "put", Bucket: bucket, Key: key, Value: value}); err != nil {
+				_ = out.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+
+	f.file, err = os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.size = counter.n
+	f.compacted = counter.n
+	return err
+}
+
+func (f *FileStore) append(rec journalRecord) error {
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	n, err := f.file.Write(append(line, '\n'))
+	f.size += int64(n)
+	return err
+}
+
+// maybeCompact compacts the journal once it has grown enough. A failed
+// compaction leaves the journal as it was; the next write tries again.
+func (f *FileStore) maybeCompact() {
+	if f.size < compactThreshold || f.size < 2*f.compacted {
+		return
+	}
+	if err := f.compact(); err != nil {
+		slog.Warn("store: failed to compact journal", "path", f.path, "err", err)
+		if f.file == nil {
+			f.file, _ = os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY, 0644)
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (f *FileStore) Put(bucket, key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.append(journalRecord{Op: "put", Bucket: bucket, Key: key, Value: value}); err != nil {
+		return err
+	}
+	f.put(bucket, key, value)
+	f.maybeCompact()
+	return nil
+}
+
+func (f *FileStore) Delete(bucket, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.buckets[bucket][key]; !ok {
+		return nil
+	}
+	if err := f.append(journalRecord{Op: "del", Bucket: bucket, Key: key}); err != nil {
+		return err
+	}
+	delete(f.buckets[bucket], key)
+	f.maybeCompact()
+	return nil
+}
+
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
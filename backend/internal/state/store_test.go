@@ -0,0 +1,142 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_ReplaysJournalAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitgym.db")
+
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Put("b", "k1", []byte("one")))
+	require.NoError(t, store.Put("b", "k2", []byte("two")))
+	require.NoError(t, store.Put("b", "k1", []byte("uno")))
+	require.NoError(t, store.Delete("b", "k2"))
+	require.NoError(t, store.Close())
+
+	// Simulate a crash mid-write: a torn record at the end of the journal
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, _ = f.WriteString(`{"op":"put","b":"b","k":"k3"`)
+	require.NoError(t, f.Close())
+
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	v, ok, err := reopened.Get("b", "k1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "uno", string(v))
+
+	keys, err := reopened.List("b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k1"}, keys)
+}
+
+func TestFileStore_RefusesCorruptJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitgym.db")
+	journal := `{"op":"put","b":"b","k":"k1","v":"b25l"}
+{"op":"put","b":"b",
+{"op":"put","b":"b","k":"k2","v":"dHdv"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(journal), 0644))
+
+	_, err := OpenFileStore(path)
+	require.Error(t, err, "a bad record followed by more records is not a torn write")
+	assert.Contains(t, err.Error(), "line 2")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, journal, string(data), "the journal must be left alone for inspection")
+}
+
+func TestFileStore_CompactsWhileOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitgym.db")
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	value := make([]byte, 64*1024)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, store.Put("b", "k", value))
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(compactThreshold), "overwritten records are compacted away")
+
+	require.NoError(t, store.Put("b", "other", []byte("kept")))
+	require.NoError(t, store.Close())
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	keys, err := reopened.List("b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k", "other"}, keys)
+}
+
+func TestUseStore_RestoresPullRequestsAndAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitgym.db")
+
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	sm := NewSessionManager()
+	require.NoError(t, sm.UseStore(store))
+
+	_, err = sm.CreatePullRequest("First", "", "feature-a", "main", "dev", "origin")
+	require.NoError(t, err)
+	second, err := sm.CreatePullRequest("Second", "", "feature-b", "main", "dev", "origin")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetPullRequestState(second.ID, "MERGED"))
+	require.NoError(t, sm.DeletePullRequest(1))
+
+	sm.RecordAudit("s1", "git status", nil)
+	sm.RecordAudit("s2", "git log", nil)
+	sm.RecordAudit("s1", "git push", errors.New("rejected"))
+	sm.RecordMissionProgress("s1", "101-basic", 1, 2)
+	sm.RecordMissionProgress("s1", "101-basic", 2, 2)
+	sm.RecordMissionSteps("s1", "101-basic", 2, 3)
+	sm.RecordMissionHint("s1", "101-basic")
+	require.NoError(t, store.Close())
+
+	// Restart with a fresh manager on the same file
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	restarted := NewSessionManager()
+	require.NoError(t, restarted.UseStore(reopened))
+
+	prs := restarted.GetPullRequests()
+	require.Len(t, prs, 1)
+	assert.Equal(t, "Second", prs[0].Title)
+	assert.Equal(t, "MERGED", prs[0].State)
+
+	next, err := restarted.CreatePullRequest("Third", "", "feature-c", "main", "dev", "origin")
+	require.NoError(t, err)
+	assert.Equal(t, 3, next.ID, "PR ids must not be reused after a restart")
+
+	audit := restarted.AuditLog("s1", 0)
+	require.Len(t, audit, 2)
+	assert.Equal(t, "git status", audit[0].Command)
+	assert.Equal(t, "rejected", audit[1].Error)
+
+	restarted.RecordAudit("s1", "git fetch", nil)
+	latest := restarted.AuditLog("", 1)
+	require.Len(t, latest, 1)
+	assert.Equal(t, uint64(4), latest[0].Seq)
+
+	progress := restarted.MissionProgressFor("s1")
+	require.Len(t, progress, 1)
+	assert.True(t, progress[0].Completed)
+	assert.Equal(t, 2, progress[0].Attempts)
+	assert.Equal(t, 2, progress[0].Step)
+	assert.Equal(t, 3, progress[0].Steps)
+	assert.Equal(t, 1, progress[0].HintsUsed)
+}
@@ -0,0 +1,538 @@
+package state
+
+// teammates.go - Virtual teammates acting on shared remotes
+//
+// A scenario file describes what simulated teammates do to a shared remote:
+// commit, create branches, force-push and open pull requests, each step after
+// an optional delay. Runs play the steps on a timer or one at a time on
+// demand, so fetch, pull and conflict lessons have someone else to work with.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+)
+
+// Teammate actions
+const (
+	TeammateCommit      = "commit"       // Commit Files/Delete on Branch
+	TeammateBranch      = "branch"       // Create Branch at From
+	TeammateForcePush   = "force-push"   // Drop commits from Branch, then optionally commit on top
+	TeammatePullRequest = "pull-request" // Open a pull request from Branch into Base
+)
+
+// DefaultScenarioDir is where scenario files are looked up, relative to the working directory.
+const DefaultScenarioDir = "scenarios"
+
+// DefaultTeammate is who acts when a scenario names no author.
+var DefaultTeammate = UserIdentity{Name: "Simulated User", Email: "simulated@example.com"}
+
+var scenarioNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// TeammateAction is one step of a scenario.
+type TeammateAction struct {
+	Action      string            `yaml:"action" json:"action"`
+	Delay       string            `yaml:"delay,omitempty" json:"delay,omitempty"`   // Wait before the step when timed, e.g. "30s"
+	Author      string            `yaml:"author,omitempty" json:"author,omitempty"` // Defaults to the scenario's author
+	Email       string            `yaml:"email,omitempty" json:"email,omitempty"`
+	Branch      string            `yaml:"branch,omitempty" json:"branch,omitempty"` // Defaults to the remote's default branch
+	From        string            `yaml:"from,omitempty" json:"from,omitempty"`     // branch: start point (default branch by default)
+	Message     string            `yaml:"message,omitempty" json:"message,omitempty"`
+	Files       map[string]string `yaml:"files,omitempty" json:"files,omitempty"`   // commit: path -> new content
+	Delete      []string          `yaml:"delete,omitempty" json:"delete,omitempty"` // commit: paths to remove
+	Drop        int               `yaml:"drop,omitempty" json:"drop,omitempty"`     // force-push: commits to drop (default 1)
+	Title       string            `yaml:"title,omitempty" json:"title,omitempty"`   // pull-request
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Base        string            `yaml:"base,omitempty" json:"base,omitempty"` // pull-request: target branch (default branch by default)
+}
+
+// TeammateScenario is a scripted sequence of teammate actions.
+type TeammateScenario struct {
+	Name        string           `yaml:"name" json:"name"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Author      string           `yaml:"author,omitempty" json:"author,omitempty"` // Default author of every step
+	Email       string           `yaml:"email,omitempty" json:"email,omitempty"`
+	Steps       []TeammateAction `yaml:"steps" json:"steps"`
+}
+
+// Validate reports the first step that is incomplete or malformed.
+func (sc *TeammateScenario) Validate() error {
+	if len(sc.Steps) == 0 {
+		return fmt.Errorf("scenario %q has no steps", sc.Name)
+	}
+	for i, a := range sc.Steps {
+		if err := a.validate(); err != nil {
+			return fmt.Errorf("scenario %q step %d: %w", sc.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (a TeammateAction) validate() error {
+	if a.Delay != "" {
+		if d, err := time.ParseDuration(a.Delay); err != nil || d < 0 {
+			return fmt.Errorf("invalid delay %q", a.Delay)
+		}
+	}
+	switch a.Action {
+	case TeammateCommit:
+		if len(a.Files) == 0 && len(a.Delete) == 0 {
+			return fmt.Errorf("commit needs files or delete")
+		}
+	case TeammateBranch:
+		if a.Branch == "" {
+			return fmt.Errorf("branch needs a branch name")
+		}
+	case TeammateForcePush:
+		if a.Drop < 0 {
+			return fmt.Errorf("force-push cannot drop %d commits", a.Drop)
+		}
+	case TeammatePullRequest:
+		if a.Branch == "" || a.Title == "" {
+			return fmt.Errorf("pull-request needs a branch and a title")
+		}
+	default:
+		return fmt.Errorf("unknown action %q (want %s, %s, %s or %s)", a.Action, TeammateCommit, TeammateBranch, TeammateForcePush, TeammatePullRequest)
+	}
+	return nil
+}
+
+// delay returns how long a timed run waits before the step.
+func (a TeammateAction) delay() time.Duration {
+	d, _ := time.ParseDuration(a.Delay)
+	return d
+}
+
+// ParseTeammateScenario decodes and validates a YAML (or JSON) scenario.
+func ParseTeammateScenario(data []byte) (*TeammateScenario, error) {
+	var sc TeammateScenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// LoadTeammateScenario reads <ScenarioDir>/<name>.yaml.
+func (sm *SessionManager) LoadTeammateScenario(name string) (*TeammateScenario, error) {
+	if !scenarioNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid scenario name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(sm.scenarioDir(), name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("scenario %q not found", name)
+	}
+	sc, err := ParseTeammateScenario(data)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Name == "" {
+		sc.Name = name
+	}
+	return sc, nil
+}
+
+// ListTeammateScenarios returns the names of the scenario files, sorted.
+func (sm *SessionManager) ListTeammateScenarios() []string {
+	matches, _ := filepath.Glob(filepath.Join(sm.scenarioDir(), "*.yaml"))
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(m), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (sm *SessionManager) scenarioDir() string {
+	if sm.ScenarioDir != "" {
+		return sm.ScenarioDir
+	}
+	return DefaultScenarioDir
+}
+
+// ApplyTeammateAction performs one teammate action on the named shared remote
+// right away and returns a line describing what happened.
+func (sm *SessionManager) ApplyTeammateAction(remote string, a TeammateAction) (string, error) {
+	if err := a.validate(); err != nil {
+		return "", err
+	}
+	user := DefaultTeammate
+	if a.Author != "" {
+		user = UserIdentity{Name: a.Author, Email: a.Email}
+		if user.Email == "" {
+			user.Email = user.Slug() + "@example.com"
+		}
+	}
+
+	repo, ok := sm.GetSharedRemote(remote)
+	if !ok {
+		return "", fmt.Errorf("remote %s not found", remote)
+	}
+	unlock := sm.LockRemote(repo)
+	out, updated, hash, err := applyTeammateAction(repo, a, user)
+	unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if a.Action == TeammatePullRequest {
+		base := a.Base
+		if base == "" {
+			base = defaultBranch(repo)
+		}
+		pr, err := sm.CreatePullRequest(a.Title, a.Description, a.Branch, base, user.Name, remote)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s opened pull request #%d: %s (%s -> %s)", user.Name, pr.ID, a.Title, a.Branch, base), nil
+	}
+	if updated != "" {
+		sm.BranchPushed(repo, updated, hash)
+	}
+	return out, nil
+}
+
+// applyTeammateAction updates repo for a ref-changing action. It returns the
+// description, the branch it moved and where to. Caller holds the remote lock of repo.
+func applyTeammateAction(repo *gogit.Repository, a TeammateAction, user UserIdentity) (string, string, plumbing.Hash, error) {
+	branch := a.Branch
+	if branch == "" {
+		branch = defaultBranch(repo)
+	}
+	refName := plumbing.NewBranchReferenceName(branch)
+
+	switch a.Action {
+	case TeammateBranch:
+		from := a.From
+		if from == "" {
+			from = defaultBranch(repo)
+		}
+		if _, err := repo.Reference(refName, true); err == nil {
+			return "", "", plumbing.ZeroHash, fmt.Errorf("branch %s already exists on the remote", branch)
+		}
+		start, err := repo.Reference(plumbing.NewBranchReferenceName(from), true)
+		if err != nil {
+			return "", "", plumbing.ZeroHash, fmt.Errorf("start point %s not found on the remote", from)
+		}
+		if err := setTeammateRef(repo, user, refName, start.Hash()); err != nil {
+			return "", "", plumbing.ZeroHash, err
+		}
+		return fmt.Sprintf("%s created branch %s from %s", user.Name, branch, from), branch, start.Hash(), nil
+
+	case TeammateCommit, TeammateForcePush:
+		ref, err := repo.Reference(refName, true)
+		if err != nil {
+			return "", "", plumbing.ZeroHash, fmt.Errorf("branch %s not found on the remote", branch)
+		}
+		tip, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return "", "", plumbing.ZeroHash, err
+		}
+
+		dropped := 0
+		if a.Action == TeammateForcePush {
+			drop := a.Drop
+			if drop == 0 {
+				drop = 1
+			}
+			for ; dropped < drop; dropped++ {
+				if tip.NumParents() == 0 {
+					return "", "", plumbing.ZeroHash, fmt.Errorf("cannot drop %d commits from %s: history is too short", drop, branch)
+				}
+				if tip, err = tip.Parent(0); err != nil {
+					return "", "", plumbing.ZeroHash, err
+				}
+			}
+		}
+
+		newTip := tip.Hash
+		message := a.Message
+		if message == "" {
+			message = "Update from " + user.Name
+		}
+		if len(a.Files) > 0 || len(a.Delete) > 0 {
+			if newTip, err = commitTeammateChanges(repo, tip, a, user, message); err != nil {
+				return "", "", plumbing.ZeroHash, err
+			}
+		}
+		if err := setTeammateRef(repo, user, refName, newTip); err != nil {
+			return "", "", plumbing.ZeroHash, err
+		}
+		if a.Action == TeammateForcePush {
+			return fmt.Sprintf("%s force-pushed %s (+ %s...%s, dropped %d commit(s))", user.Name, branch, ref.Hash().String()[:7], newTip.String()[:7], dropped), branch, newTip, nil
+		}
+		return fmt.Sprintf("%s pushed %s to %s: %s", user.Name, newTip.String()[:7], branch, strings.SplitN(message, "\n", 2)[0]), branch, newTip, nil
+	}
+	return "", "", plumbing.ZeroHash, nil
+}
+
+// setTeammateRef moves a branch of the remote and the teammate's tracking ref with it.
+func setTeammateRef(repo *gogit.Repository, user UserIdentity, name plumbing.ReferenceName, hash plumbing.Hash) error {
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return err
+	}
+	return RecordUserPush(repo, user, name.Short(), hash)
+}
+
+// defaultBranch returns the branch the remote's HEAD points at, or main.
+func defaultBranch(repo *gogit.Repository) string {
+	if head, err := repo.Reference(plumbing.HEAD, false); err == nil && head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short()
+	}
+	return "main"
+}
+
+// commitTeammateChanges stores a commit on top of parent with the action's file changes.
+func commitTeammateChanges(repo *gogit.Repository, parent *object.Commit, a TeammateAction, user UserIdentity, message string) (plumbing.Hash, error) {
+	files := make(map[string]object.TreeEntry)
+	iter, err := parent.Files()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := iter.ForEach(func(f *object.File) error {
+		files[f.Name] = object.TreeEntry{Name: f.Name, Mode: f.Mode, Hash: f.Hash}
+		return nil
+	}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	for _, path := range a.Delete {
+		delete(files, path)
+	}
+	for path, content := range a.Files {
+		hash, err := storeBlobObject(repo, []byte(content))
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		files[path] = object.TreeEntry{Name: path, Mode: filemode.Regular, Hash: hash}
+	}
+
+	tree, err := storeFlatTree(repo, files)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	sig := object.Signature{Name: user.Name, Email: user.Email, When: time.Now()}
+	return storeEncodable(repo, &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      message,
+		TreeHash:     tree,
+		ParentHashes: []plumbing.Hash{parent.Hash},
+	})
+}
+
+// storeFlatTree stores the trees for files keyed by full path and returns the root tree hash.
+func storeFlatTree(repo *gogit.Repository, files map[string]object.TreeEntry) (plumbing.Hash, error) {
+	subdirs := make(map[string]map[string]object.TreeEntry)
+	tree := &object.Tree{}
+	for path, entry := range files {
+		if dir, rest, nested := strings.Cut(path, "/"); nested {
+			if subdirs[dir] == nil {
+				subdirs[dir] = make(map[string]object.TreeEntry)
+			}
+			subdirs[dir][rest] = entry
+			continue
+		}
+		entry.Name = path
+		tree.Entries = append(tree.Entries, entry)
+	}
+	for dir, sub := range subdirs {
+		hash, err := storeFlatTree(repo, sub)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+	// Git orders entries as if directory names ended with '/'
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j]) })
+	return storeEncodable(repo, tree)
+}
+
+func storeEncodable(repo *gogit.Repository, o interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+func storeBlobObject(repo *gogit.Repository, data []byte) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(data)))
+	wr, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := wr.Write(data); err != nil {
+		_ = wr.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := wr.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+// TeammateRun is a scenario being played against a shared remote.
+type TeammateRun struct {
+	Scenario string     `json:"scenario"`
+	Remote   string     `json:"remote"`
+	Timed    bool       `json:"timed"`            // Steps play on their delays rather than on demand
+	Next     int        `json:"next"`             // Index of the next step
+	Total    int        `json:"total"`            // Number of steps
+	NextAt   *time.Time `json:"nextAt,omitempty"` // When a timed run plays the next step
+	Log      []string   `json:"log"`              // What each played step did
+	Error    string     `json:"error,omitempty"`  // Why the run stopped early
+
+	steps []TeammateAction
+	timer *time.Timer
+}
+
+// Done reports whether every step has been played or the run failed.
+func (r *TeammateRun) Done() bool {
+	return r.Next >= r.Total || r.Error != ""
+}
+
+func (r *TeammateRun) snapshot() *TeammateRun {
+	c := *r
+	c.Log = append([]string{}, r.Log...)
+	c.steps, c.timer = nil, nil
+	return &c
+}
+
+// StartTeammateScenario begins playing sc against the named remote, replacing
+// any run already going on there. Timed runs play each step after its delay;
+// other runs wait for AdvanceTeammateScenario.
+func (sm *SessionManager) StartTeammateScenario(remote string, sc *TeammateScenario, timed bool) (*TeammateRun, error) {
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := sm.GetSharedRemote(remote); !ok {
+		return nil, fmt.Errorf("remote %s not found", remote)
+	}
+	steps := make([]TeammateAction, len(sc.Steps))
+	for i, a := range sc.Steps {
+		if a.Author == "" {
+			a.Author, a.Email = sc.Author, sc.Email
+		}
+		steps[i] = a
+	}
+
+	sm.teammateMu.Lock()
+	defer sm.teammateMu.Unlock()
+	if old := sm.teammateRuns[remote]; old != nil && old.timer != nil {
+		old.timer.Stop()
+	}
+	run := &TeammateRun{Scenario: sc.Name, Remote: remote, Timed: timed, Total: len(steps), Log: []string{}, steps: steps}
+	if sm.teammateRuns == nil {
+		sm.teammateRuns = make(map[string]*TeammateRun)
+	}
+	sm.teammateRuns[remote] = run
+	if timed {
+		sm.scheduleTeammateStepLocked(run)
+	}
+	return run.snapshot(), nil
+}
+
+// AdvanceTeammateScenario plays the next step of the remote's run now.
+func (sm *SessionManager) AdvanceTeammateScenario(remote string) (*TeammateRun, error) {
+	sm.teammateMu.Lock()
+	defer sm.teammateMu.Unlock()
+	run := sm.teammateRuns[remote]
+	if run == nil {
+		return nil, fmt.Errorf("no teammate scenario is running on %s", remote)
+	}
+	if run.Done() {
+		return nil, fmt.Errorf("teammate scenario %q on %s has finished", run.Scenario, remote)
+	}
+	if run.timer != nil {
+		run.timer.Stop()
+	}
+	sm.playTeammateStepLocked(run)
+	if run.Error != "" {
+		return run.snapshot(), fmt.Errorf("%s", run.Error)
+	}
+	return run.snapshot(), nil
+}
+
+// StopTeammateScenario cancels the remote's run, keeping what it already did.
+func (sm *SessionManager) StopTeammateScenario(remote string) error {
+	sm.teammateMu.Lock()
+	defer sm.teammateMu.Unlock()
+	run := sm.teammateRuns[remote]
+	if run == nil {
+		return fmt.Errorf("no teammate scenario is running on %s", remote)
+	}
+	if run.timer != nil {
+		run.timer.Stop()
+	}
+	delete(sm.teammateRuns, remote)
+	return nil
+}
+
+// TeammateScenarioStatus returns the remote's run, if any.
+func (sm *SessionManager) TeammateScenarioStatus(remote string) (*TeammateRun, bool) {
+	sm.teammateMu.Lock()
+	defer sm.teammateMu.Unlock()
+	run := sm.teammateRuns[remote]
+	if run == nil {
+		return nil, false
+	}
+	return run.snapshot(), true
+}
+
+// playTeammateStepLocked applies the next step and schedules the one after it. Caller holds sm.teammateMu.
+func (sm *SessionManager) playTeammateStepLocked(run *TeammateRun) {
+	run.timer, run.NextAt = nil, nil
+	out, err := sm.ApplyTeammateAction(run.Remote, run.steps[run.Next])
+	if err != nil {
+		run.Error = fmt.Sprintf("step %d: %v", run.Next+1, err)
+		return
+	}
+	run.Log = append(run.Log, out)
+	run.Next++
+	if run.Timed {
+		sm.scheduleTeammateStepLocked(run)
+	}
+}
+
+// scheduleTeammateStepLocked arms the timer of a timed run's next step. Caller holds sm.teammateMu.
+func (sm *SessionManager) scheduleTeammateStepLocked(run *TeammateRun) {
+	if run.Done() {
+		return
+	}
+	delay := run.steps[run.Next].delay()
+	at := time.Now().Add(delay)
+	run.NextAt = &at
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		sm.teammateMu.Lock()
+		defer sm.teammateMu.Unlock()
+		// The step was played on demand or the run was replaced in the meantime
+		if run.timer != timer || sm.teammateRuns[run.Remote] != run {
+			return
+		}
+		sm.playTeammateStepLocked(run)
+	})
+	run.timer = timer
+}
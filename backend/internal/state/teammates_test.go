@@ -0,0 +1,166 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hotfixScenario = `
+name: hotfix
+description: Sakura fixes master and opens a PR
+author: Sakura
+steps:
+  - action: commit
+    message: "Fix typo"
+    files:
+      README.md: "fixed\n"
+  - action: branch
+    branch: feature
+  - action: commit
+    branch: feature
+    message: "Add feature"
+    files:
+      feature.txt: "feature\n"
+    delete: [README.md]
+  - action: pull-request
+    branch: feature
+    title: "Add feature"
+  - action: force-push
+    message: "Rewrite the fix"
+    files:
+      README.md: "rewritten\n"
+`
+
+func newTeammateRemote(t *testing.T) (*SessionManager, *gogit.Repository, plumbing.Hash) {
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, _ := repo.Worktree()
+	f, _ := w.Filesystem.Create("README.md")
+	_, _ = f.Write([]byte("typo\n"))
+	_ = f.Close()
+	_, _ = w.Add("README.md")
+	initial, err := w.Commit("Initial", &gogit.CommitOptions{Author: &object.Signature{Name: "User", Email: "user@example.com"}})
+	require.NoError(t, err)
+
+	sm := NewSessionManager()
+	sm.SharedRemotes["origin"] = repo
+	sm.CheckDelay = 0
+	sm.ScenarioDir = t.TempDir()
+	return sm, repo, initial
+}
+
+func TestTeammateScenario_PlayedOnDemand(t *testing.T) {
+	sm, repo, initial := newTeammateRemote(t)
+	require.NoError(t, os.WriteFile(filepath.Join(sm.ScenarioDir, "hotfix.yaml"), []byte(hotfixScenario), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sm.ScenarioDir, "broken.yaml"), []byte("steps: [{action: dance}]"), 0644))
+	assert.Equal(t, []string{"broken", "hotfix"}, sm.ListTeammateScenarios())
+	_, err := sm.LoadTeammateScenario("broken")
+	assert.ErrorContains(t, err, `unknown action "dance"`)
+	_, err = sm.LoadTeammateScenario("../hotfix")
+	assert.Error(t, err)
+
+	sc, err := sm.LoadTeammateScenario("hotfix")
+	require.NoError(t, err)
+	run, err := sm.StartTeammateScenario("origin", sc, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, run.Total)
+	assert.Nil(t, run.NextAt)
+
+	tipOf := func(branch string) *object.Commit {
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+		require.NoError(t, err)
+		c, err := repo.CommitObject(ref.Hash())
+		require.NoError(t, err)
+		return c
+	}
+
+	// 1. A commit on the default branch, attributed to the scenario's author
+	run, err = sm.AdvanceTeammateScenario("origin")
+	require.NoError(t, err)
+	fix := tipOf("master")
+	assert.Equal(t, "Fix typo", fix.Message)
+	assert.Equal(t, "Sakura", fix.Author.Name)
+	assert.Equal(t, initial, fix.ParentHashes[0])
+	file, err := fix.File("README.md")
+	require.NoError(t, err)
+	content, _ := file.Contents()
+	assert.Equal(t, "fixed\n", content)
+	userRef, err := repo.Reference(UserRefName(UserIdentity{Name: "Sakura"}, "master"), true)
+	require.NoError(t, err, "teammates leave tracking refs like real users")
+	assert.Equal(t, fix.Hash, userRef.Hash())
+	assert.Contains(t, run.Log[0], "Sakura pushed "+fix.Hash.String()[:7]+" to master")
+
+	// 2-4. A feature branch with a commit and a pull request
+	for i := 0; i < 3; i++ {
+		run, err = sm.AdvanceTeammateScenario("origin")
+		require.NoError(t, err)
+	}
+	feature := tipOf("feature")
+	assert.Equal(t, fix.Hash, feature.ParentHashes[0])
+	_, err = feature.File("README.md")
+	assert.Error(t, err, "deleted files leave the tree")
+	prs := sm.GetPullRequests()
+	require.Len(t, prs, 1)
+	assert.Equal(t, "Add feature", prs[0].Title)
+	assert.Equal(t, "feature", prs[0].HeadRef)
+	assert.Equal(t, "master", prs[0].BaseRef)
+	assert.Equal(t, "Sakura", prs[0].Creator)
+
+	// 5. A force-push replaces the fix
+	run, err = sm.AdvanceTeammateScenario("origin")
+	require.NoError(t, err)
+	rewritten := tipOf("master")
+	assert.Equal(t, initial, rewritten.ParentHashes[0])
+	assert.Equal(t, "Rewrite the fix", rewritten.Message)
+	assert.True(t, run.Done())
+
+	_, err = sm.AdvanceTeammateScenario("origin")
+	assert.ErrorContains(t, err, "has finished")
+	status, ok := sm.TeammateScenarioStatus("origin")
+	require.True(t, ok)
+	assert.Len(t, status.Log, 5)
+	require.NoError(t, sm.StopTeammateScenario("origin"))
+	_, ok = sm.TeammateScenarioStatus("origin")
+	assert.False(t, ok)
+}
+
+func TestTeammateScenario_Timed(t *testing.T) {
+	sm, repo, _ := newTeammateRemote(t)
+	sc := &TeammateScenario{Name: "timed", Steps: []TeammateAction{
+		{Action: TeammateCommit, Delay: "10ms", Files: map[string]string{"a.txt": "a"}},
+		{Action: TeammateCommit, Delay: "10ms", Files: map[string]string{"b.txt": "b"}},
+	}}
+	run, err := sm.StartTeammateScenario("origin", sc, true)
+	require.NoError(t, err)
+	assert.NotNil(t, run.NextAt)
+
+	require.Eventually(t, func() bool {
+		run, _ := sm.TeammateScenarioStatus("origin")
+		return run.Done()
+	}, 2*time.Second, 5*time.Millisecond)
+	head, _ := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	tip, _ := repo.CommitObject(head.Hash())
+	assert.Equal(t, DefaultTeammate.Name, tip.Author.Name)
+	_, err = tip.File("b.txt")
+	assert.NoError(t, err)
+
+	// Failing steps stop the run
+	bad := &TeammateScenario{Name: "bad", Steps: []TeammateAction{{Action: TeammateForcePush, Drop: 5}}}
+	_, err = sm.StartTeammateScenario("origin", bad, false)
+	require.NoError(t, err)
+	run, err = sm.AdvanceTeammateScenario("origin")
+	assert.ErrorContains(t, err, "history is too short")
+	assert.True(t, run.Done())
+	_, err = sm.StartTeammateScenario("upstream", sc, false)
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,276 @@
+package state
+
+// timeline.go - Graph snapshots for scrubbing through a session
+//
+// After every dispatched command the session records a frame: the refs the
+// command changed and the commits it created. Starting from the base, which
+// holds the state before the oldest frame, applying frames in order rebuilds
+// the graph after any command, so the frontend can scrub backwards through
+// the session and animate what each command did.
+//
+// Memory is bounded: only the last maxTimelineFrames frames are kept, and a
+// dropped frame is folded into the base, whose commits are then pruned to
+// those its refs reach. A frame lists at most maxFrameCommits new commits
+// (e.g. after cloning a large repository) and says when it left some out.
+
+import (
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// maxTimelineFrames caps how many frames a session timeline keeps.
+const maxTimelineFrames = 200
+
+// maxFrameCommits caps how many new commits one frame lists.
+const maxFrameCommits = 1000
+
+// Timeline is the graph history of a session: the base state of each
+// repository and the frames recorded since, oldest first.
+type Timeline struct {
+	Base      map[string]*TimelineBase `json:"base"`                // Keyed by repository path
+	Frames    []TimelineFrame          `json:"frames"`              // Oldest first
+	Truncated bool                     `json:"truncated,omitempty"` // Older frames were folded into the base
+}
+
+// TimelineBase is the state of a repository before the oldest frame.
+type TimelineBase struct {
+	Refs    map[string]string `json:"refs"`    // As in TraceEntry.Refs
+	Commits []Commit          `json:"commits"` // Commits the refs reach
+}
+
+// TimelineFrame is what one command changed in the graph of the repository it
+// ran in.
+type TimelineFrame struct {
+	Seq            int               `json:"seq"`                      // Seq of the command in the session trace
+	Command        string            `json:"command"`                  // The command as typed, e.g. "git commit -m wip"
+	Repo           string            `json:"repo"`                     // Repository path the command left as current; empty outside any
+	Time           time.Time         `json:"time"`                     // When the command finished
+	ExitCode       int               `json:"exitCode"`                 // 0 on success, see CommandResult
+	Refs           map[string]string `json:"refs,omitempty"`           // Refs the command created or moved, with their new value
+	DeletedRefs    []string          `json:"deletedRefs,omitempty"`    // Refs the command deleted
+	NewCommits     []Commit          `json:"newCommits,omitempty"`     // Commits reachable now that were not before
+	OmittedCommits int               `json:"omittedCommits,omitempty"` // New commits left out beyond maxFrameCommits
+}
+
+// timeline is the session-side record behind Timeline.
+type timeline struct {
+	base      map[string]*TimelineBase
+	frames    []TimelineFrame
+	truncated bool
+	refs      map[string]map[string]string      // Latest refs of each repository
+	seen      map[string]map[plumbing.Hash]bool // Commits already listed, by repository
+}
+
+// RecordTimelineFrame records what the command numbered seq changed in the
+// current repository. Caller holds the session lock.
+func (s *Session) RecordTimelineFrame(seq int, command string, exitCode int) {
+	t := &s.timeline
+	if t.refs == nil {
+		t.base = make(map[string]*TimelineBase)
+		t.refs = make(map[string]map[string]string)
+		t.seen = make(map[string]map[plumbing.Hash]bool)
+	}
+	frame := TimelineFrame{Seq: seq, Command: command, Time: time.Now(), ExitCode: exitCode}
+	repo := s.GetRepo()
+	if repo == nil {
+		t.append(frame)
+		return
+	}
+	frame.Repo = s.activeRepoPath()
+
+	refs := RefState(repo)
+	old, known := t.refs[frame.Repo]
+	if !known {
+		// The first frame of a repository starts from nothing, so a clone or
+		// init shows up as the command that created the history
+		t.base[frame.Repo] = &TimelineBase{Refs: map[string]string{}, Commits: []Commit{}}
+		t.seen[frame.Repo] = make(map[plumbing.Hash]bool)
+	}
+	for name, value := range refs {
+		if old[name] != value {
+			if frame.Refs == nil {
+				frame.Refs = make(map[string]string)
+			}
+			frame.Refs[name] = value
+		}
+	}
+	for name := range old {
+		if _, ok := refs[name]; !ok {
+			frame.DeletedRefs = append(frame.DeletedRefs, name)
+		}
+	}
+	t.refs[frame.Repo] = refs
+
+	frame.NewCommits, frame.OmittedCommits = unseenCommits(repo, refs, t.seen[frame.Repo])
+	t.append(frame)
+}
+
+// unseenCommits lists the commits the refs reach that are not in seen, at
+// most maxFrameCommits of them, and marks them seen. The walk stops at seen
+// commits. It also returns how many it left out.
+func unseenCommits(repo *gogit.Repository, refs map[string]string, seen map[plumbing.Hash]bool) ([]Commit, int) {
+	var stack []plumbing.Hash
+	for _, value := range refs {
+		if h := plumbing.NewHash(value); !h.IsZero() && len(value) == 40 {
+			stack = append(stack, h)
+		}
+	}
+	var commits []Commit
+	omitted := 0
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		commit, err := repo.CommitObject(h)
+		if err != nil {
+			if tag, err := repo.TagObject(h); err == nil {
+				stack = append(stack, tag.Target)
+			}
+			continue // Not a commit, or cut off by a shallow clone
+		}
+		if len(commits) < maxFrameCommits {
+			commits = append(commits, timelineCommit(commit.Hash, commit.ParentHashes, commit.Message, commit.Author.Name, commit.Committer.When))
+		} else {
+			omitted++
+		}
+		stack = append(stack, commit.ParentHashes...)
+	}
+	return commits, omitted
+}
+
+func timelineCommit(hash plumbing.Hash, parents []plumbing.Hash, message, author string, when time.Time) Commit {
+	c := Commit{ID: hash.String(), Message: message, Author: author, Timestamp: when.Format(time.RFC3339)}
+	if len(parents) > 0 {
+		c.ParentID = parents[0].String()
+	}
+	if len(parents) > 1 {
+		c.SecondParentID = parents[1].String()
+	}
+	return c
+}
+
+// append adds frame, folding the oldest frame into the base when full.
+func (t *timeline) append(frame TimelineFrame) {
+	if len(t.frames) >= maxTimelineFrames {
+		t.fold(t.frames[0], t.frames[1:])
+		t.frames = append(t.frames[:0:0], t.frames[1:]...)
+		t.truncated = true
+	}
+	t.frames = append(t.frames, frame)
+}
+
+// fold applies frame to the base of its repository, then drops the base
+// commits that neither the base refs nor the refs of the kept frames reach.
+// Dropped commits are forgotten, so they are listed again if they come back.
+func (t *timeline) fold(frame TimelineFrame, kept []TimelineFrame) {
+	base, ok := t.base[frame.Repo]
+	if !ok {
+		return
+	}
+	for name, value := range frame.Refs {
+		base.Refs[name] = value
+	}
+	for _, name := range frame.DeletedRefs {
+		delete(base.Refs, name)
+	}
+	byID := make(map[string]Commit, len(base.Commits)+len(frame.NewCommits))
+	for _, c := range base.Commits {
+		byID[c.ID] = c
+	}
+	for _, c := range frame.NewCommits {
+		byID[c.ID] = c
+	}
+
+	// Commits of the kept frames are walked through but stay in their frame
+	later := make(map[string]Commit)
+	var stack []string
+	for _, value := range base.Refs {
+		stack = append(stack, value)
+	}
+	for _, f := range kept {
+		if f.Repo != frame.Repo {
+			continue
+		}
+		for _, c := range f.NewCommits {
+			later[c.ID] = c
+		}
+		for _, value := range f.Refs {
+			stack = append(stack, value)
+		}
+	}
+	reached := make(map[string]bool)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reached[id] {
+			continue
+		}
+		c, ok := byID[id]
+		if !ok {
+			if c, ok = later[id]; !ok {
+				continue
+			}
+		}
+		reached[id] = true
+		stack = append(stack, c.ParentID)
+		if c.SecondParentID != "" {
+			stack = append(stack, c.SecondParentID)
+		}
+	}
+
+	commits := append(append([]Commit{}, base.Commits...), frame.NewCommits...)
+	base.Commits = commits[:0]
+	for _, c := range commits {
+		if reached[c.ID] {
+			base.Commits = append(base.Commits, c)
+		} else {
+			delete(t.seen[frame.Repo], plumbing.NewHash(c.ID))
+		}
+	}
+}
+
+// Timeline returns a copy of the session timeline. Caller holds at least the
+// session's read lock.
+func (s *Session) Timeline() *Timeline {
+	t := &s.timeline
+	out := &Timeline{
+		Base:      make(map[string]*TimelineBase, len(t.base)),
+		Frames:    append([]TimelineFrame{}, t.frames...),
+		Truncated: t.truncated,
+	}
+	for repo, base := range t.base {
+		b := &TimelineBase{Refs: make(map[string]string, len(base.Refs)), Commits: append([]Commit{}, base.Commits...)}
+		for name, value := range base.Refs {
+			b.Refs[name] = value
+		}
+		out.Base[repo] = b
+	}
+	return out
+}
+
+// ClearTimeline forgets the recorded frames, e.g. once a mission has set the
+// session up: the current state of each repository becomes the base.
+// Caller holds the session lock.
+func (s *Session) ClearTimeline() {
+	t := timeline{
+		base: make(map[string]*TimelineBase, len(s.Repos)),
+		refs: make(map[string]map[string]string, len(s.Repos)),
+		seen: make(map[string]map[plumbing.Hash]bool, len(s.Repos)),
+	}
+	for path, repo := range s.Repos {
+		refs := RefState(repo)
+		t.refs[path] = refs
+		t.seen[path] = make(map[plumbing.Hash]bool)
+		commits, _ := unseenCommits(repo, refs, t.seen[path])
+		if commits == nil {
+			commits = []Commit{}
+		}
+		t.base[path] = &TimelineBase{Refs: refs, Commits: commits}
+	}
+	s.timeline = t
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commitIDs(commits []Commit) []string {
+	ids := make([]string, 0, len(commits))
+	for _, c := range commits {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestTimeline_RecordsRefsAndNewCommits(t *testing.T) {
+	sm := NewSessionManager()
+	sess, repo, hashes := linearHistorySession(t, sm, "timeline", 2)
+
+	// Without a base the first frame brings the whole history
+	sess.RecordTimelineFrame(1, "git clone", 0)
+	tl := sess.Timeline()
+	require.Len(t, tl.Frames, 1)
+	assert.Equal(t, "repo", tl.Frames[0].Repo)
+	assert.Equal(t, hashes[1], tl.Frames[0].Refs["refs/heads/master"])
+	assert.ElementsMatch(t, hashes, commitIDs(tl.Frames[0].NewCommits))
+	assert.Empty(t, tl.Base["repo"].Commits)
+
+	sess.ClearTimeline()
+	tl = sess.Timeline()
+	assert.Empty(t, tl.Frames)
+	assert.ElementsMatch(t, hashes, commitIDs(tl.Base["repo"].Commits))
+
+	w, _ := repo.Worktree()
+	third, err := w.Commit("third", &gogit.CommitOptions{Author: &object.Signature{Name: "User", Email: "user@example.com", When: time.Now()}, AllowEmptyCommits: true})
+	require.NoError(t, err)
+	sess.RecordTimelineFrame(2, "git commit -m third", 0)
+	rewindMaster(t, sess, hashes[0])
+	sess.RecordTimelineFrame(3, "git reset --hard HEAD~2", 0)
+	rewindMaster(t, sess, third.String())
+	sess.RecordTimelineFrame(4, "git reset --hard ORIG_HEAD", 0)
+
+	tl = sess.Timeline()
+	require.Len(t, tl.Frames, 3)
+	assert.Equal(t, []string{third.String()}, commitIDs(tl.Frames[0].NewCommits))
+	assert.Equal(t, map[string]string{"refs/heads/master": hashes[0]}, tl.Frames[1].Refs)
+	assert.Empty(t, tl.Frames[1].NewCommits)
+	assert.Empty(t, tl.Frames[2].NewCommits, "commits listed before are not listed again")
+	assert.False(t, tl.Truncated)
+}
+
+func TestTimeline_FoldsOldFramesIntoBase(t *testing.T) {
+	sm := NewSessionManager()
+	sess, _, hashes := linearHistorySession(t, sm, "timeline-fold", 3)
+	sess.ClearTimeline()
+
+	rewindMaster(t, sess, hashes[0])
+	sess.RecordTimelineFrame(1, "git reset --hard HEAD~2", 0)
+	for i := 0; i < maxTimelineFrames; i++ {
+		sess.RecordTimelineFrame(i+2, "git status", 0)
+	}
+
+	tl := sess.Timeline()
+	assert.True(t, tl.Truncated)
+	assert.Len(t, tl.Frames, maxTimelineFrames)
+	assert.Equal(t, 2, tl.Frames[0].Seq)
+	base := tl.Base["repo"]
+	assert.Equal(t, hashes[0], base.Refs["refs/heads/master"])
+	assert.Equal(t, []string{hashes[0]}, commitIDs(base.Commits), "unreachable commits are pruned")
+
+	// A pruned commit that comes back is listed again
+	rewindMaster(t, sess, hashes[2])
+	sess.RecordTimelineFrame(maxTimelineFrames+2, "git reset --hard ORIG_HEAD", 0)
+	tl = sess.Timeline()
+	last := tl.Frames[len(tl.Frames)-1]
+	assert.ElementsMatch(t, hashes[1:], commitIDs(last.NewCommits))
+}
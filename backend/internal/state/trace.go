@@ -0,0 +1,108 @@
+package state
+
+// trace.go - Command traces for bug reports
+//
+// Every dispatched command is appended to the session's trace with the refs
+// it left behind. A trace is exported as JSON (GET /api/session/trace) and can
+// be replayed into a fresh session, so a learner can attach a reproducible
+// history to a bug report and a maintainer can turn it into a regression test.
+// The trace starts when the session is created, or over when a mission starts.
+
+import (
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TraceVersion is the format version of exported traces.
+const TraceVersion = 1
+
+// maxTraceEntries caps how many commands a session trace keeps; older ones are dropped.
+const maxTraceEntries = 500
+
+// Trace is the command history of a session, oldest first.
+type Trace struct {
+	Version   int          `json:"version"`
+	SessionID string       `json:"sessionId,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"` // Older entries were dropped, so a replay starts from another state
+	Entries   []TraceEntry `json:"entries"`
+}
+
+// TraceEntry is one dispatched command, or one file saved from the client's
+// file editor, and the state it left.
+type TraceEntry struct {
+	Seq      int               `json:"seq"`
+	Line     string            `json:"line,omitempty"`    // Shell line the command was part of; empty for commands run by the API directly
+	LineSeq  int               `json:"lineSeq,omitempty"` // Seq of the first command of the line, grouping the commands of one line
+	Command  string            `json:"command,omitempty"` // Resolved command name, e.g. "commit"; empty for file writes
+	Args     []string          `json:"args,omitempty"`    // Arguments as dispatched, e.g. ["commit", "-m", "wip"]
+	File     string            `json:"file,omitempty"`    // File written by the client's file editor, as an absolute path
+	Content  string            `json:"content,omitempty"` // New content of File
+	Message  string            `json:"message,omitempty"` // Message written in the client's editor, for commands resumed from it
+	Editor   bool              `json:"editor,omitempty"`  // The command stopped to open the client's editor
+	Dir      string            `json:"dir"`               // Working directory the command ran in
+	ExitCode int               `json:"exitCode"`          // 0 on success, see CommandResult
+	Refs     map[string]string `json:"refs,omitempty"`    // Refs of the current repository afterwards; HEAD is "ref: <name>" when on a branch
+	Time     time.Time         `json:"time"`
+}
+
+// RecordTrace appends entry to the session trace and returns its sequence
+// number. Caller holds the session lock.
+func (s *Session) RecordTrace(entry TraceEntry) int {
+	s.traceSeq++
+	entry.Seq = s.traceSeq
+	if entry.Line != "" && entry.LineSeq == 0 {
+		// The first command of a line numbers the line
+		entry.LineSeq = entry.Seq
+	}
+	if len(s.trace) >= maxTraceEntries {
+		s.trace = append(s.trace[:0:0], s.trace[1:]...)
+		s.traceDropped = true
+	}
+	s.trace = append(s.trace, entry)
+	return entry.Seq
+}
+
+// Trace returns a copy of the session trace. Caller holds at least the
+// session's read lock.
+func (s *Session) Trace() *Trace {
+	return &Trace{
+		Version:   TraceVersion,
+		SessionID: s.ID,
+		Truncated: s.traceDropped,
+		Entries:   append([]TraceEntry{}, s.trace...),
+	}
+}
+
+// ClearTrace forgets the recorded commands, e.g. when a mission resets the
+// session. Caller holds the session lock.
+func (s *Session) ClearTrace() {
+	s.trace = nil
+	s.traceDropped = false
+}
+
+// RefState returns the refs of repo by name, with HEAD as "ref: <branch>"
+// when it is symbolic. A nil repo has no refs.
+func RefState(repo *gogit.Repository) map[string]string {
+	if repo == nil {
+		return nil
+	}
+	refs := make(map[string]string)
+	if iter, err := repo.Storer.IterReferences(); err == nil {
+		_ = iter.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() == plumbing.HashReference {
+				refs[ref.Name().String()] = ref.Hash().String()
+			}
+			return nil
+		})
+	}
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil {
+		if head.Type() == plumbing.SymbolicReference {
+			refs["HEAD"] = "ref: " + head.Target().String()
+		} else {
+			refs["HEAD"] = head.Hash().String()
+		}
+	}
+	return refs
+}
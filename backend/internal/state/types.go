@@ -2,25 +2,32 @@ package state
 
 // GraphState represents the serialized state for the frontend
 type GraphState struct {
-	Commits          []Commit                   `json:"commits"`
-	Branches         map[string]string          `json:"branches"`
-	RemoteBranches   map[string]string          `json:"remoteBranches"`
-	Tags             map[string]string          `json:"tags"`
-	References       map[string]string          `json:"references"`
-	HEAD             Head                       `json:"HEAD"`
-	PotentialCommits []Commit                   `json:"potentialCommits"`
-	Files            []string                   `json:"files"`
-	Staging          []string                   `json:"staging"`
-	Modified         []string                   `json:"modified"`
-	Untracked        []string                   `json:"untracked"`
-	FileStatuses     map[string]string          `json:"fileStatuses"`
-	CurrentPath      string                     `json:"currentPath"`
-	Projects         []string                   `json:"projects"`
-	ProjectMetadata  map[string]ProjectMetadata `json:"projectMetadata"`
-	Remotes          []Remote                   `json:"remotes"`
-	SharedRemotes    []string                   `json:"sharedRemotes"`
-	Initialized      bool                       `json:"initialized"`
-	ActiveProject    string                     `json:"activeProject"`
+	Commits            []Commit                   `json:"commits"`
+	Branches           map[string]string          `json:"branches"`
+	RemoteBranches     map[string]string          `json:"remoteBranches"`
+	Tracking           map[string]BranchTracking  `json:"tracking"` // Local branch -> upstream divergence
+	BranchGroups       []BranchGroup              `json:"branchGroups"`
+	RemoteBranchGroups []BranchGroup              `json:"remoteBranchGroups"`
+	Tags               map[string]string          `json:"tags"`
+	References         map[string]string          `json:"references"`
+	HEAD               Head                       `json:"HEAD"`
+	PotentialCommits   []Commit                   `json:"potentialCommits"`
+	Files              []string                   `json:"files"`
+	Staging            []string                   `json:"staging"`
+	Modified           []string                   `json:"modified"`
+	Untracked          []string                   `json:"untracked"`
+	Ignored            []string                   `json:"ignored"`
+	FileStatuses       map[string]string          `json:"fileStatuses"`
+	CurrentPath        string                     `json:"currentPath"`
+	Projects           []string                   `json:"projects"`
+	ProjectMetadata    map[string]ProjectMetadata `json:"projectMetadata"`
+	Remotes            []Remote                   `json:"remotes"`
+	SharedRemotes      []string                   `json:"sharedRemotes"`
+	RemotePermission   *RemotePermission          `json:"remotePermission,omitempty"` // Remote view only: who may push to it
+	Initialized        bool                       `json:"initialized"`
+	ActiveProject      string                     `json:"activeProject"`
+	RefPagination      *RefPagination             `json:"refPagination,omitempty"`
+	CommitPagination   *CommitPagination          `json:"commitPagination,omitempty"`
 }
 
 type ProjectMetadata struct {
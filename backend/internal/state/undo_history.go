@@ -0,0 +1,474 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// Undo history
+//
+// Before every command the engine takes an UndoSnapshot of the session; when
+// the command changed something, the snapshot goes on the undo stack. Undo
+// restores the latest one and keeps the state it replaced for redo, so a
+// learner who wrecked the sandbox can step back without resetting the session.
+//
+// Snapshots are lightweight: garbage collection treats the objects they refer
+// to as reachable (see gcRoots), so a snapshot only keeps refs, index and config, and the
+// content of files whose blob is not in their repository (untracked or
+// modified files, files outside repositories). The reflog is not rewound:
+// like a real `git reset`, undo and redo show up in it as new entries.
+
+// maxUndoSnapshots caps how many snapshots each of the undo and redo stacks keeps.
+const maxUndoSnapshots = 30
+
+// ErrNothingToUndo and ErrNothingToRedo are returned when the stack is empty.
+var (
+	ErrNothingToUndo = fmt.Errorf("nothing to undo")
+	ErrNothingToRedo = fmt.Errorf("nothing to redo")
+)
+
+// UndoSnapshot is the state of a session before a command changed it.
+type UndoSnapshot struct {
+	Command string    // The command that followed the snapshot, e.g. "git commit -m wip"
+	TakenAt time.Time // When the snapshot was taken
+
+	currentDir string
+	repos      map[string]*repoSnapshot
+	files      map[string]snapshotFile // Absolute path -> file
+	dirs       map[string]bool         // Absolute paths of every directory but "/"
+	lineage    map[string]LineageLink
+	merge      *MergeState
+	rebase     *RebaseState
+	cherryPick *CherryPickState
+	am         *AmState
+}
+
+// repoSnapshot is what a snapshot keeps of one repository.
+type repoSnapshot struct {
+	repo   *gogit.Repository
+	refs   map[string]*plumbing.Reference // HEAD included
+	index  []byte                         // Encoded index
+	config []byte                         // Marshaled config
+}
+
+// snapshotFile is one file of a snapshot: its blob in repo when the
+// repository has it, or its content otherwise.
+type snapshotFile struct {
+	hash plumbing.Hash
+	mode os.FileMode
+	repo string // Repository holding the blob; empty when data is set
+	data []byte
+}
+
+// TakeUndoSnapshot records the current state of the session. Caller holds at
+// least the session's read lock.
+func (s *Session) TakeUndoSnapshot(command string) (*UndoSnapshot, error) {
+	snap := &UndoSnapshot{
+		Command:    command,
+		TakenAt:    time.Now(),
+		currentDir: s.CurrentDir,
+		repos:      make(map[string]*repoSnapshot, len(s.Repos)),
+		files:      make(map[string]snapshotFile),
+		dirs:       make(map[string]bool),
+		lineage:    make(map[string]LineageLink, len(s.Lineage)),
+	}
+	for k, v := range s.Lineage {
+		snap.lineage[k] = v
+	}
+	if s.Merge != nil {
+		m := *s.Merge
+		snap.merge = &m
+	}
+	if s.Rebase != nil {
+		rb := *s.Rebase
+		snap.rebase = &rb
+	}
+	if s.CherryPick != nil {
+		cp := *s.CherryPick
+		snap.cherryPick = &cp
+	}
+	if s.Am != nil {
+		am := *s.Am
+		snap.am = &am
+	}
+
+	for p, repo := range s.Repos {
+		rs, err := snapshotRepo(repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot repository '%s': %w", p, err)
+		}
+		snap.repos[p] = rs
+	}
+	if err := snap.walk(s, "/"); err != nil {
+		return nil, fmt.Errorf("failed to snapshot files: %w", err)
+	}
+	return snap, nil
+}
+
+func snapshotRepo(repo *gogit.Repository) (*repoSnapshot, error) {
+	rs := &repoSnapshot{repo: repo, refs: make(map[string]*plumbing.Reference)}
+	refs, err := repo.Storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		rs.refs[ref.Name().String()] = ref
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	// HEAD is not always part of IterReferences
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil {
+		rs.refs[plumbing.HEAD.String()] = head
+	}
+
+	if idx, err := repo.Storer.Index(); err == nil {
+		var buf bytes.Buffer
+		if err := index.NewEncoder(&buf).Encode(idx); err != nil {
+			return nil, err
+		}
+		rs.index = buf.Bytes()
+	}
+	if cfg, err := repo.Storer.Config(); err == nil {
+		if rs.config, err = cfg.Marshal(); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// walk records the files and directories under dir.
+func (snap *UndoSnapshot) walk(s *Session, dir string) error {
+	entries, err := s.Filesystem.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+		if e.IsDir() {
+			snap.dirs[p] = true
+			if err := snap.walk(s, p); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := readSessionFile(s, p)
+		if err != nil {
+			return err
+		}
+		file := snapshotFile{hash: plumbing.ComputeHash(plumbing.BlobObject, data), mode: e.Mode()}
+		if repoPath := snap.repoOf(p); repoPath != "" && snap.repos[repoPath].repo.Storer.HasEncodedObject(file.hash) == nil {
+			file.repo = repoPath
+		} else {
+			file.data = data
+		}
+		snap.files[p] = file
+	}
+	return nil
+}
+
+// repoOf returns the path of the innermost repository whose worktree holds p.
+func (snap *UndoSnapshot) repoOf(p string) string {
+	best := ""
+	for repoPath := range snap.repos {
+		if strings.HasPrefix(p, "/"+repoPath+"/") && len(repoPath) > len(best) {
+			best = repoPath
+		}
+	}
+	return best
+}
+
+func readSessionFile(s *Session, p string) ([]byte, error) {
+	f, err := s.Filesystem.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// sameState reports whether two snapshots describe the same repositories and
+// files. The current directory does not count: cd alone is not worth an undo.
+func (snap *UndoSnapshot) sameState(other *UndoSnapshot) bool {
+	if len(snap.repos) != len(other.repos) || len(snap.files) != len(other.files) || len(snap.dirs) != len(other.dirs) {
+		return false
+	}
+	for p, rs := range snap.repos {
+		ors, ok := other.repos[p]
+		if !ok || rs.repo != ors.repo || len(rs.refs) != len(ors.refs) ||
+			!bytes.Equal(rs.index, ors.index) || !bytes.Equal(rs.config, ors.config) {
+			return false
+		}
+		for name, ref := range rs.refs {
+			if oref, ok := ors.refs[name]; !ok || oref.String() != ref.String() {
+				return false
+			}
+		}
+	}
+	for p, f := range snap.files {
+		if of, ok := other.files[p]; !ok || of.hash != f.hash {
+			return false
+		}
+	}
+	for p := range snap.dirs {
+		if !other.dirs[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordUndoSnapshot pushes before on the undo stack if the session changed
+// since it was taken, and then forgets what could be redone. It reports
+// whether the snapshot was recorded. Caller holds the session lock.
+func (s *Session) RecordUndoSnapshot(before *UndoSnapshot) bool {
+	after, err := s.TakeUndoSnapshot(before.Command)
+	if err != nil || before.sameState(after) {
+		return false
+	}
+	s.undoStack = pushSnapshot(s.undoStack, before)
+	s.redoStack = nil
+	return true
+}
+
+// Undo restores the latest snapshot of the undo stack and returns it. The
+// state it replaces can be brought back with Redo. Caller holds the session lock.
+func (s *Session) Undo() (*UndoSnapshot, error) {
+	if len(s.undoStack) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	snap := s.undoStack[len(s.undoStack)-1]
+	current, err := s.TakeUndoSnapshot(snap.Command)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restoreSnapshot(snap); err != nil {
+		return nil, err
+	}
+	s.undoStack = s.undoStack[:len(s.undoStack)-1]
+	s.redoStack = pushSnapshot(s.redoStack, current)
+	return snap, nil
+}
+
+// Redo brings back the state the last Undo replaced and returns the snapshot
+// of it. Caller holds the session lock.
+func (s *Session) Redo() (*UndoSnapshot, error) {
+	if len(s.redoStack) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	snap := s.redoStack[len(s.redoStack)-1]
+	current, err := s.TakeUndoSnapshot(snap.Command)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restoreSnapshot(snap); err != nil {
+		return nil, err
+	}
+	s.redoStack = s.redoStack[:len(s.redoStack)-1]
+	s.undoStack = pushSnapshot(s.undoStack, current)
+	return snap, nil
+}
+
+// UndoHistory returns the commands that can be undone and redone, most recent first.
+func (s *Session) UndoHistory() (undo, redo []string) {
+	for i := len(s.undoStack) - 1; i >= 0; i-- {
+		undo = append(undo, s.undoStack[i].Command)
+	}
+	for i := len(s.redoStack) - 1; i >= 0; i-- {
+		redo = append(redo, s.redoStack[i].Command)
+	}
+	return undo, redo
+}
+
+// ClearUndoHistory forgets every snapshot, e.g. once a mission set the session up.
+func (s *Session) ClearUndoHistory() {
+	s.undoStack = nil
+	s.redoStack = nil
+}
+
+func pushSnapshot(stack []*UndoSnapshot, snap *UndoSnapshot) []*UndoSnapshot {
+	stack = append(stack, snap)
+	if len(stack) > maxUndoSnapshots {
+		stack = append([]*UndoSnapshot(nil), stack[len(stack)-maxUndoSnapshots:]...)
+	}
+	return stack
+}
+
+// restoreSnapshot puts the session back in the state of snap.
+func (s *Session) restoreSnapshot(snap *UndoSnapshot) error {
+	for p, rs := range snap.repos {
+		if err := rs.restore(); err != nil {
+			return fmt.Errorf("failed to restore repository '%s': %w", p, err)
+		}
+	}
+	if err := s.restoreFiles(snap); err != nil {
+		return fmt.Errorf("failed to restore files: %w", err)
+	}
+
+	s.Repos = make(map[string]*gogit.Repository, len(snap.repos))
+	for p, rs := range snap.repos {
+		s.Repos[p] = rs.repo
+	}
+	s.Lineage = make(map[string]LineageLink, len(snap.lineage))
+	for k, v := range snap.lineage {
+		s.Lineage[k] = v
+	}
+	s.Merge, s.Rebase, s.CherryPick, s.Am = nil, nil, nil, nil
+	if snap.merge != nil {
+		m := *snap.merge
+		s.Merge = &m
+	}
+	if snap.rebase != nil {
+		rb := *snap.rebase
+		s.Rebase = &rb
+	}
+	if snap.cherryPick != nil {
+		cp := *snap.cherryPick
+		s.CherryPick = &cp
+	}
+	if snap.am != nil {
+		am := *snap.am
+		s.Am = &am
+	}
+	s.CurrentDir = snap.currentDir
+	if !snap.dirs[s.CurrentDir] {
+		s.CurrentDir = "/"
+	}
+	if s.FileCache != nil {
+		s.FileCache.Invalidate()
+	}
+	return nil
+}
+
+func (rs *repoSnapshot) restore() error {
+	st := rs.repo.Storer
+	refs, err := st.IterReferences()
+	if err != nil {
+		return err
+	}
+	var stale []plumbing.ReferenceName
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if _, ok := rs.refs[ref.Name().String()]; !ok {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	for _, name := range stale {
+		if err := st.RemoveReference(name); err != nil {
+			return err
+		}
+	}
+	for _, ref := range rs.refs {
+		if err := st.SetReference(ref); err != nil {
+			return err
+		}
+	}
+
+	if rs.index != nil {
+		idx := &index.Index{}
+		if err := index.NewDecoder(bytes.NewReader(rs.index)).Decode(idx); err != nil {
+			return err
+		}
+		if err := st.SetIndex(idx); err != nil {
+			return err
+		}
+	}
+	if rs.config != nil {
+		cfg := config.NewConfig()
+		if err := cfg.Unmarshal(rs.config); err != nil {
+			return err
+		}
+		if err := st.SetConfig(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFiles makes the session filesystem match snap, rewriting only the
+// files that differ.
+func (s *Session) restoreFiles(snap *UndoSnapshot) error {
+	current := &UndoSnapshot{repos: snap.repos, files: make(map[string]snapshotFile), dirs: make(map[string]bool)}
+	if err := current.walk(s, "/"); err != nil {
+		return err
+	}
+
+	for p := range current.files {
+		if _, ok := snap.files[p]; !ok {
+			if err := s.Filesystem.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+	// Deepest directories first, so each one is empty when removed
+	var stale []string
+	for p := range current.dirs {
+		if !snap.dirs[p] {
+			stale = append(stale, p)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(stale)))
+	for _, p := range stale {
+		if err := s.Filesystem.Remove(p); err != nil {
+			return err
+		}
+	}
+
+	for p := range snap.dirs {
+		if err := s.Filesystem.MkdirAll(p, 0755); err != nil {
+			return err
+		}
+	}
+	for p, f := range snap.files {
+		if cur, ok := current.files[p]; ok && cur.hash == f.hash {
+			continue
+		}
+		data := f.data
+		if f.repo != "" {
+			var err error
+			if data, err = readBlob(snap.repos[f.repo].repo, f.hash); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		mode := f.mode.Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		out, err := s.Filesystem.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			_ = out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readBlob(repo *gogit.Repository, hash plumbing.Hash) ([]byte, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,185 @@
+package state
+
+// worktrees.go - Linked worktrees ("git worktree add")
+//
+// A linked worktree is a second checkout of a repository: it has its own
+// directory, HEAD and index, while objects, branches and tags stay shared with
+// the main worktree. Session.Repos holds it like any other repository, backed
+// by a WorktreeStorer over the main repository's storage, and
+// Session.Worktrees remembers which repository it belongs to.
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// WorktreeInfo describes one checkout of a repository for "git worktree list".
+type WorktreeInfo struct {
+	Path   string // Session path without the leading slash, e.g. "project"
+	Head   *plumbing.Reference
+	Linked bool // False for the main worktree
+}
+
+// MainWorktree returns the path of the repository a linked worktree belongs
+// to, or path itself for a main worktree.
+func (s *Session) MainWorktree(path string) string {
+	if main, ok := s.Worktrees[path]; ok {
+		return main
+	}
+	return path
+}
+
+// ListWorktrees returns the main worktree of the repository at path followed
+// by its linked worktrees, sorted by path.
+func (s *Session) ListWorktrees(path string) []WorktreeInfo {
+	main := s.MainWorktree(path)
+	paths := []string{main}
+	var linked []string
+	for wt, m := range s.Worktrees {
+		if m == main {
+			linked = append(linked, wt)
+		}
+	}
+	sort.Strings(linked)
+	paths = append(paths, linked...)
+
+	var list []WorktreeInfo
+	for i, p := range paths {
+		repo, ok := s.Repos[p]
+		if !ok {
+			continue
+		}
+		head, _ := repo.Storer.Reference(plumbing.HEAD)
+		list = append(list, WorktreeInfo{Path: p, Head: head, Linked: i > 0})
+	}
+	return list
+}
+
+// BranchWorktree returns the other worktree of the active repository that has
+// branch checked out, if any.
+func (s *Session) BranchWorktree(branch plumbing.ReferenceName) (string, bool) {
+	path := s.activeRepoPath()
+	for _, wt := range s.ListWorktrees(path) {
+		if wt.Path == path || wt.Head == nil {
+			continue
+		}
+		if wt.Head.Type() == plumbing.SymbolicReference && wt.Head.Target() == branch {
+			return wt.Path, true
+		}
+	}
+	return "", false
+}
+
+// AddWorktree creates a linked worktree of the repository at main in the
+// directory wtPath, with HEAD set to head. The files are not checked out yet.
+// The caller must hold the session lock.
+func (s *Session) AddWorktree(main, wtPath string, head *plumbing.Reference) (*gogit.Repository, error) {
+	mainRepo, ok := s.Repos[main]
+	if !ok {
+		return nil, fmt.Errorf("fatal: not a git repository: '%s'", main)
+	}
+	if err := s.Filesystem.MkdirAll(wtPath, 0755); err != nil {
+		return nil, err
+	}
+	fs, err := s.Filesystem.Chroot(wtPath)
+	if err != nil {
+		return nil, err
+	}
+
+	st := NewWorktreeStorer(mainRepo.Storer)
+	if err := st.SetReference(head); err != nil {
+		return nil, err
+	}
+	repo, err := gogit.Open(st, fs)
+	if err != nil {
+		return nil, err
+	}
+	// Like git, the worktree's .git is a file pointing back at the repository
+	gitdir := fmt.Sprintf("gitdir: /%s/.git/worktrees/%s\n", main, path.Base(wtPath))
+	if err := util.WriteFile(fs, ".git", []byte(gitdir), 0644); err != nil {
+		return nil, err
+	}
+
+	if s.Worktrees == nil {
+		s.Worktrees = make(map[string]string)
+	}
+	s.Worktrees[wtPath] = main
+	s.Repos[wtPath] = repo
+	return repo, nil
+}
+
+// RemoveWorktree deletes a linked worktree and its directory. Branches and
+// commits made in it stay in the repository. The caller must hold the session lock.
+func (s *Session) RemoveWorktree(wtPath string) error {
+	if _, ok := s.Worktrees[wtPath]; !ok {
+		return fmt.Errorf("fatal: '%s' is not a working tree", wtPath)
+	}
+	delete(s.Worktrees, wtPath)
+	delete(s.Repos, wtPath)
+	return s.RemoveAll(wtPath)
+}
+
+// relinkWorktrees rebuilds the linked worktrees of a copied session on top
+// of its copied main repositories, keeping each worktree's HEAD and index.
+// heads and indexes hold the state to restore, keyed by worktree path.
+func (s *Session) relinkWorktrees(heads map[string]*plumbing.Reference, indexes map[string][]byte) error {
+	for wtPath, main := range s.Worktrees {
+		mainRepo, ok := s.Repos[main]
+		head, hasHead := heads[wtPath]
+		if !ok || !hasHead {
+			delete(s.Worktrees, wtPath)
+			continue
+		}
+		fs, err := s.Filesystem.Chroot(wtPath)
+		if err != nil {
+			return err
+		}
+		st := NewWorktreeStorer(mainRepo.Storer)
+		if err := st.SetReference(head); err != nil {
+			return err
+		}
+		if data := indexes[wtPath]; len(data) > 0 {
+			idx := &index.Index{}
+			if err := index.NewDecoder(bytes.NewReader(data)).Decode(idx); err != nil {
+				return fmt.Errorf("failed to restore the index of worktree '%s': %w", wtPath, err)
+			}
+			_ = st.SetIndex(idx)
+		}
+		repo, err := gogit.Open(st, fs)
+		if err != nil {
+			return fmt.Errorf("failed to open worktree '%s': %w", wtPath, err)
+		}
+		s.Repos[wtPath] = repo
+	}
+	return nil
+}
+
+// worktreeState returns the HEAD and encoded index of every linked worktree,
+// for copying a session.
+func (s *Session) worktreeState() (map[string]*plumbing.Reference, map[string][]byte) {
+	heads := make(map[string]*plumbing.Reference)
+	indexes := make(map[string][]byte)
+	for wtPath := range s.Worktrees {
+		repo, ok := s.Repos[wtPath]
+		if !ok {
+			continue
+		}
+		if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil {
+			heads[wtPath] = head
+		}
+		if idx, err := repo.Storer.Index(); err == nil {
+			var buf bytes.Buffer
+			if err := index.NewEncoder(&buf).Encode(idx); err == nil {
+				indexes[wtPath] = buf.Bytes()
+			}
+		}
+	}
+	return heads, indexes
+}
@@ -1,5 +1,5 @@
 import React, { useEffect, useState, useCallback } from 'react';
-import { useMission, type VerificationResult } from '../../context/MissionContext';
+import { useMission, type HintResult, type VerificationResult } from '../../context/MissionContext';
 import { useDojo } from '../../context/DojoContext';
 import { motion, AnimatePresence } from 'framer-motion';
 import { useTranslation } from 'react-i18next';
@@ -15,6 +15,7 @@ interface MissionInfo {
         stars: number;
     };
     skill?: string;
+    steps?: { title: string }[];
 }
 
 interface MissionStep {
@@ -24,17 +25,19 @@ interface MissionStep {
 }
 
 const MissionPanel: React.FC = () => {
-    const { activeMissionId, endMission, verifyMission } = useMission();
+    const { activeMissionId, endMission, verifyMission, requestHint } = useMission();
     const { showResult, openDojoModal } = useDojo();
     const { t, i18n } = useTranslation();
     const [verificationResult, setVerificationResult] = useState<VerificationResult | null>(null);
     const [missionInfo, setMissionInfo] = useState<MissionInfo | null>(null);
     const [revealedHints, setRevealedHints] = useState<number>(0);
+    const [stepHint, setStepHint] = useState<HintResult | null>(null);
+    const [stepHintsUsed, setStepHintsUsed] = useState<number>(0);
     const [showSuccess, setShowSuccess] = useState(false);
     const [startTime, setStartTime] = useState<number>(Date.now());
     const [elapsedTime, setElapsedTime] = useState<number>(0);
 
-    // Mock steps based on mission validation checks
+    // Steps declared by the mission, completed as verification passes them
     const [steps, setSteps] = useState<MissionStep[]>([]);
 
     // Timer effect - update every second
@@ -51,6 +54,8 @@ const MissionPanel: React.FC = () => {
     useEffect(() => {
         if (activeMissionId) {
             setRevealedHints(0);
+            setStepHint(null);
+            setStepHintsUsed(0);
             setVerificationResult(null);
             setShowSuccess(false);
             setStartTime(Date.now());
@@ -65,14 +70,11 @@ const MissionPanel: React.FC = () => {
                     const mission = missions.find(m => m.id === activeMissionId);
                     if (mission) {
                         setMissionInfo(mission);
-                        // Initialize steps based on typical merge conflict mission
-                        if (mission.id === '001-conflict-crisis') {
-                            setSteps([
-                                { id: 'identify', description: t('mission.steps.identifyConflict'), completed: false },
-                                { id: 'resolve', description: t('mission.steps.resolveConflict'), completed: false },
-                                { id: 'commit', description: t('mission.steps.createMergeCommit'), completed: false },
-                            ]);
-                        }
+                        setSteps((mission.steps || []).map((step, index) => ({
+                            id: String(index),
+                            description: step.title,
+                            completed: false,
+                        })));
                     }
                 })
                 .catch(console.error);
@@ -81,14 +83,14 @@ const MissionPanel: React.FC = () => {
             setVerificationResult(null);
             setSteps([]);
         }
-    }, [activeMissionId, i18n.language, t]);
+    }, [activeMissionId, i18n.language]);
 
     const calculateScore = useCallback(() => {
         const baseScore = 100;
-        const hintPenalty = revealedHints * 10;
+        const hintPenalty = (revealedHints + stepHintsUsed) * 10;
         const timeBonus = Math.max(0, 20 - Math.floor(elapsedTime / 60000) * 5);
         return Math.max(0, baseScore - hintPenalty + timeBonus);
-    }, [revealedHints, elapsedTime]);
+    }, [revealedHints, stepHintsUsed, elapsedTime]);
 
     const handleVerify = useCallback(async () => {
         const result = await verifyMission();
@@ -96,12 +98,13 @@ const MissionPanel: React.FC = () => {
             setVerificationResult(result);
 
             // Update steps based on verification result
-            if (result.progress) {
+            if (result.steps) {
                 setSteps(prev => prev.map((step, index) => ({
                     ...step,
-                    completed: result.progress[index]?.passed ?? false
+                    completed: result.steps?.[index]?.completed ?? false
                 })));
             }
+            setStepHint(null);
 
             if (result.success) {
                 // Capture missionId before endMission clears it
@@ -119,7 +122,7 @@ const MissionPanel: React.FC = () => {
                     passed: true,
                     score: calculateScore(),
                     timeMs: elapsedTime,
-                    hintsUsed: revealedHints,
+                    hintsUsed: revealedHints + stepHintsUsed,
                 };
                 showResult(dojoResult, missionId);
                 endMission();
@@ -129,7 +132,7 @@ const MissionPanel: React.FC = () => {
                 }, 50);
             }
         }
-    }, [verifyMission, missionInfo?.skill, activeMissionId, calculateScore, elapsedTime, revealedHints, showResult, endMission, openDojoModal]);
+    }, [verifyMission, missionInfo?.skill, activeMissionId, calculateScore, elapsedTime, revealedHints, stepHintsUsed, showResult, endMission, openDojoModal]);
 
     const revealNextHint = () => {
         if (missionInfo && revealedHints < missionInfo.hints.length) {
@@ -137,6 +140,16 @@ const MissionPanel: React.FC = () => {
         }
     };
 
+    const requestStepHint = async () => {
+        const hint = await requestHint(i18n.language);
+        if (hint?.hint) {
+            setStepHint(hint);
+            setStepHintsUsed(prev => prev + 1);
+        }
+    };
+
+    const completedSteps = steps.filter(step => step.completed).length;
+
     const formatTime = (ms: number) => {
         const seconds = Math.floor(ms / 1000);
         const minutes = Math.floor(seconds / 60);
@@ -180,7 +193,7 @@ const MissionPanel: React.FC = () => {
                             </div>
                             <div className="stat-item">
                                 <span className="stat-label">💡 {t('mission.hintsUsed')}</span>
-                                <span className="stat-value">{revealedHints}{t('mission.times')}</span>
+                                <span className="stat-value">{revealedHints + stepHintsUsed}{t('mission.times')}</span>
                             </div>
                         </div>
 
@@ -195,7 +208,7 @@ const MissionPanel: React.FC = () => {
                                         passed: true,
                                         score: calculateScore(),
                                         timeMs: elapsedTime,
-                                        hintsUsed: revealedHints,
+                                        hintsUsed: revealedHints + stepHintsUsed,
                                     };
                                     setShowSuccess(false);
                                     showResult(result, missionId);
@@ -251,7 +264,15 @@ const MissionPanel: React.FC = () => {
                 {/* Steps Progress */}
                 {steps.length > 0 && (
                     <div className="mission-section">
-                        <h4 className="section-title">✅ {t('mission.steps.title')}</h4>
+                        <h4 className="section-title">
+                            ✅ {t('mission.steps.title')} ({completedSteps}/{steps.length})
+                        </h4>
+                        <div className="step-progress-bar">
+                            <div
+                                className="step-progress-fill"
+                                style={{ width: `${(completedSteps / steps.length) * 100}%` }}
+                            />
+                        </div>
                         <ul className="mission-steps">
                             {steps.map((step) => (
                                 <li key={step.id} className={`step-item ${step.completed ? 'completed' : ''}`}>
@@ -262,6 +283,16 @@ const MissionPanel: React.FC = () => {
                                 </li>
                             ))}
                         </ul>
+                        {stepHint && (
+                            <div className="hint-item step-hint">
+                                <span className="hint-text">{stepHint.hint}</span>
+                            </div>
+                        )}
+                        {completedSteps < steps.length && (
+                            <button onClick={requestStepHint} className="reveal-hint-btn">
+                                {t('mission.steps.hint')}
+                            </button>
+                        )}
                     </div>
                 )}
 
@@ -1,7 +1,7 @@
 
 import React, { useMemo } from 'react';
 import { useGit } from '../../context/GitAPIContext';
-import type { Commit } from '../../types/gitTypes';
+import type { BranchTracking, Commit } from '../../types/gitTypes';
 import { Cloud, GitBranch, Tag } from 'lucide-react';
 import { useTranslation } from 'react-i18next'; // Import
 
@@ -11,6 +11,20 @@ interface GitReferenceListProps {
     selectedCommitId?: string;
 }
 
+// TrackingBadge shows how a branch diverged from its upstream, e.g. "origin/main ↑2 ↓1".
+const TrackingBadge: React.FC<{ tracking: BranchTracking }> = ({ tracking }) => {
+    const { upstream, ahead, behind, gone } = tracking;
+    return (
+        <span
+            data-testid="tracking-badge"
+            title={gone ? `${upstream}: gone` : `${ahead} ahead, ${behind} behind ${upstream}`}
+            style={{ fontWeight: 'normal', color: 'var(--text-tertiary)', fontSize: '11px' }}
+        >
+            [{upstream}{gone ? ': gone' : ''}{ahead > 0 && ` ↑${ahead}`}{behind > 0 && ` ↓${behind}`}]
+        </span>
+    );
+};
+
 const GitReferenceList: React.FC<GitReferenceListProps> = ({ type, onSelect }) => {
     const { t } = useTranslation('common'); // Hook
     const { state } = useGit();
@@ -105,6 +119,9 @@ const GitReferenceList: React.FC<GitReferenceListProps> = ({ type, onSelect }) =
                                 <div style={{ display: 'flex', alignItems: 'center', gap: '8px' }}>
                                     {type === 'branches' && (item.isRemote ? <Cloud size={14} /> : <GitBranch size={14} />)}
                                     {item.name}
+                                    {!item.isRemote && state.tracking?.[item.name] && (
+                                        <TrackingBadge tracking={state.tracking[item.name]} />
+                                    )}
                                 </div>
                             </td>
                             <td style={{ padding: '8px 16px', color: 'var(--text-tertiary)' }}>
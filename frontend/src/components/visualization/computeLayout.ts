@@ -23,7 +23,9 @@ export function createBezierPath(x1: number, y1: number, x2: number, y2: number)
  * Computes the visual layout for the Git graph.
  * 
  * This function takes raw commit data and produces positioned nodes and edges
- * suitable for SVG rendering. It handles:
+ * suitable for SVG rendering. When the backend has assigned lanes and color
+ * groups (see layoutFromHints), they are used as-is so the layout stays put
+ * across polls; otherwise (e.g. with ghost commits) it handles:
  * - Sorting commits by timestamp
  * - Assigning lanes (columns) to commits
  * - Computing reachability from branch tips
@@ -57,6 +59,10 @@ export function computeLayout(
         return { nodes: [], edges: [], height: 0, badgesMap: {} };
     }
 
+    if (potentialCommits.length === 0 && commits.every(c => c.lane !== undefined)) {
+        return layoutFromHints(commits, branches, references, remoteBranches, tags, HEAD);
+    }
+
     // Sort by timestamp (newest first), with stable secondary sort
     const sortedCommits = combinedCommits
         .map((c, i) => ({ c, i }))
@@ -107,7 +113,6 @@ export function computeLayout(
 
     // --- LANE ASSIGNMENT ---
     const nodes: VizNode[] = [];
-    const edges: VizEdge[] = [];
     // activePaths tracks the commit ID currently occupying the tip of each lane
     // Lane 0 is reserved for Trunk if it exists
     const activePaths: (string | null)[] = [];
@@ -233,7 +238,61 @@ export function computeLayout(
         });
     });
 
-    // Create edges (No changes needed, uses coordinates)
+    const edges = buildEdges(nodes);
+
+    // Build badges map
+    const badgesMap = buildBadgesMap(branches, tags, references, remoteBranches, HEAD);
+
+    return {
+        nodes,
+        edges,
+        height: PADDING_TOP + combinedCommits.length * ROW_HEIGHT + PADDING_TOP,
+        badgesMap
+    };
+};
+
+/**
+ * Positions commits using the lanes and color groups computed by the backend.
+ * Commits keep the backend's order, which the lanes were assigned in.
+ */
+function layoutFromHints(
+    commits: Commit[],
+    branches: Record<string, string>,
+    references: Record<string, string>,
+    remoteBranches: Record<string, string>,
+    tags: Record<string, string>,
+    HEAD: GitState['HEAD']
+): LayoutResult {
+    const commitMap = new Map(commits.map(c => [c.id, { ...c, isGhost: false }]));
+    const reachable = computeReachability(commitMap, branches, HEAD, [], remoteBranches, tags);
+
+    const nodes: VizNode[] = commits.map((c, i) => {
+        const lane = c.lane ?? 0;
+        const isReachable = reachable.size === 0 ? true : reachable.has(c.id);
+        return {
+            ...c,
+            x: GRAPH_LEFT_PADDING + lane * LANE_WIDTH + LANE_WIDTH / 2,
+            y: PADDING_TOP + i * ROW_HEIGHT + ROW_HEIGHT / 2,
+            lane,
+            color: LANE_COLORS[(c.colorGroup ?? lane) % LANE_COLORS.length],
+            isGhost: false,
+            opacity: isReachable ? 1 : 0.3
+        };
+    });
+
+    return {
+        nodes,
+        edges: buildEdges(nodes),
+        height: PADDING_TOP + commits.length * ROW_HEIGHT + PADDING_TOP,
+        badgesMap: buildBadgesMap(branches, tags, references, remoteBranches, HEAD)
+    };
+}
+
+/**
+ * Creates the edges from each node to its parents.
+ */
+function buildEdges(nodes: VizNode[]): VizEdge[] {
+    const edges: VizEdge[] = [];
     const nodeMap = new Map(nodes.map(n => [n.id, n]));
     nodes.forEach(node => {
         const parents = [];
@@ -244,8 +303,6 @@ export function computeLayout(
             const parentNode = nodeMap.get(pid);
             if (!parentNode) return;
 
-            // Straight line if same lane, specialized bezier if column 0 involved?
-            // Existing bezier logic should handle it fine.
             const path = node.lane === parentNode.lane
                 ? `M ${node.x} ${node.y} L ${parentNode.x} ${parentNode.y}`
                 : createBezierPath(node.x, node.y, parentNode.x, parentNode.y);
@@ -261,17 +318,8 @@ export function computeLayout(
             });
         });
     });
-
-    // Build badges map
-    const badgesMap = buildBadgesMap(branches, tags, references, remoteBranches, HEAD);
-
-    return {
-        nodes,
-        edges,
-        height: PADDING_TOP + combinedCommits.length * ROW_HEIGHT + PADDING_TOP,
-        badgesMap
-    };
-};
+    return edges;
+}
 
 /**
  * Computes which commits are reachable from branch tips and HEAD.
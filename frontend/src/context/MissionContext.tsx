@@ -8,18 +8,39 @@ export interface CheckResult {
     passed: boolean;
 }
 
+export interface StepResult {
+    title: string;
+    completed: boolean;
+    progress: CheckResult[];
+}
+
 export interface VerificationResult {
     success: boolean;
     missionId: string;
     progress: CheckResult[];
+    steps?: StepResult[];
+    currentStep: number; // Index of the first unfinished step
+    totalSteps: number;
     message?: string; // For errors or summary
 }
 
+export interface HintResult {
+    missionId: string;
+    hint: string;
+    source: 'check' | 'step' | 'mission' | '';
+    stepTitle?: string;
+    currentStep: number;
+    totalSteps: number;
+    completed: boolean;
+    hintsUsed: number;
+}
+
 interface MissionContextType {
     activeMissionId: string | null;
     startMission: (missionId: string) => Promise<void>;
     endMission: () => void;
     verifyMission: () => Promise<VerificationResult | undefined>;
+    requestHint: (language: string) => Promise<HintResult | undefined>;
 }
 
 const MissionContext = createContext<MissionContextType | undefined>(undefined);
@@ -78,8 +99,20 @@ export const MissionProvider: React.FC<{ children: React.ReactNode }> = ({ child
         return await res.json() as VerificationResult;
     }, [activeMissionId, sessionId]);
 
+    // Next hint for what currently fails; the backend counts it in the learner's progress
+    const requestHint = useCallback(async (language: string) => {
+        if (!activeMissionId || !sessionId) return;
+        const res = await fetch('/api/mission/hint', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json', 'Accept-Language': language },
+            body: JSON.stringify({ sessionId, missionId: activeMissionId }),
+        });
+        if (!res.ok) return;
+        return await res.json() as HintResult;
+    }, [activeMissionId, sessionId]);
+
     return (
-        <MissionContext.Provider value={{ activeMissionId, startMission, endMission, verifyMission }}>
+        <MissionContext.Provider value={{ activeMissionId, startMission, endMission, verifyMission, requestHint }}>
             {children}
         </MissionContext.Provider>
     );
@@ -10,6 +10,7 @@ interface UseGitCommandProps {
 export const useGitCommand = ({ sessionId, gitData }: UseGitCommandProps) => {
     const {
         fetchState,
+        live,
         fetchServerState,
         setState,
         updateCommandOutput,
@@ -46,7 +47,12 @@ export const useGitCommand = ({ sessionId, gitData }: UseGitCommandProps) => {
             let isError = false;
 
             if (data.error) {
-                responseLines = [`Error: ${data.error}`];
+                // Chained command lines can print output before the failing command
+                responseLines = data.output ? [data.output, `Error: ${data.error}`] : [`Error: ${data.error}`];
+                const suggestion = data.result?.suggestion;
+                if (suggestion) {
+                    responseLines.push(suggestion.command ? `hint: ${suggestion.message} Try: ${suggestion.command}` : `hint: ${suggestion.message}`);
+                }
                 isError = true;
             } else if (data.output) {
                 responseLines = [data.output];
@@ -64,8 +70,8 @@ export const useGitCommand = ({ sessionId, gitData }: UseGitCommandProps) => {
                 });
             }
 
-            // 3. Refresh State
-            if (!options?.skipRefresh) {
+            // 3. Refresh State (pushed by the server while the state stream is live)
+            if (!options?.skipRefresh && !live) {
                 await fetchState(sessionId);
             }
 
@@ -78,7 +84,9 @@ export const useGitCommand = ({ sessionId, gitData }: UseGitCommandProps) => {
 
             // 5. Auto-refresh Server State based on command type
             if (!options?.skipRefresh) {
-                const isRemoteCommand = ['push', 'pull', 'fetch', 'clone', 'remote'].some(c => cmd.startsWith(`git ${c}`));
+                const movedRemoteRefs = (data.results ?? (data.result ? [data.result] : []))
+                    .some(r => r.payload?.refUpdates?.some(u => u.name.startsWith('refs/remotes/')) ?? false);
+                const isRemoteCommand = movedRemoteRefs || ['push', 'pull', 'fetch', 'clone', 'remote'].some(c => cmd.startsWith(`git ${c}`));
 
                 if (isRemoteCommand) {
                     // Fetch current remote list from backend (avoids stale serverState issues)
@@ -104,7 +112,7 @@ export const useGitCommand = ({ sessionId, gitData }: UseGitCommandProps) => {
             incrementCommandCount(sessionId);
             return [errorLine];
         }
-    }, [sessionId, fetchState, fetchServerState, setState, updateCommandOutput, incrementCommandCount]);
+    }, [sessionId, fetchState, live, fetchServerState, setState, updateCommandOutput, incrementCommandCount]);
 
     return { runCommand };
 };
@@ -10,6 +10,8 @@ export interface GitDataHook {
     showAllCommits: boolean;
     toggleShowAllCommits: () => void;
     fetchState: (sid: string) => Promise<void>;
+    /** True while state updates are pushed by the server, so fetchState is not needed after commands */
+    live: boolean;
     fetchServerState: (name: string) => Promise<void>;
     refreshPullRequests: () => Promise<void>;
     setState: React.Dispatch<React.SetStateAction<GitState>>;
@@ -85,32 +87,47 @@ export const useGitData = (sessionId: string): GitDataHook => {
         }));
     }, []);
 
+    const applyState = useCallback((sid: string, newState: GitState) => {
+        setState(prev => {
+            const storedOutput = sessionOutputsRef.current[sid] || [];
+            const storedCount = sessionCmdCountsRef.current[sid] || 0;
+
+            // The backend always includes dangling commits (flagged); SHOW ALL decides whether to keep them
+            const finalCommits = showAllCommits
+                ? newState.commits
+                : filterReachableCommits(newState.commits, newState);
+
+            return {
+                ...prev,
+                ...newState,
+                commits: finalCommits,
+                output: storedOutput,
+                commandCount: storedCount,
+                _sessionId: sid
+            };
+        });
+    }, [showAllCommits]);
+
     const fetchState = useCallback(async (sid: string) => {
         if (!sid) return;
         try {
-            const newState = await gitService.fetchState(sid, showAllCommits);
-
-            setState(prev => {
-                const storedOutput = sessionOutputsRef.current[sid] || [];
-                const storedCount = sessionCmdCountsRef.current[sid] || 0;
-
-                const finalCommits = showAllCommits
-                    ? newState.commits
-                    : filterReachableCommits(newState.commits, newState);
-
-                return {
-                    ...prev,
-                    ...newState,
-                    commits: finalCommits,
-                    output: storedOutput,
-                    commandCount: storedCount,
-                    _sessionId: sid
-                };
-            });
+            const newState = await gitService.fetchState(sid);
+            applyState(sid, newState);
         } catch (e) {
             console.error("fetchState failed", e);
         }
-    }, [showAllCommits]);
+    }, [applyState]);
+
+    // Pushed state updates; polling via fetchState is only the fallback while disconnected
+    const [live, setLive] = useState<boolean>(false);
+    useEffect(() => {
+        if (!sessionId || typeof EventSource === 'undefined') return;
+        return gitService.subscribeState(
+            sessionId,
+            newState => applyState(sessionId, newState),
+            setLive
+        );
+    }, [sessionId, applyState]);
 
     const fetchServerState = useCallback(async (name: string) => {
         try {
@@ -147,12 +164,13 @@ export const useGitData = (sessionId: string): GitDataHook => {
         }
     }, []);
 
-    // Re-fetch when showAllCommits or command count changes
+    // Re-fetch when showAllCommits or command count changes (commands only matter without the stream)
+    const pollTrigger = live ? 0 : state.commandCount;
     useEffect(() => {
         if (sessionId) {
             fetchState(sessionId);
         }
-    }, [sessionId, showAllCommits, fetchState, state.commandCount]);
+    }, [sessionId, showAllCommits, fetchState, pollTrigger]);
 
     return {
         state,
@@ -161,6 +179,7 @@ export const useGitData = (sessionId: string): GitDataHook => {
         showAllCommits,
         toggleShowAllCommits,
         fetchState,
+        live,
         fetchServerState,
         refreshPullRequests,
         setState,
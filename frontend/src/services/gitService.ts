@@ -1,4 +1,5 @@
-import type { GitState, PullRequest } from '../types/gitTypes';
+import type { AnalyticsReport, AuditEntry, BareRepoOptions, BlameResult, BranchRename, CheckResults, CheckRule, CommandResult, CommitPageOptions, CommitSearchQuery, CommitSearchResult, Comparison, CompletionResult, DiffResponse, FileContent, FileHistoryResult, GeneratedLesson, GitState, ImportResult, IngestManifest, Issue, Job, MaintenanceReport, MissionProgress, ObjectGraph, PullRequest, PullRequestCommits, PullRequestDiff, PullRequestMergeStrategy, PullRequestReviewState, RebasePlan, RebaseStep, RemotePresence, RemoteSyncStatus, RepoTemplateList, SavePoint, SessionLogEntry, StateDelta, StorageUsage, TeammateRun, TeammateScenario, Timeline, UpstreamEvent, UserIdentity } from '../types/gitTypes';
+import i18n from '../i18n';
 
 interface InitResponse {
     status: string;
@@ -8,8 +9,41 @@ interface InitResponse {
 interface CommandResponse {
     output?: string;
     error?: string;
+    result?: CommandResult;
+    results?: CommandResult[];
 }
 
+interface StateUpdate {
+    seq: number;
+    full: boolean;
+    changes: Record<string, unknown>;
+}
+
+// Ensure default structure matches GitState interface
+// eslint-disable-next-line @typescript-eslint/no-explicit-any
+const normalizeState = (data: any): GitState => ({
+    commits: data.commits || [],
+    branches: data.branches || {},
+    tags: data.tags || {},
+    references: data.references || {},
+    remotes: data.remotes || [],
+    remoteBranches: data.remoteBranches || {},
+    HEAD: data.HEAD || { type: 'none' },
+    files: data.files || [],
+    potentialCommits: data.potentialCommits || [],
+    staging: data.staging || [],
+    modified: data.modified || [],
+    untracked: data.untracked || [],
+    ignored: data.ignored || [],
+    fileStatuses: data.fileStatuses || {},
+    currentPath: data.currentPath || '',
+    projects: data.projects || [],
+    sharedRemotes: data.sharedRemotes || [],
+    initialized: data.initialized || false,
+    output: [], // State API doesn't return output history
+    commandCount: 0 // Managed by context
+});
+
 export const gitService = {
     async initSession(): Promise<InitResponse> {
         const res = await fetch('/api/session/init', { method: 'POST' });
@@ -17,35 +51,304 @@ export const gitService = {
         return res.json();
     },
 
-    async fetchState(sessionId: string, showAll: boolean = false): Promise<GitState> {
-        const res = await fetch(`/api/state?sessionId=${sessionId}&t=${Date.now()}&showAll=${showAll}`);
+    async fetchState(sessionId: string): Promise<GitState> {
+        const res = await fetch(`/api/state?sessionId=${sessionId}&t=${Date.now()}`);
         if (!res.ok) throw new Error('Failed to fetch state');
-        const data = await res.json();
+        return normalizeState(await res.json());
+    },
 
-        // Ensure default structure matches GitState interface
-        return {
-            commits: data.commits || [],
-            branches: data.branches || {},
-            tags: data.tags || {},
-            references: data.references || {},
-            remotes: data.remotes || [],
-            remoteBranches: data.remoteBranches || {},
-            HEAD: data.HEAD || { type: 'none' },
-            files: data.files || [],
-            potentialCommits: data.potentialCommits || [],
-            staging: data.staging || [],
-            modified: data.modified || [],
-            untracked: data.untracked || [],
-            fileStatuses: data.fileStatuses || {},
-            currentPath: data.currentPath || '',
-            projects: data.projects || [],
-            sharedRemotes: data.sharedRemotes || [],
-            initialized: data.initialized || false,
-            output: [], // State API doesn't return output history
-            commandCount: 0 // Managed by context
+    /**
+     * Fetch the state with only one page of commits, newest first.
+     * Large (e.g. ingested) repositories are too big to send whole on every poll.
+     */
+    async fetchStatePage(sessionId: string, options: CommitPageOptions): Promise<GitState> {
+        const params = new URLSearchParams({ sessionId, t: String(Date.now()) });
+        for (const [key, value] of Object.entries(options)) {
+            if (value !== undefined) params.set(key, String(value));
+        }
+        const res = await fetch(`/api/state?${params}`);
+        if (!res.ok) throw new Error('Failed to fetch state');
+        return normalizeState(await res.json());
+    },
+
+    /**
+     * Fetch what changed since state version `since` (0 for everything).
+     * Keep the returned version and pass it to the next call.
+     */
+    async fetchStateDelta(sessionId: string, since: number): Promise<StateDelta> {
+        const res = await fetch(`/api/state/delta?sessionId=${encodeURIComponent(sessionId)}&since=${since}`);
+        if (!res.ok) throw new Error('Failed to fetch state delta');
+        return res.json();
+    },
+
+    /**
+     * Subscribe to pushed state updates of a session (server-sent events).
+     * The first update carries the whole state, later ones only changed fields;
+     * onState always receives the merged, complete state.
+     * Returns a function that closes the stream.
+     */
+    subscribeState(
+        sessionId: string,
+        onState: (state: GitState) => void,
+        onConnection: (connected: boolean) => void
+    ): () => void {
+        const source = new EventSource(`/api/state/stream?sessionId=${encodeURIComponent(sessionId)}`);
+        let raw: Record<string, unknown> = {};
+
+        source.addEventListener('state', (e) => {
+            const update: StateUpdate = JSON.parse((e as MessageEvent).data);
+            raw = update.full ? { ...update.changes } : { ...raw, ...update.changes };
+            onState(normalizeState(raw));
+        });
+        source.onopen = () => onConnection(true);
+        // EventSource reconnects by itself; the server then starts over with a full update
+        source.onerror = () => onConnection(false);
+
+        return () => {
+            source.close();
+            onConnection(false);
         };
     },
 
+    async fetchDiff(sessionId: string, from: string, to: string = 'worktree'): Promise<DiffResponse> {
+        const params = new URLSearchParams({ sessionId, from, to });
+        const res = await fetch(`/api/diff?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch diff');
+        const data = await res.json();
+        return { from: data.from, to: data.to, files: data.files || [] };
+    },
+
+    // Compare head against base like a pull request. With remote, both name
+    // branches of that shared remote ("main" or "origin/main").
+    async fetchComparison(sessionId: string, base: string, head: string, remote?: string): Promise<Comparison> {
+        const params = new URLSearchParams({ sessionId, base, head });
+        if (remote) params.set('remote', remote);
+        const res = await fetch(`/api/compare?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to compare');
+        return res.json();
+    },
+
+    /**
+     * Import a real repository from a zipped .git directory into the session
+     */
+    async importRepository(sessionId: string, name: string, archive: Blob): Promise<ImportResult> {
+        const params = new URLSearchParams({ sessionId, name });
+        const res = await fetch(`/api/session/import?${params.toString()}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/zip' },
+            body: archive
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to import repository');
+        return res.json();
+    },
+
+    // URL downloading a session repository: a git bundle of its whole history
+    // ("git clone repo.bundle"), or the files of ref as zip / tar / tar.gz.
+    exportRepositoryUrl(sessionId: string, options: { repo?: string; format?: 'bundle' | 'zip' | 'tar' | 'tar.gz'; ref?: string } = {}): string {
+        const params = new URLSearchParams({ sessionId });
+        if (options.repo) params.set('repo', options.repo);
+        if (options.format) params.set('format', options.format);
+        if (options.ref) params.set('ref', options.ref);
+        return `/api/session/export?${params.toString()}`;
+    },
+
+    // URL downloading the command trace of a session, to attach to a bug report.
+    sessionTraceUrl(sessionId: string): string {
+        return `/api/session/trace?${new URLSearchParams({ sessionId }).toString()}`;
+    },
+
+    async fetchTimeline(sessionId: string): Promise<Timeline> {
+        const res = await fetch(`/api/session/timeline?${new URLSearchParams({ sessionId }).toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch timeline');
+        return res.json();
+    },
+
+    async fetchBlame(sessionId: string, path: string, options: { rev?: string; start?: number; end?: number } = {}): Promise<BlameResult> {
+        const params = new URLSearchParams({ sessionId, path });
+        if (options.rev) params.set('rev', options.rev);
+        if (options.start) params.set('start', String(options.start));
+        if (options.end) params.set('end', String(options.end));
+        const res = await fetch(`/api/blame?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch blame');
+        return res.json();
+    },
+
+    async searchCommits(sessionId: string, query: CommitSearchQuery): Promise<CommitSearchResult> {
+        const params = new URLSearchParams({ sessionId });
+        for (const [key, value] of Object.entries(query)) {
+            if (value !== undefined && value !== '' && value !== false) params.set(key, String(value));
+        }
+        const res = await fetch(`/api/search?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to search commits');
+        return res.json();
+    },
+
+    async fetchFileHistory(sessionId: string, path: string, options: { rev?: string; patch?: boolean; limit?: number } = {}): Promise<FileHistoryResult> {
+        const params = new URLSearchParams({ sessionId, path });
+        if (options.rev) params.set('rev', options.rev);
+        if (options.patch) params.set('patch', 'true');
+        if (options.limit) params.set('limit', String(options.limit));
+        const res = await fetch(`/api/file/history?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch file history');
+        return res.json();
+    },
+
+    async fetchFileAt(sessionId: string, path: string, rev: string): Promise<FileContent> {
+        const params = new URLSearchParams({ sessionId, path, rev });
+        const res = await fetch(`/api/file/at?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch file');
+        return res.json();
+    },
+
+    async fetchObjectGraph(sessionId: string, limit?: number): Promise<ObjectGraph> {
+        const params = new URLSearchParams({ sessionId });
+        if (limit) params.set('limit', String(limit));
+        const res = await fetch(`/api/objects?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch object graph');
+        return res.json();
+    },
+
+    async fetchMaintenanceReport(sessionId: string): Promise<MaintenanceReport> {
+        const res = await fetch(`/api/session/maintenance?sessionId=${sessionId}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch maintenance report');
+        return res.json();
+    },
+
+    async fetchSessionUsage(sessionId: string): Promise<StorageUsage> {
+        const res = await fetch(`/api/session/usage?sessionId=${sessionId}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch storage usage');
+        return res.json();
+    },
+
+    async fetchAuditLog(sessionId: string, limit: number = 100): Promise<AuditEntry[]> {
+        const res = await fetch(`/api/session/audit?sessionId=${sessionId}&limit=${limit}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch audit log');
+        return (await res.json()) || [];
+    },
+
+    /**
+     * Get the recent log records of a session, to attach to a support ticket
+     */
+    async fetchSessionLogs(sessionId: string): Promise<SessionLogEntry[]> {
+        const res = await fetch(`/api/session/logs?sessionId=${sessionId}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch session logs');
+        return (await res.json()) || [];
+    },
+
+    /**
+     * Get the simulated user the session commits and pushes as
+     */
+    async fetchSessionUser(sessionId: string): Promise<UserIdentity> {
+        const res = await fetch(`/api/session/user?sessionId=${sessionId}`);
+        if (!res.ok) throw new Error('Failed to fetch session user');
+        return res.json();
+    },
+
+    async setSessionUser(sessionId: string, user: { name: string; email?: string }): Promise<UserIdentity> {
+        const res = await fetch(`/api/session/user?sessionId=${sessionId}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(user)
+        });
+        if (!res.ok) {
+            const errText = await res.text();
+            throw new Error(errText || 'Failed to set session user');
+        }
+        return res.json();
+    },
+
+    /**
+     * List the save points of the session, oldest first
+     */
+    async fetchSavePoints(sessionId: string): Promise<SavePoint[]> {
+        const res = await fetch(`/api/session/snapshot?sessionId=${sessionId}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch save points');
+        const data = await res.json();
+        return data.snapshots || [];
+    },
+
+    /**
+     * Keep the current state as a save point; without a name the backend picks save-N
+     */
+    async createSavePoint(sessionId: string, name?: string): Promise<SavePoint> {
+        const res = await fetch(`/api/session/snapshot?sessionId=${sessionId}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name || '' })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to create save point');
+        return res.json();
+    },
+
+    async deleteSavePoint(sessionId: string, name: string): Promise<void> {
+        const res = await fetch(`/api/session/snapshot?sessionId=${sessionId}&name=${encodeURIComponent(name)}`, { method: 'DELETE' });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to delete save point');
+    },
+
+    /**
+     * Roll the session back to a save point. Like undo, the result carries the
+     * new state, and "gitgym undo" takes the restore back.
+     */
+    async restoreSavePoint(sessionId: string, name: string): Promise<CommandResponse> {
+        const res = await fetch(`/api/session/restore?sessionId=${sessionId}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name })
+        });
+        if (res.status === 400) throw new Error(await res.text() || 'Failed to restore save point');
+        return res.json();
+    },
+
+    /**
+     * Get the todo list of the interactive rebase started with `git rebase -i`.
+     * Returns null when no rebase is in progress.
+     */
+    async fetchRebasePlan(sessionId: string): Promise<RebasePlan | null> {
+        const res = await fetch(`/api/rebase/plan?sessionId=${sessionId}&t=${Date.now()}`);
+        if (res.status === 404) return null;
+        if (!res.ok) throw new Error('Failed to fetch rebase plan');
+        return res.json();
+    },
+
+    /**
+     * Submit the edited todo list; the backend replays the commits accordingly.
+     */
+    async submitRebasePlan(sessionId: string, steps: RebaseStep[]): Promise<CommandResponse> {
+        const res = await fetch('/api/rebase/plan', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ sessionId, steps })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to submit rebase plan');
+        return res.json();
+    },
+
+    async fetchMissionProgress(sessionId: string): Promise<MissionProgress[]> {
+        const res = await fetch(`/api/mission/progress?sessionId=${sessionId}&t=${Date.now()}`);
+        if (!res.ok) throw new Error('Failed to fetch mission progress');
+        return (await res.json()) || [];
+    },
+
+    async fetchAnalytics(sessionId?: string): Promise<AnalyticsReport> {
+        const query = sessionId ? `?sessionId=${encodeURIComponent(sessionId)}` : '';
+        const res = await fetch(`/api/analytics${query}`);
+        if (!res.ok) throw new Error('Failed to fetch analytics');
+        return res.json();
+    },
+
+    /**
+     * Scaffold missions from teachable moments in an ingested remote's history
+     */
+    async generateLessons(remote: string, options: { maxCommits?: number; maxMoments?: number; longLivedDays?: number } = {}): Promise<GeneratedLesson[]> {
+        const res = await fetch('/api/mission/generate', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ remote, ...options })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to generate lessons');
+        return (await res.json()) || [];
+    },
+
     async getRemoteState(name: string): Promise<GitState> {
         const res = await fetch(`/api/remote/state?name=${name}&t=${Date.now()}`);
         if (!res.ok) throw new Error('Failed to fetch remote state');
@@ -74,17 +377,43 @@ export const gitService = {
     },
 
 
-    async executeCommand(sessionId: string, cmd: string): Promise<CommandResponse> {
+    // With editor, commands missing a message return result.editor instead of failing.
+    // Help and suggestions come back in the language of the UI.
+    async executeCommand(sessionId: string, cmd: string, editor = false): Promise<CommandResponse> {
         const res = await fetch('/api/command', {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ sessionId, command: cmd })
+            headers: { 'Content-Type': 'application/json', 'Accept-Language': i18n.language },
+            body: JSON.stringify({ sessionId, command: cmd, editor })
         });
+        if (res.status === 413 || res.status === 429) {
+            // Request limits: the message tells the learner what to change
+            throw new Error((await res.text()).trim());
+        }
         if (!res.ok) throw new Error('Failed to execute command');
         return res.json();
     },
 
-    async ingestRemote(name: string, url: string, depth?: number): Promise<void> {
+    // Completions for the last word of a partial command line
+    async complete(sessionId: string, line: string): Promise<CompletionResult> {
+        const params = new URLSearchParams({ sessionId, line });
+        const res = await fetch(`/api/complete?${params.toString()}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to complete command');
+        return res.json();
+    },
+
+    // Saves the edited message (or cancels) and resumes the command waiting for it
+    async completeEditor(sessionId: string, id: string, message: string, cancel = false): Promise<CommandResponse> {
+        const res = await fetch('/api/editor/complete', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ sessionId, id, message, cancel })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to complete editor');
+        return res.json();
+    },
+
+    // Starts ingesting a remote and resolves once the ingest job has finished
+    async ingestRemote(name: string, url: string, depth?: number, onProgress?: (job: Job) => void): Promise<void> {
         const res = await fetch('/api/remote/ingest', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
@@ -94,6 +423,75 @@ export const gitService = {
             const errText = await res.text();
             throw new Error(errText || 'Failed to ingest remote');
         }
+        const { jobId } = await res.json();
+        await this.waitForJob(jobId, onProgress);
+    },
+
+    /**
+     * Follow a background job over server-sent events until it finishes.
+     * Resolves with the succeeded job, rejects when it failed or was cancelled.
+     */
+    waitForJob(id: string, onProgress?: (job: Job) => void): Promise<Job> {
+        return new Promise((resolve, reject) => {
+            const source = new EventSource(`/api/jobs/${encodeURIComponent(id)}/events`);
+            source.addEventListener('job', (e) => {
+                const job: Job = JSON.parse((e as MessageEvent).data);
+                onProgress?.(job);
+                if (job.state === 'succeeded') {
+                    source.close();
+                    resolve(job);
+                } else if (job.state === 'failed' || job.state === 'cancelled') {
+                    source.close();
+                    reject(new Error(job.error || `Job ${job.state}`));
+                }
+            });
+            // EventSource reconnects by itself unless the job is gone
+            source.onerror = () => {
+                if (source.readyState === EventSource.CLOSED) {
+                    reject(new Error('Lost the connection to the job'));
+                }
+            };
+        });
+    },
+
+    async cancelJob(id: string): Promise<Job> {
+        const res = await fetch(`/api/jobs/${encodeURIComponent(id)}/cancel`, { method: 'POST' });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to cancel job');
+        return res.json();
+    },
+
+    async getRemoteSync(): Promise<RemoteSyncStatus[]> {
+        const res = await fetch('/api/remote/sync');
+        if (!res.ok) throw new Error(await res.text() || 'Failed to get remote sync');
+        return res.json();
+    },
+
+    /**
+     * Make an ingested remote fetch its upstream every interval ("" stops it).
+     * With now, also sync right away and wait for that job.
+     */
+    async setRemoteSync(name: string, interval?: string, now = false): Promise<RemoteSyncStatus> {
+        const res = await fetch('/api/remote/sync', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name, interval, now })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to set remote sync');
+        if (!now) return res.json();
+        const { jobId, sync } = await res.json();
+        await this.waitForJob(jobId);
+        return sync;
+    },
+
+    /**
+     * Follow upstream moves of ingested remotes. Returns a function that stops listening.
+     */
+    subscribeUpstream(onEvent: (event: UpstreamEvent) => void): () => void {
+        const source = new EventSource('/api/remote/sync/events');
+        source.addEventListener('upstream', (e) => {
+            onEvent(JSON.parse((e as MessageEvent).data));
+        });
+        return () => source.close();
     },
 
     async getRemoteInfo(url: string): Promise<{
@@ -126,6 +524,15 @@ export const gitService = {
         return data.remotes || [];
     },
 
+    /**
+     * Get the ingest state of every shared remote
+     */
+    async listIngests(): Promise<IngestManifest[]> {
+        const res = await fetch('/api/remote/ingests');
+        if (!res.ok) return [];
+        return res.json();
+    },
+
     /**
      * Helper to get the current active remote name (since we only support single residency).
      * Returns the first remote found, or 'origin' as fallback.
@@ -156,16 +563,58 @@ export const gitService = {
         return res.json();
     },
 
-    async mergePullRequest(id: number, remoteName: string = 'origin'): Promise<void> {
+    async mergePullRequest(id: number, remoteName: string = 'origin', strategy: PullRequestMergeStrategy = 'merge', mergedBy?: string): Promise<PullRequest> {
         const res = await fetch('/api/remote/pull-requests/merge', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ id, remoteName })
+            body: JSON.stringify({ id, remoteName, strategy, mergedBy })
         });
         if (!res.ok) {
             const errText = await res.text();
             throw new Error(errText || 'Failed to merge pull request');
         }
+        return res.json();
+    },
+
+    async fetchPullRequestCommits(id: number): Promise<PullRequestCommits> {
+        const res = await fetch(`/api/pr/${id}/commits`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch pull request commits');
+        return res.json();
+    },
+
+    async fetchPullRequestDiff(id: number): Promise<PullRequestDiff> {
+        const res = await fetch(`/api/pr/${id}/diff`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch pull request diff');
+        return res.json();
+    },
+
+    async closePullRequest(id: number): Promise<PullRequest> {
+        return this.updatePullRequest('close', { id });
+    },
+
+    async reopenPullRequest(id: number): Promise<PullRequest> {
+        return this.updatePullRequest('reopen', { id });
+    },
+
+    async reviewPullRequest(id: number, author: string, state: PullRequestReviewState, body: string = ''): Promise<PullRequest> {
+        return this.updatePullRequest('review', { id, author, state, body });
+    },
+
+    async commentOnPullRequest(id: number, author: string, body: string, path?: string, line?: number): Promise<PullRequest> {
+        return this.updatePullRequest('comment', { id, author, body, path, line });
+    },
+
+    async updatePullRequest(action: 'close' | 'reopen' | 'review' | 'comment', payload: Record<string, unknown>): Promise<PullRequest> {
+        const res = await fetch(`/api/remote/pull-requests/${action}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(payload)
+        });
+        if (!res.ok) {
+            const errText = await res.text();
+            throw new Error(errText || `Failed to ${action} pull request`);
+        }
+        return res.json();
     },
 
     async deletePullRequest(id: number): Promise<void> {
@@ -180,6 +629,112 @@ export const gitService = {
         }
     },
 
+    async fetchIssues(remoteName: string = 'origin', state?: Issue['state']): Promise<Issue[]> {
+        const params = new URLSearchParams({ name: remoteName });
+        if (state) params.set('state', state);
+        const res = await fetch(`/api/remote/issues?${params}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to fetch issues');
+        return res.json();
+    },
+
+    async createIssue(issue: { remoteName: string; title: string; body?: string; creator: string }): Promise<Issue> {
+        return this.updateIssue('create', issue);
+    },
+
+    async closeIssue(remoteName: string, id: number, closedBy?: string): Promise<Issue> {
+        return this.updateIssue('close', { remoteName, id, closedBy });
+    },
+
+    async reopenIssue(remoteName: string, id: number): Promise<Issue> {
+        return this.updateIssue('reopen', { remoteName, id });
+    },
+
+    async updateIssue(action: 'create' | 'close' | 'reopen', payload: Record<string, unknown>): Promise<Issue> {
+        const res = await fetch(`/api/remote/issues/${action}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(payload)
+        });
+        if (!res.ok) {
+            const errText = await res.text();
+            throw new Error(errText || `Failed to ${action} issue`);
+        }
+        return res.json();
+    },
+
+    /**
+     * Get who is working on a shared remote and whose pushes collided
+     */
+    async fetchPresence(name: string = 'origin'): Promise<RemotePresence> {
+        const res = await fetch(`/api/remote/presence?name=${encodeURIComponent(name)}`);
+        if (!res.ok) throw new Error('Failed to fetch presence');
+        return res.json();
+    },
+
+    /**
+     * List the teammate scenarios that can be played on a shared remote
+     */
+    async fetchTeammateScenarios(): Promise<string[]> {
+        const res = await fetch('/api/remote/simulate');
+        if (!res.ok) throw new Error('Failed to fetch teammate scenarios');
+        const data = await res.json();
+        return data.scenarios || [];
+    },
+
+    async fetchTeammateRun(remote: string = 'origin'): Promise<TeammateRun | null> {
+        const res = await fetch(`/api/remote/simulate?remote=${encodeURIComponent(remote)}`);
+        if (res.status === 404) return null;
+        if (!res.ok) throw new Error('Failed to fetch teammate run');
+        return res.json();
+    },
+
+    /**
+     * Start, run through, advance or stop scripted teammate activity on a shared remote
+     */
+    async simulateTeammates(request: { remote?: string; action?: 'start' | 'run' | 'next' | 'stop'; scenario?: string; inline?: TeammateScenario; manual?: boolean }): Promise<TeammateRun> {
+        const res = await fetch('/api/remote/simulate', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(request)
+        });
+        if (!res.ok) {
+            const errText = await res.text();
+            throw new Error(errText || 'Failed to simulate teammates');
+        }
+        return res.json();
+    },
+
+    /**
+     * Get the simulated CI results of a commit, a pull request or a remote branch
+     */
+    async fetchChecks(query: { commit?: string; pr?: number; remote?: string; ref?: string }): Promise<CheckResults> {
+        const params = new URLSearchParams();
+        Object.entries(query).forEach(([key, value]) => {
+            if (value !== undefined) params.set(key, String(value));
+        });
+        const res = await fetch(`/api/checks?${params}`);
+        if (!res.ok) throw new Error('Failed to fetch checks');
+        return res.json();
+    },
+
+    async fetchCheckRules(name: string = 'origin'): Promise<CheckRule[]> {
+        const res = await fetch(`/api/checks/rules?name=${encodeURIComponent(name)}`);
+        if (!res.ok) throw new Error('Failed to fetch check rules');
+        return res.json();
+    },
+
+    async setCheckRules(name: string, rules: CheckRule[]): Promise<void> {
+        const res = await fetch('/api/checks/rules', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name, rules })
+        });
+        if (!res.ok) {
+            const errText = await res.text();
+            throw new Error(errText || 'Failed to set check rules');
+        }
+    },
+
     async resetRemote(name: string = 'origin'): Promise<void> {
         const res = await fetch('/api/remote/reset', {
             method: 'POST',
@@ -206,14 +761,14 @@ export const gitService = {
         }
     },
 
-    async createRemote(name: string, sessionId: string): Promise<{ name: string; remoteUrl: string }> {
+    async createRemote(name: string, sessionId: string, options: BareRepoOptions = {}): Promise<{ name: string; remoteUrl: string }> {
         const res = await fetch('/api/remote/create', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
                 'X-Session-ID': sessionId
             },
-            body: JSON.stringify({ name })
+            body: JSON.stringify({ name, ...options })
         });
         if (!res.ok) {
             const errText = await res.text();
@@ -222,6 +777,40 @@ export const gitService = {
         return res.json();
     },
 
+    async getRepoTemplates(): Promise<RepoTemplateList> {
+        const res = await fetch('/api/remote/templates');
+        if (!res.ok) throw new Error(await res.text() || 'Failed to get repository templates');
+        return res.json();
+    },
+
+    async getRemoteDefaultBranch(name: string): Promise<string> {
+        const res = await fetch(`/api/remote/default-branch?name=${encodeURIComponent(name)}`);
+        if (!res.ok) throw new Error(await res.text() || 'Failed to get default branch');
+        const data: { defaultBranch: string } = await res.json();
+        return data.defaultBranch;
+    },
+
+    async setRemoteDefaultBranch(name: string, branch: string): Promise<string> {
+        const res = await fetch('/api/remote/default-branch', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name, branch })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to set default branch');
+        const data: { defaultBranch: string } = await res.json();
+        return data.defaultBranch;
+    },
+
+    async renameRemoteBranch(name: string, from: string, to: string): Promise<BranchRename> {
+        const res = await fetch('/api/remote/branches/rename', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name, from, to })
+        });
+        if (!res.ok) throw new Error(await res.text() || 'Failed to rename branch');
+        return res.json();
+    },
+
     async getWorkspaceTree(sessionId: string): Promise<{
         tree: DirectoryNode[];
         currentPath: string;
@@ -6,6 +6,13 @@ export interface Commit {
     branch: string;
     timestamp: string;
     author: string;
+    dangling?: boolean; // Unreachable from any ref (e.g. left behind by amend/rebase/reset)
+    shallow?: boolean; // parents were left out by a shallow clone (git clone --depth)
+    replaces?: string; // Commit this one was rewritten from
+    replacedBy?: string[]; // Commits rewritten from this one
+    rewriteKind?: 'amend' | 'rebase' | 'cherry-pick';
+    lane?: number; // graph column assigned by the backend
+    colorGroup?: number; // color of the first-parent chain (0 = trunk), index into the lane palette
 }
 
 
@@ -14,18 +21,61 @@ export interface Remote {
     urls: string[];
 }
 
+export interface BranchGroup {
+    prefix: string; // e.g. "feature/" or "origin/release/"
+    branches: string[];
+    count: number;
+    collapsed: boolean;
+}
+
+export interface BranchTracking {
+    upstream: string; // remote-tracking branch, e.g. "origin/main"
+    ahead: number;
+    behind: number;
+    gone?: boolean; // upstream configured but no longer (or not yet) fetched
+}
+
+export interface CommitPagination {
+    total: number; // commits in the graph across all pages
+    offset: number;
+    limit?: number;
+    nextCursor?: string; // pass as `after` to fetch the next page
+    truncated?: boolean; // history continues beyond the requested depth
+}
+
+export interface CommitPageOptions {
+    limit?: number;
+    offset?: number;
+    after?: string; // commit ID cursor
+    depth?: number; // parent hops followed from each ref
+}
+
+/** Reply of /api/state/delta: what changed since the version the client last saw. */
+export interface StateDelta {
+    version: number;
+    full: boolean; // replaces everything the client had
+    changes: Record<string, unknown>; // changed GitState fields other than commits
+    commits: Commit[]; // added or changed commits
+    removedCommits?: string[];
+    commitOrder?: string[]; // newest-first commit IDs, when the order changed
+}
+
 export interface GitState {
     initialized: boolean;
     commits: Commit[];
     branches: Record<string, string>; // branchName -> commitId
     remoteBranches: Record<string, string>; // remote/branchName -> commitId
+    tracking?: Record<string, BranchTracking>; // local branchName -> divergence from its upstream
+    branchGroups?: BranchGroup[]; // branches sharing a path-style prefix
+    remoteBranchGroups?: BranchGroup[];
     tags: Record<string, string>; // tagName -> commitId
-    references: Record<string, string>; // references like ORIG_HEAD -> commitId
+    references: Record<string, string>; // pseudo-refs (ORIG_HEAD, MERGE_HEAD, CHERRY_PICK_HEAD, REBASE_HEAD, FETCH_HEAD) -> commitId
     HEAD: { type: 'branch' | 'commit' | 'none', ref: string | null, id?: string };
     potentialCommits: Commit[];
     staging: string[];
     modified: string[];
     untracked: string[];
+    ignored?: string[]; // untracked paths excluded by .gitignore ("build/" for whole directories)
     fileStatuses: Record<string, string>;
     files: string[];
     currentPath?: string;
@@ -34,6 +84,14 @@ export interface GitState {
     activeProject?: string;
     remotes?: Remote[]; // Defined remotes
     sharedRemotes?: string[];
+    remotePermission?: RemotePermission; // remote view only: who may push to it
+    refPagination?: {
+        totalBranches: number;
+        totalTags: number;
+        nextBranchCursor?: string;
+        nextTagCursor?: string;
+    };
+    commitPagination?: CommitPagination; // set when the commits were paged or cut at a depth
 
 
     output: string[];
@@ -43,8 +101,125 @@ export interface GitState {
 
 
 
+export type RemoteAccess = 'open' | 'owner' | 'read-only';
+
+export interface RemotePermission {
+    access: RemoteAccess;
+    owner?: string; // session that created the remote
+}
+
 export type PullRequestStatus = 'OPEN' | 'MERGED' | 'CLOSED';
 
+export type PullRequestMergeStrategy = 'merge' | 'squash' | 'rebase';
+
+export type PullRequestReviewState = 'APPROVED' | 'CHANGES_REQUESTED' | 'COMMENTED';
+
+export interface PullRequestReview {
+    author: string;
+    state: PullRequestReviewState;
+    body?: string;
+    createdAt: string;
+}
+
+export interface PullRequestComment {
+    id: number;
+    author: string;
+    body: string;
+    path?: string;
+    line?: number;
+    createdAt: string;
+}
+
+export interface UserIdentity {
+    name: string;
+    email: string;
+}
+
+export interface Collaborator {
+    user: UserIdentity;
+    repo: string;
+    branch?: string;
+    lastActive: string;
+    pushed?: Record<string, string>; // branch -> commit last pushed by this user
+}
+
+export interface PushConflict {
+    remote: string;
+    branch: string;
+    user: UserIdentity;
+    pushedBy: UserIdentity;
+    commit: string;
+    remoteTip: string;
+    createdAt: string;
+}
+
+export interface RemotePresence {
+    remote: string;
+    collaborators: Collaborator[];
+    conflicts: PushConflict[];
+}
+
+export interface TeammateAction {
+    action: 'commit' | 'branch' | 'force-push' | 'pull-request';
+    delay?: string; // e.g. "30s", waited before the step in timed runs
+    author?: string;
+    email?: string;
+    branch?: string;
+    from?: string;
+    message?: string;
+    files?: Record<string, string>;
+    delete?: string[];
+    drop?: number;
+    title?: string;
+    description?: string;
+    base?: string;
+}
+
+export interface TeammateScenario {
+    name: string;
+    description?: string;
+    author?: string;
+    email?: string;
+    steps: TeammateAction[];
+}
+
+export interface TeammateRun {
+    scenario: string;
+    remote: string;
+    timed: boolean;
+    next: number;
+    total: number;
+    nextAt?: string;
+    log: string[];
+    error?: string;
+}
+
+export type CheckState = 'pending' | 'success' | 'failure';
+
+export interface CheckRule {
+    name: string;
+    kind: 'file-exists' | 'file-contains' | 'commit-message';
+    path?: string;
+    pattern?: string;
+}
+
+export interface CheckStatus {
+    name: string;
+    state: CheckState;
+    description?: string;
+    remote: string;
+    ref?: string;
+    commit: string;
+    startedAt: string;
+    completedAt?: string;
+}
+
+export interface CheckResults {
+    commit: string;
+    state: CheckState | '';
+    statuses: CheckStatus[];
+}
+
 export interface PullRequest {
     id: number;
     title: string;
@@ -55,4 +230,527 @@ export interface PullRequest {
     creator: string;
     createdAt: string;
     remoteName?: string;
+    reviews?: PullRequestReview[];
+    reviewDecision?: 'APPROVED' | 'CHANGES_REQUESTED';
+    comments?: PullRequestComment[];
+    mergeStrategy?: PullRequestMergeStrategy;
+    mergeCommit?: string;
+    mergedBy?: string;
+    mergedAt?: string;
+    closedAt?: string;
+    checksCommit?: string;
+    checksState?: CheckState;
+}
+
+// base...head of a pull request on its shared remote (/api/pr/{id}/...)
+export interface PullRequestComparison {
+    id: number;
+    base: string; // base branch
+    head: string; // head branch
+    baseId: string;
+    headId: string;
+    mergeBase?: string; // missing for unrelated histories
+    ahead: number;
+    behind: number;
+    mergeable: boolean; // open, ahead of its base and free of conflicts
+    conflicts?: string[]; // files both branches changed differently
+}
+
+export interface PullRequestCommits extends PullRequestComparison {
+    commits: CommitSummary[]; // oldest first
+}
+
+export interface PullRequestDiff extends PullRequestComparison {
+    files: FileDiff[];
+    additions: number;
+    deletions: number;
+}
+
+// An issue of a shared remote; commits reaching its default branch with
+// "Fixes #N" close it
+export interface Issue {
+    id: number; // number within the remote
+    remoteName: string;
+    title: string;
+    body?: string;
+    state: 'OPEN' | 'CLOSED';
+    creator: string;
+    createdAt: string;
+    closedAt?: string;
+    closedBy?: string;
+    closingRef?: string; // commit whose message closed it
+}
+
+export type FileDiffStatus = 'added' | 'deleted' | 'modified' | 'renamed';
+
+export interface FileStat {
+    path: string;
+    oldPath?: string;
+    status: FileDiffStatus;
+    additions: number;
+    deletions: number;
+    binary?: boolean;
+}
+
+export interface FileDiff extends FileStat {
+    patch: string;
+}
+
+export interface DiffResponse {
+    from: string;
+    to: string; // 'worktree', 'index' or a revision
+    files: FileDiff[];
+}
+
+// Pull-request style comparison of head against base (/api/compare)
+export interface Comparison {
+    base: string;
+    head: string;
+    baseId: string;
+    headId: string;
+    mergeBase?: string; // missing for unrelated histories
+    ahead: number;
+    behind: number;
+    commits: CommitSummary[]; // commits ahead, newest first (capped)
+    files: FileStat[]; // changed from the merge base to head
+    additions: number;
+    deletions: number;
+}
+
+export interface RepoMaintenanceReport {
+    path: string;
+    storage: 'memory' | 'filesystem' | 'hybrid' | 'custom';
+    objects: number;
+    packs: number;
+    refs: number;
+    unreachableObjects: number; // Objects a gc would prune
+    gcRecommended: boolean;
+}
+
+export interface MaintenanceReport {
+    sessionId: string;
+    createdAt: string;
+    generatedAt: string;
+    repos: RepoMaintenanceReport[];
+    caches: {
+        fileCacheEntries: number;
+        fileCacheAgeMs: number;
+        fileCacheDirty: boolean;
+        lfsObjects: number;
+        lfsBytes: number;
+        reflogEntries: number;
+        lineageLinks: number;
+        searchIndexed: number; // commits in the search index
+    };
+    snapshots: {
+        persistenceEnabled: boolean;
+        saves: number;
+        lastSavedAt?: string;
+    };
+}
+
+export interface StorageQuota {
+    maxObjects: number; // 0 = unlimited
+    maxBlobBytes: number;
+    maxFiles: number;
+}
+
+export interface StorageUsage {
+    objects: number;
+    blobBytes: number;
+    files: number; // working tree files, excluding .git
+    quota: StorageQuota;
+    repos: { path: string; objects: number; blobBytes: number }[];
+}
+
+export interface BlameLine {
+    line: number; // 1-based
+    commit: string;
+    author: string;
+    email: string;
+    date: string;
+    text: string;
+}
+
+export interface BlameResult {
+    path: string;
+    revision: string;
+    totalLines: number;
+    lines: BlameLine[];
+}
+
+export interface FileHistoryEntry {
+    commit: string;
+    parents: string[];
+    author: string;
+    email: string;
+    date: string;
+    message: string;
+    diff?: FileDiff; // only when the patch was asked for
+}
+
+export interface FileHistoryResult {
+    path: string;
+    revision: string; // commit the history was walked from
+    commits: FileHistoryEntry[];
+}
+
+export interface CommitSearchQuery {
+    message?: string;
+    regex?: boolean; // message is a regular expression
+    ignoreCase?: boolean;
+    author?: string; // substring of "Name <email>"
+    path?: string; // file or directory the commit changed
+    since?: string; // RFC3339 or YYYY-MM-DD
+    until?: string;
+    limit?: number;
+}
+
+export interface CommitSummary {
+    id: string;
+    parents: string[];
+    author: string;
+    email: string;
+    date: string;
+    message: string;
+}
+
+export interface CommitSearchResult {
+    commits: CommitSummary[]; // newest first
+    total: number; // matches before the limit
+    indexed: number;
+}
+
+export interface FileContent {
+    path: string;
+    revision: string;
+    blob: string;
+    size: number;
+    binary?: boolean;
+    content: string; // empty for binary files
+}
+
+export type CompletionKind = 'command' | 'subcommand' | 'flag' | 'branch' | 'tag' | 'remote' | 'ref' | 'file' | 'directory';
+
+export interface Completion {
+    value: string; // replaces the word being completed
+    kind: CompletionKind;
+    description?: string; // usage of flags
+}
+
+export interface CompletionResult {
+    start: number; // offset of the word being completed in the line
+    word: string;
+    completions: Completion[];
+}
+
+export type GitObjectType = 'commit' | 'tree' | 'blob' | 'tag';
+
+export interface GraphObject {
+    id: string;
+    type: GitObjectType;
+    size: number;
+    label?: string; // commit subject or tag name
+    reachable: boolean; // reached from a ref, HEAD or the index
+}
+
+export interface ObjectEdge {
+    from: string;
+    to: string;
+    kind: 'parent' | 'tree' | 'entry' | 'target';
+    name?: string; // file or directory name of tree entries
+}
+
+export interface ObjectGraph {
+    objects: GraphObject[];
+    edges: ObjectEdge[];
+    refs: Record<string, string>; // ref name (and HEAD) -> object id
+    total: number;
+    truncated: boolean;
+}
+
+// Graph history of a session: applying the frames in order to the base of
+// their repository rebuilds the graph after each command.
+export interface TimelineBase {
+    refs: Record<string, string>; // ref name (and HEAD) -> commit id, or "ref: <target>"
+    commits: Commit[];
+}
+
+export interface TimelineFrame {
+    seq: number; // trace number of the command
+    command: string;
+    repo: string;
+    time: string;
+    exitCode: number;
+    refs?: Record<string, string>; // refs created or moved
+    deletedRefs?: string[];
+    newCommits?: Commit[];
+    omittedCommits?: number;
+}
+
+export interface Timeline {
+    base: Record<string, TimelineBase>; // keyed by repository path
+    frames: TimelineFrame[];
+    truncated?: boolean; // older frames were folded into the base
+}
+
+export interface IngestManifest {
+    name: string;
+    url: string;
+    path: string;
+    depth?: number;
+    syncInterval?: string; // Go duration, e.g. "5m0s"; absent when not following the upstream
+    state: 'cloning' | 'fetching' | 'ready' | 'failed';
+    error?: string;
+    startedAt: string;
+    updatedAt: string;
+}
+
+export type JobState = 'queued' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+
+// A background job, such as the ingest started by POST /api/remote/ingest
+export interface Job {
+    id: string;
+    kind: 'ingest' | 'sync';
+    target: string; // e.g. the remote being ingested
+    state: JobState;
+    phase?: string; // "cloning", "fetching" or git's progress phase ("Counting objects")
+    objects?: number;
+    totalObjects?: number; // absent while unknown
+    error?: string;
+    seq: number;
+    createdAt: string;
+    startedAt?: string;
+    finishedAt?: string;
+}
+
+// Initial content of a repository created by POST /api/remote/create
+export interface BareRepoOptions {
+    defaultBranch?: string; // "main" when the repository gets content
+    description?: string; // Written under the README title
+    readme?: boolean;
+    gitignore?: string; // Template name from RepoTemplateList
+    license?: string; // License key from RepoTemplateList, e.g. "MIT"
+}
+
+export interface RepoTemplateList {
+    gitignore: string[];
+    licenses: string[];
+}
+
+// A branch renamed on a shared remote (POST /api/remote/branches/rename)
+export interface BranchRename {
+    remote: string;
+    from: string;
+    to: string;
+    defaultBranch: boolean; // HEAD moved with the branch
+    retargetedPrs?: number[]; // Open pull requests now using the new name
+}
+
+// How an ingested remote follows its upstream (GET /api/remote/sync)
+export interface RemoteSyncStatus {
+    remote: string;
+    url: string;
+    interval?: string; // Go duration, e.g. "5m0s"; absent when not syncing
+    lastSync?: string;
+    nextSync?: string;
+    lastError?: string;
+}
+
+export interface UpstreamBranchUpdate {
+    branch: string;
+    from?: string; // absent for a new branch
+    to?: string; // absent for a deleted branch
+    commits: number;
+    forced?: boolean;
+}
+
+// Sent by /api/remote/sync/events when a sync found new upstream commits
+export interface UpstreamEvent {
+    seq: number;
+    remote: string;
+    url: string;
+    branches: UpstreamBranchUpdate[];
+    at: string;
+}
+
+export interface ImportedRemote {
+    name: string;
+    url: string; // Original URL without credentials
+    simulated: boolean; // Still usable because it matches an ingested shared remote
+}
+
+export interface ImportResult {
+    repo: string;
+    head: string;
+    branches: number;
+    tags: number;
+    remotes: ImportedRemote[] | null;
+    removedConfig?: string[];
+    unmerged?: string[];
+}
+
+export type RebaseAction = 'pick' | 'reword' | 'squash' | 'drop';
+
+export interface RebaseStep {
+    action: RebaseAction;
+    commit: string;
+    subject?: string;
+    message?: string; // New message for reword
+}
+
+export interface RebasePlan {
+    repo: string;
+    phase: 'planning' | 'applying';
+    headName: string;
+    origHead: string;
+    onto: string;
+    todo: RebaseStep[];
+}
+
+export interface AuditEntry {
+    seq: number;
+    time: string;
+    sessionId: string;
+    command: string;
+    error?: string;
+}
+
+export interface SessionLogEntry {
+    time: string;
+    level: 'DEBUG' | 'INFO' | 'WARN' | 'ERROR';
+    msg: string;
+    requestId?: string;
+    command?: string;
+    attrs?: Record<string, string>;
+}
+
+export interface SavePoint {
+    name: string;
+    command?: string; // Automatic save points: the command that followed
+    auto?: boolean; // Taken by a mission rather than the learner
+    createdAt: string;
+}
+
+export type LessonKind = 'merge_conflict' | 'revert' | 'long_lived_branch';
+
+export interface LessonMoment {
+    kind: LessonKind;
+    commit: string;
+    start: string;
+    incoming?: string;
+    branch?: string;
+    summary: string;
+    expected?: Record<string, string[]>; // Lines each file must contain afterwards
+    removed?: string[];
+    days?: number;
+    commits?: number;
+}
+
+export interface GeneratedLesson {
+    moment: LessonMoment;
+    mission: { id: string; title: string; description: string; skill: string; hints: string[] };
+    yaml: string; // Mission file content for authors to review
+}
+
+export interface MissionProgress {
+    sessionId: string;
+    missionId: string;
+    completed: boolean;
+    passed: number; // Checks passed in the latest attempt
+    total: number;
+    attempts: number;
+    step: number; // Steps completed in the latest attempt
+    steps: number; // 0 for missions without steps
+    hintsUsed: number;
+    updatedAt: string;
+    startedAt?: string; // First start of the mission
+    completedAt?: string; // First verification that passed every check
+}
+
+// Instructor analytics (GET /api/analytics)
+export interface CommandStats {
+    command: string; // "(unknown)" counts commands that do not exist
+    runs: number;
+    errors: number;
+    errorRate: number; // errors / runs
+    sessions: number;
+}
+
+export interface SessionStats {
+    sessionId: string;
+    runs: number;
+    errors: number;
+    errorRate: number;
+    firstCommand: string;
+    lastCommand: string;
+    commands: CommandStats[]; // Most run first
+    missionsStarted: number;
+    missionsCompleted: number;
+}
+
+export interface MissionStats {
+    missionId: string;
+    learners: number;
+    attempts: number;
+    completions: number;
+    successRate: number; // completions / learners
+    hintsUsed: number;
+    medianCompletionSeconds?: number; // From start to completion
+    meanCompletionSeconds?: number;
+    completionSecondsSum?: number;
+    timedCompletions?: number; // Completions with a known start time
+}
+
+export interface AnalyticsReport {
+    generatedAt: string;
+    sessionId?: string; // Set when the report covers one session
+    runs: number;
+    errors: number;
+    errorRate: number;
+    commands: CommandStats[]; // Most errors first
+    sessions: SessionStats[]; // Most recently active first
+    missions: MissionStats[]; // Lowest success rate first
+}
+
+export interface RefUpdate {
+    name: string;
+    old?: string; // Missing for created refs
+    new?: string; // Missing for deleted refs
+}
+
+// What a command changed, so the UI does not have to parse its output
+export interface CommandPayload {
+    head?: string;
+    previousHead?: string; // Set when HEAD moved
+    branch?: string; // Missing when detached
+    previousBranch?: string; // Set when the checked-out branch changed
+    switched?: boolean;
+    createdCommit?: string;
+    refUpdates?: RefUpdate[];
+    directory?: string; // Set when the working directory changed
+    dryRun?: boolean;
+}
+
+// A command waiting for the user to write a message (commit, merge, revert or tag -a without -m)
+export interface PendingEditor {
+    id: string;
+    command: string;
+    file: string; // e.g. ".git/COMMIT_EDITMSG"
+    template: string; // Lines starting with '#' are dropped when completed
+}
+
+export interface CommandResult {
+    command: string;
+    stdout: string;
+    stderr?: string;
+    exitCode: number;
+    payload?: CommandPayload;
+    editor?: PendingEditor; // Complete with gitService.completeEditor
+    suggestion?: Suggestion; // How to fix a failed command
+}
+
+export interface Suggestion {
+    message: string; // e.g. "did you mean 'commit'?"
+    command?: string; // command line that does what was probably meant
 }
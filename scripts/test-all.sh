@@ -9,6 +9,8 @@ echo "🐹 Verifying Backend (Go)..."
 cd backend
 echo "   > Running Unit Tests..."
 go test ./...
+echo "   > Running Integration Tests with the race detector..."
+go test -race ./internal/git/integration/...
 echo "   > Running Linters (golangci-lint)..."
 # Uses .golangci.yml config file with testifylint, gosec, staticcheck enabled
 if command -v golangci-lint &> /dev/null; then